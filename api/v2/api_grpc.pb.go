@@ -19,24 +19,28 @@ import (
 const _ = grpc.SupportPackageIsVersion9
 
 const (
-	Dex_GetClient_FullMethodName       = "/api.Dex/GetClient"
-	Dex_CreateClient_FullMethodName    = "/api.Dex/CreateClient"
-	Dex_UpdateClient_FullMethodName    = "/api.Dex/UpdateClient"
-	Dex_DeleteClient_FullMethodName    = "/api.Dex/DeleteClient"
-	Dex_ListClients_FullMethodName     = "/api.Dex/ListClients"
-	Dex_CreatePassword_FullMethodName  = "/api.Dex/CreatePassword"
-	Dex_UpdatePassword_FullMethodName  = "/api.Dex/UpdatePassword"
-	Dex_DeletePassword_FullMethodName  = "/api.Dex/DeletePassword"
-	Dex_ListPasswords_FullMethodName   = "/api.Dex/ListPasswords"
-	Dex_CreateConnector_FullMethodName = "/api.Dex/CreateConnector"
-	Dex_UpdateConnector_FullMethodName = "/api.Dex/UpdateConnector"
-	Dex_DeleteConnector_FullMethodName = "/api.Dex/DeleteConnector"
-	Dex_ListConnectors_FullMethodName  = "/api.Dex/ListConnectors"
-	Dex_GetVersion_FullMethodName      = "/api.Dex/GetVersion"
-	Dex_GetDiscovery_FullMethodName    = "/api.Dex/GetDiscovery"
-	Dex_ListRefresh_FullMethodName     = "/api.Dex/ListRefresh"
-	Dex_RevokeRefresh_FullMethodName   = "/api.Dex/RevokeRefresh"
-	Dex_VerifyPassword_FullMethodName  = "/api.Dex/VerifyPassword"
+	Dex_GetClient_FullMethodName            = "/api.Dex/GetClient"
+	Dex_CreateClient_FullMethodName         = "/api.Dex/CreateClient"
+	Dex_UpdateClient_FullMethodName         = "/api.Dex/UpdateClient"
+	Dex_DeleteClient_FullMethodName         = "/api.Dex/DeleteClient"
+	Dex_ListClients_FullMethodName          = "/api.Dex/ListClients"
+	Dex_CreatePassword_FullMethodName       = "/api.Dex/CreatePassword"
+	Dex_UpdatePassword_FullMethodName       = "/api.Dex/UpdatePassword"
+	Dex_DeletePassword_FullMethodName       = "/api.Dex/DeletePassword"
+	Dex_ListPasswords_FullMethodName        = "/api.Dex/ListPasswords"
+	Dex_CreateConnector_FullMethodName      = "/api.Dex/CreateConnector"
+	Dex_UpdateConnector_FullMethodName      = "/api.Dex/UpdateConnector"
+	Dex_DeleteConnector_FullMethodName      = "/api.Dex/DeleteConnector"
+	Dex_ListConnectors_FullMethodName       = "/api.Dex/ListConnectors"
+	Dex_GetVersion_FullMethodName           = "/api.Dex/GetVersion"
+	Dex_GetDiscovery_FullMethodName         = "/api.Dex/GetDiscovery"
+	Dex_ListRefresh_FullMethodName          = "/api.Dex/ListRefresh"
+	Dex_RevokeRefresh_FullMethodName        = "/api.Dex/RevokeRefresh"
+	Dex_VerifyPassword_FullMethodName       = "/api.Dex/VerifyPassword"
+	Dex_QueryAuditEvents_FullMethodName     = "/api.Dex/QueryAuditEvents"
+	Dex_ListTermsAcceptances_FullMethodName = "/api.Dex/ListTermsAcceptances"
+	Dex_GetLogLevels_FullMethodName         = "/api.Dex/GetLogLevels"
+	Dex_SetLogLevel_FullMethodName          = "/api.Dex/SetLogLevel"
 )
 
 // DexClient is the client API for Dex service.
@@ -83,6 +87,15 @@ type DexClient interface {
 	RevokeRefresh(ctx context.Context, in *RevokeRefreshReq, opts ...grpc.CallOption) (*RevokeRefreshResp, error)
 	// VerifyPassword returns whether a password matches a hash for a specific email or not.
 	VerifyPassword(ctx context.Context, in *VerifyPasswordReq, opts ...grpc.CallOption) (*VerifyPasswordResp, error)
+	// QueryAuditEvents lists the audit events kept in the storage.
+	QueryAuditEvents(ctx context.Context, in *QueryAuditEventsReq, opts ...grpc.CallOption) (*QueryAuditEventsResp, error)
+	// ListTermsAcceptances lists which version of the terms of a client users
+	// accepted, and when.
+	ListTermsAcceptances(ctx context.Context, in *ListTermsAcceptancesReq, opts ...grpc.CallOption) (*ListTermsAcceptancesResp, error)
+	// GetLogLevels returns the log levels of the running server.
+	GetLogLevels(ctx context.Context, in *GetLogLevelsReq, opts ...grpc.CallOption) (*GetLogLevelsResp, error)
+	// SetLogLevel changes the log level of one module of the running server.
+	SetLogLevel(ctx context.Context, in *SetLogLevelReq, opts ...grpc.CallOption) (*SetLogLevelResp, error)
 }
 
 type dexClient struct {
@@ -273,6 +286,46 @@ func (c *dexClient) VerifyPassword(ctx context.Context, in *VerifyPasswordReq, o
 	return out, nil
 }
 
+func (c *dexClient) QueryAuditEvents(ctx context.Context, in *QueryAuditEventsReq, opts ...grpc.CallOption) (*QueryAuditEventsResp, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(QueryAuditEventsResp)
+	err := c.cc.Invoke(ctx, Dex_QueryAuditEvents_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *dexClient) ListTermsAcceptances(ctx context.Context, in *ListTermsAcceptancesReq, opts ...grpc.CallOption) (*ListTermsAcceptancesResp, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListTermsAcceptancesResp)
+	err := c.cc.Invoke(ctx, Dex_ListTermsAcceptances_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *dexClient) GetLogLevels(ctx context.Context, in *GetLogLevelsReq, opts ...grpc.CallOption) (*GetLogLevelsResp, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GetLogLevelsResp)
+	err := c.cc.Invoke(ctx, Dex_GetLogLevels_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *dexClient) SetLogLevel(ctx context.Context, in *SetLogLevelReq, opts ...grpc.CallOption) (*SetLogLevelResp, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(SetLogLevelResp)
+	err := c.cc.Invoke(ctx, Dex_SetLogLevel_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // DexServer is the server API for Dex service.
 // All implementations must embed UnimplementedDexServer
 // for forward compatibility.
@@ -317,6 +370,15 @@ type DexServer interface {
 	RevokeRefresh(context.Context, *RevokeRefreshReq) (*RevokeRefreshResp, error)
 	// VerifyPassword returns whether a password matches a hash for a specific email or not.
 	VerifyPassword(context.Context, *VerifyPasswordReq) (*VerifyPasswordResp, error)
+	// QueryAuditEvents lists the audit events kept in the storage.
+	QueryAuditEvents(context.Context, *QueryAuditEventsReq) (*QueryAuditEventsResp, error)
+	// ListTermsAcceptances lists which version of the terms of a client users
+	// accepted, and when.
+	ListTermsAcceptances(context.Context, *ListTermsAcceptancesReq) (*ListTermsAcceptancesResp, error)
+	// GetLogLevels returns the log levels of the running server.
+	GetLogLevels(context.Context, *GetLogLevelsReq) (*GetLogLevelsResp, error)
+	// SetLogLevel changes the log level of one module of the running server.
+	SetLogLevel(context.Context, *SetLogLevelReq) (*SetLogLevelResp, error)
 	mustEmbedUnimplementedDexServer()
 }
 
@@ -381,6 +443,18 @@ func (UnimplementedDexServer) RevokeRefresh(context.Context, *RevokeRefreshReq)
 func (UnimplementedDexServer) VerifyPassword(context.Context, *VerifyPasswordReq) (*VerifyPasswordResp, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method VerifyPassword not implemented")
 }
+func (UnimplementedDexServer) QueryAuditEvents(context.Context, *QueryAuditEventsReq) (*QueryAuditEventsResp, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method QueryAuditEvents not implemented")
+}
+func (UnimplementedDexServer) ListTermsAcceptances(context.Context, *ListTermsAcceptancesReq) (*ListTermsAcceptancesResp, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListTermsAcceptances not implemented")
+}
+func (UnimplementedDexServer) GetLogLevels(context.Context, *GetLogLevelsReq) (*GetLogLevelsResp, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetLogLevels not implemented")
+}
+func (UnimplementedDexServer) SetLogLevel(context.Context, *SetLogLevelReq) (*SetLogLevelResp, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SetLogLevel not implemented")
+}
 func (UnimplementedDexServer) mustEmbedUnimplementedDexServer() {}
 func (UnimplementedDexServer) testEmbeddedByValue()             {}
 
@@ -726,6 +800,78 @@ func _Dex_VerifyPassword_Handler(srv interface{}, ctx context.Context, dec func(
 	return interceptor(ctx, in, info, handler)
 }
 
+func _Dex_QueryAuditEvents_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(QueryAuditEventsReq)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DexServer).QueryAuditEvents(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Dex_QueryAuditEvents_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DexServer).QueryAuditEvents(ctx, req.(*QueryAuditEventsReq))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Dex_ListTermsAcceptances_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListTermsAcceptancesReq)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DexServer).ListTermsAcceptances(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Dex_ListTermsAcceptances_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DexServer).ListTermsAcceptances(ctx, req.(*ListTermsAcceptancesReq))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Dex_GetLogLevels_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetLogLevelsReq)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DexServer).GetLogLevels(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Dex_GetLogLevels_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DexServer).GetLogLevels(ctx, req.(*GetLogLevelsReq))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Dex_SetLogLevel_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SetLogLevelReq)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DexServer).SetLogLevel(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Dex_SetLogLevel_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DexServer).SetLogLevel(ctx, req.(*SetLogLevelReq))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // Dex_ServiceDesc is the grpc.ServiceDesc for Dex service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -805,6 +951,22 @@ var Dex_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "VerifyPassword",
 			Handler:    _Dex_VerifyPassword_Handler,
 		},
+		{
+			MethodName: "QueryAuditEvents",
+			Handler:    _Dex_QueryAuditEvents_Handler,
+		},
+		{
+			MethodName: "ListTermsAcceptances",
+			Handler:    _Dex_ListTermsAcceptances_Handler,
+		},
+		{
+			MethodName: "GetLogLevels",
+			Handler:    _Dex_GetLogLevels_Handler,
+		},
+		{
+			MethodName: "SetLogLevel",
+			Handler:    _Dex_SetLogLevel_Handler,
+		},
 	},
 	Streams:  []grpc.StreamDesc{},
 	Metadata: "api/v2/api.proto",
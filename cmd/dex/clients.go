@@ -0,0 +1,340 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/ghodss/yaml"
+	"github.com/spf13/cobra"
+
+	"github.com/dexidp/dex/storage"
+)
+
+type clientsOptions struct {
+	// Config file path
+	config string
+
+	// Flags
+	tenant string
+	output string
+}
+
+// clientFlags are the client fields settable from the command line.
+type clientFlags struct {
+	id                string
+	secret            string
+	name              string
+	logoURL           string
+	public            bool
+	redirectURIs      []string
+	trustedPeers      []string
+	allowedConnectors []string
+}
+
+func (f *clientFlags) register(cmd *cobra.Command, create bool) {
+	flags := cmd.Flags()
+	if create {
+		flags.StringVar(&f.id, "id", "", "ID of the client, generated if empty")
+		flags.StringVar(&f.secret, "secret", "", "Secret of the client, generated if empty")
+		flags.BoolVar(&f.public, "public", false, "Create a public client, which has no secret")
+	}
+	flags.StringVar(&f.name, "name", "", "Name displayed to users")
+	flags.StringVar(&f.logoURL, "logo-url", "", "Logo displayed to users")
+	flags.StringSliceVar(&f.redirectURIs, "redirect-uri", nil, "Allowed redirect URI, repeatable")
+	flags.StringSliceVar(&f.trustedPeers, "trusted-peer", nil, "Client allowed to issue tokens on behalf of this one, repeatable")
+	flags.StringSliceVar(&f.allowedConnectors, "allowed-connector", nil, "Connector the client may use, repeatable; all if none")
+}
+
+// apply sets the fields of the client whose flags were given.
+func (f *clientFlags) apply(cmd *cobra.Command, c *storage.Client) {
+	flags := cmd.Flags()
+	if flags.Changed("name") {
+		c.Name = f.name
+	}
+	if flags.Changed("logo-url") {
+		c.LogoURL = f.logoURL
+	}
+	if flags.Changed("redirect-uri") {
+		c.RedirectURIs = f.redirectURIs
+	}
+	if flags.Changed("trusted-peer") {
+		c.TrustedPeers = f.trustedPeers
+	}
+	if flags.Changed("allowed-connector") {
+		c.AllowedConnectors = f.allowedConnectors
+	}
+}
+
+func commandClients() *cobra.Command {
+	options := clientsOptions{}
+
+	cmd := &cobra.Command{
+		Use:   "clients",
+		Short: "Manage the OAuth2 clients kept in the storage",
+		Long:  "Manage the OAuth2 clients kept in the storage. Static clients of the config file aren't listed and can't be changed.",
+	}
+	cmd.PersistentFlags().StringVar(&options.tenant, "tenant", "", "Operate on the clients of this tenant instead of the main issuer")
+	cmd.PersistentFlags().StringVarP(&options.output, "output", "o", "table", "Output format: table, json or yaml")
+
+	cmd.AddCommand(&cobra.Command{
+		Use:     "list [flags] [config file or directory]",
+		Short:   "List the clients",
+		Example: "dex clients list -o yaml config.yaml",
+		Args:    cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cmd.SilenceUsage = true
+			cmd.SilenceErrors = true
+			options.config = args[0]
+			return runClientsList(cmd.OutOrStdout(), options)
+		},
+	})
+
+	var createFlags clientFlags
+	createCmd := &cobra.Command{
+		Use:     "create [flags] [config file or directory]",
+		Short:   "Create a client and print it with its secret",
+		Example: "dex clients create --name 'Example App' --redirect-uri http://127.0.0.1:5555/callback config.yaml",
+		Args:    cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cmd.SilenceUsage = true
+			cmd.SilenceErrors = true
+			options.config = args[0]
+			return runClientsCreate(cmd, options, createFlags)
+		},
+	}
+	createFlags.register(createCmd, true)
+	cmd.AddCommand(createCmd)
+
+	var updateFlags clientFlags
+	updateCmd := &cobra.Command{
+		Use:     "update [flags] [config file or directory] [client ID]",
+		Short:   "Change the given fields of a client",
+		Example: "dex clients update --redirect-uri https://app.example.com/callback config.yaml example-app",
+		Args:    cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cmd.SilenceUsage = true
+			cmd.SilenceErrors = true
+			options.config = args[0]
+			return runClientsUpdate(cmd.OutOrStdout(), options, args[1], func(c *storage.Client) error {
+				updateFlags.apply(cmd, c)
+				return nil
+			}, false)
+		},
+	}
+	updateFlags.register(updateCmd, false)
+	cmd.AddCommand(updateCmd)
+
+	cmd.AddCommand(&cobra.Command{
+		Use:     "rotate-secret [flags] [config file or directory] [client ID]",
+		Short:   "Replace the secret of a client and print the new one",
+		Example: "dex clients rotate-secret config.yaml example-app",
+		Args:    cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cmd.SilenceUsage = true
+			cmd.SilenceErrors = true
+			options.config = args[0]
+			return runClientsUpdate(cmd.OutOrStdout(), options, args[1], func(c *storage.Client) error {
+				if c.Public {
+					return fmt.Errorf("client %q is public and has no secret", c.ID)
+				}
+				c.Secret = newClientSecret()
+				return nil
+			}, true)
+		},
+	})
+
+	cmd.AddCommand(&cobra.Command{
+		Use:     "delete [flags] [config file or directory] [client ID]",
+		Short:   "Delete a client",
+		Example: "dex clients delete config.yaml example-app",
+		Args:    cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cmd.SilenceUsage = true
+			cmd.SilenceErrors = true
+			options.config = args[0]
+			return runClientsDelete(cmd.OutOrStdout(), options, args[1])
+		},
+	})
+
+	return cmd
+}
+
+// newClientSecret generates a secret the way the API does.
+func newClientSecret() string {
+	return storage.NewID() + storage.NewID()
+}
+
+// clientsStorage opens the storage of the issuer selected by the options,
+// along with the config of that issuer.
+func clientsStorage(options clientsOptions) (Config, storage.Storage, error) {
+	switch options.output {
+	case "table", "json", "yaml":
+	default:
+		return Config{}, nil, fmt.Errorf("unknown output format %q", options.output)
+	}
+	c, logger, err := loadIssuerConfig(options.config, options.tenant)
+	if err != nil {
+		return c, nil, err
+	}
+	s, err := c.Storage.Config.Open(logger.With("component", "storage"))
+	if err != nil {
+		return c, nil, fmt.Errorf("failed to initialize storage: %v", err)
+	}
+	return c, s, nil
+}
+
+// checkNotStatic refuses changes to clients of the config file, which would
+// be hidden by the static client.
+func checkNotStatic(c Config, id string) error {
+	for _, client := range c.StaticClients {
+		if client.ID == id {
+			return fmt.Errorf("client %q is defined in the config file", id)
+		}
+	}
+	return nil
+}
+
+func runClientsList(out io.Writer, options clientsOptions) error {
+	_, s, err := clientsStorage(options)
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+
+	clients, err := s.ListClients(context.Background())
+	if err != nil {
+		return fmt.Errorf("failed to list clients: %v", err)
+	}
+	for i := range clients {
+		clients[i].Secret = ""
+	}
+	return printClients(out, options.output, clients, false)
+}
+
+func runClientsCreate(cmd *cobra.Command, options clientsOptions, f clientFlags) error {
+	if f.public && f.secret != "" {
+		return errors.New("--secret and --public are exclusive")
+	}
+	c, s, err := clientsStorage(options)
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+
+	client := storage.Client{
+		ID:     f.id,
+		Secret: f.secret,
+		Public: f.public,
+	}
+	if client.ID == "" {
+		client.ID = storage.NewID()
+	}
+	if client.Secret == "" && !client.Public {
+		client.Secret = newClientSecret()
+	}
+	f.apply(cmd, &client)
+	if err := checkNotStatic(c, client.ID); err != nil {
+		return err
+	}
+
+	if err := s.CreateClient(context.Background(), client); err != nil {
+		if errors.Is(err, storage.ErrAlreadyExists) {
+			return fmt.Errorf("client %q already exists", client.ID)
+		}
+		return fmt.Errorf("failed to create client: %v", err)
+	}
+	return printClients(cmd.OutOrStdout(), options.output, []storage.Client{client}, !client.Public)
+}
+
+// runClientsUpdate applies update to the client and prints the result, with
+// its secret if showSecret is set.
+func runClientsUpdate(out io.Writer, options clientsOptions, id string, update func(*storage.Client) error, showSecret bool) error {
+	c, s, err := clientsStorage(options)
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+	if err := checkNotStatic(c, id); err != nil {
+		return err
+	}
+
+	var updated storage.Client
+	err = s.UpdateClient(context.Background(), id, func(old storage.Client) (storage.Client, error) {
+		if err := update(&old); err != nil {
+			return old, err
+		}
+		updated = old
+		return old, nil
+	})
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return fmt.Errorf("client %q not found", id)
+		}
+		return fmt.Errorf("failed to update client: %v", err)
+	}
+	if !showSecret {
+		updated.Secret = ""
+	}
+	return printClients(out, options.output, []storage.Client{updated}, showSecret)
+}
+
+func runClientsDelete(out io.Writer, options clientsOptions, id string) error {
+	c, s, err := clientsStorage(options)
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+	if err := checkNotStatic(c, id); err != nil {
+		return err
+	}
+
+	if err := s.DeleteClient(context.Background(), id); err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return fmt.Errorf("client %q not found", id)
+		}
+		return fmt.Errorf("failed to delete client: %v", err)
+	}
+	fmt.Fprintf(out, "client %q deleted\n", id)
+	return nil
+}
+
+// printClients writes the clients in the format. Secrets are only part of
+// the table if showSecret is set; JSON and YAML carry the clients as given.
+func printClients(out io.Writer, format string, clients []storage.Client, showSecret bool) error {
+	switch format {
+	case "json":
+		data, err := json.MarshalIndent(clients, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to marshal clients: %v", err)
+		}
+		_, err = fmt.Fprintln(out, string(data))
+		return err
+	case "yaml":
+		data, err := yaml.Marshal(clients)
+		if err != nil {
+			return fmt.Errorf("failed to marshal clients: %v", err)
+		}
+		_, err = out.Write(data)
+		return err
+	}
+
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	header := "ID\tNAME\tPUBLIC\tREDIRECT URIS"
+	if showSecret {
+		header += "\tSECRET"
+	}
+	fmt.Fprintln(w, header)
+	for _, c := range clients {
+		line := fmt.Sprintf("%s\t%s\t%t\t%s", c.ID, c.Name, c.Public, strings.Join(c.RedirectURIs, ","))
+		if showSecret {
+			line += "\t" + c.Secret
+		}
+		fmt.Fprintln(w, line)
+	}
+	return w.Flush()
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ghodss/yaml"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dexidp/dex/storage"
+)
+
+func TestClientsCommands(t *testing.T) {
+	dir := t.TempDir()
+	config := filepath.Join(dir, "config.yaml")
+	require.NoError(t, os.WriteFile(config, []byte(`
+issuer: http://127.0.0.1:5556/dex
+storage:
+  type: sqlite3
+  config:
+    file: `+filepath.Join(dir, "dex.db")+`
+web:
+  http: 127.0.0.1:5556
+logger:
+  level: error
+staticClients:
+- id: static-app
+  name: Static App
+  secret: c3RhdGljLWFwcC1zZWNyZXQ=
+`), 0o600))
+
+	clients := func(args ...string) ([]storage.Client, error) {
+		cmd := commandClients()
+		var out bytes.Buffer
+		cmd.SetOut(&out)
+		cmd.SetArgs(append([]string{args[0], "-o", "json", config}, args[1:]...))
+		if err := cmd.Execute(); err != nil {
+			return nil, err
+		}
+		var got []storage.Client
+		require.NoError(t, json.Unmarshal(out.Bytes(), &got))
+		return got, nil
+	}
+
+	created, err := clients("create", "--id", "example-app", "--name", "Example App", "--redirect-uri", "http://127.0.0.1:5555/callback")
+	require.NoError(t, err)
+	require.Len(t, created, 1)
+	require.NotEmpty(t, created[0].Secret)
+	require.Equal(t, []string{"http://127.0.0.1:5555/callback"}, created[0].RedirectURIs)
+
+	updated, err := clients("update", "--name", "Renamed App", "example-app")
+	require.NoError(t, err)
+	require.Equal(t, "Renamed App", updated[0].Name)
+	require.Equal(t, created[0].RedirectURIs, updated[0].RedirectURIs, "fields without flags should be kept")
+	require.Empty(t, updated[0].Secret)
+
+	rotated, err := clients("rotate-secret", "example-app")
+	require.NoError(t, err)
+	require.NotEmpty(t, rotated[0].Secret)
+	require.NotEqual(t, created[0].Secret, rotated[0].Secret)
+
+	listed, err := clients("list")
+	require.NoError(t, err)
+	require.Len(t, listed, 1)
+	require.Equal(t, "example-app", listed[0].ID)
+	require.Empty(t, listed[0].Secret, "list should not print secrets")
+
+	_, err = clients("update", "--name", "Renamed", "static-app")
+	require.EqualError(t, err, `client "static-app" is defined in the config file`)
+
+	cmd := commandClients()
+	var out bytes.Buffer
+	cmd.SetOut(&out)
+	cmd.SetArgs([]string{"delete", config, "example-app"})
+	require.NoError(t, cmd.Execute())
+
+	cmd = commandClients()
+	out.Reset()
+	cmd.SetOut(&out)
+	cmd.SetArgs([]string{"list", "-o", "yaml", config})
+	require.NoError(t, cmd.Execute())
+	var remaining []storage.Client
+	require.NoError(t, yaml.Unmarshal(out.Bytes(), &remaining))
+	require.Empty(t, remaining)
+}
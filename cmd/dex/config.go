@@ -2,7 +2,9 @@ package main
 
 import (
 	"bytes"
+	"crypto/sha256"
 	"encoding/base64"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"log/slog"
@@ -14,7 +16,9 @@ import (
 	"github.com/go-jose/go-jose/v4"
 	"golang.org/x/crypto/bcrypt"
 
+	"github.com/dexidp/dex/pkg/events"
 	"github.com/dexidp/dex/pkg/featureflags"
+	"github.com/dexidp/dex/pkg/mailer"
 	"github.com/dexidp/dex/server"
 	"github.com/dexidp/dex/server/signer"
 	"github.com/dexidp/dex/storage"
@@ -73,6 +77,188 @@ type Config struct {
 
 	// MFA holds multi-factor authentication configuration.
 	MFA MFAConfig `json:"mfa"`
+
+	// RateLimits configures request rate limits for the authorization, token
+	// and device endpoints.
+	RateLimits RateLimits `json:"rateLimits"`
+
+	// LoginThrottle delays and blocks password logins from client IP
+	// addresses with many failed attempts, across all connectors.
+	LoginThrottle *LoginThrottle `json:"loginThrottle"`
+
+	// Captcha requires a CAPTCHA on the password login forms.
+	Captcha *Captcha `json:"captcha"`
+
+	// Risk posts every login and token request to a risk engine, which allows
+	// or denies it, or requires MFA, before tokens are issued.
+	Risk *Risk `json:"risk"`
+
+	// Maintenance refuses new logins with a maintenance page. It is applied
+	// on config reload, so it can be switched without a restart.
+	Maintenance Maintenance `json:"maintenance"`
+
+	// PasswordReset enables the "forgot password" flow of the password db.
+	PasswordReset *PasswordReset `json:"passwordReset"`
+
+	// EmailVerification enables verifying the emails of password db users
+	// created through the gRPC API.
+	EmailVerification *EmailVerification `json:"emailVerification"`
+
+	// Registration enables self-service sign-up for the password db.
+	Registration *Registration `json:"registration"`
+
+	// AdminUI enables the embedded admin web UI.
+	AdminUI *AdminUI `json:"adminUI"`
+
+	// Tenants are additional issuers served by this instance under
+	// <issuer>/t/<name>, each with its own storage, connectors, clients and
+	// signing keys.
+	Tenants []Tenant `json:"tenants"`
+
+	// Federation adds other dex instances as upstreams of this one.
+	Federation *Federation `json:"federation"`
+
+	// Events sends audit and identity events as CloudEvents to HTTP
+	// endpoints or Kafka.
+	Events *Events `json:"events"`
+
+	// AuditLog keeps the audit and identity events in the storage, so they
+	// can be queried through the API.
+	AuditLog *AuditLog `json:"auditLog"`
+
+	// UpstreamHTTP configures the HTTP clients connectors call their
+	// upstreams with.
+	UpstreamHTTP UpstreamHTTP `json:"upstreamHTTP"`
+
+	// DiscoveryOverrides adds fields to, or replaces fields of,
+	// /.well-known/openid-configuration.
+	DiscoveryOverrides map[string]interface{} `json:"discoveryOverrides"`
+}
+
+// UpstreamHTTP holds the settings of the HTTP clients shared by connectors.
+type UpstreamHTTP struct {
+	MaxIdleConnsPerHost int `json:"maxIdleConnsPerHost"`
+	// MaxConnsPerHost limits the connections per upstream host. Defaults to no
+	// limit.
+	MaxConnsPerHost int `json:"maxConnsPerHost"`
+	// IdleConnTimeout closes idle connections after this time, e.g. "90s".
+	IdleConnTimeout string `json:"idleConnTimeout"`
+	// TLSSessionCacheSize is the number of TLS sessions kept for resumption.
+	// Defaults to 64, negative disables resumption.
+	TLSSessionCacheSize int `json:"tlsSessionCacheSize"`
+	// Timeout of a request, e.g. "30s". Defaults to no timeout.
+	Timeout string `json:"timeout"`
+	// Proxy is the URL of the proxy for upstream requests. Defaults to the
+	// HTTP_PROXY, HTTPS_PROXY and NO_PROXY environment variables.
+	Proxy string `json:"proxy"`
+	// MaxConcurrentRequests limits the upstream requests in flight of each
+	// connector. Defaults to no limit.
+	MaxConcurrentRequests int `json:"maxConcurrentRequests"`
+	// MaxConcurrentRequestsByConnector overrides MaxConcurrentRequests for
+	// connectors by ID, 0 for no limit.
+	MaxConcurrentRequestsByConnector map[string]int `json:"maxConcurrentRequestsByConnector"`
+	// QueueTimeout is how long requests wait for the concurrency limit, e.g.
+	// "5s". Defaults to 10s.
+	QueueTimeout string `json:"queueTimeout"`
+}
+
+// RateLimits holds the rate limits applied to the authorization, token and
+// device endpoints. Limits with a zero rate are disabled.
+type RateLimits struct {
+	// Global limits the total number of requests across all clients.
+	Global RateLimit `json:"global"`
+	// PerIP limits requests per client IP address.
+	PerIP RateLimit `json:"perIP"`
+	// PerClient limits requests per OAuth2 client ID and client IP address.
+	PerClient RateLimit `json:"perClient"`
+
+	// Store keeps the buckets of all rate limits, and the failed logins of
+	// the login throttle and the CAPTCHA. "memory", the default, limits each
+	// dex instance on its own. "storage" shares them between the instances
+	// through the storage, which must be SQL. There is no Redis store,
+	// "storage" is meant for multi-replica deployments.
+	Store string `json:"store"`
+}
+
+const (
+	rateLimitStoreMemory  = "memory"
+	rateLimitStoreStorage = "storage"
+)
+
+// RateLimit is a token bucket refilled at Rate requests per second, allowing
+// bursts of up to Burst requests.
+type RateLimit struct {
+	Rate  float64 `json:"rate"`
+	Burst int     `json:"burst"`
+}
+
+// LoginThrottle holds the settings of the login throttling. Only failed
+// password logins count; each dex instance keeps them on its own.
+type LoginThrottle struct {
+	// FreeAttempts is the number of failed logins an address can make before
+	// its logins are delayed. Defaults to 5.
+	FreeAttempts int `json:"freeAttempts"`
+	// Delay after the first throttled failed login, e.g. "1s". It doubles
+	// with every further failure, up to MaxDelay, "1m" by default.
+	Delay    string `json:"delay"`
+	MaxDelay string `json:"maxDelay"`
+	// BlockAfter failed logins, the address is blocked for BlockFor, "15m" by
+	// default. Zero never blocks.
+	BlockAfter int    `json:"blockAfter"`
+	BlockFor   string `json:"blockFor"`
+	// Window is how long failed logins are remembered, "15m" by default.
+	Window string `json:"window"`
+	// Allowlist are the addresses or CIDRs never throttled, e.g. office
+	// ranges.
+	Allowlist []string `json:"allowlist"`
+}
+
+// Captcha holds the settings of the CAPTCHA of the password login forms.
+type Captcha struct {
+	// Provider is "hcaptcha", "recaptcha" or "turnstile".
+	Provider string `json:"provider"`
+	SiteKey  string `json:"siteKey"`
+	Secret   string `json:"secret"`
+	// AfterFailures requires the CAPTCHA only once the client IP address or
+	// the username had that many failed logins. Zero always requires it.
+	AfterFailures int `json:"afterFailures"`
+	// Window is how long failed logins are counted, "15m" by default.
+	Window string `json:"window"`
+}
+
+// Risk holds the settings of the risk engine webhook.
+type Risk struct {
+	// URL the logins are posted to.
+	URL string `json:"url"`
+	// Headers are added to each request, e.g. for authentication.
+	Headers map[string]string `json:"headers"`
+	// Timeout of an assessment, "5s" by default.
+	Timeout string `json:"timeout"`
+	// FailOpen allows logins if the risk engine fails. By default they are
+	// denied.
+	FailOpen bool `json:"failOpen"`
+	// StepUpMFAChain are the MFA authenticators of logins the risk engine
+	// requires step-up for, if the client has no MFA chain. Defaults to
+	// mfa.defaultMFAChain.
+	StepUpMFAChain []string `json:"stepUpMFAChain"`
+	// History is the number of recent logins sent along from the audit log.
+	// Defaults to 10.
+	History int `json:"history"`
+}
+
+// Maintenance holds the maintenance mode settings. While enabled, new logins
+// are refused; refresh tokens and the API keep working.
+type Maintenance struct {
+	Enabled bool `json:"enabled"`
+	// Message is shown on the maintenance page.
+	Message string `json:"message"`
+	// RetryAfter is sent to clients in the Retry-After header, for example
+	// "30m".
+	RetryAfter string `json:"retryAfter"`
+}
+
+func (r RateLimits) enabled() bool {
+	return r.Global.Rate > 0 || r.PerIP.Rate > 0 || r.PerClient.Rate > 0
 }
 
 // MFAConfig holds multi-factor authentication settings.
@@ -98,16 +284,43 @@ func (c Config) Validate() error {
 		{c.Web.HTTP == "" && c.Web.HTTPS == "", "must supply a HTTP/HTTPS  address to listen on"},
 		{c.Web.HTTPS != "" && c.Web.TLSCert == "", "no cert specified for HTTPS"},
 		{c.Web.HTTPS != "" && c.Web.TLSKey == "", "no private key specified for HTTPS"},
+		{c.Web.HTTPS == "" && c.Web.TLSClientCA != "", "cannot specify a TLS client CA without HTTPS"},
 		{c.Web.TLSMinVersion != "" && c.Web.TLSMinVersion != "1.2" && c.Web.TLSMinVersion != "1.3", "supported TLS versions are: 1.2, 1.3"},
 		{c.Web.TLSMaxVersion != "" && c.Web.TLSMaxVersion != "1.2" && c.Web.TLSMaxVersion != "1.3", "supported TLS versions are: 1.2, 1.3"},
 		{c.Web.TLSMaxVersion != "" && c.Web.TLSMinVersion != "" && c.Web.TLSMinVersion > c.Web.TLSMaxVersion, "TLSMinVersion greater than TLSMaxVersion"},
-		{c.GRPC.TLSCert != "" && c.GRPC.Addr == "", "no address specified for gRPC"},
-		{c.GRPC.TLSKey != "" && c.GRPC.Addr == "", "no address specified for gRPC"},
+		{c.GRPC.TLSCert != "" && c.GRPC.Addr == "" && c.GRPC.HTTPAddr == "", "no address specified for gRPC"},
+		{c.GRPC.TLSKey != "" && c.GRPC.Addr == "" && c.GRPC.HTTPAddr == "", "no address specified for gRPC"},
 		{(c.GRPC.TLSCert == "") != (c.GRPC.TLSKey == ""), "must specific both a gRPC TLS cert and key"},
 		{c.GRPC.TLSCert == "" && c.GRPC.TLSClientCA != "", "cannot specify gRPC TLS client CA without a gRPC TLS cert"},
 		{c.GRPC.TLSMinVersion != "" && c.GRPC.TLSMinVersion != "1.2" && c.GRPC.TLSMinVersion != "1.3", "supported TLS versions are: 1.2, 1.3"},
 		{c.GRPC.TLSMaxVersion != "" && c.GRPC.TLSMaxVersion != "1.2" && c.GRPC.TLSMaxVersion != "1.3", "supported TLS versions are: 1.2, 1.3"},
 		{c.GRPC.TLSMaxVersion != "" && c.GRPC.TLSMinVersion != "" && c.GRPC.TLSMinVersion > c.GRPC.TLSMaxVersion, "TLSMinVersion greater than TLSMaxVersion"},
+		{c.GRPC.Authorization != nil && c.GRPC.TLSClientCA == "", "grpc authorization requires tlsClientCA"},
+		{len(c.Web.ClientRemoteIP.TrustedProxies) > 0 && c.Web.ClientRemoteIP.Header == "", "clientRemoteIP.trustedProxies requires a header"},
+		{c.Web.ClientRemoteIP.TrustUnixSocket && c.Web.ClientRemoteIP.Header == "", "clientRemoteIP.trustUnixSocket requires a header"},
+		{c.Web.Limits.Auth.MaxBodySize < 0 || c.Web.Limits.Token.MaxBodySize < 0 || c.Web.Limits.Device.MaxBodySize < 0 || c.Web.Limits.API.MaxBodySize < 0, "request body size limits cannot be negative"},
+		{c.RateLimits.Store != "" && c.RateLimits.Store != rateLimitStoreMemory && c.RateLimits.Store != rateLimitStoreStorage, "rate limits store must be \"memory\" or \"storage\""},
+		{c.RateLimits.Global.Rate < 0 || c.RateLimits.PerIP.Rate < 0 || c.RateLimits.PerClient.Rate < 0, "rate limits cannot be negative"},
+		{c.RateLimits.Global.Rate > 0 && c.RateLimits.Global.Burst < 1, "global rate limit requires a burst of at least 1"},
+		{c.RateLimits.PerIP.Rate > 0 && c.RateLimits.PerIP.Burst < 1, "per IP rate limit requires a burst of at least 1"},
+		{c.RateLimits.PerClient.Rate > 0 && c.RateLimits.PerClient.Burst < 1, "per client rate limit requires a burst of at least 1"},
+		{c.PasswordReset != nil && !c.EnablePasswordDB, "cannot enable password reset without enabling password db"},
+		{c.PasswordReset != nil && c.PasswordReset.Mailer.Config == nil, "no mailer supplied for password reset"},
+		{c.PasswordReset != nil && c.PasswordReset.RateLimit.Rate > 0 && c.PasswordReset.RateLimit.Burst < 1, "password reset rate limit requires a burst of at least 1"},
+		{c.EmailVerification != nil && !c.EnablePasswordDB, "cannot enable email verification without enabling password db"},
+		{c.EmailVerification != nil && c.EmailVerification.Mailer.Config == nil, "no mailer supplied for email verification"},
+		{c.Registration != nil && !c.EnablePasswordDB, "cannot enable registration without enabling password db"},
+		{c.Registration != nil && c.Registration.VerifyEmail && c.EmailVerification == nil, "registration verifyEmail requires emailVerification to be configured"},
+		{c.Registration != nil && c.Registration.RateLimit.Rate > 0 && c.Registration.RateLimit.Burst < 1, "registration rate limit requires a burst of at least 1"},
+		{c.AdminUI != nil && c.AdminUI.HTTP == "" && c.Telemetry.HTTP == "", "admin UI requires either adminUI.http or telemetry.http to be set"},
+		{c.AdminUI != nil && c.AdminUI.URL == "", "no url supplied for admin UI"},
+		{c.AdminUI != nil && c.AdminUI.ClientSecret == "", "no clientSecret supplied for admin UI"},
+		{c.AdminUI != nil && len(c.AdminUI.AdminGroups) == 0, "admin UI requires at least one admin group"},
+		{c.Events != nil && len(c.Events.Sinks) == 0, "events requires at least one sink"},
+		{c.UpstreamHTTP.MaxIdleConnsPerHost < 0 || c.UpstreamHTTP.MaxConnsPerHost < 0, "upstreamHTTP connection limits cannot be negative"},
+		{c.UpstreamHTTP.MaxConcurrentRequests < 0, "upstreamHTTP.maxConcurrentRequests cannot be negative"},
+		{c.Events != nil && c.Events.QueueSize < 0, "events queueSize cannot be negative"},
+		{c.Expiry.OfflineSessions != nil && c.Expiry.OfflineSessions.MaxPerUserClient < 0, "offline sessions maxPerUserClient cannot be negative"},
 	}
 
 	var checkErrors []string
@@ -122,6 +335,13 @@ func (c Config) Validate() error {
 		return fmt.Errorf("invalid Config:\n\t-\t%s", strings.Join(checkErrors, "\n\t-\t"))
 	}
 
+	if _, err := parseTLSCipherSuites(c.Web.TLSCipherSuites); err != nil {
+		return fmt.Errorf("invalid Config: web: %v", err)
+	}
+	if _, err := parseTLSCipherSuites(c.GRPC.TLSCipherSuites); err != nil {
+		return fmt.Errorf("invalid Config: grpc: %v", err)
+	}
+
 	if c.Sessions != nil && !featureflags.SessionsEnabled.Enabled() {
 		return fmt.Errorf("sessions config requires sessions to be enabled (DEX_SESSIONS_ENABLED=true)")
 	}
@@ -130,6 +350,49 @@ func (c Config) Validate() error {
 		return err
 	}
 
+	if err := c.validateTenants(); err != nil {
+		return err
+	}
+
+	if err := c.validateFederation(); err != nil {
+		return err
+	}
+
+	if err := server.ValidateGroupsClaim(c.OAuth2.GroupsClaim); err != nil {
+		return fmt.Errorf("invalid oauth2.groupsClaim: %v", err)
+	}
+
+	if err := server.ValidateDiscoveryOverrides(c.DiscoveryOverrides); err != nil {
+		return fmt.Errorf("invalid discoveryOverrides: %v", err)
+	}
+
+	for _, client := range c.StaticClients {
+		if err := server.ValidateCustomClaims(client.CustomClaims); err != nil {
+			return fmt.Errorf("staticClients: client %q has invalid customClaims: %v", client.ID, err)
+		}
+		if client.GroupsClaim != nil {
+			if err := server.ValidateGroupsClaim(*client.GroupsClaim); err != nil {
+				return fmt.Errorf("staticClients: client %q has invalid groupsClaim: %v", client.ID, err)
+			}
+		}
+		if client.DeviceFlow != nil {
+			if err := server.ValidateDeviceFlow(*client.DeviceFlow); err != nil {
+				return fmt.Errorf("staticClients: client %q has invalid deviceFlow: %v", client.ID, err)
+			}
+		}
+		if client.Impersonation != nil {
+			if client.Public {
+				return fmt.Errorf("staticClients: public client %q can't impersonate users", client.ID)
+			}
+			if err := server.ValidateImpersonationPolicy(*client.Impersonation); err != nil {
+				return fmt.Errorf("staticClients: client %q has invalid impersonation: %v", client.ID, err)
+			}
+		}
+		if client.Terms != nil && client.Terms.Version == "" {
+			return fmt.Errorf("staticClients: client %q has terms without a version", client.ID)
+		}
+	}
+
 	return nil
 }
 
@@ -243,6 +506,16 @@ type OAuth2 struct {
 	AlwaysShowLoginScreen bool `json:"alwaysShowLoginScreen"`
 	// This is the connector that can be used for password grant
 	PasswordConnector string `json:"passwordConnector"`
+	// If true, only clients with a token exchange policy can use token
+	// exchange. If unset, a policy is only required to exchange tokens of
+	// HSDP connectors; false lets any client exchange them.
+	TokenExchangeRequiresPolicy *bool `json:"tokenExchangeRequiresPolicy"`
+	// Key signing the relay states connectors carry through upstream logins.
+	// If empty, a random key is generated on startup, so relay states don't
+	// survive restarts and only work on the instance that issued them.
+	RelayStateKey string `json:"relayStateKey"`
+	// Name and format of the groups claim, which clients can override.
+	GroupsClaim storage.GroupsClaim `json:"groupsClaim"`
 	// PKCE configuration
 	PKCE PKCE `json:"pkce"`
 	// List of additional scope prefixes to allow
@@ -269,11 +542,108 @@ type Web struct {
 	AllowedOrigins []string       `json:"allowedOrigins"`
 	AllowedHeaders []string       `json:"allowedHeaders"`
 	ClientRemoteIP ClientRemoteIP `json:"clientRemoteIP"`
+
+	// TLSCipherSuites restricts the TLS 1.2 cipher suites by their Go names,
+	// e.g. TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256.
+	TLSCipherSuites []string `json:"tlsCipherSuites"`
+
+	// TLSClientCA verifies the certificates of HTTPS clients which present
+	// one, e.g. reverse proxies in front of the authproxy connector. Clients
+	// without a certificate are still served.
+	TLSClientCA string `json:"tlsClientCA"`
+
+	// DrainTimeout is how long dex waits on shutdown for logins in flight to
+	// complete before it stops listening. New logins are rejected meanwhile.
+	DrainTimeout string `json:"drainTimeout"`
+
+	// Timeouts bound reading requests and writing responses on the HTTP(S)
+	// listeners and the JSON API.
+	Timeouts Timeouts `json:"timeouts"`
+
+	// Limits bound the handling time and body size of requests per endpoint
+	// group.
+	Limits EndpointLimits `json:"limits"`
+
+	// CORS overrides allowedOrigins and allowedHeaders per endpoint.
+	CORS CORS `json:"cors"`
+}
+
+// CORS holds the CORS policies per endpoint. Endpoints without a policy use
+// allowedOrigins and allowedHeaders, an empty policy disables CORS.
+type CORS struct {
+	Discovery  *CORSPolicy `json:"discovery"`
+	Keys       *CORSPolicy `json:"keys"`
+	Token      *CORSPolicy `json:"token"`
+	UserInfo   *CORSPolicy `json:"userinfo"`
+	Introspect *CORSPolicy `json:"introspect"`
+}
+
+// CORSPolicy configures cross-origin requests to an endpoint. Credentials
+// are never allowed.
+type CORSPolicy struct {
+	// AllowedOrigins are the origins allowed to make requests. "*" allows
+	// any origin.
+	AllowedOrigins []string `json:"allowedOrigins"`
+	// AllowClientOrigins additionally allows the allowedOrigins of the
+	// client making the request, or of any client for requests not
+	// identifying one, like preflight requests.
+	AllowClientOrigins bool `json:"allowClientOrigins"`
+	// AllowedHeaders are the request headers allowed besides the simple ones.
+	AllowedHeaders []string `json:"allowedHeaders"`
+	// MaxAge is how long browsers may cache preflight results, e.g. "10m".
+	MaxAge string `json:"maxAge"`
 }
 
+// Timeouts are the timeouts of the HTTP servers, e.g. "10s". Empty values
+// disable the respective timeout.
+type Timeouts struct {
+	// ReadHeader is how long reading the request headers may take.
+	ReadHeader string `json:"readHeader"`
+	// Read is how long reading the entire request may take.
+	Read string `json:"read"`
+	// Write is how long writing the response may take, counted from the end
+	// of the request headers. It should exceed the endpoint timeouts.
+	Write string `json:"write"`
+	// Idle is how long a keep-alive connection may wait for the next request.
+	Idle string `json:"idle"`
+}
+
+// EndpointLimits holds the request limits of the endpoint groups.
+type EndpointLimits struct {
+	// Auth covers the login flow: /auth, /callback, /approval and the
+	// password reset, registration, logout and MFA pages.
+	Auth RequestLimit `json:"auth"`
+	// Token covers /token, /token/introspect and /userinfo.
+	Token RequestLimit `json:"token"`
+	// Device covers the device flow endpoints under /device.
+	Device RequestLimit `json:"device"`
+	// API covers the gRPC API and its JSON facade.
+	API RequestLimit `json:"api"`
+}
+
+// RequestLimit bounds the requests of an endpoint group.
+type RequestLimit struct {
+	// Timeout of a request, e.g. "30s". Requests taking longer are answered
+	// with 503 Service Unavailable. Defaults to no timeout.
+	Timeout string `json:"timeout"`
+	// MaxBodySize is the largest request body accepted, in bytes. Defaults to
+	// no limit.
+	MaxBodySize int64 `json:"maxBodySize"`
+}
+
+// ClientRemoteIP configures how the IP address of clients is resolved behind
+// proxies. It is used by rate limits, sessions and logs.
 type ClientRemoteIP struct {
-	Header         string   `json:"header"`
+	// Header carrying the client address, e.g. X-Forwarded-For, Forwarded
+	// or X-Real-IP.
+	Header string `json:"header"`
+	// TrustedProxies are the addresses or CIDRs of the proxies in front of
+	// dex. The header is only honored for requests from them, and their own
+	// addresses in it are skipped. If empty, the header is ignored.
 	TrustedProxies []string `json:"trustedProxies"`
+	// TrustUnixSocket honors the header for requests through unix sockets,
+	// for proxies connecting to a unix socket listener.
+	TrustUnixSocket bool `json:"trustUnixSocket"`
 }
 
 func (cr *ClientRemoteIP) ParseTrustedProxies() ([]netip.Prefix, error) {
@@ -281,16 +651,25 @@ func (cr *ClientRemoteIP) ParseTrustedProxies() ([]netip.Prefix, error) {
 		return nil, nil
 	}
 
-	trusted := make([]netip.Prefix, 0, len(cr.TrustedProxies))
-	for _, cidr := range cr.TrustedProxies {
+	return parsePrefixes(cr.TrustedProxies)
+}
+
+// parsePrefixes parses a list of addresses or CIDRs.
+func parsePrefixes(cidrs []string) ([]netip.Prefix, error) {
+	prefixes := make([]netip.Prefix, 0, len(cidrs))
+	for _, cidr := range cidrs {
+		if ip, err := netip.ParseAddr(cidr); err == nil {
+			prefixes = append(prefixes, netip.PrefixFrom(ip, ip.BitLen()))
+			continue
+		}
 		ipNet, err := netip.ParsePrefix(cidr)
 		if err != nil {
 			return nil, fmt.Errorf("failed to parse CIDR %q: %v", cidr, err)
 		}
-		trusted = append(trusted, ipNet)
+		prefixes = append(prefixes, ipNet)
 	}
 
-	return trusted, nil
+	return prefixes, nil
 }
 
 type Headers struct {
@@ -344,6 +723,18 @@ type Telemetry struct {
 	HTTP string `json:"http"`
 	// EnableProfiling makes profiling endpoints available via web interface host:port/debug/pprof/
 	EnableProfiling bool `json:"enableProfiling"`
+	// Readiness configures the dependency checks reported by /healthz/ready.
+	Readiness Readiness `json:"readiness"`
+}
+
+// Readiness holds configuration for the dependency checks behind the readiness probe.
+type Readiness struct {
+	// CheckInterval is how often dependencies are probed. Probe results are cached
+	// between runs, so the readiness endpoint never calls upstreams directly.
+	// Defaults to "15s".
+	CheckInterval string `json:"checkInterval"`
+	// Connectors enables upstream reachability checks for connectors which support them.
+	Connectors bool `json:"connectors"`
 }
 
 // GRPC is the config for the gRPC API.
@@ -356,6 +747,29 @@ type GRPC struct {
 	TLSMinVersion string `json:"tlsMinVersion"`
 	TLSMaxVersion string `json:"tlsMaxVersion"`
 	Reflection    bool   `json:"reflection"`
+	// HTTPAddr is the address to serve the versioned JSON API, a REST facade
+	// of the gRPC API, on. It uses the same TLS settings as the gRPC API.
+	HTTPAddr string `json:"httpAddr"`
+	// TLSCipherSuites restricts the TLS 1.2 cipher suites by their Go names,
+	// e.g. TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256.
+	TLSCipherSuites []string `json:"tlsCipherSuites"`
+
+	// Authorization restricts the API methods clients may call. Requires
+	// TLSClientCA.
+	Authorization *GRPCAuthorization `json:"authorization"`
+}
+
+// GRPCAuthorization grants API clients roles by the common name of their TLS
+// client certificate and sets the role each API method requires.
+type GRPCAuthorization struct {
+	// Roles maps role names to the common names of the client certificates
+	// granted the role.
+	Roles map[string][]string `json:"roles"`
+
+	// Methods maps API methods, e.g. "CreateClient", to the role required to
+	// call them. The role of "*" is required by the methods not listed. Methods
+	// without a required role are denied.
+	Methods map[string]string `json:"methods"`
 }
 
 // Storage holds app's storage configuration.
@@ -390,27 +804,6 @@ func getORMBasedSQLStorage(normal, entBased func() StorageConfig) func() Storage
 	}
 }
 
-// Recursively expand environment variables in the map to avoid
-// issues with JSON special characters and escapes
-func expandEnvInMap(m map[string]interface{}) {
-	for k, v := range m {
-		switch vt := v.(type) {
-		case string:
-			m[k] = os.ExpandEnv(vt)
-		case map[string]interface{}:
-			expandEnvInMap(vt)
-		case []interface{}:
-			for i, item := range vt {
-				if itemMap, ok := item.(map[string]interface{}); ok {
-					expandEnvInMap(itemMap)
-				} else if itemString, ok := item.(string); ok {
-					vt[i] = os.ExpandEnv(itemString)
-				}
-			}
-		}
-	}
-}
-
 var storages = map[string]func() StorageConfig{
 	"etcd":       func() StorageConfig { return new(etcd.Etcd) },
 	"kubernetes": func() StorageConfig { return new(kubernetes.Config) },
@@ -439,21 +832,10 @@ func (s *Storage) UnmarshalJSON(b []byte) error {
 	if len(store.Config) != 0 {
 		data := []byte(store.Config)
 		if featureflags.ExpandEnv.Enabled() {
-			var rawMap map[string]interface{}
-			if err := configUnmarshaller(store.Config, &rawMap); err != nil {
-				return fmt.Errorf("unmarshal config for env expansion: %v", err)
-			}
-
-			// Recursively expand environment variables in the map to avoid
-			// issues with JSON special characters and escapes
-			expandEnvInMap(rawMap)
-
-			// Marshal the expanded map back to JSON
-			expandedData, err := json.Marshal(rawMap)
+			expandedData, err := expandPluginConfig(store.Config)
 			if err != nil {
-				return fmt.Errorf("marshal expanded config: %v", err)
+				return fmt.Errorf("storage config: %v", err)
 			}
-
 			data = expandedData
 		}
 
@@ -507,20 +889,10 @@ func (s *Signer) UnmarshalJSON(b []byte) error {
 	if len(signerData.Config) != 0 {
 		data := []byte(signerData.Config)
 		if featureflags.ExpandEnv.Enabled() {
-			var rawMap map[string]interface{}
-			if err := json.Unmarshal(signerData.Config, &rawMap); err != nil {
-				return fmt.Errorf("unmarshal config for env expansion: %v", err)
-			}
-
-			// Recursively expand environment variables in the map
-			expandEnvInMap(rawMap)
-
-			// Marshal the expanded map back to JSON
-			expandedData, err := json.Marshal(rawMap)
+			expandedData, err := expandPluginConfig(signerData.Config)
 			if err != nil {
-				return fmt.Errorf("marshal expanded config: %v", err)
+				return fmt.Errorf("signer config: %v", err)
 			}
-
 			data = expandedData
 		}
 
@@ -586,21 +958,10 @@ func (c *Connector) UnmarshalJSON(b []byte) error {
 	if len(conn.Config) != 0 {
 		data := []byte(conn.Config)
 		if featureflags.ExpandEnv.Enabled() {
-			var rawMap map[string]interface{}
-			if err := configUnmarshaller(conn.Config, &rawMap); err != nil {
-				return fmt.Errorf("unmarshal config for env expansion: %v", err)
-			}
-
-			// Recursively expand environment variables in the map to avoid
-			// issues with JSON special characters and escapes
-			expandEnvInMap(rawMap)
-
-			// Marshal the expanded map back to JSON
-			expandedData, err := json.Marshal(rawMap)
+			expandedData, err := expandPluginConfig(conn.Config)
 			if err != nil {
-				return fmt.Errorf("marshal expanded config: %v", err)
+				return fmt.Errorf("connector %q config: %v", conn.ID, err)
 			}
-
 			data = expandedData
 		}
 
@@ -626,13 +987,23 @@ func ToStorageConnector(c Connector) (storage.Connector, error) {
 		return storage.Connector{}, fmt.Errorf("failed to marshal connector config: %v", err)
 	}
 
-	return storage.Connector{
+	conn := storage.Connector{
 		ID:         c.ID,
 		Type:       c.Type,
 		Name:       c.Name,
 		Config:     data,
 		GrantTypes: c.GrantTypes,
-	}, nil
+	}
+
+	// Version static connectors by their contents, so that reloading the
+	// config only reopens the connectors which changed.
+	version, err := json.Marshal(conn)
+	if err != nil {
+		return storage.Connector{}, fmt.Errorf("failed to marshal connector: %v", err)
+	}
+	sum := sha256.Sum256(version)
+	conn.ResourceVersion = hex.EncodeToString(sum[:8])
+	return conn, nil
 }
 
 // Expiry holds configuration for the validity period of components.
@@ -651,6 +1022,9 @@ type Expiry struct {
 
 	// RefreshTokens defines refresh tokens expiry policy
 	RefreshTokens RefreshToken `json:"refreshTokens"`
+
+	// OfflineSessions defines the retention policy for offline sessions.
+	OfflineSessions *OfflineSessions `json:"offlineSessions"`
 }
 
 // Logger holds configuration required to customize logging for dex.
@@ -666,6 +1040,10 @@ type Logger struct {
 	// preferred_username, or groups in environments subject to GDPR or similar
 	// data-handling constraints.
 	ExcludeFields []string `json:"excludeFields"`
+
+	// Levels overrides the level of modules: "server", "storage", "api",
+	// "admin" or "connector.<id>" for the connector with that ID.
+	Levels map[string]slog.Level `json:"levels"`
 }
 
 type RefreshToken struct {
@@ -675,6 +1053,17 @@ type RefreshToken struct {
 	ValidIfNotUsedFor string `json:"validIfNotUsedFor"`
 }
 
+// OfflineSessions holds the retention policy for offline sessions. Stale
+// refresh tokens are pruned during garbage collection.
+type OfflineSessions struct {
+	// ValidIfNotUsedFor prunes refresh tokens that have not been used for
+	// this long.
+	ValidIfNotUsedFor string `json:"validIfNotUsedFor"`
+	// MaxPerUserClient caps the number of refresh tokens kept per user and
+	// client, evicting the least recently used ones.
+	MaxPerUserClient int `json:"maxPerUserClient"`
+}
+
 // Sessions holds authentication session configuration.
 type Sessions struct {
 	// CookieName is the name of the session cookie. Defaults to "dex_session".
@@ -694,6 +1083,210 @@ type Sessions struct {
 	SSOSharedWithDefault string `json:"ssoSharedWithDefault"`
 }
 
+// PasswordReset holds the configuration of the "forgot password" flow.
+type PasswordReset struct {
+	// Mailer delivers the reset links.
+	Mailer Mailer `json:"mailer"`
+	// Key signs the reset links. If empty, a random key is generated on
+	// startup, so links don't survive restarts and only work on the instance
+	// that sent them.
+	Key string `json:"key"`
+	// TokenValidFor is how long a reset link can be used. Defaults to "1h".
+	TokenValidFor string `json:"tokenValidFor"`
+	// RateLimit limits reset requests per email address and per client IP.
+	// Defaults to a burst of 3 and one request every ten minutes.
+	RateLimit RateLimit `json:"rateLimit"`
+}
+
+// EmailVerification holds the configuration of the email verification flow.
+type EmailVerification struct {
+	// Mailer delivers the verification links.
+	Mailer Mailer `json:"mailer"`
+	// Key signs the verification links. If empty, a random key is generated
+	// on startup, so links don't survive restarts and only work on the
+	// instance that sent them.
+	Key string `json:"key"`
+	// TokenValidFor is how long a verification link can be used. Defaults to
+	// "24h".
+	TokenValidFor string `json:"tokenValidFor"`
+}
+
+// Registration holds the configuration of self-service sign-up.
+type Registration struct {
+	// AllowedEmailDomains limits sign-up to email addresses in these domains.
+	AllowedEmailDomains []string `json:"allowedEmailDomains"`
+	// InviteCodes limits sign-up to users knowing one of these codes.
+	InviteCodes []string `json:"inviteCodes"`
+	// VerifyEmail sends new users a verification link. Requires
+	// emailVerification to be configured.
+	VerifyEmail bool `json:"verifyEmail"`
+	// RateLimit limits sign-ups per client IP. Defaults to a burst of 5 and
+	// one sign-up every ten minutes.
+	RateLimit RateLimit `json:"rateLimit"`
+}
+
+// AdminUI holds the configuration of the embedded admin web UI. Admins sign
+// in through dex with the client configured here, which is registered
+// automatically.
+type AdminUI struct {
+	// HTTP is the address of a dedicated listener for the UI. If empty, the UI
+	// is served on the telemetry listener.
+	HTTP string `json:"http"`
+	// URL is where browsers reach the UI, e.g. "http://127.0.0.1:5558/admin".
+	URL string `json:"url"`
+	// ClientID of the UI. Defaults to "dex-admin".
+	ClientID string `json:"clientID"`
+	// ClientSecret of the UI.
+	ClientSecret string `json:"clientSecret"`
+	// AdminGroups are the groups allowed to use the UI.
+	AdminGroups []string `json:"adminGroups"`
+	// SessionKey signs the session cookies of the UI. If empty, a random key
+	// is generated on startup, so admins have to sign in again after a
+	// restart.
+	SessionKey string `json:"sessionKey"`
+	// SessionValidFor is how long an admin stays signed in. Defaults to "8h".
+	SessionValidFor string `json:"sessionValidFor"`
+}
+
+// clientID returns the client ID of the admin UI.
+func (a *AdminUI) clientID() string {
+	if a.ClientID == "" {
+		return "dex-admin"
+	}
+	return a.ClientID
+}
+
+// Mailer holds the configuration of a mailer.
+type Mailer struct {
+	Type   string       `json:"type"`
+	Config MailerConfig `json:"config"`
+}
+
+// MailerConfig is a configuration that can create a mailer.
+type MailerConfig interface {
+	Open(logger *slog.Logger) (mailer.Mailer, error)
+}
+
+var (
+	_ MailerConfig = (*mailer.SMTPConfig)(nil)
+	_ MailerConfig = (*mailer.LogConfig)(nil)
+)
+
+var mailers = map[string]func() MailerConfig{
+	"smtp": func() MailerConfig { return new(mailer.SMTPConfig) },
+	"log":  func() MailerConfig { return new(mailer.LogConfig) },
+}
+
+// UnmarshalJSON allows Mailer to implement the unmarshaler interface to
+// dynamically determine the type of the mailer config.
+func (m *Mailer) UnmarshalJSON(b []byte) error {
+	var mailerData struct {
+		Type   string          `json:"type"`
+		Config json.RawMessage `json:"config"`
+	}
+	if err := configUnmarshaller(b, &mailerData); err != nil {
+		return fmt.Errorf("parse mailer: %v", err)
+	}
+	f, ok := mailers[mailerData.Type]
+	if !ok {
+		return fmt.Errorf("unknown mailer type %q", mailerData.Type)
+	}
+
+	mailerConfig := f()
+	if len(mailerData.Config) != 0 {
+		data := []byte(mailerData.Config)
+		if featureflags.ExpandEnv.Enabled() {
+			expandedData, err := expandPluginConfig(mailerData.Config)
+			if err != nil {
+				return fmt.Errorf("mailer config: %v", err)
+			}
+			data = expandedData
+		}
+
+		if err := configUnmarshaller(data, mailerConfig); err != nil {
+			return fmt.Errorf("parse mailer config: %v", err)
+		}
+	}
+	*m = Mailer{
+		Type:   mailerData.Type,
+		Config: mailerConfig,
+	}
+	return nil
+}
+
+// Events configures where audit and identity events are sent.
+type Events struct {
+	Sinks []EventSink `json:"sinks"`
+
+	// QueueSize is the number of events kept while the sinks are busy. Events
+	// are dropped once the queue is full. Defaults to 1000.
+	QueueSize int `json:"queueSize"`
+}
+
+// AuditLog holds the settings of the audit history kept in the storage.
+type AuditLog struct {
+	// Retention is how long events are kept. Defaults to "720h".
+	Retention string `json:"retention"`
+}
+
+// EventSink holds the configuration of an event sink.
+type EventSink struct {
+	Type   string          `json:"type"`
+	Config EventSinkConfig `json:"config"`
+}
+
+// EventSinkConfig is a configuration that can create an event sink.
+type EventSinkConfig interface {
+	Open(logger *slog.Logger) (events.Sink, error)
+}
+
+var (
+	_ EventSinkConfig = (*events.HTTPConfig)(nil)
+	_ EventSinkConfig = (*events.KafkaConfig)(nil)
+)
+
+var eventSinks = map[string]func() EventSinkConfig{
+	"http":  func() EventSinkConfig { return new(events.HTTPConfig) },
+	"kafka": func() EventSinkConfig { return new(events.KafkaConfig) },
+}
+
+// UnmarshalJSON allows EventSink to implement the unmarshaler interface to
+// dynamically determine the type of the sink config.
+func (s *EventSink) UnmarshalJSON(b []byte) error {
+	var sinkData struct {
+		Type   string          `json:"type"`
+		Config json.RawMessage `json:"config"`
+	}
+	if err := configUnmarshaller(b, &sinkData); err != nil {
+		return fmt.Errorf("parse event sink: %v", err)
+	}
+	f, ok := eventSinks[sinkData.Type]
+	if !ok {
+		return fmt.Errorf("unknown event sink type %q", sinkData.Type)
+	}
+
+	sinkConfig := f()
+	if len(sinkData.Config) != 0 {
+		data := []byte(sinkData.Config)
+		if featureflags.ExpandEnv.Enabled() {
+			expandedData, err := expandPluginConfig(sinkData.Config)
+			if err != nil {
+				return fmt.Errorf("event sink config: %v", err)
+			}
+			data = expandedData
+		}
+
+		if err := configUnmarshaller(data, sinkConfig); err != nil {
+			return fmt.Errorf("parse event sink config: %v", err)
+		}
+	}
+	*s = EventSink{
+		Type:   sinkData.Type,
+		Config: sinkConfig,
+	}
+	return nil
+}
+
 // MFAAuthenticator defines a multi-factor authentication provider.
 type MFAAuthenticator struct {
 	ID     string          `json:"id"`
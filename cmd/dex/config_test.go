@@ -3,16 +3,21 @@ package main
 import (
 	"encoding/json"
 	"log/slog"
+	"net/netip"
 	"os"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/ghodss/yaml"
 	"github.com/go-jose/go-jose/v4"
 	"github.com/kylelemons/godebug/pretty"
+	"github.com/stretchr/testify/require"
 
 	"github.com/dexidp/dex/connector/mock"
 	"github.com/dexidp/dex/connector/oidc"
+	"github.com/dexidp/dex/pkg/events"
+	"github.com/dexidp/dex/pkg/mailer"
 	"github.com/dexidp/dex/server"
 	"github.com/dexidp/dex/server/signer"
 	"github.com/dexidp/dex/storage"
@@ -675,3 +680,250 @@ enablePasswordDB: true
 		})
 	}
 }
+
+func TestPasswordResetConfigUnmarshal(t *testing.T) {
+	rawConfig := []byte(`
+issuer: http://127.0.0.1:5556/dex
+storage:
+  type: memory
+web:
+  http: 0.0.0.0:5556
+enablePasswordDB: true
+passwordReset:
+  mailer:
+    type: smtp
+    config:
+      host: smtp.example.com
+      from: "Dex <noreply@example.com>"
+  tokenValidFor: 30m
+`)
+	var c Config
+	data, err := yaml.YAMLToJSON(rawConfig)
+	if err != nil {
+		t.Fatalf("failed to convert yaml to json: %v", err)
+	}
+	if err := json.Unmarshal(data, &c); err != nil {
+		t.Fatalf("failed to decode config: %v", err)
+	}
+	if err := c.Validate(); err != nil {
+		t.Fatalf("invalid config: %v", err)
+	}
+	smtpConfig, ok := c.PasswordReset.Mailer.Config.(*mailer.SMTPConfig)
+	if !ok {
+		t.Fatalf("expected SMTPConfig, got %T", c.PasswordReset.Mailer.Config)
+	}
+	if smtpConfig.Host != "smtp.example.com" {
+		t.Errorf("expected host 'smtp.example.com', got %q", smtpConfig.Host)
+	}
+
+	rc, err := parsePasswordResetConfig(c.PasswordReset, slog.New(slog.DiscardHandler))
+	if err != nil {
+		t.Fatalf("failed to parse password reset config: %v", err)
+	}
+	if rc.TokenValidFor != 30*time.Minute {
+		t.Errorf("expected token validity 30m, got %v", rc.TokenValidFor)
+	}
+
+	c.EnablePasswordDB = false
+	if err := c.Validate(); err == nil {
+		t.Error("expected password reset without password db to be rejected")
+	}
+
+	if err := json.Unmarshal([]byte(`{"mailer": {"type": "pigeon"}}`), new(PasswordReset)); err == nil {
+		t.Error("expected unknown mailer type to be rejected")
+	}
+}
+
+func TestEmailVerificationConfigUnmarshal(t *testing.T) {
+	rawConfig := []byte(`
+issuer: http://127.0.0.1:5556/dex
+storage:
+  type: memory
+web:
+  http: 0.0.0.0:5556
+enablePasswordDB: true
+emailVerification:
+  mailer:
+    type: log
+`)
+	var c Config
+	data, err := yaml.YAMLToJSON(rawConfig)
+	if err != nil {
+		t.Fatalf("failed to convert yaml to json: %v", err)
+	}
+	if err := json.Unmarshal(data, &c); err != nil {
+		t.Fatalf("failed to decode config: %v", err)
+	}
+	if err := c.Validate(); err != nil {
+		t.Fatalf("invalid config: %v", err)
+	}
+
+	vc, err := parseEmailVerificationConfig(c.EmailVerification, slog.New(slog.DiscardHandler))
+	if err != nil {
+		t.Fatalf("failed to parse email verification config: %v", err)
+	}
+	if vc.TokenValidFor != 24*time.Hour {
+		t.Errorf("expected default token validity 24h, got %v", vc.TokenValidFor)
+	}
+}
+
+func TestRegistrationConfigValidate(t *testing.T) {
+	rawConfig := []byte(`
+issuer: http://127.0.0.1:5556/dex
+storage:
+  type: memory
+web:
+  http: 0.0.0.0:5556
+enablePasswordDB: true
+registration:
+  allowedEmailDomains:
+  - example.com
+  verifyEmail: true
+`)
+	var c Config
+	data, err := yaml.YAMLToJSON(rawConfig)
+	if err != nil {
+		t.Fatalf("failed to convert yaml to json: %v", err)
+	}
+	if err := json.Unmarshal(data, &c); err != nil {
+		t.Fatalf("failed to decode config: %v", err)
+	}
+	if c.Registration == nil || len(c.Registration.AllowedEmailDomains) != 1 || !c.Registration.VerifyEmail {
+		t.Fatalf("unexpected registration config: %+v", c.Registration)
+	}
+	if err := c.Validate(); err == nil {
+		t.Error("expected verifyEmail without emailVerification to be rejected")
+	}
+
+	c.Registration.VerifyEmail = false
+	if err := c.Validate(); err != nil {
+		t.Errorf("invalid config: %v", err)
+	}
+}
+
+func TestImpersonationConfigValidate(t *testing.T) {
+	c := Config{
+		Issuer:  "http://127.0.0.1:5556/dex",
+		Storage: Storage{Type: "sqlite3", Config: &sql.SQLite3{File: "examples/dex.db"}},
+		Web:     Web{HTTP: "127.0.0.1:5556"},
+		StaticClients: []storage.Client{{
+			ID:            "support",
+			Secret:        "support-secret",
+			Impersonation: &storage.ImpersonationPolicy{Connectors: []string{"ldap"}, ActorGroups: []string{"support"}},
+		}},
+	}
+	require.NoError(t, c.Validate())
+
+	c.StaticClients[0].Public = true
+	require.ErrorContains(t, c.Validate(), "can't impersonate users")
+}
+
+func TestEventsConfig(t *testing.T) {
+	rawConfig := []byte(`
+issuer: http://127.0.0.1:5556/dex
+storage:
+  type: memory
+web:
+  http: 0.0.0.0:5556
+events:
+  sinks:
+  - type: http
+    config:
+      url: https://events.example.com/dex
+  - type: kafka
+    config:
+      restProxyURL: http://kafka-rest:8082
+      topic: dex-events
+`)
+	var c Config
+	data, err := yaml.YAMLToJSON(rawConfig)
+	if err != nil {
+		t.Fatalf("failed to convert yaml to json: %v", err)
+	}
+	if err := json.Unmarshal(data, &c); err != nil {
+		t.Fatalf("failed to decode config: %v", err)
+	}
+	if err := c.Validate(); err != nil {
+		t.Errorf("invalid config: %v", err)
+	}
+	kafkaConfig, ok := c.Events.Sinks[1].Config.(*events.KafkaConfig)
+	if !ok {
+		t.Fatalf("expected KafkaConfig, got %T", c.Events.Sinks[1].Config)
+	}
+	if kafkaConfig.Topic != "dex-events" {
+		t.Errorf("unexpected topic %q", kafkaConfig.Topic)
+	}
+	queue, err := openEvents(c.Events, slog.New(slog.DiscardHandler))
+	if err != nil {
+		t.Fatalf("failed to open events: %v", err)
+	}
+	queue.Close()
+
+	if err := json.Unmarshal([]byte(`{"type": "carrier-pigeon"}`), new(EventSink)); err == nil {
+		t.Error("expected unknown event sink type to be rejected")
+	}
+	c.Events.Sinks = nil
+	if err := c.Validate(); err == nil {
+		t.Error("expected events without sinks to be rejected")
+	}
+}
+
+func TestAdminUIConfig(t *testing.T) {
+	rawConfig := []byte(`
+issuer: http://127.0.0.1:5556/dex
+storage:
+  type: memory
+web:
+  http: 0.0.0.0:5556
+adminUI:
+  url: http://127.0.0.1:5558/admin
+  clientSecret: admin-secret
+  adminGroups:
+  - dex-admins
+`)
+	var c Config
+	data, err := yaml.YAMLToJSON(rawConfig)
+	if err != nil {
+		t.Fatalf("failed to convert yaml to json: %v", err)
+	}
+	if err := json.Unmarshal(data, &c); err != nil {
+		t.Fatalf("failed to decode config: %v", err)
+	}
+	if err := c.Validate(); err == nil {
+		t.Error("expected admin UI without a listener to be rejected")
+	}
+
+	c.Telemetry.HTTP = "127.0.0.1:5558"
+	if err := c.Validate(); err != nil {
+		t.Fatalf("invalid config: %v", err)
+	}
+
+	clients, err := buildStaticClients(c, slog.New(slog.DiscardHandler))
+	if err != nil {
+		t.Fatalf("failed to build static clients: %v", err)
+	}
+	want := storage.Client{
+		ID:           "dex-admin",
+		Secret:       "admin-secret",
+		Name:         "Dex Admin",
+		RedirectURIs: []string{"http://127.0.0.1:5558/admin/callback"},
+	}
+	if diff := pretty.Compare(clients, []storage.Client{want}); diff != "" {
+		t.Errorf("unexpected static clients (-got +want):\n%s", diff)
+	}
+}
+
+func TestParseTrustedProxies(t *testing.T) {
+	cr := &ClientRemoteIP{TrustedProxies: []string{"10.0.0.0/8", "192.168.1.10", "2001:db8::1"}}
+	trusted, err := cr.ParseTrustedProxies()
+	require.NoError(t, err)
+	require.Equal(t, []netip.Prefix{
+		netip.MustParsePrefix("10.0.0.0/8"),
+		netip.MustParsePrefix("192.168.1.10/32"),
+		netip.MustParsePrefix("2001:db8::1/128"),
+	}, trusted)
+
+	cr = &ClientRemoteIP{TrustedProxies: []string{"proxy.local"}}
+	_, err = cr.ParseTrustedProxies()
+	require.Error(t, err)
+}
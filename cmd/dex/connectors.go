@@ -0,0 +1,342 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html/template"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ghodss/yaml"
+	"github.com/spf13/cobra"
+
+	"github.com/dexidp/dex/connector"
+	"github.com/dexidp/dex/pkg/featureflags"
+	"github.com/dexidp/dex/storage"
+)
+
+type connectorsTestOptions struct {
+	// Connector or config file path
+	file string
+
+	// Flags
+	id            string
+	login         bool
+	callbackURL   string
+	username      string
+	groups        bool
+	offlineAccess bool
+	timeout       time.Duration
+}
+
+func commandConnectors() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "connectors",
+		Short: "Work with connector configs",
+	}
+	cmd.AddCommand(commandConnectorsTest())
+	return cmd
+}
+
+func commandConnectorsTest() *cobra.Command {
+	options := connectorsTestOptions{}
+
+	cmd := &cobra.Command{
+		Use:   "test [flags] [connector file, or config file with --id]",
+		Short: "Open a connector, print what it resolved and optionally log in through it",
+		Long: `Open a connector, print what it resolved and optionally log in through it.
+
+The file holds a single connector as written in the connectors list of the
+config, or a whole config if --id selects one of its connectors.
+
+With --login, callback connectors are logged in through a local browser: the
+redirect URI of the connector must be the --callback-url, which is served by
+this command. Password connectors read the password from the standard input.`,
+		Example: "dex connectors test --login --callback-url http://127.0.0.1:5556/dex/callback hsdp.yaml",
+		Args:    cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cmd.SilenceUsage = true
+			cmd.SilenceErrors = true
+
+			options.file = args[0]
+
+			return runConnectorsTest(context.Background(), cmd.OutOrStdout(), cmd.InOrStdin(), options)
+		},
+	}
+
+	flags := cmd.Flags()
+
+	flags.StringVar(&options.id, "id", "", "ID of the connector to test in a config file")
+	flags.BoolVar(&options.login, "login", false, "Log in through the connector and print the resulting identity")
+	flags.StringVar(&options.callbackURL, "callback-url", "http://127.0.0.1:5556/dex/callback", "Callback URL served during a login of a callback connector")
+	flags.StringVar(&options.username, "username", "", "Username of a login through a password connector")
+	flags.BoolVar(&options.groups, "groups", true, "Request the groups of the user")
+	flags.BoolVar(&options.offlineAccess, "offline-access", false, "Request offline access from the upstream")
+	flags.DurationVar(&options.timeout, "timeout", 5*time.Minute, "How long to wait for a login to complete")
+
+	return cmd
+}
+
+// loadTestConnector reads the connector to test from the file.
+func loadTestConnector(options connectorsTestOptions) (Connector, error) {
+	if options.id != "" {
+		c, err := loadConfig(options.file)
+		if err != nil {
+			return Connector{}, err
+		}
+		for _, conn := range c.StaticConnectors {
+			if conn.ID == options.id {
+				return conn, nil
+			}
+		}
+		return Connector{}, fmt.Errorf("no connector %q in %s", options.id, options.file)
+	}
+
+	data, err := os.ReadFile(options.file)
+	if err != nil {
+		return Connector{}, fmt.Errorf("failed to read connector file %s: %v", options.file, err)
+	}
+	data, err = yaml.YAMLToJSON(data)
+	if err != nil {
+		return Connector{}, fmt.Errorf("error parsing connector file %s: %v", options.file, err)
+	}
+	if featureflags.ExpandEnv.Enabled() {
+		if data, err = expandConfig(data); err != nil {
+			return Connector{}, fmt.Errorf("error expanding connector file %s: %v", options.file, err)
+		}
+	}
+	var conn Connector
+	if err := configUnmarshaller(data, &conn); err != nil {
+		return Connector{}, fmt.Errorf("error unmarshalling connector file %s: %v", options.file, err)
+	}
+	return conn, nil
+}
+
+func runConnectorsTest(ctx context.Context, out io.Writer, in io.Reader, options connectorsTestOptions) error {
+	conf, err := loadTestConnector(options)
+	if err != nil {
+		return err
+	}
+	if err := validateStaticConnector(conf); err != nil {
+		return err
+	}
+
+	logger := slog.New(slog.NewTextHandler(os.Stderr, nil))
+	warnUnsetEnvVars(logger)
+	conn, err := conf.Config.Open(conf.ID, logger.With("connector_id", conf.ID))
+	if err != nil {
+		return fmt.Errorf("failed to open connector: %v", err)
+	}
+	if closer, ok := conn.(io.Closer); ok {
+		defer closer.Close()
+	}
+	fmt.Fprintf(out, "connector %q of type %q opened\n", conf.ID, conf.Type)
+
+	if d, ok := conn.(connector.Describer); ok {
+		printDescription(out, d.Describe())
+	}
+
+	if !options.login {
+		return nil
+	}
+
+	scopes := connector.Scopes{Groups: options.groups, OfflineAccess: options.offlineAccess}
+	ctx, cancel := context.WithTimeout(ctx, options.timeout)
+	defer cancel()
+
+	var identity connector.Identity
+	switch conn := conn.(type) {
+	case connector.PasswordConnector:
+		identity, err = passwordLogin(ctx, out, in, conn, scopes, options.username)
+	case connector.CallbackConnector, connector.SAMLConnector:
+		identity, err = browserLogin(ctx, out, conn, scopes, options.callbackURL)
+	default:
+		return fmt.Errorf("connector type %q doesn't support logins", conf.Type)
+	}
+	if err != nil {
+		return fmt.Errorf("login failed: %v", err)
+	}
+	return printIdentity(out, identity)
+}
+
+func printDescription(out io.Writer, d connector.Description) {
+	names := make([]string, 0, len(d.Endpoints))
+	for name := range d.Endpoints {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	fmt.Fprintln(out, "endpoints:")
+	for _, name := range names {
+		fmt.Fprintf(out, "  %s: %s\n", name, d.Endpoints[name])
+	}
+	fmt.Fprintf(out, "scopes: %s\n", strings.Join(d.Scopes, " "))
+}
+
+// printIdentity prints the identity with the names of the claims dex issues
+// for it.
+func printIdentity(out io.Writer, identity connector.Identity) error {
+	claims := struct {
+		Subject           string   `json:"sub"`
+		Name              string   `json:"name,omitempty"`
+		PreferredUsername string   `json:"preferred_username,omitempty"`
+		Email             string   `json:"email,omitempty"`
+		EmailVerified     bool     `json:"email_verified"`
+		Groups            []string `json:"groups,omitempty"`
+	}{
+		Subject:           identity.UserID,
+		Name:              identity.Username,
+		PreferredUsername: identity.PreferredUsername,
+		Email:             identity.Email,
+		EmailVerified:     identity.EmailVerified,
+		Groups:            identity.Groups,
+	}
+	data, err := json.MarshalIndent(claims, "", "  ")
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "login succeeded, identity:\n%s\n", data)
+	return nil
+}
+
+func passwordLogin(ctx context.Context, out io.Writer, in io.Reader, conn connector.PasswordConnector, scopes connector.Scopes, username string) (connector.Identity, error) {
+	if username == "" {
+		return connector.Identity{}, errors.New("--username is required by password connectors")
+	}
+	fmt.Fprint(out, "Password: ")
+	password, err := bufio.NewReader(in).ReadString('\n')
+	if err != nil && !errors.Is(err, io.EOF) {
+		return connector.Identity{}, fmt.Errorf("failed to read password: %v", err)
+	}
+	fmt.Fprintln(out)
+
+	identity, valid, err := conn.Login(ctx, scopes, username, strings.TrimRight(password, "\r\n"))
+	if err != nil {
+		return identity, err
+	}
+	if !valid {
+		return identity, errors.New("invalid username or password")
+	}
+	return identity, nil
+}
+
+var samlPostTmpl = template.Must(template.New("post").Parse(`<!DOCTYPE html>
+<html><body onload="document.forms[0].submit()">
+<form method="post" action="{{.URL}}">
+<input type="hidden" name="SAMLRequest" value="{{.Request}}">
+<input type="hidden" name="RelayState" value="{{.State}}">
+<noscript><input type="submit" value="Continue"></noscript>
+</form>
+</body></html>
+`))
+
+// browserLogin serves /login, which sends the browser to the upstream, and
+// the callback URL, which completes the login.
+func browserLogin(ctx context.Context, out io.Writer, conn connector.Connector, scopes connector.Scopes, callbackURL string) (connector.Identity, error) {
+	u, err := url.Parse(callbackURL)
+	if err != nil || u.Host == "" {
+		return connector.Identity{}, fmt.Errorf("invalid callback URL %q", callbackURL)
+	}
+	if u.Path == "" {
+		u.Path = "/"
+	}
+	l, err := net.Listen("tcp", u.Host)
+	if err != nil {
+		return connector.Identity{}, fmt.Errorf("failed to listen on %s: %v", u.Host, err)
+	}
+
+	state := storage.NewID()
+	var (
+		mu       sync.Mutex
+		connData []byte
+	)
+
+	type result struct {
+		identity connector.Identity
+		err      error
+	}
+	results := make(chan result, 1)
+	finish := func(w http.ResponseWriter, identity connector.Identity, err error) {
+		if err != nil {
+			http.Error(w, "Login failed: "+err.Error(), http.StatusBadRequest)
+		} else {
+			fmt.Fprintln(w, "Login succeeded, you can close this window.")
+		}
+		select {
+		case results <- result{identity, err}:
+		default:
+		}
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/login", func(w http.ResponseWriter, r *http.Request) {
+		switch conn := conn.(type) {
+		case connector.CallbackConnector:
+			loginURL, data, err := conn.LoginURL(scopes, callbackURL, state)
+			if err != nil {
+				finish(w, connector.Identity{}, err)
+				return
+			}
+			mu.Lock()
+			connData = data
+			mu.Unlock()
+			http.Redirect(w, r, loginURL, http.StatusFound)
+		case connector.SAMLConnector:
+			ssoURL, samlRequest, err := conn.POSTData(scopes, state)
+			if err != nil {
+				finish(w, connector.Identity{}, err)
+				return
+			}
+			samlPostTmpl.Execute(w, struct{ URL, Request, State string }{ssoURL, samlRequest, state})
+		}
+	})
+	mux.HandleFunc(u.Path, func(w http.ResponseWriter, r *http.Request) {
+		switch conn := conn.(type) {
+		case connector.CallbackConnector:
+			if r.URL.Query().Get("state") != state {
+				http.Error(w, "unexpected state", http.StatusBadRequest)
+				return
+			}
+			mu.Lock()
+			data := connData
+			mu.Unlock()
+			identity, err := conn.HandleCallback(scopes, data, r)
+			finish(w, identity, err)
+		case connector.SAMLConnector:
+			if err := r.ParseForm(); err != nil {
+				http.Error(w, "invalid form", http.StatusBadRequest)
+				return
+			}
+			if r.PostFormValue("RelayState") != state {
+				http.Error(w, "unexpected relay state", http.StatusBadRequest)
+				return
+			}
+			identity, err := conn.HandlePOST(scopes, r.PostFormValue("SAMLResponse"), state)
+			finish(w, identity, err)
+		}
+	})
+
+	srv := &http.Server{Handler: mux}
+	go srv.Serve(l)
+	defer srv.Close()
+
+	fmt.Fprintf(out, "Open this URL in a browser to log in:\n  http://%s/login\n", l.Addr())
+
+	select {
+	case res := <-results:
+		return res.identity, res.err
+	case <-ctx.Done():
+		return connector.Identity{}, fmt.Errorf("no login completed: %v", ctx.Err())
+	}
+}
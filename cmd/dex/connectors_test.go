@@ -0,0 +1,121 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunConnectorsTest(t *testing.T) {
+	writeFile := func(t *testing.T, content string) string {
+		path := filepath.Join(t.TempDir(), "connector.yaml")
+		require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+		return path
+	}
+
+	t.Run("PasswordLogin", func(t *testing.T) {
+		file := writeFile(t, `
+type: mockPassword
+id: mock
+name: Mock
+config:
+  username: jane
+  password: secret
+`)
+		var out bytes.Buffer
+		options := connectorsTestOptions{file: file, login: true, username: "jane", timeout: time.Minute}
+		err := runConnectorsTest(context.Background(), &out, strings.NewReader("secret\n"), options)
+		require.NoError(t, err)
+		require.Contains(t, out.String(), `connector "mock" of type "mockPassword" opened`)
+		require.Contains(t, out.String(), "login succeeded")
+
+		out.Reset()
+		err = runConnectorsTest(context.Background(), &out, strings.NewReader("wrong\n"), options)
+		require.EqualError(t, err, "login failed: invalid username or password")
+	})
+
+	t.Run("ConnectorOfConfig", func(t *testing.T) {
+		file := writeFile(t, `
+issuer: http://127.0.0.1:5556/dex
+storage:
+  type: memory
+connectors:
+- type: mockCallback
+  id: mock
+  name: Mock
+`)
+		var out bytes.Buffer
+		err := runConnectorsTest(context.Background(), &out, nil, connectorsTestOptions{file: file, id: "mock"})
+		require.NoError(t, err)
+		require.Contains(t, out.String(), `connector "mock" of type "mockCallback" opened`)
+
+		err = runConnectorsTest(context.Background(), &out, nil, connectorsTestOptions{file: file, id: "other"})
+		require.ErrorContains(t, err, `no connector "other"`)
+	})
+
+	t.Run("BrowserLogin", func(t *testing.T) {
+		l, err := net.Listen("tcp", "127.0.0.1:0")
+		require.NoError(t, err)
+		addr := l.Addr().String()
+		l.Close()
+
+		file := writeFile(t, `
+type: mockCallback
+id: mock
+name: Mock
+`)
+		out := &syncBuffer{}
+		done := make(chan error, 1)
+		go func() {
+			done <- runConnectorsTest(context.Background(), out, nil, connectorsTestOptions{
+				file:        file,
+				login:       true,
+				callbackURL: "http://" + addr + "/callback",
+				timeout:     time.Minute,
+			})
+		}()
+
+		// Play the browser: /login redirects to the upstream, which for the
+		// mock connector is the callback.
+		require.Eventually(t, func() bool {
+			resp, err := http.Get("http://" + addr + "/login")
+			if err != nil {
+				return false
+			}
+			defer resp.Body.Close()
+			body, _ := io.ReadAll(resp.Body)
+			return strings.Contains(string(body), "Login succeeded")
+		}, 10*time.Second, 10*time.Millisecond)
+
+		require.NoError(t, <-done)
+		require.Contains(t, out.String(), `"sub": "0-385-28089-0"`)
+	})
+}
+
+// syncBuffer is a bytes.Buffer safe for concurrent use.
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
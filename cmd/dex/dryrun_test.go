@@ -0,0 +1,94 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestServeDryRun(t *testing.T) {
+	dir := t.TempDir()
+	dbFile := filepath.Join(dir, "dex.db")
+	config := filepath.Join(dir, "config.yaml")
+	require.NoError(t, os.WriteFile(config, []byte(`
+issuer: http://127.0.0.1:5556/dex
+storage:
+  type: sqlite3
+  config:
+    file: `+dbFile+`
+web:
+  http: 127.0.0.1:5556
+logger:
+  level: error
+staticClients:
+- id: example-app
+  name: Example App
+  secret: ZXhhbXBsZS1hcHAtc2VjcmV0
+connectors:
+- type: mockCallback
+  id: mock
+  name: Example
+- type: oidc
+  id: upstream
+  name: Upstream
+  config:
+    issuer: http://127.0.0.1:1/unreachable
+    clientID: dex
+    clientSecret: secret
+    redirectURI: http://127.0.0.1:5556/dex/callback
+`), 0o600))
+
+	serve := func(args ...string) (string, error) {
+		cmd := commandServe()
+		var out bytes.Buffer
+		cmd.SetOut(&out)
+		cmd.SetArgs(append(args, config))
+		err := cmd.Execute()
+		return out.String(), err
+	}
+
+	t.Run("SkipNetwork", func(t *testing.T) {
+		out, err := serve("--dry-run", "--skip-network")
+		require.NoError(t, err)
+		require.Contains(t, out, "ok    config\n")
+		require.Contains(t, out, `ok    storage "sqlite3" (`)
+		require.Contains(t, out, `ok    connector "upstream" (network calls skipped)`)
+		require.Contains(t, out, "ok    server\n")
+		require.Contains(t, out, "dry run complete")
+
+		_, err = os.Stat(dbFile)
+		require.True(t, os.IsNotExist(err), "dry run created the database")
+	})
+
+	t.Run("ConnectorFailure", func(t *testing.T) {
+		out, err := serve("--dry-run")
+		require.EqualError(t, err, "dry run failed")
+		require.Contains(t, out, "FAIL  failed to initialize server:")
+		require.NotContains(t, out, "dry run complete")
+	})
+
+	t.Run("SkipNetworkInvalidConnector", func(t *testing.T) {
+		require.NoError(t, os.WriteFile(config, []byte(`
+issuer: http://127.0.0.1:5556/dex
+storage:
+  type: memory
+web:
+  http: 127.0.0.1:5556
+logger:
+  level: error
+connectors:
+- type: oidc
+  id: upstream
+  name: Upstream
+  config:
+    issuer: http://127.0.0.1:1/unreachable
+    rootCAs: [not-a-certificate]
+`), 0o600))
+		out, err := serve("--dry-run", "--skip-network")
+		require.EqualError(t, err, "dry run failed")
+		require.Contains(t, out, `FAIL  connector "upstream": failed to open connector:`)
+	})
+}
@@ -0,0 +1,274 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dexidp/dex/pkg/featureflags"
+	"github.com/dexidp/dex/pkg/secrets"
+)
+
+// secretResolveTimeout bounds a single lookup in an external secret store.
+const secretResolveTimeout = 10 * time.Second
+
+// fileRefPrefix marks a config value which is read from a file, e.g.
+// "file:///var/run/secrets/dex/client-secret".
+const fileRefPrefix = "file://"
+
+// unsetEnvVars collects the unset environment variables the config refers
+// to, which are expanded to an empty string unless DEX_EXPAND_ENV_STRICT is
+// set. The config is expanded before the logger exists, so the warnings are
+// logged later by warnUnsetEnvVars.
+var unsetEnvVars struct {
+	sync.Mutex
+	names []string
+}
+
+// warnUnsetEnvVars logs the unset environment variables the config referred
+// to since the last call.
+func warnUnsetEnvVars(logger *slog.Logger) {
+	unsetEnvVars.Lock()
+	names := unsetEnvVars.names
+	unsetEnvVars.names = nil
+	unsetEnvVars.Unlock()
+
+	for _, name := range names {
+		logger.Warn("config refers to an unset environment variable, which expands to an empty string; set DEX_EXPAND_ENV_STRICT=true to fail instead, which will become the default",
+			"name", name, "deprecated", true)
+	}
+}
+
+// expandValue resolves references in a single config value.
+//
+// ${NAME} is replaced with the value of the environment variable NAME. Unset
+// variables are an error with DEX_EXPAND_ENV_STRICT, and expand to an empty
+// string otherwise. If allowBare is true, $NAME is expanded too, following the
+// os.ExpandEnv rules that plugin configs have always used. If secret is true,
+// a value which then starts with file:// is replaced with the contents of that
+// file, without the trailing newline, and vault:// or awssm:// references are
+// looked up in the respective secret store.
+func expandValue(s string, allowBare, secret bool) (string, error) {
+	var buf strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '$' || i+1 == len(s) {
+			buf.WriteByte(s[i])
+			continue
+		}
+
+		if s[i+1] == '{' {
+			end := strings.IndexByte(s[i+2:], '}')
+			if end < 1 {
+				return "", fmt.Errorf("invalid environment variable reference at offset %d", i)
+			}
+			name := s[i+2 : i+2+end]
+			value, ok := os.LookupEnv(name)
+			if !ok {
+				if featureflags.ExpandEnvStrict.Enabled() {
+					return "", fmt.Errorf("environment variable %q is not set", name)
+				}
+				unsetEnvVars.Lock()
+				unsetEnvVars.names = append(unsetEnvVars.names, name)
+				unsetEnvVars.Unlock()
+			}
+			buf.WriteString(value)
+			i += end + 2
+			continue
+		}
+
+		if !allowBare {
+			buf.WriteByte(s[i])
+			continue
+		}
+
+		name, w := bareEnvName(s[i+1:])
+		if w == 0 {
+			buf.WriteByte(s[i])
+			continue
+		}
+		buf.WriteString(os.Getenv(name))
+		i += w
+	}
+
+	expanded := buf.String()
+	if !secret {
+		return expanded, nil
+	}
+	if path, ok := strings.CutPrefix(expanded, fileRefPrefix); ok {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return "", fmt.Errorf("failed to read referenced file: %v", err)
+		}
+		return strings.TrimRight(string(data), "\r\n"), nil
+	}
+	if secrets.Default.IsReference(expanded) {
+		ctx, cancel := context.WithTimeout(context.Background(), secretResolveTimeout)
+		defer cancel()
+		return secrets.Default.Resolve(ctx, expanded)
+	}
+	return expanded, nil
+}
+
+// bareEnvName returns the variable name at the start of s and its length,
+// using the same rules as os.Expand for names without braces.
+func bareEnvName(s string) (string, int) {
+	switch s[0] {
+	case '*', '#', '$', '@', '!', '?', '-', '0', '1', '2', '3', '4', '5', '6', '7', '8', '9':
+		return s[:1], 1
+	}
+	i := 0
+	for i < len(s) && (s[i] == '_' || '0' <= s[i] && s[i] <= '9' || 'a' <= s[i] && s[i] <= 'z' || 'A' <= s[i] && s[i] <= 'Z') {
+		i++
+	}
+	return s[:i], i
+}
+
+// secretFields are the config fields holding credentials, in the top level
+// config and in the plugin configs, by name or by their last two path
+// elements for names that don't always hold credentials. Only these fields
+// are read from files or secret stores.
+var secretFields = map[string]bool{
+	"secret":                true,
+	"clientSecret":          true,
+	"secondaryClientSecret": true,
+	"password":              true,
+	"keystonePassword":      true,
+	"bindPW":                true,
+	"hash":                  true,
+	"sharedSecret":          true,
+	"privateKey":            true,
+	"token":                 true,
+	"cookieEncryptionKey":   true,
+	"sessionKey":            true,
+	"relayStateKey":         true,
+	"passwordReset.key":     true,
+	"emailVerification.key": true,
+}
+
+// isSecretField reports whether the config field at path holds a credential.
+func isSecretField(path string) bool {
+	var elems []string
+	for _, elem := range strings.Split(path, ".") {
+		if i := strings.IndexByte(elem, '['); i >= 0 {
+			elem = elem[:i]
+		}
+		if elem != "" {
+			elems = append(elems, elem)
+		}
+	}
+	n := len(elems)
+	if n == 0 {
+		return false
+	}
+	if n >= 2 && secretFields[elems[n-2]+"."+elems[n-1]] {
+		return true
+	}
+	return secretFields[elems[n-1]]
+}
+
+// expandMap recursively resolves references in every string of a decoded JSON
+// object. Keys in skip, given as dotted paths, are left for the plugin config
+// unmarshalers which expand them on their own.
+func expandMap(m map[string]interface{}, path string, allowBare bool, skip map[string]bool) error {
+	for k, v := range m {
+		p := joinConfigPath(path, k)
+		if skip[p] {
+			continue
+		}
+		expanded, err := expandAny(v, p, allowBare, skip)
+		if err != nil {
+			return err
+		}
+		m[k] = expanded
+	}
+	return nil
+}
+
+func expandAny(v interface{}, path string, allowBare bool, skip map[string]bool) (interface{}, error) {
+	switch vt := v.(type) {
+	case string:
+		expanded, err := expandValue(vt, allowBare, isSecretField(path))
+		if err != nil {
+			return nil, fmt.Errorf("config field %s: %v", path, err)
+		}
+		return expanded, nil
+	case map[string]interface{}:
+		return vt, expandMap(vt, path, allowBare, skip)
+	case []interface{}:
+		for i, item := range vt {
+			expanded, err := expandAny(item, path+"["+strconv.Itoa(i)+"]", allowBare, skip)
+			if err != nil {
+				return nil, err
+			}
+			vt[i] = expanded
+		}
+	}
+	return v, nil
+}
+
+func joinConfigPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+// expandPluginConfig resolves references in the raw config of a storage,
+// signer or connector, returning the rewritten JSON.
+func expandPluginConfig(raw []byte) ([]byte, error) {
+	var rawMap map[string]interface{}
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.UseNumber()
+	if err := dec.Decode(&rawMap); err != nil {
+		return nil, fmt.Errorf("unmarshal config for env expansion: %v", err)
+	}
+
+	// Recursively expand the values in the map to avoid issues with JSON
+	// special characters and escapes.
+	if err := expandMap(rawMap, "", true, nil); err != nil {
+		return nil, err
+	}
+
+	expandedData, err := json.Marshal(rawMap)
+	if err != nil {
+		return nil, fmt.Errorf("marshal expanded config: %v", err)
+	}
+	return expandedData, nil
+}
+
+// expandConfig resolves ${ENV} references in all fields of the top level
+// config, and file:// and secret store references in fields holding
+// credentials. Only the braced form of environment variables is expanded
+// here, since values like bcrypt password hashes contain bare dollar signs.
+func expandConfig(raw []byte) ([]byte, error) {
+	var rawMap map[string]interface{}
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.UseNumber()
+	if err := dec.Decode(&rawMap); err != nil {
+		return nil, err
+	}
+
+	// Plugin configs are expanded by their unmarshalers, which also accept
+	// bare $NAME references.
+	skip := map[string]bool{
+		"storage.config": true,
+		"signer.config":  true,
+	}
+	if connectors, ok := rawMap["connectors"].([]interface{}); ok {
+		for i := range connectors {
+			skip["connectors["+strconv.Itoa(i)+"].config"] = true
+		}
+	}
+
+	if err := expandMap(rawMap, "", false, skip); err != nil {
+		return nil, err
+	}
+	return json.Marshal(rawMap)
+}
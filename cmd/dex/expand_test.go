@@ -0,0 +1,124 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ghodss/yaml"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExpandValue(t *testing.T) {
+	t.Setenv("DEX_TEST_SECRET", `se$cret"`)
+
+	secretFile := filepath.Join(t.TempDir(), "secret")
+	require.NoError(t, os.WriteFile(secretFile, []byte("from-file\n"), 0o600))
+	t.Setenv("DEX_TEST_SECRET_DIR", filepath.Dir(secretFile))
+
+	tests := []struct {
+		name      string
+		value     string
+		allowBare bool
+		plain     bool
+		want      string
+		wantErr   string
+	}{
+		{name: "plain", value: "foo", want: "foo"},
+		{name: "braced", value: "a-${DEX_TEST_SECRET}-b", want: `a-se$cret"-b`},
+		{name: "bare ignored", value: "$DEX_TEST_SECRET", want: "$DEX_TEST_SECRET"},
+		{name: "bare expanded", value: "$DEX_TEST_SECRET", allowBare: true, want: `se$cret"`},
+		{name: "bcrypt hash", value: "$2a$10$33EMT0cVYVlPy6WAMCLsce", want: "$2a$10$33EMT0cVYVlPy6WAMCLsce"},
+		{name: "trailing dollar", value: "foo$", allowBare: true, want: "foo$"},
+		{name: "file", value: "file://" + secretFile, want: "from-file"},
+		{name: "file with env", value: "file://${DEX_TEST_SECRET_DIR}/secret", want: "from-file"},
+		{name: "unset", value: "a-${DEX_TEST_UNSET}-b", want: "a--b"},
+		{name: "unterminated", value: "${DEX_TEST_SECRET", wantErr: "invalid environment variable reference at offset 0"},
+		{name: "missing file", value: "file:///nonexistent/dex-secret", wantErr: "failed to read referenced file"},
+		{name: "file in plain field", value: "file://" + secretFile, plain: true, want: "file://" + secretFile},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := expandValue(tc.value, tc.allowBare, !tc.plain)
+			if tc.wantErr != "" {
+				require.ErrorContains(t, err, tc.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tc.want, got)
+		})
+	}
+}
+
+func TestExpandConfig(t *testing.T) {
+	t.Setenv("DEX_TEST_CLIENT_SECRET", "client-secret")
+	t.Setenv("DEX_TEST_OIDC_SECRET", "oidc-secret")
+
+	raw, err := yaml.YAMLToJSON([]byte(`
+issuer: http://127.0.0.1:5556/dex
+storage:
+  type: memory
+web:
+  http: 127.0.0.1:5556
+staticClients:
+- id: example-app
+  name: Example App
+  secret: ${DEX_TEST_CLIENT_SECRET}
+  logoURL: file:///nonexistent/logo.png
+connectors:
+- type: oidc
+  id: oidc
+  name: OIDC
+  config:
+    issuer: https://accounts.example.com
+    clientID: foo
+    clientSecret: $DEX_TEST_OIDC_SECRET
+    redirectURI: http://127.0.0.1:5556/dex/callback
+staticPasswords:
+- email: admin@example.com
+  hash: "$2a$10$33EMT0cVYVlPy6WAMCLsceLYjWhuHpbz5yuZxu/GAFj03J9Lytjuy"
+`))
+	require.NoError(t, err)
+
+	expanded, err := expandConfig(raw)
+	require.NoError(t, err)
+
+	var c Config
+	require.NoError(t, configUnmarshaller(expanded, &c))
+	require.Equal(t, "client-secret", c.StaticClients[0].Secret)
+	require.Equal(t, "file:///nonexistent/logo.png", c.StaticClients[0].LogoURL)
+	require.Equal(t, []byte("$2a$10$33EMT0cVYVlPy6WAMCLsceLYjWhuHpbz5yuZxu/GAFj03J9Lytjuy"), c.StaticPasswords[0].Hash)
+
+	raw, err = yaml.YAMLToJSON([]byte(`
+staticClients:
+- id: example-app
+  secret: ${DEX_TEST_UNSET}
+`))
+	require.NoError(t, err)
+	expanded, err = expandConfig(raw)
+	require.NoError(t, err)
+	require.JSONEq(t, `{"staticClients":[{"id":"example-app","secret":""}]}`, string(expanded))
+
+	t.Setenv("DEX_EXPAND_ENV_STRICT", "true")
+	_, err = expandConfig(raw)
+	require.EqualError(t, err, `config field staticClients[0].secret: environment variable "DEX_TEST_UNSET" is not set`)
+}
+
+func TestIsSecretField(t *testing.T) {
+	tests := map[string]bool{
+		"staticClients[0].secret":                true,
+		"connectors[1].config.clientSecret":      true,
+		"connectors[1].config.bindPW":            true,
+		"staticPasswords[0].hash":                true,
+		"passwordReset.key":                      true,
+		"passwordReset.mailer.config.password":   true,
+		"connectors[2].config.key":               false,
+		"web.tlsKey":                             false,
+		"staticClients[0].logoURL":               false,
+		"connectors[1].config.tokenURL":          false,
+		"expiry.refreshTokens.validIfNotUsedFor": false,
+	}
+	for path, want := range tests {
+		require.Equal(t, want, isSecretField(path), path)
+	}
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/dexidp/dex/connector/oidc"
+	"github.com/dexidp/dex/storage"
+)
+
+// Federation makes this dex instance a front for other dex instances, for
+// example a global login front door for per-region deployments. Each upstream
+// is added as a connector, so users pick their region on the login page, and
+// ID tokens issued by an upstream can be exchanged for tokens of this
+// instance with the token exchange grant and the upstream's connector ID.
+//
+// Each upstream must have a client for this instance which redirects to
+// <issuer>/callback.
+type Federation struct {
+	Upstreams []FederationUpstream `json:"upstreams"`
+}
+
+// FederationUpstream is a dex instance this instance federates to.
+type FederationUpstream struct {
+	// ID of the connector for the upstream.
+	ID string `json:"id"`
+	// Name shown on the login page.
+	Name string `json:"name"`
+	// Issuer URL of the upstream. Its endpoints are discovered from it.
+	Issuer string `json:"issuer"`
+	// ClientID and ClientSecret of this instance at the upstream.
+	ClientID     string `json:"clientID"`
+	ClientSecret string `json:"clientSecret"`
+}
+
+func (c Config) validateFederation() error {
+	if c.Federation == nil {
+		return nil
+	}
+	if len(c.Federation.Upstreams) == 0 {
+		return fmt.Errorf("federation: no upstreams supplied")
+	}
+	ids := make(map[string]bool)
+	for _, conn := range c.StaticConnectors {
+		ids[conn.ID] = true
+	}
+	for _, u := range c.Federation.Upstreams {
+		switch {
+		case u.ID == "":
+			return fmt.Errorf("federation: upstream %q has no id", u.Issuer)
+		case ids[u.ID]:
+			return fmt.Errorf("federation: upstream id %q is already used by another connector", u.ID)
+		case u.Issuer == "":
+			return fmt.Errorf("federation: upstream %q has no issuer", u.ID)
+		case strings.TrimSuffix(u.Issuer, "/") == strings.TrimSuffix(c.Issuer, "/"):
+			return fmt.Errorf("federation: upstream %q cannot be this instance", u.ID)
+		case u.ClientID == "":
+			return fmt.Errorf("federation: upstream %q has no clientID", u.ID)
+		}
+		ids[u.ID] = true
+	}
+	return nil
+}
+
+// buildFederationConnectors returns the connectors of the federation
+// upstreams. Upstreams are asked for groups and refresh tokens, so refreshing
+// a token of this instance refreshes the upstream session too.
+func buildFederationConnectors(c Config, logger *slog.Logger) ([]storage.Connector, error) {
+	if c.Federation == nil {
+		return nil, nil
+	}
+	connectors := make([]storage.Connector, 0, len(c.Federation.Upstreams))
+	for _, u := range c.Federation.Upstreams {
+		name := u.Name
+		if name == "" {
+			name = u.ID
+		}
+		conn, err := ToStorageConnector(Connector{
+			Type: "oidc",
+			ID:   u.ID,
+			Name: name,
+			Config: &oidc.Config{
+				Issuer:               u.Issuer,
+				ClientID:             u.ClientID,
+				ClientSecret:         u.ClientSecret,
+				RedirectURI:          strings.TrimSuffix(c.Issuer, "/") + "/callback",
+				Scopes:               []string{"profile", "email", "groups", "offline_access"},
+				InsecureEnableGroups: true,
+				PKCEChallenge:        "S256",
+			},
+		})
+		if err != nil {
+			return nil, fmt.Errorf("federation: upstream %q: %v", u.ID, err)
+		}
+		logger.Info("config federation upstream", "connector_id", u.ID, "issuer", u.Issuer)
+		connectors = append(connectors, conn)
+	}
+	return connectors, nil
+}
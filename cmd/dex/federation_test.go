@@ -0,0 +1,68 @@
+package main
+
+import (
+	"encoding/json"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/dexidp/dex/connector/oidc"
+)
+
+func TestValidateFederation(t *testing.T) {
+	upstream := FederationUpstream{ID: "eu", Issuer: "https://eu.dex.example.com", ClientID: "front"}
+	tests := []struct {
+		name      string
+		upstreams []FederationUpstream
+		wantErr   bool
+	}{
+		{"valid", []FederationUpstream{upstream}, false},
+		{"no upstreams", nil, true},
+		{"no id", []FederationUpstream{{Issuer: upstream.Issuer, ClientID: "front"}}, true},
+		{"duplicate id", []FederationUpstream{upstream, upstream}, true},
+		{"connector id taken", []FederationUpstream{{ID: "github", Issuer: upstream.Issuer, ClientID: "front"}}, true},
+		{"no issuer", []FederationUpstream{{ID: "eu", ClientID: "front"}}, true},
+		{"self", []FederationUpstream{{ID: "eu", Issuer: "https://dex.example.com/", ClientID: "front"}}, true},
+		{"no client id", []FederationUpstream{{ID: "eu", Issuer: upstream.Issuer}}, true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			c := Config{
+				Issuer:           "https://dex.example.com",
+				StaticConnectors: []Connector{{ID: "github"}},
+				Federation:       &Federation{Upstreams: tc.upstreams},
+			}
+			err := c.validateFederation()
+			require.Equal(t, tc.wantErr, err != nil, "%v", err)
+		})
+	}
+}
+
+func TestBuildFederationConnectors(t *testing.T) {
+	c := Config{
+		Issuer: "https://dex.example.com/",
+		Federation: &Federation{Upstreams: []FederationUpstream{
+			{ID: "eu", Name: "Europe", Issuer: "https://eu.dex.example.com", ClientID: "front", ClientSecret: "secret"},
+			{ID: "us", Issuer: "https://us.dex.example.com", ClientID: "front", ClientSecret: "secret"},
+		}},
+		EnablePasswordDB: true,
+	}
+	connectors, err := buildStaticConnectors(c, slog.New(slog.DiscardHandler))
+	require.NoError(t, err)
+	require.Len(t, connectors, 3)
+
+	eu := connectors[0]
+	require.Equal(t, "eu", eu.ID)
+	require.Equal(t, "Europe", eu.Name)
+	require.Equal(t, "oidc", eu.Type)
+	var config oidc.Config
+	require.NoError(t, json.Unmarshal(eu.Config, &config))
+	require.Equal(t, "https://eu.dex.example.com", config.Issuer)
+	require.Equal(t, "https://dex.example.com/callback", config.RedirectURI)
+	require.True(t, config.InsecureEnableGroups)
+	require.Contains(t, config.Scopes, "groups")
+
+	require.Equal(t, "us", connectors[1].Name)
+	require.Equal(t, "local", connectors[2].ID)
+}
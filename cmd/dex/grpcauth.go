@@ -0,0 +1,107 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"path"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+
+	"github.com/dexidp/dex/api/v2"
+)
+
+// apiAuthorizer checks that callers of the API have the role required by the
+// method they call. Callers are identified by the common name of their
+// verified TLS client certificate.
+type apiAuthorizer struct {
+	roles   map[string]map[string]bool // common name to roles
+	methods map[string]string          // method name to required role
+	logger  *slog.Logger
+}
+
+func newAPIAuthorizer(c GRPCAuthorization, logger *slog.Logger) (*apiAuthorizer, error) {
+	a := &apiAuthorizer{
+		roles:   make(map[string]map[string]bool),
+		methods: make(map[string]string),
+		logger:  logger,
+	}
+	for role, names := range c.Roles {
+		for _, name := range names {
+			if a.roles[name] == nil {
+				a.roles[name] = make(map[string]bool)
+			}
+			a.roles[name][role] = true
+		}
+	}
+
+	known := map[string]bool{"*": true}
+	for _, m := range api.Dex_ServiceDesc.Methods {
+		known[m.MethodName] = true
+	}
+	for method, role := range c.Methods {
+		if !known[method] {
+			return nil, fmt.Errorf("unknown API method %q", method)
+		}
+		if _, ok := c.Roles[role]; !ok {
+			return nil, fmt.Errorf("method %q requires undefined role %q", method, role)
+		}
+		a.methods[method] = role
+	}
+	return a, nil
+}
+
+// authorize returns an error unless the caller may call the method.
+func (a *apiAuthorizer) authorize(ctx context.Context, fullMethod string) error {
+	method := path.Base(fullMethod)
+	role, ok := a.methods[method]
+	if !ok {
+		role, ok = a.methods["*"]
+	}
+
+	name, authenticated := callerName(ctx)
+	if !authenticated {
+		return status.Error(codes.Unauthenticated, "a verified client certificate is required")
+	}
+	if !ok || !a.roles[name][role] {
+		a.logger.WarnContext(ctx, "api call denied", "method", method, "caller", name, "required_role", role)
+		return status.Errorf(codes.PermissionDenied, "%s is not allowed to call %s", name, method)
+	}
+	return nil
+}
+
+func (a *apiAuthorizer) unaryInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if err := a.authorize(ctx, info.FullMethod); err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+func (a *apiAuthorizer) streamInterceptor() grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if err := a.authorize(ss.Context(), info.FullMethod); err != nil {
+			return err
+		}
+		return handler(srv, ss)
+	}
+}
+
+// callerName returns the common name of the verified client certificate of
+// the caller.
+func callerName(ctx context.Context) (string, bool) {
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return "", false
+	}
+	info, ok := p.AuthInfo.(credentials.TLSInfo)
+	if !ok || len(info.State.VerifiedChains) == 0 || len(info.State.VerifiedChains[0]) == 0 {
+		return "", false
+	}
+	return info.State.VerifiedChains[0][0].Subject.CommonName, true
+}
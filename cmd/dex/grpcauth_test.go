@@ -0,0 +1,83 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+
+	"github.com/dexidp/dex/api/v2"
+)
+
+func callerContext(commonName string) context.Context {
+	cert := &x509.Certificate{Subject: pkix.Name{CommonName: commonName}}
+	state := tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{cert}}}
+	return peer.NewContext(context.Background(), &peer.Peer{AuthInfo: credentials.TLSInfo{State: state}})
+}
+
+func TestAPIAuthorizer(t *testing.T) {
+	a, err := newAPIAuthorizer(GRPCAuthorization{
+		Roles: map[string][]string{
+			"admin":  {"ops"},
+			"reader": {"ops", "monitoring"},
+		},
+		Methods: map[string]string{
+			"ListClients": "reader",
+			"GetVersion":  "reader",
+			"*":           "admin",
+		},
+	}, slog.New(slog.DiscardHandler))
+	require.NoError(t, err)
+
+	tests := []struct {
+		name   string
+		ctx    context.Context
+		method string
+		want   codes.Code
+	}{
+		{"admin may create", callerContext("ops"), api.Dex_CreateClient_FullMethodName, codes.OK},
+		{"admin may list", callerContext("ops"), api.Dex_ListClients_FullMethodName, codes.OK},
+		{"reader may list", callerContext("monitoring"), api.Dex_ListClients_FullMethodName, codes.OK},
+		{"reader may not create", callerContext("monitoring"), api.Dex_CreateClient_FullMethodName, codes.PermissionDenied},
+		{"unknown caller", callerContext("someone"), api.Dex_GetVersion_FullMethodName, codes.PermissionDenied},
+		{"no certificate", context.Background(), api.Dex_GetVersion_FullMethodName, codes.Unauthenticated},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, tc.want, status.Code(a.authorize(tc.ctx, tc.method)))
+		})
+	}
+}
+
+func TestAPIAuthorizerDeniesUnlistedMethods(t *testing.T) {
+	a, err := newAPIAuthorizer(GRPCAuthorization{
+		Roles:   map[string][]string{"reader": {"monitoring"}},
+		Methods: map[string]string{"ListClients": "reader"},
+	}, slog.New(slog.DiscardHandler))
+	require.NoError(t, err)
+
+	require.NoError(t, a.authorize(callerContext("monitoring"), api.Dex_ListClients_FullMethodName))
+	require.Equal(t, codes.PermissionDenied, status.Code(a.authorize(callerContext("monitoring"), api.Dex_DeleteClient_FullMethodName)))
+}
+
+func TestNewAPIAuthorizerInvalid(t *testing.T) {
+	_, err := newAPIAuthorizer(GRPCAuthorization{
+		Roles:   map[string][]string{"admin": {"ops"}},
+		Methods: map[string]string{"CreateClients": "admin"},
+	}, slog.New(slog.DiscardHandler))
+	require.ErrorContains(t, err, "unknown API method")
+
+	_, err = newAPIAuthorizer(GRPCAuthorization{
+		Roles:   map[string][]string{"admin": {"ops"}},
+		Methods: map[string]string{"CreateClient": "writer"},
+	}, slog.New(slog.DiscardHandler))
+	require.ErrorContains(t, err, "undefined role")
+}
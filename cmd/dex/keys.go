@@ -0,0 +1,271 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/go-jose/go-jose/v4"
+	"github.com/spf13/cobra"
+
+	"github.com/dexidp/dex/server/signer"
+	"github.com/dexidp/dex/storage"
+)
+
+type keysOptions struct {
+	// Config file path
+	config string
+
+	// Flags
+	tenant string
+}
+
+func commandKeys() *cobra.Command {
+	options := keysOptions{}
+
+	cmd := &cobra.Command{
+		Use:   "keys",
+		Short: "Inspect and manage the signing keys kept in the storage",
+	}
+	cmd.PersistentFlags().StringVar(&options.tenant, "tenant", "", "Operate on the keys of this tenant instead of the main issuer")
+
+	cmd.AddCommand(&cobra.Command{
+		Use:     "list [flags] [config file or directory]",
+		Short:   "List the signing and verification keys",
+		Example: "dex keys list config.yaml",
+		Args:    cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cmd.SilenceUsage = true
+			cmd.SilenceErrors = true
+			options.config = args[0]
+			return runKeysList(cmd.OutOrStdout(), options)
+		},
+	})
+	cmd.AddCommand(&cobra.Command{
+		Use:     "rotate [flags] [config file or directory]",
+		Short:   "Replace the signing key now, regardless of the rotation period",
+		Example: "dex keys rotate config.yaml",
+		Args:    cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cmd.SilenceUsage = true
+			cmd.SilenceErrors = true
+			options.config = args[0]
+			return runKeysRotate(cmd.OutOrStdout(), options)
+		},
+	})
+	cmd.AddCommand(&cobra.Command{
+		Use:     "export [flags] [config file or directory]",
+		Short:   "Print the public keys as a JSON Web Key Set",
+		Example: "dex keys export config.yaml > jwks.json",
+		Args:    cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cmd.SilenceUsage = true
+			cmd.SilenceErrors = true
+			options.config = args[0]
+			return runKeysExport(cmd.OutOrStdout(), options)
+		},
+	})
+
+	var validFor time.Duration
+	importCmd := &cobra.Command{
+		Use:     "import [flags] [config file or directory] [JWKS file]",
+		Short:   "Add public keys as verification keys, to accept tokens signed by another issuer",
+		Example: "dex keys import --valid-for 48h config.yaml jwks.json",
+		Args:    cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cmd.SilenceUsage = true
+			cmd.SilenceErrors = true
+			options.config = args[0]
+			return runKeysImport(cmd.OutOrStdout(), options, args[1], validFor)
+		},
+	}
+	importCmd.Flags().DurationVar(&validFor, "valid-for", 24*time.Hour, "How long the imported keys are kept")
+	cmd.AddCommand(importCmd)
+
+	return cmd
+}
+
+// keysStorage opens the storage holding the keys of the issuer selected by
+// the options, along with the config of that issuer.
+func keysStorage(options keysOptions) (Config, storage.Storage, error) {
+	c, logger, err := loadIssuerConfig(options.config, options.tenant)
+	if err != nil {
+		return c, nil, err
+	}
+	if options.tenant != "" {
+		// Tenants always use a local signer.
+		c.Signer = Signer{Type: "local", Config: &signer.LocalConfig{KeysRotationPeriod: c.keysRotationPeriod()}}
+	}
+	if c.Signer.Type == "vault" {
+		return c, nil, errors.New("the keys of the vault signer are kept in vault, not in the storage")
+	}
+
+	s, err := c.Storage.Config.Open(logger.With("component", "storage"))
+	if err != nil {
+		return c, nil, fmt.Errorf("failed to initialize storage: %v", err)
+	}
+	return c, s, nil
+}
+
+func getKeys(s storage.Storage) (storage.Keys, error) {
+	keys, err := s.GetKeys(context.Background())
+	if err != nil && !errors.Is(err, storage.ErrNotFound) {
+		return keys, fmt.Errorf("failed to get keys: %v", err)
+	}
+	return keys, nil
+}
+
+// runKeysList prints a line per key. The time of the signing key is its next
+// rotation, that of a verification key is when it is removed.
+func runKeysList(out io.Writer, options keysOptions) error {
+	_, s, err := keysStorage(options)
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+
+	keys, err := getKeys(s)
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "KEY ID\tALGORITHM\tSTATUS\tUNTIL")
+	if keys.SigningKeyPub != nil {
+		fmt.Fprintf(w, "%s\t%s\tsigning\t%s\n", keys.SigningKeyPub.KeyID, keys.SigningKeyPub.Algorithm, keys.NextRotation.Format(time.RFC3339))
+	}
+	for _, key := range keys.VerificationKeys {
+		fmt.Fprintf(w, "%s\t%s\tverification\t%s\n", key.PublicKey.KeyID, key.PublicKey.Algorithm, key.Expiry.Format(time.RFC3339))
+	}
+	return w.Flush()
+}
+
+func runKeysRotate(out io.Writer, options keysOptions) error {
+	c, s, err := keysStorage(options)
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+
+	idTokensValidFor := 24 * time.Hour
+	if c.Expiry.IDTokens != "" {
+		idTokensValidFor, err = time.ParseDuration(c.Expiry.IDTokens)
+		if err != nil {
+			return fmt.Errorf("invalid config value %q for id token expiry: %v", c.Expiry.IDTokens, err)
+		}
+	}
+	local := signer.LocalConfig{KeysRotationPeriod: c.keysRotationPeriod()}
+	if lc, ok := c.Signer.Config.(*signer.LocalConfig); ok {
+		local.Algorithm = lc.Algorithm
+	}
+
+	now := func() time.Time { return time.Now().UTC() }
+	if err := local.Rotate(s, idTokensValidFor, now, slog.New(slog.DiscardHandler)); err != nil {
+		return fmt.Errorf("failed to rotate keys: %v", err)
+	}
+
+	keys, err := getKeys(s)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "signing key rotated, new key %q, next rotation at %s\n", keys.SigningKeyPub.KeyID, keys.NextRotation.Format(time.RFC3339))
+	return nil
+}
+
+func runKeysExport(out io.Writer, options keysOptions) error {
+	_, s, err := keysStorage(options)
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+
+	keys, err := getKeys(s)
+	if err != nil {
+		return err
+	}
+
+	jwks := jose.JSONWebKeySet{Keys: make([]jose.JSONWebKey, 0, len(keys.VerificationKeys)+1)}
+	if keys.SigningKeyPub != nil {
+		jwks.Keys = append(jwks.Keys, *keys.SigningKeyPub)
+	}
+	for _, key := range keys.VerificationKeys {
+		jwks.Keys = append(jwks.Keys, *key.PublicKey)
+	}
+
+	data, err := json.MarshalIndent(jwks, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal keys: %v", err)
+	}
+	_, err = fmt.Fprintln(out, string(data))
+	return err
+}
+
+func runKeysImport(out io.Writer, options keysOptions, jwksFile string, validFor time.Duration) error {
+	if validFor <= 0 {
+		return fmt.Errorf("--valid-for must be positive, got %v", validFor)
+	}
+	data, err := os.ReadFile(jwksFile)
+	if err != nil {
+		return err
+	}
+	var jwks jose.JSONWebKeySet
+	if err := json.Unmarshal(data, &jwks); err != nil {
+		return fmt.Errorf("failed to parse %s: %v", jwksFile, err)
+	}
+	for _, key := range jwks.Keys {
+		switch {
+		case key.KeyID == "":
+			return fmt.Errorf("%s: every key needs a key ID", jwksFile)
+		case !key.IsPublic():
+			return fmt.Errorf("%s: key %q is a private key, only public keys can be imported", jwksFile, key.KeyID)
+		case !key.Valid():
+			return fmt.Errorf("%s: key %q is invalid", jwksFile, key.KeyID)
+		}
+	}
+
+	_, s, err := keysStorage(options)
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+
+	expiry := time.Now().UTC().Add(validFor)
+	var imported, skipped []string
+	err = s.UpdateKeys(context.Background(), func(keys storage.Keys) (storage.Keys, error) {
+		imported, skipped = nil, nil
+		known := make(map[string]bool, len(keys.VerificationKeys)+1)
+		if keys.SigningKeyPub != nil {
+			known[keys.SigningKeyPub.KeyID] = true
+		}
+		for _, key := range keys.VerificationKeys {
+			known[key.PublicKey.KeyID] = true
+		}
+		for _, key := range jwks.Keys {
+			if known[key.KeyID] {
+				skipped = append(skipped, key.KeyID)
+				continue
+			}
+			keys.VerificationKeys = append(keys.VerificationKeys, storage.VerificationKey{PublicKey: &key, Expiry: expiry})
+			known[key.KeyID] = true
+			imported = append(imported, key.KeyID)
+		}
+		return keys, nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to update keys: %v", err)
+	}
+
+	for _, kid := range imported {
+		fmt.Fprintf(out, "imported key %q, valid until %s\n", kid, expiry.Format(time.RFC3339))
+	}
+	for _, kid := range skipped {
+		fmt.Fprintf(out, "skipped key %q, already present\n", kid)
+	}
+	return nil
+}
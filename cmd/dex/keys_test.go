@@ -0,0 +1,79 @@
+package main
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-jose/go-jose/v4"
+	"github.com/stretchr/testify/require"
+)
+
+func TestKeysCommands(t *testing.T) {
+	dir := t.TempDir()
+	config := filepath.Join(dir, "config.yaml")
+	require.NoError(t, os.WriteFile(config, []byte(`
+issuer: http://127.0.0.1:5556/dex
+storage:
+  type: sqlite3
+  config:
+    file: `+filepath.Join(dir, "dex.db")+`
+web:
+  http: 127.0.0.1:5556
+logger:
+  level: error
+`), 0o600))
+	options := keysOptions{config: config}
+
+	var out bytes.Buffer
+	require.NoError(t, runKeysRotate(&out, options))
+	require.NoError(t, runKeysRotate(&out, options))
+
+	out.Reset()
+	require.NoError(t, runKeysList(&out, options))
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	require.Len(t, lines, 3)
+	require.Equal(t, []string{"RS256", "signing"}, strings.Fields(lines[1])[1:3])
+	require.Equal(t, []string{"RS256", "verification"}, strings.Fields(lines[2])[1:3])
+
+	out.Reset()
+	require.NoError(t, runKeysExport(&out, options))
+	var exported jose.JSONWebKeySet
+	require.NoError(t, json.Unmarshal(out.Bytes(), &exported))
+	require.Len(t, exported.Keys, 2)
+	for _, key := range exported.Keys {
+		require.True(t, key.IsPublic())
+	}
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	writeJWKS := func(key jose.JSONWebKey) string {
+		data, err := json.Marshal(jose.JSONWebKeySet{Keys: []jose.JSONWebKey{key}})
+		require.NoError(t, err)
+		path := filepath.Join(dir, "jwks.json")
+		require.NoError(t, os.WriteFile(path, data, 0o600))
+		return path
+	}
+
+	private := writeJWKS(jose.JSONWebKey{Key: key, KeyID: "other", Algorithm: "RS256", Use: "sig"})
+	require.ErrorContains(t, runKeysImport(&out, options, private, time.Hour), `key "other" is a private key`)
+
+	public := writeJWKS(jose.JSONWebKey{Key: key.Public(), KeyID: "other", Algorithm: "RS256", Use: "sig"})
+	out.Reset()
+	require.NoError(t, runKeysImport(&out, options, public, time.Hour))
+	require.Contains(t, out.String(), `imported key "other"`)
+
+	out.Reset()
+	require.NoError(t, runKeysImport(&out, options, public, time.Hour))
+	require.Contains(t, out.String(), `skipped key "other", already present`)
+
+	out.Reset()
+	require.NoError(t, runKeysList(&out, options))
+	require.Contains(t, out.String(), "other")
+}
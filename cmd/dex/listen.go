@@ -0,0 +1,80 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/coreos/go-systemd/v22/activation"
+)
+
+const (
+	unixAddrPrefix    = "unix:"
+	systemdAddrPrefix = "systemd:"
+)
+
+// listenerSet opens the listeners of the configured addresses. Besides TCP
+// host:port addresses it accepts "unix:/path/to/socket" for unix domain
+// sockets and "systemd:name" for a socket passed by systemd socket
+// activation, where name is the FileDescriptorName of the socket unit.
+type listenerSet struct {
+	once    sync.Once
+	systemd map[string][]net.Listener
+	err     error
+}
+
+func (ls *listenerSet) listen(addr string) (net.Listener, error) {
+	switch {
+	case strings.HasPrefix(addr, unixAddrPrefix):
+		path := strings.TrimPrefix(addr, unixAddrPrefix)
+		if err := removeStaleSocket(path); err != nil {
+			return nil, err
+		}
+		return net.Listen("unix", path)
+	case strings.HasPrefix(addr, systemdAddrPrefix):
+		return ls.systemdListener(strings.TrimPrefix(addr, systemdAddrPrefix))
+	default:
+		return net.Listen("tcp", addr)
+	}
+}
+
+// systemdListener returns the socket passed by systemd under name. Each
+// socket can be used once.
+func (ls *listenerSet) systemdListener(name string) (net.Listener, error) {
+	// The sockets are taken from the environment, which is only possible once.
+	ls.once.Do(func() {
+		ls.systemd, ls.err = activation.ListenersWithNames()
+	})
+	if ls.err != nil {
+		return nil, fmt.Errorf("systemd socket activation: %v", ls.err)
+	}
+
+	for len(ls.systemd[name]) > 0 {
+		l := ls.systemd[name][0]
+		ls.systemd[name] = ls.systemd[name][1:]
+		// Datagram sockets are passed as nil listeners.
+		if l != nil {
+			return l, nil
+		}
+	}
+	return nil, fmt.Errorf("no systemd socket named %q was passed to dex", name)
+}
+
+// removeStaleSocket removes a unix socket left behind by a previous run,
+// which would make listening fail.
+func removeStaleSocket(path string) error {
+	fi, err := os.Lstat(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if fi.Mode()&os.ModeSocket == 0 {
+		return fmt.Errorf("%s exists and is not a socket", path)
+	}
+	return os.Remove(path)
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestListenUnix(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "dex.sock")
+
+	// A socket left behind by a previous run is replaced.
+	stale, err := net.Listen("unix", path)
+	require.NoError(t, err)
+	stale.(*net.UnixListener).SetUnlinkOnClose(false)
+	require.NoError(t, stale.Close())
+
+	var ls listenerSet
+	l, err := ls.listen("unix:" + path)
+	require.NoError(t, err)
+	defer l.Close()
+
+	server := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("ok"))
+	})}
+	go server.Serve(l)
+	defer server.Close()
+
+	client := &http.Client{Transport: &http.Transport{
+		Dial: func(_, _ string) (net.Conn, error) { return net.Dial("unix", path) },
+	}}
+	resp, err := client.Get("http://dex/")
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+}
+
+func TestListenUnixRefusesRegularFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "dex.sock")
+	require.NoError(t, os.WriteFile(path, nil, 0o600))
+
+	var ls listenerSet
+	_, err := ls.listen("unix:" + path)
+	require.Error(t, err)
+}
+
+func TestListenSystemdMissingSocket(t *testing.T) {
+	t.Setenv("LISTEN_PID", "")
+	t.Setenv("LISTEN_FDS", "")
+
+	var ls listenerSet
+	_, err := ls.listen("systemd:dex-web")
+	require.ErrorContains(t, err, `no systemd socket named "dex-web"`)
+}
@@ -3,33 +3,47 @@ package main
 import (
 	"context"
 	"fmt"
+	"io"
 	"log/slog"
+	"maps"
+	"math"
 	"os"
 	"strings"
+	"sync"
 
 	"github.com/dexidp/dex/server"
 )
 
 var logFormats = []string{"json", "text"}
 
-func newLogger(level slog.Level, format string, excludeFields []string) (*slog.Logger, error) {
+// newLogger returns the logger configured by c and the levels of its modules,
+// which can be changed at runtime.
+func newLogger(c Logger) (*slog.Logger, *logLevels, error) {
+	return newLoggerWithOutput(os.Stderr, c)
+}
+
+func newLoggerWithOutput(w io.Writer, c Logger) (*slog.Logger, *logLevels, error) {
+	levels, err := newLogLevels(c.Level, c.Levels)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Levels are checked by the module handler, so the output handler must
+	// let everything through.
+	opts := &slog.HandlerOptions{Level: slog.Level(math.MinInt)}
 	var handler slog.Handler
-	switch strings.ToLower(format) {
+	switch strings.ToLower(c.Format) {
 	case "", "text":
-		handler = slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{
-			Level: level,
-		})
+		handler = slog.NewTextHandler(w, opts)
 	case "json":
-		handler = slog.NewJSONHandler(os.Stderr, &slog.HandlerOptions{
-			Level: level,
-		})
+		handler = slog.NewJSONHandler(w, opts)
 	default:
-		return nil, fmt.Errorf("log format is not one of the supported values (%s): %s", strings.Join(logFormats, ", "), format)
+		return nil, nil, fmt.Errorf("log format is not one of the supported values (%s): %s", strings.Join(logFormats, ", "), c.Format)
 	}
 
-	handler = newExcludingHandler(handler, excludeFields)
+	handler = newExcludingHandler(handler, c.ExcludeFields)
 
-	return slog.New(newRequestContextHandler(handler)), nil
+	return slog.New(newModuleLevelHandler(newRequestContextHandler(handler), levels)), levels, nil
 }
 
 var _ slog.Handler = requestContextHandler{}
@@ -67,3 +81,97 @@ func (h requestContextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
 func (h requestContextHandler) WithGroup(name string) slog.Handler {
 	return requestContextHandler{h.handler.WithGroup(name)}
 }
+
+// logModuleKey is the attribute naming the module of a logger, e.g.
+// "storage" or "connector.hsdp". Log lines without it belong to the "server"
+// module.
+const logModuleKey = "component"
+
+const defaultLogModule = "server"
+
+// logLevels holds the log level of each module. Modules without a level of
+// their own log at the default level.
+type logLevels struct {
+	mu      sync.RWMutex
+	def     slog.Level
+	modules map[string]slog.Level
+}
+
+func newLogLevels(def slog.Level, modules map[string]slog.Level) (*logLevels, error) {
+	l := &logLevels{def: def, modules: make(map[string]slog.Level, len(modules))}
+	for module, level := range modules {
+		if module == "" {
+			return nil, fmt.Errorf("log levels: empty module name")
+		}
+		l.modules[module] = level
+	}
+	return l, nil
+}
+
+// level returns the level of a module.
+func (l *logLevels) level(module string) slog.Level {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	if level, ok := l.modules[module]; ok {
+		return level
+	}
+	return l.def
+}
+
+// SetLogLevel changes the level of a module. A nil level makes the module use
+// the default level again. An empty module changes the default level.
+func (l *logLevels) SetLogLevel(module string, level *slog.Level) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	switch {
+	case module == "" && level != nil:
+		l.def = *level
+	case level == nil:
+		delete(l.modules, module)
+	default:
+		l.modules[module] = *level
+	}
+}
+
+// LogLevels returns the default level and the levels of single modules.
+func (l *logLevels) LogLevels() (slog.Level, map[string]slog.Level) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return l.def, maps.Clone(l.modules)
+}
+
+var _ slog.Handler = moduleLevelHandler{}
+
+// moduleLevelHandler drops records below the level of the module of the
+// logger.
+type moduleLevelHandler struct {
+	handler slog.Handler
+	levels  *logLevels
+	module  string
+}
+
+func newModuleLevelHandler(handler slog.Handler, levels *logLevels) slog.Handler {
+	return moduleLevelHandler{handler: handler, levels: levels, module: defaultLogModule}
+}
+
+func (h moduleLevelHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return level >= h.levels.level(h.module)
+}
+
+func (h moduleLevelHandler) Handle(ctx context.Context, record slog.Record) error {
+	return h.handler.Handle(ctx, record)
+}
+
+func (h moduleLevelHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	module := h.module
+	for _, attr := range attrs {
+		if attr.Key == logModuleKey && attr.Value.Kind() == slog.KindString {
+			module = attr.Value.String()
+		}
+	}
+	return moduleLevelHandler{handler: h.handler.WithAttrs(attrs), levels: h.levels, module: module}
+}
+
+func (h moduleLevelHandler) WithGroup(name string) slog.Handler {
+	return moduleLevelHandler{handler: h.handler.WithGroup(name), levels: h.levels, module: h.module}
+}
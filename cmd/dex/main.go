@@ -16,6 +16,10 @@ func commandRoot() *cobra.Command {
 		},
 	}
 	rootCmd.AddCommand(commandServe())
+	rootCmd.AddCommand(commandValidate())
+	rootCmd.AddCommand(commandKeys())
+	rootCmd.AddCommand(commandClients())
+	rootCmd.AddCommand(commandConnectors())
 	rootCmd.AddCommand(commandVersion())
 	return rootCmd
 }
@@ -0,0 +1,228 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/ghodss/yaml"
+)
+
+// mergedListKeys are the top level lists which may be split across files. The
+// value is the field identifying an entry, used to detect duplicates.
+var mergedListKeys = map[string]string{
+	"connectors":      "id",
+	"staticClients":   "id",
+	"staticPasswords": "email",
+}
+
+// readConfig reads the config at path and returns it as JSON.
+//
+// The path may be a single file or a directory, in which case every .yaml,
+// .yml and .json file in it is read in lexical order. A file may also list
+// further files with a top level "include" key holding globs relative to it.
+// All files are merged into one config: connectors, static clients and static
+// passwords are concatenated, objects are merged key by key, and any other
+// value set in more than one file is reported as a conflict.
+func readConfig(path string) ([]byte, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file %s: %v", path, err)
+	}
+
+	var files []string
+	if info.IsDir() {
+		if files, err = configFilesInDir(path); err != nil {
+			return nil, err
+		}
+		if len(files) == 0 {
+			return nil, fmt.Errorf("no config files found in %s", path)
+		}
+	} else {
+		files = []string{path}
+	}
+
+	m := &configMerger{
+		merged:  make(map[string]interface{}),
+		origins: make(map[string]string),
+		ids:     make(map[string]string),
+	}
+	for _, file := range files {
+		if err := m.addFile(file, true); err != nil {
+			return nil, err
+		}
+	}
+	return json.Marshal(m.merged)
+}
+
+func configFilesInDir(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config directory %s: %v", dir, err)
+	}
+	var files []string
+	for _, e := range entries {
+		if e.IsDir() {
+			continue
+		}
+		switch filepath.Ext(e.Name()) {
+		case ".yaml", ".yml", ".json":
+			files = append(files, filepath.Join(dir, e.Name()))
+		}
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+type configMerger struct {
+	merged map[string]interface{}
+	// origins records which file set a value, keyed by its dotted path.
+	origins map[string]string
+	// ids records which file defined a list entry, keyed by list and ID.
+	ids map[string]string
+}
+
+func (m *configMerger) addFile(file string, allowInclude bool) error {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return fmt.Errorf("failed to read config file %s: %v", file, err)
+	}
+	jsonData, err := yaml.YAMLToJSON(data)
+	if err != nil {
+		return fmt.Errorf("error parse config file %s: %v", file, err)
+	}
+
+	var values map[string]interface{}
+	dec := json.NewDecoder(bytes.NewReader(jsonData))
+	dec.UseNumber()
+	if err := dec.Decode(&values); err != nil {
+		return fmt.Errorf("error parse config file %s: %v", file, err)
+	}
+
+	includes, err := includeGlobs(values)
+	if err != nil {
+		return fmt.Errorf("config file %s: %v", file, err)
+	}
+	delete(values, "include")
+	if len(includes) > 0 && !allowInclude {
+		return fmt.Errorf("config file %s: included files cannot include other files", file)
+	}
+
+	if err := m.mergeMap(m.merged, values, "", file); err != nil {
+		return err
+	}
+
+	for _, pattern := range includes {
+		if !filepath.IsAbs(pattern) {
+			pattern = filepath.Join(filepath.Dir(file), pattern)
+		}
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			return fmt.Errorf("config file %s: invalid include %q: %v", file, pattern, err)
+		}
+		sort.Strings(matches)
+		for _, match := range matches {
+			if err := m.addFile(match, false); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func includeGlobs(values map[string]interface{}) ([]string, error) {
+	raw, ok := values["include"]
+	if !ok {
+		return nil, nil
+	}
+	list, ok := raw.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("include must be a list of file globs")
+	}
+	globs := make([]string, len(list))
+	for i, item := range list {
+		if globs[i], ok = item.(string); !ok {
+			return nil, fmt.Errorf("include must be a list of file globs")
+		}
+	}
+	return globs, nil
+}
+
+func (m *configMerger) mergeMap(dst, src map[string]interface{}, path, file string) error {
+	keys := make([]string, 0, len(src))
+	for k := range src {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		v := src[k]
+		p := joinConfigPath(path, k)
+
+		if idField, ok := mergedListKeys[p]; ok {
+			if err := m.appendList(dst, k, v, idField, file); err != nil {
+				return err
+			}
+			continue
+		}
+
+		existing, exists := dst[k]
+		if !exists {
+			dst[k] = v
+			m.origins[p] = file
+			continue
+		}
+
+		existingMap, ok1 := existing.(map[string]interface{})
+		srcMap, ok2 := v.(map[string]interface{})
+		if ok1 && ok2 {
+			if err := m.mergeMap(existingMap, srcMap, p, file); err != nil {
+				return err
+			}
+			continue
+		}
+		return fmt.Errorf("config key %q is set in both %s and %s", p, m.originOf(p), file)
+	}
+	return nil
+}
+
+// originOf finds the file which set path, or the closest parent of it.
+func (m *configMerger) originOf(path string) string {
+	for {
+		if origin, ok := m.origins[path]; ok {
+			return origin
+		}
+		i := strings.LastIndexByte(path, '.')
+		if i < 0 {
+			return "another file"
+		}
+		path = path[:i]
+	}
+}
+
+func (m *configMerger) appendList(dst map[string]interface{}, key string, v interface{}, idField, file string) error {
+	items, ok := v.([]interface{})
+	if !ok {
+		return fmt.Errorf("config file %s: %s must be a list", file, key)
+	}
+
+	for _, item := range items {
+		if obj, ok := item.(map[string]interface{}); ok {
+			if id, ok := obj[idField].(string); ok && id != "" {
+				idKey := key + "\x00" + id
+				if origin, dup := m.ids[idKey]; dup {
+					return fmt.Errorf("%s entry with %s %q is defined in both %s and %s", key, idField, id, origin, file)
+				}
+				m.ids[idKey] = file
+			}
+		}
+	}
+
+	existing, _ := dst[key].([]interface{})
+	dst[key] = append(existing, items...)
+	return nil
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func writeConfigFiles(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o700))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	}
+	return dir
+}
+
+const baseConfigFile = `
+issuer: http://127.0.0.1:5556/dex
+web:
+  http: 127.0.0.1:5556
+`
+
+func TestLoadConfigDirectory(t *testing.T) {
+	dir := writeConfigFiles(t, map[string]string{
+		"00-base.yaml":    baseConfigFile,
+		"10-storage.yaml": "storage:\n  type: memory\n",
+		"20-team-a.yaml": `
+connectors:
+- type: mockCallback
+  id: team-a
+  name: Team A
+staticClients:
+- id: app-a
+  name: App A
+  secret: a
+`,
+		"30-team-b.yml": `
+connectors:
+- type: mockCallback
+  id: team-b
+  name: Team B
+`,
+		"README.md": "ignored",
+	})
+
+	c, err := loadConfig(dir)
+	require.NoError(t, err)
+	require.Equal(t, "http://127.0.0.1:5556/dex", c.Issuer)
+	require.Equal(t, "memory", c.Storage.Type)
+	require.Len(t, c.StaticConnectors, 2)
+	require.Equal(t, "team-a", c.StaticConnectors[0].ID)
+	require.Equal(t, "team-b", c.StaticConnectors[1].ID)
+	require.Len(t, c.StaticClients, 1)
+}
+
+func TestLoadConfigInclude(t *testing.T) {
+	dir := writeConfigFiles(t, map[string]string{
+		"dex.yaml": baseConfigFile + `
+storage:
+  type: memory
+include:
+- conf.d/*.yaml
+`,
+		"conf.d/b.yaml": "connectors:\n- {type: mockCallback, id: b, name: B}\n",
+		"conf.d/a.yaml": "connectors:\n- {type: mockCallback, id: a, name: A}\n",
+	})
+
+	c, err := loadConfig(filepath.Join(dir, "dex.yaml"))
+	require.NoError(t, err)
+	require.Len(t, c.StaticConnectors, 2)
+	require.Equal(t, "a", c.StaticConnectors[0].ID)
+	require.Equal(t, "b", c.StaticConnectors[1].ID)
+}
+
+func TestLoadConfigConflicts(t *testing.T) {
+	tests := []struct {
+		name    string
+		files   map[string]string
+		wantErr string
+	}{
+		{
+			name: "scalar set twice",
+			files: map[string]string{
+				"a.yaml": baseConfigFile,
+				"b.yaml": "web:\n  http: 0.0.0.0:5556\n",
+			},
+			wantErr: `config key "web.http" is set in both`,
+		},
+		{
+			name: "duplicate connector",
+			files: map[string]string{
+				"a.yaml": "connectors:\n- {type: mockCallback, id: mock, name: A}\n",
+				"b.yaml": "connectors:\n- {type: mockCallback, id: mock, name: B}\n",
+			},
+			wantErr: `connectors entry with id "mock" is defined in both`,
+		},
+		{
+			name: "nested include",
+			files: map[string]string{
+				"a.yaml":       "include: [sub/*.yaml]\n",
+				"sub/b.yaml":   "include: [other.yaml]\n",
+				"sub/other.md": "",
+			},
+			wantErr: "included files cannot include other files",
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := loadConfig(writeConfigFiles(t, tc.files))
+			require.ErrorContains(t, err, tc.wantErr)
+		})
+	}
+}
@@ -0,0 +1,170 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+
+	"github.com/dexidp/dex/server"
+	"github.com/dexidp/dex/storage"
+)
+
+// configReloadDelay is how long the config files must be left alone before a
+// change is picked up. Editors and Kubernetes config map updates touch the
+// files several times in quick succession.
+const configReloadDelay = time.Second
+
+// configReloader applies changes to the config to a running server. Static
+// clients, passwords and connectors, the frontend settings and the
+// maintenance mode are reloaded; any other change is reported and needs a
+// restart.
+type configReloader struct {
+	options serveOptions
+	logger  *slog.Logger
+	server  *server.Server
+	static  *storage.StaticObjects
+
+	mu      sync.Mutex
+	current Config
+}
+
+// reload reads the config again and applies it. Nothing is changed if the
+// new config is invalid.
+func (r *configReloader) reload(ctx context.Context) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	c, err := loadConfig(r.options.config)
+	if err != nil {
+		return err
+	}
+	warnUnsetEnvVars(r.logger)
+	applyConfigOverrides(r.options, &c)
+	if err := c.Validate(); err != nil {
+		return err
+	}
+
+	clients, err := buildStaticClients(c, r.logger)
+	if err != nil {
+		return fmt.Errorf("invalid config: %v", err)
+	}
+	connectors, err := buildStaticConnectors(c, r.logger)
+	if err != nil {
+		return err
+	}
+	maintenance, err := parseMaintenance(c.Maintenance)
+	if err != nil {
+		return fmt.Errorf("invalid config: %v", err)
+	}
+
+	if !reflect.DeepEqual(r.current.Frontend, c.Frontend) {
+		if err := r.server.ReloadWeb(c.Frontend); err != nil {
+			return err
+		}
+		r.logger.Info("frontend config reloaded")
+	}
+
+	if maintenance != r.server.Maintenance() {
+		r.server.SetMaintenance(maintenance)
+		r.logger.Info("maintenance mode changed", "enabled", maintenance.Enabled)
+	}
+
+	r.static.SetClients(clients)
+	r.static.SetPasswords(buildStaticPasswords(c))
+	r.static.SetConnectors(connectors)
+
+	if requiresRestart(r.current, c) {
+		r.logger.Warn("config changes other than static clients, passwords, connectors, frontend settings and maintenance mode require a restart")
+	}
+	r.current = c
+
+	return r.server.SyncConnectors(ctx)
+}
+
+// requiresRestart reports whether the configs differ in anything the reloader
+// cannot apply.
+func requiresRestart(old, c Config) bool {
+	for _, cfg := range []*Config{&old, &c} {
+		cfg.StaticClients = nil
+		cfg.StaticPasswords = nil
+		cfg.StaticConnectors = nil
+		cfg.EnablePasswordDB = false
+		cfg.Frontend = server.WebConfig{}
+		cfg.Maintenance = Maintenance{}
+	}
+	return !reflect.DeepEqual(old, c)
+}
+
+// run reloads the config on SIGHUP and, if watch is set, whenever the config
+// files change, until ctx is canceled.
+func (r *configReloader) run(ctx context.Context, watch bool) error {
+	sigc := make(chan os.Signal, 1)
+	signal.Notify(sigc, syscall.SIGHUP)
+	defer signal.Stop(sigc)
+
+	var (
+		events      <-chan fsnotify.Event
+		watchErrors <-chan error
+	)
+	if watch {
+		watcher, err := fsnotify.NewWatcher()
+		if err != nil {
+			return fmt.Errorf("watch config: %v", err)
+		}
+		defer watcher.Close()
+
+		// Watch the directory rather than the file to handle files being
+		// replaced by renames.
+		dir := r.options.config
+		if info, err := os.Stat(dir); err == nil && !info.IsDir() {
+			dir = filepath.Dir(dir)
+		}
+		if err := watcher.Add(dir); err != nil {
+			return fmt.Errorf("watch config: %v", err)
+		}
+		r.logger.Info("watching config for changes", "dir", dir)
+
+		events = watcher.Events
+		watchErrors = watcher.Errors
+	}
+
+	var settled <-chan time.Time
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case sig := <-sigc:
+			r.logger.Info("reloading config from signal", "signal", sig)
+			r.reloadAndLog(ctx)
+		case evt := <-events:
+			if evt.Op == fsnotify.Chmod {
+				continue
+			}
+			r.logger.Debug("config changed", "event", evt.Name, "operation", evt.Op.String())
+			settled = time.After(configReloadDelay)
+		case <-settled:
+			settled = nil
+			r.logger.Info("reloading config from file change")
+			r.reloadAndLog(ctx)
+		case err := <-watchErrors:
+			r.logger.Error("watching config", "err", err)
+		}
+	}
+}
+
+func (r *configReloader) reloadAndLog(ctx context.Context) {
+	if err := r.reload(ctx); err != nil {
+		r.logger.Error("failed to reload config", "err", err)
+		return
+	}
+	r.logger.Info("config reloaded")
+}
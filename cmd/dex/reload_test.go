@@ -0,0 +1,45 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/dexidp/dex/server"
+	"github.com/dexidp/dex/storage"
+)
+
+func TestRequiresRestart(t *testing.T) {
+	base := Config{
+		Issuer: "http://127.0.0.1:5556/dex",
+		Web:    Web{HTTP: "127.0.0.1:5556"},
+	}
+
+	reloadable := base
+	reloadable.StaticClients = []storage.Client{{ID: "example-app"}}
+	reloadable.StaticConnectors = []Connector{{ID: "mock", Type: "mockCallback"}}
+	reloadable.EnablePasswordDB = true
+	reloadable.Frontend = server.WebConfig{Theme: "dark"}
+	reloadable.Maintenance = Maintenance{Enabled: true, RetryAfter: "30m"}
+	require.False(t, requiresRestart(base, reloadable))
+
+	moved := base
+	moved.Web.HTTP = "0.0.0.0:5556"
+	require.True(t, requiresRestart(base, moved))
+}
+
+func TestToStorageConnectorResourceVersion(t *testing.T) {
+	conn := Connector{ID: "mock", Type: "mockCallback", Name: "Mock"}
+
+	a, err := ToStorageConnector(conn)
+	require.NoError(t, err)
+	b, err := ToStorageConnector(conn)
+	require.NoError(t, err)
+	require.NotEmpty(t, a.ResourceVersion)
+	require.Equal(t, a.ResourceVersion, b.ResourceVersion)
+
+	conn.Name = "Renamed"
+	c, err := ToStorageConnector(conn)
+	require.NoError(t, err)
+	require.NotEqual(t, a.ResourceVersion, c.ResourceVersion)
+}
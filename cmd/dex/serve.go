@@ -7,14 +7,16 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"log/slog"
-	"net"
 	"net/http"
 	"net/http/pprof"
+	"net/url"
 	"os"
 	"os/signal"
 	"path/filepath"
 	"runtime"
+	"slices"
 	"strings"
 	"sync/atomic"
 	"syscall"
@@ -24,7 +26,6 @@ import (
 	"github.com/AppsFlyer/go-sundheit/checks"
 	gosundheithttp "github.com/AppsFlyer/go-sundheit/http"
 	"github.com/fsnotify/fsnotify"
-	"github.com/ghodss/yaml"
 	grpcprometheus "github.com/grpc-ecosystem/go-grpc-prometheus"
 	"github.com/oklog/run"
 	"github.com/prometheus/client_golang/prometheus"
@@ -36,8 +37,12 @@ import (
 	"google.golang.org/grpc/reflection"
 
 	"github.com/dexidp/dex/api/v2"
+	"github.com/dexidp/dex/pkg/events"
 	"github.com/dexidp/dex/pkg/featureflags"
+	"github.com/dexidp/dex/pkg/httpclient"
 	"github.com/dexidp/dex/server"
+	"github.com/dexidp/dex/server/admin"
+	"github.com/dexidp/dex/server/restapi"
 	"github.com/dexidp/dex/server/signer"
 	"github.com/dexidp/dex/storage"
 )
@@ -51,6 +56,12 @@ type serveOptions struct {
 	webHTTPSAddr  string
 	telemetryAddr string
 	grpcAddr      string
+	watchConfig   bool
+	dryRun        bool
+	skipNetwork   bool
+
+	// Output of the dry run report
+	out io.Writer
 }
 
 var buildInfo = prometheus.NewGaugeVec(
@@ -66,7 +77,7 @@ func commandServe() *cobra.Command {
 	options := serveOptions{}
 
 	cmd := &cobra.Command{
-		Use:     "serve [flags] [config file]",
+		Use:     "serve [flags] [config file or directory]",
 		Short:   "Launch Dex",
 		Example: "dex serve config.yaml",
 		Args:    cobra.ExactArgs(1),
@@ -75,8 +86,14 @@ func commandServe() *cobra.Command {
 			cmd.SilenceErrors = true
 
 			options.config = args[0]
+			options.out = cmd.OutOrStdout()
 
-			return runServe(options)
+			err := runServe(options)
+			if err != nil && options.dryRun {
+				fmt.Fprintf(options.out, "FAIL  %v\n", err)
+				return errors.New("dry run failed")
+			}
+			return err
 		},
 	}
 
@@ -86,34 +103,74 @@ func commandServe() *cobra.Command {
 	flags.StringVar(&options.webHTTPSAddr, "web-https-addr", "", "Web HTTPS address")
 	flags.StringVar(&options.telemetryAddr, "telemetry-addr", "", "Telemetry address")
 	flags.StringVar(&options.grpcAddr, "grpc-addr", "", "gRPC API address")
+	flags.BoolVar(&options.watchConfig, "watch-config", false, "Reload the config when its files change, in addition to on SIGHUP")
+	flags.BoolVar(&options.dryRun, "dry-run", false, "Initialize everything without writing to the storage or listening, report and exit")
+	flags.BoolVar(&options.skipNetwork, "skip-network", false, "With --dry-run, open connectors without contacting their upstream identity providers")
 
 	return cmd
 }
 
-func runServe(options serveOptions) error {
-	configFile := options.config
-	configData, err := os.ReadFile(configFile)
+// loadConfig reads and decodes the config file, or directory of config files,
+// without validating it.
+func loadConfig(configFile string) (Config, error) {
+	var c Config
+
+	jsonConfigData, err := readConfig(configFile)
 	if err != nil {
-		return fmt.Errorf("failed to read config file %s: %v", configFile, err)
+		return c, err
 	}
 
-	var c Config
+	if featureflags.ExpandEnv.Enabled() {
+		if jsonConfigData, err = expandConfig(jsonConfigData); err != nil {
+			return c, fmt.Errorf("error expanding config file %s: %v", configFile, err)
+		}
+	}
+
+	if err := configUnmarshaller(jsonConfigData, &c); err != nil {
+		return c, fmt.Errorf("error unmarshalling config file %s: %v", configFile, err)
+	}
+	return c, nil
+}
 
-	jsonConfigData, err := yaml.YAMLToJSON(configData)
+// loadIssuerConfig loads and validates the config for commands operating on
+// the storage of an issuer: the main one, or the named tenant.
+func loadIssuerConfig(configFile, tenant string) (Config, *slog.Logger, error) {
+	c, err := loadConfig(configFile)
+	if err != nil {
+		return c, nil, err
+	}
+	if err := c.Validate(); err != nil {
+		return c, nil, err
+	}
+	logger, _, err := newLogger(c.Logger)
 	if err != nil {
-		return fmt.Errorf("error parse config file %s: %v", configFile, err)
+		return c, nil, fmt.Errorf("invalid config: %v", err)
+	}
+	warnUnsetEnvVars(logger)
+	if tenant == "" {
+		return c, logger, nil
 	}
+	for _, t := range c.Tenants {
+		if t.Name == tenant {
+			return t.config(c), logger.With("tenant", t.Name), nil
+		}
+	}
+	return c, nil, fmt.Errorf("unknown tenant %q", tenant)
+}
 
-	if err := configUnmarshaller(jsonConfigData, &c); err != nil {
-		return fmt.Errorf("error unmarshalling config file %s: %v", configFile, err)
+func runServe(options serveOptions) error {
+	c, err := loadConfig(options.config)
+	if err != nil {
+		return err
 	}
 
 	applyConfigOverrides(options, &c)
 
-	logger, err := newLogger(c.Logger.Level, c.Logger.Format, c.Logger.ExcludeFields)
+	logger, logLevels, err := newLogger(c.Logger)
 	if err != nil {
 		return fmt.Errorf("invalid config: %v", err)
 	}
+	warnUnsetEnvVars(logger)
 
 	logger.Info(
 		"Version info",
@@ -128,9 +185,19 @@ func runServe(options serveOptions) error {
 	if c.Logger.Level != slog.LevelInfo {
 		logger.Info("config using log level", "level", c.Logger.Level)
 	}
+	for module, level := range c.Logger.Levels {
+		logger.Info("config using module log level", "module", module, "level", level)
+	}
 	if err := c.Validate(); err != nil {
 		return err
 	}
+	loaded := c
+
+	var dry *dryRun
+	if options.dryRun {
+		dry = &dryRun{out: options.out, skipNetwork: options.skipNetwork}
+		dry.ok("config")
+	}
 
 	logger.Info("config issuer", "issuer", c.Issuer)
 
@@ -150,22 +217,51 @@ func runServe(options serveOptions) error {
 	}
 
 	grpcMetrics := grpcprometheus.NewServerMetrics()
+	grpcMetrics.EnableHandlingTimeHistogram()
 	err = prometheusRegistry.Register(grpcMetrics)
 	if err != nil {
 		return fmt.Errorf("failed to register gRPC server metrics: %v", err)
 	}
 
-	var grpcOptions []grpc.ServerOption
+	var (
+		grpcOptions   []grpc.ServerOption
+		grpcTLSConfig *tls.Config
+		// Every API call is measured; the metrics interceptors come first so
+		// denied and timed out calls are counted too.
+		grpcUnary  = []grpc.UnaryServerInterceptor{grpcMetrics.UnaryServerInterceptor()}
+		grpcStream = []grpc.StreamServerInterceptor{grpcMetrics.StreamServerInterceptor()}
+		// apiInterceptors are shared by the gRPC API and its JSON facade.
+		apiInterceptors []grpc.UnaryServerInterceptor
+	)
 
-	allowedTLSCiphers := []uint16{
-		tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256,
-		tls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384,
-		tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256,
-		tls.TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384,
-		tls.TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305,
-		tls.TLS_ECDHE_ECDSA_WITH_CHACHA20_POLY1305,
-		tls.TLS_RSA_WITH_AES_128_GCM_SHA256,
-		tls.TLS_RSA_WITH_AES_256_GCM_SHA384,
+	if c.GRPC.Authorization != nil {
+		authorizer, err := newAPIAuthorizer(*c.GRPC.Authorization, logger.With("component", "api"))
+		if err != nil {
+			return fmt.Errorf("invalid config: grpc authorization: %v", err)
+		}
+		apiInterceptors = append(apiInterceptors, authorizer.unaryInterceptor())
+		grpcStream = append(grpcStream, authorizer.streamInterceptor())
+		logger.Info("config grpc authorization", "roles", len(c.GRPC.Authorization.Roles), "methods", len(c.GRPC.Authorization.Methods))
+	}
+
+	timeouts, err := parseHTTPTimeouts(c.Web.Timeouts)
+	if err != nil {
+		return fmt.Errorf("invalid web timeouts config: %v", err)
+	}
+	requestLimits, apiLimits, err := parseEndpointLimits(c.Web.Limits)
+	if err != nil {
+		return fmt.Errorf("invalid web limits config: %v", err)
+	}
+	if apiLimits.Timeout > 0 {
+		apiInterceptors = append(apiInterceptors, grpcTimeoutInterceptor(apiLimits.Timeout))
+	}
+	grpcUnary = append(grpcUnary, apiInterceptors...)
+	grpcOptions = append(grpcOptions,
+		grpc.ChainUnaryInterceptor(grpcUnary...),
+		grpc.ChainStreamInterceptor(grpcStream...),
+	)
+	if apiLimits.MaxBodySize > 0 {
+		grpcOptions = append(grpcOptions, grpc.MaxRecvMsgSize(int(apiLimits.MaxBodySize)))
 	}
 
 	allowedTLSVersions := map[string]int{
@@ -182,10 +278,14 @@ func runServe(options serveOptions) error {
 		if c.GRPC.TLSMaxVersion != "" {
 			tlsMaxVersion = allowedTLSVersions[c.GRPC.TLSMaxVersion]
 		}
+		cipherSuites, err := parseTLSCipherSuites(c.GRPC.TLSCipherSuites)
+		if err != nil {
+			return fmt.Errorf("invalid config: gRPC TLS: %v", err)
+		}
 		baseTLSConfig := &tls.Config{
 			MinVersion:               uint16(tlsMinVersion),
 			MaxVersion:               uint16(tlsMaxVersion),
-			CipherSuites:             allowedTLSCiphers,
+			CipherSuites:             cipherSuites,
 			PreferServerCipherSuites: true,
 		}
 
@@ -194,18 +294,11 @@ func runServe(options serveOptions) error {
 			return fmt.Errorf("invalid config: get gRPC TLS: %v", err)
 		}
 
-		if c.GRPC.TLSClientCA != "" {
-			// Only add metrics if client auth is enabled
-			grpcOptions = append(grpcOptions,
-				grpc.StreamInterceptor(grpcMetrics.StreamServerInterceptor()),
-				grpc.UnaryInterceptor(grpcMetrics.UnaryServerInterceptor()),
-			)
-		}
-
 		grpcOptions = append(grpcOptions, grpc.Creds(credentials.NewTLS(tlsConfig)))
+		grpcTLSConfig = tlsConfig
 	}
 
-	s, err := c.Storage.Config.Open(logger)
+	s, err := dry.openStorage(c.Storage, logger.With("component", "storage"))
 	if err != nil {
 		return fmt.Errorf("failed to initialize storage: %v", err)
 	}
@@ -213,74 +306,48 @@ func runServe(options serveOptions) error {
 
 	logger.Info("config storage", "storage_type", c.Storage.Type)
 
-	if len(c.StaticClients) > 0 {
-		for i, client := range c.StaticClients {
-			if client.Name == "" {
-				return fmt.Errorf("invalid config: Name field is required for a client")
-			}
-			if client.ID == "" && client.IDEnv == "" {
-				return fmt.Errorf("invalid config: ID or IDEnv field is required for a client")
-			}
-			if client.IDEnv != "" {
-				if client.ID != "" {
-					return fmt.Errorf("invalid config: ID and IDEnv fields are exclusive for client %q", client.ID)
-				}
-				c.StaticClients[i].ID = os.Getenv(client.IDEnv)
-			}
-			if client.Secret == "" && client.SecretEnv == "" && !client.Public {
-				return fmt.Errorf("invalid config: Secret or SecretEnv field is required for client %q", client.ID)
-			}
-			if client.SecretEnv != "" {
-				if client.Secret != "" {
-					return fmt.Errorf("invalid config: Secret and SecretEnv fields are exclusive for client %q", client.ID)
-				}
-				c.StaticClients[i].Secret = os.Getenv(client.SecretEnv)
-			}
-			logger.Info("config static client", "client_name", client.Name)
+	// Checked before the storage is wrapped, which hides optional interfaces.
+	var rateLimitStorage storage.RateLimitStorage
+	if c.RateLimits.Store == rateLimitStoreStorage {
+		rs, ok := s.(storage.RateLimitStorage)
+		if !ok {
+			return fmt.Errorf("invalid config: storage type %q cannot store rate limits", c.Storage.Type)
 		}
-		s = storage.WithStaticClients(s, c.StaticClients)
+		rateLimitStorage = rs
 	}
-	if len(c.StaticPasswords) > 0 {
-		passwords := make([]storage.Password, len(c.StaticPasswords))
-		for i, p := range c.StaticPasswords {
-			passwords[i] = storage.Password(p)
+	// Connectors always keep their one-time values in the storage if it can
+	// hold them, so replays to other instances are detected.
+	replayStorage, _ := s.(storage.RateLimitStorage)
+	var offlineSessionsStorage storage.OfflineSessionsStorage
+	if c.Expiry.OfflineSessions != nil {
+		ps, ok := s.(storage.OfflineSessionsStorage)
+		if !ok {
+			return fmt.Errorf("invalid config: storage type %q cannot prune offline sessions", c.Storage.Type)
 		}
-		s = storage.WithStaticPasswords(s, passwords, logger)
+		offlineSessionsStorage = ps
 	}
-
-	storageConnectors := make([]storage.Connector, len(c.StaticConnectors))
-	for i, c := range c.StaticConnectors {
-		if c.ID == "" || c.Name == "" || c.Type == "" {
-			return fmt.Errorf("invalid config: ID, Type and Name fields are required for a connector")
-		}
-		if c.Config == nil {
-			return fmt.Errorf("invalid config: no config field for connector %q", c.ID)
-		}
-		for _, gt := range c.GrantTypes {
-			if !server.ConnectorGrantTypes[gt] {
-				return fmt.Errorf("invalid config: unknown grant type %q for connector %q", gt, c.ID)
-			}
-		}
-		logger.Info("config connector", "connector_id", c.ID)
-
-		// convert to a storage connector object
-		conn, err := ToStorageConnector(c)
-		if err != nil {
-			return fmt.Errorf("failed to initialize storage connectors: %v", err)
+	var auditStorage storage.AuditStorage
+	if c.AuditLog != nil {
+		as, ok := s.(storage.AuditStorage)
+		if !ok {
+			return fmt.Errorf("invalid config: storage type %q cannot store audit events", c.Storage.Type)
 		}
-		storageConnectors[i] = conn
+		auditStorage = as
 	}
 
-	if c.EnablePasswordDB {
-		storageConnectors = append(storageConnectors, storage.Connector{
-			ID:   server.LocalConnector,
-			Name: "Email",
-			Type: server.LocalConnector,
-		})
-		logger.Info("config connector: local passwords enabled")
+	staticClients, err := buildStaticClients(c, logger)
+	if err != nil {
+		return fmt.Errorf("invalid config: %v", err)
 	}
-
-	s = storage.WithStaticConnectors(s, storageConnectors)
+	staticConnectors, err := buildStaticConnectors(c, logger)
+	if err != nil {
+		return err
+	}
+	staticConnectors, err = dry.connectors(staticConnectors, logger.With("component", "connector"))
+	if err != nil {
+		return err
+	}
+	s, staticObjects := storage.WithStaticObjects(s, staticClients, buildStaticPasswords(c), staticConnectors, logger)
 
 	if len(c.OAuth2.ResponseTypes) > 0 {
 		logger.Info("config response types accepted", "response_types", c.OAuth2.ResponseTypes)
@@ -364,6 +431,7 @@ func runServe(options serveOptions) error {
 	default:
 		return fmt.Errorf("unknown signer type %q", c.Signer.Type)
 	}
+	dry.ok("signer")
 
 	serverConfig := server.Config{
 		AllowedGrantTypes:      c.OAuth2.GrantTypes,
@@ -382,6 +450,7 @@ func runServe(options serveOptions) error {
 		Storage:                    s,
 		Web:                        c.Frontend,
 		Logger:                     logger,
+		LogLevels:                  logLevels,
 		Now:                        now,
 		PrometheusRegistry:         prometheusRegistry,
 		HealthChecker:              healthChecker,
@@ -390,6 +459,16 @@ func runServe(options serveOptions) error {
 		IDTokensValidFor:           idTokensValidFor,
 		MFAProviders:               buildMFAProviders(c.MFA.Authenticators, c.Issuer, logger),
 		DefaultMFAChain:            c.MFA.DefaultMFAChain,
+
+		TokenExchangeRequiresPolicy: c.OAuth2.TokenExchangeRequiresPolicy,
+		RelayStateKey:               []byte(c.OAuth2.RelayStateKey),
+		GroupsClaim:                 c.OAuth2.GroupsClaim,
+		RequestLimits:               requestLimits,
+		DiscoveryOverrides:          c.DiscoveryOverrides,
+	}
+	if dry != nil {
+		// A dry run fails on connectors failing to open.
+		serverConfig.ContinueOnConnectorFailure = false
 	}
 
 	if c.Expiry.AuthRequests != "" {
@@ -421,6 +500,19 @@ func runServe(options serveOptions) error {
 
 	serverConfig.RefreshTokenPolicy = refreshTokenPolicy
 
+	if c.Expiry.OfflineSessions != nil {
+		offlineSessionPolicy, err := server.NewOfflineSessionPolicy(
+			logger,
+			c.Expiry.OfflineSessions.ValidIfNotUsedFor,
+			c.Expiry.OfflineSessions.MaxPerUserClient,
+		)
+		if err != nil {
+			return fmt.Errorf("invalid offline session retention policy config: %v", err)
+		}
+		offlineSessionPolicy.Storage = offlineSessionsStorage
+		serverConfig.OfflineSessionPolicy = offlineSessionPolicy
+	}
+
 	if featureflags.SessionsEnabled.Enabled() {
 		sessionConfig, err := parseSessionConfig(c.Sessions)
 		if err != nil {
@@ -434,16 +526,200 @@ func runServe(options serveOptions) error {
 		)
 	}
 
+	maintenance, err := parseMaintenance(c.Maintenance)
+	if err != nil {
+		return fmt.Errorf("invalid config: %v", err)
+	}
+	if maintenance.Enabled {
+		logger.Warn("maintenance mode enabled, new logins are refused", "retry_after", maintenance.RetryAfter)
+	}
+	serverConfig.Maintenance = maintenance
+
+	if c.LoginThrottle != nil {
+		loginThrottle, err := parseLoginThrottle(*c.LoginThrottle)
+		if err != nil {
+			return fmt.Errorf("invalid config: %v", err)
+		}
+		serverConfig.LoginThrottle = loginThrottle
+		logger.Info("config login throttle",
+			"free_attempts", c.LoginThrottle.FreeAttempts,
+			"block_after", c.LoginThrottle.BlockAfter,
+			"allowlist", c.LoginThrottle.Allowlist,
+		)
+	}
+
+	if c.Captcha != nil {
+		captcha, err := parseCaptcha(*c.Captcha)
+		if err != nil {
+			return fmt.Errorf("invalid config: %v", err)
+		}
+		serverConfig.Captcha = captcha
+		logger.Info("config captcha", "provider", c.Captcha.Provider, "after_failures", c.Captcha.AfterFailures)
+	}
+
+	if c.Risk != nil {
+		risk, err := parseRisk(*c.Risk)
+		if err != nil {
+			return fmt.Errorf("invalid config: %v", err)
+		}
+		serverConfig.Risk = risk
+		logger.Info("config risk engine", "url", c.Risk.URL, "fail_open", c.Risk.FailOpen)
+	}
+
+	if replayStorage != nil {
+		serverConfig.ReplayStorage = replayStorage
+	}
+	if rateLimitStorage != nil {
+		serverConfig.RateLimiter = server.NewStorageRateLimiter(rateLimitStorage, now)
+		serverConfig.RateLimitStorage = rateLimitStorage
+		logger.Info("config rate limits", "store", c.RateLimits.Store)
+	}
+
+	if c.RateLimits.enabled() {
+		serverConfig.RateLimit = &server.RateLimitConfig{
+			Global:    server.RateLimit(c.RateLimits.Global),
+			PerIP:     server.RateLimit(c.RateLimits.PerIP),
+			PerClient: server.RateLimit(c.RateLimits.PerClient),
+		}
+		logger.Info("config rate limits",
+			"global", c.RateLimits.Global,
+			"per_ip", c.RateLimits.PerIP,
+			"per_client", c.RateLimits.PerClient,
+		)
+	}
+
+	if c.PasswordReset != nil {
+		resetConfig, err := parsePasswordResetConfig(c.PasswordReset, logger)
+		if err != nil {
+			return fmt.Errorf("invalid password reset config: %v", err)
+		}
+		serverConfig.PasswordReset = resetConfig
+		logger.Info("config password reset",
+			"mailer", c.PasswordReset.Mailer.Type,
+			"token_valid_for", resetConfig.TokenValidFor,
+		)
+	}
+
+	if c.EmailVerification != nil {
+		verificationConfig, err := parseEmailVerificationConfig(c.EmailVerification, logger)
+		if err != nil {
+			return fmt.Errorf("invalid email verification config: %v", err)
+		}
+		serverConfig.EmailVerification = verificationConfig
+		logger.Info("config email verification",
+			"mailer", c.EmailVerification.Mailer.Type,
+			"token_valid_for", verificationConfig.TokenValidFor,
+		)
+	}
+
+	if c.Registration != nil {
+		serverConfig.Registration = &server.RegistrationConfig{
+			AllowedEmailDomains: c.Registration.AllowedEmailDomains,
+			InviteCodes:         c.Registration.InviteCodes,
+			VerifyEmail:         c.Registration.VerifyEmail,
+			RateLimit:           server.RateLimit(c.Registration.RateLimit),
+		}
+		logger.Info("config registration",
+			"allowed_email_domains", c.Registration.AllowedEmailDomains,
+			"invite_codes", len(c.Registration.InviteCodes) > 0,
+			"verify_email", c.Registration.VerifyEmail,
+		)
+	}
+
+	upstreamHTTP, err := parseUpstreamHTTP(c.UpstreamHTTP)
+	if err != nil {
+		return fmt.Errorf("invalid upstreamHTTP config: %v", err)
+	}
+	serverConfig.UpstreamHTTP = upstreamHTTP
+
+	if c.Events != nil {
+		queue, err := openEvents(c.Events, logger)
+		if err != nil {
+			return fmt.Errorf("invalid events config: %v", err)
+		}
+		// Deliver the events still queued once the servers are done.
+		defer queue.Close()
+		serverConfig.Events = queue
+		sinkTypes := make([]string, len(c.Events.Sinks))
+		for i, sink := range c.Events.Sinks {
+			sinkTypes[i] = sink.Type
+		}
+		logger.Info("config events", "sinks", sinkTypes)
+	}
+
+	serverConfig.CORS, err = parseCORS(c.Web.CORS)
+	if err != nil {
+		return fmt.Errorf("invalid web cors config: %v", err)
+	}
+
+	if auditStorage != nil {
+		retention := 30 * 24 * time.Hour
+		if c.AuditLog.Retention != "" {
+			retention, err = time.ParseDuration(c.AuditLog.Retention)
+			if err != nil {
+				return fmt.Errorf("invalid config value %q for audit log retention: %v", c.AuditLog.Retention, err)
+			}
+			if retention <= 0 {
+				return fmt.Errorf("audit log retention must be positive, got %v", retention)
+			}
+		}
+		serverConfig.AuditLog = &server.AuditLogConfig{Storage: auditStorage, Retention: retention}
+		logger.Info("config audit log", "retention", retention)
+	}
+
 	serverConfig.RealIPHeader = c.Web.ClientRemoteIP.Header
 	serverConfig.TrustedRealIPCIDRs, err = c.Web.ClientRemoteIP.ParseTrustedProxies()
 	if err != nil {
 		return fmt.Errorf("failed to parse client remote IP settings: %v", err)
 	}
+	serverConfig.TrustRealIPUnixSocket = c.Web.ClientRemoteIP.TrustUnixSocket
+	if c.Web.ClientRemoteIP.Header != "" {
+		if len(serverConfig.TrustedRealIPCIDRs) == 0 && !serverConfig.TrustRealIPUnixSocket {
+			logger.Warn("client remote IP header is ignored, set trustedProxies or trustUnixSocket to honor it", "header", c.Web.ClientRemoteIP.Header)
+		}
+		logger.Info("config client remote IP",
+			"header", c.Web.ClientRemoteIP.Header,
+			"trusted_proxies", c.Web.ClientRemoteIP.TrustedProxies,
+			"trust_unix_socket", c.Web.ClientRemoteIP.TrustUnixSocket,
+		)
+	}
+
+	if len(c.Tenants) > 0 {
+		// Tenant servers label their metrics with their tenant, so the metrics
+		// of the main server need the label too.
+		serverConfig.PrometheusRegistry = prometheus.WrapRegistererWith(prometheus.Labels{"tenant": ""}, prometheusRegistry)
+	}
+
+	var drainTimeout time.Duration
+	if c.Web.DrainTimeout != "" {
+		drainTimeout, err = time.ParseDuration(c.Web.DrainTimeout)
+		if err != nil {
+			return fmt.Errorf("invalid config value %q for web drain timeout: %v", c.Web.DrainTimeout, err)
+		}
+		logger.Info("config web", "drain_timeout", drainTimeout)
+	}
+	serverConfig.DrainTimeout = drainTimeout
 
 	serv, err := server.NewServer(context.Background(), serverConfig)
 	if err != nil {
 		return fmt.Errorf("failed to initialize server: %v", err)
 	}
+	// Deferred first, so connectors are closed after the listeners.
+	defer serv.CloseConnectors()
+	dry.ok("server")
+
+	var webHandler http.Handler = serv
+	if len(c.Tenants) > 0 {
+		tenants, closeTenants, err := openTenants(c, serverConfig, prometheusRegistry, logger, dry)
+		if err != nil {
+			return err
+		}
+		defer closeTenants()
+		webHandler, err = newTenantRouter(c.Issuer, serv, tenants)
+		if err != nil {
+			return err
+		}
+	}
 
 	telemetryRouter := http.NewServeMux()
 	telemetryRouter.Handle("/metrics", promhttp.HandlerFor(prometheusRegistry, promhttp.HandlerOpts{}))
@@ -457,7 +733,21 @@ func runServe(options serveOptions) error {
 		telemetryRouter.HandleFunc("/healthz/live", func(w http.ResponseWriter, _ *http.Request) {
 			_, _ = w.Write([]byte("ok"))
 		})
-		telemetryRouter.Handle("/healthz/ready", handler)
+		telemetryRouter.HandleFunc("/healthz/ready", func(w http.ResponseWriter, r *http.Request) {
+			if serv.Draining() {
+				http.Error(w, "draining", http.StatusServiceUnavailable)
+				return
+			}
+			handler.ServeHTTP(w, r)
+		})
+	}
+
+	readinessInterval := 15 * time.Second
+	if c.Telemetry.Readiness.CheckInterval != "" {
+		readinessInterval, err = time.ParseDuration(c.Telemetry.Readiness.CheckInterval)
+		if err != nil {
+			return fmt.Errorf("invalid config value %q for readiness check interval: %v", c.Telemetry.Readiness.CheckInterval, err)
+		}
 	}
 
 	healthChecker.RegisterCheck(
@@ -465,11 +755,71 @@ func runServe(options serveOptions) error {
 			CheckName: "storage",
 			CheckFunc: storage.NewCustomHealthCheckFunc(serverConfig.Storage, serverConfig.Now),
 		},
-		gosundheit.ExecutionPeriod(15*time.Second),
+		gosundheit.ExecutionPeriod(readinessInterval),
 		gosundheit.InitiallyPassing(true),
 	)
 
-	var group run.Group
+	if c.Telemetry.Readiness.Connectors {
+		logger.Info("config readiness probe checks connectors", "interval", readinessInterval)
+		healthChecker.RegisterCheck(
+			&checks.CustomCheck{
+				CheckName: "connectors",
+				CheckFunc: serv.NewConnectorsHealthCheckFunc(),
+			},
+			gosundheit.ExecutionPeriod(readinessInterval),
+			gosundheit.InitiallyPassing(true),
+		)
+	}
+
+	var adminUI *admin.Handler
+	if c.AdminUI != nil {
+		adminUI, err = newAdminUI(c, serverConfig.Storage, logger, serv)
+		if err != nil {
+			return fmt.Errorf("invalid admin UI config: %v", err)
+		}
+		if c.AdminUI.HTTP == "" {
+			telemetryRouter.Handle(adminUI.BasePath()+"/", adminUI)
+		}
+		logger.Info("config admin UI", "url", c.AdminUI.URL, "admin_groups", c.AdminUI.AdminGroups)
+		dry.ok("admin UI")
+	}
+
+	if dry != nil {
+		// Certificates of the web server are otherwise only loaded when
+		// listening.
+		if c.Web.HTTPS != "" {
+			if _, err := loadTLSConfig(c.Web.TLSCert, c.Web.TLSKey, "", &tls.Config{}); err != nil {
+				return fmt.Errorf("invalid config: get HTTP TLS: %v", err)
+			}
+			dry.ok("web TLS")
+		}
+		fmt.Fprintln(options.out, "dry run complete")
+		return nil
+	}
+
+	var (
+		group     run.Group
+		listeners listenerSet
+	)
+
+	// Drain logins in flight before the listeners shut down. run.Group
+	// interrupts actors in the order they were added, so this comes first.
+	if drainTimeout > 0 {
+		ctx, cancel := context.WithCancel(context.Background())
+		group.Add(func() error {
+			<-ctx.Done()
+			return nil
+		}, func(err error) {
+			defer cancel()
+
+			logger.Info("draining logins in flight", "timeout", drainTimeout)
+			drainCtx, drainCancel := context.WithTimeout(context.Background(), drainTimeout)
+			defer drainCancel()
+			if err := serv.Drain(drainCtx); err != nil {
+				logger.Warn("drain incomplete", "err", err)
+			}
+		})
+	}
 
 	// Set up telemetry server
 	if c.Telemetry.HTTP != "" {
@@ -477,7 +827,7 @@ func runServe(options serveOptions) error {
 
 		logger.Info("listening on", "server", name, "address", c.Telemetry.HTTP)
 
-		l, err := net.Listen("tcp", c.Telemetry.HTTP)
+		l, err := listeners.listen(c.Telemetry.HTTP)
 		if err != nil {
 			return fmt.Errorf("listening (%s) on %s: %v", name, c.Telemetry.HTTP, err)
 		}
@@ -504,20 +854,50 @@ func runServe(options serveOptions) error {
 		})
 	}
 
+	// Set up admin UI server
+	if adminUI != nil && c.AdminUI.HTTP != "" {
+		const name = "admin"
+
+		logger.Info("listening on", "server", name, "address", c.AdminUI.HTTP)
+
+		l, err := listeners.listen(c.AdminUI.HTTP)
+		if err != nil {
+			return fmt.Errorf("listening (%s) on %s: %v", name, c.AdminUI.HTTP, err)
+		}
+
+		server := &http.Server{
+			Handler: adminUI,
+		}
+		defer server.Close()
+
+		group.Add(func() error {
+			return server.Serve(l)
+		}, func(err error) {
+			ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+			defer cancel()
+
+			logger.Debug("starting graceful shutdown", "server", name)
+			if err := server.Shutdown(ctx); err != nil {
+				logger.Error("graceful shutdown", "server", name, "err", err)
+			}
+		})
+	}
+
 	// Set up http server
 	if c.Web.HTTP != "" {
 		const name = "http"
 
 		logger.Info("listening on", "server", name, "address", c.Web.HTTP)
 
-		l, err := net.Listen("tcp", c.Web.HTTP)
+		l, err := listeners.listen(c.Web.HTTP)
 		if err != nil {
 			return fmt.Errorf("listening (%s) on %s: %v", name, c.Web.HTTP, err)
 		}
 
 		server := &http.Server{
-			Handler: serv,
+			Handler: webHandler,
 		}
+		timeouts.apply(server)
 		defer server.Close()
 
 		group.Add(func() error {
@@ -539,7 +919,7 @@ func runServe(options serveOptions) error {
 
 		logger.Info("listening on", "server", name, "address", c.Web.HTTPS)
 
-		l, err := net.Listen("tcp", c.Web.HTTPS)
+		l, err := listeners.listen(c.Web.HTTPS)
 		if err != nil {
 			return fmt.Errorf("listening (%s) on %s: %v", name, c.Web.HTTPS, err)
 		}
@@ -553,22 +933,30 @@ func runServe(options serveOptions) error {
 			tlsMaxVersion = allowedTLSVersions[c.Web.TLSMaxVersion]
 		}
 
+		cipherSuites, err := parseTLSCipherSuites(c.Web.TLSCipherSuites)
+		if err != nil {
+			return fmt.Errorf("invalid config: HTTP TLS: %v", err)
+		}
 		baseTLSConfig := &tls.Config{
 			MinVersion:               uint16(tlsMinVersion),
 			MaxVersion:               uint16(tlsMaxVersion),
-			CipherSuites:             allowedTLSCiphers,
+			CipherSuites:             cipherSuites,
 			PreferServerCipherSuites: true,
 		}
+		if c.Web.TLSClientCA != "" {
+			baseTLSConfig.ClientAuth = tls.VerifyClientCertIfGiven
+		}
 
-		tlsConfig, err := newTLSReloader(logger, c.Web.TLSCert, c.Web.TLSKey, "", baseTLSConfig)
+		tlsConfig, err := newTLSReloader(logger, c.Web.TLSCert, c.Web.TLSKey, c.Web.TLSClientCA, baseTLSConfig)
 		if err != nil {
 			return fmt.Errorf("invalid config: get HTTP TLS: %v", err)
 		}
 
 		server := &http.Server{
-			Handler:   serv,
+			Handler:   webHandler,
 			TLSConfig: tlsConfig,
 		}
+		timeouts.apply(server)
 		defer server.Close()
 
 		group.Add(func() error {
@@ -588,7 +976,7 @@ func runServe(options serveOptions) error {
 	if c.GRPC.Addr != "" {
 		logger.Info("listening on", "server", "grpc", "address", c.GRPC.Addr)
 
-		grpcListener, err := net.Listen("tcp", c.GRPC.Addr)
+		grpcListener, err := listeners.listen(c.GRPC.Addr)
 		if err != nil {
 			return fmt.Errorf("listening (grpc) on %s: %w", c.GRPC.Addr, err)
 		}
@@ -610,6 +998,63 @@ func runServe(options serveOptions) error {
 		})
 	}
 
+	// Set up the JSON facade of the gRPC API
+	if c.GRPC.HTTPAddr != "" {
+		const name = "api"
+
+		logger.Info("listening on", "server", name, "address", c.GRPC.HTTPAddr)
+
+		l, err := listeners.listen(c.GRPC.HTTPAddr)
+		if err != nil {
+			return fmt.Errorf("listening (%s) on %s: %v", name, c.GRPC.HTTPAddr, err)
+		}
+
+		handler, err := restapi.New(server.NewAPI(serverConfig.Storage, logger, version, serv), logger.With("component", "api"), apiInterceptors...)
+		if err != nil {
+			return err
+		}
+		handler = server.LimitHandler(apiLimits, handler)
+		server := &http.Server{
+			Handler:   handler,
+			TLSConfig: grpcTLSConfig,
+		}
+		timeouts.apply(server)
+		defer server.Close()
+
+		group.Add(func() error {
+			if grpcTLSConfig != nil {
+				return server.ServeTLS(l, "", "")
+			}
+			return server.Serve(l)
+		}, func(err error) {
+			ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+			defer cancel()
+
+			logger.Debug("starting graceful shutdown", "server", name)
+			if err := server.Shutdown(ctx); err != nil {
+				logger.Error("graceful shutdown", "server", name, "err", err)
+			}
+		})
+	}
+
+	// Reload static clients, connectors and frontend settings on SIGHUP and,
+	// if enabled, when the config files change.
+	{
+		reloader := &configReloader{
+			options: options,
+			logger:  logger,
+			server:  serv,
+			static:  staticObjects,
+			current: loaded,
+		}
+		ctx, cancel := context.WithCancel(context.Background())
+		group.Add(func() error {
+			return reloader.run(ctx, options.watchConfig)
+		}, func(err error) {
+			cancel()
+		})
+	}
+
 	group.Add(run.SignalHandler(context.Background(), os.Interrupt, syscall.SIGTERM))
 	if err := group.Run(); err != nil {
 		if _, ok := err.(run.SignalError); !ok {
@@ -620,6 +1065,120 @@ func runServe(options serveOptions) error {
 	return nil
 }
 
+// buildStaticClients validates the clients defined in the config and resolves
+// their IDs and secrets from the environment where requested.
+func buildStaticClients(c Config, logger *slog.Logger) ([]storage.Client, error) {
+	clients := make([]storage.Client, len(c.StaticClients))
+	for i, client := range c.StaticClients {
+		if err := validateStaticClient(client); err != nil {
+			return nil, err
+		}
+		if client.IDEnv != "" {
+			client.ID = os.Getenv(client.IDEnv)
+		}
+		if client.SecretEnv != "" {
+			client.Secret = os.Getenv(client.SecretEnv)
+		}
+		logger.Info("config static client", "client_name", client.Name)
+		clients[i] = client
+	}
+	if c.AdminUI != nil {
+		clients = append(clients, storage.Client{
+			ID:           c.AdminUI.clientID(),
+			Secret:       c.AdminUI.ClientSecret,
+			Name:         "Dex Admin",
+			RedirectURIs: []string{strings.TrimSuffix(c.AdminUI.URL, "/") + "/callback"},
+		})
+	}
+	return clients, nil
+}
+
+func buildStaticPasswords(c Config) []storage.Password {
+	passwords := make([]storage.Password, len(c.StaticPasswords))
+	for i, p := range c.StaticPasswords {
+		passwords[i] = storage.Password(p)
+	}
+	return passwords
+}
+
+// buildStaticConnectors converts the connectors defined in the config to
+// storage connectors, adding the local password connector if it is enabled.
+func buildStaticConnectors(c Config, logger *slog.Logger) ([]storage.Connector, error) {
+	connectors := make([]storage.Connector, len(c.StaticConnectors))
+	for i, c := range c.StaticConnectors {
+		if err := validateStaticConnector(c); err != nil {
+			return nil, fmt.Errorf("invalid config: %v", err)
+		}
+		logger.Info("config connector", "connector_id", c.ID)
+
+		// convert to a storage connector object
+		conn, err := ToStorageConnector(c)
+		if err != nil {
+			return nil, fmt.Errorf("failed to initialize storage connectors: %v", err)
+		}
+		connectors[i] = conn
+	}
+
+	federated, err := buildFederationConnectors(c, logger)
+	if err != nil {
+		return nil, err
+	}
+	connectors = append(connectors, federated...)
+
+	if c.EnablePasswordDB {
+		connectors = append(connectors, storage.Connector{
+			ID:   server.LocalConnector,
+			Name: "Email",
+			Type: server.LocalConnector,
+		})
+		logger.Info("config connector: local passwords enabled")
+	}
+	return connectors, nil
+}
+
+// validateStaticClient checks the fields of a client defined in the config file.
+func validateStaticClient(client storage.Client) error {
+	if client.Name == "" {
+		return fmt.Errorf("Name field is required for a client")
+	}
+	if client.ID == "" && client.IDEnv == "" {
+		return fmt.Errorf("ID or IDEnv field is required for a client")
+	}
+	if client.IDEnv != "" && client.ID != "" {
+		return fmt.Errorf("ID and IDEnv fields are exclusive for client %q", client.ID)
+	}
+	if client.Secret == "" && client.SecretEnv == "" && !client.Public {
+		return fmt.Errorf("Secret or SecretEnv field is required for client %q", client.ID)
+	}
+	if client.SecretEnv != "" && client.Secret != "" {
+		return fmt.Errorf("Secret and SecretEnv fields are exclusive for client %q", client.ID)
+	}
+	if client.Theme != nil {
+		for _, link := range client.Theme.FooterLinks {
+			if link.Text == "" || link.URL == "" {
+				return fmt.Errorf("theme footer links of client %q need both text and url", client.ID)
+			}
+		}
+	}
+	return nil
+}
+
+// validateStaticConnector checks the fields of a connector defined in the config file.
+func validateStaticConnector(c Connector) error {
+	if c.ID == "" || c.Name == "" || c.Type == "" {
+		return fmt.Errorf("ID, Type and Name fields are required for a connector")
+	}
+	if c.Config == nil {
+		return fmt.Errorf("no config field for connector %q", c.ID)
+	}
+	for _, gt := range c.GrantTypes {
+		if !server.ConnectorGrantTypes[gt] {
+			return fmt.Errorf("unknown grant type %q for connector %q", gt, c.ID)
+		}
+	}
+	return nil
+}
+
 func applyConfigOverrides(options serveOptions, config *Config) {
 	if options.webHTTPAddr != "" {
 		config.Web.HTTP = options.webHTTPAddr
@@ -649,6 +1208,7 @@ func applyConfigOverrides(options serveOptions, config *Config) {
 			"refresh_token",
 			"urn:ietf:params:oauth:grant-type:device_code",
 			"urn:ietf:params:oauth:grant-type:token-exchange",
+			"urn:ietf:params:oauth:grant-type:jwt-bearer",
 		}
 		if featureflags.ClientCredentialGrantEnabledByDefault.Enabled() {
 			config.OAuth2.GrantTypes = append(config.OAuth2.GrantTypes, "client_credentials")
@@ -722,7 +1282,12 @@ func newTLSReloader(logger *slog.Logger, certFile, keyFile, caFile string, baseC
 			case sig := <-sigc:
 				logger.Debug("reloading cert from signal", "signal", sig)
 			case evt := <-watcher.Events:
-				if _, ok := watchFiles[evt.Name]; !ok || !evt.Has(fsnotify.Create) {
+				if !evt.Has(fsnotify.Create) {
+					continue loop
+				}
+				// Kubernetes updates mounted secrets by swapping the ..data
+				// symlink, which leaves the file names untouched.
+				if _, ok := watchFiles[evt.Name]; !ok && filepath.Base(evt.Name) != "..data" {
 					continue loop
 				}
 				logger.Debug("reloading cert from fsnotify", "event", evt.Name, "operation", evt.Op.String())
@@ -732,7 +1297,9 @@ func newTLSReloader(logger *slog.Logger, certFile, keyFile, caFile string, baseC
 
 			loaded, err := loadTLSConfig(certFile, keyFile, caFile, baseConfig)
 			if err != nil {
+				// Keep serving the previous certificate.
 				logger.Error("reload TLS config", "err", err)
+				continue loop
 			}
 			ptr.Store(loaded)
 		}
@@ -741,7 +1308,7 @@ func newTLSReloader(logger *slog.Logger, certFile, keyFile, caFile string, baseC
 	// https://pkg.go.dev/crypto/tls#baseConfig
 	// Server configurations must set one of Certificates, GetCertificate or GetConfigForClient.
 	if caFile != "" {
-		// grpc will use this via tls.Server for mTLS
+		// grpc and the HTTPS listener with a client CA use this via tls.Server for mTLS
 		initialConfig.GetConfigForClient = func(chi *tls.ClientHelloInfo) (*tls.Config, error) { return ptr.Load(), nil }
 	} else {
 		// net/http only uses Certificates or GetCertificate
@@ -750,6 +1317,45 @@ func newTLSReloader(logger *slog.Logger, certFile, keyFile, caFile string, baseC
 	return initialConfig, nil
 }
 
+// defaultTLSCipherSuites are the TLS 1.2 cipher suites used unless configured.
+var defaultTLSCipherSuites = []uint16{
+	tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256,
+	tls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384,
+	tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256,
+	tls.TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384,
+	tls.TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305,
+	tls.TLS_ECDHE_ECDSA_WITH_CHACHA20_POLY1305,
+	tls.TLS_RSA_WITH_AES_128_GCM_SHA256,
+	tls.TLS_RSA_WITH_AES_256_GCM_SHA384,
+}
+
+// parseTLSCipherSuites resolves cipher suite names, e.g.
+// TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256, to their IDs. Only the secure TLS 1.2
+// suites of crypto/tls are accepted; TLS 1.3 suites are not configurable.
+func parseTLSCipherSuites(names []string) ([]uint16, error) {
+	if len(names) == 0 {
+		return defaultTLSCipherSuites, nil
+	}
+
+	secure := make(map[string]*tls.CipherSuite)
+	for _, suite := range tls.CipherSuites() {
+		secure[suite.Name] = suite
+	}
+
+	ids := make([]uint16, 0, len(names))
+	for _, name := range names {
+		suite, ok := secure[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown or insecure TLS cipher suite %q", name)
+		}
+		if !slices.Contains(suite.SupportedVersions, tls.VersionTLS12) {
+			return nil, fmt.Errorf("TLS cipher suite %q cannot be configured, TLS 1.3 suites are always enabled", name)
+		}
+		ids = append(ids, suite.ID)
+	}
+	return ids, nil
+}
+
 // loadTLSConfig loads the given file paths into a [tls.Config]
 func loadTLSConfig(certFile, keyFile, caFile string, baseConfig *tls.Config) (*tls.Config, error) {
 	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
@@ -768,7 +1374,9 @@ func loadTLSConfig(certFile, keyFile, caFile string, baseConfig *tls.Config) (*t
 			return nil, errors.New("failed to parse client CA")
 		}
 
-		loadedConfig.ClientAuth = tls.RequireAndVerifyClientCert
+		if loadedConfig.ClientAuth == tls.NoClientCert {
+			loadedConfig.ClientAuth = tls.RequireAndVerifyClientCert
+		}
 		loadedConfig.ClientCAs = cPool
 	}
 	return loadedConfig, nil
@@ -835,6 +1443,245 @@ func parseSessionConfig(s *Sessions) (*server.SessionConfig, error) {
 	return sc, nil
 }
 
+// newAdminUI creates the admin UI. It performs admin actions through the gRPC
+// API implementation.
+func newAdminUI(c Config, s storage.Storage, logger *slog.Logger, serv *server.Server) (*admin.Handler, error) {
+	ac := admin.Config{
+		API:          server.NewAPI(s, logger, version, serv),
+		Issuer:       c.Issuer,
+		URL:          c.AdminUI.URL,
+		ClientID:     c.AdminUI.clientID(),
+		ClientSecret: c.AdminUI.ClientSecret,
+		AdminGroups:  c.AdminUI.AdminGroups,
+		SessionKey:   []byte(c.AdminUI.SessionKey),
+		Logger:       logger.With("component", "admin"),
+	}
+	if c.AdminUI.SessionValidFor != "" {
+		d, err := time.ParseDuration(c.AdminUI.SessionValidFor)
+		if err != nil {
+			return nil, fmt.Errorf("invalid sessionValidFor %q: %v", c.AdminUI.SessionValidFor, err)
+		}
+		if d <= 0 {
+			return nil, fmt.Errorf("sessionValidFor must be positive, got %v", d)
+		}
+		ac.SessionValidFor = d
+	}
+	return admin.New(ac)
+}
+
+func parsePasswordResetConfig(c *PasswordReset, logger *slog.Logger) (*server.PasswordResetConfig, error) {
+	m, err := c.Mailer.Config.Open(logger)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open mailer: %v", err)
+	}
+	rc := &server.PasswordResetConfig{
+		Mailer:        m,
+		Key:           []byte(c.Key),
+		TokenValidFor: time.Hour,
+		RateLimit:     server.RateLimit(c.RateLimit),
+	}
+	if c.TokenValidFor != "" {
+		d, err := time.ParseDuration(c.TokenValidFor)
+		if err != nil {
+			return nil, fmt.Errorf("invalid tokenValidFor %q: %v", c.TokenValidFor, err)
+		}
+		if d <= 0 {
+			return nil, fmt.Errorf("tokenValidFor must be positive, got %v", d)
+		}
+		rc.TokenValidFor = d
+	}
+	return rc, nil
+}
+
+func parseEmailVerificationConfig(c *EmailVerification, logger *slog.Logger) (*server.EmailVerificationConfig, error) {
+	m, err := c.Mailer.Config.Open(logger)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open mailer: %v", err)
+	}
+	vc := &server.EmailVerificationConfig{
+		Mailer:        m,
+		Key:           []byte(c.Key),
+		TokenValidFor: 24 * time.Hour,
+	}
+	if c.TokenValidFor != "" {
+		d, err := time.ParseDuration(c.TokenValidFor)
+		if err != nil {
+			return nil, fmt.Errorf("invalid tokenValidFor %q: %v", c.TokenValidFor, err)
+		}
+		if d <= 0 {
+			return nil, fmt.Errorf("tokenValidFor must be positive, got %v", d)
+		}
+		vc.TokenValidFor = d
+	}
+	return vc, nil
+}
+
+func parseUpstreamHTTP(c UpstreamHTTP) (httpclient.FactoryConfig, error) {
+	fc := httpclient.FactoryConfig{
+		MaxIdleConnsPerHost: c.MaxIdleConnsPerHost,
+		MaxConnsPerHost:     c.MaxConnsPerHost,
+		TLSSessionCacheSize: c.TLSSessionCacheSize,
+		Proxy:               c.Proxy,
+
+		MaxConcurrentRequests:         c.MaxConcurrentRequests,
+		MaxConcurrentRequestsByClient: c.MaxConcurrentRequestsByConnector,
+	}
+	for _, d := range []struct {
+		name  string
+		value string
+		dst   *time.Duration
+	}{
+		{"idleConnTimeout", c.IdleConnTimeout, &fc.IdleConnTimeout},
+		{"timeout", c.Timeout, &fc.Timeout},
+		{"queueTimeout", c.QueueTimeout, &fc.QueueTimeout},
+	} {
+		if d.value == "" {
+			continue
+		}
+		v, err := time.ParseDuration(d.value)
+		if err != nil {
+			return fc, fmt.Errorf("invalid %s %q: %v", d.name, d.value, err)
+		}
+		if v <= 0 {
+			return fc, fmt.Errorf("%s must be positive, got %v", d.name, v)
+		}
+		*d.dst = v
+	}
+	return fc, nil
+}
+
+// httpTimeouts are the parsed Web.Timeouts.
+type httpTimeouts struct {
+	readHeader, read, write, idle time.Duration
+}
+
+func (t httpTimeouts) apply(s *http.Server) {
+	s.ReadHeaderTimeout = t.readHeader
+	s.ReadTimeout = t.read
+	s.WriteTimeout = t.write
+	s.IdleTimeout = t.idle
+}
+
+func parseHTTPTimeouts(c Timeouts) (httpTimeouts, error) {
+	var t httpTimeouts
+	for _, d := range []struct {
+		name  string
+		value string
+		dst   *time.Duration
+	}{
+		{"readHeader", c.ReadHeader, &t.readHeader},
+		{"read", c.Read, &t.read},
+		{"write", c.Write, &t.write},
+		{"idle", c.Idle, &t.idle},
+	} {
+		if d.value == "" {
+			continue
+		}
+		v, err := time.ParseDuration(d.value)
+		if err != nil {
+			return t, fmt.Errorf("invalid %s timeout %q: %v", d.name, d.value, err)
+		}
+		if v <= 0 {
+			return t, fmt.Errorf("%s timeout must be positive, got %v", d.name, v)
+		}
+		*d.dst = v
+	}
+	return t, nil
+}
+
+func parseEndpointLimits(c EndpointLimits) (server.RequestLimits, server.EndpointLimits, error) {
+	var (
+		limits server.RequestLimits
+		api    server.EndpointLimits
+	)
+	for _, l := range []struct {
+		name  string
+		value RequestLimit
+		dst   *server.EndpointLimits
+	}{
+		{"auth", c.Auth, &limits.Auth},
+		{"token", c.Token, &limits.Token},
+		{"device", c.Device, &limits.Device},
+		{"api", c.API, &api},
+	} {
+		l.dst.MaxBodySize = l.value.MaxBodySize
+		if l.value.Timeout == "" {
+			continue
+		}
+		v, err := time.ParseDuration(l.value.Timeout)
+		if err != nil {
+			return limits, api, fmt.Errorf("invalid %s timeout %q: %v", l.name, l.value.Timeout, err)
+		}
+		if v <= 0 {
+			return limits, api, fmt.Errorf("%s timeout must be positive, got %v", l.name, v)
+		}
+		l.dst.Timeout = v
+	}
+	return limits, api, nil
+}
+
+func parseCORS(c CORS) (server.CORSConfig, error) {
+	var cors server.CORSConfig
+	for _, p := range []struct {
+		name  string
+		value *CORSPolicy
+		dst   **server.CORSPolicy
+	}{
+		{"discovery", c.Discovery, &cors.Discovery},
+		{"keys", c.Keys, &cors.Keys},
+		{"token", c.Token, &cors.Token},
+		{"userinfo", c.UserInfo, &cors.UserInfo},
+		{"introspect", c.Introspect, &cors.Introspect},
+	} {
+		if p.value == nil {
+			continue
+		}
+		policy := &server.CORSPolicy{
+			AllowedOrigins:     p.value.AllowedOrigins,
+			AllowClientOrigins: p.value.AllowClientOrigins,
+			AllowedHeaders:     p.value.AllowedHeaders,
+		}
+		if p.value.MaxAge != "" {
+			v, err := time.ParseDuration(p.value.MaxAge)
+			if err != nil {
+				return cors, fmt.Errorf("invalid %s maxAge %q: %v", p.name, p.value.MaxAge, err)
+			}
+			if v < 0 {
+				return cors, fmt.Errorf("%s maxAge cannot be negative, got %v", p.name, v)
+			}
+			policy.MaxAge = v
+		}
+		*p.dst = policy
+	}
+	return cors, nil
+}
+
+// grpcTimeoutInterceptor cancels unary calls taking longer than timeout.
+func grpcTimeoutInterceptor(timeout time.Duration) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		ctx, cancel := context.WithTimeout(ctx, timeout)
+		defer cancel()
+		return handler(ctx, req)
+	}
+}
+
+// openEvents opens the event sinks and returns a queue delivering to them.
+func openEvents(c *Events, logger *slog.Logger) (*events.Queue, error) {
+	sinks := make([]events.Sink, 0, len(c.Sinks))
+	for i, sinkConfig := range c.Sinks {
+		sink, err := sinkConfig.Config.Open(logger)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open sink %d: %v", i, err)
+		}
+		sinks = append(sinks, sink)
+	}
+	size := c.QueueSize
+	if size == 0 {
+		size = 1000
+	}
+	return events.NewQueue(logger.With("component", "events"), size, sinks...), nil
+}
+
 func buildMFAProviders(authenticators []MFAAuthenticator, issuerURL string, logger *slog.Logger) map[string]server.MFAProvider {
 	if len(authenticators) == 0 {
 		return nil
@@ -871,3 +1718,106 @@ func buildMFAProviders(authenticators []MFAAuthenticator, issuerURL string, logg
 	}
 	return providers
 }
+
+func parseMaintenance(c Maintenance) (server.Maintenance, error) {
+	m := server.Maintenance{Enabled: c.Enabled, Message: c.Message}
+	if c.RetryAfter != "" {
+		d, err := time.ParseDuration(c.RetryAfter)
+		if err != nil {
+			return m, fmt.Errorf("invalid maintenance retryAfter %q: %v", c.RetryAfter, err)
+		}
+		if d < 0 {
+			return m, fmt.Errorf("maintenance retryAfter cannot be negative, got %v", d)
+		}
+		m.RetryAfter = d
+	}
+	return m, nil
+}
+
+func parseLoginThrottle(c LoginThrottle) (*server.LoginThrottleConfig, error) {
+	if c.FreeAttempts < 0 || c.BlockAfter < 0 {
+		return nil, errors.New("loginThrottle attempts cannot be negative")
+	}
+	t := &server.LoginThrottleConfig{FreeAttempts: c.FreeAttempts, BlockAfter: c.BlockAfter}
+	for _, d := range []struct {
+		name  string
+		value string
+		dst   *time.Duration
+	}{
+		{"delay", c.Delay, &t.Delay},
+		{"maxDelay", c.MaxDelay, &t.MaxDelay},
+		{"blockFor", c.BlockFor, &t.BlockFor},
+		{"window", c.Window, &t.Window},
+	} {
+		if d.value == "" {
+			continue
+		}
+		v, err := time.ParseDuration(d.value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid loginThrottle %s %q: %v", d.name, d.value, err)
+		}
+		if v < 0 {
+			return nil, fmt.Errorf("loginThrottle %s cannot be negative, got %v", d.name, v)
+		}
+		*d.dst = v
+	}
+
+	allowlist, err := parsePrefixes(c.Allowlist)
+	if err != nil {
+		return nil, fmt.Errorf("invalid loginThrottle allowlist: %v", err)
+	}
+	t.Allowlist = allowlist
+	return t, nil
+}
+
+func parseCaptcha(c Captcha) (*server.CaptchaConfig, error) {
+	switch c.Provider {
+	case server.CaptchaHCaptcha, server.CaptchaReCaptcha, server.CaptchaTurnstile:
+	default:
+		return nil, fmt.Errorf("captcha provider must be %q, %q or %q, got %q", server.CaptchaHCaptcha, server.CaptchaReCaptcha, server.CaptchaTurnstile, c.Provider)
+	}
+	if c.SiteKey == "" || c.Secret == "" {
+		return nil, errors.New("captcha requires a siteKey and a secret")
+	}
+	if c.AfterFailures < 0 {
+		return nil, errors.New("captcha afterFailures cannot be negative")
+	}
+	captcha := &server.CaptchaConfig{
+		Provider:      c.Provider,
+		SiteKey:       c.SiteKey,
+		Secret:        c.Secret,
+		AfterFailures: c.AfterFailures,
+	}
+	if c.Window != "" {
+		window, err := time.ParseDuration(c.Window)
+		if err != nil {
+			return nil, fmt.Errorf("invalid captcha window %q: %v", c.Window, err)
+		}
+		captcha.Window = window
+	}
+	return captcha, nil
+}
+
+func parseRisk(c Risk) (*server.RiskConfig, error) {
+	if _, err := url.ParseRequestURI(c.URL); err != nil {
+		return nil, fmt.Errorf("invalid risk url %q: %v", c.URL, err)
+	}
+	if c.History < 0 {
+		return nil, errors.New("risk history cannot be negative")
+	}
+	risk := &server.RiskConfig{
+		URL:            c.URL,
+		Headers:        c.Headers,
+		FailOpen:       c.FailOpen,
+		StepUpMFAChain: c.StepUpMFAChain,
+		History:        c.History,
+	}
+	if c.Timeout != "" {
+		timeout, err := time.ParseDuration(c.Timeout)
+		if err != nil {
+			return nil, fmt.Errorf("invalid risk timeout %q: %v", c.Timeout, err)
+		}
+		risk.Timeout = timeout
+	}
+	return risk, nil
+}
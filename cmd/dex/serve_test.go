@@ -1,29 +1,191 @@
 package main
 
 import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
 	"log/slog"
+	"math/big"
+	"os"
+	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
+
+	"github.com/dexidp/dex/server"
 )
 
 func TestNewLogger(t *testing.T) {
 	t.Run("JSON", func(t *testing.T) {
-		logger, err := newLogger(slog.LevelInfo, "json", nil)
+		logger, _, err := newLogger(Logger{Level: slog.LevelInfo, Format: "json"})
 		require.NoError(t, err)
 		require.NotEqual(t, (*slog.Logger)(nil), logger)
 	})
 
 	t.Run("Text", func(t *testing.T) {
-		logger, err := newLogger(slog.LevelError, "text", nil)
+		logger, _, err := newLogger(Logger{Level: slog.LevelError, Format: "text"})
 		require.NoError(t, err)
 		require.NotEqual(t, (*slog.Logger)(nil), logger)
 	})
 
 	t.Run("Unknown", func(t *testing.T) {
-		logger, err := newLogger(slog.LevelError, "gofmt", nil)
+		logger, _, err := newLogger(Logger{Level: slog.LevelError, Format: "gofmt"})
 		require.Error(t, err)
 		require.Equal(t, "log format is not one of the supported values (json, text): gofmt", err.Error())
 		require.Equal(t, (*slog.Logger)(nil), logger)
 	})
 }
+
+func TestModuleLogLevels(t *testing.T) {
+	var buf bytes.Buffer
+	logger, levels, err := newLoggerWithOutput(&buf, Logger{
+		Level:  slog.LevelInfo,
+		Format: "text",
+		Levels: map[string]slog.Level{"connector.hsdp": slog.LevelDebug, "storage": slog.LevelWarn},
+	})
+	require.NoError(t, err)
+
+	connLogger := logger.With("component", "connector.hsdp")
+	storageLogger := logger.With("component", "storage")
+
+	logger.Debug("server debug")
+	connLogger.Debug("connector debug")
+	storageLogger.Info("storage info")
+	require.NotContains(t, buf.String(), "server debug")
+	require.Contains(t, buf.String(), "connector debug")
+	require.NotContains(t, buf.String(), "storage info")
+
+	// Levels changed at runtime apply to existing loggers.
+	debug := slog.LevelDebug
+	levels.SetLogLevel("storage", &debug)
+	levels.SetLogLevel("connector.hsdp", nil)
+
+	buf.Reset()
+	connLogger.Debug("connector debug")
+	storageLogger.Debug("storage debug")
+	require.NotContains(t, buf.String(), "connector debug")
+	require.Contains(t, buf.String(), "storage debug")
+
+	def, modules := levels.LogLevels()
+	require.Equal(t, slog.LevelInfo, def)
+	require.Equal(t, map[string]slog.Level{"storage": slog.LevelDebug}, modules)
+}
+
+func TestParseTLSCipherSuites(t *testing.T) {
+	suites, err := parseTLSCipherSuites(nil)
+	require.NoError(t, err)
+	require.Equal(t, defaultTLSCipherSuites, suites)
+
+	suites, err = parseTLSCipherSuites([]string{"TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256", "TLS_ECDHE_ECDSA_WITH_CHACHA20_POLY1305_SHA256"})
+	require.NoError(t, err)
+	require.Equal(t, []uint16{tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256, tls.TLS_ECDHE_ECDSA_WITH_CHACHA20_POLY1305}, suites)
+
+	for _, name := range []string{"TLS_RSA_WITH_RC4_128_SHA", "TLS_AES_128_GCM_SHA256", "TLS_BOGUS"} {
+		_, err := parseTLSCipherSuites([]string{name})
+		require.Error(t, err, name)
+	}
+}
+
+// writeTestCert writes a self-signed certificate and its key for commonName
+// to dir.
+func writeTestCert(t *testing.T, dir, commonName string) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: commonName},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "tls.crt"), pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "tls.key"), pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600))
+}
+
+func TestTLSReloaderKubernetesSecret(t *testing.T) {
+	// Lay out the directory like a mounted Kubernetes secret: the files are
+	// symlinks into ..data, itself a symlink swapped on updates.
+	dir := t.TempDir()
+	for _, version := range []string{"v1", "v2", "broken"} {
+		require.NoError(t, os.Mkdir(filepath.Join(dir, version), 0o700))
+	}
+	writeTestCert(t, filepath.Join(dir, "v1"), "v1")
+	writeTestCert(t, filepath.Join(dir, "v2"), "v2")
+	require.NoError(t, os.Symlink("v1", filepath.Join(dir, "..data")))
+	for _, name := range []string{"tls.crt", "tls.key"} {
+		require.NoError(t, os.Symlink(filepath.Join("..data", name), filepath.Join(dir, name)))
+	}
+	swap := func(version string) {
+		tmp := filepath.Join(dir, "..data_tmp")
+		require.NoError(t, os.Symlink(version, tmp))
+		require.NoError(t, os.Rename(tmp, filepath.Join(dir, "..data")))
+	}
+
+	logger := slog.New(slog.DiscardHandler)
+	tlsConfig, err := newTLSReloader(logger, filepath.Join(dir, "tls.crt"), filepath.Join(dir, "tls.key"), "", &tls.Config{})
+	require.NoError(t, err)
+
+	commonName := func() string {
+		cert, err := tlsConfig.GetCertificate(&tls.ClientHelloInfo{})
+		require.NoError(t, err)
+		leaf, err := x509.ParseCertificate(cert.Certificate[0])
+		require.NoError(t, err)
+		return leaf.Subject.CommonName
+	}
+	require.Equal(t, "v1", commonName())
+
+	swap("v2")
+	require.Eventually(t, func() bool { return commonName() == "v2" }, 5*time.Second, 10*time.Millisecond)
+
+	// A broken update keeps the previous certificate.
+	swap("broken")
+	time.Sleep(100 * time.Millisecond)
+	require.Equal(t, "v2", commonName())
+}
+
+func TestParseEndpointLimits(t *testing.T) {
+	limits, api, err := parseEndpointLimits(EndpointLimits{
+		Auth:  RequestLimit{Timeout: "30s", MaxBodySize: 1 << 20},
+		Token: RequestLimit{MaxBodySize: 1 << 16},
+		API:   RequestLimit{Timeout: "1m"},
+	})
+	require.NoError(t, err)
+	require.Equal(t, server.RequestLimits{
+		Auth:  server.EndpointLimits{Timeout: 30 * time.Second, MaxBodySize: 1 << 20},
+		Token: server.EndpointLimits{MaxBodySize: 1 << 16},
+	}, limits)
+	require.Equal(t, server.EndpointLimits{Timeout: time.Minute}, api)
+
+	_, _, err = parseEndpointLimits(EndpointLimits{Device: RequestLimit{Timeout: "soon"}})
+	require.ErrorContains(t, err, "invalid device timeout")
+
+	_, err = parseHTTPTimeouts(Timeouts{ReadHeader: "-1s"})
+	require.ErrorContains(t, err, "readHeader timeout must be positive")
+}
+
+func TestParseCORS(t *testing.T) {
+	cors, err := parseCORS(CORS{
+		Discovery: &CORSPolicy{},
+		Token:     &CORSPolicy{AllowClientOrigins: true, MaxAge: "10m"},
+	})
+	require.NoError(t, err)
+	require.Equal(t, server.CORSConfig{
+		Discovery: &server.CORSPolicy{},
+		Token:     &server.CORSPolicy{AllowClientOrigins: true, MaxAge: 10 * time.Minute},
+	}, cors)
+
+	_, err = parseCORS(CORS{Keys: &CORSPolicy{MaxAge: "forever"}})
+	require.ErrorContains(t, err, "invalid keys maxAge")
+}
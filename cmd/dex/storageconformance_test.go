@@ -0,0 +1,311 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/dexidp/dex/storage"
+	"github.com/dexidp/dex/storage/conformance"
+	"github.com/dexidp/dex/storage/memory"
+	"github.com/dexidp/dex/storage/sql"
+)
+
+// The conformance suites can be run against the storage of a config with a
+// test binary of this package, built with "make bin/dex-storage-conformance"
+// and run with
+//
+//	bin/dex-storage-conformance -test.run TestStorageConformance -test.v \
+//		-conformance.config config.yaml -conformance.suites Storage,Concurrency
+//
+// The tests create, change and delete data, and garbage collect expired
+// objects. They refuse to run against a storage which isn't empty, so point
+// the config at a storage dedicated to the tests. Everything a test leaves
+// behind is deleted before the next one runs.
+//
+// The Transactions suite nests updates of the same object, which deadlocks
+// storages holding a lock for the whole update, such as memory and sqlite3.
+// Leave it out with -conformance.suites for those.
+var (
+	conformanceConfig = flag.String("conformance.config", "", "Run the storage conformance suites against the storage of this config file or directory")
+	conformanceSuites = flag.String("conformance.suites", "", "Only run these comma separated storage conformance suites: "+strings.Join(conformanceSuiteNames(), ", "))
+)
+
+func TestStorageConformance(t *testing.T) {
+	if *conformanceConfig == "" {
+		t.Skip("no -conformance.config")
+	}
+	c, logger, err := loadIssuerConfig(*conformanceConfig, "")
+	require.NoError(t, err)
+
+	var suites []string
+	if *conformanceSuites != "" {
+		suites = strings.Split(*conformanceSuites, ",")
+	}
+	tests, err := conformanceTests(c.Storage.Config, logger.With("component", "storage"), suites)
+	require.NoError(t, err)
+
+	s, err := c.Storage.Config.Open(logger.With("component", "storage"))
+	require.NoError(t, err, "failed to initialize storage")
+	err = checkStorageEmpty(t.Context(), s)
+	s.Close()
+	require.NoError(t, err)
+
+	for _, test := range tests {
+		t.Run(test.Name, test.F)
+	}
+}
+
+func conformanceSuiteNames() []string {
+	var names []string
+	for _, suite := range conformance.Suites {
+		names = append(names, suite.Name)
+	}
+	return names
+}
+
+// conformanceTests returns the selected suites as tests which open a new
+// connection to the storage for every test, and empty the storage again once
+// the test is done.
+func conformanceTests(config StorageConfig, logger *slog.Logger, suites []string) ([]testing.InternalTest, error) {
+	for _, name := range suites {
+		if !slices.Contains(conformanceSuiteNames(), name) {
+			return nil, fmt.Errorf("unknown suite %q", name)
+		}
+	}
+
+	newStorage := func(t *testing.T) storage.Storage {
+		s, err := config.Open(logger)
+		if err != nil {
+			t.Fatalf("failed to initialize storage: %v", err)
+		}
+		// The tests close the storage they are given, so clean up with a
+		// connection of our own.
+		t.Cleanup(func() {
+			s, err := config.Open(logger)
+			if err != nil {
+				t.Errorf("failed to initialize storage: %v", err)
+				return
+			}
+			defer s.Close()
+			if err := cleanStorage(context.Background(), s); err != nil {
+				t.Errorf("failed to clean up storage: %v", err)
+			}
+		})
+		return s
+	}
+
+	var tests []testing.InternalTest
+	for _, suite := range conformance.Suites {
+		if len(suites) > 0 && !slices.Contains(suites, suite.Name) {
+			continue
+		}
+		tests = append(tests, testing.InternalTest{
+			Name: suite.Name,
+			F: func(t *testing.T) {
+				suite.Run(t, newStorage)
+			},
+		})
+	}
+	return tests, nil
+}
+
+// checkStorageEmpty refuses storages holding data the tests could change or
+// garbage collect.
+func checkStorageEmpty(ctx context.Context, s storage.Storage) error {
+	errNotEmpty := errors.New("storage isn't empty, the conformance tests only run against a storage dedicated to them")
+
+	if keys, err := s.GetKeys(ctx); err != nil && err != storage.ErrNotFound {
+		return fmt.Errorf("failed to get keys: %v", err)
+	} else if keys.SigningKey != nil || len(keys.VerificationKeys) > 0 {
+		return errNotEmpty
+	}
+	if clients, err := s.ListClients(ctx); err != nil {
+		return fmt.Errorf("failed to list clients: %v", err)
+	} else if len(clients) > 0 {
+		return errNotEmpty
+	}
+	if passwords, err := s.ListPasswords(ctx); err != nil {
+		return fmt.Errorf("failed to list passwords: %v", err)
+	} else if len(passwords) > 0 {
+		return errNotEmpty
+	}
+	if connectors, err := s.ListConnectors(ctx); err != nil {
+		return fmt.Errorf("failed to list connectors: %v", err)
+	} else if len(connectors) > 0 {
+		return errNotEmpty
+	}
+	if identities, err := s.ListUserIdentities(ctx); err != nil {
+		return fmt.Errorf("failed to list user identities: %v", err)
+	} else if len(identities) > 0 {
+		return errNotEmpty
+	}
+	if sessions, err := s.ListAuthSessions(ctx); err != nil {
+		return fmt.Errorf("failed to list auth sessions: %v", err)
+	} else if len(sessions) > 0 {
+		return errNotEmpty
+	}
+	if sessions, err := s.ListOfflineSessions(ctx); err != nil {
+		return fmt.Errorf("failed to list offline sessions: %v", err)
+	} else if len(sessions) > 0 {
+		return errNotEmpty
+	}
+	return nil
+}
+
+// cleanStorage deletes everything the conformance tests create, so every test
+// starts with an empty storage like the suites expect.
+func cleanStorage(ctx context.Context, s storage.Storage) error {
+	// Auth requests, codes, device flows and everything else which expires is
+	// garbage collected.
+	if _, err := s.GarbageCollect(ctx, time.Now().AddDate(100, 0, 0)); err != nil {
+		return fmt.Errorf("garbage collect: %v", err)
+	}
+
+	err := s.UpdateKeys(ctx, func(storage.Keys) (storage.Keys, error) {
+		return storage.Keys{}, nil
+	})
+	if err != nil {
+		return fmt.Errorf("reset keys: %v", err)
+	}
+
+	clients, err := s.ListClients(ctx)
+	if err != nil {
+		return fmt.Errorf("list clients: %v", err)
+	}
+	for _, c := range clients {
+		if err := s.DeleteClient(ctx, c.ID); err != nil && err != storage.ErrNotFound {
+			return fmt.Errorf("delete client %q: %v", c.ID, err)
+		}
+	}
+
+	passwords, err := s.ListPasswords(ctx)
+	if err != nil {
+		return fmt.Errorf("list passwords: %v", err)
+	}
+	for _, p := range passwords {
+		if err := s.DeletePassword(ctx, p.Email); err != nil && err != storage.ErrNotFound {
+			return fmt.Errorf("delete password %q: %v", p.Email, err)
+		}
+	}
+
+	connectors, err := s.ListConnectors(ctx)
+	if err != nil {
+		return fmt.Errorf("list connectors: %v", err)
+	}
+	for _, c := range connectors {
+		if err := s.DeleteConnector(ctx, c.ID); err != nil && err != storage.ErrNotFound {
+			return fmt.Errorf("delete connector %q: %v", c.ID, err)
+		}
+	}
+
+	// Kubernetes can't list refresh tokens. Tokens left there are unreachable
+	// without their IDs, so they don't affect later tests.
+	if tokens, err := s.ListRefreshTokens(ctx); err == nil {
+		for _, r := range tokens {
+			if err := s.DeleteRefresh(ctx, r.ID); err != nil && err != storage.ErrNotFound {
+				return fmt.Errorf("delete refresh token %q: %v", r.ID, err)
+			}
+		}
+	}
+
+	identities, err := s.ListUserIdentities(ctx)
+	if err != nil {
+		return fmt.Errorf("list user identities: %v", err)
+	}
+	for _, u := range identities {
+		if err := s.DeleteUserIdentity(ctx, u.UserID, u.ConnectorID); err != nil && err != storage.ErrNotFound {
+			return fmt.Errorf("delete user identity %q: %v", u.UserID, err)
+		}
+	}
+
+	sessions, err := s.ListAuthSessions(ctx)
+	if err != nil {
+		return fmt.Errorf("list auth sessions: %v", err)
+	}
+	for _, a := range sessions {
+		if err := s.DeleteAuthSession(ctx, a.UserID, a.ConnectorID); err != nil && err != storage.ErrNotFound {
+			return fmt.Errorf("delete auth session %q: %v", a.UserID, err)
+		}
+	}
+
+	offlineSessions, err := s.ListOfflineSessions(ctx)
+	if err != nil {
+		return fmt.Errorf("list offline sessions: %v", err)
+	}
+	for _, o := range offlineSessions {
+		if err := s.DeleteOfflineSessions(ctx, o.UserID, o.ConnID); err != nil && err != storage.ErrNotFound {
+			return fmt.Errorf("delete offline sessions of %q: %v", o.UserID, err)
+		}
+	}
+	return nil
+}
+
+func TestConformanceTests(t *testing.T) {
+	logger := slog.New(slog.DiscardHandler)
+
+	_, err := conformanceTests(&memory.Config{}, logger, []string{"Storage", "Unknown"})
+	require.Error(t, err)
+
+	tests, err := conformanceTests(&memory.Config{}, logger, nil)
+	require.NoError(t, err)
+	require.Len(t, tests, 3)
+
+	tests, err = conformanceTests(&memory.Config{}, logger, []string{"Storage", "Concurrency"})
+	require.NoError(t, err)
+	require.Len(t, tests, 2)
+	for _, test := range tests {
+		t.Run(test.Name, test.F)
+	}
+}
+
+func TestConformanceTestsSharedStorage(t *testing.T) {
+	// Unlike memory, every test opens the same database, which has to be
+	// emptied between the tests.
+	config := &sql.SQLite3{File: filepath.Join(t.TempDir(), "dex.db")}
+	tests, err := conformanceTests(config, slog.New(slog.DiscardHandler), []string{"Storage"})
+	require.NoError(t, err)
+	for _, test := range tests {
+		t.Run(test.Name, test.F)
+	}
+
+	s, err := config.Open(slog.New(slog.DiscardHandler))
+	require.NoError(t, err)
+	defer s.Close()
+	require.NoError(t, checkStorageEmpty(t.Context(), s))
+}
+
+func TestCheckStorageEmpty(t *testing.T) {
+	ctx := t.Context()
+	config := &sql.SQLite3{File: filepath.Join(t.TempDir(), "dex.db")}
+	s, err := config.Open(slog.New(slog.DiscardHandler))
+	require.NoError(t, err)
+	defer s.Close()
+
+	require.NoError(t, checkStorageEmpty(ctx, s))
+
+	require.NoError(t, s.CreateClient(ctx, storage.Client{ID: "example-app", Secret: "secret"}))
+	require.Error(t, checkStorageEmpty(ctx, s))
+
+	require.NoError(t, s.CreateRefresh(ctx, storage.RefreshToken{ID: "refresh", ClientID: "example-app", ConnectorID: "local", Claims: storage.Claims{UserID: "1"}}))
+	require.NoError(t, s.CreateOfflineSessions(ctx, storage.OfflineSessions{UserID: "1", ConnID: "local", Refresh: map[string]*storage.RefreshTokenRef{}}))
+	require.NoError(t, s.CreateOfflineSessions(ctx, storage.OfflineSessions{UserID: "2", ConnID: "local", Refresh: map[string]*storage.RefreshTokenRef{}}))
+
+	require.NoError(t, cleanStorage(ctx, s))
+	require.NoError(t, checkStorageEmpty(ctx, s))
+	_, err = s.GetRefresh(ctx, "refresh")
+	require.ErrorIs(t, err, storage.ErrNotFound)
+	_, err = s.GetOfflineSessions(ctx, "1", "local")
+	require.ErrorIs(t, err, storage.ErrNotFound)
+	_, err = s.GetOfflineSessions(ctx, "2", "local")
+	require.ErrorIs(t, err, storage.ErrNotFound, "offline sessions nothing else refers to")
+}
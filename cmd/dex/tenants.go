@@ -0,0 +1,181 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/dexidp/dex/server"
+	"github.com/dexidp/dex/server/signer"
+	"github.com/dexidp/dex/storage"
+)
+
+// Tenant is an additional issuer served by the same dex instance. Settings
+// not listed here, like expiry and OAuth2 settings, are shared with the main
+// issuer. Unlike static clients and connectors of the main issuer, those of
+// tenants aren't reloaded when the config changes.
+type Tenant struct {
+	// Name of the tenant. The issuer of the tenant is <issuer>/t/<name>.
+	Name string `json:"name"`
+
+	// Storage of the tenant. Tenants must not share a storage with each other
+	// or the main issuer, since signing keys and clients are kept there.
+	Storage Storage `json:"storage"`
+
+	StaticConnectors []Connector      `json:"connectors"`
+	StaticClients    []storage.Client `json:"staticClients"`
+	EnablePasswordDB bool             `json:"enablePasswordDB"`
+	StaticPasswords  []password       `json:"staticPasswords"`
+}
+
+var tenantNameRegexp = regexp.MustCompile(`^[a-z0-9]([a-z0-9-]*[a-z0-9])?$`)
+
+func (c Config) validateTenants() error {
+	names := make(map[string]bool, len(c.Tenants))
+	for _, t := range c.Tenants {
+		if !tenantNameRegexp.MatchString(t.Name) {
+			return fmt.Errorf("invalid tenant name %q: must consist of lower case letters, digits and dashes", t.Name)
+		}
+		if names[t.Name] {
+			return fmt.Errorf("duplicate tenant %q", t.Name)
+		}
+		names[t.Name] = true
+		if t.Storage.Config == nil {
+			return fmt.Errorf("tenant %q: no storage supplied", t.Name)
+		}
+		if !t.EnablePasswordDB && len(t.StaticPasswords) != 0 {
+			return fmt.Errorf("tenant %q: cannot specify static passwords without enabling password db", t.Name)
+		}
+	}
+	return nil
+}
+
+// tenantIssuer returns the issuer URL of a tenant.
+func tenantIssuer(issuer, name string) string {
+	return strings.TrimSuffix(issuer, "/") + "/t/" + name
+}
+
+// config returns the main config with the settings of the tenant applied.
+func (t Tenant) config(c Config) Config {
+	c.Issuer = tenantIssuer(c.Issuer, t.Name)
+	c.Storage = t.Storage
+	c.StaticConnectors = t.StaticConnectors
+	c.StaticClients = t.StaticClients
+	c.EnablePasswordDB = t.EnablePasswordDB
+	c.StaticPasswords = t.StaticPasswords
+	c.AdminUI = nil
+	c.Federation = nil
+	c.Tenants = nil
+	return c
+}
+
+// keysRotationPeriod returns how often the local signers of tenants rotate
+// their keys. Tenants always use a local signer, so their keys stay apart.
+func (c Config) keysRotationPeriod() string {
+	if local, ok := c.Signer.Config.(*signer.LocalConfig); ok && local.KeysRotationPeriod != "" {
+		return local.KeysRotationPeriod
+	}
+	if c.Expiry.SigningKeys != "" {
+		return c.Expiry.SigningKeys
+	}
+	return "6h"
+}
+
+// openTenants creates the servers of the tenants. The returned function closes
+// their storages.
+func openTenants(c Config, main server.Config, registry prometheus.Registerer, logger *slog.Logger, dry *dryRun) (map[string]*server.Server, func(), error) {
+	servers := make(map[string]*server.Server, len(c.Tenants))
+	var storages []storage.Storage
+	closeAll := func() {
+		for _, s := range storages {
+			s.Close()
+		}
+	}
+
+	for _, t := range c.Tenants {
+		tc := t.config(c)
+		tlogger := logger.With("tenant", t.Name)
+
+		s, err := dry.openStorage(tc.Storage, tlogger.With("component", "storage"))
+		if err != nil {
+			closeAll()
+			return nil, nil, fmt.Errorf("tenant %q: failed to initialize storage: %v", t.Name, err)
+		}
+		storages = append(storages, s)
+
+		clients, err := buildStaticClients(tc, tlogger)
+		if err != nil {
+			closeAll()
+			return nil, nil, fmt.Errorf("tenant %q: invalid config: %v", t.Name, err)
+		}
+		connectors, err := buildStaticConnectors(tc, tlogger)
+		if err != nil {
+			closeAll()
+			return nil, nil, fmt.Errorf("tenant %q: %v", t.Name, err)
+		}
+		connectors, err = dry.connectors(connectors, tlogger.With("component", "connector"))
+		if err != nil {
+			closeAll()
+			return nil, nil, fmt.Errorf("tenant %q: %v", t.Name, err)
+		}
+		s, _ = storage.WithStaticObjects(s, clients, buildStaticPasswords(tc), connectors, tlogger)
+
+		localConfig := signer.LocalConfig{KeysRotationPeriod: c.keysRotationPeriod()}
+		sig, err := localConfig.Open(context.Background(), s, main.IDTokensValidFor, main.Now, tlogger)
+		if err != nil {
+			closeAll()
+			return nil, nil, fmt.Errorf("tenant %q: failed to open local signer: %v", t.Name, err)
+		}
+
+		sc := main
+		sc.Issuer = tc.Issuer
+		sc.Storage = s
+		sc.Signer = sig
+		sc.Logger = tlogger
+		sc.PrometheusRegistry = prometheus.WrapRegistererWith(prometheus.Labels{"tenant": t.Name}, registry)
+
+		serv, err := server.NewServer(context.Background(), sc)
+		if err != nil {
+			closeAll()
+			return nil, nil, fmt.Errorf("tenant %q: failed to initialize server: %v", t.Name, err)
+		}
+		servers[t.Name] = serv
+		dry.ok("tenant %q", t.Name)
+		logger.Info("config tenant", "tenant", t.Name, "issuer", tc.Issuer, "storage_type", t.Storage.Type)
+	}
+	return servers, closeAll, nil
+}
+
+// tenantRouter sends requests for tenant issuers to the server of the
+// tenant and all others to the main server.
+type tenantRouter struct {
+	main http.Handler
+	// prefix is the path tenant issuers are served under, e.g. "/dex/t/".
+	prefix  string
+	tenants map[string]*server.Server
+}
+
+func newTenantRouter(issuer string, main http.Handler, tenants map[string]*server.Server) (*tenantRouter, error) {
+	u, err := url.Parse(issuer)
+	if err != nil {
+		return nil, fmt.Errorf("invalid issuer URL %q: %v", issuer, err)
+	}
+	return &tenantRouter{main: main, prefix: strings.TrimSuffix(u.Path, "/") + "/t/", tenants: tenants}, nil
+}
+
+func (t *tenantRouter) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if rest, ok := strings.CutPrefix(r.URL.Path, t.prefix); ok {
+		name, _, _ := strings.Cut(rest, "/")
+		if serv, ok := t.tenants[name]; ok {
+			serv.ServeHTTP(w, r)
+			return
+		}
+	}
+	t.main.ServeHTTP(w, r)
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dexidp/dex/connector/mock"
+	"github.com/dexidp/dex/server"
+	"github.com/dexidp/dex/server/signer"
+	"github.com/dexidp/dex/storage"
+	"github.com/dexidp/dex/storage/memory"
+)
+
+func TestValidateTenants(t *testing.T) {
+	memoryStorage := Storage{Type: "memory", Config: &memory.Config{}}
+	tests := []struct {
+		name    string
+		tenants []Tenant
+		wantErr bool
+	}{
+		{"valid", []Tenant{{Name: "hospital-a", Storage: memoryStorage}, {Name: "hospital-b", Storage: memoryStorage}}, false},
+		{"invalid name", []Tenant{{Name: "Hospital A", Storage: memoryStorage}}, true},
+		{"duplicate", []Tenant{{Name: "a", Storage: memoryStorage}, {Name: "a", Storage: memoryStorage}}, true},
+		{"no storage", []Tenant{{Name: "a"}}, true},
+		{"static passwords without password db", []Tenant{{Name: "a", Storage: memoryStorage, StaticPasswords: []password{{Email: "a@example.com"}}}}, true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			err := Config{Tenants: tc.tenants}.validateTenants()
+			require.Equal(t, tc.wantErr, err != nil, "%v", err)
+		})
+	}
+}
+
+func TestTenantRouter(t *testing.T) {
+	logger := slog.New(slog.DiscardHandler)
+	registry := prometheus.NewRegistry()
+	now := func() time.Time { return time.Now().UTC() }
+
+	var handler http.Handler
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handler.ServeHTTP(w, r)
+	}))
+	defer ts.Close()
+
+	c := Config{
+		Issuer: ts.URL + "/dex",
+		Tenants: []Tenant{
+			{
+				Name:             "hospital-a",
+				Storage:          Storage{Type: "memory", Config: &memory.Config{}},
+				StaticConnectors: []Connector{{Type: "mockCallback", ID: "mock", Name: "Mock", Config: &mock.CallbackConfig{}}},
+				StaticClients:    []storage.Client{{ID: "portal-a", Name: "Portal A", Secret: "secret", RedirectURIs: []string{"https://a.example.com/callback"}}},
+			},
+			{
+				Name:             "hospital-b",
+				Storage:          Storage{Type: "memory", Config: &memory.Config{}},
+				StaticConnectors: []Connector{{Type: "mockCallback", ID: "mock", Name: "Mock", Config: &mock.CallbackConfig{}}},
+			},
+		},
+	}
+
+	s := memory.New(logger)
+	require.NoError(t, s.CreateConnector(t.Context(), storage.Connector{ID: "mock", Type: "mockCallback", Name: "Mock", Config: []byte("{}")}))
+	localConfig := signer.LocalConfig{KeysRotationPeriod: "6h"}
+	sig, err := localConfig.Open(context.Background(), s, time.Hour, now, logger)
+	require.NoError(t, err)
+	serverConfig := server.Config{
+		Issuer:             c.Issuer,
+		Storage:            s,
+		Signer:             sig,
+		Web:                server.WebConfig{Dir: "../../web"},
+		Logger:             logger,
+		Now:                now,
+		IDTokensValidFor:   time.Hour,
+		PrometheusRegistry: prometheus.WrapRegistererWith(prometheus.Labels{"tenant": ""}, registry),
+	}
+	main, err := server.NewServer(t.Context(), serverConfig)
+	require.NoError(t, err)
+
+	tenants, closeTenants, err := openTenants(c, serverConfig, registry, logger, nil)
+	require.NoError(t, err)
+	defer closeTenants()
+	handler, err = newTenantRouter(c.Issuer, main, tenants)
+	require.NoError(t, err)
+
+	discover := func(issuer string) (string, string) {
+		resp, err := http.Get(issuer + "/.well-known/openid-configuration")
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		var d struct {
+			Issuer  string `json:"issuer"`
+			JWKSURI string `json:"jwks_uri"`
+		}
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&d))
+		return d.Issuer, d.JWKSURI
+	}
+	keyID := func(jwksURI string) string {
+		resp, err := http.Get(jwksURI)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		var keys struct {
+			Keys []struct {
+				KeyID string `json:"kid"`
+			} `json:"keys"`
+		}
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&keys))
+		require.NotEmpty(t, keys.Keys)
+		return keys.Keys[0].KeyID
+	}
+
+	kids := map[string]bool{}
+	for _, issuer := range []string{c.Issuer, c.Issuer + "/t/hospital-a", c.Issuer + "/t/hospital-b"} {
+		got, jwksURI := discover(issuer)
+		require.Equal(t, issuer, got)
+		kids[keyID(jwksURI)] = true
+	}
+	require.Len(t, kids, 3, "tenants must have their own signing keys")
+
+	resp, err := http.Get(c.Issuer + "/t/unknown/.well-known/openid-configuration")
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusNotFound, resp.StatusCode)
+}
@@ -0,0 +1,257 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/dexidp/dex/connector"
+	"github.com/dexidp/dex/pkg/httpclient"
+	"github.com/dexidp/dex/server"
+	"github.com/dexidp/dex/storage"
+)
+
+type validateOptions struct {
+	// Config file path
+	config string
+
+	// Flags
+	skipNetwork bool
+	skipStorage bool
+}
+
+func commandValidate() *cobra.Command {
+	options := validateOptions{}
+
+	cmd := &cobra.Command{
+		Use:     "validate [flags] [config file or directory]",
+		Short:   "Check a Dex configuration file",
+		Example: "dex validate --skip-network config.yaml",
+		Args:    cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cmd.SilenceUsage = true
+			cmd.SilenceErrors = true
+
+			options.config = args[0]
+
+			return runValidate(cmd.OutOrStdout(), options)
+		},
+	}
+
+	flags := cmd.Flags()
+
+	flags.BoolVar(&options.skipNetwork, "skip-network", false, "Open connectors without contacting their upstream identity providers")
+	flags.BoolVar(&options.skipStorage, "skip-storage", false, "Do not check storage connectivity")
+
+	return cmd
+}
+
+// runValidate performs every check it can and reports all problems found,
+// rather than stopping at the first one like serve does.
+func runValidate(out io.Writer, options validateOptions) error {
+	c, err := loadConfig(options.config)
+	if err != nil {
+		fmt.Fprintf(out, "FAIL  config: %v\n", err)
+		return errors.New("configuration is invalid")
+	}
+
+	var failed int
+	report := func(subject string, err error) {
+		if err != nil {
+			failed++
+			fmt.Fprintf(out, "FAIL  %s: %v\n", subject, err)
+			return
+		}
+		fmt.Fprintf(out, "ok    %s\n", subject)
+	}
+
+	report("config", c.Validate())
+
+	_, _, err = newLogger(c.Logger)
+	report("logger", err)
+
+	report("expiry", validateExpiry(c.Expiry))
+
+	for _, client := range c.StaticClients {
+		subject := fmt.Sprintf("client %q", client.ID)
+		if client.ID == "" {
+			subject = fmt.Sprintf("client %q", client.Name)
+		}
+		report(subject, validateStaticClient(client))
+	}
+
+	logger := slog.New(slog.DiscardHandler)
+	for _, conn := range c.StaticConnectors {
+		subject := fmt.Sprintf("connector %q", conn.ID)
+		if err := validateStaticConnector(conn); err != nil {
+			report(subject, err)
+			continue
+		}
+		if options.skipNetwork {
+			detail, err := openConnectorOffline(conn.ID, conn.Config, logger)
+			report(subject+detail, err)
+			continue
+		}
+		report(subject, openConnector(conn, logger))
+	}
+
+	if c.Storage.Config != nil {
+		if options.skipStorage {
+			report(fmt.Sprintf("storage %q (not connected)", c.Storage.Type), nil)
+		} else {
+			detail, err := checkStorage(c.Storage, logger)
+			report(fmt.Sprintf("storage %q%s", c.Storage.Type, detail), err)
+		}
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("configuration is invalid: %d problem(s) found", failed)
+	}
+	fmt.Fprintln(out, "configuration is valid")
+	return nil
+}
+
+func validateExpiry(e Expiry) error {
+	durations := []struct {
+		name  string
+		value string
+	}{
+		{"expiry.signingKeys", e.SigningKeys},
+		{"expiry.idTokens", e.IDTokens},
+		{"expiry.authRequests", e.AuthRequests},
+		{"expiry.deviceRequests", e.DeviceRequests},
+	}
+	for _, d := range durations {
+		if d.value == "" {
+			continue
+		}
+		if _, err := time.ParseDuration(d.value); err != nil {
+			return fmt.Errorf("invalid value %q for %s: %v", d.value, d.name, err)
+		}
+	}
+
+	_, err := server.NewRefreshTokenPolicy(
+		slog.New(slog.DiscardHandler),
+		e.RefreshTokens.DisableRotation,
+		e.RefreshTokens.ValidIfNotUsedFor,
+		e.RefreshTokens.AbsoluteLifetime,
+		e.RefreshTokens.ReuseInterval,
+	)
+	if err != nil {
+		return fmt.Errorf("invalid refresh token expiration policy: %v", err)
+	}
+
+	if o := e.OfflineSessions; o != nil {
+		if _, err := server.NewOfflineSessionPolicy(slog.New(slog.DiscardHandler), o.ValidIfNotUsedFor, o.MaxPerUserClient); err != nil {
+			return fmt.Errorf("invalid offline session retention policy: %v", err)
+		}
+	}
+	return nil
+}
+
+// openConnector instantiates a connector the same way the server does, which
+// also performs any upstream discovery the connector needs.
+func openConnector(c Connector, logger *slog.Logger) error {
+	conn, err := c.Config.Open(c.ID, logger)
+	if err != nil {
+		return fmt.Errorf("failed to open connector: %v", err)
+	}
+	if closer, ok := conn.(io.Closer); ok {
+		closer.Close()
+	}
+	return nil
+}
+
+// errNetworkSkipped fails the upstream calls of connectors opened without
+// network.
+var errNetworkSkipped = errors.New("network calls are skipped")
+
+// offlineTransport fails every request with errNetworkSkipped and records
+// that the connector tried to call its upstream.
+type offlineTransport struct {
+	called bool
+}
+
+func (t *offlineTransport) RoundTrip(*http.Request) (*http.Response, error) {
+	t.called = true
+	return nil, errNetworkSkipped
+}
+
+// HTTPClient implements connector.HTTPClientFactory. The TLS settings are
+// still checked like for real clients.
+func (t *offlineTransport) HTTPClient(rootCAs []string, insecureSkipVerify bool) (*http.Client, error) {
+	if _, err := httpclient.NewHTTPClient(rootCAs, insecureSkipVerify); err != nil {
+		return nil, err
+	}
+	return &http.Client{Transport: t}, nil
+}
+
+// openConnectorOffline opens a connector with its upstream calls stubbed, so
+// its config is parsed and checked without contacting the upstream. Errors
+// after a skipped call are caused by the stub, the connector is reported as
+// partially checked then. It returns details to append to the report.
+func openConnectorOffline(id string, config server.ConnectorConfig, logger *slog.Logger) (string, error) {
+	t := new(offlineTransport)
+	if cc, ok := config.(connector.HTTPClientConfig); ok {
+		cc.SetHTTPClientFactory(t)
+	}
+	// Connectors without a client factory call through the default transport.
+	defaultTransport := http.DefaultTransport
+	http.DefaultTransport = t
+	defer func() { http.DefaultTransport = defaultTransport }()
+
+	conn, err := config.Open(id, logger)
+	if err == nil {
+		if closer, ok := conn.(io.Closer); ok {
+			closer.Close()
+		}
+	}
+	switch {
+	case t.called:
+		return " (network calls skipped)", nil
+	case err != nil:
+		return "", fmt.Errorf("failed to open connector: %v", err)
+	}
+	return "", nil
+}
+
+// checkStorage checks the storage can be used without modifying it: SQL
+// storages are only checked for pending migrations, which Open would apply,
+// storages creating their schema on Open are only connected to, and the
+// others are opened and read from. It returns details to append to the
+// report.
+func checkStorage(c Storage, logger *slog.Logger) (string, error) {
+	if mc, ok := c.Config.(migrationChecker); ok {
+		n, err := mc.PendingMigrations(logger)
+		if err != nil {
+			return "", fmt.Errorf("failed to check storage migrations: %v", err)
+		}
+		return fmt.Sprintf(" (%d pending migrations)", n), nil
+	}
+	if cc, ok := c.Config.(connectionChecker); ok {
+		if err := cc.CheckConnection(logger); err != nil {
+			return "", fmt.Errorf("failed to connect to storage: %v", err)
+		}
+		return "", nil
+	}
+
+	s, err := c.Config.Open(logger)
+	if err != nil {
+		return "", fmt.Errorf("failed to initialize storage: %v", err)
+	}
+	defer s.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if _, err := s.GetKeys(ctx); err != nil && !errors.Is(err, storage.ErrNotFound) {
+		return "", fmt.Errorf("failed to read from storage: %v", err)
+	}
+	return "", nil
+}
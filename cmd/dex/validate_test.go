@@ -0,0 +1,128 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunValidate(t *testing.T) {
+	writeConfig := func(t *testing.T, content string) string {
+		path := filepath.Join(t.TempDir(), "config.yaml")
+		require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+		return path
+	}
+
+	t.Run("Valid", func(t *testing.T) {
+		config := writeConfig(t, `
+issuer: http://127.0.0.1:5556/dex
+storage:
+  type: memory
+web:
+  http: 127.0.0.1:5556
+staticClients:
+- id: example-app
+  name: Example App
+  secret: ZXhhbXBsZS1hcHAtc2VjcmV0
+connectors:
+- type: mockCallback
+  id: mock
+  name: Example
+`)
+		var out bytes.Buffer
+		err := runValidate(&out, validateOptions{config: config})
+		require.NoError(t, err)
+		require.Contains(t, out.String(), `ok    connector "mock"`)
+		require.Contains(t, out.String(), `ok    storage "memory"`)
+		require.Contains(t, out.String(), "configuration is valid")
+	})
+
+	t.Run("ReportsAllProblems", func(t *testing.T) {
+		config := writeConfig(t, `
+issuer: http://127.0.0.1:5556/dex
+storage:
+  type: memory
+web:
+  http: 127.0.0.1:5556
+expiry:
+  idTokens: tomorrow
+staticClients:
+- id: example-app
+  name: Example App
+connectors:
+- type: mockCallback
+  id: mock
+  name: Example
+  grantTypes: [implicit_flow]
+`)
+		var out bytes.Buffer
+		err := runValidate(&out, validateOptions{config: config, skipNetwork: true})
+		require.EqualError(t, err, "configuration is invalid: 3 problem(s) found")
+		require.Contains(t, out.String(), `FAIL  expiry: invalid value "tomorrow" for expiry.idTokens`)
+		require.Contains(t, out.String(), `FAIL  client "example-app": Secret or SecretEnv field is required`)
+		require.Contains(t, out.String(), `FAIL  connector "mock": unknown grant type "implicit_flow"`)
+	})
+
+	t.Run("SkipNetwork", func(t *testing.T) {
+		config := writeConfig(t, `
+issuer: http://127.0.0.1:5556/dex
+storage:
+  type: memory
+web:
+  http: 127.0.0.1:5556
+connectors:
+- type: oidc
+  id: upstream
+  name: Upstream
+  config:
+    issuer: http://127.0.0.1:1/unreachable
+    clientID: dex
+    clientSecret: secret
+    redirectURI: http://127.0.0.1:5556/dex/callback
+- type: oidc
+  id: broken
+  name: Broken
+  config:
+    issuer: http://127.0.0.1:1/unreachable
+    rootCAs: [not-a-certificate]
+`)
+		var out bytes.Buffer
+		err := runValidate(&out, validateOptions{config: config, skipNetwork: true})
+		require.EqualError(t, err, "configuration is invalid: 1 problem(s) found")
+		require.Contains(t, out.String(), `ok    connector "upstream" (network calls skipped)`)
+		require.Contains(t, out.String(), `FAIL  connector "broken": failed to open connector:`)
+	})
+
+	t.Run("DoesNotMigrateStorage", func(t *testing.T) {
+		dir := t.TempDir()
+		freshFile := filepath.Join(dir, "fresh.db")
+		emptyFile := filepath.Join(dir, "empty.db")
+		require.NoError(t, os.WriteFile(emptyFile, nil, 0o600))
+
+		for _, dbFile := range []string{freshFile, emptyFile} {
+			config := writeConfig(t, `
+issuer: http://127.0.0.1:5556/dex
+storage:
+  type: sqlite3
+  config:
+    file: `+dbFile+`
+web:
+  http: 127.0.0.1:5556
+`)
+			var out bytes.Buffer
+			err := runValidate(&out, validateOptions{config: config})
+			require.NoError(t, err)
+			require.Contains(t, out.String(), `ok    storage "sqlite3" (`)
+			require.Contains(t, out.String(), "pending migrations)")
+		}
+
+		_, err := os.Stat(freshFile)
+		require.True(t, os.IsNotExist(err), "validate created the database")
+		info, err := os.Stat(emptyFile)
+		require.NoError(t, err)
+		require.Zero(t, info.Size(), "validate created the schema")
+	})
+}
@@ -0,0 +1,392 @@
+// Package apple implements logging in through Sign in with Apple.
+package apple
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/coreos/go-oidc/v3/oidc"
+	"github.com/go-jose/go-jose/v4"
+	"github.com/go-jose/go-jose/v4/jwt"
+	"golang.org/x/oauth2"
+
+	"github.com/dexidp/dex/connector"
+)
+
+const (
+	appleIssuer = "https://appleid.apple.com"
+
+	// Apple accepts client secrets valid for up to six months. Dex signs
+	// short-lived ones and reuses them until shortly before they expire.
+	clientSecretLifetime = time.Hour
+	clientSecretRenewal  = 5 * time.Minute
+)
+
+// Config holds configuration options for Sign in with Apple.
+//
+// Apple doesn't issue client secrets. Instead, the client signs its own with
+// a private key downloaded from the Apple developer account.
+//
+// See https://developer.apple.com/documentation/sign_in_with_apple/sign_in_with_apple_rest_api
+type Config struct {
+	// ClientID is the identifier of the Services ID, e.g. "com.example.dex".
+	ClientID    string `json:"clientID"`
+	RedirectURI string `json:"redirectURI"`
+
+	// TeamID is the ten character identifier of the Apple developer team.
+	TeamID string `json:"teamID"`
+	// KeyID is the identifier of the private key.
+	KeyID string `json:"keyID"`
+	// PrivateKey is the PEM encoded ".p8" private key, given inline or as
+	// a path to a file.
+	PrivateKey     string `json:"privateKey"`
+	PrivateKeyFile string `json:"privateKeyFile"`
+
+	// Scopes defaults to "name" and "email".
+	Scopes []string `json:"scopes"`
+
+	// Issuer of the ID tokens, to point the connector at a test server.
+	// Defaults to "https://appleid.apple.com".
+	Issuer string `json:"issuer"`
+}
+
+// Open returns a connector which can be used to login users through Apple.
+func (c *Config) Open(id string, logger *slog.Logger) (connector.Connector, error) {
+	if c.ClientID == "" || c.TeamID == "" || c.KeyID == "" {
+		return nil, errors.New("apple: clientID, teamID and keyID are required")
+	}
+
+	keyPEM := []byte(c.PrivateKey)
+	if c.PrivateKeyFile != "" {
+		if c.PrivateKey != "" {
+			return nil, errors.New("apple: privateKey and privateKeyFile are mutually exclusive")
+		}
+		var err error
+		if keyPEM, err = os.ReadFile(c.PrivateKeyFile); err != nil {
+			return nil, fmt.Errorf("apple: read private key: %v", err)
+		}
+	}
+	key, err := parsePrivateKey(keyPEM)
+	if err != nil {
+		return nil, fmt.Errorf("apple: %v", err)
+	}
+
+	issuer := c.Issuer
+	if issuer == "" {
+		issuer = appleIssuer
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	provider, err := oidc.NewProvider(ctx, issuer)
+	if err != nil {
+		cancel()
+		return nil, fmt.Errorf("apple: failed to get provider: %v", err)
+	}
+
+	scopes := c.Scopes
+	if len(scopes) == 0 {
+		scopes = []string{"name", "email"}
+	}
+	endpoint := provider.Endpoint()
+	// Apple only accepts the client secret in the request body.
+	endpoint.AuthStyle = oauth2.AuthStyleInParams
+
+	return &appleConnector{
+		clientID:    c.ClientID,
+		redirectURI: c.RedirectURI,
+		teamID:      c.TeamID,
+		keyID:       c.KeyID,
+		key:         key,
+		issuer:      issuer,
+		scopes:      scopes,
+		endpoint:    endpoint,
+		verifier:    provider.Verifier(&oidc.Config{ClientID: c.ClientID}),
+		cancel:      cancel,
+		logger:      logger.With(slog.Group("connector", "type", "apple", "id", id)),
+		now:         time.Now,
+	}, nil
+}
+
+func parsePrivateKey(data []byte) (*ecdsa.PrivateKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no PEM encoded private key found")
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("parse private key: %v", err)
+	}
+	ecKey, ok := key.(*ecdsa.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("expected an ECDSA private key, got %T", key)
+	}
+	return ecKey, nil
+}
+
+// connectorData is kept between logins and refreshes.
+type connectorData struct {
+	RefreshToken string `json:"refreshToken,omitempty"`
+}
+
+var (
+	_ connector.CallbackConnector = (*appleConnector)(nil)
+	_ connector.RefreshConnector  = (*appleConnector)(nil)
+	_ connector.FormPostConnector = (*appleConnector)(nil)
+)
+
+type appleConnector struct {
+	clientID    string
+	redirectURI string
+	teamID      string
+	keyID       string
+	key         *ecdsa.PrivateKey
+	issuer      string
+	scopes      []string
+	endpoint    oauth2.Endpoint
+	verifier    *oidc.IDTokenVerifier
+	cancel      context.CancelFunc
+	logger      *slog.Logger
+	now         func() time.Time
+
+	mu                 sync.Mutex
+	clientSecret       string
+	clientSecretExpiry time.Time
+}
+
+func (c *appleConnector) Close() error {
+	c.cancel()
+	return nil
+}
+
+// FormPost reports that Apple returns the authorization response as a form
+// POST to the callback URL.
+func (c *appleConnector) FormPost() bool {
+	return true
+}
+
+func (c *appleConnector) oauth2Config() (*oauth2.Config, error) {
+	secret, err := c.getClientSecret()
+	if err != nil {
+		return nil, err
+	}
+	return &oauth2.Config{
+		ClientID:     c.clientID,
+		ClientSecret: secret,
+		Endpoint:     c.endpoint,
+		Scopes:       c.scopes,
+		RedirectURL:  c.redirectURI,
+	}, nil
+}
+
+// getClientSecret returns a client secret, which is a JWT signed with the
+// team's private key.
+//
+// See https://developer.apple.com/documentation/accountorganizationaldatasharing/creating-a-client-secret
+func (c *appleConnector) getClientSecret() (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := c.now()
+	if c.clientSecret != "" && now.Add(clientSecretRenewal).Before(c.clientSecretExpiry) {
+		return c.clientSecret, nil
+	}
+
+	signer, err := jose.NewSigner(
+		jose.SigningKey{Algorithm: jose.ES256, Key: c.key},
+		(&jose.SignerOptions{}).WithType("JWT").WithHeader("kid", c.keyID),
+	)
+	if err != nil {
+		return "", fmt.Errorf("apple: new signer: %v", err)
+	}
+	expiry := now.Add(clientSecretLifetime)
+	secret, err := jwt.Signed(signer).Claims(jwt.Claims{
+		Issuer:   c.teamID,
+		Subject:  c.clientID,
+		Audience: jwt.Audience{c.issuer},
+		IssuedAt: jwt.NewNumericDate(now),
+		Expiry:   jwt.NewNumericDate(expiry),
+	}).Serialize()
+	if err != nil {
+		return "", fmt.Errorf("apple: sign client secret: %v", err)
+	}
+	c.clientSecret, c.clientSecretExpiry = secret, expiry
+	return secret, nil
+}
+
+func (c *appleConnector) LoginURL(scopes connector.Scopes, callbackURL, state string) (string, []byte, error) {
+	if c.redirectURI != callbackURL {
+		return "", nil, fmt.Errorf("expected callback URL %q did not match the URL in the config %q", callbackURL, c.redirectURI)
+	}
+
+	// Apple doesn't need a client secret to start the login, so skip signing
+	// one here.
+	config := oauth2.Config{
+		ClientID:    c.clientID,
+		Endpoint:    c.endpoint,
+		Scopes:      c.scopes,
+		RedirectURL: c.redirectURI,
+	}
+	// Apple requires the response to be posted if any scopes are requested.
+	return config.AuthCodeURL(state, oauth2.SetAuthURLParam("response_mode", "form_post")), nil, nil
+}
+
+// user holds the user's details Apple posts to the callback URL, only on the
+// first time they sign in to the app.
+type user struct {
+	Name struct {
+		FirstName string `json:"firstName"`
+		LastName  string `json:"lastName"`
+	} `json:"name"`
+	Email string `json:"email"`
+}
+
+type oauth2Error struct {
+	error            string
+	errorDescription string
+}
+
+func (e *oauth2Error) Error() string {
+	if e.errorDescription == "" {
+		return e.error
+	}
+	return e.error + ": " + e.errorDescription
+}
+
+func (c *appleConnector) HandleCallback(s connector.Scopes, connData []byte, r *http.Request) (identity connector.Identity, err error) {
+	if errType := r.FormValue("error"); errType != "" {
+		return identity, &oauth2Error{errType, r.FormValue("error_description")}
+	}
+
+	config, err := c.oauth2Config()
+	if err != nil {
+		return identity, err
+	}
+	ctx := r.Context()
+	token, err := config.Exchange(ctx, r.FormValue("code"))
+	if err != nil {
+		return identity, fmt.Errorf("apple: failed to get token: %v", err)
+	}
+
+	identity, err = c.identity(ctx, token)
+	if err != nil {
+		return identity, err
+	}
+
+	// The user's name is only sent the first time they sign in. Dex keeps it
+	// with the user's identity if sessions are enabled; otherwise it's lost
+	// on later logins.
+	if raw := r.FormValue("user"); raw != "" {
+		var u user
+		if err := json.Unmarshal([]byte(raw), &u); err != nil {
+			return identity, fmt.Errorf("apple: unmarshal user: %v", err)
+		}
+		identity.Username = strings.TrimSpace(u.Name.FirstName + " " + u.Name.LastName)
+	}
+
+	if s.OfflineAccess {
+		data, err := json.Marshal(connectorData{RefreshToken: token.RefreshToken})
+		if err != nil {
+			return identity, fmt.Errorf("apple: marshal connector data: %v", err)
+		}
+		identity.ConnectorData = data
+	}
+	return identity, nil
+}
+
+// Refresh validates the refresh token with Apple, which also checks that the
+// user hasn't revoked dex's access. The user's name is kept, as Apple never
+// returns it again.
+func (c *appleConnector) Refresh(ctx context.Context, s connector.Scopes, identity connector.Identity) (connector.Identity, error) {
+	var data connectorData
+	if err := json.Unmarshal(identity.ConnectorData, &data); err != nil {
+		return identity, fmt.Errorf("apple: unmarshal connector data: %v", err)
+	}
+	if data.RefreshToken == "" {
+		return identity, errors.New("apple: no upstream refresh token found")
+	}
+
+	config, err := c.oauth2Config()
+	if err != nil {
+		return identity, err
+	}
+	token, err := config.TokenSource(ctx, &oauth2.Token{
+		RefreshToken: data.RefreshToken,
+		Expiry:       time.Now().Add(-time.Hour),
+	}).Token()
+	if err != nil {
+		return identity, fmt.Errorf("apple: failed to refresh token: %v", err)
+	}
+
+	refreshed, err := c.identity(ctx, token)
+	if err != nil {
+		return identity, err
+	}
+	if refreshed.UserID != identity.UserID {
+		return identity, fmt.Errorf("apple: refreshed token is for user %q, expected %q", refreshed.UserID, identity.UserID)
+	}
+	identity.Email = refreshed.Email
+	identity.EmailVerified = refreshed.EmailVerified
+	return identity, nil
+}
+
+// idTokenClaims holds the claims of Apple's ID tokens. Apple sends boolean
+// claims as strings in some cases.
+type idTokenClaims struct {
+	Subject       string   `json:"sub"`
+	Email         string   `json:"email"`
+	EmailVerified flexBool `json:"email_verified"`
+}
+
+type flexBool bool
+
+func (b *flexBool) UnmarshalJSON(data []byte) error {
+	var v interface{}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	switch v := v.(type) {
+	case bool:
+		*b = flexBool(v)
+	case string:
+		parsed, err := strconv.ParseBool(v)
+		if err != nil {
+			return fmt.Errorf("invalid boolean %q", v)
+		}
+		*b = flexBool(parsed)
+	default:
+		return fmt.Errorf("invalid boolean %s", data)
+	}
+	return nil
+}
+
+func (c *appleConnector) identity(ctx context.Context, token *oauth2.Token) (connector.Identity, error) {
+	rawIDToken, ok := token.Extra("id_token").(string)
+	if !ok {
+		return connector.Identity{}, errors.New("apple: no id_token in token response")
+	}
+	idToken, err := c.verifier.Verify(ctx, rawIDToken)
+	if err != nil {
+		return connector.Identity{}, fmt.Errorf("apple: failed to verify ID token: %v", err)
+	}
+	var claims idTokenClaims
+	if err := idToken.Claims(&claims); err != nil {
+		return connector.Identity{}, fmt.Errorf("apple: failed to decode claims: %v", err)
+	}
+	return connector.Identity{
+		UserID:        claims.Subject,
+		Email:         claims.Email,
+		EmailVerified: bool(claims.EmailVerified),
+	}, nil
+}
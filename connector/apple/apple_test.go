@@ -0,0 +1,244 @@
+package apple
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-jose/go-jose/v4"
+	"github.com/go-jose/go-jose/v4/jwt"
+
+	"github.com/dexidp/dex/connector"
+)
+
+const (
+	testClientID = "com.example.dex"
+	testTeamID   = "TEAM123456"
+	testKeyID    = "KEY1234567"
+)
+
+// newTestServer runs a fake Apple server, which checks the client secret
+// against clientKey and issues ID tokens with the given claims.
+func newTestServer(t *testing.T, clientKey *ecdsa.PublicKey, claims map[string]interface{}) *httptest.Server {
+	signingKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	jwk := jose.JSONWebKey{Key: signingKey, KeyID: "apple", Algorithm: string(jose.RS256), Use: "sig"}
+
+	var s *httptest.Server
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"issuer":                 s.URL,
+			"authorization_endpoint": s.URL + "/auth/authorize",
+			"token_endpoint":         s.URL + "/auth/token",
+			"jwks_uri":               s.URL + "/auth/keys",
+		})
+	})
+	mux.HandleFunc("/auth/keys", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(jose.JSONWebKeySet{Keys: []jose.JSONWebKey{jwk.Public()}})
+	})
+	mux.HandleFunc("/auth/token", func(w http.ResponseWriter, r *http.Request) {
+		secret, err := jwt.ParseSigned(r.PostFormValue("client_secret"), []jose.SignatureAlgorithm{jose.ES256})
+		if err != nil {
+			http.Error(w, `{"error":"invalid_client"}`, http.StatusBadRequest)
+			return
+		}
+		var c jwt.Claims
+		if err := secret.Claims(clientKey, &c); err != nil || c.Issuer != testTeamID || c.Subject != testClientID ||
+			secret.Headers[0].KeyID != testKeyID || c.Validate(jwt.Expected{AnyAudience: jwt.Audience{s.URL}, Time: time.Now()}) != nil {
+			http.Error(w, `{"error":"invalid_client"}`, http.StatusBadRequest)
+			return
+		}
+
+		signer, err := jose.NewSigner(jose.SigningKey{Algorithm: jose.RS256, Key: jwk}, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		tokenClaims := map[string]interface{}{
+			"iss": s.URL,
+			"aud": testClientID,
+			"exp": time.Now().Add(time.Hour).Unix(),
+			"iat": time.Now().Unix(),
+		}
+		for k, v := range claims {
+			tokenClaims[k] = v
+		}
+		idToken, err := jwt.Signed(signer).Claims(tokenClaims).Serialize()
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"access_token":  "access-token",
+			"refresh_token": "refresh-token",
+			"token_type":    "Bearer",
+			"expires_in":    3600,
+			"id_token":      idToken,
+		})
+	})
+	s = httptest.NewServer(mux)
+	return s
+}
+
+func newTestConnector(t *testing.T) (*appleConnector, *httptest.Server) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := newTestServer(t, &key.PublicKey, map[string]interface{}{
+		"sub":            "001234.abcdef",
+		"email":          "jane@privaterelay.appleid.com",
+		"email_verified": "true",
+	})
+	c := Config{
+		ClientID:    testClientID,
+		RedirectURI: "https://dex.example.com/callback",
+		TeamID:      testTeamID,
+		KeyID:       testKeyID,
+		PrivateKey:  string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})),
+		Issuer:      s.URL,
+	}
+	conn, err := c.Open("apple", slog.New(slog.DiscardHandler))
+	if err != nil {
+		s.Close()
+		t.Fatal(err)
+	}
+	return conn.(*appleConnector), s
+}
+
+func TestLoginURL(t *testing.T) {
+	c, s := newTestConnector(t)
+	defer s.Close()
+
+	loginURL, _, err := c.LoginURL(connector.Scopes{}, "https://dex.example.com/callback", "some-state")
+	if err != nil {
+		t.Fatal(err)
+	}
+	u, err := url.Parse(loginURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	q := u.Query()
+	if got := q.Get("response_mode"); got != "form_post" {
+		t.Errorf("expected response_mode form_post, got %q", got)
+	}
+	if got := q.Get("scope"); got != "name email" {
+		t.Errorf("expected scope %q, got %q", "name email", got)
+	}
+	if got := q.Get("client_id"); got != testClientID {
+		t.Errorf("expected client_id %q, got %q", testClientID, got)
+	}
+}
+
+func TestHandleCallback(t *testing.T) {
+	c, s := newTestConnector(t)
+	defer s.Close()
+
+	form := url.Values{
+		"code":  {"some-code"},
+		"state": {"some-state"},
+		"user":  {`{"name":{"firstName":"Jane","lastName":"Doe"},"email":"jane@privaterelay.appleid.com"}`},
+	}
+	newRequest := func(form url.Values) *http.Request {
+		r := httptest.NewRequest(http.MethodPost, "/callback", strings.NewReader(form.Encode()))
+		r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		return r
+	}
+
+	identity, err := c.HandleCallback(connector.Scopes{OfflineAccess: true}, nil, newRequest(form))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if identity.UserID != "001234.abcdef" || identity.Username != "Jane Doe" ||
+		identity.Email != "jane@privaterelay.appleid.com" || !identity.EmailVerified {
+		t.Errorf("unexpected identity %+v", identity)
+	}
+
+	// The name is only posted on the first login.
+	form.Del("user")
+	second, err := c.HandleCallback(connector.Scopes{}, nil, newRequest(form))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if second.Username != "" || second.ConnectorData != nil {
+		t.Errorf("unexpected identity %+v", second)
+	}
+
+	refreshed, err := c.Refresh(context.Background(), connector.Scopes{OfflineAccess: true}, identity)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if refreshed.Username != "Jane Doe" || refreshed.Email != identity.Email {
+		t.Errorf("unexpected refreshed identity %+v", refreshed)
+	}
+}
+
+func TestClientSecretReuse(t *testing.T) {
+	c, s := newTestConnector(t)
+	defer s.Close()
+
+	now := time.Now()
+	c.now = func() time.Time { return now }
+	first, err := c.getClientSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := c.getClientSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first != second {
+		t.Error("expected client secret to be reused")
+	}
+
+	now = now.Add(clientSecretLifetime - clientSecretRenewal)
+	third, err := c.getClientSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if third == first {
+		t.Error("expected client secret to be renewed before it expires")
+	}
+}
+
+func TestFlexBool(t *testing.T) {
+	for _, tc := range []struct {
+		data string
+		want bool
+	}{
+		{`true`, true},
+		{`"true"`, true},
+		{`"false"`, false},
+		{`false`, false},
+	} {
+		var b flexBool
+		if err := json.Unmarshal([]byte(tc.data), &b); err != nil {
+			t.Errorf("%s: %v", tc.data, err)
+		} else if bool(b) != tc.want {
+			t.Errorf("%s: expected %t, got %t", tc.data, tc.want, b)
+		}
+	}
+	var b flexBool
+	if err := json.Unmarshal([]byte(`"yes please"`), &b); err == nil {
+		t.Error("expected error for invalid boolean")
+	}
+}
@@ -4,10 +4,15 @@
 package authproxy
 
 import (
+	"crypto/subtle"
+	"errors"
 	"fmt"
 	"log/slog"
 	"net/http"
+	"net/netip"
 	"net/url"
+	"slices"
+	"strconv"
 	"strings"
 
 	"github.com/dexidp/dex/connector"
@@ -18,6 +23,10 @@ import (
 // X-Remote-Group and configured staticGroups as user's group.
 // Headers retrieved to fetch user's email and group can be configured
 // with userHeader and groupHeader.
+//
+// Anyone who can reach dex directly can set these headers, so the proxy
+// should prove itself with trustedProxies, a shared secret or a client
+// certificate. Requests failing any configured check are rejected.
 type Config struct {
 	UserIDHeader         string   `json:"userIDHeader"`
 	UserHeader           string   `json:"userHeader"`
@@ -26,6 +35,27 @@ type Config struct {
 	GroupHeader          string   `json:"groupHeader"`
 	GroupHeaderSeparator string   `json:"groupHeaderSeparator"`
 	Groups               []string `json:"staticGroups"`
+
+	// EmailVerifiedHeader holds "true" or "false". If not set, emails are
+	// verified.
+	EmailVerifiedHeader string `json:"emailVerifiedHeader"`
+	// ClaimHeaders maps custom claims of the user to the headers they are
+	// read from, e.g. "tenant": "X-Remote-Tenant". Clients receive them if
+	// they are released to them.
+	ClaimHeaders map[string]string `json:"claimHeaders"`
+
+	// TrustedProxies are the addresses or CIDR ranges the proxy connects
+	// from.
+	TrustedProxies []string `json:"trustedProxies"`
+	// SharedSecret must be sent by the proxy in SharedSecretHeader, which
+	// defaults to X-Remote-Secret.
+	SharedSecret       string `json:"sharedSecret"`
+	SharedSecretHeader string `json:"sharedSecretHeader"`
+	// RequireClientCert requires the proxy to connect with a certificate
+	// verified by the web.tlsClientCA of dex. If ClientCertNames is set, the
+	// common name or a DNS name of the certificate must be one of them.
+	RequireClientCert bool     `json:"requireClientCert"`
+	ClientCertNames   []string `json:"clientCertNames"`
 }
 
 // Open returns an authentication strategy which requires no user interaction.
@@ -54,16 +84,48 @@ func (c *Config) Open(id string, logger *slog.Logger) (connector.Connector, erro
 	if groupHeaderSeparator == "" {
 		groupHeaderSeparator = ","
 	}
+	sharedSecretHeader := c.SharedSecretHeader
+	if sharedSecretHeader == "" {
+		sharedSecretHeader = "X-Remote-Secret"
+	}
+	if len(c.ClientCertNames) > 0 && !c.RequireClientCert {
+		return nil, errors.New("clientCertNames requires requireClientCert")
+	}
+
+	trustedProxies := make([]netip.Prefix, 0, len(c.TrustedProxies))
+	for _, p := range c.TrustedProxies {
+		prefix, err := netip.ParsePrefix(p)
+		if err != nil {
+			addr, addrErr := netip.ParseAddr(p)
+			if addrErr != nil {
+				return nil, fmt.Errorf("invalid trusted proxy %q: %v", p, err)
+			}
+			prefix = netip.PrefixFrom(addr, addr.BitLen())
+		}
+		trustedProxies = append(trustedProxies, prefix.Masked())
+	}
+
+	logger = logger.With(slog.Group("connector", "type", "authproxy", "id", id))
+	if len(trustedProxies) == 0 && c.SharedSecret == "" && !c.RequireClientCert {
+		logger.Warn("no trustedProxies, sharedSecret or requireClientCert configured, identity headers are accepted from any client")
+	}
 
 	return &callback{
 		userIDHeader:         userIDHeader,
 		userHeader:           userHeader,
 		userNameHeader:       userNameHeader,
 		emailHeader:          emailHeader,
+		emailVerifiedHeader:  c.EmailVerifiedHeader,
 		groupHeader:          groupHeader,
 		groupHeaderSeparator: groupHeaderSeparator,
 		groups:               c.Groups,
-		logger:               logger.With(slog.Group("connector", "type", "authproxy", "id", id)),
+		claimHeaders:         c.ClaimHeaders,
+		trustedProxies:       trustedProxies,
+		sharedSecret:         c.SharedSecret,
+		sharedSecretHeader:   sharedSecretHeader,
+		requireClientCert:    c.RequireClientCert,
+		clientCertNames:      c.ClientCertNames,
+		logger:               logger,
 		pathSuffix:           "/" + id,
 	}, nil
 }
@@ -77,11 +139,20 @@ type callback struct {
 	userNameHeader       string
 	userHeader           string
 	emailHeader          string
+	emailVerifiedHeader  string
 	groupHeader          string
 	groupHeaderSeparator string
 	groups               []string
-	logger               *slog.Logger
-	pathSuffix           string
+	claimHeaders         map[string]string
+
+	trustedProxies     []netip.Prefix
+	sharedSecret       string
+	sharedSecretHeader string
+	requireClientCert  bool
+	clientCertNames    []string
+
+	logger     *slog.Logger
+	pathSuffix string
 }
 
 // LoginURL returns the URL to redirect the user to login with.
@@ -97,8 +168,44 @@ func (m *callback) LoginURL(s connector.Scopes, callbackURL, state string) (stri
 	return u.String(), nil, nil
 }
 
+// verifyProxy checks that the request was made by the trusted proxy.
+func (m *callback) verifyProxy(r *http.Request) error {
+	if len(m.trustedProxies) > 0 {
+		addrPort, err := netip.ParseAddrPort(r.RemoteAddr)
+		if err != nil {
+			return fmt.Errorf("parse remote address %q: %v", r.RemoteAddr, err)
+		}
+		addr := addrPort.Addr().Unmap()
+		if !slices.ContainsFunc(m.trustedProxies, func(p netip.Prefix) bool { return p.Contains(addr) }) {
+			return fmt.Errorf("request from untrusted address %s", addr)
+		}
+	}
+	if m.sharedSecret != "" {
+		if subtle.ConstantTimeCompare([]byte(r.Header.Get(m.sharedSecretHeader)), []byte(m.sharedSecret)) != 1 {
+			return fmt.Errorf("missing or invalid shared secret in HTTP header %s", m.sharedSecretHeader)
+		}
+	}
+	if m.requireClientCert {
+		if r.TLS == nil || len(r.TLS.VerifiedChains) == 0 {
+			return errors.New("no verified client certificate")
+		}
+		if len(m.clientCertNames) > 0 {
+			cert := r.TLS.VerifiedChains[0][0]
+			if !slices.Contains(m.clientCertNames, cert.Subject.CommonName) &&
+				!slices.ContainsFunc(cert.DNSNames, func(name string) bool { return slices.Contains(m.clientCertNames, name) }) {
+				return fmt.Errorf("client certificate %q is not allowed", cert.Subject.CommonName)
+			}
+		}
+	}
+	return nil
+}
+
 // HandleCallback parses the request and returns the user's identity
 func (m *callback) HandleCallback(s connector.Scopes, _ []byte, r *http.Request) (connector.Identity, error) {
+	if err := m.verifyProxy(r); err != nil {
+		m.logger.Warn("rejected request of untrusted proxy", "remote_addr", r.RemoteAddr, "err", err)
+		return connector.Identity{}, fmt.Errorf("untrusted proxy: %v", err)
+	}
 	remoteUser := r.Header.Get(m.userHeader)
 	if remoteUser == "" {
 		return connector.Identity{}, fmt.Errorf("required HTTP header %s is not set", m.userHeader)
@@ -124,12 +231,31 @@ func (m *callback) HandleCallback(s connector.Scopes, _ []byte, r *http.Request)
 		}
 		groups = append(splitheaderGroup, groups...)
 	}
+	emailVerified := true
+	if m.emailVerifiedHeader != "" {
+		if v := r.Header.Get(m.emailVerifiedHeader); v != "" {
+			var err error
+			if emailVerified, err = strconv.ParseBool(v); err != nil {
+				return connector.Identity{}, fmt.Errorf("invalid HTTP header %s: %v", m.emailVerifiedHeader, err)
+			}
+		}
+	}
+	var claims map[string]interface{}
+	for claim, header := range m.claimHeaders {
+		if v := r.Header.Get(header); v != "" {
+			if claims == nil {
+				claims = make(map[string]interface{})
+			}
+			claims[claim] = v
+		}
+	}
 	return connector.Identity{
 		UserID:            remoteUserID,
 		Username:          remoteUser,
 		PreferredUsername: remoteUserName,
 		Email:             remoteUserEmail,
-		EmailVerified:     true,
+		EmailVerified:     emailVerified,
 		Groups:            groups,
+		CustomClaims:      claims,
 	}, nil
 }
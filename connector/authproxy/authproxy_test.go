@@ -1,6 +1,9 @@
 package authproxy
 
 import (
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
 	"log/slog"
 	"net/http"
 	"reflect"
@@ -171,6 +174,111 @@ func TestStaticGroup(t *testing.T) {
 	expectEquals(t, ident.Groups[5], testStaticGroup2)
 }
 
+func TestClaimHeaders(t *testing.T) {
+	config := Config{
+		EmailVerifiedHeader: "X-Remote-Email-Verified",
+		ClaimHeaders:        map[string]string{"tenant": "X-Remote-Tenant", "department": "X-Remote-Department"},
+	}
+
+	conn, err := config.Open("test", logger)
+	expectNil(t, err)
+	callback := conn.(*callback)
+
+	req, err := http.NewRequest("GET", "/", nil)
+	expectNil(t, err)
+	req.Header = map[string][]string{
+		"X-Remote-User":           {testEmail},
+		"X-Remote-Email-Verified": {"false"},
+		"X-Remote-Tenant":         {"acme"},
+	}
+
+	ident, err := callback.HandleCallback(connector.Scopes{}, nil, req)
+	expectNil(t, err)
+
+	expectEquals(t, ident.EmailVerified, false)
+	expectEquals(t, ident.CustomClaims, map[string]interface{}{"tenant": "acme"})
+
+	req.Header.Set("X-Remote-Email-Verified", "maybe")
+	if _, err := callback.HandleCallback(connector.Scopes{}, nil, req); err == nil {
+		t.Error("expected invalid email verified header to be rejected")
+	}
+}
+
+func TestProxyTrust(t *testing.T) {
+	cert := &x509.Certificate{Subject: pkix.Name{CommonName: "oauth2-proxy"}, DNSNames: []string{"proxy.internal"}}
+	tests := []struct {
+		name       string
+		config     Config
+		remoteAddr string
+		secret     string
+		cert       *x509.Certificate
+		wantErr    bool
+	}{
+		{name: "no checks", remoteAddr: "203.0.113.7:1234"},
+		{
+			name:       "trusted proxy",
+			config:     Config{TrustedProxies: []string{"10.0.0.0/8", "192.0.2.1"}},
+			remoteAddr: "192.0.2.1:1234",
+		},
+		{
+			name:       "untrusted proxy",
+			config:     Config{TrustedProxies: []string{"10.0.0.0/8"}},
+			remoteAddr: "203.0.113.7:1234",
+			wantErr:    true,
+		},
+		{
+			name:   "shared secret",
+			config: Config{SharedSecret: "s3cret", SharedSecretHeader: "X-Proxy-Secret"},
+			secret: "s3cret",
+		},
+		{
+			name:    "wrong shared secret",
+			config:  Config{SharedSecret: "s3cret", SharedSecretHeader: "X-Proxy-Secret"},
+			secret:  "guess",
+			wantErr: true,
+		},
+		{
+			name:   "client certificate",
+			config: Config{RequireClientCert: true, ClientCertNames: []string{"proxy.internal"}},
+			cert:   cert,
+		},
+		{
+			name:    "client certificate with other name",
+			config:  Config{RequireClientCert: true, ClientCertNames: []string{"sso-appliance"}},
+			cert:    cert,
+			wantErr: true,
+		},
+		{
+			name:    "missing client certificate",
+			config:  Config{RequireClientCert: true},
+			wantErr: true,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			conn, err := tc.config.Open("test", logger)
+			expectNil(t, err)
+			callback := conn.(*callback)
+
+			req, err := http.NewRequest("GET", "/", nil)
+			expectNil(t, err)
+			req.RemoteAddr = tc.remoteAddr
+			req.Header.Set("X-Remote-User", testEmail)
+			if tc.secret != "" {
+				req.Header.Set("X-Proxy-Secret", tc.secret)
+			}
+			if tc.cert != nil {
+				req.TLS = &tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{tc.cert}}}
+			}
+
+			_, err = callback.HandleCallback(connector.Scopes{}, nil, req)
+			if tc.wantErr != (err != nil) {
+				t.Errorf("wantErr=%v, got %v", tc.wantErr, err)
+			}
+		})
+	}
+}
+
 func expectNil(t *testing.T, a interface{}) {
 	if a != nil {
 		t.Errorf("Expected %+v to equal nil", a)
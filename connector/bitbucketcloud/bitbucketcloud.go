@@ -42,6 +42,18 @@ type Config struct {
 	// When enabled, appends workspace permission suffixes (e.g. "workspace:owner",
 	// "workspace:member") to the groups claim, similar to GitLab's getGroupsPermission.
 	GetWorkspacePermissions bool `json:"getWorkspacePermissions,omitempty"`
+
+	// AllowedGroups are patterns, e.g. "acme-*", limiting the workspaces in
+	// the groups claim. Unlike Teams, users in none of them can still log in.
+	AllowedGroups []string `json:"allowedGroups,omitempty"`
+	// GroupPrefixes limits the groups claim to workspaces starting with one of
+	// the prefixes.
+	GroupPrefixes []string `json:"groupPrefixes,omitempty"`
+	// TopLevelGroups limits the groups claim to these workspaces and their
+	// permission groups.
+	TopLevelGroups []string `json:"topLevelGroups,omitempty"`
+	// MaxGroups caps the number of groups in the groups claim.
+	MaxGroups int `json:"maxGroups,omitempty"`
 }
 
 // Open returns a strategy for logging in through Bitbucket.
@@ -50,6 +62,15 @@ func (c *Config) Open(id string, logger *slog.Logger) (connector.Connector, erro
 		logger.Warn("bitbucket: includeTeamGroups is deprecated and has no effect; " +
 			"the Bitbucket 1.0 API it relied on has been removed by Atlassian")
 	}
+	groupLimit := groups.Limit{
+		Allowed:  c.AllowedGroups,
+		Prefixes: c.GroupPrefixes,
+		TopLevel: c.TopLevelGroups,
+		Max:      c.MaxGroups,
+	}
+	if err := groupLimit.Validate(); err != nil {
+		return nil, fmt.Errorf("bitbucket: %v", err)
+	}
 
 	b := bitbucketConnector{
 		redirectURI:             c.RedirectURI,
@@ -57,6 +78,7 @@ func (c *Config) Open(id string, logger *slog.Logger) (connector.Connector, erro
 		clientID:                c.ClientID,
 		clientSecret:            c.ClientSecret,
 		getWorkspacePermissions: c.GetWorkspacePermissions,
+		groupLimit:              groupLimit,
 		apiURL:                  apiURL,
 		logger:                  logger.With(slog.Group("connector", "type", "bitbucketcloud", "id", id)),
 	}
@@ -83,6 +105,7 @@ type bitbucketConnector struct {
 	logger                  *slog.Logger
 	apiURL                  string
 	getWorkspacePermissions bool
+	groupLimit              groups.Limit
 
 	// the following are used only for tests
 	hostName   string
@@ -364,14 +387,22 @@ func (b *bitbucketConnector) getGroups(ctx context.Context, client *http.Client,
 		if len(filteredTeams) == 0 {
 			return nil, fmt.Errorf("bitbucket: user %q is not in any of the required teams", userLogin)
 		}
-		return filteredTeams, nil
+		return b.limitGroups(ctx, filteredTeams, userLogin), nil
 	} else if groupScope {
-		return bitbucketTeams, nil
+		return b.limitGroups(ctx, bitbucketTeams, userLogin), nil
 	}
 
 	return nil, nil
 }
 
+func (b *bitbucketConnector) limitGroups(ctx context.Context, teams []string, userLogin string) []string {
+	limited, truncated := b.groupLimit.Apply(teams)
+	if truncated {
+		b.logger.WarnContext(ctx, "too many groups, truncating groups claim", "user", userLogin, "max_groups", b.groupLimit.Max)
+	}
+	return limited
+}
+
 type workspaceRef struct {
 	Slug string `json:"slug"`
 }
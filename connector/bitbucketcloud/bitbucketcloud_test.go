@@ -14,6 +14,7 @@ import (
 	"testing"
 
 	"github.com/dexidp/dex/connector"
+	"github.com/dexidp/dex/pkg/groups"
 )
 
 func TestUserGroups(t *testing.T) {
@@ -94,6 +95,50 @@ func TestUserGroupsWithPermissions(t *testing.T) {
 	})
 }
 
+func TestUserGroupsWithLimit(t *testing.T) {
+	workspacesResp := workspacesResponse{
+		pagedResponse: pagedResponse{
+			Size:    3,
+			Page:    1,
+			PageLen: 10,
+		},
+		Values: []workspaceAccess{
+			{Workspace: workspaceRef{Slug: "acme-web"}},
+			{Workspace: workspaceRef{Slug: "acme-ops"}},
+			{Workspace: workspaceRef{Slug: "other"}},
+		},
+	}
+
+	s := newTestServer(map[string]interface{}{
+		"/user/workspaces":                     workspacesResp,
+		"/user/workspaces/acme-web/permission": workspacePermission{Permission: "owner"},
+		"/user/workspaces/acme-ops/permission": workspacePermission{Permission: "member"},
+		"/user/workspaces/other/permission":    workspacePermission{Permission: "member"},
+	})
+	defer s.Close()
+
+	logger := slog.New(slog.NewTextHandler(&bytes.Buffer{}, nil))
+	c := bitbucketConnector{
+		apiURL:                  s.URL,
+		getWorkspacePermissions: true,
+		logger:                  logger,
+		groupLimit:              groups.Limit{TopLevel: []string{"acme-web"}},
+	}
+	got, err := c.getGroups(context.Background(), newClient(), true, "joebloggs")
+	expectNil(t, err)
+	expectEquals(t, got, []string{"acme-web", "acme-web:owner"})
+
+	c.groupLimit = groups.Limit{Prefixes: []string{"acme-"}, Max: 3}
+	got, err = c.getGroups(context.Background(), newClient(), true, "joebloggs")
+	expectNil(t, err)
+	expectEquals(t, got, []string{"acme-web", "acme-ops", "acme-web:owner"})
+
+	_, err = (&Config{AllowedGroups: []string{"["}}).Open("test", logger)
+	if err == nil {
+		t.Fatal("expected error for invalid group pattern")
+	}
+}
+
 func TestDeprecatedIncludeTeamGroups(t *testing.T) {
 	var buf bytes.Buffer
 	logger := slog.New(slog.NewTextHandler(&buf, nil))
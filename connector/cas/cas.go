@@ -0,0 +1,272 @@
+// Package cas implements logging in through a CAS server.
+package cas
+
+import (
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/dexidp/dex/connector"
+	groups_pkg "github.com/dexidp/dex/pkg/groups"
+	"github.com/dexidp/dex/pkg/httpclient"
+)
+
+// Supported versions of the CAS protocol.
+const (
+	version2 = "2.0"
+	version3 = "3.0"
+)
+
+// Config holds configuration options for CAS logins.
+//
+// An example config:
+//
+//	type: cas
+//	config:
+//	  url: https://cas.example.edu/cas
+//	  redirectURI: https://dex.example.com/callback
+//	  attributes:
+//	    email: mail
+//	    name: displayName
+//	    groups: memberOf
+//
+// See https://apereo.github.io/cas/development/protocol/CAS-Protocol-Specification.html
+type Config struct {
+	// URL of the CAS server, e.g. "https://cas.example.edu/cas".
+	URL         string `json:"url"`
+	RedirectURI string `json:"redirectURI"`
+
+	// Version of the CAS protocol, "2.0" or "3.0". Only CAS 3.0 releases
+	// attributes. Defaults to "3.0".
+	Version string `json:"version"`
+
+	// Attributes maps the attributes released by the CAS server to the
+	// user's claims. The CAS username is used for claims that aren't mapped.
+	Attributes struct {
+		UserID            string `json:"userID"`
+		Name              string `json:"name"`
+		PreferredUsername string `json:"preferredUsername"`
+		Email             string `json:"email"`
+		Groups            string `json:"groups"`
+	} `json:"attributes"`
+
+	// AllowedGroups, if set, only lets users in at least one of these groups
+	// log in.
+	AllowedGroups []string `json:"allowedGroups"`
+
+	// Certificates for SSL validation
+	RootCAs            []string `json:"rootCAs"`
+	InsecureSkipVerify bool     `json:"insecureSkipVerify"`
+}
+
+// Open returns a connector which can be used to login users through CAS.
+func (c *Config) Open(id string, logger *slog.Logger) (connector.Connector, error) {
+	casURL, err := url.Parse(strings.TrimSuffix(c.URL, "/"))
+	if err != nil || casURL.Scheme == "" || casURL.Host == "" {
+		return nil, fmt.Errorf("cas: invalid url %q", c.URL)
+	}
+
+	version := c.Version
+	switch version {
+	case "":
+		version = version3
+	case version2, version3:
+	default:
+		return nil, fmt.Errorf("cas: unsupported version %q", c.Version)
+	}
+	if version == version2 && c.Attributes != (Config{}).Attributes {
+		return nil, errors.New("cas: attributes are only released by CAS 3.0")
+	}
+	if len(c.AllowedGroups) > 0 && c.Attributes.Groups == "" {
+		return nil, errors.New("cas: allowedGroups requires the groups attribute to be mapped")
+	}
+
+	httpClient, err := httpclient.NewHTTPClient(c.RootCAs, c.InsecureSkipVerify)
+	if err != nil {
+		return nil, err
+	}
+
+	return &casConnector{
+		url:           casURL,
+		redirectURI:   c.RedirectURI,
+		version:       version,
+		userIDAttr:    c.Attributes.UserID,
+		nameAttr:      c.Attributes.Name,
+		preferredAttr: c.Attributes.PreferredUsername,
+		emailAttr:     c.Attributes.Email,
+		groupsAttr:    c.Attributes.Groups,
+		allowedGroups: c.AllowedGroups,
+		httpClient:    httpClient,
+		logger:        logger.With(slog.Group("connector", "type", "cas", "id", id)),
+	}, nil
+}
+
+var _ connector.CallbackConnector = (*casConnector)(nil)
+
+type casConnector struct {
+	url           *url.URL
+	redirectURI   string
+	version       string
+	userIDAttr    string
+	nameAttr      string
+	preferredAttr string
+	emailAttr     string
+	groupsAttr    string
+	allowedGroups []string
+	httpClient    *http.Client
+	logger        *slog.Logger
+}
+
+// serviceURL returns the URL CAS sends the user back to with a service
+// ticket. It carries the state, as CAS doesn't have a parameter for it. The
+// same URL must be given when validating the ticket.
+func (c *casConnector) serviceURL(state string) string {
+	return c.redirectURI + "?" + url.Values{"state": {state}}.Encode()
+}
+
+func (c *casConnector) endpoint(path string, query url.Values) string {
+	u := *c.url
+	u.Path += path
+	u.RawQuery = query.Encode()
+	return u.String()
+}
+
+func (c *casConnector) LoginURL(s connector.Scopes, callbackURL, state string) (string, []byte, error) {
+	if c.redirectURI != callbackURL {
+		return "", nil, fmt.Errorf("expected callback URL %q did not match the URL in the config %q", callbackURL, c.redirectURI)
+	}
+	return c.endpoint("/login", url.Values{"service": {c.serviceURL(state)}}), nil, nil
+}
+
+func (c *casConnector) HandleCallback(s connector.Scopes, connData []byte, r *http.Request) (identity connector.Identity, err error) {
+	q := r.URL.Query()
+	ticket := q.Get("ticket")
+	if ticket == "" {
+		return identity, errors.New("cas: no service ticket in callback")
+	}
+
+	success, err := c.validate(r.Context(), c.serviceURL(q.Get("state")), ticket)
+	if err != nil {
+		return identity, err
+	}
+
+	identity = connector.Identity{
+		UserID:            success.User,
+		Username:          success.User,
+		PreferredUsername: success.User,
+		// Like LDAP, CAS is backed by an institution's directory, whose
+		// addresses are considered verified.
+		EmailVerified: true,
+	}
+	attrs := success.Attributes.values()
+	for _, m := range []struct {
+		attr  string
+		claim *string
+	}{
+		{c.userIDAttr, &identity.UserID},
+		{c.nameAttr, &identity.Username},
+		{c.preferredAttr, &identity.PreferredUsername},
+		{c.emailAttr, &identity.Email},
+	} {
+		if m.attr == "" {
+			continue
+		}
+		vs := attrs[m.attr]
+		if len(vs) == 0 {
+			return identity, fmt.Errorf("cas: attribute %q not released for user %q", m.attr, success.User)
+		}
+		*m.claim = vs[0]
+	}
+
+	if c.groupsAttr != "" {
+		identity.Groups = attrs[c.groupsAttr]
+		if len(c.allowedGroups) > 0 {
+			groups := groups_pkg.Filter(identity.Groups, c.allowedGroups)
+			if len(groups) == 0 {
+				return identity, &connector.UserNotInRequiredGroupsError{UserID: identity.UserID, Groups: c.allowedGroups}
+			}
+			identity.Groups = groups
+		}
+	}
+	return identity, nil
+}
+
+// serviceResponse is the response of the serviceValidate endpoint.
+type serviceResponse struct {
+	XMLName xml.Name               `xml:"serviceResponse"`
+	Success *authenticationSuccess `xml:"authenticationSuccess"`
+	Failure *authenticationFailure `xml:"authenticationFailure"`
+}
+
+type authenticationSuccess struct {
+	User       string     `xml:"user"`
+	Attributes attributes `xml:"attributes"`
+}
+
+type authenticationFailure struct {
+	Code    string `xml:"code,attr"`
+	Message string `xml:",chardata"`
+}
+
+// attributes holds the attributes released for the user. Attributes with
+// several values are repeated.
+type attributes struct {
+	Values []struct {
+		XMLName xml.Name
+		Value   string `xml:",chardata"`
+	} `xml:",any"`
+}
+
+func (a attributes) values() map[string][]string {
+	m := make(map[string][]string)
+	for _, v := range a.Values {
+		m[v.XMLName.Local] = append(m[v.XMLName.Local], strings.TrimSpace(v.Value))
+	}
+	return m
+}
+
+// validate checks the service ticket with the CAS server.
+func (c *casConnector) validate(ctx context.Context, service, ticket string) (*authenticationSuccess, error) {
+	path := "/serviceValidate"
+	if c.version == version3 {
+		path = "/p3/serviceValidate"
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.endpoint(path, url.Values{
+		"service": {service},
+		"ticket":  {ticket},
+	}), nil)
+	if err != nil {
+		return nil, fmt.Errorf("cas: new request: %v", err)
+	}
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("cas: validate ticket: %v", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, fmt.Errorf("cas: read response: %v", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("cas: validate ticket: %s: %s", resp.Status, body)
+	}
+
+	var sr serviceResponse
+	if err := xml.Unmarshal(body, &sr); err != nil {
+		return nil, fmt.Errorf("cas: parse response: %v", err)
+	}
+	switch {
+	case sr.Failure != nil:
+		return nil, fmt.Errorf("cas: ticket validation failed: %s: %s", sr.Failure.Code, strings.TrimSpace(sr.Failure.Message))
+	case sr.Success == nil || sr.Success.User == "":
+		return nil, errors.New("cas: malformed validation response")
+	}
+	return sr.Success, nil
+}
@@ -0,0 +1,159 @@
+package cas
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"testing"
+
+	"github.com/dexidp/dex/connector"
+)
+
+const redirectURI = "https://dex.example.com/callback"
+
+const successResponse = `<cas:serviceResponse xmlns:cas="http://www.yale.edu/tp/cas">
+  <cas:authenticationSuccess>
+    <cas:user>jdoe</cas:user>
+    <cas:attributes>
+      <cas:mail>jane.doe@example.edu</cas:mail>
+      <cas:displayName>Jane Doe</cas:displayName>
+      <cas:memberOf>staff</cas:memberOf>
+      <cas:memberOf>faculty</cas:memberOf>
+    </cas:attributes>
+  </cas:authenticationSuccess>
+</cas:serviceResponse>`
+
+const failureResponse = `<cas:serviceResponse xmlns:cas="http://www.yale.edu/tp/cas">
+  <cas:authenticationFailure code="INVALID_TICKET">Ticket ST-1 not recognized</cas:authenticationFailure>
+</cas:serviceResponse>`
+
+// newTestServer runs a CAS server which accepts the ticket "ST-1" for the
+// service URL with state "some-state".
+func newTestServer(t *testing.T, path string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != path {
+			http.NotFound(w, r)
+			return
+		}
+		q := r.URL.Query()
+		if q.Get("ticket") == "ST-1" && q.Get("service") == redirectURI+"?state=some-state" {
+			fmt.Fprint(w, successResponse)
+			return
+		}
+		fmt.Fprint(w, failureResponse)
+	}))
+}
+
+func newConnector(t *testing.T, c Config) *casConnector {
+	c.RedirectURI = redirectURI
+	conn, err := c.Open("cas", slog.New(slog.DiscardHandler))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return conn.(*casConnector)
+}
+
+func callback(ticket string) *http.Request {
+	return httptest.NewRequest(http.MethodGet, redirectURI+"?"+url.Values{"state": {"some-state"}, "ticket": {ticket}}.Encode(), nil)
+}
+
+func TestLoginURL(t *testing.T) {
+	c := newConnector(t, Config{URL: "https://cas.example.edu/cas/"})
+	loginURL, _, err := c.LoginURL(connector.Scopes{}, redirectURI, "some-state")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "https://cas.example.edu/cas/login?service=" + url.QueryEscape(redirectURI+"?state=some-state")
+	if loginURL != want {
+		t.Errorf("expected login URL %q, got %q", want, loginURL)
+	}
+}
+
+func TestHandleCallback(t *testing.T) {
+	s := newTestServer(t, "/cas/p3/serviceValidate")
+	defer s.Close()
+
+	config := Config{URL: s.URL + "/cas"}
+	config.Attributes.Email = "mail"
+	config.Attributes.Name = "displayName"
+	config.Attributes.Groups = "memberOf"
+	c := newConnector(t, config)
+
+	identity, err := c.HandleCallback(connector.Scopes{}, nil, callback("ST-1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := connector.Identity{
+		UserID:            "jdoe",
+		Username:          "Jane Doe",
+		PreferredUsername: "jdoe",
+		Email:             "jane.doe@example.edu",
+		EmailVerified:     true,
+		Groups:            []string{"staff", "faculty"},
+	}
+	if !reflect.DeepEqual(identity, want) {
+		t.Errorf("expected identity %+v, got %+v", want, identity)
+	}
+
+	if _, err := c.HandleCallback(connector.Scopes{}, nil, callback("ST-2")); err == nil {
+		t.Error("expected error for invalid ticket")
+	}
+}
+
+func TestHandleCallbackVersion2(t *testing.T) {
+	s := newTestServer(t, "/serviceValidate")
+	defer s.Close()
+
+	c := newConnector(t, Config{URL: s.URL, Version: "2.0"})
+	identity, err := c.HandleCallback(connector.Scopes{}, nil, callback("ST-1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if identity.UserID != "jdoe" || identity.Username != "jdoe" || identity.Groups != nil {
+		t.Errorf("unexpected identity %+v", identity)
+	}
+}
+
+func TestAllowedGroups(t *testing.T) {
+	s := newTestServer(t, "/p3/serviceValidate")
+	defer s.Close()
+
+	config := Config{URL: s.URL, AllowedGroups: []string{"students"}}
+	config.Attributes.Groups = "memberOf"
+	c := newConnector(t, config)
+
+	_, err := c.HandleCallback(connector.Scopes{}, nil, callback("ST-1"))
+	var groupsErr *connector.UserNotInRequiredGroupsError
+	if !errors.As(err, &groupsErr) {
+		t.Errorf("expected *connector.UserNotInRequiredGroupsError, got %v", err)
+	}
+
+	c.allowedGroups = []string{"faculty", "students"}
+	identity, err := c.HandleCallback(connector.Scopes{}, nil, callback("ST-1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(identity.Groups, []string{"faculty"}) {
+		t.Errorf("expected groups to be filtered, got %q", identity.Groups)
+	}
+}
+
+func TestConfigValidation(t *testing.T) {
+	v2Attributes := Config{URL: "https://cas.example.edu", Version: "2.0"}
+	v2Attributes.Attributes.Email = "mail"
+
+	for name, c := range map[string]Config{
+		"missing url":       {},
+		"bad version":       {URL: "https://cas.example.edu", Version: "1.0"},
+		"v2 attributes":     v2Attributes,
+		"groups not mapped": {URL: "https://cas.example.edu", AllowedGroups: []string{"staff"}},
+	} {
+		if _, err := c.Open("cas", slog.New(slog.DiscardHandler)); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"context"
 	"fmt"
 	"net/http"
+	"time"
 )
 
 // UserNotInRequiredGroupsError is returned by a connector when a user
@@ -19,6 +20,27 @@ func (e *UserNotInRequiredGroupsError) Error() string {
 	return fmt.Sprintf("user %q is not in any of the required groups %v", e.UserID, e.Groups)
 }
 
+// LoginError is returned by a connector when the upstream refuses a login
+// for a reason the user can act on, like a locked account. The server shows
+// its message, translated, and its code instead of a generic error.
+type LoginError struct {
+	// Code identifies the failure, e.g. "account_locked".
+	Code string
+	// Message is the English message shown to users. The server translates
+	// it with its message catalogs.
+	Message string
+	// Err is the upstream error, which is only logged.
+	Err error
+}
+
+func (e *LoginError) Error() string {
+	return fmt.Sprintf("%s: %v", e.Code, e.Err)
+}
+
+func (e *LoginError) Unwrap() error {
+	return e.Err
+}
+
 // Connector is a mechanism for federating login to a remote identity service.
 //
 // Implementations are expected to implement either the PasswordConnector or
@@ -44,6 +66,12 @@ type Identity struct {
 
 	Groups []string
 
+	// CustomClaims are claims of the user beyond the above, e.g. tenant,
+	// organization, roles or permissions. They are added to the tokens of
+	// the clients they are released to by the client's
+	// ReleasedConnectorClaims, and never replace claims set by dex.
+	CustomClaims map[string]interface{}
+
 	// ConnectorData holds data used by the connector for subsequent requests after initial
 	// authentication, such as access tokens for upstream provides.
 	//
@@ -82,6 +110,58 @@ type CallbackConnector interface {
 	HandleCallback(s Scopes, connData []byte, r *http.Request) (identity Identity, err error)
 }
 
+// AuthRequestParams holds parameters of the client's authorization request
+// which a connector may forward to its upstream.
+type AuthRequestParams struct {
+	// Prompt is the OpenID Connect prompt parameter, e.g. "none" for silent
+	// re-authentication.
+	Prompt string
+
+	// LoginHint is the OpenID Connect login_hint parameter, a hint about the
+	// identifier the end user might use to log in.
+	LoginHint string
+
+	// RelayState is an opaque value signed by the server, carrying the state
+	// and the authorization request to restart the login from if it expires.
+	// Connectors whose upstream round trip can outlive the state, e.g.
+	// through a SAML IdP, pass it back to the callback URL as the
+	// "relay_state" query parameter.
+	RelayState string
+}
+
+// AuthRequestParamsConnector is an optional interface for callback connectors
+// which forward parameters of the client's authorization request upstream.
+// The server calls LoginURLWithParams instead of LoginURL.
+//
+// Connectors forwarding prompt=none should report the upstream's
+// login_required and similar errors from HandleCallback as an *OAuth2Error,
+// so the server can return them to the client.
+type AuthRequestParamsConnector interface {
+	LoginURLWithParams(s Scopes, callbackURL, state string, params AuthRequestParams) (string, []byte, error)
+}
+
+// OAuth2Error is an OAuth2 error response from an upstream provider.
+type OAuth2Error struct {
+	Code        string
+	Description string
+}
+
+func (e *OAuth2Error) Error() string {
+	if e.Description == "" {
+		return e.Code
+	}
+	return e.Code + ": " + e.Description
+}
+
+// FormPostConnector is implemented by callback connectors whose upstream
+// returns the authorization response as a form POST to the callback URL
+// ("response_mode=form_post"), rather than in the query string.
+//
+// See: https://openid.net/specs/oauth-v2-form-post-response-mode-1_0.html
+type FormPostConnector interface {
+	FormPost() bool
+}
+
 // SAMLConnector represents SAML connectors which implement the HTTP POST binding.
 //
 //	RelayState is handled by the server.
@@ -105,6 +185,31 @@ type SAMLConnector interface {
 	HandlePOST(s Scopes, samlResponse, inResponseTo string) (identity Identity, err error)
 }
 
+// IdPInitiatedTarget is the client a user is logged in to by an unsolicited
+// SAML response.
+type IdPInitiatedTarget struct {
+	ClientID string
+	// RedirectURI the authorization code is sent to. If empty, the client's
+	// only redirect URI is used.
+	RedirectURI string
+	// Scopes of the login. Defaults to "openid".
+	Scopes []string
+}
+
+// SAMLIdPInitiatedConnector is implemented by SAML connectors which accept
+// unsolicited responses, sent by the IdP without a request of dex
+// (IdP-initiated SSO).
+type SAMLIdPInitiatedConnector interface {
+	// IdPInitiatedTarget returns the client unsolicited responses with the
+	// relay state log users in to, or false if they aren't accepted.
+	IdPInitiatedTarget(relayState string) (IdPInitiatedTarget, bool)
+
+	// HandleUnsolicitedPOST is HandlePOST for responses which aren't in
+	// response to a request. The connector must reject responses it has
+	// accepted before.
+	HandleUnsolicitedPOST(ctx context.Context, s Scopes, samlResponse string) (identity Identity, err error)
+}
+
 // RefreshConnector is a connector that can update the client claims.
 type RefreshConnector interface {
 	// Refresh is called when a client attempts to claim a refresh token. The
@@ -117,6 +222,21 @@ type TokenIdentityConnector interface {
 	TokenIdentity(ctx context.Context, subjectTokenType, subjectToken string) (Identity, error)
 }
 
+// TokenExchangeParams holds parameters of the client's token exchange request
+// which a connector may use to build the identity.
+type TokenExchangeParams struct {
+	// Tenant is the tenant the client exchanges the token for. Connectors
+	// refusing it return an *OAuth2Error with the "invalid_target" code.
+	Tenant string
+}
+
+// TokenExchangeParamsConnector is an optional interface for token identity
+// connectors which use parameters of the token exchange request. The server
+// calls TokenIdentityWithParams instead of TokenIdentity.
+type TokenExchangeParamsConnector interface {
+	TokenIdentityWithParams(ctx context.Context, subjectTokenType, subjectToken string, params TokenExchangeParams) (Identity, error)
+}
+
 // LogoutCallbackConnector is a connector that can initiate upstream logout and
 // optionally validate the upstream provider's logout response.
 // Connectors that implement this interface support RP-Initiated Logout by
@@ -137,6 +257,105 @@ type LogoutCallbackConnector interface {
 	HandleLogoutCallback(ctx context.Context, r *http.Request) error
 }
 
+// BackchannelLogoutConnector is an optional interface for connectors that end
+// the upstream session server-side when the user logs out of dex, instead of
+// or in addition to redirecting to the upstream logout endpoint.
+type BackchannelLogoutConnector interface {
+	// BackchannelLogout ends the upstream session held in connectorData.
+	// Connectors not configured to do so return nil.
+	BackchannelLogout(ctx context.Context, connectorData []byte) error
+}
+
+// HealthChecker is an optional interface for connectors that can report whether
+// their upstream identity provider is reachable. It is used by the readiness
+// probe and must not perform any user-specific work.
+type HealthChecker interface {
+	HealthCheck(ctx context.Context) error
+}
+
+// TokenRevoker is an optional interface for connectors that can revoke the
+// upstream tokens of a user, e.g. when dex detects that a refresh token was
+// stolen.
+type TokenRevoker interface {
+	// RevokeTokens revokes the upstream tokens held in connectorData.
+	RevokeTokens(ctx context.Context, connectorData []byte) error
+}
+
+// Description is what a connector resolved about its upstream, e.g. through
+// discovery.
+type Description struct {
+	// Endpoints maps endpoint names, like "authorization", to URLs.
+	Endpoints map[string]string
+	// Scopes requested from the upstream.
+	Scopes []string
+}
+
+// Describer is an optional interface for connectors that can describe their
+// upstream, which helps diagnosing connector configs.
+type Describer interface {
+	Describe() Description
+}
+
 type PayloadExtender interface {
 	ExtendPayload(scopes []string, payload []byte, connectorData []byte) ([]byte, error)
 }
+
+// ClaimsPreviewer is an optional interface for PayloadExtenders listing the
+// claims their ExtendPayload adds which identify the user, so the approval
+// page can show them.
+type ClaimsPreviewer interface {
+	PreviewedClaims() []PreviewedClaim
+}
+
+// PreviewedClaim is a claim shown to the user on the approval page.
+type PreviewedClaim struct {
+	// Path of the claim, nested claims are given as dotted paths.
+	Path string
+	// Label shown for the claim.
+	Label string
+}
+
+// ReplayCache remembers one-time values, like the IDs of assertions, until
+// they expire. The server shares it between all dex instances using the same
+// storage, if the storage supports it.
+type ReplayCache interface {
+	// Use records id until expiry, or fails if it is already recorded.
+	Use(ctx context.Context, id string, expiry time.Time) error
+}
+
+// ReplayCacheConfig is an optional interface for connector configs that
+// remember one-time values. The server calls SetReplayCache before Open;
+// configs must keep working without it.
+type ReplayCacheConfig interface {
+	SetReplayCache(c ReplayCache)
+}
+
+// HTTPClientFactory creates the HTTP clients a connector uses to call its
+// upstream. The clients share the server's connection pools, proxy settings
+// and request metrics.
+type HTTPClientFactory interface {
+	HTTPClient(rootCAs []string, insecureSkipVerify bool) (*http.Client, error)
+}
+
+// HTTPTransportConfig tunes the connection pool of a connector's HTTP
+// clients. Zero values keep the server's settings.
+type HTTPTransportConfig struct {
+	MaxIdleConnsPerHost int
+	IdleConnTimeout     time.Duration
+	// TLSSessionCacheSize is the number of TLS sessions kept for resumption,
+	// negative disables resumption.
+	TLSSessionCacheSize int
+}
+
+// TunedHTTPClientFactory is an optional interface for HTTPClientFactory
+// implementations which can tune the connection pool of a client.
+type TunedHTTPClientFactory interface {
+	TunedHTTPClient(rootCAs []string, insecureSkipVerify bool, t HTTPTransportConfig) (*http.Client, error)
+}
+
+// HTTPClientConfig is an optional interface for connector configs that can
+// take their HTTP clients from the server. The server calls
+// SetHTTPClientFactory before Open; configs must keep working without it.
+type HTTPClientConfig interface {
+	SetHTTPClientFactory(f HTTPClientFactory)
+}
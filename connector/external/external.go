@@ -0,0 +1,384 @@
+// Package external implements connectors running out of process, which dex
+// talks to over gRPC on a local socket.
+package external
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"path/filepath"
+	"runtime/debug"
+	"slices"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+
+	"github.com/dexidp/dex/connector"
+	"github.com/dexidp/dex/connector/external/plugin"
+)
+
+// ProtocolVersion is the highest version of the plugin protocol this package
+// speaks. Plugins answer the handshake with the version they speak, which
+// must not be higher.
+const ProtocolVersion = 1
+
+// Capabilities a plugin reports in the handshake.
+const (
+	CapabilityCallback      = "callback"
+	CapabilityRefresh       = "refresh"
+	CapabilityTokenIdentity = "token_identity"
+)
+
+// healthService is the service name plugins report their health under.
+const healthService = "plugin.Connector"
+
+// Config holds the configuration parameters for an external connector.
+//
+// An example config:
+//
+//	type: external
+//	id: acme
+//	name: ACME
+//	config:
+//	  socket: /run/dex/acme.sock
+//	  timeout: 10s
+//	  config:
+//	    issuer: https://login.acme.example.com
+type Config struct {
+	// Socket is the path of the unix socket the plugin listens on.
+	Socket string `json:"socket"`
+
+	// Timeout of a call to the plugin. Defaults to 30s.
+	Timeout string `json:"timeout"`
+
+	// Config is passed to the plugin as is during the handshake.
+	Config json.RawMessage `json:"config"`
+}
+
+// Open dials the plugin and opens its connector with a handshake.
+func (c *Config) Open(id string, logger *slog.Logger) (connector.Connector, error) {
+	if c.Socket == "" {
+		return nil, errors.New("external: no socket configured")
+	}
+	timeout := 30 * time.Second
+	if c.Timeout != "" {
+		d, err := time.ParseDuration(c.Timeout)
+		if err != nil {
+			return nil, fmt.Errorf("external: invalid timeout %q: %v", c.Timeout, err)
+		}
+		timeout = d
+	}
+	socket, err := filepath.Abs(c.Socket)
+	if err != nil {
+		return nil, fmt.Errorf("external: invalid socket %q: %v", c.Socket, err)
+	}
+
+	// Calls wait for a restarting plugin until they time out.
+	conn, err := grpc.NewClient("unix://"+socket,
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithDefaultCallOptions(grpc.WaitForReady(true)),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("external: failed to dial plugin: %v", err)
+	}
+	p := &pluginConnector{
+		id:      id,
+		config:  c.Config,
+		timeout: timeout,
+		conn:    conn,
+		client:  plugin.NewConnectorClient(conn),
+		health:  grpc_health_v1.NewHealthClient(conn),
+		logger:  logger.With(slog.Group("connector", "type", "external", "id", id)),
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	resp, err := p.handshake(ctx)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	p.logger.Info("plugin opened", "socket", socket, "protocol_version", resp.ProtocolVersion, "capabilities", resp.Capabilities)
+
+	if !slices.Contains(resp.Capabilities, CapabilityCallback) {
+		conn.Close()
+		return nil, errors.New("external: plugin doesn't support callback logins")
+	}
+	refresh := slices.Contains(resp.Capabilities, CapabilityRefresh)
+	tokenIdentity := slices.Contains(resp.Capabilities, CapabilityTokenIdentity)
+
+	// The server detects the optional interfaces of a connector by type
+	// assertions, so only the calls the plugin supports are exposed.
+	switch {
+	case refresh && tokenIdentity:
+		return &refreshTokenIdentityConnector{p, refresher{p}, tokenIdentifier{p}}, nil
+	case refresh:
+		return &refreshConnector{p, refresher{p}}, nil
+	case tokenIdentity:
+		return &tokenIdentityConnector{p, tokenIdentifier{p}}, nil
+	}
+	return p, nil
+}
+
+// dexVersion is the version of the dex build, sent for logging.
+func dexVersion() string {
+	if info, ok := debug.ReadBuildInfo(); ok {
+		return info.Main.Version
+	}
+	return ""
+}
+
+var (
+	_ connector.CallbackConnector      = (*pluginConnector)(nil)
+	_ connector.HealthChecker          = (*pluginConnector)(nil)
+	_ connector.RefreshConnector       = (*refreshConnector)(nil)
+	_ connector.TokenIdentityConnector = (*tokenIdentityConnector)(nil)
+	_ connector.RefreshConnector       = (*refreshTokenIdentityConnector)(nil)
+	_ connector.TokenIdentityConnector = (*refreshTokenIdentityConnector)(nil)
+	_ io.Closer                        = (*pluginConnector)(nil)
+)
+
+type pluginConnector struct {
+	id      string
+	config  []byte
+	timeout time.Duration
+
+	conn   *grpc.ClientConn
+	client plugin.ConnectorClient
+	health grpc_health_v1.HealthClient
+	logger *slog.Logger
+}
+
+type refreshConnector struct {
+	*pluginConnector
+	refresher
+}
+
+type tokenIdentityConnector struct {
+	*pluginConnector
+	tokenIdentifier
+}
+
+type refreshTokenIdentityConnector struct {
+	*pluginConnector
+	refresher
+	tokenIdentifier
+}
+
+func (p *pluginConnector) handshake(ctx context.Context) (*plugin.HandshakeResp, error) {
+	resp, err := p.client.Handshake(ctx, &plugin.HandshakeReq{
+		ProtocolVersion: ProtocolVersion,
+		DexVersion:      dexVersion(),
+		ConnectorId:     p.id,
+		Config:          p.config,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("external: handshake failed: %v", callError(err))
+	}
+	if resp.ProtocolVersion < 1 || resp.ProtocolVersion > ProtocolVersion {
+		return nil, fmt.Errorf("external: plugin speaks protocol version %d, dex supports 1 to %d", resp.ProtocolVersion, ProtocolVersion)
+	}
+	return resp, nil
+}
+
+// call calls the plugin with the timeout. A plugin which restarted since the
+// handshake fails calls as a failed precondition: the handshake is then
+// repeated and the call retried once.
+func (p *pluginConnector) call(ctx context.Context, f func(ctx context.Context) error) error {
+	ctx, cancel := context.WithTimeout(ctx, p.timeout)
+	defer cancel()
+
+	err := f(ctx)
+	if status.Code(err) == codes.FailedPrecondition {
+		p.logger.Info("plugin lost its connector, repeating the handshake")
+		if _, herr := p.handshake(ctx); herr != nil {
+			return herr
+		}
+		err = f(ctx)
+	}
+	if err != nil {
+		return callError(err)
+	}
+	return nil
+}
+
+// callError strips the gRPC status from errors returned by the plugin.
+func callError(err error) error {
+	if s, ok := status.FromError(err); ok {
+		return errors.New(s.Message())
+	}
+	return err
+}
+
+func (p *pluginConnector) LoginURL(s connector.Scopes, callbackURL, state string) (string, []byte, error) {
+	var resp *plugin.LoginURLResp
+	err := p.call(context.Background(), func(ctx context.Context) (err error) {
+		resp, err = p.client.LoginURL(ctx, &plugin.LoginURLReq{
+			Scopes:      toScopes(s),
+			CallbackUrl: callbackURL,
+			State:       state,
+		})
+		return err
+	})
+	if err != nil {
+		return "", nil, err
+	}
+	return resp.Url, resp.ConnectorData, nil
+}
+
+func (p *pluginConnector) HandleCallback(s connector.Scopes, connData []byte, r *http.Request) (connector.Identity, error) {
+	req := &plugin.HandleCallbackReq{
+		Scopes:        toScopes(s),
+		ConnectorData: connData,
+		Method:        r.Method,
+		Url:           requestURL(r),
+	}
+	for name, values := range r.Header {
+		req.Headers = append(req.Headers, &plugin.Header{Name: name, Values: values})
+	}
+	if r.Body != nil {
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			return connector.Identity{}, fmt.Errorf("external: failed to read callback body: %v", err)
+		}
+		req.Body = body
+	}
+
+	var resp *plugin.HandleCallbackResp
+	err := p.call(r.Context(), func(ctx context.Context) (err error) {
+		resp, err = p.client.HandleCallback(ctx, req)
+		return err
+	})
+	if err != nil {
+		return connector.Identity{}, err
+	}
+	return toIdentity(resp.Identity)
+}
+
+// requestURL is the absolute URL of a request to the server.
+func requestURL(r *http.Request) string {
+	u := *r.URL
+	if u.Host == "" {
+		u.Host = r.Host
+	}
+	if u.Scheme == "" {
+		u.Scheme = "http"
+		if r.TLS != nil {
+			u.Scheme = "https"
+		}
+	}
+	return u.String()
+}
+
+// HealthCheck asks the plugin for its health, which includes the one of the
+// upstream of its connector.
+func (p *pluginConnector) HealthCheck(ctx context.Context) error {
+	var resp *grpc_health_v1.HealthCheckResponse
+	err := p.call(ctx, func(ctx context.Context) (err error) {
+		resp, err = p.health.Check(ctx, &grpc_health_v1.HealthCheckRequest{Service: healthService})
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("external: health check failed: %v", err)
+	}
+	if resp.Status != grpc_health_v1.HealthCheckResponse_SERVING {
+		return fmt.Errorf("external: plugin is %s", resp.Status)
+	}
+	return nil
+}
+
+func (p *pluginConnector) Close() error {
+	return p.conn.Close()
+}
+
+type refresher struct{ p *pluginConnector }
+
+func (r refresher) Refresh(ctx context.Context, s connector.Scopes, identity connector.Identity) (connector.Identity, error) {
+	in, err := fromIdentity(identity)
+	if err != nil {
+		return identity, err
+	}
+	var resp *plugin.RefreshResp
+	err = r.p.call(ctx, func(ctx context.Context) (err error) {
+		resp, err = r.p.client.Refresh(ctx, &plugin.RefreshReq{Scopes: toScopes(s), Identity: in})
+		return err
+	})
+	if err != nil {
+		return identity, err
+	}
+	return toIdentity(resp.Identity)
+}
+
+type tokenIdentifier struct{ p *pluginConnector }
+
+func (t tokenIdentifier) TokenIdentity(ctx context.Context, subjectTokenType, subjectToken string) (connector.Identity, error) {
+	var resp *plugin.TokenIdentityResp
+	err := t.p.call(ctx, func(ctx context.Context) (err error) {
+		resp, err = t.p.client.TokenIdentity(ctx, &plugin.TokenIdentityReq{
+			SubjectTokenType: subjectTokenType,
+			SubjectToken:     subjectToken,
+		})
+		return err
+	})
+	if err != nil {
+		return connector.Identity{}, err
+	}
+	return toIdentity(resp.Identity)
+}
+
+func toScopes(s connector.Scopes) *plugin.Scopes {
+	return &plugin.Scopes{OfflineAccess: s.OfflineAccess, Groups: s.Groups}
+}
+
+func fromScopes(s *plugin.Scopes) connector.Scopes {
+	return connector.Scopes{OfflineAccess: s.GetOfflineAccess(), Groups: s.GetGroups()}
+}
+
+func fromIdentity(identity connector.Identity) (*plugin.Identity, error) {
+	i := &plugin.Identity{
+		UserId:            identity.UserID,
+		Username:          identity.Username,
+		PreferredUsername: identity.PreferredUsername,
+		Email:             identity.Email,
+		EmailVerified:     identity.EmailVerified,
+		Groups:            identity.Groups,
+		ConnectorData:     identity.ConnectorData,
+	}
+	if len(identity.CustomClaims) > 0 {
+		data, err := json.Marshal(identity.CustomClaims)
+		if err != nil {
+			return nil, fmt.Errorf("external: failed to marshal custom claims: %v", err)
+		}
+		i.CustomClaims = data
+	}
+	return i, nil
+}
+
+func toIdentity(i *plugin.Identity) (connector.Identity, error) {
+	if i == nil {
+		return connector.Identity{}, errors.New("external: plugin returned no identity")
+	}
+	identity := connector.Identity{
+		UserID:            i.UserId,
+		Username:          i.Username,
+		PreferredUsername: i.PreferredUsername,
+		Email:             i.Email,
+		EmailVerified:     i.EmailVerified,
+		Groups:            i.Groups,
+		ConnectorData:     i.ConnectorData,
+	}
+	if len(i.CustomClaims) > 0 {
+		if err := json.Unmarshal(i.CustomClaims, &identity.CustomClaims); err != nil {
+			return connector.Identity{}, fmt.Errorf("external: invalid custom claims: %v", err)
+		}
+	}
+	return identity, nil
+}
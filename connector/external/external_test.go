@@ -0,0 +1,187 @@
+package external
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net"
+	"net/http/httptest"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/connectivity"
+
+	"github.com/dexidp/dex/connector"
+	"github.com/dexidp/dex/connector/mock"
+)
+
+// healthyCallback is the mock callback connector with a health check.
+type healthyCallback struct {
+	*mock.Callback
+	unhealthy *atomic.Bool
+}
+
+func (c healthyCallback) HealthCheck(ctx context.Context) error {
+	if c.unhealthy.Load() {
+		return errors.New("upstream down")
+	}
+	return nil
+}
+
+// passwordOnly is a connector the protocol can't serve.
+type passwordOnly struct{}
+
+func (passwordOnly) Prompt() string { return "" }
+
+func (passwordOnly) Login(context.Context, connector.Scopes, string, string) (connector.Identity, bool, error) {
+	return connector.Identity{}, false, nil
+}
+
+func servePlugin(t *testing.T, open OpenFunc) string {
+	t.Helper()
+	socket := filepath.Join(t.TempDir(), "plugin.sock")
+	startPlugin(t, socket, open)
+	return socket
+}
+
+// startPlugin serves a plugin on the socket until stop is called.
+func startPlugin(t *testing.T, socket string, open OpenFunc) (stop func()) {
+	t.Helper()
+	l, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := grpc.NewServer()
+	Register(s, open, slog.New(slog.DiscardHandler))
+	go s.Serve(l)
+	t.Cleanup(s.Stop)
+	return s.Stop
+}
+
+func TestExternalConnector(t *testing.T) {
+	var unhealthy atomic.Bool
+	var gotConfig []byte
+	socket := servePlugin(t, func(id string, config []byte, logger *slog.Logger) (connector.Connector, error) {
+		if id != "plugin" {
+			t.Errorf("expected connector ID %q, got %q", "plugin", id)
+		}
+		gotConfig = config
+		m := mock.NewCallbackConnector(logger).(*mock.Callback)
+		m.Identity.CustomClaims = map[string]interface{}{"tenant": "acme"}
+		return healthyCallback{m, &unhealthy}, nil
+	})
+
+	config := Config{Socket: socket, Config: json.RawMessage(`{"greeting":"hello"}`)}
+	conn, err := config.Open("plugin", slog.New(slog.DiscardHandler))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.(*refreshTokenIdentityConnector).Close()
+
+	if string(gotConfig) != `{"greeting":"hello"}` {
+		t.Errorf("plugin got config %s", gotConfig)
+	}
+
+	callback, ok := conn.(connector.CallbackConnector)
+	if !ok {
+		t.Fatal("expected a callback connector")
+	}
+	loginURL, _, err := callback.LoginURL(connector.Scopes{Groups: true}, "https://dex.example.com/callback", "some-state")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "https://dex.example.com/callback?state=some-state"; loginURL != want {
+		t.Errorf("expected login URL %q, got %q", want, loginURL)
+	}
+
+	r := httptest.NewRequest("GET", "/callback?state=some-state", nil)
+	identity, err := callback.HandleCallback(connector.Scopes{Groups: true}, nil, r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := mock.NewCallbackConnector(nil).(*mock.Callback).Identity
+	want.CustomClaims = map[string]interface{}{"tenant": "acme"}
+	if !reflect.DeepEqual(identity, want) {
+		t.Errorf("expected identity %#v, got %#v", want, identity)
+	}
+
+	refreshed, err := conn.(connector.RefreshConnector).Refresh(context.Background(), connector.Scopes{}, identity)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if refreshed.UserID != want.UserID {
+		t.Errorf("expected refreshed user %q, got %q", want.UserID, refreshed.UserID)
+	}
+
+	exchanged, err := conn.(connector.TokenIdentityConnector).TokenIdentity(context.Background(), "urn:ietf:params:oauth:token-type:access_token", "token")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exchanged.UserID != want.UserID {
+		t.Errorf("expected exchanged user %q, got %q", want.UserID, exchanged.UserID)
+	}
+
+	checker := conn.(connector.HealthChecker)
+	if err := checker.HealthCheck(context.Background()); err != nil {
+		t.Errorf("expected a healthy plugin, got %v", err)
+	}
+	unhealthy.Store(true)
+	if err := checker.HealthCheck(context.Background()); err == nil || !strings.Contains(err.Error(), "NOT_SERVING") {
+		t.Errorf("expected an unhealthy plugin, got %v", err)
+	}
+}
+
+func TestExternalConnectorRehandshake(t *testing.T) {
+	var opened atomic.Int32
+	open := func(id string, config []byte, logger *slog.Logger) (connector.Connector, error) {
+		opened.Add(1)
+		return mock.NewCallbackConnector(logger), nil
+	}
+	socket := filepath.Join(t.TempDir(), "plugin.sock")
+	stop := startPlugin(t, socket, open)
+
+	conn, err := (&Config{Socket: socket}).Open("plugin", slog.New(slog.DiscardHandler))
+	if err != nil {
+		t.Fatal(err)
+	}
+	callback := conn.(*refreshTokenIdentityConnector)
+	defer callback.Close()
+
+	// The plugin restarts and forgets the connector.
+	stop()
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	callback.conn.WaitForStateChange(ctx, connectivity.Ready)
+	startPlugin(t, socket, open)
+
+	if _, _, err := callback.LoginURL(connector.Scopes{}, "https://dex.example.com/callback", "state"); err != nil {
+		t.Fatalf("expected the call to succeed after a new handshake, got %v", err)
+	}
+	if n := opened.Load(); n != 2 {
+		t.Errorf("expected the connector to be opened twice, got %d", n)
+	}
+}
+
+func TestExternalConnectorUnsupported(t *testing.T) {
+	socket := servePlugin(t, func(id string, config []byte, logger *slog.Logger) (connector.Connector, error) {
+		return passwordOnly{}, nil
+	})
+	_, err := (&Config{Socket: socket}).Open("plugin", slog.New(slog.DiscardHandler))
+	if err == nil || !strings.Contains(err.Error(), "doesn't support callback logins") {
+		t.Errorf("expected the plugin to be refused, got %v", err)
+	}
+
+	socket = servePlugin(t, func(id string, config []byte, logger *slog.Logger) (connector.Connector, error) {
+		return nil, errors.New("invalid config")
+	})
+	_, err = (&Config{Socket: socket}).Open("plugin", slog.New(slog.DiscardHandler))
+	if err == nil || !strings.Contains(err.Error(), "invalid config") {
+		t.Errorf("expected the open error of the plugin, got %v", err)
+	}
+}
@@ -0,0 +1,289 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.5.1
+// - protoc             v5.29.3
+// source: connector/external/plugin/plugin.proto
+
+package plugin
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	Connector_Handshake_FullMethodName      = "/plugin.Connector/Handshake"
+	Connector_LoginURL_FullMethodName       = "/plugin.Connector/LoginURL"
+	Connector_HandleCallback_FullMethodName = "/plugin.Connector/HandleCallback"
+	Connector_Refresh_FullMethodName        = "/plugin.Connector/Refresh"
+	Connector_TokenIdentity_FullMethodName  = "/plugin.Connector/TokenIdentity"
+)
+
+// ConnectorClient is the client API for Connector service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// Connector is implemented by out-of-process connectors. Besides it, plugins
+// serve the standard gRPC health service.
+type ConnectorClient interface {
+	// Handshake opens the connector and negotiates the protocol version.
+	Handshake(ctx context.Context, in *HandshakeReq, opts ...grpc.CallOption) (*HandshakeResp, error)
+	// LoginURL returns the URL to send the user to.
+	LoginURL(ctx context.Context, in *LoginURLReq, opts ...grpc.CallOption) (*LoginURLResp, error)
+	// HandleCallback returns the user of a callback request.
+	HandleCallback(ctx context.Context, in *HandleCallbackReq, opts ...grpc.CallOption) (*HandleCallbackResp, error)
+	// Refresh returns the current identity of a user.
+	Refresh(ctx context.Context, in *RefreshReq, opts ...grpc.CallOption) (*RefreshResp, error)
+	// TokenIdentity returns the user of an upstream token.
+	TokenIdentity(ctx context.Context, in *TokenIdentityReq, opts ...grpc.CallOption) (*TokenIdentityResp, error)
+}
+
+type connectorClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewConnectorClient(cc grpc.ClientConnInterface) ConnectorClient {
+	return &connectorClient{cc}
+}
+
+func (c *connectorClient) Handshake(ctx context.Context, in *HandshakeReq, opts ...grpc.CallOption) (*HandshakeResp, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(HandshakeResp)
+	err := c.cc.Invoke(ctx, Connector_Handshake_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *connectorClient) LoginURL(ctx context.Context, in *LoginURLReq, opts ...grpc.CallOption) (*LoginURLResp, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(LoginURLResp)
+	err := c.cc.Invoke(ctx, Connector_LoginURL_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *connectorClient) HandleCallback(ctx context.Context, in *HandleCallbackReq, opts ...grpc.CallOption) (*HandleCallbackResp, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(HandleCallbackResp)
+	err := c.cc.Invoke(ctx, Connector_HandleCallback_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *connectorClient) Refresh(ctx context.Context, in *RefreshReq, opts ...grpc.CallOption) (*RefreshResp, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(RefreshResp)
+	err := c.cc.Invoke(ctx, Connector_Refresh_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *connectorClient) TokenIdentity(ctx context.Context, in *TokenIdentityReq, opts ...grpc.CallOption) (*TokenIdentityResp, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(TokenIdentityResp)
+	err := c.cc.Invoke(ctx, Connector_TokenIdentity_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// ConnectorServer is the server API for Connector service.
+// All implementations must embed UnimplementedConnectorServer
+// for forward compatibility.
+//
+// Connector is implemented by out-of-process connectors. Besides it, plugins
+// serve the standard gRPC health service.
+type ConnectorServer interface {
+	// Handshake opens the connector and negotiates the protocol version.
+	Handshake(context.Context, *HandshakeReq) (*HandshakeResp, error)
+	// LoginURL returns the URL to send the user to.
+	LoginURL(context.Context, *LoginURLReq) (*LoginURLResp, error)
+	// HandleCallback returns the user of a callback request.
+	HandleCallback(context.Context, *HandleCallbackReq) (*HandleCallbackResp, error)
+	// Refresh returns the current identity of a user.
+	Refresh(context.Context, *RefreshReq) (*RefreshResp, error)
+	// TokenIdentity returns the user of an upstream token.
+	TokenIdentity(context.Context, *TokenIdentityReq) (*TokenIdentityResp, error)
+	mustEmbedUnimplementedConnectorServer()
+}
+
+// UnimplementedConnectorServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedConnectorServer struct{}
+
+func (UnimplementedConnectorServer) Handshake(context.Context, *HandshakeReq) (*HandshakeResp, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Handshake not implemented")
+}
+func (UnimplementedConnectorServer) LoginURL(context.Context, *LoginURLReq) (*LoginURLResp, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method LoginURL not implemented")
+}
+func (UnimplementedConnectorServer) HandleCallback(context.Context, *HandleCallbackReq) (*HandleCallbackResp, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method HandleCallback not implemented")
+}
+func (UnimplementedConnectorServer) Refresh(context.Context, *RefreshReq) (*RefreshResp, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Refresh not implemented")
+}
+func (UnimplementedConnectorServer) TokenIdentity(context.Context, *TokenIdentityReq) (*TokenIdentityResp, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method TokenIdentity not implemented")
+}
+func (UnimplementedConnectorServer) mustEmbedUnimplementedConnectorServer() {}
+func (UnimplementedConnectorServer) testEmbeddedByValue()                   {}
+
+// UnsafeConnectorServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to ConnectorServer will
+// result in compilation errors.
+type UnsafeConnectorServer interface {
+	mustEmbedUnimplementedConnectorServer()
+}
+
+func RegisterConnectorServer(s grpc.ServiceRegistrar, srv ConnectorServer) {
+	// If the following call pancis, it indicates UnimplementedConnectorServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&Connector_ServiceDesc, srv)
+}
+
+func _Connector_Handshake_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(HandshakeReq)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ConnectorServer).Handshake(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Connector_Handshake_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ConnectorServer).Handshake(ctx, req.(*HandshakeReq))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Connector_LoginURL_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(LoginURLReq)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ConnectorServer).LoginURL(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Connector_LoginURL_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ConnectorServer).LoginURL(ctx, req.(*LoginURLReq))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Connector_HandleCallback_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(HandleCallbackReq)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ConnectorServer).HandleCallback(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Connector_HandleCallback_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ConnectorServer).HandleCallback(ctx, req.(*HandleCallbackReq))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Connector_Refresh_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RefreshReq)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ConnectorServer).Refresh(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Connector_Refresh_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ConnectorServer).Refresh(ctx, req.(*RefreshReq))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Connector_TokenIdentity_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(TokenIdentityReq)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ConnectorServer).TokenIdentity(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Connector_TokenIdentity_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ConnectorServer).TokenIdentity(ctx, req.(*TokenIdentityReq))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// Connector_ServiceDesc is the grpc.ServiceDesc for Connector service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var Connector_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "plugin.Connector",
+	HandlerType: (*ConnectorServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Handshake",
+			Handler:    _Connector_Handshake_Handler,
+		},
+		{
+			MethodName: "LoginURL",
+			Handler:    _Connector_LoginURL_Handler,
+		},
+		{
+			MethodName: "HandleCallback",
+			Handler:    _Connector_HandleCallback_Handler,
+		},
+		{
+			MethodName: "Refresh",
+			Handler:    _Connector_Refresh_Handler,
+		},
+		{
+			MethodName: "TokenIdentity",
+			Handler:    _Connector_TokenIdentity_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "connector/external/plugin/plugin.proto",
+}
@@ -0,0 +1,246 @@
+package external
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"sync"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+
+	"github.com/dexidp/dex/connector"
+	"github.com/dexidp/dex/connector/external/plugin"
+)
+
+// OpenFunc opens the connector of a plugin with the ID and the JSON config
+// dex passes in the handshake.
+type OpenFunc func(id string, config []byte, logger *slog.Logger) (connector.Connector, error)
+
+// Serve serves a connector to dex on the listener until it fails. It is the
+// main loop of a plugin binary:
+//
+//	l, err := net.Listen("unix", "/run/dex/hsdp.sock")
+//	if err != nil {
+//		return err
+//	}
+//	return external.Serve(l, func(id string, config []byte, logger *slog.Logger) (connector.Connector, error) {
+//		var c hsdp.Config
+//		if err := json.Unmarshal(config, &c); err != nil {
+//			return nil, err
+//		}
+//		return c.Open(id, logger)
+//	}, logger)
+func Serve(l net.Listener, open OpenFunc, logger *slog.Logger) error {
+	s := grpc.NewServer()
+	Register(s, open, logger)
+	return s.Serve(l)
+}
+
+// Register registers the connector service of a plugin and its health
+// service on a gRPC server.
+//
+// The connector is opened by the handshake of dex. A plugin serves a single
+// connector: a handshake with another ID or config replaces the connector.
+func Register(s grpc.ServiceRegistrar, open OpenFunc, logger *slog.Logger) {
+	srv := &server{open: open, logger: logger}
+	plugin.RegisterConnectorServer(s, srv)
+	grpc_health_v1.RegisterHealthServer(s, healthServer{srv})
+}
+
+type server struct {
+	plugin.UnimplementedConnectorServer
+
+	open   OpenFunc
+	logger *slog.Logger
+
+	mu     sync.Mutex
+	id     string
+	config []byte
+	conn   connector.Connector
+}
+
+// connector returns the connector opened by the handshake.
+func (s *server) connector() (connector.Connector, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.conn == nil {
+		return nil, status.Error(codes.FailedPrecondition, "no handshake")
+	}
+	return s.conn, nil
+}
+
+func (s *server) Handshake(ctx context.Context, req *plugin.HandshakeReq) (*plugin.HandshakeResp, error) {
+	if req.ProtocolVersion < ProtocolVersion {
+		return nil, status.Errorf(codes.FailedPrecondition, "plugin speaks protocol version %d, dex speaks %d", ProtocolVersion, req.ProtocolVersion)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Every dex replica does a handshake, they share the connector.
+	if s.conn == nil || s.id != req.ConnectorId || !bytes.Equal(s.config, req.Config) {
+		logger := s.logger.With("connector_id", req.ConnectorId)
+		conn, err := s.open(req.ConnectorId, req.Config, logger)
+		if err != nil {
+			return nil, status.Errorf(codes.InvalidArgument, "failed to open connector: %v", err)
+		}
+		if closer, ok := s.conn.(io.Closer); ok {
+			closer.Close()
+		}
+		s.id, s.config, s.conn = req.ConnectorId, req.Config, conn
+		logger.Info("connector opened", "dex_version", req.DexVersion)
+	}
+
+	var capabilities []string
+	if _, ok := s.conn.(connector.CallbackConnector); ok {
+		capabilities = append(capabilities, CapabilityCallback)
+	}
+	if _, ok := s.conn.(connector.RefreshConnector); ok {
+		capabilities = append(capabilities, CapabilityRefresh)
+	}
+	if _, ok := s.conn.(connector.TokenIdentityConnector); ok {
+		capabilities = append(capabilities, CapabilityTokenIdentity)
+	}
+	return &plugin.HandshakeResp{ProtocolVersion: ProtocolVersion, Capabilities: capabilities}, nil
+}
+
+func (s *server) LoginURL(ctx context.Context, req *plugin.LoginURLReq) (*plugin.LoginURLResp, error) {
+	conn, err := s.connector()
+	if err != nil {
+		return nil, err
+	}
+	c, ok := conn.(connector.CallbackConnector)
+	if !ok {
+		return nil, status.Error(codes.Unimplemented, "connector doesn't support callback logins")
+	}
+	loginURL, connData, err := c.LoginURL(fromScopes(req.Scopes), req.CallbackUrl, req.State)
+	if err != nil {
+		return nil, err
+	}
+	return &plugin.LoginURLResp{Url: loginURL, ConnectorData: connData}, nil
+}
+
+func (s *server) HandleCallback(ctx context.Context, req *plugin.HandleCallbackReq) (*plugin.HandleCallbackResp, error) {
+	conn, err := s.connector()
+	if err != nil {
+		return nil, err
+	}
+	c, ok := conn.(connector.CallbackConnector)
+	if !ok {
+		return nil, status.Error(codes.Unimplemented, "connector doesn't support callback logins")
+	}
+
+	r, err := http.NewRequestWithContext(ctx, req.Method, req.Url, bytes.NewReader(req.Body))
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid callback request: %v", err)
+	}
+	for _, h := range req.Headers {
+		r.Header[h.Name] = h.Values
+	}
+	// Connectors see the request the way a server does.
+	r.RequestURI = r.URL.RequestURI()
+
+	identity, err := c.HandleCallback(fromScopes(req.Scopes), req.ConnectorData, r)
+	if err != nil {
+		return nil, err
+	}
+	i, err := fromIdentity(identity)
+	if err != nil {
+		return nil, err
+	}
+	return &plugin.HandleCallbackResp{Identity: i}, nil
+}
+
+func (s *server) Refresh(ctx context.Context, req *plugin.RefreshReq) (*plugin.RefreshResp, error) {
+	conn, err := s.connector()
+	if err != nil {
+		return nil, err
+	}
+	c, ok := conn.(connector.RefreshConnector)
+	if !ok {
+		return nil, status.Error(codes.Unimplemented, "connector doesn't support refreshing")
+	}
+	identity, err := toIdentity(req.Identity)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	identity, err = c.Refresh(ctx, fromScopes(req.Scopes), identity)
+	if err != nil {
+		return nil, err
+	}
+	i, err := fromIdentity(identity)
+	if err != nil {
+		return nil, err
+	}
+	return &plugin.RefreshResp{Identity: i}, nil
+}
+
+func (s *server) TokenIdentity(ctx context.Context, req *plugin.TokenIdentityReq) (*plugin.TokenIdentityResp, error) {
+	conn, err := s.connector()
+	if err != nil {
+		return nil, err
+	}
+	c, ok := conn.(connector.TokenIdentityConnector)
+	if !ok {
+		return nil, status.Error(codes.Unimplemented, "connector doesn't support token exchange")
+	}
+	identity, err := c.TokenIdentity(ctx, req.SubjectTokenType, req.SubjectToken)
+	if err != nil {
+		return nil, err
+	}
+	i, err := fromIdentity(identity)
+	if err != nil {
+		return nil, err
+	}
+	return &plugin.TokenIdentityResp{Identity: i}, nil
+}
+
+// healthServer reports the plugin as serving. The connector service is only
+// serving once opened, and while the health check of its connector passes.
+type healthServer struct {
+	s *server
+}
+
+func (h healthServer) Check(ctx context.Context, req *grpc_health_v1.HealthCheckRequest) (*grpc_health_v1.HealthCheckResponse, error) {
+	serving := &grpc_health_v1.HealthCheckResponse{Status: grpc_health_v1.HealthCheckResponse_SERVING}
+	switch req.Service {
+	case "":
+		return serving, nil
+	case healthService:
+	default:
+		return nil, status.Errorf(codes.NotFound, "unknown service %q", req.Service)
+	}
+
+	conn, err := h.s.connector()
+	if err != nil {
+		return nil, err
+	}
+	if checker, ok := conn.(connector.HealthChecker); ok {
+		if err := checker.HealthCheck(ctx); err != nil {
+			h.s.logger.Warn("connector health check failed", "err", err)
+			return &grpc_health_v1.HealthCheckResponse{Status: grpc_health_v1.HealthCheckResponse_NOT_SERVING}, nil
+		}
+	}
+	return serving, nil
+}
+
+func (h healthServer) List(ctx context.Context, req *grpc_health_v1.HealthListRequest) (*grpc_health_v1.HealthListResponse, error) {
+	resp, err := h.Check(ctx, &grpc_health_v1.HealthCheckRequest{Service: healthService})
+	if err != nil {
+		resp = &grpc_health_v1.HealthCheckResponse{Status: grpc_health_v1.HealthCheckResponse_NOT_SERVING}
+	}
+	return &grpc_health_v1.HealthListResponse{Statuses: map[string]*grpc_health_v1.HealthCheckResponse{
+		"":            {Status: grpc_health_v1.HealthCheckResponse_SERVING},
+		healthService: resp,
+	}}, nil
+}
+
+func (h healthServer) Watch(*grpc_health_v1.HealthCheckRequest, grpc_health_v1.Health_WatchServer) error {
+	return status.Error(codes.Unimplemented, "watching the health is not supported")
+}
@@ -28,6 +28,9 @@ const (
 	// GitHub requires this scope to access '/user/teams' and '/orgs' API endpoints
 	// which are used when a client includes the 'groups' scope.
 	scopeOrgs = "read:org"
+	// GitHub requires this scope to see private repositories, whose
+	// permissions are loaded if 'repositories' is configured.
+	scopeRepo = "repo"
 	// githubAPIVersion pins the GitHub REST API version used in requests.
 	githubAPIVersion = "2022-11-28"
 )
@@ -52,6 +55,18 @@ type Config struct {
 	LoadAllGroups        bool   `json:"loadAllGroups"`
 	UseLoginAsID         bool   `json:"useLoginAsID"`
 	PreferredEmailDomain string `json:"preferredEmailDomain"`
+
+	// LoadOrgRoles adds the user's role in each of their organizations, as
+	// the "github_org_roles" claim, e.g. {"my-org": "admin"}. If orgs are
+	// configured, only those are included.
+	LoadOrgRoles bool `json:"loadOrgRoles"`
+
+	// Repositories, given as "owner/name", whose permission for the user is
+	// added as the "github_repo_permissions" claim, e.g. {"my-org/api":
+	// "write"}. Repositories the user has no access to are left out. Setting
+	// this makes dex request the "repo" scope, as GitHub only shows private
+	// repositories with it.
+	Repositories []string `json:"repositories"`
 }
 
 // Org holds org-team filters, in which teams are optional.
@@ -125,17 +140,30 @@ func (c *Config) Open(id string, logger *slog.Logger) (connector.Connector, erro
 		}
 	}
 
+	for _, repo := range c.Repositories {
+		if owner, name, ok := strings.Cut(repo, "/"); !ok || owner == "" || name == "" || strings.Contains(name, "/") {
+			return nil, fmt.Errorf("invalid repository %q: must be of the form \"owner/name\"", repo)
+		}
+	}
+	g.loadOrgRoles = c.LoadOrgRoles
+	g.repositories = c.Repositories
+
 	return &g, nil
 }
 
 type connectorData struct {
 	// GitHub's OAuth2 tokens never expire. We don't need a refresh token.
-	AccessToken string `json:"accessToken"`
+	AccessToken string `json:"accessToken,omitempty"`
+
+	// Roles added to the tokens' claims, if configured.
+	OrgRoles        map[string]string `json:"orgRoles,omitempty"`
+	RepoPermissions map[string]string `json:"repoPermissions,omitempty"`
 }
 
 var (
 	_ connector.CallbackConnector = (*githubConnector)(nil)
 	_ connector.RefreshConnector  = (*githubConnector)(nil)
+	_ connector.PayloadExtender   = (*githubConnector)(nil)
 )
 
 type githubConnector struct {
@@ -161,6 +189,10 @@ type githubConnector struct {
 	useLoginAsID bool
 	// the domain to be preferred among the user's emails. e.g. "github.com"
 	preferredEmailDomain string
+	// if set, the user's org roles are added as a claim
+	loadOrgRoles bool
+	// repositories whose permissions are added as a claim
+	repositories []string
 }
 
 // groupsRequired returns whether dex requires GitHub's 'read:org' scope. Dex
@@ -174,9 +206,12 @@ func (c *githubConnector) oauth2Config(scopes connector.Scopes) *oauth2.Config {
 	// 'read:org' scope is required by the GitHub API, and thus for dex to ensure
 	// a user is a member of orgs and teams provided in configs.
 	githubScopes := []string{scopeEmail}
-	if c.groupsRequired(scopes.Groups) {
+	if c.groupsRequired(scopes.Groups) || c.loadOrgRoles {
 		githubScopes = append(githubScopes, scopeOrgs)
 	}
+	if len(c.repositories) > 0 {
+		githubScopes = append(githubScopes, scopeRepo)
+	}
 
 	endpoint := github.Endpoint
 	// case when it is a GitHub Enterprise account.
@@ -267,8 +302,14 @@ func (c *githubConnector) HandleCallback(s connector.Scopes, connData []byte, r
 		identity.Groups = groups
 	}
 
+	data := connectorData{}
 	if s.OfflineAccess {
-		data := connectorData{AccessToken: token.AccessToken}
+		data.AccessToken = token.AccessToken
+	}
+	if err := c.loadRoles(ctx, client, &data); err != nil {
+		return identity, err
+	}
+	if data.AccessToken != "" || data.OrgRoles != nil || data.RepoPermissions != nil {
 		connData, err := json.Marshal(data)
 		if err != nil {
 			return identity, fmt.Errorf("marshal connector data: %v", err)
@@ -288,6 +329,9 @@ func (c *githubConnector) Refresh(ctx context.Context, s connector.Scopes, ident
 	if err := json.Unmarshal(identity.ConnectorData, &data); err != nil {
 		return identity, fmt.Errorf("github: unmarshal access token: %v", err)
 	}
+	if data.AccessToken == "" {
+		return identity, errors.New("no upstream access token found")
+	}
 
 	client := c.oauth2Config(s).Client(ctx, &oauth2.Token{AccessToken: data.AccessToken})
 	user, err := c.user(ctx, client)
@@ -312,6 +356,17 @@ func (c *githubConnector) Refresh(ctx context.Context, s connector.Scopes, ident
 		identity.Groups = groups
 	}
 
+	if c.loadOrgRoles || len(c.repositories) > 0 {
+		if err := c.loadRoles(ctx, client, &data); err != nil {
+			return identity, err
+		}
+		connData, err := json.Marshal(data)
+		if err != nil {
+			return identity, fmt.Errorf("marshal connector data: %v", err)
+		}
+		identity.ConnectorData = connData
+	}
+
 	return identity, nil
 }
 
@@ -454,6 +509,153 @@ func (c *githubConnector) userOrgTeams(ctx context.Context, client *http.Client)
 	return groups, nil
 }
 
+// loadRoles sets the user's org roles and repository permissions in data,
+// if the connector is configured to load them.
+func (c *githubConnector) loadRoles(ctx context.Context, client *http.Client, data *connectorData) error {
+	data.OrgRoles, data.RepoPermissions = nil, nil
+	if c.loadOrgRoles {
+		roles, err := c.userOrgRoles(ctx, client)
+		if err != nil {
+			return err
+		}
+		data.OrgRoles = roles
+	}
+	if len(c.repositories) > 0 {
+		permissions := make(map[string]string)
+		for _, repo := range c.repositories {
+			permission, err := c.repoPermission(ctx, client, repo)
+			if err != nil {
+				return err
+			}
+			if permission != "" {
+				permissions[repo] = permission
+			}
+		}
+		data.RepoPermissions = permissions
+	}
+	return nil
+}
+
+// membership holds a user's membership of an org as defined by
+// https://docs.github.com/en/rest/orgs/members#list-organization-memberships-for-the-authenticated-user
+type membership struct {
+	Org  org    `json:"organization"`
+	Role string `json:"role"`
+}
+
+// userOrgRoles retrieves the user's role, "admin" or "member", in each org
+// they're an active member of. If orgs are configured, others are left out.
+func (c *githubConnector) userOrgRoles(ctx context.Context, client *http.Client) (map[string]string, error) {
+	roles := make(map[string]string)
+	apiURL := c.apiURL + "/user/memberships/orgs?state=active"
+	for {
+		var (
+			memberships []membership
+			err         error
+		)
+		if apiURL, err = get(ctx, client, apiURL, &memberships); err != nil {
+			return nil, fmt.Errorf("github: get org memberships: %v", err)
+		}
+
+		for _, m := range memberships {
+			if c.orgConfigured(m.Org.Login) {
+				roles[m.Org.Login] = m.Role
+			}
+		}
+
+		if apiURL == "" {
+			break
+		}
+	}
+
+	return roles, nil
+}
+
+// orgConfigured reports whether an org is one of the configured orgs, or
+// true if none are configured.
+func (c *githubConnector) orgConfigured(name string) bool {
+	if c.org == "" && len(c.orgs) == 0 {
+		return true
+	}
+	if c.org == name {
+		return true
+	}
+	for _, o := range c.orgs {
+		if o.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// repository holds the user's permissions on a repository as defined by
+// https://docs.github.com/en/rest/repos/repos#get-a-repository
+type repository struct {
+	Permissions struct {
+		Admin    bool `json:"admin"`
+		Maintain bool `json:"maintain"`
+		Push     bool `json:"push"`
+		Triage   bool `json:"triage"`
+		Pull     bool `json:"pull"`
+	} `json:"permissions"`
+}
+
+// repoPermission retrieves the user's highest permission on a repository,
+// using the names GitHub shows in its UI: "admin", "maintain", "write",
+// "triage" or "read". An empty string is returned if the repository isn't
+// visible to the user.
+func (c *githubConnector) repoPermission(ctx context.Context, client *http.Client, repo string) (string, error) {
+	var r repository
+	if _, err := get(ctx, client, c.apiURL+"/repos/"+repo, &r); err != nil {
+		var apiErr *apiError
+		if errors.As(err, &apiErr) && apiErr.statusCode == http.StatusNotFound {
+			return "", nil
+		}
+		return "", fmt.Errorf("github: get repository %s: %v", repo, err)
+	}
+
+	switch p := r.Permissions; {
+	case p.Admin:
+		return "admin", nil
+	case p.Maintain:
+		return "maintain", nil
+	case p.Push:
+		return "write", nil
+	case p.Triage:
+		return "triage", nil
+	case p.Pull:
+		return "read", nil
+	}
+	return "", nil
+}
+
+// ExtendPayload adds the user's org roles and repository permissions to the
+// ID token claims, if the connector is configured to load them.
+func (c *githubConnector) ExtendPayload(scopes []string, payload []byte, connData []byte) ([]byte, error) {
+	if len(connData) == 0 {
+		return payload, nil
+	}
+	var data connectorData
+	if err := json.Unmarshal(connData, &data); err != nil {
+		return payload, fmt.Errorf("github: unmarshal connector data: %v", err)
+	}
+	if data.OrgRoles == nil && data.RepoPermissions == nil {
+		return payload, nil
+	}
+
+	var claims map[string]interface{}
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return payload, err
+	}
+	if data.OrgRoles != nil {
+		claims["github_org_roles"] = data.OrgRoles
+	}
+	if data.RepoPermissions != nil {
+		claims["github_repo_permissions"] = data.RepoPermissions
+	}
+	return json.Marshal(claims)
+}
+
 // get creates a "GET `apiURL`" request with context, sends the request using
 // the client, and decodes the resulting response body into v. A pagination URL
 // is returned if one exists. Any errors encountered when building requests,
@@ -476,7 +678,7 @@ func get(ctx context.Context, client *http.Client, apiURL string, v interface{})
 		if err != nil {
 			return "", fmt.Errorf("github: read body: %v", err)
 		}
-		return "", fmt.Errorf("%s: %s", resp.Status, body)
+		return "", &apiError{statusCode: resp.StatusCode, status: resp.Status, body: body}
 	}
 
 	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
@@ -486,6 +688,17 @@ func get(ctx context.Context, client *http.Client, apiURL string, v interface{})
 	return getPagination(apiURL, resp), nil
 }
 
+// apiError is returned by get for responses other than 200 OK.
+type apiError struct {
+	statusCode int
+	status     string
+	body       []byte
+}
+
+func (e *apiError) Error() string {
+	return fmt.Sprintf("%s: %s", e.status, e.body)
+}
+
 // getPagination checks the "Link" header field for "next" or "last" pagination URLs,
 // and returns "next" page URL or empty string to indicate that there are no more pages.
 // Non empty next pages' URL is returned if both "last" and "next" URLs are found and next page
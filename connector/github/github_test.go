@@ -18,9 +18,10 @@ import (
 )
 
 type testResponse struct {
-	data     interface{}
-	nextLink string
-	lastLink string
+	data       interface{}
+	nextLink   string
+	lastLink   string
+	statusCode int
 }
 
 func TestUserGroups(t *testing.T) {
@@ -168,6 +169,62 @@ func TestUsernameIncludedInFederatedIdentity(t *testing.T) {
 	expectEquals(t, identity.Groups, []string{"org-1"})
 }
 
+func TestOrgRolesAndRepoPermissions(t *testing.T) {
+	s := newTestServer(map[string]testResponse{
+		"/user": {data: user{Login: "some-login", ID: 12345678}},
+		"/user/emails": {data: []userEmail{{
+			Email:    "some@email.com",
+			Verified: true,
+			Primary:  true,
+		}}},
+		"/login/oauth/access_token": {data: map[string]interface{}{
+			"access_token": "eyJhbGciOiJSUzI1NiIsInR5cCI6IkpXVCJ9",
+			"expires_in":   "30",
+		}},
+		"/user/memberships/orgs?state=active": {data: []membership{
+			{Org: org{Login: "org-1"}, Role: "admin"},
+			{Org: org{Login: "org-2"}, Role: "member"},
+		}},
+		"/orgs/org-1/members/some-login": {statusCode: http.StatusNoContent},
+		"/repos/org-1/api": {data: map[string]interface{}{
+			"permissions": map[string]bool{"admin": false, "maintain": false, "push": true, "triage": true, "pull": true},
+		}},
+		"/repos/org-1/secret": {statusCode: http.StatusNotFound, data: map[string]string{"message": "Not Found"}},
+	})
+	defer s.Close()
+
+	hostURL, err := url.Parse(s.URL)
+	expectNil(t, err)
+
+	req, err := http.NewRequest("GET", hostURL.String(), nil)
+	expectNil(t, err)
+
+	c := githubConnector{
+		apiURL:       s.URL,
+		hostName:     hostURL.Host,
+		httpClient:   newClient(),
+		orgs:         []Org{{Name: "org-1"}},
+		loadOrgRoles: true,
+		repositories: []string{"org-1/api", "org-1/secret"},
+	}
+	identity, err := c.HandleCallback(connector.Scopes{}, nil, req)
+	expectNil(t, err)
+
+	var data connectorData
+	expectNil(t, json.Unmarshal(identity.ConnectorData, &data))
+	expectEquals(t, data.AccessToken, "")
+	expectEquals(t, data.OrgRoles, map[string]string{"org-1": "admin"})
+	expectEquals(t, data.RepoPermissions, map[string]string{"org-1/api": "write"})
+
+	payload, err := c.ExtendPayload(nil, []byte(`{"sub":"12345678"}`), identity.ConnectorData)
+	expectNil(t, err)
+	var claims map[string]interface{}
+	expectNil(t, json.Unmarshal(payload, &claims))
+	expectEquals(t, claims["sub"], "12345678")
+	expectEquals(t, claims["github_org_roles"], map[string]interface{}{"org-1": "admin"})
+	expectEquals(t, claims["github_repo_permissions"], map[string]interface{}{"org-1/api": "write"})
+}
+
 func TestLoginUsedAsIDWhenConfigured(t *testing.T) {
 	s := newTestServer(map[string]testResponse{
 		"/user": {data: user{Login: "some-login", ID: 12345678, Name: "Joe Bloggs"}},
@@ -515,6 +572,9 @@ func newTestServer(responses map[string]testResponse) *httptest.Server {
 			w.Header().Add("Link", strings.Join(linkParts, ", "))
 		}
 		w.Header().Add("Content-Type", "application/json")
+		if response.statusCode != 0 {
+			w.WriteHeader(response.statusCode)
+		}
 		json.NewEncoder(w).Encode(response.data)
 	}))
 	return s
@@ -38,6 +38,18 @@ type Config struct {
 	UseLoginAsID        bool     `json:"useLoginAsID"`
 	GetGroupsPermission bool     `json:"getGroupsPermission"`
 	RootCAData          []byte   `json:"rootCAData,omitempty"`
+
+	// AllowedGroups are patterns, e.g. "acme/*", limiting the groups in the
+	// groups claim. Unlike Groups, users in none of them can still log in.
+	AllowedGroups []string `json:"allowedGroups"`
+	// GroupPrefixes limits the groups claim to groups starting with one of
+	// the prefixes.
+	GroupPrefixes []string `json:"groupPrefixes"`
+	// TopLevelGroups limits the groups claim to these top-level groups and
+	// their subgroups.
+	TopLevelGroups []string `json:"topLevelGroups"`
+	// MaxGroups caps the number of groups in the groups claim.
+	MaxGroups int `json:"maxGroups"`
 }
 
 type gitlabUser struct {
@@ -54,6 +66,15 @@ func (c *Config) Open(id string, logger *slog.Logger) (connector.Connector, erro
 	if c.BaseURL == "" {
 		c.BaseURL = "https://gitlab.com"
 	}
+	groupLimit := groups.Limit{
+		Allowed:  c.AllowedGroups,
+		Prefixes: c.GroupPrefixes,
+		TopLevel: c.TopLevelGroups,
+		Max:      c.MaxGroups,
+	}
+	if err := groupLimit.Validate(); err != nil {
+		return nil, fmt.Errorf("gitlab: %v", err)
+	}
 	var httpClient *http.Client
 	if len(c.RootCAData) > 0 {
 		var err error
@@ -76,6 +97,7 @@ func (c *Config) Open(id string, logger *slog.Logger) (connector.Connector, erro
 		groups:              c.Groups,
 		useLoginAsID:        c.UseLoginAsID,
 		getGroupsPermission: c.GetGroupsPermission,
+		groupLimit:          groupLimit,
 		httpClient:          httpClient,
 	}, nil
 }
@@ -105,6 +127,8 @@ type gitlabConnector struct {
 
 	// if set to true permissions will be added to list of groups
 	getGroupsPermission bool
+	// limits the groups in the groups claim
+	groupLimit groups.Limit
 }
 
 func (c *gitlabConnector) oauth2Config(scopes connector.Scopes) *oauth2.Config {
@@ -408,10 +432,18 @@ func (c *gitlabConnector) getGroups(ctx context.Context, client *http.Client, gr
 		if len(filteredGroups) == 0 {
 			return nil, fmt.Errorf("gitlab: user %q is not in any of the required groups", userLogin)
 		}
-		return filteredGroups, nil
+		return c.limitGroups(ctx, filteredGroups, userLogin), nil
 	} else if groupScope {
-		return gitlabGroups, nil
+		return c.limitGroups(ctx, gitlabGroups, userLogin), nil
 	}
 
 	return nil, nil
 }
+
+func (c *gitlabConnector) limitGroups(ctx context.Context, gitlabGroups []string, userLogin string) []string {
+	limited, truncated := c.groupLimit.Apply(gitlabGroups)
+	if truncated {
+		c.logger.WarnContext(ctx, "too many groups, truncating groups claim", "user", userLogin, "max_groups", c.groupLimit.Max)
+	}
+	return limited
+}
@@ -1,6 +1,7 @@
 package gitlab
 
 import (
+	"bytes"
 	"context"
 	"crypto/tls"
 	"encoding/json"
@@ -17,6 +18,7 @@ import (
 	"time"
 
 	"github.com/dexidp/dex/connector"
+	"github.com/dexidp/dex/pkg/groups"
 )
 
 func readValidRootCAData(t *testing.T) []byte {
@@ -211,6 +213,31 @@ func TestUserGroupsWithFiltering(t *testing.T) {
 	})
 }
 
+func TestUserGroupsWithLimit(t *testing.T) {
+	s := newTestServer(map[string]interface{}{
+		"/oauth/userinfo": userInfo{
+			Groups: []string{"acme", "acme/ops", "acme/dev", "acme/dev/web", "other", "other/acme"},
+		},
+	})
+	defer s.Close()
+
+	logger := slog.New(slog.NewTextHandler(&bytes.Buffer{}, nil))
+	c := gitlabConnector{baseURL: s.URL, logger: logger, groupLimit: groups.Limit{TopLevel: []string{"acme"}}}
+	got, err := c.getGroups(context.Background(), newClient(), true, "joebloggs")
+	expectNil(t, err)
+	expectEquals(t, got, []string{"acme", "acme/ops", "acme/dev", "acme/dev/web"})
+
+	c.groupLimit = groups.Limit{Allowed: []string{"acme/*"}, Max: 2}
+	got, err = c.getGroups(context.Background(), newClient(), true, "joebloggs")
+	expectNil(t, err)
+	expectEquals(t, got, []string{"acme/ops", "acme/dev"})
+
+	c.groupLimit = groups.Limit{Prefixes: []string{"acme/d"}}
+	got, err = c.getGroups(context.Background(), newClient(), true, "joebloggs")
+	expectNil(t, err)
+	expectEquals(t, got, []string{"acme/dev", "acme/dev/web"})
+}
+
 func TestUserGroupsWithoutOrgs(t *testing.T) {
 	s := newTestServer(map[string]interface{}{
 		"/oauth/userinfo": userInfo{
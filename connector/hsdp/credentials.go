@@ -0,0 +1,93 @@
+package hsdp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"slices"
+	"sync/atomic"
+
+	"github.com/coreos/go-oidc/v3/oidc"
+	"golang.org/x/oauth2"
+)
+
+// clientCredentials is a client ID and secret of the connector at HSP IAM.
+type clientCredentials struct {
+	id           string
+	secret       string
+	oauth2Config *oauth2.Config
+	verifier     *oidc.IDTokenVerifier
+}
+
+// credentials are the primary and optional secondary client credentials of
+// the connector. IAM client secrets are rotated by configuring the new secret
+// as the secondary one before rotating it: when IAM rejects the credentials in
+// use, the connector retries with the others and keeps using them.
+type credentials struct {
+	logger  *slog.Logger
+	pairs   []*clientCredentials
+	current atomic.Int32
+}
+
+// get returns the credentials in use.
+func (c *credentials) get() *clientCredentials {
+	return c.pairs[c.current.Load()]
+}
+
+// ids returns the client IDs of all credentials.
+func (c *credentials) ids() []string {
+	ids := make([]string, 0, len(c.pairs))
+	for _, p := range c.pairs {
+		if !slices.Contains(ids, p.id) {
+			ids = append(ids, p.id)
+		}
+	}
+	return ids
+}
+
+// do calls fn with the credentials in use. If IAM rejects them, fn is retried
+// with the other credentials, which are used from then on if IAM accepts them.
+func (c *credentials) do(ctx context.Context, fn func(*clientCredentials) error) error {
+	current := c.current.Load()
+	err := fn(c.pairs[current])
+	if err == nil || len(c.pairs) == 1 || !isInvalidClient(err) {
+		return err
+	}
+
+	other := 1 - current
+	if err := fn(c.pairs[other]); err != nil {
+		return err
+	}
+	if c.current.CompareAndSwap(current, other) {
+		c.logger.WarnContext(ctx, "hsdp: IAM rejected the client credentials, switched to the other credentials",
+			"rejected_client_id", c.pairs[current].id, "client_id", c.pairs[other].id, "secondary", other == 1)
+	}
+	return nil
+}
+
+// statusError is a failed response of an IAM endpoint.
+type statusError struct {
+	code int
+	msg  string
+}
+
+func newStatusError(resp *http.Response, body []byte) *statusError {
+	return &statusError{code: resp.StatusCode, msg: fmt.Sprintf("%s: %s", resp.Status, body)}
+}
+
+func (e *statusError) Error() string {
+	return e.msg
+}
+
+// isInvalidClient reports whether IAM rejected the client credentials of a
+// request.
+func isInvalidClient(err error) bool {
+	var rerr *oauth2.RetrieveError
+	if errors.As(err, &rerr) {
+		return rerr.ErrorCode == "invalid_client" || (rerr.Response != nil && rerr.Response.StatusCode == http.StatusUnauthorized)
+	}
+	var serr *statusError
+	return errors.As(err, &serr) && serr.code == http.StatusUnauthorized
+}
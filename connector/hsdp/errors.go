@@ -0,0 +1,74 @@
+package hsdp
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+
+	"golang.org/x/oauth2"
+
+	"github.com/dexidp/dex/connector"
+)
+
+// Codes of the login errors users can act on. The messages are translated by
+// the message catalogs of dex, keep them in sync with web/i18n.
+const (
+	errInvalidAssertion    = "invalid_assertion"
+	errSAMLResponseExpired = "saml_response_expired"
+	errAccountLocked       = "account_locked"
+	errPasswordExpired     = "password_expired"
+)
+
+var loginErrorMessages = map[string]string{
+	errInvalidAssertion:    "Your identity provider's login response was not accepted. Please log in again.",
+	errSAMLResponseExpired: "Your login took too long and has expired. Please log in again.",
+	errAccountLocked:       "Your account is locked. Please contact your administrator.",
+	errPasswordExpired:     "Your password has expired. Please change it and log in again.",
+}
+
+// iamErrorResponse is the OAuth2 error body returned by HSP IAM.
+type iamErrorResponse struct {
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description"`
+}
+
+// loginErrorCode classifies an IAM error. IAM doesn't have dedicated error
+// types for these failures, they are only told apart by their description.
+func loginErrorCode(errType, description string) string {
+	d := strings.ToLower(description)
+	switch {
+	case strings.Contains(d, "locked"):
+		return errAccountLocked
+	case strings.Contains(d, "password") && strings.Contains(d, "expired"):
+		return errPasswordExpired
+	case (strings.Contains(d, "assertion") || strings.Contains(d, "saml")) && strings.Contains(d, "expired"):
+		return errSAMLResponseExpired
+	case strings.Contains(d, "assertion") || errType == "invalid_assertion":
+		return errInvalidAssertion
+	}
+	return ""
+}
+
+// loginError wraps err in a *connector.LoginError if the IAM error is one
+// users can act on, and returns it as is otherwise.
+func loginError(errType, description string, err error) error {
+	code := loginErrorCode(errType, description)
+	if code == "" {
+		return err
+	}
+	return &connector.LoginError{Code: code, Message: loginErrorMessages[code], Err: err}
+}
+
+// tokenLoginError maps an error of the IAM token endpoint, either a failed
+// exchange of the oauth2 package or the body of a failed SAML2 bearer grant.
+func tokenLoginError(body []byte, err error) error {
+	var r iamErrorResponse
+	var rerr *oauth2.RetrieveError
+	switch {
+	case errors.As(err, &rerr):
+		r = iamErrorResponse{Error: rerr.ErrorCode, ErrorDescription: rerr.ErrorDescription}
+	case json.Unmarshal(body, &r) != nil:
+		return err
+	}
+	return loginError(r.Error, r.ErrorDescription, err)
+}
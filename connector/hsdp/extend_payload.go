@@ -5,8 +5,19 @@ import (
 	"fmt"
 	"slices"
 	"strings"
+
+	"github.com/dexidp/dex/connector"
 )
 
+// PreviewedClaims implements connector.ClaimsPreviewer.
+func (c *HSDPConnector) PreviewedClaims() []connector.PreviewedClaim {
+	return []connector.PreviewedClaim{
+		{Path: "roles", Label: "Roles"},
+		{Path: "ort", Label: "Tenants"},
+		{Path: "intr.organizations.managingOrganization", Label: "Organization"},
+	}
+}
+
 func (c *HSDPConnector) ExtendPayload(scopes []string, payload []byte, cdata []byte) ([]byte, error) {
 	var cd ConnectorData
 	var originalClaims map[string]interface{}
@@ -22,7 +33,7 @@ func (c *HSDPConnector) ExtendPayload(scopes []string, payload []byte, cdata []b
 
 	// Service identity tokens should expire when the service identity token expires
 	if cd.Introspect.IdentityType == "Service" {
-		originalClaims["exp"] = cd.Introspect.Expires
+		originalClaims["exp"] = cd.Introspect.Expires - int64(c.clockSkew.Seconds())
 		originalClaims["username"] = cd.Introspect.Sub
 		originalClaims["preferred_username"] = cd.Introspect.Sub
 	}
@@ -38,6 +49,9 @@ func (c *HSDPConnector) ExtendPayload(scopes []string, payload []byte, cdata []b
 		}
 	}
 	originalClaims["idt"] = cd.Introspect.IdentityType
+	if cd.Degraded {
+		originalClaims["dgr"] = true
+	}
 	// Rewrite subject
 	var orgSubs []string
 	var orgGroups []string
@@ -47,12 +61,21 @@ func (c *HSDPConnector) ExtendPayload(scopes []string, payload []byte, cdata []b
 			orgRoles = append(orgRoles, fmt.Sprintf("urn:iamr:%s:%s", org.OrganizationID, strings.ToLower(role)))
 		}
 		for _, group := range org.Groups {
-			orgGroups = append(orgGroups, fmt.Sprintf("urn:iamg:%s:%s", org.OrganizationID, strings.ToLower(group)))
+			name, err := c.groupNamer.name(org.OrganizationID, mapper(org.OrganizationID, c.tenantMap), group)
+			if err != nil {
+				return payload, err
+			}
+			if name != "" {
+				orgGroups = append(orgGroups, name)
+			}
 		}
 	}
 	if c.enableRoleClaim && len(orgRoles) > 0 {
 		originalClaims["roles"] = orgRoles
 	}
+	if c.enableOrganizationsClaim && len(cd.Organizations) > 0 {
+		originalClaims["organizations"] = cd.Organizations
+	}
 	if c.enableGroupClaim && len(orgGroups) > 0 {
 		originalClaims["groups"] = orgGroups
 	}
@@ -87,6 +110,20 @@ func (c *HSDPConnector) ExtendPayload(scopes []string, payload []byte, cdata []b
 	if len(readTenants) > 0 {
 		originalClaims["ort"] = readTenants
 	}
+	if managingOrg := cd.Introspect.Organizations.ManagingOrganization; c.enableTenantClaim && managingOrg != "" {
+		originalClaims["managing_organization"] = managingOrg
+		originalClaims["tenant"] = mapper(managingOrg, c.tenantMap)
+	}
+	// The tenant selected in token exchange is authoritative.
+	if cd.TenantOrganization != "" {
+		originalClaims["tenant"] = mapper(cd.TenantOrganization, c.tenantMap)
+		originalClaims["tenant_organization"] = cd.TenantOrganization
+	}
+	if c.strictScopeMatching {
+		if removed := filterClaimsByGrantedScopes(originalClaims, cd.Introspect.Scope); len(removed) > 0 {
+			c.logger.Debug("dropped claims not covered by granted scopes", "sub", cd.Introspect.Sub, "claims", removed, "granted", cd.Introspect.Scope)
+		}
+	}
 	extendedPayload, err := json.Marshal(originalClaims)
 	if err != nil {
 		return payload, err
@@ -0,0 +1,81 @@
+package hsdp
+
+import (
+	"fmt"
+	"strings"
+	"text/template"
+	"unicode"
+)
+
+// defaultGroupNameTemplate renders groups the way the connector always has.
+const defaultGroupNameTemplate = "urn:iamg:{{.OrganizationID}}:{{.Group}}"
+
+// GroupNameTransform rewrites HSP IAM group names before they are emitted in
+// the groups claim. HSP IAM group names can contain spaces and unicode which
+// break tools creating Kubernetes RoleBindings from them.
+//
+// The steps are applied in the order of the fields.
+type GroupNameTransform struct {
+	// StripPrefix removes a prefix from group names, e.g. "K8S_".
+	StripPrefix string `json:"stripPrefix"`
+
+	// Lowercase lowercases group names. Defaults to true.
+	Lowercase *bool `json:"lowercase"`
+
+	// ReplaceSpaces replaces each run of whitespace in group names, e.g.
+	// with "-". Whitespace is kept if empty.
+	ReplaceSpaces string `json:"replaceSpaces"`
+
+	// Template renders the emitted group from .OrganizationID, .Tenant, the
+	// organization mapped through the tenantMap, and .Group, the transformed
+	// group name. Defaults to "urn:iamg:{{.OrganizationID}}:{{.Group}}".
+	// Groups rendering to an empty string are dropped.
+	Template string `json:"template"`
+}
+
+// groupNamer applies a GroupNameTransform.
+type groupNamer struct {
+	stripPrefix   string
+	lowercase     bool
+	replaceSpaces string
+	tmpl          *template.Template
+}
+
+func (t GroupNameTransform) compile() (*groupNamer, error) {
+	text := t.Template
+	if text == "" {
+		text = defaultGroupNameTemplate
+	}
+	tmpl, err := template.New("group").Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid groupNameTransform template: %v", err)
+	}
+	return &groupNamer{
+		stripPrefix:   t.StripPrefix,
+		lowercase:     t.Lowercase == nil || *t.Lowercase,
+		replaceSpaces: t.ReplaceSpaces,
+		tmpl:          tmpl,
+	}, nil
+}
+
+// name returns the group to emit for a group of an organization.
+func (g *groupNamer) name(organizationID, tenant, group string) (string, error) {
+	group = strings.TrimPrefix(group, g.stripPrefix)
+	if g.lowercase {
+		group = strings.ToLower(group)
+	}
+	if g.replaceSpaces != "" {
+		group = strings.Join(strings.FieldsFunc(group, unicode.IsSpace), g.replaceSpaces)
+	}
+
+	var b strings.Builder
+	err := g.tmpl.Execute(&b, struct {
+		OrganizationID string
+		Tenant         string
+		Group          string
+	}{organizationID, tenant, group})
+	if err != nil {
+		return "", fmt.Errorf("failed to render group %q: %v", group, err)
+	}
+	return strings.TrimSpace(b.String()), nil
+}
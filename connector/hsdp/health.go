@@ -0,0 +1,26 @@
+package hsdp
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// HealthCheck verifies that the HSP IAM discovery document can be fetched.
+func (c *HSDPConnector) HealthCheck(ctx context.Context) error {
+	wellKnown := strings.TrimSuffix(c.issuer, "/") + "/.well-known/openid-configuration"
+	req, err := http.NewRequest(http.MethodGet, wellKnown, nil)
+	if err != nil {
+		return fmt.Errorf("hsdp: create discovery request: %v", err)
+	}
+	resp, err := doRequest(c.clientContext(ctx), req)
+	if err != nil {
+		return fmt.Errorf("hsdp: discovery request failed: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("hsdp: discovery returned %s", resp.Status)
+	}
+	return nil
+}
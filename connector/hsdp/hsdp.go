@@ -11,6 +11,7 @@ import (
 	"log/slog"
 	"net/http"
 	"net/url"
+	"strconv"
 	"strings"
 	"time"
 
@@ -19,26 +20,57 @@ import (
 	"golang.org/x/oauth2"
 
 	"github.com/dexidp/dex/connector"
+	"github.com/dexidp/dex/pkg/httpclient"
 )
 
 // Config holds configuration options for OpenID Connect logins.
 type Config struct {
-	Issuer           string    `json:"issuer"`
-	InsecureIssuer   string    `json:"insecureIssuer"`
-	ClientID         string    `json:"clientID"`
-	ClientSecret     string    `json:"clientSecret"`
-	RedirectURI      string    `json:"redirectURI"`
-	TenantMap        TenantMap `json:"tenantMap"`
-	SAML2LoginURL    string    `json:"saml2LoginURL"`
-	IAMURL           string    `json:"iamURL"`
-	IDMURL           string    `json:"idmURL"`
-	EnableGroupClaim bool      `json:"enableGroupClaim"`
-	EnableRoleClaim  bool      `json:"enableRoleClaim"`
-	RoleAsGroupClaim bool      `json:"roleAsGroupClaim"`
+	Issuer         string    `json:"issuer"`
+	InsecureIssuer string    `json:"insecureIssuer"`
+	ClientID       string    `json:"clientID"`
+	ClientSecret   string    `json:"clientSecret"`
+	RedirectURI    string    `json:"redirectURI"`
+	TenantMap      TenantMap `json:"tenantMap"`
+	SAML2LoginURL  string    `json:"saml2LoginURL"`
+	// SecondaryClientID and SecondaryClientSecret are used when IAM rejects
+	// the client credentials with invalid_client, so the IAM client secret
+	// can be rotated without redeploying dex. SecondaryClientID defaults to
+	// ClientID.
+	SecondaryClientID     string `json:"secondaryClientID"`
+	SecondaryClientSecret string `json:"secondaryClientSecret"`
+	// SAML2LoginHintParam is the query parameter of the SAML2 login URL
+	// which receives the client's login_hint. Defaults to "login_hint".
+	SAML2LoginHintParam string `json:"saml2LoginHintParam"`
+	// SAML2RelayState passes dex's signed relay state through the SAML2 login,
+	// so logins outliving their state at a slow IdP restart from where the
+	// user started instead of failing.
+	SAML2RelayState bool `json:"saml2RelayState"`
+	// SAML2RelayStateMaxLength is the size above which the relay state is
+	// left out of the SAML2 login URL. Defaults to 2048.
+	SAML2RelayStateMaxLength int `json:"saml2RelayStateMaxLength"`
+
+	// Region and Environment of HSP IAM, e.g. "us-east" and "client-test",
+	// derive Issuer, IAMURL and IDMURL when they aren't set.
+	Region      string `json:"region"`
+	Environment string `json:"environment"`
+
+	IAMURL           string `json:"iamURL"`
+	IDMURL           string `json:"idmURL"`
+	EnableGroupClaim bool   `json:"enableGroupClaim"`
+	EnableRoleClaim  bool   `json:"enableRoleClaim"`
+	RoleAsGroupClaim bool   `json:"roleAsGroupClaim"`
+
+	// GroupNameTransform rewrites group names before they are emitted, e.g.
+	// for Kubernetes RBAC.
+	GroupNameTransform GroupNameTransform `json:"groupNameTransform"`
 
 	// Extensions implemented by HSP IAM
 	Extension
 
+	// ProviderDiscoveryOverrides replace endpoints published by discovery,
+	// which private IAM instances sometimes get wrong.
+	ProviderDiscoveryOverrides ProviderDiscoveryOverrides `json:"providerDiscoveryOverrides"`
+
 	// Causes client_secret to be passed as POST parameters instead of basic
 	// auth. This is specifically "NOT RECOMMENDED" by the OAuth2 RFC, but some
 	// providers require it.
@@ -60,10 +92,252 @@ type Config struct {
 
 	// PromptType will be used fot the prompt parameter (when offline_access, by default prompt=consent)
 	PromptType string `json:"promptType"`
+
+	// ClockSkew is subtracted from the expiry of HSP IAM tokens, so tokens
+	// derived from them never outlive them on a skewed clock, e.g. "1m".
+	ClockSkew string `json:"clockSkew"`
+
+	// EndSessionURL overrides the end_session_endpoint from discovery.
+	EndSessionURL string `json:"endSessionURL"`
+
+	// UpstreamLogout controls how dex logout ends the HSP IAM session:
+	// "none" (default) leaves the IAM session alone, "redirect" sends the
+	// user to the end session endpoint and "backchannel" revokes the
+	// session's IAM tokens.
+	UpstreamLogout string `json:"upstreamLogout"`
+
+	// DegradedMode lets logins succeed while introspection is unavailable.
+	// The identity is then built from the verified ID token and userinfo,
+	// without groups, and tokens carry a "dgr" claim.
+	DegradedMode bool `json:"degradedMode"`
+
+	// AccessTokenAudiences are the audiences accepted in JWT access tokens,
+	// which token exchange validates locally instead of introspecting them.
+	// Defaults to the client ID.
+	AccessTokenAudiences []string `json:"accessTokenAudiences"`
+
+	// StrictTokenExchange refuses introspected tokens during token exchange
+	// unless they were issued to one of AccessTokenAudiences, by their
+	// client_id, or for one, by their aud. Otherwise any active IAM token,
+	// including those of unrelated applications, can be exchanged.
+	StrictTokenExchange bool `json:"strictTokenExchange"`
+
+	// ProfileMaxAge is how long the IDM profile stored with a session is
+	// reused on refresh before it is fetched again, e.g. "24h". By default the
+	// profile is fetched on every refresh.
+	ProfileMaxAge string `json:"profileMaxAge"`
+
+	// ProfileStalePolicy decides what happens when the IDM profile cannot be
+	// fetched: "warn" (default) keeps the profile stored with the session, if
+	// any, "fail" fails the login or refresh.
+	ProfileStalePolicy string `json:"profileStalePolicy"`
+
+	// EnableTenantClaim adds the user's managing organization as a
+	// "managing_organization" claim and its tenant, mapped through TenantMap,
+	// as a "tenant" claim.
+	EnableTenantClaim bool `json:"enableTenantClaim"`
+
+	// EnableTenantSelection binds tokens from token exchange to a tenant of
+	// TenantMap: the "tenant" parameter of the request, by tenant or
+	// organization ID, or else the managing organization of the subject
+	// token. Users must be members of a requested tenant. The tenant is
+	// emitted as "tenant" claim and its organization as "tenant_organization".
+	EnableTenantSelection bool `json:"enableTenantSelection"`
+
+	// StrictScopeMatching drops claims whose upstream scope was not granted
+	// by HSP IAM, e.g. groups when the IAM token lacks the groups scope.
+	StrictScopeMatching bool `json:"strictScopeMatching"`
+
+	// EnableOrganizationsClaim adds the user's organizations, looked up in
+	// IDM, as an "organizations" claim.
+	EnableOrganizationsClaim bool `json:"enableOrganizationsClaim"`
+	// OrganizationParents adds the parent chain of each organization.
+	OrganizationParents bool `json:"organizationParents"`
+	// OrganizationCacheTTL is how long looked up organizations are cached.
+	// Defaults to "10m".
+	OrganizationCacheTTL string `json:"organizationCacheTTL"`
+
+	// Transport tunes the connection pool shared by the OAuth2, introspection
+	// and IAM calls of the connector.
+	Transport Transport `json:"transport"`
+
+	// IntrospectClaims are fields of the IAM introspection response, e.g.
+	// "token_type", "client_id" or "organizations", copied into the custom
+	// claims, so services receiving dex tokens don't introspect them again.
+	IntrospectClaims []string `json:"introspectClaims"`
+
+	httpClients connector.HTTPClientFactory
+}
+
+// SetHTTPClientFactory makes the connector call HSP IAM with clients from the
+// server.
+func (c *Config) SetHTTPClientFactory(f connector.HTTPClientFactory) {
+	c.httpClients = f
+}
+
+// Transport tunes the connection pool of the connector. Zero values keep the
+// settings of the server's upstreamHTTP.
+type Transport struct {
+	// MaxIdleConnsPerHost is the number of idle connections kept to HSP IAM.
+	MaxIdleConnsPerHost int `json:"maxIdleConnsPerHost"`
+	// IdleConnTimeout closes idle connections after this time, e.g. "5m".
+	IdleConnTimeout string `json:"idleConnTimeout"`
+	// TLSSessionCacheSize is the number of TLS sessions kept for resumption,
+	// negative disables resumption.
+	TLSSessionCacheSize int `json:"tlsSessionCacheSize"`
+}
+
+func (t Transport) parse() (connector.HTTPTransportConfig, error) {
+	tc := connector.HTTPTransportConfig{
+		MaxIdleConnsPerHost: t.MaxIdleConnsPerHost,
+		TLSSessionCacheSize: t.TLSSessionCacheSize,
+	}
+	if t.MaxIdleConnsPerHost < 0 {
+		return tc, fmt.Errorf("invalid transport.maxIdleConnsPerHost %d", t.MaxIdleConnsPerHost)
+	}
+	if t.IdleConnTimeout != "" {
+		d, err := time.ParseDuration(t.IdleConnTimeout)
+		if err != nil || d <= 0 {
+			return tc, fmt.Errorf("invalid transport.idleConnTimeout %q", t.IdleConnTimeout)
+		}
+		tc.IdleConnTimeout = d
+	}
+	return tc, nil
+}
+
+// newHTTPClient returns the client of all calls of the connector to HSP IAM,
+// so they share one connection pool. Without clients from the server the
+// connector has its own pool.
+func (c *Config) newHTTPClient(tc connector.HTTPTransportConfig) (*http.Client, error) {
+	if tuned, ok := c.httpClients.(connector.TunedHTTPClientFactory); ok {
+		return tuned.TunedHTTPClient(nil, false, tc)
+	}
+	if c.httpClients != nil {
+		return c.httpClients.HTTPClient(nil, false)
+	}
+	f, err := httpclient.NewFactory(httpclient.FactoryConfig{})
+	if err != nil {
+		return nil, err
+	}
+	return f.TunedClient("hsdp", nil, false, httpclient.TransportConfig(tc))
 }
 
+// Extension holds the fields HSP IAM adds to the discovery document.
 type Extension struct {
 	IntrospectionEndpoint string `json:"introspection_endpoint"`
+	RevocationEndpoint    string `json:"revocation_endpoint"`
+	EndSessionEndpoint    string `json:"end_session_endpoint"`
+
+	// Endpoints holds every "*_endpoint" field of the discovery document by
+	// name without the suffix, including extensions dex doesn't use.
+	Endpoints map[string]string `json:"-"`
+}
+
+// ProviderDiscoveryOverrides replace endpoints from the discovery document.
+type ProviderDiscoveryOverrides struct {
+	AuthURL          string `json:"authURL"`
+	TokenURL         string `json:"tokenURL"`
+	UserInfoURL      string `json:"userInfoURL"`
+	JWKSURL          string `json:"jwksURL"`
+	IntrospectionURL string `json:"introspectionURL"`
+	RevocationURL    string `json:"revocationURL"`
+	EndSessionURL    string `json:"endSessionURL"`
+}
+
+func (o ProviderDiscoveryOverrides) empty() bool {
+	return o == ProviderDiscoveryOverrides{}
+}
+
+// validate checks that the overrides are absolute http(s) URLs.
+func (o ProviderDiscoveryOverrides) validate() error {
+	for name, u := range map[string]string{
+		"authURL":          o.AuthURL,
+		"tokenURL":         o.TokenURL,
+		"userInfoURL":      o.UserInfoURL,
+		"jwksURL":          o.JWKSURL,
+		"introspectionURL": o.IntrospectionURL,
+		"revocationURL":    o.RevocationURL,
+		"endSessionURL":    o.EndSessionURL,
+	} {
+		if u == "" {
+			continue
+		}
+		parsed, err := url.Parse(u)
+		if err != nil || (parsed.Scheme != "https" && parsed.Scheme != "http") || parsed.Host == "" {
+			return fmt.Errorf("providerDiscoveryOverrides.%s must be an absolute http or https URL, got %q", name, u)
+		}
+	}
+	return nil
+}
+
+// discover reads the discovery document of the issuer and applies the
+// overrides to it.
+func discover(ctx context.Context, issuer string, overrides ProviderDiscoveryOverrides, ext *Extension) (*oidc.Provider, error) {
+	provider, err := oidc.NewProvider(ctx, issuer)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get provider: %v", err)
+	}
+
+	// HSP IAM extension
+	if err := provider.Claims(ext); err != nil {
+		return nil, fmt.Errorf("failed to get introspection endpoint: %v", err)
+	}
+	var fields map[string]any
+	if err := provider.Claims(&fields); err != nil {
+		return nil, fmt.Errorf("failed to parse discovery document: %v", err)
+	}
+	ext.Endpoints = make(map[string]string)
+	for name, v := range fields {
+		if s, ok := v.(string); ok && strings.HasSuffix(name, "_endpoint") {
+			ext.Endpoints[strings.TrimSuffix(name, "_endpoint")] = s
+		}
+	}
+
+	if overrides.IntrospectionURL != "" {
+		ext.IntrospectionEndpoint = overrides.IntrospectionURL
+	}
+	if overrides.RevocationURL != "" {
+		ext.RevocationEndpoint = overrides.RevocationURL
+	}
+	if overrides.EndSessionURL != "" {
+		ext.EndSessionEndpoint = overrides.EndSessionURL
+	}
+	if overrides.AuthURL == "" && overrides.TokenURL == "" && overrides.UserInfoURL == "" && overrides.JWKSURL == "" {
+		return provider, nil
+	}
+
+	var v struct {
+		Issuer      string   `json:"issuer"`
+		AuthURL     string   `json:"authorization_endpoint"`
+		TokenURL    string   `json:"token_endpoint"`
+		JWKSURL     string   `json:"jwks_uri"`
+		UserInfoURL string   `json:"userinfo_endpoint"`
+		Algorithms  []string `json:"id_token_signing_alg_values_supported"`
+	}
+	if err := provider.Claims(&v); err != nil {
+		return nil, fmt.Errorf("failed to extract provider discovery claims: %v", err)
+	}
+	config := oidc.ProviderConfig{
+		IssuerURL:   v.Issuer,
+		AuthURL:     v.AuthURL,
+		TokenURL:    v.TokenURL,
+		JWKSURL:     v.JWKSURL,
+		UserInfoURL: v.UserInfoURL,
+		Algorithms:  v.Algorithms,
+	}
+	if overrides.AuthURL != "" {
+		config.AuthURL = overrides.AuthURL
+	}
+	if overrides.TokenURL != "" {
+		config.TokenURL = overrides.TokenURL
+	}
+	if overrides.UserInfoURL != "" {
+		config.UserInfoURL = overrides.UserInfoURL
+	}
+	if overrides.JWKSURL != "" {
+		config.JWKSURL = overrides.JWKSURL
+	}
+	return config.NewProvider(ctx), nil
 }
 
 type AudienceTrustMap map[string]string
@@ -81,6 +355,18 @@ type ConnectorData struct {
 	TenantMap        TenantMap
 	Introspect       iam.IntrospectResponse
 	User             iam.Profile
+
+	Organizations []Organization `json:",omitempty"`
+
+	// ProfileFetchedAt is when User was fetched from IDM.
+	ProfileFetchedAt time.Time `json:",omitempty"`
+
+	// Degraded is set when the session was created without introspection.
+	Degraded bool `json:",omitempty"`
+
+	// TenantOrganization is the organization of the tenant selected in token
+	// exchange.
+	TenantOrganization string `json:",omitempty"`
 }
 
 type caller uint
@@ -94,22 +380,84 @@ const (
 // Open returns a connector which can be used to log in users through an upstream
 // OpenID Connect provider.
 func (c *Config) Open(id string, logger *slog.Logger) (conn connector.Connector, err error) {
+	if err := c.autoconfigure(); err != nil {
+		return nil, fmt.Errorf("hsdp: %v", err)
+	}
+
+	var clockSkew time.Duration
+	if c.ClockSkew != "" {
+		if clockSkew, err = time.ParseDuration(c.ClockSkew); err != nil {
+			return nil, fmt.Errorf("hsdp: invalid clockSkew %q: %v", c.ClockSkew, err)
+		}
+	}
+
+	var profileMaxAge time.Duration
+	if c.ProfileMaxAge != "" {
+		if profileMaxAge, err = time.ParseDuration(c.ProfileMaxAge); err != nil {
+			return nil, fmt.Errorf("hsdp: invalid profileMaxAge %q: %v", c.ProfileMaxAge, err)
+		}
+	}
+	organizationCacheTTL := 10 * time.Minute
+	if c.OrganizationCacheTTL != "" {
+		if organizationCacheTTL, err = time.ParseDuration(c.OrganizationCacheTTL); err != nil {
+			return nil, fmt.Errorf("hsdp: invalid organizationCacheTTL %q: %v", c.OrganizationCacheTTL, err)
+		}
+	}
+
+	switch c.UpstreamLogout {
+	case "":
+		c.UpstreamLogout = upstreamLogoutNone
+	case upstreamLogoutRedirect, upstreamLogoutBackchannel, upstreamLogoutNone:
+	default:
+		return nil, fmt.Errorf("hsdp: unknown upstreamLogout %q, must be %q, %q or %q", c.UpstreamLogout, upstreamLogoutRedirect, upstreamLogoutBackchannel, upstreamLogoutNone)
+	}
+
+	if err := c.ProviderDiscoveryOverrides.validate(); err != nil {
+		return nil, fmt.Errorf("hsdp: %v", err)
+	}
+
+	groupNamer, err := c.GroupNameTransform.compile()
+	if err != nil {
+		return nil, fmt.Errorf("hsdp: %v", err)
+	}
+	if err := validateIntrospectClaims(c.IntrospectClaims); err != nil {
+		return nil, fmt.Errorf("hsdp: %v", err)
+	}
+
+	switch c.ProfileStalePolicy {
+	case "", profileStaleWarn, profileStaleFail:
+	default:
+		return nil, fmt.Errorf("hsdp: unknown profileStalePolicy %q, must be %q or %q", c.ProfileStalePolicy, profileStaleWarn, profileStaleFail)
+	}
+
+	transport, err := c.Transport.parse()
+	if err != nil {
+		return nil, fmt.Errorf("hsdp: %v", err)
+	}
+	httpClient, err := c.newHTTPClient(transport)
+	if err != nil {
+		return nil, fmt.Errorf("hsdp: failed to create HTTP client: %v", err)
+	}
+
 	parentContext, cancel := context.WithCancel(context.Background())
+	parentContext = oidc.ClientContext(parentContext, httpClient)
 
 	ctx := oidc.InsecureIssuerURLContext(parentContext, c.InsecureIssuer)
 
-	provider, err := oidc.NewProvider(ctx, c.Issuer)
+	provider, err := discover(ctx, c.Issuer, c.ProviderDiscoveryOverrides, &c.Extension)
 	if err != nil {
 		cancel()
-		return nil, fmt.Errorf("failed to get provider: %v", err)
+		return nil, err
+	}
+	if !c.ProviderDiscoveryOverrides.empty() {
+		logger.Warn("overrides for connector are set, this can be a vulnerability when not properly configured", "connector_id", id)
 	}
 
 	endpoint := provider.Endpoint()
 
-	// HSP IAM extension
-	if err := provider.Claims(&c.Extension); err != nil {
-		cancel()
-		return nil, fmt.Errorf("failed to get introspection endpoint: %v", err)
+	endSessionURL := c.EndSessionEndpoint
+	if c.EndSessionURL != "" && c.ProviderDiscoveryOverrides.EndSessionURL == "" {
+		endSessionURL = c.EndSessionURL
 	}
 
 	if c.BasicAuthUnsupported != nil {
@@ -131,8 +479,14 @@ func (c *Config) Open(id string, logger *slog.Logger) (conn connector.Connector,
 	if c.PromptType == "" {
 		c.PromptType = "consent"
 	}
+	if c.SAML2LoginHintParam == "" {
+		c.SAML2LoginHintParam = "login_hint"
+	}
+	if c.SAML2RelayStateMaxLength == 0 {
+		c.SAML2RelayStateMaxLength = 2048
+	}
 
-	client, err := iam.NewClient(nil, &iam.Config{
+	client, err := iam.NewClient(httpClient, &iam.Config{
 		OAuth2ClientID: c.ClientID,
 		OAuth2Secret:   c.ClientSecret,
 		IAMURL:         c.IAMURL,
@@ -143,29 +497,62 @@ func (c *Config) Open(id string, logger *slog.Logger) (conn connector.Connector,
 		return nil, fmt.Errorf("error creating HSP IAM client: %w", err)
 	}
 
-	clientID := c.ClientID
+	creds := &credentials{logger: logger}
+	pair := func(id, secret string) *clientCredentials {
+		return &clientCredentials{
+			id:     id,
+			secret: secret,
+			oauth2Config: &oauth2.Config{
+				ClientID:     id,
+				ClientSecret: secret,
+				Endpoint:     endpoint,
+				Scopes:       scopes,
+				RedirectURL:  c.RedirectURI,
+			},
+			verifier: provider.Verifier(
+				&oidc.Config{
+					ClientID:        id,
+					SkipIssuerCheck: true, // Horribly broken currently
+				},
+			),
+		}
+	}
+	creds.pairs = append(creds.pairs, pair(c.ClientID, c.ClientSecret))
+	if c.SecondaryClientSecret != "" {
+		secondaryClientID := c.SecondaryClientID
+		if secondaryClientID == "" {
+			secondaryClientID = c.ClientID
+		}
+		creds.pairs = append(creds.pairs, pair(secondaryClientID, c.SecondaryClientSecret))
+	}
+
+	accessTokenAudiences := c.AccessTokenAudiences
+	if len(accessTokenAudiences) == 0 {
+		accessTokenAudiences = creds.ids()
+	}
 	return &HSDPConnector{
-		provider:      provider,
-		client:        client,
-		redirectURI:   c.RedirectURI,
-		introspectURI: c.IntrospectionEndpoint,
-		tenantMap:     c.TenantMap,
-		samlLoginURL:  c.SAML2LoginURL,
-		clientID:      c.ClientID,
-		clientSecret:  c.ClientSecret,
-		oauth2Config: &oauth2.Config{
-			ClientID:     clientID,
-			ClientSecret: c.ClientSecret,
-			Endpoint:     endpoint,
-			Scopes:       scopes,
-			RedirectURL:  c.RedirectURI,
-		},
-		verifier: provider.Verifier(
+		provider:           provider,
+		client:             client,
+		httpClient:         httpClient,
+		issuer:             c.Issuer,
+		redirectURI:        c.RedirectURI,
+		introspectURI:      c.IntrospectionEndpoint,
+		revokeURI:          c.RevocationEndpoint,
+		tenantMap:          c.TenantMap,
+		samlLoginURL:       c.SAML2LoginURL,
+		samlLoginHintParam: c.SAML2LoginHintParam,
+		samlRelayState:     c.SAML2RelayState,
+		samlRelayStateMax:  c.SAML2RelayStateMaxLength,
+		credentials:        creds,
+		oauth2Config:       creds.pairs[0].oauth2Config,
+		accessTokenVerifier: provider.Verifier(
 			&oidc.Config{
-				ClientID:        clientID,
-				SkipIssuerCheck: true, // Horribly broken currently
+				SkipClientIDCheck: true, // Checked against accessTokenAudiences
+				SkipIssuerCheck:   true,
 			},
 		),
+		accessTokenAudiences:      accessTokenAudiences,
+		strictTokenExchange:       c.StrictTokenExchange,
 		logger:                    logger,
 		cancel:                    cancel,
 		hostedDomains:             c.HostedDomains,
@@ -174,12 +561,35 @@ func (c *Config) Open(id string, logger *slog.Logger) (conn connector.Connector,
 		enableGroupClaim:          c.EnableGroupClaim,
 		enableRoleClaim:           c.EnableRoleClaim,
 		roleAsGroupClaim:          c.RoleAsGroupClaim,
+		groupNamer:                groupNamer,
+		clockSkew:                 clockSkew,
+		degradedMode:              c.DegradedMode,
+		profileMaxAge:             profileMaxAge,
+		failOnStaleProfile:        c.ProfileStalePolicy == profileStaleFail,
+		enableOrganizationsClaim:  c.EnableOrganizationsClaim,
+		organizationParents:       c.OrganizationParents,
+		organizations:             newOrganizationCache(organizationCacheTTL),
+		strictScopeMatching:       c.StrictScopeMatching,
+		enableTenantClaim:         c.EnableTenantClaim,
+		enableTenantSelection:     c.EnableTenantSelection,
+		endSessionURL:             endSessionURL,
+		upstreamLogout:            c.UpstreamLogout,
+		discoveredEndpoints:       c.Endpoints,
+		introspectClaims:          c.IntrospectClaims,
 	}, nil
 }
 
 var (
-	_ connector.CallbackConnector = (*HSDPConnector)(nil)
-	_ connector.RefreshConnector  = (*HSDPConnector)(nil)
+	_ connector.CallbackConnector          = (*HSDPConnector)(nil)
+	_ connector.RefreshConnector           = (*HSDPConnector)(nil)
+	_ connector.HealthChecker              = (*HSDPConnector)(nil)
+	_ connector.TokenRevoker               = (*HSDPConnector)(nil)
+	_ connector.BackchannelLogoutConnector = (*HSDPConnector)(nil)
+	_ connector.LogoutCallbackConnector    = (*HSDPConnector)(nil)
+	_ connector.Describer                  = (*HSDPConnector)(nil)
+
+	_ connector.TokenExchangeParamsConnector = (*HSDPConnector)(nil)
+	_ connector.ClaimsPreviewer              = (*HSDPConnector)(nil)
 )
 
 type tokenResponse struct {
@@ -194,13 +604,20 @@ type tokenResponse struct {
 type HSDPConnector struct {
 	provider                  *oidc.Provider
 	client                    *iam.Client
+	httpClient                *http.Client
+	issuer                    string
 	redirectURI               string
 	introspectURI             string
+	revokeURI                 string
 	samlLoginURL              string
-	clientID                  string
-	clientSecret              string
+	samlLoginHintParam        string
+	samlRelayState            bool
+	samlRelayStateMax         int
+	credentials               *credentials
 	oauth2Config              *oauth2.Config
-	verifier                  *oidc.IDTokenVerifier
+	accessTokenVerifier       *oidc.IDTokenVerifier
+	accessTokenAudiences      []string
+	strictTokenExchange       bool
 	cancel                    context.CancelFunc
 	logger                    *slog.Logger
 	hostedDomains             []string
@@ -208,8 +625,29 @@ type HSDPConnector struct {
 	enableGroupClaim          bool
 	enableRoleClaim           bool
 	roleAsGroupClaim          bool
+	groupNamer                *groupNamer
 	promptType                string
 	tenantMap                 TenantMap
+	clockSkew                 time.Duration
+	degradedMode              bool
+	profileMaxAge             time.Duration
+	failOnStaleProfile        bool
+	enableOrganizationsClaim  bool
+	organizationParents       bool
+	organizations             *organizationCache
+	strictScopeMatching       bool
+	enableTenantClaim         bool
+	enableTenantSelection     bool
+	endSessionURL             string
+	upstreamLogout            string
+	discoveredEndpoints       map[string]string
+	introspectClaims          []string
+}
+
+// expiry returns the expiry of a token expiring in expiresIn seconds, less the
+// clock skew.
+func (c *HSDPConnector) expiry(expiresIn int64) time.Time {
+	return time.Now().Add(time.Duration(expiresIn)*time.Second - c.clockSkew)
 }
 
 func (c *HSDPConnector) isSAML() bool {
@@ -221,16 +659,60 @@ func (c *HSDPConnector) Close() error {
 	return nil
 }
 
+func (c *HSDPConnector) Describe() connector.Description {
+	endpoints := map[string]string{
+		"authorization": c.oauth2Config.Endpoint.AuthURL,
+		"token":         c.oauth2Config.Endpoint.TokenURL,
+		"introspection": c.introspectURI,
+		"revocation":    c.revokeURI,
+		"userinfo":      c.provider.UserInfoEndpoint(),
+		"end_session":   c.endSessionURL,
+		"saml2_login":   c.samlLoginURL,
+	}
+	for name, u := range c.discoveredEndpoints {
+		if _, ok := endpoints[name]; !ok {
+			endpoints[name] = u
+		}
+	}
+	for name, u := range endpoints {
+		if u == "" {
+			delete(endpoints, name)
+		}
+	}
+	return connector.Description{Endpoints: endpoints, Scopes: c.oauth2Config.Scopes}
+}
+
 func (c *HSDPConnector) LoginURL(s connector.Scopes, callbackURL, state string) (string, []byte, error) {
+	return c.LoginURLWithParams(s, callbackURL, state, connector.AuthRequestParams{})
+}
+
+// LoginURLWithParams forwards prompt=none to HSP IAM for silent
+// re-authentication.
+func (c *HSDPConnector) LoginURLWithParams(s connector.Scopes, callbackURL, state string, params connector.AuthRequestParams) (string, []byte, error) {
 	if c.redirectURI != callbackURL {
 		return "", nil, fmt.Errorf("expected callback URL %q did not match the URL in the config %q", callbackURL, c.redirectURI)
 	}
+	silent := params.Prompt == "none"
 
 	// SAML2 flow
 	if c.isSAML() {
 		cbu, _ := url.Parse(callbackURL)
 		values := cbu.Query()
 		values.Set("state", state)
+		if c.samlRelayState && params.RelayState != "" {
+			if len(params.RelayState) <= c.samlRelayStateMax {
+				values.Set("relay_state", params.RelayState)
+			} else {
+				c.logger.Warn("relay state too long, leaving it out of the SAML2 login", "length", len(params.RelayState), "max", c.samlRelayStateMax)
+			}
+		}
+		if silent {
+			// The SAML2 login can't be silent, fail right away.
+			values.Set("error", "login_required")
+			values.Set("error_description", "silent login is not supported for SAML2")
+			cbu.RawQuery = values.Encode()
+			return cbu.String(), nil, nil
+		}
 		cbu.RawQuery = values.Encode()
 
 		u, err := url.Parse(c.samlLoginURL)
@@ -239,6 +721,9 @@ func (c *HSDPConnector) LoginURL(s connector.Scopes, callbackURL, state string)
 		}
 		values = u.Query()
 		values.Set("redirect_uri", cbu.String())
+		if params.LoginHint != "" {
+			values.Set(c.samlLoginHintParam, params.LoginHint)
+		}
 		u.RawQuery = values.Encode()
 		return u.String(), nil, nil
 	}
@@ -252,28 +737,34 @@ func (c *HSDPConnector) LoginURL(s connector.Scopes, callbackURL, state string)
 		opts = append(opts, oauth2.SetAuthURLParam("hd", preferredDomain))
 	}
 
-	if s.OfflineAccess {
+	switch {
+	case silent:
+		// prompt=none must not be combined with other values.
+		opts = append(opts, oauth2.SetAuthURLParam("prompt", "none"))
+		if s.OfflineAccess {
+			opts = append(opts, oauth2.AccessTypeOffline)
+		}
+	case s.OfflineAccess:
 		opts = append(opts, oauth2.AccessTypeOffline, oauth2.SetAuthURLParam("prompt", c.promptType))
 	}
-	return c.oauth2Config.AuthCodeURL(state, opts...), nil, nil
-}
-
-type oauth2Error struct {
-	error            string
-	errorDescription string
+	return c.credentials.get().oauth2Config.AuthCodeURL(state, opts...), nil, nil
 }
 
-func (e *oauth2Error) Error() string {
-	if e.errorDescription == "" {
-		return e.error
+// clientContext makes upstream requests made with ctx use the connector's HTTP
+// client.
+func (c *HSDPConnector) clientContext(ctx context.Context) context.Context {
+	if c.httpClient == nil {
+		return ctx
 	}
-	return e.error + ": " + e.errorDescription
+	return oidc.ClientContext(ctx, c.httpClient)
 }
 
 func (c *HSDPConnector) HandleCallback(s connector.Scopes, _ []byte, r *http.Request) (identity connector.Identity, err error) {
+	ctx := c.clientContext(r.Context())
 	q := r.URL.Query()
 	if errType := q.Get("error"); errType != "" {
-		return identity, &oauth2Error{errType, q.Get("error_description")}
+		description := q.Get("error_description")
+		return identity, loginError(errType, description, &connector.OAuth2Error{Code: errType, Description: description})
 	}
 
 	// SAML2 flow
@@ -283,24 +774,31 @@ func (c *HSDPConnector) HandleCallback(s connector.Scopes, _ []byte, r *http.Req
 		form.Add("grant_type", "urn:ietf:params:oauth:grant-type:saml2-bearer")
 		form.Add("assertion", assertion)
 		requestBody := form.Encode()
-		req, _ := http.NewRequest(http.MethodPost, c.oauth2Config.Endpoint.TokenURL, io.NopCloser(strings.NewReader(requestBody)))
-		req.SetBasicAuth(c.clientID, c.clientSecret)
-		req.Header.Set("Accept", "application/json")
-		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
-		req.Header.Set("Api-Version", "2")
-		req.ContentLength = int64(len(requestBody))
-
-		resp, err := doRequest(r.Context(), req)
-		if err != nil {
-			return identity, err
-		}
-		defer resp.Body.Close()
-		body, err := io.ReadAll(resp.Body)
+
+		var body []byte
+		err := c.credentials.do(ctx, func(creds *clientCredentials) error {
+			req, _ := http.NewRequest(http.MethodPost, c.oauth2Config.Endpoint.TokenURL, io.NopCloser(strings.NewReader(requestBody)))
+			req.SetBasicAuth(creds.id, creds.secret)
+			req.Header.Set("Accept", "application/json")
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			req.Header.Set("Api-Version", "2")
+			req.ContentLength = int64(len(requestBody))
+
+			resp, err := doRequest(ctx, req)
+			if err != nil {
+				return err
+			}
+			defer resp.Body.Close()
+			if body, err = io.ReadAll(resp.Body); err != nil {
+				return err
+			}
+			if resp.StatusCode != http.StatusOK {
+				return newStatusError(resp, body)
+			}
+			return nil
+		})
 		if err != nil {
-			return identity, err
-		}
-		if resp.StatusCode != http.StatusOK {
-			return identity, fmt.Errorf("%s: %s", resp.Status, body)
+			return identity, tokenLoginError(body, err)
 		}
 
 		var tr tokenResponse
@@ -311,17 +809,21 @@ func (c *HSDPConnector) HandleCallback(s connector.Scopes, _ []byte, r *http.Req
 			AccessToken:  tr.AccessToken,
 			TokenType:    tr.TokenType,
 			RefreshToken: tr.RefreshToken,
-			Expiry:       time.Unix(tr.ExpiresIn, 0),
+			Expiry:       c.expiry(tr.ExpiresIn),
 		}
-		return c.createIdentity(r.Context(), identity, token, r, createCaller)
+		return c.createIdentity(ctx, identity, token, r, createCaller, nil)
 	}
 
-	token, err := c.oauth2Config.Exchange(r.Context(), q.Get("code"))
+	var token *oauth2.Token
+	err = c.credentials.do(ctx, func(creds *clientCredentials) (err error) {
+		token, err = creds.oauth2Config.Exchange(ctx, q.Get("code"))
+		return err
+	})
 	if err != nil {
-		return identity, fmt.Errorf("oidc: failed to get token: %v", err)
+		return identity, tokenLoginError(nil, fmt.Errorf("oidc: failed to get token: %w", err))
 	}
 
-	return c.createIdentity(r.Context(), identity, token, r, createCaller)
+	return c.createIdentity(ctx, identity, token, r, createCaller, nil)
 }
 
 // Refresh is used to refresh a session with the refresh token provided by the IdP
@@ -336,12 +838,17 @@ func (c *HSDPConnector) Refresh(ctx context.Context, s connector.Scopes, identit
 		RefreshToken: string(cd.RefreshToken),
 		Expiry:       time.Now().Add(-time.Hour),
 	}
-	token, err := c.oauth2Config.TokenSource(ctx, t).Token()
+	ctx = c.clientContext(ctx)
+	var token *oauth2.Token
+	err = c.credentials.do(ctx, func(creds *clientCredentials) (err error) {
+		token, err = creds.oauth2Config.TokenSource(ctx, t).Token()
+		return err
+	})
 	if err != nil {
 		return identity, fmt.Errorf("oidc: failed to get refresh token: %v", err)
 	}
 
-	return c.createIdentity(ctx, identity, token, nil, refreshCaller)
+	return c.createIdentity(ctx, identity, token, nil, refreshCaller, &cd)
 }
 
 func (c *HSDPConnector) TokenIdentity(ctx context.Context, subjectTokenType, subjectToken string) (connector.Identity, error) {
@@ -350,10 +857,12 @@ func (c *HSDPConnector) TokenIdentity(ctx context.Context, subjectTokenType, sub
 		AccessToken: subjectToken,
 		TokenType:   "Bearer",
 	}
-	return c.createIdentity(ctx, identity, token, nil, exchangeCaller)
+	return c.createIdentity(c.clientContext(ctx), identity, token, nil, exchangeCaller, nil)
 }
 
-func (c *HSDPConnector) createIdentity(ctx context.Context, identity connector.Identity, token *oauth2.Token, r *http.Request, caller caller) (connector.Identity, error) {
+// createIdentity builds the identity for a token. prev is the connector data
+// of the session being refreshed, if any.
+func (c *HSDPConnector) createIdentity(ctx context.Context, identity connector.Identity, token *oauth2.Token, r *http.Request, caller caller, prev *ConnectorData) (connector.Identity, error) {
 	var claims map[string]interface{}
 
 	cd := ConnectorData{}
@@ -373,10 +882,38 @@ func (c *HSDPConnector) createIdentity(ctx context.Context, identity connector.I
 	if err := userInfo.Claims(&claims); err != nil {
 		return identity, fmt.Errorf("hsdp: failed to decode userinfo claims: %v", err)
 	}
-	// Introspect so we can get group assignments
-	introspectResponse, err := c.introspect(ctx, oauth2.StaticTokenSource(token))
+	// Introspect so we can get group assignments. Exchanged JWT access
+	// tokens carry them and are validated locally instead.
+	var introspectResponse *iam.IntrospectResponse
+	if caller == exchangeCaller && isJWT(token.AccessToken) {
+		introspectResponse, err = c.verifyAccessToken(ctx, token.AccessToken)
+		if err != nil {
+			return identity, fmt.Errorf("hsdp: invalid access token: %v", err)
+		}
+	}
+	if introspectResponse == nil {
+		var introspection *introspection
+		introspection, err = c.introspect(ctx, oauth2.StaticTokenSource(token))
+		if err == nil {
+			if caller == exchangeCaller && c.strictTokenExchange {
+				if err := c.checkExchangedToken(introspection); err != nil {
+					return identity, fmt.Errorf("hsdp: token exchange refused: %v", err)
+				}
+			}
+			introspectResponse = &introspection.IntrospectResponse
+		}
+	}
 	if err != nil {
-		return identity, fmt.Errorf("hsdp: introspect failed: %w", err)
+		if !c.degradedMode {
+			return identity, fmt.Errorf("hsdp: introspect failed: %w", err)
+		}
+		degraded, dErr := c.degradedIntrospection(ctx, token, claims)
+		if dErr != nil {
+			return identity, fmt.Errorf("hsdp: introspect failed: %w", errors.Join(err, dErr))
+		}
+		c.logger.WarnContext(ctx, "introspection failed, continuing in degraded mode", "sub", degraded.Sub, "err", err)
+		introspectResponse = degraded
+		cd.Degraded = true
 	}
 
 	hasEmailScope := false
@@ -397,11 +934,6 @@ func (c *HSDPConnector) createIdentity(ctx context.Context, identity connector.I
 		return identity, errors.New("missing \"email\" claim")
 	}
 
-	emailVerified := true
-
-	if c.isSAML() { // For SAML2 we claim email verification for now
-		emailVerified = true
-	}
 	hostedDomain, _ := claims["hd"].(string)
 
 	if len(c.hostedDomains) > 0 {
@@ -421,20 +953,23 @@ func (c *HSDPConnector) createIdentity(ctx context.Context, identity connector.I
 	cd.AccessToken = []byte(token.AccessToken)
 	cd.Introspect = *introspectResponse
 
-	// Get user info for profile details
-	user, _, err := c.client.WithToken(token.AccessToken).Users.LegacyGetUserByUUID(introspectResponse.Sub)
-	if err != nil {
-		c.logger.Error("failed to get user profile", "error", err)
+	if c.enableOrganizationsClaim {
+		cd.Organizations = c.resolveOrganizations(ctx, token.AccessToken, introspectResponse)
 	}
-	if user != nil {
-		cd.User = *user
+
+	// Get user info for profile details
+	if err := c.loadProfile(ctx, &cd, token.AccessToken, prev); err != nil {
+		return identity, err
 	}
 
+	emailVerified := c.insecureSkipEmailVerified || introspectResponse.IdentityType == "Service" || isEmailVerified(claims, cd.User)
+
 	identity = connector.Identity{
 		UserID:        introspectResponse.Sub,
 		Username:      introspectResponse.Username,
 		Email:         email,
 		EmailVerified: emailVerified,
+		CustomClaims:  c.customClaims(introspectResponse),
 	}
 
 	// Attach connector data
@@ -447,6 +982,76 @@ func (c *HSDPConnector) createIdentity(ctx context.Context, identity connector.I
 	return identity, nil
 }
 
+// isEmailVerified reports whether the email address of a user has been
+// verified, preferring the email_verified userinfo claim over the
+// emailVerifiedStatus of the IDM profile.
+func isEmailVerified(claims map[string]interface{}, profile iam.Profile) bool {
+	if verified, ok := claims["email_verified"].(bool); ok {
+		return verified
+	}
+	verified, _ := strconv.ParseBool(profile.EmailVerifiedStatus)
+	return verified
+}
+
+// customClaims returns the claims of the identity which clients get when
+// their releasedConnectorClaims name them: the managing organization, its
+// tenant, the permissions of the user by organization and the fields of the
+// introspection response named by introspectClaims.
+func (c *HSDPConnector) customClaims(introspect *iam.IntrospectResponse) map[string]interface{} {
+	claims := make(map[string]interface{})
+	if managingOrg := introspect.Organizations.ManagingOrganization; managingOrg != "" {
+		claims["managing_organization"] = managingOrg
+		claims["tenant"] = mapper(managingOrg, c.tenantMap)
+	}
+	permissions := make(map[string]interface{})
+	for _, org := range introspect.Organizations.OrganizationList {
+		if len(org.Permissions) > 0 {
+			permissions[org.OrganizationID] = org.Permissions
+		}
+	}
+	if len(permissions) > 0 {
+		claims["permissions"] = permissions
+	}
+	if len(c.introspectClaims) > 0 {
+		fields := introspectFields(introspect)
+		for _, name := range c.introspectClaims {
+			if v := fields[name]; v != nil && v != "" {
+				claims[name] = v
+			}
+		}
+	}
+	return claims
+}
+
+// degradedIntrospection stands in for introspection using the verified ID
+// token of the login and the userinfo claims. The result has no organizations
+// and therefore yields no groups or roles.
+func (c *HSDPConnector) degradedIntrospection(ctx context.Context, token *oauth2.Token, claims map[string]interface{}) (*iam.IntrospectResponse, error) {
+	rawIDToken, ok := token.Extra("id_token").(string)
+	if !ok || rawIDToken == "" {
+		return nil, errors.New("no id_token for degraded mode")
+	}
+	idToken, err := c.credentials.get().verifier.Verify(ctx, rawIDToken)
+	if err != nil {
+		return nil, fmt.Errorf("failed to verify id_token for degraded mode: %v", err)
+	}
+	if sub, _ := claims["sub"].(string); sub != "" && sub != idToken.Subject {
+		return nil, errors.New("userinfo subject does not match id_token")
+	}
+
+	username, _ := claims["preferred_username"].(string)
+	if username == "" {
+		username, _ = claims["username"].(string)
+	}
+	return &iam.IntrospectResponse{
+		Active:   true,
+		Sub:      idToken.Subject,
+		Username: username,
+		Expires:  idToken.Expiry.Unix(),
+		ISS:      idToken.Issuer,
+	}, nil
+}
+
 // removeElement removes an element from a slice. It works for any ordered type (e.g., numbers, strings).
 func removeElement[T comparable](slice []T, elementToRemove T) []T {
 	var newSlice []T
@@ -16,6 +16,7 @@ import (
 	"net/http/httptest"
 	"reflect"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 
@@ -40,12 +41,13 @@ func TestHandleCallback(t *testing.T) {
 			expectUserID:   "subvalue",
 			expectUserName: "username",
 			token: map[string]interface{}{
-				"sub":         "subvalue",
-				"name":        "namevalue",
-				"username":    "username",
-				"email":       "emailvalue",
-				"given_name":  "givenname",
-				"family_name": "familyname",
+				"sub":            "subvalue",
+				"name":           "namevalue",
+				"username":       "username",
+				"email":          "emailvalue",
+				"email_verified": true,
+				"given_name":     "givenname",
+				"family_name":    "familyname",
 			},
 		},
 	}
@@ -107,6 +109,155 @@ func TestHandleCallback(t *testing.T) {
 	}
 }
 
+type countingTransport struct {
+	mu       sync.Mutex
+	requests map[string]int
+}
+
+func (t *countingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	t.mu.Lock()
+	t.requests[req.URL.Path]++
+	t.mu.Unlock()
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+type testHTTPClients struct{ transport *countingTransport }
+
+func (f testHTTPClients) HTTPClient([]string, bool) (*http.Client, error) {
+	return &http.Client{Transport: f.transport}, nil
+}
+
+func TestSharedHTTPClient(t *testing.T) {
+	testServer, iamServer, idmServer, err := setupServers(map[string]interface{}{
+		"sub":      "subvalue",
+		"username": "username",
+		"email":    "emailvalue",
+	})
+	if err != nil {
+		t.Fatal("failed to setup test server", err)
+	}
+	defer testServer.Close()
+	defer iamServer.Close()
+	defer idmServer.Close()
+
+	transport := &countingTransport{requests: make(map[string]int)}
+	config := hsdp.Config{
+		Issuer:       testServer.URL,
+		ClientID:     "clientID",
+		ClientSecret: "clientSecret",
+		IAMURL:       iamServer.URL,
+		IDMURL:       idmServer.URL,
+		RedirectURI:  testServer.URL + "/callback",
+	}
+	config.SetHTTPClientFactory(testHTTPClients{transport})
+
+	conn, err := newConnector(config)
+	if err != nil {
+		t.Fatal("failed to create new connector", err)
+	}
+	req, err := newRequestWithAuthCode(testServer.URL, "someCode")
+	if err != nil {
+		t.Fatal("failed to create request", err)
+	}
+	if _, err := conn.HandleCallback(connector.Scopes{Groups: true}, nil, req); err != nil {
+		t.Fatal("handle callback failed", err)
+	}
+
+	for _, path := range []string{"/.well-known/openid-configuration", "/token", "/userinfo"} {
+		if transport.requests[path] == 0 {
+			t.Errorf("expected request to %s through the shared client, got %v", path, transport.requests)
+		}
+	}
+}
+
+// revoked records the tokens revoked at the test servers.
+var revoked struct {
+	sync.Mutex
+	hints []string
+}
+
+func TestRevokeTokens(t *testing.T) {
+	testServer, iamServer, idmServer, err := setupServers(map[string]interface{}{"sub": "subvalue", "username": "username"})
+	if err != nil {
+		t.Fatal("failed to setup test server", err)
+	}
+	defer testServer.Close()
+	defer iamServer.Close()
+	defer idmServer.Close()
+
+	conn, err := newConnector(hsdp.Config{
+		Issuer:       testServer.URL,
+		ClientID:     "clientID",
+		ClientSecret: "clientSecret",
+		IAMURL:       iamServer.URL,
+		IDMURL:       idmServer.URL,
+		RedirectURI:  testServer.URL + "/callback",
+	})
+	if err != nil {
+		t.Fatal("failed to create new connector", err)
+	}
+
+	data, err := json.Marshal(hsdp.ConnectorData{RefreshToken: []byte("refresh"), AccessToken: []byte("access")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	revoked.Lock()
+	revoked.hints = nil
+	revoked.Unlock()
+	if err := conn.RevokeTokens(t.Context(), data); err != nil {
+		t.Fatal("revoke tokens failed", err)
+	}
+	revoked.Lock()
+	defer revoked.Unlock()
+	if want := []string{"refresh_token:refresh", "access_token:access"}; !reflect.DeepEqual(revoked.hints, want) {
+		t.Errorf("expected revoked tokens %v, got %v", want, revoked.hints)
+	}
+}
+
+func TestExtendPayloadClockSkew(t *testing.T) {
+	testServer, iamServer, idmServer, err := setupServers(map[string]interface{}{"sub": "subvalue"})
+	if err != nil {
+		t.Fatal("failed to setup test server", err)
+	}
+	defer testServer.Close()
+	defer iamServer.Close()
+	defer idmServer.Close()
+
+	conn, err := newConnector(hsdp.Config{
+		Issuer:       testServer.URL,
+		ClientID:     "clientID",
+		ClientSecret: "clientSecret",
+		IAMURL:       iamServer.URL,
+		IDMURL:       idmServer.URL,
+		RedirectURI:  testServer.URL + "/callback",
+		ClockSkew:    "1m",
+	})
+	if err != nil {
+		t.Fatal("failed to create new connector", err)
+	}
+
+	expires := time.Now().Add(time.Hour).Unix()
+	cdata, err := json.Marshal(hsdp.ConnectorData{
+		Introspect: iam.IntrospectResponse{IdentityType: "Service", Sub: "service@example.com", Expires: expires},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	payload, err := conn.ExtendPayload(nil, []byte(`{"sub":"subvalue"}`), cdata)
+	if err != nil {
+		t.Fatal("extend payload failed", err)
+	}
+	var claims struct {
+		Expiry int64 `json:"exp"`
+	}
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		t.Fatal(err)
+	}
+	if claims.Expiry != expires-60 {
+		t.Errorf("Expected exp %d to equal %d", claims.Expiry, expires-60)
+	}
+}
+
 func setupServers(tok map[string]interface{}) (dexmux *httptest.Server, iammux *httptest.Server, idmmux *httptest.Server, err error) {
 	key, err := rsa.GenerateKey(rand.Reader, 1024)
 	if err != nil {
@@ -164,9 +315,20 @@ func setupServers(tok map[string]interface{}) (dexmux *httptest.Server, iammux *
 			"userinfo_endpoint":      fmt.Sprintf("%s/userinfo", url),
 			"jwks_uri":               fmt.Sprintf("%s/keys", url),
 			"introspection_endpoint": fmt.Sprintf("%s/introspect", url),
+			"revocation_endpoint":    fmt.Sprintf("%s/revoke", url),
 		})
 	})
 
+	mux.HandleFunc("/revoke", func(w http.ResponseWriter, r *http.Request) {
+		if user, _, _ := r.BasicAuth(); user != "clientID" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		revoked.Lock()
+		revoked.hints = append(revoked.hints, r.FormValue("token_type_hint")+":"+r.FormValue("token"))
+		revoked.Unlock()
+	})
+
 	mux.HandleFunc("/introspect", func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Add("Content-Type", "application/json")
 		json.NewEncoder(w).Encode(&iam.IntrospectResponse{
@@ -0,0 +1,1319 @@
+package hsdp_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dip-software/go-dip-api/iam"
+
+	"github.com/dexidp/dex/connector"
+	"github.com/dexidp/dex/connector/hsdp"
+	"github.com/dexidp/dex/connector/hsdp/internal/iamtest"
+)
+
+var (
+	iamUser = iamtest.User{
+		Sub:      "user-uuid",
+		Username: "rswanson",
+		Email:    "ron@example.com",
+		Profile:  &iam.Profile{GivenName: "Ron", FamilyName: "Swanson"},
+		Organizations: []iamtest.Organization{{
+			ID:          "org-1",
+			Groups:      []string{"Admins"},
+			Roles:       []string{"ADMIN"},
+			Permissions: []string{"LOG.READ"},
+		}},
+	}
+	iamService = iamtest.User{
+		Sub:          "service@example.com",
+		Username:     "service",
+		IdentityType: "Service",
+	}
+)
+
+func newIAMConnector(t *testing.T, iamServer *iamtest.Server, saml bool, opts ...func(*hsdp.Config)) *hsdp.HSDPConnector {
+	t.Helper()
+	config := hsdp.Config{
+		Issuer:           iamServer.Issuer.URL,
+		ClientID:         iamServer.ClientID,
+		ClientSecret:     iamServer.ClientSecret,
+		IAMURL:           iamServer.IAM.URL,
+		IDMURL:           iamServer.IDM.URL,
+		RedirectURI:      "https://dex.example.com/callback",
+		EnableGroupClaim: true,
+		TenantMap:        hsdp.TenantMap{"org-1": "tenant-1"},
+	}
+	if saml {
+		config.SAML2LoginURL = iamServer.Issuer.URL + "/saml2/login"
+	}
+	for _, opt := range opts {
+		opt(&config)
+	}
+	conn, err := newConnector(config)
+	if err != nil {
+		t.Fatal("failed to create new connector", err)
+	}
+	return conn
+}
+
+func callbackRequest(t *testing.T, query url.Values) *http.Request {
+	t.Helper()
+	req, err := http.NewRequest(http.MethodGet, "https://dex.example.com/callback?"+query.Encode(), nil)
+	if err != nil {
+		t.Fatal("failed to create request", err)
+	}
+	return req
+}
+
+func TestIAMLogin(t *testing.T) {
+	tests := []struct {
+		name      string
+		saml      bool
+		user      iamtest.User
+		query     url.Values
+		failPath  string
+		wantErr   bool
+		wantEmail string
+		wantName  string
+	}{
+		{
+			name:      "OIDC",
+			user:      iamUser,
+			query:     url.Values{"code": {"valid"}},
+			wantEmail: "ron@example.com",
+			wantName:  "Ron Swanson",
+		},
+		{
+			name:      "SAML2 bearer",
+			saml:      true,
+			user:      iamUser,
+			query:     url.Values{"assertion": {"valid"}},
+			wantEmail: "ron@example.com",
+			wantName:  "Ron Swanson",
+		},
+		{
+			name:      "Service identity",
+			user:      iamService,
+			query:     url.Values{"code": {"valid"}},
+			wantEmail: "service@example.com",
+		},
+		{
+			name:    "Unknown code",
+			user:    iamUser,
+			query:   url.Values{"code": {"unknown"}},
+			wantErr: true,
+		},
+		{
+			name:    "Unknown assertion",
+			saml:    true,
+			user:    iamUser,
+			query:   url.Values{"assertion": {"unknown"}},
+			wantErr: true,
+		},
+		{
+			name:    "Upstream error",
+			user:    iamUser,
+			query:   url.Values{"error": {"access_denied"}},
+			wantErr: true,
+		},
+		{
+			name:     "Introspection unavailable",
+			user:     iamUser,
+			query:    url.Values{"code": {"valid"}},
+			failPath: "/introspect",
+			wantErr:  true,
+		},
+		{
+			name:      "Legacy user API unavailable",
+			user:      iamUser,
+			query:     url.Values{"code": {"valid"}},
+			failPath:  "/security/users/user-uuid",
+			wantEmail: "ron@example.com",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			iamServer := iamtest.NewServer(t, "clientID", "clientSecret")
+			conn := newIAMConnector(t, iamServer, tc.saml)
+			if tc.saml {
+				iamServer.AddAssertion("valid", tc.user)
+			} else {
+				iamServer.AddCode("valid", tc.user)
+			}
+			if tc.failPath != "" {
+				iamServer.Fail(tc.failPath, http.StatusServiceUnavailable)
+			}
+
+			identity, err := conn.HandleCallback(connector.Scopes{OfflineAccess: true, Groups: true}, nil, callbackRequest(t, tc.query))
+			if tc.wantErr {
+				if err == nil {
+					t.Fatal("expected handle callback to fail")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal("handle callback failed", err)
+			}
+			if identity.UserID != tc.user.Sub {
+				t.Errorf("expected user ID %q, got %q", tc.user.Sub, identity.UserID)
+			}
+			if identity.Username != tc.user.Username {
+				t.Errorf("expected username %q, got %q", tc.user.Username, identity.Username)
+			}
+			if identity.Email != tc.wantEmail {
+				t.Errorf("expected email %q, got %q", tc.wantEmail, identity.Email)
+			}
+
+			var cd hsdp.ConnectorData
+			if err := json.Unmarshal(identity.ConnectorData, &cd); err != nil {
+				t.Fatal(err)
+			}
+			if len(cd.RefreshToken) == 0 {
+				t.Error("expected a refresh token in the connector data")
+			}
+			if tc.saml && string(cd.Assertion) != "valid" {
+				t.Errorf("expected the assertion to be kept, got %q", cd.Assertion)
+			}
+
+			payload, err := conn.ExtendPayload(nil, []byte(`{"sub":"subvalue"}`), identity.ConnectorData)
+			if err != nil {
+				t.Fatal("extend payload failed", err)
+			}
+			var claims struct {
+				Name   string   `json:"name"`
+				Groups []string `json:"groups"`
+				ORT    []string `json:"ort"`
+			}
+			if err := json.Unmarshal(payload, &claims); err != nil {
+				t.Fatal(err)
+			}
+			if claims.Name != tc.wantName {
+				t.Errorf("expected name %q, got %q", tc.wantName, claims.Name)
+			}
+			if len(tc.user.Organizations) > 0 {
+				if want := []string{"urn:iamg:org-1:admins"}; !reflect.DeepEqual(claims.Groups, want) {
+					t.Errorf("expected groups %v, got %v", want, claims.Groups)
+				}
+				if want := []string{"tenant-1"}; !reflect.DeepEqual(claims.ORT, want) {
+					t.Errorf("expected tenants %v, got %v", want, claims.ORT)
+				}
+			}
+		})
+	}
+}
+
+func TestIAMDegradedMode(t *testing.T) {
+	tests := []struct {
+		name         string
+		degradedMode bool
+		wantErr      bool
+	}{
+		{name: "Disabled", wantErr: true},
+		{name: "Enabled", degradedMode: true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			iamServer := iamtest.NewServer(t, "clientID", "clientSecret")
+			conn := newIAMConnector(t, iamServer, false, func(c *hsdp.Config) {
+				c.DegradedMode = tc.degradedMode
+			})
+			user := iamUser
+			user.Claims = map[string]any{"preferred_username": user.Username}
+			iamServer.AddCode("valid", user)
+			iamServer.Fail("/introspect", http.StatusServiceUnavailable)
+
+			identity, err := conn.HandleCallback(connector.Scopes{Groups: true}, nil, callbackRequest(t, url.Values{"code": {"valid"}}))
+			if tc.wantErr {
+				if err == nil {
+					t.Fatal("expected handle callback to fail")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal("handle callback failed", err)
+			}
+			if identity.UserID != user.Sub || identity.Username != user.Username {
+				t.Errorf("expected user %q (%q), got %q (%q)", user.Sub, user.Username, identity.UserID, identity.Username)
+			}
+
+			payload, err := conn.ExtendPayload(nil, []byte(`{"sub":"subvalue"}`), identity.ConnectorData)
+			if err != nil {
+				t.Fatal("extend payload failed", err)
+			}
+			var claims map[string]any
+			if err := json.Unmarshal(payload, &claims); err != nil {
+				t.Fatal(err)
+			}
+			if claims["dgr"] != true {
+				t.Errorf("expected a degraded claim, got %v", claims)
+			}
+			if _, ok := claims["groups"]; ok {
+				t.Errorf("expected no groups in degraded mode, got %v", claims["groups"])
+			}
+		})
+	}
+}
+
+func TestIAMRefresh(t *testing.T) {
+	tests := []struct {
+		name    string
+		token   string
+		wantErr bool
+	}{
+		{name: "Valid refresh token", token: "valid"},
+		{name: "Unknown refresh token", token: "unknown", wantErr: true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			iamServer := iamtest.NewServer(t, "clientID", "clientSecret")
+			conn := newIAMConnector(t, iamServer, false)
+			iamServer.AddRefreshToken("valid", iamUser)
+
+			data, err := json.Marshal(hsdp.ConnectorData{RefreshToken: []byte(tc.token)})
+			if err != nil {
+				t.Fatal(err)
+			}
+			identity, err := conn.Refresh(t.Context(), connector.Scopes{OfflineAccess: true}, connector.Identity{ConnectorData: data})
+			if tc.wantErr {
+				if err == nil {
+					t.Fatal("expected refresh to fail")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal("refresh failed", err)
+			}
+			if identity.UserID != iamUser.Sub {
+				t.Errorf("expected user ID %q, got %q", iamUser.Sub, identity.UserID)
+			}
+			var cd hsdp.ConnectorData
+			if err := json.Unmarshal(identity.ConnectorData, &cd); err != nil {
+				t.Fatal(err)
+			}
+			if string(cd.RefreshToken) == tc.token {
+				t.Error("expected the refresh token to be rotated")
+			}
+		})
+	}
+}
+
+func TestIAMTokenExchange(t *testing.T) {
+	tests := []struct {
+		name      string
+		token     string
+		user      iamtest.User
+		wantErr   bool
+		wantEmail string
+	}{
+		{name: "User token", token: "valid", user: iamUser, wantEmail: "ron@example.com"},
+		{name: "Service token", token: "valid", user: iamService, wantEmail: "service@example.com"},
+		{name: "Unknown token", token: "unknown", user: iamUser, wantErr: true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			iamServer := iamtest.NewServer(t, "clientID", "clientSecret")
+			conn := newIAMConnector(t, iamServer, false)
+			iamServer.AddAccessToken("valid", tc.user)
+
+			identity, err := conn.TokenIdentity(t.Context(), "urn:ietf:params:oauth:token-type:access_token", tc.token)
+			if tc.wantErr {
+				if err == nil {
+					t.Fatal("expected token exchange to fail")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal("token exchange failed", err)
+			}
+			if identity.UserID != tc.user.Sub {
+				t.Errorf("expected user ID %q, got %q", tc.user.Sub, identity.UserID)
+			}
+			if identity.Email != tc.wantEmail {
+				t.Errorf("expected email %q, got %q", tc.wantEmail, identity.Email)
+			}
+		})
+	}
+}
+
+func TestIAMJWTAccessTokenExchange(t *testing.T) {
+	tests := []struct {
+		name           string
+		audience       string
+		expiry         time.Duration
+		opaque         bool
+		wantErr        bool
+		wantIntrospect int
+	}{
+		{name: "JWT", audience: "clientID", expiry: time.Hour},
+		{name: "JWT for other audience", audience: "other", expiry: time.Hour, wantErr: true},
+		{name: "Expired JWT", audience: "clientID", expiry: -time.Hour, wantErr: true},
+		{name: "Opaque", opaque: true, wantIntrospect: 1},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			iamServer := iamtest.NewServer(t, "clientID", "clientSecret")
+			conn := newIAMConnector(t, iamServer, false)
+			token := "opaque"
+			if tc.opaque {
+				iamServer.AddAccessToken(token, iamUser)
+			} else {
+				token = iamServer.JWTAccessToken(t, iamUser, tc.audience, time.Now().Add(tc.expiry))
+			}
+
+			identity, err := conn.TokenIdentity(t.Context(), "urn:ietf:params:oauth:token-type:access_token", token)
+			if tc.wantErr {
+				if err == nil {
+					t.Fatal("expected token exchange to fail")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal("token exchange failed", err)
+			}
+			if got := iamServer.Requests("/introspect"); got != tc.wantIntrospect {
+				t.Errorf("expected %d introspection requests, got %d", tc.wantIntrospect, got)
+			}
+			if identity.UserID != iamUser.Sub {
+				t.Errorf("expected user ID %q, got %q", iamUser.Sub, identity.UserID)
+			}
+
+			payload, err := conn.ExtendPayload(nil, []byte(`{"sub":"subvalue"}`), identity.ConnectorData)
+			if err != nil {
+				t.Fatal("extend payload failed", err)
+			}
+			var claims struct {
+				Groups []string `json:"groups"`
+			}
+			if err := json.Unmarshal(payload, &claims); err != nil {
+				t.Fatal(err)
+			}
+			if want := []string{"urn:iamg:org-1:admins"}; !reflect.DeepEqual(claims.Groups, want) {
+				t.Errorf("expected groups %v, got %v", want, claims.Groups)
+			}
+		})
+	}
+}
+
+func TestIAMRevokeAndHealth(t *testing.T) {
+	iamServer := iamtest.NewServer(t, "clientID", "clientSecret")
+	conn := newIAMConnector(t, iamServer, false)
+
+	if err := conn.HealthCheck(t.Context()); err != nil {
+		t.Fatal("health check failed", err)
+	}
+	iamServer.Fail("/.well-known/openid-configuration", http.StatusServiceUnavailable)
+	if err := conn.HealthCheck(t.Context()); err == nil {
+		t.Error("expected health check to fail")
+	}
+
+	data, err := json.Marshal(hsdp.ConnectorData{RefreshToken: []byte("refresh"), AccessToken: []byte("access")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := conn.RevokeTokens(t.Context(), data); err != nil {
+		t.Fatal("revoke tokens failed", err)
+	}
+	if want := []string{"refresh_token:refresh", "access_token:access"}; !reflect.DeepEqual(iamServer.Revoked(), want) {
+		t.Errorf("expected revoked tokens %v, got %v", want, iamServer.Revoked())
+	}
+}
+
+func TestIAMProfileMaxAge(t *testing.T) {
+	stored := iam.Profile{GivenName: "Leslie", FamilyName: "Knope"}
+	tests := []struct {
+		name        string
+		fetchedAgo  time.Duration
+		policy      string
+		idmDown     bool
+		noStored    bool
+		wantErr     bool
+		wantGiven   string
+		wantRefetch bool
+	}{
+		{name: "Fresh profile is reused", fetchedAgo: time.Minute, idmDown: true, wantGiven: "Leslie"},
+		{name: "Stale profile is fetched", fetchedAgo: 2 * time.Hour, wantGiven: "Ron", wantRefetch: true},
+		{name: "Stale profile is kept with warn", fetchedAgo: 2 * time.Hour, idmDown: true, wantGiven: "Leslie"},
+		{name: "Stale profile fails with fail", fetchedAgo: 2 * time.Hour, policy: "fail", idmDown: true, wantErr: true},
+		{name: "Missing profile is skipped with warn", idmDown: true, noStored: true},
+		{name: "Missing profile fails with fail", policy: "fail", idmDown: true, noStored: true, wantErr: true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			iamServer := iamtest.NewServer(t, "clientID", "clientSecret")
+			conn := newIAMConnector(t, iamServer, false, func(c *hsdp.Config) {
+				c.ProfileMaxAge = "1h"
+				c.ProfileStalePolicy = tc.policy
+			})
+			iamServer.AddRefreshToken("valid", iamUser)
+			if tc.idmDown {
+				iamServer.Fail("/security/users/"+iamUser.Sub, http.StatusServiceUnavailable)
+			}
+
+			fetchedAt := time.Now().Add(-tc.fetchedAgo)
+			prev := hsdp.ConnectorData{
+				RefreshToken:     []byte("valid"),
+				Introspect:       iam.IntrospectResponse{Sub: iamUser.Sub},
+				User:             stored,
+				ProfileFetchedAt: fetchedAt,
+			}
+			if tc.noStored {
+				prev.User, prev.ProfileFetchedAt = iam.Profile{}, time.Time{}
+			}
+			data, err := json.Marshal(prev)
+			if err != nil {
+				t.Fatal(err)
+			}
+			identity, err := conn.Refresh(t.Context(), connector.Scopes{OfflineAccess: true}, connector.Identity{ConnectorData: data})
+			if tc.wantErr {
+				if err == nil {
+					t.Fatal("expected refresh to fail")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal("refresh failed", err)
+			}
+
+			var cd hsdp.ConnectorData
+			if err := json.Unmarshal(identity.ConnectorData, &cd); err != nil {
+				t.Fatal(err)
+			}
+			if cd.User.GivenName != tc.wantGiven {
+				t.Errorf("expected given name %q, got %q", tc.wantGiven, cd.User.GivenName)
+			}
+			if refetched := cd.ProfileFetchedAt.After(fetchedAt); refetched != tc.wantRefetch {
+				t.Errorf("expected profile refetched %v, fetched at %v", tc.wantRefetch, cd.ProfileFetchedAt)
+			}
+		})
+	}
+}
+
+func TestIAMOrganizationsClaim(t *testing.T) {
+	tests := []struct {
+		name    string
+		parents bool
+		want    []hsdp.Organization
+	}{
+		{
+			name: "Organizations",
+			want: []hsdp.Organization{{ID: "org-1", Name: "Pawnee Parks"}},
+		},
+		{
+			name:    "Organizations with parents",
+			parents: true,
+			want: []hsdp.Organization{{
+				ID:      "org-1",
+				Name:    "Pawnee Parks",
+				Parents: []hsdp.OrganizationRef{{ID: "org-root", Name: "Pawnee"}, {ID: "org-missing"}},
+			}},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			iamServer := iamtest.NewServer(t, "clientID", "clientSecret")
+			iamServer.AddOrganization("org-1", "Pawnee Parks", "org-root")
+			iamServer.AddOrganization("org-root", "Pawnee", "org-missing")
+			conn := newIAMConnector(t, iamServer, false, func(c *hsdp.Config) {
+				c.EnableOrganizationsClaim = true
+				c.OrganizationParents = tc.parents
+			})
+
+			for i := range 2 {
+				iamServer.AddAccessToken("valid", iamUser)
+				identity, err := conn.TokenIdentity(t.Context(), "urn:ietf:params:oauth:token-type:access_token", "valid")
+				if err != nil {
+					t.Fatal("token exchange failed", err)
+				}
+				payload, err := conn.ExtendPayload(nil, []byte(`{"sub":"subvalue"}`), identity.ConnectorData)
+				if err != nil {
+					t.Fatal("extend payload failed", err)
+				}
+				var claims struct {
+					Organizations []hsdp.Organization `json:"organizations"`
+				}
+				if err := json.Unmarshal(payload, &claims); err != nil {
+					t.Fatal(err)
+				}
+				if !reflect.DeepEqual(claims.Organizations, tc.want) {
+					t.Errorf("login %d: expected organizations %+v, got %+v", i, tc.want, claims.Organizations)
+				}
+			}
+
+			// The second login is served from the cache.
+			if n := iamServer.Requests("/authorize/scim/v2/Organizations/org-1"); n != 1 {
+				t.Errorf("expected 1 organization lookup, got %d", n)
+			}
+		})
+	}
+}
+
+func TestIAMEmailVerified(t *testing.T) {
+	tests := []struct {
+		name       string
+		claims     map[string]any
+		status     string
+		skipVerify bool
+		want       bool
+	}{
+		{name: "Unverified"},
+		{name: "Verified in userinfo", claims: map[string]any{"email_verified": true}, want: true},
+		{name: "Userinfo takes precedence", claims: map[string]any{"email_verified": false}, status: "true"},
+		{name: "Verified in profile", status: "true", want: true},
+		{name: "Skip email verification", skipVerify: true, want: true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			iamServer := iamtest.NewServer(t, "clientID", "clientSecret")
+			conn := newIAMConnector(t, iamServer, false, func(c *hsdp.Config) {
+				c.InsecureSkipEmailVerified = tc.skipVerify
+			})
+
+			user := iamUser
+			user.Claims = tc.claims
+			user.Profile = &iam.Profile{GivenName: "Ron", FamilyName: "Swanson", EmailVerifiedStatus: tc.status}
+			iamServer.AddAccessToken("valid", user)
+			identity, err := conn.TokenIdentity(t.Context(), "urn:ietf:params:oauth:token-type:access_token", "valid")
+			if err != nil {
+				t.Fatal("token exchange failed", err)
+			}
+			if identity.EmailVerified != tc.want {
+				t.Errorf("expected email verified %t, got %t", tc.want, identity.EmailVerified)
+			}
+		})
+	}
+}
+
+func TestIAMTenantClaim(t *testing.T) {
+	for _, enabled := range []bool{false, true} {
+		t.Run(fmt.Sprintf("enabled=%t", enabled), func(t *testing.T) {
+			iamServer := iamtest.NewServer(t, "clientID", "clientSecret")
+			conn := newIAMConnector(t, iamServer, false, func(c *hsdp.Config) {
+				c.EnableTenantClaim = enabled
+			})
+
+			iamServer.AddAccessToken("valid", iamUser)
+			identity, err := conn.TokenIdentity(t.Context(), "urn:ietf:params:oauth:token-type:access_token", "valid")
+			if err != nil {
+				t.Fatal("token exchange failed", err)
+			}
+			payload, err := conn.ExtendPayload(nil, []byte(`{"sub":"subvalue"}`), identity.ConnectorData)
+			if err != nil {
+				t.Fatal("extend payload failed", err)
+			}
+			var claims struct {
+				ManagingOrganization string `json:"managing_organization"`
+				Tenant               string `json:"tenant"`
+			}
+			if err := json.Unmarshal(payload, &claims); err != nil {
+				t.Fatal(err)
+			}
+
+			wantOrg, wantTenant := "", ""
+			if enabled {
+				wantOrg, wantTenant = "org-1", "tenant-1"
+			}
+			if claims.ManagingOrganization != wantOrg {
+				t.Errorf("expected managing_organization %q, got %q", wantOrg, claims.ManagingOrganization)
+			}
+			if claims.Tenant != wantTenant {
+				t.Errorf("expected tenant %q, got %q", wantTenant, claims.Tenant)
+			}
+
+			// The custom claims of the identity don't depend on the
+			// config, clients choose which they get.
+			wantCustom := map[string]interface{}{
+				"managing_organization": "org-1",
+				"tenant":                "tenant-1",
+				"permissions":           map[string]interface{}{"org-1": []string{"LOG.READ"}},
+			}
+			if !reflect.DeepEqual(identity.CustomClaims, wantCustom) {
+				t.Errorf("expected custom claims %v, got %v", wantCustom, identity.CustomClaims)
+			}
+		})
+	}
+}
+
+func TestIAMIntrospectClaims(t *testing.T) {
+	iamServer := iamtest.NewServer(t, "clientID", "clientSecret")
+	conn := newIAMConnector(t, iamServer, false, func(c *hsdp.Config) {
+		c.IntrospectClaims = []string{"token_type", "client_id", "identity_type", "organizations"}
+	})
+
+	iamServer.AddAccessToken("valid", iamUser)
+	identity, err := conn.TokenIdentity(t.Context(), "urn:ietf:params:oauth:token-type:access_token", "valid")
+	if err != nil {
+		t.Fatal("token exchange failed", err)
+	}
+	if got := identity.CustomClaims["token_type"]; got != "Bearer" {
+		t.Errorf("expected token_type claim %q, got %v", "Bearer", got)
+	}
+	if got := identity.CustomClaims["client_id"]; got != "clientID" {
+		t.Errorf("expected client_id claim %q, got %v", "clientID", got)
+	}
+	if _, ok := identity.CustomClaims["identity_type"]; ok {
+		t.Error("expected empty identity_type to be left out")
+	}
+	orgs, ok := identity.CustomClaims["organizations"].(map[string]interface{})
+	if !ok || orgs["managingOrganization"] != "org-1" {
+		t.Errorf("expected organizations claim with managing organization org-1, got %v", identity.CustomClaims["organizations"])
+	}
+
+	_, err = newConnector(hsdp.Config{Issuer: iamServer.Issuer.URL, IntrospectClaims: []string{"unknown"}})
+	if err == nil || !strings.Contains(err.Error(), "introspectClaims") {
+		t.Errorf("expected error for unknown introspect claim, got %v", err)
+	}
+}
+
+func TestIAMStrictScopeMatching(t *testing.T) {
+	tests := []struct {
+		name       string
+		strict     bool
+		scope      string
+		wantClaims []string
+		wantAbsent []string
+	}{
+		{
+			name:       "Not strict",
+			scope:      "openid email",
+			wantClaims: []string{"email", "name", "groups"},
+		},
+		{
+			name:       "All scopes granted",
+			strict:     true,
+			scope:      "openid email profile groups",
+			wantClaims: []string{"email", "name", "groups"},
+		},
+		{
+			name:       "Groups and profile not granted",
+			strict:     true,
+			scope:      "openid email",
+			wantClaims: []string{"email"},
+			wantAbsent: []string{"name", "groups", "username"},
+		},
+		{
+			name:       "No scopes reported",
+			strict:     true,
+			wantClaims: []string{"email", "name", "groups"},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			iamServer := iamtest.NewServer(t, "clientID", "clientSecret")
+			conn := newIAMConnector(t, iamServer, false, func(c *hsdp.Config) {
+				c.StrictScopeMatching = tc.strict
+			})
+			user := iamUser
+			user.Scope = tc.scope
+			iamServer.AddAccessToken("valid", user)
+
+			identity, err := conn.TokenIdentity(t.Context(), "urn:ietf:params:oauth:token-type:access_token", "valid")
+			if err != nil {
+				t.Fatal("token exchange failed", err)
+			}
+			payload, err := conn.ExtendPayload(nil, []byte(`{"sub":"subvalue","email":"ron@example.com"}`), identity.ConnectorData)
+			if err != nil {
+				t.Fatal("extend payload failed", err)
+			}
+			var claims map[string]any
+			if err := json.Unmarshal(payload, &claims); err != nil {
+				t.Fatal(err)
+			}
+			for _, c := range tc.wantClaims {
+				if _, ok := claims[c]; !ok {
+					t.Errorf("expected claim %q in %v", c, claims)
+				}
+			}
+			for _, c := range tc.wantAbsent {
+				if _, ok := claims[c]; ok {
+					t.Errorf("expected no claim %q in %v", c, claims)
+				}
+			}
+		})
+	}
+}
+
+func TestIAMLogoutURL(t *testing.T) {
+	tests := []struct {
+		name        string
+		mode        string
+		override    string
+		wantURL     string
+		wantRevoked []string
+	}{
+		{name: "Default", wantURL: ""},
+		{name: "Redirect", mode: "redirect", wantURL: "/logout?client_id=clientID&post_logout_redirect_uri=https%3A%2F%2Fdex.example.com%2Flogout%2Fcallback"},
+		{name: "Redirect to override", mode: "redirect", override: "https://iam.example.com/end", wantURL: "https://iam.example.com/end?client_id=clientID&post_logout_redirect_uri=https%3A%2F%2Fdex.example.com%2Flogout%2Fcallback"},
+		{name: "Backchannel", mode: "backchannel", wantRevoked: []string{"refresh_token:refresh", "access_token:access"}},
+		{name: "None", mode: "none"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			iamServer := iamtest.NewServer(t, "clientID", "clientSecret")
+			conn := newIAMConnector(t, iamServer, false, func(c *hsdp.Config) {
+				c.UpstreamLogout = tc.mode
+				c.EndSessionURL = tc.override
+			})
+			data, err := json.Marshal(hsdp.ConnectorData{RefreshToken: []byte("refresh"), AccessToken: []byte("access")})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if err := conn.BackchannelLogout(t.Context(), data); err != nil {
+				t.Fatal("backchannel logout failed", err)
+			}
+			logoutURL, err := conn.LogoutURL(t.Context(), data, "https://dex.example.com/logout/callback")
+			if err != nil {
+				t.Fatal("logout URL failed", err)
+			}
+			wantURL := tc.wantURL
+			if tc.override == "" && wantURL != "" {
+				wantURL = iamServer.Issuer.URL + wantURL
+			}
+			if logoutURL != wantURL {
+				t.Errorf("expected logout URL %q, got %q", wantURL, logoutURL)
+			}
+			if !reflect.DeepEqual(iamServer.Revoked(), tc.wantRevoked) {
+				t.Errorf("expected revoked tokens %v, got %v", tc.wantRevoked, iamServer.Revoked())
+			}
+		})
+	}
+}
+
+func TestIAMSAML2LoginHint(t *testing.T) {
+	tests := []struct {
+		name      string
+		param     string
+		loginHint string
+		want      url.Values
+	}{
+		{name: "No login hint", want: url.Values{}},
+		{name: "Default parameter", loginHint: "leslie@pawnee.gov", want: url.Values{"login_hint": {"leslie@pawnee.gov"}}},
+		{name: "Custom parameter", param: "username", loginHint: "leslie@pawnee.gov", want: url.Values{"username": {"leslie@pawnee.gov"}}},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			iamServer := iamtest.NewServer(t, "clientID", "clientSecret")
+			conn := newIAMConnector(t, iamServer, true, func(c *hsdp.Config) {
+				c.SAML2LoginHintParam = tc.param
+			})
+
+			loginURL, _, err := conn.LoginURLWithParams(connector.Scopes{}, "https://dex.example.com/callback", "state", connector.AuthRequestParams{LoginHint: tc.loginHint})
+			if err != nil {
+				t.Fatal("login URL failed", err)
+			}
+			u, err := url.Parse(loginURL)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := u.Query()
+			got.Del("redirect_uri")
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("expected login URL parameters %v, got %v", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestIAMSAML2RelayState(t *testing.T) {
+	tests := []struct {
+		name       string
+		enabled    bool
+		maxLength  int
+		relayState string
+		want       string
+	}{
+		{name: "Disabled", relayState: "signed"},
+		{name: "Enabled", enabled: true, relayState: "signed", want: "signed"},
+		{name: "Too long", enabled: true, maxLength: 4, relayState: "signed"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			iamServer := iamtest.NewServer(t, "clientID", "clientSecret")
+			conn := newIAMConnector(t, iamServer, true, func(c *hsdp.Config) {
+				c.SAML2RelayState = tc.enabled
+				c.SAML2RelayStateMaxLength = tc.maxLength
+			})
+
+			loginURL, _, err := conn.LoginURLWithParams(connector.Scopes{}, "https://dex.example.com/callback", "state", connector.AuthRequestParams{RelayState: tc.relayState})
+			if err != nil {
+				t.Fatal("login URL failed", err)
+			}
+			u, err := url.Parse(loginURL)
+			if err != nil {
+				t.Fatal(err)
+			}
+			callback, err := url.Parse(u.Query().Get("redirect_uri"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := callback.Query().Get("state"); got != "state" {
+				t.Errorf("expected state %q in the redirect URI, got %q", "state", got)
+			}
+			if got := callback.Query().Get("relay_state"); got != tc.want {
+				t.Errorf("expected relay state %q in the redirect URI, got %q", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestIAMSilentLogin(t *testing.T) {
+	tests := []struct {
+		name       string
+		saml       bool
+		wantPrompt string
+	}{
+		{name: "OIDC", wantPrompt: "none"},
+		{name: "SAML2", saml: true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			iamServer := iamtest.NewServer(t, "clientID", "clientSecret")
+			conn := newIAMConnector(t, iamServer, tc.saml)
+
+			loginURL, _, err := conn.LoginURLWithParams(connector.Scopes{OfflineAccess: true}, "https://dex.example.com/callback", "state", connector.AuthRequestParams{Prompt: "none"})
+			if err != nil {
+				t.Fatal("login URL failed", err)
+			}
+			u, err := url.Parse(loginURL)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tc.wantPrompt != "" {
+				if got := u.Query().Get("prompt"); got != tc.wantPrompt {
+					t.Errorf("expected prompt %q, got %q", tc.wantPrompt, got)
+				}
+				// IAM redirects back with login_required without an IAM session.
+				u.RawQuery = url.Values{"state": {"state"}, "error": {"login_required"}}.Encode()
+			} else if u.Host != "dex.example.com" {
+				t.Errorf("expected SAML2 silent login to return to dex, got %q", loginURL)
+			}
+
+			_, err = conn.HandleCallback(connector.Scopes{}, nil, callbackRequest(t, u.Query()))
+			var oauth2Err *connector.OAuth2Error
+			if !errors.As(err, &oauth2Err) || oauth2Err.Code != "login_required" {
+				t.Errorf("expected a login_required error, got %v", err)
+			}
+		})
+	}
+}
+
+func TestIAMLoginErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		saml     bool
+		query    url.Values
+		reject   string
+		wantCode string
+	}{
+		{name: "Invalid assertion", saml: true, reject: "Invalid SAML assertion signature", wantCode: "invalid_assertion"},
+		{name: "Expired SAML response", saml: true, reject: "SAML assertion has expired", wantCode: "saml_response_expired"},
+		{name: "Locked account", saml: true, reject: "User account is locked", wantCode: "account_locked"},
+		{name: "Password expired", reject: "The password of the user has expired", wantCode: "password_expired"},
+		{name: "Locked account upstream", query: url.Values{"error": {"access_denied"}, "error_description": {"Account locked"}}, wantCode: "account_locked"},
+		{name: "Other error", saml: true, reject: "Something went wrong"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			iamServer := iamtest.NewServer(t, "clientID", "clientSecret")
+			conn := newIAMConnector(t, iamServer, tc.saml)
+			query := tc.query
+			if query == nil {
+				iamServer.RejectGrant("rejected", tc.reject)
+				query = url.Values{"code": {"rejected"}}
+				if tc.saml {
+					query = url.Values{"assertion": {"rejected"}}
+				}
+			}
+
+			_, err := conn.HandleCallback(connector.Scopes{}, nil, callbackRequest(t, query))
+			if err == nil {
+				t.Fatal("expected handle callback to fail")
+			}
+			var loginErr *connector.LoginError
+			if !errors.As(err, &loginErr) {
+				if tc.wantCode != "" {
+					t.Errorf("expected a %s login error, got %v", tc.wantCode, err)
+				}
+				return
+			}
+			if loginErr.Code != tc.wantCode {
+				t.Errorf("expected login error code %q, got %q", tc.wantCode, loginErr.Code)
+			}
+			if loginErr.Message == "" {
+				t.Error("expected a message for users")
+			}
+		})
+	}
+}
+
+func TestIAMClientSecretRotation(t *testing.T) {
+	tests := []struct {
+		name            string
+		saml            bool
+		secondarySecret string
+		wantErr         bool
+	}{
+		{name: "OIDC", secondarySecret: "newSecret"},
+		{name: "SAML2", saml: true, secondarySecret: "newSecret"},
+		{name: "No secondary secret", wantErr: true},
+		{name: "Secondary secret rejected", secondarySecret: "otherSecret", wantErr: true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			iamServer := iamtest.NewServer(t, "clientID", "clientSecret")
+			conn := newIAMConnector(t, iamServer, tc.saml, func(c *hsdp.Config) {
+				c.SecondaryClientSecret = tc.secondarySecret
+			})
+			// The secret is rotated at IAM.
+			iamServer.ClientSecret = "newSecret"
+
+			query := url.Values{"code": {"valid"}}
+			if tc.saml {
+				iamServer.AddAssertion("valid", iamUser)
+				query = url.Values{"assertion": {"valid"}}
+			} else {
+				iamServer.AddCode("valid", iamUser)
+			}
+			identity, err := conn.HandleCallback(connector.Scopes{OfflineAccess: true}, nil, callbackRequest(t, query))
+			if tc.wantErr {
+				if err == nil {
+					t.Fatal("expected handle callback to fail")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal("handle callback failed", err)
+			}
+
+			// The secondary secret is used from now on.
+			if _, err := conn.Refresh(context.Background(), connector.Scopes{OfflineAccess: true}, identity); err != nil {
+				t.Fatal("refresh failed", err)
+			}
+			if err := conn.RevokeTokens(context.Background(), identity.ConnectorData); err != nil {
+				t.Fatal("revoke failed", err)
+			}
+		})
+	}
+}
+
+func TestIAMRegion(t *testing.T) {
+	tests := []struct {
+		name        string
+		region      string
+		environment string
+		wantErr     string
+	}{
+		{name: "Explicit URLs take precedence", region: "us-east", environment: "client-test"},
+		{name: "Production alias", region: "eu-west-1", environment: "production"},
+		{name: "Region without environment", region: "us-east", wantErr: "must be set together"},
+		{name: "Unknown environment", region: "us-east", environment: "staging", wantErr: "no HSP IAM"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			iamServer := iamtest.NewServer(t, "clientID", "clientSecret")
+			config := hsdp.Config{
+				Issuer:      iamServer.Issuer.URL,
+				ClientID:    iamServer.ClientID,
+				IAMURL:      iamServer.IAM.URL,
+				IDMURL:      iamServer.IDM.URL,
+				RedirectURI: "https://dex.example.com/callback",
+				Region:      tc.region,
+				Environment: tc.environment,
+			}
+			_, err := newConnector(config)
+			if tc.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+					t.Fatalf("expected an error containing %q, got %v", tc.wantErr, err)
+				}
+				return
+			}
+			// The IAM of the region isn't reachable, the explicit URLs are used.
+			if err != nil {
+				t.Fatal("failed to open the connector", err)
+			}
+		})
+	}
+}
+
+func TestIAMDiscoveryOverrides(t *testing.T) {
+	tests := []struct {
+		name      string
+		overrides bool
+		wantErr   bool
+	}{
+		{name: "Wrong discovery", wantErr: true},
+		{name: "Overridden", overrides: true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			iamServer := iamtest.NewServer(t, "clientID", "clientSecret")
+			// Private IAM instances publish endpoints that aren't reachable.
+			iamServer.SetDiscovery("token_endpoint", "http://127.0.0.1:1/token")
+			iamServer.SetDiscovery("introspection_endpoint", "http://127.0.0.1:1/introspect")
+			iamServer.SetDiscovery("check_session_endpoint", iamServer.Issuer.URL+"/check")
+			conn := newIAMConnector(t, iamServer, false, func(c *hsdp.Config) {
+				if tc.overrides {
+					c.ProviderDiscoveryOverrides = hsdp.ProviderDiscoveryOverrides{
+						TokenURL:         iamServer.Issuer.URL + "/token",
+						IntrospectionURL: iamServer.Issuer.URL + "/introspect",
+					}
+				}
+			})
+			iamServer.AddCode("valid", iamUser)
+
+			_, err := conn.HandleCallback(connector.Scopes{Groups: true}, nil, callbackRequest(t, url.Values{"code": {"valid"}}))
+			if tc.wantErr {
+				if err == nil {
+					t.Fatal("expected handle callback to fail")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal("handle callback failed", err)
+			}
+
+			endpoints := conn.Describe().Endpoints
+			if got, want := endpoints["token"], iamServer.Issuer.URL+"/token"; got != want {
+				t.Errorf("expected token endpoint %q, got %q", want, got)
+			}
+			if got, want := endpoints["check_session"], iamServer.Issuer.URL+"/check"; got != want {
+				t.Errorf("expected check_session endpoint %q, got %q", want, got)
+			}
+		})
+	}
+
+	t.Run("Invalid override", func(t *testing.T) {
+		iamServer := iamtest.NewServer(t, "clientID", "clientSecret")
+		_, err := newConnector(hsdp.Config{
+			Issuer:                     iamServer.Issuer.URL,
+			ClientID:                   iamServer.ClientID,
+			ClientSecret:               iamServer.ClientSecret,
+			IAMURL:                     iamServer.IAM.URL,
+			IDMURL:                     iamServer.IDM.URL,
+			RedirectURI:                "https://dex.example.com/callback",
+			ProviderDiscoveryOverrides: hsdp.ProviderDiscoveryOverrides{TokenURL: "/token"},
+		})
+		if err == nil {
+			t.Fatal("expected open to fail")
+		}
+	})
+}
+
+func TestIAMGroupNameTransform(t *testing.T) {
+	lowercase := false
+	tests := []struct {
+		name      string
+		transform hsdp.GroupNameTransform
+		want      []string
+	}{
+		{
+			name: "Default",
+			want: []string{"urn:iamg:org-1:k8s_cluster  admins", "urn:iamg:org-1:readers"},
+		},
+		{
+			name: "Kubernetes",
+			transform: hsdp.GroupNameTransform{
+				StripPrefix:   "K8S_",
+				ReplaceSpaces: "-",
+				Template:      "{{.Tenant}}:{{.Group}}",
+			},
+			want: []string{"tenant-1:cluster-admins", "tenant-1:readers"},
+		},
+		{
+			name: "Keep case and drop groups",
+			transform: hsdp.GroupNameTransform{
+				Lowercase: &lowercase,
+				Template:  `{{if ne .Group "Readers"}}{{.Group}}{{end}}`,
+			},
+			want: []string{"K8S_Cluster  Admins"},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			iamServer := iamtest.NewServer(t, "clientID", "clientSecret")
+			conn := newIAMConnector(t, iamServer, false, func(c *hsdp.Config) {
+				c.GroupNameTransform = tc.transform
+			})
+			user := iamUser
+			user.Organizations = []iamtest.Organization{{
+				ID:     "org-1",
+				Groups: []string{"K8S_Cluster  Admins", "Readers"},
+			}}
+			iamServer.AddAccessToken("valid", user)
+
+			identity, err := conn.TokenIdentity(t.Context(), "urn:ietf:params:oauth:token-type:access_token", "valid")
+			if err != nil {
+				t.Fatal("token exchange failed", err)
+			}
+			payload, err := conn.ExtendPayload(nil, []byte(`{"sub":"subvalue"}`), identity.ConnectorData)
+			if err != nil {
+				t.Fatal("extend payload failed", err)
+			}
+			var claims struct {
+				Groups []string `json:"groups"`
+			}
+			if err := json.Unmarshal(payload, &claims); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(claims.Groups, tc.want) {
+				t.Errorf("expected groups %q, got %q", tc.want, claims.Groups)
+			}
+		})
+	}
+
+	_, err := newConnector(hsdp.Config{GroupNameTransform: hsdp.GroupNameTransform{Template: "{{.Group"}})
+	if err == nil {
+		t.Error("expected an invalid template to be refused")
+	}
+}
+
+func TestIAMTenantSelection(t *testing.T) {
+	user := iamUser
+	user.Organizations = append(user.Organizations, iamtest.Organization{ID: "org-2"}, iamtest.Organization{ID: "org-3"})
+
+	tests := []struct {
+		name       string
+		disabled   bool
+		tenant     string
+		wantOrg    string
+		wantTenant string
+		wantErr    bool
+	}{
+		{name: "Derived from the token", wantOrg: "org-1", wantTenant: "tenant-1"},
+		{name: "By tenant", tenant: "tenant-2", wantOrg: "org-2", wantTenant: "tenant-2"},
+		{name: "By organization", tenant: "org-2", wantOrg: "org-2", wantTenant: "tenant-2"},
+		{name: "Not in the tenant map", tenant: "org-3", wantErr: true},
+		{name: "Not a member", tenant: "tenant-4", wantErr: true},
+		{name: "Disabled", disabled: true},
+		{name: "Disabled with tenant", disabled: true, tenant: "tenant-2", wantErr: true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			iamServer := iamtest.NewServer(t, "clientID", "clientSecret")
+			conn := newIAMConnector(t, iamServer, false, func(c *hsdp.Config) {
+				c.EnableTenantSelection = !tc.disabled
+				c.TenantMap = hsdp.TenantMap{"org-1": "tenant-1", "org-2": "tenant-2", "org-4": "tenant-4"}
+			})
+			iamServer.AddAccessToken("valid", user)
+
+			identity, err := conn.TokenIdentityWithParams(t.Context(), "urn:ietf:params:oauth:token-type:access_token", "valid", connector.TokenExchangeParams{Tenant: tc.tenant})
+			if tc.wantErr {
+				var oauth2Err *connector.OAuth2Error
+				if !errors.As(err, &oauth2Err) || oauth2Err.Code != "invalid_target" {
+					t.Fatalf("expected an invalid_target error, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal("token exchange failed", err)
+			}
+			if tc.wantTenant != "" && identity.CustomClaims["tenant"] != tc.wantTenant {
+				t.Errorf("expected custom claim tenant %q, got %v", tc.wantTenant, identity.CustomClaims["tenant"])
+			}
+
+			payload, err := conn.ExtendPayload(nil, []byte(`{"sub":"subvalue"}`), identity.ConnectorData)
+			if err != nil {
+				t.Fatal("extend payload failed", err)
+			}
+			var claims struct {
+				Tenant             string `json:"tenant"`
+				TenantOrganization string `json:"tenant_organization"`
+			}
+			if err := json.Unmarshal(payload, &claims); err != nil {
+				t.Fatal(err)
+			}
+			if claims.Tenant != tc.wantTenant {
+				t.Errorf("expected tenant %q, got %q", tc.wantTenant, claims.Tenant)
+			}
+			if claims.TenantOrganization != tc.wantOrg {
+				t.Errorf("expected tenant_organization %q, got %q", tc.wantOrg, claims.TenantOrganization)
+			}
+		})
+	}
+}
+
+func TestIAMStrictTokenExchange(t *testing.T) {
+	tests := []struct {
+		name      string
+		strict    bool
+		audiences []string
+		clientID  string
+		audience  []string
+		wantErr   bool
+	}{
+		{name: "Not strict", clientID: "other-app"},
+		{name: "Own client", strict: true},
+		{name: "Other client", strict: true, clientID: "other-app", wantErr: true},
+		{name: "Allowed client", strict: true, audiences: []string{"other-app"}, clientID: "other-app"},
+		{name: "Allowed audience", strict: true, audiences: []string{"https://api.example.com"}, clientID: "other-app", audience: []string{"https://api.example.com"}},
+		{name: "Other audience", strict: true, audiences: []string{"https://api.example.com"}, clientID: "other-app", audience: []string{"https://other.example.com"}, wantErr: true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			iamServer := iamtest.NewServer(t, "clientID", "clientSecret")
+			conn := newIAMConnector(t, iamServer, false, func(c *hsdp.Config) {
+				c.StrictTokenExchange = tc.strict
+				c.AccessTokenAudiences = tc.audiences
+			})
+			user := iamUser
+			user.ClientID = tc.clientID
+			user.Audience = tc.audience
+			iamServer.AddAccessToken("valid", user)
+
+			identity, err := conn.TokenIdentity(t.Context(), "urn:ietf:params:oauth:token-type:access_token", "valid")
+			if tc.wantErr {
+				if err == nil {
+					t.Fatal("expected the token to be refused")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal("token exchange failed", err)
+			}
+			if identity.UserID != iamUser.Sub {
+				t.Errorf("expected user %q, got %q", iamUser.Sub, identity.UserID)
+			}
+		})
+	}
+}
+
+func TestIAMTransport(t *testing.T) {
+	iamServer := iamtest.NewServer(t, "clientID", "clientSecret")
+	conn := newIAMConnector(t, iamServer, false, func(c *hsdp.Config) {
+		c.Transport = hsdp.Transport{MaxIdleConnsPerHost: 20, IdleConnTimeout: "5m", TLSSessionCacheSize: 128}
+	})
+	iamServer.AddAccessToken("valid", iamUser)
+	if _, err := conn.TokenIdentity(t.Context(), "urn:ietf:params:oauth:token-type:access_token", "valid"); err != nil {
+		t.Fatal("token exchange failed", err)
+	}
+
+	_, err := newConnector(hsdp.Config{Issuer: iamServer.Issuer.URL, Transport: hsdp.Transport{IdleConnTimeout: "forever"}})
+	if err == nil || !strings.Contains(err.Error(), "idleConnTimeout") {
+		t.Errorf("expected error for invalid idleConnTimeout, got %v", err)
+	}
+}
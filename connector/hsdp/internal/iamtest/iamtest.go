@@ -0,0 +1,545 @@
+// Package iamtest implements a fake HSP IAM and IDM for testing the hsdp
+// connector without real IAM credentials.
+package iamtest
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/dip-software/go-dip-api/iam"
+	"github.com/go-jose/go-jose/v4"
+)
+
+const saml2BearerGrant = "urn:ietf:params:oauth:grant-type:saml2-bearer"
+
+// User is an identity known to the fake IAM.
+type User struct {
+	Sub      string
+	Username string
+	Email    string
+
+	// IdentityType is returned by introspection, e.g. "Service".
+	IdentityType string
+
+	// Scope is the space separated scope returned by introspection.
+	Scope string
+
+	// Organizations is returned by introspection.
+	Organizations []Organization
+
+	// ClientID is the client the user's tokens were issued to, returned by
+	// introspection. Defaults to the client of the server.
+	ClientID string
+
+	// Audience is returned by introspection as aud, if set.
+	Audience []string
+
+	// Profile is served by the legacy user API. Users without a profile are
+	// not found there.
+	Profile *iam.Profile
+
+	// Claims are added to the userinfo response.
+	Claims map[string]any
+}
+
+// Organization is an organization membership of a User.
+type Organization struct {
+	ID          string
+	Name        string
+	Groups      []string
+	Roles       []string
+	Permissions []string
+}
+
+// Server is a fake HSP IAM. Issuer serves OIDC discovery, the token,
+// userinfo, introspection, revocation and end session endpoints, IAM and IDM
+// serve the health checks, the legacy user API and the organization API.
+type Server struct {
+	Issuer *httptest.Server
+	IAM    *httptest.Server
+	IDM    *httptest.Server
+
+	ClientID     string
+	ClientSecret string
+
+	key *jose.JSONWebKey
+
+	mu         sync.Mutex
+	codes      map[string]User
+	assertions map[string]User
+	rejected   map[string]string
+	access     map[string]User
+	refresh    map[string]User
+	orgs       map[string]iam.Organization
+	revoked    []string
+	failures   map[string]int
+	requests   map[string]int
+	discovery  map[string]any
+	next       int
+}
+
+// NewServer starts a fake IAM accepting the given client credentials. The
+// servers are closed when the test finishes.
+func NewServer(t testing.TB, clientID, clientSecret string) *Server {
+	t.Helper()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("iamtest: generate key: %v", err)
+	}
+
+	s := &Server{
+		ClientID:     clientID,
+		ClientSecret: clientSecret,
+		key:          &jose.JSONWebKey{Key: key, KeyID: "iamtest", Algorithm: string(jose.RS256), Use: "sig"},
+		codes:        make(map[string]User),
+		assertions:   make(map[string]User),
+		rejected:     make(map[string]string),
+		access:       make(map[string]User),
+		refresh:      make(map[string]User),
+		orgs:         make(map[string]iam.Organization),
+		failures:     make(map[string]int),
+		requests:     make(map[string]int),
+		discovery:    make(map[string]any),
+	}
+
+	issuer := http.NewServeMux()
+	issuer.HandleFunc("GET /.well-known/openid-configuration", s.handleDiscovery)
+	issuer.HandleFunc("GET /keys", s.handleKeys)
+	issuer.HandleFunc("POST /token", s.handleToken)
+	issuer.HandleFunc("GET /userinfo", s.handleUserInfo)
+	issuer.HandleFunc("POST /introspect", s.handleIntrospect)
+	issuer.HandleFunc("POST /revoke", s.handleRevoke)
+	issuer.HandleFunc("GET /logout", s.handleEndSession)
+
+	iamMux := http.NewServeMux()
+	iamMux.HandleFunc("GET /healthz", handleHealth)
+
+	idmMux := http.NewServeMux()
+	idmMux.HandleFunc("GET /healthz", handleHealth)
+	idmMux.HandleFunc("GET /security/users/{uuid}", s.handleLegacyUser)
+	idmMux.HandleFunc("GET /authorize/scim/v2/Organizations/{id}", s.handleOrganization)
+
+	s.Issuer = httptest.NewServer(s.intercept(issuer))
+	s.IAM = httptest.NewServer(s.intercept(iamMux))
+	s.IDM = httptest.NewServer(s.intercept(idmMux))
+	t.Cleanup(func() {
+		s.Issuer.Close()
+		s.IAM.Close()
+		s.IDM.Close()
+	})
+	return s
+}
+
+// AddCode makes an authorization code log in the user.
+func (s *Server) AddCode(code string, u User) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.codes[code] = u
+}
+
+// AddAssertion makes a SAML2 assertion log in the user.
+func (s *Server) AddAssertion(assertion string, u User) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.assertions[assertion] = u
+}
+
+// RejectGrant makes the token endpoint refuse an authorization code or SAML2
+// assertion with an invalid_grant error with the description.
+func (s *Server) RejectGrant(grant, description string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.rejected[grant] = description
+}
+
+// AddAccessToken makes an access token valid for the user.
+func (s *Server) AddAccessToken(token string, u User) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.access[token] = u
+}
+
+// AddRefreshToken makes a refresh token valid for the user.
+func (s *Server) AddRefreshToken(token string, u User) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.refresh[token] = u
+}
+
+// AddOrganization makes an organization known to the SCIM organization API.
+func (s *Server) AddOrganization(id, name, parentID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.orgs[id] = iam.Organization{
+		Schemas: []string{"urn:ietf:params:scim:schemas:core:philips:hsdp:2.0:Organization"},
+		ID:      id,
+		Name:    name,
+		Parent:  iam.Attribute{Value: parentID},
+		Active:  true,
+	}
+}
+
+// SetDiscovery publishes value as field of the discovery document, replacing
+// the default. A nil value removes the field.
+func (s *Server) SetDiscovery(field string, value any) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.discovery[field] = value
+}
+
+// Requests returns the number of requests made to path on any of the servers.
+func (s *Server) Requests(path string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.requests[path]
+}
+
+// Fail makes requests to path on any of the servers fail with status. A zero
+// status clears the failure.
+func (s *Server) Fail(path string, status int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if status == 0 {
+		delete(s.failures, path)
+		return
+	}
+	s.failures[path] = status
+}
+
+// Revoked returns the revoked tokens as "<token_type_hint>:<token>".
+func (s *Server) Revoked() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.revoked...)
+}
+
+// intercept counts requests and injects failures.
+func (s *Server) intercept(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		s.requests[r.URL.Path]++
+		status := s.failures[r.URL.Path]
+		s.mu.Unlock()
+		if status != 0 {
+			http.Error(w, http.StatusText(status), status)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (s *Server) authenticClient(r *http.Request) bool {
+	id, secret, ok := r.BasicAuth()
+	if !ok {
+		id, secret = r.PostFormValue("client_id"), r.PostFormValue("client_secret")
+	}
+	return id == s.ClientID && secret == s.ClientSecret
+}
+
+func (s *Server) bearer(r *http.Request) (User, bool) {
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok {
+		return User{}, false
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	u, ok := s.access[token]
+	return u, ok
+}
+
+func (s *Server) handleDiscovery(w http.ResponseWriter, r *http.Request) {
+	issuer := s.Issuer.URL
+	doc := map[string]any{
+		"issuer":                                issuer,
+		"authorization_endpoint":                issuer + "/authorize",
+		"token_endpoint":                        issuer + "/token",
+		"userinfo_endpoint":                     issuer + "/userinfo",
+		"jwks_uri":                              issuer + "/keys",
+		"introspection_endpoint":                issuer + "/introspect",
+		"revocation_endpoint":                   issuer + "/revoke",
+		"end_session_endpoint":                  issuer + "/logout",
+		"id_token_signing_alg_values_supported": []string{string(jose.RS256)},
+	}
+	s.mu.Lock()
+	for field, value := range s.discovery {
+		if value == nil {
+			delete(doc, field)
+		} else {
+			doc[field] = value
+		}
+	}
+	s.mu.Unlock()
+	writeJSON(w, http.StatusOK, doc)
+}
+
+func (s *Server) handleKeys(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, jose.JSONWebKeySet{Keys: []jose.JSONWebKey{s.key.Public()}})
+}
+
+func (s *Server) handleToken(w http.ResponseWriter, r *http.Request) {
+	if !s.authenticClient(r) {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "invalid_client"})
+		return
+	}
+
+	var (
+		lookup map[string]User
+		key    string
+	)
+	switch grant := r.PostFormValue("grant_type"); grant {
+	case "authorization_code":
+		lookup, key = s.codes, r.PostFormValue("code")
+	case "refresh_token":
+		lookup, key = s.refresh, r.PostFormValue("refresh_token")
+	case saml2BearerGrant:
+		if r.Header.Get("Api-Version") != "2" {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_request", "error_description": "unsupported api version"})
+			return
+		}
+		lookup, key = s.assertions, r.PostFormValue("assertion")
+	default:
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "unsupported_grant_type"})
+		return
+	}
+
+	s.mu.Lock()
+	description, rejected := s.rejected[key]
+	s.mu.Unlock()
+	if rejected {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_grant", "error_description": description})
+		return
+	}
+
+	s.mu.Lock()
+	u, ok := lookup[key]
+	var accessToken, refreshToken string
+	if ok {
+		// Codes and assertions are single use and refresh tokens rotate.
+		delete(lookup, key)
+		s.next++
+		accessToken = fmt.Sprintf("access-%d", s.next)
+		refreshToken = fmt.Sprintf("refresh-%d", s.next)
+		s.access[accessToken] = u
+		s.refresh[refreshToken] = u
+	}
+	s.mu.Unlock()
+	if !ok {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_grant"})
+		return
+	}
+
+	idToken, err := s.idToken(u)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"access_token":  accessToken,
+		"refresh_token": refreshToken,
+		"id_token":      idToken,
+		"token_type":    "Bearer",
+		"expires_in":    3600,
+	})
+}
+
+func (s *Server) idToken(u User) (string, error) {
+	return s.sign(map[string]any{
+		"iss": s.Issuer.URL,
+		"sub": u.Sub,
+		"aud": s.ClientID,
+		"exp": time.Now().Add(time.Hour).Unix(),
+		"iat": time.Now().Unix(),
+	})
+}
+
+// JWTAccessToken returns a signed JWT access token for the user, carrying
+// the introspection response as claims, and makes it valid for the user.
+func (s *Server) JWTAccessToken(t testing.TB, u User, audience string, expiry time.Time) string {
+	t.Helper()
+	claims := s.introspection(u)
+	delete(claims, "active")
+	claims["iss"] = s.Issuer.URL
+	claims["aud"] = audience
+	claims["exp"] = expiry.Unix()
+	claims["iat"] = time.Now().Unix()
+	token, err := s.sign(claims)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.AddAccessToken(token, u)
+	return token
+}
+
+func (s *Server) sign(claims map[string]any) (string, error) {
+	signer, err := jose.NewSigner(jose.SigningKey{Algorithm: jose.RS256, Key: s.key}, nil)
+	if err != nil {
+		return "", fmt.Errorf("iamtest: create signer: %v", err)
+	}
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+	jws, err := signer.Sign(payload)
+	if err != nil {
+		return "", fmt.Errorf("iamtest: sign token: %v", err)
+	}
+	return jws.CompactSerialize()
+}
+
+func (s *Server) handleUserInfo(w http.ResponseWriter, r *http.Request) {
+	u, ok := s.bearer(r)
+	if !ok {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	claims := map[string]any{"sub": u.Sub}
+	if u.Email != "" {
+		claims["email"] = u.Email
+	}
+	for k, v := range u.Claims {
+		claims[k] = v
+	}
+	writeJSON(w, http.StatusOK, claims)
+}
+
+type introspectOrganization struct {
+	OrganizationID   string   `json:"organizationId"`
+	OrganizationName string   `json:"organizationName"`
+	Permissions      []string `json:"permissions"`
+	Groups           []string `json:"groups"`
+	Roles            []string `json:"roles"`
+}
+
+func (s *Server) handleIntrospect(w http.ResponseWriter, r *http.Request) {
+	if !s.authenticClient(r) {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	s.mu.Lock()
+	u, ok := s.access[r.PostFormValue("token")]
+	s.mu.Unlock()
+	if !ok {
+		writeJSON(w, http.StatusOK, map[string]any{"active": false})
+		return
+	}
+	writeJSON(w, http.StatusOK, s.introspection(u))
+}
+
+// introspection returns the introspection response for an access token of
+// the user.
+func (s *Server) introspection(u User) map[string]any {
+	organizations := map[string]any{}
+	orgs := make([]introspectOrganization, 0, len(u.Organizations))
+	for _, o := range u.Organizations {
+		orgs = append(orgs, introspectOrganization{
+			OrganizationID:   o.ID,
+			OrganizationName: o.Name,
+			Permissions:      o.Permissions,
+			Groups:           o.Groups,
+			Roles:            o.Roles,
+		})
+	}
+	organizations["organizationList"] = orgs
+	if len(orgs) > 0 {
+		organizations["managingOrganization"] = orgs[0].OrganizationID
+	}
+	clientID := u.ClientID
+	if clientID == "" {
+		clientID = s.ClientID
+	}
+	resp := map[string]any{
+		"active":        true,
+		"scope":         u.Scope,
+		"sub":           u.Sub,
+		"username":      u.Username,
+		"client_id":     clientID,
+		"token_type":    "Bearer",
+		"identity_type": u.IdentityType,
+		"exp":           time.Now().Add(time.Hour).Unix(),
+		"organizations": organizations,
+	}
+	if len(u.Audience) > 0 {
+		resp["aud"] = u.Audience
+	}
+	return resp
+}
+
+func (s *Server) handleRevoke(w http.ResponseWriter, r *http.Request) {
+	if !s.authenticClient(r) {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	token := r.PostFormValue("token")
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.access, token)
+	delete(s.refresh, token)
+	s.revoked = append(s.revoked, r.PostFormValue("token_type_hint")+":"+token)
+}
+
+func (s *Server) handleEndSession(w http.ResponseWriter, r *http.Request) {
+	redirectURI := r.URL.Query().Get("post_logout_redirect_uri")
+	if redirectURI == "" {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	if r.URL.Query().Get("client_id") != s.ClientID {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	http.Redirect(w, r, redirectURI, http.StatusFound)
+}
+
+func (s *Server) handleLegacyUser(w http.ResponseWriter, r *http.Request) {
+	u, ok := s.bearer(r)
+	if !ok {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	if u.Sub != r.PathValue("uuid") || u.Profile == nil {
+		writeJSON(w, http.StatusNotFound, map[string]string{"responseCode": "404", "responseMessage": "user not found"})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"exchange": map[string]any{
+			"loginId": u.Username,
+			"profile": u.Profile,
+		},
+		"responseCode":    "200",
+		"responseMessage": "Success",
+	})
+}
+
+func (s *Server) handleOrganization(w http.ResponseWriter, r *http.Request) {
+	if _, ok := s.bearer(r); !ok {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	s.mu.Lock()
+	org, ok := s.orgs[r.PathValue("id")]
+	s.mu.Unlock()
+	if !ok {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "not found"})
+		return
+	}
+	writeJSON(w, http.StatusOK, org)
+}
+
+func handleHealth(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]string{"Status": "OK"})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
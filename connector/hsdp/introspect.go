@@ -8,13 +8,61 @@ import (
 	"io"
 	"net/http"
 	"net/url"
+	"slices"
 	"strings"
 
 	"github.com/dip-software/go-dip-api/iam"
 	"golang.org/x/oauth2"
 )
 
-func (c *HSDPConnector) introspect(ctx context.Context, tokenSource oauth2.TokenSource) (*iam.IntrospectResponse, error) {
+// introspection is the response of the introspection endpoint.
+type introspection struct {
+	iam.IntrospectResponse
+
+	// Audience is the aud of the token, if IAM returns it.
+	Audience audience `json:"aud"`
+}
+
+// audience is an aud claim, which is a string or an array of strings.
+type audience []string
+
+func (a *audience) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err == nil {
+		*a = audience{s}
+		return nil
+	}
+	var list []string
+	if err := json.Unmarshal(b, &list); err != nil {
+		return err
+	}
+	*a = list
+	return nil
+}
+
+// introspectFields returns the fields of an introspection response by their
+// JSON name.
+func introspectFields(introspect *iam.IntrospectResponse) map[string]interface{} {
+	var fields map[string]interface{}
+	if b, err := json.Marshal(introspect); err == nil {
+		json.Unmarshal(b, &fields)
+	}
+	return fields
+}
+
+// validateIntrospectClaims checks that the introspection response has the
+// fields named by introspectClaims.
+func validateIntrospectClaims(names []string) error {
+	fields := introspectFields(&iam.IntrospectResponse{})
+	for _, name := range names {
+		if _, ok := fields[name]; !ok {
+			return fmt.Errorf("unknown introspection field %q in introspectClaims", name)
+		}
+	}
+	return nil
+}
+
+func (c *HSDPConnector) introspect(ctx context.Context, tokenSource oauth2.TokenSource) (*introspection, error) {
 	if c.introspectURI == "" {
 		return nil, errors.New("hsdp: introspect endpoint is missing")
 	}
@@ -31,32 +79,48 @@ func (c *HSDPConnector) introspect(ctx context.Context, tokenSource oauth2.Token
 
 	form := url.Values{}
 	form.Add("token", token.AccessToken)
-	req.Body = io.NopCloser(strings.NewReader(form.Encode()))
-	req.ContentLength = int64(len(form.Encode()))
-	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
-	req.Header.Set("Api-Version", "4")
-	req.SetBasicAuth(c.oauth2Config.ClientID, c.oauth2Config.ClientSecret)
 
-	resp, err := doRequest(ctx, req)
-	if err != nil {
-		return nil, err
-	}
-	defer resp.Body.Close()
-	body, err := io.ReadAll(resp.Body)
+	var body []byte
+	err = c.credentials.do(ctx, func(creds *clientCredentials) error {
+		req.Body = io.NopCloser(strings.NewReader(form.Encode()))
+		req.ContentLength = int64(len(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req.Header.Set("Api-Version", "4")
+		req.SetBasicAuth(creds.id, creds.secret)
+
+		resp, err := doRequest(ctx, req)
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+		if body, err = io.ReadAll(resp.Body); err != nil {
+			return err
+		}
+		if resp.StatusCode != http.StatusOK {
+			return newStatusError(resp, body)
+		}
+		return nil
+	})
 	if err != nil {
 		return nil, err
 	}
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("%s: %s", resp.Status, body)
-	}
 
-	var introspectResponse iam.IntrospectResponse
+	var introspectResponse introspection
 	if err := json.Unmarshal(body, &introspectResponse); err != nil {
 		return nil, fmt.Errorf("hsdp: failed to decode introspect: %v", err)
 	}
 	return &introspectResponse, nil
 }
 
+// checkExchangedToken refuses tokens issued to, and for, none of the
+// accepted access token audiences.
+func (c *HSDPConnector) checkExchangedToken(i *introspection) error {
+	if slices.Contains(c.accessTokenAudiences, i.ClientID) || hasAudience(i.Audience, c.accessTokenAudiences) {
+		return nil
+	}
+	return fmt.Errorf("token of client %q for audience %q is not accepted", i.ClientID, []string(i.Audience))
+}
+
 func doRequest(ctx context.Context, req *http.Request) (*http.Response, error) {
 	client := http.DefaultClient
 	if c, ok := ctx.Value(oauth2.HTTPClient).(*http.Client); ok {
@@ -64,3 +128,50 @@ func doRequest(ctx context.Context, req *http.Request) (*http.Response, error) {
 	}
 	return client.Do(req.WithContext(ctx))
 }
+
+// isJWT reports whether token has the shape of a signed JWT rather than an
+// opaque IAM token.
+func isJWT(token string) bool {
+	parts := strings.Split(token, ".")
+	return len(parts) == 3 && parts[0] != "" && parts[1] != "" && parts[2] != ""
+}
+
+// verifyAccessToken validates the signature, audience and expiry of a JWT
+// access token and returns its claims as an introspection response. A nil
+// response without an error means the token doesn't carry the
+// organizations and has to be introspected.
+func (c *HSDPConnector) verifyAccessToken(ctx context.Context, rawToken string) (*iam.IntrospectResponse, error) {
+	token, err := c.accessTokenVerifier.Verify(ctx, rawToken)
+	if err != nil {
+		return nil, err
+	}
+	if !hasAudience(token.Audience, c.accessTokenAudiences) {
+		return nil, fmt.Errorf("expected audience in %q, got %q", c.accessTokenAudiences, token.Audience)
+	}
+
+	var claims map[string]json.RawMessage
+	if err := token.Claims(&claims); err != nil {
+		return nil, fmt.Errorf("failed to decode claims: %v", err)
+	}
+	if _, ok := claims["organizations"]; !ok {
+		c.logger.DebugContext(ctx, "access token has no organizations, introspecting it", "sub", token.Subject)
+		return nil, nil
+	}
+	var introspectResponse iam.IntrospectResponse
+	if err := token.Claims(&introspectResponse); err != nil {
+		return nil, fmt.Errorf("failed to decode claims: %v", err)
+	}
+	introspectResponse.Active = true
+	return &introspectResponse, nil
+}
+
+func hasAudience(audience, accepted []string) bool {
+	for _, a := range audience {
+		for _, b := range accepted {
+			if a == b {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,50 @@
+package hsdp
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+)
+
+const (
+	upstreamLogoutRedirect    = "redirect"
+	upstreamLogoutBackchannel = "backchannel"
+	upstreamLogoutNone        = "none"
+)
+
+// LogoutURL returns the HSP IAM end session URL in redirect mode, so logging
+// out of dex also ends the IAM SSO session.
+func (c *HSDPConnector) LogoutURL(_ context.Context, _ []byte, postLogoutRedirectURI string) (string, error) {
+	if c.upstreamLogout != upstreamLogoutRedirect {
+		return "", nil
+	}
+	if c.endSessionURL == "" {
+		return "", nil
+	}
+	u, err := url.Parse(c.endSessionURL)
+	if err != nil {
+		return "", fmt.Errorf("hsdp: failed to parse end session URL: %v", err)
+	}
+	q := u.Query()
+	if postLogoutRedirectURI != "" {
+		q.Set("post_logout_redirect_uri", postLogoutRedirectURI)
+		q.Set("client_id", c.credentials.get().id)
+	}
+	u.RawQuery = q.Encode()
+	return u.String(), nil
+}
+
+// BackchannelLogout revokes the session's IAM tokens in backchannel mode.
+func (c *HSDPConnector) BackchannelLogout(ctx context.Context, connectorData []byte) error {
+	if c.upstreamLogout != upstreamLogoutBackchannel || len(connectorData) == 0 {
+		return nil
+	}
+	return c.RevokeTokens(ctx, connectorData)
+}
+
+// HandleLogoutCallback is a no-op, HSP IAM redirects back without a response
+// to validate.
+func (c *HSDPConnector) HandleLogoutCallback(_ context.Context, _ *http.Request) error {
+	return nil
+}
@@ -0,0 +1,113 @@
+package hsdp
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/dip-software/go-dip-api/iam"
+)
+
+// maxOrganizationDepth bounds the parent chain walk in case of cycles.
+const maxOrganizationDepth = 10
+
+// Organization is an entry of the "organizations" claim.
+type Organization struct {
+	ID          string `json:"id"`
+	Name        string `json:"name,omitempty"`
+	DisplayName string `json:"displayName,omitempty"`
+	// Parents lists the parent organizations, nearest first.
+	Parents []OrganizationRef `json:"parents,omitempty"`
+}
+
+// OrganizationRef identifies a parent organization.
+type OrganizationRef struct {
+	ID   string `json:"id"`
+	Name string `json:"name,omitempty"`
+}
+
+type cachedOrganization struct {
+	name        string
+	displayName string
+	parentID    string
+	expires     time.Time
+}
+
+// organizationCache caches organizations looked up in IDM, which rarely
+// change, across logins.
+type organizationCache struct {
+	ttl time.Duration
+
+	mu   sync.Mutex
+	orgs map[string]cachedOrganization
+}
+
+func newOrganizationCache(ttl time.Duration) *organizationCache {
+	return &organizationCache{ttl: ttl, orgs: make(map[string]cachedOrganization)}
+}
+
+func (oc *organizationCache) get(id string) (cachedOrganization, bool) {
+	oc.mu.Lock()
+	defer oc.mu.Unlock()
+	org, ok := oc.orgs[id]
+	if !ok || time.Now().After(org.expires) {
+		return cachedOrganization{}, false
+	}
+	return org, true
+}
+
+func (oc *organizationCache) put(id string, org cachedOrganization) {
+	oc.mu.Lock()
+	defer oc.mu.Unlock()
+	org.expires = time.Now().Add(oc.ttl)
+	oc.orgs[id] = org
+}
+
+// lookupOrganization returns an organization from the cache or IDM.
+func (c *HSDPConnector) lookupOrganization(client *iam.Client, id string) (cachedOrganization, error) {
+	if org, ok := c.organizations.get(id); ok {
+		return org, nil
+	}
+	found, _, err := client.Organizations.GetOrganizationByID(id)
+	if err != nil {
+		return cachedOrganization{}, err
+	}
+	org := cachedOrganization{name: found.Name, displayName: found.DisplayName, parentID: found.Parent.Value}
+	c.organizations.put(id, org)
+	return org, nil
+}
+
+// resolveOrganizations resolves the organizations of an introspection
+// response, and their parents if configured. Organizations that cannot be
+// looked up are reported with the name from introspection.
+func (c *HSDPConnector) resolveOrganizations(ctx context.Context, accessToken string, introspect *iam.IntrospectResponse) []Organization {
+	client := c.client.WithToken(accessToken)
+
+	var orgs []Organization
+	for _, member := range introspect.Organizations.OrganizationList {
+		org := Organization{ID: member.OrganizationID, Name: member.OrganizationName}
+		found, err := c.lookupOrganization(client, member.OrganizationID)
+		if err != nil {
+			c.logger.WarnContext(ctx, "failed to look up organization", "org_id", member.OrganizationID, "err", err)
+			orgs = append(orgs, org)
+			continue
+		}
+		org.Name = found.name
+		org.DisplayName = found.displayName
+
+		seen := map[string]bool{org.ID: true}
+		for parentID := found.parentID; c.organizationParents && parentID != "" && !seen[parentID] && len(org.Parents) < maxOrganizationDepth; {
+			seen[parentID] = true
+			parent, err := c.lookupOrganization(client, parentID)
+			if err != nil {
+				c.logger.WarnContext(ctx, "failed to look up parent organization", "org_id", parentID, "err", err)
+				org.Parents = append(org.Parents, OrganizationRef{ID: parentID})
+				break
+			}
+			org.Parents = append(org.Parents, OrganizationRef{ID: parentID, Name: parent.name})
+			parentID = parent.parentID
+		}
+		orgs = append(orgs, org)
+	}
+	return orgs
+}
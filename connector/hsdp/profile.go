@@ -0,0 +1,54 @@
+package hsdp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+const (
+	profileStaleWarn = "warn"
+	profileStaleFail = "fail"
+)
+
+// loadProfile sets the IDM profile of cd. When a maximum profile age is
+// configured, the profile stored with the refreshed session is reused until
+// it exceeds that age. If fetching the profile fails, the profile stale
+// policy decides: "fail" fails the login or refresh, "warn" keeps the stored
+// profile of the session if there is one.
+func (c *HSDPConnector) loadProfile(ctx context.Context, cd *ConnectorData, accessToken string, prev *ConnectorData) error {
+	sub := cd.Introspect.Sub
+	stored := prev != nil && !prev.ProfileFetchedAt.IsZero() && prev.Introspect.Sub == sub
+	if stored && c.profileMaxAge > 0 && time.Since(prev.ProfileFetchedAt) <= c.profileMaxAge {
+		cd.User = prev.User
+		cd.ProfileFetchedAt = prev.ProfileFetchedAt
+		return nil
+	}
+
+	user, _, err := c.client.WithToken(accessToken).Users.LegacyGetUserByUUID(sub)
+	if err == nil && user != nil {
+		cd.User = *user
+		cd.ProfileFetchedAt = time.Now()
+		return nil
+	}
+	if err == nil {
+		err = errors.New("no profile returned")
+	}
+	c.logger.ErrorContext(ctx, "failed to get user profile", "sub", sub, "err", err)
+
+	if c.failOnStaleProfile {
+		if stored {
+			return fmt.Errorf("hsdp: stored profile is %s old and could not be refreshed: %v", time.Since(prev.ProfileFetchedAt).Round(time.Second), err)
+		}
+		return fmt.Errorf("hsdp: failed to get user profile: %v", err)
+	}
+	if !stored {
+		c.logger.WarnContext(ctx, "continuing without user profile", "sub", sub)
+		return nil
+	}
+	c.logger.WarnContext(ctx, "using stale user profile", "sub", sub, "age", time.Since(prev.ProfileFetchedAt).Round(time.Second))
+	cd.User = prev.User
+	cd.ProfileFetchedAt = prev.ProfileFetchedAt
+	return nil
+}
@@ -0,0 +1,42 @@
+package hsdp
+
+import (
+	"errors"
+	"fmt"
+
+	autoconf "github.com/dip-software/go-dip-api/config"
+)
+
+// autoconfigure derives the HSP IAM endpoints which aren't set from Region
+// and Environment.
+func (c *Config) autoconfigure() error {
+	if c.Region == "" && c.Environment == "" {
+		return nil
+	}
+	if c.Region == "" || c.Environment == "" {
+		return errors.New("region and environment must be set together")
+	}
+	ac, err := autoconf.New(autoconf.WithRegion(c.Region), autoconf.WithEnv(c.Environment))
+	if err != nil {
+		return fmt.Errorf("failed to load HSP endpoints: %v", err)
+	}
+	iamURL, idmURL := ac.Service("iam").URL, ac.Service("idm").URL
+	if iamURL == "" || idmURL == "" {
+		return fmt.Errorf("no HSP IAM in region %q and environment %q", c.Region, c.Environment)
+	}
+
+	if c.IAMURL == "" {
+		c.IAMURL = iamURL
+	}
+	if c.IDMURL == "" {
+		c.IDMURL = idmURL
+	}
+	if c.Issuer == "" {
+		c.Issuer = c.IAMURL + "/authorize/oauth2/v2"
+		// IAM returns another issuer than the one it is discovered at.
+		if c.InsecureIssuer == "" {
+			c.InsecureIssuer = c.IAMURL + "/oauth2/access_token"
+		}
+	}
+	return nil
+}
@@ -0,0 +1,70 @@
+package hsdp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// RevokeTokens revokes the HSP IAM refresh and access tokens of a session at
+// the revocation endpoint. Revoking the refresh token ends the upstream
+// session, the access token is revoked so it can't be used until it expires.
+func (c *HSDPConnector) RevokeTokens(ctx context.Context, connectorData []byte) error {
+	if c.revokeURI == "" {
+		return errors.New("hsdp: revocation endpoint is missing")
+	}
+	var cd ConnectorData
+	if err := json.Unmarshal(connectorData, &cd); err != nil {
+		return fmt.Errorf("hsdp: failed to unmarshal connector data: %v", err)
+	}
+
+	ctx = c.clientContext(ctx)
+	for _, t := range []struct {
+		token []byte
+		hint  string
+	}{
+		{cd.RefreshToken, "refresh_token"},
+		{cd.AccessToken, "access_token"},
+	} {
+		if len(t.token) == 0 {
+			continue
+		}
+		if err := c.revoke(ctx, string(t.token), t.hint); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *HSDPConnector) revoke(ctx context.Context, token, hint string) error {
+	form := url.Values{"token": {token}, "token_type_hint": {hint}}.Encode()
+	err := c.credentials.do(ctx, func(creds *clientCredentials) error {
+		req, err := http.NewRequest(http.MethodPost, c.revokeURI, strings.NewReader(form))
+		if err != nil {
+			return fmt.Errorf("create revoke request: %v", err)
+		}
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req.Header.Set("Api-Version", "2")
+		req.SetBasicAuth(creds.id, creds.secret)
+
+		resp, err := doRequest(ctx, req)
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+			return newStatusError(resp, body)
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("hsdp: revoke %s: %w", hint, err)
+	}
+	return nil
+}
@@ -0,0 +1,44 @@
+package hsdp
+
+import "strings"
+
+// claimScopes maps the claims dex releases to the upstream scope that must
+// have been granted by HSP IAM for them to be released.
+var claimScopes = map[string]string{
+	"email":                 "email",
+	"email_verified":        "email",
+	"name":                  "profile",
+	"given_name":            "profile",
+	"family_name":           "profile",
+	"username":              "profile",
+	"preferred_username":    "profile",
+	"groups":                "groups",
+	"roles":                 "groups",
+	"organizations":         "groups",
+	"managing_organization": "groups",
+	"tenant":                "groups",
+	"tenant_organization":   "groups",
+}
+
+// filterClaimsByGrantedScopes removes the claims whose upstream scope was not
+// granted according to introspection. Nothing is removed if introspection did
+// not report any scopes, for example in degraded mode.
+func filterClaimsByGrantedScopes(claims map[string]interface{}, grantedScope string) []string {
+	granted := strings.Fields(grantedScope)
+	if len(granted) == 0 {
+		return nil
+	}
+	grantedSet := make(map[string]bool, len(granted))
+	for _, s := range granted {
+		grantedSet[s] = true
+	}
+
+	var removed []string
+	for claim, scope := range claimScopes {
+		if _, ok := claims[claim]; ok && !grantedSet[scope] {
+			delete(claims, claim)
+			removed = append(removed, claim)
+		}
+	}
+	return removed
+}
@@ -0,0 +1,81 @@
+package hsdp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"slices"
+
+	"github.com/dip-software/go-dip-api/iam"
+
+	"github.com/dexidp/dex/connector"
+)
+
+const errInvalidTarget = "invalid_target"
+
+// TokenIdentityWithParams exchanges a token like TokenIdentity. With tenant
+// selection enabled, the identity is bound to the tenant of the request, or
+// else to the tenant of the managing organization of the token.
+func (c *HSDPConnector) TokenIdentityWithParams(ctx context.Context, subjectTokenType, subjectToken string, params connector.TokenExchangeParams) (connector.Identity, error) {
+	if params.Tenant != "" && !c.enableTenantSelection {
+		return connector.Identity{}, &connector.OAuth2Error{Code: errInvalidTarget, Description: "Tenant selection is not enabled."}
+	}
+	identity, err := c.TokenIdentity(ctx, subjectTokenType, subjectToken)
+	if err != nil || !c.enableTenantSelection {
+		return identity, err
+	}
+
+	var cd ConnectorData
+	if err := json.Unmarshal(identity.ConnectorData, &cd); err != nil {
+		return connector.Identity{}, fmt.Errorf("hsdp: failed to unmarshal connector data: %v", err)
+	}
+	org, err := c.selectTenant(&cd.Introspect, params.Tenant)
+	if err != nil || org == "" {
+		return identity, err
+	}
+	cd.TenantOrganization = org
+	if identity.ConnectorData, err = json.Marshal(&cd); err != nil {
+		return connector.Identity{}, fmt.Errorf("hsdp: failed to encode connector data: %v", err)
+	}
+	identity.CustomClaims["tenant"] = mapper(org, c.tenantMap)
+	return identity, nil
+}
+
+// selectTenant returns the organization of the tenant a token is exchanged
+// for. Without a requested tenant, it is the managing organization of the
+// token, if it is in the tenantMap.
+func (c *HSDPConnector) selectTenant(introspect *iam.IntrospectResponse, tenant string) (string, error) {
+	if tenant == "" {
+		org := introspect.Organizations.ManagingOrganization
+		if _, ok := c.tenantMap[org]; !ok {
+			return "", nil
+		}
+		return org, nil
+	}
+
+	org := c.tenantOrganization(tenant)
+	if org == "" {
+		return "", &connector.OAuth2Error{Code: errInvalidTarget, Description: fmt.Sprintf("Unknown tenant %q.", tenant)}
+	}
+	for _, o := range introspect.Organizations.OrganizationList {
+		if o.OrganizationID == org {
+			return org, nil
+		}
+	}
+	return "", &connector.OAuth2Error{Code: errInvalidTarget, Description: fmt.Sprintf("User is not a member of tenant %q.", tenant)}
+}
+
+// tenantOrganization returns the organization of a tenant of the tenantMap,
+// named by its organization ID or its tenant.
+func (c *HSDPConnector) tenantOrganization(tenant string) string {
+	if _, ok := c.tenantMap[tenant]; ok {
+		return tenant
+	}
+	for _, org := range slices.Sorted(maps.Keys(c.tenantMap)) {
+		if c.tenantMap[org] == tenant {
+			return org
+		}
+	}
+	return ""
+}
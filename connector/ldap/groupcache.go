@@ -0,0 +1,81 @@
+package ldap
+
+import (
+	"container/list"
+	"sync"
+	"time"
+
+	"github.com/go-ldap/ldap/v3"
+)
+
+// defaultGroupCacheSize is the default number of group search results kept
+// by the group cache.
+const defaultGroupCacheSize = 10000
+
+// groupCache remembers group search results for a while. Group hierarchies
+// change rarely, so caching them spares the directory a query for every level
+// of nesting on every login. It holds at most size results, evicting the least
+// recently used ones first.
+type groupCache struct {
+	ttl  time.Duration
+	size int
+	now  func() time.Time
+
+	mu      sync.Mutex
+	entries map[string]*list.Element
+	// lru orders the entries from the most to the least recently used.
+	lru *list.List
+}
+
+type groupCacheEntry struct {
+	key     string
+	groups  []*ldap.Entry
+	filter  string
+	expires time.Time
+}
+
+func newGroupCache(ttl time.Duration, size int) *groupCache {
+	return &groupCache{
+		ttl:     ttl,
+		size:    size,
+		now:     time.Now,
+		entries: make(map[string]*list.Element),
+		lru:     list.New(),
+	}
+}
+
+func (c *groupCache) get(key string) ([]*ldap.Entry, string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	elem, ok := c.entries[key]
+	if !ok {
+		return nil, "", false
+	}
+	e := elem.Value.(*groupCacheEntry)
+	if c.now().After(e.expires) {
+		c.remove(elem)
+		return nil, "", false
+	}
+	c.lru.MoveToFront(elem)
+	return e.groups, e.filter, true
+}
+
+func (c *groupCache) put(key string, groups []*ldap.Entry, filter string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e := &groupCacheEntry{key: key, groups: groups, filter: filter, expires: c.now().Add(c.ttl)}
+	if elem, ok := c.entries[key]; ok {
+		elem.Value = e
+		c.lru.MoveToFront(elem)
+		return
+	}
+	c.entries[key] = c.lru.PushFront(e)
+	for c.lru.Len() > c.size {
+		c.remove(c.lru.Back())
+	}
+}
+
+func (c *groupCache) remove(elem *list.Element) {
+	c.lru.Remove(elem)
+	delete(c.entries, elem.Value.(*groupCacheEntry).key)
+}
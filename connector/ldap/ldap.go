@@ -5,6 +5,7 @@ import (
 	"context"
 	"crypto/tls"
 	"crypto/x509"
+	"encoding/binary"
 	"encoding/json"
 	"fmt"
 	"log/slog"
@@ -12,6 +13,7 @@ import (
 	"net/url"
 	"os"
 	"strings"
+	"time"
 
 	"github.com/go-ldap/ldap/v3"
 
@@ -28,6 +30,12 @@ import (
 //     type: ldap
 //     config:
 //       host: ldap.example.com:636
+//       # Tried in order when the host above is unreachable.
+//       failoverHosts:
+//       - ldap2.example.com:636
+//       pool:
+//         size: 4
+//         maxIdleTime: 5m
 //       # The following field is required if using port 389.
 //       # insecureNoSSL: true
 //       rootCA: /etc/dex/ldap.ca
@@ -58,6 +66,8 @@ import (
 //         - userAttr: DN
 //           groupAttr: member
 //         nameAttr: name
+//         # On Active Directory, resolve nested groups on the server instead:
+//         # matchingRuleInChain: true
 //
 
 // UsernameAttributes represents one or more LDAP attributes to match against
@@ -95,6 +105,27 @@ type Config struct {
 	// guessed based on the TLS configuration. 389 or 636.
 	Host string `json:"host"`
 
+	// FailoverHosts are tried in order when Host can't be reached. They take
+	// the same form as Host and use the same TLS configuration.
+	FailoverHosts []string `json:"failoverHosts"`
+
+	// Pool configures the connections kept open between logins.
+	Pool struct {
+		// Maximum number of idle connections kept open. Defaults to 4. Set to
+		// -1 to close connections after every request.
+		Size int `json:"size"`
+
+		// Idle connections are closed after this long. Defaults to "5m".
+		MaxIdleTime string `json:"maxIdleTime"`
+
+		// Timeout for connecting to a host. Defaults to "10s".
+		DialTimeout string `json:"dialTimeout"`
+
+		// A host which couldn't be reached is skipped for this long before
+		// it's tried again. Defaults to "30s".
+		HostRetryInterval string `json:"hostRetryInterval"`
+	} `json:"pool"`
+
 	// Required if LDAP host does not use TLS.
 	InsecureNoSSL bool `json:"insecureNoSSL"`
 
@@ -185,6 +216,35 @@ type Config struct {
 
 		// The attribute of the group that represents its name.
 		NameAttr string `json:"nameAttr"`
+
+		// MatchingRuleInChain resolves nested groups on the server, using the
+		// Active Directory matching rule LDAP_MATCHING_RULE_IN_CHAIN. Each user
+		// matcher then finds all groups the user is a transitive member of in
+		// a single query, so its userAttr must hold the user's DN.
+		MatchingRuleInChain bool `json:"matchingRuleInChain"`
+
+		// MaxRecursionDepth limits how many levels of parent groups are
+		// followed for user matchers with a recursionGroupAttr. Defaults to no
+		// limit.
+		MaxRecursionDepth int `json:"maxRecursionDepth"`
+
+		// RecursionCacheTTL caches the parent groups of each group for this
+		// long, e.g. "5m", so logins don't walk the whole hierarchy every
+		// time. Defaults to no caching. With TokenGroups, it caches the
+		// names of the groups by SID.
+		RecursionCacheTTL string `json:"recursionCacheTTL"`
+
+		// RecursionCacheSize is the most group search results the cache
+		// holds, the least recently used are dropped first. Defaults to
+		// 10000.
+		RecursionCacheSize int `json:"recursionCacheSize"`
+
+		// TokenGroups reads the groups of users from the Active Directory
+		// tokenGroups attribute, which holds the SIDs of all security groups
+		// the user is a transitive member of, and resolves them to the groups
+		// below baseDN matching filter. userMatchers are not used then.
+		// Distribution groups aren't part of tokenGroups.
+		TokenGroups bool `json:"tokenGroups"`
 	} `json:"groupSearch"`
 }
 
@@ -278,20 +338,39 @@ func (c *Config) openConnector(logger *slog.Logger) (*ldapConnector, error) {
 		return nil, fmt.Errorf("ldap: missing required field %q", "userSearch.username")
 	}
 
-	var (
-		host string
-		err  error
-	)
-	if host, _, err = net.SplitHostPort(c.Host); err != nil {
-		host = c.Host
-		if c.InsecureNoSSL {
-			c.Host += ":389"
-		} else {
-			c.Host += ":636"
-		}
+	c.Host = c.withDefaultPort(c.Host)
+	hosts := []string{c.Host}
+	for _, h := range c.FailoverHosts {
+		hosts = append(hosts, c.withDefaultPort(h))
 	}
 
-	tlsConfig := &tls.Config{ServerName: host, InsecureSkipVerify: c.InsecureSkipVerify}
+	poolSize := c.Pool.Size
+	if poolSize == 0 {
+		poolSize = defaultPoolSize
+	}
+	maxIdleTime, err := parseDuration("pool.maxIdleTime", c.Pool.MaxIdleTime, defaultMaxIdleTime)
+	if err != nil {
+		return nil, err
+	}
+	dialTimeout, err := parseDuration("pool.dialTimeout", c.Pool.DialTimeout, defaultDialTimeout)
+	if err != nil {
+		return nil, err
+	}
+	retryInterval, err := parseDuration("pool.hostRetryInterval", c.Pool.HostRetryInterval, defaultHostRetryInterval)
+	if err != nil {
+		return nil, err
+	}
+
+	recursionCacheTTL, err := parseDuration("groupSearch.recursionCacheTTL", c.GroupSearch.RecursionCacheTTL, 0)
+	if err != nil {
+		return nil, err
+	}
+	recursionCacheSize := c.GroupSearch.RecursionCacheSize
+	if recursionCacheSize <= 0 {
+		recursionCacheSize = defaultGroupCacheSize
+	}
+
+	tlsConfig := &tls.Config{InsecureSkipVerify: c.InsecureSkipVerify}
 	if c.RootCA != "" || len(c.RootCAData) != 0 {
 		data := c.RootCAData
 		if len(data) == 0 {
@@ -314,6 +393,13 @@ func (c *Config) openConnector(logger *slog.Logger) (*ldapConnector, error) {
 		}
 		tlsConfig.Certificates = append(tlsConfig.Certificates, cert)
 	}
+	if c.GroupSearch.TokenGroups && c.GroupSearch.MatchingRuleInChain {
+		return nil, fmt.Errorf("ldap: groupSearch.tokenGroups and groupSearch.matchingRuleInChain are mutually exclusive")
+	}
+	if c.GroupSearch.TokenGroups && c.GroupSearch.NameAttr == "" {
+		return nil, fmt.Errorf("ldap: groupSearch.tokenGroups requires groupSearch.nameAttr")
+	}
+
 	userSearchScope, ok := parseScope(c.UserSearch.Scope)
 	if !ok {
 		return nil, fmt.Errorf("userSearch.Scope unknown value %q", c.UserSearch.Scope)
@@ -325,7 +411,42 @@ func (c *Config) openConnector(logger *slog.Logger) (*ldapConnector, error) {
 
 	// TODO(nabokihms): remove it after deleting deprecated groupSearch options
 	c.GroupSearch.UserMatchers = userMatchers(c, logger)
-	return &ldapConnector{*c, userSearchScope, groupSearchScope, tlsConfig, c.UserSearch.Username, logger}, nil
+	conn := &ldapConnector{
+		Config:           *c,
+		userSearchScope:  userSearchScope,
+		groupSearchScope: groupSearchScope,
+		tlsConfig:        tlsConfig,
+		dialTimeout:      dialTimeout,
+		usernameAttrs:    c.UserSearch.Username,
+		logger:           logger,
+	}
+	if recursionCacheTTL > 0 {
+		conn.parentGroupCache = newGroupCache(recursionCacheTTL, recursionCacheSize)
+	}
+	conn.pool = newConnPool(hosts, conn.dial, poolSize, maxIdleTime, retryInterval, logger)
+	return conn, nil
+}
+
+func parseDuration(name, value string, def time.Duration) (time.Duration, error) {
+	if value == "" {
+		return def, nil
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, fmt.Errorf("ldap: invalid %s %q: %v", name, value, err)
+	}
+	return d, nil
+}
+
+// withDefaultPort adds the LDAP or LDAPS port to a host without one.
+func (c *Config) withDefaultPort(host string) string {
+	if _, _, err := net.SplitHostPort(host); err == nil {
+		return host
+	}
+	if c.InsecureNoSSL {
+		return host + ":389"
+	}
+	return host + ":636"
 }
 
 var (
@@ -339,55 +460,101 @@ type ldapConnector struct {
 	userSearchScope  int
 	groupSearchScope int
 
-	tlsConfig *tls.Config
+	tlsConfig   *tls.Config
+	dialTimeout time.Duration
+
+	pool *connPool
+
+	// If set, caches the results of recursive group searches.
+	parentGroupCache *groupCache
 
 	usernameAttrs []string
 
 	logger *slog.Logger
 }
 
-// do initializes a connection to the LDAP directory and passes it to the
-// provided function. It then performs appropriate teardown or reuse before
-// returning.
+// do takes a connection to the LDAP directory from the pool, binds it as the
+// service account and passes it to the provided function. The connection is
+// returned to the pool afterwards, unless it broke.
 func (c *ldapConnector) do(_ context.Context, f func(c *ldap.Conn) error) error {
 	// TODO(ericchiang): support context here
+	conn, reused, err := c.pool.get()
+	if err != nil {
+		return err
+	}
+
+	// Logins bind connections as the user, so always bind as the service
+	// account again. This also finds pooled connections the server dropped.
+	err = c.bind(conn.Conn)
+	if err != nil && reused && ldap.IsErrorWithCode(err, ldap.ErrorNetwork) {
+		conn.Close()
+		if conn, _, err = c.pool.get(); err != nil {
+			return err
+		}
+		err = c.bind(conn.Conn)
+	}
+	if err != nil {
+		conn.Close()
+		return err
+	}
+
+	err = f(conn.Conn)
+	c.pool.put(conn)
+	return err
+}
+
+// dial opens a new connection to host.
+func (c *ldapConnector) dial(host string) (*ldap.Conn, error) {
+	tlsConfig := c.tlsConfig.Clone()
+	if hostname, _, err := net.SplitHostPort(host); err == nil {
+		tlsConfig.ServerName = hostname
+	}
+	dialer := ldap.DialWithDialer(&net.Dialer{Timeout: c.dialTimeout})
+
 	var (
 		conn *ldap.Conn
 		err  error
 	)
-
 	switch {
 	case c.InsecureNoSSL:
-		u := url.URL{Scheme: "ldap", Host: c.Host}
-		conn, err = ldap.DialURL(u.String())
+		u := url.URL{Scheme: "ldap", Host: host}
+		conn, err = ldap.DialURL(u.String(), dialer)
 	case c.StartTLS:
-		u := url.URL{Scheme: "ldap", Host: c.Host}
-		conn, err = ldap.DialURL(u.String())
+		u := url.URL{Scheme: "ldap", Host: host}
+		conn, err = ldap.DialURL(u.String(), dialer)
 		if err != nil {
-			return fmt.Errorf("failed to connect: %v", err)
+			return nil, fmt.Errorf("failed to connect: %v", err)
 		}
-		if err := conn.StartTLS(c.tlsConfig); err != nil {
-			return fmt.Errorf("start TLS failed: %v", err)
+		if err := conn.StartTLS(tlsConfig); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("start TLS failed: %v", err)
 		}
 	default:
-		u := url.URL{Scheme: "ldaps", Host: c.Host}
-		conn, err = ldap.DialURL(u.String(), ldap.DialWithTLSConfig(c.tlsConfig))
+		u := url.URL{Scheme: "ldaps", Host: host}
+		conn, err = ldap.DialURL(u.String(), ldap.DialWithTLSDialer(tlsConfig, &net.Dialer{Timeout: c.dialTimeout}))
 	}
 	if err != nil {
-		return fmt.Errorf("failed to connect: %v", err)
+		return nil, fmt.Errorf("failed to connect: %v", err)
 	}
-	defer conn.Close()
+	return conn, nil
+}
 
+// bind authenticates a connection as the service account.
+func (c *ldapConnector) bind(conn *ldap.Conn) error {
 	// If bindDN and bindPW are empty this will default to an anonymous bind.
 	if c.BindDN == "" && c.BindPW == "" {
 		if err := conn.UnauthenticatedBind(""); err != nil {
-			return fmt.Errorf("ldap: initial anonymous bind failed: %v", err)
+			return fmt.Errorf("ldap: initial anonymous bind failed: %w", err)
 		}
 	} else if err := conn.Bind(c.BindDN, c.BindPW); err != nil {
-		return fmt.Errorf("ldap: initial bind for user %q failed: %v", c.BindDN, err)
+		return fmt.Errorf("ldap: initial bind for user %q failed: %w", c.BindDN, err)
 	}
+	return nil
+}
 
-	return f(conn)
+// Close closes the idle connections to the LDAP directory.
+func (c *ldapConnector) Close() error {
+	return c.pool.Close()
 }
 
 func (c *ldapConnector) getAttrs(e ldap.Entry, name string) []string {
@@ -641,6 +808,10 @@ func (c *ldapConnector) groups(ctx context.Context, user ldap.Entry) ([]string,
 		return nil, nil
 	}
 
+	if c.GroupSearch.TokenGroups {
+		return c.tokenGroups(ctx, user)
+	}
+
 	var groupNames []string
 
 	for _, matcher := range c.GroupSearch.UserMatchers {
@@ -676,7 +847,7 @@ func (c *ldapConnector) groups(ctx context.Context, user ldap.Entry) ([]string,
 
 		// Recursive Search
 		c.logger.Info("Recursive group search enabled", "groupAttr", matcher.GroupAttr, "recursionAttr", matcher.RecursionGroupAttr)
-		for {
+		for depth := 1; ; depth++ {
 			var nextLevel []*ldap.Entry
 			for _, group := range groups {
 				name := c.getAttr(*group, c.GroupSearch.NameAttr)
@@ -700,8 +871,12 @@ func (c *ldapConnector) groups(ctx context.Context, user ldap.Entry) ([]string,
 
 				groupNames = append(groupNames, name)
 
+				if max := c.GroupSearch.MaxRecursionDepth; max > 0 && depth > max {
+					continue
+				}
+
 				// Search for parent groups using the group's DN.
-				parents, filter, err := c.queryGroups(ctx, matcher.RecursionGroupAttr, group.DN)
+				parents, filter, err := c.parentGroups(ctx, matcher.RecursionGroupAttr, group.DN)
 				if err != nil {
 					return nil, err
 				}
@@ -720,11 +895,41 @@ func (c *ldapConnector) groups(ctx context.Context, user ldap.Entry) ([]string,
 	return groupNames, nil
 }
 
-func (c *ldapConnector) queryGroups(ctx context.Context, memberAttr, dn string) ([]*ldap.Entry, string, error) {
+// matchingRuleInChain is the OID of LDAP_MATCHING_RULE_IN_CHAIN, which makes
+// Active Directory match attributes through any number of nested entries.
+const matchingRuleInChain = "1.2.840.113556.1.4.1941"
+
+// groupFilter returns the filter for the groups whose memberAttr holds dn.
+func (c *ldapConnector) groupFilter(memberAttr, dn string) string {
+	if c.GroupSearch.MatchingRuleInChain {
+		memberAttr += ":" + matchingRuleInChain + ":"
+	}
 	filter := fmt.Sprintf("(%s=%s)", memberAttr, ldap.EscapeFilter(dn))
 	if c.GroupSearch.Filter != "" {
 		filter = fmt.Sprintf("(&%s%s)", c.GroupSearch.Filter, filter)
 	}
+	return filter
+}
+
+// parentGroups is queryGroups for the recursive search, which may be cached.
+func (c *ldapConnector) parentGroups(ctx context.Context, memberAttr, dn string) ([]*ldap.Entry, string, error) {
+	if c.parentGroupCache == nil {
+		return c.queryGroups(ctx, memberAttr, dn)
+	}
+	key := memberAttr + "\x00" + dn
+	if entries, filter, ok := c.parentGroupCache.get(key); ok {
+		return entries, filter, nil
+	}
+	entries, filter, err := c.queryGroups(ctx, memberAttr, dn)
+	if err != nil {
+		return nil, filter, err
+	}
+	c.parentGroupCache.put(key, entries, filter)
+	return entries, filter, nil
+}
+
+func (c *ldapConnector) queryGroups(ctx context.Context, memberAttr, dn string) ([]*ldap.Entry, string, error) {
+	filter := c.groupFilter(memberAttr, dn)
 
 	req := &ldap.SearchRequest{
 		BaseDN:     c.GroupSearch.BaseDN,
@@ -757,6 +962,162 @@ func (c *ldapConnector) queryGroups(ctx context.Context, memberAttr, dn string)
 	return entries, filter, nil
 }
 
+// tokenGroupsBatchSize is the number of SIDs resolved to groups per search.
+const tokenGroupsBatchSize = 100
+
+// tokenGroups returns the names of the groups in the tokenGroups attribute of
+// a user, which Active Directory computes on request for a single entry.
+func (c *ldapConnector) tokenGroups(ctx context.Context, user ldap.Entry) ([]string, error) {
+	var sids [][]byte
+	if err := c.do(ctx, func(conn *ldap.Conn) error {
+		req := &ldap.SearchRequest{
+			BaseDN:     user.DN,
+			Scope:      ldap.ScopeBaseObject,
+			Filter:     "(objectClass=*)",
+			Attributes: []string{"tokenGroups"},
+		}
+		c.logger.Info("performing ldap search", "base_dn", req.BaseDN, "scope", scopeString(req.Scope), "filter", req.Filter)
+		resp, err := conn.Search(req)
+		if err != nil {
+			return fmt.Errorf("ldap: search for tokenGroups of %q failed: %v", user.DN, err)
+		}
+		if len(resp.Entries) != 1 {
+			return fmt.Errorf("ldap: search for tokenGroups of %q returned %d entries", user.DN, len(resp.Entries))
+		}
+		sids = resp.Entries[0].GetRawAttributeValues("tokenGroups")
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+
+	// Resolve the SIDs which aren't cached in batches.
+	names := make(map[string]string, len(sids))
+	var unresolved [][]byte
+	for _, sid := range sids {
+		if c.parentGroupCache != nil {
+			if entries, _, ok := c.parentGroupCache.get(sidCacheKey(sid)); ok {
+				for _, e := range entries {
+					names[string(sid)] = c.getAttr(*e, c.GroupSearch.NameAttr)
+				}
+				continue
+			}
+		}
+		unresolved = append(unresolved, sid)
+	}
+	for len(unresolved) > 0 {
+		batch := unresolved[:min(len(unresolved), tokenGroupsBatchSize)]
+		unresolved = unresolved[len(batch):]
+
+		entries, filter, err := c.groupsBySID(ctx, batch)
+		if err != nil {
+			return nil, err
+		}
+		found := make(map[string]*ldap.Entry, len(entries))
+		for _, e := range entries {
+			name := c.getAttr(*e, c.GroupSearch.NameAttr)
+			if name == "" {
+				return nil, fmt.Errorf("ldap: group entity %q missing required attribute %q", e.DN, c.GroupSearch.NameAttr)
+			}
+			sid := e.GetRawAttributeValue("objectSid")
+			names[string(sid)] = name
+			found[string(sid)] = e
+		}
+		for _, sid := range batch {
+			e, ok := found[string(sid)]
+			if !ok {
+				// Outside of baseDN or filtered out, e.g. built-in groups.
+				c.logger.Debug("no group found for SID", "sid", sidString(sid))
+			}
+			if c.parentGroupCache != nil {
+				var cached []*ldap.Entry
+				if ok {
+					cached = []*ldap.Entry{e}
+				}
+				c.parentGroupCache.put(sidCacheKey(sid), cached, filter)
+			}
+		}
+	}
+
+	groupNames := make([]string, 0, len(names))
+	for _, sid := range sids {
+		if name, ok := names[string(sid)]; ok {
+			groupNames = append(groupNames, name)
+		}
+	}
+	return groupNames, nil
+}
+
+// groupsBySID searches the groups with the given SIDs.
+func (c *ldapConnector) groupsBySID(ctx context.Context, sids [][]byte) ([]*ldap.Entry, string, error) {
+	filter := sidFilter(sids)
+	if c.GroupSearch.Filter != "" {
+		filter = fmt.Sprintf("(&%s%s)", c.GroupSearch.Filter, filter)
+	}
+	req := &ldap.SearchRequest{
+		BaseDN:     c.GroupSearch.BaseDN,
+		Filter:     filter,
+		Scope:      c.groupSearchScope,
+		Attributes: []string{c.GroupSearch.NameAttr, "objectSid"},
+	}
+
+	var entries []*ldap.Entry
+	if err := c.do(ctx, func(conn *ldap.Conn) error {
+		c.logger.Info("performing ldap search", "base_dn", req.BaseDN, "scope", scopeString(req.Scope), "sids", len(sids))
+		resp, err := conn.Search(req)
+		if err != nil {
+			if ldapErr, ok := err.(*ldap.Error); ok && ldapErr.ResultCode == ldap.LDAPResultNoSuchObject {
+				return nil
+			}
+			return fmt.Errorf("ldap: search for groups by SID failed: %v", err)
+		}
+		entries = resp.Entries
+		return nil
+	}); err != nil {
+		return nil, filter, err
+	}
+	return entries, filter, nil
+}
+
+// sidFilter returns a filter matching the objectSid of any of sids. Binary
+// values are escaped byte by byte.
+func sidFilter(sids [][]byte) string {
+	var b strings.Builder
+	if len(sids) > 1 {
+		b.WriteString("(|")
+	}
+	for _, sid := range sids {
+		b.WriteString("(objectSid=")
+		for _, v := range sid {
+			fmt.Fprintf(&b, "\\%02x", v)
+		}
+		b.WriteString(")")
+	}
+	if len(sids) > 1 {
+		b.WriteString(")")
+	}
+	return b.String()
+}
+
+func sidCacheKey(sid []byte) string {
+	return "objectSid\x00" + string(sid)
+}
+
+// sidString formats a binary SID like "S-1-5-21-...", for logging.
+func sidString(sid []byte) string {
+	if len(sid) < 8 || len(sid) != 8+4*int(sid[1]) {
+		return fmt.Sprintf("%x", sid)
+	}
+	var authority uint64
+	for _, v := range sid[2:8] {
+		authority = authority<<8 | uint64(v)
+	}
+	s := fmt.Sprintf("S-%d-%d", sid[0], authority)
+	for i := 8; i < len(sid); i += 4 {
+		s += fmt.Sprintf("-%d", binary.LittleEndian.Uint32(sid[i:]))
+	}
+	return s
+}
+
 func (c *ldapConnector) Prompt() string {
 	return c.UsernamePrompt
 }
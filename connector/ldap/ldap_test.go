@@ -6,7 +6,9 @@ import (
 	"log/slog"
 	"os"
 	"testing"
+	"time"
 
+	"github.com/go-ldap/ldap/v3"
 	"github.com/kylelemons/godebug/pretty"
 
 	"github.com/dexidp/dex/connector"
@@ -642,6 +644,141 @@ func TestNestedGroups(t *testing.T) {
 	runTests(t, connectLDAP, c, tests)
 }
 
+func TestNestedGroupsMaxDepth(t *testing.T) {
+	c := &Config{}
+	c.UserSearch.BaseDN = "ou=People,ou=TestNestedGroups,dc=example,dc=org"
+	c.UserSearch.NameAttr = "cn"
+	c.UserSearch.EmailAttr = "mail"
+	c.UserSearch.IDAttr = "DN"
+	c.UserSearch.Username = UsernameAttributes{"cn"}
+
+	c.GroupSearch.BaseDN = "ou=TestNestedGroups,dc=example,dc=org"
+	c.GroupSearch.UserMatchers = []UserMatcher{
+		{
+			UserAttr:           "DN",
+			GroupAttr:          "member",
+			RecursionGroupAttr: "member",
+		},
+	}
+	c.GroupSearch.NameAttr = "cn"
+	c.GroupSearch.MaxRecursionDepth = 1
+	c.GroupSearch.RecursionCacheTTL = "1m"
+
+	tests := []subtest{
+		{
+			name:     "nestedgroups_jane_one_level",
+			username: "jane",
+			password: "foo",
+			groups:   true,
+			want: connector.Identity{
+				UserID:        "cn=jane,ou=People,ou=TestNestedGroups,dc=example,dc=org",
+				Username:      "jane",
+				Email:         "janedoe@example.com",
+				EmailVerified: true,
+				Groups:        []string{"childGroup", "circularGroup1", "intermediateGroup"},
+			},
+		},
+	}
+	runTests(t, connectLDAP, c, tests)
+}
+
+func TestGroupFilterMatchingRuleInChain(t *testing.T) {
+	tests := []struct {
+		name    string
+		filter  string
+		inChain bool
+		want    string
+	}{
+		{
+			name: "direct",
+			want: `(member=cn=jane \28x\29,dc=example,dc=org)`,
+		},
+		{
+			name:    "matching rule in chain",
+			filter:  "(objectClass=group)",
+			inChain: true,
+			want:    `(&(objectClass=group)(member:1.2.840.113556.1.4.1941:=cn=jane \28x\29,dc=example,dc=org))`,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			c := &ldapConnector{}
+			c.GroupSearch.Filter = tc.filter
+			c.GroupSearch.MatchingRuleInChain = tc.inChain
+			if got := c.groupFilter("member", "cn=jane (x),dc=example,dc=org"); got != tc.want {
+				t.Errorf("expected filter %q, got %q", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestTokenGroupsSID(t *testing.T) {
+	// S-1-5-21-1004336348-1177238915-682003330-513 (Domain Users)
+	sid := []byte{
+		0x01, 0x05, 0x00, 0x00, 0x00, 0x00, 0x00, 0x05,
+		0x15, 0x00, 0x00, 0x00, 0xdc, 0xf4, 0xdc, 0x3b,
+		0x83, 0x3d, 0x2b, 0x46, 0x82, 0x8b, 0xa6, 0x28,
+		0x01, 0x02, 0x00, 0x00,
+	}
+	if got, want := sidString(sid), "S-1-5-21-1004336348-1177238915-682003330-513"; got != want {
+		t.Errorf("expected SID %q, got %q", want, got)
+	}
+
+	want := `(objectSid=\01\05\00\00\00\00\00\05\15\00\00\00\dc\f4\dc\3b\83\3d\2b\46\82\8b\a6\28\01\02\00\00)`
+	if got := sidFilter([][]byte{sid}); got != want {
+		t.Errorf("expected filter %q, got %q", want, got)
+	}
+	if got := sidFilter([][]byte{{0x01, 0x00}, {0x28, 0x29}}); got != `(|(objectSid=\01\00)(objectSid=\28\29))` {
+		t.Errorf("unexpected filter for multiple SIDs: %q", got)
+	}
+}
+
+func TestTokenGroupsConfig(t *testing.T) {
+	c := &Config{Host: "localhost", InsecureNoSSL: true}
+	c.UserSearch.BaseDN = "cn=users,dc=example,dc=com"
+	c.UserSearch.Username = UsernameAttributes{"sAMAccountName"}
+	c.GroupSearch.BaseDN = "cn=groups,dc=example,dc=com"
+	c.GroupSearch.TokenGroups = true
+	if _, err := c.openConnector(slog.New(slog.DiscardHandler)); err == nil {
+		t.Error("expected tokenGroups without nameAttr to be rejected")
+	}
+	c.GroupSearch.NameAttr = "cn"
+	c.GroupSearch.MatchingRuleInChain = true
+	if _, err := c.openConnector(slog.New(slog.DiscardHandler)); err == nil {
+		t.Error("expected tokenGroups with matchingRuleInChain to be rejected")
+	}
+}
+
+func TestGroupCache(t *testing.T) {
+	now := time.Now()
+	cache := newGroupCache(time.Minute, 2)
+	cache.now = func() time.Time { return now }
+
+	groups := []*ldap.Entry{{DN: "cn=parent,dc=example,dc=org"}}
+	cache.put("member\x00cn=child,dc=example,dc=org", groups, "(member=cn=child,dc=example,dc=org)")
+
+	got, filter, ok := cache.get("member\x00cn=child,dc=example,dc=org")
+	if !ok || len(got) != 1 || got[0].DN != groups[0].DN || filter == "" {
+		t.Fatalf("expected cached groups, got %v %q %t", got, filter, ok)
+	}
+
+	// The least recently used entry is evicted once the cache is full.
+	cache.put("member\x00cn=other,dc=example,dc=org", groups, "(member=cn=other,dc=example,dc=org)")
+	cache.get("member\x00cn=child,dc=example,dc=org")
+	cache.put("member\x00cn=third,dc=example,dc=org", groups, "(member=cn=third,dc=example,dc=org)")
+	if _, _, ok := cache.get("member\x00cn=other,dc=example,dc=org"); ok {
+		t.Errorf("expected the least recently used groups to be evicted")
+	}
+	if len(cache.entries) != 2 || cache.lru.Len() != 2 {
+		t.Errorf("expected 2 cached entries, got %d", len(cache.entries))
+	}
+
+	now = now.Add(2 * time.Minute)
+	if _, _, ok := cache.get("member\x00cn=child,dc=example,dc=org"); ok {
+		t.Errorf("expected cached groups to expire")
+	}
+}
+
 func getenv(key, defaultVal string) string {
 	if val := os.Getenv(key); val != "" {
 		return val
@@ -0,0 +1,159 @@
+package ldap
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/go-ldap/ldap/v3"
+)
+
+// Defaults for the connection pool configuration.
+const (
+	defaultPoolSize          = 4
+	defaultMaxIdleTime       = 5 * time.Minute
+	defaultDialTimeout       = 10 * time.Second
+	defaultHostRetryInterval = 30 * time.Second
+)
+
+// connPool keeps connections to the LDAP servers open between requests, so
+// logins don't pay for a TCP and TLS handshake each time.
+//
+// Hosts are tried in the order they're configured. A host which can't be
+// reached is skipped until retryInterval has passed, and idle connections to
+// a host are dropped once a host before it may be reachable again, so traffic
+// returns to the primary server when it recovers.
+type connPool struct {
+	hosts         []string
+	dial          func(host string) (*ldap.Conn, error)
+	size          int
+	maxIdleTime   time.Duration
+	retryInterval time.Duration
+	now           func() time.Time
+	logger        *slog.Logger
+
+	mu        sync.Mutex
+	idle      []*pooledConn
+	downUntil []time.Time
+	closed    bool
+}
+
+type pooledConn struct {
+	*ldap.Conn
+	host      int
+	idleSince time.Time
+}
+
+func newConnPool(hosts []string, dial func(host string) (*ldap.Conn, error), size int, maxIdleTime, retryInterval time.Duration, logger *slog.Logger) *connPool {
+	return &connPool{
+		hosts:         hosts,
+		dial:          dial,
+		size:          size,
+		maxIdleTime:   maxIdleTime,
+		retryInterval: retryInterval,
+		now:           time.Now,
+		logger:        logger,
+		downUntil:     make([]time.Time, len(hosts)),
+	}
+}
+
+// get returns an idle connection if there is a usable one, or dials a new one.
+// reused reports whether the connection was taken from the pool, in which case
+// the server may have dropped it without the client noticing yet.
+func (p *connPool) get() (conn *pooledConn, reused bool, err error) {
+	p.mu.Lock()
+	now := p.now()
+	preferred := p.preferredHost(now)
+	for len(p.idle) > 0 {
+		conn = p.idle[len(p.idle)-1]
+		p.idle = p.idle[:len(p.idle)-1]
+		if conn.IsClosing() || now.Sub(conn.idleSince) > p.maxIdleTime || conn.host > preferred {
+			conn.Close()
+			continue
+		}
+		p.mu.Unlock()
+		return conn, true, nil
+	}
+	p.mu.Unlock()
+
+	conn, err = p.dialAny()
+	return conn, false, err
+}
+
+// put returns a connection to the pool, or closes it if the pool is full or
+// the connection is broken.
+func (p *connPool) put(conn *pooledConn) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.closed || conn.IsClosing() || len(p.idle) >= p.size {
+		conn.Close()
+		return
+	}
+	conn.idleSince = p.now()
+	p.idle = append(p.idle, conn)
+}
+
+// Close closes all idle connections. Connections in use are closed when they
+// are returned.
+func (p *connPool) Close() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.closed = true
+	for _, conn := range p.idle {
+		conn.Close()
+	}
+	p.idle = nil
+	return nil
+}
+
+// preferredHost returns the first host not known to be down.
+func (p *connPool) preferredHost(now time.Time) int {
+	for i, until := range p.downUntil {
+		if !now.Before(until) {
+			return i
+		}
+	}
+	return 0
+}
+
+func (p *connPool) dialAny() (*pooledConn, error) {
+	p.mu.Lock()
+	now := p.now()
+	var candidates []int
+	for i := range p.hosts {
+		if !now.Before(p.downUntil[i]) {
+			candidates = append(candidates, i)
+		}
+	}
+	if len(candidates) == 0 {
+		// Every host failed recently. Try them all rather than giving up.
+		for i := range p.hosts {
+			candidates = append(candidates, i)
+		}
+	}
+	p.mu.Unlock()
+
+	var errs []error
+	for _, i := range candidates {
+		conn, err := p.dial(p.hosts[i])
+		p.mu.Lock()
+		if err != nil {
+			p.downUntil[i] = p.now().Add(p.retryInterval)
+			p.mu.Unlock()
+			if len(p.hosts) > 1 {
+				p.logger.Warn("ldap host unreachable, trying the next one", "host", p.hosts[i], "err", err)
+			}
+			errs = append(errs, err)
+			continue
+		}
+		p.downUntil[i] = time.Time{}
+		p.mu.Unlock()
+		return &pooledConn{Conn: conn, host: i}, nil
+	}
+	if len(errs) == 1 {
+		return nil, errs[0]
+	}
+	return nil, fmt.Errorf("all hosts unreachable: %w", errors.Join(errs...))
+}
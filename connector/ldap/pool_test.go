@@ -0,0 +1,154 @@
+package ldap
+
+import (
+	"errors"
+	"log/slog"
+	"net"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/go-ldap/ldap/v3"
+)
+
+// fakeDialer hands out connections over in-memory pipes and records which
+// hosts were dialed. Hosts in down fail to connect.
+type fakeDialer struct {
+	down   map[string]bool
+	dialed []string
+}
+
+func (d *fakeDialer) dial(host string) (*ldap.Conn, error) {
+	d.dialed = append(d.dialed, host)
+	if d.down[host] {
+		return nil, errors.New("connection refused")
+	}
+	client, server := net.Pipe()
+	go func() {
+		// Drain the client's writes until it closes the connection.
+		buf := make([]byte, 512)
+		for {
+			if _, err := server.Read(buf); err != nil {
+				return
+			}
+		}
+	}()
+	conn := ldap.NewConn(client, false)
+	conn.Start()
+	return conn, nil
+}
+
+func newTestPool(hosts []string, d *fakeDialer, size int) (*connPool, *time.Time) {
+	now := time.Now()
+	p := newConnPool(hosts, d.dial, size, time.Minute, 30*time.Second, slog.New(slog.DiscardHandler))
+	p.now = func() time.Time { return now }
+	return p, &now
+}
+
+func TestConnPoolReuse(t *testing.T) {
+	d := &fakeDialer{}
+	p, now := newTestPool([]string{"a:389"}, d, 1)
+	defer p.Close()
+
+	first, reused, err := p.get()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reused {
+		t.Errorf("expected a new connection")
+	}
+	p.put(first)
+
+	second, reused, err := p.get()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reused || second != first {
+		t.Errorf("expected the idle connection to be reused")
+	}
+
+	// The pool only keeps one idle connection.
+	third, _, err := p.get()
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.put(second)
+	p.put(third)
+	if !third.IsClosing() {
+		t.Errorf("expected connection exceeding the pool size to be closed")
+	}
+
+	// Connections idle for too long are replaced.
+	*now = now.Add(2 * time.Minute)
+	fourth, reused, err := p.get()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reused || !second.IsClosing() {
+		t.Errorf("expected stale idle connection to be closed and replaced")
+	}
+	p.put(fourth)
+
+	if len(d.dialed) != 3 {
+		t.Errorf("expected 3 dials, got %d", len(d.dialed))
+	}
+}
+
+func TestConnPoolFailover(t *testing.T) {
+	d := &fakeDialer{down: map[string]bool{"primary:636": true}}
+	p, now := newTestPool([]string{"primary:636", "secondary:636"}, d, 2)
+	defer p.Close()
+
+	conn, _, err := p.get()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if conn.host != 1 {
+		t.Fatalf("expected connection to the failover host, got host %d", conn.host)
+	}
+
+	// The primary host is skipped while it's known to be down.
+	other, _, err := p.get()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := d.dialed, []string{"primary:636", "secondary:636", "secondary:636"}; !slices.Equal(got, want) {
+		t.Errorf("expected dials %q, got %q", want, got)
+	}
+	p.put(conn)
+	p.put(other)
+
+	// Once the primary host recovers, connections to the failover host are
+	// dropped in favor of it.
+	delete(d.down, "primary:636")
+	*now = now.Add(time.Minute / 2)
+	conn, reused, err := p.get()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reused || conn.host != 0 {
+		t.Errorf("expected a new connection to the primary host, got host %d (reused %t)", conn.host, reused)
+	}
+	if !other.IsClosing() {
+		t.Errorf("expected idle connection to the failover host to be closed")
+	}
+	p.put(conn)
+}
+
+func TestConnPoolAllHostsDown(t *testing.T) {
+	d := &fakeDialer{down: map[string]bool{"a:389": true, "b:389": true}}
+	p, _ := newTestPool([]string{"a:389", "b:389"}, d, 1)
+	defer p.Close()
+
+	if _, _, err := p.get(); err == nil {
+		t.Fatal("expected error when no host is reachable")
+	}
+
+	// With every host down, all of them are tried again rather than none.
+	if _, _, err := p.get(); err == nil {
+		t.Fatal("expected error when no host is reachable")
+	}
+	if len(d.dialed) != 4 {
+		t.Errorf("expected every host to be tried twice, got dials %q", d.dialed)
+	}
+}
@@ -4,6 +4,7 @@ package microsoft
 import (
 	"bytes"
 	"context"
+	"encoding/base64"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -40,6 +41,12 @@ const (
 	// Microsoft requires this scope to return a refresh token
 	// see https://docs.microsoft.com/en-us/azure/active-directory/develop/v2-permissions-and-consent#offline_access
 	scopeOfflineAccess = "offline_access"
+	// Microsoft requires this scope to return an ID token, which holds the
+	// user's groups and app roles.
+	scopeOpenID = "openid"
+
+	// Maximum number of ids Microsoft Graph resolves in one getByIds request.
+	maxGetByIDs = 1000
 )
 
 // Config holds configuration options for microsoft logins.
@@ -68,6 +75,19 @@ type Config struct {
 	// following values: "name", "email", "mailNickname" or "onPremisesSamAccountName".
 	// If unset, the preferred_username field will remain empty.
 	PreferredUsernameField string `json:"preferredUsernameField"`
+
+	// GroupsFromIDToken reads the user's group ids from the "groups" claim of
+	// the ID token, rather than listing them through Microsoft Graph. The app
+	// registration must be configured to emit group object ids in the claim.
+	// If the user is in more groups than fit in a token (the "groups
+	// overage" case), they're listed through Microsoft Graph instead.
+	GroupsFromIDToken bool `json:"groupsFromIDToken"`
+
+	// AppRoles adds the app roles assigned to the user, from the "roles"
+	// claim of the ID token, to their groups. AppRolePrefix is prepended to
+	// each role, to tell them apart from groups.
+	AppRoles      bool   `json:"appRoles"`
+	AppRolePrefix string `json:"appRolePrefix"`
 }
 
 // Open returns a strategy for logging in through Microsoft.
@@ -89,6 +109,9 @@ func (c *Config) Open(id string, logger *slog.Logger) (connector.Connector, erro
 		domainHint:             c.DomainHint,
 		scopes:                 c.Scopes,
 		preferredUsernameField: c.PreferredUsernameField,
+		groupsFromIDToken:      c.GroupsFromIDToken,
+		appRoles:               c.AppRoles,
+		appRolePrefix:          c.AppRolePrefix,
 	}
 
 	if m.apiURL == "" {
@@ -145,6 +168,9 @@ type microsoftConnector struct {
 	domainHint             string
 	scopes                 []string
 	preferredUsernameField string
+	groupsFromIDToken      bool
+	appRoles               bool
+	appRolePrefix          string
 }
 
 func (c *microsoftConnector) isOrgTenant() bool {
@@ -155,6 +181,11 @@ func (c *microsoftConnector) groupsRequired(groupScope bool) bool {
 	return (len(c.groups) > 0 || groupScope) && c.isOrgTenant()
 }
 
+// idTokenRequired reports whether groups are read from the ID token.
+func (c *microsoftConnector) idTokenRequired(groupScope bool) bool {
+	return (c.groupsFromIDToken || c.appRoles) && c.groupsRequired(groupScope)
+}
+
 func (c *microsoftConnector) oauth2Config(scopes connector.Scopes) *oauth2.Config {
 	var microsoftScopes []string
 	if len(c.scopes) > 0 {
@@ -165,6 +196,9 @@ func (c *microsoftConnector) oauth2Config(scopes connector.Scopes) *oauth2.Confi
 	if c.groupsRequired(scopes.Groups) {
 		microsoftScopes = append(microsoftScopes, scopeGroups)
 	}
+	if c.idTokenRequired(scopes.Groups) {
+		microsoftScopes = append(microsoftScopes, scopeOpenID)
+	}
 
 	if scopes.OfflineAccess {
 		microsoftScopes = append(microsoftScopes, scopeOfflineAccess)
@@ -233,7 +267,17 @@ func (c *microsoftConnector) HandleCallback(s connector.Scopes, connData []byte,
 	c.setPreferredUsername(&identity, user)
 
 	if c.groupsRequired(s.Groups) {
-		groups, err := c.getGroups(ctx, client, user.ID)
+		var claims *idTokenClaims
+		if c.idTokenRequired(s.Groups) {
+			rawIDToken, ok := token.Extra("id_token").(string)
+			if !ok {
+				return identity, errors.New("microsoft: no id_token in token response")
+			}
+			if claims, err = parseIDTokenClaims(rawIDToken); err != nil {
+				return identity, fmt.Errorf("microsoft: %v", err)
+			}
+		}
+		groups, err := c.getGroups(ctx, client, user.ID, claims)
 		if err != nil {
 			return identity, fmt.Errorf("microsoft: get groups: %w", err)
 		}
@@ -297,11 +341,19 @@ func (c *microsoftConnector) Refresh(ctx context.Context, s connector.Scopes, id
 		RefreshToken: data.RefreshToken,
 		Expiry:       data.Expiry,
 	}
+	// Only a refreshed token comes with a new ID token, so refresh even if
+	// the access token is still valid.
+	idTokenRequired := c.idTokenRequired(s.Groups)
+	if idTokenRequired {
+		tok.Expiry = time.Now()
+	}
 
+	var rawIDToken string
 	client := oauth2.NewClient(ctx, &notifyRefreshTokenSource{
 		new: c.oauth2Config(s).TokenSource(ctx, tok),
 		t:   tok,
 		f: func(tok *oauth2.Token) error {
+			rawIDToken, _ = tok.Extra("id_token").(string)
 			data := connectorData{
 				AccessToken:  tok.AccessToken,
 				RefreshToken: tok.RefreshToken,
@@ -325,7 +377,16 @@ func (c *microsoftConnector) Refresh(ctx context.Context, s connector.Scopes, id
 	c.setPreferredUsername(&identity, user)
 
 	if c.groupsRequired(s.Groups) {
-		groups, err := c.getGroups(ctx, client, user.ID)
+		var claims *idTokenClaims
+		if idTokenRequired {
+			if rawIDToken == "" {
+				return identity, errors.New("microsoft: no id_token in refresh token response")
+			}
+			if claims, err = parseIDTokenClaims(rawIDToken); err != nil {
+				return identity, fmt.Errorf("microsoft: %v", err)
+			}
+		}
+		groups, err := c.getGroups(ctx, client, user.ID, claims)
 		if err != nil {
 			return identity, fmt.Errorf("microsoft: get groups: %w", err)
 		}
@@ -429,10 +490,64 @@ type group struct {
 	Name string `json:"displayName"`
 }
 
-func (c *microsoftConnector) getGroups(ctx context.Context, client *http.Client, userID string) ([]string, error) {
-	userGroups, err := c.getGroupIDs(ctx, client)
+// idTokenClaims holds the claims of an ID token which carry the user's groups
+// and app roles.
+//
+// See https://learn.microsoft.com/en-us/entra/identity-platform/id-token-claims-reference
+type idTokenClaims struct {
+	Groups []string `json:"groups"`
+	Roles  []string `json:"roles"`
+
+	// Set instead of the groups claim if the user is in too many groups.
+	HasGroups  bool              `json:"hasgroups"`
+	ClaimNames map[string]string `json:"_claim_names"`
+}
+
+// groupsOverage reports whether the user's groups didn't fit in the token.
+func (c *idTokenClaims) groupsOverage() bool {
+	_, ok := c.ClaimNames["groups"]
+	return ok || c.HasGroups
+}
+
+// parseIDTokenClaims decodes the claims of an ID token. The signature isn't
+// verified, which is allowed for ID tokens received directly from the token
+// endpoint over TLS.
+//
+// See https://openid.net/specs/openid-connect-core-1_0.html#IDTokenValidation
+func parseIDTokenClaims(rawIDToken string) (*idTokenClaims, error) {
+	parts := strings.Split(rawIDToken, ".")
+	if len(parts) != 3 {
+		return nil, errors.New("malformed id_token")
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("malformed id_token payload: %v", err)
+	}
+	var claims idTokenClaims
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return nil, fmt.Errorf("unmarshal id_token claims: %v", err)
+	}
+	return &claims, nil
+}
+
+// getGroups returns the user's groups. If claims are given, groups and app
+// roles are read from them, falling back to Microsoft Graph for groups in
+// case of a groups overage.
+func (c *microsoftConnector) getGroups(ctx context.Context, client *http.Client, userID string, claims *idTokenClaims) ([]string, error) {
+	var (
+		userGroups []string
+		err        error
+	)
+	if c.groupsFromIDToken && claims != nil && !claims.groupsOverage() {
+		userGroups = claims.Groups
+	} else {
+		if c.groupsFromIDToken && claims != nil {
+			c.logger.Debug("groups overage in id_token, listing groups through microsoft graph", "user", userID)
+		}
+		userGroups, err = c.getGroupIDs(ctx, client)
+		if err != nil {
+			return nil, err
+		}
 	}
 
 	if c.groupNameFormat == GroupName {
@@ -442,6 +557,12 @@ func (c *microsoftConnector) getGroups(ctx context.Context, client *http.Client,
 		}
 	}
 
+	if c.appRoles && claims != nil {
+		for _, role := range claims.Roles {
+			userGroups = append(userGroups, c.appRolePrefix+role)
+		}
+	}
+
 	// ensure that the user is in at least one required group
 	filteredGroups := groups_pkg.Filter(userGroups, c.groups)
 	if len(c.groups) > 0 && len(filteredGroups) == 0 {
@@ -477,6 +598,16 @@ func (c *microsoftConnector) getGroupIDs(ctx context.Context, client *http.Clien
 }
 
 func (c *microsoftConnector) getGroupNames(ctx context.Context, client *http.Client, ids []string) (groups []string, err error) {
+	// Users in a groups overage can be in more groups than can be resolved
+	// in one request.
+	for len(ids) > maxGetByIDs {
+		names, err := c.getGroupNames(ctx, client, ids[:maxGetByIDs])
+		if err != nil {
+			return groups, err
+		}
+		groups = append(groups, names...)
+		ids = ids[maxGetByIDs:]
+	}
 	if len(ids) == 0 {
 		return
 	}
@@ -1,6 +1,7 @@
 package microsoft
 
 import (
+	"encoding/base64"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -184,6 +185,82 @@ func TestUserNotInRequiredGroupFromGraphAPI(t *testing.T) {
 	}
 }
 
+func TestUserGroupsFromIDToken(t *testing.T) {
+	tests := []struct {
+		name   string
+		claims map[string]interface{}
+		want   []string
+	}{
+		{
+			name:   "groups in token",
+			claims: map[string]interface{}{"groups": []string{"a"}, "roles": []string{"Admin"}},
+			want:   []string{"a", "role:Admin"},
+		},
+		{
+			name: "groups overage",
+			claims: map[string]interface{}{
+				"_claim_names": map[string]string{"groups": "src1"},
+				"roles":        []string{"Admin"},
+			},
+			want: []string{"b", "c", "role:Admin"},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			s := newTestServer(map[string]testResponse{
+				"/v1.0/me?$select=id,displayName,userPrincipalName,mailNickname,onPremisesSamAccountName": {data: user{}},
+				"/v1.0/me/getMemberGroups": {data: map[string]interface{}{
+					"value": []string{"b", "c"},
+				}},
+				"/" + tenant + "/oauth2/v2.0/token": {data: map[string]interface{}{
+					"access_token": "eyJhbGciOiJSUzI1NiIsInR5cCI6IkpXVCJ9",
+					"expires_in":   "30",
+					"id_token":     newIDToken(t, tc.claims),
+				}},
+			})
+			defer s.Close()
+
+			req, _ := http.NewRequest("GET", s.URL, nil)
+
+			c := microsoftConnector{
+				apiURL:            s.URL,
+				graphURL:          s.URL,
+				tenant:            tenant,
+				groupNameFormat:   GroupID,
+				groupsFromIDToken: true,
+				appRoles:          true,
+				appRolePrefix:     "role:",
+				logger:            slog.Default(),
+			}
+			identity, err := c.HandleCallback(connector.Scopes{Groups: true}, nil, req)
+			expectNil(t, err)
+			expectEquals(t, identity.Groups, tc.want)
+		})
+	}
+}
+
+func TestLoginURLWithIDTokenGroups(t *testing.T) {
+	conn := microsoftConnector{
+		apiURL:            "https://test.com",
+		redirectURI:       "https://test.com",
+		tenant:            tenant,
+		groupsFromIDToken: true,
+	}
+
+	loginURL, _, _ := conn.LoginURL(connector.Scopes{Groups: true}, conn.redirectURI, "some-state")
+
+	parsedLoginURL, _ := url.Parse(loginURL)
+	expectEquals(t, parsedLoginURL.Query().Get("scope"), "user.read directory.read.all openid")
+}
+
+func newIDToken(t *testing.T, claims map[string]interface{}) string {
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return "eyJhbGciOiJSUzI1NiJ9." + base64.RawURLEncoding.EncodeToString(payload) + ".c2lnbmF0dXJl"
+}
+
 func newTestServer(responses map[string]testResponse) *httptest.Server {
 	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		response, found := responses[r.RequestURI]
@@ -8,6 +8,7 @@ import (
 	"log/slog"
 	"net/http"
 	"net/url"
+	"time"
 
 	"github.com/dexidp/dex/connector"
 )
@@ -39,6 +40,13 @@ type Callback struct {
 	// The returned identity.
 	Identity connector.Identity
 	Logger   *slog.Logger
+
+	// Identities, if set, are returned instead of Identity. The callback
+	// request's "identity" query parameter picks one by username or user ID,
+	// defaulting to the first.
+	Identities []connector.Identity
+
+	failures failures
 }
 
 // LoginURL returns the URL to redirect the user to login with.
@@ -57,25 +65,162 @@ var connectorData = []byte("foobar")
 
 // HandleCallback parses the request and returns the user's identity
 func (m *Callback) HandleCallback(s connector.Scopes, connData []byte, r *http.Request) (connector.Identity, error) {
-	return m.Identity, nil
+	if err := m.failures.login(r.Context()); err != nil {
+		return connector.Identity{}, err
+	}
+	if len(m.Identities) == 0 {
+		return m.Identity, nil
+	}
+	name := r.URL.Query().Get("identity")
+	if name == "" {
+		return m.Identities[0], nil
+	}
+	for _, identity := range m.Identities {
+		if identity.Username == name || identity.UserID == name {
+			return identity, nil
+		}
+	}
+	return connector.Identity{}, fmt.Errorf("mock: no identity %q", name)
 }
 
 // Refresh updates the identity during a refresh token request.
 func (m *Callback) Refresh(ctx context.Context, s connector.Scopes, identity connector.Identity) (connector.Identity, error) {
-	return m.Identity, nil
+	if err := m.failures.refresh(ctx); err != nil {
+		return identity, err
+	}
+	if len(m.Identities) == 0 {
+		return m.Identity, nil
+	}
+	for _, i := range m.Identities {
+		if i.UserID == identity.UserID {
+			return i, nil
+		}
+	}
+	return identity, fmt.Errorf("mock: no identity with user ID %q", identity.UserID)
 }
 
 func (m *Callback) TokenIdentity(ctx context.Context, subjectTokenType, subjectToken string) (connector.Identity, error) {
 	return m.Identity, nil
 }
 
+// Identity is a fake identity returned by the mock connectors.
+type Identity struct {
+	UserID            string   `json:"userID"`
+	Username          string   `json:"username"`
+	PreferredUsername string   `json:"preferredUsername"`
+	Email             string   `json:"email"`
+	EmailVerified     bool     `json:"emailVerified"`
+	Groups            []string `json:"groups"`
+	ConnectorData     string   `json:"connectorData"`
+
+	// Password the mockPassword connector accepts for this identity.
+	Password string `json:"password"`
+}
+
+func (i Identity) identity() connector.Identity {
+	identity := connector.Identity{
+		UserID:            i.UserID,
+		Username:          i.Username,
+		PreferredUsername: i.PreferredUsername,
+		Email:             i.Email,
+		EmailVerified:     i.EmailVerified,
+		Groups:            i.Groups,
+	}
+	if i.ConnectorData != "" {
+		identity.ConnectorData = []byte(i.ConnectorData)
+	}
+	if identity.UserID == "" {
+		identity.UserID = i.Username
+	}
+	return identity
+}
+
+// Failures configures failures the mock connectors inject, to test how
+// clients handle them.
+type Failures struct {
+	// LoginError, if set, fails every login with this message.
+	LoginError string `json:"loginError"`
+	// RefreshError, if set, fails every refresh with this message.
+	RefreshError string `json:"refreshError"`
+	// Delay, e.g. "2s", is waited before every login and refresh response.
+	Delay string `json:"delay"`
+}
+
+func (f Failures) parse() (failures, error) {
+	parsed := failures{loginError: f.LoginError, refreshError: f.RefreshError}
+	if f.Delay != "" {
+		delay, err := time.ParseDuration(f.Delay)
+		if err != nil {
+			return parsed, fmt.Errorf("invalid delay %q: %v", f.Delay, err)
+		}
+		parsed.delay = delay
+	}
+	return parsed, nil
+}
+
+type failures struct {
+	loginError   string
+	refreshError string
+	delay        time.Duration
+}
+
+func (f failures) wait(ctx context.Context) error {
+	if f.delay == 0 {
+		return nil
+	}
+	t := time.NewTimer(f.delay)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (f failures) login(ctx context.Context) error {
+	if err := f.wait(ctx); err != nil {
+		return err
+	}
+	if f.loginError != "" {
+		return errors.New(f.loginError)
+	}
+	return nil
+}
+
+func (f failures) refresh(ctx context.Context) error {
+	if err := f.wait(ctx); err != nil {
+		return err
+	}
+	if f.refreshError != "" {
+		return errors.New(f.refreshError)
+	}
+	return nil
+}
+
 // CallbackConfig holds the configuration parameters for a connector which requires no interaction.
-type CallbackConfig struct{}
+type CallbackConfig struct {
+	// Identities to return instead of the default one. Which one is picked
+	// by the "identity" query parameter of the callback request, holding a
+	// username or user ID.
+	Identities []Identity `json:"identities"`
+
+	Failures Failures `json:"failures"`
+}
 
 // Open returns an authentication strategy which requires no user interaction.
 func (c *CallbackConfig) Open(id string, logger *slog.Logger) (connector.Connector, error) {
 	logger = logger.With(slog.Group("connector", "type", "callback", "id", id))
-	return NewCallbackConnector(logger), nil
+	conn := NewCallbackConnector(logger).(*Callback)
+	for _, identity := range c.Identities {
+		conn.Identities = append(conn.Identities, identity.identity())
+	}
+	failures, err := c.Failures.parse()
+	if err != nil {
+		return nil, err
+	}
+	conn.failures = failures
+	return conn, nil
 }
 
 // PasswordConfig holds the configuration for a mock connector which prompts for the supplied
@@ -83,17 +228,38 @@ func (c *CallbackConfig) Open(id string, logger *slog.Logger) (connector.Connect
 type PasswordConfig struct {
 	Username string `json:"username"`
 	Password string `json:"password"`
+
+	// Identities which can log in with their username and password, in
+	// addition to the one above.
+	Identities []Identity `json:"identities"`
+
+	Failures Failures `json:"failures"`
 }
 
 // Open returns an authentication strategy which prompts for a predefined username and password.
 func (c *PasswordConfig) Open(id string, logger *slog.Logger) (connector.Connector, error) {
-	if c.Username == "" {
-		return nil, errors.New("no username supplied")
+	if len(c.Identities) == 0 {
+		if c.Username == "" {
+			return nil, errors.New("no username supplied")
+		}
+		if c.Password == "" {
+			return nil, errors.New("no password supplied")
+		}
 	}
-	if c.Password == "" {
-		return nil, errors.New("no password supplied")
+
+	p := &passwordConnector{username: c.Username, password: c.Password, logger: logger}
+	for _, identity := range c.Identities {
+		if identity.Username == "" || identity.Password == "" {
+			return nil, errors.New("identities require a username and password")
+		}
+		p.identities = append(p.identities, identity)
 	}
-	return &passwordConnector{c.Username, c.Password, logger}, nil
+	failures, err := c.Failures.parse()
+	if err != nil {
+		return nil, err
+	}
+	p.failures = failures
+	return p, nil
 }
 
 var (
@@ -102,15 +268,20 @@ var (
 )
 
 type passwordConnector struct {
-	username string
-	password string
-	logger   *slog.Logger
+	username   string
+	password   string
+	identities []Identity
+	failures   failures
+	logger     *slog.Logger
 }
 
 func (p passwordConnector) Close() error { return nil }
 
 func (p passwordConnector) Login(ctx context.Context, s connector.Scopes, username, password string) (identity connector.Identity, validPassword bool, err error) {
-	if username == p.username && password == p.password {
+	if err := p.failures.login(ctx); err != nil {
+		return identity, false, err
+	}
+	if p.username != "" && username == p.username && password == p.password {
 		return connector.Identity{
 			UserID:        "0-385-28089-0",
 			Username:      "Kilgore Trout",
@@ -119,11 +290,24 @@ func (p passwordConnector) Login(ctx context.Context, s connector.Scopes, userna
 			ConnectorData: []byte(`{"test": "true"}`),
 		}, true, nil
 	}
+	for _, i := range p.identities {
+		if username == i.Username && password == i.Password {
+			return i.identity(), true, nil
+		}
+	}
 	return identity, false, nil
 }
 
 func (p passwordConnector) Prompt() string { return "" }
 
-func (p passwordConnector) Refresh(_ context.Context, _ connector.Scopes, identity connector.Identity) (connector.Identity, error) {
+func (p passwordConnector) Refresh(ctx context.Context, _ connector.Scopes, identity connector.Identity) (connector.Identity, error) {
+	if err := p.failures.refresh(ctx); err != nil {
+		return identity, err
+	}
+	for _, i := range p.identities {
+		if configured := i.identity(); configured.UserID == identity.UserID {
+			return configured, nil
+		}
+	}
 	return identity, nil
 }
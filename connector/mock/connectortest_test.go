@@ -0,0 +1,108 @@
+package mock
+
+import (
+	"context"
+	"log/slog"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/dexidp/dex/connector"
+)
+
+var testIdentities = []Identity{
+	{UserID: "1", Username: "alice", Email: "alice@example.com", Groups: []string{"admins"}, Password: "alice-pw"},
+	{Username: "bob", Groups: []string{"users"}, ConnectorData: `{"token":"bob"}`, Password: "bob-pw"},
+}
+
+func TestCallbackIdentities(t *testing.T) {
+	c := &CallbackConfig{Identities: testIdentities}
+	conn, err := c.Open("mock", slog.New(slog.DiscardHandler))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cb := conn.(*Callback)
+
+	identity, err := cb.HandleCallback(connector.Scopes{}, nil, httptest.NewRequest("GET", "/callback?state=x", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if identity.Username != "alice" {
+		t.Errorf("expected the first identity by default, got %q", identity.Username)
+	}
+
+	identity, err = cb.HandleCallback(connector.Scopes{}, nil, httptest.NewRequest("GET", "/callback?state=x&identity=bob", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if identity.UserID != "bob" || string(identity.ConnectorData) != `{"token":"bob"}` {
+		t.Errorf("unexpected identity %+v", identity)
+	}
+
+	if _, err := cb.HandleCallback(connector.Scopes{}, nil, httptest.NewRequest("GET", "/callback?identity=carol", nil)); err == nil {
+		t.Error("expected error for unknown identity")
+	}
+
+	refreshed, err := cb.Refresh(context.Background(), connector.Scopes{}, connector.Identity{UserID: "1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if refreshed.Email != "alice@example.com" {
+		t.Errorf("unexpected refreshed identity %+v", refreshed)
+	}
+}
+
+func TestPasswordIdentities(t *testing.T) {
+	c := &PasswordConfig{Identities: testIdentities}
+	conn, err := c.Open("mock", slog.New(slog.DiscardHandler))
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := conn.(connector.PasswordConnector)
+
+	identity, valid, err := p.Login(context.Background(), connector.Scopes{}, "bob", "bob-pw")
+	if err != nil || !valid {
+		t.Fatalf("expected valid login, got %t, %v", valid, err)
+	}
+	if identity.Groups[0] != "users" {
+		t.Errorf("unexpected identity %+v", identity)
+	}
+
+	if _, valid, _ := p.Login(context.Background(), connector.Scopes{}, "bob", "alice-pw"); valid {
+		t.Error("expected invalid password")
+	}
+}
+
+func TestFailureInjection(t *testing.T) {
+	c := &CallbackConfig{Failures: Failures{RefreshError: "upstream unavailable", Delay: "10ms"}}
+	conn, err := c.Open("mock", slog.New(slog.DiscardHandler))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cb := conn.(*Callback)
+
+	start := time.Now()
+	if _, err := cb.HandleCallback(connector.Scopes{}, nil, httptest.NewRequest("GET", "/callback", nil)); err != nil {
+		t.Fatal(err)
+	}
+	if time.Since(start) < 10*time.Millisecond {
+		t.Error("expected login to be delayed")
+	}
+
+	_, err = cb.Refresh(context.Background(), connector.Scopes{}, cb.Identity)
+	if err == nil || err.Error() != "upstream unavailable" {
+		t.Errorf("expected refresh error, got %v", err)
+	}
+
+	// Waiting is cut short when the request is canceled.
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := cb.Refresh(ctx, connector.Scopes{}, cb.Identity); err != context.Canceled {
+		t.Errorf("expected context.Canceled, got %v", err)
+	}
+
+	c.Failures.Delay = "soon"
+	if _, err := c.Open("mock", slog.New(slog.DiscardHandler)); err == nil {
+		t.Error("expected error for invalid delay")
+	}
+}
@@ -0,0 +1,193 @@
+package oidc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/coreos/go-oidc/v3/oidc"
+	"golang.org/x/oauth2"
+
+	"github.com/dexidp/dex/connector"
+)
+
+// KeycloakConfig holds configuration options for logins through Keycloak.
+//
+// It accepts all options of the generic OpenID Connect connector, and
+// additionally maps the user's realm and client roles, which Keycloak puts
+// in the "realm_access" and "resource_access" claims, to groups.
+type KeycloakConfig struct {
+	Config
+
+	Roles KeycloakRoles `json:"roles"`
+}
+
+// KeycloakRoles configures which Keycloak roles are mapped to groups.
+type KeycloakRoles struct {
+	// Realm maps the user's realm roles to groups. Defaults to true.
+	Realm *bool `json:"realm"`
+
+	// RealmPrefix is prepended to the names of realm roles.
+	RealmPrefix string `json:"realmPrefix"`
+
+	// Clients whose roles are mapped to groups, named "<client>:<role>".
+	// "*" maps the roles of every client. Defaults to the connector's own
+	// client ID.
+	Clients []string `json:"clients"`
+
+	// Filter is a regular expression the group names of the roles must match.
+	Filter string `json:"filter"`
+
+	// IncludeDefaultRoles keeps the roles Keycloak grants to every user,
+	// like "offline_access" and "default-roles-<realm>", which are left out
+	// by default.
+	IncludeDefaultRoles bool `json:"includeDefaultRoles"`
+}
+
+// Roles Keycloak grants to every user of a realm by default.
+var (
+	keycloakDefaultRealmRoles  = []string{"offline_access", "uma_authorization"}
+	keycloakDefaultClientRoles = map[string][]string{
+		"account": {"manage-account", "manage-account-links", "view-profile"},
+	}
+)
+
+// keycloakRoles maps the roles in Keycloak's tokens to groups.
+type keycloakRoles struct {
+	realm       bool
+	realmPrefix string
+	clients     []string
+	filter      *regexp.Regexp
+	defaults    bool
+
+	// verifies access tokens, whose audience isn't dex's client
+	verifier  *oidc.IDTokenVerifier
+	clockSkew time.Duration
+}
+
+// Open returns a connector which can be used to login users through Keycloak.
+func (c *KeycloakConfig) Open(id string, logger *slog.Logger) (connector.Connector, error) {
+	roles := &keycloakRoles{
+		realm:       c.Roles.Realm == nil || *c.Roles.Realm,
+		realmPrefix: c.Roles.RealmPrefix,
+		clients:     c.Roles.Clients,
+		defaults:    c.Roles.IncludeDefaultRoles,
+	}
+	if len(roles.clients) == 0 {
+		roles.clients = []string{c.ClientID}
+	}
+	if c.Roles.Filter != "" {
+		filter, err := regexp.Compile(c.Roles.Filter)
+		if err != nil {
+			return nil, fmt.Errorf("keycloak: invalid roles filter: %v", err)
+		}
+		roles.filter = filter
+	}
+
+	conn, err := c.Config.Open(id, logger)
+	if err != nil {
+		return nil, err
+	}
+	oidcConn := conn.(*oidcConnector)
+	oidcConn.logger = logger.With(slog.Group("connector", "type", "keycloak", "id", id))
+	roles.verifier = oidcConn.provider.Verifier(&oidc.Config{SkipClientIDCheck: true, SkipExpiryCheck: true})
+	roles.clockSkew = oidcConn.clockSkew
+	oidcConn.keycloakRoles = roles
+	return oidcConn, nil
+}
+
+// groups returns the groups for the roles in claims. Keycloak only adds roles
+// to the ID token if configured to, so if they're missing there, they're read
+// from the access token instead.
+func (k *keycloakRoles) groups(ctx context.Context, claims map[string]interface{}, token *oauth2.Token) ([]string, error) {
+	_, hasRealm := claims["realm_access"]
+	_, hasClients := claims["resource_access"]
+	if !hasRealm && !hasClients && token.AccessToken != "" {
+		accessToken, err := verify(ctx, k.verifier, token.AccessToken, k.clockSkew)
+		if err != nil {
+			return nil, fmt.Errorf("failed to verify access token: %v", err)
+		}
+		claims = nil
+		if err := accessToken.Claims(&claims); err != nil {
+			return nil, fmt.Errorf("failed to decode access token claims: %v", err)
+		}
+	}
+
+	var groups []string
+	if k.realm {
+		roles, err := keycloakRoleNames(claims["realm_access"])
+		if err != nil {
+			return nil, fmt.Errorf("malformed \"realm_access\" claim: %v", err)
+		}
+		for _, role := range roles {
+			if !k.defaults && (slices.Contains(keycloakDefaultRealmRoles, role) || strings.HasPrefix(role, "default-roles-")) {
+				continue
+			}
+			groups = k.appendGroup(groups, k.realmPrefix+role)
+		}
+	}
+
+	resourceAccess, _ := claims["resource_access"].(map[string]interface{})
+	clients := k.clients
+	if slices.Contains(clients, "*") {
+		clients = make([]string, 0, len(resourceAccess))
+		for client := range resourceAccess {
+			clients = append(clients, client)
+		}
+		sort.Strings(clients)
+	}
+	for _, client := range clients {
+		roles, err := keycloakRoleNames(resourceAccess[client])
+		if err != nil {
+			return nil, fmt.Errorf("malformed \"resource_access\" claim for client %q: %v", client, err)
+		}
+		for _, role := range roles {
+			if !k.defaults && slices.Contains(keycloakDefaultClientRoles[client], role) {
+				continue
+			}
+			groups = k.appendGroup(groups, client+":"+role)
+		}
+	}
+	return groups, nil
+}
+
+func (k *keycloakRoles) appendGroup(groups []string, group string) []string {
+	if k.filter != nil && !k.filter.MatchString(group) {
+		return groups
+	}
+	return append(groups, group)
+}
+
+// keycloakRoleNames returns the roles of an access object, which has the form
+// {"roles": ["role-a", "role-b"]}.
+func keycloakRoleNames(access interface{}) ([]string, error) {
+	if access == nil {
+		return nil, nil
+	}
+	obj, ok := access.(map[string]interface{})
+	if !ok {
+		return nil, errors.New("not an object")
+	}
+	vs, ok := obj["roles"].([]interface{})
+	if !ok {
+		if obj["roles"] == nil {
+			return nil, nil
+		}
+		return nil, errors.New("roles is not an array")
+	}
+	roles := make([]string, 0, len(vs))
+	for _, v := range vs {
+		role, ok := v.(string)
+		if !ok {
+			return nil, errors.New("role is not a string")
+		}
+		roles = append(roles, role)
+	}
+	return roles, nil
+}
@@ -0,0 +1,129 @@
+package oidc
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"testing"
+
+	"github.com/dexidp/dex/connector"
+)
+
+var keycloakToken = map[string]interface{}{
+	"sub":            "subvalue",
+	"name":           "namevalue",
+	"email":          "emailvalue",
+	"email_verified": true,
+	"realm_access": map[string]interface{}{
+		"roles": []string{"default-roles-acme", "offline_access", "uma_authorization", "admin"},
+	},
+	"resource_access": map[string]interface{}{
+		"clientID": map[string]interface{}{"roles": []string{"editor"}},
+		"other":    map[string]interface{}{"roles": []string{"viewer"}},
+		"account":  map[string]interface{}{"roles": []string{"manage-account", "view-profile"}},
+	},
+}
+
+func TestKeycloakRoles(t *testing.T) {
+	realmRoles := false
+	tests := []struct {
+		name         string
+		roles        KeycloakRoles
+		expectGroups []string
+	}{
+		{
+			name:         "defaults",
+			expectGroups: []string{"admin", "clientID:editor"},
+		},
+		{
+			name:         "all clients with prefix",
+			roles:        KeycloakRoles{RealmPrefix: "realm:", Clients: []string{"*"}},
+			expectGroups: []string{"realm:admin", "clientID:editor", "other:viewer"},
+		},
+		{
+			name:         "client roles only",
+			roles:        KeycloakRoles{Realm: &realmRoles, Clients: []string{"other", "missing"}},
+			expectGroups: []string{"other:viewer"},
+		},
+		{
+			name:         "default roles included and filtered",
+			roles:        KeycloakRoles{Clients: []string{"account"}, IncludeDefaultRoles: true, Filter: "^(offline_access|account:.*)$"},
+			expectGroups: []string{"offline_access", "account:manage-account", "account:view-profile"},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			testServer, err := setupServer(keycloakToken, true)
+			if err != nil {
+				t.Fatal("failed to setup test server", err)
+			}
+			defer testServer.Close()
+
+			conn, err := newKeycloakConnector(testServer.URL, tc.roles)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			req, err := newRequestWithAuthCode(testServer.URL, "someCode")
+			if err != nil {
+				t.Fatal("failed to create request", err)
+			}
+
+			identity, err := conn.HandleCallback(connector.Scopes{Groups: true}, nil, req)
+			if err != nil {
+				t.Fatal("handle callback failed", err)
+			}
+			expectEquals(t, identity.Groups, tc.expectGroups)
+		})
+	}
+}
+
+func TestKeycloakRolesFromAccessToken(t *testing.T) {
+	testServer, err := setupServer(keycloakToken, false)
+	if err != nil {
+		t.Fatal("failed to setup test server", err)
+	}
+	defer testServer.Close()
+
+	conn, err := newKeycloakConnector(testServer.URL, KeycloakRoles{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	token, err := conn.oauth2Config.Exchange(context.Background(), "someCode")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The ID token claims don't hold the roles, so the access token is used.
+	groups, err := conn.keycloakRoles.groups(context.Background(), map[string]interface{}{"sub": "subvalue"}, token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectEquals(t, groups, []string{"admin", "clientID:editor"})
+
+	token.AccessToken = "not-a-jwt"
+	if _, err := conn.keycloakRoles.groups(context.Background(), map[string]interface{}{}, token); err == nil {
+		t.Error("expected error for an access token which can't be verified")
+	}
+}
+
+func newKeycloakConnector(serverURL string, roles KeycloakRoles) (*oidcConnector, error) {
+	basicAuth := true
+	config := KeycloakConfig{
+		Config: Config{
+			Issuer:               serverURL,
+			ClientID:             "clientID",
+			ClientSecret:         "clientSecret",
+			RedirectURI:          fmt.Sprintf("%s/callback", serverURL),
+			BasicAuthUnsupported: &basicAuth,
+		},
+		Roles: roles,
+	}
+	conn, err := config.Open("id", slog.New(slog.DiscardHandler))
+	if err != nil {
+		return nil, fmt.Errorf("unable to open: %v", err)
+	}
+	return conn.(*oidcConnector), nil
+}
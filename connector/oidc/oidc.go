@@ -86,6 +86,10 @@ type Config struct {
 	// Disable certificate verification
 	InsecureSkipVerify bool `json:"insecureSkipVerify"`
 
+	// ClockSkew is the time tokens of the provider are still accepted after
+	// they expired, for providers with skewed clocks, e.g. "2m".
+	ClockSkew string `json:"clockSkew"`
+
 	// GetUserInfo uses the userinfo endpoint to get additional claims for
 	// the token. This is especially useful where upstreams return "thin"
 	// id tokens
@@ -124,6 +128,14 @@ type Config struct {
 		FilterGroupClaims  FilterGroupClaims    `json:"filterGroupClaims"`
 		ModifyGroupNames   ModifyGroupNames     `json:"modifyGroupNames"`
 	} `json:"claimModifications"`
+
+	httpClients connector.HTTPClientFactory
+}
+
+// SetHTTPClientFactory makes the connector call the provider with clients
+// from the server.
+func (c *Config) SetHTTPClientFactory(f connector.HTTPClientFactory) {
+	c.httpClients = f
 }
 
 type ProviderDiscoveryOverrides struct {
@@ -271,7 +283,19 @@ func (c *Config) Open(id string, logger *slog.Logger) (conn connector.Connector,
 		return nil, fmt.Errorf("support for the Hosted domains option had been deprecated and removed, consider switching to the Google connector")
 	}
 
-	httpClient, err := httpclient.NewHTTPClient(c.RootCAs, c.InsecureSkipVerify)
+	var clockSkew time.Duration
+	if c.ClockSkew != "" {
+		if clockSkew, err = time.ParseDuration(c.ClockSkew); err != nil {
+			return nil, fmt.Errorf("oidc: invalid clockSkew %q: %v", c.ClockSkew, err)
+		}
+	}
+
+	var httpClient *http.Client
+	if c.httpClients != nil {
+		httpClient, err = c.httpClients.HTTPClient(c.RootCAs, c.InsecureSkipVerify)
+	} else {
+		httpClient, err = httpclient.NewHTTPClient(c.RootCAs, c.InsecureSkipVerify)
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -373,8 +397,10 @@ func (c *Config) Open(id string, logger *slog.Logger) (conn connector.Connector,
 		},
 		verifier: provider.VerifierContext(
 			ctx, // Pass our ctx with customized http.Client
-			&oidc.Config{ClientID: clientID},
+			// The expiry is checked by verify, tolerating the clock skew.
+			&oidc.Config{ClientID: clientID, SkipExpiryCheck: true},
 		),
+		clockSkew:                 clockSkew,
 		logger:                    logger.With(slog.Group("connector", "type", "oidc", "id", id)),
 		cancel:                    cancel,
 		httpClient:                httpClient,
@@ -404,6 +430,7 @@ var (
 	_ connector.RefreshConnector        = (*oidcConnector)(nil)
 	_ connector.TokenIdentityConnector  = (*oidcConnector)(nil)
 	_ connector.LogoutCallbackConnector = (*oidcConnector)(nil)
+	_ connector.Describer               = (*oidcConnector)(nil)
 )
 
 type oidcConnector struct {
@@ -411,6 +438,7 @@ type oidcConnector struct {
 	redirectURI               string
 	oauth2Config              *oauth2.Config
 	verifier                  *oidc.IDTokenVerifier
+	clockSkew                 time.Duration
 	cancel                    context.CancelFunc
 	logger                    *slog.Logger
 	httpClient                *http.Client
@@ -432,6 +460,20 @@ type oidcConnector struct {
 	groupsSuffix              string
 	pkceChallenge             string
 	endSessionURL             string
+	keycloakRoles             *keycloakRoles
+}
+
+// verify verifies a token with a verifier skipping the expiry check, and
+// checks the expiry tolerating the clock skew.
+func verify(ctx context.Context, verifier *oidc.IDTokenVerifier, rawToken string, clockSkew time.Duration) (*oidc.IDToken, error) {
+	token, err := verifier.Verify(ctx, rawToken)
+	if err != nil {
+		return nil, err
+	}
+	if time.Now().After(token.Expiry.Add(clockSkew)) {
+		return nil, &oidc.TokenExpiredError{Expiry: token.Expiry}
+	}
+	return token, nil
 }
 
 func (c *oidcConnector) Close() error {
@@ -439,6 +481,20 @@ func (c *oidcConnector) Close() error {
 	return nil
 }
 
+func (c *oidcConnector) Describe() connector.Description {
+	endpoints := map[string]string{
+		"authorization": c.oauth2Config.Endpoint.AuthURL,
+		"token":         c.oauth2Config.Endpoint.TokenURL,
+	}
+	if userInfo := c.provider.UserInfoEndpoint(); userInfo != "" {
+		endpoints["userinfo"] = userInfo
+	}
+	if c.endSessionURL != "" {
+		endpoints["end_session"] = c.endSessionURL
+	}
+	return connector.Description{Endpoints: endpoints, Scopes: c.oauth2Config.Scopes}
+}
+
 func (c *oidcConnector) LoginURL(s connector.Scopes, callbackURL, state string) (string, []byte, error) {
 	if c.redirectURI != callbackURL {
 		return "", nil, fmt.Errorf("expected callback URL %q did not match the URL in the config %q", callbackURL, c.redirectURI)
@@ -560,7 +616,7 @@ func (c *oidcConnector) createIdentity(ctx context.Context, identity connector.I
 	var claims map[string]interface{}
 
 	if rawIDToken, ok := token.Extra("id_token").(string); ok {
-		idToken, err := c.verifier.Verify(ctx, rawIDToken)
+		idToken, err := verify(ctx, c.verifier, rawIDToken, c.clockSkew)
 		if err != nil {
 			return identity, fmt.Errorf("oidc: failed to verify ID Token: %v", err)
 		}
@@ -572,7 +628,8 @@ func (c *oidcConnector) createIdentity(ctx context.Context, identity connector.I
 		switch token.TokenType {
 		case "urn:ietf:params:oauth:token-type:id_token":
 			// Verify only works on ID tokens
-			idToken, err := c.provider.Verifier(&oidc.Config{SkipClientIDCheck: true}).Verify(ctx, token.AccessToken)
+			verifier := c.provider.Verifier(&oidc.Config{SkipClientIDCheck: true, SkipExpiryCheck: true})
+			idToken, err := verify(ctx, verifier, token.AccessToken, c.clockSkew)
 			if err != nil {
 				return identity, fmt.Errorf("oidc: failed to verify token: %v", err)
 			}
@@ -688,18 +745,26 @@ func (c *oidcConnector) createIdentity(ctx context.Context, identity connector.I
 				}
 			}
 		}
+	}
 
-		// Validate that the user is part of allowedGroups
-		if len(c.allowedGroups) > 0 {
-			groupMatches := groups_pkg.Filter(groups, c.allowedGroups)
+	if c.keycloakRoles != nil {
+		roles, err := c.keycloakRoles.groups(ctx, claims, token)
+		if err != nil {
+			return identity, fmt.Errorf("keycloak: %v", err)
+		}
+		groups = append(groups, roles...)
+	}
 
-			if len(groupMatches) == 0 {
-				// No group membership matches found, disallowing
-				return identity, fmt.Errorf("user not a member of allowed groups")
-			}
+	// Validate that the user is part of allowedGroups
+	if (c.insecureEnableGroups || c.keycloakRoles != nil) && len(c.allowedGroups) > 0 {
+		groupMatches := groups_pkg.Filter(groups, c.allowedGroups)
 
-			groups = groupMatches
+		if len(groupMatches) == 0 {
+			// No group membership matches found, disallowing
+			return identity, fmt.Errorf("user not a member of allowed groups")
 		}
+
+		groups = groupMatches
 	}
 
 	// add prefix/suffix to groups
@@ -750,6 +750,74 @@ func TestTokenIdentity(t *testing.T) {
 	}
 }
 
+func TestClockSkew(t *testing.T) {
+	tokenTypeID := "urn:ietf:params:oauth:token-type:id_token"
+
+	tests := []struct {
+		name        string
+		clockSkew   string
+		expectError bool
+	}{
+		{
+			name:        "expired token",
+			expectError: true,
+		}, {
+			name:      "expired token within clock skew",
+			clockSkew: "2m",
+		}, {
+			name:        "expired token beyond clock skew",
+			clockSkew:   "30s",
+			expectError: true,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			testServer, err := setupServer(map[string]any{
+				"sub":  "subvalue",
+				"name": "namevalue",
+				"exp":  time.Now().Add(-time.Minute).Unix(),
+			}, true)
+			if err != nil {
+				t.Fatal("failed to setup test server", err)
+			}
+			defer testServer.Close()
+			conn, err := newConnector(Config{
+				Issuer:    testServer.URL,
+				Scopes:    []string{"openid"},
+				ClockSkew: tc.clockSkew,
+			})
+			if err != nil {
+				t.Fatal("failed to create new connector", err)
+			}
+
+			res, err := http.Get(testServer.URL + "/token")
+			if err != nil {
+				t.Fatal("failed to get initial token", err)
+			}
+			defer res.Body.Close()
+			var tokenResponse map[string]any
+			if err := json.NewDecoder(res.Body).Decode(&tokenResponse); err != nil {
+				t.Fatal("failed to decode initial token", err)
+			}
+
+			_, err = conn.TokenIdentity(t.Context(), tokenTypeID, tokenResponse["id_token"].(string))
+			if tc.expectError {
+				if err == nil || !strings.Contains(err.Error(), "token is expired") {
+					t.Fatalf("expected token expired error, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal("failed to get token identity", err)
+			}
+		})
+	}
+
+	if _, err := newConnector(Config{ClockSkew: "two minutes"}); err == nil {
+		t.Error("expected error for invalid clock skew")
+	}
+}
+
 func TestPromptType(t *testing.T) {
 	pointer := func(s string) *string {
 		return &s
@@ -835,6 +903,34 @@ func TestProviderOverride(t *testing.T) {
 	})
 }
 
+func TestDescribe(t *testing.T) {
+	testServer, err := setupServer(map[string]any{"sub": "subvalue"}, true)
+	if err != nil {
+		t.Fatal("failed to setup test server", err)
+	}
+	defer testServer.Close()
+
+	conn, err := newConnector(Config{
+		Issuer: testServer.URL,
+		Scopes: []string{"groups"},
+	})
+	if err != nil {
+		t.Fatal("failed to create new connector", err)
+	}
+
+	want := connector.Description{
+		Endpoints: map[string]string{
+			"authorization": testServer.URL + "/authorize",
+			"token":         testServer.URL + "/token",
+			"userinfo":      testServer.URL + "/userinfo",
+		},
+		Scopes: []string{"openid", "groups"},
+	}
+	if got := conn.Describe(); !reflect.DeepEqual(got, want) {
+		t.Errorf("expected description %v, got %v", want, got)
+	}
+}
+
 func setupServer(tok map[string]interface{}, idTokenDesired bool) (*httptest.Server, error) {
 	key, err := rsa.GenerateKey(rand.Reader, 1024)
 	if err != nil {
@@ -864,7 +960,9 @@ func setupServer(tok map[string]interface{}, idTokenDesired bool) (*httptest.Ser
 	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
 		url := fmt.Sprintf("http://%s", r.Host)
 		tok["iss"] = url
-		tok["exp"] = time.Now().Add(time.Hour).Unix()
+		if _, ok := tok["exp"]; !ok {
+			tok["exp"] = time.Now().Add(time.Hour).Unix()
+		}
 		tok["aud"] = "clientID"
 		token, err := newToken(&jwk, tok)
 		if err != nil {
@@ -31,6 +31,14 @@ type Config struct {
 	Groups       []string `json:"groups"`
 	InsecureCA   bool     `json:"insecureCA"`
 	RootCA       string   `json:"rootCA"`
+
+	httpClients connector.HTTPClientFactory
+}
+
+// SetHTTPClientFactory makes the connector call OpenShift with clients from
+// the server.
+func (c *Config) SetHTTPClientFactory(f connector.HTTPClientFactory) {
+	c.httpClients = f
 }
 
 var (
@@ -68,7 +76,12 @@ func (c *Config) Open(id string, logger *slog.Logger) (conn connector.Connector,
 		rootCAs = append(rootCAs, c.RootCA)
 	}
 
-	httpClient, err := httpclient.NewHTTPClient(rootCAs, c.InsecureCA)
+	var httpClient *http.Client
+	if c.httpClients != nil {
+		httpClient, err = c.httpClients.HTTPClient(rootCAs, c.InsecureCA)
+	} else {
+		httpClient, err = httpclient.NewHTTPClient(rootCAs, c.InsecureCA)
+	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to create HTTP client: %w", err)
 	}
@@ -0,0 +1,217 @@
+package radius
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/md5"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+// Packet codes, see RFC 2865 section 3.
+const (
+	codeAccessRequest   = 1
+	codeAccessAccept    = 2
+	codeAccessReject    = 3
+	codeAccessChallenge = 11
+)
+
+// Attribute types, see RFC 2865 section 5 and RFC 3579 section 3.2.
+const (
+	attrUserName             = 1
+	attrUserPassword         = 2
+	attrFilterID             = 11
+	attrReplyMessage         = 18
+	attrClass                = 25
+	attrNASIdentifier        = 32
+	attrMessageAuthenticator = 80
+)
+
+const (
+	headerLen        = 20
+	authenticatorLen = 16
+	maxPasswordLen   = 128
+	maxPacketLen     = 4096
+)
+
+type attribute struct {
+	typ   byte
+	value []byte
+}
+
+// packet is a RADIUS packet. Authenticator is the request authenticator of
+// requests and the response authenticator of responses.
+type packet struct {
+	code          byte
+	identifier    byte
+	authenticator [authenticatorLen]byte
+	attributes    []attribute
+}
+
+func (p *packet) get(typ byte) []byte {
+	for _, a := range p.attributes {
+		if a.typ == typ {
+			return a.value
+		}
+	}
+	return nil
+}
+
+func (p *packet) all(typ byte) [][]byte {
+	var values [][]byte
+	for _, a := range p.attributes {
+		if a.typ == typ {
+			values = append(values, a.value)
+		}
+	}
+	return values
+}
+
+// newAccessRequest returns an Access-Request with a random identifier and
+// request authenticator, and the password hidden as described in RFC 2865
+// section 5.2.
+func newAccessRequest(secret []byte, username, password, nasIdentifier string) (*packet, error) {
+	if len(password) > maxPasswordLen {
+		return nil, fmt.Errorf("password longer than %d bytes", maxPasswordLen)
+	}
+	p := &packet{code: codeAccessRequest}
+	var id [1]byte
+	if _, err := rand.Read(id[:]); err != nil {
+		return nil, err
+	}
+	p.identifier = id[0]
+	if _, err := rand.Read(p.authenticator[:]); err != nil {
+		return nil, err
+	}
+	p.attributes = []attribute{
+		{attrUserName, []byte(username)},
+		{attrUserPassword, hidePassword(secret, p.authenticator[:], []byte(password))},
+		{attrNASIdentifier, []byte(nasIdentifier)},
+		// Protects against forged responses (BlastRADIUS), see RFC 3579
+		// section 3.2. Filled in by encode.
+		{attrMessageAuthenticator, make([]byte, md5.Size)},
+	}
+	return p, nil
+}
+
+// hidePassword pads the password to a multiple of 16 bytes and XORs each
+// block with the MD5 of the secret and the previous block, starting with the
+// request authenticator.
+func hidePassword(secret, authenticator, password []byte) []byte {
+	padded := make([]byte, max(16, (len(password)+15)/16*16))
+	copy(padded, password)
+	prev := authenticator
+	for i := 0; i < len(padded); i += 16 {
+		h := md5.Sum(append(append([]byte{}, secret...), prev...))
+		for j := range 16 {
+			padded[i+j] ^= h[j]
+		}
+		prev = padded[i : i+16]
+	}
+	return padded
+}
+
+// encode serializes a request, computing its Message-Authenticator.
+func (p *packet) encode(secret []byte) ([]byte, error) {
+	b, err := p.marshal()
+	if err != nil {
+		return nil, err
+	}
+	if off := messageAuthenticatorOffset(b); off >= 0 {
+		mac := hmac.New(md5.New, secret)
+		mac.Write(b)
+		copy(b[off:], mac.Sum(nil))
+	}
+	return b, nil
+}
+
+func (p *packet) marshal() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.Write([]byte{p.code, p.identifier, 0, 0})
+	buf.Write(p.authenticator[:])
+	for _, a := range p.attributes {
+		if len(a.value) > 253 {
+			return nil, fmt.Errorf("attribute %d longer than 253 bytes", a.typ)
+		}
+		buf.Write([]byte{a.typ, byte(len(a.value) + 2)})
+		buf.Write(a.value)
+	}
+	b := buf.Bytes()
+	if len(b) > maxPacketLen {
+		return nil, errors.New("packet too long")
+	}
+	binary.BigEndian.PutUint16(b[2:4], uint16(len(b)))
+	return b, nil
+}
+
+// messageAuthenticatorOffset returns the offset of the value of the
+// Message-Authenticator attribute in an encoded packet, or -1.
+func messageAuthenticatorOffset(b []byte) int {
+	for i := headerLen; i+2 <= len(b); i += int(b[i+1]) {
+		if b[i+1] < 2 {
+			return -1
+		}
+		if b[i] == attrMessageAuthenticator && b[i+1] == 2+md5.Size {
+			return i + 2
+		}
+	}
+	return -1
+}
+
+func decode(b []byte) (*packet, error) {
+	if len(b) < headerLen {
+		return nil, errors.New("packet too short")
+	}
+	length := int(binary.BigEndian.Uint16(b[2:4]))
+	if length < headerLen || length > len(b) {
+		return nil, fmt.Errorf("invalid packet length %d", length)
+	}
+	b = b[:length]
+	p := &packet{code: b[0], identifier: b[1]}
+	copy(p.authenticator[:], b[4:headerLen])
+	for i := headerLen; i < len(b); {
+		if i+2 > len(b) || b[i+1] < 2 || i+int(b[i+1]) > len(b) {
+			return nil, errors.New("invalid attribute length")
+		}
+		p.attributes = append(p.attributes, attribute{b[i], b[i+2 : i+int(b[i+1])]})
+		i += int(b[i+1])
+	}
+	return p, nil
+}
+
+// verifyResponse checks the response authenticator and, if present, the
+// Message-Authenticator of a response to a request with the given request
+// authenticator. raw is the encoded response.
+func verifyResponse(raw []byte, secret, requestAuthenticator []byte, requireMessageAuthenticator bool) error {
+	length := int(binary.BigEndian.Uint16(raw[2:4]))
+	raw = raw[:length]
+
+	h := md5.New()
+	h.Write(raw[:4])
+	h.Write(requestAuthenticator)
+	h.Write(raw[headerLen:])
+	h.Write(secret)
+	if !hmac.Equal(h.Sum(nil), raw[4:headerLen]) {
+		return errors.New("invalid response authenticator, is the shared secret correct?")
+	}
+
+	off := messageAuthenticatorOffset(raw)
+	if off < 0 {
+		if requireMessageAuthenticator {
+			return errors.New("response has no Message-Authenticator")
+		}
+		return nil
+	}
+	b := append([]byte{}, raw...)
+	copy(b[4:headerLen], requestAuthenticator)
+	got := append([]byte{}, b[off:off+md5.Size]...)
+	clear(b[off : off+md5.Size])
+	mac := hmac.New(md5.New, secret)
+	mac.Write(b)
+	if !hmac.Equal(mac.Sum(nil), got) {
+		return errors.New("invalid Message-Authenticator")
+	}
+	return nil
+}
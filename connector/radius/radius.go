@@ -0,0 +1,277 @@
+// Package radius implements a connector which checks passwords against a
+// RADIUS server, e.g. an OTP appliance.
+package radius
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/dexidp/dex/connector"
+)
+
+// Config holds the configuration of a RADIUS connector. Users are
+// authenticated with a PAP Access-Request. For one-time passwords users
+// append the code to their password, or enter it as the password, as the
+// RADIUS server expects. Access-Challenge responses are not supported.
+//
+// An example config:
+//
+//	type: radius
+//	config:
+//	  server: otp.example.com:1812
+//	  failoverServers:
+//	  - otp2.example.com:1812
+//	  secret: $RADIUS_SECRET
+//	  timeout: 3s
+//	  retries: 2
+//	  emailSuffix: example.com
+//	  groupsAttribute: class
+//	  usernamePrompt: Username and OTP
+type Config struct {
+	// Server is the host and port of the RADIUS server. The port defaults to
+	// 1812.
+	Server string `json:"server"`
+	// FailoverServers are tried in order when Server doesn't respond.
+	FailoverServers []string `json:"failoverServers"`
+
+	// Secret shared with the RADIUS server.
+	Secret string `json:"secret"`
+
+	// NASIdentifier identifies dex to the RADIUS server. Defaults to "dex".
+	NASIdentifier string `json:"nasIdentifier"`
+
+	// Timeout of each attempt, e.g. "3s". Defaults to 5s.
+	Timeout string `json:"timeout"`
+	// Retries is the number of times a request is resent to a server which
+	// doesn't respond, before the next server is tried. Defaults to 2.
+	// Set to -1 to never resend.
+	Retries int `json:"retries"`
+
+	// AllowMissingMessageAuthenticator accepts responses without a
+	// Message-Authenticator attribute, for servers which don't send it. Such
+	// responses can be forged by an attacker on the network path.
+	AllowMissingMessageAuthenticator bool `json:"allowMissingMessageAuthenticator"`
+
+	// EmailSuffix makes the email of users "<username>@<emailSuffix>".
+	// RADIUS doesn't provide emails, so they are empty otherwise.
+	EmailSuffix string `json:"emailSuffix"`
+
+	// GroupsAttribute is the attribute of the Access-Accept the groups of the
+	// user are read from, "class" or "filterId". Each value is a group.
+	GroupsAttribute string `json:"groupsAttribute"`
+
+	// UsernamePrompt overrides the label of the username field.
+	UsernamePrompt string `json:"usernamePrompt"`
+}
+
+// Values of Config.GroupsAttribute.
+const (
+	groupsAttributeClass    = "class"
+	groupsAttributeFilterID = "filterId"
+)
+
+const (
+	defaultPort    = "1812"
+	defaultTimeout = 5 * time.Second
+	defaultRetries = 2
+)
+
+// Open returns a connector checking passwords against the RADIUS server.
+func (c *Config) Open(id string, logger *slog.Logger) (connector.Connector, error) {
+	return c.openConnector(logger.With(slog.Group("connector", "type", "radius", "id", id)))
+}
+
+func (c *Config) openConnector(logger *slog.Logger) (*radiusConnector, error) {
+	if c.Server == "" {
+		return nil, errors.New("radius: no server configured")
+	}
+	if c.Secret == "" {
+		return nil, errors.New("radius: no secret configured")
+	}
+
+	var servers []string
+	for _, s := range append([]string{c.Server}, c.FailoverServers...) {
+		if _, _, err := net.SplitHostPort(s); err != nil {
+			s = net.JoinHostPort(s, defaultPort)
+		}
+		servers = append(servers, s)
+	}
+
+	timeout := defaultTimeout
+	if c.Timeout != "" {
+		var err error
+		if timeout, err = time.ParseDuration(c.Timeout); err != nil {
+			return nil, fmt.Errorf("radius: invalid timeout %q: %v", c.Timeout, err)
+		}
+	}
+	retries := c.Retries
+	switch {
+	case retries == 0:
+		retries = defaultRetries
+	case retries < 0:
+		retries = 0
+	}
+
+	var groupsAttr byte
+	switch c.GroupsAttribute {
+	case "":
+	case groupsAttributeClass:
+		groupsAttr = attrClass
+	case groupsAttributeFilterID:
+		groupsAttr = attrFilterID
+	default:
+		return nil, fmt.Errorf("radius: unknown groupsAttribute %q, must be %q or %q", c.GroupsAttribute, groupsAttributeClass, groupsAttributeFilterID)
+	}
+
+	nasIdentifier := c.NASIdentifier
+	if nasIdentifier == "" {
+		nasIdentifier = "dex"
+	}
+
+	return &radiusConnector{
+		servers:        servers,
+		secret:         []byte(c.Secret),
+		nasIdentifier:  nasIdentifier,
+		timeout:        timeout,
+		retries:        retries,
+		requireMsgAuth: !c.AllowMissingMessageAuthenticator,
+		emailSuffix:    c.EmailSuffix,
+		groupsAttr:     groupsAttr,
+		usernamePrompt: c.UsernamePrompt,
+		logger:         logger,
+	}, nil
+}
+
+var _ connector.PasswordConnector = (*radiusConnector)(nil)
+
+type radiusConnector struct {
+	servers        []string
+	secret         []byte
+	nasIdentifier  string
+	timeout        time.Duration
+	retries        int
+	requireMsgAuth bool
+	emailSuffix    string
+	groupsAttr     byte
+	usernamePrompt string
+	logger         *slog.Logger
+}
+
+func (c *radiusConnector) Prompt() string {
+	return c.usernamePrompt
+}
+
+func (c *radiusConnector) Login(ctx context.Context, s connector.Scopes, username, password string) (connector.Identity, bool, error) {
+	if username == "" || password == "" {
+		return connector.Identity{}, false, nil
+	}
+
+	req, err := newAccessRequest(c.secret, username, password, c.nasIdentifier)
+	if err != nil {
+		return connector.Identity{}, false, fmt.Errorf("radius: %v", err)
+	}
+	resp, err := c.exchange(ctx, req)
+	if err != nil {
+		return connector.Identity{}, false, err
+	}
+
+	switch resp.code {
+	case codeAccessAccept:
+	case codeAccessReject:
+		c.logger.InfoContext(ctx, "access rejected", "username", username, "reply_message", string(resp.get(attrReplyMessage)))
+		return connector.Identity{}, false, nil
+	case codeAccessChallenge:
+		c.logger.ErrorContext(ctx, "access challenges are not supported, rejecting login", "username", username)
+		return connector.Identity{}, false, nil
+	default:
+		return connector.Identity{}, false, fmt.Errorf("radius: unexpected response code %d", resp.code)
+	}
+
+	ident := connector.Identity{
+		UserID:   username,
+		Username: username,
+	}
+	if c.emailSuffix != "" {
+		ident.Email = username + "@" + c.emailSuffix
+		ident.EmailVerified = true
+	}
+	if s.Groups && c.groupsAttr != 0 {
+		for _, v := range resp.all(c.groupsAttr) {
+			if group := strings.TrimSpace(string(v)); group != "" {
+				ident.Groups = append(ident.Groups, group)
+			}
+		}
+	}
+	return ident, true, nil
+}
+
+// exchange sends a request to the servers in order, resending it to each
+// server which doesn't respond in time, and returns the first valid response.
+func (c *radiusConnector) exchange(ctx context.Context, req *packet) (*packet, error) {
+	raw, err := req.encode(c.secret)
+	if err != nil {
+		return nil, fmt.Errorf("radius: encode request: %v", err)
+	}
+
+	var errs []error
+	for _, server := range c.servers {
+		resp, err := c.exchangeWith(ctx, server, req, raw)
+		if err == nil {
+			return resp, nil
+		}
+		if ctx.Err() != nil {
+			return nil, fmt.Errorf("radius: %v", ctx.Err())
+		}
+		c.logger.WarnContext(ctx, "RADIUS server failed", "server", server, "err", err)
+		errs = append(errs, fmt.Errorf("%s: %v", server, err))
+	}
+	return nil, fmt.Errorf("radius: no server responded: %v", errors.Join(errs...))
+}
+
+func (c *radiusConnector) exchangeWith(ctx context.Context, server string, req *packet, raw []byte) (*packet, error) {
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "udp", server)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	buf := make([]byte, maxPacketLen)
+	for attempt := 0; attempt <= c.retries; attempt++ {
+		if _, err := conn.Write(raw); err != nil {
+			return nil, err
+		}
+		deadline := time.Now().Add(c.timeout)
+		if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+			deadline = d
+		}
+		conn.SetReadDeadline(deadline)
+
+		for {
+			n, err := conn.Read(buf)
+			if err != nil {
+				var netErr net.Error
+				if errors.As(err, &netErr) && netErr.Timeout() && ctx.Err() == nil {
+					break // resend
+				}
+				return nil, err
+			}
+			resp, err := decode(buf[:n])
+			if err != nil || resp.identifier != req.identifier {
+				// Not the response to this request, keep waiting.
+				continue
+			}
+			if err := verifyResponse(buf[:n], c.secret, req.authenticator[:], c.requireMsgAuth); err != nil {
+				c.logger.WarnContext(ctx, "discarding invalid RADIUS response", "server", server, "err", err)
+				continue
+			}
+			return resp, nil
+		}
+	}
+	return nil, fmt.Errorf("no response after %d attempts", c.retries+1)
+}
@@ -0,0 +1,213 @@
+package radius
+
+import (
+	"crypto/hmac"
+	"crypto/md5"
+	"encoding/binary"
+	"log/slog"
+	"net"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/dexidp/dex/connector"
+)
+
+const testSecret = "s3cret"
+
+// testServer is a RADIUS server accepting the password "pass123456", i.e. a
+// password followed by an OTP.
+type testServer struct {
+	conn      *net.UDPConn
+	secret    []byte
+	drop      atomic.Int32 // number of requests to ignore
+	requests  atomic.Int32
+	noMsgAuth atomic.Bool
+}
+
+func newTestServer(t *testing.T, secret string) *testServer {
+	conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	s := &testServer{conn: conn, secret: []byte(secret)}
+	go s.serve(t)
+	return s
+}
+
+func (s *testServer) addr() string {
+	return s.conn.LocalAddr().String()
+}
+
+func (s *testServer) serve(t *testing.T) {
+	buf := make([]byte, maxPacketLen)
+	for {
+		n, addr, err := s.conn.ReadFromUDP(buf)
+		if err != nil {
+			return
+		}
+		s.requests.Add(1)
+		if s.drop.Add(-1) >= 0 {
+			continue
+		}
+		req, err := decode(buf[:n])
+		if err != nil {
+			t.Errorf("decode request: %v", err)
+			continue
+		}
+		if off := messageAuthenticatorOffset(buf[:n]); off < 0 {
+			t.Errorf("request has no Message-Authenticator")
+		}
+
+		resp := &packet{code: codeAccessReject, identifier: req.identifier}
+		password := unhidePassword(s.secret, req.authenticator[:], req.get(attrUserPassword))
+		if string(req.get(attrUserName)) == "jane" && password == "pass123456" {
+			resp.code = codeAccessAccept
+			resp.attributes = append(resp.attributes,
+				attribute{attrClass, []byte("admins")},
+				attribute{attrClass, []byte("developers")},
+				attribute{attrFilterID, []byte("vpn")},
+			)
+		}
+		if !s.noMsgAuth.Load() {
+			resp.attributes = append(resp.attributes, attribute{attrMessageAuthenticator, make([]byte, md5.Size)})
+		}
+		s.conn.WriteToUDP(encodeResponse(t, resp, req.authenticator[:], s.secret), addr)
+	}
+}
+
+func unhidePassword(secret, authenticator, hidden []byte) string {
+	password := make([]byte, len(hidden))
+	prev := authenticator
+	for i := 0; i+16 <= len(hidden); i += 16 {
+		h := md5.Sum(append(append([]byte{}, secret...), prev...))
+		for j := range 16 {
+			password[i+j] = hidden[i+j] ^ h[j]
+		}
+		prev = hidden[i : i+16]
+	}
+	for len(password) > 0 && password[len(password)-1] == 0 {
+		password = password[:len(password)-1]
+	}
+	return string(password)
+}
+
+func encodeResponse(t *testing.T, p *packet, requestAuthenticator, secret []byte) []byte {
+	p.authenticator = [authenticatorLen]byte(requestAuthenticator)
+	b, err := p.marshal()
+	require.NoError(t, err)
+	if off := messageAuthenticatorOffset(b); off >= 0 {
+		mac := hmac.New(md5.New, secret)
+		mac.Write(b)
+		copy(b[off:], mac.Sum(nil))
+	}
+	h := md5.New()
+	h.Write(b)
+	h.Write(secret)
+	copy(b[4:headerLen], h.Sum(nil))
+	binary.BigEndian.PutUint16(b[2:4], uint16(len(b)))
+	return b
+}
+
+func openTestConnector(t *testing.T, c Config) *radiusConnector {
+	if c.Secret == "" {
+		c.Secret = testSecret
+	}
+	if c.Timeout == "" {
+		c.Timeout = "200ms"
+	}
+	conn, err := c.openConnector(slog.New(slog.DiscardHandler))
+	require.NoError(t, err)
+	return conn
+}
+
+func TestLogin(t *testing.T) {
+	srv := newTestServer(t, testSecret)
+	c := openTestConnector(t, Config{
+		Server:          srv.addr(),
+		EmailSuffix:     "example.com",
+		GroupsAttribute: "class",
+	})
+
+	ident, valid, err := c.Login(t.Context(), connector.Scopes{Groups: true}, "jane", "pass123456")
+	require.NoError(t, err)
+	require.True(t, valid)
+	require.Equal(t, connector.Identity{
+		UserID:        "jane",
+		Username:      "jane",
+		Email:         "jane@example.com",
+		EmailVerified: true,
+		Groups:        []string{"admins", "developers"},
+	}, ident)
+
+	ident, valid, err = c.Login(t.Context(), connector.Scopes{}, "jane", "pass123456")
+	require.NoError(t, err)
+	require.True(t, valid)
+	require.Empty(t, ident.Groups, "groups are only returned for the groups scope")
+
+	_, valid, err = c.Login(t.Context(), connector.Scopes{}, "jane", "pass000000")
+	require.NoError(t, err)
+	require.False(t, valid)
+
+	_, valid, err = c.Login(t.Context(), connector.Scopes{}, "jane", "")
+	require.NoError(t, err)
+	require.False(t, valid)
+}
+
+func TestRetriesAndFailover(t *testing.T) {
+	srv := newTestServer(t, testSecret)
+	srv.drop.Store(2)
+	c := openTestConnector(t, Config{Server: srv.addr(), Retries: 2})
+
+	_, valid, err := c.Login(t.Context(), connector.Scopes{}, "jane", "pass123456")
+	require.NoError(t, err)
+	require.True(t, valid)
+	require.EqualValues(t, 3, srv.requests.Load())
+
+	// The failover server is used when the first one doesn't respond.
+	down := newTestServer(t, testSecret)
+	down.drop.Store(1 << 20)
+	up := newTestServer(t, testSecret)
+	c = openTestConnector(t, Config{Server: down.addr(), FailoverServers: []string{up.addr()}, Retries: -1})
+
+	_, valid, err = c.Login(t.Context(), connector.Scopes{}, "jane", "pass123456")
+	require.NoError(t, err)
+	require.True(t, valid)
+	require.EqualValues(t, 1, down.requests.Load())
+
+	c = openTestConnector(t, Config{Server: down.addr(), Retries: 1})
+	_, _, err = c.Login(t.Context(), connector.Scopes{}, "jane", "pass123456")
+	require.Error(t, err)
+}
+
+func TestResponseVerification(t *testing.T) {
+	// Responses signed with another secret are discarded.
+	srv := newTestServer(t, "other")
+	c := openTestConnector(t, Config{Server: srv.addr(), Retries: -1})
+	_, _, err := c.Login(t.Context(), connector.Scopes{}, "jane", "pass123456")
+	require.Error(t, err)
+
+	srv = newTestServer(t, testSecret)
+	srv.noMsgAuth.Store(true)
+	c = openTestConnector(t, Config{Server: srv.addr(), Retries: -1})
+	_, _, err = c.Login(t.Context(), connector.Scopes{}, "jane", "pass123456")
+	require.Error(t, err, "responses without Message-Authenticator must be rejected by default")
+
+	c = openTestConnector(t, Config{Server: srv.addr(), Retries: -1, AllowMissingMessageAuthenticator: true})
+	_, valid, err := c.Login(t.Context(), connector.Scopes{}, "jane", "pass123456")
+	require.NoError(t, err)
+	require.True(t, valid)
+}
+
+func TestOpen(t *testing.T) {
+	_, err := (&Config{Secret: testSecret}).openConnector(slog.New(slog.DiscardHandler))
+	require.Error(t, err)
+	_, err = (&Config{Server: "localhost"}).openConnector(slog.New(slog.DiscardHandler))
+	require.Error(t, err)
+	_, err = (&Config{Server: "localhost", Secret: testSecret, GroupsAttribute: "memberOf"}).openConnector(slog.New(slog.DiscardHandler))
+	require.Error(t, err)
+
+	c, err := (&Config{Server: "localhost", Secret: testSecret}).openConnector(slog.New(slog.DiscardHandler))
+	require.NoError(t, err)
+	require.Equal(t, []string{"localhost:1812"}, c.servers)
+}
@@ -4,6 +4,7 @@ package saml
 import (
 	"bytes"
 	"context"
+	"crypto/rsa"
 	"crypto/x509"
 	"encoding/base64"
 	"encoding/json"
@@ -46,8 +47,8 @@ const (
 	// subject confirmation methods
 	subjectConfirmationMethodBearer = "urn:oasis:names:tc:SAML:2.0:cm:bearer"
 
-	// allowed clock drift for timestamp validation
-	allowedClockDrift = time.Duration(30) * time.Second
+	// default allowed clock drift for timestamp validation
+	defaultClockSkew = time.Duration(30) * time.Second
 )
 
 var (
@@ -84,6 +85,19 @@ type Config struct {
 
 	InsecureSkipSignatureValidation bool `json:"insecureSkipSignatureValidation"`
 
+	// PEM encoded key pair of dex as a service provider, as files or raw data.
+	// The key decrypts encrypted assertions and, if SignAuthnRequests is set,
+	// signs authentication requests, in which case the certificate is
+	// required too. The IdP needs the certificate to encrypt assertions and
+	// to verify requests.
+	Key      string `json:"key"`
+	KeyData  []byte `json:"keyData"`
+	Cert     string `json:"cert"`
+	CertData []byte `json:"certData"`
+
+	// SignAuthnRequests signs the AuthnRequests sent to the IdP with Key.
+	SignAuthnRequests bool `json:"signAuthnRequests"`
+
 	// Assertion attribute names to lookup various claims with.
 	UsernameAttr string `json:"usernameAttr"`
 	EmailAttr    string `json:"emailAttr"`
@@ -109,6 +123,47 @@ type Config struct {
 	//		urn:oasis:names:tc:SAML:2.0:nameid-format:persistent
 	//
 	NameIDPolicyFormat string `json:"nameIDPolicyFormat"`
+
+	// ClockSkew is the clock drift allowed when validating the time window
+	// of assertions, e.g. "2m". Defaults to 30s.
+	ClockSkew string `json:"clockSkew"`
+
+	// IdPInitiated accepts unsolicited responses, for IdPs which only
+	// support IdP-initiated SSO. The IdP must post them to the redirect URI,
+	// which has to include the connector ID ("/callback/<id>"). Assertions are
+	// remembered in dex's storage until they expire to reject replays. If the
+	// storage can't hold them, they're remembered in memory, so replays to
+	// other dex instances aren't detected.
+	IdPInitiated *IdPInitiatedConfig `json:"idpInitiated"`
+
+	replays connector.ReplayCache
+}
+
+// SetReplayCache implements connector.ReplayCacheConfig.
+func (c *Config) SetReplayCache(replays connector.ReplayCache) {
+	c.replays = replays
+}
+
+// IdPInitiatedConfig configures IdP-initiated SSO.
+type IdPInitiatedConfig struct {
+	// Targets are the clients users can be logged in to. The IdP selects
+	// one with the RelayState of its response.
+	Targets []IdPInitiatedTarget `json:"targets"`
+}
+
+// IdPInitiatedTarget is a client users can be logged in to by unsolicited
+// responses.
+type IdPInitiatedTarget struct {
+	// RelayState selecting the target. The target without one is used for
+	// responses without a RelayState.
+	RelayState string `json:"relayState"`
+
+	ClientID string `json:"clientID"`
+	// RedirectURI of the client the authorization code is sent to. Defaults
+	// to the client's only redirect URI.
+	RedirectURI string `json:"redirectURI"`
+	// Scopes of the login. Defaults to "openid".
+	Scopes []string `json:"scopes"`
 }
 
 type certStore struct {
@@ -149,11 +204,20 @@ func (c *Config) openConnector(logger *slog.Logger) (*provider, error) {
 		return nil, fmt.Errorf("missing required fields %q", missing)
 	}
 
+	clockSkew := defaultClockSkew
+	if c.ClockSkew != "" {
+		var err error
+		if clockSkew, err = time.ParseDuration(c.ClockSkew); err != nil {
+			return nil, fmt.Errorf("invalid clockSkew %q: %v", c.ClockSkew, err)
+		}
+	}
+
 	p := &provider{
 		entityIssuer:  c.EntityIssuer,
 		ssoIssuer:     c.SSOIssuer,
 		ssoURL:        c.SSOURL,
 		now:           time.Now,
+		clockSkew:     clockSkew,
 		usernameAttr:  c.UsernameAttr,
 		emailAttr:     c.EmailAttr,
 		groupsAttr:    c.GroupsAttr,
@@ -166,6 +230,30 @@ func (c *Config) openConnector(logger *slog.Logger) (*provider, error) {
 		nameIDPolicyFormat: c.NameIDPolicyFormat,
 	}
 
+	if c.IdPInitiated != nil {
+		if len(c.IdPInitiated.Targets) == 0 {
+			return nil, errors.New("idpInitiated: no targets configured")
+		}
+		p.idpTargets = make(map[string]connector.IdPInitiatedTarget, len(c.IdPInitiated.Targets))
+		for _, t := range c.IdPInitiated.Targets {
+			if t.ClientID == "" {
+				return nil, fmt.Errorf("idpInitiated: target %q has no clientID", t.RelayState)
+			}
+			if _, ok := p.idpTargets[t.RelayState]; ok {
+				return nil, fmt.Errorf("idpInitiated: duplicate target for relayState %q", t.RelayState)
+			}
+			p.idpTargets[t.RelayState] = connector.IdPInitiatedTarget{
+				ClientID:    t.ClientID,
+				RedirectURI: t.RedirectURI,
+				Scopes:      t.Scopes,
+			}
+		}
+		p.replays = c.replays
+		if p.replays == nil {
+			p.replays = &replayCache{seen: make(map[string]time.Time), now: func() time.Time { return p.now() }}
+		}
+	}
+
 	if p.nameIDPolicyFormat == "" {
 		p.nameIDPolicyFormat = nameIDFormatPersistent
 	} else {
@@ -229,9 +317,84 @@ func (c *Config) openConnector(logger *slog.Logger) (*provider, error) {
 		}
 		p.validator = dsig.NewDefaultValidationContext(certStore{certs})
 	}
+
+	if err := c.loadKeyPair(p); err != nil {
+		return nil, err
+	}
 	return p, nil
 }
 
+// loadKeyPair loads dex's own key pair, used to decrypt assertions and sign
+// requests.
+func (c *Config) loadKeyPair(p *provider) error {
+	keyData, err := fileOrData(c.Key, c.KeyData)
+	if err != nil {
+		return fmt.Errorf("read key file: %v", err)
+	}
+	certData, err := fileOrData(c.Cert, c.CertData)
+	if err != nil {
+		return fmt.Errorf("read cert file: %v", err)
+	}
+	if keyData == nil {
+		if c.SignAuthnRequests {
+			return errors.New("signAuthnRequests requires 'key' or 'keyData'")
+		}
+		return nil
+	}
+
+	block, _ := pem.Decode(keyData)
+	if block == nil {
+		return errors.New("no PEM data found in key")
+	}
+	var key interface{}
+	if block.Type == "RSA PRIVATE KEY" {
+		key, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	} else {
+		key, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	}
+	if err != nil {
+		return fmt.Errorf("parse key: %v", err)
+	}
+	rsaKey, ok := key.(*rsa.PrivateKey)
+	if !ok {
+		return fmt.Errorf("key must be an RSA key, got %T", key)
+	}
+	p.key = rsaKey
+
+	if !c.SignAuthnRequests {
+		return nil
+	}
+	if certData == nil {
+		return errors.New("signAuthnRequests requires 'cert' or 'certData'")
+	}
+	block, _ = pem.Decode(certData)
+	if block == nil {
+		return errors.New("no PEM data found in cert")
+	}
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return fmt.Errorf("parse cert: %v", err)
+	}
+	if !rsaKey.PublicKey.Equal(cert.PublicKey) {
+		return errors.New("cert does not match key")
+	}
+
+	signer, err := dsig.NewSigningContext(rsaKey, [][]byte{cert.Raw})
+	if err != nil {
+		return err
+	}
+	signer.Canonicalizer = dsig.MakeC14N10ExclusiveCanonicalizerWithPrefixList("")
+	p.signer = signer
+	return nil
+}
+
+func fileOrData(file string, data []byte) ([]byte, error) {
+	if file != "" {
+		return os.ReadFile(file)
+	}
+	return data, nil
+}
+
 var (
 	_ connector.SAMLConnector    = (*provider)(nil)
 	_ connector.RefreshConnector = (*provider)(nil)
@@ -242,11 +405,17 @@ type provider struct {
 	ssoIssuer    string
 	ssoURL       string
 
-	now func() time.Time
+	now       func() time.Time
+	clockSkew time.Duration
 
 	// If nil, don't do signature validation.
 	validator *dsig.ValidationContext
 
+	// If set, decrypt encrypted assertions with key and sign requests with
+	// signer.
+	key    *rsa.PrivateKey
+	signer *dsig.SigningContext
+
 	// Attribute mappings
 	usernameAttr  string
 	emailAttr     string
@@ -259,9 +428,39 @@ type provider struct {
 
 	nameIDPolicyFormat string
 
+	// idpTargets are the clients of unsolicited responses by relay state.
+	// If nil, they are rejected.
+	idpTargets map[string]connector.IdPInitiatedTarget
+	replays    connector.ReplayCache
+
 	logger *slog.Logger
 }
 
+// replayCache remembers the assertions of unsolicited responses in memory
+// until they expire, if the server doesn't provide a connector.ReplayCache.
+type replayCache struct {
+	mu   sync.Mutex
+	seen map[string]time.Time
+	now  func() time.Time
+}
+
+// Use implements connector.ReplayCache.
+func (c *replayCache) Use(_ context.Context, id string, expiry time.Time) error {
+	now := c.now()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for seenID, seenExpiry := range c.seen {
+		if now.After(seenExpiry) {
+			delete(c.seen, seenID)
+		}
+	}
+	if _, ok := c.seen[id]; ok {
+		return errors.New("already used")
+	}
+	c.seen[id] = expiry
+	return nil
+}
+
 // cachedIdentity stores the identity from SAML assertion for refresh token support.
 // Since SAML has no native refresh mechanism, we cache the identity obtained during
 // the initial authentication and return it on subsequent refresh requests.
@@ -314,12 +513,37 @@ func (p *provider) POSTData(s connector.Scopes, id string) (action, value string
 	if err != nil {
 		return "", "", fmt.Errorf("marshal authn request: %v", err)
 	}
+	if p.signer != nil {
+		if data, err = p.signRequest(data); err != nil {
+			return "", "", fmt.Errorf("sign authn request: %v", err)
+		}
+	}
 
 	// See: https://docs.oasis-open.org/security/saml/v2.0/saml-bindings-2.0-os.pdf
 	// "3.5.4 Message Encoding"
 	return p.ssoURL, base64.StdEncoding.EncodeToString(data), nil
 }
 
+// signRequest adds an enveloped signature to a request. The schema requires
+// the signature to directly follow the Issuer element, if there is one.
+func (p *provider) signRequest(data []byte) ([]byte, error) {
+	doc := etree.NewDocument()
+	if err := doc.ReadFromBytes(data); err != nil {
+		return nil, err
+	}
+	root := doc.Root()
+	sig, err := p.signer.ConstructSignature(root, true)
+	if err != nil {
+		return nil, err
+	}
+	index := 0
+	if iss := root.SelectElement("Issuer"); iss != nil {
+		index = iss.Index() + 1
+	}
+	root.InsertChildAt(index, sig)
+	return doc.WriteToBytes()
+}
+
 // HandlePOST interprets a request from a SAML provider attempting to verify a
 // user's identity.
 //
@@ -330,6 +554,27 @@ func (p *provider) POSTData(s connector.Scopes, id string) (action, value string
 // * Verify various parts of the Assertion element. Conditions, audience, etc.
 // * Map the Assertion's attribute elements to user info.
 func (p *provider) HandlePOST(s connector.Scopes, samlResponse, inResponseTo string) (ident connector.Identity, err error) {
+	return p.handlePOST(context.Background(), s, samlResponse, inResponseTo, false)
+}
+
+// IdPInitiatedTarget implements connector.SAMLIdPInitiatedConnector.
+func (p *provider) IdPInitiatedTarget(relayState string) (connector.IdPInitiatedTarget, bool) {
+	t, ok := p.idpTargets[relayState]
+	return t, ok
+}
+
+// HandleUnsolicitedPOST implements connector.SAMLIdPInitiatedConnector. The
+// response is verified like the responses to requests, except that it must
+// not have an InResponseTo value. Its assertion is remembered until it
+// expires, so it can only be used once.
+func (p *provider) HandleUnsolicitedPOST(ctx context.Context, s connector.Scopes, samlResponse string) (ident connector.Identity, err error) {
+	if p.idpTargets == nil {
+		return ident, errors.New("unsolicited responses are not accepted")
+	}
+	return p.handlePOST(ctx, s, samlResponse, "", true)
+}
+
+func (p *provider) handlePOST(ctx context.Context, s connector.Scopes, samlResponse, inResponseTo string, unsolicited bool) (ident connector.Identity, err error) {
 	rawResp, err := base64.StdEncoding.DecodeString(samlResponse)
 	if err != nil {
 		return ident, fmt.Errorf("decode response: %v", err)
@@ -343,10 +588,14 @@ func (p *provider) HandlePOST(s connector.Scopes, samlResponse, inResponseTo str
 	// Root element is allowed to not be signed if the Assertion element is.
 	rootElementSigned := true
 	if p.validator != nil {
-		rawResp, rootElementSigned, err = verifyResponseSig(p.validator, rawResp)
+		rawResp, rootElementSigned, err = verifyResponseSig(p.validator, p.key, rawResp)
 		if err != nil {
 			return ident, fmt.Errorf("verify signature: %v", err)
 		}
+	} else if p.key != nil {
+		if rawResp, err = decryptResponse(p.key, rawResp); err != nil {
+			return ident, err
+		}
 	}
 
 	var resp response
@@ -384,6 +633,9 @@ func (p *provider) HandlePOST(s connector.Scopes, samlResponse, inResponseTo str
 
 	assertion := resp.Assertion
 	if assertion == nil {
+		if resp.EncryptedAssertion != nil {
+			return ident, fmt.Errorf("response contained an encrypted assertion, but no key is configured to decrypt it")
+		}
 		return ident, fmt.Errorf("response did not contain an assertion")
 	}
 
@@ -407,6 +659,12 @@ func (p *provider) HandlePOST(s connector.Scopes, samlResponse, inResponseTo str
 		}
 	}
 
+	if unsolicited {
+		if err = p.useAssertion(ctx, assertion); err != nil {
+			return ident, err
+		}
+	}
+
 	switch {
 	case subject.NameID != nil:
 		if ident.UserID = subject.NameID.Value; ident.UserID == "" {
@@ -572,10 +830,10 @@ func (p *provider) validateSubject(subject *subject, inResponseTo string) error
 			notBefore := time.Time(data.NotBefore)
 			notOnOrAfter := time.Time(data.NotOnOrAfter)
 			now := p.now()
-			if !notBefore.IsZero() && before(now, notBefore) {
+			if !notBefore.IsZero() && p.before(now, notBefore) {
 				return fmt.Errorf("at %s got response that cannot be processed before %s", now, notBefore)
 			}
-			if !notOnOrAfter.IsZero() && after(now, notOnOrAfter) {
+			if !notOnOrAfter.IsZero() && p.after(now, notOnOrAfter) {
 				return fmt.Errorf("at %s got response that cannot be processed because it expired at %s", now, notOnOrAfter)
 			}
 			if r := data.Recipient; r != "" && r != p.redirectURI {
@@ -596,6 +854,32 @@ func (p *provider) validateSubject(subject *subject, inResponseTo string) error
 	return fmt.Errorf("failed to validate subject confirmation: %v", errs)
 }
 
+// useAssertion protects against replays of the assertion of an unsolicited
+// response. Without a request of dex, nothing else ties a response to a single
+// login. It is remembered until the end of its validity, so assertions which
+// don't expire are rejected.
+func (p *provider) useAssertion(ctx context.Context, a *assertion) error {
+	if a.ID == "" {
+		return errors.New("assertion has no ID")
+	}
+	var expiry time.Time
+	if a.Conditions != nil {
+		expiry = time.Time(a.Conditions.NotOnOrAfter)
+	}
+	for _, c := range a.Subject.SubjectConfirmations {
+		if data := c.SubjectConfirmationData; data != nil && time.Time(data.NotOnOrAfter).After(expiry) {
+			expiry = time.Time(data.NotOnOrAfter)
+		}
+	}
+	if expiry.IsZero() {
+		return errors.New("unsolicited responses must have an expiry (NotOnOrAfter)")
+	}
+	if err := p.replays.Use(ctx, a.ID, expiry.Add(p.clockSkew)); err != nil {
+		return fmt.Errorf("assertion %q: %v", a.ID, err)
+	}
+	return nil
+}
+
 // validateConditions ensures that dex is the intended audience
 // for the request, and not another service provider.
 //
@@ -605,12 +889,12 @@ func (p *provider) validateConditions(conditions *conditions) error {
 	// Ensure the conditions haven't expired.
 	now := p.now()
 	notBefore := time.Time(conditions.NotBefore)
-	if !notBefore.IsZero() && before(now, notBefore) {
+	if !notBefore.IsZero() && p.before(now, notBefore) {
 		return fmt.Errorf("at %s got response that cannot be processed before %s", now, notBefore)
 	}
 
 	notOnOrAfter := time.Time(conditions.NotOnOrAfter)
-	if !notOnOrAfter.IsZero() && after(now, notOnOrAfter) {
+	if !notOnOrAfter.IsZero() && p.after(now, notOnOrAfter) {
 		return fmt.Errorf("at %s got response that cannot be processed because it expired at %s", now, notOnOrAfter)
 	}
 
@@ -656,7 +940,11 @@ func (p *provider) validateConditions(conditions *conditions) error {
 //
 // Note: we still don't support multiple <Assertion> tags. If there are
 // multiple present this code will only process the first.
-func verifyResponseSig(validator *dsig.ValidationContext, data []byte) (signed []byte, rootVerified bool, err error) {
+//
+// If key is set, encrypted assertions are decrypted: after verifying the root
+// element, whose signature covers them in encrypted form, or before verifying
+// the assertion, whose signature is part of the encrypted content.
+func verifyResponseSig(validator *dsig.ValidationContext, key *rsa.PrivateKey, data []byte) (signed []byte, rootVerified bool, err error) {
 	doc := etree.NewDocument()
 	if err = doc.ReadFromBytes(data); err != nil {
 		return nil, false, fmt.Errorf("parse document: %v", err)
@@ -669,11 +957,22 @@ func verifyResponseSig(validator *dsig.ValidationContext, data []byte) (signed [
 	transformedResponse, err := validator.Validate(response)
 	if err == nil {
 		// Root element is verified, return it.
+		if key != nil {
+			if err := decryptAssertions(transformedResponse, key); err != nil {
+				return nil, false, err
+			}
+		}
 		doc.SetRoot(transformedResponse)
 		signed, err = doc.WriteToBytes()
 		return signed, true, err
 	}
 
+	if key != nil {
+		if err := decryptAssertions(response, key); err != nil {
+			return nil, false, err
+		}
+	}
+
 	// Ensures xmlns are copied down to the assertion element when they are defined in the root
 	//
 	// TODO: Only select from child elements of the root.
@@ -700,14 +999,30 @@ func verifyResponseSig(validator *dsig.ValidationContext, data []byte) (signed [
 	return signed, false, err
 }
 
+// decryptResponse decrypts the assertions of a response without verifying
+// its signature.
+func decryptResponse(key *rsa.PrivateKey, data []byte) ([]byte, error) {
+	doc := etree.NewDocument()
+	if err := doc.ReadFromBytes(data); err != nil {
+		return nil, fmt.Errorf("parse document: %v", err)
+	}
+	if doc.Root() == nil {
+		return nil, fmt.Errorf("parse document: empty root")
+	}
+	if err := decryptAssertions(doc.Root(), key); err != nil {
+		return nil, err
+	}
+	return doc.WriteToBytes()
+}
+
 // before determines if a given time is before the current time, with an
 // allowed clock drift.
-func before(now, notBefore time.Time) bool {
-	return now.Add(allowedClockDrift).Before(notBefore)
+func (p *provider) before(now, notBefore time.Time) bool {
+	return now.Add(p.clockSkew).Before(notBefore)
 }
 
 // after determines if a given time is after the current time, with an
 // allowed clock drift.
-func after(now, notOnOrAfter time.Time) bool {
-	return now.After(notOnOrAfter.Add(allowedClockDrift))
+func (p *provider) after(now, notOnOrAfter time.Time) bool {
+	return now.After(notOnOrAfter.Add(p.clockSkew))
 }
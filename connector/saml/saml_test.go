@@ -56,6 +56,8 @@ type responseTest struct {
 	allowedGroups []string
 	filterGroups  bool
 
+	clockSkew string
+
 	// Expected outcome of the test.
 	wantErr   bool
 	wantIdent connector.Identity
@@ -290,6 +292,30 @@ func TestExpiredAssertion(t *testing.T) {
 	test.run(t)
 }
 
+func TestExpiredAssertionWithinClockSkew(t *testing.T) {
+	test := responseTest{
+		caFile:       "testdata/ca.crt",
+		respFile:     "testdata/good-resp.xml",
+		now:          "2017-04-04T04:40:59.330Z", // Assertion expired a minute ago.
+		usernameAttr: "Name",
+		emailAttr:    "email",
+		inResponseTo: "6zmm5mguyebwvajyf2sdwwcw6m",
+		redirectURI:  "http://127.0.0.1:5556/dex/callback",
+		wantErr:      true,
+	}
+	test.run(t)
+
+	test.clockSkew = "2m"
+	test.wantErr = false
+	test.wantIdent = connector.Identity{
+		UserID:        "eric.chiang+okta@coreos.com",
+		Username:      "Eric",
+		Email:         "eric.chiang+okta@coreos.com",
+		EmailVerified: true,
+	}
+	test.run(t)
+}
+
 // TestAssertionSignedNotResponse ensures the connector validates SAML 2.0
 // responses where the assertion is signed but the root element, the
 // response, isn't.
@@ -414,6 +440,7 @@ func (r responseTest) run(t *testing.T) {
 		EntityIssuer:  r.entityIssuer,
 		AllowedGroups: r.allowedGroups,
 		FilterGroups:  r.filterGroups,
+		ClockSkew:     r.clockSkew,
 		// Never logging in, don't need this.
 		SSOURL: "http://foo.bar/",
 	}
@@ -575,7 +602,7 @@ func runVerify(t *testing.T, ca string, resp string, shouldSucceed bool) {
 		t.Fatal(err)
 	}
 
-	if _, _, err := verifyResponseSig(validator, data); err != nil {
+	if _, _, err := verifyResponseSig(validator, nil, data); err != nil {
 		if shouldSucceed {
 			t.Fatal(err)
 		}
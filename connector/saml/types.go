@@ -176,6 +176,9 @@ type response struct {
 
 	// TODO(ericchiang): How do deal with multiple assertions?
 	Assertion *assertion `xml:"Assertion,omitempty"`
+
+	// Only set if the assertion could not be decrypted.
+	EncryptedAssertion *struct{} `xml:"urn:oasis:names:tc:SAML:2.0:assertion EncryptedAssertion,omitempty"`
 }
 
 type assertion struct {
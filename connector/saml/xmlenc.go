@@ -0,0 +1,246 @@
+package saml
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rsa"
+	_ "crypto/sha1" // for the default OAEP digest
+	_ "crypto/sha256"
+	_ "crypto/sha512"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/beevik/etree"
+	xrv "github.com/mattermost/xml-roundtrip-validator"
+)
+
+// XML Encryption algorithms supported for encrypted assertions.
+//
+// See: https://www.w3.org/TR/xmlenc-core1/
+const (
+	xmlencNamespace = "http://www.w3.org/2001/04/xmlenc#"
+	samlAssertionNS = "urn:oasis:names:tc:SAML:2.0:assertion"
+
+	algRSAOAEPMGF1P = "http://www.w3.org/2001/04/xmlenc#rsa-oaep-mgf1p"
+	algRSAOAEP      = "http://www.w3.org/2009/xmlenc11#rsa-oaep"
+
+	algAES128CBC = "http://www.w3.org/2001/04/xmlenc#aes128-cbc"
+	algAES192CBC = "http://www.w3.org/2001/04/xmlenc#aes192-cbc"
+	algAES256CBC = "http://www.w3.org/2001/04/xmlenc#aes256-cbc"
+	algAES128GCM = "http://www.w3.org/2009/xmlenc11#aes128-gcm"
+	algAES192GCM = "http://www.w3.org/2009/xmlenc11#aes192-gcm"
+	algAES256GCM = "http://www.w3.org/2009/xmlenc11#aes256-gcm"
+)
+
+var digestAlgorithms = map[string]crypto.Hash{
+	"http://www.w3.org/2000/09/xmldsig#sha1":        crypto.SHA1,
+	"http://www.w3.org/2001/04/xmlenc#sha256":       crypto.SHA256,
+	"http://www.w3.org/2001/04/xmldsig-more#sha384": crypto.SHA384,
+	"http://www.w3.org/2001/04/xmlenc#sha512":       crypto.SHA512,
+}
+
+var mgfAlgorithms = map[string]crypto.Hash{
+	"http://www.w3.org/2009/xmlenc11#mgf1sha1":   crypto.SHA1,
+	"http://www.w3.org/2009/xmlenc11#mgf1sha256": crypto.SHA256,
+	"http://www.w3.org/2009/xmlenc11#mgf1sha384": crypto.SHA384,
+	"http://www.w3.org/2009/xmlenc11#mgf1sha512": crypto.SHA512,
+}
+
+// decryptAssertions replaces every <EncryptedAssertion> child of the response
+// element with the <Assertion> it holds.
+func decryptAssertions(response *etree.Element, key *rsa.PrivateKey) error {
+	for _, el := range response.ChildElements() {
+		if el.Tag != "EncryptedAssertion" || el.NamespaceURI() != samlAssertionNS {
+			continue
+		}
+		plaintext, err := decryptElement(el, key)
+		if err != nil {
+			return fmt.Errorf("decrypt assertion: %v", err)
+		}
+		if err := xrv.Validate(bytes.NewReader(plaintext)); err != nil {
+			return fmt.Errorf("validating decrypted assertion: %v", err)
+		}
+
+		doc := etree.NewDocument()
+		if err := doc.ReadFromBytes(plaintext); err != nil {
+			return fmt.Errorf("parse decrypted assertion: %v", err)
+		}
+		assertion := doc.Root()
+		if assertion == nil || assertion.Tag != "Assertion" {
+			return errors.New("encrypted assertion does not contain an Assertion element")
+		}
+		// The assertion may use prefixes declared on the element it was
+		// encrypted in, which is going away.
+		for _, attr := range el.Attr {
+			if (attr.Space == "xmlns" || (attr.Space == "" && attr.Key == "xmlns")) && assertion.SelectAttr(attr.FullKey()) == nil {
+				assertion.CreateAttr(attr.FullKey(), attr.Value)
+			}
+		}
+		response.InsertChildAt(el.Index(), assertion)
+		response.RemoveChild(el)
+	}
+	return nil
+}
+
+// decryptElement decrypts the <EncryptedData> held by an <EncryptedAssertion>
+// element. The content encryption key is either given inline in the data's
+// <KeyInfo>, or next to it as an <EncryptedKey> element.
+func decryptElement(el *etree.Element, key *rsa.PrivateKey) ([]byte, error) {
+	data := childNS(el, xmlencNamespace, "EncryptedData")
+	if data == nil {
+		return nil, errors.New("no EncryptedData element")
+	}
+
+	encryptedKey := el.FindElement("./EncryptedData/KeyInfo/EncryptedKey")
+	if encryptedKey == nil {
+		encryptedKey = childNS(el, xmlencNamespace, "EncryptedKey")
+	}
+	if encryptedKey == nil {
+		return nil, errors.New("no EncryptedKey element")
+	}
+	cek, err := decryptKey(encryptedKey, key)
+	if err != nil {
+		return nil, err
+	}
+
+	ciphertext, err := cipherValue(data)
+	if err != nil {
+		return nil, err
+	}
+	return decryptData(encryptionMethod(data), cek, ciphertext)
+}
+
+// decryptKey decrypts the content encryption key with dex's private key.
+// RSA PKCS #1 v1.5 key transport is deliberately not supported, as it is
+// vulnerable to padding oracle attacks.
+func decryptKey(encryptedKey *etree.Element, key *rsa.PrivateKey) ([]byte, error) {
+	method := encryptedKey.FindElement("./EncryptionMethod")
+	if method == nil {
+		return nil, errors.New("EncryptedKey has no EncryptionMethod")
+	}
+
+	opts := &rsa.OAEPOptions{Hash: crypto.SHA1, MGFHash: crypto.SHA1}
+	if digest := method.FindElement("./DigestMethod"); digest != nil {
+		h, ok := digestAlgorithms[digest.SelectAttrValue("Algorithm", "")]
+		if !ok {
+			return nil, fmt.Errorf("unsupported key digest algorithm %q", digest.SelectAttrValue("Algorithm", ""))
+		}
+		opts.Hash = h
+	}
+
+	switch alg := method.SelectAttrValue("Algorithm", ""); alg {
+	case algRSAOAEPMGF1P:
+		// The mask generation function is always MGF1 with SHA-1.
+	case algRSAOAEP:
+		if mgf := method.FindElement("./MGF"); mgf != nil {
+			h, ok := mgfAlgorithms[mgf.SelectAttrValue("Algorithm", "")]
+			if !ok {
+				return nil, fmt.Errorf("unsupported mask generation function %q", mgf.SelectAttrValue("Algorithm", ""))
+			}
+			opts.MGFHash = h
+		}
+	default:
+		return nil, fmt.Errorf("unsupported key transport algorithm %q", alg)
+	}
+	if params := method.FindElement("./OAEPparams"); params != nil {
+		label, err := base64.StdEncoding.DecodeString(strings.TrimSpace(params.Text()))
+		if err != nil {
+			return nil, fmt.Errorf("decode OAEPparams: %v", err)
+		}
+		opts.Label = label
+	}
+
+	ciphertext, err := cipherValue(encryptedKey)
+	if err != nil {
+		return nil, err
+	}
+	cek, err := key.Decrypt(nil, ciphertext, opts)
+	if err != nil {
+		return nil, fmt.Errorf("decrypt key: %v", err)
+	}
+	return cek, nil
+}
+
+func decryptData(alg string, key, ciphertext []byte) ([]byte, error) {
+	var keySize int
+	switch alg {
+	case algAES128CBC, algAES128GCM:
+		keySize = 16
+	case algAES192CBC, algAES192GCM:
+		keySize = 24
+	case algAES256CBC, algAES256GCM:
+		keySize = 32
+	default:
+		return nil, fmt.Errorf("unsupported data encryption algorithm %q", alg)
+	}
+	if len(key) != keySize {
+		return nil, fmt.Errorf("expected %d byte key for %s, got %d bytes", keySize, alg, len(key))
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	switch alg {
+	case algAES128GCM, algAES192GCM, algAES256GCM:
+		gcm, err := cipher.NewGCM(block)
+		if err != nil {
+			return nil, err
+		}
+		if len(ciphertext) < gcm.NonceSize()+gcm.Overhead() {
+			return nil, errors.New("ciphertext too short")
+		}
+		nonce, sealed := ciphertext[:gcm.NonceSize()], ciphertext[gcm.NonceSize():]
+		plaintext, err := gcm.Open(nil, nonce, sealed, nil)
+		if err != nil {
+			return nil, fmt.Errorf("decrypt data: %v", err)
+		}
+		return plaintext, nil
+	default:
+		// The IV is prepended to the ciphertext, and the last byte of the
+		// plaintext gives the length of the padding.
+		if len(ciphertext) < 2*aes.BlockSize || len(ciphertext)%aes.BlockSize != 0 {
+			return nil, errors.New("invalid ciphertext length")
+		}
+		iv, sealed := ciphertext[:aes.BlockSize], ciphertext[aes.BlockSize:]
+		plaintext := make([]byte, len(sealed))
+		cipher.NewCBCDecrypter(block, iv).CryptBlocks(plaintext, sealed)
+		padding := int(plaintext[len(plaintext)-1])
+		if padding == 0 || padding > aes.BlockSize {
+			return nil, errors.New("invalid padding")
+		}
+		return plaintext[:len(plaintext)-padding], nil
+	}
+}
+
+func encryptionMethod(el *etree.Element) string {
+	if method := el.FindElement("./EncryptionMethod"); method != nil {
+		return method.SelectAttrValue("Algorithm", "")
+	}
+	return ""
+}
+
+func cipherValue(el *etree.Element) ([]byte, error) {
+	value := el.FindElement("./CipherData/CipherValue")
+	if value == nil {
+		return nil, fmt.Errorf("%s has no CipherValue", el.Tag)
+	}
+	data, err := base64.StdEncoding.DecodeString(strings.Join(strings.Fields(value.Text()), ""))
+	if err != nil {
+		return nil, fmt.Errorf("decode CipherValue: %v", err)
+	}
+	return data, nil
+}
+
+func childNS(el *etree.Element, namespace, tag string) *etree.Element {
+	for _, child := range el.ChildElements() {
+		if child.Tag == tag && child.NamespaceURI() == namespace {
+			return child
+		}
+	}
+	return nil
+}
@@ -0,0 +1,438 @@
+package saml
+
+import (
+	"crypto"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"log/slog"
+	"math/big"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/beevik/etree"
+	"github.com/kylelemons/godebug/pretty"
+	dsig "github.com/russellhaering/goxmldsig"
+
+	"github.com/dexidp/dex/connector"
+)
+
+type testKeyPair struct {
+	key     *rsa.PrivateKey
+	cert    *x509.Certificate
+	keyPEM  []byte
+	certPEM []byte
+}
+
+func newTestKeyPair(t *testing.T, cn string) testKeyPair {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: cn},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return testKeyPair{
+		key:     key,
+		cert:    cert,
+		keyPEM:  pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)}),
+		certPEM: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+	}
+}
+
+func (kp testKeyPair) sign(t *testing.T, el *etree.Element) *etree.Element {
+	t.Helper()
+	ctx, err := dsig.NewSigningContext(kp.key, [][]byte{kp.cert.Raw})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx.Canonicalizer = dsig.MakeC14N10ExclusiveCanonicalizerWithPrefixList("")
+	signed, err := ctx.SignEnveloped(el)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signed
+}
+
+type encryptOptions struct {
+	dataAlg      string
+	keyAlg       string
+	keyDigestAlg string
+	mgfAlg       string
+	// Put the EncryptedKey next to the EncryptedData rather than in it.
+	detachedKey bool
+}
+
+// encryptAssertion builds an <EncryptedAssertion> element the way an IdP
+// would, encrypting plaintext for pub.
+func encryptAssertion(t *testing.T, plaintext []byte, pub *rsa.PublicKey, opts encryptOptions) string {
+	t.Helper()
+
+	var keySize int
+	switch opts.dataAlg {
+	case algAES128CBC, algAES128GCM:
+		keySize = 16
+	case algAES256CBC, algAES256GCM:
+		keySize = 32
+	}
+	cek := make([]byte, keySize)
+	rand.Read(cek)
+	block, err := aes.NewCipher(cek)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var ciphertext []byte
+	switch opts.dataAlg {
+	case algAES128GCM, algAES256GCM:
+		gcm, _ := cipher.NewGCM(block)
+		nonce := make([]byte, gcm.NonceSize())
+		rand.Read(nonce)
+		ciphertext = gcm.Seal(nonce, nonce, plaintext, nil)
+	default:
+		padding := aes.BlockSize - len(plaintext)%aes.BlockSize
+		padded := append(append([]byte{}, plaintext...), make([]byte, padding)...)
+		padded[len(padded)-1] = byte(padding)
+		ciphertext = make([]byte, aes.BlockSize+len(padded))
+		rand.Read(ciphertext[:aes.BlockSize])
+		cipher.NewCBCEncrypter(block, ciphertext[:aes.BlockSize]).CryptBlocks(ciphertext[aes.BlockSize:], padded)
+	}
+
+	oaep := &rsa.OAEPOptions{Hash: crypto.SHA1, MGFHash: crypto.SHA1}
+	digestMethod := ""
+	if opts.keyDigestAlg != "" {
+		oaep.Hash = digestAlgorithms[opts.keyDigestAlg]
+		digestMethod = fmt.Sprintf(`<ds:DigestMethod Algorithm="%s"/>`, opts.keyDigestAlg)
+	}
+	mgfMethod := ""
+	if opts.mgfAlg != "" {
+		oaep.MGFHash = mgfAlgorithms[opts.mgfAlg]
+		mgfMethod = fmt.Sprintf(`<xenc11:MGF xmlns:xenc11="http://www.w3.org/2009/xmlenc11#" Algorithm="%s"/>`, opts.mgfAlg)
+	}
+	// EncryptOAEP uses the same hash for the digest and the mask generation.
+	if oaep.Hash != oaep.MGFHash {
+		t.Fatal("digest and MGF algorithms must match")
+	}
+	encryptedCEK, err := rsa.EncryptOAEP(oaep.Hash.New(), rand.Reader, pub, cek, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	encryptedKey := fmt.Sprintf(`<xenc:EncryptedKey xmlns:xenc="http://www.w3.org/2001/04/xmlenc#">`+
+		`<xenc:EncryptionMethod Algorithm="%s">%s%s</xenc:EncryptionMethod>`+
+		`<xenc:CipherData><xenc:CipherValue>%s</xenc:CipherValue></xenc:CipherData>`+
+		`</xenc:EncryptedKey>`, opts.keyAlg, digestMethod, mgfMethod, base64.StdEncoding.EncodeToString(encryptedCEK))
+	inline, detached := encryptedKey, ""
+	if opts.detachedKey {
+		inline, detached = "", encryptedKey
+	}
+
+	return fmt.Sprintf(`<saml2:EncryptedAssertion xmlns:saml2="urn:oasis:names:tc:SAML:2.0:assertion">`+
+		`<xenc:EncryptedData xmlns:xenc="http://www.w3.org/2001/04/xmlenc#" Type="http://www.w3.org/2001/04/xmlenc#Element">`+
+		`<xenc:EncryptionMethod Algorithm="%s"/>`+
+		`<ds:KeyInfo xmlns:ds="http://www.w3.org/2000/09/xmldsig#">%s</ds:KeyInfo>`+
+		`<xenc:CipherData><xenc:CipherValue>%s</xenc:CipherValue></xenc:CipherData>`+
+		`</xenc:EncryptedData>%s</saml2:EncryptedAssertion>`,
+		opts.dataAlg, inline, base64.StdEncoding.EncodeToString(ciphertext), detached)
+}
+
+func TestEncryptedAssertion(t *testing.T) {
+	idp := newTestKeyPair(t, "idp")
+	sp := newTestKeyPair(t, "dex")
+
+	gcm := encryptOptions{dataAlg: algAES256GCM, keyAlg: algRSAOAEPMGF1P}
+	tests := []struct {
+		name          string
+		opts          encryptOptions
+		signResponse  bool
+		signAssertion bool
+		noKey         bool
+		wantErr       string
+	}{
+		{name: "aes256-gcm", opts: gcm},
+		{name: "aes128-cbc with detached key", opts: encryptOptions{dataAlg: algAES128CBC, keyAlg: algRSAOAEPMGF1P, detachedKey: true}},
+		{name: "xmlenc11 rsa-oaep with sha256", opts: encryptOptions{
+			dataAlg:      algAES128GCM,
+			keyAlg:       algRSAOAEP,
+			keyDigestAlg: "http://www.w3.org/2001/04/xmlenc#sha256",
+			mgfAlg:       "http://www.w3.org/2009/xmlenc11#mgf1sha256",
+		}},
+		{name: "signed response", opts: gcm, signResponse: true},
+		{name: "signed assertion", opts: gcm, signAssertion: true},
+		{name: "unsupported key transport", opts: encryptOptions{dataAlg: algAES256GCM, keyAlg: "http://www.w3.org/2001/04/xmlenc#rsa-1_5"}, wantErr: "unsupported key transport algorithm"},
+		{name: "no key", opts: gcm, noKey: true, wantErr: "no key is configured"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			doc := etree.NewDocument()
+			if err := doc.ReadFromFile("testdata/good-resp.tmpl"); err != nil {
+				t.Fatal(err)
+			}
+			root := doc.Root()
+			root.RemoveChild(root.SelectElement("Signature"))
+
+			assertion := root.SelectElement("Assertion")
+			plain := etree.NewDocument()
+			if tc.signAssertion {
+				plain.SetRoot(idp.sign(t, assertion))
+			} else {
+				plain.SetRoot(assertion.Copy())
+			}
+			plaintext, err := plain.WriteToBytes()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			encrypted := etree.NewDocument()
+			if err := encrypted.ReadFromString(encryptAssertion(t, plaintext, &sp.key.PublicKey, tc.opts)); err != nil {
+				t.Fatal(err)
+			}
+			root.InsertChildAt(assertion.Index(), encrypted.Root())
+			root.RemoveChild(assertion)
+			if tc.signResponse {
+				doc.SetRoot(idp.sign(t, root))
+			}
+			resp, err := doc.WriteToBytes()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			c := Config{
+				UsernameAttr: "Name",
+				EmailAttr:    "email",
+				GroupsAttr:   "groups",
+				RedirectURI:  "http://127.0.0.1:5556/dex/callback",
+				SSOURL:       "http://foo.bar/",
+			}
+			if !tc.noKey {
+				c.KeyData = sp.keyPEM
+			}
+			if tc.signResponse || tc.signAssertion {
+				c.CAData = idp.certPEM
+			} else {
+				c.InsecureSkipSignatureValidation = true
+			}
+			conn, err := c.openConnector(slog.New(slog.DiscardHandler))
+			if err != nil {
+				t.Fatal(err)
+			}
+			now, _ := time.Parse(timeFormat, "2017-04-04T04:34:59.330Z")
+			conn.now = func() time.Time { return now }
+
+			ident, err := conn.HandlePOST(connector.Scopes{Groups: true}, base64.StdEncoding.EncodeToString(resp), "6zmm5mguyebwvajyf2sdwwcw6m")
+			if tc.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+					t.Fatalf("expected error containing %q, got %v", tc.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("handle response: %v", err)
+			}
+
+			ident.ConnectorData = nil
+			sort.Strings(ident.Groups)
+			want := connector.Identity{
+				UserID:        "eric.chiang+okta@coreos.com",
+				Username:      "Eric",
+				Email:         "eric.chiang+okta@coreos.com",
+				EmailVerified: true,
+				Groups:        []string{"Admins", "Everyone"},
+			}
+			if diff := pretty.Compare(ident, want); diff != "" {
+				t.Error(diff)
+			}
+		})
+	}
+}
+
+func TestSignedAuthnRequest(t *testing.T) {
+	sp := newTestKeyPair(t, "dex")
+
+	c := Config{
+		EntityIssuer:                    "https://dex.example.com/callback",
+		UsernameAttr:                    "Name",
+		EmailAttr:                       "email",
+		RedirectURI:                     "https://dex.example.com/callback",
+		SSOURL:                          "https://idp.example.com/sso",
+		InsecureSkipSignatureValidation: true,
+		KeyData:                         sp.keyPEM,
+		CertData:                        sp.certPEM,
+		SignAuthnRequests:               true,
+	}
+	conn, err := c.openConnector(slog.New(slog.DiscardHandler))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, value, err := conn.POSTData(connector.Scopes{}, "request-id")
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := base64.StdEncoding.DecodeString(value)
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc := etree.NewDocument()
+	if err := doc.ReadFromBytes(data); err != nil {
+		t.Fatal(err)
+	}
+
+	root := doc.Root()
+	issuer, sig := root.SelectElement("Issuer"), root.SelectElement("Signature")
+	if issuer == nil || sig == nil {
+		t.Fatalf("expected Issuer and Signature elements in request: %s", data)
+	}
+	if sig.Index() != issuer.Index()+1 {
+		t.Errorf("expected Signature to directly follow Issuer")
+	}
+
+	validator := dsig.NewDefaultValidationContext(certStore{[]*x509.Certificate{sp.cert}})
+	if _, err := validator.Validate(root); err != nil {
+		t.Errorf("validate request signature: %v", err)
+	}
+}
+
+func TestSignAuthnRequestsConfig(t *testing.T) {
+	sp := newTestKeyPair(t, "dex")
+	other := newTestKeyPair(t, "other")
+
+	tests := []struct {
+		name    string
+		key     []byte
+		cert    []byte
+		wantErr bool
+	}{
+		{name: "key pair", key: sp.keyPEM, cert: sp.certPEM},
+		{name: "no key", cert: sp.certPEM, wantErr: true},
+		{name: "no cert", key: sp.keyPEM, wantErr: true},
+		{name: "mismatched cert", key: sp.keyPEM, cert: other.certPEM, wantErr: true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			c := Config{
+				UsernameAttr:                    "Name",
+				EmailAttr:                       "email",
+				RedirectURI:                     "https://dex.example.com/callback",
+				SSOURL:                          "https://idp.example.com/sso",
+				InsecureSkipSignatureValidation: true,
+				KeyData:                         tc.key,
+				CertData:                        tc.cert,
+				SignAuthnRequests:               true,
+			}
+			_, err := c.openConnector(slog.New(slog.DiscardHandler))
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("wantErr=%t, got %v", tc.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestIdPInitiated(t *testing.T) {
+	idp := newTestKeyPair(t, "idp")
+
+	// unsolicitedResponse signs the good response without its InResponseTo
+	// values, like an IdP-initiated login.
+	unsolicitedResponse := func(keepInResponseTo bool) string {
+		doc := etree.NewDocument()
+		if err := doc.ReadFromFile("testdata/good-resp.tmpl"); err != nil {
+			t.Fatal(err)
+		}
+		root := doc.Root()
+		root.RemoveChild(root.SelectElement("Signature"))
+		if !keepInResponseTo {
+			root.RemoveAttr("InResponseTo")
+			for _, el := range root.FindElements("//SubjectConfirmationData") {
+				el.RemoveAttr("InResponseTo")
+			}
+		}
+		doc.SetRoot(idp.sign(t, root))
+		resp, err := doc.WriteToBytes()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return base64.StdEncoding.EncodeToString(resp)
+	}
+
+	c := Config{
+		CAData:       idp.certPEM,
+		UsernameAttr: "Name",
+		EmailAttr:    "email",
+		RedirectURI:  "http://127.0.0.1:5556/dex/callback",
+		SSOURL:       "http://foo.bar/",
+		IdPInitiated: &IdPInitiatedConfig{Targets: []IdPInitiatedTarget{
+			{ClientID: "default-app"},
+			{RelayState: "wiki", ClientID: "wiki", RedirectURI: "https://wiki.example.com/callback", Scopes: []string{"openid", "groups"}},
+		}},
+	}
+	conn, err := c.openConnector(slog.New(slog.DiscardHandler))
+	if err != nil {
+		t.Fatal(err)
+	}
+	now, _ := time.Parse(timeFormat, "2017-04-04T04:34:59.330Z")
+	conn.now = func() time.Time { return now }
+
+	if target, ok := conn.IdPInitiatedTarget("wiki"); !ok || target.RedirectURI != "https://wiki.example.com/callback" {
+		t.Errorf("unexpected target for relay state %q: %+v", "wiki", target)
+	}
+	if _, ok := conn.IdPInitiatedTarget("unknown"); ok {
+		t.Error("expected no target for unknown relay state")
+	}
+
+	resp := unsolicitedResponse(false)
+	ident, err := conn.HandleUnsolicitedPOST(t.Context(), connector.Scopes{}, resp)
+	if err != nil {
+		t.Fatalf("handle unsolicited response: %v", err)
+	}
+	if ident.Email != "eric.chiang+okta@coreos.com" {
+		t.Errorf("unexpected email %q", ident.Email)
+	}
+
+	if _, err := conn.HandleUnsolicitedPOST(t.Context(), connector.Scopes{}, resp); err == nil || !strings.Contains(err.Error(), "already used") {
+		t.Errorf("expected replayed response to be rejected, got %v", err)
+	}
+
+	// Assertions are forgotten once they expired.
+	now = now.Add(time.Hour)
+	replays := conn.replays.(*replayCache)
+	if err := replays.Use(t.Context(), "other", now); err != nil {
+		t.Fatal(err)
+	}
+	if len(replays.seen) != 1 {
+		t.Errorf("expected expired assertions to be forgotten, got %d", len(replays.seen))
+	}
+
+	if _, err := conn.HandleUnsolicitedPOST(t.Context(), connector.Scopes{}, unsolicitedResponse(true)); err == nil {
+		t.Error("expected response to a request to be rejected")
+	}
+
+	c.IdPInitiated = nil
+	conn, err = c.openConnector(slog.New(slog.DiscardHandler))
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn.now = func() time.Time { return now }
+	if _, err := conn.HandleUnsolicitedPOST(t.Context(), connector.Scopes{}, unsolicitedResponse(false)); err == nil {
+		t.Error("expected unsolicited responses to be rejected without idpInitiated")
+	}
+}
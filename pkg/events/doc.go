@@ -0,0 +1,3 @@
+// Package events delivers audit and identity events, such as logins and
+// registrations, as CloudEvents to HTTP endpoints or Kafka.
+package events
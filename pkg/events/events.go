@@ -0,0 +1,258 @@
+package events
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dexidp/dex/pkg/httpclient"
+)
+
+// SpecVersion is the CloudEvents version of the events.
+const SpecVersion = "1.0"
+
+// Event types. The version suffix is the version of the schema of the event
+// data: fields may be added within a version, any other change to the data
+// gets a new type, so consumers can keep parsing the versions they know.
+const (
+	TypeLogin             = "io.dexidp.login.v1"
+	TypeLoginDenied       = "io.dexidp.login_denied.v1"
+	TypeLogout            = "io.dexidp.logout.v1"
+	TypeRegistration      = "io.dexidp.registration.v1"
+	TypePasswordReset     = "io.dexidp.password_reset.v1"
+	TypeEmailVerification = "io.dexidp.email_verification.v1"
+	TypeTokenExchange     = "io.dexidp.token_exchange.v1"
+	TypeRefreshTokenReuse = "io.dexidp.refresh_token_reuse.v1"
+	TypeImpersonation     = "io.dexidp.impersonation.v1"
+)
+
+// Event is a CloudEvent in the JSON event format.
+type Event struct {
+	SpecVersion     string    `json:"specversion"`
+	ID              string    `json:"id"`
+	Source          string    `json:"source"`
+	Type            string    `json:"type"`
+	Subject         string    `json:"subject,omitempty"`
+	Time            time.Time `json:"time"`
+	DataContentType string    `json:"datacontenttype"`
+	Data            any       `json:"data"`
+}
+
+// Sink delivers events.
+type Sink interface {
+	Send(ctx context.Context, e Event) error
+}
+
+// HTTPConfig configures a sink that posts each event in the structured
+// content mode of the CloudEvents HTTP binding.
+type HTTPConfig struct {
+	// URL the events are posted to.
+	URL string `json:"url"`
+
+	// Headers are added to each request, e.g. for authentication.
+	Headers map[string]string `json:"headers"`
+
+	RootCAs            []string `json:"rootCAs"`
+	InsecureSkipVerify bool     `json:"insecureSkipVerify"`
+
+	// Timeout of a single delivery. Defaults to "10s".
+	Timeout string `json:"timeout"`
+}
+
+// Open returns a sink for the HTTP endpoint.
+func (c *HTTPConfig) Open(logger *slog.Logger) (Sink, error) {
+	if _, err := url.ParseRequestURI(c.URL); err != nil {
+		return nil, fmt.Errorf("http: invalid url %q: %v", c.URL, err)
+	}
+	client, err := newHTTPClient(c.RootCAs, c.InsecureSkipVerify, c.Timeout)
+	if err != nil {
+		return nil, fmt.Errorf("http: %v", err)
+	}
+	return &httpSink{
+		client:      client,
+		url:         c.URL,
+		headers:     c.Headers,
+		contentType: "application/cloudevents+json",
+		encode:      func(e Event) ([]byte, error) { return json.Marshal(e) },
+	}, nil
+}
+
+// KafkaConfig configures a sink that produces events to a Kafka topic through
+// a Kafka REST Proxy (v2 API). Each event is a record in the structured
+// content mode of the CloudEvents Kafka binding, keyed by its subject so the
+// events of a user stay in order.
+type KafkaConfig struct {
+	// RESTProxyURL is the base URL of the REST Proxy, e.g.
+	// "http://kafka-rest:8082".
+	RESTProxyURL string `json:"restProxyURL"`
+	Topic        string `json:"topic"`
+
+	// Username and Password authenticate with HTTP basic auth. If empty, no
+	// authentication is attempted.
+	Username string `json:"username"`
+	Password string `json:"password"`
+
+	RootCAs            []string `json:"rootCAs"`
+	InsecureSkipVerify bool     `json:"insecureSkipVerify"`
+
+	// Timeout of a single delivery. Defaults to "10s".
+	Timeout string `json:"timeout"`
+}
+
+// Open returns a sink for the Kafka topic.
+func (c *KafkaConfig) Open(logger *slog.Logger) (Sink, error) {
+	if _, err := url.ParseRequestURI(c.RESTProxyURL); err != nil {
+		return nil, fmt.Errorf("kafka: invalid restProxyURL %q: %v", c.RESTProxyURL, err)
+	}
+	if c.Topic == "" {
+		return nil, errors.New("kafka: no topic specified")
+	}
+	client, err := newHTTPClient(c.RootCAs, c.InsecureSkipVerify, c.Timeout)
+	if err != nil {
+		return nil, fmt.Errorf("kafka: %v", err)
+	}
+	s := &httpSink{
+		client:      client,
+		url:         strings.TrimSuffix(c.RESTProxyURL, "/") + "/topics/" + url.PathEscape(c.Topic),
+		contentType: "application/vnd.kafka.json.v2+json",
+		encode: func(e Event) ([]byte, error) {
+			type record struct {
+				Key   string `json:"key,omitempty"`
+				Value Event  `json:"value"`
+			}
+			return json.Marshal(struct {
+				Records []record `json:"records"`
+			}{[]record{{Key: e.Subject, Value: e}}})
+		},
+	}
+	if c.Username != "" {
+		s.username, s.password = c.Username, c.Password
+	}
+	return s, nil
+}
+
+func newHTTPClient(rootCAs []string, insecureSkipVerify bool, timeout string) (*http.Client, error) {
+	client, err := httpclient.NewHTTPClient(rootCAs, insecureSkipVerify)
+	if err != nil {
+		return nil, err
+	}
+	client.Timeout = 10 * time.Second
+	if timeout != "" {
+		d, err := time.ParseDuration(timeout)
+		if err != nil {
+			return nil, fmt.Errorf("invalid timeout %q: %v", timeout, err)
+		}
+		client.Timeout = d
+	}
+	return client, nil
+}
+
+type httpSink struct {
+	client      *http.Client
+	url         string
+	headers     map[string]string
+	username    string
+	password    string
+	contentType string
+	encode      func(Event) ([]byte, error)
+}
+
+func (s *httpSink) Send(ctx context.Context, e Event) error {
+	body, err := s.encode(e)
+	if err != nil {
+		return fmt.Errorf("encode event: %v", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	for k, v := range s.headers {
+		req.Header.Set(k, v)
+	}
+	req.Header.Set("Content-Type", s.contentType)
+	if s.username != "" {
+		req.SetBasicAuth(s.username, s.password)
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("post event: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("post event: %s: %s", resp.Status, bytes.TrimSpace(msg))
+	}
+	return nil
+}
+
+// Queue delivers events to sinks in the background, so slow sinks don't hold
+// up requests. Events are dropped and logged if the queue is full.
+type Queue struct {
+	sinks  []Sink
+	logger *slog.Logger
+	events chan Event
+	done   chan struct{}
+
+	mu     sync.RWMutex
+	closed bool
+}
+
+// NewQueue starts delivering events to the sinks. size is the number of
+// events kept while the sinks are busy.
+func NewQueue(logger *slog.Logger, size int, sinks ...Sink) *Queue {
+	q := &Queue{
+		sinks:  sinks,
+		logger: logger,
+		events: make(chan Event, size),
+		done:   make(chan struct{}),
+	}
+	go q.run()
+	return q
+}
+
+// Send queues an event. It never blocks.
+func (q *Queue) Send(ctx context.Context, e Event) error {
+	q.mu.RLock()
+	defer q.mu.RUnlock()
+	if q.closed {
+		return errors.New("events: queue closed")
+	}
+	select {
+	case q.events <- e:
+		return nil
+	default:
+		return errors.New("events: queue full, event dropped")
+	}
+}
+
+// Close delivers the queued events and stops the queue. Events sent after
+// Close are not delivered.
+func (q *Queue) Close() {
+	q.mu.Lock()
+	if !q.closed {
+		q.closed = true
+		close(q.events)
+	}
+	q.mu.Unlock()
+	<-q.done
+}
+
+func (q *Queue) run() {
+	defer close(q.done)
+	for e := range q.events {
+		for _, sink := range q.sinks {
+			if err := sink.Send(context.Background(), e); err != nil {
+				q.logger.Error("failed to deliver event", "type", e.Type, "id", e.ID, "err", err)
+			}
+		}
+	}
+}
@@ -0,0 +1,137 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func testEvent() Event {
+	return Event{
+		SpecVersion:     SpecVersion,
+		ID:              "1",
+		Source:          "https://dex.example.com",
+		Type:            TypeLogin,
+		Subject:         "user-1",
+		Time:            time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC),
+		DataContentType: "application/json",
+		Data:            map[string]any{"connector_id": "hsdp"},
+	}
+}
+
+func TestHTTPSink(t *testing.T) {
+	var (
+		contentType string
+		auth        string
+		body        map[string]any
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		contentType = r.Header.Get("Content-Type")
+		auth = r.Header.Get("Authorization")
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer srv.Close()
+
+	sink, err := (&HTTPConfig{URL: srv.URL, Headers: map[string]string{"Authorization": "Bearer token"}}).Open(slog.New(slog.DiscardHandler))
+	require.NoError(t, err)
+	require.NoError(t, sink.Send(t.Context(), testEvent()))
+
+	require.Equal(t, "application/cloudevents+json", contentType)
+	require.Equal(t, "Bearer token", auth)
+	require.Equal(t, "1.0", body["specversion"])
+	require.Equal(t, "io.dexidp.login.v1", body["type"])
+	require.Equal(t, "user-1", body["subject"])
+	require.Equal(t, "2026-01-02T03:04:05Z", body["time"])
+	require.Equal(t, map[string]any{"connector_id": "hsdp"}, body["data"])
+}
+
+func TestHTTPSinkError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "nope", http.StatusBadRequest)
+	}))
+	defer srv.Close()
+
+	sink, err := (&HTTPConfig{URL: srv.URL}).Open(slog.New(slog.DiscardHandler))
+	require.NoError(t, err)
+	require.ErrorContains(t, sink.Send(t.Context(), testEvent()), "400 Bad Request: nope")
+}
+
+func TestKafkaSink(t *testing.T) {
+	var (
+		path, contentType, user string
+		body                    struct {
+			Records []struct {
+				Key   string         `json:"key"`
+				Value map[string]any `json:"value"`
+			} `json:"records"`
+		}
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.Path
+		contentType = r.Header.Get("Content-Type")
+		user, _, _ = r.BasicAuth()
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+	}))
+	defer srv.Close()
+
+	_, err := (&KafkaConfig{RESTProxyURL: srv.URL}).Open(slog.New(slog.DiscardHandler))
+	require.Error(t, err)
+
+	sink, err := (&KafkaConfig{RESTProxyURL: srv.URL + "/", Topic: "dex-events", Username: "dex", Password: "secret"}).Open(slog.New(slog.DiscardHandler))
+	require.NoError(t, err)
+	require.NoError(t, sink.Send(t.Context(), testEvent()))
+
+	require.Equal(t, "/topics/dex-events", path)
+	require.Equal(t, "application/vnd.kafka.json.v2+json", contentType)
+	require.Equal(t, "dex", user)
+	require.Len(t, body.Records, 1)
+	require.Equal(t, "user-1", body.Records[0].Key)
+	require.Equal(t, "io.dexidp.login.v1", body.Records[0].Value["type"])
+}
+
+type chanSink chan Event
+
+func (s chanSink) Send(_ context.Context, e Event) error {
+	s <- e
+	return nil
+}
+
+type blockingSink chan struct{}
+
+func (s blockingSink) Send(context.Context, Event) error {
+	<-s
+	return nil
+}
+
+func TestQueue(t *testing.T) {
+	sink := make(chanSink, 10)
+	q := NewQueue(slog.New(slog.DiscardHandler), 10, sink)
+	require.NoError(t, q.Send(t.Context(), testEvent()))
+	q.Close()
+	require.Len(t, sink, 1)
+	require.Error(t, q.Send(t.Context(), testEvent()))
+}
+
+func TestQueueFull(t *testing.T) {
+	release := make(blockingSink)
+	q := NewQueue(slog.New(slog.DiscardHandler), 1, release)
+	defer q.Close()
+	defer close(release)
+
+	// The first event is picked up by the blocked sink, the second fills the
+	// queue.
+	var errs int
+	for range 3 {
+		if q.Send(t.Context(), testEvent()) != nil {
+			errs++
+		}
+	}
+	require.GreaterOrEqual(t, errs, 1)
+}
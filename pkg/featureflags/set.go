@@ -9,6 +9,10 @@ var (
 	// $ sign is a part of the password for LDAP user.
 	ExpandEnv = newFlag("expand_env", true)
 
+	// ExpandEnvStrict fails on ${NAME} references to unset environment variables in the config,
+	// which are expanded to an empty string with a deprecation warning otherwise.
+	ExpandEnvStrict = newFlag("expand_env_strict", false)
+
 	// APIConnectorsCRUD allows CRUD operations on connectors through the gRPC API
 	APIConnectorsCRUD = newFlag("api_connectors_crud", false)
 
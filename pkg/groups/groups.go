@@ -1,5 +1,12 @@
 package groups
 
+import (
+	"fmt"
+	"path"
+	"slices"
+	"strings"
+)
+
 // Filter filters out any groups of given that are not in required. Thus it may
 // happen that the resulting slice is empty.
 func Filter(given, required []string) []string {
@@ -15,3 +22,67 @@ func Filter(given, required []string) []string {
 	}
 	return groups
 }
+
+// Limit restricts the groups a connector emits, keeping ID tokens of users in
+// many groups small. The zero value emits all groups.
+type Limit struct {
+	// Allowed are path.Match patterns, e.g. "acme/*". Groups matching none of
+	// them are dropped.
+	Allowed []string
+	// Prefixes drops groups starting with none of them.
+	Prefixes []string
+	// TopLevel drops groups outside of these top-level groups. A group is in a
+	// top-level group if it's the group itself or a subgroup or permission of
+	// it, i.e. starts with "<top-level>/" or "<top-level>:".
+	TopLevel []string
+	// Max is the maximum number of groups emitted, 0 for no limit.
+	Max int
+}
+
+// Validate checks the patterns of the limit.
+func (l Limit) Validate() error {
+	for _, p := range l.Allowed {
+		if _, err := path.Match(p, ""); err != nil {
+			return fmt.Errorf("invalid group pattern %q: %v", p, err)
+		}
+	}
+	if l.Max < 0 {
+		return fmt.Errorf("invalid maximum number of groups %d", l.Max)
+	}
+	return nil
+}
+
+// Apply returns the groups of given passing the limit, in their order. It
+// reports whether groups were dropped because of Max.
+func (l Limit) Apply(given []string) ([]string, bool) {
+	var groups []string
+	for _, group := range given {
+		if l.allows(group) {
+			groups = append(groups, group)
+		}
+	}
+	if l.Max > 0 && len(groups) > l.Max {
+		return groups[:l.Max], true
+	}
+	return groups, false
+}
+
+func (l Limit) allows(group string) bool {
+	if len(l.Allowed) > 0 && !slices.ContainsFunc(l.Allowed, func(p string) bool {
+		ok, _ := path.Match(p, group)
+		return ok
+	}) {
+		return false
+	}
+	if len(l.Prefixes) > 0 && !slices.ContainsFunc(l.Prefixes, func(p string) bool {
+		return strings.HasPrefix(group, p)
+	}) {
+		return false
+	}
+	if len(l.TopLevel) > 0 && !slices.ContainsFunc(l.TopLevel, func(top string) bool {
+		return group == top || strings.HasPrefix(group, top+"/") || strings.HasPrefix(group, top+":")
+	}) {
+		return false
+	}
+	return true
+}
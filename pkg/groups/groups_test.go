@@ -24,3 +24,29 @@ func TestFilter(t *testing.T) {
 		})
 	}
 }
+
+func TestLimit(t *testing.T) {
+	given := []string{"acme", "acme/ops", "acme:owner", "acmecorp", "other/acme"}
+	cases := map[string]struct {
+		limit     groups.Limit
+		expected  []string
+		truncated bool
+	}{
+		"no limit":  {limit: groups.Limit{}, expected: given},
+		"allowed":   {limit: groups.Limit{Allowed: []string{"acme*"}}, expected: []string{"acme", "acme:owner", "acmecorp"}},
+		"prefixes":  {limit: groups.Limit{Prefixes: []string{"acme/", "other"}}, expected: []string{"acme/ops", "other/acme"}},
+		"top-level": {limit: groups.Limit{TopLevel: []string{"acme"}}, expected: []string{"acme", "acme/ops", "acme:owner"}},
+		"max":       {limit: groups.Limit{TopLevel: []string{"acme"}, Max: 2}, expected: []string{"acme", "acme/ops"}, truncated: true},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			actual, truncated := tc.limit.Apply(given)
+			assert.Equal(t, tc.expected, actual)
+			assert.Equal(t, tc.truncated, truncated)
+		})
+	}
+
+	assert.Error(t, groups.Limit{Allowed: []string{"["}}.Validate())
+	assert.Error(t, groups.Limit{Max: -1}.Validate())
+	assert.NoError(t, groups.Limit{Allowed: []string{"acme/*"}}.Validate())
+}
@@ -0,0 +1,377 @@
+package httpclient
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// FactoryConfig holds the settings shared by all clients of a Factory. Zero
+// values keep the defaults of NewHTTPClient.
+type FactoryConfig struct {
+	// MaxIdleConnsPerHost limits the idle connections kept per upstream host.
+	// Defaults to 2, the default of net/http.
+	MaxIdleConnsPerHost int
+
+	// MaxConnsPerHost limits the connections per upstream host. Zero means no
+	// limit.
+	MaxConnsPerHost int
+
+	// IdleConnTimeout closes idle connections after this time. Defaults to 90
+	// seconds.
+	IdleConnTimeout time.Duration
+
+	// TLSSessionCacheSize is the number of TLS sessions kept per transport
+	// for resumption, which saves full handshakes on new connections.
+	// Defaults to 64, negative disables resumption.
+	TLSSessionCacheSize int
+
+	// Timeout of a request including reading the response. Zero means no
+	// timeout.
+	Timeout time.Duration
+
+	// Proxy is the URL of the proxy for all upstream requests. If empty, the
+	// proxy is taken from the HTTP_PROXY, HTTPS_PROXY and NO_PROXY environment
+	// variables.
+	Proxy string
+
+	// MaxConcurrentRequests limits the requests in flight of each client
+	// name, e.g. of each connector, until their response body is closed.
+	// Zero means no limit.
+	MaxConcurrentRequests int
+
+	// MaxConcurrentRequestsByClient overrides MaxConcurrentRequests for the
+	// named clients.
+	MaxConcurrentRequestsByClient map[string]int
+
+	// QueueTimeout is how long a request waits for one of the concurrent
+	// requests to finish before it fails with ErrSaturated. Defaults to 10
+	// seconds.
+	QueueTimeout time.Duration
+}
+
+// TransportConfig tunes the connection pool of a client, e.g. of a connector
+// on a hot path. Zero values keep the settings of the FactoryConfig.
+type TransportConfig struct {
+	MaxIdleConnsPerHost int
+	IdleConnTimeout     time.Duration
+	TLSSessionCacheSize int
+}
+
+// ErrSaturated is returned by clients whose concurrent requests stayed at
+// their limit for the queue timeout.
+var ErrSaturated = errors.New("too many concurrent upstream requests")
+
+// Factory creates HTTP clients for calls to upstream services. Clients with
+// the same TLS settings share a transport, and so its connection pool, and
+// requests are counted per client and upstream host.
+type Factory struct {
+	config FactoryConfig
+	proxy  func(*http.Request) (*url.URL, error)
+
+	mu         sync.Mutex
+	transports map[string]*http.Transport
+	limiters   map[string]*limiter
+
+	requests *prometheus.CounterVec
+	duration *prometheus.HistogramVec
+	inFlight *prometheus.GaugeVec
+	queued   *prometheus.GaugeVec
+	rejected *prometheus.CounterVec
+}
+
+// NewFactory returns a factory for clients with the config's settings.
+func NewFactory(c FactoryConfig) (*Factory, error) {
+	f := &Factory{
+		config:     c,
+		proxy:      http.ProxyFromEnvironment,
+		transports: make(map[string]*http.Transport),
+		limiters:   make(map[string]*limiter),
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "upstream_http_requests_total",
+			Help: "Count of requests to upstream services by client, host and status code.",
+		}, []string{"client", "host", "code"}),
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "upstream_http_request_duration_seconds",
+			Help:    "Duration of requests to upstream services by client and host.",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"client", "host"}),
+		inFlight: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "upstream_http_requests_in_flight",
+			Help: "Requests to upstream services in flight by client, for clients with a concurrency limit.",
+		}, []string{"client"}),
+		queued: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "upstream_http_requests_queued",
+			Help: "Requests to upstream services waiting for the concurrency limit of their client.",
+		}, []string{"client"}),
+		rejected: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "upstream_http_requests_rejected_total",
+			Help: "Count of requests to upstream services which timed out waiting for the concurrency limit of their client.",
+		}, []string{"client"}),
+	}
+	if c.MaxConcurrentRequests < 0 {
+		return nil, fmt.Errorf("invalid maximum of concurrent requests %d", c.MaxConcurrentRequests)
+	}
+	for name, n := range c.MaxConcurrentRequestsByClient {
+		if n < 0 {
+			return nil, fmt.Errorf("invalid maximum of concurrent requests %d for %q", n, name)
+		}
+	}
+	if c.Proxy != "" {
+		proxyURL, err := url.Parse(c.Proxy)
+		if err != nil {
+			return nil, fmt.Errorf("invalid proxy %q: %v", c.Proxy, err)
+		}
+		f.proxy = http.ProxyURL(proxyURL)
+	}
+	return f, nil
+}
+
+// Register registers the request metrics of the clients.
+func (f *Factory) Register(r prometheus.Registerer) error {
+	for _, c := range []prometheus.Collector{f.requests, f.duration, f.inFlight, f.queued, f.rejected} {
+		if err := r.Register(c); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Client returns a client for the named user, e.g. a connector ID, trusting
+// the given root CAs in addition to the system pool.
+func (f *Factory) Client(name string, rootCAs []string, insecureSkipVerify bool) (*http.Client, error) {
+	return f.TunedClient(name, rootCAs, insecureSkipVerify, TransportConfig{})
+}
+
+// TunedClient is like Client, with the connection pool tuned by tc. Clients
+// with the same TLS settings and tuning share a transport.
+func (f *Factory) TunedClient(name string, rootCAs []string, insecureSkipVerify bool, tc TransportConfig) (*http.Client, error) {
+	transport, err := f.transport(rootCAs, insecureSkipVerify, f.tuning(tc))
+	if err != nil {
+		return nil, err
+	}
+	return &http.Client{
+		Transport: &instrumentedTransport{next: transport, factory: f, name: name, limiter: f.limiter(name)},
+		Timeout:   f.config.Timeout,
+	}, nil
+}
+
+// limiter returns the concurrency limiter shared by the clients of name, or
+// nil if they have no limit.
+func (f *Factory) limiter(name string) *limiter {
+	n, ok := f.config.MaxConcurrentRequestsByClient[name]
+	if !ok {
+		n = f.config.MaxConcurrentRequests
+	}
+	if n == 0 {
+		return nil
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if l, ok := f.limiters[name]; ok {
+		return l
+	}
+	timeout := f.config.QueueTimeout
+	if timeout == 0 {
+		timeout = 10 * time.Second
+	}
+	l := &limiter{
+		slots:    make(chan struct{}, n),
+		timeout:  timeout,
+		inFlight: f.inFlight.WithLabelValues(name),
+		queued:   f.queued.WithLabelValues(name),
+		rejected: f.rejected.WithLabelValues(name),
+	}
+	f.limiters[name] = l
+	return l
+}
+
+// limiter is a semaphore bounding the requests in flight of a client.
+type limiter struct {
+	slots    chan struct{}
+	timeout  time.Duration
+	inFlight prometheus.Gauge
+	queued   prometheus.Gauge
+	rejected prometheus.Counter
+}
+
+// acquire waits for a free slot, at most the queue timeout.
+func (l *limiter) acquire(ctx context.Context) error {
+	select {
+	case l.slots <- struct{}{}:
+		l.inFlight.Inc()
+		return nil
+	default:
+	}
+
+	l.queued.Inc()
+	defer l.queued.Dec()
+	timer := time.NewTimer(l.timeout)
+	defer timer.Stop()
+	select {
+	case l.slots <- struct{}{}:
+		l.inFlight.Inc()
+		return nil
+	case <-timer.C:
+		l.rejected.Inc()
+		return ErrSaturated
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (l *limiter) release() {
+	l.inFlight.Dec()
+	<-l.slots
+}
+
+// releasingBody releases the slot of a request once its response body is
+// closed or read to the end.
+type releasingBody struct {
+	io.ReadCloser
+	release func()
+}
+
+func (b *releasingBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if err == io.EOF {
+		b.release()
+	}
+	return n, err
+}
+
+func (b *releasingBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.release()
+	return err
+}
+
+// tuning fills in the zero values of tc from the factory config.
+func (f *Factory) tuning(tc TransportConfig) TransportConfig {
+	if tc.MaxIdleConnsPerHost == 0 {
+		tc.MaxIdleConnsPerHost = f.config.MaxIdleConnsPerHost
+	}
+	if tc.IdleConnTimeout == 0 {
+		tc.IdleConnTimeout = f.config.IdleConnTimeout
+	}
+	if tc.IdleConnTimeout == 0 {
+		tc.IdleConnTimeout = 90 * time.Second
+	}
+	if tc.TLSSessionCacheSize == 0 {
+		tc.TLSSessionCacheSize = f.config.TLSSessionCacheSize
+	}
+	if tc.TLSSessionCacheSize == 0 {
+		tc.TLSSessionCacheSize = 64
+	}
+	return tc
+}
+
+func (f *Factory) transport(rootCAs []string, insecureSkipVerify bool, tc TransportConfig) (*http.Transport, error) {
+	key := fmt.Sprintf("%t\x00%d\x00%s\x00%d\x00", insecureSkipVerify, tc.MaxIdleConnsPerHost, tc.IdleConnTimeout, tc.TLSSessionCacheSize) +
+		strings.Join(rootCAs, "\x00")
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if t, ok := f.transports[key]; ok {
+		return t, nil
+	}
+
+	tlsConfig, err := newTLSConfig(rootCAs, insecureSkipVerify)
+	if err != nil {
+		return nil, err
+	}
+	if tc.TLSSessionCacheSize > 0 {
+		tlsConfig.ClientSessionCache = tls.NewLRUClientSessionCache(tc.TLSSessionCacheSize)
+	}
+	t := &http.Transport{
+		TLSClientConfig: tlsConfig,
+		Proxy:           f.proxy,
+		DialContext: (&net.Dialer{
+			Timeout:   30 * time.Second,
+			KeepAlive: 30 * time.Second,
+		}).DialContext,
+		MaxIdleConns:          100,
+		MaxIdleConnsPerHost:   tc.MaxIdleConnsPerHost,
+		MaxConnsPerHost:       f.config.MaxConnsPerHost,
+		IdleConnTimeout:       tc.IdleConnTimeout,
+		TLSHandshakeTimeout:   10 * time.Second,
+		ExpectContinueTimeout: 1 * time.Second,
+		ForceAttemptHTTP2:     true,
+	}
+	f.transports[key] = t
+	return t, nil
+}
+
+// CloseIdleConnections closes the idle connections of all clients.
+func (f *Factory) CloseIdleConnections() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, t := range f.transports {
+		t.CloseIdleConnections()
+	}
+}
+
+// RequestIDHeader carries the ID of the dex request upstream requests are
+// made for, so they can be correlated in the logs of the upstream.
+const RequestIDHeader = "X-Request-Id"
+
+type requestIDKey struct{}
+
+// WithRequestID makes requests sent with ctx by clients of a Factory carry
+// id in the RequestIDHeader.
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+type instrumentedTransport struct {
+	next    http.RoundTripper
+	factory *Factory
+	name    string
+	limiter *limiter
+}
+
+func (t *instrumentedTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if id, ok := req.Context().Value(requestIDKey{}).(string); ok && req.Header.Get(RequestIDHeader) == "" {
+		// Round trippers must not modify the request.
+		req = req.Clone(req.Context())
+		req.Header.Set(RequestIDHeader, id)
+	}
+
+	if t.limiter != nil {
+		if err := t.limiter.acquire(req.Context()); err != nil {
+			if req.Body != nil {
+				req.Body.Close()
+			}
+			return nil, fmt.Errorf("%s: %w", t.name, err)
+		}
+	}
+
+	start := time.Now()
+	resp, err := t.next.RoundTrip(req)
+	code := "error"
+	if err == nil {
+		code = strconv.Itoa(resp.StatusCode)
+	}
+	if t.limiter != nil {
+		if err != nil {
+			t.limiter.release()
+		} else {
+			resp.Body = &releasingBody{ReadCloser: resp.Body, release: sync.OnceFunc(t.limiter.release)}
+		}
+	}
+	t.factory.requests.WithLabelValues(t.name, req.URL.Host, code).Inc()
+	t.factory.duration.WithLabelValues(t.name, req.URL.Host).Observe(time.Since(start).Seconds())
+	return resp, err
+}
@@ -0,0 +1,181 @@
+package httpclient_test
+
+import (
+	"context"
+	"encoding/pem"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dexidp/dex/pkg/httpclient"
+)
+
+func TestFactory(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+	}))
+	defer ts.Close()
+
+	f, err := httpclient.NewFactory(httpclient.FactoryConfig{MaxIdleConnsPerHost: 10})
+	require.NoError(t, err)
+	registry := prometheus.NewRegistry()
+	require.NoError(t, f.Register(registry))
+
+	hsdp, err := f.Client("hsdp", nil, false)
+	require.NoError(t, err)
+	oidc, err := f.Client("oidc", nil, false)
+	require.NoError(t, err)
+
+	for _, c := range []*http.Client{hsdp, hsdp, oidc} {
+		resp, err := c.Get(ts.URL)
+		require.NoError(t, err)
+		resp.Body.Close()
+	}
+
+	host := strings.TrimPrefix(ts.URL, "http://")
+	expected := `
+# HELP upstream_http_requests_total Count of requests to upstream services by client, host and status code.
+# TYPE upstream_http_requests_total counter
+upstream_http_requests_total{client="hsdp",code="418",host="` + host + `"} 2
+upstream_http_requests_total{client="oidc",code="418",host="` + host + `"} 1
+`
+	assert.NoError(t, testutil.GatherAndCompare(registry, strings.NewReader(expected), "upstream_http_requests_total"))
+}
+
+func TestFactoryProxy(t *testing.T) {
+	var proxied string
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		proxied = r.URL.String()
+	}))
+	defer proxy.Close()
+
+	f, err := httpclient.NewFactory(httpclient.FactoryConfig{Proxy: proxy.URL})
+	require.NoError(t, err)
+	c, err := f.Client("hsdp", nil, false)
+	require.NoError(t, err)
+
+	resp, err := c.Get("http://iam.example.com/authorize/oauth2/token")
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, "http://iam.example.com/authorize/oauth2/token", proxied)
+
+	_, err = httpclient.NewFactory(httpclient.FactoryConfig{Proxy: "://"})
+	assert.Error(t, err)
+}
+
+func TestFactoryRequestID(t *testing.T) {
+	var got []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = append(got, r.Header.Get(httpclient.RequestIDHeader))
+	}))
+	defer ts.Close()
+
+	f, err := httpclient.NewFactory(httpclient.FactoryConfig{})
+	require.NoError(t, err)
+	c, err := f.Client("hsdp", nil, false)
+	require.NoError(t, err)
+
+	for _, ctx := range []context.Context{t.Context(), httpclient.WithRequestID(t.Context(), "req-1")} {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, ts.URL, nil)
+		require.NoError(t, err)
+		resp, err := c.Do(req)
+		require.NoError(t, err)
+		resp.Body.Close()
+		require.Empty(t, req.Header.Get(httpclient.RequestIDHeader), "request must not be modified")
+	}
+	require.Equal(t, []string{"", "req-1"}, got)
+}
+
+func TestFactoryConcurrencyLimit(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+	defer ts.Close()
+
+	f, err := httpclient.NewFactory(httpclient.FactoryConfig{
+		MaxConcurrentRequests:         1,
+		MaxConcurrentRequestsByClient: map[string]int{"oidc": 0},
+		QueueTimeout:                  50 * time.Millisecond,
+	})
+	require.NoError(t, err)
+	registry := prometheus.NewRegistry()
+	require.NoError(t, f.Register(registry))
+
+	hsdp, err := f.Client("hsdp", nil, false)
+	require.NoError(t, err)
+	// Clients of the same name share the limit.
+	hsdp2, err := f.Client("hsdp", nil, false)
+	require.NoError(t, err)
+	oidc, err := f.Client("oidc", nil, false)
+	require.NoError(t, err)
+
+	// The slot is held until the response body is closed.
+	resp, err := hsdp.Get(ts.URL)
+	require.NoError(t, err)
+
+	_, err = hsdp2.Get(ts.URL)
+	require.ErrorIs(t, err, httpclient.ErrSaturated)
+
+	other, err := oidc.Get(ts.URL)
+	require.NoError(t, err, "clients without a limit are not throttled")
+	other.Body.Close()
+
+	resp.Body.Close()
+	resp, err = hsdp2.Get(ts.URL)
+	require.NoError(t, err)
+	_, err = io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	resp.Body.Close()
+
+	expected := `
+# HELP upstream_http_requests_in_flight Requests to upstream services in flight by client, for clients with a concurrency limit.
+# TYPE upstream_http_requests_in_flight gauge
+upstream_http_requests_in_flight{client="hsdp"} 0
+# HELP upstream_http_requests_rejected_total Count of requests to upstream services which timed out waiting for the concurrency limit of their client.
+# TYPE upstream_http_requests_rejected_total counter
+upstream_http_requests_rejected_total{client="hsdp"} 1
+`
+	assert.NoError(t, testutil.GatherAndCompare(registry, strings.NewReader(expected), "upstream_http_requests_in_flight", "upstream_http_requests_rejected_total"))
+
+	_, err = httpclient.NewFactory(httpclient.FactoryConfig{MaxConcurrentRequests: -1})
+	require.Error(t, err)
+}
+
+func TestFactoryTLSSessionResumption(t *testing.T) {
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+	defer ts.Close()
+	rootCA := string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: ts.Certificate().Raw}))
+
+	f, err := httpclient.NewFactory(httpclient.FactoryConfig{})
+	require.NoError(t, err)
+
+	resumed := func(tc httpclient.TransportConfig) bool {
+		t.Helper()
+		c, err := f.TunedClient("hsdp", []string{rootCA}, false, tc)
+		require.NoError(t, err)
+		var didResume bool
+		for range 2 {
+			resp, err := c.Get(ts.URL)
+			require.NoError(t, err)
+			_, err = io.ReadAll(resp.Body)
+			require.NoError(t, err)
+			resp.Body.Close()
+			didResume = resp.TLS.DidResume
+			// Force a new connection.
+			f.CloseIdleConnections()
+		}
+		return didResume
+	}
+	assert.True(t, resumed(httpclient.TransportConfig{MaxIdleConnsPerHost: 10}), "TLS sessions are resumed by default")
+	assert.False(t, resumed(httpclient.TransportConfig{TLSSessionCacheSize: -1}))
+}
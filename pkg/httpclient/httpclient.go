@@ -31,7 +31,7 @@ func extractCAs(input []string) [][]byte {
 	return result
 }
 
-func NewHTTPClient(rootCAs []string, insecureSkipVerify bool) (*http.Client, error) {
+func newTLSConfig(rootCAs []string, insecureSkipVerify bool) (*tls.Config, error) {
 	pool, err := x509.SystemCertPool()
 	if err != nil {
 		return nil, err
@@ -45,10 +45,18 @@ func NewHTTPClient(rootCAs []string, insecureSkipVerify bool) (*http.Client, err
 				"or a path to a PEM encoded certificate", index)
 		}
 	}
+	return &tlsConfig, nil
+}
+
+func NewHTTPClient(rootCAs []string, insecureSkipVerify bool) (*http.Client, error) {
+	tlsConfig, err := newTLSConfig(rootCAs, insecureSkipVerify)
+	if err != nil {
+		return nil, err
+	}
 
 	return &http.Client{
 		Transport: &http.Transport{
-			TLSClientConfig: &tlsConfig,
+			TLSClientConfig: tlsConfig,
 			Proxy:           http.ProxyFromEnvironment,
 			DialContext: (&net.Dialer{
 				Timeout:   30 * time.Second,
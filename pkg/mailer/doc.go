@@ -0,0 +1,3 @@
+// Package mailer delivers the emails dex sends to users, such as password
+// reset links, over SMTP or to the log for development.
+package mailer
@@ -0,0 +1,135 @@
+package mailer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"mime"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Message is a plain text email to a single recipient.
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer sends emails.
+type Mailer interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// SMTPConfig configures a mailer that delivers emails through an SMTP server.
+// The connection is upgraded with STARTTLS if the server supports it.
+type SMTPConfig struct {
+	// Host and Port of the SMTP server. Port defaults to 587.
+	Host string `json:"host"`
+	Port int    `json:"port"`
+
+	// Username and Password authenticate with PLAIN auth. If empty, no
+	// authentication is attempted.
+	Username string `json:"username"`
+	Password string `json:"password"`
+
+	// From is the sender address, e.g. "Dex <noreply@example.com>".
+	From string `json:"from"`
+}
+
+// Open returns a mailer for the SMTP server.
+func (c *SMTPConfig) Open(logger *slog.Logger) (Mailer, error) {
+	if c.Host == "" {
+		return nil, errors.New("smtp: no host specified")
+	}
+	from, err := mail.ParseAddress(c.From)
+	if err != nil {
+		return nil, fmt.Errorf("smtp: invalid from address %q: %v", c.From, err)
+	}
+	port := c.Port
+	if port == 0 {
+		port = 587
+	}
+
+	m := &smtpMailer{
+		addr:     net.JoinHostPort(c.Host, strconv.Itoa(port)),
+		from:     from,
+		sendMail: smtp.SendMail,
+		now:      time.Now,
+	}
+	if c.Username != "" {
+		m.auth = smtp.PlainAuth("", c.Username, c.Password, c.Host)
+	}
+	return m, nil
+}
+
+type smtpMailer struct {
+	addr string
+	auth smtp.Auth
+	from *mail.Address
+
+	sendMail func(addr string, a smtp.Auth, from string, to []string, msg []byte) error
+	now      func() time.Time
+}
+
+func (m *smtpMailer) Send(ctx context.Context, msg Message) error {
+	to, err := mail.ParseAddress(msg.To)
+	if err != nil {
+		return fmt.Errorf("smtp: invalid recipient %q: %v", msg.To, err)
+	}
+	data, err := m.format(to, msg)
+	if err != nil {
+		return err
+	}
+	if err := m.sendMail(m.addr, m.auth, m.from.Address, []string{to.Address}, data); err != nil {
+		return fmt.Errorf("smtp: send: %v", err)
+	}
+	return nil
+}
+
+// format renders a message with the headers required by RFC 5322. Header
+// values are checked for line breaks so user input can't inject headers.
+func (m *smtpMailer) format(to *mail.Address, msg Message) ([]byte, error) {
+	if strings.ContainsAny(msg.Subject, "\r\n") {
+		return nil, errors.New("smtp: subject contains a line break")
+	}
+
+	var b strings.Builder
+	header := func(key, value string) {
+		b.WriteString(key + ": " + value + "\r\n")
+	}
+	header("From", m.from.String())
+	header("To", to.String())
+	header("Subject", mime.QEncoding.Encode("utf-8", msg.Subject))
+	header("Date", m.now().Format(time.RFC1123Z))
+	header("MIME-Version", "1.0")
+	header("Content-Type", `text/plain; charset="utf-8"`)
+	header("Content-Transfer-Encoding", "8bit")
+	b.WriteString("\r\n")
+	b.WriteString(strings.ReplaceAll(strings.ReplaceAll(msg.Body, "\r\n", "\n"), "\n", "\r\n"))
+	return []byte(b.String()), nil
+}
+
+// LogConfig configures a mailer that writes emails to the log instead of
+// sending them. It is meant for development only, as the log then holds the
+// links sent to users.
+type LogConfig struct{}
+
+// Open returns a mailer that logs emails.
+func (c *LogConfig) Open(logger *slog.Logger) (Mailer, error) {
+	return logMailer{logger}, nil
+}
+
+type logMailer struct {
+	logger *slog.Logger
+}
+
+func (m logMailer) Send(ctx context.Context, msg Message) error {
+	m.logger.InfoContext(ctx, "email", "to", msg.To, "subject", msg.Subject, "body", msg.Body)
+	return nil
+}
@@ -0,0 +1,59 @@
+package mailer
+
+import (
+	"context"
+	"log/slog"
+	"net/smtp"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSMTPMailer(t *testing.T) {
+	c := SMTPConfig{Host: "smtp.example.com", Username: "dex", Password: "secret", From: "Dex <noreply@example.com>"}
+	mailer, err := c.Open(slog.New(slog.DiscardHandler))
+	require.NoError(t, err)
+
+	var (
+		gotAddr string
+		gotFrom string
+		gotTo   []string
+		gotMsg  string
+	)
+	m := mailer.(*smtpMailer)
+	m.now = func() time.Time { return time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC) }
+	m.sendMail = func(addr string, a smtp.Auth, from string, to []string, msg []byte) error {
+		gotAddr, gotFrom, gotTo, gotMsg = addr, from, to, string(msg)
+		return nil
+	}
+
+	err = m.Send(context.Background(), Message{
+		To:      "jane@example.com",
+		Subject: "Réinitialiser",
+		Body:    "Hello\nWorld",
+	})
+	require.NoError(t, err)
+	require.Equal(t, "smtp.example.com:587", gotAddr)
+	require.Equal(t, "noreply@example.com", gotFrom)
+	require.Equal(t, []string{"jane@example.com"}, gotTo)
+	require.Equal(t, "From: \"Dex\" <noreply@example.com>\r\n"+
+		"To: <jane@example.com>\r\n"+
+		"Subject: =?utf-8?q?R=C3=A9initialiser?=\r\n"+
+		"Date: Fri, 02 Jan 2026 03:04:05 +0000\r\n"+
+		"MIME-Version: 1.0\r\n"+
+		"Content-Type: text/plain; charset=\"utf-8\"\r\n"+
+		"Content-Transfer-Encoding: 8bit\r\n"+
+		"\r\n"+
+		"Hello\r\nWorld", gotMsg)
+
+	require.Error(t, m.Send(context.Background(), Message{To: "jane@example.com\r\nBcc: eve@example.com", Subject: "Hi"}))
+	require.Error(t, m.Send(context.Background(), Message{To: "jane@example.com", Subject: "Hi\r\nBcc: eve@example.com"}))
+}
+
+func TestSMTPConfigInvalid(t *testing.T) {
+	_, err := (&SMTPConfig{From: "noreply@example.com"}).Open(slog.New(slog.DiscardHandler))
+	require.Error(t, err)
+	_, err = (&SMTPConfig{Host: "smtp.example.com", From: "not an address"}).Open(slog.New(slog.DiscardHandler))
+	require.Error(t, err)
+}
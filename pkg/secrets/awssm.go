@@ -0,0 +1,54 @@
+package secrets
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsconfig "github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/secretsmanager"
+)
+
+type secretsManagerAPI interface {
+	GetSecretValue(ctx context.Context, params *secretsmanager.GetSecretValueInput, optFns ...func(*secretsmanager.Options)) (*secretsmanager.GetSecretValueOutput, error)
+}
+
+type awsSecretsManagerResolver struct {
+	client secretsManagerAPI
+}
+
+// NewAWSSecretsManagerResolver returns a resolver for awssm:// references using
+// the default AWS credential and region chain.
+func NewAWSSecretsManagerResolver() (Resolver, error) {
+	cfg, err := awsconfig.LoadDefaultConfig(context.Background())
+	if err != nil {
+		return nil, err
+	}
+	return &awsSecretsManagerResolver{client: secretsmanager.NewFromConfig(cfg)}, nil
+}
+
+func (a *awsSecretsManagerResolver) Resolve(ctx context.Context, secretID, key string) (string, error) {
+	if secretID == "" {
+		return "", fmt.Errorf("expected awssm://<secret-id>[#<key>]")
+	}
+
+	out, err := a.client.GetSecretValue(ctx, &secretsmanager.GetSecretValueInput{
+		SecretId: aws.String(secretID),
+	})
+	if err != nil {
+		return "", err
+	}
+	if out.SecretString == nil {
+		return "", fmt.Errorf("secret has no string value")
+	}
+	if key == "" {
+		return *out.SecretString, nil
+	}
+
+	var data map[string]interface{}
+	if err := json.Unmarshal([]byte(*out.SecretString), &data); err != nil {
+		return "", fmt.Errorf("secret is not a JSON object")
+	}
+	return stringField(data, key)
+}
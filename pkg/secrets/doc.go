@@ -0,0 +1,4 @@
+// Package secrets resolves references to secrets kept in external stores, such
+// as HashiCorp Vault (vault://) or AWS Secrets Manager (awssm://), so they do
+// not have to be written into the config file.
+package secrets
@@ -0,0 +1,116 @@
+package secrets
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// Resolver fetches the value a secret reference points to. For a reference
+// "scheme://path#key", path and key are passed without the separators.
+type Resolver interface {
+	Resolve(ctx context.Context, path, key string) (string, error)
+}
+
+// Registry maps URI schemes to the resolvers handling them. Resolvers are
+// created on first use, so that unused providers do not need credentials.
+type Registry struct {
+	mu        sync.Mutex
+	factories map[string]func() (Resolver, error)
+	resolvers map[string]Resolver
+}
+
+// NewRegistry returns an empty registry.
+func NewRegistry() *Registry {
+	return &Registry{
+		factories: make(map[string]func() (Resolver, error)),
+		resolvers: make(map[string]Resolver),
+	}
+}
+
+// Register adds a resolver for a URI scheme.
+func (r *Registry) Register(scheme string, factory func() (Resolver, error)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.factories[scheme] = factory
+	delete(r.resolvers, scheme)
+}
+
+// IsReference reports whether value is a URI with a registered scheme.
+func (r *Registry) IsReference(value string) bool {
+	scheme, _, ok := strings.Cut(value, "://")
+	if !ok {
+		return false
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	_, ok = r.factories[scheme]
+	return ok
+}
+
+// Resolve returns the secret a reference points to. Errors never contain the
+// secret value.
+func (r *Registry) Resolve(ctx context.Context, value string) (string, error) {
+	scheme, rest, ok := strings.Cut(value, "://")
+	if !ok {
+		return "", fmt.Errorf("invalid secret reference")
+	}
+	path, key, _ := strings.Cut(rest, "#")
+
+	resolver, err := r.resolver(scheme)
+	if err != nil {
+		return "", err
+	}
+
+	secret, err := resolver.Resolve(ctx, path, key)
+	if err != nil {
+		return "", fmt.Errorf("resolve %s://%s: %v", scheme, path, err)
+	}
+	return secret, nil
+}
+
+func (r *Registry) resolver(scheme string) (Resolver, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if resolver, ok := r.resolvers[scheme]; ok {
+		return resolver, nil
+	}
+	factory, ok := r.factories[scheme]
+	if !ok {
+		return nil, fmt.Errorf("unknown secret provider %q", scheme)
+	}
+	resolver, err := factory()
+	if err != nil {
+		return nil, fmt.Errorf("initialize %s secret provider: %v", scheme, err)
+	}
+	r.resolvers[scheme] = resolver
+	return resolver, nil
+}
+
+// Default is the registry of the built-in providers.
+//
+//   - vault://<mount>/<path>#<key> reads a key of a KV version 2 secret. The
+//     client is configured through the standard VAULT_* environment variables.
+//   - awssm://<secret-id>#<key> reads an AWS Secrets Manager secret. Without a
+//     key the whole secret string is returned, otherwise it is parsed as a JSON
+//     object. Credentials and region come from the default AWS config chain.
+var Default = func() *Registry {
+	r := NewRegistry()
+	r.Register("vault", NewVaultResolver)
+	r.Register("awssm", NewAWSSecretsManagerResolver)
+	return r
+}()
+
+func stringField(data map[string]interface{}, key string) (string, error) {
+	v, ok := data[key]
+	if !ok {
+		return "", fmt.Errorf("secret has no key %q", key)
+	}
+	s, ok := v.(string)
+	if !ok {
+		return "", fmt.Errorf("secret key %q is not a string", key)
+	}
+	return s, nil
+}
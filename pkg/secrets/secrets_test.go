@@ -0,0 +1,112 @@
+package secrets
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/secretsmanager"
+	vault "github.com/openbao/openbao/api/v2"
+	"github.com/stretchr/testify/require"
+)
+
+type resolverFunc func(ctx context.Context, path, key string) (string, error)
+
+func (f resolverFunc) Resolve(ctx context.Context, path, key string) (string, error) {
+	return f(ctx, path, key)
+}
+
+func TestRegistry(t *testing.T) {
+	r := NewRegistry()
+
+	var created int
+	r.Register("test", func() (Resolver, error) {
+		created++
+		return resolverFunc(func(_ context.Context, path, key string) (string, error) {
+			if path == "missing" {
+				return "", errors.New("not found")
+			}
+			return path + "/" + key, nil
+		}), nil
+	})
+
+	require.True(t, r.IsReference("test://foo#bar"))
+	require.False(t, r.IsReference("https://example.com"))
+	require.False(t, r.IsReference("test"))
+
+	got, err := r.Resolve(t.Context(), "test://foo/bar#baz")
+	require.NoError(t, err)
+	require.Equal(t, "foo/bar/baz", got)
+
+	_, err = r.Resolve(t.Context(), "test://missing#key")
+	require.EqualError(t, err, "resolve test://missing: not found")
+
+	_, err = r.Resolve(t.Context(), "other://foo")
+	require.EqualError(t, err, `unknown secret provider "other"`)
+
+	require.Equal(t, 1, created, "resolver should be created once")
+}
+
+func TestVaultResolver(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/secret/data/dex/hsdp" {
+			http.NotFound(w, r)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"data": map[string]interface{}{
+				"data":     map[string]interface{}{"clientSecret": "s3cret"},
+				"metadata": map[string]interface{}{"version": 1},
+			},
+		})
+	}))
+	defer srv.Close()
+
+	config := vault.DefaultConfig()
+	config.Address = srv.URL
+	client, err := vault.NewClient(config)
+	require.NoError(t, err)
+	v := &vaultResolver{client: client}
+
+	got, err := v.Resolve(t.Context(), "secret/dex/hsdp", "clientSecret")
+	require.NoError(t, err)
+	require.Equal(t, "s3cret", got)
+
+	_, err = v.Resolve(t.Context(), "secret/dex/hsdp", "other")
+	require.EqualError(t, err, `secret has no key "other"`)
+
+	_, err = v.Resolve(t.Context(), "secret/dex/hsdp", "")
+	require.Error(t, err)
+}
+
+type fakeSecretsManager map[string]string
+
+func (f fakeSecretsManager) GetSecretValue(_ context.Context, in *secretsmanager.GetSecretValueInput, _ ...func(*secretsmanager.Options)) (*secretsmanager.GetSecretValueOutput, error) {
+	v, ok := f[aws.ToString(in.SecretId)]
+	if !ok {
+		return nil, errors.New("ResourceNotFoundException")
+	}
+	return &secretsmanager.GetSecretValueOutput{SecretString: aws.String(v)}, nil
+}
+
+func TestAWSSecretsManagerResolver(t *testing.T) {
+	a := &awsSecretsManagerResolver{client: fakeSecretsManager{
+		"dex/postgres": "plain-password",
+		"arn:aws:secretsmanager:eu-west-1:123456789012:secret:dex/hsdp": `{"clientSecret":"s3cret"}`,
+	}}
+
+	got, err := a.Resolve(t.Context(), "dex/postgres", "")
+	require.NoError(t, err)
+	require.Equal(t, "plain-password", got)
+
+	got, err = a.Resolve(t.Context(), "arn:aws:secretsmanager:eu-west-1:123456789012:secret:dex/hsdp", "clientSecret")
+	require.NoError(t, err)
+	require.Equal(t, "s3cret", got)
+
+	_, err = a.Resolve(t.Context(), "dex/postgres", "password")
+	require.EqualError(t, err, "secret is not a JSON object")
+}
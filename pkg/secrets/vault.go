@@ -0,0 +1,36 @@
+package secrets
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	vault "github.com/openbao/openbao/api/v2"
+)
+
+type vaultResolver struct {
+	client *vault.Client
+}
+
+// NewVaultResolver returns a resolver for vault:// references, configured from
+// the VAULT_ADDR, VAULT_TOKEN and related environment variables.
+func NewVaultResolver() (Resolver, error) {
+	client, err := vault.NewClient(vault.DefaultConfig())
+	if err != nil {
+		return nil, err
+	}
+	return &vaultResolver{client: client}, nil
+}
+
+func (v *vaultResolver) Resolve(ctx context.Context, ref, key string) (string, error) {
+	mount, path, _ := strings.Cut(ref, "/")
+	if mount == "" || path == "" || key == "" {
+		return "", fmt.Errorf("expected vault://<mount>/<path>#<key>")
+	}
+
+	secret, err := v.client.KVv2(mount).Get(ctx, path)
+	if err != nil {
+		return "", err
+	}
+	return stringField(secret.Data, key)
+}
@@ -0,0 +1,169 @@
+// Package admin implements an embedded admin web UI for dex. Admins sign in
+// through dex itself and manage OAuth2 clients, view connectors and revoke
+// refresh tokens through the gRPC API implementation.
+package admin
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/coreos/go-oidc/v3/oidc"
+	"golang.org/x/oauth2"
+
+	"github.com/dexidp/dex/api/v2"
+)
+
+// Config holds the configuration of the admin UI.
+type Config struct {
+	// API performs the admin actions. Usually the value returned by
+	// server.NewAPI.
+	API api.DexServer
+
+	// Issuer is the issuer URL of dex. Admins sign in through it.
+	Issuer string
+
+	// URL is where browsers reach the UI, for example
+	// "http://127.0.0.1:5559/admin". Its path is the path the UI is served
+	// under.
+	URL string
+
+	// ClientID and ClientSecret identify the UI to dex. The client must be
+	// allowed to redirect to URL + "/callback".
+	ClientID     string
+	ClientSecret string
+
+	// AdminGroups are the groups allowed to use the UI. Admins must be a
+	// member of at least one of them.
+	AdminGroups []string
+
+	// SessionKey signs the session cookies. If empty, a random key is
+	// generated, so admins have to sign in again after a restart.
+	SessionKey []byte
+
+	// SessionValidFor is how long an admin stays signed in. Defaults to 8
+	// hours.
+	SessionValidFor time.Duration
+
+	// HTTPClient is used to reach the issuer. Defaults to http.DefaultClient.
+	HTTPClient *http.Client
+
+	Logger *slog.Logger
+
+	// If specified, the UI uses this function to determine the current time.
+	Now func() time.Time
+}
+
+// Handler serves the admin UI.
+type Handler struct {
+	api         api.DexServer
+	basePath    string
+	secure      bool
+	oauth2      *oauth2.Config
+	verifier    *oidc.IDTokenVerifier
+	adminGroups []string
+	sessionKey  []byte
+	sessionTTL  time.Duration
+	httpClient  *http.Client
+	logger      *slog.Logger
+	now         func() time.Time
+	mux         *http.ServeMux
+}
+
+// New returns the handler of the admin UI.
+func New(c Config) (*Handler, error) {
+	if c.API == nil {
+		return nil, errors.New("admin: no API supplied")
+	}
+	if c.ClientID == "" {
+		return nil, errors.New("admin: no client ID supplied")
+	}
+	if len(c.AdminGroups) == 0 {
+		return nil, errors.New("admin: no admin groups supplied")
+	}
+	u, err := url.Parse(c.URL)
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return nil, fmt.Errorf("admin: invalid URL %q", c.URL)
+	}
+	issuer := strings.TrimSuffix(c.Issuer, "/")
+	if issuer == "" {
+		return nil, errors.New("admin: no issuer supplied")
+	}
+
+	h := &Handler{
+		api:         c.API,
+		basePath:    strings.TrimSuffix(u.Path, "/"),
+		secure:      u.Scheme == "https",
+		adminGroups: c.AdminGroups,
+		sessionKey:  c.SessionKey,
+		sessionTTL:  c.SessionValidFor,
+		httpClient:  c.HTTPClient,
+		logger:      c.Logger,
+		now:         c.Now,
+	}
+	if len(h.sessionKey) == 0 {
+		h.sessionKey = make([]byte, 32)
+		if _, err := rand.Read(h.sessionKey); err != nil {
+			return nil, fmt.Errorf("admin: generate session key: %v", err)
+		}
+	}
+	if h.sessionTTL == 0 {
+		h.sessionTTL = 8 * time.Hour
+	}
+	if h.httpClient == nil {
+		h.httpClient = http.DefaultClient
+	}
+	if h.logger == nil {
+		h.logger = slog.New(slog.DiscardHandler)
+	}
+	if h.now == nil {
+		h.now = time.Now
+	}
+
+	h.oauth2 = &oauth2.Config{
+		ClientID:     c.ClientID,
+		ClientSecret: c.ClientSecret,
+		Endpoint: oauth2.Endpoint{
+			AuthURL:  issuer + "/auth",
+			TokenURL: issuer + "/token",
+		},
+		RedirectURL: strings.TrimSuffix(c.URL, "/") + "/callback",
+		Scopes:      []string{oidc.ScopeOpenID, "email", "profile", "groups"},
+	}
+	keySet := oidc.NewRemoteKeySet(oidc.ClientContext(context.Background(), h.httpClient), issuer+"/keys")
+	h.verifier = oidc.NewVerifier(c.Issuer, keySet, &oidc.Config{ClientID: c.ClientID, Now: h.now})
+
+	p := h.basePath
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET "+p+"/{$}", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, p+"/clients", http.StatusFound)
+	})
+	mux.HandleFunc("GET "+p+"/login", h.handleLogin)
+	mux.HandleFunc("GET "+p+"/callback", h.handleCallback)
+	mux.HandleFunc("POST "+p+"/logout", h.requireAdmin(h.handleLogout))
+	mux.HandleFunc("GET "+p+"/clients", h.requireAdmin(h.handleClients))
+	mux.HandleFunc("POST "+p+"/clients/create", h.requireAdmin(h.handleCreateClient))
+	mux.HandleFunc("POST "+p+"/clients/delete", h.requireAdmin(h.handleDeleteClient))
+	mux.HandleFunc("GET "+p+"/connectors", h.requireAdmin(h.handleConnectors))
+	mux.HandleFunc("GET "+p+"/sessions", h.requireAdmin(h.handleSessions))
+	mux.HandleFunc("POST "+p+"/sessions/revoke", h.requireAdmin(h.handleRevokeSession))
+	h.mux = mux
+	return h, nil
+}
+
+// BasePath returns the path the UI is served under, without a trailing slash.
+func (h *Handler) BasePath() string {
+	return h.basePath
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("X-Frame-Options", "DENY")
+	w.Header().Set("Cache-Control", "no-store")
+	h.mux.ServeHTTP(w, r)
+}
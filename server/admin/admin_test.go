@@ -0,0 +1,232 @@
+package admin
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/cookiejar"
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/go-jose/go-jose/v4"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dexidp/dex/server"
+	"github.com/dexidp/dex/server/internal"
+	"github.com/dexidp/dex/storage"
+	"github.com/dexidp/dex/storage/memory"
+)
+
+// newTestIssuer returns a fake issuer which answers every code with an ID
+// token for a user in the given groups.
+func newTestIssuer(t *testing.T, groups *[]string) *httptest.Server {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	signer, err := jose.NewSigner(jose.SigningKey{Algorithm: jose.RS256, Key: &jose.JSONWebKey{Key: key, KeyID: "test"}}, nil)
+	require.NoError(t, err)
+
+	var issuer *httptest.Server
+	mux := http.NewServeMux()
+	mux.HandleFunc("/keys", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(jose.JSONWebKeySet{Keys: []jose.JSONWebKey{{Key: key.Public(), KeyID: "test", Algorithm: "RS256", Use: "sig"}}})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		claims, _ := json.Marshal(map[string]any{
+			"iss":    issuer.URL,
+			"sub":    "admin-sub",
+			"aud":    "dex-admin",
+			"exp":    time.Now().Add(time.Hour).Unix(),
+			"iat":    time.Now().Unix(),
+			"email":  "admin@example.com",
+			"groups": *groups,
+		})
+		jws, err := signer.Sign(claims)
+		require.NoError(t, err)
+		idToken, err := jws.CompactSerialize()
+		require.NoError(t, err)
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]any{
+			"access_token": "access",
+			"token_type":   "bearer",
+			"id_token":     idToken,
+		})
+	})
+	issuer = httptest.NewServer(mux)
+	return issuer
+}
+
+func newTestAdmin(t *testing.T, groups *[]string) (*httptest.Server, storage.Storage, *http.Client) {
+	logger := slog.New(slog.DiscardHandler)
+	issuer := newTestIssuer(t, groups)
+	t.Cleanup(issuer.Close)
+
+	s := memory.New(logger)
+	var h *Handler
+	ui := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		h.ServeHTTP(w, r)
+	}))
+	t.Cleanup(ui.Close)
+
+	var err error
+	h, err = New(Config{
+		API:          server.NewAPI(s, logger, "test", nil),
+		Issuer:       issuer.URL,
+		URL:          ui.URL + "/admin",
+		ClientID:     "dex-admin",
+		ClientSecret: "secret",
+		AdminGroups:  []string{"admins"},
+		Logger:       logger,
+	})
+	require.NoError(t, err)
+
+	jar, err := cookiejar.New(nil)
+	require.NoError(t, err)
+	client := &http.Client{
+		Jar: jar,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+	return ui, s, client
+}
+
+// signIn goes through the login flow and returns the status of the callback.
+func signIn(t *testing.T, ui *httptest.Server, client *http.Client) int {
+	resp, err := client.Get(ui.URL + "/admin/login")
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusFound, resp.StatusCode)
+	authURL, err := url.Parse(resp.Header.Get("Location"))
+	require.NoError(t, err)
+	require.Equal(t, ui.URL+"/admin/callback", authURL.Query().Get("redirect_uri"))
+
+	resp, err = client.Get(ui.URL + "/admin/callback?" + url.Values{"code": {"code"}, "state": {authURL.Query().Get("state")}}.Encode())
+	require.NoError(t, err)
+	resp.Body.Close()
+	return resp.StatusCode
+}
+
+func get(t *testing.T, client *http.Client, u string) (int, string) {
+	resp, err := client.Get(u)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	return resp.StatusCode, string(body)
+}
+
+func post(t *testing.T, client *http.Client, u string, vals url.Values) (int, string) {
+	resp, err := client.PostForm(u, vals)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	return resp.StatusCode, string(body)
+}
+
+func TestAdminUI(t *testing.T) {
+	t.Setenv("DEX_API_CONNECTORS_CRUD", "true")
+	groups := []string{"admins"}
+	ui, s, client := newTestAdmin(t, &groups)
+	ctx := t.Context()
+
+	require.NoError(t, s.CreateClient(ctx, storage.Client{ID: "existing-app", Name: "Existing App", Secret: "secret"}))
+	require.NoError(t, s.CreateConnector(ctx, storage.Connector{ID: "github", Type: "github", Name: "GitHub"}))
+
+	resp, err := client.Get(ui.URL + "/admin/clients")
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusFound, resp.StatusCode)
+	require.Equal(t, "/admin/login", resp.Header.Get("Location"))
+
+	require.Equal(t, http.StatusFound, signIn(t, ui, client))
+
+	code, body := get(t, client, ui.URL+"/admin/clients")
+	require.Equal(t, http.StatusOK, code)
+	require.Contains(t, body, "Existing App")
+	csrf := regexp.MustCompile(`name="csrf" value="([^"]+)"`).FindStringSubmatch(body)[1]
+
+	code, _ = post(t, client, ui.URL+"/admin/clients/create", url.Values{"id": {"new-app"}})
+	require.Equal(t, http.StatusForbidden, code)
+
+	code, body = post(t, client, ui.URL+"/admin/clients/create", url.Values{
+		"csrf":          {csrf},
+		"id":            {"new-app"},
+		"name":          {"New App"},
+		"redirect_uris": {"https://app.example.com/callback\nhttps://app.example.com/other"},
+	})
+	require.Equal(t, http.StatusOK, code)
+	require.Contains(t, body, "Created client new-app.")
+	c, err := s.GetClient(ctx, "new-app")
+	require.NoError(t, err)
+	require.Equal(t, []string{"https://app.example.com/callback", "https://app.example.com/other"}, c.RedirectURIs)
+	require.Contains(t, body, c.Secret)
+
+	code, _ = post(t, client, ui.URL+"/admin/clients/delete", url.Values{"csrf": {csrf}, "id": {"existing-app"}})
+	require.Equal(t, http.StatusOK, code)
+	_, err = s.GetClient(ctx, "existing-app")
+	require.Equal(t, storage.ErrNotFound, err)
+
+	code, body = get(t, client, ui.URL+"/admin/connectors")
+	require.Equal(t, http.StatusOK, code)
+	require.Contains(t, body, "GitHub")
+
+	now := time.Now()
+	require.NoError(t, s.CreateRefresh(ctx, storage.RefreshToken{ID: "refresh-1", ClientID: "new-app", CreatedAt: now, LastUsed: now}))
+	require.NoError(t, s.CreateOfflineSessions(ctx, storage.OfflineSessions{
+		UserID: "1",
+		ConnID: "local",
+		Refresh: map[string]*storage.RefreshTokenRef{
+			"new-app": {ID: "refresh-1", ClientID: "new-app", CreatedAt: now, LastUsed: now},
+		},
+	}))
+	subject, err := internal.Marshal(&internal.IDTokenSubject{UserId: "1", ConnId: "local"})
+	require.NoError(t, err)
+
+	code, body = get(t, client, ui.URL+"/admin/sessions?"+url.Values{"user_id": {subject}}.Encode())
+	require.Equal(t, http.StatusOK, code)
+	require.Contains(t, body, "new-app")
+
+	code, body = post(t, client, ui.URL+"/admin/sessions/revoke", url.Values{"csrf": {csrf}, "user_id": {subject}, "client_id": {"new-app"}})
+	require.Equal(t, http.StatusOK, code)
+	require.Contains(t, body, "No refresh tokens.")
+	_, err = s.GetRefresh(ctx, "refresh-1")
+	require.Equal(t, storage.ErrNotFound, err)
+
+	code, _ = post(t, client, ui.URL+"/admin/logout", url.Values{"csrf": {csrf}})
+	require.Equal(t, http.StatusOK, code)
+	resp, err = client.Get(ui.URL + "/admin/clients")
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusFound, resp.StatusCode)
+}
+
+func TestAdminUIRequiresAdminGroup(t *testing.T) {
+	groups := []string{"developers"}
+	ui, _, client := newTestAdmin(t, &groups)
+
+	require.Equal(t, http.StatusForbidden, signIn(t, ui, client))
+
+	resp, err := client.Get(ui.URL + "/admin/clients")
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusFound, resp.StatusCode)
+}
+
+func TestAdminUIRejectsInvalidState(t *testing.T) {
+	groups := []string{"admins"}
+	ui, _, client := newTestAdmin(t, &groups)
+
+	resp, err := client.Get(ui.URL + "/admin/login")
+	require.NoError(t, err)
+	resp.Body.Close()
+
+	code, _ := get(t, client, ui.URL+"/admin/callback?code=code&state=forged")
+	require.Equal(t, http.StatusBadRequest, code)
+}
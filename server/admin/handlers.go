@@ -0,0 +1,136 @@
+package admin
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/dexidp/dex/api/v2"
+)
+
+func (h *Handler) handleClients(w http.ResponseWriter, r *http.Request) {
+	h.renderClients(w, r, "", "")
+}
+
+func (h *Handler) renderClients(w http.ResponseWriter, r *http.Request, flash, errMsg string) {
+	resp, err := h.api.ListClients(r.Context(), &api.ListClientReq{})
+	if err != nil {
+		h.logger.ErrorContext(r.Context(), "admin: failed to list clients", "err", err)
+		h.renderMessage(w, http.StatusInternalServerError, "Failed to list clients.")
+		return
+	}
+	h.render(w, r, "clients", flash, errMsg, resp.Clients)
+}
+
+func (h *Handler) handleCreateClient(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	s := sessionFromContext(ctx)
+
+	client := &api.Client{
+		Id:           strings.TrimSpace(r.PostFormValue("id")),
+		Name:         strings.TrimSpace(r.PostFormValue("name")),
+		RedirectUris: strings.Fields(r.PostFormValue("redirect_uris")),
+		Public:       r.PostFormValue("public") == "on",
+	}
+	resp, err := h.api.CreateClient(ctx, &api.CreateClientReq{Client: client})
+	if err != nil {
+		h.audit(ctx, s, "create_client", client.Id, err.Error())
+		h.renderClients(w, r, "", "Failed to create client: "+err.Error())
+		return
+	}
+	if resp.AlreadyExists {
+		h.audit(ctx, s, "create_client", client.Id, "already exists")
+		h.renderClients(w, r, "", "A client with this ID already exists.")
+		return
+	}
+	h.audit(ctx, s, "create_client", resp.Client.Id, "")
+
+	flash := "Created client " + resp.Client.Id + "."
+	if resp.Client.Secret != "" {
+		flash += " Its secret is " + resp.Client.Secret + ". It won't be shown again."
+	}
+	h.renderClients(w, r, flash, "")
+}
+
+func (h *Handler) handleDeleteClient(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	s := sessionFromContext(ctx)
+
+	id := r.PostFormValue("id")
+	resp, err := h.api.DeleteClient(ctx, &api.DeleteClientReq{Id: id})
+	switch {
+	case err != nil:
+		h.audit(ctx, s, "delete_client", id, err.Error())
+		h.renderClients(w, r, "", "Failed to delete client: "+err.Error())
+	case resp.NotFound:
+		h.audit(ctx, s, "delete_client", id, "not found")
+		h.renderClients(w, r, "", "Client "+id+" doesn't exist.")
+	default:
+		h.audit(ctx, s, "delete_client", id, "")
+		h.renderClients(w, r, "Deleted client "+id+".", "")
+	}
+}
+
+func (h *Handler) handleConnectors(w http.ResponseWriter, r *http.Request) {
+	// Listing connectors may be disabled by a feature flag, so show why
+	// instead of failing the page.
+	resp, err := h.api.ListConnectors(r.Context(), &api.ListConnectorReq{})
+	if err != nil {
+		h.render(w, r, "connectors", "", "Failed to list connectors: "+err.Error(), []*api.Connector(nil))
+		return
+	}
+	h.render(w, r, "connectors", "", "", resp.Connectors)
+}
+
+// refreshToken is a refresh token shown on the sessions page.
+type refreshToken struct {
+	ClientID  string
+	CreatedAt time.Time
+	LastUsed  time.Time
+}
+
+func (h *Handler) handleSessions(w http.ResponseWriter, r *http.Request) {
+	h.renderSessions(w, r, r.URL.Query().Get("user_id"), "", "")
+}
+
+func (h *Handler) renderSessions(w http.ResponseWriter, r *http.Request, userID, flash, errMsg string) {
+	data := struct {
+		UserID string
+		Tokens []refreshToken
+	}{UserID: userID}
+	if userID != "" {
+		resp, err := h.api.ListRefresh(r.Context(), &api.ListRefreshReq{UserId: userID})
+		if err != nil {
+			errMsg = "Failed to list refresh tokens: " + err.Error()
+		} else {
+			for _, t := range resp.RefreshTokens {
+				data.Tokens = append(data.Tokens, refreshToken{
+					ClientID:  t.ClientId,
+					CreatedAt: time.Unix(t.CreatedAt, 0),
+					LastUsed:  time.Unix(t.LastUsed, 0),
+				})
+			}
+		}
+	}
+	h.render(w, r, "sessions", flash, errMsg, data)
+}
+
+func (h *Handler) handleRevokeSession(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	s := sessionFromContext(ctx)
+
+	userID, clientID := r.PostFormValue("user_id"), r.PostFormValue("client_id")
+	target := userID + "/" + clientID
+	resp, err := h.api.RevokeRefresh(ctx, &api.RevokeRefreshReq{UserId: userID, ClientId: clientID})
+	switch {
+	case err != nil:
+		h.audit(ctx, s, "revoke_refresh", target, err.Error())
+		h.renderSessions(w, r, userID, "", "Failed to revoke refresh token: "+err.Error())
+	case resp.NotFound:
+		h.audit(ctx, s, "revoke_refresh", target, "not found")
+		h.renderSessions(w, r, userID, "", "The refresh token doesn't exist anymore.")
+	default:
+		h.audit(ctx, s, "revoke_refresh", target, "")
+		h.renderSessions(w, r, userID, "Revoked the refresh token of client "+clientID+".", "")
+	}
+}
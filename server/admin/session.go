@@ -0,0 +1,213 @@
+package admin
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/coreos/go-oidc/v3/oidc"
+)
+
+const (
+	sessionCookie = "dex_admin_session"
+	stateCookie   = "dex_admin_state"
+)
+
+// session identifies a signed in admin.
+type session struct {
+	Subject string `json:"sub"`
+	Email   string `json:"email"`
+	Expiry  int64  `json:"exp"`
+
+	// cookie is the signed cookie value the session was read from. CSRF
+	// tokens are derived from it.
+	cookie string
+}
+
+type sessionKey struct{}
+
+// mac signs a value for a purpose, so a signature for one purpose can't be
+// replayed for another.
+func (h *Handler) mac(purpose, value string) []byte {
+	m := hmac.New(sha256.New, h.sessionKey)
+	m.Write([]byte(purpose))
+	m.Write([]byte{0})
+	m.Write([]byte(value))
+	return m.Sum(nil)
+}
+
+func (h *Handler) encodeSession(s session) (string, error) {
+	data, err := json.Marshal(s)
+	if err != nil {
+		return "", err
+	}
+	payload := base64.RawURLEncoding.EncodeToString(data)
+	return payload + "." + base64.RawURLEncoding.EncodeToString(h.mac("session", payload)), nil
+}
+
+// sessionFromRequest returns the session of the admin making a request, if
+// it has a valid session cookie.
+func (h *Handler) sessionFromRequest(r *http.Request) (session, bool) {
+	cookie, err := r.Cookie(sessionCookie)
+	if err != nil {
+		return session{}, false
+	}
+	payload, sig, ok := strings.Cut(cookie.Value, ".")
+	if !ok {
+		return session{}, false
+	}
+	mac, err := base64.RawURLEncoding.DecodeString(sig)
+	if err != nil || !hmac.Equal(mac, h.mac("session", payload)) {
+		return session{}, false
+	}
+	data, err := base64.RawURLEncoding.DecodeString(payload)
+	if err != nil {
+		return session{}, false
+	}
+	var s session
+	if err := json.Unmarshal(data, &s); err != nil || h.now().Unix() >= s.Expiry {
+		return session{}, false
+	}
+	s.cookie = cookie.Value
+	return s, true
+}
+
+// csrfToken returns the token forms must post back for a session.
+func (h *Handler) csrfToken(s session) string {
+	return base64.RawURLEncoding.EncodeToString(h.mac("csrf", s.cookie))
+}
+
+func (h *Handler) setCookie(w http.ResponseWriter, name, value string, maxAge int) {
+	path := h.basePath
+	if path == "" {
+		path = "/"
+	}
+	http.SetCookie(w, &http.Cookie{
+		Name:     name,
+		Value:    value,
+		Path:     path,
+		MaxAge:   maxAge,
+		HttpOnly: true,
+		Secure:   h.secure,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+// requireAdmin only lets signed in admins through. Posted forms must also
+// carry the CSRF token of the session.
+func (h *Handler) requireAdmin(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		s, ok := h.sessionFromRequest(r)
+		if !ok {
+			if r.Method == http.MethodGet {
+				http.Redirect(w, r, h.basePath+"/login", http.StatusFound)
+				return
+			}
+			h.renderMessage(w, http.StatusUnauthorized, "Your session has expired. Please sign in again.")
+			return
+		}
+		if r.Method == http.MethodPost {
+			token, err := base64.RawURLEncoding.DecodeString(r.PostFormValue("csrf"))
+			if err != nil || !hmac.Equal(token, h.mac("csrf", s.cookie)) {
+				h.renderMessage(w, http.StatusForbidden, "Invalid form. Please reload the page and try again.")
+				return
+			}
+		}
+		next(w, r.WithContext(context.WithValue(r.Context(), sessionKey{}, s)))
+	}
+}
+
+func sessionFromContext(ctx context.Context) session {
+	s, _ := ctx.Value(sessionKey{}).(session)
+	return s
+}
+
+func (h *Handler) handleLogin(w http.ResponseWriter, r *http.Request) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		h.logger.ErrorContext(r.Context(), "admin: failed to generate state", "err", err)
+		h.renderMessage(w, http.StatusInternalServerError, "Internal server error.")
+		return
+	}
+	state := base64.RawURLEncoding.EncodeToString(b)
+	h.setCookie(w, stateCookie, state, 600)
+	http.Redirect(w, r, h.oauth2.AuthCodeURL(state), http.StatusFound)
+}
+
+func (h *Handler) handleCallback(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	q := r.URL.Query()
+	if errType := q.Get("error"); errType != "" {
+		h.renderMessage(w, http.StatusBadRequest, "Sign in failed: "+errType+" "+q.Get("error_description"))
+		return
+	}
+	state, err := r.Cookie(stateCookie)
+	if err != nil || state.Value == "" || state.Value != q.Get("state") {
+		h.renderMessage(w, http.StatusBadRequest, "Invalid sign in state. Please try again.")
+		return
+	}
+	h.setCookie(w, stateCookie, "", -1)
+
+	ctx = oidc.ClientContext(ctx, h.httpClient)
+	token, err := h.oauth2.Exchange(ctx, q.Get("code"))
+	if err != nil {
+		h.logger.ErrorContext(ctx, "admin: failed to exchange code", "err", err)
+		h.renderMessage(w, http.StatusBadGateway, "Sign in failed.")
+		return
+	}
+	rawIDToken, _ := token.Extra("id_token").(string)
+	idToken, err := h.verifier.Verify(ctx, rawIDToken)
+	if err != nil {
+		h.logger.ErrorContext(ctx, "admin: failed to verify ID token", "err", err)
+		h.renderMessage(w, http.StatusBadGateway, "Sign in failed.")
+		return
+	}
+	var claims struct {
+		Email  string   `json:"email"`
+		Groups []string `json:"groups"`
+	}
+	if err := idToken.Claims(&claims); err != nil {
+		h.logger.ErrorContext(ctx, "admin: failed to parse ID token claims", "err", err)
+		h.renderMessage(w, http.StatusBadGateway, "Sign in failed.")
+		return
+	}
+
+	s := session{Subject: idToken.Subject, Email: claims.Email, Expiry: h.now().Add(h.sessionTTL).Unix()}
+	if !slices.ContainsFunc(claims.Groups, func(g string) bool { return slices.Contains(h.adminGroups, g) }) {
+		h.audit(ctx, s, "sign_in", "", "not in an admin group")
+		h.renderMessage(w, http.StatusForbidden, "You are not allowed to use the admin UI.")
+		return
+	}
+	value, err := h.encodeSession(s)
+	if err != nil {
+		h.logger.ErrorContext(ctx, "admin: failed to encode session", "err", err)
+		h.renderMessage(w, http.StatusInternalServerError, "Internal server error.")
+		return
+	}
+	h.setCookie(w, sessionCookie, value, int(h.sessionTTL.Seconds()))
+	h.audit(ctx, s, "sign_in", "", "")
+	http.Redirect(w, r, h.basePath+"/clients", http.StatusFound)
+}
+
+func (h *Handler) handleLogout(w http.ResponseWriter, r *http.Request) {
+	h.setCookie(w, sessionCookie, "", -1)
+	h.audit(r.Context(), sessionFromContext(r.Context()), "sign_out", "", "")
+	h.renderMessage(w, http.StatusOK, "You have been signed out.")
+}
+
+// audit records an admin action. An empty reason means the action succeeded.
+func (h *Handler) audit(ctx context.Context, s session, action, target, reason string) {
+	outcome := "succeeded"
+	if reason != "" {
+		outcome = "failed"
+	}
+	h.logger.InfoContext(ctx, "admin action",
+		"action", action, "target", target, "outcome", outcome, "reason", reason,
+		"admin", s.Email, "admin_sub", s.Subject)
+}
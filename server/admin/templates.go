@@ -0,0 +1,57 @@
+package admin
+
+import (
+	"embed"
+	"html/template"
+	"net/http"
+	"strings"
+)
+
+//go:embed templates/*.html
+var templateFS embed.FS
+
+var templates = template.Must(template.New("").Funcs(template.FuncMap{
+	"join": strings.Join,
+}).ParseFS(templateFS, "templates/*.html"))
+
+// page is the data passed to the page templates.
+type page struct {
+	Base  string
+	Page  string
+	Email string
+	CSRF  string
+	Flash string
+	Error string
+	Data  any
+}
+
+// render renders a page for a signed in admin.
+func (h *Handler) render(w http.ResponseWriter, r *http.Request, name, flash, errMsg string, data any) {
+	s := sessionFromContext(r.Context())
+	status := http.StatusOK
+	if errMsg != "" {
+		status = http.StatusBadRequest
+	}
+	h.execute(w, status, name+".html", page{
+		Base:  h.basePath,
+		Page:  name,
+		Email: s.Email,
+		CSRF:  h.csrfToken(s),
+		Flash: flash,
+		Error: errMsg,
+		Data:  data,
+	})
+}
+
+// renderMessage renders a page with only a message, for example an error.
+func (h *Handler) renderMessage(w http.ResponseWriter, status int, msg string) {
+	h.execute(w, status, "message.html", page{Base: h.basePath, Flash: msg})
+}
+
+func (h *Handler) execute(w http.ResponseWriter, status int, name string, p page) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(status)
+	if err := templates.ExecuteTemplate(w, name, p); err != nil {
+		h.logger.Error("admin: template error", "template", name, "err", err)
+	}
+}
@@ -6,7 +6,9 @@ import (
 	"errors"
 	"fmt"
 	"log/slog"
+	"sort"
 	"strconv"
+	"time"
 
 	"golang.org/x/crypto/bcrypt"
 
@@ -18,7 +20,14 @@ import (
 
 // apiVersion increases every time a new call is added to the API. Clients should use this info
 // to determine if the server supports specific features.
-const apiVersion = 3
+const apiVersion = 5
+
+const (
+	// defaultAuditEventsLimit is the number of audit events returned if the
+	// request doesn't set a limit; maxAuditEventsLimit is the most returned.
+	defaultAuditEventsLimit = 100
+	maxAuditEventsLimit     = 1000
+)
 
 const (
 	// recCost is the recommended bcrypt cost, which balances hash strength and
@@ -212,12 +221,20 @@ func (d dexAPI) CreatePassword(ctx context.Context, req *api.CreatePasswordReq)
 	} else {
 		return nil, errors.New("no hash of password supplied")
 	}
+	if req.VerifyEmail && !d.emailVerificationEnabled() {
+		return nil, errors.New("email verification is not enabled")
+	}
 
 	p := storage.Password{
 		Email:    req.Password.Email,
 		Hash:     req.Password.Hash,
 		Username: req.Password.Username,
 		UserID:   req.Password.UserId,
+		Groups:   req.Password.Groups,
+	}
+	if req.VerifyEmail {
+		verified := false
+		p.EmailVerified = &verified
 	}
 	if err := d.s.CreatePassword(ctx, p); err != nil {
 		if err == storage.ErrAlreadyExists {
@@ -227,6 +244,13 @@ func (d dexAPI) CreatePassword(ctx context.Context, req *api.CreatePasswordReq)
 		return nil, fmt.Errorf("create password: %v", err)
 	}
 
+	if req.VerifyEmail {
+		if err := d.server.sendEmailVerificationLink(ctx, p); err != nil {
+			d.logger.Error("failed to send verification email", "err", err)
+			return nil, fmt.Errorf("password created, but %v", err)
+		}
+	}
+
 	return &api.CreatePasswordResp{}, nil
 }
 
@@ -234,9 +258,15 @@ func (d dexAPI) UpdatePassword(ctx context.Context, req *api.UpdatePasswordReq)
 	if req.Email == "" {
 		return nil, errors.New("no email supplied")
 	}
-	if req.NewHash == nil && req.NewUsername == "" {
+	if req.NewHash == nil && req.NewUsername == "" && req.NewGroups == nil && !req.ClearGroups && !req.VerifyEmail {
 		return nil, errors.New("nothing to update")
 	}
+	if req.NewGroups != nil && req.ClearGroups {
+		return nil, errors.New("can't both set and clear groups")
+	}
+	if req.VerifyEmail && !d.emailVerificationEnabled() {
+		return nil, errors.New("email verification is not enabled")
+	}
 
 	if req.NewHash != nil {
 		if err := checkCost(req.NewHash); err != nil {
@@ -244,6 +274,7 @@ func (d dexAPI) UpdatePassword(ctx context.Context, req *api.UpdatePasswordReq)
 		}
 	}
 
+	var updated storage.Password
 	updater := func(old storage.Password) (storage.Password, error) {
 		if req.NewHash != nil {
 			old.Hash = req.NewHash
@@ -253,6 +284,18 @@ func (d dexAPI) UpdatePassword(ctx context.Context, req *api.UpdatePasswordReq)
 			old.Username = req.NewUsername
 		}
 
+		if req.NewGroups != nil {
+			old.Groups = req.NewGroups
+		} else if req.ClearGroups {
+			old.Groups = nil
+		}
+
+		if req.VerifyEmail {
+			verified := false
+			old.EmailVerified = &verified
+		}
+
+		updated = old
 		return old, nil
 	}
 
@@ -264,9 +307,21 @@ func (d dexAPI) UpdatePassword(ctx context.Context, req *api.UpdatePasswordReq)
 		return nil, fmt.Errorf("update password: %v", err)
 	}
 
+	if req.VerifyEmail {
+		if err := d.server.sendEmailVerificationLink(ctx, updated); err != nil {
+			d.logger.Error("failed to send verification email", "err", err)
+			return nil, fmt.Errorf("password updated, but %v", err)
+		}
+	}
+
 	return &api.UpdatePasswordResp{}, nil
 }
 
+// emailVerificationEnabled reports whether verification links can be sent.
+func (d dexAPI) emailVerificationEnabled() bool {
+	return d.server != nil && d.server.emailVerification != nil
+}
+
 func (d dexAPI) DeletePassword(ctx context.Context, req *api.DeletePasswordReq) (*api.DeletePasswordResp, error) {
 	if req.Email == "" {
 		return nil, errors.New("no email supplied")
@@ -291,8 +346,7 @@ func (d dexAPI) GetVersion(ctx context.Context, req *api.VersionReq) (*api.Versi
 }
 
 func (d dexAPI) GetDiscovery(ctx context.Context, req *api.DiscoveryReq) (*api.DiscoveryResp, error) {
-	discoveryDoc := d.server.constructDiscovery(ctx)
-	data, err := json.Marshal(discoveryDoc)
+	data, err := d.server.discoveryDocument(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("failed to marshal discovery data: %v", err)
 	}
@@ -314,9 +368,11 @@ func (d dexAPI) ListPasswords(ctx context.Context, req *api.ListPasswordReq) (*a
 	passwords := make([]*api.Password, 0, len(passwordList))
 	for _, password := range passwordList {
 		p := api.Password{
-			Email:    password.Email,
-			Username: password.Username,
-			UserId:   password.UserID,
+			Email:         password.Email,
+			Username:      password.Username,
+			UserId:        password.UserID,
+			Groups:        password.Groups,
+			EmailVerified: resolvePasswordEmailVerified(password),
 		}
 		passwords = append(passwords, &p)
 	}
@@ -617,3 +673,124 @@ func defaultTo[T comparable](v, def T) T {
 	}
 	return v
 }
+
+func (d dexAPI) QueryAuditEvents(ctx context.Context, req *api.QueryAuditEventsReq) (*api.QueryAuditEventsResp, error) {
+	if d.server == nil || d.server.auditLog == nil {
+		return nil, errors.New("audit log is not enabled")
+	}
+	if req.Limit < 0 {
+		return nil, errors.New("limit cannot be negative")
+	}
+
+	filter := storage.AuditEventFilter{
+		Subject:     req.Subject,
+		ClientID:    req.ClientId,
+		ConnectorID: req.ConnectorId,
+		Type:        req.Type,
+		Limit:       int(req.Limit),
+	}
+	if req.Since != 0 {
+		filter.Since = time.Unix(req.Since, 0)
+	}
+	if req.Until != 0 {
+		filter.Until = time.Unix(req.Until, int64(time.Second-1))
+	}
+	if filter.Limit == 0 {
+		filter.Limit = defaultAuditEventsLimit
+	}
+	filter.Limit = min(filter.Limit, maxAuditEventsLimit)
+
+	events, err := d.server.auditLog.Storage.ListAuditEvents(ctx, filter)
+	if err != nil {
+		d.logger.Error("failed to list audit events", "err", err)
+		return nil, fmt.Errorf("list audit events: %v", err)
+	}
+
+	resp := &api.QueryAuditEventsResp{Events: make([]*api.AuditEvent, 0, len(events))}
+	for _, e := range events {
+		resp.Events = append(resp.Events, &api.AuditEvent{
+			Id:          e.ID,
+			Type:        e.Type,
+			Time:        e.Time.Unix(),
+			Subject:     e.Subject,
+			ClientId:    e.ClientID,
+			ConnectorId: e.ConnectorID,
+			Data:        e.Data,
+		})
+	}
+	return resp, nil
+}
+
+func (d dexAPI) ListTermsAcceptances(ctx context.Context, req *api.ListTermsAcceptancesReq) (*api.ListTermsAcceptancesResp, error) {
+	identities, err := d.s.ListUserIdentities(ctx)
+	if err != nil {
+		d.logger.Error("failed to list user identities", "err", err)
+		return nil, fmt.Errorf("list user identities: %v", err)
+	}
+
+	resp := &api.ListTermsAcceptancesResp{Acceptances: []*api.TermsAcceptance{}}
+	for _, ui := range identities {
+		if req.UserId != "" && ui.UserID != req.UserId {
+			continue
+		}
+		for clientID, terms := range ui.AcceptedTerms {
+			if req.ClientId != "" && clientID != req.ClientId {
+				continue
+			}
+			resp.Acceptances = append(resp.Acceptances, &api.TermsAcceptance{
+				UserId:      ui.UserID,
+				ConnectorId: ui.ConnectorID,
+				ClientId:    clientID,
+				Version:     terms.Version,
+				AcceptedAt:  terms.AcceptedAt.Unix(),
+				Email:       ui.Claims.Email,
+			})
+		}
+	}
+	sort.Slice(resp.Acceptances, func(i, j int) bool {
+		a, b := resp.Acceptances[i], resp.Acceptances[j]
+		if a.ClientId != b.ClientId {
+			return a.ClientId < b.ClientId
+		}
+		return a.AcceptedAt < b.AcceptedAt
+	})
+	return resp, nil
+}
+
+func (d dexAPI) GetLogLevels(ctx context.Context, req *api.GetLogLevelsReq) (*api.GetLogLevelsResp, error) {
+	if d.server == nil || d.server.logLevels == nil {
+		return nil, errors.New("log levels cannot be changed")
+	}
+	def, modules := logLevelStrings(d.server.logLevels)
+	return &api.GetLogLevelsResp{Default: def, Modules: modules}, nil
+}
+
+func (d dexAPI) SetLogLevel(ctx context.Context, req *api.SetLogLevelReq) (*api.SetLogLevelResp, error) {
+	if d.server == nil || d.server.logLevels == nil {
+		return nil, errors.New("log levels cannot be changed")
+	}
+
+	var level *slog.Level
+	if req.Level != "" {
+		level = new(slog.Level)
+		if err := level.UnmarshalText([]byte(req.Level)); err != nil {
+			return nil, fmt.Errorf("invalid level: %v", err)
+		}
+	} else if req.Module == "" {
+		return nil, errors.New("the default level cannot be reset")
+	}
+	d.server.logLevels.SetLogLevel(req.Module, level)
+	d.logger.Info("log level changed", "module", req.Module, "level", req.Level)
+
+	def, modules := logLevelStrings(d.server.logLevels)
+	return &api.SetLogLevelResp{Default: def, Modules: modules}, nil
+}
+
+func logLevelStrings(l LogLevels) (string, map[string]string) {
+	def, levels := l.LogLevels()
+	modules := make(map[string]string, len(levels))
+	for module, level := range levels {
+		modules[module] = level.String()
+	}
+	return def.String(), modules
+}
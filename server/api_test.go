@@ -2,12 +2,14 @@ package server
 
 import (
 	"log/slog"
+	"maps"
 	"net"
 	"slices"
 	"strings"
 	"testing"
 	"time"
 
+	"github.com/stretchr/testify/require"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials/insecure"
 
@@ -170,6 +172,74 @@ func TestPassword(t *testing.T) {
 	}
 }
 
+// Attempts to create, list and update the groups of a test Password
+func TestPasswordGroups(t *testing.T) {
+	logger := newLogger(t)
+	s := memory.New(logger)
+
+	client := newAPI(t, s, logger)
+	defer client.Close()
+
+	ctx := t.Context()
+
+	email := "test@example.com"
+	createReq := api.CreatePasswordReq{
+		Password: &api.Password{
+			Email: email,
+			// bcrypt hash of the value "test1" with cost 10
+			Hash:     []byte("$2a$10$XVMN/Fid.Ks4CXgzo8fpR.iU1khOMsP5g9xQeXuBm1wXjRX8pjUtO"),
+			Username: "test",
+			UserId:   "test123",
+			Groups:   []string{"admins"},
+		},
+	}
+	if _, err := client.CreatePassword(ctx, &createReq); err != nil {
+		t.Fatalf("Unable to create password: %v", err)
+	}
+
+	listResp, err := client.ListPasswords(ctx, &api.ListPasswordReq{})
+	if err != nil {
+		t.Fatalf("Unable to list passwords: %v", err)
+	}
+	if len(listResp.Passwords) != 1 || !slices.Equal(listResp.Passwords[0].Groups, []string{"admins"}) {
+		t.Fatalf("ListPasswords returned unexpected groups: %v", listResp.Passwords)
+	}
+
+	updateReq := api.UpdatePasswordReq{
+		Email:     email,
+		NewGroups: []string{"admins", "devs"},
+	}
+	if _, err := client.UpdatePassword(ctx, &updateReq); err != nil {
+		t.Fatalf("Unable to update password: %v", err)
+	}
+	pass, err := s.GetPassword(ctx, email)
+	if err != nil {
+		t.Fatalf("Unable to retrieve password: %v", err)
+	}
+	if !slices.Equal(pass.Groups, updateReq.NewGroups) {
+		t.Fatalf("UpdatePassword failed. Expected groups %v retrieved %v", updateReq.NewGroups, pass.Groups)
+	}
+	if pass.Username != "test" {
+		t.Fatalf("UpdatePassword changed username to %s", pass.Username)
+	}
+
+	// Setting and clearing groups at once is rejected.
+	if _, err := client.UpdatePassword(ctx, &api.UpdatePasswordReq{Email: email, NewGroups: []string{"devs"}, ClearGroups: true}); err == nil {
+		t.Fatal("UpdatePassword accepted both new and cleared groups")
+	}
+
+	if _, err := client.UpdatePassword(ctx, &api.UpdatePasswordReq{Email: email, ClearGroups: true}); err != nil {
+		t.Fatalf("Unable to clear groups: %v", err)
+	}
+	pass, err = s.GetPassword(ctx, email)
+	if err != nil {
+		t.Fatalf("Unable to retrieve password: %v", err)
+	}
+	if len(pass.Groups) != 0 {
+		t.Fatalf("UpdatePassword failed to clear groups, retrieved %v", pass.Groups)
+	}
+}
+
 // Ensures checkCost returns expected values
 func TestCheckCost(t *testing.T) {
 	logger := newLogger(t)
@@ -924,3 +994,53 @@ func TestListClients(t *testing.T) {
 		}
 	}
 }
+
+type testLogLevels struct {
+	def     slog.Level
+	modules map[string]slog.Level
+}
+
+func (l *testLogLevels) LogLevels() (slog.Level, map[string]slog.Level) {
+	return l.def, maps.Clone(l.modules)
+}
+
+func (l *testLogLevels) SetLogLevel(module string, level *slog.Level) {
+	switch {
+	case module == "" && level != nil:
+		l.def = *level
+	case level == nil:
+		delete(l.modules, module)
+	default:
+		l.modules[module] = *level
+	}
+}
+
+func TestLogLevels(t *testing.T) {
+	ctx := t.Context()
+
+	httpServer, s := newTestServer(t, func(c *Config) {
+		c.LogLevels = &testLogLevels{def: slog.LevelInfo, modules: map[string]slog.Level{"connector.hsdp": slog.LevelDebug}}
+	})
+	defer httpServer.Close()
+	srv := NewAPI(s.storage, s.logger, "test", s)
+
+	resp, err := srv.SetLogLevel(ctx, &api.SetLogLevelReq{Module: "storage", Level: "debug"})
+	require.NoError(t, err)
+	require.Equal(t, "INFO", resp.Default)
+	require.Equal(t, map[string]string{"connector.hsdp": "DEBUG", "storage": "DEBUG"}, resp.Modules)
+
+	_, err = srv.SetLogLevel(ctx, &api.SetLogLevelReq{Module: "connector.hsdp"})
+	require.NoError(t, err)
+	_, err = srv.SetLogLevel(ctx, &api.SetLogLevelReq{Module: "storage", Level: "loud"})
+	require.Error(t, err)
+	_, err = srv.SetLogLevel(ctx, &api.SetLogLevelReq{})
+	require.Error(t, err)
+
+	levels, err := srv.GetLogLevels(ctx, &api.GetLogLevelsReq{})
+	require.NoError(t, err)
+	require.Equal(t, "INFO", levels.Default)
+	require.Equal(t, map[string]string{"storage": "DEBUG"}, levels.Modules)
+
+	_, err = NewAPI(s.storage, s.logger, "test", nil).GetLogLevels(ctx, &api.GetLogLevelsReq{})
+	require.Error(t, err)
+}
@@ -0,0 +1,184 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/dexidp/dex/storage"
+)
+
+// CAPTCHA providers.
+const (
+	CaptchaHCaptcha  = "hcaptcha"
+	CaptchaReCaptcha = "recaptcha"
+	CaptchaTurnstile = "turnstile"
+)
+
+// captchaProvider describes the widget and the verification endpoint of a
+// CAPTCHA provider. They all implement the siteverify API of reCAPTCHA.
+type captchaProvider struct {
+	scriptURL string
+	// class of the element the widget is rendered in.
+	class string
+	// responseField is the form field the widget submits its token in.
+	responseField string
+	verifyURL     string
+}
+
+var captchaProviders = map[string]captchaProvider{
+	CaptchaHCaptcha: {
+		scriptURL:     "https://js.hcaptcha.com/1/api.js",
+		class:         "h-captcha",
+		responseField: "h-captcha-response",
+		verifyURL:     "https://api.hcaptcha.com/siteverify",
+	},
+	CaptchaReCaptcha: {
+		scriptURL:     "https://www.google.com/recaptcha/api.js",
+		class:         "g-recaptcha",
+		responseField: "g-recaptcha-response",
+		verifyURL:     "https://www.google.com/recaptcha/api/siteverify",
+	},
+	CaptchaTurnstile: {
+		scriptURL:     "https://challenges.cloudflare.com/turnstile/v0/api.js",
+		class:         "cf-turnstile",
+		responseField: "cf-turnstile-response",
+		verifyURL:     "https://challenges.cloudflare.com/turnstile/v0/siteverify",
+	},
+}
+
+// CaptchaConfig requires a CAPTCHA on the password login forms, e.g. of the
+// local password database and LDAP.
+type CaptchaConfig struct {
+	// Provider is one of CaptchaHCaptcha, CaptchaReCaptcha and
+	// CaptchaTurnstile.
+	Provider string
+	// SiteKey is shown to browsers, Secret verifies their responses.
+	SiteKey string
+	Secret  string
+
+	// AfterFailures requires the CAPTCHA only once the client IP address or
+	// the username had that many failed logins. Zero always requires it.
+	AfterFailures int
+	// Window is how long failed logins are counted after the last one.
+	// Defaults to 15 minutes.
+	Window time.Duration
+
+	// VerifyURL overrides the verification endpoint of the provider.
+	VerifyURL string
+}
+
+// captchaWidget is the CAPTCHA shown on the password login form.
+type captchaWidget struct {
+	ScriptURL string
+	Class     string
+	SiteKey   string
+	// Failed is set if the last response was rejected.
+	Failed bool
+}
+
+type captcha struct {
+	config   CaptchaConfig
+	provider captchaProvider
+	client   *http.Client
+	// failures counts the failed logins per IP address and username. Only
+	// the bookkeeping of the login throttle is used, it never delays.
+	failures *loginThrottle
+}
+
+func newCaptcha(config CaptchaConfig, client *http.Client, store storage.RateLimitStorage, now func() time.Time) (*captcha, error) {
+	provider, ok := captchaProviders[config.Provider]
+	if !ok {
+		return nil, fmt.Errorf("unknown CAPTCHA provider %q", config.Provider)
+	}
+	if config.SiteKey == "" || config.Secret == "" {
+		return nil, errors.New("CAPTCHA requires a site key and a secret")
+	}
+	if config.VerifyURL != "" {
+		provider.verifyURL = config.VerifyURL
+	}
+	return &captcha{
+		config:   config,
+		provider: provider,
+		client:   client,
+		failures: newLoginThrottle(LoginThrottleConfig{
+			FreeAttempts: math.MaxInt,
+			Window:       value(config.Window, 15*time.Minute),
+		}, store, "captcha:", now),
+	}, nil
+}
+
+// required reports whether logins of username from ip need a CAPTCHA. An
+// empty username only checks the address.
+func (c *captcha) required(ctx context.Context, ip, username string) (bool, error) {
+	if c.config.AfterFailures == 0 {
+		return true, nil
+	}
+	n, err := c.failures.count(ctx, "ip:"+ip)
+	if err != nil || n >= c.config.AfterFailures || username == "" {
+		return n >= c.config.AfterFailures, err
+	}
+	n, err = c.failures.count(ctx, "user:"+strings.ToLower(username))
+	return n >= c.config.AfterFailures, err
+}
+
+// failed records a failed login of username from ip.
+func (c *captcha) failed(ctx context.Context, ip, username string) error {
+	if err := c.failures.failed(ctx, "ip:"+ip); err != nil {
+		return err
+	}
+	if username != "" {
+		return c.failures.failed(ctx, "user:"+strings.ToLower(username))
+	}
+	return nil
+}
+
+func (c *captcha) widget(failed bool) *captchaWidget {
+	return &captchaWidget{
+		ScriptURL: c.provider.scriptURL,
+		Class:     c.provider.class,
+		SiteKey:   c.config.SiteKey,
+		Failed:    failed,
+	}
+}
+
+// verify checks the CAPTCHA response submitted with r at the provider.
+func (c *captcha) verify(ctx context.Context, r *http.Request, ip string) error {
+	response := r.PostFormValue(c.provider.responseField)
+	if response == "" {
+		return errors.New("no CAPTCHA response")
+	}
+
+	form := url.Values{"secret": {c.config.Secret}, "response": {response}, "remoteip": {ip}}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.provider.verifyURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("verify CAPTCHA: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("verify CAPTCHA: %s", resp.Status)
+	}
+
+	var result struct {
+		Success    bool     `json:"success"`
+		ErrorCodes []string `json:"error-codes"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return fmt.Errorf("verify CAPTCHA: decode response: %v", err)
+	}
+	if !result.Success {
+		return fmt.Errorf("CAPTCHA rejected: %s", strings.Join(result.ErrorCodes, ", "))
+	}
+	return nil
+}
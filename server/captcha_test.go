@@ -0,0 +1,86 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/dexidp/dex/storage"
+)
+
+func TestPasswordLoginCaptcha(t *testing.T) {
+	ctx := t.Context()
+	connID := "mockPw"
+
+	verifier := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ok := r.PostFormValue("secret") == "captcha-secret" && r.PostFormValue("response") == "solved"
+		json.NewEncoder(w).Encode(map[string]any{"success": ok})
+	}))
+	defer verifier.Close()
+
+	httpServer, s := newTestServer(t, func(c *Config) {
+		c.Captcha = &CaptchaConfig{
+			Provider:      CaptchaHCaptcha,
+			SiteKey:       "captcha-site-key",
+			Secret:        "captcha-secret",
+			AfterFailures: 1,
+			VerifyURL:     verifier.URL,
+		}
+	})
+	defer httpServer.Close()
+
+	sc := storage.Connector{
+		ID:              connID,
+		Type:            "mockPassword",
+		Name:            "MockPassword",
+		ResourceVersion: "1",
+		Config:          []byte(`{"username": "foo", "password": "password"}`),
+	}
+	require.NoError(t, s.storage.CreateConnector(ctx, sc))
+	_, err := s.OpenConnector(sc)
+	require.NoError(t, err)
+
+	authReq := storage.AuthRequest{
+		ID:            "captcha",
+		ConnectorID:   connID,
+		RedirectURI:   "cb",
+		Expiry:        time.Now().Add(time.Minute),
+		ResponseTypes: []string{responseTypeCode},
+	}
+	require.NoError(t, s.storage.CreateAuthRequest(ctx, authReq))
+
+	path := fmt.Sprintf("/auth/%s/login?state=%s", connID, authReq.ID)
+	login := func(form url.Values) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		rr := httptest.NewRecorder()
+		s.handlePasswordLogin(rr, req)
+		return rr
+	}
+
+	rr := httptest.NewRecorder()
+	s.handlePasswordLogin(rr, httptest.NewRequest(http.MethodGet, path, nil))
+	require.Equal(t, http.StatusOK, rr.Code)
+	require.NotContains(t, rr.Body.String(), "h-captcha", "no CAPTCHA before failed logins")
+
+	rr = login(url.Values{"login": {"foo"}, "password": {"wrong"}})
+	require.Equal(t, http.StatusUnauthorized, rr.Code)
+	require.Contains(t, rr.Body.String(), `data-sitekey="captcha-site-key"`)
+
+	rr = login(url.Values{"login": {"foo"}, "password": {"password"}})
+	require.Equal(t, http.StatusBadRequest, rr.Code, "missing CAPTCHA response")
+	require.Contains(t, rr.Body.String(), "captcha-error")
+
+	rr = login(url.Values{"login": {"foo"}, "password": {"password"}, "h-captcha-response": {"unsolved"}})
+	require.Equal(t, http.StatusBadRequest, rr.Code, "rejected CAPTCHA response")
+
+	rr = login(url.Values{"login": {"foo"}, "password": {"password"}, "h-captcha-response": {"solved"}})
+	require.Equal(t, http.StatusSeeOther, rr.Code)
+}
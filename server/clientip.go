@@ -0,0 +1,112 @@
+package server
+
+import (
+	"net"
+	"net/http"
+	"net/netip"
+	"slices"
+	"strings"
+)
+
+// clientIPResolver resolves the IP address of the client which sent a
+// request, taking proxy headers into account.
+type clientIPResolver struct {
+	// header carries the client address set by proxies, e.g.
+	// X-Forwarded-For, Forwarded or X-Real-IP. Empty ignores proxy headers.
+	header string
+	// trusted are the networks of the proxies in front of dex. If empty,
+	// the header is ignored from IP peers.
+	trusted []netip.Prefix
+	// trustUnixSocket honors the header from peers connecting through a unix
+	// socket, which have no address to check.
+	trustUnixSocket bool
+}
+
+func newClientIPResolver(header string, trusted []netip.Prefix, trustUnixSocket bool) clientIPResolver {
+	return clientIPResolver{header: http.CanonicalHeaderKey(header), trusted: trusted, trustUnixSocket: trustUnixSocket}
+}
+
+func (c clientIPResolver) trustedProxy(ip netip.Addr) bool {
+	return slices.ContainsFunc(c.trusted, func(p netip.Prefix) bool { return p.Contains(ip) })
+}
+
+// resolve returns the IP address of the client. The header is only honored
+// if the request comes from a trusted proxy, or through a unix socket if
+// those are trusted. Addresses in it are read from the closest proxy onwards,
+// and the first one not belonging to a trusted proxy is the client's, so
+// clients can't spoof their address by sending the header themselves.
+func (c clientIPResolver) resolve(r *http.Request) string {
+	peer := r.RemoteAddr
+	if host, _, err := net.SplitHostPort(peer); err == nil {
+		peer = host
+	}
+	peerIP, err := netip.ParseAddr(peer)
+	if err != nil {
+		// Unix sockets have no IP address.
+		if c.header == "" || !c.trustUnixSocket {
+			return peer
+		}
+	} else if c.header == "" || !c.trustedProxy(peerIP) {
+		return peerIP.Unmap().String()
+	}
+
+	var hops []string
+	switch c.header {
+	case "X-Forwarded-For":
+		for _, v := range r.Header.Values(c.header) {
+			hops = append(hops, strings.Split(v, ",")...)
+		}
+	case "Forwarded":
+		hops = forwardedFor(r.Header.Values(c.header))
+	default:
+		hops = []string{r.Header.Get(c.header)}
+	}
+
+	client := peerIP
+	for i := len(hops) - 1; i >= 0; i-- {
+		ip, ok := parseHop(hops[i])
+		if !ok {
+			// Obfuscated, "unknown" or garbage: nothing further out can
+			// be trusted.
+			break
+		}
+		client = ip
+		if !c.trustedProxy(ip) {
+			break
+		}
+	}
+	if !client.IsValid() {
+		return peer
+	}
+	return client.Unmap().String()
+}
+
+// forwardedFor returns the "for" parameters of the elements of Forwarded
+// headers (RFC 7239). Elements without one yield an empty string.
+func forwardedFor(values []string) []string {
+	var hops []string
+	for _, v := range values {
+		for _, element := range strings.Split(v, ",") {
+			var hop string
+			for _, pair := range strings.Split(element, ";") {
+				key, value, ok := strings.Cut(strings.TrimSpace(pair), "=")
+				if ok && strings.EqualFold(key, "for") {
+					hop = value
+				}
+			}
+			hops = append(hops, hop)
+		}
+	}
+	return hops
+}
+
+// parseHop parses an address of a proxy header, which may be quoted and
+// carry a port, e.g. "[2001:db8::1]:4711".
+func parseHop(hop string) (netip.Addr, bool) {
+	hop = strings.Trim(strings.TrimSpace(hop), `"`)
+	if ap, err := netip.ParseAddrPort(hop); err == nil {
+		return ap.Addr(), true
+	}
+	ip, err := netip.ParseAddr(strings.TrimSuffix(strings.TrimPrefix(hop, "["), "]"))
+	return ip, err == nil
+}
@@ -0,0 +1,133 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestClientIPResolver(t *testing.T) {
+	trusted := []netip.Prefix{netip.MustParsePrefix("10.0.0.0/8")}
+
+	tests := []struct {
+		name            string
+		header          string
+		trusted         []netip.Prefix
+		trustUnixSocket bool
+		remoteAddr      string
+		headers         map[string]string
+		want            string
+	}{
+		{
+			name:       "no header configured",
+			remoteAddr: "203.0.113.7:4711",
+			headers:    map[string]string{"X-Forwarded-For": "198.51.100.1"},
+			want:       "203.0.113.7",
+		},
+		{
+			name:       "untrusted peer",
+			header:     "X-Forwarded-For",
+			trusted:    trusted,
+			remoteAddr: "203.0.113.7:4711",
+			headers:    map[string]string{"X-Forwarded-For": "198.51.100.1"},
+			want:       "203.0.113.7",
+		},
+		{
+			name:       "trusted proxy chain",
+			header:     "X-Forwarded-For",
+			trusted:    trusted,
+			remoteAddr: "10.0.0.1:4711",
+			headers:    map[string]string{"X-Forwarded-For": "192.0.2.66, 198.51.100.1, 10.1.2.3"},
+			want:       "198.51.100.1",
+		},
+		{
+			name:       "all hops trusted",
+			header:     "x-forwarded-for",
+			trusted:    trusted,
+			remoteAddr: "10.0.0.1:4711",
+			headers:    map[string]string{"X-Forwarded-For": "10.3.3.3,10.1.2.3"},
+			want:       "10.3.3.3",
+		},
+		{
+			name:       "no trusted proxies ignores header",
+			header:     "X-Forwarded-For",
+			remoteAddr: "203.0.113.7:4711",
+			headers:    map[string]string{"X-Forwarded-For": "192.0.2.66, 198.51.100.1"},
+			want:       "203.0.113.7",
+		},
+		{
+			name:       "untrusted unix socket",
+			header:     "X-Forwarded-For",
+			trusted:    trusted,
+			remoteAddr: "@",
+			headers:    map[string]string{"X-Forwarded-For": "198.51.100.1"},
+			want:       "@",
+		},
+		{
+			name:            "trusted unix socket",
+			header:          "X-Forwarded-For",
+			trusted:         trusted,
+			trustUnixSocket: true,
+			remoteAddr:      "@",
+			headers:         map[string]string{"X-Forwarded-For": "192.0.2.66, 198.51.100.1, 10.1.2.3"},
+			want:            "198.51.100.1",
+		},
+		{
+			name:            "trusted unix socket without header",
+			header:          "X-Forwarded-For",
+			trustUnixSocket: true,
+			remoteAddr:      "@",
+			want:            "@",
+		},
+		{
+			name:       "garbage stops the walk",
+			header:     "X-Forwarded-For",
+			trusted:    trusted,
+			remoteAddr: "10.0.0.1:4711",
+			headers:    map[string]string{"X-Forwarded-For": "192.0.2.66, garbage, 10.1.2.3"},
+			want:       "10.1.2.3",
+		},
+		{
+			name:       "forwarded header",
+			header:     "Forwarded",
+			trusted:    trusted,
+			remoteAddr: "10.0.0.1:4711",
+			headers:    map[string]string{"Forwarded": `for=192.0.2.66, for="[2001:db8::1]:4711";proto=https, for=10.1.2.3`},
+			want:       "2001:db8::1",
+		},
+		{
+			name:       "forwarded header obfuscated",
+			header:     "Forwarded",
+			trusted:    trusted,
+			remoteAddr: "10.0.0.1:4711",
+			headers:    map[string]string{"Forwarded": `for=192.0.2.66, for=_hidden`},
+			want:       "10.0.0.1",
+		},
+		{
+			name:       "single address header",
+			header:     "X-Real-IP",
+			trusted:    trusted,
+			remoteAddr: "10.0.0.1:4711",
+			headers:    map[string]string{"X-Real-IP": "198.51.100.1"},
+			want:       "198.51.100.1",
+		},
+		{
+			name:       "ipv4 mapped peer",
+			remoteAddr: "[::ffff:203.0.113.7]:4711",
+			want:       "203.0.113.7",
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			r.RemoteAddr = tc.remoteAddr
+			for k, v := range tc.headers {
+				r.Header.Set(k, v)
+			}
+			require.Equal(t, tc.want, newClientIPResolver(tc.header, tc.trusted, tc.trustUnixSocket).resolve(r))
+		})
+	}
+}
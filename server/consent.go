@@ -0,0 +1,330 @@
+package server
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dexidp/dex/connector"
+	"github.com/dexidp/dex/storage"
+)
+
+// releasedClaim is a claim shown to the user on the approval page.
+type releasedClaim struct {
+	Name   string
+	Values []string
+}
+
+// previewedClaims lists the standard claims shown on the approval page, in
+// display order. Connectors extending the payload list their own claims
+// through connector.ClaimsPreviewer. Claims not listed, such as the subject or
+// token metadata, are not shown.
+var previewedClaims = []connector.PreviewedClaim{
+	{Path: "name", Label: "Name"},
+	{Path: "preferred_username", Label: "Username"},
+	{Path: "email", Label: "Email address"},
+	{Path: "groups", Label: "Groups"},
+}
+
+// releasedClaimsTTL bounds how long the released claims of an auth request
+// are cached, in case it doesn't expire sooner.
+const releasedClaimsTTL = 10 * time.Minute
+
+// releasedClaimsCache keeps the claims previewed for auth requests, so the
+// approval page, the consent record and the consent check share them and
+// connectors extend the payload once per auth request.
+type releasedClaimsCache struct {
+	mu        sync.Mutex
+	entries   map[string]releasedClaimsEntry
+	lastSweep time.Time
+}
+
+type releasedClaimsEntry struct {
+	claims []releasedClaim
+	expiry time.Time
+}
+
+func (c *releasedClaimsCache) get(key string, now time.Time) ([]releasedClaim, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.entries[key]
+	if !ok || !now.Before(e.expiry) {
+		return nil, false
+	}
+	return e.claims, true
+}
+
+func (c *releasedClaimsCache) put(key string, claims []releasedClaim, expiry, now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.entries == nil {
+		c.entries = make(map[string]releasedClaimsEntry)
+	}
+	if now.Sub(c.lastSweep) > time.Minute {
+		for k, e := range c.entries {
+			if !now.Before(e.expiry) {
+				delete(c.entries, k)
+			}
+		}
+		c.lastSweep = now
+	}
+	c.entries[key] = releasedClaimsEntry{claims: claims, expiry: expiry}
+}
+
+// releasedClaimsKey identifies the inputs of the released claims of authReq.
+func releasedClaimsKey(authReq storage.AuthRequest) (string, error) {
+	data, err := json.Marshal(struct {
+		ID            string
+		ClientID      string
+		ConnectorID   string
+		Scopes        []string
+		Claims        storage.Claims
+		ConnectorData []byte
+	}{authReq.ID, authReq.ClientID, authReq.ConnectorID, authReq.Scopes, authReq.Claims, authReq.ConnectorData})
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// releasedClaims returns the claims an ID token issued for authReq will
+// carry, as far as they identify the user. Connectors which extend the token
+// payload contribute their claims too, so the preview matches what the client
+// actually receives. The result is cached for the auth request.
+func (s *Server) releasedClaims(ctx context.Context, authReq storage.AuthRequest) []releasedClaim {
+	now := s.now()
+	key, err := releasedClaimsKey(authReq)
+	if err == nil {
+		if released, ok := s.releasedClaimsCache.get(key, now); ok {
+			return released
+		}
+	}
+
+	released := s.computeReleasedClaims(ctx, authReq)
+	if err == nil {
+		expiry := now.Add(releasedClaimsTTL)
+		if !authReq.Expiry.IsZero() && authReq.Expiry.Before(expiry) {
+			expiry = authReq.Expiry
+		}
+		s.releasedClaimsCache.put(key, released, expiry, now)
+	}
+	return released
+}
+
+func (s *Server) computeReleasedClaims(ctx context.Context, authReq storage.AuthRequest) []releasedClaim {
+	claims := make(map[string]interface{})
+	for _, scope := range authReq.Scopes {
+		switch scope {
+		case scopeEmail:
+			claims["email"] = authReq.Claims.Email
+		case scopeGroups:
+			claims["groups"] = authReq.Claims.Groups
+		case scopeProfile:
+			claims["name"] = authReq.Claims.Username
+			claims["preferred_username"] = authReq.Claims.PreferredUsername
+		}
+	}
+
+	connectorClaims, err := s.connectorClaims(ctx, authReq.ClientID, authReq.Claims)
+	if err != nil {
+		s.logger.WarnContext(ctx, "failed to preview connector claims", "connector_id", authReq.ConnectorID, "err", err)
+	}
+	for name, value := range connectorClaims {
+		if _, ok := claims[name]; !ok {
+			claims[name] = value
+		}
+	}
+
+	previewed := previewedClaims
+	if len(authReq.ConnectorData) > 0 {
+		extended, extenderClaims, err := s.extendPreview(ctx, authReq, claims)
+		if err != nil {
+			s.logger.WarnContext(ctx, "failed to preview connector claims", "connector_id", authReq.ConnectorID, "err", err)
+		} else {
+			claims = extended
+			previewed = append(slices.Clip(previewed), extenderClaims...)
+		}
+	}
+
+	var released []releasedClaim
+	shown := make(map[string]bool, len(previewed))
+	for _, c := range previewed {
+		if shown[c.Path] {
+			continue
+		}
+		shown[c.Path] = true
+		values := claimValues(lookupClaim(claims, c.Path))
+		if len(values) > 0 {
+			released = append(released, releasedClaim{Name: c.Label, Values: values})
+		}
+	}
+	// Custom claims of the connector are shown by name after the known ones.
+	names := make([]string, 0, len(connectorClaims))
+	for name := range connectorClaims {
+		if !shown[name] {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if values := claimValues(claims[name]); len(values) > 0 {
+			released = append(released, releasedClaim{Name: name, Values: values})
+		}
+	}
+	return released
+}
+
+// extendPreview extends the previewed claims like the connector extends the
+// token payload. It also returns the claims the connector wants shown.
+func (s *Server) extendPreview(ctx context.Context, authReq storage.AuthRequest, claims map[string]interface{}) (map[string]interface{}, []connector.PreviewedClaim, error) {
+	conn, err := s.getConnector(ctx, authReq.ConnectorID)
+	if err != nil {
+		return nil, nil, err
+	}
+	extender, ok := conn.Connector.(connector.PayloadExtender)
+	if !ok {
+		return claims, nil, nil
+	}
+	var extenderClaims []connector.PreviewedClaim
+	if previewer, ok := conn.Connector.(connector.ClaimsPreviewer); ok {
+		extenderClaims = previewer.PreviewedClaims()
+	}
+
+	// Connectors may rewrite the subject, so give them one to work with.
+	withSub := map[string]interface{}{"sub": authReq.Claims.UserID}
+	for k, v := range claims {
+		withSub[k] = v
+	}
+	payload, err := json.Marshal(withSub)
+	if err != nil {
+		return nil, nil, err
+	}
+	payload, err = extender.ExtendPayload(authReq.Scopes, payload, authReq.ConnectorData)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var extended map[string]interface{}
+	if err := json.Unmarshal(payload, &extended); err != nil {
+		return nil, nil, err
+	}
+	return extended, extenderClaims, nil
+}
+
+func lookupClaim(claims map[string]interface{}, path string) interface{} {
+	var v interface{} = claims
+	for _, key := range strings.Split(path, ".") {
+		m, ok := v.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		v = m[key]
+	}
+	return v
+}
+
+func claimValues(v interface{}) []string {
+	switch vt := v.(type) {
+	case nil:
+		return nil
+	case string:
+		if vt == "" {
+			return nil
+		}
+		return []string{vt}
+	case []string:
+		return vt
+	case []interface{}:
+		values := make([]string, 0, len(vt))
+		for _, item := range vt {
+			values = append(values, claimValues(item)...)
+		}
+		return values
+	default:
+		return []string{fmt.Sprint(vt)}
+	}
+}
+
+// claimsDigest summarizes released claims, so a later login can tell whether
+// they changed since the user consented.
+func claimsDigest(claims []releasedClaim) string {
+	data, _ := json.Marshal(claims)
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// termsAccepted reports whether the user accepted the current version of the
+// client's terms, if it has any.
+func termsAccepted(client storage.Client, ui storage.UserIdentity) bool {
+	return client.Terms == nil || ui.AcceptedTerms[client.ID].Version == client.Terms.Version
+}
+
+// clientTermsAccepted is termsAccepted for a client looked up by its ID.
+func (s *Server) clientTermsAccepted(ctx context.Context, clientID string, ui storage.UserIdentity) bool {
+	client, err := s.storage.GetClient(ctx, clientID)
+	if err != nil && !errors.Is(err, storage.ErrNotFound) {
+		s.logger.ErrorContext(ctx, "failed to get client", "client_id", clientID, "err", err)
+		return false
+	}
+	return termsAccepted(client, ui)
+}
+
+// recordTermsAcceptance stores the terms a user accepted when sessions are
+// disabled, and no user identity is kept for their consents. The identity is
+// created if the user has none yet.
+func (s *Server) recordTermsAcceptance(ctx context.Context, authReq storage.AuthRequest, terms storage.ClientTerms) {
+	acceptance := storage.TermsAcceptance{Version: terms.Version, AcceptedAt: s.now()}
+	err := s.storage.UpdateUserIdentity(ctx, authReq.Claims.UserID, authReq.ConnectorID, func(old storage.UserIdentity) (storage.UserIdentity, error) {
+		if old.AcceptedTerms == nil {
+			old.AcceptedTerms = make(map[string]storage.TermsAcceptance)
+		}
+		old.AcceptedTerms[authReq.ClientID] = acceptance
+		return old, nil
+	})
+	if errors.Is(err, storage.ErrNotFound) {
+		err = s.storage.CreateUserIdentity(ctx, storage.UserIdentity{
+			UserID:        authReq.Claims.UserID,
+			ConnectorID:   authReq.ConnectorID,
+			Claims:        authReq.Claims,
+			Consents:      make(map[string][]string),
+			AcceptedTerms: map[string]storage.TermsAcceptance{authReq.ClientID: acceptance},
+			CreatedAt:     acceptance.AcceptedAt,
+			LastLogin:     acceptance.AcceptedAt,
+		})
+	}
+	if err != nil {
+		s.logger.ErrorContext(ctx, "failed to record accepted terms", "client_id", authReq.ClientID, "err", err)
+	}
+}
+
+// consentCovers reports whether the consent stored in ui lets authReq skip the
+// approval page: the user must have approved all requested scopes and the
+// current terms of the client and, for clients requiring it, the claims
+// released now must be those they saw then.
+func (s *Server) consentCovers(ctx context.Context, ui storage.UserIdentity, authReq storage.AuthRequest) bool {
+	if !scopesCoveredByConsent(ui.Consents[authReq.ClientID], authReq.Scopes) {
+		return false
+	}
+
+	client, err := s.storage.GetClient(ctx, authReq.ClientID)
+	if err != nil && !errors.Is(err, storage.ErrNotFound) {
+		s.logger.ErrorContext(ctx, "failed to get client", "client_id", authReq.ClientID, "err", err)
+		return false
+	}
+	if !termsAccepted(client, ui) {
+		return false
+	}
+	if !client.RequireConsentOnClaimChange {
+		return true
+	}
+	return ui.ConsentedClaims[authReq.ClientID] == claimsDigest(s.releasedClaims(ctx, authReq))
+}
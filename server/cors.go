@@ -0,0 +1,187 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/handlers"
+
+	"github.com/dexidp/dex/storage"
+)
+
+// clientOriginsTTL is how long the allowed origins of the clients are cached.
+const clientOriginsTTL = time.Minute
+
+// CORSPolicy configures cross-origin requests to an endpoint. Credentials
+// are never allowed.
+type CORSPolicy struct {
+	// AllowedOrigins are the origins allowed to make requests. "*" allows
+	// any origin.
+	AllowedOrigins []string
+	// AllowClientOrigins additionally allows the AllowedOrigins of the
+	// client making the request, or of any client for requests not
+	// identifying one, like preflight requests.
+	AllowClientOrigins bool
+	// AllowedHeaders are the request headers allowed besides the simple ones.
+	AllowedHeaders []string
+	// MaxAge is how long browsers may cache the result of a preflight
+	// request. Browsers cap it at ten minutes or less.
+	MaxAge time.Duration
+}
+
+func (p *CORSPolicy) enabled() bool {
+	return p != nil && (len(p.AllowedOrigins) > 0 || p.AllowClientOrigins)
+}
+
+// CORSConfig holds the CORS policies of the endpoints serving cross-origin
+// requests. A nil policy falls back to Config.AllowedOrigins and
+// Config.AllowedHeaders.
+type CORSConfig struct {
+	Discovery  *CORSPolicy
+	Keys       *CORSPolicy
+	Token      *CORSPolicy
+	UserInfo   *CORSPolicy
+	Introspect *CORSPolicy
+}
+
+// withCORS serves cross-origin requests allowed by p.
+func (s *Server) withCORS(p *CORSPolicy, h http.Handler) http.Handler {
+	if !p.enabled() {
+		return h
+	}
+	opts := []handlers.CORSOption{handlers.AllowedHeaders(p.AllowedHeaders)}
+	if p.MaxAge > 0 {
+		opts = append(opts, handlers.MaxAge(int(p.MaxAge.Seconds())))
+	}
+	if slices.Contains(p.AllowedOrigins, "*") {
+		return handlers.CORS(append(opts, handlers.AllowedOrigins(p.AllowedOrigins))...)(h)
+	}
+
+	opts = slices.Clip(opts)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// The allowed origin depends on the request.
+		w.Header().Add("Vary", "Origin")
+		cors := handlers.CORS(append(opts, handlers.AllowedOriginValidator(func(origin string) bool {
+			if slices.Contains(p.AllowedOrigins, origin) {
+				return true
+			}
+			return p.AllowClientOrigins && s.clientOriginAllowed(r, origin)
+		}))...)(h)
+		cors.ServeHTTP(w, r)
+	})
+}
+
+// clientOriginAllowed reports whether origin is an allowed origin of the
+// client making r. Requests not identifying a client, like preflight requests
+// and requests for public documents, are checked against the origins of all
+// clients.
+func (s *Server) clientOriginAllowed(r *http.Request, origin string) bool {
+	ctx := r.Context()
+	clientID := s.corsClientID(r)
+	if clientID == "" {
+		return s.clientOrigins.allowed(ctx, s, origin)
+	}
+	client, err := s.storage.GetClient(ctx, clientID)
+	if err != nil {
+		if !errors.Is(err, storage.ErrNotFound) {
+			s.logger.ErrorContext(ctx, "failed to get client for CORS", "err", err)
+		}
+		return false
+	}
+	return slices.Contains(client.AllowedOrigins, origin)
+}
+
+// corsClientID returns the ID of the client making r: the client
+// authenticating with HTTP basic auth or sending a client_id, or the client
+// an access token was issued to. Preflight requests carry no client.
+func (s *Server) corsClientID(r *http.Request) string {
+	if r.Method == http.MethodOptions {
+		return ""
+	}
+	if id, _, ok := r.BasicAuth(); ok {
+		if id, err := url.QueryUnescape(id); err == nil {
+			return id
+		}
+		return ""
+	}
+	if r.Method == http.MethodPost {
+		if err := r.ParseForm(); err != nil {
+			// Let the handler parse the form again and report the error.
+			r.Form, r.PostForm = nil, nil
+			return ""
+		}
+	}
+	if id := r.FormValue("client_id"); id != "" {
+		return id
+	}
+
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok {
+		return ""
+	}
+	payload, err := (&signerKeySet{s.signer}).VerifySignature(r.Context(), token)
+	if err != nil {
+		return ""
+	}
+	var claims struct {
+		Audience        audience `json:"aud"`
+		AuthorizedParty string   `json:"azp"`
+	}
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return ""
+	}
+	if claims.AuthorizedParty != "" {
+		return claims.AuthorizedParty
+	}
+	if len(claims.Audience) > 0 {
+		return claims.Audience[0]
+	}
+	return ""
+}
+
+// clientOriginsCache holds the allowed origins of all clients so CORS
+// requests not identifying a client don't list the clients every time.
+type clientOriginsCache struct {
+	mu      sync.Mutex
+	origins map[string]bool
+	expires time.Time
+}
+
+func (c *clientOriginsCache) allowed(ctx context.Context, s *Server, origin string) bool {
+	now := s.now()
+
+	c.mu.Lock()
+	origins := c.origins
+	fresh := origins != nil && now.Before(c.expires)
+	c.mu.Unlock()
+	if fresh {
+		return origins[origin]
+	}
+
+	// Concurrent refreshes may list the clients more than once, but requests
+	// don't wait on each other's storage calls.
+	clients, err := s.storage.ListClients(ctx)
+	if err != nil {
+		s.logger.ErrorContext(ctx, "failed to list clients for CORS", "err", err)
+		return false
+	}
+	origins = make(map[string]bool)
+	for _, client := range clients {
+		for _, o := range client.AllowedOrigins {
+			origins[o] = true
+		}
+	}
+
+	c.mu.Lock()
+	c.origins = origins
+	c.expires = now.Add(clientOriginsTTL)
+	c.mu.Unlock()
+	return origins[origin]
+}
@@ -0,0 +1,99 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/dexidp/dex/storage"
+)
+
+func TestCORSPolicies(t *testing.T) {
+	httpServer, s := newTestServer(t, func(c *Config) {
+		c.AllowedOrigins = []string{"https://legacy.example.com"}
+		c.AllowedHeaders = []string{"Authorization"}
+		c.CORS = CORSConfig{
+			Discovery: &CORSPolicy{},
+			Token: &CORSPolicy{
+				AllowClientOrigins: true,
+				AllowedHeaders:     []string{"Authorization"},
+				MaxAge:             5 * time.Minute,
+			},
+			UserInfo: &CORSPolicy{AllowedOrigins: []string{"*"}, AllowedHeaders: []string{"Authorization"}},
+		}
+	})
+	defer httpServer.Close()
+
+	require.NoError(t, s.storage.CreateClient(t.Context(), storage.Client{
+		ID:             "spa",
+		Public:         true,
+		RedirectURIs:   []string{"https://spa.example.com/callback"},
+		AllowedOrigins: []string{"https://spa.example.com"},
+	}))
+
+	preflight := func(path, origin string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodOptions, path, nil)
+		req.Header.Set("Origin", origin)
+		req.Header.Set("Access-Control-Request-Method", http.MethodPost)
+		req.Header.Set("Access-Control-Request-Headers", "Authorization")
+		rr := httptest.NewRecorder()
+		s.ServeHTTP(rr, req)
+		return rr
+	}
+
+	tests := []struct {
+		name        string
+		path        string
+		origin      string
+		allowOrigin string
+	}{
+		{"client origin on token", "/token", "https://spa.example.com", "https://spa.example.com"},
+		{"unknown origin on token", "/token", "https://evil.example.com", ""},
+		{"discovery locked down", "/.well-known/openid-configuration", "https://spa.example.com", ""},
+		{"any origin on userinfo", "/userinfo", "https://evil.example.com", "*"},
+		{"default policy on keys", "/keys", "https://legacy.example.com", "https://legacy.example.com"},
+		{"client origin not in default policy", "/keys", "https://spa.example.com", ""},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			rr := preflight(tc.path, tc.origin)
+			require.Equal(t, tc.allowOrigin, rr.Header().Get("Access-Control-Allow-Origin"))
+			require.Empty(t, rr.Header().Get("Access-Control-Allow-Credentials"))
+		})
+	}
+
+	rr := preflight("/token", "https://spa.example.com")
+	require.Equal(t, "300", rr.Header().Get("Access-Control-Max-Age"))
+	require.Equal(t, "Authorization", rr.Header().Get("Access-Control-Allow-Headers"))
+	require.Contains(t, rr.Header().Values("Vary"), "Origin")
+
+	require.NoError(t, s.storage.CreateClient(t.Context(), storage.Client{
+		ID:             "other",
+		Public:         true,
+		RedirectURIs:   []string{"https://other.example.com/callback"},
+		AllowedOrigins: []string{"https://other.example.com"},
+	}))
+
+	// Actual requests are checked against the origins of the requesting
+	// client only.
+	token := func(origin, clientID string) *httptest.ResponseRecorder {
+		form := url.Values{"grant_type": {grantTypeRefreshToken}, "client_id": {clientID}, "refresh_token": {"invalid"}}
+		req := httptest.NewRequest(http.MethodPost, "/token", strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req.Header.Set("Origin", origin)
+		rr := httptest.NewRecorder()
+		s.ServeHTTP(rr, req)
+		return rr
+	}
+	rr = token("https://spa.example.com", "spa")
+	require.Equal(t, "https://spa.example.com", rr.Header().Get("Access-Control-Allow-Origin"))
+	rr = token("https://other.example.com", "spa")
+	require.Empty(t, rr.Header().Get("Access-Control-Allow-Origin"), "origin of another client allowed")
+	rr = token("https://other.example.com", "other")
+	require.Equal(t, "https://other.example.com", rr.Header().Get("Access-Control-Allow-Origin"))
+}
@@ -0,0 +1,99 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"text/template"
+
+	"github.com/dexidp/dex/storage"
+)
+
+// customClaimsData is the data the templates of a client's custom claims are
+// executed with, e.g. "{{.Email}}" or "{{.ConnectorID}}".
+type customClaimsData struct {
+	storage.Claims
+	ConnectorID string
+}
+
+// isClaimTemplate reports whether a custom claim's value is a template rather
+// than a constant.
+func isClaimTemplate(value interface{}) (string, bool) {
+	s, ok := value.(string)
+	return s, ok && strings.Contains(s, "{{")
+}
+
+// ValidateCustomClaims checks that the templates of a client's custom claims
+// parse.
+func ValidateCustomClaims(claims map[string]interface{}) error {
+	for name, value := range claims {
+		if registeredClaims[name] {
+			return fmt.Errorf("claim %q is set by dex", name)
+		}
+		if text, ok := isClaimTemplate(value); ok {
+			if _, err := template.New(name).Option("missingkey=error").Parse(text); err != nil {
+				return fmt.Errorf("claim %q: %v", name, err)
+			}
+		}
+	}
+	return nil
+}
+
+// renderCustomClaims returns a client's custom claims for a user, executing
+// the templates among them.
+func renderCustomClaims(claims map[string]interface{}, user storage.Claims, connID string) (map[string]interface{}, error) {
+	data := customClaimsData{Claims: user, ConnectorID: connID}
+	rendered := make(map[string]interface{}, len(claims))
+	for name, value := range claims {
+		text, ok := isClaimTemplate(value)
+		if !ok {
+			rendered[name] = value
+			continue
+		}
+		tmpl, err := template.New(name).Option("missingkey=error").Parse(text)
+		if err != nil {
+			return nil, fmt.Errorf("claim %q: %v", name, err)
+		}
+		var b strings.Builder
+		if err := tmpl.Execute(&b, data); err != nil {
+			return nil, fmt.Errorf("claim %q: %v", name, err)
+		}
+		rendered[name] = b.String()
+	}
+	return rendered, nil
+}
+
+// releasedConnectorClaims returns the custom claims of a connector identity
+// which a client's policy releases to it: the named ones, or all for "*".
+func releasedConnectorClaims(claims map[string]interface{}, policy []string) map[string]interface{} {
+	released := make(map[string]interface{})
+	for _, name := range policy {
+		if name == "*" {
+			for n, v := range claims {
+				released[n] = v
+			}
+			break
+		}
+		if v, ok := claims[name]; ok {
+			released[name] = v
+		}
+	}
+	return released
+}
+
+// connectorClaims returns the custom claims of the user's connector identity
+// released to the client.
+func (s *Server) connectorClaims(ctx context.Context, clientID string, claims storage.Claims) (map[string]interface{}, error) {
+	if len(claims.CustomClaims) == 0 {
+		return nil, nil
+	}
+	client, err := s.storage.GetClient(ctx, clientID)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get client: %v", err)
+	}
+	return releasedConnectorClaims(claims.CustomClaims, client.ReleasedConnectorClaims), nil
+}
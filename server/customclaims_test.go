@@ -0,0 +1,145 @@
+package server
+
+import (
+	"testing"
+	"time"
+
+	"github.com/coreos/go-oidc/v3/oidc"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dexidp/dex/storage"
+)
+
+func TestValidateCustomClaims(t *testing.T) {
+	require.NoError(t, ValidateCustomClaims(map[string]interface{}{
+		"tenant": "radiology",
+		"login":  "{{.Email}}",
+		"level":  3,
+	}))
+	require.Error(t, ValidateCustomClaims(map[string]interface{}{"login": "{{.Email"}))
+	require.Error(t, ValidateCustomClaims(map[string]interface{}{"sub": "admin"}))
+}
+
+func TestRenderCustomClaims(t *testing.T) {
+	user := storage.Claims{UserID: "1", Username: "jane", Email: "jane@example.com", Groups: []string{"admins", "devs"}}
+
+	claims, err := renderCustomClaims(map[string]interface{}{
+		"tenant":  "radiology",
+		"login":   "{{.ConnectorID}}:{{.Email}}",
+		"primary": "{{index .Groups 0}}",
+		"level":   3,
+	}, user, "ldap")
+	require.NoError(t, err)
+	require.Equal(t, map[string]interface{}{
+		"tenant":  "radiology",
+		"login":   "ldap:jane@example.com",
+		"primary": "admins",
+		"level":   3,
+	}, claims)
+
+	_, err = renderCustomClaims(map[string]interface{}{"login": "{{.Unknown}}"}, user, "ldap")
+	require.Error(t, err)
+}
+
+func TestNewIDTokenCustomClaims(t *testing.T) {
+	ctx := t.Context()
+	httpServer, s := newTestServer(t, nil)
+	defer httpServer.Close()
+
+	require.NoError(t, s.storage.CreateClient(ctx, storage.Client{
+		ID: "client_1",
+		CustomClaims: map[string]interface{}{
+			"tenant": "radiology",
+			"login":  "{{.Email}}",
+			"email":  "other@example.com",
+		},
+	}))
+
+	user := storage.Claims{UserID: "1", Username: "jane", Email: "jane@example.com"}
+	scopes := []string{"openid", "email"}
+
+	provider, err := oidc.NewProvider(ctx, httpServer.URL)
+	require.NoError(t, err)
+	verifier := provider.Verifier(&oidc.Config{ClientID: "client_1"})
+
+	var claims struct {
+		Tenant string `json:"tenant"`
+		Login  string `json:"login"`
+		Email  string `json:"email"`
+	}
+
+	idToken, _, err := s.newIDToken(ctx, "client_1", user, scopes, "", "", "", "mock", time.Time{}, nil)
+	require.NoError(t, err)
+	token, err := verifier.Verify(ctx, idToken)
+	require.NoError(t, err)
+	require.NoError(t, token.Claims(&claims))
+	require.Equal(t, "radiology", claims.Tenant)
+	require.Equal(t, "jane@example.com", claims.Login)
+	// Claims set by dex aren't replaced.
+	require.Equal(t, "jane@example.com", claims.Email)
+
+	// Access tokens don't carry custom claims.
+	accessToken, _, err := s.newAccessToken(ctx, "client_1", user, scopes, "", "mock", time.Time{}, nil)
+	require.NoError(t, err)
+	token, err = verifier.Verify(ctx, accessToken)
+	require.NoError(t, err)
+	claims.Tenant = ""
+	require.NoError(t, token.Claims(&claims))
+	require.Empty(t, claims.Tenant)
+}
+
+func TestNewTokenConnectorClaims(t *testing.T) {
+	ctx := t.Context()
+	httpServer, s := newTestServer(t, nil)
+	defer httpServer.Close()
+
+	for _, client := range []storage.Client{
+		{ID: "named", ReleasedConnectorClaims: []string{"tenant"}},
+		{ID: "all", ReleasedConnectorClaims: []string{"*"}},
+		{ID: "none"},
+	} {
+		require.NoError(t, s.storage.CreateClient(ctx, client))
+	}
+
+	user := storage.Claims{
+		UserID: "1",
+		Email:  "jane@example.com",
+		CustomClaims: map[string]interface{}{
+			"tenant": "radiology",
+			"roles":  []string{"viewer"},
+			"sub":    "admin",
+		},
+	}
+
+	provider, err := oidc.NewProvider(ctx, httpServer.URL)
+	require.NoError(t, err)
+
+	tests := []struct {
+		clientID string
+		want     map[string]interface{}
+	}{
+		{clientID: "named", want: map[string]interface{}{"tenant": "radiology"}},
+		{clientID: "all", want: map[string]interface{}{"tenant": "radiology", "roles": []interface{}{"viewer"}}},
+		{clientID: "none", want: map[string]interface{}{}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.clientID, func(t *testing.T) {
+			idToken, _, err := s.newIDToken(ctx, tc.clientID, user, []string{"openid"}, "", "", "", "mock", time.Time{}, nil)
+			require.NoError(t, err)
+			token, err := provider.Verifier(&oidc.Config{ClientID: tc.clientID}).Verify(ctx, idToken)
+			require.NoError(t, err)
+
+			var claims map[string]interface{}
+			require.NoError(t, token.Claims(&claims))
+			got := make(map[string]interface{})
+			for _, name := range []string{"tenant", "roles"} {
+				if v, ok := claims[name]; ok {
+					got[name] = v
+				}
+			}
+			require.Equal(t, tc.want, got)
+			// Claims set by dex aren't replaced.
+			require.NotEqual(t, "admin", claims["sub"])
+		})
+	}
+}
@@ -1,9 +1,13 @@
 package server
 
 import (
+	"bytes"
+	"context"
+	"encoding/base64"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"image/png"
 	"net/http"
 	"net/url"
 	"path"
@@ -11,6 +15,9 @@ import (
 	"strings"
 	"time"
 
+	"github.com/boombuler/barcode"
+	"github.com/boombuler/barcode/qr"
+
 	"github.com/dexidp/dex/storage"
 )
 
@@ -29,10 +36,128 @@ type deviceCodeResponse struct {
 	PollInterval int `json:"interval"`
 }
 
+// defaultDevicePollInterval is the minimum number of seconds between polls of
+// the token endpoint for clients without their own interval.
+const defaultDevicePollInterval = 5
+
 func (s *Server) getDeviceVerificationURI() string {
 	return path.Join(s.issuerURL.Path, "/device/auth/verify_code")
 }
 
+// deviceVerificationURIComplete returns the device page with the user code
+// filled in, for devices to show as a link or QR code.
+func (s *Server) deviceVerificationURIComplete(userCode string) string {
+	return s.absURL("/device") + "?" + url.Values{"user_code": {userCode}}.Encode()
+}
+
+// deviceFlowSettings are the device flow settings of a client.
+type deviceFlowSettings struct {
+	// pollInterval is in seconds.
+	pollInterval     int
+	validFor         time.Duration
+	userCodeAlphabet string
+	userCodeLength   int
+}
+
+// userCode returns a new user code for the client.
+func (d deviceFlowSettings) userCode() string {
+	return storage.NewUserCodeFrom(d.userCodeAlphabet, d.userCodeLength)
+}
+
+// ValidateDeviceFlow checks the device flow settings of a client.
+func ValidateDeviceFlow(c storage.DeviceFlowConfig) error {
+	if c.PollInterval < 0 || c.ExpiresIn < 0 {
+		return errors.New("pollInterval and expiresIn must not be negative")
+	}
+	if c.UserCodeLength != 0 && (c.UserCodeLength < 6 || c.UserCodeLength > 32) {
+		return fmt.Errorf("userCodeLength must be between 6 and 32, got %d", c.UserCodeLength)
+	}
+	if c.UserCodeAlphabet == "" {
+		return nil
+	}
+	seen := make(map[rune]bool)
+	for _, r := range c.UserCodeAlphabet {
+		if (r < 'A' || r > 'Z') && (r < '0' || r > '9') {
+			return fmt.Errorf("userCodeAlphabet may only contain upper case letters and digits, got %q", r)
+		}
+		if seen[r] {
+			return fmt.Errorf("userCodeAlphabet contains %q twice", r)
+		}
+		seen[r] = true
+	}
+	if len(seen) < 2 {
+		return errors.New("userCodeAlphabet needs at least two characters")
+	}
+	return nil
+}
+
+// deviceFlowSettings returns the device flow settings of a client.
+func (s *Server) deviceFlowSettings(ctx context.Context, clientID string) deviceFlowSettings {
+	d := deviceFlowSettings{pollInterval: defaultDevicePollInterval, validFor: s.deviceRequestsValidFor}
+	if clientID == "" {
+		return d
+	}
+	client, err := s.storage.GetClient(ctx, clientID)
+	if err != nil {
+		if err != storage.ErrNotFound {
+			s.logger.ErrorContext(ctx, "failed to get client", "client_id", clientID, "err", err)
+		}
+		return d
+	}
+	if c := client.DeviceFlow; c != nil {
+		if c.PollInterval > 0 {
+			d.pollInterval = c.PollInterval
+		}
+		if c.ExpiresIn > 0 {
+			d.validFor = time.Duration(c.ExpiresIn) * time.Second
+		}
+		d.userCodeAlphabet = c.UserCodeAlphabet
+		d.userCodeLength = c.UserCodeLength
+	}
+	return d
+}
+
+// deviceFlowOnly reports whether the client is restricted to the device flow.
+func deviceFlowOnly(client storage.Client) bool {
+	return client.DeviceFlow != nil && client.DeviceFlow.Only
+}
+
+// handleDeviceQRCode serves a QR code of the device page with a user code
+// filled in, so devices with a screen can show it for users to scan.
+func (s *Server) handleDeviceQRCode(w http.ResponseWriter, r *http.Request) {
+	userCode := r.URL.Query().Get("user_code")
+	if r.Method != http.MethodGet || userCode == "" {
+		s.renderError(r, w, http.StatusBadRequest, "Requested resource does not exist.")
+		return
+	}
+	img, err := generateQRCode(s.deviceVerificationURIComplete(userCode), 256)
+	if err != nil {
+		s.logger.ErrorContext(r.Context(), "failed to generate device QR code", "err", err)
+		s.renderError(r, w, http.StatusInternalServerError, "")
+		return
+	}
+	w.Header().Set("Content-Type", "image/png")
+	w.Header().Set("Cache-Control", "no-store")
+	w.Write(img)
+}
+
+// generateQRCode returns a PNG of a QR code of content.
+func generateQRCode(content string, size int) ([]byte, error) {
+	code, err := qr.Encode(content, qr.M, qr.Auto)
+	if err != nil {
+		return nil, fmt.Errorf("encode QR code: %w", err)
+	}
+	code, err = barcode.Scale(code, size, size)
+	if err != nil {
+		return nil, fmt.Errorf("scale QR code: %w", err)
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, code); err != nil {
+		return nil, fmt.Errorf("encode QR code image: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
 func (s *Server) handleDeviceExchange(w http.ResponseWriter, r *http.Request) {
 	switch r.Method {
 	case http.MethodGet:
@@ -45,7 +170,18 @@ func (s *Server) handleDeviceExchange(w http.ResponseWriter, r *http.Request) {
 		if err != nil {
 			invalidAttempt = false
 		}
-		if err := s.templates.device(r, w, s.getDeviceVerificationURI(), userCode, invalidAttempt); err != nil {
+		// Offer to continue on another device, e.g. a phone, when the
+		// user code is filled in.
+		var qrCode string
+		if userCode != "" && !invalidAttempt {
+			img, err := generateQRCode(s.deviceVerificationURIComplete(userCode), 200)
+			if err != nil {
+				s.logger.ErrorContext(r.Context(), "failed to generate device QR code", "err", err)
+			} else {
+				qrCode = base64.StdEncoding.EncodeToString(img)
+			}
+		}
+		if err := s.templates().device(r, w, s.getDeviceVerificationURI(), userCode, qrCode, invalidAttempt); err != nil {
 			s.logger.ErrorContext(r.Context(), "server template error", "err", err)
 			s.renderError(r, w, http.StatusNotFound, "Page not found")
 		}
@@ -56,7 +192,6 @@ func (s *Server) handleDeviceExchange(w http.ResponseWriter, r *http.Request) {
 
 func (s *Server) handleDeviceCode(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
-	pollIntervalSeconds := 5
 
 	switch r.Method {
 	case http.MethodPost:
@@ -94,11 +229,14 @@ func (s *Server) handleDeviceCode(w http.ResponseWriter, r *http.Request) {
 		// Make device code
 		deviceCode := storage.NewDeviceCode()
 
+		settings := s.deviceFlowSettings(ctx, clientID)
+		pollIntervalSeconds, validFor := settings.pollInterval, settings.validFor
+
 		// make user code
-		userCode := storage.NewUserCode()
+		userCode := settings.userCode()
 
 		// Generate the expire time
-		expireTime := time.Now().Add(s.deviceRequestsValidFor)
+		expireTime := time.Now().Add(validFor)
 
 		// Store the Device Request
 		deviceReq := storage.DeviceRequest{
@@ -122,7 +260,7 @@ func (s *Server) handleDeviceCode(w http.ResponseWriter, r *http.Request) {
 			Status:              deviceTokenPending,
 			Expiry:              expireTime,
 			LastRequestTime:     s.now(),
-			PollIntervalSeconds: 0,
+			PollIntervalSeconds: pollIntervalSeconds,
 			PKCE: storage.PKCE{
 				CodeChallenge:       codeChallenge,
 				CodeChallengeMethod: codeChallengeMethod,
@@ -135,26 +273,12 @@ func (s *Server) handleDeviceCode(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
-		u, err := url.Parse(s.issuerURL.String())
-		if err != nil {
-			s.logger.ErrorContext(r.Context(), "could not parse issuer URL", "err", err)
-			s.tokenErrHelper(w, errInvalidRequest, "", http.StatusInternalServerError)
-			return
-		}
-		u.Path = path.Join(u.Path, "device")
-		vURI := u.String()
-
-		q := u.Query()
-		q.Set("user_code", userCode)
-		u.RawQuery = q.Encode()
-		vURIComplete := u.String()
-
 		code := deviceCodeResponse{
 			DeviceCode:              deviceCode,
 			UserCode:                userCode,
-			VerificationURI:         vURI,
-			VerificationURIComplete: vURIComplete,
-			ExpireTime:              int(s.deviceRequestsValidFor.Seconds()),
+			VerificationURI:         s.absURL("/device"),
+			VerificationURIComplete: s.deviceVerificationURIComplete(userCode),
+			ExpireTime:              int(validFor.Seconds()),
 			PollInterval:            pollIntervalSeconds,
 		}
 
@@ -225,16 +349,16 @@ func (s *Server) handleDeviceToken(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Rate Limiting check
+	// Rate Limiting check. The poll interval of the client was stored with
+	// the device token, the device can't pick another one.
 	slowDown := false
 	pollInterval := deviceToken.PollIntervalSeconds
 	minRequestTime := deviceToken.LastRequestTime.Add(time.Second * time.Duration(pollInterval))
 	if now.Before(minRequestTime) {
 		slowDown = true
-		// Continually increase the poll interval until the user waits the proper time
-		pollInterval += 5
-	} else {
-		pollInterval = 5
+		// Per RFC 8628 section 3.5, the interval is increased for this and
+		// all subsequent requests.
+		pollInterval += defaultDevicePollInterval
 	}
 
 	switch deviceToken.Status {
@@ -344,7 +468,7 @@ func (s *Server) handleDeviceCallback(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
-		resp, err := s.exchangeAuthCode(ctx, w, authCode, client)
+		resp, err := s.exchangeAuthCode(ctx, w, authCode, client, authCode.Resources)
 		if err != nil {
 			s.logger.ErrorContext(r.Context(), "could not exchange auth code for clien", "client_id", deviceReq.ClientID, "err", err)
 			s.renderError(r, w, http.StatusInternalServerError, "Failed to exchange auth code.")
@@ -386,7 +510,7 @@ func (s *Server) handleDeviceCallback(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
-		if err := s.templates.deviceSuccess(r, w, client.Name); err != nil {
+		if err := s.templates().deviceSuccess(r, w, client.Name); err != nil {
 			s.logger.ErrorContext(r.Context(), "Server template error", "err", err)
 			s.renderError(r, w, http.StatusNotFound, "Page not found")
 		}
@@ -423,7 +547,7 @@ func (s *Server) verifyUserCode(w http.ResponseWriter, r *http.Request) {
 			if err != nil && err != storage.ErrNotFound {
 				s.logger.ErrorContext(r.Context(), "failed to get device request", "err", err)
 			}
-			if err := s.templates.device(r, w, s.getDeviceVerificationURI(), userCode, true); err != nil {
+			if err := s.templates().device(r, w, s.getDeviceVerificationURI(), userCode, "", true); err != nil {
 				s.logger.ErrorContext(r.Context(), "Server template error", "err", err)
 				s.renderError(r, w, http.StatusNotFound, "Page not found")
 			}
@@ -3,6 +3,7 @@ package server
 import (
 	"bytes"
 	"encoding/json"
+	"image/png"
 	"io"
 	"net/http"
 	"net/http/httptest"
@@ -12,6 +13,8 @@ import (
 	"testing"
 	"time"
 
+	"github.com/stretchr/testify/require"
+
 	"github.com/dexidp/dex/storage"
 )
 
@@ -860,3 +863,157 @@ func TestVerifyCodeResponse(t *testing.T) {
 		})
 	}
 }
+
+func TestDeviceFlowClientSettings(t *testing.T) {
+	httpServer, s := newTestServer(t, nil)
+	defer httpServer.Close()
+
+	require.NoError(t, s.storage.CreateClient(t.Context(), storage.Client{
+		ID:         "tv",
+		Public:     true,
+		DeviceFlow: &storage.DeviceFlowConfig{PollInterval: 10, ExpiresIn: 120},
+	}))
+
+	requestCode := func(clientID string) deviceCodeResponse {
+		resp, err := http.PostForm(httpServer.URL+"/device/code", url.Values{"client_id": {clientID}})
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		var code deviceCodeResponse
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&code))
+		return code
+	}
+
+	code := requestCode("tv")
+	require.Equal(t, 10, code.PollInterval)
+	require.Equal(t, 120, code.ExpireTime)
+	require.Equal(t, httpServer.URL+"/device?user_code="+code.UserCode, code.VerificationURIComplete)
+
+	code = requestCode("test")
+	require.Equal(t, defaultDevicePollInterval, code.PollInterval)
+	require.Equal(t, int(s.deviceRequestsValidFor.Seconds()), code.ExpireTime)
+	require.Regexp(t, `^[BCDFGHJKLMNPQRSTVWXZ]{4}-[BCDFGHJKLMNPQRSTVWXZ]{4}$`, code.UserCode)
+
+	require.NoError(t, s.storage.CreateClient(t.Context(), storage.Client{
+		ID:         "keypad",
+		Public:     true,
+		DeviceFlow: &storage.DeviceFlowConfig{UserCodeLength: 10, UserCodeAlphabet: "0123456789"},
+	}))
+	code = requestCode("keypad")
+	require.Regexp(t, `^[0-9]{4}-[0-9]{4}-[0-9]{2}$`, code.UserCode)
+}
+
+func TestDeviceFlowOnlyClient(t *testing.T) {
+	httpServer, s := newTestServer(t, nil)
+	defer httpServer.Close()
+
+	require.NoError(t, s.storage.CreateClient(t.Context(), storage.Client{
+		ID:           "device-only",
+		Secret:       "secret",
+		RedirectURIs: []string{"https://app.example.com/callback", s.absPath(deviceCallbackURI)},
+		DeviceFlow:   &storage.DeviceFlowConfig{Only: true},
+	}))
+
+	authorize := func(redirectURI string) int {
+		q := url.Values{
+			"client_id":     {"device-only"},
+			"redirect_uri":  {redirectURI},
+			"response_type": {"code"},
+			"scope":         {"openid"},
+		}
+		rr := httptest.NewRecorder()
+		s.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/auth/mock?"+q.Encode(), nil))
+		return rr.Code
+	}
+	require.Equal(t, http.StatusBadRequest, authorize("https://app.example.com/callback"))
+	require.NotEqual(t, http.StatusBadRequest, authorize(s.absPath(deviceCallbackURI)))
+
+	resp, err := http.PostForm(httpServer.URL+"/token", url.Values{
+		"grant_type":    {grantTypeClientCredentials},
+		"client_id":     {"device-only"},
+		"client_secret": {"secret"},
+	})
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	var tokenErr struct {
+		Error string `json:"error"`
+	}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&tokenErr))
+	require.Equal(t, errUnauthorizedClient, tokenErr.Error)
+}
+
+func TestValidateDeviceFlow(t *testing.T) {
+	require.NoError(t, ValidateDeviceFlow(storage.DeviceFlowConfig{UserCodeLength: 6, UserCodeAlphabet: "0123456789"}))
+	require.Error(t, ValidateDeviceFlow(storage.DeviceFlowConfig{UserCodeAlphabet: "abc"}), "lower case")
+	require.Error(t, ValidateDeviceFlow(storage.DeviceFlowConfig{UserCodeAlphabet: "AAB"}), "duplicate characters")
+	require.Error(t, ValidateDeviceFlow(storage.DeviceFlowConfig{UserCodeAlphabet: "A-B"}), "dash")
+	require.Error(t, ValidateDeviceFlow(storage.DeviceFlowConfig{UserCodeLength: 4}))
+}
+
+func TestDeviceQRCode(t *testing.T) {
+	httpServer, _ := newTestServer(t, nil)
+	defer httpServer.Close()
+
+	resp, err := http.Get(httpServer.URL + "/device/qr?user_code=ABCD-EFGH")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Equal(t, "image/png", resp.Header.Get("Content-Type"))
+	_, err = png.Decode(resp.Body)
+	require.NoError(t, err)
+
+	resp, err = http.Get(httpServer.URL + "/device?user_code=ABCD-EFGH")
+	require.NoError(t, err)
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	require.NoError(t, err)
+	require.Contains(t, string(body), "data:image/png;base64,")
+}
+
+func TestDeviceTokenPollInterval(t *testing.T) {
+	t0 := time.Now()
+	now := t0
+	httpServer, s := newTestServer(t, func(c *Config) {
+		c.Now = func() time.Time { return now }
+	})
+	defer httpServer.Close()
+
+	require.NoError(t, s.storage.CreateClient(t.Context(), storage.Client{
+		ID:         "tv",
+		Public:     true,
+		DeviceFlow: &storage.DeviceFlowConfig{PollInterval: 10},
+	}))
+
+	resp, err := http.PostForm(httpServer.URL+"/device/code", url.Values{"client_id": {"tv"}})
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	var code deviceCodeResponse
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&code))
+
+	token, err := s.storage.GetDeviceToken(t.Context(), code.DeviceCode)
+	require.NoError(t, err)
+	require.Equal(t, 10, token.PollIntervalSeconds)
+
+	poll := func(clientID string) string {
+		data := url.Values{"grant_type": {grantTypeDeviceCode}, "device_code": {code.DeviceCode}, "client_id": {clientID}}
+		req := httptest.NewRequest(http.MethodPost, "/device/token", strings.NewReader(data.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		rr := httptest.NewRecorder()
+		s.ServeHTTP(rr, req)
+		var res struct {
+			Error string `json:"error"`
+		}
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &res))
+		return res.Error
+	}
+
+	// Another client_id doesn't lower the interval of the client.
+	now = t0.Add(6 * time.Second)
+	require.Equal(t, deviceTokenSlowDown, poll("test"))
+	now = now.Add(11 * time.Second)
+	require.Equal(t, deviceTokenSlowDown, poll("test"))
+	// Slowed down twice, the device now waits 20 seconds.
+	now = now.Add(20 * time.Second)
+	require.Equal(t, deviceTokenPending, poll("test"))
+}
@@ -0,0 +1,77 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// ValidateDiscoveryOverrides checks the fields operators add to, or replace
+// in, the discovery document. The issuer can't be overridden, and fields
+// named like endpoints or URIs must be absolute HTTP(S) URLs.
+func ValidateDiscoveryOverrides(overrides map[string]interface{}) error {
+	for name, value := range overrides {
+		if name == "" {
+			return errors.New("empty field name")
+		}
+		if name == "issuer" {
+			return errors.New("the issuer cannot be overridden")
+		}
+		if value == nil {
+			return fmt.Errorf("field %q has no value", name)
+		}
+		if !isDiscoveryURLField(name) {
+			continue
+		}
+		s, ok := value.(string)
+		if !ok {
+			return fmt.Errorf("field %q must be a URL", name)
+		}
+		u, err := url.Parse(s)
+		if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+			return fmt.Errorf("field %q must be an absolute http(s) URL, got %q", name, s)
+		}
+	}
+	return nil
+}
+
+// isDiscoveryURLField reports whether a field of the discovery document holds
+// a URL, e.g. revocation_endpoint or jwks_uri.
+func isDiscoveryURLField(name string) bool {
+	return strings.HasSuffix(name, "_endpoint") || strings.HasSuffix(name, "_uri") || strings.HasSuffix(name, "_url")
+}
+
+// discoveryDocument returns the discovery document, with the overrides of
+// the config applied.
+func (s *Server) discoveryDocument(ctx context.Context) ([]byte, error) {
+	d := s.constructDiscovery(ctx)
+	if len(s.discoveryOverrides) == 0 {
+		return json.MarshalIndent(d, "", "  ")
+	}
+
+	data, err := json.Marshal(d)
+	if err != nil {
+		return nil, err
+	}
+	var doc map[string]interface{}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	for name, value := range s.discoveryOverrides {
+		doc[name] = value
+	}
+	if data, err = json.MarshalIndent(doc, "", "  "); err != nil {
+		return nil, err
+	}
+
+	// Overrides of standard fields must keep their type.
+	dec := json.NewDecoder(bytes.NewReader(data))
+	if err := dec.Decode(&discovery{}); err != nil {
+		return nil, fmt.Errorf("invalid discovery overrides: %v", err)
+	}
+	return data, nil
+}
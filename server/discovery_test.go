@@ -0,0 +1,72 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateDiscoveryOverrides(t *testing.T) {
+	tests := []struct {
+		name      string
+		overrides map[string]interface{}
+		wantErr   bool
+	}{
+		{name: "none"},
+		{
+			name: "endpoints and custom fields",
+			overrides: map[string]interface{}{
+				"revocation_endpoint":        "https://iam.example.com/revoke",
+				"service_documentation_url":  "http://docs.example.com",
+				"hsdp_region":                "us-east",
+				"claims_parameter_supported": true,
+			},
+		},
+		{name: "issuer", overrides: map[string]interface{}{"issuer": "https://other.example.com"}, wantErr: true},
+		{name: "null", overrides: map[string]interface{}{"hsdp_region": nil}, wantErr: true},
+		{name: "relative URL", overrides: map[string]interface{}{"end_session_endpoint": "/logout"}, wantErr: true},
+		{name: "other scheme", overrides: map[string]interface{}{"jwks_uri": "ftp://example.com/keys"}, wantErr: true},
+		{name: "URL not a string", overrides: map[string]interface{}{"registration_endpoint": 1.0}, wantErr: true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			err := ValidateDiscoveryOverrides(tc.overrides)
+			if tc.wantErr {
+				require.Error(t, err)
+			} else {
+				require.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestDiscoveryOverrides(t *testing.T) {
+	httpServer, server := newTestServer(t, func(c *Config) {
+		c.DiscoveryOverrides = map[string]interface{}{
+			"revocation_endpoint": "https://iam.example.com/revoke",
+			"hsdp_region":         "us-east",
+			"scopes_supported":    []interface{}{"openid", "email"},
+		}
+	})
+	defer httpServer.Close()
+
+	rr := httptest.NewRecorder()
+	server.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/.well-known/openid-configuration", nil))
+	require.Equal(t, http.StatusOK, rr.Code)
+
+	var got map[string]interface{}
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &got))
+	require.Equal(t, "https://iam.example.com/revoke", got["revocation_endpoint"])
+	require.Equal(t, "us-east", got["hsdp_region"])
+	require.Equal(t, []interface{}{"openid", "email"}, got["scopes_supported"])
+	require.Equal(t, httpServer.URL, got["issuer"])
+	require.Equal(t, httpServer.URL+"/token", got["token_endpoint"])
+
+	// Overrides of standard fields must keep their type.
+	server.discoveryOverrides = map[string]interface{}{"scopes_supported": "openid"}
+	_, err := server.discoveryDocument(t.Context())
+	require.Error(t, err)
+}
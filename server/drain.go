@@ -0,0 +1,139 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// drainPollInterval is how often Drain checks for logins in flight.
+const drainPollInterval = 100 * time.Millisecond
+
+// loginTracker tracks the logins started on this server which have not
+// completed yet. The tracker is local to the server, a login completed on
+// another replica is only forgotten once it expires, so logins are tracked no
+// longer than the drain timeout.
+type loginTracker struct {
+	mu     sync.Mutex
+	logins map[string]time.Time // auth request ID to expiry
+	maxAge time.Duration        // zero disables tracking
+}
+
+func (t *loginTracker) start(id string, now, expiry time.Time) {
+	if t.maxAge == 0 {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.logins == nil {
+		t.logins = make(map[string]time.Time)
+	}
+	t.pruneLocked(now)
+	if limit := now.Add(t.maxAge); expiry.After(limit) {
+		expiry = limit
+	}
+	t.logins[id] = expiry
+}
+
+func (t *loginTracker) done(id string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	delete(t.logins, id)
+}
+
+// prune forgets the expired logins.
+func (t *loginTracker) prune(now time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.pruneLocked(now)
+}
+
+func (t *loginTracker) pruneLocked(now time.Time) {
+	for id, expiry := range t.logins {
+		if now.After(expiry) {
+			delete(t.logins, id)
+		}
+	}
+}
+
+// pending returns the number of logins in flight, forgetting expired ones.
+func (t *loginTracker) pending(now time.Time) int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.pruneLocked(now)
+	return len(t.logins)
+}
+
+type loginContextKey struct{}
+
+// withLogin marks the request as part of the login of the auth request, so
+// rendering an error ends the login.
+func withLogin(r *http.Request, authReqID string) *http.Request {
+	return r.WithContext(context.WithValue(r.Context(), loginContextKey{}, authReqID))
+}
+
+// failLogin ends the login of the request, if any, after an error.
+func (s *Server) failLogin(r *http.Request) {
+	if id, ok := r.Context().Value(loginContextKey{}).(string); ok {
+		s.logins.done(id)
+	}
+}
+
+// Drain stops the server from starting new logins and waits until the logins
+// in flight on it have completed or expired, or ctx is done. Callbacks,
+// approvals and token requests keep being served.
+func (s *Server) Drain(ctx context.Context) error {
+	s.draining.Store(true)
+
+	ticker := time.NewTicker(drainPollInterval)
+	defer ticker.Stop()
+	for {
+		n := s.logins.pending(s.now())
+		if n == 0 {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("server: %d logins still in flight: %w", n, ctx.Err())
+		case <-ticker.C:
+		}
+	}
+}
+
+// Draining reports whether Drain has been called.
+func (s *Server) Draining() bool {
+	return s.draining.Load()
+}
+
+// rejectIfDraining renders an error for requests starting a new login while
+// the server is draining. It reports whether the request was rejected.
+func (s *Server) rejectIfDraining(w http.ResponseWriter, r *http.Request) bool {
+	if !s.draining.Load() {
+		return false
+	}
+	w.Header().Set("Retry-After", "1")
+	s.renderError(r, w, http.StatusServiceUnavailable, "Server is shutting down, please try again.")
+	return true
+}
+
+// CloseConnectors closes all open connectors, and the replaced ones waiting to
+// be closed. It is meant to be called on shutdown, once the server no longer
+// serves requests.
+func (s *Server) CloseConnectors() {
+	s.mu.Lock()
+	connectors := s.connectors
+	s.connectors = make(map[string]Connector)
+	retired := s.retiredConnectors
+	s.retiredConnectors = nil
+	s.mu.Unlock()
+
+	for id, conn := range connectors {
+		closeConnector(s.logger, id, conn.Connector)
+	}
+	for r := range retired {
+		r.timer.Stop()
+		closeConnector(s.logger, r.id, r.conn)
+	}
+}
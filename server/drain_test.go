@@ -0,0 +1,119 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/dexidp/dex/connector"
+)
+
+func TestDrain(t *testing.T) {
+	httpServer, s := newTestServer(t, func(c *Config) {
+		c.DrainTimeout = time.Hour
+	})
+	defer httpServer.Close()
+
+	now := s.now()
+	s.logins.start("in-flight", now, now.Add(time.Hour))
+	s.logins.start("expired", now, now.Add(-time.Minute))
+
+	drained := make(chan error, 1)
+	go func() { drained <- s.Drain(t.Context()) }()
+
+	require.Eventually(t, s.Draining, time.Second, 10*time.Millisecond)
+	for _, path := range []string{"/auth", "/auth/mock"} {
+		rr := httptest.NewRecorder()
+		s.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, path+"?client_id=test&response_type=code", nil))
+		require.Equal(t, http.StatusServiceUnavailable, rr.Code, path)
+	}
+
+	select {
+	case err := <-drained:
+		t.Fatalf("drain returned with a login in flight: %v", err)
+	case <-time.After(2 * drainPollInterval):
+	}
+
+	s.logins.done("in-flight")
+	select {
+	case err := <-drained:
+		require.NoError(t, err)
+	case <-time.After(time.Second):
+		t.Fatal("drain did not return after the last login completed")
+	}
+}
+
+func TestDrainTimeout(t *testing.T) {
+	httpServer, s := newTestServer(t, func(c *Config) {
+		c.DrainTimeout = time.Hour
+	})
+	defer httpServer.Close()
+
+	s.logins.start("abandoned", s.now(), s.now().Add(time.Hour))
+
+	ctx, cancel := context.WithTimeout(t.Context(), 3*drainPollInterval)
+	defer cancel()
+	require.ErrorIs(t, s.Drain(ctx), context.DeadlineExceeded)
+}
+
+func TestLoginTracker(t *testing.T) {
+	now := time.Now()
+
+	disabled := loginTracker{}
+	disabled.start("login", now, now.Add(time.Hour))
+	require.Zero(t, disabled.pending(now))
+
+	tracker := loginTracker{maxAge: time.Minute}
+	tracker.start("abandoned", now, now.Add(24*time.Hour))
+	require.Equal(t, 1, tracker.pending(now))
+
+	// Logins are tracked no longer than the drain timeout, even if their
+	// auth request is valid for longer.
+	later := now.Add(2 * time.Minute)
+	tracker.start("new", later, later.Add(24*time.Hour))
+	tracker.mu.Lock()
+	require.NotContains(t, tracker.logins, "abandoned")
+	tracker.mu.Unlock()
+	require.Equal(t, 1, tracker.pending(later))
+}
+
+func TestDrainFailedLogin(t *testing.T) {
+	httpServer, s := newTestServer(t, func(c *Config) {
+		c.DrainTimeout = time.Hour
+	})
+	defer httpServer.Close()
+
+	s.logins.start("failed", s.now(), s.now().Add(time.Hour))
+	r := withLogin(httptest.NewRequest(http.MethodGet, "/callback", nil), "failed")
+	s.renderError(r, httptest.NewRecorder(), http.StatusInternalServerError, "Login error.")
+	require.Zero(t, s.logins.pending(s.now()))
+}
+
+type closeRecorder struct {
+	connector.CallbackConnector
+	closed atomic.Bool
+}
+
+func (c *closeRecorder) Close() error {
+	c.closed.Store(true)
+	return nil
+}
+
+func TestCloseConnectors(t *testing.T) {
+	httpServer, s := newTestServer(t, nil)
+	defer httpServer.Close()
+
+	conn := &closeRecorder{CallbackConnector: &promptForwardingConnector{}}
+	registerTestConnector(t, s, "closing", conn)
+
+	s.CloseConnectors()
+	require.True(t, conn.closed.Load())
+	s.mu.Lock()
+	require.Empty(t, s.connectors)
+	s.mu.Unlock()
+}
@@ -0,0 +1,95 @@
+package server
+
+import (
+	"net/http"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/dexidp/dex/api/v2"
+	"github.com/dexidp/dex/connector"
+	"github.com/dexidp/dex/pkg/mailer"
+)
+
+func TestEmailVerification(t *testing.T) {
+	ctx := t.Context()
+	mails := make(testMailer, 10)
+	httpServer, s := newTestServer(t, func(c *Config) {
+		c.EmailVerification = &EmailVerificationConfig{Mailer: mails}
+	})
+	defer httpServer.Close()
+
+	dexAPI := NewAPI(s.storage, s.logger, "test", s)
+	_, err := dexAPI.CreatePassword(ctx, &api.CreatePasswordReq{
+		Password: &api.Password{
+			Email: "jane@example.com",
+			// bcrypt hash of the value "test1" with cost 10
+			Hash:     []byte("$2a$10$XVMN/Fid.Ks4CXgzo8fpR.iU1khOMsP5g9xQeXuBm1wXjRX8pjUtO"),
+			Username: "jane",
+			UserId:   "1",
+		},
+		VerifyEmail: true,
+	})
+	require.NoError(t, err)
+
+	var msg mailer.Message
+	select {
+	case msg = <-mails:
+	case <-time.After(5 * time.Second):
+		t.Fatal("no verification email sent")
+	}
+	require.Equal(t, "jane@example.com", msg.To)
+
+	list, err := dexAPI.ListPasswords(ctx, &api.ListPasswordReq{})
+	require.NoError(t, err)
+	require.Len(t, list.Passwords, 1)
+	require.False(t, list.Passwords[0].EmailVerified)
+
+	identity, ok, err := newPasswordDB(s.storage).Login(ctx, connector.Scopes{}, "jane@example.com", "test1")
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.False(t, identity.EmailVerified)
+
+	resp, err := http.Get(httpServer.URL + "/verify-email?token=invalid")
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+	link := regexp.MustCompile(`https?://\S+token=\S+`).FindString(msg.Body)
+	resp, err = http.Get(link)
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	identity, ok, err = newPasswordDB(s.storage).Login(ctx, connector.Scopes{}, "jane@example.com", "test1")
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.True(t, identity.EmailVerified)
+
+	// Asking for verification again marks the email unverified until the new
+	// link is opened.
+	_, err = dexAPI.UpdatePassword(ctx, &api.UpdatePasswordReq{Email: "jane@example.com", VerifyEmail: true})
+	require.NoError(t, err)
+	p, err := s.storage.GetPassword(ctx, "jane@example.com")
+	require.NoError(t, err)
+	require.False(t, resolvePasswordEmailVerified(p))
+	require.Len(t, mails, 1)
+}
+
+func TestEmailVerificationDisabled(t *testing.T) {
+	httpServer, s := newTestServer(t, nil)
+	defer httpServer.Close()
+
+	dexAPI := NewAPI(s.storage, s.logger, "test", s)
+	_, err := dexAPI.CreatePassword(t.Context(), &api.CreatePasswordReq{
+		Password: &api.Password{
+			Email:  "jane@example.com",
+			Hash:   []byte("$2a$10$XVMN/Fid.Ks4CXgzo8fpR.iU1khOMsP5g9xQeXuBm1wXjRX8pjUtO"),
+			UserId: "1",
+		},
+		VerifyEmail: true,
+	})
+	require.Error(t, err)
+}
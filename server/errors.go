@@ -1,5 +1,13 @@
 package server
 
+import (
+	"encoding/json"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
 // Safe error messages for user-facing responses.
 // These messages are intentionally generic to avoid leaking internal details.
 // All actual error details should be logged server-side.
@@ -27,4 +35,74 @@ const (
 	// ErrMsgNotInRequiredGroups is shown when a user authenticates successfully
 	// but is not a member of any of the groups required by the connector.
 	ErrMsgNotInRequiredGroups = "You are not a member of any of the required groups to authenticate."
+
+	// ErrMsgLoginDenied is shown when the risk engine denies a login.
+	ErrMsgLoginDenied = "Your login was denied. Please contact your administrator."
 )
+
+// jsonError is the body of errors of the auth and callback endpoints for
+// clients preferring JSON, e.g. SPAs and CLIs, instead of the error page.
+type jsonError struct {
+	Error       string `json:"error"`
+	Description string `json:"error_description,omitempty"`
+	// LoginErrorCode is the code of a connector login error, if any.
+	LoginErrorCode string `json:"login_error_code,omitempty"`
+	// RequestID correlates the error with the logs of dex.
+	RequestID string `json:"request_id,omitempty"`
+}
+
+// prefersJSON reports whether the Accept header of r ranks JSON above HTML.
+// Browsers, and clients accepting anything, get the error page.
+func prefersJSON(r *http.Request) bool {
+	var jsonQ, htmlQ float64
+	for _, accept := range strings.Split(r.Header.Get("Accept"), ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(accept))
+		if err != nil {
+			continue
+		}
+		q := 1.0
+		if v, ok := params["q"]; ok {
+			if q, err = strconv.ParseFloat(v, 64); err != nil {
+				continue
+			}
+		}
+		switch {
+		case mediaType == "application/json" || strings.HasSuffix(mediaType, "+json"):
+			jsonQ = max(jsonQ, q)
+		case mediaType == "text/html":
+			htmlQ = max(htmlQ, q)
+		}
+	}
+	return jsonQ > 0 && jsonQ > htmlQ
+}
+
+// jsonErrorType maps the status of an error page to an OAuth2 error code.
+func jsonErrorType(status int) string {
+	switch status {
+	case http.StatusBadRequest, http.StatusNotFound, http.StatusMethodNotAllowed:
+		return errInvalidRequest
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return errAccessDenied
+	case http.StatusTooManyRequests, http.StatusServiceUnavailable:
+		return errTemporarilyUnavailable
+	}
+	return errServerError
+}
+
+func (s *Server) renderJSONError(r *http.Request, w http.ResponseWriter, status int, description, loginErrCode string) {
+	body, err := json.Marshal(jsonError{
+		Error:          jsonErrorType(status),
+		Description:    description,
+		LoginErrorCode: loginErrCode,
+		RequestID:      RequestID(r.Context()),
+	})
+	if err != nil {
+		s.logger.ErrorContext(r.Context(), "failed to marshal error response", "err", err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+	w.WriteHeader(status)
+	w.Write(body)
+}
@@ -2,6 +2,7 @@ package server
 
 import (
 	"bytes"
+	"encoding/json"
 	"io"
 	"net/http"
 	"net/http/httptest"
@@ -298,3 +299,52 @@ func TestTokenErrorDoesNotLeakDetails(t *testing.T) {
 	require.NotContains(t, bodyStr, "not found")
 	require.NotContains(t, bodyStr, ".go:")
 }
+
+func TestPrefersJSON(t *testing.T) {
+	tests := map[string]bool{
+		"":    false,
+		"*/*": false,
+		"text/html,application/xhtml+xml,*/*;q=0.8": false,
+		"application/json":                          true,
+		"application/problem+json":                  true,
+		"application/json, */*;q=0.5":               true,
+		"text/html;q=0.9, application/json":         true,
+		"application/json;q=0.5, text/html":         false,
+		"application/json;q=0":                      false,
+	}
+	for accept, want := range tests {
+		r := httptest.NewRequest(http.MethodGet, "/auth", nil)
+		r.Header.Set("Accept", accept)
+		require.Equal(t, want, prefersJSON(r), "Accept: %q", accept)
+	}
+}
+
+// TestJSONErrors verifies that the auth and callback endpoints return OAuth2
+// errors as JSON to clients asking for JSON.
+func TestJSONErrors(t *testing.T) {
+	httpServer, s := newTestServer(t, nil)
+	defer httpServer.Close()
+
+	for _, path := range []string{"/auth?client_id=unknown", "/callback?code=test&state=invalid"} {
+		rr := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.Header.Set("Accept", "application/json")
+		req.Header.Set("X-Request-Id", "req-123")
+		s.ServeHTTP(rr, req)
+
+		require.Equal(t, http.StatusBadRequest, rr.Code, path)
+		require.Equal(t, "application/json", rr.Header().Get("Content-Type"))
+		var body jsonError
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &body))
+		require.Equal(t, errInvalidRequest, body.Error)
+		require.NotEmpty(t, body.Description)
+		require.Equal(t, "req-123", body.RequestID)
+	}
+
+	// Browsers still get the error page.
+	rr := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/auth?client_id=unknown", nil)
+	req.Header.Set("Accept", "text/html,*/*;q=0.8")
+	s.ServeHTTP(rr, req)
+	require.NotEqual(t, "application/json", rr.Header().Get("Content-Type"))
+}
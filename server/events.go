@@ -0,0 +1,59 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/dexidp/dex/pkg/events"
+	"github.com/dexidp/dex/storage"
+)
+
+// emitEvent sends an audit or identity event, if events are enabled, and
+// keeps it in the audit history, if enabled. subject is the user the event is
+// about, if known.
+func (s *Server) emitEvent(ctx context.Context, eventType, subject string, data map[string]any) {
+	if s.events == nil && s.auditLog == nil {
+		return
+	}
+	e := events.Event{
+		SpecVersion:     events.SpecVersion,
+		ID:              storage.NewID(),
+		Source:          s.issuerURL.String(),
+		Type:            eventType,
+		Subject:         subject,
+		Time:            s.now().UTC(),
+		DataContentType: "application/json",
+		Data:            data,
+	}
+	if s.events != nil {
+		if err := s.events.Send(ctx, e); err != nil {
+			s.logger.ErrorContext(ctx, "failed to send event", "type", eventType, "err", err)
+		}
+	}
+	if s.auditLog != nil {
+		if err := s.storeAuditEvent(ctx, e, data); err != nil {
+			s.logger.ErrorContext(ctx, "failed to store audit event", "type", eventType, "err", err)
+		}
+	}
+}
+
+// storeAuditEvent adds an event to the audit history.
+func (s *Server) storeAuditEvent(ctx context.Context, e events.Event, data map[string]any) error {
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return fmt.Errorf("marshal event data: %v", err)
+	}
+	clientID, _ := data["client_id"].(string)
+	connectorID, _ := data["connector_id"].(string)
+	return s.auditLog.Storage.CreateAuditEvent(ctx, storage.AuditEvent{
+		ID:          e.ID,
+		Type:        e.Type,
+		Time:        e.Time,
+		Subject:     e.Subject,
+		ClientID:    clientID,
+		ConnectorID: connectorID,
+		Data:        raw,
+		Expiry:      e.Time.Add(s.auditLog.Retention),
+	})
+}
@@ -0,0 +1,98 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/dexidp/dex/api/v2"
+	"github.com/dexidp/dex/pkg/events"
+	"github.com/dexidp/dex/storage"
+)
+
+type testEventSink []events.Event
+
+func (s *testEventSink) Send(_ context.Context, e events.Event) error {
+	*s = append(*s, e)
+	return nil
+}
+
+func TestEvents(t *testing.T) {
+	var sink testEventSink
+	httpServer, s := newTestServer(t, func(c *Config) {
+		c.Registration = &RegistrationConfig{}
+		c.Events = &sink
+	})
+	defer httpServer.Close()
+
+	resp, err := http.PostForm(httpServer.URL+"/register", url.Values{
+		"email":            {"jane@example.com"},
+		"password":         {"password"},
+		"confirm_password": {"password"},
+	})
+	require.NoError(t, err)
+	resp.Body.Close()
+
+	require.Len(t, sink, 1)
+	e := sink[0]
+	require.Equal(t, events.SpecVersion, e.SpecVersion)
+	require.Equal(t, events.TypeRegistration, e.Type)
+	require.Equal(t, s.issuerURL.String(), e.Source)
+	require.NotEmpty(t, e.ID)
+	require.Equal(t, "succeeded", e.Data.(map[string]any)["outcome"])
+	require.Equal(t, "jane@example.com", e.Data.(map[string]any)["email"])
+}
+
+func TestAuditLog(t *testing.T) {
+	httpServer, s := newTestServer(t, func(c *Config) {
+		c.Registration = &RegistrationConfig{}
+		c.AuditLog = &AuditLogConfig{Storage: c.Storage.(storage.AuditStorage), Retention: time.Hour}
+	})
+	defer httpServer.Close()
+
+	for _, email := range []string{"jane@example.com", "john@example.com"} {
+		resp, err := http.PostForm(httpServer.URL+"/register", url.Values{
+			"email":            {email},
+			"password":         {"password"},
+			"confirm_password": {"password"},
+		})
+		require.NoError(t, err)
+		resp.Body.Close()
+	}
+
+	srv := NewAPI(s.storage, s.logger, "test", s)
+	resp, err := srv.QueryAuditEvents(t.Context(), &api.QueryAuditEventsReq{Type: events.TypeRegistration})
+	require.NoError(t, err)
+	require.Len(t, resp.Events, 2)
+
+	// Newest first.
+	var data map[string]any
+	require.NoError(t, json.Unmarshal(resp.Events[0].Data, &data))
+	require.Equal(t, "john@example.com", data["email"])
+	require.Equal(t, s.now().Unix(), resp.Events[0].Time)
+
+	resp, err = srv.QueryAuditEvents(t.Context(), &api.QueryAuditEventsReq{Type: events.TypeRegistration, Limit: 1})
+	require.NoError(t, err)
+	require.Len(t, resp.Events, 1)
+
+	resp, err = srv.QueryAuditEvents(t.Context(), &api.QueryAuditEventsReq{Until: s.now().Add(-time.Minute).Unix()})
+	require.NoError(t, err)
+	require.Empty(t, resp.Events)
+
+	result, err := s.storage.GarbageCollect(t.Context(), s.now().Add(2*time.Hour))
+	require.NoError(t, err)
+	require.Equal(t, int64(2), result.AuditEvents)
+}
+
+func TestAuditLogDisabled(t *testing.T) {
+	httpServer, s := newTestServer(t, nil)
+	defer httpServer.Close()
+
+	_, err := NewAPI(s.storage, s.logger, "test", s).QueryAuditEvents(t.Context(), &api.QueryAuditEventsReq{})
+	require.Error(t, err)
+}
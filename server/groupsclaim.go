@@ -0,0 +1,72 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/dexidp/dex/storage"
+)
+
+// ValidateGroupsClaim checks the name and format of a groups claim.
+func ValidateGroupsClaim(c storage.GroupsClaim) error {
+	switch c.Format {
+	case "", storage.GroupsClaimArray, storage.GroupsClaimString:
+	default:
+		return fmt.Errorf("unknown format %q, must be %q or %q", c.Format, storage.GroupsClaimArray, storage.GroupsClaimString)
+	}
+	if registeredClaims[c.Name] {
+		return fmt.Errorf("claim %q is set by dex", c.Name)
+	}
+	return nil
+}
+
+// groupsClaimOf returns the groups claim of the tokens issued to a client:
+// the server's, with the fields the client sets replaced.
+func (s *Server) groupsClaimOf(client storage.Client) storage.GroupsClaim {
+	c := s.groupsClaim
+	if o := client.GroupsClaim; o != nil {
+		if o.Name != "" {
+			c.Name = o.Name
+		}
+		if o.Format != "" {
+			c.Format = o.Format
+		}
+	}
+	return c
+}
+
+// reshapeGroups moves the groups of a token payload, set by dex or a
+// connector, to the name and format of the groups claim.
+func reshapeGroups(payload []byte, c storage.GroupsClaim) ([]byte, error) {
+	name := c.Name
+	if name == "" {
+		name = "groups"
+	}
+	if name == "groups" && c.Format != storage.GroupsClaimString {
+		return payload, nil
+	}
+
+	var claims map[string]interface{}
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return nil, err
+	}
+	groups, ok := claims["groups"]
+	if !ok {
+		return payload, nil
+	}
+	delete(claims, "groups")
+
+	if c.Format == storage.GroupsClaimString {
+		list, _ := groups.([]interface{})
+		names := make([]string, 0, len(list))
+		for _, g := range list {
+			if name, ok := g.(string); ok {
+				names = append(names, name)
+			}
+		}
+		groups = strings.Join(names, " ")
+	}
+	claims[name] = groups
+	return json.Marshal(claims)
+}
@@ -0,0 +1,61 @@
+package server
+
+import (
+	"testing"
+	"time"
+
+	"github.com/coreos/go-oidc/v3/oidc"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dexidp/dex/storage"
+)
+
+func TestValidateGroupsClaim(t *testing.T) {
+	require.NoError(t, ValidateGroupsClaim(storage.GroupsClaim{}))
+	require.NoError(t, ValidateGroupsClaim(storage.GroupsClaim{Name: "cognito:groups", Format: storage.GroupsClaimString}))
+	require.EqualError(t, ValidateGroupsClaim(storage.GroupsClaim{Format: "csv"}), `unknown format "csv", must be "array" or "string"`)
+	require.EqualError(t, ValidateGroupsClaim(storage.GroupsClaim{Name: "sub"}), `claim "sub" is set by dex`)
+}
+
+func TestNewTokenGroupsClaim(t *testing.T) {
+	ctx := t.Context()
+	httpServer, s := newTestServer(t, func(c *Config) {
+		c.GroupsClaim = storage.GroupsClaim{Name: "roles"}
+	})
+	defer httpServer.Close()
+
+	for _, client := range []storage.Client{
+		{ID: "server"},
+		{ID: "string", GroupsClaim: &storage.GroupsClaim{Format: storage.GroupsClaimString}},
+		{ID: "cognito", GroupsClaim: &storage.GroupsClaim{Name: "cognito:groups"}},
+	} {
+		require.NoError(t, s.storage.CreateClient(ctx, client))
+	}
+
+	user := storage.Claims{UserID: "1", Groups: []string{"admins", "readers"}}
+	provider, err := oidc.NewProvider(ctx, httpServer.URL)
+	require.NoError(t, err)
+
+	tests := []struct {
+		clientID string
+		name     string
+		want     interface{}
+	}{
+		{clientID: "server", name: "roles", want: []interface{}{"admins", "readers"}},
+		{clientID: "string", name: "roles", want: "admins readers"},
+		{clientID: "cognito", name: "cognito:groups", want: []interface{}{"admins", "readers"}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.clientID, func(t *testing.T) {
+			idToken, _, err := s.newIDToken(ctx, tc.clientID, user, []string{"openid", "groups"}, "", "", "", "mock", time.Time{}, nil)
+			require.NoError(t, err)
+			token, err := provider.Verifier(&oidc.Config{ClientID: tc.clientID}).Verify(ctx, idToken)
+			require.NoError(t, err)
+
+			var claims map[string]interface{}
+			require.NoError(t, token.Claims(&claims))
+			require.Equal(t, tc.want, claims[tc.name])
+			require.NotContains(t, claims, "groups")
+		})
+	}
+}
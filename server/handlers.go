@@ -10,8 +10,10 @@ import (
 	"fmt"
 	"html/template"
 	"maps"
+	"math"
 	"net/http"
 	"net/url"
+	"slices"
 	"sort"
 	"strconv"
 	"strings"
@@ -22,6 +24,7 @@ import (
 	"github.com/gorilla/mux"
 
 	"github.com/dexidp/dex/connector"
+	"github.com/dexidp/dex/pkg/events"
 	"github.com/dexidp/dex/pkg/featureflags"
 	"github.com/dexidp/dex/server/internal"
 	"github.com/dexidp/dex/storage"
@@ -33,8 +36,12 @@ const (
 )
 
 func (s *Server) handlePublicKeys(w http.ResponseWriter, r *http.Request) {
+	if doc, ok := s.publicKeys.get(s.now()); ok {
+		doc.serve(w, r, maxAgeHeader(publicKeysMaxAge))
+		return
+	}
+
 	ctx := r.Context()
-	// TODO(ericchiang): Cache this.
 	keys, err := s.signer.ValidationKeys(ctx)
 	if err != nil {
 		s.logger.ErrorContext(r.Context(), "failed to get keys", "err", err)
@@ -62,14 +69,12 @@ func (s *Server) handlePublicKeys(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	doc := newCachedDocument(data)
+	s.publicKeys.set(doc, s.now().Add(publicKeysCacheTTL))
+
 	// We don't have NextRotation info from Signer interface easily,
 	// so we'll just set a reasonable default cache time.
-	maxAge := time.Minute * 10
-
-	w.Header().Set("Cache-Control", fmt.Sprintf("max-age=%d, must-revalidate", int(maxAge.Seconds())))
-	w.Header().Set("Content-Type", "application/json")
-	w.Header().Set("Content-Length", strconv.Itoa(len(data)))
-	w.Write(data)
+	doc.serve(w, r, maxAgeHeader(publicKeysMaxAge))
 }
 
 type discovery struct {
@@ -92,17 +97,14 @@ type discovery struct {
 }
 
 func (s *Server) discoveryHandler(ctx context.Context) (http.HandlerFunc, error) {
-	d := s.constructDiscovery(ctx)
-
-	data, err := json.MarshalIndent(d, "", "  ")
+	data, err := s.discoveryDocument(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("failed to marshal discovery data: %v", err)
 	}
 
+	doc := newCachedDocument(data)
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		w.Header().Set("Content-Type", "application/json")
-		w.Header().Set("Content-Length", strconv.Itoa(len(data)))
-		w.Write(data)
+		doc.serve(w, r, maxAgeHeader(discoveryMaxAge))
 	}), nil
 }
 
@@ -166,6 +168,9 @@ func (s *Server) grantTypeFromAuthRequest(r *http.Request) string {
 // handleAuthorization handles the OAuth2 auth endpoint.
 func (s *Server) handleAuthorization(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
+	if s.rejectIfDraining(w, r) || s.rejectIfMaintenance(w, r) {
+		return
+	}
 	// Extract the arguments
 	if err := r.ParseForm(); err != nil {
 		s.logger.ErrorContext(r.Context(), "failed to parse arguments", "err", err)
@@ -175,30 +180,21 @@ func (s *Server) handleAuthorization(w http.ResponseWriter, r *http.Request) {
 	}
 
 	connectorID := r.Form.Get("connector_id")
-	allConnectors, err := s.storage.ListConnectors(ctx)
-	if err != nil {
-		s.logger.ErrorContext(r.Context(), "failed to get list of connectors", "err", err)
-		s.renderError(r, w, http.StatusInternalServerError, "Failed to retrieve connector list.")
-		return
-	}
-
-	// Determine the grant type from the authorization request to filter connectors.
-	grantType := s.grantTypeFromAuthRequest(r)
-	connectors := make([]storage.Connector, 0, len(allConnectors))
-	for _, c := range allConnectors {
-		if GrantTypeAllowed(c.GrantTypes, grantType) {
-			connectors = append(connectors, c)
-		}
-	}
 
-	// Filter connectors based on the client's allowed connectors list.
 	// client_id is required per RFC 6749 §4.1.1.
 	client, authErr := s.getClientWithAuthError(ctx, r.Form.Get("client_id"))
 	if authErr != nil {
 		s.renderError(r, w, authErr.Status, authErr.Error())
 		return
 	}
-	connectors = filterConnectors(connectors, client.AllowedConnectors)
+	r = withClientTheme(r, client)
+
+	connectors, err := s.connectorsForClient(r, client)
+	if err != nil {
+		s.logger.ErrorContext(r.Context(), "failed to get list of connectors", "err", err)
+		s.renderError(r, w, http.StatusInternalServerError, "Failed to retrieve connector list.")
+		return
+	}
 
 	if len(connectors) == 0 {
 		s.renderError(r, w, http.StatusBadRequest, "No connectors available for this client.")
@@ -287,11 +283,30 @@ func (s *Server) handleAuthorization(w http.ResponseWriter, r *http.Request) {
 		})
 	}
 
-	if err := s.templates.login(r, w, connectorInfos); err != nil {
+	if err := s.templates().login(r, w, connectorInfos); err != nil {
 		s.logger.ErrorContext(r.Context(), "server template error", "err", err)
 	}
 }
 
+// connectorsForClient lists the connectors a login for client may use: those
+// which allow the grant type of the authorization request and are in the
+// client's allowed connectors list.
+func (s *Server) connectorsForClient(r *http.Request, client storage.Client) ([]storage.Connector, error) {
+	allConnectors, err := s.storage.ListConnectors(r.Context())
+	if err != nil {
+		return nil, err
+	}
+
+	grantType := s.grantTypeFromAuthRequest(r)
+	connectors := make([]storage.Connector, 0, len(allConnectors))
+	for _, c := range allConnectors {
+		if GrantTypeAllowed(c.GrantTypes, grantType) {
+			connectors = append(connectors, c)
+		}
+	}
+	return filterConnectors(connectors, client.AllowedConnectors), nil
+}
+
 // filterConnectors filters the list of connectors by the allowed connector IDs.
 // If allowedConnectors is empty, all connectors are returned (no filtering).
 func filterConnectors(connectors []storage.Connector, allowedConnectors []string) []storage.Connector {
@@ -327,6 +342,18 @@ func isConnectorAllowed(allowedConnectors []string, connectorID string) bool {
 	return false
 }
 
+// withClientThemeByID attaches the branding of the client with the given ID
+// to the request. Failing to look the client up only loses the branding, so
+// the error is logged and otherwise ignored.
+func (s *Server) withClientThemeByID(r *http.Request, clientID string) *http.Request {
+	client, err := s.storage.GetClient(r.Context(), clientID)
+	if err != nil {
+		s.logger.WarnContext(r.Context(), "failed to get client for theme", "client_id", clientID, "err", err)
+		return r
+	}
+	return withClientTheme(r, client)
+}
+
 // getClientWithAuthError retrieves a client by ID and returns a displayedAuthErr on failure.
 // Invalid client_id is not treated as a redirect error per RFC 6749 §4.1.2.1.
 // https://datatracker.ietf.org/doc/html/rfc6749#section-4.1.2.1
@@ -345,6 +372,9 @@ func (s *Server) getClientWithAuthError(ctx context.Context, clientID string) (s
 
 func (s *Server) handleConnectorLogin(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
+	if s.rejectIfDraining(w, r) || s.rejectIfMaintenance(w, r) {
+		return
+	}
 	authReq, hintSubject, err := s.parseAuthorizationRequest(r)
 	if err != nil {
 		s.logger.ErrorContext(r.Context(), "failed to parse authorization request", "err", err)
@@ -414,6 +444,8 @@ func (s *Server) handleConnectorLogin(w http.ResponseWriter, r *http.Request) {
 		s.renderError(r, w, http.StatusInternalServerError, "Failed to connect to the database.")
 		return
 	}
+	s.logins.start(authReq.ID, s.now(), authReq.Expiry)
+	r = withLogin(r, authReq.ID)
 
 	// Handle OIDC prompt parameter and session-based login.
 	prompt, err := ParsePrompt(authReq.Prompt)
@@ -444,16 +476,20 @@ func (s *Server) handleConnectorLogin(w http.ResponseWriter, r *http.Request) {
 		// prompt=none: no UI allowed.
 		if prompt.None() {
 			redirectURL, ok := s.trySessionLoginWithSession(ctx, r, w, authReq, session)
-			if !ok {
-				s.redirectWithError(w, r, authReq, errLoginRequired, "User not authenticated")
-				return
-			}
-			if redirectURL != "" {
+			_, forwardsPrompt := conn.Connector.(connector.AuthRequestParamsConnector)
+			switch {
+			case ok && redirectURL != "":
 				// Session found but user interaction is needed (consent or MFA) — no UI allowed.
 				s.redirectWithError(w, r, authReq, errInteractionRequired, "User interaction required")
 				return
+			case ok:
+				return
+			case !forwardsPrompt:
+				s.redirectWithError(w, r, authReq, errLoginRequired, "User not authenticated")
+				return
 			}
-			return
+			// The connector forwards prompt=none, the upstream may still log
+			// the user in silently.
 		}
 
 		if !prompt.Login() {
@@ -472,8 +508,11 @@ func (s *Server) handleConnectorLogin(w http.ResponseWriter, r *http.Request) {
 	// Work out where the "Select another login method" link should go.
 	// Include prompt=select_account so that handleAuthorization skips
 	// session-based connector reuse and shows the connector list.
+	// Clients pinned to a single connector never get a way back to the list.
 	backLink := ""
-	if len(s.connectors) > 1 {
+	if connectors, err := s.connectorsForClient(r, client); err != nil {
+		s.logger.ErrorContext(r.Context(), "failed to get list of connectors", "err", err)
+	} else if len(connectors) > 1 {
 		backLinkParams := make(url.Values)
 		maps.Copy(backLinkParams, r.Form)
 		if s.sessionConfig != nil {
@@ -493,7 +532,20 @@ func (s *Server) handleConnectorLogin(w http.ResponseWriter, r *http.Request) {
 			// Use the auth request ID as the "state" token.
 			//
 			// TODO(ericchiang): Is this appropriate or should we also be using a nonce?
-			callbackURL, connData, err := conn.LoginURL(scopes, s.absURL("/callback"), authReq.ID)
+			var (
+				callbackURL string
+				connData    []byte
+			)
+			if paramsConn, ok := conn.(connector.AuthRequestParamsConnector); ok {
+				params := connector.AuthRequestParams{Prompt: authReq.Prompt, LoginHint: authReq.LoginHint}
+				restart := url.URL{Path: s.absPath("/auth", url.PathEscape(connID)), RawQuery: r.Form.Encode()}
+				if params.RelayState, err = s.newRelayState(authReq.ID, restart.String()); err != nil {
+					s.logger.ErrorContext(r.Context(), "failed to create relay state", "connector_id", connID, "err", err)
+				}
+				callbackURL, connData, err = paramsConn.LoginURLWithParams(scopes, s.absURL("/callback"), authReq.ID, params)
+			} else {
+				callbackURL, connData, err = conn.LoginURL(scopes, s.absURL("/callback"), authReq.ID)
+			}
 			if err != nil {
 				s.logger.ErrorContext(r.Context(), "connector returned error when creating callback", "connector_id", connID, "err", err)
 				s.renderError(r, w, http.StatusInternalServerError, "Login error.")
@@ -576,6 +628,7 @@ func (s *Server) handlePasswordLogin(w http.ResponseWriter, r *http.Request) {
 		s.renderError(r, w, http.StatusInternalServerError, "Database error.")
 		return
 	}
+	r = withLogin(s.withClientThemeByID(r, authReq.ClientID), authReq.ID)
 
 	connID, err := url.PathUnescape(mux.Vars(r)["connector"])
 	if err != nil {
@@ -604,9 +657,36 @@ func (s *Server) handlePasswordLogin(w http.ResponseWriter, r *http.Request) {
 
 	rememberMe := s.rememberMeDefault()
 
+	var resetLink, registerLink string
+	if conn.Type == LocalConnector {
+		state := "?" + url.Values{"state": {authReq.ID}}.Encode()
+		if s.passwordReset != nil {
+			resetLink = s.absPath("/password/reset") + state
+		}
+		if s.registration != nil {
+			registerLink = s.absPath("/register") + state
+		}
+	}
+
+	// captchaWidget returns the CAPTCHA to show on the form, if logins of
+	// username from the client need one.
+	captchaWidget := func(username string, failed bool) *captchaWidget {
+		if s.captcha == nil {
+			return nil
+		}
+		// Require a CAPTCHA if the failed logins can't be read.
+		required, err := s.captcha.required(r.Context(), rateLimitIP(r), username)
+		if err != nil {
+			s.logger.ErrorContext(r.Context(), "failed to read failed logins", "err", err)
+		} else if !required {
+			return nil
+		}
+		return s.captcha.widget(failed)
+	}
+
 	switch r.Method {
 	case http.MethodGet:
-		if err := s.templates.password(r, w, r.URL.String(), "", usernamePrompt(pwConn), false, backLink, rememberMe); err != nil {
+		if err := s.templates().password(r, w, r.URL.String(), "", usernamePrompt(pwConn), false, backLink, resetLink, registerLink, rememberMe, captchaWidget("", false)); err != nil {
 			s.logger.ErrorContext(r.Context(), "server template error", "err", err)
 		}
 	case http.MethodPost:
@@ -614,6 +694,22 @@ func (s *Server) handlePasswordLogin(w http.ResponseWriter, r *http.Request) {
 		password := r.FormValue("password")
 		scopes := parseScopes(authReq.Scopes)
 
+		if ok, retryAfter := s.allowLogin(r); !ok {
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+			s.renderError(r, w, http.StatusTooManyRequests, "Too many failed login attempts. Please try again later.")
+			return
+		}
+
+		if widget := captchaWidget(username, true); widget != nil {
+			if err := s.captcha.verify(ctx, r, rateLimitIP(r)); err != nil {
+				s.logger.InfoContext(r.Context(), "CAPTCHA verification failed", "user", username, "err", err)
+				if err := s.templates().password(r, w, r.URL.String(), username, usernamePrompt(pwConn), false, backLink, resetLink, registerLink, rememberMe, widget); err != nil {
+					s.logger.ErrorContext(r.Context(), "server template error", "err", err)
+				}
+				return
+			}
+		}
+
 		identity, ok, err := pwConn.Login(r.Context(), scopes, username, password)
 		if err != nil {
 			s.logger.ErrorContext(r.Context(), "failed to login user", "err", err)
@@ -621,13 +717,18 @@ func (s *Server) handlePasswordLogin(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 		if !ok {
-			if err := s.templates.password(r, w, r.URL.String(), username, usernamePrompt(pwConn), true, backLink, rememberMe); err != nil {
+			s.loginFailed(r, username)
+			if err := s.templates().password(r, w, r.URL.String(), username, usernamePrompt(pwConn), true, backLink, resetLink, registerLink, rememberMe, captchaWidget(username, false)); err != nil {
 				s.logger.ErrorContext(r.Context(), "server template error", "err", err)
 			}
 			s.logger.ErrorContext(r.Context(), "failed login attempt: Invalid credentials.", "user", username)
 			return
 		}
-		redirectURL, canSkipApproval, err := s.finalizeLogin(r.Context(), identity, authReq, conn.Connector)
+		redirectURL, canSkipApproval, err := s.finalizeLogin(r, identity, authReq, conn.Connector)
+		if errors.Is(err, errLoginDenied) {
+			s.renderError(r, w, http.StatusForbidden, ErrMsgLoginDenied)
+			return
+		}
 		if err != nil {
 			s.logger.ErrorContext(r.Context(), "failed to finalize login", "err", err)
 			s.renderError(r, w, http.StatusInternalServerError, "Login error.")
@@ -661,15 +762,34 @@ func (s *Server) handlePasswordLogin(w http.ResponseWriter, r *http.Request) {
 
 func (s *Server) handleConnectorCallback(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
-	var authID string
+	var authID, restart string
 	switch r.Method {
 	case http.MethodGet: // OAuth2 callback
-		if authID = r.URL.Query().Get("state"); authID == "" {
+		authID = r.URL.Query().Get("state")
+		if relayState := r.URL.Query().Get("relay_state"); relayState != "" {
+			relayID, relayRestart, err := s.parseRelayState(relayState)
+			if err == nil && authID != "" && authID != relayID {
+				err = errors.New("relay state doesn't match the state")
+			}
+			if err != nil {
+				s.logger.ErrorContext(r.Context(), "invalid relay state", "err", err)
+				s.renderError(r, w, http.StatusBadRequest, "User session error.")
+				return
+			}
+			authID, restart = relayID, relayRestart
+		}
+		if authID == "" {
 			s.renderError(r, w, http.StatusBadRequest, "User session error.")
 			return
 		}
-	case http.MethodPost: // SAML POST binding
+	case http.MethodPost: // SAML POST binding or OAuth2 form post response
 		if authID = r.PostFormValue("RelayState"); authID == "" {
+			authID = r.PostFormValue("state")
+		}
+		if s.handleIdPInitiatedLogin(w, r, authID) {
+			return
+		}
+		if authID == "" {
 			s.renderError(r, w, http.StatusBadRequest, "User session error.")
 			return
 		}
@@ -680,6 +800,14 @@ func (s *Server) handleConnectorCallback(w http.ResponseWriter, r *http.Request)
 
 	authReq, err := s.storage.GetAuthRequest(ctx, authID)
 	if err != nil {
+		if err == storage.ErrNotFound && restart != "" {
+			// The login outlived its auth request, e.g. at a slow SAML IdP.
+			// Start over from the client's authorization request, so the user
+			// still lands on the page they started from.
+			s.logger.InfoContext(r.Context(), "auth request expired during login, restarting it")
+			http.Redirect(w, r, restart, http.StatusFound)
+			return
+		}
 		if err == storage.ErrNotFound {
 			s.logger.ErrorContext(r.Context(), "invalid 'state' parameter provided", "err", err)
 			s.renderError(r, w, http.StatusBadRequest, "Requested resource does not exist.")
@@ -689,6 +817,7 @@ func (s *Server) handleConnectorCallback(w http.ResponseWriter, r *http.Request)
 		s.renderError(r, w, http.StatusInternalServerError, "Database error.")
 		return
 	}
+	r = withLogin(r, authReq.ID)
 
 	connID, err := url.PathUnescape(mux.Vars(r)["connector"])
 	if err != nil {
@@ -711,7 +840,8 @@ func (s *Server) handleConnectorCallback(w http.ResponseWriter, r *http.Request)
 	var identity connector.Identity
 	switch conn := conn.Connector.(type) {
 	case connector.CallbackConnector:
-		if r.Method != http.MethodGet {
+		formPost, ok := conn.(connector.FormPostConnector)
+		if r.Method != http.MethodGet && !(ok && formPost.FormPost()) {
 			s.logger.ErrorContext(r.Context(), "SAML request mapped to OAuth2 connector")
 			s.renderError(r, w, http.StatusBadRequest, "Invalid request")
 			return
@@ -731,16 +861,42 @@ func (s *Server) handleConnectorCallback(w http.ResponseWriter, r *http.Request)
 
 	if err != nil {
 		s.logger.ErrorContext(r.Context(), "failed to authenticate", "err", err)
-		var groupsErr *connector.UserNotInRequiredGroupsError
-		if errors.As(err, &groupsErr) {
-			s.renderError(r, w, http.StatusForbidden, ErrMsgNotInRequiredGroups)
-		} else {
-			s.renderError(r, w, http.StatusInternalServerError, ErrMsgAuthenticationFailed)
+		if code, desc, ok := silentLoginError(authReq, err); ok {
+			s.redirectWithError(w, r, &authReq, code, desc)
+			return
 		}
+		s.renderConnectorError(w, r, err)
 		return
 	}
 
-	redirectURL, canSkipApproval, err := s.finalizeLogin(ctx, identity, authReq, conn.Connector)
+	s.completeConnectorLogin(w, r, identity, authReq, conn.Connector)
+}
+
+// renderConnectorError renders the error of a connector which failed to
+// authenticate the user.
+func (s *Server) renderConnectorError(w http.ResponseWriter, r *http.Request, err error) {
+	var (
+		groupsErr *connector.UserNotInRequiredGroupsError
+		loginErr  *connector.LoginError
+	)
+	if errors.As(err, &groupsErr) {
+		s.renderError(r, w, http.StatusForbidden, ErrMsgNotInRequiredGroups)
+	} else if errors.As(err, &loginErr) {
+		s.renderLoginError(r, w, http.StatusUnauthorized, loginErr.Message, loginErr.Code)
+	} else {
+		s.renderError(r, w, http.StatusInternalServerError, ErrMsgAuthenticationFailed)
+	}
+}
+
+// completeConnectorLogin finalizes the login of a user authenticated by a
+// connector callback and continues with approval or the code response.
+func (s *Server) completeConnectorLogin(w http.ResponseWriter, r *http.Request, identity connector.Identity, authReq storage.AuthRequest, conn connector.Connector) {
+	ctx := r.Context()
+	redirectURL, canSkipApproval, err := s.finalizeLogin(r, identity, authReq, conn)
+	if errors.Is(err, errLoginDenied) {
+		s.renderError(r, w, http.StatusForbidden, ErrMsgLoginDenied)
+		return
+	}
 	if err != nil {
 		s.logger.ErrorContext(r.Context(), "failed to finalize login", "err", err)
 		s.renderError(r, w, http.StatusInternalServerError, "Login error.")
@@ -767,12 +923,51 @@ func (s *Server) handleConnectorCallback(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
+	if prompt, _ := ParsePrompt(authReq.Prompt); prompt.None() {
+		// The upstream logged the user in silently, but consent or MFA needs UI.
+		s.redirectWithError(w, r, &authReq, errInteractionRequired, "User interaction required")
+		return
+	}
+
 	http.Redirect(w, r, redirectURL, http.StatusSeeOther)
 }
 
+// silentLoginError maps the error of a prompt=none login forwarded to the
+// upstream to the error returned to the client, if the upstream reported that
+// the login needs user interaction.
+func silentLoginError(authReq storage.AuthRequest, err error) (string, string, bool) {
+	if prompt, _ := ParsePrompt(authReq.Prompt); !prompt.None() {
+		return "", "", false
+	}
+	var oauth2Err *connector.OAuth2Error
+	if !errors.As(err, &oauth2Err) {
+		return "", "", false
+	}
+	switch oauth2Err.Code {
+	case errLoginRequired, errInteractionRequired, errConsentRequired, errAccountSelectionRequired:
+		return oauth2Err.Code, oauth2Err.Description, true
+	}
+	return "", "", false
+}
+
 // finalizeLogin associates the user's identity with the current AuthRequest, then returns
 // the approval page's path.
-func (s *Server) finalizeLogin(ctx context.Context, identity connector.Identity, authReq storage.AuthRequest, conn connector.Connector) (string, bool, error) {
+func (s *Server) finalizeLogin(r *http.Request, identity connector.Identity, authReq storage.AuthRequest, conn connector.Connector) (string, bool, error) {
+	ctx := r.Context()
+
+	// Some upstreams, like Apple, only return the user's name on their first
+	// login. Keep the one seen before.
+	if identity.Username == "" && featureflags.SessionsEnabled.Enabled() {
+		if ui, err := s.storage.GetUserIdentity(ctx, identity.UserID, authReq.ConnectorID); err == nil {
+			identity.Username = ui.Claims.Username
+		}
+	}
+
+	stepUp, err := s.assessLogin(r, riskFlowLogin, identity, authReq.ConnectorID, authReq.ClientID, authReq.Scopes)
+	if err != nil {
+		return "", false, err
+	}
+
 	claims := storage.Claims{
 		UserID:            identity.UserID,
 		Username:          identity.Username,
@@ -780,6 +975,7 @@ func (s *Server) finalizeLogin(ctx context.Context, identity connector.Identity,
 		Email:             identity.Email,
 		EmailVerified:     identity.EmailVerified,
 		Groups:            identity.Groups,
+		CustomClaims:      identity.CustomClaims,
 	}
 
 	updater := func(a storage.AuthRequest) (storage.AuthRequest, error) {
@@ -792,6 +988,8 @@ func (s *Server) finalizeLogin(ctx context.Context, identity connector.Identity,
 	if err := s.storage.UpdateAuthRequest(ctx, authReq.ID, updater); err != nil {
 		return "", false, fmt.Errorf("failed to update auth request: %v", err)
 	}
+	authReq.Claims = claims
+	authReq.ConnectorData = identity.ConnectorData
 
 	email := claims.Email
 	if !claims.EmailVerified {
@@ -802,6 +1000,18 @@ func (s *Server) finalizeLogin(ctx context.Context, identity connector.Identity,
 		"connector_id", authReq.ConnectorID, "user_id", claims.UserID,
 		"username", claims.Username, "preferred_username", claims.PreferredUsername,
 		"email", email, "groups", claims.Groups)
+	s.emitEvent(ctx, events.TypeLogin, claims.UserID, map[string]any{
+		"connector_id":       authReq.ConnectorID,
+		"client_id":          authReq.ClientID,
+		"user_id":            claims.UserID,
+		"username":           claims.Username,
+		"preferred_username": claims.PreferredUsername,
+		"email":              claims.Email,
+		"email_verified":     claims.EmailVerified,
+		"groups":             claims.Groups,
+		"ip":                 rateLimitIP(r),
+		"user_agent":         r.UserAgent(),
+	})
 
 	offlineAccessRequested := false
 	for _, scope := range authReq.Scopes {
@@ -891,6 +1101,15 @@ func (s *Server) finalizeLogin(ctx context.Context, identity connector.Identity,
 	if err != nil {
 		return "", false, fmt.Errorf("failed to get MFA chain for client: %v", err)
 	}
+	if len(mfaChain) == 0 && stepUp {
+		if mfaChain, err = s.stepUpMFAChain(ctx, authReq.ConnectorID); err != nil {
+			return "", false, fmt.Errorf("failed to get step-up MFA chain: %v", err)
+		}
+		if len(mfaChain) == 0 {
+			s.logger.ErrorContext(ctx, "risk engine requires step-up, but no MFA authenticator applies", "connector_id", authReq.ConnectorID)
+			return "", false, errLoginDenied
+		}
+	}
 	if len(mfaChain) > 0 {
 		return s.buildMFARedirectURL(authReq, mfaChain[0]), false, nil
 	}
@@ -903,14 +1122,21 @@ func (s *Server) finalizeLogin(ctx context.Context, identity connector.Identity,
 		return "", false, fmt.Errorf("failed to update auth request MFA status: %v", err)
 	}
 
-	// Skip approval if globally configured.
+	// Skip approval if globally configured, unless the client has terms the
+	// user still has to accept.
 	if s.skipApproval && !authReq.ForceApprovalPrompt {
-		return "", true, nil
+		var ui storage.UserIdentity
+		if userIdentity != nil {
+			ui = *userIdentity
+		}
+		if s.clientTermsAccepted(ctx, authReq.ClientID, ui) {
+			return "", true, nil
+		}
 	}
 
 	// Skip approval if user already consented to the requested scopes for this client.
 	if !authReq.ForceApprovalPrompt && userIdentity != nil {
-		if scopesCoveredByConsent(userIdentity.Consents[authReq.ClientID], authReq.Scopes) {
+		if s.consentCovers(ctx, *userIdentity, authReq) {
 			return "", true, nil
 		}
 	}
@@ -935,6 +1161,7 @@ func (s *Server) handleApproval(w http.ResponseWriter, r *http.Request) {
 		s.renderError(r, w, http.StatusInternalServerError, "Database error.")
 		return
 	}
+	r = withLogin(r, authReq.ID)
 	if !authReq.LoggedIn {
 		s.logger.ErrorContext(r.Context(), "auth request does not have an identity for approval")
 		s.renderError(r, w, http.StatusInternalServerError, "Login process not yet finalized.")
@@ -964,31 +1191,31 @@ func (s *Server) handleApproval(w http.ResponseWriter, r *http.Request) {
 
 	switch r.Method {
 	case http.MethodGet:
+		client, err := s.storage.GetClient(ctx, authReq.ClientID)
+		if err != nil {
+			s.logger.ErrorContext(r.Context(), "Failed to get client", "client_id", authReq.ClientID, "err", err)
+			s.renderError(r, w, http.StatusInternalServerError, "Failed to retrieve client.")
+			return
+		}
+
 		// Skip the approval page and issue the code directly if:
 		// 1. The client didn't force the approval prompt, AND
 		// 2. Either the server is configured to skip approval globally,
-		//    or the user has already consented to all requested scopes for this client.
+		//    or the user has already consented to all requested scopes for this client, AND
+		// 3. The user accepted the current terms of the client, if it has any.
 		// This handles the MFA redirect case: after MFA completion the user lands on
 		// /approval via GET, and we don't want to show the consent screen again.
 		if !authReq.ForceApprovalPrompt {
-			if s.skipApproval {
-				s.sendCodeResponse(w, r, authReq)
-				return
-			}
 			ui, err := s.storage.GetUserIdentity(ctx, authReq.Claims.UserID, authReq.ConnectorID)
-			if err == nil && scopesCoveredByConsent(ui.Consents[authReq.ClientID], authReq.Scopes) {
+			if (s.skipApproval && termsAccepted(client, ui)) || (err == nil && s.consentCovers(ctx, ui, authReq)) {
 				s.sendCodeResponse(w, r, authReq)
 				return
 			}
 		}
 
-		client, err := s.storage.GetClient(ctx, authReq.ClientID)
-		if err != nil {
-			s.logger.ErrorContext(r.Context(), "Failed to get client", "client_id", authReq.ClientID, "err", err)
-			s.renderError(r, w, http.StatusInternalServerError, "Failed to retrieve client.")
-			return
-		}
-		if err := s.templates.approval(r, w, authReq.ID, authReq.Claims.Username, client.Name, authReq.Scopes); err != nil {
+		r = withClientTheme(r, client)
+		claims := s.releasedClaims(ctx, authReq)
+		if err := s.templates().approval(r, w, authReq.ID, authReq.Claims.Username, client.Name, authReq.Scopes, claims, client.Terms); err != nil {
 			s.logger.ErrorContext(r.Context(), "server template error", "err", err)
 		}
 	case http.MethodPost:
@@ -996,17 +1223,40 @@ func (s *Server) handleApproval(w http.ResponseWriter, r *http.Request) {
 			s.renderError(r, w, http.StatusInternalServerError, "Approval rejected.")
 			return
 		}
-		// Persist user-approved scopes as consent for this client.
+		client, err := s.storage.GetClient(ctx, authReq.ClientID)
+		if err != nil && !errors.Is(err, storage.ErrNotFound) {
+			s.logger.ErrorContext(ctx, "Failed to get client", "client_id", authReq.ClientID, "err", err)
+			s.renderError(r, w, http.StatusInternalServerError, "Failed to retrieve client.")
+			return
+		}
+		// Persist user-approved scopes as consent for this client, along with
+		// the claims they were shown and the terms they accepted.
 		if featureflags.SessionsEnabled.Enabled() {
+			digest := claimsDigest(s.releasedClaims(ctx, authReq))
 			if err := s.storage.UpdateUserIdentity(ctx, authReq.Claims.UserID, authReq.ConnectorID, func(old storage.UserIdentity) (storage.UserIdentity, error) {
 				if old.Consents == nil {
 					old.Consents = make(map[string][]string)
 				}
 				old.Consents[authReq.ClientID] = authReq.Scopes
+				if old.ConsentedClaims == nil {
+					old.ConsentedClaims = make(map[string]string)
+				}
+				old.ConsentedClaims[authReq.ClientID] = digest
+				if client.Terms != nil {
+					if old.AcceptedTerms == nil {
+						old.AcceptedTerms = make(map[string]storage.TermsAcceptance)
+					}
+					old.AcceptedTerms[authReq.ClientID] = storage.TermsAcceptance{
+						Version:    client.Terms.Version,
+						AcceptedAt: s.now(),
+					}
+				}
 				return old, nil
 			}); err != nil {
 				s.logger.ErrorContext(ctx, "failed to update user identity consents", "err", err)
 			}
+		} else if client.Terms != nil {
+			s.recordTermsAcceptance(ctx, authReq, *client.Terms)
 		}
 		s.sendCodeResponse(w, r, authReq)
 	}
@@ -1021,6 +1271,7 @@ func (s *Server) sendCodeResponse(w http.ResponseWriter, r *http.Request, authRe
 		return
 	}
 
+	s.logins.done(authReq.ID)
 	if err := s.storage.DeleteAuthRequest(ctx, authReq.ID); err != nil {
 		if err != storage.ErrNotFound {
 			s.logger.ErrorContext(r.Context(), "Failed to delete authorization request", "err", err)
@@ -1068,6 +1319,7 @@ func (s *Server) sendCodeResponse(w http.ResponseWriter, r *http.Request, authRe
 				ConnectorData: authReq.ConnectorData,
 				PKCE:          authReq.PKCE,
 				AuthTime:      authReq.AuthTime,
+				Resources:     authReq.Resources,
 			}
 			if err := s.storage.CreateAuthCode(ctx, code); err != nil {
 				s.logger.ErrorContext(r.Context(), "Failed to create auth code", "err", err)
@@ -1078,7 +1330,7 @@ func (s *Server) sendCodeResponse(w http.ResponseWriter, r *http.Request, authRe
 			// Implicit and hybrid flows that try to use the OOB redirect URI are
 			// rejected earlier. If we got here we're using the code flow.
 			if authReq.RedirectURI == redirectURIOOB {
-				if err := s.templates.oob(r, w, code.ID); err != nil {
+				if err := s.templates().oob(r, w, code.ID); err != nil {
 					s.logger.ErrorContext(r.Context(), "server template error", "err", err)
 				}
 				return
@@ -1087,7 +1339,8 @@ func (s *Server) sendCodeResponse(w http.ResponseWriter, r *http.Request, authRe
 			implicitOrHybrid = true
 			var err error
 
-			accessToken, _, err = s.newAccessToken(r.Context(), authReq.ClientID, authReq.Claims, authReq.Scopes, authReq.Nonce, authReq.ConnectorID, authReq.AuthTime, authReq.ConnectorData)
+			opts := tokenOptions{resources: authReq.Resources}
+			accessToken, _, err = s.newToken(r.Context(), authReq.ClientID, authReq.Claims, authReq.Scopes, authReq.Nonce, storage.NewID(), "", authReq.ConnectorID, authReq.AuthTime, authReq.ConnectorData, opts)
 			if err != nil {
 				s.logger.ErrorContext(r.Context(), "failed to create new access token", "err", err)
 				s.tokenErrHelper(w, errServerError, "", http.StatusInternalServerError)
@@ -1216,6 +1469,11 @@ func (s *Server) withClientFromStorage(w http.ResponseWriter, r *http.Request, h
 		return
 	}
 
+	if deviceFlowOnly(client) && r.PostFormValue("grant_type") != grantTypeRefreshToken {
+		s.tokenErrHelper(w, errUnauthorizedClient, "Client is restricted to the device flow.", http.StatusBadRequest)
+		return
+	}
+
 	handler(w, r, client)
 }
 
@@ -1252,6 +1510,8 @@ func (s *Server) handleToken(w http.ResponseWriter, r *http.Request) {
 		s.withClientFromStorage(w, r, s.handleTokenExchange)
 	case grantTypeClientCredentials:
 		s.withClientFromStorage(w, r, s.handleClientCredentialsGrant)
+	case grantTypeJWTBearer:
+		s.withClientFromStorage(w, r, s.handleJWTBearerGrant)
 	default:
 		s.tokenErrHelper(w, errUnsupportedGrantType, "", http.StatusBadRequest)
 	}
@@ -1322,7 +1582,13 @@ func (s *Server) handleAuthCode(w http.ResponseWriter, r *http.Request, client s
 		return
 	}
 
-	tokenResponse, err := s.exchangeAuthCode(ctx, w, authCode, client)
+	resources, err := tokenResources(client, authCode.Resources, r.PostForm["resource"])
+	if err != nil {
+		s.tokenErrHelper(w, errInvalidTarget, fmt.Sprintf("Invalid resource indicator: %v.", err), http.StatusBadRequest)
+		return
+	}
+
+	tokenResponse, err := s.exchangeAuthCode(ctx, w, authCode, client, resources)
 	if err != nil {
 		s.tokenErrHelper(w, errServerError, "", http.StatusInternalServerError)
 		return
@@ -1330,8 +1596,11 @@ func (s *Server) handleAuthCode(w http.ResponseWriter, r *http.Request, client s
 	s.writeAccessToken(w, tokenResponse)
 }
 
-func (s *Server) exchangeAuthCode(ctx context.Context, w http.ResponseWriter, authCode storage.AuthCode, client storage.Client) (*accessTokenResponse, error) {
-	accessToken, _, err := s.newAccessToken(ctx, client.ID, authCode.Claims, authCode.Scopes, authCode.Nonce, authCode.ConnectorID, authCode.AuthTime, authCode.ConnectorData)
+// exchangeAuthCode issues the tokens of an auth code. The access token is
+// restricted to resources, if any.
+func (s *Server) exchangeAuthCode(ctx context.Context, w http.ResponseWriter, authCode storage.AuthCode, client storage.Client, resources []string) (*accessTokenResponse, error) {
+	opts := tokenOptions{resources: resources}
+	accessToken, _, err := s.newToken(ctx, client.ID, authCode.Claims, authCode.Scopes, authCode.Nonce, storage.NewID(), "", authCode.ConnectorID, authCode.AuthTime, authCode.ConnectorData, opts)
 	if err != nil {
 		s.logger.ErrorContext(ctx, "failed to create new access token", "err", err)
 		s.tokenErrHelper(w, errServerError, "", http.StatusInternalServerError)
@@ -1387,15 +1656,18 @@ func (s *Server) exchangeAuthCode(ctx context.Context, w http.ResponseWriter, au
 	}()
 	var refreshToken string
 	if reqRefresh {
+		rotationKey, tokenValue := newRefreshTokenValue()
 		refresh := storage.RefreshToken{
 			ID:            storage.NewID(),
-			Token:         storage.NewID(),
+			Token:         tokenValue,
+			RotationKey:   rotationKey,
 			ClientID:      authCode.ClientID,
 			ConnectorID:   authCode.ConnectorID,
 			Scopes:        authCode.Scopes,
 			Claims:        authCode.Claims,
 			Nonce:         authCode.Nonce,
 			ConnectorData: authCode.ConnectorData,
+			Resources:     authCode.Resources,
 			CreatedAt:     s.now(),
 			LastUsed:      s.now(),
 		}
@@ -1437,14 +1709,25 @@ func (s *Server) exchangeAuthCode(ctx context.Context, w http.ResponseWriter, au
 			LastUsed:  refresh.LastUsed,
 		}
 
-		// Try to retrieve an existing OfflineSession object for the corresponding user.
-		if session, err := s.storage.GetOfflineSessions(ctx, refresh.Claims.UserID, refresh.ConnectorID); err != nil {
-			if err != storage.ErrNotFound {
-				s.logger.ErrorContext(ctx, "failed to get offline session", "err", err)
-				s.tokenErrHelper(w, errServerError, "", http.StatusInternalServerError)
-				deleteToken = true
-				return nil, err
+		// Update the existing OfflineSession object for the user with the new
+		// RefreshTokenRef. Updating first saves a read for returning users; the
+		// session is only created if it does not exist yet.
+		var oldTokenRef *storage.RefreshTokenRef
+		err := s.storage.UpdateOfflineSessions(ctx, refresh.Claims.UserID, refresh.ConnectorID, func(old storage.OfflineSessions) (storage.OfflineSessions, error) {
+			// Some backends hand a zero value to the updater instead of failing
+			// when the session is missing.
+			if old.UserID == "" || old.Refresh == nil {
+				return old, storage.ErrNotFound
 			}
+			oldTokenRef = old.Refresh[tokenRef.ClientID]
+			old.Refresh[tokenRef.ClientID] = &tokenRef
+			if len(refresh.ConnectorData) > 0 {
+				old.ConnectorData = refresh.ConnectorData
+			}
+			return old, nil
+		})
+		switch {
+		case errors.Is(err, storage.ErrNotFound):
 			offlineSessions := storage.OfflineSessions{
 				UserID:        refresh.Claims.UserID,
 				ConnID:        refresh.ConnectorID,
@@ -1461,29 +1744,16 @@ func (s *Server) exchangeAuthCode(ctx context.Context, w http.ResponseWriter, au
 				deleteToken = true
 				return nil, err
 			}
-		} else {
-			if oldTokenRef, ok := session.Refresh[tokenRef.ClientID]; ok {
-				// Delete old refresh token from storage.
-				if err := s.storage.DeleteRefresh(ctx, oldTokenRef.ID); err != nil && err != storage.ErrNotFound {
-					s.logger.ErrorContext(ctx, "failed to delete refresh token", "err", err)
-					s.tokenErrHelper(w, errServerError, "", http.StatusInternalServerError)
-					deleteToken = true
-					return nil, err
-				}
-			}
-
-			// Update existing OfflineSession obj with new RefreshTokenRef.
-			if err := s.storage.UpdateOfflineSessions(ctx, session.UserID, session.ConnID, func(old storage.OfflineSessions) (storage.OfflineSessions, error) {
-				old.Refresh[tokenRef.ClientID] = &tokenRef
-				if len(refresh.ConnectorData) > 0 {
-					old.ConnectorData = refresh.ConnectorData
-				}
-				return old, nil
-			}); err != nil {
-				s.logger.ErrorContext(ctx, "failed to update offline session", "err", err)
-				s.tokenErrHelper(w, errServerError, "", http.StatusInternalServerError)
-				deleteToken = true
-				return nil, err
+		case err != nil:
+			s.logger.ErrorContext(ctx, "failed to update offline session", "err", err)
+			s.tokenErrHelper(w, errServerError, "", http.StatusInternalServerError)
+			deleteToken = true
+			return nil, err
+		case oldTokenRef != nil:
+			// The session no longer references the old refresh token, so a
+			// failure to delete it only leaves an unusable token behind.
+			if err := s.storage.DeleteRefresh(ctx, oldTokenRef.ID); err != nil && err != storage.ErrNotFound {
+				s.logger.ErrorContext(ctx, "failed to delete refresh token", "err", err)
 			}
 		}
 	}
@@ -1587,6 +1857,11 @@ func (s *Server) handlePasswordGrant(w http.ResponseWriter, r *http.Request, cli
 
 	// Which connector
 	connID := s.passwordConnector
+	if !isConnectorAllowed(client.AllowedConnectors, connID) {
+		s.logger.ErrorContext(r.Context(), "password connector not allowed for client", "connector_id", connID, "client_id", client.ID)
+		s.tokenErrHelper(w, errUnauthorizedClient, "Client is not allowed to use the password grant.", http.StatusBadRequest)
+		return
+	}
 	conn, err := s.getConnector(ctx, connID)
 	if err != nil {
 		s.tokenErrHelper(w, errInvalidRequest, "Requested connector does not exist.", http.StatusBadRequest)
@@ -1607,6 +1882,11 @@ func (s *Server) handlePasswordGrant(w http.ResponseWriter, r *http.Request, cli
 	// Login
 	username := q.Get("username")
 	password := q.Get("password")
+	if ok, retryAfter := s.allowLogin(r); !ok {
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+		s.tokenErrHelper(w, errTemporarilyUnavailable, "Too many failed login attempts.", http.StatusTooManyRequests)
+		return
+	}
 	identity, ok, err := passwordConnector.Login(ctx, parseScopes(scopes), username, password)
 	if err != nil {
 		s.logger.ErrorContext(r.Context(), "failed to login user", "err", err)
@@ -1614,9 +1894,13 @@ func (s *Server) handlePasswordGrant(w http.ResponseWriter, r *http.Request, cli
 		return
 	}
 	if !ok {
+		s.loginFailed(r, username)
 		s.tokenErrHelper(w, errAccessDenied, "Invalid username or password", http.StatusUnauthorized)
 		return
 	}
+	if !s.allowTokenRequest(w, r, riskFlowPassword, identity, connID, client.ID, scopes) {
+		return
+	}
 
 	// Build the claims to send the id token
 	claims := storage.Claims{
@@ -1626,6 +1910,7 @@ func (s *Server) handlePasswordGrant(w http.ResponseWriter, r *http.Request, cli
 		Email:             identity.Email,
 		EmailVerified:     identity.EmailVerified,
 		Groups:            identity.Groups,
+		CustomClaims:      identity.CustomClaims,
 	}
 
 	accessToken, _, err := s.newAccessToken(ctx, client.ID, claims, scopes, nonce, connID, time.Time{}, identity.ConnectorData)
@@ -1664,9 +1949,11 @@ func (s *Server) handlePasswordGrant(w http.ResponseWriter, r *http.Request, cli
 	}()
 	var refreshToken string
 	if reqRefresh {
+		rotationKey, tokenValue := newRefreshTokenValue()
 		refresh := storage.RefreshToken{
 			ID:          storage.NewID(),
-			Token:       storage.NewID(),
+			Token:       tokenValue,
+			RotationKey: rotationKey,
 			ClientID:    client.ID,
 			ConnectorID: connID,
 			Scopes:      scopes,
@@ -1788,10 +2075,17 @@ func (s *Server) handleTokenExchange(w http.ResponseWriter, r *http.Request, cli
 	}
 	subjectToken := q.Get("subject_token")          // REQUIRED
 	subjectTokenType := q.Get("subject_token_type") // REQUIRED
-	connID := q.Get("connector_id")                 // REQUIRED, not in RFC
+	connID := q.Get("connector_id")                 // REQUIRED for tokens of connectors, not in RFC
+	tenant := q.Get("tenant")                       // OPTIONAL, not in RFC
+	audiences := q["audience"]                      // OPTIONAL
+	if client.TokenExchange == nil {
+		// Only a policy can allow audiences. Without one the parameter is
+		// ignored, like it was before policies existed.
+		audiences = nil
+	}
 
 	switch subjectTokenType {
-	case tokenTypeID, tokenTypeAccess: // ok, continue
+	case tokenTypeID, tokenTypeAccess, tokenTypeRefresh, tokenTypeSubject: // ok, continue
 	default:
 		s.tokenErrHelper(w, errRequestNotSupported, "Invalid subject_token_type.", http.StatusBadRequest)
 		return
@@ -1802,12 +2096,56 @@ func (s *Server) handleTokenExchange(w http.ResponseWriter, r *http.Request, cli
 		return
 	}
 
+	if subjectTokenType == tokenTypeSubject {
+		s.handleImpersonation(w, r, client, subjectToken, impersonationRequest{
+			requestedTokenType: requestedTokenType,
+			scopes:             scopes,
+		})
+		return
+	}
+
+	// Refresh tokens, and access tokens without a connector, are issued by
+	// dex itself.
+	if subjectTokenType == tokenTypeRefresh || (subjectTokenType == tokenTypeAccess && connID == "") {
+		if connID != "" {
+			s.tokenErrHelper(w, errInvalidRequest, "connector_id must be omitted for tokens issued by dex.", http.StatusBadRequest)
+			return
+		}
+		s.handleDexTokenExchange(w, r, client, subjectToken, tokenExchangeRequest{
+			subjectTokenType:   subjectTokenType,
+			requestedTokenType: requestedTokenType,
+			scopes:             scopes,
+			audiences:          audiences,
+		})
+		return
+	}
+
+	if !isConnectorAllowed(client.AllowedConnectors, connID) {
+		s.logger.ErrorContext(r.Context(), "connector not allowed for client", "connector_id", connID, "client_id", client.ID)
+		s.tokenErrHelper(w, errInvalidRequest, "Connector not allowed for this client.", http.StatusBadRequest)
+		return
+	}
+
 	conn, err := s.getConnector(ctx, connID)
 	if err != nil {
 		s.logger.ErrorContext(r.Context(), "failed to get connector", "err", err)
 		s.tokenErrHelper(w, errInvalidRequest, "Requested connector does not exist.", http.StatusBadRequest)
 		return
 	}
+
+	exchange := tokenExchangeRequest{
+		connID:             connID,
+		connType:           conn.Type,
+		subjectTokenType:   subjectTokenType,
+		requestedTokenType: requestedTokenType,
+		scopes:             scopes,
+		audiences:          audiences,
+	}
+	if errType, description := s.checkTokenExchangePolicy(client, exchange); errType != "" {
+		s.auditTokenExchange(ctx, client, exchange, "", description)
+		s.tokenErrHelper(w, errType, description, http.StatusBadRequest)
+		return
+	}
 	if !GrantTypeAllowed(conn.GrantTypes, grantTypeTokenExchange) {
 		s.logger.ErrorContext(r.Context(), "connector does not allow token exchange", "connector_id", connID)
 		s.tokenErrHelper(w, errInvalidRequest, "Requested connector does not support token exchange.", http.StatusBadRequest)
@@ -1819,12 +2157,32 @@ func (s *Server) handleTokenExchange(w http.ResponseWriter, r *http.Request, cli
 		s.tokenErrHelper(w, errInvalidRequest, "Requested connector does not exist.", http.StatusBadRequest)
 		return
 	}
-	identity, err := teConn.TokenIdentity(ctx, subjectTokenType, subjectToken)
+	var identity connector.Identity
+	if paramsConn, ok := conn.Connector.(connector.TokenExchangeParamsConnector); ok {
+		identity, err = paramsConn.TokenIdentityWithParams(ctx, subjectTokenType, subjectToken, connector.TokenExchangeParams{Tenant: tenant})
+	} else if tenant != "" {
+		s.tokenErrHelper(w, errInvalidRequest, "Requested connector does not support tenant selection.", http.StatusBadRequest)
+		return
+	} else {
+		identity, err = teConn.TokenIdentity(ctx, subjectTokenType, subjectToken)
+	}
+	var oauth2Err *connector.OAuth2Error
+	if errors.As(err, &oauth2Err) && oauth2Err.Code == errInvalidTarget {
+		s.logger.ErrorContext(r.Context(), "connector refused the tenant", "tenant", tenant, "err", err)
+		s.auditTokenExchange(ctx, client, exchange, "", oauth2Err.Description)
+		s.tokenErrHelper(w, errInvalidTarget, oauth2Err.Description, http.StatusBadRequest)
+		return
+	}
 	if err != nil {
 		s.logger.ErrorContext(r.Context(), "failed to verify subject token", "err", err)
+		s.auditTokenExchange(ctx, client, exchange, "", "Invalid subject token.")
 		s.tokenErrHelper(w, errAccessDenied, "", http.StatusUnauthorized)
 		return
 	}
+	if !s.allowTokenRequest(w, r, riskFlowTokenExchange, identity, connID, client.ID, scopes) {
+		s.auditTokenExchange(ctx, client, exchange, identity.UserID, "Denied by risk assessment.")
+		return
+	}
 
 	claims := storage.Claims{
 		UserID:            identity.UserID,
@@ -1833,17 +2191,20 @@ func (s *Server) handleTokenExchange(w http.ResponseWriter, r *http.Request, cli
 		Email:             identity.Email,
 		EmailVerified:     identity.EmailVerified,
 		Groups:            identity.Groups,
+		CustomClaims:      identity.CustomClaims,
 	}
 	resp := accessTokenResponse{
 		IssuedTokenType: requestedTokenType,
 		TokenType:       "bearer",
 	}
 	var expiry time.Time
+	opts := tokenOptions{audience: exchange.audiences}
 	switch requestedTokenType {
 	case tokenTypeID:
-		resp.AccessToken, expiry, err = s.newIDToken(r.Context(), client.ID, claims, scopes, "", "", "", connID, time.Time{}, identity.ConnectorData)
+		opts.customClaims = client.CustomClaims
+		resp.AccessToken, expiry, err = s.newToken(r.Context(), client.ID, claims, scopes, "", "", "", connID, time.Time{}, identity.ConnectorData, opts)
 	case tokenTypeAccess:
-		resp.AccessToken, expiry, err = s.newAccessToken(r.Context(), client.ID, claims, scopes, "", connID, time.Time{}, identity.ConnectorData)
+		resp.AccessToken, expiry, err = s.newToken(r.Context(), client.ID, claims, scopes, "", storage.NewID(), "", connID, time.Time{}, identity.ConnectorData, opts)
 	default:
 		s.tokenErrHelper(w, errRequestNotSupported, "Invalid requested_token_type.", http.StatusBadRequest)
 		return
@@ -1853,6 +2214,7 @@ func (s *Server) handleTokenExchange(w http.ResponseWriter, r *http.Request, cli
 		s.tokenErrHelper(w, errServerError, "", http.StatusInternalServerError)
 		return
 	}
+	s.auditTokenExchange(ctx, client, exchange, claims.UserID, "")
 	resp.ExpiresIn = int(time.Until(expiry).Seconds())
 
 	// Token response must include cache headers https://tools.ietf.org/html/rfc6749#section-5.1
@@ -1862,40 +2224,24 @@ func (s *Server) handleTokenExchange(w http.ResponseWriter, r *http.Request, cli
 	json.NewEncoder(w).Encode(resp)
 }
 
-func (s *Server) handleClientCredentialsGrant(w http.ResponseWriter, r *http.Request, client storage.Client) {
+// validateNonInteractiveScopes validates the scopes of a grant without a user
+// session, which can't be refreshed. It writes an error response and returns
+// false if the scopes are invalid.
+func (s *Server) validateNonInteractiveScopes(w http.ResponseWriter, r *http.Request, clientID string, scopes []string, grantType string) (hasOpenIDScope, ok bool) {
 	ctx := r.Context()
-
-	// client_credentials requires a confidential client.
-	if client.Public {
-		s.tokenErrHelper(w, errUnauthorizedClient, "Public clients cannot use client_credentials grant.", http.StatusBadRequest)
-		return
-	}
-
-	// Parse scopes from request.
-	if err := r.ParseForm(); err != nil {
-		s.tokenErrHelper(w, errInvalidRequest, "Couldn't parse data", http.StatusBadRequest)
-		return
-	}
-	scopes := strings.Fields(r.Form.Get("scope"))
-
-	// Validate scopes.
 	var (
 		unrecognized  []string
 		invalidScopes []string
 	)
-	hasOpenIDScope := false
 	for _, scope := range scopes {
 		switch scope {
 		case scopeOpenID:
 			hasOpenIDScope = true
 		case scopeEmail, scopeProfile, scopeGroups:
 			// allowed
-		case scopeOfflineAccess:
-			s.tokenErrHelper(w, errInvalidScope, "client_credentials grant does not support offline_access scope.", http.StatusBadRequest)
-			return
-		case scopeFederatedID:
-			s.tokenErrHelper(w, errInvalidScope, "client_credentials grant does not support federated:id scope.", http.StatusBadRequest)
-			return
+		case scopeOfflineAccess, scopeFederatedID:
+			s.tokenErrHelper(w, errInvalidScope, fmt.Sprintf("%s grant does not support %s scope.", grantType, scope), http.StatusBadRequest)
+			return false, false
 		default:
 			peerID, ok := parseCrossClientScope(scope)
 			if !ok {
@@ -1903,11 +2249,11 @@ func (s *Server) handleClientCredentialsGrant(w http.ResponseWriter, r *http.Req
 				continue
 			}
 
-			isTrusted, err := s.validateCrossClientTrust(ctx, client.ID, peerID)
+			isTrusted, err := s.validateCrossClientTrust(ctx, clientID, peerID)
 			if err != nil {
-				s.logger.ErrorContext(ctx, "error validating cross client trust", "client_id", client.ID, "peer_id", peerID, "err", err)
+				s.logger.ErrorContext(ctx, "error validating cross client trust", "client_id", clientID, "peer_id", peerID, "err", err)
 				s.tokenErrHelper(w, errInvalidClient, "Error validating cross client trust.", http.StatusBadRequest)
-				return
+				return false, false
 			}
 			if !isTrusted {
 				invalidScopes = append(invalidScopes, scope)
@@ -1916,10 +2262,56 @@ func (s *Server) handleClientCredentialsGrant(w http.ResponseWriter, r *http.Req
 	}
 	if len(unrecognized) > 0 {
 		s.tokenErrHelper(w, errInvalidScope, fmt.Sprintf("Unrecognized scope(s) %q", unrecognized), http.StatusBadRequest)
-		return
+		return false, false
 	}
 	if len(invalidScopes) > 0 {
 		s.tokenErrHelper(w, errInvalidScope, fmt.Sprintf("Client can't request scope(s) %q", invalidScopes), http.StatusBadRequest)
+		return false, false
+	}
+	return hasOpenIDScope, true
+}
+
+func (s *Server) handleClientCredentialsGrant(w http.ResponseWriter, r *http.Request, client storage.Client) {
+	ctx := r.Context()
+
+	// client_credentials requires a confidential client.
+	if client.Public {
+		s.tokenErrHelper(w, errUnauthorizedClient, "Public clients cannot use client_credentials grant.", http.StatusBadRequest)
+		return
+	}
+
+	// Parse scopes from request.
+	if err := r.ParseForm(); err != nil {
+		s.tokenErrHelper(w, errInvalidRequest, "Couldn't parse data", http.StatusBadRequest)
+		return
+	}
+	scopes := strings.Fields(r.Form.Get("scope"))
+	hasOpenIDScope, ok := s.validateNonInteractiveScopes(w, r, client.ID, scopes, grantTypeClientCredentials)
+	if !ok {
+		return
+	}
+
+	// Audiences must be configured for the client. If none are requested,
+	// the token is issued for all of them.
+	var opts tokenOptions
+	if cc := client.ClientCredentials; cc != nil {
+		opts.claims = cc.Claims
+		opts.audience = cc.Audiences
+		var requested audience
+		for _, v := range r.Form["audience"] {
+			requested = append(requested, strings.Fields(v)...)
+		}
+		if len(requested) > 0 {
+			for _, aud := range requested {
+				if !slices.Contains(cc.Audiences, aud) {
+					s.tokenErrHelper(w, errInvalidTarget, fmt.Sprintf("Client can't request audience %q", aud), http.StatusBadRequest)
+					return
+				}
+			}
+			opts.audience = requested
+		}
+	} else if r.Form.Get("audience") != "" {
+		s.tokenErrHelper(w, errInvalidTarget, "Client has no audiences configured.", http.StatusBadRequest)
 		return
 	}
 
@@ -1943,7 +2335,7 @@ func (s *Server) handleClientCredentialsGrant(w http.ResponseWriter, r *http.Req
 	// Creating connectors with an empty ID with the config and API is prohibited
 	connID := ""
 
-	accessToken, expiry, err := s.newAccessToken(ctx, client.ID, claims, scopes, nonce, connID, time.Time{}, nil)
+	accessToken, expiry, err := s.newToken(ctx, client.ID, claims, scopes, nonce, storage.NewID(), "", connID, time.Time{}, nil, opts)
 	if err != nil {
 		s.logger.ErrorContext(ctx, "client_credentials grant failed to create new access token", "err", err)
 		s.tokenErrHelper(w, errServerError, "", http.StatusInternalServerError)
@@ -1952,7 +2344,8 @@ func (s *Server) handleClientCredentialsGrant(w http.ResponseWriter, r *http.Req
 
 	var idToken string
 	if hasOpenIDScope {
-		idToken, expiry, err = s.newIDToken(ctx, client.ID, claims, scopes, nonce, accessToken, "", connID, time.Time{}, nil)
+		opts.customClaims = client.CustomClaims
+		idToken, expiry, err = s.newToken(ctx, client.ID, claims, scopes, nonce, accessToken, "", connID, time.Time{}, nil, opts)
 		if err != nil {
 			s.logger.ErrorContext(ctx, "client_credentials grant failed to create new ID token", "err", err)
 			s.tokenErrHelper(w, errServerError, "", http.StatusInternalServerError)
@@ -2002,7 +2395,18 @@ func (s *Server) writeAccessToken(w http.ResponseWriter, resp *accessTokenRespon
 }
 
 func (s *Server) renderError(r *http.Request, w http.ResponseWriter, status int, description string) {
-	if err := s.templates.err(r, w, status, description); err != nil {
+	s.renderLoginError(r, w, status, description, "")
+}
+
+// renderLoginError renders the error page, or a JSON error for clients
+// preferring JSON.
+func (s *Server) renderLoginError(r *http.Request, w http.ResponseWriter, status int, description, loginErrCode string) {
+	s.failLogin(r)
+	if prefersJSON(r) {
+		s.renderJSONError(r, w, status, description, loginErrCode)
+		return
+	}
+	if err := s.templates().loginErr(r, w, status, description, loginErrCode); err != nil {
 		s.logger.ErrorContext(r.Context(), "server template error", "err", err)
 	}
 }
@@ -12,6 +12,8 @@ import (
 
 	"github.com/stretchr/testify/require"
 
+	"github.com/dexidp/dex/api/v2"
+	"github.com/dexidp/dex/connector"
 	"github.com/dexidp/dex/storage"
 )
 
@@ -111,3 +113,261 @@ func TestHandleApprovalDoubleSubmitPOST(t *testing.T) {
 	require.Contains(t, secondRR.Body.String(), "User session error.")
 	require.NotContains(t, secondRR.Body.String(), "Database error.")
 }
+
+func TestHandleApprovalShowsReleasedClaims(t *testing.T) {
+	ctx := t.Context()
+	httpServer, server := newTestServer(t, func(c *Config) {
+		c.SkipApprovalScreen = false
+		c.Storage.CreateClient(ctx, storage.Client{ID: "test", Name: "Test App"})
+	})
+	defer httpServer.Close()
+
+	authReq := storage.AuthRequest{
+		ID:            "approval-claims",
+		ClientID:      "test",
+		ConnectorID:   "mock",
+		ResponseTypes: []string{responseTypeCode},
+		RedirectURI:   "https://client.example/callback",
+		Scopes:        []string{scopeOpenID, scopeEmail, scopeGroups},
+		Expiry:        time.Now().Add(time.Minute),
+		LoggedIn:      true,
+		MFAValidated:  true,
+		HMACKey:       []byte("approval-claims-key"),
+		Claims: storage.Claims{
+			UserID:   "1",
+			Username: "jane",
+			Email:    "jane@example.com",
+			Groups:   []string{"radiology", "cardiology"},
+		},
+	}
+	require.NoError(t, server.storage.CreateAuthRequest(ctx, authReq))
+
+	mac := computeHMAC(authReq.HMACKey, authReq.ID, "")
+	rr := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/approval?req="+authReq.ID+"&hmac="+url.QueryEscape(mac), nil)
+	server.ServeHTTP(rr, req)
+
+	require.Equal(t, http.StatusOK, rr.Code)
+	body := rr.Body.String()
+	require.Contains(t, body, "jane@example.com")
+	require.Contains(t, body, "radiology")
+	require.Contains(t, body, "cardiology")
+	// The profile scope was not requested, so the name is not released.
+	require.NotContains(t, body, "<dt>Name</dt>")
+}
+
+// previewingConnector extends payloads with a tenant claim, which it lists
+// for the approval page.
+type previewingConnector struct {
+	connector.CallbackConnector
+	extended int
+}
+
+func (c *previewingConnector) ExtendPayload(scopes []string, payload []byte, connectorData []byte) ([]byte, error) {
+	c.extended++
+	return addClaims(payload, map[string]interface{}{"tenant": string(connectorData)})
+}
+
+func (c *previewingConnector) PreviewedClaims() []connector.PreviewedClaim {
+	return []connector.PreviewedClaim{{Path: "tenant", Label: "Tenant"}}
+}
+
+func TestReleasedClaimsFromConnector(t *testing.T) {
+	ctx := t.Context()
+	httpServer, server := newTestServer(t, nil)
+	defer httpServer.Close()
+
+	conn := &previewingConnector{CallbackConnector: &promptForwardingConnector{}}
+	registerTestConnector(t, server, "previewing", conn)
+
+	authReq := storage.AuthRequest{
+		ID:            "previewing",
+		ConnectorID:   "previewing",
+		Scopes:        []string{scopeOpenID, scopeEmail},
+		Expiry:        time.Now().Add(time.Minute),
+		Claims:        storage.Claims{UserID: "1", Email: "jane@example.com"},
+		ConnectorData: []byte("radiology"),
+	}
+	want := []releasedClaim{
+		{Name: "Email address", Values: []string{"jane@example.com"}},
+		{Name: "Tenant", Values: []string{"radiology"}},
+	}
+	require.Equal(t, want, server.releasedClaims(ctx, authReq))
+	require.Equal(t, want, server.releasedClaims(ctx, authReq))
+	require.Equal(t, 1, conn.extended, "payload should be extended once per auth request")
+
+	authReq.ConnectorData = []byte("cardiology")
+	require.Equal(t, "cardiology", server.releasedClaims(ctx, authReq)[1].Values[0])
+}
+
+func TestConsentCoversClaimChanges(t *testing.T) {
+	ctx := t.Context()
+	httpServer, server := newTestServer(t, func(c *Config) {
+		c.Storage.CreateClient(ctx, storage.Client{ID: "strict", RequireConsentOnClaimChange: true})
+		c.Storage.CreateClient(ctx, storage.Client{ID: "lenient"})
+	})
+	defer httpServer.Close()
+
+	authReq := storage.AuthRequest{
+		ConnectorID: "mock",
+		Scopes:      []string{scopeOpenID, scopeGroups},
+		Claims:      storage.Claims{UserID: "1", Groups: []string{"a"}},
+	}
+	digest := claimsDigest(server.releasedClaims(ctx, authReq))
+	ui := storage.UserIdentity{
+		Consents:        map[string][]string{"strict": authReq.Scopes, "lenient": authReq.Scopes},
+		ConsentedClaims: map[string]string{"strict": digest, "lenient": digest},
+	}
+
+	for _, clientID := range []string{"strict", "lenient"} {
+		authReq.ClientID = clientID
+		require.True(t, server.consentCovers(ctx, ui, authReq), clientID)
+	}
+
+	authReq.Claims.Groups = []string{"a", "b"}
+
+	authReq.ClientID = "strict"
+	require.False(t, server.consentCovers(ctx, ui, authReq), "changed claims should need new consent")
+
+	authReq.ClientID = "lenient"
+	require.True(t, server.consentCovers(ctx, ui, authReq), "client does not require consent on claim change")
+}
+
+func TestHandleApprovalTerms(t *testing.T) {
+	ctx := t.Context()
+	setSessionsEnabled(t, true)
+
+	acceptedAt := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	httpServer, server := newTestServer(t, func(c *Config) {
+		c.SkipApprovalScreen = true
+		c.Now = func() time.Time { return acceptedAt }
+		c.Storage.CreateClient(ctx, storage.Client{
+			ID:    "test",
+			Name:  "Test App",
+			Terms: &storage.ClientTerms{Version: "v1", URL: "https://client.example/terms"},
+		})
+		c.Storage.CreateUserIdentity(ctx, storage.UserIdentity{
+			UserID:      "1",
+			ConnectorID: "mock",
+			Claims:      storage.Claims{UserID: "1", Email: "jane@example.com"},
+		})
+	})
+	defer httpServer.Close()
+
+	newAuthRequest := func(id string) storage.AuthRequest {
+		t.Helper()
+		authReq := storage.AuthRequest{
+			ID:            id,
+			ClientID:      "test",
+			ConnectorID:   "mock",
+			ResponseTypes: []string{responseTypeCode},
+			RedirectURI:   "https://client.example/callback",
+			Scopes:        []string{scopeOpenID},
+			Expiry:        acceptedAt.Add(time.Minute),
+			LoggedIn:      true,
+			MFAValidated:  true,
+			HMACKey:       []byte(id + "-key"),
+			Claims:        storage.Claims{UserID: "1", Username: "jane"},
+		}
+		require.NoError(t, server.storage.CreateAuthRequest(ctx, authReq))
+		return authReq
+	}
+	approval := func(method string, authReq storage.AuthRequest) *httptest.ResponseRecorder {
+		t.Helper()
+		mac := computeHMAC(authReq.HMACKey, authReq.ID, "")
+		form := url.Values{"req": {authReq.ID}, "hmac": {mac}, "approval": {"approve"}}
+		rr := httptest.NewRecorder()
+		req := httptest.NewRequest(method, "/approval?"+form.Encode(), nil)
+		server.ServeHTTP(rr, req)
+		return rr
+	}
+
+	// The terms are shown despite the approval screen being skipped.
+	authReq := newAuthRequest("terms-1")
+	rr := approval(http.MethodGet, authReq)
+	require.Equal(t, http.StatusOK, rr.Code)
+	require.Contains(t, rr.Body.String(), "version v1")
+	require.Contains(t, rr.Body.String(), "https://client.example/terms")
+
+	rr = approval(http.MethodPost, authReq)
+	require.Equal(t, http.StatusSeeOther, rr.Code)
+	ui, err := server.storage.GetUserIdentity(ctx, "1", "mock")
+	require.NoError(t, err)
+	require.Equal(t, storage.TermsAcceptance{Version: "v1", AcceptedAt: acceptedAt}, ui.AcceptedTerms["test"])
+
+	// Accepted terms are not shown again.
+	rr = approval(http.MethodGet, newAuthRequest("terms-2"))
+	require.Equal(t, http.StatusSeeOther, rr.Code)
+
+	// A new version of the terms has to be accepted again.
+	require.NoError(t, server.storage.UpdateClient(ctx, "test", func(c storage.Client) (storage.Client, error) {
+		c.Terms = &storage.ClientTerms{Version: "v2"}
+		return c, nil
+	}))
+	rr = approval(http.MethodGet, newAuthRequest("terms-3"))
+	require.Equal(t, http.StatusOK, rr.Code)
+	require.Contains(t, rr.Body.String(), "version v2")
+
+	resp, err := NewAPI(server.storage, server.logger, "test", server).ListTermsAcceptances(ctx, &api.ListTermsAcceptancesReq{ClientId: "test"})
+	require.NoError(t, err)
+	require.Len(t, resp.Acceptances, 1)
+	require.Equal(t, &api.TermsAcceptance{
+		UserId:      "1",
+		ConnectorId: "mock",
+		ClientId:    "test",
+		Version:     "v1",
+		AcceptedAt:  acceptedAt.Unix(),
+		Email:       "jane@example.com",
+	}, resp.Acceptances[0])
+}
+
+func TestHandleApprovalTermsWithoutSessions(t *testing.T) {
+	ctx := t.Context()
+	setSessionsEnabled(t, false)
+
+	acceptedAt := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	httpServer, server := newTestServer(t, func(c *Config) {
+		c.SkipApprovalScreen = true
+		c.Now = func() time.Time { return acceptedAt }
+		c.Storage.CreateClient(ctx, storage.Client{
+			ID:    "test",
+			Name:  "Test App",
+			Terms: &storage.ClientTerms{Version: "v1"},
+		})
+	})
+	defer httpServer.Close()
+
+	approval := func(method, id string) *httptest.ResponseRecorder {
+		t.Helper()
+		authReq := storage.AuthRequest{
+			ID:            id,
+			ClientID:      "test",
+			ConnectorID:   "mock",
+			ResponseTypes: []string{responseTypeCode},
+			RedirectURI:   "https://client.example/callback",
+			Scopes:        []string{scopeOpenID},
+			Expiry:        acceptedAt.Add(time.Minute),
+			LoggedIn:      true,
+			MFAValidated:  true,
+			HMACKey:       []byte(id + "-key"),
+			Claims:        storage.Claims{UserID: "1", Username: "jane", Email: "jane@example.com"},
+		}
+		require.NoError(t, server.storage.CreateAuthRequest(ctx, authReq))
+		form := url.Values{"req": {id}, "hmac": {computeHMAC(authReq.HMACKey, id, "")}, "approval": {"approve"}}
+		rr := httptest.NewRecorder()
+		server.ServeHTTP(rr, httptest.NewRequest(method, "/approval?"+form.Encode(), nil))
+		return rr
+	}
+
+	rr := approval(http.MethodPost, "terms-1")
+	require.Equal(t, http.StatusSeeOther, rr.Code)
+
+	// Accepted terms are not shown again, without sessions too.
+	rr = approval(http.MethodGet, "terms-2")
+	require.Equal(t, http.StatusSeeOther, rr.Code)
+
+	resp, err := NewAPI(server.storage, server.logger, "test", server).ListTermsAcceptances(ctx, &api.ListTermsAcceptancesReq{ClientId: "test"})
+	require.NoError(t, err)
+	require.Len(t, resp.Acceptances, 1)
+	require.Equal(t, "v1", resp.Acceptances[0].Version)
+}
@@ -1286,6 +1286,107 @@ func TestHandleClientCredentials(t *testing.T) {
 	}
 }
 
+func TestHandleClientCredentialsAudiencesAndClaims(t *testing.T) {
+	tests := []struct {
+		name         string
+		audience     []string
+		wantCode     int
+		wantAudience []string
+	}{
+		{
+			name:         "All configured audiences",
+			wantCode:     200,
+			wantAudience: []string{"test", "https://api-a.example.com", "https://api-b.example.com"},
+		},
+		{
+			name:         "Requested audience",
+			audience:     []string{"https://api-b.example.com"},
+			wantCode:     200,
+			wantAudience: []string{"test", "https://api-b.example.com"},
+		},
+		{
+			name:         "Space separated audiences",
+			audience:     []string{"https://api-b.example.com https://api-a.example.com"},
+			wantCode:     200,
+			wantAudience: []string{"test", "https://api-b.example.com", "https://api-a.example.com"},
+		},
+		{
+			name:     "Audience not configured",
+			audience: []string{"https://api-a.example.com", "https://other.example.com"},
+			wantCode: 400,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := t.Context()
+
+			httpServer, s := newTestServer(t, func(c *Config) {
+				c.Now = time.Now
+			})
+			defer httpServer.Close()
+
+			err := s.storage.CreateClient(ctx, storage.Client{
+				ID:     "test",
+				Secret: "barfoo",
+				Name:   "Test Client",
+				ClientCredentials: &storage.ClientCredentialsConfig{
+					Audiences: []string{"https://api-a.example.com", "https://api-b.example.com"},
+					Claims: map[string]interface{}{
+						"tier": "gold",
+						// Registered claims can't be overridden.
+						"sub": "admin",
+					},
+				},
+			})
+			require.NoError(t, err)
+
+			v := url.Values{}
+			v.Add("grant_type", "client_credentials")
+			for _, aud := range tc.audience {
+				v.Add("audience", aud)
+			}
+
+			req, _ := http.NewRequest("POST", httpServer.URL+"/token", bytes.NewBufferString(v.Encode()))
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			req.SetBasicAuth("test", "barfoo")
+
+			rr := httptest.NewRecorder()
+			s.ServeHTTP(rr, req)
+
+			require.Equal(t, tc.wantCode, rr.Code, rr.Body.String())
+			if tc.wantCode != 200 {
+				require.Contains(t, rr.Body.String(), errInvalidTarget)
+				return
+			}
+
+			var resp struct {
+				AccessToken string `json:"access_token"`
+			}
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+
+			provider, err := oidc.NewProvider(ctx, httpServer.URL)
+			require.NoError(t, err)
+			verifier := provider.Verifier(&oidc.Config{ClientID: "https://api-b.example.com"})
+			token, err := verifier.Verify(ctx, resp.AccessToken)
+			require.NoError(t, err)
+			require.Equal(t, tc.wantAudience, token.Audience)
+
+			var claims struct {
+				AuthorizingParty string `json:"azp"`
+				Tier             string `json:"tier"`
+			}
+			require.NoError(t, token.Claims(&claims))
+			require.Equal(t, "test", claims.AuthorizingParty)
+			require.Equal(t, "gold", claims.Tier)
+
+			var sub internal.IDTokenSubject
+			require.NoError(t, internal.Unmarshal(token.Subject, &sub))
+			require.Equal(t, "test", sub.UserId)
+		})
+	}
+}
+
 func TestHandleConnectorCallbackWithSkipApproval(t *testing.T) {
 	ctx := t.Context()
 
@@ -1568,6 +1669,37 @@ func TestHandleTokenExchangeConnectorGrantTypeRestriction(t *testing.T) {
 	require.Equal(t, http.StatusBadRequest, rr.Code, rr.Body.String())
 }
 
+func TestHandleTokenExchangeTenantUnsupported(t *testing.T) {
+	ctx := t.Context()
+	httpServer, s := newTestServer(t, func(c *Config) {
+		c.Storage.CreateClient(ctx, storage.Client{
+			ID:     "client_1",
+			Secret: "secret_1",
+		})
+	})
+	defer httpServer.Close()
+
+	vals := make(url.Values)
+	vals.Set("grant_type", grantTypeTokenExchange)
+	vals.Set("connector_id", "mock")
+	vals.Set("scope", "openid")
+	vals.Set("subject_token_type", tokenTypeID)
+	vals.Set("subject_token", "foobar")
+	vals.Set("tenant", "tenant-1")
+	vals.Set("client_id", "client_1")
+	vals.Set("client_secret", "secret_1")
+
+	rr := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, httpServer.URL+"/token", strings.NewReader(vals.Encode()))
+	req.Header.Set("content-type", "application/x-www-form-urlencoded")
+
+	s.handleToken(rr, req)
+
+	// The mock connector can't bind tokens to a tenant.
+	require.Equal(t, http.StatusBadRequest, rr.Code, rr.Body.String())
+	require.Contains(t, rr.Body.String(), "does not support tenant selection")
+}
+
 func TestHandleAuthorizationConnectorGrantTypeFiltering(t *testing.T) {
 	tests := []struct {
 		name string
@@ -2149,6 +2281,51 @@ func TestHandleAuthorizationWithoutAllowedConnectors(t *testing.T) {
 	require.Equal(t, http.StatusOK, rr.Code)
 }
 
+func TestHandleAuthorizationClientTheme(t *testing.T) {
+	ctx := t.Context()
+
+	httpServer, s := newTestServerMultipleConnectors(t, nil)
+	defer httpServer.Close()
+
+	branded := storage.Client{
+		ID:           "branded-client",
+		Secret:       "secret",
+		RedirectURIs: []string{"https://example.com/callback"},
+		Name:         "Branded Client",
+		Theme: &storage.ClientTheme{
+			LogoURL:      "https://example.com/logo.svg",
+			PrimaryColor: "#0b5ed7",
+			FooterLinks:  []storage.ThemeLink{{Text: "Privacy policy", URL: "https://example.com/privacy"}},
+		},
+	}
+	plain := storage.Client{
+		ID:           "plain-client",
+		Secret:       "secret",
+		RedirectURIs: []string{"https://example.com/callback"},
+		Name:         "Plain Client",
+	}
+	require.NoError(t, s.storage.CreateClient(ctx, branded))
+	require.NoError(t, s.storage.CreateClient(ctx, plain))
+
+	loginPage := func(clientID string) string {
+		rr := httptest.NewRecorder()
+		req := httptest.NewRequest("GET", fmt.Sprintf("/auth?client_id=%s&redirect_uri=%s&response_type=code&scope=openid",
+			clientID, url.QueryEscape("https://example.com/callback")), nil)
+		s.ServeHTTP(rr, req)
+		require.Equal(t, http.StatusOK, rr.Code)
+		return rr.Body.String()
+	}
+
+	body := loginPage(branded.ID)
+	require.Contains(t, body, `src="https://example.com/logo.svg"`)
+	require.Contains(t, body, "background-color: #0b5ed7")
+	require.Contains(t, body, `href="https://example.com/privacy"`)
+
+	body = loginPage(plain.ID)
+	require.NotContains(t, body, "example.com/logo.svg")
+	require.NotContains(t, body, "theme-footer")
+}
+
 func TestBackLinkIncludesPromptSelectAccount(t *testing.T) {
 	ctx := t.Context()
 
@@ -2197,3 +2374,200 @@ func TestBackLinkIncludesPromptSelectAccount(t *testing.T) {
 	require.Equal(t, "select_account", backURL.Query().Get("prompt"),
 		"back link should include prompt=select_account")
 }
+
+func TestBackLinkOmittedForPinnedClient(t *testing.T) {
+	ctx := t.Context()
+
+	httpServer, s := newTestServerMultipleConnectors(t, nil)
+	defer httpServer.Close()
+
+	pwConn := storage.Connector{
+		ID:              "mockPw",
+		Type:            "mockPassword",
+		Name:            "MockPassword",
+		ResourceVersion: "1",
+		Config:          []byte(`{"username": "foo", "password": "bar"}`),
+	}
+	require.NoError(t, s.storage.CreateConnector(ctx, pwConn))
+	_, err := s.OpenConnector(pwConn)
+	require.NoError(t, err)
+
+	client := storage.Client{
+		ID:                "pinned-client",
+		Secret:            "secret",
+		RedirectURIs:      []string{"https://example.com/callback"},
+		Name:              "Pinned Client",
+		AllowedConnectors: []string{"mockPw"},
+	}
+	require.NoError(t, s.storage.CreateClient(ctx, client))
+
+	rr := httptest.NewRecorder()
+	authURL := fmt.Sprintf("/auth/mockPw?client_id=%s&redirect_uri=%s&response_type=code&scope=openid",
+		client.ID, url.QueryEscape("https://example.com/callback"))
+	req := httptest.NewRequest("GET", authURL, nil)
+	s.ServeHTTP(rr, req)
+
+	require.Equal(t, http.StatusFound, rr.Code)
+
+	loc, err := url.Parse(rr.Header().Get("Location"))
+	require.NoError(t, err)
+	require.Empty(t, loc.Query().Get("back"), "back link should not be set when the client may only use one connector")
+}
+
+func TestHandleTokenExchangeConnectorNotAllowed(t *testing.T) {
+	ctx := t.Context()
+	httpServer, s := newTestServer(t, func(c *Config) {
+		c.Storage.CreateClient(ctx, storage.Client{
+			ID:                "client_1",
+			Secret:            "secret_1",
+			AllowedConnectors: []string{"other"},
+		})
+	})
+	defer httpServer.Close()
+
+	vals := make(url.Values)
+	vals.Set("grant_type", grantTypeTokenExchange)
+	vals.Set("connector_id", "mock")
+	vals.Set("scope", "openid")
+	vals.Set("requested_token_type", tokenTypeAccess)
+	vals.Set("subject_token_type", tokenTypeID)
+	vals.Set("subject_token", "foobar")
+	vals.Set("client_id", "client_1")
+	vals.Set("client_secret", "secret_1")
+
+	rr := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, httpServer.URL+"/token", strings.NewReader(vals.Encode()))
+	req.Header.Set("content-type", "application/x-www-form-urlencoded")
+
+	s.handleToken(rr, req)
+
+	require.Equal(t, http.StatusBadRequest, rr.Code, rr.Body.String())
+	require.Contains(t, rr.Body.String(), "Connector not allowed")
+}
+
+func TestHandleRefreshTokenConnectorNotAllowed(t *testing.T) {
+	ctx := t.Context()
+	httpServer, s := newTestServer(t, nil)
+	defer httpServer.Close()
+
+	mockRefreshTokenTestStorage(t, s.storage, false)
+	require.NoError(t, s.storage.UpdateClient(ctx, "test", func(old storage.Client) (storage.Client, error) {
+		old.AllowedConnectors = []string{"other"}
+		return old, nil
+	}))
+
+	tokenData, err := internal.Marshal(&internal.RefreshToken{RefreshId: "test", Token: "bar"})
+	require.NoError(t, err)
+
+	vals := make(url.Values)
+	vals.Set("grant_type", grantTypeRefreshToken)
+	vals.Set("refresh_token", tokenData)
+
+	rr := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, httpServer.URL+"/token", strings.NewReader(vals.Encode()))
+	req.Header.Set("content-type", "application/x-www-form-urlencoded")
+	req.SetBasicAuth("test", "barfoo")
+
+	s.handleToken(rr, req)
+
+	require.Equal(t, http.StatusBadRequest, rr.Code, rr.Body.String())
+	require.Contains(t, rr.Body.String(), errUnauthorizedClient)
+
+	refresh, err := s.storage.GetRefresh(ctx, "test")
+	require.NoError(t, err)
+	require.Equal(t, "bar", refresh.Token, "refresh token should not be rotated")
+}
+
+// promptForwardingConnector is a callback connector which forwards the
+// prompt and login_hint parameters upstream.
+type promptForwardingConnector struct {
+	params      connector.AuthRequestParams
+	callbackErr error
+}
+
+func (c *promptForwardingConnector) LoginURL(s connector.Scopes, callbackURL, state string) (string, []byte, error) {
+	return c.LoginURLWithParams(s, callbackURL, state, connector.AuthRequestParams{})
+}
+
+func (c *promptForwardingConnector) LoginURLWithParams(_ connector.Scopes, callbackURL, state string, params connector.AuthRequestParams) (string, []byte, error) {
+	c.params = params
+	return callbackURL + "?state=" + url.QueryEscape(state), nil, nil
+}
+
+func (c *promptForwardingConnector) HandleCallback(connector.Scopes, []byte, *http.Request) (connector.Identity, error) {
+	if c.callbackErr != nil {
+		return connector.Identity{}, c.callbackErr
+	}
+	return connector.Identity{UserID: "silent-user", Email: "silent@example.com", EmailVerified: true}, nil
+}
+
+func TestSilentLoginPromptForwarding(t *testing.T) {
+	tests := []struct {
+		name         string
+		sessions     bool
+		skipApproval bool
+		callbackErr  error
+		wantError    string
+	}{
+		{name: "Silent login", skipApproval: true},
+		{name: "Silent login without dex session", sessions: true, skipApproval: true},
+		{name: "Upstream requires login", skipApproval: true, callbackErr: &connector.OAuth2Error{Code: "login_required"}, wantError: errLoginRequired},
+		{name: "Consent requires interaction", wantError: errInteractionRequired},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := t.Context()
+			httpServer, s := newTestServer(t, func(c *Config) {
+				c.SkipApprovalScreen = tc.skipApproval
+				if tc.sessions {
+					c.SessionConfig = &SessionConfig{
+						CookieName:        "dex_session",
+						AbsoluteLifetime:  24 * time.Hour,
+						ValidIfNotUsedFor: time.Hour,
+					}
+				}
+			})
+			defer httpServer.Close()
+
+			require.NoError(t, s.storage.CreateClient(ctx, storage.Client{
+				ID:           "silent-client",
+				Secret:       "secret",
+				RedirectURIs: []string{"https://client.example.com/callback"},
+			}))
+			conn := &promptForwardingConnector{callbackErr: tc.callbackErr}
+			registerTestConnector(t, s, "forwarding", conn)
+
+			v := url.Values{
+				"response_type": {"code"},
+				"client_id":     {"silent-client"},
+				"redirect_uri":  {"https://client.example.com/callback"},
+				"scope":         {"openid email"},
+				"prompt":        {"none"},
+				"login_hint":    {"silent@example.com"},
+				"state":         {"xyz"},
+			}
+			rr := httptest.NewRecorder()
+			s.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/auth/forwarding?"+v.Encode(), nil))
+			require.Equal(t, http.StatusFound, rr.Code, rr.Body.String())
+			require.Equal(t, "none", conn.params.Prompt)
+			require.Equal(t, "silent@example.com", conn.params.LoginHint)
+
+			upstream, err := url.Parse(rr.Header().Get("Location"))
+			require.NoError(t, err)
+			rr = httptest.NewRecorder()
+			s.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/callback?"+upstream.RawQuery, nil))
+			require.Contains(t, []int{http.StatusFound, http.StatusSeeOther}, rr.Code, rr.Body.String())
+
+			back, err := url.Parse(rr.Header().Get("Location"))
+			require.NoError(t, err)
+			require.Equal(t, "client.example.com", back.Host)
+			require.Equal(t, "xyz", back.Query().Get("state"))
+			if tc.wantError != "" {
+				require.Equal(t, tc.wantError, back.Query().Get("error"))
+				return
+			}
+			require.NotEmpty(t, back.Query().Get("code"))
+		})
+	}
+}
@@ -0,0 +1,70 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dexidp/dex/connector"
+)
+
+// connectorHealthCheckTimeout bounds a single connector probe so that one
+// unresponsive upstream cannot stall the whole readiness check.
+const connectorHealthCheckTimeout = 5 * time.Second
+
+// NewConnectorsHealthCheckFunc returns a health check function which probes the
+// upstream of every opened connector implementing connector.HealthChecker.
+//
+// The returned details map connector IDs to "ok" or the probe error, so the
+// readiness endpoint can report which dependency is failing.
+func (s *Server) NewConnectorsHealthCheckFunc() func(context.Context) (details interface{}, err error) {
+	return func(ctx context.Context) (details interface{}, err error) {
+		s.mu.Lock()
+		checkers := make(map[string]connector.HealthChecker)
+		for id, conn := range s.connectors {
+			if hc, ok := conn.Connector.(connector.HealthChecker); ok {
+				checkers[id] = hc
+			}
+		}
+		s.mu.Unlock()
+
+		var (
+			mu      sync.Mutex
+			wg      sync.WaitGroup
+			results = make(map[string]string, len(checkers))
+			failed  []string
+		)
+		for id, hc := range checkers {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+
+				checkCtx, cancel := context.WithTimeout(ctx, connectorHealthCheckTimeout)
+				defer cancel()
+
+				status := "ok"
+				checkErr := hc.HealthCheck(checkCtx)
+				if checkErr != nil {
+					status = checkErr.Error()
+				}
+
+				mu.Lock()
+				defer mu.Unlock()
+				results[id] = status
+				if checkErr != nil {
+					failed = append(failed, id)
+				}
+			}()
+		}
+		wg.Wait()
+
+		if len(failed) > 0 {
+			sort.Strings(failed)
+			return results, fmt.Errorf("connectors unreachable: %s", strings.Join(failed, ", "))
+		}
+		return results, nil
+	}
+}
@@ -0,0 +1,37 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+type healthCheckConnector struct {
+	err error
+}
+
+func (c healthCheckConnector) HealthCheck(context.Context) error { return c.err }
+
+func TestConnectorsHealthCheck(t *testing.T) {
+	_, s := newTestServer(t, nil)
+
+	s.mu.Lock()
+	s.connectors["healthy"] = Connector{Connector: healthCheckConnector{}}
+	s.connectors["unhealthy"] = Connector{Connector: healthCheckConnector{err: errors.New("connection refused")}}
+	s.mu.Unlock()
+
+	check := s.NewConnectorsHealthCheckFunc()
+	details, err := check(t.Context())
+	require.EqualError(t, err, "connectors unreachable: unhealthy")
+	require.Equal(t, map[string]string{
+		"healthy":   "ok",
+		"unhealthy": "connection refused",
+	}, details)
+
+	s.CloseConnector("unhealthy")
+	details, err = check(t.Context())
+	require.NoError(t, err)
+	require.Equal(t, map[string]string{"healthy": "ok"}, details)
+}
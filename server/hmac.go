@@ -4,6 +4,10 @@ import (
 	"crypto/hmac"
 	"crypto/sha256"
 	"encoding/base64"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
 
 	"google.golang.org/protobuf/proto"
 
@@ -38,3 +42,48 @@ func marshalHMACPayload(values []string) []byte {
 	data, _ := proto.Marshal(payload)
 	return data
 }
+
+var errInvalidEmailToken = errors.New("invalid email token")
+
+// emailToken is a signed, expiring token sent to a user's email address, e.g.
+// in a password reset link. It has the form "email.expiry.mac", the email
+// base64 raw-URL-encoded and the expiry in Unix seconds.
+type emailToken struct {
+	email  string
+	expiry string
+	mac    string
+}
+
+// newEmailToken returns a token for email. The MAC covers the purpose and
+// values, which aren't part of the token and must be passed to verify again.
+func newEmailToken(key []byte, purpose, email string, expiry time.Time, values ...string) string {
+	t := emailToken{email: email, expiry: strconv.FormatInt(expiry.Unix(), 10)}
+	t.mac = computeHMAC(key, t.payload(purpose, values)...)
+	return base64.RawURLEncoding.EncodeToString([]byte(email)) + "." + t.expiry + "." + t.mac
+}
+
+// parseEmailToken decodes a token and checks that it hasn't expired. Its MAC
+// must still be checked with verify.
+func parseEmailToken(token string, now time.Time) (emailToken, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return emailToken{}, errInvalidEmailToken
+	}
+	email, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return emailToken{}, errInvalidEmailToken
+	}
+	expiry, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil || now.After(time.Unix(expiry, 0)) {
+		return emailToken{}, errInvalidEmailToken
+	}
+	return emailToken{email: string(email), expiry: parts[1], mac: parts[2]}, nil
+}
+
+func (t emailToken) verify(key []byte, purpose string, values ...string) bool {
+	return verifyHMAC(key, t.mac, t.payload(purpose, values)...)
+}
+
+func (t emailToken) payload(purpose string, values []string) []string {
+	return append([]string{purpose, t.email, t.expiry}, values...)
+}
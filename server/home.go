@@ -11,7 +11,7 @@ import (
 )
 
 func (s *Server) handleHome(w http.ResponseWriter, r *http.Request) {
-	if s.sessionConfig == nil || s.templates.homeTmpl == nil {
+	if s.sessionConfig == nil || s.templates().homeTmpl == nil {
 		s.handleHomeInline(w, r)
 		return
 	}
@@ -36,7 +36,7 @@ func (s *Server) handleHome(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	if err := s.templates.home(r, w, data); err != nil {
+	if err := s.templates().home(r, w, data); err != nil {
 		s.logger.ErrorContext(ctx, "failed to render home template", "err", err)
 		s.renderError(r, w, http.StatusInternalServerError, "Internal server error.")
 	}
@@ -0,0 +1,98 @@
+package server
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// discoveryMaxAge is how long clients may cache the discovery document. It
+	// only changes when dex is restarted with a different configuration.
+	discoveryMaxAge = time.Hour
+
+	// publicKeysMaxAge is how long clients may cache the JWKS before revalidating.
+	publicKeysMaxAge = 10 * time.Minute
+
+	// publicKeysCacheTTL bounds how long the marshaled JWKS is served from memory
+	// before the signer is asked again. Kept short so rotated keys are published
+	// promptly.
+	publicKeysCacheTTL = 30 * time.Second
+)
+
+// cachedDocument is a marshaled JSON response and its entity tag.
+type cachedDocument struct {
+	data []byte
+	etag string
+}
+
+func newCachedDocument(data []byte) cachedDocument {
+	sum := sha256.Sum256(data)
+	return cachedDocument{
+		data: data,
+		etag: `"` + hex.EncodeToString(sum[:16]) + `"`,
+	}
+}
+
+// serve writes the document with caching headers, answering with 304 Not
+// Modified if the client already holds the current version.
+func (d cachedDocument) serve(w http.ResponseWriter, r *http.Request, cacheControl string) {
+	w.Header().Set("Cache-Control", cacheControl)
+	w.Header().Set("ETag", d.etag)
+
+	if etagMatches(r.Header.Get("If-None-Match"), d.etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Content-Length", strconv.Itoa(len(d.data)))
+	w.Write(d.data)
+}
+
+// etagMatches implements the weak comparison of RFC 9110 section 13.1.2.
+func etagMatches(ifNoneMatch, etag string) bool {
+	if ifNoneMatch == "" {
+		return false
+	}
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
+
+func maxAgeHeader(maxAge time.Duration) string {
+	return fmt.Sprintf("max-age=%d, must-revalidate", int(maxAge.Seconds()))
+}
+
+// publicKeysCache holds the marshaled JWKS so frequent polling by resource
+// servers does not reach the signer's storage on every request.
+type publicKeysCache struct {
+	mu      sync.Mutex
+	doc     cachedDocument
+	expires time.Time
+}
+
+func (c *publicKeysCache) get(now time.Time) (cachedDocument, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.doc.data == nil || !now.Before(c.expires) {
+		return cachedDocument{}, false
+	}
+	return c.doc, true
+}
+
+func (c *publicKeysCache) set(doc cachedDocument, expires time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.doc = doc
+	c.expires = expires
+}
@@ -0,0 +1,51 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestETagMatches(t *testing.T) {
+	etag := `"abc"`
+	require.False(t, etagMatches("", etag))
+	require.True(t, etagMatches(`"abc"`, etag))
+	require.True(t, etagMatches(`W/"abc"`, etag))
+	require.True(t, etagMatches(`"xyz", "abc"`, etag))
+	require.True(t, etagMatches("*", etag))
+	require.False(t, etagMatches(`"xyz"`, etag))
+}
+
+func TestConditionalDocuments(t *testing.T) {
+	httpServer, server := newTestServer(t, nil)
+	defer httpServer.Close()
+
+	for _, path := range []string{"/.well-known/openid-configuration", "/keys"} {
+		t.Run(path, func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			server.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, path, nil))
+			require.Equal(t, http.StatusOK, rr.Code)
+			require.NotEmpty(t, rr.Header().Get("Cache-Control"))
+
+			etag := rr.Header().Get("ETag")
+			require.NotEmpty(t, etag)
+
+			req := httptest.NewRequest(http.MethodGet, path, nil)
+			req.Header.Set("If-None-Match", etag)
+			rr = httptest.NewRecorder()
+			server.ServeHTTP(rr, req)
+			require.Equal(t, http.StatusNotModified, rr.Code)
+			require.Equal(t, etag, rr.Header().Get("ETag"))
+			require.Empty(t, rr.Body.Bytes())
+
+			req = httptest.NewRequest(http.MethodGet, path, nil)
+			req.Header.Set("If-None-Match", `"stale"`)
+			rr = httptest.NewRecorder()
+			server.ServeHTTP(rr, req)
+			require.Equal(t, http.StatusOK, rr.Code)
+			require.NotEmpty(t, rr.Body.Bytes())
+		})
+	}
+}
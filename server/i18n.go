@@ -0,0 +1,151 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"path"
+	"sort"
+	"strings"
+
+	"golang.org/x/text/language"
+
+	"github.com/dexidp/dex/web"
+)
+
+// defaultLanguage is the language the templates are written in. Messages are
+// looked up by their English text, so it needs no catalog.
+const defaultLanguage = "en"
+
+// catalog holds the translations of the web pages and picks the language to
+// use for a request.
+type catalog struct {
+	// messages maps a language to translations keyed by the English text.
+	messages  map[string]map[string]string
+	languages []string
+	matcher   language.Matcher
+}
+
+// loadCatalog reads the message catalogs from the i18n directory, one JSON
+// file per language named after it (e.g. "nl.json"). The catalogs built into
+// dex are read first, then those in webFS, and finally overrides, so a
+// deployment can replace single messages without copying whole catalogs.
+//
+// If languages is set, only those are offered; otherwise every language with
+// a catalog is. The first language offered is used when none of the ones a
+// browser accepts are available.
+func loadCatalog(webFS fs.FS, languages []string, overrides map[string]map[string]string) (*catalog, error) {
+	messages := make(map[string]map[string]string)
+	add := func(lang string, m map[string]string) {
+		if messages[lang] == nil {
+			messages[lang] = make(map[string]string)
+		}
+		for k, v := range m {
+			messages[lang][k] = v
+		}
+	}
+
+	for _, fsys := range []fs.FS{web.FS(), webFS} {
+		catalogs, err := readCatalogs(fsys)
+		if err != nil {
+			return nil, err
+		}
+		for lang, m := range catalogs {
+			add(lang, m)
+		}
+	}
+	for lang, m := range overrides {
+		add(lang, m)
+	}
+
+	if len(languages) == 0 {
+		languages = []string{defaultLanguage}
+		for lang := range messages {
+			if lang != defaultLanguage {
+				languages = append(languages, lang)
+			}
+		}
+		sort.Strings(languages[1:])
+	}
+
+	tags := make([]language.Tag, len(languages))
+	for i, lang := range languages {
+		tag, err := language.Parse(lang)
+		if err != nil {
+			return nil, fmt.Errorf("invalid language %q: %v", lang, err)
+		}
+		if lang != defaultLanguage && messages[lang] == nil {
+			return nil, fmt.Errorf("no messages found for language %q", lang)
+		}
+		tags[i] = tag
+	}
+
+	return &catalog{
+		messages:  messages,
+		languages: languages,
+		matcher:   language.NewMatcher(tags),
+	}, nil
+}
+
+func readCatalogs(fsys fs.FS) (map[string]map[string]string, error) {
+	files, err := fs.ReadDir(fsys, "i18n")
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("read i18n dir: %v", err)
+	}
+
+	catalogs := make(map[string]map[string]string)
+	for _, file := range files {
+		if file.IsDir() || path.Ext(file.Name()) != ".json" {
+			continue
+		}
+		data, err := fs.ReadFile(fsys, path.Join("i18n", file.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("read i18n catalog: %v", err)
+		}
+		var m map[string]string
+		if err := json.Unmarshal(data, &m); err != nil {
+			return nil, fmt.Errorf("parse i18n catalog %s: %v", file.Name(), err)
+		}
+		catalogs[strings.TrimSuffix(file.Name(), ".json")] = m
+	}
+	return catalogs, nil
+}
+
+// translator returns the translator for the language best matching the
+// request's Accept-Language header.
+func (c *catalog) translator(r *http.Request) translator {
+	if c == nil {
+		return translator{Lang: defaultLanguage}
+	}
+	lang := c.languages[0]
+	if accepted, _, err := language.ParseAcceptLanguage(r.Header.Get("Accept-Language")); err == nil && len(accepted) > 0 {
+		if _, i, confidence := c.matcher.Match(accepted...); confidence != language.No {
+			lang = c.languages[i]
+		}
+	}
+	return translator{Lang: lang, messages: c.messages[lang]}
+}
+
+// translator is embedded in the data of every template, so templates can
+// write {{ .T "Log in to %s" issuer }} and <html lang="{{ .Lang }}">.
+type translator struct {
+	Lang     string
+	messages map[string]string
+}
+
+// T translates msg and formats it with args like fmt.Sprintf. Messages
+// without a translation are used as they are.
+func (t translator) T(msg string, args ...interface{}) string {
+	if translated, ok := t.messages[msg]; ok && translated != "" {
+		msg = translated
+	}
+	if len(args) == 0 {
+		return msg
+	}
+	return fmt.Sprintf(msg, args...)
+}
@@ -0,0 +1,106 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/dexidp/dex/web"
+)
+
+func TestCatalogNegotiation(t *testing.T) {
+	c, err := loadCatalog(web.FS(), nil, nil)
+	require.NoError(t, err)
+	require.Equal(t, defaultLanguage, c.languages[0])
+
+	tests := []struct {
+		acceptLanguage string
+		wantLang       string
+		wantText       string
+	}{
+		{"", "en", "Log in to Your Account"},
+		{"nl-NL,nl;q=0.9,en;q=0.5", "nl", "Inloggen op uw account"},
+		{"de-CH", "de", "Bei Ihrem Konto anmelden"},
+		{"fr;q=0.8,ja", "fr", "Connectez-vous à votre compte"},
+		{"ja", "en", "Log in to Your Account"},
+		{"not a language", "en", "Log in to Your Account"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.acceptLanguage, func(t *testing.T) {
+			r := httptest.NewRequest("GET", "/auth", nil)
+			r.Header.Set("Accept-Language", tc.acceptLanguage)
+
+			tr := c.translator(r)
+			require.Equal(t, tc.wantLang, tr.Lang)
+			require.Equal(t, tc.wantText, tr.T("Log in to Your Account"))
+		})
+	}
+}
+
+func TestCatalogOverrides(t *testing.T) {
+	webFS := fstest.MapFS{
+		"i18n/nl.json": {Data: []byte(`{"Remember me": "Ingelogd blijven"}`)},
+	}
+	overrides := map[string]map[string]string{
+		"nl": {"Log in to %s": "Aanmelden bij %s"},
+	}
+	c, err := loadCatalog(webFS, []string{"nl", "en"}, overrides)
+	require.NoError(t, err)
+
+	r := httptest.NewRequest("GET", "/auth", nil)
+	tr := c.translator(r)
+	require.Equal(t, "nl", tr.Lang, "first configured language is the default")
+	require.Equal(t, "Ingelogd blijven", tr.T("Remember me"))
+	require.Equal(t, "Aanmelden bij ACME", tr.T("Log in to %s", "ACME"))
+	require.Equal(t, "Wachtwoord", tr.T("Password"), "built-in messages are kept")
+	require.Equal(t, "Untranslated", tr.T("Untranslated"))
+}
+
+func TestCatalogUnknownLanguage(t *testing.T) {
+	_, err := loadCatalog(web.FS(), []string{"en", "sv"}, nil)
+	require.Error(t, err)
+
+	_, err = loadCatalog(web.FS(), []string{"en", "!!"}, nil)
+	require.Error(t, err)
+}
+
+func TestTranslatedLoginPage(t *testing.T) {
+	tmpls, err := loadTemplates(webConfig{
+		webFS:     web.FS(),
+		issuer:    "ACME",
+		issuerURL: "https://example.com/dex",
+	}, "templates")
+	require.NoError(t, err)
+
+	r := httptest.NewRequest("GET", "/auth", nil)
+	r.Header.Set("Accept-Language", "de")
+	w := httptest.NewRecorder()
+	require.NoError(t, tmpls.login(r, w, []connectorInfo{{ID: "mock", Name: "Example", URL: "/auth/mock"}}))
+
+	body := w.Body.String()
+	require.Contains(t, body, `<html lang="de">`)
+	require.Contains(t, body, "Anmelden bei ACME")
+	require.Contains(t, body, "Anmelden mit Example")
+}
+
+func TestTranslatedLoginError(t *testing.T) {
+	tmpls, err := loadTemplates(webConfig{
+		webFS:     web.FS(),
+		issuer:    "ACME",
+		issuerURL: "https://example.com/dex",
+	}, "templates")
+	require.NoError(t, err)
+
+	r := httptest.NewRequest("GET", "/callback", nil)
+	r.Header.Set("Accept-Language", "fr")
+	w := httptest.NewRecorder()
+	require.NoError(t, tmpls.loginErr(r, w, http.StatusUnauthorized, "Your account is locked. Please contact your administrator.", "account_locked"))
+
+	require.Equal(t, http.StatusUnauthorized, w.Code)
+	body := w.Body.String()
+	require.Contains(t, body, "Votre compte est verrouillé.")
+	require.Contains(t, body, "Code d&#39;erreur : account_locked")
+}
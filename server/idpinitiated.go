@@ -0,0 +1,106 @@
+package server
+
+import (
+	"crypto"
+	"errors"
+	"net/http"
+	"net/url"
+
+	"github.com/gorilla/mux"
+
+	"github.com/dexidp/dex/connector"
+	"github.com/dexidp/dex/storage"
+)
+
+// handleIdPInitiatedLogin handles unsolicited SAML responses, which IdPs
+// post to the callback of the connector without a request of dex. Their
+// RelayState, if any, selects the client the user is logged in to rather
+// than an auth request. It reports whether r was such a response.
+func (s *Server) handleIdPInitiatedLogin(w http.ResponseWriter, r *http.Request, relayState string) bool {
+	ctx := r.Context()
+	connID, err := url.PathUnescape(mux.Vars(r)["connector"])
+	if err != nil || connID == "" || r.PostFormValue("SAMLResponse") == "" {
+		return false
+	}
+	conn, err := s.getConnector(ctx, connID)
+	if err != nil {
+		return false
+	}
+	idpConn, ok := conn.Connector.(connector.SAMLIdPInitiatedConnector)
+	if !ok {
+		return false
+	}
+	target, ok := idpConn.IdPInitiatedTarget(relayState)
+	if !ok {
+		return false
+	}
+	if relayState != "" {
+		// The relay state of responses to requests is their auth request.
+		if _, err := s.storage.GetAuthRequest(ctx, relayState); !errors.Is(err, storage.ErrNotFound) {
+			return false
+		}
+	}
+
+	if s.rejectIfDraining(w, r) || s.rejectIfMaintenance(w, r) {
+		return true
+	}
+	if !GrantTypeAllowed(conn.GrantTypes, grantTypeAuthorizationCode) {
+		s.logger.ErrorContext(ctx, "connector does not allow requested grant type", "connector_id", connID, "grant_type", grantTypeAuthorizationCode)
+		s.renderError(r, w, http.StatusBadRequest, "Requested connector does not support this grant type.")
+		return true
+	}
+
+	client, err := s.storage.GetClient(ctx, target.ClientID)
+	if err != nil {
+		s.logger.ErrorContext(ctx, "failed to get client of IdP-initiated login", "client_id", target.ClientID, "err", err)
+		s.renderError(r, w, http.StatusInternalServerError, "Requested resource does not exist.")
+		return true
+	}
+	redirectURI := target.RedirectURI
+	if redirectURI == "" && len(client.RedirectURIs) == 1 {
+		redirectURI = client.RedirectURIs[0]
+	}
+	if !validateRedirectURI(client, redirectURI) || deviceFlowOnly(client) {
+		s.logger.ErrorContext(ctx, "unregistered redirect_uri of IdP-initiated login", "redirect_uri", redirectURI, "client_id", client.ID)
+		s.renderError(r, w, http.StatusBadRequest, "Unregistered redirect_uri.")
+		return true
+	}
+	if !isConnectorAllowed(client.AllowedConnectors, connID) {
+		s.logger.ErrorContext(ctx, "connector not allowed for client of IdP-initiated login", "connector_id", connID, "client_id", client.ID)
+		s.renderError(r, w, http.StatusBadRequest, "Connector not allowed for this client.")
+		return true
+	}
+	scopes := target.Scopes
+	if len(scopes) == 0 {
+		scopes = []string{scopeOpenID}
+	}
+
+	identity, err := idpConn.HandleUnsolicitedPOST(ctx, parseScopes(scopes), r.PostFormValue("SAMLResponse"))
+	if err != nil {
+		s.logger.ErrorContext(ctx, "failed to authenticate IdP-initiated login", "connector_id", connID, "err", err)
+		s.renderConnectorError(w, r, err)
+		return true
+	}
+
+	authReq := storage.AuthRequest{
+		ID:            storage.NewID(),
+		ClientID:      client.ID,
+		Scopes:        scopes,
+		RedirectURI:   redirectURI,
+		ResponseTypes: []string{responseTypeCode},
+		ConnectorID:   connID,
+		Expiry:        s.now().Add(s.authRequestsValidFor),
+		HMACKey:       storage.NewHMACKey(crypto.SHA256),
+	}
+	if err := s.storage.CreateAuthRequest(ctx, authReq); err != nil {
+		s.logger.ErrorContext(ctx, "failed to create authorization request", "err", err)
+		s.renderError(r, w, http.StatusInternalServerError, "Failed to connect to the database.")
+		return true
+	}
+	s.logins.start(authReq.ID, s.now(), authReq.Expiry)
+	r = withLogin(r, authReq.ID)
+	s.logger.InfoContext(ctx, "IdP-initiated login", "connector_id", connID, "client_id", client.ID)
+
+	s.completeConnectorLogin(w, r, identity, authReq, conn.Connector)
+	return true
+}
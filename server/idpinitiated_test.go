@@ -0,0 +1,73 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/dexidp/dex/connector"
+	"github.com/dexidp/dex/storage"
+)
+
+// mockIdPInitiatedConnector accepts every unsolicited response once.
+type mockIdPInitiatedConnector struct {
+	mockSAMLRefreshConnector
+	targets map[string]connector.IdPInitiatedTarget
+	used    map[string]bool
+}
+
+func (m *mockIdPInitiatedConnector) IdPInitiatedTarget(relayState string) (connector.IdPInitiatedTarget, bool) {
+	t, ok := m.targets[relayState]
+	return t, ok
+}
+
+func (m *mockIdPInitiatedConnector) HandleUnsolicitedPOST(_ context.Context, s connector.Scopes, samlResponse string) (connector.Identity, error) {
+	if m.used[samlResponse] {
+		return connector.Identity{}, &connector.LoginError{Message: "replayed response"}
+	}
+	m.used[samlResponse] = true
+	return connector.Identity{UserID: "idp-user", Username: "idp", Email: "idp@example.com", EmailVerified: true}, nil
+}
+
+func TestIdPInitiatedLogin(t *testing.T) {
+	httpServer, s := newTestServer(t, nil)
+	defer httpServer.Close()
+
+	require.NoError(t, s.storage.CreateClient(t.Context(), storage.Client{
+		ID:           "partner-app",
+		Secret:       "secret",
+		RedirectURIs: []string{"https://partner.example.com/callback"},
+	}))
+	registerTestConnector(t, s, "partner-idp", &mockIdPInitiatedConnector{
+		targets: map[string]connector.IdPInitiatedTarget{
+			"": {ClientID: "partner-app", Scopes: []string{"openid", "email"}},
+		},
+		used: make(map[string]bool),
+	})
+
+	post := func(form url.Values) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/callback/partner-idp", strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		rr := httptest.NewRecorder()
+		s.ServeHTTP(rr, req)
+		return rr
+	}
+
+	rr := post(url.Values{"SAMLResponse": {"response-1"}})
+	require.Equal(t, http.StatusSeeOther, rr.Code)
+	location, err := url.Parse(rr.Header().Get("Location"))
+	require.NoError(t, err)
+	require.Equal(t, "partner.example.com", location.Host)
+	require.NotEmpty(t, location.Query().Get("code"))
+
+	rr = post(url.Values{"SAMLResponse": {"response-1"}})
+	require.Equal(t, http.StatusUnauthorized, rr.Code, "replayed responses must be rejected")
+
+	rr = post(url.Values{"SAMLResponse": {"response-2"}, "RelayState": {"unknown"}})
+	require.Equal(t, http.StatusBadRequest, rr.Code, "relay states without a target are not unsolicited logins")
+}
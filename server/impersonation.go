@@ -0,0 +1,221 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/dexidp/dex/pkg/events"
+	"github.com/dexidp/dex/server/internal"
+	"github.com/dexidp/dex/storage"
+)
+
+// tokenTypeSubject is the subject token type of impersonation requests. The
+// subject token is the "sub" claim dex issues for the user.
+const tokenTypeSubject = "urn:dexidp:params:oauth:token-type:subject"
+
+const defaultImpersonationValidFor = 15 * time.Minute
+
+// ValidateImpersonationPolicy checks the impersonation policy of a client.
+func ValidateImpersonationPolicy(p storage.ImpersonationPolicy) error {
+	if len(p.Connectors) == 0 {
+		return errors.New("no connectors whose users can be impersonated")
+	}
+	if len(p.ActorGroups) == 0 {
+		return errors.New("no actorGroups allowed to impersonate users")
+	}
+	for _, scope := range p.Scopes {
+		switch scope {
+		case scopeOpenID, scopeEmail, scopeProfile, scopeGroups:
+		default:
+			return fmt.Errorf("scope %q can't be granted to impersonation tokens", scope)
+		}
+	}
+	if p.ValidFor != "" {
+		if d, err := time.ParseDuration(p.ValidFor); err != nil || d <= 0 {
+			return fmt.Errorf("invalid validFor %q", p.ValidFor)
+		}
+	}
+	return nil
+}
+
+// impersonationRequest holds the parameters of an impersonation relevant to
+// the audit record.
+type impersonationRequest struct {
+	actor              *actorClaim
+	userID             string
+	connID             string
+	requestedTokenType string
+	scopes             []string
+}
+
+// handleImpersonation issues a token for another user to a confidential
+// client with an impersonation policy. The actor token of the engineer, issued to the same
+// client, is named in the "act" claim of the token.
+func (s *Server) handleImpersonation(w http.ResponseWriter, r *http.Request, client storage.Client, subjectToken string, req impersonationRequest) {
+	ctx := r.Context()
+	q := r.Form
+
+	deny := func(errType, description string, status int) {
+		s.auditImpersonation(ctx, client, req, description)
+		s.tokenErrHelper(w, errType, description, status)
+	}
+
+	policy := client.Impersonation
+	if policy == nil {
+		deny(errUnauthorizedClient, "Client is not allowed to impersonate users.", http.StatusBadRequest)
+		return
+	}
+	// Anyone can present the client_id of a public client, so impersonation
+	// requires a client that authenticated with its secret.
+	if client.Public {
+		deny(errUnauthorizedClient, "Public clients can't impersonate users.", http.StatusBadRequest)
+		return
+	}
+	if err := ValidateImpersonationPolicy(*policy); err != nil {
+		s.logger.ErrorContext(ctx, "invalid impersonation policy", "client_id", client.ID, "err", err)
+		s.tokenErrHelper(w, errServerError, "", http.StatusInternalServerError)
+		return
+	}
+
+	switch q.Get("actor_token_type") {
+	case tokenTypeAccess, tokenTypeID:
+	default:
+		deny(errInvalidRequest, "An actor_token of type access_token or id_token is required.", http.StatusBadRequest)
+		return
+	}
+	actor, actorSub, err := s.verifyClientToken(ctx, client, q.Get("actor_token"))
+	if err != nil {
+		s.logger.ErrorContext(ctx, "failed to verify actor token", "err", err)
+		s.tokenErrHelper(w, errServerError, "", http.StatusInternalServerError)
+		return
+	}
+	if actor == nil {
+		deny(errAccessDenied, "Invalid actor token.", http.StatusUnauthorized)
+		return
+	}
+	req.actor = &actorClaim{Subject: actor.Subject, Email: actor.Email, ClientID: client.ID}
+	if actor.Actor != nil {
+		// Record who is really behind the impersonation token.
+		req.actor = actor.Actor
+		deny(errAccessDenied, "Impersonation tokens can't be used as actor token.", http.StatusForbidden)
+		return
+	}
+	if !slices.ContainsFunc(actor.Groups, func(group string) bool { return slices.Contains(policy.ActorGroups, group) }) {
+		deny(errAccessDenied, "Actor is not allowed to impersonate users.", http.StatusForbidden)
+		return
+	}
+
+	sub := new(internal.IDTokenSubject)
+	if err := internal.Unmarshal(subjectToken, sub); err != nil {
+		deny(errInvalidRequest, "Invalid subject_token.", http.StatusBadRequest)
+		return
+	}
+	req.userID, req.connID = sub.UserId, sub.ConnId
+	if sub.UserId == actorSub.UserId && sub.ConnId == actorSub.ConnId {
+		deny(errInvalidRequest, "Actor can't impersonate themselves.", http.StatusBadRequest)
+		return
+	}
+	if !slices.Contains(policy.Connectors, sub.ConnId) || !isConnectorAllowed(client.AllowedConnectors, sub.ConnId) {
+		deny(errInvalidRequest, "Client can't impersonate users of this connector.", http.StatusBadRequest)
+		return
+	}
+
+	allowedScopes := policy.Scopes
+	if len(allowedScopes) == 0 {
+		allowedScopes = []string{scopeOpenID}
+	}
+	if len(req.scopes) == 0 {
+		req.scopes = []string{scopeOpenID}
+	}
+	for _, scope := range req.scopes {
+		if !slices.Contains(allowedScopes, scope) {
+			deny(errInvalidScope, fmt.Sprintf("Client can't request scope %q.", scope), http.StatusBadRequest)
+			return
+		}
+	}
+	if len(q["audience"]) > 0 || len(q["resource"]) > 0 {
+		deny(errInvalidTarget, "Impersonation tokens can't be requested for other audiences.", http.StatusBadRequest)
+		return
+	}
+
+	// The claims of the user are known from their last login.
+	identity, err := s.storage.GetUserIdentity(ctx, sub.UserId, sub.ConnId)
+	if errors.Is(err, storage.ErrNotFound) {
+		deny(errInvalidRequest, "Unknown subject.", http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		s.logger.ErrorContext(ctx, "failed to get user identity", "err", err)
+		s.tokenErrHelper(w, errServerError, "", http.StatusInternalServerError)
+		return
+	}
+	if slices.ContainsFunc(identity.Claims.Groups, func(group string) bool { return slices.Contains(policy.ProtectedGroups, group) }) {
+		deny(errAccessDenied, "Subject can't be impersonated.", http.StatusForbidden)
+		return
+	}
+
+	if !s.allowTokenRequest(w, r, riskFlowImpersonation, claimsIdentity(identity.Claims), sub.ConnId, client.ID, req.scopes) {
+		s.auditImpersonation(ctx, client, req, "Denied by risk assessment.")
+		return
+	}
+
+	opts := tokenOptions{actor: req.actor, validFor: defaultImpersonationValidFor}
+	if policy.ValidFor != "" {
+		opts.validFor, _ = time.ParseDuration(policy.ValidFor)
+	}
+	resp := &accessTokenResponse{
+		IssuedTokenType: req.requestedTokenType,
+		TokenType:       "bearer",
+	}
+	var expiry time.Time
+	switch req.requestedTokenType {
+	case tokenTypeAccess:
+		resp.AccessToken, expiry, err = s.newToken(ctx, client.ID, identity.Claims, req.scopes, "", storage.NewID(), "", sub.ConnId, time.Time{}, nil, opts)
+	case tokenTypeID:
+		resp.AccessToken, expiry, err = s.newToken(ctx, client.ID, identity.Claims, req.scopes, "", "", "", sub.ConnId, time.Time{}, nil, opts)
+	default:
+		deny(errRequestNotSupported, "Impersonation only issues access and ID tokens.", http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		s.logger.ErrorContext(ctx, "impersonation failed to create new token", "requested_token_type", req.requestedTokenType, "err", err)
+		s.tokenErrHelper(w, errServerError, "", http.StatusInternalServerError)
+		return
+	}
+	s.auditImpersonation(ctx, client, req, "")
+	resp.ExpiresIn = int(time.Until(expiry).Seconds())
+	s.writeAccessToken(w, resp)
+}
+
+// auditImpersonation records the outcome of an impersonation request. An
+// empty reason means the request was granted.
+func (s *Server) auditImpersonation(ctx context.Context, client storage.Client, req impersonationRequest, reason string) {
+	outcome := "granted"
+	if reason != "" {
+		outcome = "denied"
+	}
+	var actorSub, actorEmail string
+	if req.actor != nil {
+		actorSub, actorEmail = req.actor.Subject, req.actor.Email
+	}
+	s.logger.WarnContext(ctx, "impersonation",
+		"outcome", outcome, "reason", reason,
+		"client_id", client.ID, "actor_sub", actorSub, "actor_email", actorEmail,
+		"connector_id", req.connID, "user_id", req.userID,
+		"requested_token_type", req.requestedTokenType, "scopes", req.scopes)
+	s.emitEvent(ctx, events.TypeImpersonation, req.userID, map[string]any{
+		"outcome":              outcome,
+		"reason":               reason,
+		"client_id":            client.ID,
+		"actor_sub":            actorSub,
+		"actor_email":          actorEmail,
+		"connector_id":         req.connID,
+		"user_id":              req.userID,
+		"requested_token_type": req.requestedTokenType,
+		"scopes":               req.scopes,
+	})
+}
@@ -0,0 +1,256 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/coreos/go-oidc/v3/oidc"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dexidp/dex/storage"
+)
+
+func TestValidateImpersonationPolicy(t *testing.T) {
+	valid := storage.ImpersonationPolicy{Connectors: []string{"ldap"}, ActorGroups: []string{"support"}}
+	require.NoError(t, ValidateImpersonationPolicy(valid))
+
+	p := valid
+	p.Connectors = nil
+	require.Error(t, ValidateImpersonationPolicy(p))
+	p = valid
+	p.ActorGroups = nil
+	require.Error(t, ValidateImpersonationPolicy(p))
+	p = valid
+	p.Scopes = []string{scopeOpenID, scopeOfflineAccess}
+	require.Error(t, ValidateImpersonationPolicy(p))
+	p = valid
+	p.ValidFor = "-5m"
+	require.Error(t, ValidateImpersonationPolicy(p))
+}
+
+func TestHandleImpersonation(t *testing.T) {
+	ctx := t.Context()
+	var logs bytes.Buffer
+	httpServer, s := newTestServer(t, func(c *Config) {
+		c.Logger = slog.New(slog.NewJSONHandler(&logs, nil))
+		require.NoError(t, c.Storage.CreateClient(ctx, storage.Client{
+			ID:     "support",
+			Secret: "support-secret",
+			Impersonation: &storage.ImpersonationPolicy{
+				Connectors:      []string{"mock"},
+				ActorGroups:     []string{"support"},
+				ProtectedGroups: []string{"admins"},
+				Scopes:          []string{scopeOpenID, scopeEmail, scopeGroups},
+				ValidFor:        "5m",
+			},
+		}))
+		require.NoError(t, c.Storage.CreateClient(ctx, storage.Client{ID: "app", Secret: "app-secret"}))
+		require.NoError(t, c.Storage.CreateClient(ctx, storage.Client{
+			ID:            "kiosk",
+			Secret:        "kiosk-secret",
+			Public:        true,
+			Impersonation: &storage.ImpersonationPolicy{Connectors: []string{"mock"}, ActorGroups: []string{"support"}},
+		}))
+		for _, ui := range []storage.UserIdentity{
+			{UserID: "jane", ConnectorID: "mock", Claims: storage.Claims{UserID: "jane", Email: "jane@example.com", EmailVerified: true, Groups: []string{"users"}}},
+			{UserID: "boss", ConnectorID: "mock", Claims: storage.Claims{UserID: "boss", Groups: []string{"admins"}}},
+		} {
+			require.NoError(t, c.Storage.CreateUserIdentity(ctx, ui))
+		}
+	})
+	defer httpServer.Close()
+
+	actorToken := func(clientID, userID string, groups ...string) string {
+		claims := storage.Claims{UserID: userID, Email: userID + "@example.com", EmailVerified: true, Groups: groups}
+		token, _, err := s.newToken(ctx, clientID, claims, []string{scopeOpenID, scopeEmail, scopeGroups}, "", storage.NewID(), "", "mock", time.Time{}, nil, tokenOptions{})
+		require.NoError(t, err)
+		return token
+	}
+	subject := func(userID string) string {
+		sub, err := genSubject(userID, "mock")
+		require.NoError(t, err)
+		return sub
+	}
+	engineer := actorToken("support", "eng", "support")
+	impersonatedToken := func(userID string, groups ...string) string {
+		claims := storage.Claims{UserID: userID, Email: userID + "@example.com", EmailVerified: true, Groups: groups}
+		opts := tokenOptions{actor: &actorClaim{Subject: subject("eng"), Email: "eng@example.com", ClientID: "support"}, validFor: time.Minute}
+		token, _, err := s.newToken(ctx, "support", claims, []string{scopeOpenID, scopeEmail, scopeGroups}, "", storage.NewID(), "", "mock", time.Time{}, nil, opts)
+		require.NoError(t, err)
+		return token
+	}
+
+	tests := []struct {
+		name               string
+		clientID           string
+		subject            string
+		actorToken         string
+		scope              string
+		requestedTokenType string
+
+		wantCode  int
+		wantError string
+	}{
+		{
+			name:     "granted",
+			subject:  subject("jane"),
+			scope:    "openid email",
+			wantCode: http.StatusOK,
+		},
+		{
+			name:      "client without policy",
+			clientID:  "app",
+			subject:   subject("jane"),
+			wantCode:  http.StatusBadRequest,
+			wantError: errUnauthorizedClient,
+		},
+		{
+			name:       "public client",
+			clientID:   "kiosk",
+			subject:    subject("jane"),
+			actorToken: actorToken("kiosk", "eng", "support"),
+			wantCode:   http.StatusBadRequest,
+			wantError:  errUnauthorizedClient,
+		},
+		{
+			name:       "no actor token",
+			subject:    subject("jane"),
+			actorToken: "-",
+			wantCode:   http.StatusBadRequest,
+			wantError:  errInvalidRequest,
+		},
+		{
+			name:       "actor token of another client",
+			subject:    subject("jane"),
+			actorToken: actorToken("app", "eng", "support"),
+			wantCode:   http.StatusUnauthorized,
+			wantError:  errAccessDenied,
+		},
+		{
+			name:       "actor not in actor groups",
+			subject:    subject("jane"),
+			actorToken: actorToken("support", "dev", "developers"),
+			wantCode:   http.StatusForbidden,
+			wantError:  errAccessDenied,
+		},
+		{
+			name:       "impersonation token as actor token",
+			subject:    subject("jane"),
+			actorToken: impersonatedToken("colleague", "support"),
+			wantCode:   http.StatusForbidden,
+			wantError:  errAccessDenied,
+		},
+		{
+			name:      "protected subject",
+			subject:   subject("boss"),
+			wantCode:  http.StatusForbidden,
+			wantError: errAccessDenied,
+		},
+		{
+			name:      "actor as subject",
+			subject:   subject("eng"),
+			wantCode:  http.StatusBadRequest,
+			wantError: errInvalidRequest,
+		},
+		{
+			name:      "unknown subject",
+			subject:   subject("john"),
+			wantCode:  http.StatusBadRequest,
+			wantError: errInvalidRequest,
+		},
+		{
+			name:      "scope not allowed",
+			subject:   subject("jane"),
+			scope:     "openid profile",
+			wantCode:  http.StatusBadRequest,
+			wantError: errInvalidScope,
+		},
+		{
+			name:               "refresh token",
+			subject:            subject("jane"),
+			requestedTokenType: tokenTypeRefresh,
+			wantCode:           http.StatusBadRequest,
+			wantError:          errRequestNotSupported,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			logs.Reset()
+			clientID := "support"
+			if tc.clientID != "" {
+				clientID = tc.clientID
+			}
+			vals := url.Values{
+				"grant_type":         {grantTypeTokenExchange},
+				"subject_token_type": {tokenTypeSubject},
+				"subject_token":      {tc.subject},
+				"client_id":          {clientID},
+				"client_secret":      {clientID + "-secret"},
+			}
+			switch tc.actorToken {
+			case "":
+				vals.Set("actor_token", engineer)
+				vals.Set("actor_token_type", tokenTypeAccess)
+			case "-":
+			default:
+				vals.Set("actor_token", tc.actorToken)
+				vals.Set("actor_token_type", tokenTypeAccess)
+			}
+			setNonEmpty(vals, "scope", tc.scope)
+			setNonEmpty(vals, "requested_token_type", tc.requestedTokenType)
+
+			rr := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodPost, httpServer.URL+"/token", strings.NewReader(vals.Encode()))
+			req.Header.Set("content-type", "application/x-www-form-urlencoded")
+			s.handleToken(rr, req)
+
+			require.Equal(t, tc.wantCode, rr.Code, logs.String())
+			if tc.wantCode != http.StatusOK {
+				var res struct {
+					Error string `json:"error"`
+				}
+				require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &res))
+				require.Equal(t, tc.wantError, res.Error)
+				require.Contains(t, logs.String(), `"msg":"impersonation","outcome":"denied"`)
+				return
+			}
+
+			var res accessTokenResponse
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &res))
+			require.LessOrEqual(t, res.ExpiresIn, 300)
+			provider, err := oidc.NewProvider(ctx, httpServer.URL)
+			require.NoError(t, err)
+			token, err := provider.Verifier(&oidc.Config{ClientID: "support"}).Verify(ctx, res.AccessToken)
+			require.NoError(t, err)
+			require.Equal(t, tc.subject, token.Subject)
+			var claims idTokenClaims
+			require.NoError(t, token.Claims(&claims))
+			require.Equal(t, "jane@example.com", claims.Email)
+			require.Equal(t, &actorClaim{Subject: subject("eng"), Email: "eng@example.com", ClientID: "support"}, claims.Actor)
+			require.Contains(t, logs.String(), `"msg":"impersonation","outcome":"granted"`)
+		})
+	}
+
+	t.Run("impersonation token as subject token", func(t *testing.T) {
+		vals := url.Values{
+			"grant_type":         {grantTypeTokenExchange},
+			"subject_token_type": {tokenTypeAccess},
+			"subject_token":      {impersonatedToken("jane", "users")},
+			"client_id":          {"support"},
+			"client_secret":      {"support-secret"},
+		}
+		rr := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, httpServer.URL+"/token", strings.NewReader(vals.Encode()))
+		req.Header.Set("content-type", "application/x-www-form-urlencoded")
+		s.handleToken(rr, req)
+
+		require.Equal(t, http.StatusUnauthorized, rr.Code, rr.Body.String())
+	})
+}
@@ -91,6 +91,8 @@ type IntrospectionExtra struct {
 	PreferredUsername string `json:"preferred_username,omitempty"`
 
 	FederatedIDClaims *federatedIDClaims `json:"federated_claims,omitempty"`
+
+	Actor *actorClaim `json:"act,omitempty"`
 }
 
 type TokenTypeEnum int
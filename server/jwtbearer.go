@@ -0,0 +1,189 @@
+package server
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/coreos/go-oidc/v3/oidc"
+
+	"github.com/dexidp/dex/storage"
+)
+
+// jwtBearerKeySets caches the key sets of the issuers trusted for the JWT
+// bearer grant, so their keys aren't fetched on every request.
+type jwtBearerKeySets struct {
+	mu      sync.Mutex
+	keySets map[string]*oidc.RemoteKeySet
+}
+
+func (k *jwtBearerKeySets) get(jwksURL string) *oidc.RemoteKeySet {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	if k.keySets == nil {
+		k.keySets = make(map[string]*oidc.RemoteKeySet)
+	}
+	keySet, ok := k.keySets[jwksURL]
+	if !ok {
+		// The key set outlives the request, so it can't use its context.
+		keySet = oidc.NewRemoteKeySet(context.Background(), jwksURL)
+		k.keySets[jwksURL] = keySet
+	}
+	return keySet
+}
+
+// jwtBearerClaims are the claims of an assertion mapped to the user's claims.
+type jwtBearerClaims struct {
+	Email             string   `json:"email"`
+	EmailVerified     *bool    `json:"email_verified"`
+	Name              string   `json:"name"`
+	PreferredUsername string   `json:"preferred_username"`
+	Groups            []string `json:"groups"`
+}
+
+// jwtBearerConnectorID is the connector ID in the subjects of users asserted
+// by an issuer. It keeps subjects of different issuers, and of client
+// credentials, apart.
+func jwtBearerConnectorID(issuer string) string {
+	return "jwt-bearer:" + issuer
+}
+
+// unverifiedIssuer returns the "iss" claim of a JWT, to find the key set
+// verifying it.
+func unverifiedIssuer(assertion string) (string, error) {
+	parts := strings.Split(assertion, ".")
+	if len(parts) != 3 {
+		return "", errors.New("malformed jwt")
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return "", fmt.Errorf("malformed jwt payload: %v", err)
+	}
+	var claims struct {
+		Issuer string `json:"iss"`
+	}
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return "", fmt.Errorf("malformed jwt payload: %v", err)
+	}
+	return claims.Issuer, nil
+}
+
+// verifyJWTBearerAssertion verifies an assertion against the issuers trusted
+// by the client and returns the claims of its subject, and the issuer.
+func (s *Server) verifyJWTBearerAssertion(ctx context.Context, client storage.Client, assertion string) (storage.Claims, string, error) {
+	iss, err := unverifiedIssuer(assertion)
+	if err != nil {
+		return storage.Claims{}, "", err
+	}
+	i := slices.IndexFunc(client.JWTBearerIssuers, func(issuer storage.JWTBearerIssuer) bool {
+		return issuer.Issuer == iss
+	})
+	if i < 0 {
+		return storage.Claims{}, "", fmt.Errorf("issuer %q is not trusted by the client", iss)
+	}
+	issuer := client.JWTBearerIssuers[i]
+
+	verifier := oidc.NewVerifier(issuer.Issuer, s.jwtBearerKeys.get(issuer.JWKSURL), &oidc.Config{
+		// The audience is checked below, as there may be several valid ones.
+		SkipClientIDCheck: true,
+		Now:               s.now,
+	})
+	token, err := verifier.Verify(ctx, assertion)
+	if err != nil {
+		return storage.Claims{}, "", err
+	}
+
+	audiences := []string{issuer.Audience}
+	if issuer.Audience == "" {
+		audiences = []string{s.issuerURL.String(), s.absURL("/token")}
+	}
+	if !slices.ContainsFunc(token.Audience, func(aud string) bool { return slices.Contains(audiences, aud) }) {
+		return storage.Claims{}, "", fmt.Errorf("expected audience %q, got %q", audiences, token.Audience)
+	}
+	if token.Subject == "" {
+		return storage.Claims{}, "", errors.New("missing subject")
+	}
+	if len(issuer.AllowedSubjects) > 0 && !slices.Contains(issuer.AllowedSubjects, token.Subject) {
+		return storage.Claims{}, "", fmt.Errorf("subject %q is not allowed", token.Subject)
+	}
+
+	var c jwtBearerClaims
+	if err := token.Claims(&c); err != nil {
+		return storage.Claims{}, "", fmt.Errorf("failed to decode claims: %v", err)
+	}
+	claims := storage.Claims{
+		UserID:            token.Subject,
+		Username:          c.Name,
+		PreferredUsername: c.PreferredUsername,
+		Email:             c.Email,
+		Groups:            c.Groups,
+	}
+	if c.EmailVerified != nil {
+		claims.EmailVerified = *c.EmailVerified
+	}
+	return claims, issuer.Issuer, nil
+}
+
+// handleJWTBearerGrant trades a JWT issued by an issuer the client trusts for
+// dex tokens, as described in RFC 7523.
+func (s *Server) handleJWTBearerGrant(w http.ResponseWriter, r *http.Request, client storage.Client) {
+	ctx := r.Context()
+
+	if len(client.JWTBearerIssuers) == 0 {
+		s.tokenErrHelper(w, errUnauthorizedClient, "Client has no JWT bearer issuers configured.", http.StatusBadRequest)
+		return
+	}
+
+	assertion := r.Form.Get("assertion")
+	if assertion == "" {
+		s.tokenErrHelper(w, errInvalidRequest, "Missing assertion.", http.StatusBadRequest)
+		return
+	}
+
+	scopes := strings.Fields(r.Form.Get("scope"))
+	hasOpenIDScope, ok := s.validateNonInteractiveScopes(w, r, client.ID, scopes, grantTypeJWTBearer)
+	if !ok {
+		return
+	}
+
+	claims, iss, err := s.verifyJWTBearerAssertion(ctx, client, assertion)
+	if err != nil {
+		s.logger.InfoContext(ctx, "invalid jwt bearer assertion", "client_id", client.ID, "err", err)
+		s.tokenErrHelper(w, errInvalidGrant, "Invalid assertion.", http.StatusBadRequest)
+		return
+	}
+
+	// The assertion's subject isn't a user of a connector, the issuer takes
+	// its place.
+	connID := jwtBearerConnectorID(iss)
+	if !s.allowTokenRequest(w, r, riskFlowJWTBearer, claimsIdentity(claims), connID, client.ID, scopes) {
+		return
+	}
+
+	accessToken, expiry, err := s.newAccessToken(ctx, client.ID, claims, scopes, "", connID, time.Time{}, nil)
+	if err != nil {
+		s.logger.ErrorContext(ctx, "jwt bearer grant failed to create new access token", "err", err)
+		s.tokenErrHelper(w, errServerError, "", http.StatusInternalServerError)
+		return
+	}
+
+	var idToken string
+	if hasOpenIDScope {
+		idToken, expiry, err = s.newIDToken(ctx, client.ID, claims, scopes, "", accessToken, "", connID, time.Time{}, nil)
+		if err != nil {
+			s.logger.ErrorContext(ctx, "jwt bearer grant failed to create new ID token", "err", err)
+			s.tokenErrHelper(w, errServerError, "", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	resp := s.toAccessTokenResponse(idToken, accessToken, "", expiry)
+	s.writeAccessToken(w, resp)
+}
@@ -0,0 +1,203 @@
+package server
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/coreos/go-oidc/v3/oidc"
+	"github.com/go-jose/go-jose/v4"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dexidp/dex/server/internal"
+	"github.com/dexidp/dex/storage"
+)
+
+func TestHandleJWTBearerGrant(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	jwks := jose.JSONWebKeySet{Keys: []jose.JSONWebKey{{Key: key.Public(), KeyID: "workload", Algorithm: "RS256", Use: "sig"}}}
+	jwksServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(jwks)
+	}))
+	defer jwksServer.Close()
+
+	signer, err := jose.NewSigner(jose.SigningKey{Algorithm: jose.RS256, Key: key}, (&jose.SignerOptions{}).WithHeader("kid", "workload"))
+	require.NoError(t, err)
+	sign := func(claims map[string]interface{}) string {
+		payload, err := json.Marshal(claims)
+		require.NoError(t, err)
+		jws, err := signer.Sign(payload)
+		require.NoError(t, err)
+		token, err := jws.CompactSerialize()
+		require.NoError(t, err)
+		return token
+	}
+
+	httpServer, s := newTestServer(t, func(c *Config) {
+		c.AllowedGrantTypes = append(c.AllowedGrantTypes, grantTypeJWTBearer)
+	})
+	defer httpServer.Close()
+
+	ctx := t.Context()
+	err = s.storage.CreateClient(ctx, storage.Client{
+		ID:     "workload-client",
+		Secret: "secret",
+		JWTBearerIssuers: []storage.JWTBearerIssuer{{
+			Issuer:          "https://spiffe.example.com",
+			JWKSURL:         jwksServer.URL,
+			AllowedSubjects: []string{"spiffe://example.com/billing", "spiffe://example.com/reports"},
+		}},
+	})
+	require.NoError(t, err)
+	err = s.storage.CreateClient(ctx, storage.Client{
+		ID:     "other-client",
+		Secret: "secret",
+	})
+	require.NoError(t, err)
+
+	valid := func() map[string]interface{} {
+		return map[string]interface{}{
+			"iss":    "https://spiffe.example.com",
+			"sub":    "spiffe://example.com/billing",
+			"aud":    httpServer.URL + "/token",
+			"exp":    time.Now().Add(time.Minute).Unix(),
+			"email":  "billing@example.com",
+			"groups": []string{"payments"},
+		}
+	}
+
+	tests := []struct {
+		name      string
+		clientID  string
+		claims    func(c map[string]interface{})
+		scope     string
+		wantCode  int
+		wantError string
+	}{
+		{
+			name:     "valid assertion",
+			clientID: "workload-client",
+			scope:    "openid email groups",
+			wantCode: http.StatusOK,
+		},
+		{
+			name:     "issuer URL as audience",
+			clientID: "workload-client",
+			claims:   func(c map[string]interface{}) { c["aud"] = httpServer.URL },
+			wantCode: http.StatusOK,
+		},
+		{
+			name:      "client without issuers",
+			clientID:  "other-client",
+			wantCode:  http.StatusBadRequest,
+			wantError: errUnauthorizedClient,
+		},
+		{
+			name:      "untrusted issuer",
+			clientID:  "workload-client",
+			claims:    func(c map[string]interface{}) { c["iss"] = "https://evil.example.com" },
+			wantCode:  http.StatusBadRequest,
+			wantError: errInvalidGrant,
+		},
+		{
+			name:      "wrong audience",
+			clientID:  "workload-client",
+			claims:    func(c map[string]interface{}) { c["aud"] = "https://other.example.com" },
+			wantCode:  http.StatusBadRequest,
+			wantError: errInvalidGrant,
+		},
+		{
+			name:      "expired assertion",
+			clientID:  "workload-client",
+			claims:    func(c map[string]interface{}) { c["exp"] = time.Now().Add(-time.Minute).Unix() },
+			wantCode:  http.StatusBadRequest,
+			wantError: errInvalidGrant,
+		},
+		{
+			name:      "subject not allowed",
+			clientID:  "workload-client",
+			claims:    func(c map[string]interface{}) { c["sub"] = "spiffe://example.com/admin" },
+			wantCode:  http.StatusBadRequest,
+			wantError: errInvalidGrant,
+		},
+		{
+			name:      "offline access",
+			clientID:  "workload-client",
+			scope:     "openid offline_access",
+			wantCode:  http.StatusBadRequest,
+			wantError: errInvalidScope,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			claims := valid()
+			if tc.claims != nil {
+				tc.claims(claims)
+			}
+
+			v := url.Values{}
+			v.Add("grant_type", grantTypeJWTBearer)
+			v.Add("assertion", sign(claims))
+			if tc.scope != "" {
+				v.Add("scope", tc.scope)
+			}
+			req, _ := http.NewRequest("POST", httpServer.URL+"/token", bytes.NewBufferString(v.Encode()))
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			req.SetBasicAuth(tc.clientID, "secret")
+
+			rr := httptest.NewRecorder()
+			s.ServeHTTP(rr, req)
+			require.Equal(t, tc.wantCode, rr.Code, rr.Body.String())
+
+			if tc.wantCode != http.StatusOK {
+				var resp struct {
+					Error string `json:"error"`
+				}
+				require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+				require.Equal(t, tc.wantError, resp.Error)
+				return
+			}
+
+			var resp struct {
+				AccessToken  string `json:"access_token"`
+				IDToken      string `json:"id_token"`
+				RefreshToken string `json:"refresh_token"`
+			}
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+			require.NotEmpty(t, resp.AccessToken)
+			require.Empty(t, resp.RefreshToken)
+
+			provider, err := oidc.NewProvider(ctx, httpServer.URL)
+			require.NoError(t, err)
+			token, err := provider.Verifier(&oidc.Config{ClientID: tc.clientID}).Verify(ctx, resp.AccessToken)
+			require.NoError(t, err)
+
+			var sub internal.IDTokenSubject
+			require.NoError(t, internal.Unmarshal(token.Subject, &sub))
+			require.Equal(t, "spiffe://example.com/billing", sub.UserId)
+			require.Equal(t, jwtBearerConnectorID("https://spiffe.example.com"), sub.ConnId)
+
+			if tc.scope == "" {
+				require.Empty(t, resp.IDToken)
+				return
+			}
+			idToken, err := provider.Verifier(&oidc.Config{ClientID: tc.clientID}).Verify(ctx, resp.IDToken)
+			require.NoError(t, err)
+			var idClaims struct {
+				Email  string   `json:"email"`
+				Groups []string `json:"groups"`
+			}
+			require.NoError(t, idToken.Claims(&idClaims))
+			require.Equal(t, "billing@example.com", idClaims.Email)
+			require.Equal(t, []string{"payments"}, idClaims.Groups)
+		})
+	}
+}
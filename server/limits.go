@@ -0,0 +1,54 @@
+package server
+
+import (
+	"net/http"
+	"time"
+)
+
+// EndpointLimits bounds the requests to a group of endpoints. Zero values
+// disable the respective limit.
+type EndpointLimits struct {
+	// Timeout is how long a handler may take before the request is answered
+	// with 503 Service Unavailable.
+	Timeout time.Duration
+	// MaxBodySize is the largest request body accepted, in bytes. Larger
+	// bodies are answered with 413 Request Entity Too Large.
+	MaxBodySize int64
+}
+
+// RequestLimits holds the limits of the endpoint groups served by the server.
+type RequestLimits struct {
+	// Auth covers the login flow: the authorization, connector login,
+	// callback and approval endpoints as well as the password reset, email
+	// verification, registration, logout and MFA pages.
+	Auth EndpointLimits
+	// Token covers the token, introspection and userinfo endpoints.
+	Token EndpointLimits
+	// Device covers the device flow endpoints.
+	Device EndpointLimits
+}
+
+// LimitHandler applies l to the requests served by h.
+func LimitHandler(l EndpointLimits, h http.Handler) http.Handler {
+	if l.MaxBodySize > 0 {
+		next := h
+		h = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			// Reject what is known to be too large before reading anything,
+			// the reader below catches bodies of unknown length.
+			if r.ContentLength > l.MaxBodySize {
+				http.Error(w, "Request body too large.", http.StatusRequestEntityTooLarge)
+				return
+			}
+			r.Body = http.MaxBytesReader(w, r.Body, l.MaxBodySize)
+			next.ServeHTTP(w, r)
+		})
+	}
+	if l.Timeout > 0 {
+		h = http.TimeoutHandler(h, l.Timeout, "Request timed out.")
+	}
+	return h
+}
+
+func withLimits(l EndpointLimits, h http.HandlerFunc) http.HandlerFunc {
+	return LimitHandler(l, h).ServeHTTP
+}
@@ -0,0 +1,67 @@
+package server
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLimitHandlerBodySize(t *testing.T) {
+	var readErr error
+	h := LimitHandler(EndpointLimits{MaxBodySize: 8}, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, readErr = io.ReadAll(r.Body)
+	}))
+
+	rr := httptest.NewRecorder()
+	h.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/", strings.NewReader("small")))
+	require.Equal(t, http.StatusOK, rr.Code)
+	require.NoError(t, readErr)
+
+	rr = httptest.NewRecorder()
+	h.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/", strings.NewReader("far too large")))
+	require.Equal(t, http.StatusRequestEntityTooLarge, rr.Code)
+
+	// Bodies of unknown length are cut off while reading.
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader("far too large"))
+	req.ContentLength = -1
+	rr = httptest.NewRecorder()
+	h.ServeHTTP(rr, req)
+	var maxBytesErr *http.MaxBytesError
+	require.ErrorAs(t, readErr, &maxBytesErr)
+}
+
+func TestLimitHandlerTimeout(t *testing.T) {
+	h := LimitHandler(EndpointLimits{Timeout: 10 * time.Millisecond}, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	}))
+
+	rr := httptest.NewRecorder()
+	h.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/", nil))
+	require.Equal(t, http.StatusServiceUnavailable, rr.Code)
+}
+
+func TestRequestLimitedEndpoints(t *testing.T) {
+	httpServer, s := newTestServer(t, func(c *Config) {
+		c.RequestLimits.Token = EndpointLimits{MaxBodySize: 16}
+	})
+	defer httpServer.Close()
+
+	body := "grant_type=client_credentials&client_id=foo&client_secret=bar"
+	req := httptest.NewRequest(http.MethodPost, "/token", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	rr := httptest.NewRecorder()
+	s.ServeHTTP(rr, req)
+	require.Equal(t, http.StatusRequestEntityTooLarge, rr.Code)
+
+	// Other groups are not limited.
+	req = httptest.NewRequest(http.MethodPost, "/auth", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	rr = httptest.NewRecorder()
+	s.ServeHTTP(rr, req)
+	require.NotEqual(t, http.StatusRequestEntityTooLarge, rr.Code)
+}
@@ -0,0 +1,227 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"net/netip"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/dexidp/dex/storage"
+)
+
+// LoginThrottleConfig throttles password logins per client IP address after
+// failed attempts, across all connectors. Unlike the request rate limits, only
+// failed logins count, so addresses trying many passwords are slowed down
+// while users logging in normally aren't. The failed logins are counted in
+// Config.RateLimitStorage.
+type LoginThrottleConfig struct {
+	// FreeAttempts is the number of failed logins an address can make before
+	// its logins are delayed. Defaults to 5.
+	FreeAttempts int
+	// Delay is how long an address waits after its first throttled failed
+	// login. It doubles with every further failure, up to MaxDelay. They
+	// default to a second and a minute.
+	Delay    time.Duration
+	MaxDelay time.Duration
+	// BlockAfter failed logins, the address is blocked for BlockFor, 15
+	// minutes by default. Zero never blocks.
+	BlockAfter int
+	BlockFor   time.Duration
+	// Window is how long failed logins of an address are remembered after
+	// its last one. Defaults to 15 minutes.
+	Window time.Duration
+	// Allowlist are the networks never throttled, e.g. office ranges behind
+	// a single NAT address.
+	Allowlist []netip.Prefix
+}
+
+// loginThrottle counts the failed logins per key in rate limit buckets, where
+// TAT is the time of the last failure and Count the number of failures. With
+// a shared storage all dex instances throttle together.
+type loginThrottle struct {
+	config LoginThrottleConfig
+	store  storage.RateLimitStorage
+	prefix string
+	now    func() time.Time
+}
+
+// newLoginThrottle returns a throttle keeping its counters in store under keys
+// starting with prefix, or in memory if store is nil.
+func newLoginThrottle(config LoginThrottleConfig, store storage.RateLimitStorage, prefix string, now func() time.Time) *loginThrottle {
+	if store == nil {
+		store = newMemoryBuckets(now)
+	}
+	return &loginThrottle{
+		config: config,
+		store:  store,
+		prefix: prefix,
+		now:    now,
+	}
+}
+
+func (t *loginThrottle) allowlisted(ip string) bool {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return false
+	}
+	addr = addr.Unmap()
+	return slices.ContainsFunc(t.config.Allowlist, func(p netip.Prefix) bool { return p.Contains(addr) })
+}
+
+// retryAt returns when the key with failures may log in again.
+func (t *loginThrottle) retryAt(b storage.RateLimitBucket) time.Time {
+	c := t.config
+	switch {
+	case c.BlockAfter > 0 && b.Count >= c.BlockAfter:
+		return b.TAT.Add(c.BlockFor)
+	case b.Count > c.FreeAttempts:
+		delay := c.Delay << min(b.Count-c.FreeAttempts-1, 30)
+		if delay <= 0 || (c.MaxDelay > 0 && delay > c.MaxDelay) {
+			delay = c.MaxDelay
+		}
+		return b.TAT.Add(delay)
+	}
+	return b.TAT
+}
+
+// expiry returns when the failures can be forgotten.
+func (t *loginThrottle) expiry(b storage.RateLimitBucket) time.Time {
+	forget := b.TAT.Add(t.config.Window)
+	if retryAt := t.retryAt(b); retryAt.After(forget) {
+		return retryAt
+	}
+	return forget
+}
+
+// failures returns the remembered failed logins of key.
+func (t *loginThrottle) failures(ctx context.Context, key string) (storage.RateLimitBucket, error) {
+	now := t.now()
+	var failures storage.RateLimitBucket
+	err := t.store.UpdateRateLimitBucket(ctx, t.prefix+key, func(b storage.RateLimitBucket) (storage.RateLimitBucket, error) {
+		if b.Count > 0 && now.Before(b.Expiry) {
+			failures = b
+		}
+		return b, nil
+	})
+	return failures, err
+}
+
+// wait returns how long ip has to wait before its next login, zero if it can
+// log in now.
+func (t *loginThrottle) wait(ctx context.Context, ip string) (time.Duration, error) {
+	if t.allowlisted(ip) {
+		return 0, nil
+	}
+	b, err := t.failures(ctx, ip)
+	if err != nil || b.Count == 0 {
+		return 0, err
+	}
+	return max(t.retryAt(b).Sub(t.now()), 0), nil
+}
+
+// count returns the remembered failed logins of key.
+func (t *loginThrottle) count(ctx context.Context, key string) (int, error) {
+	b, err := t.failures(ctx, key)
+	return b.Count, err
+}
+
+// failed records a failed login of key.
+func (t *loginThrottle) failed(ctx context.Context, key string) error {
+	if t.allowlisted(key) {
+		return nil
+	}
+	now := t.now()
+	return t.store.UpdateRateLimitBucket(ctx, t.prefix+key, func(b storage.RateLimitBucket) (storage.RateLimitBucket, error) {
+		if !now.Before(b.Expiry) {
+			b.Count = 0
+		}
+		b.Count++
+		b.TAT = now
+		b.Expiry = t.expiry(b)
+		return b, nil
+	})
+}
+
+// memoryBuckets keeps rate limit buckets in memory when they aren't shared
+// through the storage, so each dex instance counts on its own.
+type memoryBuckets struct {
+	now func() time.Time
+
+	mu        sync.Mutex
+	buckets   map[string]storage.RateLimitBucket
+	lastSweep time.Time
+}
+
+func newMemoryBuckets(now func() time.Time) *memoryBuckets {
+	return &memoryBuckets{now: now, buckets: make(map[string]storage.RateLimitBucket)}
+}
+
+// UpdateRateLimitBucket implements storage.RateLimitStorage. Expired buckets
+// are deleted at most once a minute.
+func (m *memoryBuckets) UpdateRateLimitBucket(_ context.Context, key string, updater func(b storage.RateLimitBucket) (storage.RateLimitBucket, error)) error {
+	now := m.now()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if now.Sub(m.lastSweep) > time.Minute {
+		for k, b := range m.buckets {
+			if now.After(b.Expiry) {
+				delete(m.buckets, k)
+			}
+		}
+		m.lastSweep = now
+	}
+
+	b, ok := m.buckets[key]
+	if !ok {
+		b = storage.RateLimitBucket{Key: key}
+	}
+	b, err := updater(b)
+	if err != nil {
+		return err
+	}
+	if ok || now.Before(b.Expiry) {
+		m.buckets[key] = b
+	}
+	return nil
+}
+
+// allowLogin checks whether the client of r may attempt a password login. It
+// returns false and the time to wait if the address is throttled. Logins are
+// allowed if the failed logins can't be read.
+func (s *Server) allowLogin(r *http.Request) (bool, time.Duration) {
+	if s.loginThrottle == nil {
+		return true, 0
+	}
+	wait, err := s.loginThrottle.wait(r.Context(), rateLimitIP(r))
+	if err != nil {
+		s.logger.ErrorContext(r.Context(), "failed to read failed logins", "err", err)
+		return true, 0
+	}
+	if wait <= 0 {
+		return true, 0
+	}
+	if s.rateLimitedRequests != nil {
+		s.rateLimitedRequests.WithLabelValues("login").Inc()
+	}
+	s.logger.InfoContext(r.Context(), "login throttled", "remote_ip", rateLimitIP(r), "retry_after", wait)
+	return false, wait
+}
+
+// loginFailed records a failed password login of username by the client of
+// r.
+func (s *Server) loginFailed(r *http.Request, username string) {
+	if s.loginThrottle != nil {
+		if err := s.loginThrottle.failed(r.Context(), rateLimitIP(r)); err != nil {
+			s.logger.ErrorContext(r.Context(), "failed to record failed login", "err", err)
+		}
+	}
+	if s.captcha != nil {
+		if err := s.captcha.failed(r.Context(), rateLimitIP(r), username); err != nil {
+			s.logger.ErrorContext(r.Context(), "failed to record failed login", "err", err)
+		}
+	}
+}
@@ -0,0 +1,127 @@
+package server
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/dexidp/dex/storage"
+	"github.com/dexidp/dex/storage/memory"
+)
+
+func TestLoginThrottle(t *testing.T) {
+	stores := map[string]storage.RateLimitStorage{
+		"memory":  nil,
+		"storage": memory.New(slog.New(slog.DiscardHandler)).(storage.RateLimitStorage),
+	}
+	for name, store := range stores {
+		t.Run(name, func(t *testing.T) {
+			ctx := t.Context()
+			now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+			throttle := newLoginThrottle(LoginThrottleConfig{
+				FreeAttempts: 2,
+				Delay:        time.Second,
+				MaxDelay:     4 * time.Second,
+				BlockAfter:   6,
+				BlockFor:     time.Hour,
+				Window:       time.Minute,
+				Allowlist:    []netip.Prefix{netip.MustParsePrefix("192.0.2.0/24")},
+			}, store, "login:", func() time.Time { return now })
+
+			failed := func(ip string) {
+				require.NoError(t, throttle.failed(ctx, ip))
+			}
+			wait := func(ip string) time.Duration {
+				d, err := throttle.wait(ctx, ip)
+				require.NoError(t, err)
+				return d
+			}
+
+			const ip = "10.0.0.1"
+			for _, want := range []time.Duration{0, 0, time.Second, 2 * time.Second, 4 * time.Second} {
+				failed(ip)
+				require.Equal(t, want, wait(ip))
+			}
+			require.Zero(t, wait("10.0.0.2"), "other addresses are not throttled")
+
+			failed(ip)
+			require.Equal(t, time.Hour, wait(ip), "address should be blocked")
+
+			now = now.Add(time.Hour)
+			require.Zero(t, wait(ip))
+			failed(ip)
+			require.Zero(t, wait(ip), "failures should be forgotten after the block")
+
+			for i := 0; i < 10; i++ {
+				failed("192.0.2.7")
+			}
+			require.Zero(t, wait("192.0.2.7"), "allowlisted addresses are never throttled")
+		})
+	}
+}
+
+func TestLoginThrottleShared(t *testing.T) {
+	ctx := t.Context()
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	store := memory.New(slog.New(slog.DiscardHandler)).(storage.RateLimitStorage)
+	config := LoginThrottleConfig{FreeAttempts: 1, Delay: time.Second, Window: time.Minute}
+	replica1 := newLoginThrottle(config, store, "login:", func() time.Time { return now })
+	replica2 := newLoginThrottle(config, store, "login:", func() time.Time { return now })
+
+	const ip = "10.0.0.1"
+	require.NoError(t, replica1.failed(ctx, ip))
+	require.NoError(t, replica2.failed(ctx, ip))
+	wait, err := replica1.wait(ctx, ip)
+	require.NoError(t, err)
+	require.Equal(t, time.Second, wait, "failures on other replicas should count")
+}
+
+func TestPasswordLoginThrottled(t *testing.T) {
+	ctx := t.Context()
+	connID := "mockPw"
+
+	httpServer, s := newTestServer(t, func(c *Config) {
+		c.LoginThrottle = &LoginThrottleConfig{FreeAttempts: 1, Delay: time.Minute}
+	})
+	defer httpServer.Close()
+
+	sc := storage.Connector{
+		ID:              connID,
+		Type:            "mockPassword",
+		Name:            "MockPassword",
+		ResourceVersion: "1",
+		Config:          []byte(`{"username": "foo", "password": "password"}`),
+	}
+	require.NoError(t, s.storage.CreateConnector(ctx, sc))
+	_, err := s.OpenConnector(sc)
+	require.NoError(t, err)
+
+	authReq := storage.AuthRequest{
+		ID:            "throttled",
+		ConnectorID:   connID,
+		RedirectURI:   "cb",
+		Expiry:        time.Now().Add(time.Minute),
+		ResponseTypes: []string{responseTypeCode},
+	}
+	require.NoError(t, s.storage.CreateAuthRequest(ctx, authReq))
+
+	login := func(password string) *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+		path := fmt.Sprintf("/auth/%s/login?state=%s&login=foo&password=%s", connID, authReq.ID, password)
+		s.handlePasswordLogin(rr, httptest.NewRequest(http.MethodPost, path, nil))
+		return rr
+	}
+
+	require.Equal(t, http.StatusUnauthorized, login("wrong").Code)
+	require.Equal(t, http.StatusUnauthorized, login("wrong").Code)
+
+	rr := login("password")
+	require.Equal(t, http.StatusTooManyRequests, rr.Code, "even valid credentials are refused while throttled")
+	require.Equal(t, "60", rr.Header().Get("Retry-After"))
+}
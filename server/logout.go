@@ -9,6 +9,7 @@ import (
 	"slices"
 
 	"github.com/dexidp/dex/connector"
+	"github.com/dexidp/dex/pkg/events"
 	"github.com/dexidp/dex/server/internal"
 	"github.com/dexidp/dex/storage"
 )
@@ -21,7 +22,8 @@ import (
 //  1. Validate id_token_hint (signature + issuer; expiry skipped per spec)
 //  2. Extract user identity (subject) and client (audience/azp) from the token
 //  3. Validate post_logout_redirect_uri against the client's registered URIs
-//  4. Revoke refresh tokens for the user/connector pair
+//  4. Revoke refresh tokens for the user/connector pair, and let connectors
+//     implementing BackchannelLogoutConnector end the upstream session
 //  5. If the auth session exists and upstream connector implements LogoutCallbackConnector:
 //     a. Store LogoutState + HMAC key in the session (not deleted yet)
 //     b. Redirect to upstream logout with signed state
@@ -131,6 +133,7 @@ func (s *Server) handleLogout(w http.ResponseWriter, r *http.Request) {
 	var connectorData []byte
 	if userID != "" && connectorID != "" {
 		connectorData = s.revokeRefreshTokens(ctx, userID, connectorID)
+		s.backchannelLogout(ctx, connectorID, connectorData)
 	}
 
 	// Try upstream logout. This requires a live auth session to store the HMAC key
@@ -226,11 +229,28 @@ func (s *Server) finishLogout(w http.ResponseWriter, r *http.Request, postLogout
 		}
 	}
 
-	if err := s.templates.logout(r, w, backURL, loggedOut); err != nil {
+	if err := s.templates().logout(r, w, backURL, loggedOut); err != nil {
 		s.logger.ErrorContext(r.Context(), "server template error", "err", err)
 	}
 }
 
+// backchannelLogout lets the connector end the upstream session server-side,
+// if it supports that. Like the redirect, it is best-effort.
+func (s *Server) backchannelLogout(ctx context.Context, connectorID string, connectorData []byte) {
+	conn, err := s.getConnector(ctx, connectorID)
+	if err != nil {
+		return
+	}
+	logoutConn, ok := conn.Connector.(connector.BackchannelLogoutConnector)
+	if !ok {
+		return
+	}
+	if err := logoutConn.BackchannelLogout(ctx, connectorData); err != nil {
+		s.logger.ErrorContext(ctx, "logout: upstream backchannel logout failed",
+			"connector_id", connectorID, "err", err)
+	}
+}
+
 // tryUpstreamLogout attempts to redirect to the upstream provider's logout endpoint.
 // It stores LogoutState in the auth session before redirecting so the callback can
 // read it back. Returns the redirect URL and true on success, or ("", false) if
@@ -303,6 +323,10 @@ func (s *Server) deleteAuthSession(ctx context.Context, userID, connectorID stri
 		return false
 	}
 	s.logger.InfoContext(ctx, "logout successful", "user_id", userID, "connector_id", connectorID)
+	s.emitEvent(ctx, events.TypeLogout, userID, map[string]any{
+		"connector_id": connectorID,
+		"user_id":      userID,
+	})
 	return true
 }
 
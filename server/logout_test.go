@@ -12,6 +12,7 @@ import (
 
 	"github.com/stretchr/testify/require"
 
+	"github.com/dexidp/dex/connector"
 	"github.com/dexidp/dex/storage"
 )
 
@@ -380,3 +381,46 @@ func TestRevokeRefreshTokensReturnsConnectorData(t *testing.T) {
 	require.Empty(t, os.Refresh)
 	require.Equal(t, expectedConnData, os.ConnectorData)
 }
+
+type backchannelLogoutRecorder struct {
+	connector.CallbackConnector
+	connectorData []byte
+}
+
+func (c *backchannelLogoutRecorder) BackchannelLogout(_ context.Context, connectorData []byte) error {
+	c.connectorData = connectorData
+	return nil
+}
+
+func TestHandleLogoutBackchannel(t *testing.T) {
+	httpServer, server := newTestServerWithSessions(t, nil)
+	defer httpServer.Close()
+
+	ctx := t.Context()
+	clientID := "test-client"
+	userID := "test-user"
+	connectorID := "backchannel"
+
+	conn := &backchannelLogoutRecorder{}
+	registerTestConnector(t, server, connectorID, conn)
+
+	require.NoError(t, server.storage.CreateClient(ctx, storage.Client{
+		ID: clientID, Secret: "secret",
+		RedirectURIs: []string{"https://example.com/callback"},
+	}))
+	require.NoError(t, server.storage.CreateOfflineSessions(ctx, storage.OfflineSessions{
+		UserID: userID, ConnID: connectorID,
+		Refresh:       map[string]*storage.RefreshTokenRef{},
+		ConnectorData: []byte(`{"upstream":"tokens"}`),
+	}))
+
+	idToken, _, err := server.newIDToken(ctx, clientID, storage.Claims{
+		UserID: userID, Username: "testuser", Email: "test@example.com",
+	}, []string{"openid"}, "", "", "", connectorID, time.Now(), nil)
+	require.NoError(t, err)
+
+	rr := httptest.NewRecorder()
+	server.ServeHTTP(rr, httptest.NewRequest("GET", "/logout?id_token_hint="+url.QueryEscape(idToken), nil))
+	require.Equal(t, http.StatusOK, rr.Code)
+	require.Equal(t, `{"upstream":"tokens"}`, string(conn.connectorData))
+}
@@ -0,0 +1,65 @@
+package server
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// defaultMaintenanceMessage is shown on the maintenance page if no message is
+// configured.
+const defaultMaintenanceMessage = "Logins are temporarily disabled for maintenance. Please try again later."
+
+// Maintenance configures the maintenance mode of the server. While it is
+// enabled new logins are refused with a maintenance page. Logins already in
+// progress, refresh tokens and the API keep working.
+type Maintenance struct {
+	Enabled bool
+
+	// Message is shown on the maintenance page. Defaults to a generic notice.
+	Message string
+
+	// RetryAfter is sent to clients in the Retry-After header. Left out if
+	// zero.
+	RetryAfter time.Duration
+}
+
+// SetMaintenance enables, disables or updates the maintenance mode. It takes
+// effect for the next request.
+func (s *Server) SetMaintenance(m Maintenance) {
+	s.maintenance.Store(&m)
+}
+
+// Maintenance returns the current maintenance mode settings.
+func (s *Server) Maintenance() Maintenance {
+	if m := s.maintenance.Load(); m != nil {
+		return *m
+	}
+	return Maintenance{}
+}
+
+// rejectIfMaintenance renders the maintenance page for requests starting a
+// new login while the maintenance mode is enabled. It reports whether the
+// request was rejected.
+func (s *Server) rejectIfMaintenance(w http.ResponseWriter, r *http.Request) bool {
+	m := s.Maintenance()
+	if !m.Enabled {
+		return false
+	}
+	if m.RetryAfter > 0 {
+		seconds := int64((m.RetryAfter + time.Second - 1) / time.Second)
+		w.Header().Set("Retry-After", strconv.FormatInt(seconds, 10))
+	}
+	message := m.Message
+	if message == "" {
+		message = defaultMaintenanceMessage
+	}
+	if prefersJSON(r) {
+		s.renderJSONError(r, w, http.StatusServiceUnavailable, message, "")
+		return true
+	}
+	if err := s.templates().maintenance(r, w, message); err != nil {
+		s.logger.ErrorContext(r.Context(), "server template error", "err", err)
+	}
+	return true
+}
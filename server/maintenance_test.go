@@ -0,0 +1,54 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMaintenance(t *testing.T) {
+	httpServer, s := newTestServer(t, func(c *Config) {
+		c.Maintenance = Maintenance{Enabled: true, Message: "Upstream IAM upgrade in progress.", RetryAfter: 90 * time.Second}
+	})
+	defer httpServer.Close()
+
+	for _, path := range []string{"/auth", "/auth/mock"} {
+		rr := httptest.NewRecorder()
+		s.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, path+"?client_id=test&response_type=code", nil))
+		require.Equal(t, http.StatusServiceUnavailable, rr.Code, path)
+		require.Equal(t, "90", rr.Header().Get("Retry-After"), path)
+		require.Contains(t, rr.Body.String(), "Upstream IAM upgrade in progress.", path)
+	}
+
+	// Token requests, such as refreshes, are still handled.
+	form := url.Values{"grant_type": {"refresh_token"}, "refresh_token": {"bogus"}}
+	req := httptest.NewRequest(http.MethodPost, "/token", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.SetBasicAuth("test", "secret")
+	rr := httptest.NewRecorder()
+	s.ServeHTTP(rr, req)
+	require.NotEqual(t, http.StatusServiceUnavailable, rr.Code)
+
+	s.SetMaintenance(Maintenance{})
+	require.False(t, s.Maintenance().Enabled)
+	rr = httptest.NewRecorder()
+	s.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/auth?client_id=test&response_type=code", nil))
+	require.NotEqual(t, http.StatusServiceUnavailable, rr.Code)
+}
+
+func TestMaintenanceDefaultMessage(t *testing.T) {
+	httpServer, s := newTestServer(t, nil)
+	defer httpServer.Close()
+
+	s.SetMaintenance(Maintenance{Enabled: true})
+	rr := httptest.NewRecorder()
+	s.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/auth", nil))
+	require.Equal(t, http.StatusServiceUnavailable, rr.Code)
+	require.Empty(t, rr.Header().Get("Retry-After"))
+	require.Contains(t, rr.Body.String(), "Logins Temporarily Disabled")
+}
@@ -155,7 +155,7 @@ func (s *Server) handleWebAuthn(w http.ResponseWriter, r *http.Request) {
 		mode = "register"
 	}
 
-	if err := s.templates.webauthnVerify(r, w, mode, mfa.authenticatorID); err != nil {
+	if err := s.templates().webauthnVerify(r, w, mode, mfa.authenticatorID); err != nil {
 		s.logger.ErrorContext(r.Context(), "server template error", "err", err)
 	}
 }
@@ -270,7 +270,7 @@ func (s *Server) renderTOTPPage(secret *storage.MFASecret, lastFail bool, issuer
 			return
 		}
 	}
-	if err := s.templates.totpVerify(r, w, r.URL.String(), issuer, connectorID, qrCode, lastFail); err != nil {
+	if err := s.templates().totpVerify(r, w, r.URL.String(), issuer, connectorID, qrCode, lastFail); err != nil {
 		s.logger.ErrorContext(r.Context(), "server template error", "err", err)
 	}
 }
@@ -314,6 +314,12 @@ func (s *Server) mfaChainForClient(ctx context.Context, clientID, connectorID st
 		source = s.defaultMFAChain
 	}
 
+	return s.filterMFAChain(ctx, source, connectorID)
+}
+
+// filterMFAChain returns the authenticators of source enabled for the type
+// of the connector.
+func (s *Server) filterMFAChain(ctx context.Context, source []string, connectorID string) ([]string, error) {
 	// Resolve connector type from connector ID.
 	connectorType, err := s.getConnectorType(ctx, connectorID)
 	if err != nil {
@@ -355,6 +361,13 @@ func (s *Server) completeMFAStep(ctx context.Context, authReq storage.AuthReques
 	if err != nil {
 		return "", fmt.Errorf("get MFA chain: %w", err)
 	}
+	if len(mfaChain) == 0 {
+		// Only step-up logins required by the risk engine have MFA steps
+		// without a chain of the client.
+		if mfaChain, err = s.stepUpMFAChain(ctx, authReq.ConnectorID); err != nil {
+			return "", fmt.Errorf("get step-up MFA chain: %w", err)
+		}
+	}
 
 	// Find the next authenticator in the chain after the current one.
 	var nextAuthenticator string
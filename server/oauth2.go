@@ -50,6 +50,7 @@ func newDisplayedErr(status int, format string, a ...interface{}) *displayedAuth
 // redirectWithError redirects back to the client with an OAuth2 error response.
 // Used for prompt=none when login or consent is required.
 func (s *Server) redirectWithError(w http.ResponseWriter, r *http.Request, authReq *storage.AuthRequest, errType, description string) {
+	s.logins.done(authReq.ID)
 	err := &redirectedAuthErr{
 		State:       authReq.State,
 		RedirectURI: authReq.RedirectURI,
@@ -120,21 +121,23 @@ func tokenErr(w http.ResponseWriter, typ, description string, statusCode int) er
 
 //nolint
 const (
-	errInvalidRequest          = "invalid_request"
-	errUnauthorizedClient      = "unauthorized_client"
-	errAccessDenied            = "access_denied"
-	errUnsupportedResponseType = "unsupported_response_type"
-	errRequestNotSupported     = "request_not_supported"
-	errInvalidScope            = "invalid_scope"
-	errServerError             = "server_error"
-	errTemporarilyUnavailable  = "temporarily_unavailable"
-	errUnsupportedGrantType    = "unsupported_grant_type"
-	errInvalidGrant            = "invalid_grant"
-	errInvalidClient           = "invalid_client"
-	errInactiveToken           = "inactive_token"
-	errLoginRequired           = "login_required"
-	errInteractionRequired     = "interaction_required"
-	errConsentRequired         = "consent_required"
+	errInvalidRequest           = "invalid_request"
+	errUnauthorizedClient       = "unauthorized_client"
+	errAccessDenied             = "access_denied"
+	errUnsupportedResponseType  = "unsupported_response_type"
+	errRequestNotSupported      = "request_not_supported"
+	errInvalidScope             = "invalid_scope"
+	errServerError              = "server_error"
+	errTemporarilyUnavailable   = "temporarily_unavailable"
+	errUnsupportedGrantType     = "unsupported_grant_type"
+	errInvalidGrant             = "invalid_grant"
+	errInvalidClient            = "invalid_client"
+	errInactiveToken            = "inactive_token"
+	errLoginRequired            = "login_required"
+	errInteractionRequired      = "interaction_required"
+	errConsentRequired          = "consent_required"
+	errAccountSelectionRequired = "account_selection_required"
+	errInvalidTarget            = "invalid_target"
 )
 
 const (
@@ -163,6 +166,7 @@ const (
 	grantTypeDeviceCode        = "urn:ietf:params:oauth:grant-type:device_code"
 	grantTypeTokenExchange     = "urn:ietf:params:oauth:grant-type:token-exchange"
 	grantTypeClientCredentials = "client_credentials"
+	grantTypeJWTBearer         = "urn:ietf:params:oauth:grant-type:jwt-bearer"
 )
 
 // ConnectorGrantTypes is the set of grant types that can be restricted per connector.
@@ -267,6 +271,15 @@ func (a audience) MarshalJSON() ([]byte, error) {
 	return json.Marshal([]string(a))
 }
 
+func (a *audience) UnmarshalJSON(b []byte) error {
+	var aud string
+	if err := json.Unmarshal(b, &aud); err == nil {
+		*a = audience{aud}
+		return nil
+	}
+	return json.Unmarshal(b, (*[]string)(a))
+}
+
 type idTokenClaims struct {
 	Issuer           string   `json:"iss"`
 	Subject          string   `json:"sub"`
@@ -290,6 +303,16 @@ type idTokenClaims struct {
 	PreferredUsername string `json:"preferred_username,omitempty"`
 
 	FederatedIDClaims *federatedIDClaims `json:"federated_claims,omitempty"`
+
+	Actor *actorClaim `json:"act,omitempty"`
+}
+
+// actorClaim is the "act" claim (RFC 8693) of a token issued to an actor on
+// behalf of its subject.
+type actorClaim struct {
+	Subject  string `json:"sub"`
+	Email    string `json:"email,omitempty"`
+	ClientID string `json:"client_id,omitempty"`
 }
 
 type federatedIDClaims struct {
@@ -298,10 +321,14 @@ type federatedIDClaims struct {
 }
 
 func (s *Server) newAccessToken(ctx context.Context, clientID string, claims storage.Claims, scopes []string, nonce, connID string, authTime time.Time, connectorData []byte) (accessToken string, expiry time.Time, err error) {
-	return s.newIDToken(ctx, clientID, claims, scopes, nonce, storage.NewID(), "", connID, authTime, connectorData)
+	return s.newToken(ctx, clientID, claims, scopes, nonce, storage.NewID(), "", connID, authTime, connectorData, tokenOptions{})
 }
 
 func getClientID(aud audience, azp string) (string, error) {
+	// Tokens restricted to resources don't carry the client in their audience.
+	if azp != "" {
+		return azp, nil
+	}
 	switch len(aud) {
 	case 0:
 		return "", fmt.Errorf("no audience is set, could not find ClientID")
@@ -344,9 +371,43 @@ func genSubject(userID string, connID string) (string, error) {
 	return internal.Marshal(sub)
 }
 
+// tokenOptions customizes the tokens of a grant beyond what the scopes
+// determine.
+type tokenOptions struct {
+	// audience is added to the audience requested through the scopes.
+	audience audience
+	// claims are added to the token. They never replace claims set by dex.
+	claims map[string]interface{}
+	// resources, if set, replace the audience of the token, restricting it
+	// to the resource servers it was requested for (RFC 8707).
+	resources []string
+	// customClaims are the custom claims of the client, rendered for the
+	// user. Like claims, they never replace claims set by dex.
+	customClaims map[string]interface{}
+	// actor is set for tokens issued to someone impersonating the user.
+	actor *actorClaim
+	// validFor shortens the lifetime of the token, if set.
+	validFor time.Duration
+}
+
 func (s *Server) newIDToken(ctx context.Context, clientID string, claims storage.Claims, scopes []string, nonce, accessToken, code, connID string, authTime time.Time, connectorData []byte) (idToken string, expiry time.Time, err error) {
+	var opts tokenOptions
+	client, err := s.storage.GetClient(ctx, clientID)
+	switch {
+	case err == nil:
+		opts.customClaims = client.CustomClaims
+	case !errors.Is(err, storage.ErrNotFound):
+		return "", expiry, fmt.Errorf("failed to get client: %v", err)
+	}
+	return s.newToken(ctx, clientID, claims, scopes, nonce, accessToken, code, connID, authTime, connectorData, opts)
+}
+
+func (s *Server) newToken(ctx context.Context, clientID string, claims storage.Claims, scopes []string, nonce, accessToken, code, connID string, authTime time.Time, connectorData []byte, opts tokenOptions) (idToken string, expiry time.Time, err error) {
 	issuedAt := s.now()
 	expiry = issuedAt.Add(s.idTokensValidFor)
+	if opts.validFor > 0 && opts.validFor < s.idTokensValidFor {
+		expiry = issuedAt.Add(opts.validFor)
+	}
 
 	subjectString, err := genSubject(claims.UserID, connID)
 	if err != nil {
@@ -361,6 +422,7 @@ func (s *Server) newIDToken(ctx context.Context, clientID string, claims storage
 		Expiry:   expiry.Unix(),
 		IssuedAt: issuedAt.Unix(),
 		JWTID:    uuid.New().String(),
+		Actor:    opts.actor,
 	}
 
 	// Include auth_time when sessions are enabled and the value is available.
@@ -427,7 +489,15 @@ func (s *Server) newIDToken(ctx context.Context, clientID string, claims storage
 	}
 
 	tok.Audience = getAudience(clientID, scopes)
-	if len(tok.Audience) > 1 {
+	for _, aud := range opts.audience {
+		if !tok.Audience.contains(aud) {
+			tok.Audience = append(tok.Audience, aud)
+		}
+	}
+	if len(opts.resources) > 0 {
+		tok.Audience = audience(opts.resources)
+		tok.AuthorizingParty = clientID
+	} else if len(tok.Audience) > 1 {
 		// The current client becomes the authorizing party.
 		tok.AuthorizingParty = clientID
 	}
@@ -437,6 +507,37 @@ func (s *Server) newIDToken(ctx context.Context, clientID string, claims storage
 		return "", expiry, fmt.Errorf("could not serialize claims: %v", err)
 	}
 
+	if len(opts.claims) > 0 {
+		if payload, err = addClaims(payload, opts.claims); err != nil {
+			return "", expiry, fmt.Errorf("could not add claims: %v", err)
+		}
+	}
+
+	if len(opts.customClaims) > 0 {
+		customClaims, err := renderCustomClaims(opts.customClaims, claims, connID)
+		if err != nil {
+			return "", expiry, fmt.Errorf("could not render custom claims: %v", err)
+		}
+		if payload, err = addClaims(payload, customClaims); err != nil {
+			return "", expiry, fmt.Errorf("could not add custom claims: %v", err)
+		}
+	}
+
+	client, err := s.storage.GetClient(ctx, clientID)
+	if err != nil {
+		if !errors.Is(err, storage.ErrNotFound) {
+			return "", expiry, fmt.Errorf("failed to get client: %v", err)
+		}
+		client = storage.Client{}
+	}
+
+	connectorClaims := releasedConnectorClaims(claims.CustomClaims, client.ReleasedConnectorClaims)
+	if len(connectorClaims) > 0 {
+		if payload, err = addClaims(payload, connectorClaims); err != nil {
+			return "", expiry, fmt.Errorf("could not add connector claims: %v", err)
+		}
+	}
+
 	// Allow connectors to extend the payload with additional claims
 	if connID != "" && connectorData != nil {
 		conn, err := s.getConnector(ctx, connID)
@@ -452,12 +553,39 @@ func (s *Server) newIDToken(ctx context.Context, clientID string, claims storage
 		}
 	}
 
+	if payload, err = reshapeGroups(payload, s.groupsClaimOf(client)); err != nil {
+		return "", expiry, fmt.Errorf("could not reshape groups claim: %v", err)
+	}
+
 	if idToken, err = s.signer.Sign(ctx, payload); err != nil {
 		return "", expiry, fmt.Errorf("failed to sign payload: %v", err)
 	}
 	return idToken, expiry, nil
 }
 
+// addClaims adds claims to a token payload, leaving the claims already in it
+// and the registered claims untouched.
+func addClaims(payload []byte, claims map[string]interface{}) ([]byte, error) {
+	var merged map[string]interface{}
+	if err := json.Unmarshal(payload, &merged); err != nil {
+		return nil, err
+	}
+	for name, value := range claims {
+		if _, ok := merged[name]; ok || registeredClaims[name] {
+			continue
+		}
+		merged[name] = value
+	}
+	return json.Marshal(merged)
+}
+
+// registeredClaims can only be set by dex, even if a token doesn't carry them.
+var registeredClaims = map[string]bool{
+	"iss": true, "sub": true, "aud": true, "exp": true, "nbf": true, "iat": true, "jti": true,
+	"azp": true, "nonce": true, "auth_time": true, "at_hash": true, "c_hash": true,
+	"act": true,
+}
+
 // validateIDTokenHint verifies the signature and issuer of an id_token_hint.
 // Expired tokens are accepted per OIDC Core 1.0 §3.1.2.1.
 // Returns the verified token so callers can extract Subject, Audience, etc.
@@ -533,6 +661,10 @@ func (s *Server) parseAuthorizationRequest(r *http.Request) (*storage.AuthReques
 	if redirectURI == deviceCallbackURI && client.Public {
 		redirectURI = s.absPath(deviceCallbackURI)
 	}
+	if deviceFlowOnly(client) && redirectURI != s.absPath(deviceCallbackURI) {
+		s.logger.ErrorContext(r.Context(), "client is restricted to the device flow", "redirect_uri", redirectURI, "client_id", clientID)
+		return nil, "", newDisplayedErr(http.StatusBadRequest, "Client is restricted to the device flow.")
+	}
 
 	// From here on out, we want to redirect back to the client with an error.
 	newRedirectedErr := func(typ, format string, a ...interface{}) *redirectedAuthErr {
@@ -614,6 +746,11 @@ func (s *Server) parseAuthorizationRequest(r *http.Request) (*storage.AuthReques
 		return nil, "", newRedirectedErr(errInvalidScope, "Client can't request scope(s) %q", invalidScopes)
 	}
 
+	resources := q["resource"]
+	if err := validateResources(client, resources); err != nil {
+		return nil, "", newRedirectedErr(errInvalidTarget, "Invalid resource indicator: %v.", err)
+	}
+
 	var rt struct {
 		code    bool
 		idToken bool
@@ -697,11 +834,13 @@ func (s *Server) parseAuthorizationRequest(r *http.Request) (*storage.AuthReques
 		Nonce:               nonce,
 		ForceApprovalPrompt: forceApproval,
 		Prompt:              prompt.String(),
+		LoginHint:           q.Get("login_hint"),
 		MaxAge:              maxAge,
 		Scopes:              scopes,
 		RedirectURI:         redirectURI,
 		ResponseTypes:       responseTypes,
 		ConnectorID:         connectorID,
+		Resources:           resources,
 		PKCE: storage.PKCE{
 			CodeChallenge:       codeChallenge,
 			CodeChallengeMethod: codeChallengeMethod,
@@ -34,6 +34,10 @@ func TestGetClientID(t *testing.T) {
 	cid, err = getClientID(audience{"a", "b"}, "azp")
 	require.Equal(t, "azp", cid)
 	require.NoError(t, err)
+
+	cid, err = getClientID(audience{"https://api.example.com"}, "azp")
+	require.Equal(t, "azp", cid)
+	require.NoError(t, err)
 }
 
 func TestGetAudience(t *testing.T) {
@@ -411,6 +415,62 @@ func TestParseAuthorizationRequest(t *testing.T) {
 				"scope":                 "openid email profile",
 			},
 		},
+		{
+			name: "allowed resource",
+			clients: []storage.Client{
+				{
+					ID:           "foo",
+					RedirectURIs: []string{"https://example.com/foo"},
+					Resources:    []string{"https://api.example.com"},
+				},
+			},
+			supportedResponseTypes: []string{"code"},
+			queryParams: map[string]string{
+				"client_id":     "foo",
+				"redirect_uri":  "https://example.com/foo",
+				"response_type": "code",
+				"scope":         "openid email profile",
+				"resource":      "https://api.example.com",
+			},
+		},
+		{
+			name: "resource not allowed",
+			clients: []storage.Client{
+				{
+					ID:           "foo",
+					RedirectURIs: []string{"https://example.com/foo"},
+					Resources:    []string{"https://api.example.com"},
+				},
+			},
+			supportedResponseTypes: []string{"code"},
+			queryParams: map[string]string{
+				"client_id":     "foo",
+				"redirect_uri":  "https://example.com/foo",
+				"response_type": "code",
+				"scope":         "openid email profile",
+				"resource":      "https://billing.example.com",
+			},
+			expectedError: &redirectedAuthErr{Type: errInvalidTarget},
+		},
+		{
+			name: "resource with fragment",
+			clients: []storage.Client{
+				{
+					ID:           "foo",
+					RedirectURIs: []string{"https://example.com/foo"},
+					Resources:    []string{"https://api.example.com#v1"},
+				},
+			},
+			supportedResponseTypes: []string{"code"},
+			queryParams: map[string]string{
+				"client_id":     "foo",
+				"redirect_uri":  "https://example.com/foo",
+				"response_type": "code",
+				"scope":         "openid email profile",
+				"resource":      "https://api.example.com#v1",
+			},
+			expectedError: &redirectedAuthErr{Type: errInvalidTarget},
+		},
 	}
 
 	for _, tc := range tests {
@@ -1049,6 +1109,7 @@ func TestNewIDTokenUsesStoredAlgorithmUntilNextRotation(t *testing.T) {
 	require.NoError(t, err)
 
 	s := &Server{
+		storage:          store,
 		signer:           sig,
 		issuerURL:        *issuerURL,
 		logger:           logger,
@@ -1139,6 +1200,7 @@ func TestNewIDTokenContainsJTI(t *testing.T) {
 	require.NoError(t, err)
 
 	s := &Server{
+		storage:          store,
 		signer:           sig,
 		issuerURL:        *issuerURL,
 		logger:           logger,
@@ -0,0 +1,164 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sort"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/dexidp/dex/storage"
+)
+
+// OfflineSessionPolicy controls how long offline sessions are retained.
+// Pruning runs alongside garbage collection.
+type OfflineSessionPolicy struct {
+	// Storage deletes offline sessions left without refresh tokens. Usually
+	// the same storage the server uses.
+	Storage storage.OfflineSessionsStorage
+
+	validIfNotUsedFor time.Duration // interval after which an unused refresh token is pruned
+	maxPerUserClient  int           // number of refresh tokens kept per user and client
+}
+
+func NewOfflineSessionPolicy(logger *slog.Logger, validIfNotUsedFor string, maxPerUserClient int) (*OfflineSessionPolicy, error) {
+	p := OfflineSessionPolicy{}
+
+	if validIfNotUsedFor != "" {
+		d, err := time.ParseDuration(validIfNotUsedFor)
+		if err != nil {
+			return nil, fmt.Errorf("invalid config value %q for offline sessions valid if not used for: %v", validIfNotUsedFor, err)
+		}
+		if d < 0 {
+			return nil, fmt.Errorf("invalid config value %q for offline sessions valid if not used for: must not be negative", validIfNotUsedFor)
+		}
+		p.validIfNotUsedFor = d
+		logger.Info("config offline sessions", "valid_if_not_used_for", validIfNotUsedFor)
+	}
+
+	if maxPerUserClient < 0 {
+		return nil, fmt.Errorf("invalid config value %d for offline sessions max per user and client: must not be negative", maxPerUserClient)
+	}
+	if maxPerUserClient > 0 {
+		p.maxPerUserClient = maxPerUserClient
+		logger.Info("config offline sessions", "max_per_user_client", maxPerUserClient)
+	}
+
+	return &p, nil
+}
+
+func (p *OfflineSessionPolicy) expiredBecauseUnused(now, lastUsed time.Time) bool {
+	if p.validIfNotUsedFor == 0 {
+		return false // expiration disabled
+	}
+	return now.After(lastUsed.Add(p.validIfNotUsedFor))
+}
+
+func newOfflineSessionsGauge() *prometheus.GaugeVec {
+	return prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "offline_sessions",
+		Help: "Number of refresh tokens retained after the last offline session pruning run.",
+	}, []string{"connector_id"})
+}
+
+// pruneOfflineSessions deletes refresh tokens that have not been used for
+// longer than the policy allows and, for each user and client, evicts the
+// least recently used tokens over the configured cap. Offline sessions left
+// without any refresh token are deleted as well. It returns the number of
+// deleted tokens and sessions.
+func (s *Server) pruneOfflineSessions(ctx context.Context, now time.Time) (tokensDeleted, sessionsDeleted int, err error) {
+	p := s.offlineSessionPolicy
+
+	tokens, err := s.storage.ListRefreshTokens(ctx)
+	if err != nil {
+		return 0, 0, fmt.Errorf("list refresh tokens: %v", err)
+	}
+
+	type userClient struct{ userID, clientID string }
+
+	var prune []storage.RefreshToken
+	kept := make(map[userClient][]storage.RefreshToken)
+	for _, t := range tokens {
+		if p.expiredBecauseUnused(now, t.LastUsed) {
+			prune = append(prune, t)
+			continue
+		}
+		k := userClient{t.Claims.UserID, t.ClientID}
+		kept[k] = append(kept[k], t)
+	}
+
+	counts := make(map[string]int)
+	for _, ts := range kept {
+		if p.maxPerUserClient > 0 && len(ts) > p.maxPerUserClient {
+			sort.Slice(ts, func(i, j int) bool { return ts[i].LastUsed.After(ts[j].LastUsed) })
+			prune = append(prune, ts[p.maxPerUserClient:]...)
+			ts = ts[:p.maxPerUserClient]
+		}
+		for _, t := range ts {
+			counts[t.ConnectorID]++
+		}
+	}
+
+	for _, t := range prune {
+		if err := s.revokeRefreshToken(ctx, t); err != nil {
+			s.logger.ErrorContext(ctx, "offline sessions: failed to prune refresh token",
+				"token_id", t.ID, "client_id", t.ClientID, "connector_id", t.ConnectorID, "err", err)
+			counts[t.ConnectorID]++
+			continue
+		}
+		tokensDeleted++
+	}
+
+	// Sessions without refresh tokens only hold connector data, which the
+	// token endpoint restores from the auth code when it issues a new one.
+	sessions, err := s.storage.ListOfflineSessions(ctx)
+	if err != nil {
+		return tokensDeleted, 0, fmt.Errorf("list offline sessions: %v", err)
+	}
+	for _, o := range sessions {
+		if len(o.Refresh) > 0 {
+			continue
+		}
+		// A login may add a refresh token to the session meanwhile.
+		deleted, err := p.Storage.DeleteOfflineSessionsIfEmpty(ctx, o.UserID, o.ConnID)
+		if err != nil && !errors.Is(err, storage.ErrNotFound) {
+			s.logger.ErrorContext(ctx, "offline sessions: failed to delete empty offline session",
+				"user_id", o.UserID, "connector_id", o.ConnID, "err", err)
+			continue
+		}
+		if deleted {
+			sessionsDeleted++
+		}
+	}
+
+	if s.offlineSessionsGauge != nil {
+		s.offlineSessionsGauge.Reset()
+		for connID, n := range counts {
+			s.offlineSessionsGauge.WithLabelValues(connID).Set(float64(n))
+		}
+	}
+
+	return tokensDeleted, sessionsDeleted, nil
+}
+
+// revokeRefreshToken drops the offline session's reference to the token, if
+// it still points to it, and deletes the token itself.
+func (s *Server) revokeRefreshToken(ctx context.Context, t storage.RefreshToken) error {
+	err := s.storage.UpdateOfflineSessions(ctx, t.Claims.UserID, t.ConnectorID, func(old storage.OfflineSessions) (storage.OfflineSessions, error) {
+		if ref, ok := old.Refresh[t.ClientID]; ok && ref.ID == t.ID {
+			delete(old.Refresh, t.ClientID)
+		}
+		return old, nil
+	})
+	if err != nil && !errors.Is(err, storage.ErrNotFound) {
+		return fmt.Errorf("update offline sessions: %v", err)
+	}
+
+	if err := s.storage.DeleteRefresh(ctx, t.ID); err != nil && !errors.Is(err, storage.ErrNotFound) {
+		return fmt.Errorf("delete refresh token: %v", err)
+	}
+	return nil
+}
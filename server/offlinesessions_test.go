@@ -0,0 +1,107 @@
+package server
+
+import (
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dexidp/dex/storage"
+)
+
+func TestNewOfflineSessionPolicy(t *testing.T) {
+	logger := slog.New(slog.DiscardHandler)
+
+	p, err := NewOfflineSessionPolicy(logger, "720h", 3)
+	require.NoError(t, err)
+	require.Equal(t, 720*time.Hour, p.validIfNotUsedFor)
+	require.Equal(t, 3, p.maxPerUserClient)
+
+	_, err = NewOfflineSessionPolicy(logger, "forever", 0)
+	require.Error(t, err)
+	_, err = NewOfflineSessionPolicy(logger, "-1h", 0)
+	require.Error(t, err)
+	_, err = NewOfflineSessionPolicy(logger, "", -1)
+	require.Error(t, err)
+}
+
+func TestPruneOfflineSessions(t *testing.T) {
+	ctx := t.Context()
+	now := time.Now().UTC().Round(time.Second)
+
+	registry := prometheus.NewRegistry()
+	httpServer, s := newTestServer(t, func(c *Config) {
+		c.OfflineSessionPolicy = &OfflineSessionPolicy{
+			Storage:           c.Storage.(storage.OfflineSessionsStorage),
+			validIfNotUsedFor: 24 * time.Hour,
+			maxPerUserClient:  2,
+		}
+		c.PrometheusRegistry = registry
+	})
+	defer httpServer.Close()
+
+	newToken := func(id, userID, clientID, connID string, lastUsed time.Time) storage.RefreshToken {
+		return storage.RefreshToken{
+			ID:          id,
+			Token:       "token-" + id,
+			ClientID:    clientID,
+			ConnectorID: connID,
+			CreatedAt:   lastUsed,
+			LastUsed:    lastUsed,
+			Claims:      storage.Claims{UserID: userID},
+			Nonce:       "foo",
+			Scopes:      []string{"openid", "offline_access"},
+		}
+	}
+	tokens := []storage.RefreshToken{
+		newToken("stale", "u1", "c1", "conn-a", now.Add(-48*time.Hour)),
+		newToken("oldest", "u1", "c2", "conn-a", now.Add(-3*time.Hour)),
+		newToken("older", "u1", "c2", "conn-b", now.Add(-2*time.Hour)),
+		newToken("newest", "u1", "c2", "conn-a", now.Add(-time.Hour)),
+		newToken("other", "u2", "c1", "conn-b", now.Add(-time.Hour)),
+	}
+	for _, tok := range tokens {
+		require.NoError(t, s.storage.CreateRefresh(ctx, tok))
+	}
+	require.NoError(t, s.storage.CreateOfflineSessions(ctx, storage.OfflineSessions{
+		UserID: "u1",
+		ConnID: "conn-a",
+		Refresh: map[string]*storage.RefreshTokenRef{
+			"c1": {ID: "stale", ClientID: "c1"},
+			"c2": {ID: "newest", ClientID: "c2"},
+		},
+	}))
+	require.NoError(t, s.storage.CreateOfflineSessions(ctx, storage.OfflineSessions{
+		UserID:  "u3",
+		ConnID:  "conn-a",
+		Refresh: map[string]*storage.RefreshTokenRef{},
+	}))
+
+	tokensDeleted, sessionsDeleted, err := s.pruneOfflineSessions(ctx, now)
+	require.NoError(t, err)
+	require.Equal(t, 2, tokensDeleted)
+	require.Equal(t, 1, sessionsDeleted)
+
+	for _, id := range []string{"stale", "oldest"} {
+		_, err := s.storage.GetRefresh(ctx, id)
+		require.ErrorIs(t, err, storage.ErrNotFound, id)
+	}
+	for _, id := range []string{"older", "newest", "other"} {
+		_, err := s.storage.GetRefresh(ctx, id)
+		require.NoError(t, err, id)
+	}
+
+	session, err := s.storage.GetOfflineSessions(ctx, "u1", "conn-a")
+	require.NoError(t, err)
+	require.NotContains(t, session.Refresh, "c1")
+	require.Equal(t, "newest", session.Refresh["c2"].ID)
+
+	_, err = s.storage.GetOfflineSessions(ctx, "u3", "conn-a")
+	require.ErrorIs(t, err, storage.ErrNotFound)
+
+	require.Equal(t, 1.0, testutil.ToFloat64(s.offlineSessionsGauge.WithLabelValues("conn-a")))
+	require.Equal(t, 2.0, testutil.ToFloat64(s.offlineSessionsGauge.WithLabelValues("conn-b")))
+}
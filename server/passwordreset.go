@@ -0,0 +1,247 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/dexidp/dex/pkg/events"
+	"github.com/dexidp/dex/pkg/mailer"
+	"github.com/dexidp/dex/storage"
+)
+
+// minPasswordLength is the shortest password accepted when resetting one or
+// registering.
+const minPasswordLength = 8
+
+// defaultPasswordResetRateLimit allows a few reset requests per email address
+// and client IP, then one every ten minutes.
+var defaultPasswordResetRateLimit = RateLimit{Rate: 1.0 / 600, Burst: 3}
+
+// PasswordResetConfig enables the "forgot password" flow of the local
+// connector: users request a reset link by email and pick a new password.
+type PasswordResetConfig struct {
+	// Mailer delivers the reset links.
+	Mailer mailer.Mailer
+
+	// Key signs the reset tokens. If empty, a random key is generated, so links
+	// stop working on restart and aren't accepted by other dex instances.
+	Key []byte
+
+	// TokenValidFor is how long a reset link can be used. Defaults to 1 hour.
+	TokenValidFor time.Duration
+
+	// RateLimit limits reset requests per email address and per client IP.
+	// Defaults to a burst of 3 and one request every ten minutes.
+	RateLimit RateLimit
+}
+
+// newPasswordResetToken returns a signed reset token for a password. The token
+// is bound to the current hash, so it can be used once and stops working after
+// any other password change.
+func (s *Server) newPasswordResetToken(p storage.Password) string {
+	expiry := s.now().Add(s.passwordReset.TokenValidFor)
+	return newEmailToken(s.passwordReset.Key, "password_reset", p.Email, expiry, string(p.Hash))
+}
+
+// verifyPasswordResetToken returns the password a reset token was issued for.
+func (s *Server) verifyPasswordResetToken(ctx context.Context, token string) (storage.Password, error) {
+	t, err := parseEmailToken(token, s.now())
+	if err != nil {
+		return storage.Password{}, err
+	}
+	p, err := s.storage.GetPassword(ctx, t.email)
+	if err != nil {
+		if err == storage.ErrNotFound {
+			return storage.Password{}, errInvalidEmailToken
+		}
+		return storage.Password{}, err
+	}
+	if !t.verify(s.passwordReset.Key, "password_reset", string(p.Hash)) {
+		return storage.Password{}, errInvalidEmailToken
+	}
+	return p, nil
+}
+
+// auditPasswordReset records a step of the password reset flow. An empty
+// reason means the step succeeded.
+func (s *Server) auditPasswordReset(ctx context.Context, r *http.Request, event, email, reason string) {
+	outcome := "succeeded"
+	if reason != "" {
+		outcome = "failed"
+	}
+	s.logger.InfoContext(ctx, "password reset",
+		"event", event, "outcome", outcome, "reason", reason,
+		"email", email, "client_ip", rateLimitIP(r))
+	s.emitEvent(ctx, events.TypePasswordReset, "", map[string]any{
+		"event":     event,
+		"outcome":   outcome,
+		"reason":    reason,
+		"email":     email,
+		"client_ip": rateLimitIP(r),
+	})
+}
+
+// localLoginURL returns the password login page of the auth request a
+// self-service flow was started from, if any.
+func (s *Server) localLoginURL(authReqID string) string {
+	if authReqID == "" {
+		return ""
+	}
+	return s.absPath("/auth", LocalConnector, "login") + "?" + url.Values{"state": {authReqID}}.Encode()
+}
+
+// handlePasswordResetRequest shows the form to request a reset link and sends
+// the link. The response is the same whether or not the address has an
+// account, so the form can't be used to find out who has one.
+func (s *Server) handlePasswordResetRequest(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	backLink := s.localLoginURL(r.URL.Query().Get("state"))
+
+	switch r.Method {
+	case http.MethodGet:
+		if err := s.templates().passwordReset(r, w, r.URL.String(), backLink, false); err != nil {
+			s.logger.ErrorContext(ctx, "server template error", "err", err)
+		}
+	case http.MethodPost:
+		email := strings.TrimSpace(r.FormValue("email"))
+		if email == "" {
+			s.renderError(r, w, http.StatusBadRequest, "No email address provided.")
+			return
+		}
+
+		ok, retryAfter := s.allowSelfService(ctx, "password_reset", s.passwordReset.RateLimit,
+			"password-reset-ip:"+rateLimitIP(r), "password-reset-email:"+strings.ToLower(email))
+		if !ok {
+			s.auditPasswordReset(ctx, r, "requested", email, "rate limited")
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+			s.renderError(r, w, http.StatusTooManyRequests, "Too many requests. Please try again later.")
+			return
+		}
+
+		p, err := s.storage.GetPassword(ctx, email)
+		switch {
+		case err == storage.ErrNotFound:
+			s.auditPasswordReset(ctx, r, "requested", email, "unknown email")
+		case err != nil:
+			s.logger.ErrorContext(ctx, "failed to get password", "err", err)
+			s.renderError(r, w, http.StatusInternalServerError, "Database error.")
+			return
+		default:
+			s.auditPasswordReset(ctx, r, "requested", p.Email, "")
+			s.sendPasswordResetLink(ctx, p)
+		}
+
+		if err := s.templates().passwordReset(r, w, r.URL.String(), backLink, true); err != nil {
+			s.logger.ErrorContext(ctx, "server template error", "err", err)
+		}
+	default:
+		s.renderError(r, w, http.StatusBadRequest, "Unsupported request method.")
+	}
+}
+
+// sendPasswordResetLink mails a reset link in the background, so the time the
+// mailer takes doesn't reveal whether an address has an account.
+func (s *Server) sendPasswordResetLink(ctx context.Context, p storage.Password) {
+	link := s.absURL("/password/reset/confirm") + "?" + url.Values{"token": {s.newPasswordResetToken(p)}}.Encode()
+	msg := mailer.Message{
+		To:      p.Email,
+		Subject: "Reset your password",
+		Body: fmt.Sprintf("Someone asked to reset the password of your account at %s.\n\n"+
+			"Open the link below within %s to choose a new password:\n\n%s\n\n"+
+			"If you didn't ask for this, you can ignore this email.\n",
+			s.issuerURL.String(), s.passwordReset.TokenValidFor, link),
+	}
+
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), time.Minute)
+	go func() {
+		defer cancel()
+		if err := s.passwordReset.Mailer.Send(ctx, msg); err != nil {
+			s.logger.ErrorContext(ctx, "failed to send password reset email", "err", err)
+		}
+	}()
+}
+
+// handlePasswordReset shows the form to choose a new password for a reset
+// token and stores the new password.
+func (s *Server) handlePasswordReset(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	token := r.FormValue("token")
+
+	p, err := s.verifyPasswordResetToken(ctx, token)
+	if err != nil {
+		if err != errInvalidEmailToken {
+			s.logger.ErrorContext(ctx, "failed to get password", "err", err)
+			s.renderError(r, w, http.StatusInternalServerError, "Database error.")
+			return
+		}
+		if r.Method == http.MethodPost {
+			s.auditPasswordReset(ctx, r, "completed", "", "invalid token")
+		}
+		s.renderError(r, w, http.StatusBadRequest, "This password reset link is invalid or has expired.")
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		if err := s.templates().passwordResetConfirm(r, w, token, "", false); err != nil {
+			s.logger.ErrorContext(ctx, "server template error", "err", err)
+		}
+	case http.MethodPost:
+		password := r.FormValue("password")
+		var invalid string
+		switch {
+		case len(password) < minPasswordLength:
+			invalid = "Password is too short."
+		case password != r.FormValue("confirm_password"):
+			invalid = "Passwords don't match."
+		}
+		if invalid != "" {
+			if err := s.templates().passwordResetConfirm(r, w, token, invalid, false); err != nil {
+				s.logger.ErrorContext(ctx, "server template error", "err", err)
+			}
+			return
+		}
+
+		hash, err := bcrypt.GenerateFromPassword([]byte(password), recCost)
+		if err != nil {
+			s.logger.ErrorContext(ctx, "failed to hash password", "err", err)
+			s.renderError(r, w, http.StatusInternalServerError, "Internal server error.")
+			return
+		}
+		updater := func(old storage.Password) (storage.Password, error) {
+			// The token is bound to the hash it was issued for; a concurrent
+			// reset with the same token must not succeed twice.
+			if string(old.Hash) != string(p.Hash) {
+				return old, errInvalidEmailToken
+			}
+			old.Hash = hash
+			return old, nil
+		}
+		if err := s.storage.UpdatePassword(ctx, p.Email, updater); err != nil {
+			if err == errInvalidEmailToken {
+				s.auditPasswordReset(ctx, r, "completed", p.Email, "invalid token")
+				s.renderError(r, w, http.StatusBadRequest, "This password reset link is invalid or has expired.")
+				return
+			}
+			s.auditPasswordReset(ctx, r, "completed", p.Email, "storage error")
+			s.logger.ErrorContext(ctx, "failed to update password", "err", err)
+			s.renderError(r, w, http.StatusInternalServerError, "Database error.")
+			return
+		}
+
+		s.auditPasswordReset(ctx, r, "completed", p.Email, "")
+		if err := s.templates().passwordResetConfirm(r, w, "", "", true); err != nil {
+			s.logger.ErrorContext(ctx, "server template error", "err", err)
+		}
+	default:
+		s.renderError(r, w, http.StatusBadRequest, "Unsupported request method.")
+	}
+}
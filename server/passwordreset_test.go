@@ -0,0 +1,136 @@
+package server
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/dexidp/dex/pkg/mailer"
+	"github.com/dexidp/dex/storage"
+)
+
+type testMailer chan mailer.Message
+
+func (m testMailer) Send(_ context.Context, msg mailer.Message) error {
+	m <- msg
+	return nil
+}
+
+func TestPasswordReset(t *testing.T) {
+	ctx := t.Context()
+	mails := make(testMailer, 10)
+	httpServer, s := newTestServer(t, func(c *Config) {
+		c.PasswordReset = &PasswordResetConfig{Mailer: mails}
+	})
+	defer httpServer.Close()
+
+	hash, err := bcrypt.GenerateFromPassword([]byte("password"), bcrypt.DefaultCost)
+	require.NoError(t, err)
+	require.NoError(t, s.storage.CreatePassword(ctx, storage.Password{
+		Email:    "jane@example.com",
+		Hash:     hash,
+		Username: "jane",
+		UserID:   "1",
+	}))
+
+	post := func(path string, vals url.Values) (int, string) {
+		resp, err := http.PostForm(httpServer.URL+path, vals)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		return resp.StatusCode, string(body)
+	}
+
+	// Unknown addresses get the same response, but no email.
+	code, body := post("/password/reset", url.Values{"email": {"john@example.com"}})
+	require.Equal(t, http.StatusOK, code)
+	require.Contains(t, body, "If an account exists")
+
+	code, _ = post("/password/reset", url.Values{"email": {"jane@example.com"}})
+	require.Equal(t, http.StatusOK, code)
+
+	var msg mailer.Message
+	select {
+	case msg = <-mails:
+	case <-time.After(5 * time.Second):
+		t.Fatal("no password reset email sent")
+	}
+	require.Equal(t, "jane@example.com", msg.To)
+	link := regexp.MustCompile(`https?://\S+token=\S+`).FindString(msg.Body)
+	u, err := url.Parse(link)
+	require.NoError(t, err)
+	token := u.Query().Get("token")
+	require.NotEmpty(t, token)
+
+	resp, err := http.Get(link)
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	code, body = post("/password/reset/confirm", url.Values{"token": {token}, "password": {"new-password"}, "confirm_password": {"other-password"}})
+	require.Equal(t, http.StatusBadRequest, code)
+	require.Contains(t, body, "Passwords don&#39;t match.")
+
+	code, body = post("/password/reset/confirm", url.Values{"token": {token}, "password": {"new-password"}, "confirm_password": {"new-password"}})
+	require.Equal(t, http.StatusOK, code, body)
+
+	p, err := s.storage.GetPassword(ctx, "jane@example.com")
+	require.NoError(t, err)
+	require.NoError(t, bcrypt.CompareHashAndPassword(p.Hash, []byte("new-password")))
+
+	// Reset links can only be used once.
+	code, _ = post("/password/reset/confirm", url.Values{"token": {token}, "password": {"newer-password"}, "confirm_password": {"newer-password"}})
+	require.Equal(t, http.StatusBadRequest, code)
+
+	// Tampered links are rejected.
+	code, _ = post("/password/reset/confirm", url.Values{"token": {strings.Replace(token, ".", ".1", 1)}, "password": {"newer-password"}, "confirm_password": {"newer-password"}})
+	require.Equal(t, http.StatusBadRequest, code)
+}
+
+func TestPasswordResetTokenExpiry(t *testing.T) {
+	now := time.Now()
+	httpServer, s := newTestServer(t, func(c *Config) {
+		c.Now = func() time.Time { return now }
+		c.PasswordReset = &PasswordResetConfig{Mailer: make(testMailer, 1)}
+	})
+	defer httpServer.Close()
+
+	p := storage.Password{Email: "jane@example.com", Hash: []byte("hash"), Username: "jane", UserID: "1"}
+	require.NoError(t, s.storage.CreatePassword(t.Context(), p))
+
+	token := s.newPasswordResetToken(p)
+	_, err := s.verifyPasswordResetToken(t.Context(), token)
+	require.NoError(t, err)
+
+	now = now.Add(2 * time.Hour)
+	_, err = s.verifyPasswordResetToken(t.Context(), token)
+	require.Equal(t, errInvalidEmailToken, err)
+}
+
+func TestPasswordResetRateLimit(t *testing.T) {
+	httpServer, _ := newTestServer(t, func(c *Config) {
+		c.PasswordReset = &PasswordResetConfig{
+			Mailer:    make(testMailer, 10),
+			RateLimit: RateLimit{Rate: 0.001, Burst: 2},
+		}
+	})
+	defer httpServer.Close()
+
+	var codes []int
+	for range 3 {
+		resp, err := http.PostForm(httpServer.URL+"/password/reset", url.Values{"email": {"jane@example.com"}})
+		require.NoError(t, err)
+		resp.Body.Close()
+		codes = append(codes, resp.StatusCode)
+	}
+	require.Equal(t, []int{http.StatusOK, http.StatusOK, http.StatusTooManyRequests}, codes)
+}
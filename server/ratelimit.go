@@ -0,0 +1,259 @@
+package server
+
+import (
+	"context"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+
+	"github.com/dexidp/dex/storage"
+)
+
+// RateLimit describes a token bucket which refills at Rate requests per second
+// and allows bursts of up to Burst requests. A zero Rate disables the limit.
+type RateLimit struct {
+	Rate  float64
+	Burst int
+}
+
+func (l RateLimit) enabled() bool {
+	return l.Rate > 0
+}
+
+// RateLimitConfig holds the rate limits applied to the authorization, token and
+// device endpoints.
+type RateLimitConfig struct {
+	// Global limits the total number of requests across all clients.
+	Global RateLimit
+	// PerIP limits requests per client IP address.
+	PerIP RateLimit
+	// PerClient limits requests per OAuth2 client ID and client IP address.
+	PerClient RateLimit
+
+	// Limiter keeps track of the buckets. Defaults to Config.RateLimiter.
+	Limiter RateLimiter
+}
+
+// RateLimiter keeps request budgets for arbitrary keys.
+type RateLimiter interface {
+	// Allow consumes a single request from the bucket identified by key. If the
+	// bucket is exhausted it reports false and how long the caller should wait
+	// before retrying.
+	Allow(ctx context.Context, key string, limit RateLimit) (ok bool, retryAfter time.Duration, err error)
+}
+
+// rateLimiterIdleTimeout is how long an unused in-memory bucket is kept around.
+const rateLimiterIdleTimeout = 10 * time.Minute
+
+type memoryRateLimiter struct {
+	now func() time.Time
+
+	mu        sync.Mutex
+	buckets   map[string]*memoryBucket
+	lastSweep time.Time
+}
+
+type memoryBucket struct {
+	limiter  *rate.Limiter
+	lastSeen time.Time
+}
+
+// NewMemoryRateLimiter returns a RateLimiter that keeps its buckets in memory.
+func NewMemoryRateLimiter(now func() time.Time) RateLimiter {
+	if now == nil {
+		now = time.Now
+	}
+	return &memoryRateLimiter{
+		now:     now,
+		buckets: make(map[string]*memoryBucket),
+	}
+}
+
+func (m *memoryRateLimiter) Allow(_ context.Context, key string, limit RateLimit) (bool, time.Duration, error) {
+	now := m.now()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if now.Sub(m.lastSweep) > time.Minute {
+		for k, b := range m.buckets {
+			if now.Sub(b.lastSeen) > rateLimiterIdleTimeout {
+				delete(m.buckets, k)
+			}
+		}
+		m.lastSweep = now
+	}
+
+	b, ok := m.buckets[key]
+	if !ok || b.limiter.Limit() != rate.Limit(limit.Rate) || b.limiter.Burst() != limit.Burst {
+		b = &memoryBucket{limiter: rate.NewLimiter(rate.Limit(limit.Rate), limit.Burst)}
+		m.buckets[key] = b
+	}
+	b.lastSeen = now
+
+	r := b.limiter.ReserveN(now, 1)
+	if !r.OK() {
+		// Burst is smaller than a single request, nothing will ever pass.
+		return false, time.Minute, nil
+	}
+	if delay := r.DelayFrom(now); delay > 0 {
+		r.CancelAt(now)
+		return false, delay, nil
+	}
+	return true, 0, nil
+}
+
+type storageRateLimiter struct {
+	store storage.RateLimitStorage
+	now   func() time.Time
+}
+
+// NewStorageRateLimiter returns a RateLimiter that keeps its buckets in a
+// storage, so that limits are enforced across all dex instances sharing it.
+func NewStorageRateLimiter(store storage.RateLimitStorage, now func() time.Time) RateLimiter {
+	if now == nil {
+		now = time.Now
+	}
+	return &storageRateLimiter{store: store, now: now}
+}
+
+// Allow implements the generic cell rate algorithm, which needs a single
+// timestamp per bucket.
+func (l *storageRateLimiter) Allow(ctx context.Context, key string, limit RateLimit) (bool, time.Duration, error) {
+	now := l.now()
+	interval := time.Duration(float64(time.Second) / limit.Rate)
+	tolerance := interval * time.Duration(limit.Burst)
+
+	var (
+		ok         bool
+		retryAfter time.Duration
+	)
+	err := l.store.UpdateRateLimitBucket(ctx, key, func(b storage.RateLimitBucket) (storage.RateLimitBucket, error) {
+		tat := b.TAT
+		if tat.Before(now) {
+			tat = now
+		}
+		next := tat.Add(interval)
+		if allowAt := next.Add(-tolerance); now.Before(allowAt) {
+			ok, retryAfter = false, allowAt.Sub(now)
+			return b, nil
+		}
+		ok = true
+		b.TAT = next
+		b.Expiry = next
+		return b, nil
+	})
+	if err != nil {
+		return false, 0, err
+	}
+	return ok, retryAfter, nil
+}
+
+// rateLimitClientID extracts the client ID from the basic auth header or the
+// query of a request. The body isn't read, it belongs to the handlers, so
+// client IDs posted in a form are only limited per IP address.
+func rateLimitClientID(r *http.Request) string {
+	if clientID, _, ok := r.BasicAuth(); ok {
+		return clientID
+	}
+	return r.URL.Query().Get("client_id")
+}
+
+// rateLimitIP returns the client address without the port.
+func rateLimitIP(r *http.Request) string {
+	addr := remoteIP(r)
+	if host, _, err := net.SplitHostPort(addr); err == nil {
+		return host
+	}
+	return addr
+}
+
+// allowRequest checks the configured limits for a request. It returns false and
+// the time to wait if any of them are exhausted.
+func (s *Server) allowRequest(r *http.Request) (bool, time.Duration) {
+	c := s.rateLimit
+
+	type check struct {
+		key   string
+		limit RateLimit
+		scope string
+	}
+	ip := rateLimitIP(r)
+	checks := []check{{key: "global", limit: c.Global, scope: "global"}}
+	if c.PerClient.enabled() {
+		// The client isn't authenticated yet, its budget is kept per IP
+		// address so that nobody else can exhaust it.
+		if clientID := rateLimitClientID(r); clientID != "" {
+			checks = append(checks, check{key: "client:" + clientID + "|" + ip, limit: c.PerClient, scope: "client"})
+		}
+	}
+	checks = append(checks, check{key: "ip:" + ip, limit: c.PerIP, scope: "ip"})
+
+	for _, ch := range checks {
+		if !ch.limit.enabled() {
+			continue
+		}
+		ok, retryAfter, err := c.Limiter.Allow(r.Context(), ch.key, ch.limit)
+		if err != nil {
+			// Fail open: an unavailable limiter backend must not take logins down.
+			s.logger.ErrorContext(r.Context(), "rate limiter failed", "scope", ch.scope, "err", err)
+			continue
+		}
+		if !ok {
+			if s.rateLimitedRequests != nil {
+				s.rateLimitedRequests.WithLabelValues(ch.scope).Inc()
+			}
+			s.logger.InfoContext(r.Context(), "request rate limited", "scope", ch.scope, "path", r.URL.Path)
+			return false, retryAfter
+		}
+	}
+	return true, 0
+}
+
+// withRateLimit wraps a handler with the configured rate limits. Browser facing
+// endpoints render the error page, API endpoints return an OAuth2 error body.
+func (s *Server) withRateLimit(api bool, h http.HandlerFunc) http.HandlerFunc {
+	if s.rateLimit == nil {
+		return h
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		ok, retryAfter := s.allowRequest(r)
+		if ok {
+			h(w, r)
+			return
+		}
+
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+		if api {
+			s.tokenErrHelper(w, errTemporarilyUnavailable, "Too many requests.", http.StatusTooManyRequests)
+			return
+		}
+		s.renderError(r, w, http.StatusTooManyRequests, "Too many requests. Please try again later.")
+	}
+}
+
+// allowSelfService checks the limit of a self-service flow, such as password
+// resets, for each of keys. It returns false and the time to wait if any of
+// them is exhausted.
+func (s *Server) allowSelfService(ctx context.Context, scope string, limit RateLimit, keys ...string) (bool, time.Duration) {
+	for _, key := range keys {
+		ok, retryAfter, err := s.selfServiceLimiter.Allow(ctx, key, limit)
+		if err != nil {
+			// Fail open, like the request rate limits.
+			s.logger.ErrorContext(ctx, "rate limiter failed", "scope", scope, "err", err)
+			continue
+		}
+		if !ok {
+			if s.rateLimitedRequests != nil {
+				s.rateLimitedRequests.WithLabelValues(scope).Inc()
+			}
+			return false, retryAfter
+		}
+	}
+	return true, 0
+}
@@ -0,0 +1,113 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/dexidp/dex/storage"
+	"github.com/dexidp/dex/storage/memory"
+)
+
+func TestMemoryRateLimiter(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	limiter := NewMemoryRateLimiter(func() time.Time { return now })
+	limit := RateLimit{Rate: 1, Burst: 2}
+
+	for i := 0; i < 2; i++ {
+		ok, _, err := limiter.Allow(t.Context(), "ip:10.0.0.1", limit)
+		require.NoError(t, err)
+		require.True(t, ok, "request %d should be allowed within burst", i)
+	}
+
+	ok, retryAfter, err := limiter.Allow(t.Context(), "ip:10.0.0.1", limit)
+	require.NoError(t, err)
+	require.False(t, ok)
+	require.Equal(t, time.Second, retryAfter)
+
+	// Other keys have their own bucket.
+	ok, _, err = limiter.Allow(t.Context(), "ip:10.0.0.2", limit)
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	now = now.Add(time.Second)
+	ok, _, err = limiter.Allow(t.Context(), "ip:10.0.0.1", limit)
+	require.NoError(t, err)
+	require.True(t, ok)
+}
+
+func TestRateLimitedEndpoints(t *testing.T) {
+	httpServer, s := newTestServer(t, func(c *Config) {
+		c.RateLimit = &RateLimitConfig{
+			PerClient: RateLimit{Rate: 0.001, Burst: 1},
+		}
+	})
+	defer httpServer.Close()
+
+	postToken := func(clientID, remoteAddr string) *httptest.ResponseRecorder {
+		body := url.Values{"grant_type": {"client_credentials"}}
+		req := httptest.NewRequest(http.MethodPost, "/token", strings.NewReader(body.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req.SetBasicAuth(clientID, "secret")
+		req.RemoteAddr = remoteAddr
+		rr := httptest.NewRecorder()
+		s.ServeHTTP(rr, req)
+		return rr
+	}
+
+	rr := postToken("foo", "192.0.2.1:1234")
+	require.NotEqual(t, http.StatusTooManyRequests, rr.Code)
+
+	rr = postToken("foo", "192.0.2.1:1234")
+	require.Equal(t, http.StatusTooManyRequests, rr.Code)
+	require.NotEmpty(t, rr.Header().Get("Retry-After"))
+	require.Contains(t, rr.Body.String(), errTemporarilyUnavailable)
+
+	rr = postToken("bar", "192.0.2.1:1234")
+	require.NotEqual(t, http.StatusTooManyRequests, rr.Code)
+
+	// Requests of other addresses don't use up the budget of the client.
+	rr = postToken("foo", "192.0.2.2:1234")
+	require.NotEqual(t, http.StatusTooManyRequests, rr.Code)
+
+	req := httptest.NewRequest(http.MethodGet, "/auth?client_id=foo", nil)
+	req.RemoteAddr = "192.0.2.1:1234"
+	rr = httptest.NewRecorder()
+	s.ServeHTTP(rr, req)
+	require.Equal(t, http.StatusTooManyRequests, rr.Code)
+}
+
+func TestStorageRateLimiter(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	store := memory.New(newLogger(t)).(storage.RateLimitStorage)
+	clock := func() time.Time { return now }
+	// Two instances sharing the storage share the budget.
+	a := NewStorageRateLimiter(store, clock)
+	b := NewStorageRateLimiter(store, clock)
+	limit := RateLimit{Rate: 1, Burst: 2}
+
+	for i, limiter := range []RateLimiter{a, b} {
+		ok, _, err := limiter.Allow(t.Context(), "ip:10.0.0.1", limit)
+		require.NoError(t, err)
+		require.True(t, ok, "request %d should be allowed within burst", i)
+	}
+
+	ok, retryAfter, err := a.Allow(t.Context(), "ip:10.0.0.1", limit)
+	require.NoError(t, err)
+	require.False(t, ok)
+	require.Equal(t, time.Second, retryAfter)
+
+	ok, _, err = b.Allow(t.Context(), "ip:10.0.0.2", limit)
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	now = now.Add(time.Second)
+	ok, _, err = b.Allow(t.Context(), "ip:10.0.0.1", limit)
+	require.NoError(t, err)
+	require.True(t, ok)
+}
@@ -7,6 +7,7 @@ import (
 	"log/slog"
 	"net/http"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/dexidp/dex/connector"
@@ -147,11 +148,17 @@ type refreshContext struct {
 	connector     Connector
 	connectorData []byte
 
-	scopes []string
+	scopes    []string
+	resources []string
+
+	// parent is the refresh token this one was exchanged for, if any, and
+	// generation the number of its ancestors.
+	parent     *storage.RefreshToken
+	generation int
 }
 
 // getRefreshTokenFromStorage checks that refresh token is valid and exists in the storage and gets its info
-func (s *Server) getRefreshTokenFromStorage(ctx context.Context, clientID *string, token *internal.RefreshToken) (*refreshContext, *refreshError) {
+func (s *Server) getRefreshTokenFromStorage(ctx context.Context, client *storage.Client, token *internal.RefreshToken) (*refreshContext, *refreshError) {
 	refreshCtx := refreshContext{requestToken: token}
 
 	// Get RefreshToken
@@ -164,9 +171,9 @@ func (s *Server) getRefreshTokenFromStorage(ctx context.Context, clientID *strin
 		return nil, invalidErr
 	}
 
-	// Only check ClientID if it was provided;
-	if clientID != nil && (refresh.ClientID != *clientID) {
-		s.logger.ErrorContext(ctx, "trying to claim token for different client", "client_id", clientID, "refresh_client_id", refresh.ClientID)
+	// Only check the client if it was provided;
+	if client != nil && (refresh.ClientID != client.ID) {
+		s.logger.ErrorContext(ctx, "trying to claim token for different client", "client_id", client.ID, "refresh_client_id", refresh.ClientID)
 		// According to https://datatracker.ietf.org/doc/html/rfc6749#section-5.2 Dex should respond with an
 		//  invalid grant error if token has already been claimed by another client.
 		return nil, &refreshError{msg: errInvalidGrant, desc: invalidErr.desc, code: http.StatusBadRequest}
@@ -180,6 +187,9 @@ func (s *Server) getRefreshTokenFromStorage(ctx context.Context, clientID *strin
 			fallthrough
 		case refresh.ObsoleteToken == "":
 			s.logger.ErrorContext(ctx, "refresh token claimed twice", "token_id", refresh.ID)
+			if isRotatedOut(refresh, token) {
+				s.handleRefreshTokenReuse(ctx, refresh)
+			}
 			return nil, invalidErr
 		}
 	}
@@ -194,6 +204,14 @@ func (s *Server) getRefreshTokenFromStorage(ctx context.Context, clientID *strin
 		return nil, expiredErr
 	}
 
+	if refresh.ParentID != "" {
+		var rerr *refreshError
+		refreshCtx.parent, refreshCtx.generation, rerr = s.refreshTokenParent(ctx, refresh)
+		if rerr != nil {
+			return nil, rerr
+		}
+	}
+
 	refreshCtx.storageToken = &refresh
 
 	// Get Connector
@@ -206,6 +224,10 @@ func (s *Server) getRefreshTokenFromStorage(ctx context.Context, clientID *strin
 		s.logger.ErrorContext(ctx, "connector does not allow refresh token grant", "connector_id", refresh.ConnectorID)
 		return nil, &refreshError{msg: errInvalidRequest, desc: "Connector does not support refresh tokens.", code: http.StatusBadRequest}
 	}
+	if client != nil && !isConnectorAllowed(client.AllowedConnectors, refresh.ConnectorID) {
+		s.logger.ErrorContext(ctx, "connector not allowed for client", "connector_id", refresh.ConnectorID, "client_id", client.ID)
+		return nil, &refreshError{msg: errUnauthorizedClient, desc: "Client is not allowed to use this connector.", code: http.StatusBadRequest}
+	}
 
 	// Get Connector Data
 	session, err := s.storage.GetOfflineSessions(ctx, refresh.Claims.UserID, refresh.ConnectorID)
@@ -296,6 +318,23 @@ func (s *Server) updateOfflineSession(ctx context.Context, refresh *storage.Refr
 		return old, nil
 	}
 
+	// Writes which would only bump the LastUsed time stamp are batched, so a
+	// busy client does not rewrite the same offline session on every refresh.
+	writeKey := refresh.Claims.UserID + "\x00" + refresh.ConnectorID + "\x00" + refresh.ClientID
+	if len(ident.ConnectorData) == 0 && !s.offlineSessionWrites.due(writeKey, lastUsed) {
+		// The token still has to be the one the offline session refers to.
+		session, err := s.storage.GetOfflineSessions(ctx, refresh.Claims.UserID, refresh.ConnectorID)
+		if err != nil {
+			s.logger.ErrorContext(ctx, "failed to get offline session", "err", err)
+			return newInternalServerError()
+		}
+		if ref := session.Refresh[refresh.ClientID]; ref == nil || ref.ID != refresh.ID {
+			s.logger.ErrorContext(ctx, "failed to update offline session", "err", "refresh token invalid")
+			return newInternalServerError()
+		}
+		return nil
+	}
+
 	// Update LastUsed time stamp in refresh token reference object
 	// in offline session for the user.
 	err := s.storage.UpdateOfflineSessions(ctx, refresh.Claims.UserID, refresh.ConnectorID, offlineSessionUpdater)
@@ -303,10 +342,23 @@ func (s *Server) updateOfflineSession(ctx context.Context, refresh *storage.Refr
 		s.logger.ErrorContext(ctx, "failed to update offline session", "err", err)
 		return newInternalServerError()
 	}
+	s.offlineSessionWrites.mark(writeKey, lastUsed)
 
 	return nil
 }
 
+func claimsIdentity(claims storage.Claims) connector.Identity {
+	return connector.Identity{
+		UserID:            claims.UserID,
+		Username:          claims.Username,
+		PreferredUsername: claims.PreferredUsername,
+		Email:             claims.Email,
+		EmailVerified:     claims.EmailVerified,
+		Groups:            claims.Groups,
+		CustomClaims:      claims.CustomClaims,
+	}
+}
+
 // updateRefreshToken updates refresh token and offline session in the storage
 func (s *Server) updateRefreshToken(ctx context.Context, rCtx *refreshContext) (*internal.RefreshToken, connector.Identity, *refreshError) {
 	var rerr *refreshError
@@ -314,18 +366,12 @@ func (s *Server) updateRefreshToken(ctx context.Context, rCtx *refreshContext) (
 	newToken := &internal.RefreshToken{
 		Token:     rCtx.requestToken.Token,
 		RefreshId: rCtx.requestToken.RefreshId,
+		Rotation:  rCtx.requestToken.Rotation,
 	}
 
 	lastUsed := s.now()
 
-	ident := connector.Identity{
-		UserID:            rCtx.storageToken.Claims.UserID,
-		Username:          rCtx.storageToken.Claims.Username,
-		PreferredUsername: rCtx.storageToken.Claims.PreferredUsername,
-		Email:             rCtx.storageToken.Claims.Email,
-		EmailVerified:     rCtx.storageToken.Claims.EmailVerified,
-		Groups:            rCtx.storageToken.Claims.Groups,
-	}
+	ident := claimsIdentity(rCtx.storageToken.Claims)
 
 	refreshTokenUpdater := func(old storage.RefreshToken) (storage.RefreshToken, error) {
 		rotationEnabled := s.refreshTokenPolicy.RotationEnabled()
@@ -345,6 +391,9 @@ func (s *Server) updateRefreshToken(ctx context.Context, rCtx *refreshContext) (
 			// Return previously generated token for all requests with an obsolete tokens
 			if old.ObsoleteToken == rCtx.requestToken.Token {
 				newToken.Token = old.Token
+				if old.RotationKey != "" {
+					newToken.Rotation = rCtx.requestToken.Rotation + 1
+				}
 			}
 
 			// Do not update last used time for offline session if token is allowed to be reused
@@ -359,7 +408,12 @@ func (s *Server) updateRefreshToken(ctx context.Context, rCtx *refreshContext) (
 
 			// Issue new refresh token
 			old.ObsoleteToken = old.Token
-			newToken.Token = storage.NewID()
+			if old.RotationKey == "" {
+				// Tokens issued before rotation keys get one now.
+				old.RotationKey = storage.NewID()
+			}
+			newToken.Rotation = rCtx.requestToken.Rotation + 1
+			newToken.Token = refreshTokenValue(old.RotationKey, newToken.Rotation)
 		}
 
 		old.Token = newToken.Token
@@ -371,9 +425,15 @@ func (s *Server) updateRefreshToken(ctx context.Context, rCtx *refreshContext) (
 		// Call  only once if there is a request which is not in the reuse interval.
 		// This is required to avoid multiple calls to the external IdP for concurrent requests.
 		// Dex will call the connector's Refresh method only once if request is not in reuse interval.
-		ident, rerr = s.refreshWithConnector(ctx, rCtx, ident)
-		if rerr != nil {
-			return old, rerr
+		if rCtx.parent != nil {
+			// Children of a token exchange follow the claims of their parent,
+			// which is refreshed with the connector.
+			ident = claimsIdentity(rCtx.parent.Claims)
+		} else {
+			ident, rerr = s.refreshWithConnector(ctx, rCtx, ident)
+			if rerr != nil {
+				return old, rerr
+			}
 		}
 
 		// Update the claims of the refresh token.
@@ -384,6 +444,7 @@ func (s *Server) updateRefreshToken(ctx context.Context, rCtx *refreshContext) (
 		old.Claims.Email = ident.Email
 		old.Claims.EmailVerified = ident.EmailVerified
 		old.Claims.Groups = ident.Groups
+		old.Claims.CustomClaims = ident.CustomClaims
 
 		return old, nil
 	}
@@ -395,14 +456,106 @@ func (s *Server) updateRefreshToken(ctx context.Context, rCtx *refreshContext) (
 		return nil, ident, newInternalServerError()
 	}
 
-	rerr = s.updateOfflineSession(ctx, rCtx.storageToken, ident, lastUsed)
-	if rerr != nil {
-		return nil, ident, rerr
+	// Children of a token exchange aren't referenced by the offline session.
+	if rCtx.parent == nil {
+		rerr = s.updateOfflineSession(ctx, rCtx.storageToken, ident, lastUsed)
+		if rerr != nil {
+			return nil, ident, rerr
+		}
 	}
 
 	return newToken, ident, nil
 }
 
+type refreshResult struct {
+	rCtx     *refreshContext
+	newToken *internal.RefreshToken
+	ident    connector.Identity
+}
+
+// redeemRefreshToken validates the presented refresh token and updates it in the
+// storage. Concurrent requests of the same client address presenting the same
+// token for the same scopes share a single round of storage reads and writes,
+// so refresh storms caused by clients retrying in parallel do not turn into
+// update conflicts. Requests from elsewhere are redeemed on their own, a
+// replayed token doesn't get the result of the legitimate request.
+func (s *Server) redeemRefreshToken(r *http.Request, client storage.Client, token *internal.RefreshToken) (*refreshResult, *refreshError) {
+	clientID := client.ID
+	requestedResources := r.PostForm["resource"]
+	key := strings.Join([]string{clientID, remoteIP(r), token.RefreshId, token.Token, r.PostFormValue("scope"), strings.Join(requestedResources, " ")}, "\x00")
+
+	v, err, _ := s.refreshGroup.Do(key, func() (interface{}, error) {
+		// Do not let the first caller going away fail the requests waiting on it.
+		ctx := context.WithoutCancel(r.Context())
+
+		rCtx, rerr := s.getRefreshTokenFromStorage(ctx, &client, token)
+		if rerr != nil {
+			return nil, rerr
+		}
+
+		rCtx.scopes, rerr = s.getRefreshScopes(r, rCtx.storageToken)
+		if rerr != nil {
+			return nil, rerr
+		}
+
+		resources, err := tokenResources(client, rCtx.storageToken.Resources, requestedResources)
+		if err != nil {
+			desc := fmt.Sprintf("Invalid resource indicator: %v.", err)
+			return nil, &refreshError{msg: errInvalidTarget, desc: desc, code: http.StatusBadRequest}
+		}
+		rCtx.resources = resources
+
+		newToken, ident, rerr := s.updateRefreshToken(ctx, rCtx)
+		if rerr != nil {
+			return nil, rerr
+		}
+		return &refreshResult{rCtx: rCtx, newToken: newToken, ident: ident}, nil
+	})
+	if err != nil {
+		var rerr *refreshError
+		if errors.As(err, &rerr) {
+			return nil, rerr
+		}
+		return nil, newInternalServerError()
+	}
+	return v.(*refreshResult), nil
+}
+
+// offlineSessionLastUsedInterval is the minimum time between two offline session
+// writes which would only update the LastUsed time stamp.
+const offlineSessionLastUsedInterval = time.Minute
+
+// lastUsedWrites remembers when offline sessions were last written.
+type lastUsedWrites struct {
+	mu        sync.Mutex
+	written   map[string]time.Time
+	lastSweep time.Time
+}
+
+func (l *lastUsedWrites) due(key string, now time.Time) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	written, ok := l.written[key]
+	return !ok || now.Sub(written) >= offlineSessionLastUsedInterval
+}
+
+func (l *lastUsedWrites) mark(key string, now time.Time) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.written == nil {
+		l.written = make(map[string]time.Time)
+	}
+	if now.Sub(l.lastSweep) >= offlineSessionLastUsedInterval {
+		for k, t := range l.written {
+			if now.Sub(t) >= offlineSessionLastUsedInterval {
+				delete(l.written, k)
+			}
+		}
+		l.lastSweep = now
+	}
+	l.written[key] = now
+}
+
 // handleRefreshToken handles a refresh token request https://tools.ietf.org/html/rfc6749#section-6
 // this method is the entrypoint for refresh tokens handling
 func (s *Server) handleRefreshToken(w http.ResponseWriter, r *http.Request, client storage.Client) {
@@ -412,21 +565,13 @@ func (s *Server) handleRefreshToken(w http.ResponseWriter, r *http.Request, clie
 		return
 	}
 
-	rCtx, rerr := s.getRefreshTokenFromStorage(r.Context(), &client.ID, token)
+	res, rerr := s.redeemRefreshToken(r, client, token)
 	if rerr != nil {
 		s.refreshTokenErrHelper(w, rerr)
 		return
 	}
-
-	rCtx.scopes, rerr = s.getRefreshScopes(r, rCtx.storageToken)
-	if rerr != nil {
-		s.refreshTokenErrHelper(w, rerr)
-		return
-	}
-
-	newToken, ident, rerr := s.updateRefreshToken(r.Context(), rCtx)
-	if rerr != nil {
-		s.refreshTokenErrHelper(w, rerr)
+	rCtx, newToken, ident := res.rCtx, res.newToken, res.ident
+	if !s.allowTokenRequest(w, r, riskFlowRefresh, ident, rCtx.storageToken.ConnectorID, client.ID, rCtx.scopes) {
 		return
 	}
 
@@ -437,6 +582,7 @@ func (s *Server) handleRefreshToken(w http.ResponseWriter, r *http.Request, clie
 		Email:             ident.Email,
 		EmailVerified:     ident.EmailVerified,
 		Groups:            ident.Groups,
+		CustomClaims:      ident.CustomClaims,
 	}
 
 	authTime := time.Time{}
@@ -450,7 +596,8 @@ func (s *Server) handleRefreshToken(w http.ResponseWriter, r *http.Request, clie
 		authTime = ui.LastLogin
 	}
 
-	accessToken, _, err := s.newAccessToken(r.Context(), client.ID, claims, rCtx.scopes, rCtx.storageToken.Nonce, rCtx.storageToken.ConnectorID, authTime, rCtx.connectorData)
+	opts := tokenOptions{audience: rCtx.storageToken.Audience, resources: rCtx.resources}
+	accessToken, _, err := s.newToken(r.Context(), client.ID, claims, rCtx.scopes, rCtx.storageToken.Nonce, storage.NewID(), "", rCtx.storageToken.ConnectorID, authTime, rCtx.connectorData, opts)
 	if err != nil {
 		s.logger.ErrorContext(r.Context(), "failed to create new access token", "err", err)
 		s.refreshTokenErrHelper(w, newInternalServerError())
@@ -366,3 +366,39 @@ func TestRefreshTokenPolicy(t *testing.T) {
 		require.Equal(t, true, r.CompletelyExpired(lastTime))
 	})
 }
+
+func TestOfflineSessionLastUsedWrites(t *testing.T) {
+	var writes lastUsedWrites
+	t0 := time.Now()
+
+	require.True(t, writes.due("a", t0))
+	writes.mark("a", t0)
+
+	require.False(t, writes.due("a", t0.Add(offlineSessionLastUsedInterval/2)))
+	require.True(t, writes.due("b", t0.Add(offlineSessionLastUsedInterval/2)))
+	require.True(t, writes.due("a", t0.Add(offlineSessionLastUsedInterval)))
+
+	// Stale entries are dropped on the next write.
+	writes.mark("b", t0.Add(2*offlineSessionLastUsedInterval))
+	require.NotContains(t, writes.written, "a")
+}
+
+func TestUpdateOfflineSessionChecksRefreshID(t *testing.T) {
+	httpServer, s := newTestServer(t, nil)
+	defer httpServer.Close()
+	mockRefreshTokenTestStorage(t, s.storage, false)
+
+	ctx := t.Context()
+	now := time.Now()
+	refresh, err := s.storage.GetRefresh(ctx, "test")
+	require.NoError(t, err)
+	s.offlineSessionWrites.mark("1\x00test\x00test", now)
+
+	ident := claimsIdentity(refresh.Claims)
+	require.Nil(t, s.updateOfflineSession(ctx, &refresh, ident, now))
+
+	// A token the offline session doesn't refer to is refused, even if the
+	// write would be skipped.
+	refresh.ID = "replaced"
+	require.NotNil(t, s.updateOfflineSession(ctx, &refresh, ident, now))
+}
@@ -0,0 +1,135 @@
+package server
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/dexidp/dex/connector"
+	"github.com/dexidp/dex/pkg/events"
+	"github.com/dexidp/dex/server/internal"
+	"github.com/dexidp/dex/storage"
+)
+
+// newRefreshTokenValue returns the rotation key and the first value of a new
+// refresh token.
+func newRefreshTokenValue() (key, value string) {
+	key = storage.NewID()
+	return key, refreshTokenValue(key, 0)
+}
+
+// refreshTokenValue derives the value a refresh token has after the given
+// number of rotations. Deriving the values instead of picking random ones lets
+// dex recognize every value it ever issued for the token, without storing
+// them.
+func refreshTokenValue(key string, rotation uint32) string {
+	return computeHMAC([]byte(key), "refresh_token", strconv.FormatUint(uint64(rotation), 10))
+}
+
+// isRotatedOut reports whether token holds a value the refresh token had
+// before one of its rotations.
+func isRotatedOut(refresh storage.RefreshToken, token *internal.RefreshToken) bool {
+	if token.Token == "" || token.Token == refresh.Token {
+		return false
+	}
+	if token.Token == refresh.ObsoleteToken {
+		return true
+	}
+	// Tokens issued before rotation keys only know their last value.
+	if refresh.RotationKey == "" {
+		return false
+	}
+	return verifyHMAC([]byte(refresh.RotationKey), token.Token, "refresh_token", strconv.FormatUint(uint64(token.Rotation), 10))
+}
+
+// handleRefreshTokenReuse responds to a rotated out refresh token value being
+// presented again after its reuse interval, as configured by the client's
+// reuse policy. Every detection is audited, with or without a policy.
+// Either the client or a thief holds the current token; as dex can't tell
+// which, the policy can revoke the token along with the tokens exchanged for
+// it. Tokens of other clients are left alone.
+func (s *Server) handleRefreshTokenReuse(ctx context.Context, refresh storage.RefreshToken) {
+	var (
+		sessionsRevoked, upstreamRevoked bool
+		reason                           string
+	)
+	defer func() {
+		s.auditRefreshTokenReuse(ctx, refresh, sessionsRevoked, upstreamRevoked, reason)
+	}()
+
+	client, err := s.storage.GetClient(ctx, refresh.ClientID)
+	if err != nil {
+		s.logger.ErrorContext(ctx, "refresh token reuse: failed to get client", "client_id", refresh.ClientID, "err", err)
+		reason = "failed to get client"
+		return
+	}
+	policy := client.RefreshTokenReuse
+	if policy == nil || !policy.RevokeSessions {
+		return
+	}
+
+	// The connector data of the offline session is the latest, read it
+	// before the token goes away.
+	connectorData := refresh.ConnectorData
+	if session, err := s.storage.GetOfflineSessions(ctx, refresh.Claims.UserID, refresh.ConnectorID); err == nil && len(session.ConnectorData) > 0 {
+		connectorData = session.ConnectorData
+	}
+
+	// Tokens exchanged for this one are revoked with their parent.
+	if err := s.revokeRefreshToken(ctx, refresh); err != nil {
+		s.logger.ErrorContext(ctx, "refresh token reuse: failed to revoke refresh token",
+			"token_id", refresh.ID, "err", err)
+		reason = "refresh token revocation failed"
+		return
+	}
+	sessionsRevoked = true
+
+	if policy.RevokeUpstream {
+		upstreamRevoked, err = s.revokeUpstreamTokens(ctx, refresh.ConnectorID, connectorData)
+		if err != nil {
+			s.logger.ErrorContext(ctx, "refresh token reuse: failed to revoke upstream tokens",
+				"connector_id", refresh.ConnectorID, "err", err)
+			reason = "upstream revocation failed"
+		}
+	}
+}
+
+// revokeUpstreamTokens revokes the user's tokens at the connector's upstream,
+// if the connector supports it. It reports whether tokens were revoked.
+func (s *Server) revokeUpstreamTokens(ctx context.Context, connectorID string, connectorData []byte) (bool, error) {
+	conn, err := s.getConnector(ctx, connectorID)
+	if err != nil {
+		return false, err
+	}
+	revoker, ok := conn.Connector.(connector.TokenRevoker)
+	if !ok || len(connectorData) == 0 {
+		return false, nil
+	}
+	if err := revoker.RevokeTokens(ctx, connectorData); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// auditRefreshTokenReuse records a detected refresh token reuse and what was
+// revoked in response. An empty reason means the response succeeded.
+func (s *Server) auditRefreshTokenReuse(ctx context.Context, refresh storage.RefreshToken, sessionsRevoked, upstreamRevoked bool, reason string) {
+	outcome := "succeeded"
+	if reason != "" {
+		outcome = "failed"
+	}
+	s.logger.WarnContext(ctx, "refresh token reuse detected",
+		"outcome", outcome, "reason", reason,
+		"token_id", refresh.ID, "client_id", refresh.ClientID,
+		"connector_id", refresh.ConnectorID, "user_id", refresh.Claims.UserID,
+		"sessions_revoked", sessionsRevoked, "upstream_revoked", upstreamRevoked)
+	s.emitEvent(ctx, events.TypeRefreshTokenReuse, refresh.Claims.UserID, map[string]any{
+		"outcome":          outcome,
+		"reason":           reason,
+		"token_id":         refresh.ID,
+		"client_id":        refresh.ClientID,
+		"connector_id":     refresh.ConnectorID,
+		"user_id":          refresh.Claims.UserID,
+		"sessions_revoked": sessionsRevoked,
+		"upstream_revoked": upstreamRevoked,
+	})
+}
@@ -0,0 +1,145 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/dexidp/dex/pkg/events"
+	"github.com/dexidp/dex/server/internal"
+	"github.com/dexidp/dex/storage"
+)
+
+func TestRefreshTokenReuse(t *testing.T) {
+	tests := []struct {
+		name        string
+		policy      *storage.RefreshTokenReusePolicy
+		wantRevoked bool
+	}{
+		{name: "No policy"},
+		{name: "Detect only", policy: &storage.RefreshTokenReusePolicy{}},
+		{name: "Revoke sessions", policy: &storage.RefreshTokenReusePolicy{RevokeSessions: true, RevokeUpstream: true}, wantRevoked: true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := t.Context()
+			var sink testEventSink
+			httpServer, s := newTestServer(t, func(c *Config) {
+				c.RefreshTokenPolicy = &RefreshTokenPolicy{rotateRefreshTokens: true}
+				c.Events = &sink
+			})
+			defer httpServer.Close()
+
+			// The token was rotated from "bar" to "testtest".
+			mockRefreshTokenTestStorage(t, s.storage, true)
+			require.NoError(t, s.storage.UpdateClient(ctx, "test", func(old storage.Client) (storage.Client, error) {
+				old.RefreshTokenReuse = tc.policy
+				return old, nil
+			}))
+
+			// Another client's token in the same offline session.
+			other, err := s.storage.GetRefresh(ctx, "test")
+			require.NoError(t, err)
+			other.ID, other.ClientID, other.Token, other.ObsoleteToken = "other", "other", "baz", ""
+			require.NoError(t, s.storage.CreateRefresh(ctx, other))
+			require.NoError(t, s.storage.UpdateOfflineSessions(ctx, "1", "test", func(old storage.OfflineSessions) (storage.OfflineSessions, error) {
+				old.Refresh["other"] = &storage.RefreshTokenRef{ID: "other", ClientID: "other"}
+				return old, nil
+			}))
+
+			tokenData, err := internal.Marshal(&internal.RefreshToken{RefreshId: "test", Token: "bar"})
+			require.NoError(t, err)
+			v := url.Values{"grant_type": {"refresh_token"}, "refresh_token": {tokenData}}
+			req, _ := http.NewRequest(http.MethodPost, s.absURL("/token"), bytes.NewBufferString(v.Encode()))
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			req.SetBasicAuth("test", "barfoo")
+			rr := httptest.NewRecorder()
+			s.ServeHTTP(rr, req)
+			require.Equal(t, http.StatusBadRequest, rr.Code)
+
+			_, err = s.storage.GetRefresh(ctx, "test")
+			if tc.wantRevoked {
+				require.ErrorIs(t, err, storage.ErrNotFound)
+				session, err := s.storage.GetOfflineSessions(ctx, "1", "test")
+				require.NoError(t, err)
+				require.NotContains(t, session.Refresh, "test")
+				require.Contains(t, session.Refresh, "other")
+			} else {
+				require.NoError(t, err)
+			}
+			_, err = s.storage.GetRefresh(ctx, "other")
+			require.NoError(t, err)
+
+			// Reuse is audited even without a policy.
+			require.Len(t, sink, 1)
+			require.Equal(t, events.TypeRefreshTokenReuse, sink[0].Type)
+			require.Equal(t, "1", sink[0].Subject)
+			data := sink[0].Data.(map[string]any)
+			require.Equal(t, tc.wantRevoked, data["sessions_revoked"])
+			require.Equal(t, "succeeded", data["outcome"])
+		})
+	}
+}
+
+func TestRefreshTokenReuseOfOlderRotation(t *testing.T) {
+	ctx := t.Context()
+	httpServer, s := newTestServer(t, func(c *Config) {
+		c.RefreshTokenPolicy = &RefreshTokenPolicy{rotateRefreshTokens: true}
+	})
+	defer httpServer.Close()
+
+	mockRefreshTokenTestStorage(t, s.storage, false)
+	require.NoError(t, s.storage.UpdateClient(ctx, "test", func(old storage.Client) (storage.Client, error) {
+		old.RefreshTokenReuse = &storage.RefreshTokenReusePolicy{RevokeSessions: true}
+		return old, nil
+	}))
+
+	refresh := func(token string) *httptest.ResponseRecorder {
+		v := url.Values{"grant_type": {"refresh_token"}, "refresh_token": {token}}
+		req, _ := http.NewRequest(http.MethodPost, s.absURL("/token"), bytes.NewBufferString(v.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req.SetBasicAuth("test", "barfoo")
+		rr := httptest.NewRecorder()
+		s.ServeHTTP(rr, req)
+		return rr
+	}
+
+	key, value := newRefreshTokenValue()
+	require.NoError(t, s.storage.UpdateRefreshToken(ctx, "test", func(old storage.RefreshToken) (storage.RefreshToken, error) {
+		old.Token, old.RotationKey = value, key
+		return old, nil
+	}))
+	first, err := internal.Marshal(&internal.RefreshToken{RefreshId: "test", Token: value})
+	require.NoError(t, err)
+
+	// Rotate twice, so the first value is no longer the obsolete one.
+	token := first
+	for range 2 {
+		rr := refresh(token)
+		require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+		var res struct {
+			RefreshToken string `json:"refresh_token"`
+		}
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &res))
+		token = res.RefreshToken
+	}
+
+	// A made up value doesn't revoke anything.
+	forged, err := internal.Marshal(&internal.RefreshToken{RefreshId: "test", Token: "forged", Rotation: 1})
+	require.NoError(t, err)
+	require.Equal(t, http.StatusBadRequest, refresh(forged).Code)
+	_, err = s.storage.GetRefresh(ctx, "test")
+	require.NoError(t, err)
+
+	// The first value is two rotations old.
+	require.Equal(t, http.StatusBadRequest, refresh(first).Code)
+	_, err = s.storage.GetRefresh(ctx, "test")
+	require.ErrorIs(t, err, storage.ErrNotFound)
+	require.Equal(t, http.StatusBadRequest, refresh(token).Code)
+}
@@ -0,0 +1,234 @@
+package server
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"math"
+	"mime"
+	"net/http"
+	"net/mail"
+	"slices"
+	"strconv"
+	"strings"
+
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/dexidp/dex/pkg/events"
+	"github.com/dexidp/dex/storage"
+)
+
+// defaultRegistrationRateLimit allows a few sign-ups per client IP, then one
+// every ten minutes.
+var defaultRegistrationRateLimit = RateLimit{Rate: 1.0 / 600, Burst: 5}
+
+// RegistrationConfig enables self-service sign-up for the local connector.
+// Users register on a page linked from the login form, or by posting JSON to
+// the same endpoint.
+//
+// If neither AllowedEmailDomains nor InviteCodes is set, anyone can register.
+type RegistrationConfig struct {
+	// AllowedEmailDomains limits sign-up to email addresses in these domains.
+	AllowedEmailDomains []string
+
+	// InviteCodes limits sign-up to users knowing one of these codes.
+	InviteCodes []string
+
+	// VerifyEmail sends new users a verification link, which marks their
+	// email as verified. Requires email verification to be enabled.
+	VerifyEmail bool
+
+	// RateLimit limits sign-ups per client IP. Defaults to a burst of 5 and
+	// one sign-up every ten minutes.
+	RateLimit RateLimit
+}
+
+// registration is a sign-up submitted through the form or the JSON API.
+type registration struct {
+	Email      string `json:"email"`
+	Username   string `json:"username"`
+	Password   string `json:"password"`
+	InviteCode string `json:"invite_code"`
+}
+
+// validate checks a sign-up against the registration config. It returns a
+// message to show to the user if the sign-up is rejected.
+func (c *RegistrationConfig) validate(reg registration) string {
+	addr, err := mail.ParseAddress(reg.Email)
+	if err != nil || addr.Address != reg.Email {
+		return "Invalid email address."
+	}
+	if len(reg.Password) < minPasswordLength {
+		return "Password is too short."
+	}
+	if len(c.AllowedEmailDomains) > 0 {
+		_, domain, _ := strings.Cut(reg.Email, "@")
+		if !slices.ContainsFunc(c.AllowedEmailDomains, func(allowed string) bool {
+			return strings.EqualFold(allowed, domain)
+		}) {
+			return "Sign-up isn't allowed for this email address."
+		}
+	}
+	if len(c.InviteCodes) > 0 {
+		if !slices.ContainsFunc(c.InviteCodes, func(code string) bool {
+			return subtle.ConstantTimeCompare([]byte(code), []byte(reg.InviteCode)) == 1
+		}) {
+			return "Invalid invite code."
+		}
+	}
+	return ""
+}
+
+// register creates the password of a sign-up. A non-empty message means the
+// sign-up was rejected and should be shown to the user.
+func (s *Server) register(ctx context.Context, r *http.Request, reg registration) (storage.Password, string, error) {
+	reg.Email = strings.TrimSpace(reg.Email)
+	if invalid := s.registration.validate(reg); invalid != "" {
+		s.auditRegistration(ctx, r, reg.Email, invalid)
+		return storage.Password{}, invalid, nil
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(reg.Password), recCost)
+	if err != nil {
+		return storage.Password{}, "", err
+	}
+	username := reg.Username
+	if username == "" {
+		username, _, _ = strings.Cut(reg.Email, "@")
+	}
+	// Nobody has proven to own the email address of a sign-up yet.
+	verified := false
+	p := storage.Password{
+		Email:         reg.Email,
+		Hash:          hash,
+		Username:      username,
+		UserID:        storage.NewID(),
+		EmailVerified: &verified,
+	}
+	if err := s.storage.CreatePassword(ctx, p); err != nil {
+		if err == storage.ErrAlreadyExists {
+			invalid := "An account with this email address already exists."
+			s.auditRegistration(ctx, r, reg.Email, invalid)
+			return storage.Password{}, invalid, nil
+		}
+		return storage.Password{}, "", err
+	}
+	s.auditRegistration(ctx, r, p.Email, "")
+
+	if s.registration.VerifyEmail {
+		if err := s.sendEmailVerificationLink(ctx, p); err != nil {
+			// The account exists, an admin can send a new link later.
+			s.logger.ErrorContext(ctx, "failed to send verification email", "err", err)
+		}
+	}
+	return p, "", nil
+}
+
+// auditRegistration records a sign-up. An empty reason means the account was
+// created.
+func (s *Server) auditRegistration(ctx context.Context, r *http.Request, email, reason string) {
+	outcome := "succeeded"
+	if reason != "" {
+		outcome = "failed"
+	}
+	s.logger.InfoContext(ctx, "registration",
+		"outcome", outcome, "reason", reason, "email", email, "client_ip", rateLimitIP(r))
+	s.emitEvent(ctx, events.TypeRegistration, "", map[string]any{
+		"outcome":   outcome,
+		"reason":    reason,
+		"email":     email,
+		"client_ip": rateLimitIP(r),
+	})
+}
+
+// handleRegister shows the sign-up form and creates accounts from it. Requests
+// with a JSON body are handled as API calls and answered with JSON.
+func (s *Server) handleRegister(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	isAPI := mediaType == "application/json"
+
+	authReqID := r.URL.Query().Get("state")
+	loginLink := s.localLoginURL(authReqID)
+	showInviteCode := len(s.registration.InviteCodes) > 0
+
+	renderForm := func(reg registration, invalid string) {
+		if err := s.templates().register(r, w, r.URL.String(), loginLink, reg.Email, reg.Username, invalid, showInviteCode, false); err != nil {
+			s.logger.ErrorContext(ctx, "server template error", "err", err)
+		}
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		renderForm(registration{}, "")
+	case http.MethodPost:
+		if ok, retryAfter := s.allowSelfService(ctx, "registration", s.registration.RateLimit, "registration-ip:"+rateLimitIP(r)); !ok {
+			s.auditRegistration(ctx, r, "", "rate limited")
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+			if isAPI {
+				s.tokenErrHelper(w, errTemporarilyUnavailable, "Too many requests.", http.StatusTooManyRequests)
+				return
+			}
+			s.renderError(r, w, http.StatusTooManyRequests, "Too many requests. Please try again later.")
+			return
+		}
+
+		var reg registration
+		if isAPI {
+			if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<16)).Decode(&reg); err != nil {
+				s.tokenErrHelper(w, errInvalidRequest, "Invalid JSON body.", http.StatusBadRequest)
+				return
+			}
+		} else {
+			reg = registration{
+				Email:      r.FormValue("email"),
+				Username:   r.FormValue("username"),
+				Password:   r.FormValue("password"),
+				InviteCode: r.FormValue("invite_code"),
+			}
+			if reg.Password != r.FormValue("confirm_password") {
+				renderForm(reg, "Passwords don't match.")
+				return
+			}
+		}
+
+		p, invalid, err := s.register(ctx, r, reg)
+		if err != nil {
+			s.logger.ErrorContext(ctx, "failed to register user", "err", err)
+			if isAPI {
+				s.tokenErrHelper(w, errServerError, "", http.StatusInternalServerError)
+				return
+			}
+			s.renderError(r, w, http.StatusInternalServerError, "Database error.")
+			return
+		}
+		if invalid != "" {
+			if isAPI {
+				s.tokenErrHelper(w, errInvalidRequest, invalid, http.StatusBadRequest)
+				return
+			}
+			renderForm(reg, invalid)
+			return
+		}
+
+		if isAPI {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusCreated)
+			json.NewEncoder(w).Encode(struct {
+				UserID        string `json:"user_id"`
+				Email         string `json:"email"`
+				EmailVerified bool   `json:"email_verified"`
+			}{p.UserID, p.Email, resolvePasswordEmailVerified(p)})
+			return
+		}
+		if loginLink != "" {
+			http.Redirect(w, r, loginLink, http.StatusSeeOther)
+			return
+		}
+		if err := s.templates().register(r, w, "", "", p.Email, "", "", false, true); err != nil {
+			s.logger.ErrorContext(ctx, "server template error", "err", err)
+		}
+	default:
+		s.renderError(r, w, http.StatusBadRequest, "Unsupported request method.")
+	}
+}
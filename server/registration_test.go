@@ -0,0 +1,142 @@
+package server
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/dexidp/dex/pkg/mailer"
+)
+
+func TestRegistration(t *testing.T) {
+	ctx := t.Context()
+	httpServer, s := newTestServer(t, func(c *Config) {
+		c.Registration = &RegistrationConfig{
+			AllowedEmailDomains: []string{"example.com"},
+			InviteCodes:         []string{"let-me-in"},
+		}
+	})
+	defer httpServer.Close()
+
+	post := func(vals url.Values) (int, string) {
+		resp, err := http.PostForm(httpServer.URL+"/register", vals)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		return resp.StatusCode, string(body)
+	}
+	form := func(email, invite string) url.Values {
+		return url.Values{
+			"email":            {email},
+			"password":         {"password"},
+			"confirm_password": {"password"},
+			"invite_code":      {invite},
+		}
+	}
+
+	resp, err := http.Get(httpServer.URL + "/register")
+	require.NoError(t, err)
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Contains(t, string(body), `name="invite_code"`)
+
+	code, body2 := post(form("jane@other.com", "let-me-in"))
+	require.Equal(t, http.StatusBadRequest, code)
+	require.Contains(t, body2, "Sign-up isn&#39;t allowed for this email address.")
+
+	code, body2 = post(form("jane@example.com", "wrong"))
+	require.Equal(t, http.StatusBadRequest, code)
+	require.Contains(t, body2, "Invalid invite code.")
+
+	code, body2 = post(form("jane@example.com", "let-me-in"))
+	require.Equal(t, http.StatusOK, code, body2)
+	require.Contains(t, body2, "Your account has been created.")
+
+	p, err := s.storage.GetPassword(ctx, "jane@example.com")
+	require.NoError(t, err)
+	require.Equal(t, "jane", p.Username)
+	require.NotEmpty(t, p.UserID)
+	require.False(t, resolvePasswordEmailVerified(p))
+	require.NoError(t, bcrypt.CompareHashAndPassword(p.Hash, []byte("password")))
+
+	code, body2 = post(form("jane@example.com", "let-me-in"))
+	require.Equal(t, http.StatusBadRequest, code)
+	require.Contains(t, body2, "An account with this email address already exists.")
+}
+
+func TestRegistrationAPI(t *testing.T) {
+	mails := make(testMailer, 10)
+	httpServer, s := newTestServer(t, func(c *Config) {
+		c.EmailVerification = &EmailVerificationConfig{Mailer: mails}
+		c.Registration = &RegistrationConfig{VerifyEmail: true}
+	})
+	defer httpServer.Close()
+
+	register := func(body string) (int, map[string]any) {
+		resp, err := http.Post(httpServer.URL+"/register", "application/json", strings.NewReader(body))
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		var out map[string]any
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&out))
+		return resp.StatusCode, out
+	}
+
+	code, out := register(`{"email": "jane@example.com", "password": "short"}`)
+	require.Equal(t, http.StatusBadRequest, code)
+	require.Equal(t, "invalid_request", out["error"])
+
+	code, out = register(`{"email": "jane@example.com", "username": "Jane Doe", "password": "password"}`)
+	require.Equal(t, http.StatusCreated, code)
+	require.Equal(t, "jane@example.com", out["email"])
+	require.Equal(t, false, out["email_verified"])
+	require.NotEmpty(t, out["user_id"])
+
+	p, err := s.storage.GetPassword(t.Context(), "jane@example.com")
+	require.NoError(t, err)
+	require.Equal(t, "Jane Doe", p.Username)
+	require.False(t, resolvePasswordEmailVerified(p))
+
+	var msg mailer.Message
+	select {
+	case msg = <-mails:
+	case <-time.After(5 * time.Second):
+		t.Fatal("no verification email sent")
+	}
+	require.Equal(t, "jane@example.com", msg.To)
+}
+
+func TestRegistrationRateLimit(t *testing.T) {
+	httpServer, _ := newTestServer(t, func(c *Config) {
+		c.Registration = &RegistrationConfig{RateLimit: RateLimit{Rate: 0.001, Burst: 2}}
+	})
+	defer httpServer.Close()
+
+	var codes []int
+	for range 3 {
+		resp, err := http.PostForm(httpServer.URL+"/register", url.Values{"email": {"invalid"}})
+		require.NoError(t, err)
+		resp.Body.Close()
+		codes = append(codes, resp.StatusCode)
+	}
+	require.Equal(t, []int{http.StatusBadRequest, http.StatusBadRequest, http.StatusTooManyRequests}, codes)
+}
+
+func TestRegistrationDisabled(t *testing.T) {
+	httpServer, _ := newTestServer(t, nil)
+	defer httpServer.Close()
+
+	resp, err := http.Get(httpServer.URL + "/register")
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusNotFound, resp.StatusCode)
+}
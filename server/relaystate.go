@@ -0,0 +1,76 @@
+package server
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+)
+
+const (
+	// relayStatePurpose keeps MACs of other tokens from being accepted for
+	// relay states.
+	relayStatePurpose = "relay_state"
+	// relayStateValidFor outlives auth requests, so expired logins can be
+	// restarted.
+	relayStateValidFor = 24 * time.Hour
+	// maxRelayStateRestart bounds the size of relay states, which upstreams
+	// carry in URLs. Longer restart URLs are left out.
+	maxRelayStateRestart = 2048
+)
+
+var errInvalidRelayState = errors.New("invalid relay state")
+
+// relayStateClaims are the claims of a relay state.
+type relayStateClaims struct {
+	// Subject is the ID of the auth request.
+	Subject  string `json:"sub"`
+	Expiry   int64  `json:"exp"`
+	IssuedAt int64  `json:"iat"`
+	// Restart is the authorization request URL the login restarts from.
+	Restart string `json:"rst,omitempty"`
+}
+
+// newRelayState returns a relay state for an auth request, of the form
+// "claims.mac" with the claims base64 raw-URL-encoded and MACed with the relay
+// state key. Connectors carry it through upstream round trips, see
+// connector.AuthRequestParams.
+func (s *Server) newRelayState(authID, restart string) (string, error) {
+	if len(restart) > maxRelayStateRestart {
+		restart = ""
+	}
+	now := s.now()
+	payload, err := json.Marshal(relayStateClaims{
+		Subject:  authID,
+		Expiry:   now.Add(relayStateValidFor).Unix(),
+		IssuedAt: now.Unix(),
+		Restart:  restart,
+	})
+	if err != nil {
+		return "", err
+	}
+	claims := base64.RawURLEncoding.EncodeToString(payload)
+	return claims + "." + computeHMAC(s.relayStateKey, relayStatePurpose, claims), nil
+}
+
+// parseRelayState verifies a relay state and returns the ID of its auth
+// request and the URL to restart the login from, if any.
+func (s *Server) parseRelayState(raw string) (authID, restart string, err error) {
+	encoded, mac, ok := strings.Cut(raw, ".")
+	if !ok || !verifyHMAC(s.relayStateKey, mac, relayStatePurpose, encoded) {
+		return "", "", errInvalidRelayState
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return "", "", errInvalidRelayState
+	}
+	var claims relayStateClaims
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return "", "", errInvalidRelayState
+	}
+	if s.now().After(time.Unix(claims.Expiry, 0)) {
+		return "", "", errors.New("relay state expired")
+	}
+	return claims.Subject, claims.Restart, nil
+}
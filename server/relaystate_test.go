@@ -0,0 +1,76 @@
+package server
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRelayState(t *testing.T) {
+	ctx := t.Context()
+	httpServer, s := newTestServer(t, nil)
+	defer httpServer.Close()
+
+	restart := "/auth/mock?client_id=example-app&state=deep-link"
+	relayState, err := s.newRelayState("expired-auth-request", restart)
+	require.NoError(t, err)
+
+	authID, gotRestart, err := s.parseRelayState(relayState)
+	require.NoError(t, err)
+	require.Equal(t, "expired-auth-request", authID)
+	require.Equal(t, restart, gotRestart)
+
+	other, err := s.newRelayState("other-auth-request", "")
+	require.NoError(t, err)
+	key := s.relayStateKey
+	s.relayStateKey = []byte("another key")
+	_, _, err = s.parseRelayState(other)
+	require.Error(t, err, "relay state of another key")
+	s.relayStateKey = key
+
+	signed, err := s.signer.Sign(ctx, []byte(`{"sub":"expired-auth-request"}`))
+	require.NoError(t, err)
+	_, _, err = s.parseRelayState(signed)
+	require.Error(t, err, "token signed with the signing keys")
+
+	now := s.now
+	s.now = func() time.Time { return now().Add(relayStateValidFor + time.Minute) }
+	_, _, err = s.parseRelayState(relayState)
+	require.Error(t, err, "expired relay state")
+	s.now = now
+
+	long, err := s.newRelayState("expired-auth-request", "/auth/mock?"+strings.Repeat("a", maxRelayStateRestart))
+	require.NoError(t, err)
+	_, gotRestart, err = s.parseRelayState(long)
+	require.NoError(t, err)
+	require.Empty(t, gotRestart, "long restart URLs should be left out")
+
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}}
+	callback := func(query url.Values) *http.Response {
+		resp, err := client.Get(httpServer.URL + "/callback?" + query.Encode())
+		require.NoError(t, err)
+		resp.Body.Close()
+		return resp
+	}
+
+	// The auth request expired during the login, which restarts.
+	resp := callback(url.Values{"state": {"expired-auth-request"}, "relay_state": {relayState}})
+	require.Equal(t, http.StatusFound, resp.StatusCode)
+	require.Equal(t, restart, resp.Header.Get("Location"))
+
+	// The relay state alone is enough, if the upstream dropped the state.
+	resp = callback(url.Values{"relay_state": {relayState}})
+	require.Equal(t, http.StatusFound, resp.StatusCode)
+
+	resp = callback(url.Values{"state": {"other-auth-request"}, "relay_state": {relayState}})
+	require.Equal(t, http.StatusBadRequest, resp.StatusCode, "relay state of another auth request")
+
+	resp = callback(url.Values{"relay_state": {relayState + "x"}})
+	require.Equal(t, http.StatusBadRequest, resp.StatusCode, "tampered relay state")
+}
@@ -0,0 +1,158 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/dexidp/dex/connector"
+	"github.com/dexidp/dex/web"
+)
+
+// webAssets is the parsed frontend configuration. It is replaced as a whole
+// when the web config is reloaded.
+type webAssets struct {
+	static    http.Handler
+	theme     http.Handler
+	robots    http.HandlerFunc
+	templates *templates
+}
+
+func newWebAssets(issuerURL string, c WebConfig) (*webAssets, error) {
+	webFS := web.FS()
+	if c.Dir != "" {
+		webFS = os.DirFS(c.Dir)
+	} else if c.WebFS != nil {
+		webFS = c.WebFS
+	}
+
+	static, theme, robots, tmpls, err := loadWebConfig(webConfig{
+		webFS:       webFS,
+		logoURL:     c.LogoURL,
+		issuerURL:   issuerURL,
+		issuer:      c.Issuer,
+		theme:       c.Theme,
+		extra:       c.Extra,
+		title:       c.Title,
+		footerText:  c.FooterText,
+		footerLinks: c.FooterLinks,
+		customCSS:   c.CustomCSS,
+		languages:   c.Languages,
+		messages:    c.Messages,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &webAssets{static: static, theme: theme, robots: robots, templates: tmpls}, nil
+}
+
+func (s *Server) templates() *templates {
+	return s.web.Load().templates
+}
+
+// ReloadWeb replaces the frontend templates and assets. Pages rendered after
+// it returns use the new config; if loading it fails the old one is kept.
+func (s *Server) ReloadWeb(c WebConfig) error {
+	assets, err := newWebAssets(s.issuerURL.String(), c)
+	if err != nil {
+		return fmt.Errorf("server: failed to load web static: %v", err)
+	}
+	s.web.Store(assets)
+	return nil
+}
+
+// SyncConnectors brings the open connectors in line with the storage. It
+// opens connectors which were added or whose ResourceVersion changed and
+// closes the ones which were removed. Unchanged connectors are left alone, so
+// logins in progress through them are not interrupted.
+func (s *Server) SyncConnectors(ctx context.Context) error {
+	storageConnectors, err := s.storage.ListConnectors(ctx)
+	if err != nil {
+		return fmt.Errorf("server: failed to list connector objects from storage: %v", err)
+	}
+
+	var errs []error
+	present := make(map[string]bool, len(storageConnectors))
+	for _, conn := range storageConnectors {
+		present[conn.ID] = true
+
+		s.mu.Lock()
+		current, ok := s.connectors[conn.ID]
+		s.mu.Unlock()
+		if ok && current.ResourceVersion == conn.ResourceVersion {
+			continue
+		}
+
+		if _, err := s.OpenConnector(conn); err != nil {
+			errs = append(errs, fmt.Errorf("connector %q: %v", conn.ID, err))
+			continue
+		}
+		s.logger.Info("connector opened", "connector_id", conn.ID, "reopened", ok)
+	}
+
+	var removed []string
+	s.mu.Lock()
+	for id := range s.connectors {
+		if !present[id] {
+			removed = append(removed, id)
+		}
+	}
+	s.mu.Unlock()
+	for _, id := range removed {
+		s.CloseConnector(id)
+		s.logger.Info("connector closed", "connector_id", id)
+	}
+
+	return errors.Join(errs...)
+}
+
+// retiredConnector is a connector which was replaced or removed while
+// requests may still be using it.
+type retiredConnector struct {
+	id    string
+	conn  connector.Connector
+	timer *time.Timer
+}
+
+// retireConnector closes a connector which was replaced or removed after a
+// grace period, the lifetime of an auth request, so logins in progress
+// through it can complete. CloseConnectors closes it right away.
+func (s *Server) retireConnector(id string, c connector.Connector) {
+	if _, ok := c.(io.Closer); !ok {
+		return
+	}
+
+	r := &retiredConnector{id: id, conn: c}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	r.timer = time.AfterFunc(s.authRequestsValidFor, func() {
+		s.mu.Lock()
+		_, ok := s.retiredConnectors[r]
+		delete(s.retiredConnectors, r)
+		s.mu.Unlock()
+		if ok {
+			closeConnector(s.logger, id, c)
+		}
+	})
+	if s.retiredConnectors == nil {
+		s.retiredConnectors = make(map[*retiredConnector]struct{})
+	}
+	s.retiredConnectors[r] = struct{}{}
+}
+
+// closeConnector releases the resources held by a connector which is no
+// longer in use.
+func closeConnector(logger *slog.Logger, id string, c connector.Connector) {
+	closer, ok := c.(io.Closer)
+	if !ok {
+		return
+	}
+	if err := closer.Close(); err != nil {
+		logger.Error("failed to close connector", "connector_id", id, "err", err)
+	}
+}
@@ -0,0 +1,89 @@
+package server
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/dexidp/dex/storage"
+)
+
+func TestSyncConnectors(t *testing.T) {
+	httpServer, s := newTestServer(t, nil)
+	defer httpServer.Close()
+
+	ctx := t.Context()
+
+	_, err := s.getConnector(ctx, "mock")
+	require.NoError(t, err)
+	s.mu.Lock()
+	before := s.connectors["mock"]
+	s.mu.Unlock()
+
+	require.NoError(t, s.storage.CreateConnector(ctx, storage.Connector{
+		ID:              "added",
+		Type:            "mockCallback",
+		Name:            "Added",
+		ResourceVersion: "1",
+	}))
+	require.NoError(t, s.SyncConnectors(ctx))
+
+	s.mu.Lock()
+	require.Contains(t, s.connectors, "added")
+	require.Equal(t, before, s.connectors["mock"], "unchanged connector should not be reopened")
+	s.mu.Unlock()
+
+	require.NoError(t, s.storage.UpdateConnector(ctx, "added", func(c storage.Connector) (storage.Connector, error) {
+		c.ResourceVersion = "2"
+		return c, nil
+	}))
+	require.NoError(t, s.storage.DeleteConnector(ctx, "mock"))
+	require.NoError(t, s.SyncConnectors(ctx))
+
+	s.mu.Lock()
+	require.NotContains(t, s.connectors, "mock")
+	require.Equal(t, "2", s.connectors["added"].ResourceVersion)
+	s.mu.Unlock()
+}
+
+func TestRetireConnector(t *testing.T) {
+	httpServer, s := newTestServer(t, func(c *Config) {
+		c.AuthRequestsValidFor = 100 * time.Millisecond
+	})
+	defer httpServer.Close()
+
+	removed := &closeRecorder{CallbackConnector: &promptForwardingConnector{}}
+	registerTestConnector(t, s, "removed", removed)
+	s.CloseConnector("removed")
+	require.False(t, removed.closed.Load(), "connector closed before the auth requests using it expired")
+	require.Eventually(t, removed.closed.Load, time.Second, 10*time.Millisecond)
+
+	pending := &closeRecorder{CallbackConnector: &promptForwardingConnector{}}
+	registerTestConnector(t, s, "pending", pending)
+	s.CloseConnector("pending")
+	s.CloseConnectors()
+	require.True(t, pending.closed.Load(), "retired connector not closed on shutdown")
+}
+
+func TestReloadWeb(t *testing.T) {
+	httpServer, s := newTestServer(t, nil)
+	defer httpServer.Close()
+
+	robots := func() string {
+		rr := httptest.NewRecorder()
+		s.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/robots.txt", nil))
+		body, _ := io.ReadAll(rr.Body)
+		return string(body)
+	}
+	require.NotEmpty(t, robots())
+
+	require.Error(t, s.ReloadWeb(WebConfig{Dir: t.TempDir()}))
+	require.NotEmpty(t, robots(), "failed reload should keep the old assets")
+
+	require.NoError(t, s.ReloadWeb(WebConfig{Dir: "../web", Issuer: "Reloaded"}))
+	require.NotNil(t, s.templates())
+}
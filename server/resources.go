@@ -0,0 +1,47 @@
+package server
+
+import (
+	"fmt"
+	"net/url"
+	"slices"
+
+	"github.com/dexidp/dex/storage"
+)
+
+// validateResources checks the resource indicators (RFC 8707) requested by a
+// client. Each must be an absolute URI without a fragment, allowed for the
+// client.
+func validateResources(client storage.Client, resources []string) error {
+	for _, resource := range resources {
+		u, err := url.Parse(resource)
+		if err != nil || !u.IsAbs() || u.Fragment != "" {
+			return fmt.Errorf("invalid resource %q", resource)
+		}
+		if !slices.Contains(client.Resources, resource) {
+			return fmt.Errorf("client can't request resource %q", resource)
+		}
+	}
+	return nil
+}
+
+// tokenResources returns the resources an access token is issued for at the
+// token endpoint. The requested resources must be a subset of the ones granted
+// at the authorization endpoint, or be allowed for the client if none were
+// granted. Without requested resources, the token is issued for all granted
+// ones.
+func tokenResources(client storage.Client, granted, requested []string) ([]string, error) {
+	if len(requested) == 0 {
+		return granted, nil
+	}
+	if err := validateResources(client, requested); err != nil {
+		return nil, err
+	}
+	if len(granted) > 0 {
+		for _, resource := range requested {
+			if !slices.Contains(granted, resource) {
+				return nil, fmt.Errorf("resource %q was not granted", resource)
+			}
+		}
+	}
+	return requested, nil
+}
@@ -0,0 +1,167 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/coreos/go-oidc/v3/oidc"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dexidp/dex/storage"
+)
+
+func TestTokenResources(t *testing.T) {
+	client := storage.Client{
+		ID:        "client_1",
+		Resources: []string{"https://api.example.com", "https://billing.example.com", "https://reports.example.com"},
+	}
+	granted := []string{"https://api.example.com", "https://billing.example.com"}
+
+	tests := []struct {
+		name      string
+		granted   []string
+		requested []string
+		want      []string
+		wantErr   bool
+	}{
+		{
+			name:    "all granted resources",
+			granted: granted,
+			want:    granted,
+		},
+		{
+			name:      "subset of granted resources",
+			granted:   granted,
+			requested: []string{"https://billing.example.com"},
+			want:      []string{"https://billing.example.com"},
+		},
+		{
+			name:      "resource not granted",
+			granted:   granted,
+			requested: []string{"https://reports.example.com"},
+			wantErr:   true,
+		},
+		{
+			name:      "allowed resource without grant",
+			requested: []string{"https://reports.example.com"},
+			want:      []string{"https://reports.example.com"},
+		},
+		{
+			name:      "resource not allowed",
+			requested: []string{"https://other.example.com"},
+			wantErr:   true,
+		},
+		{
+			name:      "relative resource",
+			requested: []string{"/api"},
+			wantErr:   true,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := tokenResources(client, tc.granted, tc.requested)
+			if tc.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tc.want, got)
+		})
+	}
+}
+
+func TestHandleAuthCodeResources(t *testing.T) {
+	ctx := t.Context()
+	httpServer, s := newTestServer(t, nil)
+	defer httpServer.Close()
+
+	client := storage.Client{
+		ID:           "client_1",
+		Secret:       "secret_1",
+		RedirectURIs: []string{"https://example.com/callback"},
+		Resources:    []string{"https://api.example.com", "https://billing.example.com"},
+	}
+	require.NoError(t, s.storage.CreateClient(ctx, client))
+
+	provider, err := oidc.NewProvider(ctx, httpServer.URL)
+	require.NoError(t, err)
+	verifier := provider.Verifier(&oidc.Config{SkipClientIDCheck: true})
+
+	token := func(vals url.Values) (*httptest.ResponseRecorder, accessTokenResponse) {
+		vals.Set("client_id", client.ID)
+		vals.Set("client_secret", client.Secret)
+		rr := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, httpServer.URL+"/token", strings.NewReader(vals.Encode()))
+		req.Header.Set("content-type", "application/x-www-form-urlencoded")
+		s.handleToken(rr, req)
+
+		var res accessTokenResponse
+		if rr.Code == http.StatusOK {
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &res))
+		}
+		return rr, res
+	}
+	newCode := func() string {
+		code := storage.AuthCode{
+			ID:          storage.NewID(),
+			ClientID:    client.ID,
+			RedirectURI: "https://example.com/callback",
+			ConnectorID: "mock",
+			Scopes:      []string{"openid", "offline_access"},
+			Claims:      storage.Claims{UserID: "1", Username: "jane"},
+			Expiry:      time.Now().Add(time.Minute),
+			Resources:   []string{"https://api.example.com", "https://billing.example.com"},
+		}
+		require.NoError(t, s.storage.CreateAuthCode(ctx, code))
+		return code.ID
+	}
+
+	t.Run("resource not granted", func(t *testing.T) {
+		rr, _ := token(url.Values{
+			"grant_type":   {grantTypeAuthorizationCode},
+			"code":         {newCode()},
+			"redirect_uri": {"https://example.com/callback"},
+			"resource":     {"https://other.example.com"},
+		})
+		require.Equal(t, http.StatusBadRequest, rr.Code)
+		require.Contains(t, rr.Body.String(), errInvalidTarget)
+	})
+
+	rr, res := token(url.Values{
+		"grant_type":   {grantTypeAuthorizationCode},
+		"code":         {newCode()},
+		"redirect_uri": {"https://example.com/callback"},
+		"resource":     {"https://api.example.com"},
+	})
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+
+	accessToken, err := verifier.Verify(ctx, res.AccessToken)
+	require.NoError(t, err)
+	require.Equal(t, []string{"https://api.example.com"}, accessToken.Audience)
+	var claims struct {
+		AuthorizingParty string `json:"azp"`
+	}
+	require.NoError(t, accessToken.Claims(&claims))
+	require.Equal(t, client.ID, claims.AuthorizingParty)
+
+	idToken, err := verifier.Verify(ctx, res.IDToken)
+	require.NoError(t, err)
+	require.Equal(t, []string{client.ID}, idToken.Audience)
+
+	// The refresh token keeps the resources granted with the code.
+	rr, res = token(url.Values{
+		"grant_type":    {grantTypeRefreshToken},
+		"refresh_token": {res.RefreshToken},
+		"resource":      {"https://billing.example.com"},
+	})
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+
+	accessToken, err = verifier.Verify(ctx, res.AccessToken)
+	require.NoError(t, err)
+	require.Equal(t, []string{"https://billing.example.com"}, accessToken.Audience)
+}
@@ -0,0 +1,178 @@
+package restapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	"github.com/dexidp/dex/api/v2"
+)
+
+// openAPIDocument generates the OpenAPI v3 description of the JSON API from
+// the routes and the protobuf descriptors of the gRPC API, so it can't drift
+// from what the handlers accept and return.
+func openAPIDocument() ([]byte, error) {
+	service := api.File_api_v2_api_proto.Services().ByName("Dex")
+	if service == nil {
+		return nil, fmt.Errorf("service Dex not found")
+	}
+
+	schemas := map[string]any{
+		"Error": map[string]any{
+			"type": "object",
+			"properties": map[string]any{
+				"error":   map[string]any{"type": "string", "description": "Machine readable error code."},
+				"message": map[string]any{"type": "string"},
+			},
+			"required": []string{"error", "message"},
+		},
+	}
+	paths := map[string]map[string]any{}
+
+	for _, rt := range routes {
+		method := service.Methods().ByName(protoreflect.Name(rt.rpc))
+		if method == nil {
+			return nil, fmt.Errorf("service Dex has no method %s", rt.rpc)
+		}
+		addSchema(schemas, method.Input())
+		addSchema(schemas, method.Output())
+
+		op := map[string]any{
+			"operationId": lowerFirst(rt.rpc),
+			"summary":     rt.summary,
+			"responses": map[string]any{
+				"200": jsonContent("Success.", method.Output()),
+				"default": map[string]any{
+					"description": "Error.",
+					"content": map[string]any{
+						"application/json": map[string]any{"schema": ref("Error")},
+					},
+				},
+			},
+		}
+		var params []any
+		for _, name := range pathParams(rt.path) {
+			params = append(params, map[string]any{
+				"name":     name,
+				"in":       "path",
+				"required": true,
+				"schema":   map[string]any{"type": "string"},
+			})
+		}
+		if len(params) > 0 {
+			op["parameters"] = params
+		}
+		if rt.hasBody() {
+			op["requestBody"] = jsonContent("", method.Input())
+		}
+
+		path := "/api/" + Version + rt.path
+		if paths[path] == nil {
+			paths[path] = map[string]any{}
+		}
+		paths[path][strings.ToLower(rt.method)] = op
+	}
+
+	paths["/api/"+Version+"/openapi.json"] = map[string]any{
+		strings.ToLower(http.MethodGet): map[string]any{
+			"operationId": "getOpenAPI",
+			"summary":     "Get this OpenAPI document",
+			"responses": map[string]any{
+				"200": map[string]any{
+					"description": "The OpenAPI document.",
+					"content":     map[string]any{"application/json": map[string]any{}},
+				},
+			},
+		},
+	}
+
+	return json.MarshalIndent(map[string]any{
+		"openapi": "3.0.3",
+		"info": map[string]any{
+			"title":       "Dex API",
+			"version":     Version,
+			"description": "JSON facade of the dex gRPC API. Messages use the field names of the protobuf definitions; 64-bit integers are encoded as strings.",
+		},
+		"paths":      paths,
+		"components": map[string]any{"schemas": schemas},
+	}, "", "  ")
+}
+
+func jsonContent(description string, md protoreflect.MessageDescriptor) map[string]any {
+	c := map[string]any{
+		"content": map[string]any{
+			"application/json": map[string]any{"schema": ref(string(md.Name()))},
+		},
+	}
+	if description != "" {
+		c["description"] = description
+	}
+	return c
+}
+
+func ref(name string) map[string]any {
+	return map[string]any{"$ref": "#/components/schemas/" + name}
+}
+
+// addSchema adds the schema of a message and the messages it references.
+func addSchema(schemas map[string]any, md protoreflect.MessageDescriptor) {
+	name := string(md.Name())
+	if _, ok := schemas[name]; ok {
+		return
+	}
+	props := map[string]any{}
+	schemas[name] = map[string]any{"type": "object", "properties": props}
+
+	fields := md.Fields()
+	for i := 0; i < fields.Len(); i++ {
+		fd := fields.Get(i)
+		schema := fieldSchema(schemas, fd)
+		if fd.IsList() {
+			schema = map[string]any{"type": "array", "items": schema}
+		}
+		props[string(fd.Name())] = schema
+	}
+}
+
+// fieldSchema returns the schema of a single value of a field, following the
+// protobuf JSON mapping.
+func fieldSchema(schemas map[string]any, fd protoreflect.FieldDescriptor) map[string]any {
+	switch fd.Kind() {
+	case protoreflect.BoolKind:
+		return map[string]any{"type": "boolean"}
+	case protoreflect.StringKind:
+		return map[string]any{"type": "string"}
+	case protoreflect.BytesKind:
+		return map[string]any{"type": "string", "format": "byte"}
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind:
+		return map[string]any{"type": "integer", "format": "int32"}
+	case protoreflect.Uint32Kind, protoreflect.Fixed32Kind:
+		return map[string]any{"type": "integer", "format": "int64", "minimum": 0}
+	case protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind,
+		protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		return map[string]any{"type": "string", "format": "int64"}
+	case protoreflect.FloatKind, protoreflect.DoubleKind:
+		return map[string]any{"type": "number"}
+	case protoreflect.EnumKind:
+		values := fd.Enum().Values()
+		names := make([]string, values.Len())
+		for i := range names {
+			names[i] = string(values.Get(i).Name())
+		}
+		return map[string]any{"type": "string", "enum": names}
+	case protoreflect.MessageKind, protoreflect.GroupKind:
+		addSchema(schemas, fd.Message())
+		return ref(string(fd.Message().Name()))
+	}
+	return map[string]any{}
+}
+
+func lowerFirst(s string) string {
+	if s == "" {
+		return s
+	}
+	return strings.ToLower(s[:1]) + s[1:]
+}
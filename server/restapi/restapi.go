@@ -0,0 +1,245 @@
+// Package restapi implements a versioned JSON facade of the dex gRPC API and
+// the OpenAPI description of it, so HTTP clients and generated SDKs can use
+// the API without gRPC.
+//
+// Requests and responses are the protobuf messages of the gRPC API encoded as
+// JSON with their proto field names. Every RPC is mapped to one route under
+// /api/v1; the OpenAPI document is served at /api/v1/openapi.json.
+package restapi
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	"github.com/dexidp/dex/api/v2"
+)
+
+// Version is the version of the JSON API, used as the prefix of all routes.
+const Version = "v1"
+
+// maxBodySize is the largest request body accepted.
+const maxBodySize = 1 << 20
+
+// route maps an HTTP method and path to an RPC of the gRPC API. Path
+// parameters are named after the fields of the RPC's request message they
+// set.
+type route struct {
+	method  string
+	path    string
+	rpc     string
+	summary string
+}
+
+// hasBody reports whether the request message of a route is read from the
+// request body.
+func (r route) hasBody() bool {
+	return r.method == http.MethodPost || r.method == http.MethodPut
+}
+
+var routes = []route{
+	{http.MethodGet, "/clients", "ListClients", "List clients"},
+	{http.MethodPost, "/clients", "CreateClient", "Create a client"},
+	{http.MethodGet, "/clients/{id}", "GetClient", "Get a client"},
+	{http.MethodPut, "/clients/{id}", "UpdateClient", "Update a client"},
+	{http.MethodDelete, "/clients/{id}", "DeleteClient", "Delete a client"},
+	{http.MethodGet, "/passwords", "ListPasswords", "List passwords"},
+	{http.MethodPost, "/passwords", "CreatePassword", "Create a password"},
+	{http.MethodPost, "/passwords/verify", "VerifyPassword", "Verify a password"},
+	{http.MethodPut, "/passwords/{email}", "UpdatePassword", "Update a password"},
+	{http.MethodDelete, "/passwords/{email}", "DeletePassword", "Delete a password"},
+	{http.MethodGet, "/connectors", "ListConnectors", "List connectors"},
+	{http.MethodPost, "/connectors", "CreateConnector", "Create a connector"},
+	{http.MethodPut, "/connectors/{id}", "UpdateConnector", "Update a connector"},
+	{http.MethodDelete, "/connectors/{id}", "DeleteConnector", "Delete a connector"},
+	{http.MethodGet, "/users/{user_id}/refresh_tokens", "ListRefresh", "List the refresh tokens of a user"},
+	{http.MethodDelete, "/users/{user_id}/refresh_tokens/{client_id}", "RevokeRefresh", "Revoke the refresh token of a user for a client"},
+	{http.MethodGet, "/version", "GetVersion", "Get the version of dex"},
+	{http.MethodGet, "/discovery", "GetDiscovery", "Get the OpenID Connect discovery document"},
+	{http.MethodPost, "/audit_events/query", "QueryAuditEvents", "Query the stored audit events"},
+	{http.MethodPost, "/terms_acceptances/query", "ListTermsAcceptances", "List the terms of clients accepted by users"},
+	{http.MethodGet, "/log_levels", "GetLogLevels", "Get the log levels"},
+	{http.MethodPut, "/log_levels", "SetLogLevel", "Change the log level of a module"},
+}
+
+var (
+	marshaler   = protojson.MarshalOptions{UseProtoNames: true, EmitUnpopulated: true}
+	unmarshaler = protojson.UnmarshalOptions{}
+)
+
+// New returns a handler serving the JSON API backed by srv, usually the value
+// returned by server.NewAPI.
+//
+// Calls go through the interceptors in order, like calls of a gRPC server
+// with the interceptors chained. The context passed to them carries the peer
+// of the request, including its TLS connection state, so the same
+// interceptors can authorize gRPC and JSON API calls.
+func New(srv api.DexServer, logger *slog.Logger, interceptors ...grpc.UnaryServerInterceptor) (http.Handler, error) {
+	doc, err := openAPIDocument()
+	if err != nil {
+		return nil, fmt.Errorf("restapi: generate OpenAPI document: %v", err)
+	}
+
+	mux := http.NewServeMux()
+	prefix := "/api/" + Version
+	mux.HandleFunc("GET "+prefix+"/openapi.json", func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(doc)
+	})
+
+	v := reflect.ValueOf(srv)
+	for _, rt := range routes {
+		method := v.MethodByName(rt.rpc)
+		if !method.IsValid() {
+			return nil, fmt.Errorf("restapi: API has no method %s", rt.rpc)
+		}
+		mux.Handle(rt.method+" "+prefix+rt.path, &handler{route: rt, call: method, interceptors: interceptors, logger: logger})
+	}
+	return mux, nil
+}
+
+// handler serves the route of one RPC.
+type handler struct {
+	route        route
+	call         reflect.Value
+	interceptors []grpc.UnaryServerInterceptor
+	logger       *slog.Logger
+}
+
+func (h *handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	req := reflect.New(h.call.Type().In(1).Elem()).Interface().(proto.Message)
+
+	if h.route.hasBody() {
+		body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxBodySize))
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "invalid_request", "Failed to read request body.")
+			return
+		}
+		if len(body) > 0 {
+			if err := unmarshaler.Unmarshal(body, req); err != nil {
+				writeError(w, http.StatusBadRequest, "invalid_request", "Invalid request body: "+err.Error())
+				return
+			}
+		}
+	}
+
+	// Path parameters override fields set in the body.
+	fields := req.ProtoReflect().Descriptor().Fields()
+	for _, name := range pathParams(h.route.path) {
+		fd := fields.ByName(protoreflect.Name(name))
+		req.ProtoReflect().Set(fd, protoreflect.ValueOfString(r.PathValue(name)))
+	}
+
+	resp, err := h.invoke(peerContext(r), req)
+	if err != nil {
+		code, httpStatus := errorStatus(err)
+		if httpStatus >= http.StatusInternalServerError {
+			h.logger.ErrorContext(r.Context(), "api request failed", "rpc", h.route.rpc, "err", err)
+		}
+		writeError(w, httpStatus, code, status.Convert(err).Message())
+		return
+	}
+
+	data, err := marshaler.Marshal(resp.(proto.Message))
+	if err != nil {
+		h.logger.ErrorContext(r.Context(), "failed to marshal api response", "rpc", h.route.rpc, "err", err)
+		writeError(w, http.StatusInternalServerError, "internal", "Failed to encode response.")
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(data)
+}
+
+// invoke calls the RPC of the route through the interceptors.
+func (h *handler) invoke(ctx context.Context, req proto.Message) (any, error) {
+	call := func(ctx context.Context, req any) (any, error) {
+		out := h.call.Call([]reflect.Value{reflect.ValueOf(ctx), reflect.ValueOf(req)})
+		err, _ := out[1].Interface().(error)
+		return out[0].Interface(), err
+	}
+	info := &grpc.UnaryServerInfo{FullMethod: "/" + api.Dex_ServiceDesc.ServiceName + "/" + h.route.rpc}
+	for i := len(h.interceptors) - 1; i >= 0; i-- {
+		interceptor, next := h.interceptors[i], call
+		call = func(ctx context.Context, req any) (any, error) {
+			return interceptor(ctx, req, info, next)
+		}
+	}
+	return call(ctx, req)
+}
+
+// peerContext returns the context of r with the client of the request
+// attached as gRPC peer.
+func peerContext(r *http.Request) context.Context {
+	p := &peer.Peer{Addr: remoteAddr(r.RemoteAddr)}
+	if r.TLS != nil {
+		p.AuthInfo = credentials.TLSInfo{State: *r.TLS}
+	}
+	return peer.NewContext(r.Context(), p)
+}
+
+// remoteAddr is the address of the client of a request.
+type remoteAddr string
+
+func (a remoteAddr) Network() string { return "tcp" }
+func (a remoteAddr) String() string  { return string(a) }
+
+// pathParams returns the names of the parameters of a route path.
+func pathParams(path string) []string {
+	var params []string
+	for _, seg := range strings.Split(path, "/") {
+		if strings.HasPrefix(seg, "{") && strings.HasSuffix(seg, "}") {
+			params = append(params, seg[1:len(seg)-1])
+		}
+	}
+	return params
+}
+
+// errorStatus maps an error of the gRPC API to an error code and HTTP status.
+// Errors without a gRPC status are reported as internal errors, like gRPC
+// gateways do.
+func errorStatus(err error) (string, int) {
+	switch status.Code(err) {
+	case codes.InvalidArgument, codes.FailedPrecondition, codes.OutOfRange:
+		return "invalid_request", http.StatusBadRequest
+	case codes.NotFound:
+		return "not_found", http.StatusNotFound
+	case codes.AlreadyExists, codes.Aborted:
+		return "conflict", http.StatusConflict
+	case codes.PermissionDenied:
+		return "permission_denied", http.StatusForbidden
+	case codes.Unauthenticated:
+		return "unauthenticated", http.StatusUnauthorized
+	case codes.Unimplemented:
+		return "unimplemented", http.StatusNotImplemented
+	case codes.Unavailable:
+		return "unavailable", http.StatusServiceUnavailable
+	default:
+		return "internal", http.StatusInternalServerError
+	}
+}
+
+// apiError is the body of error responses.
+type apiError struct {
+	Error   string `json:"error"`
+	Message string `json:"message"`
+}
+
+func writeError(w http.ResponseWriter, httpStatus int, code, msg string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(httpStatus)
+	json.NewEncoder(w).Encode(apiError{Error: code, Message: msg})
+}
@@ -0,0 +1,164 @@
+package restapi
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+
+	"github.com/dexidp/dex/api/v2"
+	"github.com/dexidp/dex/server"
+	"github.com/dexidp/dex/storage"
+	"github.com/dexidp/dex/storage/memory"
+)
+
+func newTestAPI(t *testing.T) (*httptest.Server, storage.Storage) {
+	logger := slog.New(slog.DiscardHandler)
+	s := memory.New(logger)
+	h, err := New(server.NewAPI(s, logger, "test", nil), logger)
+	require.NoError(t, err)
+	srv := httptest.NewServer(h)
+	t.Cleanup(srv.Close)
+	return srv, s
+}
+
+func do(t *testing.T, method, url, body string) (int, map[string]any) {
+	req, err := http.NewRequest(method, url, strings.NewReader(body))
+	require.NoError(t, err)
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	var out map[string]any
+	require.NoError(t, json.Unmarshal(data, &out), string(data))
+	return resp.StatusCode, out
+}
+
+func TestRoutesCoverAPI(t *testing.T) {
+	routed := map[string]bool{}
+	for _, rt := range routes {
+		routed[rt.rpc] = true
+	}
+	methods := api.File_api_v2_api_proto.Services().ByName("Dex").Methods()
+	for i := 0; i < methods.Len(); i++ {
+		name := string(methods.Get(i).Name())
+		require.True(t, routed[name], "RPC %s has no route", name)
+	}
+}
+
+func TestClients(t *testing.T) {
+	srv, s := newTestAPI(t)
+	base := srv.URL + "/api/v1"
+
+	code, out := do(t, http.MethodPost, base+"/clients", `{"client": {"id": "app", "name": "App", "redirect_uris": ["https://app.example.com/callback"]}}`)
+	require.Equal(t, http.StatusOK, code)
+	require.Equal(t, false, out["already_exists"])
+	secret := out["client"].(map[string]any)["secret"].(string)
+	require.NotEmpty(t, secret)
+
+	c, err := s.GetClient(t.Context(), "app")
+	require.NoError(t, err)
+	require.Equal(t, secret, c.Secret)
+
+	code, out = do(t, http.MethodGet, base+"/clients/app", "")
+	require.Equal(t, http.StatusOK, code)
+	require.Equal(t, "App", out["client"].(map[string]any)["name"])
+
+	code, _ = do(t, http.MethodPut, base+"/clients/app", `{"name": "Renamed"}`)
+	require.Equal(t, http.StatusOK, code)
+	c, err = s.GetClient(t.Context(), "app")
+	require.NoError(t, err)
+	require.Equal(t, "Renamed", c.Name)
+
+	code, out = do(t, http.MethodGet, base+"/clients", "")
+	require.Equal(t, http.StatusOK, code)
+	require.Len(t, out["clients"], 1)
+
+	code, out = do(t, http.MethodDelete, base+"/clients/app", "")
+	require.Equal(t, http.StatusOK, code)
+	require.Equal(t, false, out["not_found"])
+
+	code, out = do(t, http.MethodDelete, base+"/clients/app", "")
+	require.Equal(t, http.StatusOK, code)
+	require.Equal(t, true, out["not_found"])
+}
+
+func TestErrors(t *testing.T) {
+	srv, _ := newTestAPI(t)
+	base := srv.URL + "/api/v1"
+
+	code, out := do(t, http.MethodPost, base+"/clients", `{"client": `)
+	require.Equal(t, http.StatusBadRequest, code)
+	require.Equal(t, "invalid_request", out["error"])
+
+	code, out = do(t, http.MethodPost, base+"/clients", `{"unknown_field": 1}`)
+	require.Equal(t, http.StatusBadRequest, code)
+	require.Equal(t, "invalid_request", out["error"])
+
+	code, out = do(t, http.MethodPost, base+"/passwords", `{}`)
+	require.Equal(t, http.StatusInternalServerError, code)
+	require.Equal(t, "no password supplied", out["message"])
+}
+
+func TestOpenAPIDocument(t *testing.T) {
+	srv, _ := newTestAPI(t)
+
+	code, doc := do(t, http.MethodGet, srv.URL+"/api/v1/openapi.json", "")
+	require.Equal(t, http.StatusOK, code)
+	require.Equal(t, "3.0.3", doc["openapi"])
+
+	paths := doc["paths"].(map[string]any)
+	for _, rt := range routes {
+		op, ok := paths["/api/v1"+rt.path].(map[string]any)[strings.ToLower(rt.method)]
+		require.True(t, ok, "%s %s not documented", rt.method, rt.path)
+		require.NotEmpty(t, op.(map[string]any)["operationId"])
+	}
+
+	schemas := doc["components"].(map[string]any)["schemas"].(map[string]any)
+	client := schemas["Client"].(map[string]any)["properties"].(map[string]any)
+	require.Equal(t, map[string]any{"type": "array", "items": map[string]any{"type": "string"}}, client["redirect_uris"])
+	ref := schemas["CreateClientReq"].(map[string]any)["properties"].(map[string]any)["client"]
+	require.Equal(t, map[string]any{"$ref": "#/components/schemas/Client"}, ref)
+}
+
+func TestInterceptors(t *testing.T) {
+	logger := slog.New(slog.DiscardHandler)
+	var calls []string
+	trace := func(name string) grpc.UnaryServerInterceptor {
+		return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+			_, ok := peer.FromContext(ctx)
+			require.True(t, ok, "no peer in context")
+			calls = append(calls, name+" "+info.FullMethod)
+			return handler(ctx, req)
+		}
+	}
+	deny := func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if info.FullMethod == api.Dex_DeleteClient_FullMethodName {
+			return nil, status.Error(codes.PermissionDenied, "not allowed")
+		}
+		return handler(ctx, req)
+	}
+	h, err := New(server.NewAPI(memory.New(logger), logger, "test", nil), logger, trace("first"), deny, trace("second"))
+	require.NoError(t, err)
+	srv := httptest.NewServer(h)
+	defer srv.Close()
+
+	code, _ := do(t, http.MethodGet, srv.URL+"/api/v1/clients", "")
+	require.Equal(t, http.StatusOK, code)
+	require.Equal(t, []string{"first /api.Dex/ListClients", "second /api.Dex/ListClients"}, calls)
+
+	code, out := do(t, http.MethodDelete, srv.URL+"/api/v1/clients/app", "")
+	require.Equal(t, http.StatusForbidden, code)
+	require.Equal(t, "permission_denied", out["error"])
+}
@@ -0,0 +1,256 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/dexidp/dex/connector"
+	"github.com/dexidp/dex/pkg/events"
+	"github.com/dexidp/dex/storage"
+)
+
+// RiskConfig posts every login and token request of a user to a risk engine
+// before dex issues tokens. The engine allows the login, denies it, or
+// requires the user to complete multi-factor authentication first. Token
+// requests without a browser, where users can't step up, are denied instead.
+type RiskConfig struct {
+	// URL the assessments are posted to.
+	URL string
+	// Headers are added to each request, e.g. for authentication.
+	Headers map[string]string
+	// Timeout of an assessment. Defaults to 5 seconds.
+	Timeout time.Duration
+	// FailOpen allows logins if the risk engine can't be reached or answers
+	// with an error. By default they are denied.
+	FailOpen bool
+
+	// StepUpMFAChain are the authenticators users complete when step-up is
+	// required and the client has no MFA chain. Defaults to the default MFA
+	// chain.
+	StepUpMFAChain []string
+	// History is the number of recent logins of the user sent along, taken
+	// from the audit log. Defaults to 10.
+	History int
+}
+
+// Decisions of the risk engine.
+const (
+	riskAllow  = "allow"
+	riskDeny   = "deny"
+	riskStepUp = "step_up"
+)
+
+// Flows in which tokens are issued, sent to the risk engine.
+const (
+	riskFlowLogin         = "login"
+	riskFlowSession       = "session"
+	riskFlowPassword      = "password"
+	riskFlowRefresh       = "refresh_token"
+	riskFlowTokenExchange = "token_exchange"
+	riskFlowJWTBearer     = "jwt_bearer"
+	riskFlowImpersonation = "impersonation"
+)
+
+// riskAssessment is posted to the risk engine.
+type riskAssessment struct {
+	Flow        string       `json:"flow"`
+	IP          string       `json:"ip"`
+	UserAgent   string       `json:"user_agent"`
+	ConnectorID string       `json:"connector_id"`
+	ClientID    string       `json:"client_id"`
+	Identity    riskIdentity `json:"identity"`
+	History     []riskLogin  `json:"history"`
+	LastLogin   *time.Time   `json:"last_login,omitempty"`
+	Scopes      []string     `json:"scopes"`
+}
+
+type riskIdentity struct {
+	UserID            string   `json:"user_id"`
+	Username          string   `json:"username"`
+	PreferredUsername string   `json:"preferred_username"`
+	Email             string   `json:"email"`
+	EmailVerified     bool     `json:"email_verified"`
+	Groups            []string `json:"groups"`
+}
+
+// riskLogin is a previous login of the user.
+type riskLogin struct {
+	Time      time.Time `json:"time"`
+	ClientID  string    `json:"client_id"`
+	IP        string    `json:"ip,omitempty"`
+	UserAgent string    `json:"user_agent,omitempty"`
+}
+
+// riskDecision is the answer of the risk engine.
+type riskDecision struct {
+	Decision string `json:"decision"`
+	// Reason is logged, it isn't shown to the user.
+	Reason string `json:"reason"`
+}
+
+// errLoginDenied is returned by finalizeLogin if the risk engine denied the
+// login.
+var errLoginDenied = errors.New("login denied by risk engine")
+
+type riskEngine struct {
+	config RiskConfig
+	client *http.Client
+}
+
+// assess posts the assessment to the risk engine and returns its decision.
+func (e *riskEngine) assess(ctx context.Context, a riskAssessment) (riskDecision, error) {
+	body, err := json.Marshal(a)
+	if err != nil {
+		return riskDecision{}, fmt.Errorf("encode assessment: %v", err)
+	}
+	ctx, cancel := context.WithTimeout(ctx, value(e.config.Timeout, 5*time.Second))
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.config.URL, bytes.NewReader(body))
+	if err != nil {
+		return riskDecision{}, err
+	}
+	for k, v := range e.config.Headers {
+		req.Header.Set(k, v)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return riskDecision{}, fmt.Errorf("post assessment: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return riskDecision{}, fmt.Errorf("post assessment: %s: %s", resp.Status, bytes.TrimSpace(msg))
+	}
+
+	var d riskDecision
+	if err := json.NewDecoder(resp.Body).Decode(&d); err != nil {
+		return riskDecision{}, fmt.Errorf("decode decision: %v", err)
+	}
+	switch d.Decision {
+	case riskAllow, riskDeny, riskStepUp:
+		return d, nil
+	}
+	return riskDecision{}, fmt.Errorf("unknown decision %q", d.Decision)
+}
+
+// loginHistory returns the recent logins of a user from the audit log.
+func (s *Server) loginHistory(ctx context.Context, userID, connectorID string) []riskLogin {
+	if s.auditLog == nil {
+		return nil
+	}
+	logins, err := s.auditLog.Storage.ListAuditEvents(ctx, storage.AuditEventFilter{
+		Subject:     userID,
+		ConnectorID: connectorID,
+		Type:        events.TypeLogin,
+		Limit:       s.risk.config.History,
+	})
+	if err != nil {
+		s.logger.ErrorContext(ctx, "failed to list login history", "err", err)
+		return nil
+	}
+	history := make([]riskLogin, 0, len(logins))
+	for _, e := range logins {
+		var data struct {
+			IP        string `json:"ip"`
+			UserAgent string `json:"user_agent"`
+		}
+		json.Unmarshal(e.Data, &data)
+		history = append(history, riskLogin{Time: e.Time, ClientID: e.ClientID, IP: data.IP, UserAgent: data.UserAgent})
+	}
+	return history
+}
+
+// assessLogin asks the risk engine about a login or token request in flow. It
+// reports whether the user has to step up, or returns errLoginDenied.
+func (s *Server) assessLogin(r *http.Request, flow string, identity connector.Identity, connectorID, clientID string, scopes []string) (bool, error) {
+	if s.risk == nil {
+		return false, nil
+	}
+	ctx := r.Context()
+
+	a := riskAssessment{
+		Flow:        flow,
+		IP:          rateLimitIP(r),
+		UserAgent:   r.UserAgent(),
+		ConnectorID: connectorID,
+		ClientID:    clientID,
+		Identity: riskIdentity{
+			UserID:            identity.UserID,
+			Username:          identity.Username,
+			PreferredUsername: identity.PreferredUsername,
+			Email:             identity.Email,
+			EmailVerified:     identity.EmailVerified,
+			Groups:            identity.Groups,
+		},
+		History: s.loginHistory(ctx, identity.UserID, connectorID),
+		Scopes:  scopes,
+	}
+	if ui, err := s.storage.GetUserIdentity(ctx, identity.UserID, connectorID); err == nil && !ui.LastLogin.IsZero() {
+		a.LastLogin = &ui.LastLogin
+	}
+
+	d, err := s.risk.assess(ctx, a)
+	if err != nil {
+		if s.risk.config.FailOpen {
+			s.logger.WarnContext(ctx, "risk assessment failed, allowing login", "user_id", identity.UserID, "err", err)
+			return false, nil
+		}
+		s.logger.ErrorContext(ctx, "risk assessment failed, denying login", "user_id", identity.UserID, "err", err)
+		return false, errLoginDenied
+	}
+
+	switch d.Decision {
+	case riskDeny:
+		s.logger.InfoContext(ctx, "login denied by risk engine", "flow", flow, "user_id", identity.UserID, "connector_id", connectorID, "reason", d.Reason)
+		s.emitEvent(ctx, events.TypeLoginDenied, identity.UserID, map[string]any{
+			"flow":         flow,
+			"connector_id": connectorID,
+			"client_id":    clientID,
+			"user_id":      identity.UserID,
+			"ip":           a.IP,
+			"user_agent":   a.UserAgent,
+			"reason":       d.Reason,
+		})
+		return false, errLoginDenied
+	case riskStepUp:
+		s.logger.InfoContext(ctx, "risk engine requires step-up", "user_id", identity.UserID, "connector_id", connectorID, "reason", d.Reason)
+		return true, nil
+	}
+	return false, nil
+}
+
+// allowTokenRequest asks the risk engine about a token request made without a
+// browser. Since the user can't step up there, step-up is a denial. It writes
+// the error response and returns false if the request is denied.
+func (s *Server) allowTokenRequest(w http.ResponseWriter, r *http.Request, flow string, identity connector.Identity, connectorID, clientID string, scopes []string) bool {
+	stepUp, err := s.assessLogin(r, flow, identity, connectorID, clientID, scopes)
+	if err == nil && !stepUp {
+		return true
+	}
+	if stepUp {
+		s.logger.InfoContext(r.Context(), "risk engine requires step-up, denying token request", "flow", flow, "user_id", identity.UserID, "connector_id", connectorID)
+	}
+	s.tokenErrHelper(w, errAccessDenied, "Token request denied by risk assessment.", http.StatusForbidden)
+	return false
+}
+
+// stepUpMFAChain returns the authenticators of a step-up login with the
+// connector, filtered by its type like the chains of clients.
+func (s *Server) stepUpMFAChain(ctx context.Context, connectorID string) ([]string, error) {
+	if s.risk == nil || len(s.mfaProviders) == 0 {
+		return nil, nil
+	}
+	source := s.risk.config.StepUpMFAChain
+	if len(source) == 0 {
+		source = s.defaultMFAChain
+	}
+	return s.filterMFAChain(ctx, source, connectorID)
+}
@@ -0,0 +1,162 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/dexidp/dex/storage"
+)
+
+func TestRiskEngine(t *testing.T) {
+	ctx := t.Context()
+	setSessionsEnabled(t, true)
+	connID := "mockPw"
+
+	var (
+		decision   string
+		assessment riskAssessment
+	)
+	engine := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "Bearer risk-token", r.Header.Get("Authorization"))
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&assessment))
+		if decision == "" {
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		json.NewEncoder(w).Encode(riskDecision{Decision: decision, Reason: "test"})
+	}))
+	defer engine.Close()
+
+	httpServer, s := newTestServer(t, func(c *Config) {
+		c.MFAProviders = map[string]MFAProvider{"totp": NewTOTPProvider("test-issuer", nil)}
+		c.Risk = &RiskConfig{
+			URL:            engine.URL,
+			Headers:        map[string]string{"Authorization": "Bearer risk-token"},
+			StepUpMFAChain: []string{"totp"},
+		}
+		c.Storage.CreateClient(ctx, storage.Client{ID: "test"})
+	})
+	defer httpServer.Close()
+
+	sc := storage.Connector{
+		ID:              connID,
+		Type:            "mockPassword",
+		Name:            "MockPassword",
+		ResourceVersion: "1",
+		Config:          []byte(`{"username": "foo", "password": "password"}`),
+	}
+	require.NoError(t, s.storage.CreateConnector(ctx, sc))
+	_, err := s.OpenConnector(sc)
+	require.NoError(t, err)
+
+	login := func(id string) *httptest.ResponseRecorder {
+		t.Helper()
+		authReq := storage.AuthRequest{
+			ID:            id,
+			ClientID:      "test",
+			ConnectorID:   connID,
+			RedirectURI:   "cb",
+			Expiry:        time.Now().Add(time.Minute),
+			ResponseTypes: []string{responseTypeCode},
+			HMACKey:       []byte(id),
+		}
+		require.NoError(t, s.storage.CreateAuthRequest(ctx, authReq))
+
+		path := fmt.Sprintf("/auth/%s/login?state=%s&login=foo&password=password", connID, id)
+		req := httptest.NewRequest(http.MethodPost, path, nil)
+		req.Header.Set("User-Agent", "risk-test")
+		rr := httptest.NewRecorder()
+		s.handlePasswordLogin(rr, req)
+		return rr
+	}
+
+	decision = riskAllow
+	rr := login("risk-allow")
+	require.Equal(t, http.StatusSeeOther, rr.Code)
+	require.NotContains(t, rr.Header().Get("Location"), "/mfa")
+	require.Equal(t, "risk-test", assessment.UserAgent)
+	require.Equal(t, "kilgore@kilgore.trout", assessment.Identity.Email)
+	require.Equal(t, connID, assessment.ConnectorID)
+	require.Equal(t, "test", assessment.ClientID)
+
+	decision = riskStepUp
+	rr = login("risk-step-up")
+	require.Equal(t, http.StatusSeeOther, rr.Code)
+	require.Contains(t, rr.Header().Get("Location"), "/mfa/totp")
+	require.NotNil(t, assessment.LastLogin, "the previous login should be sent along")
+
+	decision = riskDeny
+	rr = login("risk-deny")
+	require.Equal(t, http.StatusForbidden, rr.Code)
+	authReq, err := s.storage.GetAuthRequest(ctx, "risk-deny")
+	require.NoError(t, err)
+	require.False(t, authReq.LoggedIn, "denied logins must not be finalized")
+
+	// Logins are denied if the risk engine fails.
+	decision = ""
+	rr = login("risk-unavailable")
+	require.Equal(t, http.StatusForbidden, rr.Code)
+}
+
+func TestRiskEngineTokenRequests(t *testing.T) {
+	var (
+		decision   string
+		assessment riskAssessment
+	)
+	engine := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&assessment))
+		json.NewEncoder(w).Encode(riskDecision{Decision: decision, Reason: "test"})
+	}))
+	defer engine.Close()
+
+	httpServer, s := newTestServer(t, func(c *Config) {
+		c.PasswordConnector = "test"
+		c.Risk = &RiskConfig{URL: engine.URL}
+	})
+	defer httpServer.Close()
+	mockConnectorDataTestStorage(t, s.storage)
+
+	token := func(vals url.Values) *httptest.ResponseRecorder {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodPost, "/token", strings.NewReader(vals.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req.SetBasicAuth("test", "barfoo")
+		rr := httptest.NewRecorder()
+		s.ServeHTTP(rr, req)
+		return rr
+	}
+	passwordGrant := url.Values{
+		"grant_type": {grantTypePassword},
+		"scope":      {"openid offline_access"},
+		"username":   {"test"},
+		"password":   {"test"},
+	}
+
+	decision = riskAllow
+	rr := token(passwordGrant)
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	require.Equal(t, riskFlowPassword, assessment.Flow)
+	var resp struct {
+		RefreshToken string `json:"refresh_token"`
+	}
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+
+	// Users can't step up without a browser.
+	decision = riskStepUp
+	rr = token(passwordGrant)
+	require.Equal(t, http.StatusForbidden, rr.Code)
+
+	decision = riskDeny
+	rr = token(url.Values{"grant_type": {grantTypeRefreshToken}, "refresh_token": {resp.RefreshToken}})
+	require.Equal(t, http.StatusForbidden, rr.Code)
+	require.Equal(t, riskFlowRefresh, assessment.Flow)
+	require.Equal(t, "test", assessment.ConnectorID)
+}
@@ -2,16 +2,15 @@ package server
 
 import (
 	"context"
+	"crypto"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io/fs"
 	"log/slog"
-	"net"
 	"net/http"
 	"net/netip"
 	"net/url"
-	"os"
 	"path"
 	"slices"
 	"sort"
@@ -22,16 +21,19 @@ import (
 
 	gosundheit "github.com/AppsFlyer/go-sundheit"
 	"github.com/google/uuid"
-	"github.com/gorilla/handlers"
 	"github.com/gorilla/mux"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"golang.org/x/crypto/bcrypt"
+	"golang.org/x/sync/singleflight"
 
 	"github.com/dexidp/dex/connector"
+	"github.com/dexidp/dex/connector/apple"
 	"github.com/dexidp/dex/connector/atlassiancrowd"
 	"github.com/dexidp/dex/connector/authproxy"
 	"github.com/dexidp/dex/connector/bitbucketcloud"
+	"github.com/dexidp/dex/connector/cas"
+	"github.com/dexidp/dex/connector/external"
 	"github.com/dexidp/dex/connector/gitea"
 	"github.com/dexidp/dex/connector/github"
 	"github.com/dexidp/dex/connector/gitlab"
@@ -45,11 +47,13 @@ import (
 	"github.com/dexidp/dex/connector/oauth"
 	"github.com/dexidp/dex/connector/oidc"
 	"github.com/dexidp/dex/connector/openshift"
+	"github.com/dexidp/dex/connector/radius"
 	"github.com/dexidp/dex/connector/saml"
+	"github.com/dexidp/dex/pkg/events"
 	"github.com/dexidp/dex/pkg/featureflags"
+	"github.com/dexidp/dex/pkg/httpclient"
 	"github.com/dexidp/dex/server/signer"
 	"github.com/dexidp/dex/storage"
-	"github.com/dexidp/dex/web"
 )
 
 // LocalConnector is the local passwordDB connector which is an internal
@@ -88,9 +92,12 @@ type Config struct {
 	// Headers is a map of headers to be added to the all responses.
 	Headers http.Header
 
-	// Header to extract real ip from.
-	RealIPHeader       string
-	TrustedRealIPCIDRs []netip.Prefix
+	// Header to extract real ip from, e.g. X-Forwarded-For, Forwarded or
+	// X-Real-IP. It is only honored for requests from TrustedRealIPCIDRs, and
+	// through unix sockets if TrustRealIPUnixSocket is set.
+	RealIPHeader          string
+	TrustedRealIPCIDRs    []netip.Prefix
+	TrustRealIPUnixSocket bool
 
 	// List of allowed origins for CORS requests on discovery, token and keys endpoint.
 	// If none are indicated, CORS requests are disabled. Passing in "*" will allow any
@@ -100,6 +107,13 @@ type Config struct {
 	// List of allowed headers for CORS requests on discovery, token, and keys endpoint.
 	AllowedHeaders []string
 
+	// CORS overrides AllowedOrigins and AllowedHeaders per endpoint.
+	CORS CORSConfig
+
+	// DiscoveryOverrides are added to, or replace fields of, the discovery
+	// document, e.g. to advertise a revocation_endpoint served elsewhere.
+	DiscoveryOverrides map[string]interface{}
+
 	// If enabled, the server won't prompt the user to approve authorization requests.
 	// Logging in implies approval.
 	SkipApprovalScreen bool
@@ -114,9 +128,27 @@ type Config struct {
 	// Refresh token expiration settings
 	RefreshTokenPolicy *RefreshTokenPolicy
 
+	// OfflineSessionPolicy prunes stale refresh tokens during garbage
+	// collection. Nil disables pruning.
+	OfflineSessionPolicy *OfflineSessionPolicy
+
 	// If set, the server will use this connector to handle password grants
 	PasswordConnector string
 
+	// TokenExchangeRequiresPolicy controls whether only clients with a token
+	// exchange policy can use token exchange. If nil, a policy is only
+	// required to exchange tokens of HSDP connectors.
+	TokenExchangeRequiresPolicy *bool
+
+	// RelayStateKey signs the relay states connectors carry through upstream
+	// logins. If empty, a random key is generated, so relay states aren't
+	// accepted after a restart or by other dex instances.
+	RelayStateKey []byte
+
+	// GroupsClaim is the name and format of the groups claim in tokens.
+	// Clients can override it. Defaults to a "groups" array.
+	GroupsClaim storage.GroupsClaim
+
 	// PKCE configuration
 	PKCE PKCEConfig
 
@@ -132,7 +164,10 @@ type Config struct {
 	// Signer is used to sign tokens.
 	Signer signer.Signer
 
-	PrometheusRegistry *prometheus.Registry
+	// PrometheusRegistry registers the metrics of the server. Servers sharing
+	// a registry must wrap it to tell their metrics apart, for example with
+	// prometheus.WrapRegistererWith.
+	PrometheusRegistry prometheus.Registerer
 
 	HealthChecker gosundheit.Health
 
@@ -150,6 +185,98 @@ type Config struct {
 	DefaultMFAChain []string
 
 	AllowedScopePrefixes []string
+
+	// RateLimit configures request rate limits for the authorization, token and
+	// device endpoints. Nil disables rate limiting.
+	RateLimit *RateLimitConfig
+
+	// RateLimiter keeps the buckets of the request and self-service rate
+	// limits. Defaults to an in-memory limiter, which only enforces limits
+	// within a single dex process.
+	RateLimiter RateLimiter
+
+	// RateLimitStorage keeps the failed logins counted by the login throttle
+	// and the CAPTCHA. Defaults to memory, which only counts the failed logins
+	// of a single dex process.
+	RateLimitStorage storage.RateLimitStorage
+
+	// ReplayStorage keeps the one-time values of connectors, e.g. the
+	// assertions of unsolicited SAML responses, in rate limit buckets.
+	// Defaults to memory, which only detects replays to a single dex process.
+	ReplayStorage storage.RateLimitStorage
+
+	// LoginThrottle throttles password logins per client IP address after
+	// failed attempts. Nil disables it.
+	LoginThrottle *LoginThrottleConfig
+
+	// Captcha requires a CAPTCHA on the password login forms. Nil disables
+	// it.
+	Captcha *CaptchaConfig
+
+	// Risk posts logins to a risk engine before tokens are issued. Nil
+	// disables it.
+	Risk *RiskConfig
+
+	// DrainTimeout bounds how long Drain waits for a login in flight. Logins
+	// are not tracked if zero.
+	DrainTimeout time.Duration
+
+	// Maintenance is the maintenance mode the server starts in. It can be
+	// changed later with SetMaintenance.
+	Maintenance Maintenance
+
+	// RequestLimits bounds the handling time and request body size of the
+	// authorization, token and device endpoints.
+	RequestLimits RequestLimits
+
+	// PasswordReset enables the "forgot password" flow of the local connector.
+	// Nil disables it.
+	PasswordReset *PasswordResetConfig
+
+	// EmailVerification enables verifying the emails of password db users
+	// created through the API. Nil disables it.
+	EmailVerification *EmailVerificationConfig
+
+	// Registration enables self-service sign-up for the local connector. Nil
+	// disables it.
+	Registration *RegistrationConfig
+
+	// UpstreamHTTP configures the HTTP clients connectors call their
+	// upstreams with. Connectors that don't support shared clients create
+	// their own.
+	UpstreamHTTP httpclient.FactoryConfig
+
+	// Events receives audit and identity events as CloudEvents. Sends happen
+	// while handling requests, so slow sinks should be wrapped in an
+	// events.Queue. Nil disables events.
+	Events events.Sink
+
+	// AuditLog keeps the audit and identity events in the storage, so they
+	// can be queried through the API. Nil disables the history.
+	AuditLog *AuditLogConfig
+
+	// LogLevels lets the API change the log levels at runtime. Nil disables
+	// the log level methods of the API.
+	LogLevels LogLevels
+}
+
+// LogLevels are the log levels of the running server, kept by whoever created
+// its logger.
+type LogLevels interface {
+	// LogLevels returns the default level and the levels of single modules.
+	LogLevels() (slog.Level, map[string]slog.Level)
+	// SetLogLevel changes the level of a module. A nil level makes the module
+	// use the default level again, an empty module changes the default.
+	SetLogLevel(module string, level *slog.Level)
+}
+
+// AuditLogConfig configures the audit history kept in the storage.
+type AuditLogConfig struct {
+	// Storage keeps the events. Usually the same storage the server uses.
+	Storage storage.AuditStorage
+
+	// Retention is how long events are kept.
+	Retention time.Duration
 }
 
 // SessionConfig holds resolved session configuration.
@@ -193,6 +320,30 @@ type WebConfig struct {
 
 	// Map of extra values passed into the templates
 	Extra map[string]string
+
+	// Title of the web pages. Defaults to Issuer.
+	Title string
+
+	// FooterText is shown at the bottom of every page, e.g. a copyright
+	// notice.
+	FooterText string
+
+	// FooterLinks are shown at the bottom of every page, unless the client
+	// has footer links of its own in its theme.
+	FooterLinks []storage.ThemeLink
+
+	// CustomCSS is added to every page after the theme's stylesheet, so
+	// small changes such as colors or fonts don't need a custom theme.
+	CustomCSS string
+
+	// Languages offered on the web pages, picked by the browser's
+	// Accept-Language header. The first one is used if none of them match.
+	// Defaults to English followed by every language with a message catalog.
+	Languages []string
+
+	// Messages overrides single translations, keyed by language and the
+	// English text of the message.
+	Messages map[string]map[string]string
 }
 
 // PKCEConfig holds PKCE (Proof Key for Code Exchange) settings.
@@ -218,12 +369,22 @@ type Server struct {
 	mu sync.Mutex
 	// Map of connector IDs to connectors.
 	connectors map[string]Connector
+	// Replaced and removed connectors waiting to be closed.
+	retiredConnectors map[*retiredConnector]struct{}
 
 	storage storage.Storage
 
 	mux http.Handler
 
-	templates *templates
+	// The frontend templates and assets, replaced by ReloadWeb.
+	web atomic.Pointer[webAssets]
+
+	// Set by Drain to stop starting new logins.
+	draining atomic.Bool
+	// Logins in flight, waited for by Drain.
+	logins loginTracker
+	// Set by SetMaintenance to refuse new logins with a maintenance page.
+	maintenance atomic.Pointer[Maintenance]
 
 	// If enabled, don't prompt user for approval after logging in through connector.
 	skipApproval bool
@@ -234,6 +395,12 @@ type Server struct {
 	// Used for password grant
 	passwordConnector string
 
+	tokenExchangeRequiresPolicy *bool
+
+	relayStateKey []byte
+
+	groupsClaim storage.GroupsClaim
+
 	supportedResponseTypes map[string]bool
 
 	supportedGrantTypes []string
@@ -250,14 +417,57 @@ type Server struct {
 
 	refreshTokenPolicy *RefreshTokenPolicy
 
+	offlineSessionPolicy *OfflineSessionPolicy
+	offlineSessionsGauge *prometheus.GaugeVec
+
 	logger *slog.Logger
 
 	signer signer.Signer
 
 	sessionConfig *SessionConfig
 
+	discoveryOverrides map[string]interface{}
+
 	mfaProviders    map[string]MFAProvider
 	defaultMFAChain []string
+
+	rateLimit           *RateLimitConfig
+	rateLimitedRequests *prometheus.CounterVec
+
+	passwordReset     *PasswordResetConfig
+	emailVerification *EmailVerificationConfig
+	registration      *RegistrationConfig
+
+	events    events.Sink
+	auditLog  *AuditLogConfig
+	logLevels LogLevels
+
+	// httpClients creates the HTTP clients connectors call their upstreams
+	// with.
+	httpClients *httpclient.Factory
+
+	// replayStorage keeps the one-time values of connectors.
+	replayStorage storage.RateLimitStorage
+
+	// selfServiceLimiter keeps the rate limit buckets of the password reset
+	// and registration flows.
+	selfServiceLimiter RateLimiter
+
+	loginThrottle *loginThrottle
+	captcha       *captcha
+	risk          *riskEngine
+
+	publicKeys publicKeysCache
+
+	clientOrigins clientOriginsCache
+
+	releasedClaimsCache releasedClaimsCache
+
+	jwtBearerKeys jwtBearerKeySets
+
+	// refreshGroup deduplicates concurrent redemptions of the same refresh token.
+	refreshGroup         singleflight.Group
+	offlineSessionWrites lastUsedWrites
 }
 
 // NewServer constructs a server from the provided config.
@@ -300,6 +510,7 @@ func newServer(ctx context.Context, c Config) (*Server, error) {
 		grantTypeRefreshToken:      true,
 		grantTypeDeviceCode:        true,
 		grantTypeTokenExchange:     true,
+		grantTypeJWTBearer:         true,
 	}
 	supportedRes := make(map[string]bool)
 
@@ -337,23 +548,14 @@ func newServer(ctx context.Context, c Config) (*Server, error) {
 	}
 	sort.Strings(supportedGrants)
 
-	webFS := web.FS()
-	if c.Web.Dir != "" {
-		webFS = os.DirFS(c.Web.Dir)
-	} else if c.Web.WebFS != nil {
-		webFS = c.Web.WebFS
+	if err := ValidateGroupsClaim(c.GroupsClaim); err != nil {
+		return nil, fmt.Errorf("server: invalid groups claim: %v", err)
 	}
-
-	web := webConfig{
-		webFS:     webFS,
-		logoURL:   c.Web.LogoURL,
-		issuerURL: c.Issuer,
-		issuer:    c.Web.Issuer,
-		theme:     c.Web.Theme,
-		extra:     c.Web.Extra,
+	if err := ValidateDiscoveryOverrides(c.DiscoveryOverrides); err != nil {
+		return nil, fmt.Errorf("server: invalid discovery overrides: %v", err)
 	}
 
-	static, theme, robots, tmpls, err := loadWebConfig(web)
+	assets, err := newWebAssets(c.Issuer, c.Web)
 	if err != nil {
 		return nil, fmt.Errorf("server: failed to load web static: %v", err)
 	}
@@ -372,19 +574,144 @@ func newServer(ctx context.Context, c Config) (*Server, error) {
 		pkce:                   c.PKCE,
 		allowedScopePrefixes:   c.AllowedScopePrefixes,
 		idTokensValidFor:       value(c.IDTokensValidFor, 24*time.Hour),
+		logins:                 loginTracker{maxAge: c.DrainTimeout},
 		authRequestsValidFor:   value(c.AuthRequestsValidFor, 24*time.Hour),
 		deviceRequestsValidFor: value(c.DeviceRequestsValidFor, 5*time.Minute),
 		refreshTokenPolicy:     c.RefreshTokenPolicy,
+		offlineSessionPolicy:   c.OfflineSessionPolicy,
 		skipApproval:           c.SkipApprovalScreen,
 		alwaysShowLogin:        c.AlwaysShowLoginScreen,
 		now:                    now,
-		templates:              tmpls,
 		passwordConnector:      c.PasswordConnector,
 		logger:                 c.Logger,
 		signer:                 c.Signer,
 		sessionConfig:          c.SessionConfig,
+		discoveryOverrides:     c.DiscoveryOverrides,
 		mfaProviders:           c.MFAProviders,
 		defaultMFAChain:        c.DefaultMFAChain,
+		rateLimit:              c.RateLimit,
+
+		tokenExchangeRequiresPolicy: c.TokenExchangeRequiresPolicy,
+		relayStateKey:               c.RelayStateKey,
+		groupsClaim:                 c.GroupsClaim,
+	}
+	if len(s.relayStateKey) == 0 {
+		s.relayStateKey = storage.NewHMACKey(crypto.SHA256)
+	}
+
+	s.web.Store(assets)
+	s.SetMaintenance(c.Maintenance)
+
+	limiter := c.RateLimiter
+	if limiter == nil {
+		limiter = NewMemoryRateLimiter(now)
+	}
+	if s.rateLimit != nil && s.rateLimit.Limiter == nil {
+		s.rateLimit.Limiter = limiter
+	}
+
+	if c.LoginThrottle != nil {
+		throttleConfig := *c.LoginThrottle
+		if throttleConfig.FreeAttempts == 0 {
+			throttleConfig.FreeAttempts = 5
+		}
+		throttleConfig.Delay = value(throttleConfig.Delay, time.Second)
+		throttleConfig.MaxDelay = value(throttleConfig.MaxDelay, time.Minute)
+		throttleConfig.BlockFor = value(throttleConfig.BlockFor, 15*time.Minute)
+		throttleConfig.Window = value(throttleConfig.Window, 15*time.Minute)
+		s.loginThrottle = newLoginThrottle(throttleConfig, c.RateLimitStorage, "login:", now)
+	}
+
+	if c.PasswordReset != nil {
+		if c.PasswordReset.Mailer == nil {
+			return nil, errors.New("server: password reset requires a mailer")
+		}
+		resetConfig := *c.PasswordReset
+		if len(resetConfig.Key) == 0 {
+			resetConfig.Key = storage.NewHMACKey(crypto.SHA256)
+		}
+		resetConfig.TokenValidFor = value(resetConfig.TokenValidFor, time.Hour)
+		if !resetConfig.RateLimit.enabled() {
+			resetConfig.RateLimit = defaultPasswordResetRateLimit
+		}
+		s.passwordReset = &resetConfig
+	}
+
+	if c.EmailVerification != nil {
+		if c.EmailVerification.Mailer == nil {
+			return nil, errors.New("server: email verification requires a mailer")
+		}
+		verificationConfig := *c.EmailVerification
+		if len(verificationConfig.Key) == 0 {
+			verificationConfig.Key = storage.NewHMACKey(crypto.SHA256)
+		}
+		verificationConfig.TokenValidFor = value(verificationConfig.TokenValidFor, 24*time.Hour)
+		s.emailVerification = &verificationConfig
+	}
+
+	if c.Registration != nil {
+		if c.Registration.VerifyEmail && s.emailVerification == nil {
+			return nil, errors.New("server: registration with email verification requires email verification to be enabled")
+		}
+		registrationConfig := *c.Registration
+		if !registrationConfig.RateLimit.enabled() {
+			registrationConfig.RateLimit = defaultRegistrationRateLimit
+		}
+		s.registration = &registrationConfig
+	}
+
+	s.events = c.Events
+	s.auditLog = c.AuditLog
+	s.logLevels = c.LogLevels
+
+	s.replayStorage = c.ReplayStorage
+	if s.replayStorage == nil {
+		s.replayStorage = newMemoryBuckets(now)
+	}
+
+	s.httpClients, err = httpclient.NewFactory(c.UpstreamHTTP)
+	if err != nil {
+		return nil, fmt.Errorf("server: invalid upstream HTTP config: %v", err)
+	}
+	if c.PrometheusRegistry != nil {
+		if err := s.httpClients.Register(c.PrometheusRegistry); err != nil {
+			return nil, fmt.Errorf("server: failed to register upstream HTTP metrics: %v", err)
+		}
+	}
+
+	if c.Captcha != nil {
+		client, err := s.httpClients.Client("captcha", nil, false)
+		if err != nil {
+			return nil, fmt.Errorf("server: failed to create CAPTCHA HTTP client: %v", err)
+		}
+		if s.captcha, err = newCaptcha(*c.Captcha, client, c.RateLimitStorage, now); err != nil {
+			return nil, fmt.Errorf("server: %v", err)
+		}
+	}
+
+	if c.Risk != nil {
+		client, err := s.httpClients.Client("risk", nil, false)
+		if err != nil {
+			return nil, fmt.Errorf("server: failed to create risk engine HTTP client: %v", err)
+		}
+		riskConfig := *c.Risk
+		if riskConfig.History == 0 {
+			riskConfig.History = 10
+		}
+		for _, authID := range riskConfig.StepUpMFAChain {
+			if _, ok := s.mfaProviders[authID]; !ok {
+				return nil, fmt.Errorf("server: risk step-up MFA chain references unknown authenticator %q", authID)
+			}
+		}
+		s.risk = &riskEngine{config: riskConfig, client: client}
+	}
+
+	if s.passwordReset != nil || s.registration != nil {
+		if s.rateLimit != nil {
+			s.selfServiceLimiter = s.rateLimit.Limiter
+		} else {
+			s.selfServiceLimiter = limiter
+		}
 	}
 
 	// Retrieves connector objects in backend storage. This list includes the static connectors
@@ -442,6 +769,19 @@ func newServer(ctx context.Context, c Config) (*Server, error) {
 
 		c.PrometheusRegistry.MustRegister(requestCounter, durationHist, sizeHist)
 
+		if s.rateLimit != nil || s.selfServiceLimiter != nil || s.loginThrottle != nil {
+			s.rateLimitedRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+				Name: "rate_limited_requests_total",
+				Help: "Count of requests rejected by rate limits.",
+			}, []string{"scope"})
+			c.PrometheusRegistry.MustRegister(s.rateLimitedRequests)
+		}
+
+		if s.offlineSessionPolicy != nil {
+			s.offlineSessionsGauge = newOfflineSessionsGauge()
+			c.PrometheusRegistry.MustRegister(s.offlineSessionsGauge)
+		}
+
 		instrumentHandler = func(handlerName string, handler http.Handler) http.HandlerFunc {
 			return promhttp.InstrumentHandlerDuration(durationHist.MustCurryWith(prometheus.Labels{"handler": handlerName}),
 				promhttp.InstrumentHandlerCounter(requestCounter.MustCurryWith(prometheus.Labels{"handler": handlerName}),
@@ -451,33 +791,7 @@ func newServer(ctx context.Context, c Config) (*Server, error) {
 		}
 	}
 
-	parseRealIP := func(r *http.Request) (string, error) {
-		remoteAddr, _, err := net.SplitHostPort(r.RemoteAddr)
-		if err != nil {
-			return "", err
-		}
-
-		remoteIP, err := netip.ParseAddr(remoteAddr)
-		if err != nil {
-			return "", err
-		}
-
-		for _, n := range c.TrustedRealIPCIDRs {
-			if !n.Contains(remoteIP) {
-				return remoteAddr, nil // Fallback to the address from the request if the header is provided
-			}
-		}
-
-		ipVal := r.Header.Get(c.RealIPHeader)
-		if ipVal != "" {
-			ip, err := netip.ParseAddr(ipVal)
-			if err == nil {
-				return ip.String(), nil
-			}
-		}
-
-		return remoteAddr, nil
-	}
+	clientIP := newClientIPResolver(c.RealIPHeader, c.TrustedRealIPCIDRs, c.TrustRealIPUnixSocket)
 
 	handlerWithHeaders := func(handlerName string, handler http.Handler) http.HandlerFunc {
 		return func(w http.ResponseWriter, r *http.Request) {
@@ -487,14 +801,10 @@ func newServer(ctx context.Context, c Config) (*Server, error) {
 
 			// Context values are used for logging purposes with the log/slog logger.
 			rCtx := r.Context()
-			rCtx = WithRequestID(rCtx)
-
-			if c.RealIPHeader != "" {
-				realIP, err := parseRealIP(r)
-				if err == nil {
-					rCtx = WithRemoteIP(rCtx, realIP)
-				}
-			}
+			id := requestID(r)
+			w.Header().Set(requestIDHeader, id)
+			rCtx = withRequestID(rCtx, id)
+			rCtx = WithRemoteIP(rCtx, clientIP.resolve(r))
 
 			r = r.WithContext(rCtx)
 			instrumentHandler(handlerName, handler)(w, r)
@@ -512,16 +822,20 @@ func newServer(ctx context.Context, c Config) (*Server, error) {
 		prefix := path.Join(issuerURL.Path, p)
 		r.PathPrefix(prefix).Handler(http.StripPrefix(prefix, h))
 	}
-	handleWithCORS := func(p string, h http.HandlerFunc) {
-		var handler http.Handler = h
-		if len(c.AllowedOrigins) > 0 {
-			cors := handlers.CORS(
-				handlers.AllowedOrigins(c.AllowedOrigins),
-				handlers.AllowedHeaders(c.AllowedHeaders),
-			)
-			handler = cors(handler)
+	defaultCORS := &CORSPolicy{AllowedOrigins: c.AllowedOrigins, AllowedHeaders: c.AllowedHeaders}
+	handleWithCORS := func(p string, policy *CORSPolicy, h http.HandlerFunc) {
+		if policy == nil {
+			policy = defaultCORS
 		}
-		r.Handle(path.Join(issuerURL.Path, p), handlerWithHeaders(p, handler))
+		r.Handle(path.Join(issuerURL.Path, p), handlerWithHeaders(p, s.withCORS(policy, h)))
+	}
+	// The CORS policy may read the client ID from the form, so the limits
+	// apply to it too.
+	handleWithLimitsAndCORS := func(p string, l EndpointLimits, policy *CORSPolicy, h http.HandlerFunc) {
+		if policy == nil {
+			policy = defaultCORS
+		}
+		r.Handle(path.Join(issuerURL.Path, p), handlerWithHeaders(p, LimitHandler(l, s.withCORS(policy, h))))
 	}
 	r.NotFoundHandler = http.NotFoundHandler()
 
@@ -529,24 +843,24 @@ func newServer(ctx context.Context, c Config) (*Server, error) {
 	if err != nil {
 		return nil, err
 	}
-	handleWithCORS("/.well-known/openid-configuration", discoveryHandler)
-	handleWithCORS("/", s.handleHome)
-
-	// TODO(ericchiang): rate limit certain paths based on IP.
-	handleWithCORS("/token", s.handleToken)
-	handleWithCORS("/keys", s.handlePublicKeys)
-	handleWithCORS("/userinfo", s.handleUserInfo)
-	handleWithCORS("/token/introspect", s.handleIntrospect)
-	handleFunc("/auth", s.handleAuthorization)
-	handleFunc("/auth/{connector}", s.handleConnectorLogin)
-	handleFunc("/auth/{connector}/login", s.handlePasswordLogin)
-	handleFunc("/device", s.handleDeviceExchange)
-	handleFunc("/device/auth/verify_code", s.verifyUserCode)
-	handleFunc("/device/code", s.handleDeviceCode)
+	handleWithCORS("/.well-known/openid-configuration", c.CORS.Discovery, discoveryHandler)
+	handleWithCORS("/", nil, s.handleHome)
+
+	handleWithLimitsAndCORS("/token", c.RequestLimits.Token, c.CORS.Token, s.withRateLimit(true, s.handleToken))
+	handleWithCORS("/keys", c.CORS.Keys, s.handlePublicKeys)
+	handleWithLimitsAndCORS("/userinfo", c.RequestLimits.Token, c.CORS.UserInfo, s.handleUserInfo)
+	handleWithLimitsAndCORS("/token/introspect", c.RequestLimits.Token, c.CORS.Introspect, s.handleIntrospect)
+	handleFunc("/auth", withLimits(c.RequestLimits.Auth, s.withRateLimit(false, s.handleAuthorization)))
+	handleFunc("/auth/{connector}", withLimits(c.RequestLimits.Auth, s.withRateLimit(false, s.handleConnectorLogin)))
+	handleFunc("/auth/{connector}/login", withLimits(c.RequestLimits.Auth, s.withRateLimit(false, s.handlePasswordLogin)))
+	handleFunc("/device", withLimits(c.RequestLimits.Device, s.withRateLimit(false, s.handleDeviceExchange)))
+	handleFunc("/device/auth/verify_code", withLimits(c.RequestLimits.Device, s.withRateLimit(false, s.verifyUserCode)))
+	handleFunc("/device/code", withLimits(c.RequestLimits.Device, s.withRateLimit(true, s.handleDeviceCode)))
+	handleFunc("/device/qr", withLimits(c.RequestLimits.Device, s.withRateLimit(false, s.handleDeviceQRCode)))
 	// TODO(nabokihms): "/device/token" endpoint is deprecated, consider using /token endpoint instead
-	handleFunc("/device/token", s.handleDeviceTokenDeprecated)
-	handleFunc(deviceCallbackURI, s.handleDeviceCallback)
-	handleFunc("/callback", func(w http.ResponseWriter, r *http.Request) {
+	handleFunc("/device/token", withLimits(c.RequestLimits.Device, s.withRateLimit(true, s.handleDeviceTokenDeprecated)))
+	handleFunc(deviceCallbackURI, withLimits(c.RequestLimits.Device, s.handleDeviceCallback))
+	handleFunc("/callback", withLimits(c.RequestLimits.Auth, func(w http.ResponseWriter, r *http.Request) {
 		// Strip the X-Remote-* headers to prevent security issues on
 		// misconfigured authproxy connector setups.
 		for key := range r.Header {
@@ -555,24 +869,34 @@ func newServer(ctx context.Context, c Config) (*Server, error) {
 			}
 		}
 		s.handleConnectorCallback(w, r)
-	})
+	}))
 	// For easier connector-specific web server configuration, e.g. for the
 	// "authproxy" connector.
-	handleFunc("/callback/{connector}", s.handleConnectorCallback)
-	handleFunc("/approval", s.handleApproval)
+	handleFunc("/callback/{connector}", withLimits(c.RequestLimits.Auth, s.handleConnectorCallback))
+	handleFunc("/approval", withLimits(c.RequestLimits.Auth, s.handleApproval))
+	if s.passwordReset != nil {
+		handleFunc("/password/reset", withLimits(c.RequestLimits.Auth, s.withRateLimit(false, s.handlePasswordResetRequest)))
+		handleFunc("/password/reset/confirm", withLimits(c.RequestLimits.Auth, s.withRateLimit(false, s.handlePasswordReset)))
+	}
+	if s.emailVerification != nil {
+		handleFunc("/verify-email", withLimits(c.RequestLimits.Auth, s.withRateLimit(false, s.handleVerifyEmail)))
+	}
+	if s.registration != nil {
+		handleFunc("/register", withLimits(c.RequestLimits.Auth, s.withRateLimit(false, s.handleRegister)))
+	}
 	// OIDC RP-Initiated logout endpoints, DEX_SESSIONS_ENABLED=true feature flag is required.
 	if c.SessionConfig != nil {
-		handleFunc("/logout", s.handleLogout)
-		handleFunc("/logout/callback", s.handleLogoutCallback)
+		handleFunc("/logout", withLimits(c.RequestLimits.Auth, s.handleLogout))
+		handleFunc("/logout/callback", withLimits(c.RequestLimits.Auth, s.handleLogoutCallback))
 	}
 	// MFA verification endpoints, DEX_SESSIONS_ENABLED=true feature flag is required.
 	if c.SessionConfig != nil {
-		handleFunc("/mfa/totp", s.handleTOTP)
-		handleFunc("/mfa/webauthn", s.handleWebAuthn)
-		handleFunc("/mfa/webauthn/register/begin", s.handleWebAuthnRegisterBegin)
-		handleFunc("/mfa/webauthn/register/finish", s.handleWebAuthnRegisterFinish)
-		handleFunc("/mfa/webauthn/login/begin", s.handleWebAuthnLoginBegin)
-		handleFunc("/mfa/webauthn/login/finish", s.handleWebAuthnLoginFinish)
+		handleFunc("/mfa/totp", withLimits(c.RequestLimits.Auth, s.handleTOTP))
+		handleFunc("/mfa/webauthn", withLimits(c.RequestLimits.Auth, s.handleWebAuthn))
+		handleFunc("/mfa/webauthn/register/begin", withLimits(c.RequestLimits.Auth, s.handleWebAuthnRegisterBegin))
+		handleFunc("/mfa/webauthn/register/finish", withLimits(c.RequestLimits.Auth, s.handleWebAuthnRegisterFinish))
+		handleFunc("/mfa/webauthn/login/begin", withLimits(c.RequestLimits.Auth, s.handleWebAuthnLoginBegin))
+		handleFunc("/mfa/webauthn/login/finish", withLimits(c.RequestLimits.Auth, s.handleWebAuthnLoginFinish))
 	}
 	handle("/healthz", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		if !c.HealthChecker.IsHealthy() {
@@ -582,9 +906,15 @@ func newServer(ctx context.Context, c Config) (*Server, error) {
 		fmt.Fprintf(w, "Health check passed")
 	}))
 
-	handlePrefix("/static", static)
-	handlePrefix("/theme", theme)
-	handleFunc("/robots.txt", robots)
+	handlePrefix("/static", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.web.Load().static.ServeHTTP(w, r)
+	}))
+	handlePrefix("/theme", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.web.Load().theme.ServeHTTP(w, r)
+	}))
+	handleFunc("/robots.txt", func(w http.ResponseWriter, r *http.Request) {
+		s.web.Load().robots(w, r)
+	})
 
 	s.mux = r
 
@@ -733,13 +1063,23 @@ func (s *Server) startGarbageCollection(ctx context.Context, frequency time.Dura
 			case <-ctx.Done():
 				return
 			case <-time.After(frequency):
+				s.logins.prune(now())
 				if r, err := s.storage.GarbageCollect(ctx, now()); err != nil {
 					s.logger.ErrorContext(ctx, "garbage collection failed", "err", err)
 				} else if !r.IsEmpty() {
 					s.logger.InfoContext(ctx, "garbage collection run, delete auth",
 						"requests", r.AuthRequests, "auth_codes", r.AuthCodes,
 						"device_requests", r.DeviceRequests, "device_tokens", r.DeviceTokens,
-						"auth_sessions", r.AuthSessions)
+						"auth_sessions", r.AuthSessions, "rate_limit_buckets", r.RateLimitBuckets,
+						"audit_events", r.AuditEvents)
+				}
+				if s.offlineSessionPolicy != nil {
+					if tokens, sessions, err := s.pruneOfflineSessions(ctx, now()); err != nil {
+						s.logger.ErrorContext(ctx, "offline session pruning failed", "err", err)
+					} else if tokens > 0 || sessions > 0 {
+						s.logger.InfoContext(ctx, "offline session pruning run, delete",
+							"refresh_tokens", tokens, "offline_sessions", sessions)
+					}
 				}
 			}
 		}
@@ -764,6 +1104,9 @@ var ConnectorsConfig = map[string]func() ConnectorConfig{
 	"google":          func() ConnectorConfig { return new(google.Config) },
 	"hsdp":            func() ConnectorConfig { return new(hsdp.Config) },
 	"oidc":            func() ConnectorConfig { return new(oidc.Config) },
+	"apple":           func() ConnectorConfig { return new(apple.Config) },
+	"cas":             func() ConnectorConfig { return new(cas.Config) },
+	"keycloak":        func() ConnectorConfig { return new(oidc.KeycloakConfig) },
 	"oauth":           func() ConnectorConfig { return new(oauth.Config) },
 	"saml":            func() ConnectorConfig { return new(saml.Config) },
 	"authproxy":       func() ConnectorConfig { return new(authproxy.Config) },
@@ -772,12 +1115,53 @@ var ConnectorsConfig = map[string]func() ConnectorConfig{
 	"bitbucket-cloud": func() ConnectorConfig { return new(bitbucketcloud.Config) },
 	"openshift":       func() ConnectorConfig { return new(openshift.Config) },
 	"atlassian-crowd": func() ConnectorConfig { return new(atlassiancrowd.Config) },
+	"external":        func() ConnectorConfig { return new(external.Config) },
+	"radius":          func() ConnectorConfig { return new(radius.Config) },
 	// Keep around for backwards compatibility.
 	"samlExperimental": func() ConnectorConfig { return new(saml.Config) },
 }
 
+// connectorHTTPClients creates the HTTP clients of a connector, labelling
+// their metrics with the connector ID.
+type connectorHTTPClients struct {
+	factory *httpclient.Factory
+	id      string
+}
+
+func (c connectorHTTPClients) HTTPClient(rootCAs []string, insecureSkipVerify bool) (*http.Client, error) {
+	return c.factory.Client(c.id, rootCAs, insecureSkipVerify)
+}
+
+func (c connectorHTTPClients) TunedHTTPClient(rootCAs []string, insecureSkipVerify bool, t connector.HTTPTransportConfig) (*http.Client, error) {
+	return c.factory.TunedClient(c.id, rootCAs, insecureSkipVerify, httpclient.TransportConfig(t))
+}
+
+// connectorReplays keeps the one-time values of a connector in rate limit
+// buckets, which expire with the values.
+type connectorReplays struct {
+	store storage.RateLimitStorage
+	now   func() time.Time
+	id    string
+}
+
+var errReplayed = errors.New("already used")
+
+// Use implements connector.ReplayCache.
+func (c connectorReplays) Use(ctx context.Context, id string, expiry time.Time) error {
+	now := c.now()
+	return c.store.UpdateRateLimitBucket(ctx, "replay:"+c.id+":"+id, func(b storage.RateLimitBucket) (storage.RateLimitBucket, error) {
+		if now.Before(b.Expiry) {
+			return b, errReplayed
+		}
+		b.TAT = now
+		b.Count = 1
+		b.Expiry = expiry
+		return b, nil
+	})
+}
+
 // openConnector will parse the connector config and open the connector.
-func openConnector(logger *slog.Logger, conn storage.Connector) (connector.Connector, error) {
+func openConnector(logger *slog.Logger, conn storage.Connector, httpClients *httpclient.Factory, replays connectorReplays) (connector.Connector, error) {
 	var c connector.Connector
 
 	f, ok := ConnectorsConfig[conn.Type]
@@ -792,6 +1176,13 @@ func openConnector(logger *slog.Logger, conn storage.Connector) (connector.Conne
 			return c, fmt.Errorf("parse connector config: %v", err)
 		}
 	}
+	if cc, ok := connConfig.(connector.HTTPClientConfig); ok && httpClients != nil {
+		cc.SetHTTPClientFactory(connectorHTTPClients{factory: httpClients, id: conn.ID})
+	}
+	if cc, ok := connConfig.(connector.ReplayCacheConfig); ok && replays.store != nil {
+		replays.id = conn.ID
+		cc.SetReplayCache(replays)
+	}
 
 	c, err := connConfig.Open(conn.ID, logger)
 	if err != nil {
@@ -809,7 +1200,7 @@ func (s *Server) OpenConnector(conn storage.Connector) (Connector, error) {
 		c = newPasswordDB(s.storage)
 	} else {
 		var err error
-		c, err = openConnector(s.logger, conn)
+		c, err = openConnector(s.logger.With("component", "connector."+conn.ID), conn, s.httpClients, connectorReplays{store: s.replayStorage, now: s.now})
 		if err != nil {
 			return Connector{}, fmt.Errorf("failed to open connector: %v", err)
 		}
@@ -822,17 +1213,28 @@ func (s *Server) OpenConnector(conn storage.Connector) (Connector, error) {
 		GrantTypes:      conn.GrantTypes,
 	}
 	s.mu.Lock()
+	old, replaced := s.connectors[conn.ID]
 	s.connectors[conn.ID] = connector
 	s.mu.Unlock()
 
+	if replaced {
+		s.retireConnector(conn.ID, old.Connector)
+	}
+
 	return connector, nil
 }
 
-// CloseConnector removes the connector from the server's in-memory map.
+// CloseConnector removes the connector from the server's in-memory map and
+// closes it once the requests in flight through it are done.
 func (s *Server) CloseConnector(id string) {
 	s.mu.Lock()
+	old, ok := s.connectors[id]
 	delete(s.connectors, id)
 	s.mu.Unlock()
+
+	if ok {
+		s.retireConnector(id, old.Connector)
+	}
 }
 
 // getConnector retrieves the connector object with the given id from the storage
@@ -878,7 +1280,41 @@ const (
 )
 
 func WithRequestID(ctx context.Context) context.Context {
-	return context.WithValue(ctx, RequestKeyRequestID, uuid.NewString())
+	return withRequestID(ctx, uuid.NewString())
+}
+
+// withRequestID stores the request ID for logs and for upstream requests of
+// connectors.
+func withRequestID(ctx context.Context, id string) context.Context {
+	ctx = context.WithValue(ctx, RequestKeyRequestID, id)
+	return httpclient.WithRequestID(ctx, id)
+}
+
+// requestIDHeader carries the request ID in requests and responses.
+const requestIDHeader = "X-Request-Id"
+
+// maxRequestIDLength bounds incoming request IDs, which end up in logs.
+const maxRequestIDLength = 128
+
+// requestID returns the ID of the request set by a proxy in front of dex, or
+// a new one if it has none or an invalid one.
+func requestID(r *http.Request) string {
+	id := r.Header.Get(requestIDHeader)
+	if id == "" || len(id) > maxRequestIDLength {
+		return uuid.NewString()
+	}
+	for _, c := range id {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || strings.ContainsRune("-_.:", c)) {
+			return uuid.NewString()
+		}
+	}
+	return id
+}
+
+// RequestID returns the ID of the request ctx belongs to, if any.
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(RequestKeyRequestID).(string)
+	return id
 }
 
 func WithRemoteIP(ctx context.Context, ip string) context.Context {
@@ -2055,3 +2055,49 @@ func TestConnectorFailureHandling(t *testing.T) {
 		})
 	}
 }
+
+func TestRequestID(t *testing.T) {
+	httpServer, s := newTestServer(t, nil)
+	defer httpServer.Close()
+
+	get := func(id string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/auth?client_id=unknown&response_type=code", nil)
+		if id != "" {
+			req.Header.Set(requestIDHeader, id)
+		}
+		rr := httptest.NewRecorder()
+		s.ServeHTTP(rr, req)
+		return rr
+	}
+
+	// An incoming ID is kept and shown on the error page.
+	rr := get("ingress-4711")
+	require.Equal(t, "ingress-4711", rr.Header().Get(requestIDHeader))
+	require.Contains(t, rr.Body.String(), "Request ID: ingress-4711")
+
+	// Missing and invalid IDs are replaced.
+	for _, id := range []string{"", "bad id\n", strings.Repeat("a", maxRequestIDLength+1)} {
+		rr = get(id)
+		got := rr.Header().Get(requestIDHeader)
+		require.NotEmpty(t, got)
+		require.NotEqual(t, id, got)
+		require.Contains(t, rr.Body.String(), got)
+	}
+}
+
+func TestConnectorReplays(t *testing.T) {
+	ctx := t.Context()
+	now := time.Now()
+	store := memory.New(newLogger(t)).(storage.RateLimitStorage)
+	saml := connectorReplays{store: store, now: func() time.Time { return now }, id: "saml"}
+
+	require.NoError(t, saml.Use(ctx, "assertion", now.Add(time.Minute)))
+	require.ErrorIs(t, saml.Use(ctx, "assertion", now.Add(time.Minute)), errReplayed)
+
+	other := saml
+	other.id = "other-saml"
+	require.NoError(t, other.Use(ctx, "assertion", now.Add(time.Minute)), "values are kept per connector")
+
+	now = now.Add(2 * time.Minute)
+	require.NoError(t, saml.Use(ctx, "assertion", now.Add(time.Minute)), "expired values are forgotten")
+}
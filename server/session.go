@@ -26,7 +26,8 @@ func (s *Server) rememberMeDefault() *bool {
 	return &v
 }
 
-// remoteIP returns the real IP from context (set by parseRealIP middleware) or falls back to r.RemoteAddr.
+// remoteIP returns the client IP resolved by the request middleware or falls
+// back to r.RemoteAddr.
 func remoteIP(r *http.Request) string {
 	if ip, ok := r.Context().Value(RequestKeyRemoteIP).(string); ok && ip != "" {
 		return ip
@@ -448,6 +449,14 @@ func (s *Server) finishSessionLogin(ctx context.Context, r *http.Request, w http
 		Email:             ui.Claims.Email,
 		EmailVerified:     ui.Claims.EmailVerified,
 		Groups:            ui.Claims.Groups,
+		CustomClaims:      ui.Claims.CustomClaims,
+	}
+
+	// A denied login falls back to logging in with the connector, which is
+	// assessed again.
+	stepUp, err := s.assessLogin(r, riskFlowSession, claimsIdentity(claims), session.ConnectorID, authReq.ClientID, authReq.Scopes)
+	if err != nil {
+		return "", false
 	}
 
 	// Update AuthRequest with stored identity and auth_time from last login.
@@ -478,6 +487,12 @@ func (s *Server) finishSessionLogin(ctx context.Context, r *http.Request, w http
 		s.logger.ErrorContext(ctx, "session: failed to get MFA chain", "err", err)
 		return "", false
 	}
+	if len(mfaChain) == 0 && stepUp {
+		if mfaChain, err = s.stepUpMFAChain(ctx, session.ConnectorID); err != nil || len(mfaChain) == 0 {
+			s.logger.ErrorContext(ctx, "session: risk engine requires step-up, but no MFA authenticator applies", "connector_id", session.ConnectorID, "err", err)
+			return "", false
+		}
+	}
 	if len(mfaChain) > 0 {
 		// Re-read auth request to get the updated state (LoggedIn, Claims, ConnectorID).
 		updated, err := s.storage.GetAuthRequest(ctx, authReq.ID)
@@ -488,8 +503,10 @@ func (s *Server) finishSessionLogin(ctx context.Context, r *http.Request, w http
 		return s.buildMFARedirectURL(updated, mfaChain[0]), true
 	}
 
-	// Skip approval if globally configured or user already consented to the requested scopes.
-	if !authReq.ForceApprovalPrompt && (s.skipApproval || scopesCoveredByConsent(ui.Consents[authReq.ClientID], authReq.Scopes)) {
+	// Skip approval if globally configured or user already consented to the requested scopes,
+	// and accepted the current terms of the client.
+	if !authReq.ForceApprovalPrompt && (s.skipApproval || scopesCoveredByConsent(ui.Consents[authReq.ClientID], authReq.Scopes)) &&
+		s.clientTermsAccepted(ctx, authReq.ClientID, *ui) {
 		// Re-read to get the updated AuthRequest (LoggedIn, Claims, ConnectorID set above).
 		updated, err := s.storage.GetAuthRequest(ctx, authReq.ID)
 		if err != nil {
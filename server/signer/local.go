@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 	"log/slog"
+	"sync"
 	"time"
 
 	"github.com/go-jose/go-jose/v4"
@@ -35,6 +36,30 @@ func (c *LocalConfig) signingAlgorithm() (jose.SignatureAlgorithm, error) {
 
 // Open creates a new local signer.
 func (c *LocalConfig) Open(_ context.Context, s storage.Storage, idTokenValidFor time.Duration, now func() time.Time, logger *slog.Logger) (Signer, error) {
+	r, err := c.rotator(s, idTokenValidFor, now, logger)
+	if err != nil {
+		return nil, err
+	}
+	return &localSigner{
+		storage: s,
+		rotator: r,
+		logger:  logger,
+	}, nil
+}
+
+// Rotate replaces the signing key in the storage now, regardless of the
+// rotation period. The replaced key is kept as a verification key, like on a
+// scheduled rotation. Running signers pick up the new key within a minute.
+func (c *LocalConfig) Rotate(s storage.Storage, idTokenValidFor time.Duration, now func() time.Time, logger *slog.Logger) error {
+	r, err := c.rotator(s, idTokenValidFor, now, logger)
+	if err != nil {
+		return err
+	}
+	r.force = true
+	return r.rotate()
+}
+
+func (c *LocalConfig) rotator(s storage.Storage, idTokenValidFor time.Duration, now func() time.Time, logger *slog.Logger) (*keyRotator, error) {
 	rotateKeysAfter, err := time.ParseDuration(c.KeysRotationPeriod)
 	if err != nil {
 		return nil, fmt.Errorf("invalid config value %q for local signer rotation period: %v", c.KeysRotationPeriod, err)
@@ -49,20 +74,64 @@ func (c *LocalConfig) Open(_ context.Context, s storage.Storage, idTokenValidFor
 	if err != nil {
 		return nil, err
 	}
-	r := &keyRotator{s, strategy, now, logger}
-	return &localSigner{
-		storage: s,
-		rotator: r,
-		logger:  logger,
-	}, nil
+	return &keyRotator{Storage: s, strategy: strategy, now: now, logger: logger}, nil
 }
 
+// keysCacheTTL bounds how long keys read from the storage are reused. Keys only
+// change on rotation, so the cache is also dropped once NextRotation passes.
+const keysCacheTTL = time.Minute
+
 // localSigner signs payloads using keys stored in the Dex storage.
 // It manages key rotation and storage using the existing keyRotator logic.
 type localSigner struct {
 	storage storage.Storage
 	rotator *keyRotator
 	logger  *slog.Logger
+
+	mu          sync.Mutex
+	keys        storage.Keys
+	keysExpires time.Time
+}
+
+// getKeys returns the keys from the storage, reusing the last read until the
+// next rotation so every issued token does not cost a storage round trip.
+func (l *localSigner) getKeys(ctx context.Context) (storage.Keys, error) {
+	now := l.rotator.now()
+
+	l.mu.Lock()
+	if now.Before(l.keysExpires) {
+		keys := l.keys
+		l.mu.Unlock()
+		return keys, nil
+	}
+	l.mu.Unlock()
+
+	keys, err := l.storage.GetKeys(ctx)
+	if err != nil || keys.SigningKey == nil {
+		return keys, err
+	}
+
+	expires := now.Add(keysCacheTTL)
+	if !keys.NextRotation.IsZero() && keys.NextRotation.Before(expires) {
+		expires = keys.NextRotation
+	}
+
+	l.mu.Lock()
+	l.keys = keys
+	l.keysExpires = expires
+	l.mu.Unlock()
+	return keys, nil
+}
+
+func (l *localSigner) rotate() {
+	if err := l.rotator.rotate(); err != nil {
+		l.logRotateError(err)
+		return
+	}
+
+	l.mu.Lock()
+	l.keysExpires = time.Time{}
+	l.mu.Unlock()
 }
 
 // Start begins key rotation in a new goroutine, closing once the context is canceled.
@@ -71,9 +140,7 @@ type localSigner struct {
 // healthy storages will return from this call with valid keys.
 func (l *localSigner) Start(ctx context.Context) {
 	// Try to rotate immediately so properly configured storages will have keys.
-	if err := l.rotator.rotate(); err != nil {
-		l.logRotateError(err)
-	}
+	l.rotate()
 
 	go func() {
 		for {
@@ -81,9 +148,7 @@ func (l *localSigner) Start(ctx context.Context) {
 			case <-ctx.Done():
 				return
 			case <-time.After(time.Second * 30):
-				if err := l.rotator.rotate(); err != nil {
-					l.logRotateError(err)
-				}
+				l.rotate()
 			}
 		}
 	}()
@@ -98,7 +163,7 @@ func (l *localSigner) logRotateError(err error) {
 }
 
 func (l *localSigner) Sign(ctx context.Context, payload []byte) (string, error) {
-	keys, err := l.storage.GetKeys(ctx)
+	keys, err := l.getKeys(ctx)
 	if err != nil {
 		return "", fmt.Errorf("failed to get keys: %v", err)
 	}
@@ -116,7 +181,7 @@ func (l *localSigner) Sign(ctx context.Context, payload []byte) (string, error)
 }
 
 func (l *localSigner) ValidationKeys(ctx context.Context) ([]*jose.JSONWebKey, error) {
-	keys, err := l.storage.GetKeys(ctx)
+	keys, err := l.getKeys(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get keys: %v", err)
 	}
@@ -134,7 +199,7 @@ func (l *localSigner) ValidationKeys(ctx context.Context) ([]*jose.JSONWebKey, e
 }
 
 func (l *localSigner) Algorithm(ctx context.Context) (jose.SignatureAlgorithm, error) {
-	keys, err := l.storage.GetKeys(ctx)
+	keys, err := l.getKeys(ctx)
 	if err != nil && !errors.Is(err, storage.ErrNotFound) {
 		return "", fmt.Errorf("failed to get keys: %v", err)
 	}
@@ -256,3 +256,58 @@ func TestLocalSignerDoesNotRevertSharedKeyAlgorithmBeforeNextRotation(t *testing
 	require.NoError(t, err)
 	requireVerifiedByAnyKey(t, token, jose.ES256, keys, payload)
 }
+
+func TestLocalSignerCachesKeysUntilNextRotation(t *testing.T) {
+	ctx := context.Background()
+	s := memory.New(slog.New(slog.DiscardHandler))
+
+	currentTime := time.Now().UTC()
+
+	firstPriv, firstPub := newTestRSAJWKPair(t)
+	err := s.UpdateKeys(ctx, func(keys storage.Keys) (storage.Keys, error) {
+		keys.SigningKey = firstPriv
+		keys.SigningKeyPub = firstPub
+		keys.NextRotation = currentTime.Add(keysCacheTTL / 2)
+		return keys, nil
+	})
+	require.NoError(t, err)
+
+	ls := newTestLocalSigner(t, LocalConfig{}, s, func() time.Time { return currentTime })
+
+	keys, err := ls.ValidationKeys(ctx)
+	require.NoError(t, err)
+	require.Equal(t, firstPub.KeyID, keys[0].KeyID)
+
+	// Replace the key behind the signer's back, as another replica rotating would.
+	secondPriv, secondPub := newTestRSAJWKPair(t)
+	err = s.UpdateKeys(ctx, func(keys storage.Keys) (storage.Keys, error) {
+		keys.SigningKey = secondPriv
+		keys.SigningKeyPub = secondPub
+		keys.NextRotation = currentTime.Add(time.Hour)
+		return keys, nil
+	})
+	require.NoError(t, err)
+
+	keys, err = ls.ValidationKeys(ctx)
+	require.NoError(t, err)
+	require.Equal(t, firstPub.KeyID, keys[0].KeyID, "keys should be served from cache")
+
+	currentTime = currentTime.Add(keysCacheTTL / 2)
+	keys, err = ls.ValidationKeys(ctx)
+	require.NoError(t, err)
+	require.Equal(t, secondPub.KeyID, keys[0].KeyID, "cache should expire at the next rotation")
+}
+
+func TestLocalConfigRotate(t *testing.T) {
+	s := memory.New(slog.New(slog.DiscardHandler))
+	now := func() time.Time { return time.Now().UTC() }
+	config := LocalConfig{KeysRotationPeriod: "6h"}
+
+	require.NoError(t, config.Rotate(s, time.Hour, now, slog.New(slog.DiscardHandler)))
+	first := signingKeyID(t, s)
+
+	// The key isn't due for rotation, but is replaced anyway.
+	require.NoError(t, config.Rotate(s, time.Hour, now, slog.New(slog.DiscardHandler)))
+	require.NotEqual(t, first, signingKeyID(t, s))
+	require.Equal(t, []string{first}, verificationKeyIDs(t, s))
+}
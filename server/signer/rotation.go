@@ -89,12 +89,18 @@ type keyRotator struct {
 	now      func() time.Time
 
 	logger *slog.Logger
+
+	// force rotates the signing key even if it hasn't expired.
+	force bool
 }
 
 func (k keyRotator) rotationReason(keys storage.Keys, tNow time.Time) string {
 	if keys.SigningKey == nil {
 		return "missing signing key"
 	}
+	if k.force {
+		return "forced"
+	}
 
 	if tNow.Before(keys.NextRotation) {
 		return ""
@@ -1,6 +1,7 @@
 package server
 
 import (
+	"context"
 	"fmt"
 	"html/template"
 	"io"
@@ -12,20 +13,28 @@ import (
 	"strings"
 
 	"github.com/Masterminds/sprig/v3"
+
+	"github.com/dexidp/dex/storage"
+	"github.com/dexidp/dex/web"
 )
 
 const (
-	tmplApproval       = "approval.html"
-	tmplLogin          = "login.html"
-	tmplPassword       = "password.html"
-	tmplOOB            = "oob.html"
-	tmplError          = "error.html"
-	tmplDevice         = "device.html"
-	tmplDeviceSuccess  = "device_success.html"
-	tmplTOTPVerify     = "totp_verify.html"
-	tmplWebAuthnVerify = "webauthn_verify.html"
-	tmplHome           = "home.html"
-	tmplLogout         = "logout.html"
+	tmplApproval             = "approval.html"
+	tmplLogin                = "login.html"
+	tmplPassword             = "password.html"
+	tmplOOB                  = "oob.html"
+	tmplError                = "error.html"
+	tmplDevice               = "device.html"
+	tmplDeviceSuccess        = "device_success.html"
+	tmplTOTPVerify           = "totp_verify.html"
+	tmplWebAuthnVerify       = "webauthn_verify.html"
+	tmplHome                 = "home.html"
+	tmplLogout               = "logout.html"
+	tmplPasswordReset        = "password_reset.html"
+	tmplPasswordResetConfirm = "password_reset_confirm.html"
+	tmplEmailVerified        = "email_verified.html"
+	tmplRegister             = "register.html"
+	tmplMaintenance          = "maintenance.html"
 )
 
 var requiredTmpls = []string{
@@ -42,27 +51,51 @@ var requiredTmpls = []string{
 	tmplLogout,
 }
 
+// optionalTmpls are the templates of newer features. Frontend directories
+// written before them don't need to provide them, the built-in ones are used
+// instead.
+var optionalTmpls = []string{
+	tmplPasswordReset,
+	tmplPasswordResetConfirm,
+	tmplEmailVerified,
+	tmplRegister,
+	tmplMaintenance,
+}
+
 type templates struct {
-	loginTmpl          *template.Template
-	approvalTmpl       *template.Template
-	passwordTmpl       *template.Template
-	oobTmpl            *template.Template
-	errorTmpl          *template.Template
-	deviceTmpl         *template.Template
-	deviceSuccessTmpl  *template.Template
-	totpVerifyTmpl     *template.Template
-	webauthnVerifyTmpl *template.Template
-	homeTmpl           *template.Template
-	logoutTmpl         *template.Template
+	loginTmpl                *template.Template
+	approvalTmpl             *template.Template
+	passwordTmpl             *template.Template
+	oobTmpl                  *template.Template
+	errorTmpl                *template.Template
+	deviceTmpl               *template.Template
+	deviceSuccessTmpl        *template.Template
+	totpVerifyTmpl           *template.Template
+	webauthnVerifyTmpl       *template.Template
+	homeTmpl                 *template.Template
+	logoutTmpl               *template.Template
+	passwordResetTmpl        *template.Template
+	passwordResetConfirmTmpl *template.Template
+	emailVerifiedTmpl        *template.Template
+	registerTmpl             *template.Template
+	maintenanceTmpl          *template.Template
+
+	catalog *catalog
 }
 
 type webConfig struct {
-	webFS     fs.FS
-	logoURL   string
-	issuer    string
-	theme     string
-	issuerURL string
-	extra     map[string]string
+	webFS       fs.FS
+	logoURL     string
+	issuer      string
+	theme       string
+	issuerURL   string
+	extra       map[string]string
+	title       string
+	footerText  string
+	footerLinks []storage.ThemeLink
+	customCSS   string
+	languages   []string
+	messages    map[string]map[string]string
 }
 
 func getFuncMap(c webConfig) (template.FuncMap, error) {
@@ -74,9 +107,14 @@ func getFuncMap(c webConfig) (template.FuncMap, error) {
 	}
 
 	additionalFuncs := map[string]interface{}{
-		"extra":  func(k string) string { return c.extra[k] },
-		"issuer": func() string { return c.issuer },
-		"logo":   func() string { return c.logoURL },
+		"extra":       func(k string) string { return c.extra[k] },
+		"issuer":      func() string { return c.issuer },
+		"logo":        func() string { return c.logoURL },
+		"title":       func() string { return c.title },
+		"footerText":  func() string { return c.footerText },
+		"footerLinks": func() []storage.ThemeLink { return c.footerLinks },
+		// The CSS comes from the operator's config, so it is trusted.
+		"customCSS": func() template.CSS { return template.CSS(c.customCSS) },
 		"url": func(reqPath, assetPath string) string {
 			return relativeURL(issuerURL.Path, reqPath, assetPath)
 		},
@@ -111,6 +149,9 @@ func loadWebConfig(c webConfig) (http.Handler, http.Handler, http.HandlerFunc, *
 	if c.issuer == "" {
 		c.issuer = "dex"
 	}
+	if c.title == "" {
+		c.title = c.issuer
+	}
 	if c.logoURL == "" {
 		c.logoURL = "theme/logo.png"
 	}
@@ -173,18 +214,41 @@ func loadTemplates(c webConfig, templatesDir string) (*templates, error) {
 	if len(missingTmpls) > 0 {
 		return nil, fmt.Errorf("missing template(s): %s", missingTmpls)
 	}
+	for _, tmplName := range optionalTmpls {
+		if tmpls.Lookup(tmplName) != nil {
+			continue
+		}
+		content, err := fs.ReadFile(web.FS(), path.Join("templates", tmplName))
+		if err != nil {
+			return nil, fmt.Errorf("read built-in template %s: %v", tmplName, err)
+		}
+		if _, err := tmpls.New(tmplName).Parse(string(content)); err != nil {
+			return nil, fmt.Errorf("parse built-in template %s: %v", tmplName, err)
+		}
+	}
+
+	catalog, err := loadCatalog(c.webFS, c.languages, c.messages)
+	if err != nil {
+		return nil, err
+	}
 	return &templates{
-		loginTmpl:          tmpls.Lookup(tmplLogin),
-		approvalTmpl:       tmpls.Lookup(tmplApproval),
-		passwordTmpl:       tmpls.Lookup(tmplPassword),
-		oobTmpl:            tmpls.Lookup(tmplOOB),
-		errorTmpl:          tmpls.Lookup(tmplError),
-		deviceTmpl:         tmpls.Lookup(tmplDevice),
-		deviceSuccessTmpl:  tmpls.Lookup(tmplDeviceSuccess),
-		totpVerifyTmpl:     tmpls.Lookup(tmplTOTPVerify),
-		webauthnVerifyTmpl: tmpls.Lookup(tmplWebAuthnVerify),
-		homeTmpl:           tmpls.Lookup(tmplHome),
-		logoutTmpl:         tmpls.Lookup(tmplLogout),
+		loginTmpl:                tmpls.Lookup(tmplLogin),
+		approvalTmpl:             tmpls.Lookup(tmplApproval),
+		passwordTmpl:             tmpls.Lookup(tmplPassword),
+		oobTmpl:                  tmpls.Lookup(tmplOOB),
+		errorTmpl:                tmpls.Lookup(tmplError),
+		deviceTmpl:               tmpls.Lookup(tmplDevice),
+		deviceSuccessTmpl:        tmpls.Lookup(tmplDeviceSuccess),
+		totpVerifyTmpl:           tmpls.Lookup(tmplTOTPVerify),
+		webauthnVerifyTmpl:       tmpls.Lookup(tmplWebAuthnVerify),
+		homeTmpl:                 tmpls.Lookup(tmplHome),
+		logoutTmpl:               tmpls.Lookup(tmplLogout),
+		passwordResetTmpl:        tmpls.Lookup(tmplPasswordReset),
+		passwordResetConfirmTmpl: tmpls.Lookup(tmplPasswordResetConfirm),
+		emailVerifiedTmpl:        tmpls.Lookup(tmplEmailVerified),
+		registerTmpl:             tmpls.Lookup(tmplRegister),
+		maintenanceTmpl:          tmpls.Lookup(tmplMaintenance),
+		catalog:                  catalog,
 	}, nil
 }
 
@@ -271,6 +335,26 @@ var scopeDescriptions = map[string]string{
 	"groups": "View your groups",
 }
 
+type clientThemeKey struct{}
+
+// withClientTheme attaches the branding of client to the request, so pages
+// rendered for it use that instead of the global theme.
+func withClientTheme(r *http.Request, client storage.Client) *http.Request {
+	if client.Theme == nil {
+		return r
+	}
+	return r.WithContext(context.WithValue(r.Context(), clientThemeKey{}, client.Theme))
+}
+
+// themeFromRequest returns the client branding attached to the request, or a
+// zero theme which leaves the global one in place.
+func themeFromRequest(r *http.Request) storage.ClientTheme {
+	if theme, ok := r.Context().Value(clientThemeKey{}).(*storage.ClientTheme); ok {
+		return *theme
+	}
+	return storage.ClientTheme{}
+}
+
 type connectorInfo struct {
 	ID   string
 	Name string
@@ -284,57 +368,77 @@ func (n byName) Len() int           { return len(n) }
 func (n byName) Less(i, j int) bool { return n[i].Name < n[j].Name }
 func (n byName) Swap(i, j int)      { n[i], n[j] = n[j], n[i] }
 
-func (t *templates) device(r *http.Request, w http.ResponseWriter, postURL string, userCode string, lastWasInvalid bool) error {
+func (t *templates) device(r *http.Request, w http.ResponseWriter, postURL string, userCode string, qrCode string, lastWasInvalid bool) error {
 	if lastWasInvalid {
 		w.WriteHeader(http.StatusBadRequest)
 	}
 	data := struct {
+		translator
 		PostURL  string
 		UserCode string
+		QRCode   string
 		Invalid  bool
 		ReqPath  string
-	}{postURL, userCode, lastWasInvalid, r.URL.Path}
+		Theme    storage.ClientTheme
+	}{t.catalog.translator(r), postURL, userCode, qrCode, lastWasInvalid, r.URL.Path, themeFromRequest(r)}
 	return renderTemplate(w, t.deviceTmpl, data)
 }
 
 func (t *templates) deviceSuccess(r *http.Request, w http.ResponseWriter, clientName string) error {
 	data := struct {
+		translator
 		ClientName string
 		ReqPath    string
-	}{clientName, r.URL.Path}
+		Theme      storage.ClientTheme
+	}{t.catalog.translator(r), clientName, r.URL.Path, themeFromRequest(r)}
 	return renderTemplate(w, t.deviceSuccessTmpl, data)
 }
 
 func (t *templates) login(r *http.Request, w http.ResponseWriter, connectors []connectorInfo) error {
 	sort.Sort(byName(connectors))
 	data := struct {
+		translator
 		Connectors []connectorInfo
 		ReqPath    string
-	}{connectors, r.URL.Path}
+		Theme      storage.ClientTheme
+	}{t.catalog.translator(r), connectors, r.URL.Path, themeFromRequest(r)}
 	return renderTemplate(w, t.loginTmpl, data)
 }
 
-func (t *templates) password(r *http.Request, w http.ResponseWriter, postURL, lastUsername, usernamePrompt string, lastWasInvalid bool, backLink string, rememberMe *bool) error {
-	if lastWasInvalid {
+func (t *templates) password(r *http.Request, w http.ResponseWriter, postURL, lastUsername, usernamePrompt string, lastWasInvalid bool, backLink, resetLink, registerLink string, rememberMe *bool, captcha *captchaWidget) error {
+	switch {
+	case lastWasInvalid:
 		w.WriteHeader(http.StatusUnauthorized)
+	case captcha != nil && captcha.Failed:
+		w.WriteHeader(http.StatusBadRequest)
 	}
 	data := struct {
+		translator
 		PostURL           string
 		BackLink          string
+		ResetLink         string
+		RegisterLink      string
 		Username          string
 		UsernamePrompt    string
 		Invalid           bool
 		ReqPath           string
 		ShowRememberMe    bool
 		RememberMeChecked bool
+		Captcha           *captchaWidget
+		Theme             storage.ClientTheme
 	}{
+		translator:     t.catalog.translator(r),
 		PostURL:        postURL,
 		BackLink:       backLink,
+		ResetLink:      resetLink,
+		RegisterLink:   registerLink,
 		Username:       lastUsername,
 		UsernamePrompt: usernamePrompt,
 		Invalid:        lastWasInvalid,
 		ReqPath:        r.URL.Path,
 		ShowRememberMe: rememberMe != nil,
+		Captcha:        captcha,
+		Theme:          themeFromRequest(r),
 	}
 	if rememberMe != nil {
 		data.RememberMeChecked = *rememberMe
@@ -342,7 +446,62 @@ func (t *templates) password(r *http.Request, w http.ResponseWriter, postURL, la
 	return renderTemplate(w, t.passwordTmpl, data)
 }
 
-func (t *templates) approval(r *http.Request, w http.ResponseWriter, authReqID, username, clientName string, scopes []string) error {
+func (t *templates) passwordReset(r *http.Request, w http.ResponseWriter, postURL, backLink string, sent bool) error {
+	data := struct {
+		translator
+		PostURL  string
+		BackLink string
+		Sent     bool
+		ReqPath  string
+		Theme    storage.ClientTheme
+	}{t.catalog.translator(r), postURL, backLink, sent, r.URL.Path, themeFromRequest(r)}
+	return renderTemplate(w, t.passwordResetTmpl, data)
+}
+
+func (t *templates) passwordResetConfirm(r *http.Request, w http.ResponseWriter, token, invalid string, done bool) error {
+	if invalid != "" {
+		w.WriteHeader(http.StatusBadRequest)
+	}
+	data := struct {
+		translator
+		Token   string
+		Invalid string
+		Done    bool
+		ReqPath string
+		Theme   storage.ClientTheme
+	}{t.catalog.translator(r), token, invalid, done, r.URL.Path, themeFromRequest(r)}
+	return renderTemplate(w, t.passwordResetConfirmTmpl, data)
+}
+
+func (t *templates) register(r *http.Request, w http.ResponseWriter, postURL, loginLink, email, username, invalid string, showInviteCode, done bool) error {
+	if invalid != "" {
+		w.WriteHeader(http.StatusBadRequest)
+	}
+	data := struct {
+		translator
+		PostURL        string
+		LoginLink      string
+		Email          string
+		Username       string
+		Invalid        string
+		ShowInviteCode bool
+		Done           bool
+		ReqPath        string
+		Theme          storage.ClientTheme
+	}{t.catalog.translator(r), postURL, loginLink, email, username, invalid, showInviteCode, done, r.URL.Path, themeFromRequest(r)}
+	return renderTemplate(w, t.registerTmpl, data)
+}
+
+func (t *templates) emailVerified(r *http.Request, w http.ResponseWriter) error {
+	data := struct {
+		translator
+		ReqPath string
+		Theme   storage.ClientTheme
+	}{t.catalog.translator(r), r.URL.Path, themeFromRequest(r)}
+	return renderTemplate(w, t.emailVerifiedTmpl, data)
+}
+
+func (t *templates) approval(r *http.Request, w http.ResponseWriter, authReqID, username, clientName string, scopes []string, claims []releasedClaim, terms *storage.ClientTerms) error {
 	accesses := []string{}
 	for _, scope := range scopes {
 		access, ok := scopeDescriptions[scope]
@@ -352,12 +511,16 @@ func (t *templates) approval(r *http.Request, w http.ResponseWriter, authReqID,
 	}
 	sort.Strings(accesses)
 	data := struct {
+		translator
 		User      string
 		Client    string
 		AuthReqID string
 		Scopes    []string
+		Claims    []releasedClaim
+		Terms     *storage.ClientTerms
 		ReqPath   string
-	}{username, clientName, authReqID, accesses, r.URL.Path}
+		Theme     storage.ClientTheme
+	}{t.catalog.translator(r), username, clientName, authReqID, accesses, claims, terms, r.URL.Path, themeFromRequest(r)}
 	return renderTemplate(w, t.approvalTmpl, data)
 }
 
@@ -366,17 +529,20 @@ func (t *templates) totpVerify(r *http.Request, w http.ResponseWriter, postURL,
 		w.WriteHeader(http.StatusUnauthorized)
 	}
 	data := struct {
+		translator
 		PostURL   string
 		Invalid   bool
 		Issuer    string
 		Connector string
 		QRCode    string
 		ReqPath   string
-	}{postURL, lastWasInvalid, issuer, connector, qrCode, r.URL.Path}
+		Theme     storage.ClientTheme
+	}{t.catalog.translator(r), postURL, lastWasInvalid, issuer, connector, qrCode, r.URL.Path, themeFromRequest(r)}
 	return renderTemplate(w, t.totpVerifyTmpl, data)
 }
 
 type homeData struct {
+	translator
 	LoggedIn       bool
 	Username       string
 	Email          string
@@ -389,48 +555,77 @@ type homeData struct {
 	LogoutURL      string
 	DiscoveryURL   string
 	ReqPath        string
+	Theme          storage.ClientTheme
 }
 
 func (t *templates) home(r *http.Request, w http.ResponseWriter, data homeData) error {
+	data.translator = t.catalog.translator(r)
 	data.ReqPath = r.URL.Path
+	data.Theme = themeFromRequest(r)
 	return renderTemplate(w, t.homeTmpl, data)
 }
 
 func (t *templates) logout(r *http.Request, w http.ResponseWriter, backURL string, loggedOut bool) error {
 	data := struct {
+		translator
 		BackURL   string
 		LoggedOut bool
 		ReqPath   string
-	}{backURL, loggedOut, r.URL.Path}
+		Theme     storage.ClientTheme
+	}{t.catalog.translator(r), backURL, loggedOut, r.URL.Path, themeFromRequest(r)}
 	return renderTemplate(w, t.logoutTmpl, data)
 }
 
 func (t *templates) webauthnVerify(r *http.Request, w http.ResponseWriter, mode, authenticatorID string) error {
 	data := struct {
+		translator
 		// Mode must be server-controlled ("register" or "login") and never derived
 		// from user input to prevent XSS in the template's script context.
 		Mode            string
 		AuthenticatorID string
 		ReqPath         string
-	}{mode, authenticatorID, r.URL.Path}
+		Theme           storage.ClientTheme
+	}{t.catalog.translator(r), mode, authenticatorID, r.URL.Path, themeFromRequest(r)}
 	return renderTemplate(w, t.webauthnVerifyTmpl, data)
 }
 
 func (t *templates) oob(r *http.Request, w http.ResponseWriter, code string) error {
 	data := struct {
+		translator
 		Code    string
 		ReqPath string
-	}{code, r.URL.Path}
+		Theme   storage.ClientTheme
+	}{t.catalog.translator(r), code, r.URL.Path, themeFromRequest(r)}
 	return renderTemplate(w, t.oobTmpl, data)
 }
 
-func (t *templates) err(r *http.Request, w http.ResponseWriter, errCode int, errMsg string) error {
-	w.WriteHeader(errCode)
+func (t *templates) maintenance(r *http.Request, w http.ResponseWriter, message string) error {
+	w.WriteHeader(http.StatusServiceUnavailable)
 	data := struct {
-		ErrType string
-		ErrMsg  string
+		translator
+		Message string
 		ReqPath string
-	}{http.StatusText(errCode), errMsg, r.URL.Path}
+		Theme   storage.ClientTheme
+	}{t.catalog.translator(r), message, r.URL.Path, themeFromRequest(r)}
+	if err := t.maintenanceTmpl.Execute(w, data); err != nil {
+		return fmt.Errorf("rendering template %s failed: %s", t.maintenanceTmpl.Name(), err)
+	}
+	return nil
+}
+
+// loginErr renders the error page with the code of a login error, which users
+// can report to their administrator.
+func (t *templates) loginErr(r *http.Request, w http.ResponseWriter, errCode int, errMsg, loginErrCode string) error {
+	w.WriteHeader(errCode)
+	data := struct {
+		translator
+		ErrType      string
+		ErrMsg       string
+		LoginErrCode string
+		ReqPath      string
+		RequestID    string
+		Theme        storage.ClientTheme
+	}{t.catalog.translator(r), http.StatusText(errCode), errMsg, loginErrCode, r.URL.Path, RequestID(r.Context()), themeFromRequest(r)}
 	if err := t.errorTmpl.Execute(w, data); err != nil {
 		return fmt.Errorf("rendering template %s failed: %s", t.errorTmpl.Name(), err)
 	}
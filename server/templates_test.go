@@ -1,6 +1,18 @@
 package server
 
-import "testing"
+import (
+	"io/fs"
+	"net/http/httptest"
+	"path"
+	"slices"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/dexidp/dex/storage"
+	"github.com/dexidp/dex/web"
+)
 
 func TestRelativeURL(t *testing.T) {
 	tests := []struct {
@@ -70,3 +82,69 @@ func TestRelativeURL(t *testing.T) {
 		})
 	}
 }
+
+func TestWebCustomization(t *testing.T) {
+	tmpls, err := loadTemplates(webConfig{
+		webFS:       web.FS(),
+		issuer:      "ACME",
+		issuerURL:   "https://example.com/dex",
+		title:       "ACME Sign-in",
+		footerText:  "© ACME Corp",
+		footerLinks: []storage.ThemeLink{{Text: "Privacy", URL: "https://example.com/privacy"}},
+		customCSS:   ".theme-body { font-family: serif; }",
+	}, "templates")
+	require.NoError(t, err)
+
+	render := func(theme *storage.ClientTheme) string {
+		r := httptest.NewRequest("GET", "/auth", nil)
+		if theme != nil {
+			r = withClientTheme(r, storage.Client{Theme: theme})
+		}
+		w := httptest.NewRecorder()
+		require.NoError(t, tmpls.login(r, w, nil))
+		return w.Body.String()
+	}
+
+	body := render(nil)
+	require.Contains(t, body, "<title>ACME Sign-in</title>")
+	require.Contains(t, body, "<style>.theme-body { font-family: serif; }</style>")
+	require.Contains(t, body, "© ACME Corp")
+	require.Contains(t, body, `href="https://example.com/privacy"`)
+
+	body = render(&storage.ClientTheme{FooterLinks: []storage.ThemeLink{{Text: "Help", URL: "https://app.example.com/help"}}})
+	require.Contains(t, body, `href="https://app.example.com/help"`)
+	require.NotContains(t, body, `href="https://example.com/privacy"`, "client footer links replace the default ones")
+	require.Contains(t, body, "© ACME Corp")
+}
+
+func TestOptionalTemplates(t *testing.T) {
+	// A frontend directory predating the optional templates.
+	webFS := fstest.MapFS{}
+	files, err := fs.ReadDir(web.FS(), "templates")
+	require.NoError(t, err)
+	for _, file := range files {
+		if slices.Contains(optionalTmpls, file.Name()) {
+			continue
+		}
+		data, err := fs.ReadFile(web.FS(), path.Join("templates", file.Name()))
+		require.NoError(t, err)
+		webFS[path.Join("templates", file.Name())] = &fstest.MapFile{Data: data}
+	}
+
+	tmpls, err := loadTemplates(webConfig{
+		webFS:     webFS,
+		issuer:    "ACME",
+		issuerURL: "https://example.com/dex",
+	}, "templates")
+	require.NoError(t, err)
+	require.NotNil(t, tmpls.registerTmpl)
+	require.NotNil(t, tmpls.maintenanceTmpl)
+
+	// Required templates are still enforced.
+	delete(webFS, path.Join("templates", tmplLogin))
+	_, err = loadTemplates(webConfig{
+		webFS:     webFS,
+		issuerURL: "https://example.com/dex",
+	}, "templates")
+	require.ErrorContains(t, err, tmplLogin)
+}
@@ -0,0 +1,357 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/coreos/go-oidc/v3/oidc"
+
+	"github.com/dexidp/dex/pkg/events"
+	"github.com/dexidp/dex/server/internal"
+	"github.com/dexidp/dex/storage"
+)
+
+// maxRefreshTokenGenerations limits how often a refresh token can be
+// exchanged for a child, and the child in turn.
+const maxRefreshTokenGenerations = 5
+
+// tokenExchangePolicyConnectorTypes are the types of the connectors whose
+// tokens only clients with a token exchange policy can exchange, unless
+// Config.TokenExchangeRequiresPolicy is set. HSDP IAM tokens are accepted
+// across the whole organization, so any client could exchange them otherwise.
+var tokenExchangePolicyConnectorTypes = []string{"hsdp"}
+
+// tokenExchangeRequest holds the parameters of a token exchange relevant to
+// the client's policy and the audit record.
+type tokenExchangeRequest struct {
+	connID             string
+	connType           string
+	subjectTokenType   string
+	requestedTokenType string
+	scopes             []string
+	audiences          []string
+}
+
+// checkTokenExchangePolicy returns the error type and description of the
+// response if the client's policy doesn't allow the exchange.
+func (s *Server) checkTokenExchangePolicy(client storage.Client, req tokenExchangeRequest) (errType, description string) {
+	policy := client.TokenExchange
+	if policy == nil {
+		if s.requiresTokenExchangePolicy(req.connType) {
+			return errUnauthorizedClient, "Client is not allowed to use token exchange."
+		}
+		return "", ""
+	}
+
+	if len(policy.SubjectTokenTypes) > 0 && !slices.Contains(policy.SubjectTokenTypes, req.subjectTokenType) {
+		return errInvalidRequest, fmt.Sprintf("Client can't exchange subject_token_type %q.", req.subjectTokenType)
+	}
+	if len(policy.Connectors) > 0 && !slices.Contains(policy.Connectors, req.connID) {
+		return errInvalidRequest, "Client can't exchange tokens of this connector."
+	}
+	if len(policy.Scopes) > 0 {
+		for _, scope := range req.scopes {
+			if !slices.Contains(policy.Scopes, scope) {
+				return errInvalidScope, fmt.Sprintf("Client can't request scope %q.", scope)
+			}
+		}
+	}
+	for _, aud := range req.audiences {
+		if !slices.Contains(policy.Audiences, aud) {
+			return errInvalidTarget, fmt.Sprintf("Client can't request audience %q.", aud)
+		}
+	}
+	return "", ""
+}
+
+// requiresTokenExchangePolicy reports whether clients need a token exchange
+// policy to exchange tokens of connectors of the given type, which is empty
+// for tokens issued by dex.
+func (s *Server) requiresTokenExchangePolicy(connType string) bool {
+	if s.tokenExchangeRequiresPolicy != nil {
+		return *s.tokenExchangeRequiresPolicy
+	}
+	return slices.Contains(tokenExchangePolicyConnectorTypes, connType)
+}
+
+// auditTokenExchange records the outcome of a token exchange. An empty reason
+// means the exchange was granted.
+func (s *Server) auditTokenExchange(ctx context.Context, client storage.Client, req tokenExchangeRequest, userID, reason string) {
+	outcome := "granted"
+	if reason != "" {
+		outcome = "denied"
+	}
+	s.logger.InfoContext(ctx, "token exchange",
+		"outcome", outcome, "reason", reason,
+		"client_id", client.ID, "connector_id", req.connID, "user_id", userID,
+		"subject_token_type", req.subjectTokenType, "requested_token_type", req.requestedTokenType,
+		"scopes", req.scopes, "audiences", req.audiences)
+	s.emitEvent(ctx, events.TypeTokenExchange, userID, map[string]any{
+		"outcome":              outcome,
+		"reason":               reason,
+		"client_id":            client.ID,
+		"connector_id":         req.connID,
+		"user_id":              userID,
+		"subject_token_type":   req.subjectTokenType,
+		"requested_token_type": req.requestedTokenType,
+		"scopes":               req.scopes,
+		"audiences":            req.audiences,
+	})
+}
+
+// dexSubject is the subject token of an exchange of a token issued by dex.
+type dexSubject struct {
+	claims        storage.Claims
+	connID        string
+	connectorData []byte
+	// scopes the subject token was granted.
+	scopes []string
+
+	// refresh is the subject if it's a refresh token, and generation the
+	// number of its ancestors.
+	refresh    *storage.RefreshToken
+	generation int
+}
+
+// dexTokenSubject verifies a refresh or access token issued by dex to the
+// client. It returns nil if the token isn't valid.
+func (s *Server) dexTokenSubject(ctx context.Context, client storage.Client, subjectTokenType, subjectToken string) (*dexSubject, error) {
+	if subjectTokenType == tokenTypeRefresh {
+		token := new(internal.RefreshToken)
+		if err := internal.Unmarshal(subjectToken, token); err != nil {
+			return nil, nil
+		}
+		rCtx, rerr := s.getRefreshTokenFromStorage(ctx, &client, token)
+		if rerr != nil {
+			if rerr.code == http.StatusInternalServerError {
+				return nil, errors.New("failed to get refresh token")
+			}
+			return nil, nil
+		}
+		return &dexSubject{
+			claims:        rCtx.storageToken.Claims,
+			connID:        rCtx.storageToken.ConnectorID,
+			connectorData: rCtx.connectorData,
+			scopes:        rCtx.storageToken.Scopes,
+			refresh:       rCtx.storageToken,
+			generation:    rCtx.generation,
+		}, nil
+	}
+
+	claims, sub, err := s.verifyClientToken(ctx, client, subjectToken)
+	if claims == nil || err != nil {
+		return nil, err
+	}
+	// Impersonation tokens can't be exchanged, the new token would lose
+	// their actor and lifetime.
+	if claims.Actor != nil {
+		return nil, nil
+	}
+
+	// Access tokens don't list their scopes, only the claims released for
+	// them.
+	subject := &dexSubject{
+		claims: storage.Claims{
+			UserID:            sub.UserId,
+			Username:          claims.Name,
+			PreferredUsername: claims.PreferredUsername,
+			Email:             claims.Email,
+			Groups:            claims.Groups,
+		},
+		connID: sub.ConnId,
+		scopes: []string{scopeOpenID},
+	}
+	if claims.EmailVerified != nil {
+		subject.claims.EmailVerified = *claims.EmailVerified
+		subject.scopes = append(subject.scopes, scopeEmail)
+	}
+	if claims.Groups != nil {
+		subject.scopes = append(subject.scopes, scopeGroups)
+	}
+	if claims.Name != "" || claims.PreferredUsername != "" {
+		subject.scopes = append(subject.scopes, scopeProfile)
+	}
+	return subject, nil
+}
+
+// verifyClientToken verifies an access or ID token dex issued to the client.
+// It returns nil claims if the token isn't valid.
+func (s *Server) verifyClientToken(ctx context.Context, client storage.Client, rawToken string) (*idTokenClaims, *internal.IDTokenSubject, error) {
+	verifier := oidc.NewVerifier(s.issuerURL.String(), &signerKeySet{s.signer}, &oidc.Config{SkipClientIDCheck: true})
+	token, err := verifier.Verify(ctx, rawToken)
+	if err != nil {
+		return nil, nil, nil
+	}
+	var claims idTokenClaims
+	if err := token.Claims(&claims); err != nil {
+		return nil, nil, fmt.Errorf("failed to decode claims: %v", err)
+	}
+	if clientID, err := getClientID(claims.Audience, claims.AuthorizingParty); err != nil || clientID != client.ID {
+		return nil, nil, nil
+	}
+	sub := new(internal.IDTokenSubject)
+	if err := internal.Unmarshal(token.Subject, sub); err != nil {
+		return nil, nil, nil
+	}
+	return &claims, sub, nil
+}
+
+// handleDexTokenExchange exchanges a refresh or access token issued by dex
+// for a token with a subset of its scopes and, if the client's policy
+// allows, another audience. Refresh tokens are exchanged for children, which
+// are revoked with their parent.
+func (s *Server) handleDexTokenExchange(w http.ResponseWriter, r *http.Request, client storage.Client, subjectToken string, exchange tokenExchangeRequest) {
+	ctx := r.Context()
+
+	subject, err := s.dexTokenSubject(ctx, client, exchange.subjectTokenType, subjectToken)
+	if err != nil {
+		s.logger.ErrorContext(ctx, "failed to verify subject token", "err", err)
+		s.tokenErrHelper(w, errServerError, "", http.StatusInternalServerError)
+		return
+	}
+	if subject == nil {
+		s.auditTokenExchange(ctx, client, exchange, "", "Invalid subject token.")
+		s.tokenErrHelper(w, errAccessDenied, "", http.StatusUnauthorized)
+		return
+	}
+	exchange.connID = subject.connID
+
+	if !isConnectorAllowed(client.AllowedConnectors, subject.connID) {
+		s.auditTokenExchange(ctx, client, exchange, subject.claims.UserID, "Connector not allowed for this client.")
+		s.tokenErrHelper(w, errInvalidRequest, "Connector not allowed for this client.", http.StatusBadRequest)
+		return
+	}
+
+	// Without requested scopes, the token gets all scopes of the subject.
+	if len(exchange.scopes) == 0 {
+		exchange.scopes = subject.scopes
+	}
+	for _, scope := range exchange.scopes {
+		if !slices.Contains(subject.scopes, scope) {
+			description := fmt.Sprintf("Subject token wasn't granted scope %q.", scope)
+			s.auditTokenExchange(ctx, client, exchange, subject.claims.UserID, description)
+			s.tokenErrHelper(w, errInvalidScope, description, http.StatusBadRequest)
+			return
+		}
+	}
+	if errType, description := s.checkTokenExchangePolicy(client, exchange); errType != "" {
+		s.auditTokenExchange(ctx, client, exchange, subject.claims.UserID, description)
+		s.tokenErrHelper(w, errType, description, http.StatusBadRequest)
+		return
+	}
+
+	if !s.allowTokenRequest(w, r, riskFlowTokenExchange, claimsIdentity(subject.claims), subject.connID, client.ID, exchange.scopes) {
+		s.auditTokenExchange(ctx, client, exchange, subject.claims.UserID, "Denied by risk assessment.")
+		return
+	}
+
+	// Children of a refresh token keep its audience unless another one is
+	// requested.
+	audience := exchange.audiences
+	if len(audience) == 0 && subject.refresh != nil {
+		audience = subject.refresh.Audience
+	}
+
+	resp := &accessTokenResponse{
+		IssuedTokenType: exchange.requestedTokenType,
+		TokenType:       "bearer",
+	}
+	var expiry time.Time
+	opts := tokenOptions{audience: audience}
+	switch exchange.requestedTokenType {
+	case tokenTypeID:
+		opts.customClaims = client.CustomClaims
+		resp.AccessToken, expiry, err = s.newToken(ctx, client.ID, subject.claims, exchange.scopes, "", "", "", subject.connID, time.Time{}, subject.connectorData, opts)
+	case tokenTypeAccess:
+		resp.AccessToken, expiry, err = s.newToken(ctx, client.ID, subject.claims, exchange.scopes, "", storage.NewID(), "", subject.connID, time.Time{}, subject.connectorData, opts)
+	case tokenTypeRefresh:
+		if subject.refresh == nil {
+			s.tokenErrHelper(w, errInvalidRequest, "Only refresh tokens can be exchanged for refresh tokens.", http.StatusBadRequest)
+			return
+		}
+		if subject.generation >= maxRefreshTokenGenerations {
+			s.auditTokenExchange(ctx, client, exchange, subject.claims.UserID, "Refresh token can't be exchanged further.")
+			s.tokenErrHelper(w, errInvalidRequest, "Refresh token can't be exchanged further.", http.StatusBadRequest)
+			return
+		}
+		resp.AccessToken, err = s.newChildRefreshToken(ctx, subject.refresh, exchange.scopes, audience)
+	default:
+		s.tokenErrHelper(w, errRequestNotSupported, "Invalid requested_token_type.", http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		s.logger.ErrorContext(ctx, "token exchange failed to create new token", "requested_token_type", exchange.requestedTokenType, "err", err)
+		s.tokenErrHelper(w, errServerError, "", http.StatusInternalServerError)
+		return
+	}
+	s.auditTokenExchange(ctx, client, exchange, subject.claims.UserID, "")
+	if !expiry.IsZero() {
+		resp.ExpiresIn = int(time.Until(expiry).Seconds())
+	}
+	s.writeAccessToken(w, resp)
+}
+
+// newChildRefreshToken issues a refresh token restricted to a subset of the
+// scopes of its parent.
+func (s *Server) newChildRefreshToken(ctx context.Context, parent *storage.RefreshToken, scopes, audience []string) (string, error) {
+	now := s.now()
+	rotationKey, tokenValue := newRefreshTokenValue()
+	child := storage.RefreshToken{
+		ID:          storage.NewID(),
+		Token:       tokenValue,
+		RotationKey: rotationKey,
+		CreatedAt:   now,
+		LastUsed:    now,
+		ClientID:    parent.ClientID,
+		ConnectorID: parent.ConnectorID,
+		Claims:      parent.Claims,
+		Scopes:      scopes,
+		Nonce:       parent.Nonce,
+		Resources:   parent.Resources,
+		ParentID:    parent.ID,
+		Audience:    audience,
+	}
+	if err := s.storage.CreateRefresh(ctx, child); err != nil {
+		return "", fmt.Errorf("failed to create refresh token: %v", err)
+	}
+	return internal.Marshal(&internal.RefreshToken{RefreshId: child.ID, Token: child.Token})
+}
+
+// refreshTokenParent returns the parent of a refresh token issued by a token
+// exchange, and the number of its ancestors. The token is deleted once one of
+// its ancestors is revoked.
+func (s *Server) refreshTokenParent(ctx context.Context, refresh storage.RefreshToken) (*storage.RefreshToken, int, *refreshError) {
+	var parent *storage.RefreshToken
+	generation := 0
+	for id := refresh.ParentID; id != ""; generation++ {
+		if generation == maxRefreshTokenGenerations {
+			s.logger.ErrorContext(ctx, "refresh token has too many ancestors", "token_id", refresh.ID)
+			return nil, 0, invalidErr
+		}
+		ancestor, err := s.storage.GetRefresh(ctx, id)
+		if errors.Is(err, storage.ErrNotFound) {
+			s.logger.InfoContext(ctx, "refresh token revoked with its parent", "token_id", refresh.ID, "parent_id", id)
+			if err := s.storage.DeleteRefresh(ctx, refresh.ID); err != nil && !errors.Is(err, storage.ErrNotFound) {
+				s.logger.ErrorContext(ctx, "failed to delete refresh token", "token_id", refresh.ID, "err", err)
+			}
+			return nil, 0, invalidErr
+		}
+		if err != nil {
+			s.logger.ErrorContext(ctx, "failed to get parent refresh token", "err", err)
+			return nil, 0, newInternalServerError()
+		}
+		if s.refreshTokenPolicy.CompletelyExpired(ancestor.CreatedAt) {
+			s.logger.ErrorContext(ctx, "parent refresh token expired", "token_id", refresh.ID, "parent_id", id)
+			return nil, 0, expiredErr
+		}
+		if parent == nil {
+			parent = &ancestor
+		}
+		id = ancestor.ParentID
+	}
+	return parent, generation, nil
+}
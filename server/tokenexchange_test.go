@@ -0,0 +1,298 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/coreos/go-oidc/v3/oidc"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dexidp/dex/server/internal"
+	"github.com/dexidp/dex/storage"
+)
+
+func TestHandleTokenExchangePolicy(t *testing.T) {
+	policy := &storage.TokenExchangePolicy{
+		SubjectTokenTypes: []string{tokenTypeID},
+		Connectors:        []string{"mock"},
+		Audiences:         []string{"https://api.example.com"},
+		Scopes:            []string{"openid", "email"},
+	}
+
+	tests := []struct {
+		name             string
+		policy           *storage.TokenExchangePolicy
+		requiresPolicy   *bool
+		connType         string
+		connID           string
+		subjectTokenType string
+		scope            string
+		audience         string
+
+		wantCode     int
+		wantError    string
+		wantAudience []string
+	}{
+		{
+			name:         "allowed by policy",
+			policy:       policy,
+			scope:        "openid email",
+			audience:     "https://api.example.com",
+			wantCode:     http.StatusOK,
+			wantAudience: []string{"client_1", "https://api.example.com"},
+		},
+		{
+			name:         "no policy",
+			scope:        "openid groups",
+			wantCode:     http.StatusOK,
+			wantAudience: []string{"client_1"},
+		},
+		{
+			name:         "no policy with audience",
+			audience:     "https://api.example.com",
+			wantCode:     http.StatusOK,
+			wantAudience: []string{"client_1"},
+		},
+		{
+			name:           "policy required",
+			requiresPolicy: boolPtr(true),
+			wantCode:       http.StatusBadRequest,
+			wantError:      errUnauthorizedClient,
+		},
+		{
+			name:      "policy required for hsdp by default",
+			connType:  "hsdp",
+			wantCode:  http.StatusBadRequest,
+			wantError: errUnauthorizedClient,
+		},
+		{
+			name:           "policy not required for hsdp",
+			connType:       "hsdp",
+			requiresPolicy: boolPtr(false),
+			wantCode:       http.StatusOK,
+			wantAudience:   []string{"client_1"},
+		},
+		{
+			name:         "hsdp allowed by policy",
+			connType:     "hsdp",
+			policy:       policy,
+			wantCode:     http.StatusOK,
+			wantAudience: []string{"client_1"},
+		},
+		{
+			name:             "subject token type not allowed",
+			policy:           policy,
+			subjectTokenType: tokenTypeAccess,
+			wantCode:         http.StatusBadRequest,
+			wantError:        errInvalidRequest,
+		},
+		{
+			name:      "connector not allowed",
+			policy:    policy,
+			connID:    "mock2",
+			wantCode:  http.StatusBadRequest,
+			wantError: errInvalidRequest,
+		},
+		{
+			name:      "scope not allowed",
+			policy:    policy,
+			scope:     "openid groups",
+			wantCode:  http.StatusBadRequest,
+			wantError: errInvalidScope,
+		},
+		{
+			name:      "audience not allowed",
+			policy:    policy,
+			audience:  "https://other.example.com",
+			wantCode:  http.StatusBadRequest,
+			wantError: errInvalidTarget,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := t.Context()
+			var logs bytes.Buffer
+			httpServer, s := newTestServer(t, func(c *Config) {
+				c.Logger = slog.New(slog.NewJSONHandler(&logs, nil))
+				c.TokenExchangeRequiresPolicy = tc.requiresPolicy
+				c.Storage.CreateConnector(ctx, storage.Connector{
+					ID:              "mock2",
+					Type:            "mockCallback",
+					Name:            "Mock",
+					ResourceVersion: "1",
+				})
+				c.Storage.CreateClient(ctx, storage.Client{
+					ID:            "client_1",
+					Secret:        "secret_1",
+					TokenExchange: tc.policy,
+				})
+			})
+			defer httpServer.Close()
+			if tc.connType != "" {
+				conn, err := s.getConnector(ctx, "mock")
+				require.NoError(t, err)
+				conn.Type = tc.connType
+				s.mu.Lock()
+				s.connectors["mock"] = conn
+				s.mu.Unlock()
+			}
+
+			vals := make(url.Values)
+			vals.Set("grant_type", grantTypeTokenExchange)
+			vals.Set("connector_id", "mock")
+			setNonEmpty(vals, "connector_id", tc.connID)
+			vals.Set("subject_token_type", tokenTypeID)
+			setNonEmpty(vals, "subject_token_type", tc.subjectTokenType)
+			vals.Set("subject_token", "foobar")
+			setNonEmpty(vals, "scope", tc.scope)
+			setNonEmpty(vals, "audience", tc.audience)
+			vals.Set("client_id", "client_1")
+			vals.Set("client_secret", "secret_1")
+
+			rr := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodPost, httpServer.URL+"/token", strings.NewReader(vals.Encode()))
+			req.Header.Set("content-type", "application/x-www-form-urlencoded")
+			s.handleToken(rr, req)
+
+			require.Equal(t, tc.wantCode, rr.Code, rr.Body.String())
+			if tc.wantCode != http.StatusOK {
+				var res struct {
+					Error string `json:"error"`
+				}
+				require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &res))
+				require.Equal(t, tc.wantError, res.Error)
+				require.Contains(t, logs.String(), `"msg":"token exchange","outcome":"denied"`)
+				return
+			}
+
+			var res accessTokenResponse
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &res))
+			provider, err := oidc.NewProvider(ctx, httpServer.URL)
+			require.NoError(t, err)
+			token, err := provider.Verifier(&oidc.Config{ClientID: "client_1"}).Verify(ctx, res.AccessToken)
+			require.NoError(t, err)
+			require.Equal(t, tc.wantAudience, []string(token.Audience))
+			require.Contains(t, logs.String(), `"msg":"token exchange","outcome":"granted"`)
+		})
+	}
+}
+
+func TestHandleTokenExchangeDexTokens(t *testing.T) {
+	ctx := t.Context()
+	httpServer, s := newTestServer(t, func(c *Config) {
+		mockRefreshTokenTestStorage(t, c.Storage, false)
+		require.NoError(t, c.Storage.UpdateClient(ctx, "test", func(old storage.Client) (storage.Client, error) {
+			old.TokenExchange = &storage.TokenExchangePolicy{Audiences: []string{"https://api.example.com"}}
+			return old, nil
+		}))
+	})
+	defer httpServer.Close()
+
+	parent, err := internal.Marshal(&internal.RefreshToken{RefreshId: "test", Token: "bar"})
+	require.NoError(t, err)
+
+	post := func(vals url.Values) *httptest.ResponseRecorder {
+		vals.Set("client_id", "test")
+		vals.Set("client_secret", "barfoo")
+		rr := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, httpServer.URL+"/token", strings.NewReader(vals.Encode()))
+		req.Header.Set("content-type", "application/x-www-form-urlencoded")
+		s.handleToken(rr, req)
+		return rr
+	}
+	exchange := func(subjectTokenType, subjectToken, requestedTokenType, scope, audience string) *httptest.ResponseRecorder {
+		vals := url.Values{
+			"grant_type":           {grantTypeTokenExchange},
+			"subject_token_type":   {subjectTokenType},
+			"subject_token":        {subjectToken},
+			"requested_token_type": {requestedTokenType},
+		}
+		setNonEmpty(vals, "scope", scope)
+		setNonEmpty(vals, "audience", audience)
+		return post(vals)
+	}
+	errorOf := func(rr *httptest.ResponseRecorder) string {
+		var res struct {
+			Error string `json:"error"`
+		}
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &res))
+		return res.Error
+	}
+	provider, err := oidc.NewProvider(ctx, httpServer.URL)
+	require.NoError(t, err)
+	verifier := provider.Verifier(&oidc.Config{ClientID: "test"})
+
+	// A refresh token is exchanged for an access token with fewer scopes.
+	rr := exchange(tokenTypeRefresh, parent, tokenTypeAccess, "openid email", "https://api.example.com")
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	var res accessTokenResponse
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &res))
+	require.Equal(t, tokenTypeAccess, res.IssuedTokenType)
+	token, err := verifier.Verify(ctx, res.AccessToken)
+	require.NoError(t, err)
+	require.Equal(t, []string{"test", "https://api.example.com"}, token.Audience)
+	var claims idTokenClaims
+	require.NoError(t, token.Claims(&claims))
+	require.Equal(t, "jane.doe@example.com", claims.Email)
+	require.Empty(t, claims.Name, "the profile scope wasn't requested")
+	accessToken := res.AccessToken
+
+	rr = exchange(tokenTypeRefresh, parent, tokenTypeAccess, "openid groups", "")
+	require.Equal(t, http.StatusBadRequest, rr.Code)
+	require.Equal(t, errInvalidScope, errorOf(rr), "scopes not granted to the subject can't be requested")
+
+	// An access token issued by dex can be down-scoped too, but not be
+	// exchanged for a refresh token.
+	rr = exchange(tokenTypeAccess, accessToken, tokenTypeAccess, "openid", "")
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	rr = exchange(tokenTypeAccess, accessToken, tokenTypeAccess, "openid profile", "")
+	require.Equal(t, errInvalidScope, errorOf(rr))
+	rr = exchange(tokenTypeAccess, accessToken, tokenTypeRefresh, "openid", "")
+	require.Equal(t, errInvalidRequest, errorOf(rr))
+
+	// A refresh token is exchanged for a child keeping the audience.
+	rr = exchange(tokenTypeRefresh, parent, tokenTypeRefresh, "openid email", "https://api.example.com")
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	res = accessTokenResponse{}
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &res))
+	require.Equal(t, tokenTypeRefresh, res.IssuedTokenType)
+	child := new(internal.RefreshToken)
+	require.NoError(t, internal.Unmarshal(res.AccessToken, child))
+	stored, err := s.storage.GetRefresh(ctx, child.RefreshId)
+	require.NoError(t, err)
+	require.Equal(t, "test", stored.ParentID)
+	require.Equal(t, []string{"openid", "email"}, stored.Scopes)
+
+	refresh := func(token string) *httptest.ResponseRecorder {
+		return post(url.Values{"grant_type": {grantTypeRefreshToken}, "refresh_token": {token}})
+	}
+	rr = refresh(res.AccessToken)
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	res = accessTokenResponse{}
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &res))
+	token, err = verifier.Verify(ctx, res.AccessToken)
+	require.NoError(t, err)
+	require.Equal(t, []string{"test", "https://api.example.com"}, token.Audience)
+
+	// The parent keeps working, and its offline session isn't touched.
+	rr = refresh(parent)
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	var parentRes accessTokenResponse
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &parentRes))
+
+	// Revoking the parent revokes the child.
+	require.NoError(t, s.storage.DeleteRefresh(ctx, "test"))
+	rr = refresh(res.RefreshToken)
+	require.Equal(t, http.StatusBadRequest, rr.Code, rr.Body.String())
+	_, err = s.storage.GetRefresh(ctx, child.RefreshId)
+	require.ErrorIs(t, err, storage.ErrNotFound)
+
+	rr = exchange(tokenTypeRefresh, parentRes.RefreshToken, tokenTypeAccess, "", "")
+	require.Equal(t, http.StatusUnauthorized, rr.Code, rr.Body.String())
+}
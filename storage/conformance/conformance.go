@@ -1,8 +1,25 @@
 // Package conformance provides conformance tests for storage implementations.
+//
+// Storage implementations kept outside of this repository can be verified
+// against the semantics dex relies on by calling the suites from their own
+// tests:
+//
+//	func TestConformance(t *testing.T) {
+//		for _, suite := range conformance.Suites {
+//			t.Run(suite.Name, func(t *testing.T) {
+//				suite.Run(t, newEmptyStorage)
+//			})
+//		}
+//	}
+//
+// A deployed storage can be checked with TestStorageConformance of the dex
+// command's test binary instead, see cmd/dex/storageconformance_test.go.
 package conformance
 
 import (
+	"context"
 	"crypto/ecdsa"
+	"errors"
 	"reflect"
 	"sort"
 	"testing"
@@ -52,6 +69,7 @@ func RunTests(t *testing.T, newStorage func(t *testing.T) storage.Storage) {
 		{"PasswordCRUD", testPasswordCRUD},
 		{"KeysCRUD", testKeysCRUD},
 		{"OfflineSessionCRUD", testOfflineSessionCRUD},
+		{"EmptyOfflineSessions", testEmptyOfflineSessions},
 		{"ConnectorCRUD", testConnectorCRUD},
 		{"GarbageCollection", testGC},
 		{"TimezoneSupport", testTimezones},
@@ -59,9 +77,24 @@ func RunTests(t *testing.T, newStorage func(t *testing.T) storage.Storage) {
 		{"DeviceTokenCRUD", testDeviceTokenCRUD},
 		{"UserIdentityCRUD", testUserIdentityCRUD},
 		{"AuthSessionCRUD", testAuthSessionCRUD},
+		{"RateLimitBuckets", testRateLimitBuckets},
+		{"AuditEvents", testAuditEvents},
 	})
 }
 
+// Suite is a named set of conformance tests.
+type Suite struct {
+	Name string
+	Run  func(t *testing.T, newStorage func(t *testing.T) storage.Storage)
+}
+
+// Suites are all the conformance tests a storage has to pass.
+var Suites = []Suite{
+	{"Storage", RunTests},
+	{"Transactions", RunTransactionTests},
+	{"Concurrency", RunConcurrencyTests},
+}
+
 func mustLoadJWK(b string) *jose.JSONWebKey {
 	var jwt jose.JSONWebKey
 	if err := jwt.UnmarshalJSON([]byte(b)); err != nil {
@@ -154,9 +187,12 @@ func testAuthRequestCRUD(t *testing.T, s storage.Storage) {
 			Email:         "jane.doe@example.com",
 			EmailVerified: true,
 			Groups:        []string{"a", "b"},
+			CustomClaims:  map[string]interface{}{"tenant": "radiology", "roles": []interface{}{"admin"}},
 		},
-		PKCE:    codeChallenge,
-		HMACKey: []byte("hmac_key"),
+		PKCE:      codeChallenge,
+		HMACKey:   []byte("hmac_key"),
+		Resources: []string{"https://api.example.com"},
+		LoginHint: "jane.doe@example.com",
 	}
 
 	identity := storage.Claims{Email: "foobar"}
@@ -237,6 +273,7 @@ func testAuthCodeCRUD(t *testing.T, s storage.Storage) {
 		RedirectURI:   "https://localhost:80/callback",
 		Nonce:         "foobar",
 		Scopes:        []string{"openid", "email"},
+		Resources:     []string{"https://api.example.com"},
 		Expiry:        neverExpire,
 		AuthTime:      defaultAuthTime,
 		ConnectorID:   "ldap",
@@ -251,6 +288,7 @@ func testAuthCodeCRUD(t *testing.T, s storage.Storage) {
 			Email:         "jane.doe@example.com",
 			EmailVerified: true,
 			Groups:        []string{"a", "b"},
+			CustomClaims:  map[string]interface{}{"tenant": "radiology", "roles": []interface{}{"admin"}},
 		},
 	}
 
@@ -320,6 +358,50 @@ func testClientCRUD(t *testing.T, s storage.Storage) {
 		Name:              "dex client",
 		LogoURL:           "https://goo.gl/JIyzIC",
 		AllowedConnectors: []string{"github", "google"},
+		Theme: &storage.ClientTheme{
+			LogoURL:      "https://example.com/logo.svg",
+			PrimaryColor: "#0b5ed7",
+			FooterLinks:  []storage.ThemeLink{{Text: "Privacy", URL: "https://example.com/privacy"}},
+		},
+		RequireConsentOnClaimChange: true,
+		ClientCredentials: &storage.ClientCredentialsConfig{
+			Audiences: []string{"https://api.example.com"},
+			Claims:    map[string]interface{}{"tier": "gold"},
+		},
+		JWTBearerIssuers: []storage.JWTBearerIssuer{{
+			Issuer:          "https://spiffe.example.com",
+			JWKSURL:         "https://spiffe.example.com/keys",
+			AllowedSubjects: []string{"spiffe://example.com/billing"},
+		}},
+		TokenExchange: &storage.TokenExchangePolicy{
+			SubjectTokenTypes: []string{"urn:ietf:params:oauth:token-type:id_token"},
+			Connectors:        []string{"google"},
+		},
+		Resources:               []string{"https://api.example.com", "https://billing.example.com"},
+		CustomClaims:            map[string]interface{}{"tenant": "radiology", "login": "{{.Email}}"},
+		ReleasedConnectorClaims: []string{"tenant"},
+		RefreshTokenReuse: &storage.RefreshTokenReusePolicy{
+			RevokeSessions: true,
+			RevokeUpstream: true,
+		},
+		DeviceFlow: &storage.DeviceFlowConfig{
+			PollInterval: 10,
+			ExpiresIn:    600,
+		},
+		AllowedOrigins: []string{"https://app.example.com"},
+		GroupsClaim: &storage.GroupsClaim{
+			Name:   "cognito:groups",
+			Format: storage.GroupsClaimString,
+		},
+		Terms: &storage.ClientTerms{
+			Version: "2024-05",
+			URL:     "https://example.com/terms",
+		},
+		Impersonation: &storage.ImpersonationPolicy{
+			Connectors:  []string{"ldap"},
+			ActorGroups: []string{"support"},
+			ValidFor:    "10m",
+		},
 	}
 	err := s.DeleteClient(ctx, id1)
 	mustBeErrNotFound(t, "client", err)
@@ -427,6 +509,10 @@ func testRefreshTokenCRUD(t *testing.T, s storage.Storage) {
 		ClientID:      "client_id",
 		ConnectorID:   "client_secret",
 		Scopes:        []string{"openid", "email", "profile"},
+		Resources:     []string{"https://api.example.com"},
+		ParentID:      storage.NewID(),
+		Audience:      []string{"https://billing.example.com"},
+		RotationKey:   storage.NewID(),
 		CreatedAt:     time.Now().UTC().Round(time.Millisecond),
 		LastUsed:      time.Now().UTC().Round(time.Millisecond),
 		Claims: storage.Claims{
@@ -435,6 +521,7 @@ func testRefreshTokenCRUD(t *testing.T, s storage.Storage) {
 			Email:         "jane.doe@example.com",
 			EmailVerified: true,
 			Groups:        []string{"a", "b"},
+			CustomClaims:  map[string]interface{}{"tenant": "radiology", "roles": []interface{}{"admin"}},
 		},
 		ConnectorData: []byte(`{"some":"data"}`),
 	}
@@ -701,6 +788,14 @@ func testOfflineSessionCRUD(t *testing.T, s storage.Storage) {
 
 	getAndCompare(userID1, "Conn1", session1)
 
+	sessions, err := s.ListOfflineSessions(ctx)
+	if err != nil {
+		t.Fatalf("list offline sessions: %v", err)
+	}
+	if len(sessions) != 2 {
+		t.Fatalf("expected 2 offline sessions, got %d", len(sessions))
+	}
+
 	if err := s.DeleteOfflineSessions(ctx, session1.UserID, session1.ConnID); err != nil {
 		t.Fatalf("failed to delete offline session: %v", err)
 	}
@@ -713,6 +808,41 @@ func testOfflineSessionCRUD(t *testing.T, s storage.Storage) {
 	mustBeErrNotFound(t, "offline session", err)
 }
 
+func testEmptyOfflineSessions(t *testing.T, s storage.Storage) {
+	ps, ok := s.(storage.OfflineSessionsStorage)
+	if !ok {
+		t.Skip("storage does not support pruning offline sessions")
+	}
+	ctx := t.Context()
+	userID := storage.NewID()
+
+	_, err := ps.DeleteOfflineSessionsIfEmpty(ctx, userID, "Conn1")
+	mustBeErrNotFound(t, "offline session", err)
+
+	session := storage.OfflineSessions{
+		UserID:  userID,
+		ConnID:  "Conn1",
+		Refresh: map[string]*storage.RefreshTokenRef{"client_id": {ID: storage.NewID(), ClientID: "client_id"}},
+	}
+	require.NoError(t, s.CreateOfflineSessions(ctx, session))
+
+	deleted, err := ps.DeleteOfflineSessionsIfEmpty(ctx, userID, "Conn1")
+	require.NoError(t, err)
+	require.False(t, deleted, "offline session with a refresh token was deleted")
+	_, err = s.GetOfflineSessions(ctx, userID, "Conn1")
+	require.NoError(t, err)
+
+	require.NoError(t, s.UpdateOfflineSessions(ctx, userID, "Conn1", func(old storage.OfflineSessions) (storage.OfflineSessions, error) {
+		delete(old.Refresh, "client_id")
+		return old, nil
+	}))
+	deleted, err = ps.DeleteOfflineSessionsIfEmpty(ctx, userID, "Conn1")
+	require.NoError(t, err)
+	require.True(t, deleted)
+	_, err = s.GetOfflineSessions(ctx, userID, "Conn1")
+	mustBeErrNotFound(t, "offline session", err)
+}
+
 func testConnectorCRUD(t *testing.T, s storage.Storage) {
 	ctx := t.Context()
 	id1 := storage.NewID()
@@ -1298,6 +1428,7 @@ func testUserIdentityCRUD(t *testing.T, s storage.Storage) {
 			Email:         "jane@example.com",
 			EmailVerified: true,
 			Groups:        []string{"a", "b"},
+			CustomClaims:  map[string]interface{}{"tenant": "radiology", "roles": []interface{}{"admin"}},
 		},
 		Consents:     make(map[string][]string),
 		CreatedAt:    now,
@@ -1331,6 +1462,10 @@ func testUserIdentityCRUD(t *testing.T, s storage.Storage) {
 	// Update: add consent entry.
 	if err := s.UpdateUserIdentity(ctx, u1.UserID, u1.ConnectorID, func(old storage.UserIdentity) (storage.UserIdentity, error) {
 		old.Consents["client1"] = []string{"openid", "email"}
+		old.ConsentedClaims = map[string]string{"client1": "digest"}
+		old.AcceptedTerms = map[string]storage.TermsAcceptance{
+			"client1": {Version: "2024-05", AcceptedAt: time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)},
+		}
 		return old, nil
 	}); err != nil {
 		t.Fatalf("update user identity: %v", err)
@@ -1345,6 +1480,16 @@ func testUserIdentityCRUD(t *testing.T, s storage.Storage) {
 	if diff := pretty.Compare(wantConsents, got.Consents); diff != "" {
 		t.Errorf("user identity consents did not match after update: %s", diff)
 	}
+	wantConsentedClaims := map[string]string{"client1": "digest"}
+	if diff := pretty.Compare(wantConsentedClaims, got.ConsentedClaims); diff != "" {
+		t.Errorf("user identity consented claims did not match after update: %s", diff)
+	}
+	wantAcceptedTerms := map[string]storage.TermsAcceptance{
+		"client1": {Version: "2024-05", AcceptedAt: time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)},
+	}
+	if diff := pretty.Compare(wantAcceptedTerms, got.AcceptedTerms); diff != "" {
+		t.Errorf("user identity accepted terms did not match after update: %s", diff)
+	}
 
 	// List and verify.
 	identities, err := s.ListUserIdentities(ctx)
@@ -1462,3 +1607,117 @@ func testAuthSessionCRUD(t *testing.T, s storage.Storage) {
 	_, err = s.GetAuthSession(ctx, session.UserID, session.ConnectorID)
 	mustBeErrNotFound(t, "auth session", err)
 }
+
+func testRateLimitBuckets(t *testing.T, s storage.Storage) {
+	rs, ok := s.(storage.RateLimitStorage)
+	if !ok {
+		t.Skip("storage does not support shared rate limits")
+	}
+	ctx := t.Context()
+	now := time.Now().UTC().Round(time.Millisecond)
+	key := "ip:192.0.2.1"
+
+	err := rs.UpdateRateLimitBucket(ctx, key, func(b storage.RateLimitBucket) (storage.RateLimitBucket, error) {
+		require.Equal(t, key, b.Key)
+		require.True(t, b.TAT.Before(now), "new bucket should be full")
+		require.Zero(t, b.Count)
+		b.TAT = now.Add(time.Second)
+		b.Count = 2
+		b.Expiry = now.Add(time.Second)
+		return b, nil
+	})
+	require.NoError(t, err)
+
+	// Failed updates are not stored.
+	errUpdate := errors.New("denied")
+	err = rs.UpdateRateLimitBucket(ctx, key, func(b storage.RateLimitBucket) (storage.RateLimitBucket, error) {
+		require.True(t, b.TAT.Equal(now.Add(time.Second)), "got TAT %v", b.TAT)
+		b.TAT = now.Add(time.Hour)
+		return b, errUpdate
+	})
+	require.ErrorIs(t, err, errUpdate)
+
+	// Denied requests leave the bucket unchanged, which must not be retried
+	// as a lost update.
+	var calls int
+	deniedCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	err = rs.UpdateRateLimitBucket(deniedCtx, key, func(b storage.RateLimitBucket) (storage.RateLimitBucket, error) {
+		calls++
+		require.True(t, b.TAT.Equal(now.Add(time.Second)), "got TAT %v", b.TAT)
+		require.Equal(t, 2, b.Count)
+		require.True(t, b.Expiry.Equal(now.Add(time.Second)), "got expiry %v", b.Expiry)
+		return b, nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, 1, calls, "unchanged bucket should be updated once")
+
+	result, err := s.GarbageCollect(ctx, now)
+	require.NoError(t, err)
+	require.Zero(t, result.RateLimitBuckets)
+
+	result, err = s.GarbageCollect(ctx, now.Add(time.Minute))
+	require.NoError(t, err)
+	require.Equal(t, int64(1), result.RateLimitBuckets)
+}
+
+func testAuditEvents(t *testing.T, s storage.Storage) {
+	as, ok := s.(storage.AuditStorage)
+	if !ok {
+		t.Skip("storage does not support audit events")
+	}
+	ctx := t.Context()
+	now := time.Now().UTC().Round(time.Millisecond)
+
+	newEvent := func(subject, clientID string, at time.Time) storage.AuditEvent {
+		return storage.AuditEvent{
+			ID:          storage.NewID(),
+			Type:        "io.dexidp.login.v1",
+			Time:        at,
+			Subject:     subject,
+			ClientID:    clientID,
+			ConnectorID: "ldap",
+			Data:        []byte(`{"user_id":"` + subject + `"}`),
+			Expiry:      at.Add(time.Hour),
+		}
+	}
+	first := newEvent("alice", "app", now.Add(-2*time.Minute))
+	second := newEvent("bob", "app", now.Add(-time.Minute))
+	third := newEvent("alice", "cli", now)
+	for _, e := range []storage.AuditEvent{second, third, first} {
+		require.NoError(t, as.CreateAuditEvent(ctx, e))
+	}
+	err := as.CreateAuditEvent(ctx, first)
+	mustBeErrAlreadyExists(t, "audit event", err)
+
+	ids := func(f storage.AuditEventFilter) []string {
+		events, err := as.ListAuditEvents(ctx, f)
+		require.NoError(t, err)
+		var ids []string
+		for _, e := range events {
+			ids = append(ids, e.ID)
+		}
+		return ids
+	}
+
+	require.Equal(t, []string{third.ID, second.ID, first.ID}, ids(storage.AuditEventFilter{}))
+	require.Equal(t, []string{third.ID, first.ID}, ids(storage.AuditEventFilter{Subject: "alice"}))
+	require.Equal(t, []string{second.ID, first.ID}, ids(storage.AuditEventFilter{ClientID: "app"}))
+	require.Equal(t, []string{third.ID}, ids(storage.AuditEventFilter{Subject: "alice", Limit: 1}))
+	require.Equal(t, []string{second.ID}, ids(storage.AuditEventFilter{Since: second.Time, Until: second.Time}))
+	require.Empty(t, ids(storage.AuditEventFilter{ConnectorID: "github"}))
+
+	events, err := as.ListAuditEvents(ctx, storage.AuditEventFilter{Type: first.Type, Until: first.Time})
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	got := events[0]
+	require.True(t, got.Time.Equal(first.Time), "got time %v", got.Time)
+	require.True(t, got.Expiry.Equal(first.Expiry), "got expiry %v", got.Expiry)
+	got.Time, got.Expiry = first.Time, first.Expiry
+	require.Equal(t, first, got)
+
+	result, err := s.GarbageCollect(ctx, now.Add(time.Hour-90*time.Second))
+	require.NoError(t, err)
+	require.Equal(t, int64(1), result.AuditEvents)
+	require.Equal(t, []string{third.ID, second.ID}, ids(storage.AuditEventFilter{}))
+}
@@ -36,6 +36,7 @@ func RunTransactionTests(t *testing.T, newStorage func(t *testing.T) storage.Sto
 func RunConcurrencyTests(t *testing.T, newStorage func(t *testing.T) storage.Storage) {
 	runTests(t, newStorage, []subTest{
 		{"RefreshTokenParallelUpdate", testRefreshTokenParallelUpdate},
+		{"RateLimitBucketParallelUpdate", testRateLimitBucketParallelUpdate},
 	})
 }
 
@@ -350,3 +351,50 @@ func testRefreshTokenParallelUpdate(t *testing.T, s storage.Storage) {
 		t.Errorf("stored token %q does not match expected final value %q", stored.Token, strconv.Itoa(successes))
 	}
 }
+
+func testRateLimitBucketParallelUpdate(t *testing.T, s storage.Storage) {
+	rs, ok := s.(storage.RateLimitStorage)
+	if !ok {
+		t.Skip("storage does not support shared rate limits")
+	}
+	ctx := t.Context()
+	key := "ip:" + storage.NewID()
+	start := time.Now().UTC().Round(time.Millisecond)
+
+	const numWorkers = 50
+
+	var wg sync.WaitGroup
+	errs := make([]error, numWorkers)
+	for i := range numWorkers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs[i] = rs.UpdateRateLimitBucket(ctx, key, func(b storage.RateLimitBucket) (storage.RateLimitBucket, error) {
+				if b.TAT.Before(start) {
+					b.TAT = start
+				}
+				b.TAT = b.TAT.Add(time.Second)
+				b.Count++
+				b.Expiry = b.TAT
+				return b, nil
+			})
+		}()
+	}
+	wg.Wait()
+
+	for _, err := range errs {
+		require.NoError(t, err)
+	}
+
+	err := rs.UpdateRateLimitBucket(ctx, key, func(b storage.RateLimitBucket) (storage.RateLimitBucket, error) {
+		want := start.Add(numWorkers * time.Second)
+		if !b.TAT.Equal(want) {
+			t.Errorf("lost updates detected: TAT is %v, want %v", b.TAT, want)
+		}
+		if b.Count != numWorkers {
+			t.Errorf("lost updates detected: count is %d, want %d", b.Count, numWorkers)
+		}
+		return b, nil
+	})
+	require.NoError(t, err)
+}
@@ -20,6 +20,7 @@ func (d *Database) CreateAuthCode(ctx context.Context, code storage.AuthCode) er
 		SetClaimsUsername(code.Claims.Username).
 		SetClaimsPreferredUsername(code.Claims.PreferredUsername).
 		SetClaimsGroups(code.Claims.Groups).
+		SetClaimsCustom(code.Claims.CustomClaims).
 		SetCodeChallenge(code.PKCE.CodeChallenge).
 		SetCodeChallengeMethod(code.PKCE.CodeChallengeMethod).
 		// Save utc time into database because ent doesn't support comparing dates with different timezones
@@ -27,6 +28,7 @@ func (d *Database) CreateAuthCode(ctx context.Context, code storage.AuthCode) er
 		SetConnectorID(code.ConnectorID).
 		SetConnectorData(code.ConnectorData).
 		SetAuthTime(code.AuthTime).
+		SetResources(code.Resources).
 		Save(ctx)
 	if err != nil {
 		return convertDBError("create auth code: %w", err)
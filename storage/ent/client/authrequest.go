@@ -25,6 +25,7 @@ func (d *Database) CreateAuthRequest(ctx context.Context, authRequest storage.Au
 		SetClaimsUsername(authRequest.Claims.Username).
 		SetClaimsPreferredUsername(authRequest.Claims.PreferredUsername).
 		SetClaimsGroups(authRequest.Claims.Groups).
+		SetClaimsCustom(authRequest.Claims.CustomClaims).
 		SetCodeChallenge(authRequest.PKCE.CodeChallenge).
 		SetCodeChallengeMethod(authRequest.PKCE.CodeChallengeMethod).
 		// Save utc time into database because ent doesn't support comparing dates with different timezones
@@ -35,8 +36,10 @@ func (d *Database) CreateAuthRequest(ctx context.Context, authRequest storage.Au
 		SetMfaValidated(authRequest.MFAValidated).
 		SetWebauthnSessionData(authRequest.WebAuthnSessionData).
 		SetPrompt(authRequest.Prompt).
+		SetLoginHint(authRequest.LoginHint).
 		SetMaxAge(authRequest.MaxAge).
 		SetAuthTime(authRequest.AuthTime).
+		SetResources(authRequest.Resources).
 		Save(ctx)
 	if err != nil {
 		return convertDBError("create auth request: %w", err)
@@ -94,6 +97,7 @@ func (d *Database) UpdateAuthRequest(ctx context.Context, id string, updater fun
 		SetClaimsUsername(newAuthRequest.Claims.Username).
 		SetClaimsPreferredUsername(newAuthRequest.Claims.PreferredUsername).
 		SetClaimsGroups(newAuthRequest.Claims.Groups).
+		SetClaimsCustom(newAuthRequest.Claims.CustomClaims).
 		SetCodeChallenge(newAuthRequest.PKCE.CodeChallenge).
 		SetCodeChallengeMethod(newAuthRequest.PKCE.CodeChallengeMethod).
 		// Save utc time into database because ent doesn't support comparing dates with different timezones
@@ -104,8 +108,10 @@ func (d *Database) UpdateAuthRequest(ctx context.Context, id string, updater fun
 		SetMfaValidated(newAuthRequest.MFAValidated).
 		SetWebauthnSessionData(newAuthRequest.WebAuthnSessionData).
 		SetPrompt(newAuthRequest.Prompt).
+		SetLoginHint(newAuthRequest.LoginHint).
 		SetMaxAge(newAuthRequest.MaxAge).
 		SetAuthTime(newAuthRequest.AuthTime).
+		SetResources(newAuthRequest.Resources).
 		Save(context.TODO())
 	if err != nil {
 		return rollback(tx, "update auth request uploading: %w", err)
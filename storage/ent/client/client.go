@@ -20,6 +20,20 @@ func (d *Database) CreateClient(ctx context.Context, client storage.Client) erro
 		SetMfaChain(client.MFAChain).
 		SetPostLogoutRedirectUris(client.PostLogoutRedirectURIs).
 		SetSSOSharedWith(client.SSOSharedWith).
+		SetTheme(client.Theme).
+		SetRequireConsentOnClaimChange(client.RequireConsentOnClaimChange).
+		SetClientCredentials(client.ClientCredentials).
+		SetJWTBearerIssuers(client.JWTBearerIssuers).
+		SetTokenExchange(client.TokenExchange).
+		SetResources(client.Resources).
+		SetCustomClaims(client.CustomClaims).
+		SetRefreshTokenReuse(client.RefreshTokenReuse).
+		SetDeviceFlow(client.DeviceFlow).
+		SetAllowedOrigins(client.AllowedOrigins).
+		SetReleasedConnectorClaims(client.ReleasedConnectorClaims).
+		SetGroupsClaim(client.GroupsClaim).
+		SetTerms(client.Terms).
+		SetImpersonation(client.Impersonation).
 		Save(ctx)
 	if err != nil {
 		return convertDBError("create oauth2 client: %w", err)
@@ -87,6 +101,20 @@ func (d *Database) UpdateClient(ctx context.Context, id string, updater func(old
 		SetMfaChain(newClient.MFAChain).
 		SetPostLogoutRedirectUris(newClient.PostLogoutRedirectURIs).
 		SetSSOSharedWith(newClient.SSOSharedWith).
+		SetTheme(newClient.Theme).
+		SetRequireConsentOnClaimChange(newClient.RequireConsentOnClaimChange).
+		SetClientCredentials(newClient.ClientCredentials).
+		SetJWTBearerIssuers(newClient.JWTBearerIssuers).
+		SetTokenExchange(newClient.TokenExchange).
+		SetResources(newClient.Resources).
+		SetCustomClaims(newClient.CustomClaims).
+		SetRefreshTokenReuse(newClient.RefreshTokenReuse).
+		SetDeviceFlow(newClient.DeviceFlow).
+		SetAllowedOrigins(newClient.AllowedOrigins).
+		SetReleasedConnectorClaims(newClient.ReleasedConnectorClaims).
+		SetGroupsClaim(newClient.GroupsClaim).
+		SetTerms(newClient.Terms).
+		SetImpersonation(newClient.Impersonation).
 		Save(ctx)
 	if err != nil {
 		return rollback(tx, "update client uploading: %w", err)
@@ -16,7 +16,10 @@ import (
 	"github.com/dexidp/dex/storage/ent/db/migrate"
 )
 
-var _ storage.Storage = (*Database)(nil)
+var (
+	_ storage.Storage                = (*Database)(nil)
+	_ storage.OfflineSessionsStorage = (*Database)(nil)
+)
 
 type Database struct {
 	client    *db.Client
@@ -6,6 +6,7 @@ import (
 	"fmt"
 
 	"github.com/dexidp/dex/storage"
+	"github.com/dexidp/dex/storage/ent/db/offlinesession"
 )
 
 // CreateOfflineSessions saves provided offline session into the database.
@@ -40,6 +41,20 @@ func (d *Database) GetOfflineSessions(ctx context.Context, userID, connID string
 	return toStorageOfflineSession(offlineSession), nil
 }
 
+// ListOfflineSessions extracts all offline sessions from the database.
+func (d *Database) ListOfflineSessions(ctx context.Context) ([]storage.OfflineSessions, error) {
+	offlineSessions, err := d.client.OfflineSession.Query().All(ctx)
+	if err != nil {
+		return nil, convertDBError("list offline sessions: %w", err)
+	}
+
+	storageOfflineSessions := make([]storage.OfflineSessions, 0, len(offlineSessions))
+	for _, o := range offlineSessions {
+		storageOfflineSessions = append(storageOfflineSessions, toStorageOfflineSession(o))
+	}
+	return storageOfflineSessions, nil
+}
+
 // DeleteOfflineSessions deletes an offline session from the database by user id and connector id.
 func (d *Database) DeleteOfflineSessions(ctx context.Context, userID, connID string) error {
 	id := compositeKeyID(userID, connID, d.hasher)
@@ -51,6 +66,30 @@ func (d *Database) DeleteOfflineSessions(ctx context.Context, userID, connID str
 	return nil
 }
 
+// DeleteOfflineSessionsIfEmpty deletes an offline session from the database by user id and connector id
+// if it has no refresh tokens.
+func (d *Database) DeleteOfflineSessionsIfEmpty(ctx context.Context, userID, connID string) (bool, error) {
+	id := compositeKeyID(userID, connID, d.hasher)
+
+	offlineSession, err := d.client.OfflineSession.Get(ctx, id)
+	if err != nil {
+		return false, convertDBError("get offline session: %w", err)
+	}
+	if len(toStorageOfflineSession(offlineSession).Refresh) > 0 {
+		return false, nil
+	}
+
+	// Only delete the session as it was read, a login may have added a
+	// refresh token to it since.
+	n, err := d.client.OfflineSession.Delete().
+		Where(offlinesession.ID(id), offlinesession.RefreshEQ(offlineSession.Refresh)).
+		Exec(ctx)
+	if err != nil {
+		return false, convertDBError("delete offline session: %w", err)
+	}
+	return n > 0, nil
+}
+
 // UpdateOfflineSessions changes an offline session by user id and connector id using an updater function.
 func (d *Database) UpdateOfflineSessions(ctx context.Context, userID string, connID string, updater func(s storage.OfflineSessions) (storage.OfflineSessions, error)) error {
 	id := compositeKeyID(userID, connID, d.hasher)
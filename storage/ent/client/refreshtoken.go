@@ -19,6 +19,7 @@ func (d *Database) CreateRefresh(ctx context.Context, refresh storage.RefreshTok
 		SetClaimsUsername(refresh.Claims.Username).
 		SetClaimsPreferredUsername(refresh.Claims.PreferredUsername).
 		SetClaimsGroups(refresh.Claims.Groups).
+		SetClaimsCustom(refresh.Claims.CustomClaims).
 		SetConnectorID(refresh.ConnectorID).
 		SetConnectorData(refresh.ConnectorData).
 		SetToken(refresh.Token).
@@ -26,6 +27,10 @@ func (d *Database) CreateRefresh(ctx context.Context, refresh storage.RefreshTok
 		// Save utc time into database because ent doesn't support comparing dates with different timezones
 		SetLastUsed(refresh.LastUsed.UTC()).
 		SetCreatedAt(refresh.CreatedAt.UTC()).
+		SetResources(refresh.Resources).
+		SetParentID(refresh.ParentID).
+		SetAudience(refresh.Audience).
+		SetRotationKey(refresh.RotationKey).
 		Save(ctx)
 	if err != nil {
 		return convertDBError("create refresh token: %w", err)
@@ -92,6 +97,7 @@ func (d *Database) UpdateRefreshToken(ctx context.Context, id string, updater fu
 		SetClaimsUsername(newtToken.Claims.Username).
 		SetClaimsPreferredUsername(newtToken.Claims.PreferredUsername).
 		SetClaimsGroups(newtToken.Claims.Groups).
+		SetClaimsCustom(newtToken.Claims.CustomClaims).
 		SetConnectorID(newtToken.ConnectorID).
 		SetConnectorData(newtToken.ConnectorData).
 		SetToken(newtToken.Token).
@@ -99,6 +105,10 @@ func (d *Database) UpdateRefreshToken(ctx context.Context, id string, updater fu
 		// Save utc time into database because ent doesn't support comparing dates with different timezones
 		SetLastUsed(newtToken.LastUsed.UTC()).
 		SetCreatedAt(newtToken.CreatedAt.UTC()).
+		SetResources(newtToken.Resources).
+		SetParentID(newtToken.ParentID).
+		SetAudience(newtToken.Audience).
+		SetRotationKey(newtToken.RotationKey).
 		Save(ctx)
 	if err != nil {
 		return rollback(tx, "update refresh token uploading: %w", err)
@@ -40,6 +40,7 @@ func toStorageAuthRequest(a *db.AuthRequest) storage.AuthRequest {
 			Email:             a.ClaimsEmail,
 			EmailVerified:     a.ClaimsEmailVerified,
 			Groups:            a.ClaimsGroups,
+			CustomClaims:      a.ClaimsCustom,
 		},
 		PKCE: storage.PKCE{
 			CodeChallenge:       a.CodeChallenge,
@@ -53,9 +54,11 @@ func toStorageAuthRequest(a *db.AuthRequest) storage.AuthRequest {
 			}
 			return nil
 		}(),
-		Prompt:   a.Prompt,
-		MaxAge:   a.MaxAge,
-		AuthTime: a.AuthTime,
+		Prompt:    a.Prompt,
+		LoginHint: a.LoginHint,
+		MaxAge:    a.MaxAge,
+		AuthTime:  a.AuthTime,
+		Resources: a.Resources,
 	}
 }
 
@@ -76,12 +79,14 @@ func toStorageAuthCode(a *db.AuthCode) storage.AuthCode {
 			Email:             a.ClaimsEmail,
 			EmailVerified:     a.ClaimsEmailVerified,
 			Groups:            a.ClaimsGroups,
+			CustomClaims:      a.ClaimsCustom,
 		},
 		PKCE: storage.PKCE{
 			CodeChallenge:       a.CodeChallenge,
 			CodeChallengeMethod: a.CodeChallengeMethod,
 		},
-		AuthTime: a.AuthTime,
+		AuthTime:  a.AuthTime,
+		Resources: a.Resources,
 	}
 }
 
@@ -98,6 +103,21 @@ func toStorageClient(c *db.OAuth2Client) storage.Client {
 		MFAChain:               c.MfaChain,
 		PostLogoutRedirectURIs: c.PostLogoutRedirectUris,
 		SSOSharedWith:          c.SSOSharedWith,
+		Theme:                  c.Theme,
+
+		RequireConsentOnClaimChange: c.RequireConsentOnClaimChange,
+		ClientCredentials:           c.ClientCredentials,
+		JWTBearerIssuers:            c.JWTBearerIssuers,
+		TokenExchange:               c.TokenExchange,
+		Resources:                   c.Resources,
+		CustomClaims:                c.CustomClaims,
+		RefreshTokenReuse:           c.RefreshTokenReuse,
+		DeviceFlow:                  c.DeviceFlow,
+		AllowedOrigins:              c.AllowedOrigins,
+		ReleasedConnectorClaims:     c.ReleasedConnectorClaims,
+		GroupsClaim:                 c.GroupsClaim,
+		Terms:                       c.Terms,
+		Impersonation:               c.Impersonation,
 	}
 }
 
@@ -149,7 +169,12 @@ func toStorageRefreshToken(r *db.RefreshToken) storage.RefreshToken {
 			Email:             r.ClaimsEmail,
 			EmailVerified:     r.ClaimsEmailVerified,
 			Groups:            r.ClaimsGroups,
+			CustomClaims:      r.ClaimsCustom,
 		},
+		Resources:   r.Resources,
+		ParentID:    r.ParentID,
+		Audience:    r.Audience,
+		RotationKey: r.RotationKey,
 	}
 }
 
@@ -188,10 +213,13 @@ func toStorageUserIdentity(u *db.UserIdentity) storage.UserIdentity {
 			Email:             u.ClaimsEmail,
 			EmailVerified:     u.ClaimsEmailVerified,
 			Groups:            u.ClaimsGroups,
+			CustomClaims:      u.ClaimsCustom,
 		},
-		CreatedAt:    u.CreatedAt,
-		LastLogin:    u.LastLogin,
-		BlockedUntil: u.BlockedUntil,
+		ConsentedClaims: u.ConsentedClaims,
+		AcceptedTerms:   u.AcceptedTerms,
+		CreatedAt:       u.CreatedAt,
+		LastLogin:       u.LastLogin,
+		BlockedUntil:    u.BlockedUntil,
 	}
 
 	if u.Consents != nil {
@@ -42,7 +42,10 @@ func (d *Database) CreateUserIdentity(ctx context.Context, identity storage.User
 		SetClaimsEmail(identity.Claims.Email).
 		SetClaimsEmailVerified(identity.Claims.EmailVerified).
 		SetClaimsGroups(identity.Claims.Groups).
+		SetClaimsCustom(identity.Claims.CustomClaims).
 		SetConsents(encodedConsents).
+		SetConsentedClaims(identity.ConsentedClaims).
+		SetAcceptedTerms(identity.AcceptedTerms).
 		SetMfaSecrets(encodedMFASecrets).
 		SetWebauthnCredentials(encodedWebAuthnCreds).
 		SetCreatedAt(identity.CreatedAt).
@@ -128,7 +131,10 @@ func (d *Database) UpdateUserIdentity(ctx context.Context, userID string, connec
 		SetClaimsEmail(newUserIdentity.Claims.Email).
 		SetClaimsEmailVerified(newUserIdentity.Claims.EmailVerified).
 		SetClaimsGroups(newUserIdentity.Claims.Groups).
+		SetClaimsCustom(newUserIdentity.Claims.CustomClaims).
 		SetConsents(encodedConsents).
+		SetConsentedClaims(newUserIdentity.ConsentedClaims).
+		SetAcceptedTerms(newUserIdentity.AcceptedTerms).
 		SetMfaSecrets(encodedMFASecrets).
 		SetWebauthnCredentials(encodedWebAuthnCreds).
 		SetCreatedAt(newUserIdentity.CreatedAt).
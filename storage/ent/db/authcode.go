@@ -49,7 +49,11 @@ type AuthCode struct {
 	// CodeChallengeMethod holds the value of the "code_challenge_method" field.
 	CodeChallengeMethod string `json:"code_challenge_method,omitempty"`
 	// AuthTime holds the value of the "auth_time" field.
-	AuthTime     time.Time `json:"auth_time,omitempty"`
+	AuthTime time.Time `json:"auth_time,omitempty"`
+	// Resources holds the value of the "resources" field.
+	Resources []string `json:"resources,omitempty"`
+	// ClaimsCustom holds the value of the "claims_custom" field.
+	ClaimsCustom map[string]interface{} `json:"claims_custom,omitempty"`
 	selectValues sql.SelectValues
 }
 
@@ -58,7 +62,7 @@ func (*AuthCode) scanValues(columns []string) ([]any, error) {
 	values := make([]any, len(columns))
 	for i := range columns {
 		switch columns[i] {
-		case authcode.FieldScopes, authcode.FieldClaimsGroups, authcode.FieldConnectorData:
+		case authcode.FieldScopes, authcode.FieldClaimsGroups, authcode.FieldConnectorData, authcode.FieldResources, authcode.FieldClaimsCustom:
 			values[i] = new([]byte)
 		case authcode.FieldClaimsEmailVerified:
 			values[i] = new(sql.NullBool)
@@ -187,6 +191,22 @@ func (_m *AuthCode) assignValues(columns []string, values []any) error {
 			} else if value.Valid {
 				_m.AuthTime = value.Time
 			}
+		case authcode.FieldResources:
+			if value, ok := values[i].(*[]byte); !ok {
+				return fmt.Errorf("unexpected type %T for field resources", values[i])
+			} else if value != nil && len(*value) > 0 {
+				if err := json.Unmarshal(*value, &_m.Resources); err != nil {
+					return fmt.Errorf("unmarshal field resources: %w", err)
+				}
+			}
+		case authcode.FieldClaimsCustom:
+			if value, ok := values[i].(*[]byte); !ok {
+				return fmt.Errorf("unexpected type %T for field claims_custom", values[i])
+			} else if value != nil && len(*value) > 0 {
+				if err := json.Unmarshal(*value, &_m.ClaimsCustom); err != nil {
+					return fmt.Errorf("unmarshal field claims_custom: %w", err)
+				}
+			}
 		default:
 			_m.selectValues.Set(columns[i], values[i])
 		}
@@ -272,6 +292,12 @@ func (_m *AuthCode) String() string {
 	builder.WriteString(", ")
 	builder.WriteString("auth_time=")
 	builder.WriteString(_m.AuthTime.Format(time.ANSIC))
+	builder.WriteString(", ")
+	builder.WriteString("resources=")
+	builder.WriteString(fmt.Sprintf("%v", _m.Resources))
+	builder.WriteString(", ")
+	builder.WriteString("claims_custom=")
+	builder.WriteString(fmt.Sprintf("%v", _m.ClaimsCustom))
 	builder.WriteByte(')')
 	return builder.String()
 }
@@ -43,6 +43,10 @@ const (
 	FieldCodeChallengeMethod = "code_challenge_method"
 	// FieldAuthTime holds the string denoting the auth_time field in the database.
 	FieldAuthTime = "auth_time"
+	// FieldResources holds the string denoting the resources field in the database.
+	FieldResources = "resources"
+	// FieldClaimsCustom holds the string denoting the claims_custom field in the database.
+	FieldClaimsCustom = "claims_custom"
 	// Table holds the table name of the authcode in the database.
 	Table = "auth_codes"
 )
@@ -66,6 +70,8 @@ var Columns = []string{
 	FieldCodeChallenge,
 	FieldCodeChallengeMethod,
 	FieldAuthTime,
+	FieldResources,
+	FieldClaimsCustom,
 }
 
 // ValidColumn reports if the column name is valid (part of the table columns).
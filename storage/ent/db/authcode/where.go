@@ -954,6 +954,26 @@ func AuthTimeNotNil() predicate.AuthCode {
 	return predicate.AuthCode(sql.FieldNotNull(FieldAuthTime))
 }
 
+// ResourcesIsNil applies the IsNil predicate on the "resources" field.
+func ResourcesIsNil() predicate.AuthCode {
+	return predicate.AuthCode(sql.FieldIsNull(FieldResources))
+}
+
+// ResourcesNotNil applies the NotNil predicate on the "resources" field.
+func ResourcesNotNil() predicate.AuthCode {
+	return predicate.AuthCode(sql.FieldNotNull(FieldResources))
+}
+
+// ClaimsCustomIsNil applies the IsNil predicate on the "claims_custom" field.
+func ClaimsCustomIsNil() predicate.AuthCode {
+	return predicate.AuthCode(sql.FieldIsNull(FieldClaimsCustom))
+}
+
+// ClaimsCustomNotNil applies the NotNil predicate on the "claims_custom" field.
+func ClaimsCustomNotNil() predicate.AuthCode {
+	return predicate.AuthCode(sql.FieldNotNull(FieldClaimsCustom))
+}
+
 // And groups predicates with the AND operator between them.
 func And(predicates ...predicate.AuthCode) predicate.AuthCode {
 	return predicate.AuthCode(sql.AndPredicates(predicates...))
@@ -148,6 +148,18 @@ func (_c *AuthCodeCreate) SetNillableAuthTime(v *time.Time) *AuthCodeCreate {
 	return _c
 }
 
+// SetResources sets the "resources" field.
+func (_c *AuthCodeCreate) SetResources(v []string) *AuthCodeCreate {
+	_c.mutation.SetResources(v)
+	return _c
+}
+
+// SetClaimsCustom sets the "claims_custom" field.
+func (_c *AuthCodeCreate) SetClaimsCustom(v map[string]interface{}) *AuthCodeCreate {
+	_c.mutation.SetClaimsCustom(v)
+	return _c
+}
+
 // SetID sets the "id" field.
 func (_c *AuthCodeCreate) SetID(v string) *AuthCodeCreate {
 	_c.mutation.SetID(v)
@@ -380,6 +392,14 @@ func (_c *AuthCodeCreate) createSpec() (*AuthCode, *sqlgraph.CreateSpec) {
 		_spec.SetField(authcode.FieldAuthTime, field.TypeTime, value)
 		_node.AuthTime = value
 	}
+	if value, ok := _c.mutation.Resources(); ok {
+		_spec.SetField(authcode.FieldResources, field.TypeJSON, value)
+		_node.Resources = value
+	}
+	if value, ok := _c.mutation.ClaimsCustom(); ok {
+		_spec.SetField(authcode.FieldClaimsCustom, field.TypeJSON, value)
+		_node.ClaimsCustom = value
+	}
 	return _node, _spec
 }
 
@@ -265,6 +265,30 @@ func (_u *AuthCodeUpdate) ClearAuthTime() *AuthCodeUpdate {
 	return _u
 }
 
+// SetResources sets the "resources" field.
+func (_u *AuthCodeUpdate) SetResources(v []string) *AuthCodeUpdate {
+	_u.mutation.SetResources(v)
+	return _u
+}
+
+// ClearResources clears the value of the "resources" field.
+func (_u *AuthCodeUpdate) ClearResources() *AuthCodeUpdate {
+	_u.mutation.ClearResources()
+	return _u
+}
+
+// SetClaimsCustom sets the "claims_custom" field.
+func (_u *AuthCodeUpdate) SetClaimsCustom(v map[string]interface{}) *AuthCodeUpdate {
+	_u.mutation.SetClaimsCustom(v)
+	return _u
+}
+
+// ClearClaimsCustom clears the value of the "claims_custom" field.
+func (_u *AuthCodeUpdate) ClearClaimsCustom() *AuthCodeUpdate {
+	_u.mutation.ClearClaimsCustom()
+	return _u
+}
+
 // Mutation returns the AuthCodeMutation object of the builder.
 func (_u *AuthCodeUpdate) Mutation() *AuthCodeMutation {
 	return _u.mutation
@@ -419,6 +443,18 @@ func (_u *AuthCodeUpdate) sqlSave(ctx context.Context) (_node int, err error) {
 	if _u.mutation.AuthTimeCleared() {
 		_spec.ClearField(authcode.FieldAuthTime, field.TypeTime)
 	}
+	if value, ok := _u.mutation.Resources(); ok {
+		_spec.SetField(authcode.FieldResources, field.TypeJSON, value)
+	}
+	if _u.mutation.ResourcesCleared() {
+		_spec.ClearField(authcode.FieldResources, field.TypeJSON)
+	}
+	if value, ok := _u.mutation.ClaimsCustom(); ok {
+		_spec.SetField(authcode.FieldClaimsCustom, field.TypeJSON, value)
+	}
+	if _u.mutation.ClaimsCustomCleared() {
+		_spec.ClearField(authcode.FieldClaimsCustom, field.TypeJSON)
+	}
 	if _node, err = sqlgraph.UpdateNodes(ctx, _u.driver, _spec); err != nil {
 		if _, ok := err.(*sqlgraph.NotFoundError); ok {
 			err = &NotFoundError{authcode.Label}
@@ -675,6 +711,30 @@ func (_u *AuthCodeUpdateOne) ClearAuthTime() *AuthCodeUpdateOne {
 	return _u
 }
 
+// SetResources sets the "resources" field.
+func (_u *AuthCodeUpdateOne) SetResources(v []string) *AuthCodeUpdateOne {
+	_u.mutation.SetResources(v)
+	return _u
+}
+
+// ClearResources clears the value of the "resources" field.
+func (_u *AuthCodeUpdateOne) ClearResources() *AuthCodeUpdateOne {
+	_u.mutation.ClearResources()
+	return _u
+}
+
+// SetClaimsCustom sets the "claims_custom" field.
+func (_u *AuthCodeUpdateOne) SetClaimsCustom(v map[string]interface{}) *AuthCodeUpdateOne {
+	_u.mutation.SetClaimsCustom(v)
+	return _u
+}
+
+// ClearClaimsCustom clears the value of the "claims_custom" field.
+func (_u *AuthCodeUpdateOne) ClearClaimsCustom() *AuthCodeUpdateOne {
+	_u.mutation.ClearClaimsCustom()
+	return _u
+}
+
 // Mutation returns the AuthCodeMutation object of the builder.
 func (_u *AuthCodeUpdateOne) Mutation() *AuthCodeMutation {
 	return _u.mutation
@@ -859,6 +919,18 @@ func (_u *AuthCodeUpdateOne) sqlSave(ctx context.Context) (_node *AuthCode, err
 	if _u.mutation.AuthTimeCleared() {
 		_spec.ClearField(authcode.FieldAuthTime, field.TypeTime)
 	}
+	if value, ok := _u.mutation.Resources(); ok {
+		_spec.SetField(authcode.FieldResources, field.TypeJSON, value)
+	}
+	if _u.mutation.ResourcesCleared() {
+		_spec.ClearField(authcode.FieldResources, field.TypeJSON)
+	}
+	if value, ok := _u.mutation.ClaimsCustom(); ok {
+		_spec.SetField(authcode.FieldClaimsCustom, field.TypeJSON, value)
+	}
+	if _u.mutation.ClaimsCustomCleared() {
+		_spec.ClearField(authcode.FieldClaimsCustom, field.TypeJSON)
+	}
 	_node = &AuthCode{config: _u.config}
 	_spec.Assign = _node.assignValues
 	_spec.ScanValues = _node.scanValues
@@ -67,7 +67,13 @@ type AuthRequest struct {
 	// MaxAge holds the value of the "max_age" field.
 	MaxAge int `json:"max_age,omitempty"`
 	// AuthTime holds the value of the "auth_time" field.
-	AuthTime     time.Time `json:"auth_time,omitempty"`
+	AuthTime time.Time `json:"auth_time,omitempty"`
+	// Resources holds the value of the "resources" field.
+	Resources []string `json:"resources,omitempty"`
+	// LoginHint holds the value of the "login_hint" field.
+	LoginHint string `json:"login_hint,omitempty"`
+	// ClaimsCustom holds the value of the "claims_custom" field.
+	ClaimsCustom map[string]interface{} `json:"claims_custom,omitempty"`
 	selectValues sql.SelectValues
 }
 
@@ -76,13 +82,13 @@ func (*AuthRequest) scanValues(columns []string) ([]any, error) {
 	values := make([]any, len(columns))
 	for i := range columns {
 		switch columns[i] {
-		case authrequest.FieldScopes, authrequest.FieldResponseTypes, authrequest.FieldClaimsGroups, authrequest.FieldConnectorData, authrequest.FieldHmacKey, authrequest.FieldWebauthnSessionData:
+		case authrequest.FieldScopes, authrequest.FieldResponseTypes, authrequest.FieldClaimsGroups, authrequest.FieldConnectorData, authrequest.FieldHmacKey, authrequest.FieldWebauthnSessionData, authrequest.FieldResources, authrequest.FieldClaimsCustom:
 			values[i] = new([]byte)
 		case authrequest.FieldForceApprovalPrompt, authrequest.FieldLoggedIn, authrequest.FieldClaimsEmailVerified, authrequest.FieldMfaValidated:
 			values[i] = new(sql.NullBool)
 		case authrequest.FieldMaxAge:
 			values[i] = new(sql.NullInt64)
-		case authrequest.FieldID, authrequest.FieldClientID, authrequest.FieldRedirectURI, authrequest.FieldNonce, authrequest.FieldState, authrequest.FieldClaimsUserID, authrequest.FieldClaimsUsername, authrequest.FieldClaimsEmail, authrequest.FieldClaimsPreferredUsername, authrequest.FieldConnectorID, authrequest.FieldCodeChallenge, authrequest.FieldCodeChallengeMethod, authrequest.FieldPrompt:
+		case authrequest.FieldID, authrequest.FieldClientID, authrequest.FieldRedirectURI, authrequest.FieldNonce, authrequest.FieldState, authrequest.FieldClaimsUserID, authrequest.FieldClaimsUsername, authrequest.FieldClaimsEmail, authrequest.FieldClaimsPreferredUsername, authrequest.FieldConnectorID, authrequest.FieldCodeChallenge, authrequest.FieldCodeChallengeMethod, authrequest.FieldPrompt, authrequest.FieldLoginHint:
 			values[i] = new(sql.NullString)
 		case authrequest.FieldExpiry, authrequest.FieldAuthTime:
 			values[i] = new(sql.NullTime)
@@ -263,6 +269,28 @@ func (_m *AuthRequest) assignValues(columns []string, values []any) error {
 			} else if value.Valid {
 				_m.AuthTime = value.Time
 			}
+		case authrequest.FieldResources:
+			if value, ok := values[i].(*[]byte); !ok {
+				return fmt.Errorf("unexpected type %T for field resources", values[i])
+			} else if value != nil && len(*value) > 0 {
+				if err := json.Unmarshal(*value, &_m.Resources); err != nil {
+					return fmt.Errorf("unmarshal field resources: %w", err)
+				}
+			}
+		case authrequest.FieldLoginHint:
+			if value, ok := values[i].(*sql.NullString); !ok {
+				return fmt.Errorf("unexpected type %T for field login_hint", values[i])
+			} else if value.Valid {
+				_m.LoginHint = value.String
+			}
+		case authrequest.FieldClaimsCustom:
+			if value, ok := values[i].(*[]byte); !ok {
+				return fmt.Errorf("unexpected type %T for field claims_custom", values[i])
+			} else if value != nil && len(*value) > 0 {
+				if err := json.Unmarshal(*value, &_m.ClaimsCustom); err != nil {
+					return fmt.Errorf("unmarshal field claims_custom: %w", err)
+				}
+			}
 		default:
 			_m.selectValues.Set(columns[i], values[i])
 		}
@@ -377,6 +405,15 @@ func (_m *AuthRequest) String() string {
 	builder.WriteString(", ")
 	builder.WriteString("auth_time=")
 	builder.WriteString(_m.AuthTime.Format(time.ANSIC))
+	builder.WriteString(", ")
+	builder.WriteString("resources=")
+	builder.WriteString(fmt.Sprintf("%v", _m.Resources))
+	builder.WriteString(", ")
+	builder.WriteString("login_hint=")
+	builder.WriteString(_m.LoginHint)
+	builder.WriteString(", ")
+	builder.WriteString("claims_custom=")
+	builder.WriteString(fmt.Sprintf("%v", _m.ClaimsCustom))
 	builder.WriteByte(')')
 	return builder.String()
 }
@@ -61,6 +61,12 @@ const (
 	FieldMaxAge = "max_age"
 	// FieldAuthTime holds the string denoting the auth_time field in the database.
 	FieldAuthTime = "auth_time"
+	// FieldResources holds the string denoting the resources field in the database.
+	FieldResources = "resources"
+	// FieldLoginHint holds the string denoting the login_hint field in the database.
+	FieldLoginHint = "login_hint"
+	// FieldClaimsCustom holds the string denoting the claims_custom field in the database.
+	FieldClaimsCustom = "claims_custom"
 	// Table holds the table name of the authrequest in the database.
 	Table = "auth_requests"
 )
@@ -93,6 +99,9 @@ var Columns = []string{
 	FieldPrompt,
 	FieldMaxAge,
 	FieldAuthTime,
+	FieldResources,
+	FieldLoginHint,
+	FieldClaimsCustom,
 }
 
 // ValidColumn reports if the column name is valid (part of the table columns).
@@ -224,3 +233,8 @@ func ByMaxAge(opts ...sql.OrderTermOption) OrderOption {
 func ByAuthTime(opts ...sql.OrderTermOption) OrderOption {
 	return sql.OrderByField(FieldAuthTime, opts...).ToFunc()
 }
+
+// ByLoginHint orders the results by the login_hint field.
+func ByLoginHint(opts ...sql.OrderTermOption) OrderOption {
+	return sql.OrderByField(FieldLoginHint, opts...).ToFunc()
+}
@@ -1294,6 +1294,51 @@ func AuthTimeNotNil() predicate.AuthRequest {
 	return predicate.AuthRequest(sql.FieldNotNull(FieldAuthTime))
 }
 
+// ResourcesIsNil applies the IsNil predicate on the "resources" field.
+func ResourcesIsNil() predicate.AuthRequest {
+	return predicate.AuthRequest(sql.FieldIsNull(FieldResources))
+}
+
+// ResourcesNotNil applies the NotNil predicate on the "resources" field.
+func ResourcesNotNil() predicate.AuthRequest {
+	return predicate.AuthRequest(sql.FieldNotNull(FieldResources))
+}
+
+// LoginHint applies equality check predicate on the "login_hint" field. It's identical to LoginHintEQ.
+func LoginHint(v string) predicate.AuthRequest {
+	return predicate.AuthRequest(sql.FieldEQ(FieldLoginHint, v))
+}
+
+// LoginHintEQ applies the EQ predicate on the "login_hint" field.
+func LoginHintEQ(v string) predicate.AuthRequest {
+	return predicate.AuthRequest(sql.FieldEQ(FieldLoginHint, v))
+}
+
+// LoginHintNEQ applies the NEQ predicate on the "login_hint" field.
+func LoginHintNEQ(v string) predicate.AuthRequest {
+	return predicate.AuthRequest(sql.FieldNEQ(FieldLoginHint, v))
+}
+
+// LoginHintIsNil applies the IsNil predicate on the "login_hint" field.
+func LoginHintIsNil() predicate.AuthRequest {
+	return predicate.AuthRequest(sql.FieldIsNull(FieldLoginHint))
+}
+
+// LoginHintNotNil applies the NotNil predicate on the "login_hint" field.
+func LoginHintNotNil() predicate.AuthRequest {
+	return predicate.AuthRequest(sql.FieldNotNull(FieldLoginHint))
+}
+
+// ClaimsCustomIsNil applies the IsNil predicate on the "claims_custom" field.
+func ClaimsCustomIsNil() predicate.AuthRequest {
+	return predicate.AuthRequest(sql.FieldIsNull(FieldClaimsCustom))
+}
+
+// ClaimsCustomNotNil applies the NotNil predicate on the "claims_custom" field.
+func ClaimsCustomNotNil() predicate.AuthRequest {
+	return predicate.AuthRequest(sql.FieldNotNull(FieldClaimsCustom))
+}
+
 // And groups predicates with the AND operator between them.
 func And(predicates ...predicate.AuthRequest) predicate.AuthRequest {
 	return predicate.AuthRequest(sql.AndPredicates(predicates...))
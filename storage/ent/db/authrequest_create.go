@@ -226,6 +226,32 @@ func (_c *AuthRequestCreate) SetNillableAuthTime(v *time.Time) *AuthRequestCreat
 	return _c
 }
 
+// SetResources sets the "resources" field.
+func (_c *AuthRequestCreate) SetResources(v []string) *AuthRequestCreate {
+	_c.mutation.SetResources(v)
+	return _c
+}
+
+// SetLoginHint sets the "login_hint" field.
+func (_c *AuthRequestCreate) SetLoginHint(v string) *AuthRequestCreate {
+	_c.mutation.SetLoginHint(v)
+	return _c
+}
+
+// SetNillableLoginHint sets the "login_hint" field if the given value is not nil.
+func (_c *AuthRequestCreate) SetNillableLoginHint(v *string) *AuthRequestCreate {
+	if v != nil {
+		_c.SetLoginHint(*v)
+	}
+	return _c
+}
+
+// SetClaimsCustom sets the "claims_custom" field.
+func (_c *AuthRequestCreate) SetClaimsCustom(v map[string]interface{}) *AuthRequestCreate {
+	_c.mutation.SetClaimsCustom(v)
+	return _c
+}
+
 // SetID sets the "id" field.
 func (_c *AuthRequestCreate) SetID(v string) *AuthRequestCreate {
 	_c.mutation.SetID(v)
@@ -492,6 +518,18 @@ func (_c *AuthRequestCreate) createSpec() (*AuthRequest, *sqlgraph.CreateSpec) {
 		_spec.SetField(authrequest.FieldAuthTime, field.TypeTime, value)
 		_node.AuthTime = value
 	}
+	if value, ok := _c.mutation.Resources(); ok {
+		_spec.SetField(authrequest.FieldResources, field.TypeJSON, value)
+		_node.Resources = value
+	}
+	if value, ok := _c.mutation.LoginHint(); ok {
+		_spec.SetField(authrequest.FieldLoginHint, field.TypeString, value)
+		_node.LoginHint = value
+	}
+	if value, ok := _c.mutation.ClaimsCustom(); ok {
+		_spec.SetField(authrequest.FieldClaimsCustom, field.TypeJSON, value)
+		_node.ClaimsCustom = value
+	}
 	return _node, _spec
 }
 
@@ -392,6 +392,50 @@ func (_u *AuthRequestUpdate) ClearAuthTime() *AuthRequestUpdate {
 	return _u
 }
 
+// SetResources sets the "resources" field.
+func (_u *AuthRequestUpdate) SetResources(v []string) *AuthRequestUpdate {
+	_u.mutation.SetResources(v)
+	return _u
+}
+
+// ClearResources clears the value of the "resources" field.
+func (_u *AuthRequestUpdate) ClearResources() *AuthRequestUpdate {
+	_u.mutation.ClearResources()
+	return _u
+}
+
+// SetLoginHint sets the "login_hint" field.
+func (_u *AuthRequestUpdate) SetLoginHint(v string) *AuthRequestUpdate {
+	_u.mutation.SetLoginHint(v)
+	return _u
+}
+
+// SetNillableLoginHint sets the "login_hint" field if the given value is not nil.
+func (_u *AuthRequestUpdate) SetNillableLoginHint(v *string) *AuthRequestUpdate {
+	if v != nil {
+		_u.SetLoginHint(*v)
+	}
+	return _u
+}
+
+// ClearLoginHint clears the value of the "login_hint" field.
+func (_u *AuthRequestUpdate) ClearLoginHint() *AuthRequestUpdate {
+	_u.mutation.ClearLoginHint()
+	return _u
+}
+
+// SetClaimsCustom sets the "claims_custom" field.
+func (_u *AuthRequestUpdate) SetClaimsCustom(v map[string]interface{}) *AuthRequestUpdate {
+	_u.mutation.SetClaimsCustom(v)
+	return _u
+}
+
+// ClearClaimsCustom clears the value of the "claims_custom" field.
+func (_u *AuthRequestUpdate) ClearClaimsCustom() *AuthRequestUpdate {
+	_u.mutation.ClearClaimsCustom()
+	return _u
+}
+
 // Mutation returns the AuthRequestMutation object of the builder.
 func (_u *AuthRequestUpdate) Mutation() *AuthRequestMutation {
 	return _u.mutation
@@ -544,6 +588,24 @@ func (_u *AuthRequestUpdate) sqlSave(ctx context.Context) (_node int, err error)
 	if _u.mutation.AuthTimeCleared() {
 		_spec.ClearField(authrequest.FieldAuthTime, field.TypeTime)
 	}
+	if value, ok := _u.mutation.Resources(); ok {
+		_spec.SetField(authrequest.FieldResources, field.TypeJSON, value)
+	}
+	if _u.mutation.ResourcesCleared() {
+		_spec.ClearField(authrequest.FieldResources, field.TypeJSON)
+	}
+	if value, ok := _u.mutation.LoginHint(); ok {
+		_spec.SetField(authrequest.FieldLoginHint, field.TypeString, value)
+	}
+	if _u.mutation.LoginHintCleared() {
+		_spec.ClearField(authrequest.FieldLoginHint, field.TypeString)
+	}
+	if value, ok := _u.mutation.ClaimsCustom(); ok {
+		_spec.SetField(authrequest.FieldClaimsCustom, field.TypeJSON, value)
+	}
+	if _u.mutation.ClaimsCustomCleared() {
+		_spec.ClearField(authrequest.FieldClaimsCustom, field.TypeJSON)
+	}
 	if _node, err = sqlgraph.UpdateNodes(ctx, _u.driver, _spec); err != nil {
 		if _, ok := err.(*sqlgraph.NotFoundError); ok {
 			err = &NotFoundError{authrequest.Label}
@@ -927,6 +989,50 @@ func (_u *AuthRequestUpdateOne) ClearAuthTime() *AuthRequestUpdateOne {
 	return _u
 }
 
+// SetResources sets the "resources" field.
+func (_u *AuthRequestUpdateOne) SetResources(v []string) *AuthRequestUpdateOne {
+	_u.mutation.SetResources(v)
+	return _u
+}
+
+// ClearResources clears the value of the "resources" field.
+func (_u *AuthRequestUpdateOne) ClearResources() *AuthRequestUpdateOne {
+	_u.mutation.ClearResources()
+	return _u
+}
+
+// SetLoginHint sets the "login_hint" field.
+func (_u *AuthRequestUpdateOne) SetLoginHint(v string) *AuthRequestUpdateOne {
+	_u.mutation.SetLoginHint(v)
+	return _u
+}
+
+// SetNillableLoginHint sets the "login_hint" field if the given value is not nil.
+func (_u *AuthRequestUpdateOne) SetNillableLoginHint(v *string) *AuthRequestUpdateOne {
+	if v != nil {
+		_u.SetLoginHint(*v)
+	}
+	return _u
+}
+
+// ClearLoginHint clears the value of the "login_hint" field.
+func (_u *AuthRequestUpdateOne) ClearLoginHint() *AuthRequestUpdateOne {
+	_u.mutation.ClearLoginHint()
+	return _u
+}
+
+// SetClaimsCustom sets the "claims_custom" field.
+func (_u *AuthRequestUpdateOne) SetClaimsCustom(v map[string]interface{}) *AuthRequestUpdateOne {
+	_u.mutation.SetClaimsCustom(v)
+	return _u
+}
+
+// ClearClaimsCustom clears the value of the "claims_custom" field.
+func (_u *AuthRequestUpdateOne) ClearClaimsCustom() *AuthRequestUpdateOne {
+	_u.mutation.ClearClaimsCustom()
+	return _u
+}
+
 // Mutation returns the AuthRequestMutation object of the builder.
 func (_u *AuthRequestUpdateOne) Mutation() *AuthRequestMutation {
 	return _u.mutation
@@ -1109,6 +1215,24 @@ func (_u *AuthRequestUpdateOne) sqlSave(ctx context.Context) (_node *AuthRequest
 	if _u.mutation.AuthTimeCleared() {
 		_spec.ClearField(authrequest.FieldAuthTime, field.TypeTime)
 	}
+	if value, ok := _u.mutation.Resources(); ok {
+		_spec.SetField(authrequest.FieldResources, field.TypeJSON, value)
+	}
+	if _u.mutation.ResourcesCleared() {
+		_spec.ClearField(authrequest.FieldResources, field.TypeJSON)
+	}
+	if value, ok := _u.mutation.LoginHint(); ok {
+		_spec.SetField(authrequest.FieldLoginHint, field.TypeString, value)
+	}
+	if _u.mutation.LoginHintCleared() {
+		_spec.ClearField(authrequest.FieldLoginHint, field.TypeString)
+	}
+	if value, ok := _u.mutation.ClaimsCustom(); ok {
+		_spec.SetField(authrequest.FieldClaimsCustom, field.TypeJSON, value)
+	}
+	if _u.mutation.ClaimsCustomCleared() {
+		_spec.ClearField(authrequest.FieldClaimsCustom, field.TypeJSON)
+	}
 	_node = &AuthRequest{config: _u.config}
 	_spec.Assign = _node.assignValues
 	_spec.ScanValues = _node.scanValues
@@ -27,6 +27,8 @@ var (
 		{Name: "code_challenge", Type: field.TypeString, Size: 2147483647, Default: "", SchemaType: map[string]string{"mysql": "varchar(384)", "postgres": "text", "sqlite3": "text"}},
 		{Name: "code_challenge_method", Type: field.TypeString, Size: 2147483647, Default: "", SchemaType: map[string]string{"mysql": "varchar(384)", "postgres": "text", "sqlite3": "text"}},
 		{Name: "auth_time", Type: field.TypeTime, Nullable: true, SchemaType: map[string]string{"mysql": "datetime(3)", "postgres": "timestamptz", "sqlite3": "timestamp"}},
+		{Name: "resources", Type: field.TypeJSON, Nullable: true},
+		{Name: "claims_custom", Type: field.TypeJSON, Nullable: true},
 	}
 	// AuthCodesTable holds the schema information for the "auth_codes" table.
 	AuthCodesTable = &schema.Table{
@@ -62,6 +64,9 @@ var (
 		{Name: "prompt", Type: field.TypeString, Size: 2147483647, Default: "", SchemaType: map[string]string{"mysql": "varchar(384)", "postgres": "text", "sqlite3": "text"}},
 		{Name: "max_age", Type: field.TypeInt, Default: -1},
 		{Name: "auth_time", Type: field.TypeTime, Nullable: true, SchemaType: map[string]string{"mysql": "datetime(3)", "postgres": "timestamptz", "sqlite3": "timestamp"}},
+		{Name: "resources", Type: field.TypeJSON, Nullable: true},
+		{Name: "login_hint", Type: field.TypeString, Nullable: true, Size: 2147483647, SchemaType: map[string]string{"mysql": "varchar(384)", "postgres": "text", "sqlite3": "text"}},
+		{Name: "claims_custom", Type: field.TypeJSON, Nullable: true},
 	}
 	// AuthRequestsTable holds the schema information for the "auth_requests" table.
 	AuthRequestsTable = &schema.Table{
@@ -165,6 +170,20 @@ var (
 		{Name: "mfa_chain", Type: field.TypeJSON, Nullable: true},
 		{Name: "post_logout_redirect_uris", Type: field.TypeJSON, Nullable: true},
 		{Name: "sso_shared_with", Type: field.TypeJSON, Nullable: true},
+		{Name: "theme", Type: field.TypeJSON, Nullable: true},
+		{Name: "require_consent_on_claim_change", Type: field.TypeBool, Nullable: true},
+		{Name: "client_credentials", Type: field.TypeJSON, Nullable: true},
+		{Name: "jwt_bearer_issuers", Type: field.TypeJSON, Nullable: true},
+		{Name: "token_exchange", Type: field.TypeJSON, Nullable: true},
+		{Name: "resources", Type: field.TypeJSON, Nullable: true},
+		{Name: "custom_claims", Type: field.TypeJSON, Nullable: true},
+		{Name: "refresh_token_reuse", Type: field.TypeJSON, Nullable: true},
+		{Name: "device_flow", Type: field.TypeJSON, Nullable: true},
+		{Name: "allowed_origins", Type: field.TypeJSON, Nullable: true},
+		{Name: "released_connector_claims", Type: field.TypeJSON, Nullable: true},
+		{Name: "groups_claim", Type: field.TypeJSON, Nullable: true},
+		{Name: "terms", Type: field.TypeJSON, Nullable: true},
+		{Name: "impersonation", Type: field.TypeJSON, Nullable: true},
 	}
 	// Oauth2clientsTable holds the schema information for the "oauth2clients" table.
 	Oauth2clientsTable = &schema.Table{
@@ -222,6 +241,11 @@ var (
 		{Name: "obsolete_token", Type: field.TypeString, Size: 2147483647, Default: "", SchemaType: map[string]string{"mysql": "varchar(384)", "postgres": "text", "sqlite3": "text"}},
 		{Name: "created_at", Type: field.TypeTime, SchemaType: map[string]string{"mysql": "datetime(3)", "postgres": "timestamptz", "sqlite3": "timestamp"}},
 		{Name: "last_used", Type: field.TypeTime, SchemaType: map[string]string{"mysql": "datetime(3)", "postgres": "timestamptz", "sqlite3": "timestamp"}},
+		{Name: "resources", Type: field.TypeJSON, Nullable: true},
+		{Name: "claims_custom", Type: field.TypeJSON, Nullable: true},
+		{Name: "parent_id", Type: field.TypeString, Nullable: true, Size: 2147483647, SchemaType: map[string]string{"mysql": "varchar(384)", "postgres": "text", "sqlite3": "text"}},
+		{Name: "audience", Type: field.TypeJSON, Nullable: true},
+		{Name: "rotation_key", Type: field.TypeString, Nullable: true},
 	}
 	// RefreshTokensTable holds the schema information for the "refresh_tokens" table.
 	RefreshTokensTable = &schema.Table{
@@ -246,6 +270,9 @@ var (
 		{Name: "created_at", Type: field.TypeTime, SchemaType: map[string]string{"mysql": "datetime(3)", "postgres": "timestamptz", "sqlite3": "timestamp"}},
 		{Name: "last_login", Type: field.TypeTime, SchemaType: map[string]string{"mysql": "datetime(3)", "postgres": "timestamptz", "sqlite3": "timestamp"}},
 		{Name: "blocked_until", Type: field.TypeTime, SchemaType: map[string]string{"mysql": "datetime(3)", "postgres": "timestamptz", "sqlite3": "timestamp"}},
+		{Name: "consented_claims", Type: field.TypeJSON, Nullable: true},
+		{Name: "claims_custom", Type: field.TypeJSON, Nullable: true},
+		{Name: "accepted_terms", Type: field.TypeJSON, Nullable: true},
 	}
 	// UserIdentitiesTable holds the schema information for the "user_identities" table.
 	UserIdentitiesTable = &schema.Table{
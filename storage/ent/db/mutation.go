@@ -75,6 +75,8 @@ type AuthCodeMutation struct {
 	code_challenge            *string
 	code_challenge_method     *string
 	auth_time                 *time.Time
+	resources                 *[]string
+	claims_custom             *map[string]interface{}
 	clearedFields             map[string]struct{}
 	done                      bool
 	oldValue                  func(context.Context) (*AuthCode, error)
@@ -845,6 +847,104 @@ func (m *AuthCodeMutation) ResetAuthTime() {
 	delete(m.clearedFields, authcode.FieldAuthTime)
 }
 
+// SetResources sets the "resources" field.
+func (m *AuthCodeMutation) SetResources(v []string) {
+	m.resources = &v
+}
+
+// Resources returns the value of the "resources" field in the mutation.
+func (m *AuthCodeMutation) Resources() (r []string, exists bool) {
+	v := m.resources
+	if v == nil {
+		return
+	}
+	return *v, true
+}
+
+// OldResources returns the old "resources" field's value of the AuthCode entity.
+// If the AuthCode object wasn't provided to the builder, the object is fetched from the database.
+// An error is returned if the mutation operation is not UpdateOne, or the database query fails.
+func (m *AuthCodeMutation) OldResources(ctx context.Context) (v []string, err error) {
+	if !m.op.Is(OpUpdateOne) {
+		return v, errors.New("OldResources is only allowed on UpdateOne operations")
+	}
+	if m.id == nil || m.oldValue == nil {
+		return v, errors.New("OldResources requires an ID field in the mutation")
+	}
+	oldValue, err := m.oldValue(ctx)
+	if err != nil {
+		return v, fmt.Errorf("querying old value for OldResources: %w", err)
+	}
+	return oldValue.Resources, nil
+}
+
+// ClearResources clears the value of the "resources" field.
+func (m *AuthCodeMutation) ClearResources() {
+	m.resources = nil
+	m.clearedFields[authcode.FieldResources] = struct{}{}
+}
+
+// ResourcesCleared returns if the "resources" field was cleared in this mutation.
+func (m *AuthCodeMutation) ResourcesCleared() bool {
+	_, ok := m.clearedFields[authcode.FieldResources]
+	return ok
+}
+
+// ResetResources resets all changes to the "resources" field.
+func (m *AuthCodeMutation) ResetResources() {
+	m.resources = nil
+	delete(m.clearedFields, authcode.FieldResources)
+}
+
+// SetClaimsCustom sets the "claims_custom" field.
+func (m *AuthCodeMutation) SetClaimsCustom(v map[string]interface{}) {
+	m.claims_custom = &v
+}
+
+// ClaimsCustom returns the value of the "claims_custom" field in the mutation.
+func (m *AuthCodeMutation) ClaimsCustom() (r map[string]interface{}, exists bool) {
+	v := m.claims_custom
+	if v == nil {
+		return
+	}
+	return *v, true
+}
+
+// OldClaimsCustom returns the old "claims_custom" field's value of the AuthCode entity.
+// If the AuthCode object wasn't provided to the builder, the object is fetched from the database.
+// An error is returned if the mutation operation is not UpdateOne, or the database query fails.
+func (m *AuthCodeMutation) OldClaimsCustom(ctx context.Context) (v map[string]interface{}, err error) {
+	if !m.op.Is(OpUpdateOne) {
+		return v, errors.New("OldClaimsCustom is only allowed on UpdateOne operations")
+	}
+	if m.id == nil || m.oldValue == nil {
+		return v, errors.New("OldClaimsCustom requires an ID field in the mutation")
+	}
+	oldValue, err := m.oldValue(ctx)
+	if err != nil {
+		return v, fmt.Errorf("querying old value for OldClaimsCustom: %w", err)
+	}
+	return oldValue.ClaimsCustom, nil
+}
+
+// ClearClaimsCustom clears the value of the "claims_custom" field.
+func (m *AuthCodeMutation) ClearClaimsCustom() {
+	m.claims_custom = nil
+	m.clearedFields[authcode.FieldClaimsCustom] = struct{}{}
+}
+
+// ClaimsCustomCleared returns if the "claims_custom" field was cleared in this mutation.
+func (m *AuthCodeMutation) ClaimsCustomCleared() bool {
+	_, ok := m.clearedFields[authcode.FieldClaimsCustom]
+	return ok
+}
+
+// ResetClaimsCustom resets all changes to the "claims_custom" field.
+func (m *AuthCodeMutation) ResetClaimsCustom() {
+	m.claims_custom = nil
+	delete(m.clearedFields, authcode.FieldClaimsCustom)
+}
+
 // Where appends a list predicates to the AuthCodeMutation builder.
 func (m *AuthCodeMutation) Where(ps ...predicate.AuthCode) {
 	m.predicates = append(m.predicates, ps...)
@@ -879,7 +979,7 @@ func (m *AuthCodeMutation) Type() string {
 // order to get all numeric fields that were incremented/decremented, call
 // AddedFields().
 func (m *AuthCodeMutation) Fields() []string {
-	fields := make([]string, 0, 16)
+	fields := make([]string, 0, 18)
 	if m.client_id != nil {
 		fields = append(fields, authcode.FieldClientID)
 	}
@@ -928,6 +1028,12 @@ func (m *AuthCodeMutation) Fields() []string {
 	if m.auth_time != nil {
 		fields = append(fields, authcode.FieldAuthTime)
 	}
+	if m.resources != nil {
+		fields = append(fields, authcode.FieldResources)
+	}
+	if m.claims_custom != nil {
+		fields = append(fields, authcode.FieldClaimsCustom)
+	}
 	return fields
 }
 
@@ -968,6 +1074,10 @@ func (m *AuthCodeMutation) Field(name string) (ent.Value, bool) {
 		return m.CodeChallengeMethod()
 	case authcode.FieldAuthTime:
 		return m.AuthTime()
+	case authcode.FieldResources:
+		return m.Resources()
+	case authcode.FieldClaimsCustom:
+		return m.ClaimsCustom()
 	}
 	return nil, false
 }
@@ -1009,6 +1119,10 @@ func (m *AuthCodeMutation) OldField(ctx context.Context, name string) (ent.Value
 		return m.OldCodeChallengeMethod(ctx)
 	case authcode.FieldAuthTime:
 		return m.OldAuthTime(ctx)
+	case authcode.FieldResources:
+		return m.OldResources(ctx)
+	case authcode.FieldClaimsCustom:
+		return m.OldClaimsCustom(ctx)
 	}
 	return nil, fmt.Errorf("unknown AuthCode field %s", name)
 }
@@ -1130,6 +1244,20 @@ func (m *AuthCodeMutation) SetField(name string, value ent.Value) error {
 		}
 		m.SetAuthTime(v)
 		return nil
+	case authcode.FieldResources:
+		v, ok := value.([]string)
+		if !ok {
+			return fmt.Errorf("unexpected type %T for field %s", value, name)
+		}
+		m.SetResources(v)
+		return nil
+	case authcode.FieldClaimsCustom:
+		v, ok := value.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("unexpected type %T for field %s", value, name)
+		}
+		m.SetClaimsCustom(v)
+		return nil
 	}
 	return fmt.Errorf("unknown AuthCode field %s", name)
 }
@@ -1172,6 +1300,12 @@ func (m *AuthCodeMutation) ClearedFields() []string {
 	if m.FieldCleared(authcode.FieldAuthTime) {
 		fields = append(fields, authcode.FieldAuthTime)
 	}
+	if m.FieldCleared(authcode.FieldResources) {
+		fields = append(fields, authcode.FieldResources)
+	}
+	if m.FieldCleared(authcode.FieldClaimsCustom) {
+		fields = append(fields, authcode.FieldClaimsCustom)
+	}
 	return fields
 }
 
@@ -1198,6 +1332,12 @@ func (m *AuthCodeMutation) ClearField(name string) error {
 	case authcode.FieldAuthTime:
 		m.ClearAuthTime()
 		return nil
+	case authcode.FieldResources:
+		m.ClearResources()
+		return nil
+	case authcode.FieldClaimsCustom:
+		m.ClearClaimsCustom()
+		return nil
 	}
 	return fmt.Errorf("unknown AuthCode nullable field %s", name)
 }
@@ -1254,6 +1394,12 @@ func (m *AuthCodeMutation) ResetField(name string) error {
 	case authcode.FieldAuthTime:
 		m.ResetAuthTime()
 		return nil
+	case authcode.FieldResources:
+		m.ResetResources()
+		return nil
+	case authcode.FieldClaimsCustom:
+		m.ResetClaimsCustom()
+		return nil
 	}
 	return fmt.Errorf("unknown AuthCode field %s", name)
 }
@@ -1341,6 +1487,9 @@ type AuthRequestMutation struct {
 	max_age                   *int
 	addmax_age                *int
 	auth_time                 *time.Time
+	resources                 *[]string
+	login_hint                *string
+	claims_custom             *map[string]interface{}
 	clearedFields             map[string]struct{}
 	done                      bool
 	oldValue                  func(context.Context) (*AuthRequest, error)
@@ -2497,6 +2646,153 @@ func (m *AuthRequestMutation) ResetAuthTime() {
 	delete(m.clearedFields, authrequest.FieldAuthTime)
 }
 
+// SetResources sets the "resources" field.
+func (m *AuthRequestMutation) SetResources(v []string) {
+	m.resources = &v
+}
+
+// Resources returns the value of the "resources" field in the mutation.
+func (m *AuthRequestMutation) Resources() (r []string, exists bool) {
+	v := m.resources
+	if v == nil {
+		return
+	}
+	return *v, true
+}
+
+// OldResources returns the old "resources" field's value of the AuthRequest entity.
+// If the AuthRequest object wasn't provided to the builder, the object is fetched from the database.
+// An error is returned if the mutation operation is not UpdateOne, or the database query fails.
+func (m *AuthRequestMutation) OldResources(ctx context.Context) (v []string, err error) {
+	if !m.op.Is(OpUpdateOne) {
+		return v, errors.New("OldResources is only allowed on UpdateOne operations")
+	}
+	if m.id == nil || m.oldValue == nil {
+		return v, errors.New("OldResources requires an ID field in the mutation")
+	}
+	oldValue, err := m.oldValue(ctx)
+	if err != nil {
+		return v, fmt.Errorf("querying old value for OldResources: %w", err)
+	}
+	return oldValue.Resources, nil
+}
+
+// ClearResources clears the value of the "resources" field.
+func (m *AuthRequestMutation) ClearResources() {
+	m.resources = nil
+	m.clearedFields[authrequest.FieldResources] = struct{}{}
+}
+
+// ResourcesCleared returns if the "resources" field was cleared in this mutation.
+func (m *AuthRequestMutation) ResourcesCleared() bool {
+	_, ok := m.clearedFields[authrequest.FieldResources]
+	return ok
+}
+
+// ResetResources resets all changes to the "resources" field.
+func (m *AuthRequestMutation) ResetResources() {
+	m.resources = nil
+	delete(m.clearedFields, authrequest.FieldResources)
+}
+
+// SetLoginHint sets the "login_hint" field.
+func (m *AuthRequestMutation) SetLoginHint(s string) {
+	m.login_hint = &s
+}
+
+// LoginHint returns the value of the "login_hint" field in the mutation.
+func (m *AuthRequestMutation) LoginHint() (r string, exists bool) {
+	v := m.login_hint
+	if v == nil {
+		return
+	}
+	return *v, true
+}
+
+// OldLoginHint returns the old "login_hint" field's value of the AuthRequest entity.
+// If the AuthRequest object wasn't provided to the builder, the object is fetched from the database.
+// An error is returned if the mutation operation is not UpdateOne, or the database query fails.
+func (m *AuthRequestMutation) OldLoginHint(ctx context.Context) (v string, err error) {
+	if !m.op.Is(OpUpdateOne) {
+		return v, errors.New("OldLoginHint is only allowed on UpdateOne operations")
+	}
+	if m.id == nil || m.oldValue == nil {
+		return v, errors.New("OldLoginHint requires an ID field in the mutation")
+	}
+	oldValue, err := m.oldValue(ctx)
+	if err != nil {
+		return v, fmt.Errorf("querying old value for OldLoginHint: %w", err)
+	}
+	return oldValue.LoginHint, nil
+}
+
+// ClearLoginHint clears the value of the "login_hint" field.
+func (m *AuthRequestMutation) ClearLoginHint() {
+	m.login_hint = nil
+	m.clearedFields[authrequest.FieldLoginHint] = struct{}{}
+}
+
+// LoginHintCleared returns if the "login_hint" field was cleared in this mutation.
+func (m *AuthRequestMutation) LoginHintCleared() bool {
+	_, ok := m.clearedFields[authrequest.FieldLoginHint]
+	return ok
+}
+
+// ResetLoginHint resets all changes to the "login_hint" field.
+func (m *AuthRequestMutation) ResetLoginHint() {
+	m.login_hint = nil
+	delete(m.clearedFields, authrequest.FieldLoginHint)
+}
+
+// SetClaimsCustom sets the "claims_custom" field.
+func (m *AuthRequestMutation) SetClaimsCustom(v map[string]interface{}) {
+	m.claims_custom = &v
+}
+
+// ClaimsCustom returns the value of the "claims_custom" field in the mutation.
+func (m *AuthRequestMutation) ClaimsCustom() (r map[string]interface{}, exists bool) {
+	v := m.claims_custom
+	if v == nil {
+		return
+	}
+	return *v, true
+}
+
+// OldClaimsCustom returns the old "claims_custom" field's value of the AuthRequest entity.
+// If the AuthRequest object wasn't provided to the builder, the object is fetched from the database.
+// An error is returned if the mutation operation is not UpdateOne, or the database query fails.
+func (m *AuthRequestMutation) OldClaimsCustom(ctx context.Context) (v map[string]interface{}, err error) {
+	if !m.op.Is(OpUpdateOne) {
+		return v, errors.New("OldClaimsCustom is only allowed on UpdateOne operations")
+	}
+	if m.id == nil || m.oldValue == nil {
+		return v, errors.New("OldClaimsCustom requires an ID field in the mutation")
+	}
+	oldValue, err := m.oldValue(ctx)
+	if err != nil {
+		return v, fmt.Errorf("querying old value for OldClaimsCustom: %w", err)
+	}
+	return oldValue.ClaimsCustom, nil
+}
+
+// ClearClaimsCustom clears the value of the "claims_custom" field.
+func (m *AuthRequestMutation) ClearClaimsCustom() {
+	m.claims_custom = nil
+	m.clearedFields[authrequest.FieldClaimsCustom] = struct{}{}
+}
+
+// ClaimsCustomCleared returns if the "claims_custom" field was cleared in this mutation.
+func (m *AuthRequestMutation) ClaimsCustomCleared() bool {
+	_, ok := m.clearedFields[authrequest.FieldClaimsCustom]
+	return ok
+}
+
+// ResetClaimsCustom resets all changes to the "claims_custom" field.
+func (m *AuthRequestMutation) ResetClaimsCustom() {
+	m.claims_custom = nil
+	delete(m.clearedFields, authrequest.FieldClaimsCustom)
+}
+
 // Where appends a list predicates to the AuthRequestMutation builder.
 func (m *AuthRequestMutation) Where(ps ...predicate.AuthRequest) {
 	m.predicates = append(m.predicates, ps...)
@@ -2531,7 +2827,7 @@ func (m *AuthRequestMutation) Type() string {
 // order to get all numeric fields that were incremented/decremented, call
 // AddedFields().
 func (m *AuthRequestMutation) Fields() []string {
-	fields := make([]string, 0, 25)
+	fields := make([]string, 0, 28)
 	if m.client_id != nil {
 		fields = append(fields, authrequest.FieldClientID)
 	}
@@ -2607,6 +2903,15 @@ func (m *AuthRequestMutation) Fields() []string {
 	if m.auth_time != nil {
 		fields = append(fields, authrequest.FieldAuthTime)
 	}
+	if m.resources != nil {
+		fields = append(fields, authrequest.FieldResources)
+	}
+	if m.login_hint != nil {
+		fields = append(fields, authrequest.FieldLoginHint)
+	}
+	if m.claims_custom != nil {
+		fields = append(fields, authrequest.FieldClaimsCustom)
+	}
 	return fields
 }
 
@@ -2665,6 +2970,12 @@ func (m *AuthRequestMutation) Field(name string) (ent.Value, bool) {
 		return m.MaxAge()
 	case authrequest.FieldAuthTime:
 		return m.AuthTime()
+	case authrequest.FieldResources:
+		return m.Resources()
+	case authrequest.FieldLoginHint:
+		return m.LoginHint()
+	case authrequest.FieldClaimsCustom:
+		return m.ClaimsCustom()
 	}
 	return nil, false
 }
@@ -2724,6 +3035,12 @@ func (m *AuthRequestMutation) OldField(ctx context.Context, name string) (ent.Va
 		return m.OldMaxAge(ctx)
 	case authrequest.FieldAuthTime:
 		return m.OldAuthTime(ctx)
+	case authrequest.FieldResources:
+		return m.OldResources(ctx)
+	case authrequest.FieldLoginHint:
+		return m.OldLoginHint(ctx)
+	case authrequest.FieldClaimsCustom:
+		return m.OldClaimsCustom(ctx)
 	}
 	return nil, fmt.Errorf("unknown AuthRequest field %s", name)
 }
@@ -2908,6 +3225,27 @@ func (m *AuthRequestMutation) SetField(name string, value ent.Value) error {
 		}
 		m.SetAuthTime(v)
 		return nil
+	case authrequest.FieldResources:
+		v, ok := value.([]string)
+		if !ok {
+			return fmt.Errorf("unexpected type %T for field %s", value, name)
+		}
+		m.SetResources(v)
+		return nil
+	case authrequest.FieldLoginHint:
+		v, ok := value.(string)
+		if !ok {
+			return fmt.Errorf("unexpected type %T for field %s", value, name)
+		}
+		m.SetLoginHint(v)
+		return nil
+	case authrequest.FieldClaimsCustom:
+		v, ok := value.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("unexpected type %T for field %s", value, name)
+		}
+		m.SetClaimsCustom(v)
+		return nil
 	}
 	return fmt.Errorf("unknown AuthRequest field %s", name)
 }
@@ -2971,6 +3309,15 @@ func (m *AuthRequestMutation) ClearedFields() []string {
 	if m.FieldCleared(authrequest.FieldAuthTime) {
 		fields = append(fields, authrequest.FieldAuthTime)
 	}
+	if m.FieldCleared(authrequest.FieldResources) {
+		fields = append(fields, authrequest.FieldResources)
+	}
+	if m.FieldCleared(authrequest.FieldLoginHint) {
+		fields = append(fields, authrequest.FieldLoginHint)
+	}
+	if m.FieldCleared(authrequest.FieldClaimsCustom) {
+		fields = append(fields, authrequest.FieldClaimsCustom)
+	}
 	return fields
 }
 
@@ -3003,6 +3350,15 @@ func (m *AuthRequestMutation) ClearField(name string) error {
 	case authrequest.FieldAuthTime:
 		m.ClearAuthTime()
 		return nil
+	case authrequest.FieldResources:
+		m.ClearResources()
+		return nil
+	case authrequest.FieldLoginHint:
+		m.ClearLoginHint()
+		return nil
+	case authrequest.FieldClaimsCustom:
+		m.ClearClaimsCustom()
+		return nil
 	}
 	return fmt.Errorf("unknown AuthRequest nullable field %s", name)
 }
@@ -3086,6 +3442,15 @@ func (m *AuthRequestMutation) ResetField(name string) error {
 	case authrequest.FieldAuthTime:
 		m.ResetAuthTime()
 		return nil
+	case authrequest.FieldResources:
+		m.ResetResources()
+		return nil
+	case authrequest.FieldLoginHint:
+		m.ResetLoginHint()
+		return nil
+	case authrequest.FieldClaimsCustom:
+		m.ResetClaimsCustom()
+		return nil
 	}
 	return fmt.Errorf("unknown AuthRequest field %s", name)
 }
@@ -6472,6 +6837,20 @@ type OAuth2ClientMutation struct {
 	appendpost_logout_redirect_uris []string
 	sso_shared_with                 *[]string
 	appendsso_shared_with           []string
+	theme                           **storage.ClientTheme
+	require_consent_on_claim_change *bool
+	client_credentials              **storage.ClientCredentialsConfig
+	jwt_bearer_issuers              *[]storage.JWTBearerIssuer
+	token_exchange                  **storage.TokenExchangePolicy
+	resources                       *[]string
+	custom_claims                   *map[string]interface{}
+	refresh_token_reuse             **storage.RefreshTokenReusePolicy
+	device_flow                     **storage.DeviceFlowConfig
+	allowed_origins                 *[]string
+	released_connector_claims       *[]string
+	groups_claim                    **storage.GroupsClaim
+	terms                           **storage.ClientTerms
+	impersonation                   **storage.ImpersonationPolicy
 	clearedFields                   map[string]struct{}
 	done                            bool
 	oldValue                        func(context.Context) (*OAuth2Client, error)
@@ -7116,78 +7495,806 @@ func (m *OAuth2ClientMutation) ResetSSOSharedWith() {
 	delete(m.clearedFields, oauth2client.FieldSSOSharedWith)
 }
 
-// Where appends a list predicates to the OAuth2ClientMutation builder.
-func (m *OAuth2ClientMutation) Where(ps ...predicate.OAuth2Client) {
-	m.predicates = append(m.predicates, ps...)
+// SetTheme sets the "theme" field.
+func (m *OAuth2ClientMutation) SetTheme(st *storage.ClientTheme) {
+	m.theme = &st
 }
 
-// WhereP appends storage-level predicates to the OAuth2ClientMutation builder. Using this method,
-// users can use type-assertion to append predicates that do not depend on any generated package.
-func (m *OAuth2ClientMutation) WhereP(ps ...func(*sql.Selector)) {
-	p := make([]predicate.OAuth2Client, len(ps))
-	for i := range ps {
-		p[i] = ps[i]
+// Theme returns the value of the "theme" field in the mutation.
+func (m *OAuth2ClientMutation) Theme() (r *storage.ClientTheme, exists bool) {
+	v := m.theme
+	if v == nil {
+		return
 	}
-	m.Where(p...)
+	return *v, true
 }
 
-// Op returns the operation name.
-func (m *OAuth2ClientMutation) Op() Op {
-	return m.op
+// OldTheme returns the old "theme" field's value of the OAuth2Client entity.
+// If the OAuth2Client object wasn't provided to the builder, the object is fetched from the database.
+// An error is returned if the mutation operation is not UpdateOne, or the database query fails.
+func (m *OAuth2ClientMutation) OldTheme(ctx context.Context) (v *storage.ClientTheme, err error) {
+	if !m.op.Is(OpUpdateOne) {
+		return v, errors.New("OldTheme is only allowed on UpdateOne operations")
+	}
+	if m.id == nil || m.oldValue == nil {
+		return v, errors.New("OldTheme requires an ID field in the mutation")
+	}
+	oldValue, err := m.oldValue(ctx)
+	if err != nil {
+		return v, fmt.Errorf("querying old value for OldTheme: %w", err)
+	}
+	return oldValue.Theme, nil
 }
 
-// SetOp allows setting the mutation operation.
-func (m *OAuth2ClientMutation) SetOp(op Op) {
-	m.op = op
+// ClearTheme clears the value of the "theme" field.
+func (m *OAuth2ClientMutation) ClearTheme() {
+	m.theme = nil
+	m.clearedFields[oauth2client.FieldTheme] = struct{}{}
 }
 
-// Type returns the node type of this mutation (OAuth2Client).
-func (m *OAuth2ClientMutation) Type() string {
-	return m.typ
+// ThemeCleared returns if the "theme" field was cleared in this mutation.
+func (m *OAuth2ClientMutation) ThemeCleared() bool {
+	_, ok := m.clearedFields[oauth2client.FieldTheme]
+	return ok
 }
 
-// Fields returns all fields that were changed during this mutation. Note that in
-// order to get all numeric fields that were incremented/decremented, call
-// AddedFields().
-func (m *OAuth2ClientMutation) Fields() []string {
-	fields := make([]string, 0, 10)
-	if m.secret != nil {
-		fields = append(fields, oauth2client.FieldSecret)
-	}
-	if m.redirect_uris != nil {
-		fields = append(fields, oauth2client.FieldRedirectUris)
-	}
-	if m.trusted_peers != nil {
-		fields = append(fields, oauth2client.FieldTrustedPeers)
-	}
-	if m.public != nil {
-		fields = append(fields, oauth2client.FieldPublic)
-	}
-	if m.name != nil {
-		fields = append(fields, oauth2client.FieldName)
-	}
-	if m.logo_url != nil {
-		fields = append(fields, oauth2client.FieldLogoURL)
-	}
-	if m.allowed_connectors != nil {
-		fields = append(fields, oauth2client.FieldAllowedConnectors)
+// ResetTheme resets all changes to the "theme" field.
+func (m *OAuth2ClientMutation) ResetTheme() {
+	m.theme = nil
+	delete(m.clearedFields, oauth2client.FieldTheme)
+}
+
+// SetRequireConsentOnClaimChange sets the "require_consent_on_claim_change" field.
+func (m *OAuth2ClientMutation) SetRequireConsentOnClaimChange(b bool) {
+	m.require_consent_on_claim_change = &b
+}
+
+// RequireConsentOnClaimChange returns the value of the "require_consent_on_claim_change" field in the mutation.
+func (m *OAuth2ClientMutation) RequireConsentOnClaimChange() (r bool, exists bool) {
+	v := m.require_consent_on_claim_change
+	if v == nil {
+		return
 	}
-	if m.mfa_chain != nil {
-		fields = append(fields, oauth2client.FieldMfaChain)
+	return *v, true
+}
+
+// OldRequireConsentOnClaimChange returns the old "require_consent_on_claim_change" field's value of the OAuth2Client entity.
+// If the OAuth2Client object wasn't provided to the builder, the object is fetched from the database.
+// An error is returned if the mutation operation is not UpdateOne, or the database query fails.
+func (m *OAuth2ClientMutation) OldRequireConsentOnClaimChange(ctx context.Context) (v bool, err error) {
+	if !m.op.Is(OpUpdateOne) {
+		return v, errors.New("OldRequireConsentOnClaimChange is only allowed on UpdateOne operations")
 	}
-	if m.post_logout_redirect_uris != nil {
-		fields = append(fields, oauth2client.FieldPostLogoutRedirectUris)
+	if m.id == nil || m.oldValue == nil {
+		return v, errors.New("OldRequireConsentOnClaimChange requires an ID field in the mutation")
 	}
-	if m.sso_shared_with != nil {
-		fields = append(fields, oauth2client.FieldSSOSharedWith)
+	oldValue, err := m.oldValue(ctx)
+	if err != nil {
+		return v, fmt.Errorf("querying old value for OldRequireConsentOnClaimChange: %w", err)
 	}
-	return fields
+	return oldValue.RequireConsentOnClaimChange, nil
 }
 
-// Field returns the value of a field with the given name. The second boolean
-// return value indicates that this field was not set, or was not defined in the
-// schema.
-func (m *OAuth2ClientMutation) Field(name string) (ent.Value, bool) {
+// ClearRequireConsentOnClaimChange clears the value of the "require_consent_on_claim_change" field.
+func (m *OAuth2ClientMutation) ClearRequireConsentOnClaimChange() {
+	m.require_consent_on_claim_change = nil
+	m.clearedFields[oauth2client.FieldRequireConsentOnClaimChange] = struct{}{}
+}
+
+// RequireConsentOnClaimChangeCleared returns if the "require_consent_on_claim_change" field was cleared in this mutation.
+func (m *OAuth2ClientMutation) RequireConsentOnClaimChangeCleared() bool {
+	_, ok := m.clearedFields[oauth2client.FieldRequireConsentOnClaimChange]
+	return ok
+}
+
+// ResetRequireConsentOnClaimChange resets all changes to the "require_consent_on_claim_change" field.
+func (m *OAuth2ClientMutation) ResetRequireConsentOnClaimChange() {
+	m.require_consent_on_claim_change = nil
+	delete(m.clearedFields, oauth2client.FieldRequireConsentOnClaimChange)
+}
+
+// SetClientCredentials sets the "client_credentials" field.
+func (m *OAuth2ClientMutation) SetClientCredentials(v *storage.ClientCredentialsConfig) {
+	m.client_credentials = &v
+}
+
+// ClientCredentials returns the value of the "client_credentials" field in the mutation.
+func (m *OAuth2ClientMutation) ClientCredentials() (r *storage.ClientCredentialsConfig, exists bool) {
+	v := m.client_credentials
+	if v == nil {
+		return
+	}
+	return *v, true
+}
+
+// OldClientCredentials returns the old "client_credentials" field's value of the OAuth2Client entity.
+// If the OAuth2Client object wasn't provided to the builder, the object is fetched from the database.
+// An error is returned if the mutation operation is not UpdateOne, or the database query fails.
+func (m *OAuth2ClientMutation) OldClientCredentials(ctx context.Context) (v *storage.ClientCredentialsConfig, err error) {
+	if !m.op.Is(OpUpdateOne) {
+		return v, errors.New("OldClientCredentials is only allowed on UpdateOne operations")
+	}
+	if m.id == nil || m.oldValue == nil {
+		return v, errors.New("OldClientCredentials requires an ID field in the mutation")
+	}
+	oldValue, err := m.oldValue(ctx)
+	if err != nil {
+		return v, fmt.Errorf("querying old value for OldClientCredentials: %w", err)
+	}
+	return oldValue.ClientCredentials, nil
+}
+
+// ClearClientCredentials clears the value of the "client_credentials" field.
+func (m *OAuth2ClientMutation) ClearClientCredentials() {
+	m.client_credentials = nil
+	m.clearedFields[oauth2client.FieldClientCredentials] = struct{}{}
+}
+
+// ClientCredentialsCleared returns if the "client_credentials" field was cleared in this mutation.
+func (m *OAuth2ClientMutation) ClientCredentialsCleared() bool {
+	_, ok := m.clearedFields[oauth2client.FieldClientCredentials]
+	return ok
+}
+
+// ResetClientCredentials resets all changes to the "client_credentials" field.
+func (m *OAuth2ClientMutation) ResetClientCredentials() {
+	m.client_credentials = nil
+	delete(m.clearedFields, oauth2client.FieldClientCredentials)
+}
+
+// SetJWTBearerIssuers sets the "jwt_bearer_issuers" field.
+func (m *OAuth2ClientMutation) SetJWTBearerIssuers(v []storage.JWTBearerIssuer) {
+	m.jwt_bearer_issuers = &v
+}
+
+// JWTBearerIssuers returns the value of the "jwt_bearer_issuers" field in the mutation.
+func (m *OAuth2ClientMutation) JWTBearerIssuers() (r []storage.JWTBearerIssuer, exists bool) {
+	v := m.jwt_bearer_issuers
+	if v == nil {
+		return
+	}
+	return *v, true
+}
+
+// OldJWTBearerIssuers returns the old "jwt_bearer_issuers" field's value of the OAuth2Client entity.
+// If the OAuth2Client object wasn't provided to the builder, the object is fetched from the database.
+// An error is returned if the mutation operation is not UpdateOne, or the database query fails.
+func (m *OAuth2ClientMutation) OldJWTBearerIssuers(ctx context.Context) (v []storage.JWTBearerIssuer, err error) {
+	if !m.op.Is(OpUpdateOne) {
+		return v, errors.New("OldJWTBearerIssuers is only allowed on UpdateOne operations")
+	}
+	if m.id == nil || m.oldValue == nil {
+		return v, errors.New("OldJWTBearerIssuers requires an ID field in the mutation")
+	}
+	oldValue, err := m.oldValue(ctx)
+	if err != nil {
+		return v, fmt.Errorf("querying old value for OldJWTBearerIssuers: %w", err)
+	}
+	return oldValue.JWTBearerIssuers, nil
+}
+
+// ClearJWTBearerIssuers clears the value of the "jwt_bearer_issuers" field.
+func (m *OAuth2ClientMutation) ClearJWTBearerIssuers() {
+	m.jwt_bearer_issuers = nil
+	m.clearedFields[oauth2client.FieldJWTBearerIssuers] = struct{}{}
+}
+
+// JWTBearerIssuersCleared returns if the "jwt_bearer_issuers" field was cleared in this mutation.
+func (m *OAuth2ClientMutation) JWTBearerIssuersCleared() bool {
+	_, ok := m.clearedFields[oauth2client.FieldJWTBearerIssuers]
+	return ok
+}
+
+// ResetJWTBearerIssuers resets all changes to the "jwt_bearer_issuers" field.
+func (m *OAuth2ClientMutation) ResetJWTBearerIssuers() {
+	m.jwt_bearer_issuers = nil
+	delete(m.clearedFields, oauth2client.FieldJWTBearerIssuers)
+}
+
+// SetTokenExchange sets the "token_exchange" field.
+func (m *OAuth2ClientMutation) SetTokenExchange(v *storage.TokenExchangePolicy) {
+	m.token_exchange = &v
+}
+
+// TokenExchange returns the value of the "token_exchange" field in the mutation.
+func (m *OAuth2ClientMutation) TokenExchange() (r *storage.TokenExchangePolicy, exists bool) {
+	v := m.token_exchange
+	if v == nil {
+		return
+	}
+	return *v, true
+}
+
+// OldTokenExchange returns the old "token_exchange" field's value of the OAuth2Client entity.
+// If the OAuth2Client object wasn't provided to the builder, the object is fetched from the database.
+// An error is returned if the mutation operation is not UpdateOne, or the database query fails.
+func (m *OAuth2ClientMutation) OldTokenExchange(ctx context.Context) (v *storage.TokenExchangePolicy, err error) {
+	if !m.op.Is(OpUpdateOne) {
+		return v, errors.New("OldTokenExchange is only allowed on UpdateOne operations")
+	}
+	if m.id == nil || m.oldValue == nil {
+		return v, errors.New("OldTokenExchange requires an ID field in the mutation")
+	}
+	oldValue, err := m.oldValue(ctx)
+	if err != nil {
+		return v, fmt.Errorf("querying old value for OldTokenExchange: %w", err)
+	}
+	return oldValue.TokenExchange, nil
+}
+
+// ClearTokenExchange clears the value of the "token_exchange" field.
+func (m *OAuth2ClientMutation) ClearTokenExchange() {
+	m.token_exchange = nil
+	m.clearedFields[oauth2client.FieldTokenExchange] = struct{}{}
+}
+
+// TokenExchangeCleared returns if the "token_exchange" field was cleared in this mutation.
+func (m *OAuth2ClientMutation) TokenExchangeCleared() bool {
+	_, ok := m.clearedFields[oauth2client.FieldTokenExchange]
+	return ok
+}
+
+// ResetTokenExchange resets all changes to the "token_exchange" field.
+func (m *OAuth2ClientMutation) ResetTokenExchange() {
+	m.token_exchange = nil
+	delete(m.clearedFields, oauth2client.FieldTokenExchange)
+}
+
+// SetResources sets the "resources" field.
+func (m *OAuth2ClientMutation) SetResources(v []string) {
+	m.resources = &v
+}
+
+// Resources returns the value of the "resources" field in the mutation.
+func (m *OAuth2ClientMutation) Resources() (r []string, exists bool) {
+	v := m.resources
+	if v == nil {
+		return
+	}
+	return *v, true
+}
+
+// OldResources returns the old "resources" field's value of the OAuth2Client entity.
+// If the OAuth2Client object wasn't provided to the builder, the object is fetched from the database.
+// An error is returned if the mutation operation is not UpdateOne, or the database query fails.
+func (m *OAuth2ClientMutation) OldResources(ctx context.Context) (v []string, err error) {
+	if !m.op.Is(OpUpdateOne) {
+		return v, errors.New("OldResources is only allowed on UpdateOne operations")
+	}
+	if m.id == nil || m.oldValue == nil {
+		return v, errors.New("OldResources requires an ID field in the mutation")
+	}
+	oldValue, err := m.oldValue(ctx)
+	if err != nil {
+		return v, fmt.Errorf("querying old value for OldResources: %w", err)
+	}
+	return oldValue.Resources, nil
+}
+
+// ClearResources clears the value of the "resources" field.
+func (m *OAuth2ClientMutation) ClearResources() {
+	m.resources = nil
+	m.clearedFields[oauth2client.FieldResources] = struct{}{}
+}
+
+// ResourcesCleared returns if the "resources" field was cleared in this mutation.
+func (m *OAuth2ClientMutation) ResourcesCleared() bool {
+	_, ok := m.clearedFields[oauth2client.FieldResources]
+	return ok
+}
+
+// ResetResources resets all changes to the "resources" field.
+func (m *OAuth2ClientMutation) ResetResources() {
+	m.resources = nil
+	delete(m.clearedFields, oauth2client.FieldResources)
+}
+
+// SetCustomClaims sets the "custom_claims" field.
+func (m *OAuth2ClientMutation) SetCustomClaims(v map[string]interface{}) {
+	m.custom_claims = &v
+}
+
+// CustomClaims returns the value of the "custom_claims" field in the mutation.
+func (m *OAuth2ClientMutation) CustomClaims() (r map[string]interface{}, exists bool) {
+	v := m.custom_claims
+	if v == nil {
+		return
+	}
+	return *v, true
+}
+
+// OldCustomClaims returns the old "custom_claims" field's value of the OAuth2Client entity.
+// If the OAuth2Client object wasn't provided to the builder, the object is fetched from the database.
+// An error is returned if the mutation operation is not UpdateOne, or the database query fails.
+func (m *OAuth2ClientMutation) OldCustomClaims(ctx context.Context) (v map[string]interface{}, err error) {
+	if !m.op.Is(OpUpdateOne) {
+		return v, errors.New("OldCustomClaims is only allowed on UpdateOne operations")
+	}
+	if m.id == nil || m.oldValue == nil {
+		return v, errors.New("OldCustomClaims requires an ID field in the mutation")
+	}
+	oldValue, err := m.oldValue(ctx)
+	if err != nil {
+		return v, fmt.Errorf("querying old value for OldCustomClaims: %w", err)
+	}
+	return oldValue.CustomClaims, nil
+}
+
+// ClearCustomClaims clears the value of the "custom_claims" field.
+func (m *OAuth2ClientMutation) ClearCustomClaims() {
+	m.custom_claims = nil
+	m.clearedFields[oauth2client.FieldCustomClaims] = struct{}{}
+}
+
+// CustomClaimsCleared returns if the "custom_claims" field was cleared in this mutation.
+func (m *OAuth2ClientMutation) CustomClaimsCleared() bool {
+	_, ok := m.clearedFields[oauth2client.FieldCustomClaims]
+	return ok
+}
+
+// ResetCustomClaims resets all changes to the "custom_claims" field.
+func (m *OAuth2ClientMutation) ResetCustomClaims() {
+	m.custom_claims = nil
+	delete(m.clearedFields, oauth2client.FieldCustomClaims)
+}
+
+// SetRefreshTokenReuse sets the "refresh_token_reuse" field.
+func (m *OAuth2ClientMutation) SetRefreshTokenReuse(v *storage.RefreshTokenReusePolicy) {
+	m.refresh_token_reuse = &v
+}
+
+// RefreshTokenReuse returns the value of the "refresh_token_reuse" field in the mutation.
+func (m *OAuth2ClientMutation) RefreshTokenReuse() (r *storage.RefreshTokenReusePolicy, exists bool) {
+	v := m.refresh_token_reuse
+	if v == nil {
+		return
+	}
+	return *v, true
+}
+
+// OldRefreshTokenReuse returns the old "refresh_token_reuse" field's value of the OAuth2Client entity.
+// If the OAuth2Client object wasn't provided to the builder, the object is fetched from the database.
+// An error is returned if the mutation operation is not UpdateOne, or the database query fails.
+func (m *OAuth2ClientMutation) OldRefreshTokenReuse(ctx context.Context) (v *storage.RefreshTokenReusePolicy, err error) {
+	if !m.op.Is(OpUpdateOne) {
+		return v, errors.New("OldRefreshTokenReuse is only allowed on UpdateOne operations")
+	}
+	if m.id == nil || m.oldValue == nil {
+		return v, errors.New("OldRefreshTokenReuse requires an ID field in the mutation")
+	}
+	oldValue, err := m.oldValue(ctx)
+	if err != nil {
+		return v, fmt.Errorf("querying old value for OldRefreshTokenReuse: %w", err)
+	}
+	return oldValue.RefreshTokenReuse, nil
+}
+
+// ClearRefreshTokenReuse clears the value of the "refresh_token_reuse" field.
+func (m *OAuth2ClientMutation) ClearRefreshTokenReuse() {
+	m.refresh_token_reuse = nil
+	m.clearedFields[oauth2client.FieldRefreshTokenReuse] = struct{}{}
+}
+
+// RefreshTokenReuseCleared returns if the "refresh_token_reuse" field was cleared in this mutation.
+func (m *OAuth2ClientMutation) RefreshTokenReuseCleared() bool {
+	_, ok := m.clearedFields[oauth2client.FieldRefreshTokenReuse]
+	return ok
+}
+
+// ResetRefreshTokenReuse resets all changes to the "refresh_token_reuse" field.
+func (m *OAuth2ClientMutation) ResetRefreshTokenReuse() {
+	m.refresh_token_reuse = nil
+	delete(m.clearedFields, oauth2client.FieldRefreshTokenReuse)
+}
+
+// SetDeviceFlow sets the "device_flow" field.
+func (m *OAuth2ClientMutation) SetDeviceFlow(v *storage.DeviceFlowConfig) {
+	m.device_flow = &v
+}
+
+// DeviceFlow returns the value of the "device_flow" field in the mutation.
+func (m *OAuth2ClientMutation) DeviceFlow() (r *storage.DeviceFlowConfig, exists bool) {
+	v := m.device_flow
+	if v == nil {
+		return
+	}
+	return *v, true
+}
+
+// OldDeviceFlow returns the old "device_flow" field's value of the OAuth2Client entity.
+// If the OAuth2Client object wasn't provided to the builder, the object is fetched from the database.
+// An error is returned if the mutation operation is not UpdateOne, or the database query fails.
+func (m *OAuth2ClientMutation) OldDeviceFlow(ctx context.Context) (v *storage.DeviceFlowConfig, err error) {
+	if !m.op.Is(OpUpdateOne) {
+		return v, errors.New("OldDeviceFlow is only allowed on UpdateOne operations")
+	}
+	if m.id == nil || m.oldValue == nil {
+		return v, errors.New("OldDeviceFlow requires an ID field in the mutation")
+	}
+	oldValue, err := m.oldValue(ctx)
+	if err != nil {
+		return v, fmt.Errorf("querying old value for OldDeviceFlow: %w", err)
+	}
+	return oldValue.DeviceFlow, nil
+}
+
+// ClearDeviceFlow clears the value of the "device_flow" field.
+func (m *OAuth2ClientMutation) ClearDeviceFlow() {
+	m.device_flow = nil
+	m.clearedFields[oauth2client.FieldDeviceFlow] = struct{}{}
+}
+
+// DeviceFlowCleared returns if the "device_flow" field was cleared in this mutation.
+func (m *OAuth2ClientMutation) DeviceFlowCleared() bool {
+	_, ok := m.clearedFields[oauth2client.FieldDeviceFlow]
+	return ok
+}
+
+// ResetDeviceFlow resets all changes to the "device_flow" field.
+func (m *OAuth2ClientMutation) ResetDeviceFlow() {
+	m.device_flow = nil
+	delete(m.clearedFields, oauth2client.FieldDeviceFlow)
+}
+
+// SetAllowedOrigins sets the "allowed_origins" field.
+func (m *OAuth2ClientMutation) SetAllowedOrigins(v []string) {
+	m.allowed_origins = &v
+}
+
+// AllowedOrigins returns the value of the "allowed_origins" field in the mutation.
+func (m *OAuth2ClientMutation) AllowedOrigins() (r []string, exists bool) {
+	v := m.allowed_origins
+	if v == nil {
+		return
+	}
+	return *v, true
+}
+
+// OldAllowedOrigins returns the old "allowed_origins" field's value of the OAuth2Client entity.
+// If the OAuth2Client object wasn't provided to the builder, the object is fetched from the database.
+// An error is returned if the mutation operation is not UpdateOne, or the database query fails.
+func (m *OAuth2ClientMutation) OldAllowedOrigins(ctx context.Context) (v []string, err error) {
+	if !m.op.Is(OpUpdateOne) {
+		return v, errors.New("OldAllowedOrigins is only allowed on UpdateOne operations")
+	}
+	if m.id == nil || m.oldValue == nil {
+		return v, errors.New("OldAllowedOrigins requires an ID field in the mutation")
+	}
+	oldValue, err := m.oldValue(ctx)
+	if err != nil {
+		return v, fmt.Errorf("querying old value for OldAllowedOrigins: %w", err)
+	}
+	return oldValue.AllowedOrigins, nil
+}
+
+// ClearAllowedOrigins clears the value of the "allowed_origins" field.
+func (m *OAuth2ClientMutation) ClearAllowedOrigins() {
+	m.allowed_origins = nil
+	m.clearedFields[oauth2client.FieldAllowedOrigins] = struct{}{}
+}
+
+// AllowedOriginsCleared returns if the "allowed_origins" field was cleared in this mutation.
+func (m *OAuth2ClientMutation) AllowedOriginsCleared() bool {
+	_, ok := m.clearedFields[oauth2client.FieldAllowedOrigins]
+	return ok
+}
+
+// ResetAllowedOrigins resets all changes to the "allowed_origins" field.
+func (m *OAuth2ClientMutation) ResetAllowedOrigins() {
+	m.allowed_origins = nil
+	delete(m.clearedFields, oauth2client.FieldAllowedOrigins)
+}
+
+// SetReleasedConnectorClaims sets the "released_connector_claims" field.
+func (m *OAuth2ClientMutation) SetReleasedConnectorClaims(v []string) {
+	m.released_connector_claims = &v
+}
+
+// ReleasedConnectorClaims returns the value of the "released_connector_claims" field in the mutation.
+func (m *OAuth2ClientMutation) ReleasedConnectorClaims() (r []string, exists bool) {
+	v := m.released_connector_claims
+	if v == nil {
+		return
+	}
+	return *v, true
+}
+
+// OldReleasedConnectorClaims returns the old "released_connector_claims" field's value of the OAuth2Client entity.
+// If the OAuth2Client object wasn't provided to the builder, the object is fetched from the database.
+// An error is returned if the mutation operation is not UpdateOne, or the database query fails.
+func (m *OAuth2ClientMutation) OldReleasedConnectorClaims(ctx context.Context) (v []string, err error) {
+	if !m.op.Is(OpUpdateOne) {
+		return v, errors.New("OldReleasedConnectorClaims is only allowed on UpdateOne operations")
+	}
+	if m.id == nil || m.oldValue == nil {
+		return v, errors.New("OldReleasedConnectorClaims requires an ID field in the mutation")
+	}
+	oldValue, err := m.oldValue(ctx)
+	if err != nil {
+		return v, fmt.Errorf("querying old value for OldReleasedConnectorClaims: %w", err)
+	}
+	return oldValue.ReleasedConnectorClaims, nil
+}
+
+// ClearReleasedConnectorClaims clears the value of the "released_connector_claims" field.
+func (m *OAuth2ClientMutation) ClearReleasedConnectorClaims() {
+	m.released_connector_claims = nil
+	m.clearedFields[oauth2client.FieldReleasedConnectorClaims] = struct{}{}
+}
+
+// ReleasedConnectorClaimsCleared returns if the "released_connector_claims" field was cleared in this mutation.
+func (m *OAuth2ClientMutation) ReleasedConnectorClaimsCleared() bool {
+	_, ok := m.clearedFields[oauth2client.FieldReleasedConnectorClaims]
+	return ok
+}
+
+// ResetReleasedConnectorClaims resets all changes to the "released_connector_claims" field.
+func (m *OAuth2ClientMutation) ResetReleasedConnectorClaims() {
+	m.released_connector_claims = nil
+	delete(m.clearedFields, oauth2client.FieldReleasedConnectorClaims)
+}
+
+// SetGroupsClaim sets the "groups_claim" field.
+func (m *OAuth2ClientMutation) SetGroupsClaim(v *storage.GroupsClaim) {
+	m.groups_claim = &v
+}
+
+// GroupsClaim returns the value of the "groups_claim" field in the mutation.
+func (m *OAuth2ClientMutation) GroupsClaim() (r *storage.GroupsClaim, exists bool) {
+	v := m.groups_claim
+	if v == nil {
+		return
+	}
+	return *v, true
+}
+
+// OldGroupsClaim returns the old "groups_claim" field's value of the OAuth2Client entity.
+// If the OAuth2Client object wasn't provided to the builder, the object is fetched from the database.
+// An error is returned if the mutation operation is not UpdateOne, or the database query fails.
+func (m *OAuth2ClientMutation) OldGroupsClaim(ctx context.Context) (v *storage.GroupsClaim, err error) {
+	if !m.op.Is(OpUpdateOne) {
+		return v, errors.New("OldGroupsClaim is only allowed on UpdateOne operations")
+	}
+	if m.id == nil || m.oldValue == nil {
+		return v, errors.New("OldGroupsClaim requires an ID field in the mutation")
+	}
+	oldValue, err := m.oldValue(ctx)
+	if err != nil {
+		return v, fmt.Errorf("querying old value for OldGroupsClaim: %w", err)
+	}
+	return oldValue.GroupsClaim, nil
+}
+
+// ClearGroupsClaim clears the value of the "groups_claim" field.
+func (m *OAuth2ClientMutation) ClearGroupsClaim() {
+	m.groups_claim = nil
+	m.clearedFields[oauth2client.FieldGroupsClaim] = struct{}{}
+}
+
+// GroupsClaimCleared returns if the "groups_claim" field was cleared in this mutation.
+func (m *OAuth2ClientMutation) GroupsClaimCleared() bool {
+	_, ok := m.clearedFields[oauth2client.FieldGroupsClaim]
+	return ok
+}
+
+// ResetGroupsClaim resets all changes to the "groups_claim" field.
+func (m *OAuth2ClientMutation) ResetGroupsClaim() {
+	m.groups_claim = nil
+	delete(m.clearedFields, oauth2client.FieldGroupsClaim)
+}
+
+// SetTerms sets the "terms" field.
+func (m *OAuth2ClientMutation) SetTerms(v *storage.ClientTerms) {
+	m.terms = &v
+}
+
+// Terms returns the value of the "terms" field in the mutation.
+func (m *OAuth2ClientMutation) Terms() (r *storage.ClientTerms, exists bool) {
+	v := m.terms
+	if v == nil {
+		return
+	}
+	return *v, true
+}
+
+// OldTerms returns the old "terms" field's value of the OAuth2Client entity.
+// If the OAuth2Client object wasn't provided to the builder, the object is fetched from the database.
+// An error is returned if the mutation operation is not UpdateOne, or the database query fails.
+func (m *OAuth2ClientMutation) OldTerms(ctx context.Context) (v *storage.ClientTerms, err error) {
+	if !m.op.Is(OpUpdateOne) {
+		return v, errors.New("OldTerms is only allowed on UpdateOne operations")
+	}
+	if m.id == nil || m.oldValue == nil {
+		return v, errors.New("OldTerms requires an ID field in the mutation")
+	}
+	oldValue, err := m.oldValue(ctx)
+	if err != nil {
+		return v, fmt.Errorf("querying old value for OldTerms: %w", err)
+	}
+	return oldValue.Terms, nil
+}
+
+// ClearTerms clears the value of the "terms" field.
+func (m *OAuth2ClientMutation) ClearTerms() {
+	m.terms = nil
+	m.clearedFields[oauth2client.FieldTerms] = struct{}{}
+}
+
+// TermsCleared returns if the "terms" field was cleared in this mutation.
+func (m *OAuth2ClientMutation) TermsCleared() bool {
+	_, ok := m.clearedFields[oauth2client.FieldTerms]
+	return ok
+}
+
+// ResetTerms resets all changes to the "terms" field.
+func (m *OAuth2ClientMutation) ResetTerms() {
+	m.terms = nil
+	delete(m.clearedFields, oauth2client.FieldTerms)
+}
+
+// SetImpersonation sets the "impersonation" field.
+func (m *OAuth2ClientMutation) SetImpersonation(v *storage.ImpersonationPolicy) {
+	m.impersonation = &v
+}
+
+// Impersonation returns the value of the "impersonation" field in the mutation.
+func (m *OAuth2ClientMutation) Impersonation() (r *storage.ImpersonationPolicy, exists bool) {
+	v := m.impersonation
+	if v == nil {
+		return
+	}
+	return *v, true
+}
+
+// OldImpersonation returns the old "impersonation" field's value of the OAuth2Client entity.
+// If the OAuth2Client object wasn't provided to the builder, the object is fetched from the database.
+// An error is returned if the mutation operation is not UpdateOne, or the database query fails.
+func (m *OAuth2ClientMutation) OldImpersonation(ctx context.Context) (v *storage.ImpersonationPolicy, err error) {
+	if !m.op.Is(OpUpdateOne) {
+		return v, errors.New("OldImpersonation is only allowed on UpdateOne operations")
+	}
+	if m.id == nil || m.oldValue == nil {
+		return v, errors.New("OldImpersonation requires an ID field in the mutation")
+	}
+	oldValue, err := m.oldValue(ctx)
+	if err != nil {
+		return v, fmt.Errorf("querying old value for OldImpersonation: %w", err)
+	}
+	return oldValue.Impersonation, nil
+}
+
+// ClearImpersonation clears the value of the "impersonation" field.
+func (m *OAuth2ClientMutation) ClearImpersonation() {
+	m.impersonation = nil
+	m.clearedFields[oauth2client.FieldImpersonation] = struct{}{}
+}
+
+// ImpersonationCleared returns if the "impersonation" field was cleared in this mutation.
+func (m *OAuth2ClientMutation) ImpersonationCleared() bool {
+	_, ok := m.clearedFields[oauth2client.FieldImpersonation]
+	return ok
+}
+
+// ResetImpersonation resets all changes to the "impersonation" field.
+func (m *OAuth2ClientMutation) ResetImpersonation() {
+	m.impersonation = nil
+	delete(m.clearedFields, oauth2client.FieldImpersonation)
+}
+
+// Where appends a list predicates to the OAuth2ClientMutation builder.
+func (m *OAuth2ClientMutation) Where(ps ...predicate.OAuth2Client) {
+	m.predicates = append(m.predicates, ps...)
+}
+
+// WhereP appends storage-level predicates to the OAuth2ClientMutation builder. Using this method,
+// users can use type-assertion to append predicates that do not depend on any generated package.
+func (m *OAuth2ClientMutation) WhereP(ps ...func(*sql.Selector)) {
+	p := make([]predicate.OAuth2Client, len(ps))
+	for i := range ps {
+		p[i] = ps[i]
+	}
+	m.Where(p...)
+}
+
+// Op returns the operation name.
+func (m *OAuth2ClientMutation) Op() Op {
+	return m.op
+}
+
+// SetOp allows setting the mutation operation.
+func (m *OAuth2ClientMutation) SetOp(op Op) {
+	m.op = op
+}
+
+// Type returns the node type of this mutation (OAuth2Client).
+func (m *OAuth2ClientMutation) Type() string {
+	return m.typ
+}
+
+// Fields returns all fields that were changed during this mutation. Note that in
+// order to get all numeric fields that were incremented/decremented, call
+// AddedFields().
+func (m *OAuth2ClientMutation) Fields() []string {
+	fields := make([]string, 0, 24)
+	if m.secret != nil {
+		fields = append(fields, oauth2client.FieldSecret)
+	}
+	if m.redirect_uris != nil {
+		fields = append(fields, oauth2client.FieldRedirectUris)
+	}
+	if m.trusted_peers != nil {
+		fields = append(fields, oauth2client.FieldTrustedPeers)
+	}
+	if m.public != nil {
+		fields = append(fields, oauth2client.FieldPublic)
+	}
+	if m.name != nil {
+		fields = append(fields, oauth2client.FieldName)
+	}
+	if m.logo_url != nil {
+		fields = append(fields, oauth2client.FieldLogoURL)
+	}
+	if m.allowed_connectors != nil {
+		fields = append(fields, oauth2client.FieldAllowedConnectors)
+	}
+	if m.mfa_chain != nil {
+		fields = append(fields, oauth2client.FieldMfaChain)
+	}
+	if m.post_logout_redirect_uris != nil {
+		fields = append(fields, oauth2client.FieldPostLogoutRedirectUris)
+	}
+	if m.sso_shared_with != nil {
+		fields = append(fields, oauth2client.FieldSSOSharedWith)
+	}
+	if m.theme != nil {
+		fields = append(fields, oauth2client.FieldTheme)
+	}
+	if m.require_consent_on_claim_change != nil {
+		fields = append(fields, oauth2client.FieldRequireConsentOnClaimChange)
+	}
+	if m.client_credentials != nil {
+		fields = append(fields, oauth2client.FieldClientCredentials)
+	}
+	if m.jwt_bearer_issuers != nil {
+		fields = append(fields, oauth2client.FieldJWTBearerIssuers)
+	}
+	if m.token_exchange != nil {
+		fields = append(fields, oauth2client.FieldTokenExchange)
+	}
+	if m.resources != nil {
+		fields = append(fields, oauth2client.FieldResources)
+	}
+	if m.custom_claims != nil {
+		fields = append(fields, oauth2client.FieldCustomClaims)
+	}
+	if m.refresh_token_reuse != nil {
+		fields = append(fields, oauth2client.FieldRefreshTokenReuse)
+	}
+	if m.device_flow != nil {
+		fields = append(fields, oauth2client.FieldDeviceFlow)
+	}
+	if m.allowed_origins != nil {
+		fields = append(fields, oauth2client.FieldAllowedOrigins)
+	}
+	if m.released_connector_claims != nil {
+		fields = append(fields, oauth2client.FieldReleasedConnectorClaims)
+	}
+	if m.groups_claim != nil {
+		fields = append(fields, oauth2client.FieldGroupsClaim)
+	}
+	if m.terms != nil {
+		fields = append(fields, oauth2client.FieldTerms)
+	}
+	if m.impersonation != nil {
+		fields = append(fields, oauth2client.FieldImpersonation)
+	}
+	return fields
+}
+
+// Field returns the value of a field with the given name. The second boolean
+// return value indicates that this field was not set, or was not defined in the
+// schema.
+func (m *OAuth2ClientMutation) Field(name string) (ent.Value, bool) {
 	switch name {
 	case oauth2client.FieldSecret:
 		return m.Secret()
@@ -7209,6 +8316,34 @@ func (m *OAuth2ClientMutation) Field(name string) (ent.Value, bool) {
 		return m.PostLogoutRedirectUris()
 	case oauth2client.FieldSSOSharedWith:
 		return m.SSOSharedWith()
+	case oauth2client.FieldTheme:
+		return m.Theme()
+	case oauth2client.FieldRequireConsentOnClaimChange:
+		return m.RequireConsentOnClaimChange()
+	case oauth2client.FieldClientCredentials:
+		return m.ClientCredentials()
+	case oauth2client.FieldJWTBearerIssuers:
+		return m.JWTBearerIssuers()
+	case oauth2client.FieldTokenExchange:
+		return m.TokenExchange()
+	case oauth2client.FieldResources:
+		return m.Resources()
+	case oauth2client.FieldCustomClaims:
+		return m.CustomClaims()
+	case oauth2client.FieldRefreshTokenReuse:
+		return m.RefreshTokenReuse()
+	case oauth2client.FieldDeviceFlow:
+		return m.DeviceFlow()
+	case oauth2client.FieldAllowedOrigins:
+		return m.AllowedOrigins()
+	case oauth2client.FieldReleasedConnectorClaims:
+		return m.ReleasedConnectorClaims()
+	case oauth2client.FieldGroupsClaim:
+		return m.GroupsClaim()
+	case oauth2client.FieldTerms:
+		return m.Terms()
+	case oauth2client.FieldImpersonation:
+		return m.Impersonation()
 	}
 	return nil, false
 }
@@ -7238,6 +8373,34 @@ func (m *OAuth2ClientMutation) OldField(ctx context.Context, name string) (ent.V
 		return m.OldPostLogoutRedirectUris(ctx)
 	case oauth2client.FieldSSOSharedWith:
 		return m.OldSSOSharedWith(ctx)
+	case oauth2client.FieldTheme:
+		return m.OldTheme(ctx)
+	case oauth2client.FieldRequireConsentOnClaimChange:
+		return m.OldRequireConsentOnClaimChange(ctx)
+	case oauth2client.FieldClientCredentials:
+		return m.OldClientCredentials(ctx)
+	case oauth2client.FieldJWTBearerIssuers:
+		return m.OldJWTBearerIssuers(ctx)
+	case oauth2client.FieldTokenExchange:
+		return m.OldTokenExchange(ctx)
+	case oauth2client.FieldResources:
+		return m.OldResources(ctx)
+	case oauth2client.FieldCustomClaims:
+		return m.OldCustomClaims(ctx)
+	case oauth2client.FieldRefreshTokenReuse:
+		return m.OldRefreshTokenReuse(ctx)
+	case oauth2client.FieldDeviceFlow:
+		return m.OldDeviceFlow(ctx)
+	case oauth2client.FieldAllowedOrigins:
+		return m.OldAllowedOrigins(ctx)
+	case oauth2client.FieldReleasedConnectorClaims:
+		return m.OldReleasedConnectorClaims(ctx)
+	case oauth2client.FieldGroupsClaim:
+		return m.OldGroupsClaim(ctx)
+	case oauth2client.FieldTerms:
+		return m.OldTerms(ctx)
+	case oauth2client.FieldImpersonation:
+		return m.OldImpersonation(ctx)
 	}
 	return nil, fmt.Errorf("unknown OAuth2Client field %s", name)
 }
@@ -7266,56 +8429,154 @@ func (m *OAuth2ClientMutation) SetField(name string, value ent.Value) error {
 		if !ok {
 			return fmt.Errorf("unexpected type %T for field %s", value, name)
 		}
-		m.SetTrustedPeers(v)
+		m.SetTrustedPeers(v)
+		return nil
+	case oauth2client.FieldPublic:
+		v, ok := value.(bool)
+		if !ok {
+			return fmt.Errorf("unexpected type %T for field %s", value, name)
+		}
+		m.SetPublic(v)
+		return nil
+	case oauth2client.FieldName:
+		v, ok := value.(string)
+		if !ok {
+			return fmt.Errorf("unexpected type %T for field %s", value, name)
+		}
+		m.SetName(v)
+		return nil
+	case oauth2client.FieldLogoURL:
+		v, ok := value.(string)
+		if !ok {
+			return fmt.Errorf("unexpected type %T for field %s", value, name)
+		}
+		m.SetLogoURL(v)
+		return nil
+	case oauth2client.FieldAllowedConnectors:
+		v, ok := value.([]string)
+		if !ok {
+			return fmt.Errorf("unexpected type %T for field %s", value, name)
+		}
+		m.SetAllowedConnectors(v)
+		return nil
+	case oauth2client.FieldMfaChain:
+		v, ok := value.([]string)
+		if !ok {
+			return fmt.Errorf("unexpected type %T for field %s", value, name)
+		}
+		m.SetMfaChain(v)
+		return nil
+	case oauth2client.FieldPostLogoutRedirectUris:
+		v, ok := value.([]string)
+		if !ok {
+			return fmt.Errorf("unexpected type %T for field %s", value, name)
+		}
+		m.SetPostLogoutRedirectUris(v)
+		return nil
+	case oauth2client.FieldSSOSharedWith:
+		v, ok := value.([]string)
+		if !ok {
+			return fmt.Errorf("unexpected type %T for field %s", value, name)
+		}
+		m.SetSSOSharedWith(v)
+		return nil
+	case oauth2client.FieldTheme:
+		v, ok := value.(*storage.ClientTheme)
+		if !ok {
+			return fmt.Errorf("unexpected type %T for field %s", value, name)
+		}
+		m.SetTheme(v)
+		return nil
+	case oauth2client.FieldRequireConsentOnClaimChange:
+		v, ok := value.(bool)
+		if !ok {
+			return fmt.Errorf("unexpected type %T for field %s", value, name)
+		}
+		m.SetRequireConsentOnClaimChange(v)
+		return nil
+	case oauth2client.FieldClientCredentials:
+		v, ok := value.(*storage.ClientCredentialsConfig)
+		if !ok {
+			return fmt.Errorf("unexpected type %T for field %s", value, name)
+		}
+		m.SetClientCredentials(v)
+		return nil
+	case oauth2client.FieldJWTBearerIssuers:
+		v, ok := value.([]storage.JWTBearerIssuer)
+		if !ok {
+			return fmt.Errorf("unexpected type %T for field %s", value, name)
+		}
+		m.SetJWTBearerIssuers(v)
+		return nil
+	case oauth2client.FieldTokenExchange:
+		v, ok := value.(*storage.TokenExchangePolicy)
+		if !ok {
+			return fmt.Errorf("unexpected type %T for field %s", value, name)
+		}
+		m.SetTokenExchange(v)
+		return nil
+	case oauth2client.FieldResources:
+		v, ok := value.([]string)
+		if !ok {
+			return fmt.Errorf("unexpected type %T for field %s", value, name)
+		}
+		m.SetResources(v)
+		return nil
+	case oauth2client.FieldCustomClaims:
+		v, ok := value.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("unexpected type %T for field %s", value, name)
+		}
+		m.SetCustomClaims(v)
 		return nil
-	case oauth2client.FieldPublic:
-		v, ok := value.(bool)
+	case oauth2client.FieldRefreshTokenReuse:
+		v, ok := value.(*storage.RefreshTokenReusePolicy)
 		if !ok {
 			return fmt.Errorf("unexpected type %T for field %s", value, name)
 		}
-		m.SetPublic(v)
+		m.SetRefreshTokenReuse(v)
 		return nil
-	case oauth2client.FieldName:
-		v, ok := value.(string)
+	case oauth2client.FieldDeviceFlow:
+		v, ok := value.(*storage.DeviceFlowConfig)
 		if !ok {
 			return fmt.Errorf("unexpected type %T for field %s", value, name)
 		}
-		m.SetName(v)
+		m.SetDeviceFlow(v)
 		return nil
-	case oauth2client.FieldLogoURL:
-		v, ok := value.(string)
+	case oauth2client.FieldAllowedOrigins:
+		v, ok := value.([]string)
 		if !ok {
 			return fmt.Errorf("unexpected type %T for field %s", value, name)
 		}
-		m.SetLogoURL(v)
+		m.SetAllowedOrigins(v)
 		return nil
-	case oauth2client.FieldAllowedConnectors:
+	case oauth2client.FieldReleasedConnectorClaims:
 		v, ok := value.([]string)
 		if !ok {
 			return fmt.Errorf("unexpected type %T for field %s", value, name)
 		}
-		m.SetAllowedConnectors(v)
+		m.SetReleasedConnectorClaims(v)
 		return nil
-	case oauth2client.FieldMfaChain:
-		v, ok := value.([]string)
+	case oauth2client.FieldGroupsClaim:
+		v, ok := value.(*storage.GroupsClaim)
 		if !ok {
 			return fmt.Errorf("unexpected type %T for field %s", value, name)
 		}
-		m.SetMfaChain(v)
+		m.SetGroupsClaim(v)
 		return nil
-	case oauth2client.FieldPostLogoutRedirectUris:
-		v, ok := value.([]string)
+	case oauth2client.FieldTerms:
+		v, ok := value.(*storage.ClientTerms)
 		if !ok {
 			return fmt.Errorf("unexpected type %T for field %s", value, name)
 		}
-		m.SetPostLogoutRedirectUris(v)
+		m.SetTerms(v)
 		return nil
-	case oauth2client.FieldSSOSharedWith:
-		v, ok := value.([]string)
+	case oauth2client.FieldImpersonation:
+		v, ok := value.(*storage.ImpersonationPolicy)
 		if !ok {
 			return fmt.Errorf("unexpected type %T for field %s", value, name)
 		}
-		m.SetSSOSharedWith(v)
+		m.SetImpersonation(v)
 		return nil
 	}
 	return fmt.Errorf("unknown OAuth2Client field %s", name)
@@ -7365,6 +8626,48 @@ func (m *OAuth2ClientMutation) ClearedFields() []string {
 	if m.FieldCleared(oauth2client.FieldSSOSharedWith) {
 		fields = append(fields, oauth2client.FieldSSOSharedWith)
 	}
+	if m.FieldCleared(oauth2client.FieldTheme) {
+		fields = append(fields, oauth2client.FieldTheme)
+	}
+	if m.FieldCleared(oauth2client.FieldRequireConsentOnClaimChange) {
+		fields = append(fields, oauth2client.FieldRequireConsentOnClaimChange)
+	}
+	if m.FieldCleared(oauth2client.FieldClientCredentials) {
+		fields = append(fields, oauth2client.FieldClientCredentials)
+	}
+	if m.FieldCleared(oauth2client.FieldJWTBearerIssuers) {
+		fields = append(fields, oauth2client.FieldJWTBearerIssuers)
+	}
+	if m.FieldCleared(oauth2client.FieldTokenExchange) {
+		fields = append(fields, oauth2client.FieldTokenExchange)
+	}
+	if m.FieldCleared(oauth2client.FieldResources) {
+		fields = append(fields, oauth2client.FieldResources)
+	}
+	if m.FieldCleared(oauth2client.FieldCustomClaims) {
+		fields = append(fields, oauth2client.FieldCustomClaims)
+	}
+	if m.FieldCleared(oauth2client.FieldRefreshTokenReuse) {
+		fields = append(fields, oauth2client.FieldRefreshTokenReuse)
+	}
+	if m.FieldCleared(oauth2client.FieldDeviceFlow) {
+		fields = append(fields, oauth2client.FieldDeviceFlow)
+	}
+	if m.FieldCleared(oauth2client.FieldAllowedOrigins) {
+		fields = append(fields, oauth2client.FieldAllowedOrigins)
+	}
+	if m.FieldCleared(oauth2client.FieldReleasedConnectorClaims) {
+		fields = append(fields, oauth2client.FieldReleasedConnectorClaims)
+	}
+	if m.FieldCleared(oauth2client.FieldGroupsClaim) {
+		fields = append(fields, oauth2client.FieldGroupsClaim)
+	}
+	if m.FieldCleared(oauth2client.FieldTerms) {
+		fields = append(fields, oauth2client.FieldTerms)
+	}
+	if m.FieldCleared(oauth2client.FieldImpersonation) {
+		fields = append(fields, oauth2client.FieldImpersonation)
+	}
 	return fields
 }
 
@@ -7397,6 +8700,48 @@ func (m *OAuth2ClientMutation) ClearField(name string) error {
 	case oauth2client.FieldSSOSharedWith:
 		m.ClearSSOSharedWith()
 		return nil
+	case oauth2client.FieldTheme:
+		m.ClearTheme()
+		return nil
+	case oauth2client.FieldRequireConsentOnClaimChange:
+		m.ClearRequireConsentOnClaimChange()
+		return nil
+	case oauth2client.FieldClientCredentials:
+		m.ClearClientCredentials()
+		return nil
+	case oauth2client.FieldJWTBearerIssuers:
+		m.ClearJWTBearerIssuers()
+		return nil
+	case oauth2client.FieldTokenExchange:
+		m.ClearTokenExchange()
+		return nil
+	case oauth2client.FieldResources:
+		m.ClearResources()
+		return nil
+	case oauth2client.FieldCustomClaims:
+		m.ClearCustomClaims()
+		return nil
+	case oauth2client.FieldRefreshTokenReuse:
+		m.ClearRefreshTokenReuse()
+		return nil
+	case oauth2client.FieldDeviceFlow:
+		m.ClearDeviceFlow()
+		return nil
+	case oauth2client.FieldAllowedOrigins:
+		m.ClearAllowedOrigins()
+		return nil
+	case oauth2client.FieldReleasedConnectorClaims:
+		m.ClearReleasedConnectorClaims()
+		return nil
+	case oauth2client.FieldGroupsClaim:
+		m.ClearGroupsClaim()
+		return nil
+	case oauth2client.FieldTerms:
+		m.ClearTerms()
+		return nil
+	case oauth2client.FieldImpersonation:
+		m.ClearImpersonation()
+		return nil
 	}
 	return fmt.Errorf("unknown OAuth2Client nullable field %s", name)
 }
@@ -7435,6 +8780,48 @@ func (m *OAuth2ClientMutation) ResetField(name string) error {
 	case oauth2client.FieldSSOSharedWith:
 		m.ResetSSOSharedWith()
 		return nil
+	case oauth2client.FieldTheme:
+		m.ResetTheme()
+		return nil
+	case oauth2client.FieldRequireConsentOnClaimChange:
+		m.ResetRequireConsentOnClaimChange()
+		return nil
+	case oauth2client.FieldClientCredentials:
+		m.ResetClientCredentials()
+		return nil
+	case oauth2client.FieldJWTBearerIssuers:
+		m.ResetJWTBearerIssuers()
+		return nil
+	case oauth2client.FieldTokenExchange:
+		m.ResetTokenExchange()
+		return nil
+	case oauth2client.FieldResources:
+		m.ResetResources()
+		return nil
+	case oauth2client.FieldCustomClaims:
+		m.ResetCustomClaims()
+		return nil
+	case oauth2client.FieldRefreshTokenReuse:
+		m.ResetRefreshTokenReuse()
+		return nil
+	case oauth2client.FieldDeviceFlow:
+		m.ResetDeviceFlow()
+		return nil
+	case oauth2client.FieldAllowedOrigins:
+		m.ResetAllowedOrigins()
+		return nil
+	case oauth2client.FieldReleasedConnectorClaims:
+		m.ResetReleasedConnectorClaims()
+		return nil
+	case oauth2client.FieldGroupsClaim:
+		m.ResetGroupsClaim()
+		return nil
+	case oauth2client.FieldTerms:
+		m.ResetTerms()
+		return nil
+	case oauth2client.FieldImpersonation:
+		m.ResetImpersonation()
+		return nil
 	}
 	return fmt.Errorf("unknown OAuth2Client field %s", name)
 }
@@ -8788,6 +10175,11 @@ type RefreshTokenMutation struct {
 	obsolete_token            *string
 	created_at                *time.Time
 	last_used                 *time.Time
+	resources                 *[]string
+	claims_custom             *map[string]interface{}
+	parent_id                 *string
+	audience                  *[]string
+	rotation_key              *string
 	clearedFields             map[string]struct{}
 	done                      bool
 	oldValue                  func(context.Context) (*RefreshToken, error)
@@ -9311,202 +10703,447 @@ func (m *RefreshTokenMutation) OldConnectorID(ctx context.Context) (v string, er
 	return oldValue.ConnectorID, nil
 }
 
-// ResetConnectorID resets all changes to the "connector_id" field.
-func (m *RefreshTokenMutation) ResetConnectorID() {
-	m.connector_id = nil
+// ResetConnectorID resets all changes to the "connector_id" field.
+func (m *RefreshTokenMutation) ResetConnectorID() {
+	m.connector_id = nil
+}
+
+// SetConnectorData sets the "connector_data" field.
+func (m *RefreshTokenMutation) SetConnectorData(b []byte) {
+	m.connector_data = &b
+}
+
+// ConnectorData returns the value of the "connector_data" field in the mutation.
+func (m *RefreshTokenMutation) ConnectorData() (r []byte, exists bool) {
+	v := m.connector_data
+	if v == nil {
+		return
+	}
+	return *v, true
+}
+
+// OldConnectorData returns the old "connector_data" field's value of the RefreshToken entity.
+// If the RefreshToken object wasn't provided to the builder, the object is fetched from the database.
+// An error is returned if the mutation operation is not UpdateOne, or the database query fails.
+func (m *RefreshTokenMutation) OldConnectorData(ctx context.Context) (v *[]byte, err error) {
+	if !m.op.Is(OpUpdateOne) {
+		return v, errors.New("OldConnectorData is only allowed on UpdateOne operations")
+	}
+	if m.id == nil || m.oldValue == nil {
+		return v, errors.New("OldConnectorData requires an ID field in the mutation")
+	}
+	oldValue, err := m.oldValue(ctx)
+	if err != nil {
+		return v, fmt.Errorf("querying old value for OldConnectorData: %w", err)
+	}
+	return oldValue.ConnectorData, nil
+}
+
+// ClearConnectorData clears the value of the "connector_data" field.
+func (m *RefreshTokenMutation) ClearConnectorData() {
+	m.connector_data = nil
+	m.clearedFields[refreshtoken.FieldConnectorData] = struct{}{}
+}
+
+// ConnectorDataCleared returns if the "connector_data" field was cleared in this mutation.
+func (m *RefreshTokenMutation) ConnectorDataCleared() bool {
+	_, ok := m.clearedFields[refreshtoken.FieldConnectorData]
+	return ok
+}
+
+// ResetConnectorData resets all changes to the "connector_data" field.
+func (m *RefreshTokenMutation) ResetConnectorData() {
+	m.connector_data = nil
+	delete(m.clearedFields, refreshtoken.FieldConnectorData)
+}
+
+// SetToken sets the "token" field.
+func (m *RefreshTokenMutation) SetToken(s string) {
+	m.token = &s
+}
+
+// Token returns the value of the "token" field in the mutation.
+func (m *RefreshTokenMutation) Token() (r string, exists bool) {
+	v := m.token
+	if v == nil {
+		return
+	}
+	return *v, true
+}
+
+// OldToken returns the old "token" field's value of the RefreshToken entity.
+// If the RefreshToken object wasn't provided to the builder, the object is fetched from the database.
+// An error is returned if the mutation operation is not UpdateOne, or the database query fails.
+func (m *RefreshTokenMutation) OldToken(ctx context.Context) (v string, err error) {
+	if !m.op.Is(OpUpdateOne) {
+		return v, errors.New("OldToken is only allowed on UpdateOne operations")
+	}
+	if m.id == nil || m.oldValue == nil {
+		return v, errors.New("OldToken requires an ID field in the mutation")
+	}
+	oldValue, err := m.oldValue(ctx)
+	if err != nil {
+		return v, fmt.Errorf("querying old value for OldToken: %w", err)
+	}
+	return oldValue.Token, nil
+}
+
+// ResetToken resets all changes to the "token" field.
+func (m *RefreshTokenMutation) ResetToken() {
+	m.token = nil
+}
+
+// SetObsoleteToken sets the "obsolete_token" field.
+func (m *RefreshTokenMutation) SetObsoleteToken(s string) {
+	m.obsolete_token = &s
+}
+
+// ObsoleteToken returns the value of the "obsolete_token" field in the mutation.
+func (m *RefreshTokenMutation) ObsoleteToken() (r string, exists bool) {
+	v := m.obsolete_token
+	if v == nil {
+		return
+	}
+	return *v, true
+}
+
+// OldObsoleteToken returns the old "obsolete_token" field's value of the RefreshToken entity.
+// If the RefreshToken object wasn't provided to the builder, the object is fetched from the database.
+// An error is returned if the mutation operation is not UpdateOne, or the database query fails.
+func (m *RefreshTokenMutation) OldObsoleteToken(ctx context.Context) (v string, err error) {
+	if !m.op.Is(OpUpdateOne) {
+		return v, errors.New("OldObsoleteToken is only allowed on UpdateOne operations")
+	}
+	if m.id == nil || m.oldValue == nil {
+		return v, errors.New("OldObsoleteToken requires an ID field in the mutation")
+	}
+	oldValue, err := m.oldValue(ctx)
+	if err != nil {
+		return v, fmt.Errorf("querying old value for OldObsoleteToken: %w", err)
+	}
+	return oldValue.ObsoleteToken, nil
+}
+
+// ResetObsoleteToken resets all changes to the "obsolete_token" field.
+func (m *RefreshTokenMutation) ResetObsoleteToken() {
+	m.obsolete_token = nil
+}
+
+// SetCreatedAt sets the "created_at" field.
+func (m *RefreshTokenMutation) SetCreatedAt(t time.Time) {
+	m.created_at = &t
+}
+
+// CreatedAt returns the value of the "created_at" field in the mutation.
+func (m *RefreshTokenMutation) CreatedAt() (r time.Time, exists bool) {
+	v := m.created_at
+	if v == nil {
+		return
+	}
+	return *v, true
+}
+
+// OldCreatedAt returns the old "created_at" field's value of the RefreshToken entity.
+// If the RefreshToken object wasn't provided to the builder, the object is fetched from the database.
+// An error is returned if the mutation operation is not UpdateOne, or the database query fails.
+func (m *RefreshTokenMutation) OldCreatedAt(ctx context.Context) (v time.Time, err error) {
+	if !m.op.Is(OpUpdateOne) {
+		return v, errors.New("OldCreatedAt is only allowed on UpdateOne operations")
+	}
+	if m.id == nil || m.oldValue == nil {
+		return v, errors.New("OldCreatedAt requires an ID field in the mutation")
+	}
+	oldValue, err := m.oldValue(ctx)
+	if err != nil {
+		return v, fmt.Errorf("querying old value for OldCreatedAt: %w", err)
+	}
+	return oldValue.CreatedAt, nil
+}
+
+// ResetCreatedAt resets all changes to the "created_at" field.
+func (m *RefreshTokenMutation) ResetCreatedAt() {
+	m.created_at = nil
+}
+
+// SetLastUsed sets the "last_used" field.
+func (m *RefreshTokenMutation) SetLastUsed(t time.Time) {
+	m.last_used = &t
+}
+
+// LastUsed returns the value of the "last_used" field in the mutation.
+func (m *RefreshTokenMutation) LastUsed() (r time.Time, exists bool) {
+	v := m.last_used
+	if v == nil {
+		return
+	}
+	return *v, true
+}
+
+// OldLastUsed returns the old "last_used" field's value of the RefreshToken entity.
+// If the RefreshToken object wasn't provided to the builder, the object is fetched from the database.
+// An error is returned if the mutation operation is not UpdateOne, or the database query fails.
+func (m *RefreshTokenMutation) OldLastUsed(ctx context.Context) (v time.Time, err error) {
+	if !m.op.Is(OpUpdateOne) {
+		return v, errors.New("OldLastUsed is only allowed on UpdateOne operations")
+	}
+	if m.id == nil || m.oldValue == nil {
+		return v, errors.New("OldLastUsed requires an ID field in the mutation")
+	}
+	oldValue, err := m.oldValue(ctx)
+	if err != nil {
+		return v, fmt.Errorf("querying old value for OldLastUsed: %w", err)
+	}
+	return oldValue.LastUsed, nil
+}
+
+// ResetLastUsed resets all changes to the "last_used" field.
+func (m *RefreshTokenMutation) ResetLastUsed() {
+	m.last_used = nil
 }
 
-// SetConnectorData sets the "connector_data" field.
-func (m *RefreshTokenMutation) SetConnectorData(b []byte) {
-	m.connector_data = &b
+// SetResources sets the "resources" field.
+func (m *RefreshTokenMutation) SetResources(v []string) {
+	m.resources = &v
 }
 
-// ConnectorData returns the value of the "connector_data" field in the mutation.
-func (m *RefreshTokenMutation) ConnectorData() (r []byte, exists bool) {
-	v := m.connector_data
+// Resources returns the value of the "resources" field in the mutation.
+func (m *RefreshTokenMutation) Resources() (r []string, exists bool) {
+	v := m.resources
 	if v == nil {
 		return
 	}
 	return *v, true
 }
 
-// OldConnectorData returns the old "connector_data" field's value of the RefreshToken entity.
+// OldResources returns the old "resources" field's value of the RefreshToken entity.
 // If the RefreshToken object wasn't provided to the builder, the object is fetched from the database.
 // An error is returned if the mutation operation is not UpdateOne, or the database query fails.
-func (m *RefreshTokenMutation) OldConnectorData(ctx context.Context) (v *[]byte, err error) {
+func (m *RefreshTokenMutation) OldResources(ctx context.Context) (v []string, err error) {
 	if !m.op.Is(OpUpdateOne) {
-		return v, errors.New("OldConnectorData is only allowed on UpdateOne operations")
+		return v, errors.New("OldResources is only allowed on UpdateOne operations")
 	}
 	if m.id == nil || m.oldValue == nil {
-		return v, errors.New("OldConnectorData requires an ID field in the mutation")
+		return v, errors.New("OldResources requires an ID field in the mutation")
 	}
 	oldValue, err := m.oldValue(ctx)
 	if err != nil {
-		return v, fmt.Errorf("querying old value for OldConnectorData: %w", err)
+		return v, fmt.Errorf("querying old value for OldResources: %w", err)
 	}
-	return oldValue.ConnectorData, nil
+	return oldValue.Resources, nil
 }
 
-// ClearConnectorData clears the value of the "connector_data" field.
-func (m *RefreshTokenMutation) ClearConnectorData() {
-	m.connector_data = nil
-	m.clearedFields[refreshtoken.FieldConnectorData] = struct{}{}
+// ClearResources clears the value of the "resources" field.
+func (m *RefreshTokenMutation) ClearResources() {
+	m.resources = nil
+	m.clearedFields[refreshtoken.FieldResources] = struct{}{}
 }
 
-// ConnectorDataCleared returns if the "connector_data" field was cleared in this mutation.
-func (m *RefreshTokenMutation) ConnectorDataCleared() bool {
-	_, ok := m.clearedFields[refreshtoken.FieldConnectorData]
+// ResourcesCleared returns if the "resources" field was cleared in this mutation.
+func (m *RefreshTokenMutation) ResourcesCleared() bool {
+	_, ok := m.clearedFields[refreshtoken.FieldResources]
 	return ok
 }
 
-// ResetConnectorData resets all changes to the "connector_data" field.
-func (m *RefreshTokenMutation) ResetConnectorData() {
-	m.connector_data = nil
-	delete(m.clearedFields, refreshtoken.FieldConnectorData)
+// ResetResources resets all changes to the "resources" field.
+func (m *RefreshTokenMutation) ResetResources() {
+	m.resources = nil
+	delete(m.clearedFields, refreshtoken.FieldResources)
 }
 
-// SetToken sets the "token" field.
-func (m *RefreshTokenMutation) SetToken(s string) {
-	m.token = &s
+// SetClaimsCustom sets the "claims_custom" field.
+func (m *RefreshTokenMutation) SetClaimsCustom(v map[string]interface{}) {
+	m.claims_custom = &v
 }
 
-// Token returns the value of the "token" field in the mutation.
-func (m *RefreshTokenMutation) Token() (r string, exists bool) {
-	v := m.token
+// ClaimsCustom returns the value of the "claims_custom" field in the mutation.
+func (m *RefreshTokenMutation) ClaimsCustom() (r map[string]interface{}, exists bool) {
+	v := m.claims_custom
 	if v == nil {
 		return
 	}
 	return *v, true
 }
 
-// OldToken returns the old "token" field's value of the RefreshToken entity.
+// OldClaimsCustom returns the old "claims_custom" field's value of the RefreshToken entity.
 // If the RefreshToken object wasn't provided to the builder, the object is fetched from the database.
 // An error is returned if the mutation operation is not UpdateOne, or the database query fails.
-func (m *RefreshTokenMutation) OldToken(ctx context.Context) (v string, err error) {
+func (m *RefreshTokenMutation) OldClaimsCustom(ctx context.Context) (v map[string]interface{}, err error) {
 	if !m.op.Is(OpUpdateOne) {
-		return v, errors.New("OldToken is only allowed on UpdateOne operations")
+		return v, errors.New("OldClaimsCustom is only allowed on UpdateOne operations")
 	}
 	if m.id == nil || m.oldValue == nil {
-		return v, errors.New("OldToken requires an ID field in the mutation")
+		return v, errors.New("OldClaimsCustom requires an ID field in the mutation")
 	}
 	oldValue, err := m.oldValue(ctx)
 	if err != nil {
-		return v, fmt.Errorf("querying old value for OldToken: %w", err)
+		return v, fmt.Errorf("querying old value for OldClaimsCustom: %w", err)
 	}
-	return oldValue.Token, nil
+	return oldValue.ClaimsCustom, nil
 }
 
-// ResetToken resets all changes to the "token" field.
-func (m *RefreshTokenMutation) ResetToken() {
-	m.token = nil
+// ClearClaimsCustom clears the value of the "claims_custom" field.
+func (m *RefreshTokenMutation) ClearClaimsCustom() {
+	m.claims_custom = nil
+	m.clearedFields[refreshtoken.FieldClaimsCustom] = struct{}{}
 }
 
-// SetObsoleteToken sets the "obsolete_token" field.
-func (m *RefreshTokenMutation) SetObsoleteToken(s string) {
-	m.obsolete_token = &s
+// ClaimsCustomCleared returns if the "claims_custom" field was cleared in this mutation.
+func (m *RefreshTokenMutation) ClaimsCustomCleared() bool {
+	_, ok := m.clearedFields[refreshtoken.FieldClaimsCustom]
+	return ok
 }
 
-// ObsoleteToken returns the value of the "obsolete_token" field in the mutation.
-func (m *RefreshTokenMutation) ObsoleteToken() (r string, exists bool) {
-	v := m.obsolete_token
+// ResetClaimsCustom resets all changes to the "claims_custom" field.
+func (m *RefreshTokenMutation) ResetClaimsCustom() {
+	m.claims_custom = nil
+	delete(m.clearedFields, refreshtoken.FieldClaimsCustom)
+}
+
+// SetParentID sets the "parent_id" field.
+func (m *RefreshTokenMutation) SetParentID(s string) {
+	m.parent_id = &s
+}
+
+// ParentID returns the value of the "parent_id" field in the mutation.
+func (m *RefreshTokenMutation) ParentID() (r string, exists bool) {
+	v := m.parent_id
 	if v == nil {
 		return
 	}
 	return *v, true
 }
 
-// OldObsoleteToken returns the old "obsolete_token" field's value of the RefreshToken entity.
+// OldParentID returns the old "parent_id" field's value of the RefreshToken entity.
 // If the RefreshToken object wasn't provided to the builder, the object is fetched from the database.
 // An error is returned if the mutation operation is not UpdateOne, or the database query fails.
-func (m *RefreshTokenMutation) OldObsoleteToken(ctx context.Context) (v string, err error) {
+func (m *RefreshTokenMutation) OldParentID(ctx context.Context) (v string, err error) {
 	if !m.op.Is(OpUpdateOne) {
-		return v, errors.New("OldObsoleteToken is only allowed on UpdateOne operations")
+		return v, errors.New("OldParentID is only allowed on UpdateOne operations")
 	}
 	if m.id == nil || m.oldValue == nil {
-		return v, errors.New("OldObsoleteToken requires an ID field in the mutation")
+		return v, errors.New("OldParentID requires an ID field in the mutation")
 	}
 	oldValue, err := m.oldValue(ctx)
 	if err != nil {
-		return v, fmt.Errorf("querying old value for OldObsoleteToken: %w", err)
+		return v, fmt.Errorf("querying old value for OldParentID: %w", err)
 	}
-	return oldValue.ObsoleteToken, nil
+	return oldValue.ParentID, nil
 }
 
-// ResetObsoleteToken resets all changes to the "obsolete_token" field.
-func (m *RefreshTokenMutation) ResetObsoleteToken() {
-	m.obsolete_token = nil
+// ClearParentID clears the value of the "parent_id" field.
+func (m *RefreshTokenMutation) ClearParentID() {
+	m.parent_id = nil
+	m.clearedFields[refreshtoken.FieldParentID] = struct{}{}
 }
 
-// SetCreatedAt sets the "created_at" field.
-func (m *RefreshTokenMutation) SetCreatedAt(t time.Time) {
-	m.created_at = &t
+// ParentIDCleared returns if the "parent_id" field was cleared in this mutation.
+func (m *RefreshTokenMutation) ParentIDCleared() bool {
+	_, ok := m.clearedFields[refreshtoken.FieldParentID]
+	return ok
 }
 
-// CreatedAt returns the value of the "created_at" field in the mutation.
-func (m *RefreshTokenMutation) CreatedAt() (r time.Time, exists bool) {
-	v := m.created_at
+// ResetParentID resets all changes to the "parent_id" field.
+func (m *RefreshTokenMutation) ResetParentID() {
+	m.parent_id = nil
+	delete(m.clearedFields, refreshtoken.FieldParentID)
+}
+
+// SetAudience sets the "audience" field.
+func (m *RefreshTokenMutation) SetAudience(v []string) {
+	m.audience = &v
+}
+
+// Audience returns the value of the "audience" field in the mutation.
+func (m *RefreshTokenMutation) Audience() (r []string, exists bool) {
+	v := m.audience
 	if v == nil {
 		return
 	}
 	return *v, true
 }
 
-// OldCreatedAt returns the old "created_at" field's value of the RefreshToken entity.
+// OldAudience returns the old "audience" field's value of the RefreshToken entity.
 // If the RefreshToken object wasn't provided to the builder, the object is fetched from the database.
 // An error is returned if the mutation operation is not UpdateOne, or the database query fails.
-func (m *RefreshTokenMutation) OldCreatedAt(ctx context.Context) (v time.Time, err error) {
+func (m *RefreshTokenMutation) OldAudience(ctx context.Context) (v []string, err error) {
 	if !m.op.Is(OpUpdateOne) {
-		return v, errors.New("OldCreatedAt is only allowed on UpdateOne operations")
+		return v, errors.New("OldAudience is only allowed on UpdateOne operations")
 	}
 	if m.id == nil || m.oldValue == nil {
-		return v, errors.New("OldCreatedAt requires an ID field in the mutation")
+		return v, errors.New("OldAudience requires an ID field in the mutation")
 	}
 	oldValue, err := m.oldValue(ctx)
 	if err != nil {
-		return v, fmt.Errorf("querying old value for OldCreatedAt: %w", err)
+		return v, fmt.Errorf("querying old value for OldAudience: %w", err)
 	}
-	return oldValue.CreatedAt, nil
+	return oldValue.Audience, nil
 }
 
-// ResetCreatedAt resets all changes to the "created_at" field.
-func (m *RefreshTokenMutation) ResetCreatedAt() {
-	m.created_at = nil
+// ClearAudience clears the value of the "audience" field.
+func (m *RefreshTokenMutation) ClearAudience() {
+	m.audience = nil
+	m.clearedFields[refreshtoken.FieldAudience] = struct{}{}
 }
 
-// SetLastUsed sets the "last_used" field.
-func (m *RefreshTokenMutation) SetLastUsed(t time.Time) {
-	m.last_used = &t
+// AudienceCleared returns if the "audience" field was cleared in this mutation.
+func (m *RefreshTokenMutation) AudienceCleared() bool {
+	_, ok := m.clearedFields[refreshtoken.FieldAudience]
+	return ok
 }
 
-// LastUsed returns the value of the "last_used" field in the mutation.
-func (m *RefreshTokenMutation) LastUsed() (r time.Time, exists bool) {
-	v := m.last_used
+// ResetAudience resets all changes to the "audience" field.
+func (m *RefreshTokenMutation) ResetAudience() {
+	m.audience = nil
+	delete(m.clearedFields, refreshtoken.FieldAudience)
+}
+
+// SetRotationKey sets the "rotation_key" field.
+func (m *RefreshTokenMutation) SetRotationKey(s string) {
+	m.rotation_key = &s
+}
+
+// RotationKey returns the value of the "rotation_key" field in the mutation.
+func (m *RefreshTokenMutation) RotationKey() (r string, exists bool) {
+	v := m.rotation_key
 	if v == nil {
 		return
 	}
 	return *v, true
 }
 
-// OldLastUsed returns the old "last_used" field's value of the RefreshToken entity.
+// OldRotationKey returns the old "rotation_key" field's value of the RefreshToken entity.
 // If the RefreshToken object wasn't provided to the builder, the object is fetched from the database.
 // An error is returned if the mutation operation is not UpdateOne, or the database query fails.
-func (m *RefreshTokenMutation) OldLastUsed(ctx context.Context) (v time.Time, err error) {
+func (m *RefreshTokenMutation) OldRotationKey(ctx context.Context) (v string, err error) {
 	if !m.op.Is(OpUpdateOne) {
-		return v, errors.New("OldLastUsed is only allowed on UpdateOne operations")
+		return v, errors.New("OldRotationKey is only allowed on UpdateOne operations")
 	}
 	if m.id == nil || m.oldValue == nil {
-		return v, errors.New("OldLastUsed requires an ID field in the mutation")
+		return v, errors.New("OldRotationKey requires an ID field in the mutation")
 	}
 	oldValue, err := m.oldValue(ctx)
 	if err != nil {
-		return v, fmt.Errorf("querying old value for OldLastUsed: %w", err)
+		return v, fmt.Errorf("querying old value for OldRotationKey: %w", err)
 	}
-	return oldValue.LastUsed, nil
+	return oldValue.RotationKey, nil
 }
 
-// ResetLastUsed resets all changes to the "last_used" field.
-func (m *RefreshTokenMutation) ResetLastUsed() {
-	m.last_used = nil
+// ClearRotationKey clears the value of the "rotation_key" field.
+func (m *RefreshTokenMutation) ClearRotationKey() {
+	m.rotation_key = nil
+	m.clearedFields[refreshtoken.FieldRotationKey] = struct{}{}
+}
+
+// RotationKeyCleared returns if the "rotation_key" field was cleared in this mutation.
+func (m *RefreshTokenMutation) RotationKeyCleared() bool {
+	_, ok := m.clearedFields[refreshtoken.FieldRotationKey]
+	return ok
+}
+
+// ResetRotationKey resets all changes to the "rotation_key" field.
+func (m *RefreshTokenMutation) ResetRotationKey() {
+	m.rotation_key = nil
+	delete(m.clearedFields, refreshtoken.FieldRotationKey)
 }
 
 // Where appends a list predicates to the RefreshTokenMutation builder.
@@ -9543,7 +11180,7 @@ func (m *RefreshTokenMutation) Type() string {
 // order to get all numeric fields that were incremented/decremented, call
 // AddedFields().
 func (m *RefreshTokenMutation) Fields() []string {
-	fields := make([]string, 0, 15)
+	fields := make([]string, 0, 20)
 	if m.client_id != nil {
 		fields = append(fields, refreshtoken.FieldClientID)
 	}
@@ -9589,6 +11226,21 @@ func (m *RefreshTokenMutation) Fields() []string {
 	if m.last_used != nil {
 		fields = append(fields, refreshtoken.FieldLastUsed)
 	}
+	if m.resources != nil {
+		fields = append(fields, refreshtoken.FieldResources)
+	}
+	if m.claims_custom != nil {
+		fields = append(fields, refreshtoken.FieldClaimsCustom)
+	}
+	if m.parent_id != nil {
+		fields = append(fields, refreshtoken.FieldParentID)
+	}
+	if m.audience != nil {
+		fields = append(fields, refreshtoken.FieldAudience)
+	}
+	if m.rotation_key != nil {
+		fields = append(fields, refreshtoken.FieldRotationKey)
+	}
 	return fields
 }
 
@@ -9627,6 +11279,16 @@ func (m *RefreshTokenMutation) Field(name string) (ent.Value, bool) {
 		return m.CreatedAt()
 	case refreshtoken.FieldLastUsed:
 		return m.LastUsed()
+	case refreshtoken.FieldResources:
+		return m.Resources()
+	case refreshtoken.FieldClaimsCustom:
+		return m.ClaimsCustom()
+	case refreshtoken.FieldParentID:
+		return m.ParentID()
+	case refreshtoken.FieldAudience:
+		return m.Audience()
+	case refreshtoken.FieldRotationKey:
+		return m.RotationKey()
 	}
 	return nil, false
 }
@@ -9666,6 +11328,16 @@ func (m *RefreshTokenMutation) OldField(ctx context.Context, name string) (ent.V
 		return m.OldCreatedAt(ctx)
 	case refreshtoken.FieldLastUsed:
 		return m.OldLastUsed(ctx)
+	case refreshtoken.FieldResources:
+		return m.OldResources(ctx)
+	case refreshtoken.FieldClaimsCustom:
+		return m.OldClaimsCustom(ctx)
+	case refreshtoken.FieldParentID:
+		return m.OldParentID(ctx)
+	case refreshtoken.FieldAudience:
+		return m.OldAudience(ctx)
+	case refreshtoken.FieldRotationKey:
+		return m.OldRotationKey(ctx)
 	}
 	return nil, fmt.Errorf("unknown RefreshToken field %s", name)
 }
@@ -9780,6 +11452,41 @@ func (m *RefreshTokenMutation) SetField(name string, value ent.Value) error {
 		}
 		m.SetLastUsed(v)
 		return nil
+	case refreshtoken.FieldResources:
+		v, ok := value.([]string)
+		if !ok {
+			return fmt.Errorf("unexpected type %T for field %s", value, name)
+		}
+		m.SetResources(v)
+		return nil
+	case refreshtoken.FieldClaimsCustom:
+		v, ok := value.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("unexpected type %T for field %s", value, name)
+		}
+		m.SetClaimsCustom(v)
+		return nil
+	case refreshtoken.FieldParentID:
+		v, ok := value.(string)
+		if !ok {
+			return fmt.Errorf("unexpected type %T for field %s", value, name)
+		}
+		m.SetParentID(v)
+		return nil
+	case refreshtoken.FieldAudience:
+		v, ok := value.([]string)
+		if !ok {
+			return fmt.Errorf("unexpected type %T for field %s", value, name)
+		}
+		m.SetAudience(v)
+		return nil
+	case refreshtoken.FieldRotationKey:
+		v, ok := value.(string)
+		if !ok {
+			return fmt.Errorf("unexpected type %T for field %s", value, name)
+		}
+		m.SetRotationKey(v)
+		return nil
 	}
 	return fmt.Errorf("unknown RefreshToken field %s", name)
 }
@@ -9819,6 +11526,21 @@ func (m *RefreshTokenMutation) ClearedFields() []string {
 	if m.FieldCleared(refreshtoken.FieldConnectorData) {
 		fields = append(fields, refreshtoken.FieldConnectorData)
 	}
+	if m.FieldCleared(refreshtoken.FieldResources) {
+		fields = append(fields, refreshtoken.FieldResources)
+	}
+	if m.FieldCleared(refreshtoken.FieldClaimsCustom) {
+		fields = append(fields, refreshtoken.FieldClaimsCustom)
+	}
+	if m.FieldCleared(refreshtoken.FieldParentID) {
+		fields = append(fields, refreshtoken.FieldParentID)
+	}
+	if m.FieldCleared(refreshtoken.FieldAudience) {
+		fields = append(fields, refreshtoken.FieldAudience)
+	}
+	if m.FieldCleared(refreshtoken.FieldRotationKey) {
+		fields = append(fields, refreshtoken.FieldRotationKey)
+	}
 	return fields
 }
 
@@ -9842,6 +11564,21 @@ func (m *RefreshTokenMutation) ClearField(name string) error {
 	case refreshtoken.FieldConnectorData:
 		m.ClearConnectorData()
 		return nil
+	case refreshtoken.FieldResources:
+		m.ClearResources()
+		return nil
+	case refreshtoken.FieldClaimsCustom:
+		m.ClearClaimsCustom()
+		return nil
+	case refreshtoken.FieldParentID:
+		m.ClearParentID()
+		return nil
+	case refreshtoken.FieldAudience:
+		m.ClearAudience()
+		return nil
+	case refreshtoken.FieldRotationKey:
+		m.ClearRotationKey()
+		return nil
 	}
 	return fmt.Errorf("unknown RefreshToken nullable field %s", name)
 }
@@ -9895,6 +11632,21 @@ func (m *RefreshTokenMutation) ResetField(name string) error {
 	case refreshtoken.FieldLastUsed:
 		m.ResetLastUsed()
 		return nil
+	case refreshtoken.FieldResources:
+		m.ResetResources()
+		return nil
+	case refreshtoken.FieldClaimsCustom:
+		m.ResetClaimsCustom()
+		return nil
+	case refreshtoken.FieldParentID:
+		m.ResetParentID()
+		return nil
+	case refreshtoken.FieldAudience:
+		m.ResetAudience()
+		return nil
+	case refreshtoken.FieldRotationKey:
+		m.ResetRotationKey()
+		return nil
 	}
 	return fmt.Errorf("unknown RefreshToken field %s", name)
 }
@@ -9968,6 +11720,9 @@ type UserIdentityMutation struct {
 	created_at                *time.Time
 	last_login                *time.Time
 	blocked_until             *time.Time
+	consented_claims          *map[string]string
+	claims_custom             *map[string]interface{}
+	accepted_terms            *map[string]storage.TermsAcceptance
 	clearedFields             map[string]struct{}
 	done                      bool
 	oldValue                  func(context.Context) (*UserIdentity, error)
@@ -10637,6 +12392,153 @@ func (m *UserIdentityMutation) ResetBlockedUntil() {
 	m.blocked_until = nil
 }
 
+// SetConsentedClaims sets the "consented_claims" field.
+func (m *UserIdentityMutation) SetConsentedClaims(v map[string]string) {
+	m.consented_claims = &v
+}
+
+// ConsentedClaims returns the value of the "consented_claims" field in the mutation.
+func (m *UserIdentityMutation) ConsentedClaims() (r map[string]string, exists bool) {
+	v := m.consented_claims
+	if v == nil {
+		return
+	}
+	return *v, true
+}
+
+// OldConsentedClaims returns the old "consented_claims" field's value of the UserIdentity entity.
+// If the UserIdentity object wasn't provided to the builder, the object is fetched from the database.
+// An error is returned if the mutation operation is not UpdateOne, or the database query fails.
+func (m *UserIdentityMutation) OldConsentedClaims(ctx context.Context) (v map[string]string, err error) {
+	if !m.op.Is(OpUpdateOne) {
+		return v, errors.New("OldConsentedClaims is only allowed on UpdateOne operations")
+	}
+	if m.id == nil || m.oldValue == nil {
+		return v, errors.New("OldConsentedClaims requires an ID field in the mutation")
+	}
+	oldValue, err := m.oldValue(ctx)
+	if err != nil {
+		return v, fmt.Errorf("querying old value for OldConsentedClaims: %w", err)
+	}
+	return oldValue.ConsentedClaims, nil
+}
+
+// ClearConsentedClaims clears the value of the "consented_claims" field.
+func (m *UserIdentityMutation) ClearConsentedClaims() {
+	m.consented_claims = nil
+	m.clearedFields[useridentity.FieldConsentedClaims] = struct{}{}
+}
+
+// ConsentedClaimsCleared returns if the "consented_claims" field was cleared in this mutation.
+func (m *UserIdentityMutation) ConsentedClaimsCleared() bool {
+	_, ok := m.clearedFields[useridentity.FieldConsentedClaims]
+	return ok
+}
+
+// ResetConsentedClaims resets all changes to the "consented_claims" field.
+func (m *UserIdentityMutation) ResetConsentedClaims() {
+	m.consented_claims = nil
+	delete(m.clearedFields, useridentity.FieldConsentedClaims)
+}
+
+// SetClaimsCustom sets the "claims_custom" field.
+func (m *UserIdentityMutation) SetClaimsCustom(v map[string]interface{}) {
+	m.claims_custom = &v
+}
+
+// ClaimsCustom returns the value of the "claims_custom" field in the mutation.
+func (m *UserIdentityMutation) ClaimsCustom() (r map[string]interface{}, exists bool) {
+	v := m.claims_custom
+	if v == nil {
+		return
+	}
+	return *v, true
+}
+
+// OldClaimsCustom returns the old "claims_custom" field's value of the UserIdentity entity.
+// If the UserIdentity object wasn't provided to the builder, the object is fetched from the database.
+// An error is returned if the mutation operation is not UpdateOne, or the database query fails.
+func (m *UserIdentityMutation) OldClaimsCustom(ctx context.Context) (v map[string]interface{}, err error) {
+	if !m.op.Is(OpUpdateOne) {
+		return v, errors.New("OldClaimsCustom is only allowed on UpdateOne operations")
+	}
+	if m.id == nil || m.oldValue == nil {
+		return v, errors.New("OldClaimsCustom requires an ID field in the mutation")
+	}
+	oldValue, err := m.oldValue(ctx)
+	if err != nil {
+		return v, fmt.Errorf("querying old value for OldClaimsCustom: %w", err)
+	}
+	return oldValue.ClaimsCustom, nil
+}
+
+// ClearClaimsCustom clears the value of the "claims_custom" field.
+func (m *UserIdentityMutation) ClearClaimsCustom() {
+	m.claims_custom = nil
+	m.clearedFields[useridentity.FieldClaimsCustom] = struct{}{}
+}
+
+// ClaimsCustomCleared returns if the "claims_custom" field was cleared in this mutation.
+func (m *UserIdentityMutation) ClaimsCustomCleared() bool {
+	_, ok := m.clearedFields[useridentity.FieldClaimsCustom]
+	return ok
+}
+
+// ResetClaimsCustom resets all changes to the "claims_custom" field.
+func (m *UserIdentityMutation) ResetClaimsCustom() {
+	m.claims_custom = nil
+	delete(m.clearedFields, useridentity.FieldClaimsCustom)
+}
+
+// SetAcceptedTerms sets the "accepted_terms" field.
+func (m *UserIdentityMutation) SetAcceptedTerms(v map[string]storage.TermsAcceptance) {
+	m.accepted_terms = &v
+}
+
+// AcceptedTerms returns the value of the "accepted_terms" field in the mutation.
+func (m *UserIdentityMutation) AcceptedTerms() (r map[string]storage.TermsAcceptance, exists bool) {
+	v := m.accepted_terms
+	if v == nil {
+		return
+	}
+	return *v, true
+}
+
+// OldAcceptedTerms returns the old "accepted_terms" field's value of the UserIdentity entity.
+// If the UserIdentity object wasn't provided to the builder, the object is fetched from the database.
+// An error is returned if the mutation operation is not UpdateOne, or the database query fails.
+func (m *UserIdentityMutation) OldAcceptedTerms(ctx context.Context) (v map[string]storage.TermsAcceptance, err error) {
+	if !m.op.Is(OpUpdateOne) {
+		return v, errors.New("OldAcceptedTerms is only allowed on UpdateOne operations")
+	}
+	if m.id == nil || m.oldValue == nil {
+		return v, errors.New("OldAcceptedTerms requires an ID field in the mutation")
+	}
+	oldValue, err := m.oldValue(ctx)
+	if err != nil {
+		return v, fmt.Errorf("querying old value for OldAcceptedTerms: %w", err)
+	}
+	return oldValue.AcceptedTerms, nil
+}
+
+// ClearAcceptedTerms clears the value of the "accepted_terms" field.
+func (m *UserIdentityMutation) ClearAcceptedTerms() {
+	m.accepted_terms = nil
+	m.clearedFields[useridentity.FieldAcceptedTerms] = struct{}{}
+}
+
+// AcceptedTermsCleared returns if the "accepted_terms" field was cleared in this mutation.
+func (m *UserIdentityMutation) AcceptedTermsCleared() bool {
+	_, ok := m.clearedFields[useridentity.FieldAcceptedTerms]
+	return ok
+}
+
+// ResetAcceptedTerms resets all changes to the "accepted_terms" field.
+func (m *UserIdentityMutation) ResetAcceptedTerms() {
+	m.accepted_terms = nil
+	delete(m.clearedFields, useridentity.FieldAcceptedTerms)
+}
+
 // Where appends a list predicates to the UserIdentityMutation builder.
 func (m *UserIdentityMutation) Where(ps ...predicate.UserIdentity) {
 	m.predicates = append(m.predicates, ps...)
@@ -10671,7 +12573,7 @@ func (m *UserIdentityMutation) Type() string {
 // order to get all numeric fields that were incremented/decremented, call
 // AddedFields().
 func (m *UserIdentityMutation) Fields() []string {
-	fields := make([]string, 0, 14)
+	fields := make([]string, 0, 17)
 	if m.user_id != nil {
 		fields = append(fields, useridentity.FieldUserID)
 	}
@@ -10714,6 +12616,15 @@ func (m *UserIdentityMutation) Fields() []string {
 	if m.blocked_until != nil {
 		fields = append(fields, useridentity.FieldBlockedUntil)
 	}
+	if m.consented_claims != nil {
+		fields = append(fields, useridentity.FieldConsentedClaims)
+	}
+	if m.claims_custom != nil {
+		fields = append(fields, useridentity.FieldClaimsCustom)
+	}
+	if m.accepted_terms != nil {
+		fields = append(fields, useridentity.FieldAcceptedTerms)
+	}
 	return fields
 }
 
@@ -10750,6 +12661,12 @@ func (m *UserIdentityMutation) Field(name string) (ent.Value, bool) {
 		return m.LastLogin()
 	case useridentity.FieldBlockedUntil:
 		return m.BlockedUntil()
+	case useridentity.FieldConsentedClaims:
+		return m.ConsentedClaims()
+	case useridentity.FieldClaimsCustom:
+		return m.ClaimsCustom()
+	case useridentity.FieldAcceptedTerms:
+		return m.AcceptedTerms()
 	}
 	return nil, false
 }
@@ -10787,6 +12704,12 @@ func (m *UserIdentityMutation) OldField(ctx context.Context, name string) (ent.V
 		return m.OldLastLogin(ctx)
 	case useridentity.FieldBlockedUntil:
 		return m.OldBlockedUntil(ctx)
+	case useridentity.FieldConsentedClaims:
+		return m.OldConsentedClaims(ctx)
+	case useridentity.FieldClaimsCustom:
+		return m.OldClaimsCustom(ctx)
+	case useridentity.FieldAcceptedTerms:
+		return m.OldAcceptedTerms(ctx)
 	}
 	return nil, fmt.Errorf("unknown UserIdentity field %s", name)
 }
@@ -10894,6 +12817,27 @@ func (m *UserIdentityMutation) SetField(name string, value ent.Value) error {
 		}
 		m.SetBlockedUntil(v)
 		return nil
+	case useridentity.FieldConsentedClaims:
+		v, ok := value.(map[string]string)
+		if !ok {
+			return fmt.Errorf("unexpected type %T for field %s", value, name)
+		}
+		m.SetConsentedClaims(v)
+		return nil
+	case useridentity.FieldClaimsCustom:
+		v, ok := value.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("unexpected type %T for field %s", value, name)
+		}
+		m.SetClaimsCustom(v)
+		return nil
+	case useridentity.FieldAcceptedTerms:
+		v, ok := value.(map[string]storage.TermsAcceptance)
+		if !ok {
+			return fmt.Errorf("unexpected type %T for field %s", value, name)
+		}
+		m.SetAcceptedTerms(v)
+		return nil
 	}
 	return fmt.Errorf("unknown UserIdentity field %s", name)
 }
@@ -10933,6 +12877,15 @@ func (m *UserIdentityMutation) ClearedFields() []string {
 	if m.FieldCleared(useridentity.FieldWebauthnCredentials) {
 		fields = append(fields, useridentity.FieldWebauthnCredentials)
 	}
+	if m.FieldCleared(useridentity.FieldConsentedClaims) {
+		fields = append(fields, useridentity.FieldConsentedClaims)
+	}
+	if m.FieldCleared(useridentity.FieldClaimsCustom) {
+		fields = append(fields, useridentity.FieldClaimsCustom)
+	}
+	if m.FieldCleared(useridentity.FieldAcceptedTerms) {
+		fields = append(fields, useridentity.FieldAcceptedTerms)
+	}
 	return fields
 }
 
@@ -10956,6 +12909,15 @@ func (m *UserIdentityMutation) ClearField(name string) error {
 	case useridentity.FieldWebauthnCredentials:
 		m.ClearWebauthnCredentials()
 		return nil
+	case useridentity.FieldConsentedClaims:
+		m.ClearConsentedClaims()
+		return nil
+	case useridentity.FieldClaimsCustom:
+		m.ClearClaimsCustom()
+		return nil
+	case useridentity.FieldAcceptedTerms:
+		m.ClearAcceptedTerms()
+		return nil
 	}
 	return fmt.Errorf("unknown UserIdentity nullable field %s", name)
 }
@@ -11006,6 +12968,15 @@ func (m *UserIdentityMutation) ResetField(name string) error {
 	case useridentity.FieldBlockedUntil:
 		m.ResetBlockedUntil()
 		return nil
+	case useridentity.FieldConsentedClaims:
+		m.ResetConsentedClaims()
+		return nil
+	case useridentity.FieldClaimsCustom:
+		m.ResetClaimsCustom()
+		return nil
+	case useridentity.FieldAcceptedTerms:
+		m.ResetAcceptedTerms()
+		return nil
 	}
 	return fmt.Errorf("unknown UserIdentity field %s", name)
 }
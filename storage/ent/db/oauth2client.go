@@ -9,6 +9,7 @@ import (
 
 	"entgo.io/ent"
 	"entgo.io/ent/dialect/sql"
+	"github.com/dexidp/dex/storage"
 	"github.com/dexidp/dex/storage/ent/db/oauth2client"
 )
 
@@ -37,6 +38,34 @@ type OAuth2Client struct {
 	PostLogoutRedirectUris []string `json:"post_logout_redirect_uris,omitempty"`
 	// SSOSharedWith holds the value of the "sso_shared_with" field.
 	SSOSharedWith []string `json:"sso_shared_with,omitempty"`
+	// Theme holds the value of the "theme" field.
+	Theme *storage.ClientTheme `json:"theme,omitempty"`
+	// RequireConsentOnClaimChange holds the value of the "require_consent_on_claim_change" field.
+	RequireConsentOnClaimChange bool `json:"require_consent_on_claim_change,omitempty"`
+	// ClientCredentials holds the value of the "client_credentials" field.
+	ClientCredentials *storage.ClientCredentialsConfig `json:"client_credentials,omitempty"`
+	// JWTBearerIssuers holds the value of the "jwt_bearer_issuers" field.
+	JWTBearerIssuers []storage.JWTBearerIssuer `json:"jwt_bearer_issuers,omitempty"`
+	// TokenExchange holds the value of the "token_exchange" field.
+	TokenExchange *storage.TokenExchangePolicy `json:"token_exchange,omitempty"`
+	// Resources holds the value of the "resources" field.
+	Resources []string `json:"resources,omitempty"`
+	// CustomClaims holds the value of the "custom_claims" field.
+	CustomClaims map[string]interface{} `json:"custom_claims,omitempty"`
+	// RefreshTokenReuse holds the value of the "refresh_token_reuse" field.
+	RefreshTokenReuse *storage.RefreshTokenReusePolicy `json:"refresh_token_reuse,omitempty"`
+	// DeviceFlow holds the value of the "device_flow" field.
+	DeviceFlow *storage.DeviceFlowConfig `json:"device_flow,omitempty"`
+	// AllowedOrigins holds the value of the "allowed_origins" field.
+	AllowedOrigins []string `json:"allowed_origins,omitempty"`
+	// ReleasedConnectorClaims holds the value of the "released_connector_claims" field.
+	ReleasedConnectorClaims []string `json:"released_connector_claims,omitempty"`
+	// GroupsClaim holds the value of the "groups_claim" field.
+	GroupsClaim *storage.GroupsClaim `json:"groups_claim,omitempty"`
+	// Terms holds the value of the "terms" field.
+	Terms *storage.ClientTerms `json:"terms,omitempty"`
+	// Impersonation holds the value of the "impersonation" field.
+	Impersonation *storage.ImpersonationPolicy `json:"impersonation,omitempty"`
 	selectValues  sql.SelectValues
 }
 
@@ -45,9 +74,9 @@ func (*OAuth2Client) scanValues(columns []string) ([]any, error) {
 	values := make([]any, len(columns))
 	for i := range columns {
 		switch columns[i] {
-		case oauth2client.FieldRedirectUris, oauth2client.FieldTrustedPeers, oauth2client.FieldAllowedConnectors, oauth2client.FieldMfaChain, oauth2client.FieldPostLogoutRedirectUris, oauth2client.FieldSSOSharedWith:
+		case oauth2client.FieldRedirectUris, oauth2client.FieldTrustedPeers, oauth2client.FieldAllowedConnectors, oauth2client.FieldMfaChain, oauth2client.FieldPostLogoutRedirectUris, oauth2client.FieldSSOSharedWith, oauth2client.FieldTheme, oauth2client.FieldClientCredentials, oauth2client.FieldJWTBearerIssuers, oauth2client.FieldTokenExchange, oauth2client.FieldResources, oauth2client.FieldCustomClaims, oauth2client.FieldRefreshTokenReuse, oauth2client.FieldDeviceFlow, oauth2client.FieldAllowedOrigins, oauth2client.FieldReleasedConnectorClaims, oauth2client.FieldGroupsClaim, oauth2client.FieldTerms, oauth2client.FieldImpersonation:
 			values[i] = new([]byte)
-		case oauth2client.FieldPublic:
+		case oauth2client.FieldPublic, oauth2client.FieldRequireConsentOnClaimChange:
 			values[i] = new(sql.NullBool)
 		case oauth2client.FieldID, oauth2client.FieldSecret, oauth2client.FieldName, oauth2client.FieldLogoURL:
 			values[i] = new(sql.NullString)
@@ -144,6 +173,116 @@ func (_m *OAuth2Client) assignValues(columns []string, values []any) error {
 					return fmt.Errorf("unmarshal field sso_shared_with: %w", err)
 				}
 			}
+		case oauth2client.FieldTheme:
+			if value, ok := values[i].(*[]byte); !ok {
+				return fmt.Errorf("unexpected type %T for field theme", values[i])
+			} else if value != nil && len(*value) > 0 {
+				if err := json.Unmarshal(*value, &_m.Theme); err != nil {
+					return fmt.Errorf("unmarshal field theme: %w", err)
+				}
+			}
+		case oauth2client.FieldRequireConsentOnClaimChange:
+			if value, ok := values[i].(*sql.NullBool); !ok {
+				return fmt.Errorf("unexpected type %T for field require_consent_on_claim_change", values[i])
+			} else if value.Valid {
+				_m.RequireConsentOnClaimChange = value.Bool
+			}
+		case oauth2client.FieldClientCredentials:
+			if value, ok := values[i].(*[]byte); !ok {
+				return fmt.Errorf("unexpected type %T for field client_credentials", values[i])
+			} else if value != nil && len(*value) > 0 {
+				if err := json.Unmarshal(*value, &_m.ClientCredentials); err != nil {
+					return fmt.Errorf("unmarshal field client_credentials: %w", err)
+				}
+			}
+		case oauth2client.FieldJWTBearerIssuers:
+			if value, ok := values[i].(*[]byte); !ok {
+				return fmt.Errorf("unexpected type %T for field jwt_bearer_issuers", values[i])
+			} else if value != nil && len(*value) > 0 {
+				if err := json.Unmarshal(*value, &_m.JWTBearerIssuers); err != nil {
+					return fmt.Errorf("unmarshal field jwt_bearer_issuers: %w", err)
+				}
+			}
+		case oauth2client.FieldTokenExchange:
+			if value, ok := values[i].(*[]byte); !ok {
+				return fmt.Errorf("unexpected type %T for field token_exchange", values[i])
+			} else if value != nil && len(*value) > 0 {
+				if err := json.Unmarshal(*value, &_m.TokenExchange); err != nil {
+					return fmt.Errorf("unmarshal field token_exchange: %w", err)
+				}
+			}
+		case oauth2client.FieldResources:
+			if value, ok := values[i].(*[]byte); !ok {
+				return fmt.Errorf("unexpected type %T for field resources", values[i])
+			} else if value != nil && len(*value) > 0 {
+				if err := json.Unmarshal(*value, &_m.Resources); err != nil {
+					return fmt.Errorf("unmarshal field resources: %w", err)
+				}
+			}
+		case oauth2client.FieldCustomClaims:
+			if value, ok := values[i].(*[]byte); !ok {
+				return fmt.Errorf("unexpected type %T for field custom_claims", values[i])
+			} else if value != nil && len(*value) > 0 {
+				if err := json.Unmarshal(*value, &_m.CustomClaims); err != nil {
+					return fmt.Errorf("unmarshal field custom_claims: %w", err)
+				}
+			}
+		case oauth2client.FieldRefreshTokenReuse:
+			if value, ok := values[i].(*[]byte); !ok {
+				return fmt.Errorf("unexpected type %T for field refresh_token_reuse", values[i])
+			} else if value != nil && len(*value) > 0 {
+				if err := json.Unmarshal(*value, &_m.RefreshTokenReuse); err != nil {
+					return fmt.Errorf("unmarshal field refresh_token_reuse: %w", err)
+				}
+			}
+		case oauth2client.FieldDeviceFlow:
+			if value, ok := values[i].(*[]byte); !ok {
+				return fmt.Errorf("unexpected type %T for field device_flow", values[i])
+			} else if value != nil && len(*value) > 0 {
+				if err := json.Unmarshal(*value, &_m.DeviceFlow); err != nil {
+					return fmt.Errorf("unmarshal field device_flow: %w", err)
+				}
+			}
+		case oauth2client.FieldAllowedOrigins:
+			if value, ok := values[i].(*[]byte); !ok {
+				return fmt.Errorf("unexpected type %T for field allowed_origins", values[i])
+			} else if value != nil && len(*value) > 0 {
+				if err := json.Unmarshal(*value, &_m.AllowedOrigins); err != nil {
+					return fmt.Errorf("unmarshal field allowed_origins: %w", err)
+				}
+			}
+		case oauth2client.FieldReleasedConnectorClaims:
+			if value, ok := values[i].(*[]byte); !ok {
+				return fmt.Errorf("unexpected type %T for field released_connector_claims", values[i])
+			} else if value != nil && len(*value) > 0 {
+				if err := json.Unmarshal(*value, &_m.ReleasedConnectorClaims); err != nil {
+					return fmt.Errorf("unmarshal field released_connector_claims: %w", err)
+				}
+			}
+		case oauth2client.FieldGroupsClaim:
+			if value, ok := values[i].(*[]byte); !ok {
+				return fmt.Errorf("unexpected type %T for field groups_claim", values[i])
+			} else if value != nil && len(*value) > 0 {
+				if err := json.Unmarshal(*value, &_m.GroupsClaim); err != nil {
+					return fmt.Errorf("unmarshal field groups_claim: %w", err)
+				}
+			}
+		case oauth2client.FieldTerms:
+			if value, ok := values[i].(*[]byte); !ok {
+				return fmt.Errorf("unexpected type %T for field terms", values[i])
+			} else if value != nil && len(*value) > 0 {
+				if err := json.Unmarshal(*value, &_m.Terms); err != nil {
+					return fmt.Errorf("unmarshal field terms: %w", err)
+				}
+			}
+		case oauth2client.FieldImpersonation:
+			if value, ok := values[i].(*[]byte); !ok {
+				return fmt.Errorf("unexpected type %T for field impersonation", values[i])
+			} else if value != nil && len(*value) > 0 {
+				if err := json.Unmarshal(*value, &_m.Impersonation); err != nil {
+					return fmt.Errorf("unmarshal field impersonation: %w", err)
+				}
+			}
 		default:
 			_m.selectValues.Set(columns[i], values[i])
 		}
@@ -209,6 +348,48 @@ func (_m *OAuth2Client) String() string {
 	builder.WriteString(", ")
 	builder.WriteString("sso_shared_with=")
 	builder.WriteString(fmt.Sprintf("%v", _m.SSOSharedWith))
+	builder.WriteString(", ")
+	builder.WriteString("theme=")
+	builder.WriteString(fmt.Sprintf("%v", _m.Theme))
+	builder.WriteString(", ")
+	builder.WriteString("require_consent_on_claim_change=")
+	builder.WriteString(fmt.Sprintf("%v", _m.RequireConsentOnClaimChange))
+	builder.WriteString(", ")
+	builder.WriteString("client_credentials=")
+	builder.WriteString(fmt.Sprintf("%v", _m.ClientCredentials))
+	builder.WriteString(", ")
+	builder.WriteString("jwt_bearer_issuers=")
+	builder.WriteString(fmt.Sprintf("%v", _m.JWTBearerIssuers))
+	builder.WriteString(", ")
+	builder.WriteString("token_exchange=")
+	builder.WriteString(fmt.Sprintf("%v", _m.TokenExchange))
+	builder.WriteString(", ")
+	builder.WriteString("resources=")
+	builder.WriteString(fmt.Sprintf("%v", _m.Resources))
+	builder.WriteString(", ")
+	builder.WriteString("custom_claims=")
+	builder.WriteString(fmt.Sprintf("%v", _m.CustomClaims))
+	builder.WriteString(", ")
+	builder.WriteString("refresh_token_reuse=")
+	builder.WriteString(fmt.Sprintf("%v", _m.RefreshTokenReuse))
+	builder.WriteString(", ")
+	builder.WriteString("device_flow=")
+	builder.WriteString(fmt.Sprintf("%v", _m.DeviceFlow))
+	builder.WriteString(", ")
+	builder.WriteString("allowed_origins=")
+	builder.WriteString(fmt.Sprintf("%v", _m.AllowedOrigins))
+	builder.WriteString(", ")
+	builder.WriteString("released_connector_claims=")
+	builder.WriteString(fmt.Sprintf("%v", _m.ReleasedConnectorClaims))
+	builder.WriteString(", ")
+	builder.WriteString("groups_claim=")
+	builder.WriteString(fmt.Sprintf("%v", _m.GroupsClaim))
+	builder.WriteString(", ")
+	builder.WriteString("terms=")
+	builder.WriteString(fmt.Sprintf("%v", _m.Terms))
+	builder.WriteString(", ")
+	builder.WriteString("impersonation=")
+	builder.WriteString(fmt.Sprintf("%v", _m.Impersonation))
 	builder.WriteByte(')')
 	return builder.String()
 }
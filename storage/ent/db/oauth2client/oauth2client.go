@@ -31,6 +31,34 @@ const (
 	FieldPostLogoutRedirectUris = "post_logout_redirect_uris"
 	// FieldSSOSharedWith holds the string denoting the sso_shared_with field in the database.
 	FieldSSOSharedWith = "sso_shared_with"
+	// FieldTheme holds the string denoting the theme field in the database.
+	FieldTheme = "theme"
+	// FieldRequireConsentOnClaimChange holds the string denoting the require_consent_on_claim_change field in the database.
+	FieldRequireConsentOnClaimChange = "require_consent_on_claim_change"
+	// FieldClientCredentials holds the string denoting the client_credentials field in the database.
+	FieldClientCredentials = "client_credentials"
+	// FieldJWTBearerIssuers holds the string denoting the jwt_bearer_issuers field in the database.
+	FieldJWTBearerIssuers = "jwt_bearer_issuers"
+	// FieldTokenExchange holds the string denoting the token_exchange field in the database.
+	FieldTokenExchange = "token_exchange"
+	// FieldResources holds the string denoting the resources field in the database.
+	FieldResources = "resources"
+	// FieldCustomClaims holds the string denoting the custom_claims field in the database.
+	FieldCustomClaims = "custom_claims"
+	// FieldRefreshTokenReuse holds the string denoting the refresh_token_reuse field in the database.
+	FieldRefreshTokenReuse = "refresh_token_reuse"
+	// FieldDeviceFlow holds the string denoting the device_flow field in the database.
+	FieldDeviceFlow = "device_flow"
+	// FieldAllowedOrigins holds the string denoting the allowed_origins field in the database.
+	FieldAllowedOrigins = "allowed_origins"
+	// FieldReleasedConnectorClaims holds the string denoting the released_connector_claims field in the database.
+	FieldReleasedConnectorClaims = "released_connector_claims"
+	// FieldGroupsClaim holds the string denoting the groups_claim field in the database.
+	FieldGroupsClaim = "groups_claim"
+	// FieldTerms holds the string denoting the terms field in the database.
+	FieldTerms = "terms"
+	// FieldImpersonation holds the string denoting the impersonation field in the database.
+	FieldImpersonation = "impersonation"
 	// Table holds the table name of the oauth2client in the database.
 	Table = "oauth2clients"
 )
@@ -48,6 +76,20 @@ var Columns = []string{
 	FieldMfaChain,
 	FieldPostLogoutRedirectUris,
 	FieldSSOSharedWith,
+	FieldTheme,
+	FieldRequireConsentOnClaimChange,
+	FieldClientCredentials,
+	FieldJWTBearerIssuers,
+	FieldTokenExchange,
+	FieldResources,
+	FieldCustomClaims,
+	FieldRefreshTokenReuse,
+	FieldDeviceFlow,
+	FieldAllowedOrigins,
+	FieldReleasedConnectorClaims,
+	FieldGroupsClaim,
+	FieldTerms,
+	FieldImpersonation,
 }
 
 // ValidColumn reports if the column name is valid (part of the table columns).
@@ -98,3 +140,8 @@ func ByName(opts ...sql.OrderTermOption) OrderOption {
 func ByLogoURL(opts ...sql.OrderTermOption) OrderOption {
 	return sql.OrderByField(FieldLogoURL, opts...).ToFunc()
 }
+
+// ByRequireConsentOnClaimChange orders the results by the require_consent_on_claim_change field.
+func ByRequireConsentOnClaimChange(opts ...sql.OrderTermOption) OrderOption {
+	return sql.OrderByField(FieldRequireConsentOnClaimChange, opts...).ToFunc()
+}
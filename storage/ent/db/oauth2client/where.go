@@ -347,6 +347,161 @@ func SSOSharedWithNotNil() predicate.OAuth2Client {
 	return predicate.OAuth2Client(sql.FieldNotNull(FieldSSOSharedWith))
 }
 
+// ThemeIsNil applies the IsNil predicate on the "theme" field.
+func ThemeIsNil() predicate.OAuth2Client {
+	return predicate.OAuth2Client(sql.FieldIsNull(FieldTheme))
+}
+
+// ThemeNotNil applies the NotNil predicate on the "theme" field.
+func ThemeNotNil() predicate.OAuth2Client {
+	return predicate.OAuth2Client(sql.FieldNotNull(FieldTheme))
+}
+
+// RequireConsentOnClaimChange applies equality check predicate on the "require_consent_on_claim_change" field. It's identical to RequireConsentOnClaimChangeEQ.
+func RequireConsentOnClaimChange(v bool) predicate.OAuth2Client {
+	return predicate.OAuth2Client(sql.FieldEQ(FieldRequireConsentOnClaimChange, v))
+}
+
+// RequireConsentOnClaimChangeEQ applies the EQ predicate on the "require_consent_on_claim_change" field.
+func RequireConsentOnClaimChangeEQ(v bool) predicate.OAuth2Client {
+	return predicate.OAuth2Client(sql.FieldEQ(FieldRequireConsentOnClaimChange, v))
+}
+
+// RequireConsentOnClaimChangeNEQ applies the NEQ predicate on the "require_consent_on_claim_change" field.
+func RequireConsentOnClaimChangeNEQ(v bool) predicate.OAuth2Client {
+	return predicate.OAuth2Client(sql.FieldNEQ(FieldRequireConsentOnClaimChange, v))
+}
+
+// RequireConsentOnClaimChangeIsNil applies the IsNil predicate on the "require_consent_on_claim_change" field.
+func RequireConsentOnClaimChangeIsNil() predicate.OAuth2Client {
+	return predicate.OAuth2Client(sql.FieldIsNull(FieldRequireConsentOnClaimChange))
+}
+
+// RequireConsentOnClaimChangeNotNil applies the NotNil predicate on the "require_consent_on_claim_change" field.
+func RequireConsentOnClaimChangeNotNil() predicate.OAuth2Client {
+	return predicate.OAuth2Client(sql.FieldNotNull(FieldRequireConsentOnClaimChange))
+}
+
+// ClientCredentialsIsNil applies the IsNil predicate on the "client_credentials" field.
+func ClientCredentialsIsNil() predicate.OAuth2Client {
+	return predicate.OAuth2Client(sql.FieldIsNull(FieldClientCredentials))
+}
+
+// ClientCredentialsNotNil applies the NotNil predicate on the "client_credentials" field.
+func ClientCredentialsNotNil() predicate.OAuth2Client {
+	return predicate.OAuth2Client(sql.FieldNotNull(FieldClientCredentials))
+}
+
+// JWTBearerIssuersIsNil applies the IsNil predicate on the "jwt_bearer_issuers" field.
+func JWTBearerIssuersIsNil() predicate.OAuth2Client {
+	return predicate.OAuth2Client(sql.FieldIsNull(FieldJWTBearerIssuers))
+}
+
+// JWTBearerIssuersNotNil applies the NotNil predicate on the "jwt_bearer_issuers" field.
+func JWTBearerIssuersNotNil() predicate.OAuth2Client {
+	return predicate.OAuth2Client(sql.FieldNotNull(FieldJWTBearerIssuers))
+}
+
+// TokenExchangeIsNil applies the IsNil predicate on the "token_exchange" field.
+func TokenExchangeIsNil() predicate.OAuth2Client {
+	return predicate.OAuth2Client(sql.FieldIsNull(FieldTokenExchange))
+}
+
+// TokenExchangeNotNil applies the NotNil predicate on the "token_exchange" field.
+func TokenExchangeNotNil() predicate.OAuth2Client {
+	return predicate.OAuth2Client(sql.FieldNotNull(FieldTokenExchange))
+}
+
+// ResourcesIsNil applies the IsNil predicate on the "resources" field.
+func ResourcesIsNil() predicate.OAuth2Client {
+	return predicate.OAuth2Client(sql.FieldIsNull(FieldResources))
+}
+
+// ResourcesNotNil applies the NotNil predicate on the "resources" field.
+func ResourcesNotNil() predicate.OAuth2Client {
+	return predicate.OAuth2Client(sql.FieldNotNull(FieldResources))
+}
+
+// CustomClaimsIsNil applies the IsNil predicate on the "custom_claims" field.
+func CustomClaimsIsNil() predicate.OAuth2Client {
+	return predicate.OAuth2Client(sql.FieldIsNull(FieldCustomClaims))
+}
+
+// CustomClaimsNotNil applies the NotNil predicate on the "custom_claims" field.
+func CustomClaimsNotNil() predicate.OAuth2Client {
+	return predicate.OAuth2Client(sql.FieldNotNull(FieldCustomClaims))
+}
+
+// RefreshTokenReuseIsNil applies the IsNil predicate on the "refresh_token_reuse" field.
+func RefreshTokenReuseIsNil() predicate.OAuth2Client {
+	return predicate.OAuth2Client(sql.FieldIsNull(FieldRefreshTokenReuse))
+}
+
+// RefreshTokenReuseNotNil applies the NotNil predicate on the "refresh_token_reuse" field.
+func RefreshTokenReuseNotNil() predicate.OAuth2Client {
+	return predicate.OAuth2Client(sql.FieldNotNull(FieldRefreshTokenReuse))
+}
+
+// DeviceFlowIsNil applies the IsNil predicate on the "device_flow" field.
+func DeviceFlowIsNil() predicate.OAuth2Client {
+	return predicate.OAuth2Client(sql.FieldIsNull(FieldDeviceFlow))
+}
+
+// DeviceFlowNotNil applies the NotNil predicate on the "device_flow" field.
+func DeviceFlowNotNil() predicate.OAuth2Client {
+	return predicate.OAuth2Client(sql.FieldNotNull(FieldDeviceFlow))
+}
+
+// AllowedOriginsIsNil applies the IsNil predicate on the "allowed_origins" field.
+func AllowedOriginsIsNil() predicate.OAuth2Client {
+	return predicate.OAuth2Client(sql.FieldIsNull(FieldAllowedOrigins))
+}
+
+// AllowedOriginsNotNil applies the NotNil predicate on the "allowed_origins" field.
+func AllowedOriginsNotNil() predicate.OAuth2Client {
+	return predicate.OAuth2Client(sql.FieldNotNull(FieldAllowedOrigins))
+}
+
+// ReleasedConnectorClaimsIsNil applies the IsNil predicate on the "released_connector_claims" field.
+func ReleasedConnectorClaimsIsNil() predicate.OAuth2Client {
+	return predicate.OAuth2Client(sql.FieldIsNull(FieldReleasedConnectorClaims))
+}
+
+// ReleasedConnectorClaimsNotNil applies the NotNil predicate on the "released_connector_claims" field.
+func ReleasedConnectorClaimsNotNil() predicate.OAuth2Client {
+	return predicate.OAuth2Client(sql.FieldNotNull(FieldReleasedConnectorClaims))
+}
+
+// GroupsClaimIsNil applies the IsNil predicate on the "groups_claim" field.
+func GroupsClaimIsNil() predicate.OAuth2Client {
+	return predicate.OAuth2Client(sql.FieldIsNull(FieldGroupsClaim))
+}
+
+// GroupsClaimNotNil applies the NotNil predicate on the "groups_claim" field.
+func GroupsClaimNotNil() predicate.OAuth2Client {
+	return predicate.OAuth2Client(sql.FieldNotNull(FieldGroupsClaim))
+}
+
+// TermsIsNil applies the IsNil predicate on the "terms" field.
+func TermsIsNil() predicate.OAuth2Client {
+	return predicate.OAuth2Client(sql.FieldIsNull(FieldTerms))
+}
+
+// TermsNotNil applies the NotNil predicate on the "terms" field.
+func TermsNotNil() predicate.OAuth2Client {
+	return predicate.OAuth2Client(sql.FieldNotNull(FieldTerms))
+}
+
+// ImpersonationIsNil applies the IsNil predicate on the "impersonation" field.
+func ImpersonationIsNil() predicate.OAuth2Client {
+	return predicate.OAuth2Client(sql.FieldIsNull(FieldImpersonation))
+}
+
+// ImpersonationNotNil applies the NotNil predicate on the "impersonation" field.
+func ImpersonationNotNil() predicate.OAuth2Client {
+	return predicate.OAuth2Client(sql.FieldNotNull(FieldImpersonation))
+}
+
 // And groups predicates with the AND operator between them.
 func And(predicates ...predicate.OAuth2Client) predicate.OAuth2Client {
 	return predicate.OAuth2Client(sql.AndPredicates(predicates...))
@@ -9,6 +9,7 @@ import (
 
 	"entgo.io/ent/dialect/sql/sqlgraph"
 	"entgo.io/ent/schema/field"
+	"github.com/dexidp/dex/storage"
 	"github.com/dexidp/dex/storage/ent/db/oauth2client"
 )
 
@@ -79,6 +80,98 @@ func (_c *OAuth2ClientCreate) SetSSOSharedWith(v []string) *OAuth2ClientCreate {
 	return _c
 }
 
+// SetTheme sets the "theme" field.
+func (_c *OAuth2ClientCreate) SetTheme(v *storage.ClientTheme) *OAuth2ClientCreate {
+	_c.mutation.SetTheme(v)
+	return _c
+}
+
+// SetRequireConsentOnClaimChange sets the "require_consent_on_claim_change" field.
+func (_c *OAuth2ClientCreate) SetRequireConsentOnClaimChange(v bool) *OAuth2ClientCreate {
+	_c.mutation.SetRequireConsentOnClaimChange(v)
+	return _c
+}
+
+// SetNillableRequireConsentOnClaimChange sets the "require_consent_on_claim_change" field if the given value is not nil.
+func (_c *OAuth2ClientCreate) SetNillableRequireConsentOnClaimChange(v *bool) *OAuth2ClientCreate {
+	if v != nil {
+		_c.SetRequireConsentOnClaimChange(*v)
+	}
+	return _c
+}
+
+// SetClientCredentials sets the "client_credentials" field.
+func (_c *OAuth2ClientCreate) SetClientCredentials(v *storage.ClientCredentialsConfig) *OAuth2ClientCreate {
+	_c.mutation.SetClientCredentials(v)
+	return _c
+}
+
+// SetJWTBearerIssuers sets the "jwt_bearer_issuers" field.
+func (_c *OAuth2ClientCreate) SetJWTBearerIssuers(v []storage.JWTBearerIssuer) *OAuth2ClientCreate {
+	_c.mutation.SetJWTBearerIssuers(v)
+	return _c
+}
+
+// SetTokenExchange sets the "token_exchange" field.
+func (_c *OAuth2ClientCreate) SetTokenExchange(v *storage.TokenExchangePolicy) *OAuth2ClientCreate {
+	_c.mutation.SetTokenExchange(v)
+	return _c
+}
+
+// SetResources sets the "resources" field.
+func (_c *OAuth2ClientCreate) SetResources(v []string) *OAuth2ClientCreate {
+	_c.mutation.SetResources(v)
+	return _c
+}
+
+// SetCustomClaims sets the "custom_claims" field.
+func (_c *OAuth2ClientCreate) SetCustomClaims(v map[string]interface{}) *OAuth2ClientCreate {
+	_c.mutation.SetCustomClaims(v)
+	return _c
+}
+
+// SetRefreshTokenReuse sets the "refresh_token_reuse" field.
+func (_c *OAuth2ClientCreate) SetRefreshTokenReuse(v *storage.RefreshTokenReusePolicy) *OAuth2ClientCreate {
+	_c.mutation.SetRefreshTokenReuse(v)
+	return _c
+}
+
+// SetDeviceFlow sets the "device_flow" field.
+func (_c *OAuth2ClientCreate) SetDeviceFlow(v *storage.DeviceFlowConfig) *OAuth2ClientCreate {
+	_c.mutation.SetDeviceFlow(v)
+	return _c
+}
+
+// SetAllowedOrigins sets the "allowed_origins" field.
+func (_c *OAuth2ClientCreate) SetAllowedOrigins(v []string) *OAuth2ClientCreate {
+	_c.mutation.SetAllowedOrigins(v)
+	return _c
+}
+
+// SetReleasedConnectorClaims sets the "released_connector_claims" field.
+func (_c *OAuth2ClientCreate) SetReleasedConnectorClaims(v []string) *OAuth2ClientCreate {
+	_c.mutation.SetReleasedConnectorClaims(v)
+	return _c
+}
+
+// SetGroupsClaim sets the "groups_claim" field.
+func (_c *OAuth2ClientCreate) SetGroupsClaim(v *storage.GroupsClaim) *OAuth2ClientCreate {
+	_c.mutation.SetGroupsClaim(v)
+	return _c
+}
+
+// SetTerms sets the "terms" field.
+func (_c *OAuth2ClientCreate) SetTerms(v *storage.ClientTerms) *OAuth2ClientCreate {
+	_c.mutation.SetTerms(v)
+	return _c
+}
+
+// SetImpersonation sets the "impersonation" field.
+func (_c *OAuth2ClientCreate) SetImpersonation(v *storage.ImpersonationPolicy) *OAuth2ClientCreate {
+	_c.mutation.SetImpersonation(v)
+	return _c
+}
+
 // SetID sets the "id" field.
 func (_c *OAuth2ClientCreate) SetID(v string) *OAuth2ClientCreate {
 	_c.mutation.SetID(v)
@@ -226,6 +319,62 @@ func (_c *OAuth2ClientCreate) createSpec() (*OAuth2Client, *sqlgraph.CreateSpec)
 		_spec.SetField(oauth2client.FieldSSOSharedWith, field.TypeJSON, value)
 		_node.SSOSharedWith = value
 	}
+	if value, ok := _c.mutation.Theme(); ok {
+		_spec.SetField(oauth2client.FieldTheme, field.TypeJSON, value)
+		_node.Theme = value
+	}
+	if value, ok := _c.mutation.RequireConsentOnClaimChange(); ok {
+		_spec.SetField(oauth2client.FieldRequireConsentOnClaimChange, field.TypeBool, value)
+		_node.RequireConsentOnClaimChange = value
+	}
+	if value, ok := _c.mutation.ClientCredentials(); ok {
+		_spec.SetField(oauth2client.FieldClientCredentials, field.TypeJSON, value)
+		_node.ClientCredentials = value
+	}
+	if value, ok := _c.mutation.JWTBearerIssuers(); ok {
+		_spec.SetField(oauth2client.FieldJWTBearerIssuers, field.TypeJSON, value)
+		_node.JWTBearerIssuers = value
+	}
+	if value, ok := _c.mutation.TokenExchange(); ok {
+		_spec.SetField(oauth2client.FieldTokenExchange, field.TypeJSON, value)
+		_node.TokenExchange = value
+	}
+	if value, ok := _c.mutation.Resources(); ok {
+		_spec.SetField(oauth2client.FieldResources, field.TypeJSON, value)
+		_node.Resources = value
+	}
+	if value, ok := _c.mutation.CustomClaims(); ok {
+		_spec.SetField(oauth2client.FieldCustomClaims, field.TypeJSON, value)
+		_node.CustomClaims = value
+	}
+	if value, ok := _c.mutation.RefreshTokenReuse(); ok {
+		_spec.SetField(oauth2client.FieldRefreshTokenReuse, field.TypeJSON, value)
+		_node.RefreshTokenReuse = value
+	}
+	if value, ok := _c.mutation.DeviceFlow(); ok {
+		_spec.SetField(oauth2client.FieldDeviceFlow, field.TypeJSON, value)
+		_node.DeviceFlow = value
+	}
+	if value, ok := _c.mutation.AllowedOrigins(); ok {
+		_spec.SetField(oauth2client.FieldAllowedOrigins, field.TypeJSON, value)
+		_node.AllowedOrigins = value
+	}
+	if value, ok := _c.mutation.ReleasedConnectorClaims(); ok {
+		_spec.SetField(oauth2client.FieldReleasedConnectorClaims, field.TypeJSON, value)
+		_node.ReleasedConnectorClaims = value
+	}
+	if value, ok := _c.mutation.GroupsClaim(); ok {
+		_spec.SetField(oauth2client.FieldGroupsClaim, field.TypeJSON, value)
+		_node.GroupsClaim = value
+	}
+	if value, ok := _c.mutation.Terms(); ok {
+		_spec.SetField(oauth2client.FieldTerms, field.TypeJSON, value)
+		_node.Terms = value
+	}
+	if value, ok := _c.mutation.Impersonation(); ok {
+		_spec.SetField(oauth2client.FieldImpersonation, field.TypeJSON, value)
+		_node.Impersonation = value
+	}
 	return _node, _spec
 }
 
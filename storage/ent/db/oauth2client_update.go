@@ -11,6 +11,7 @@ import (
 	"entgo.io/ent/dialect/sql/sqlgraph"
 	"entgo.io/ent/dialect/sql/sqljson"
 	"entgo.io/ent/schema/field"
+	"github.com/dexidp/dex/storage"
 	"github.com/dexidp/dex/storage/ent/db/oauth2client"
 	"github.com/dexidp/dex/storage/ent/db/predicate"
 )
@@ -192,6 +193,182 @@ func (_u *OAuth2ClientUpdate) ClearSSOSharedWith() *OAuth2ClientUpdate {
 	return _u
 }
 
+// SetTheme sets the "theme" field.
+func (_u *OAuth2ClientUpdate) SetTheme(v *storage.ClientTheme) *OAuth2ClientUpdate {
+	_u.mutation.SetTheme(v)
+	return _u
+}
+
+// ClearTheme clears the value of the "theme" field.
+func (_u *OAuth2ClientUpdate) ClearTheme() *OAuth2ClientUpdate {
+	_u.mutation.ClearTheme()
+	return _u
+}
+
+// SetRequireConsentOnClaimChange sets the "require_consent_on_claim_change" field.
+func (_u *OAuth2ClientUpdate) SetRequireConsentOnClaimChange(v bool) *OAuth2ClientUpdate {
+	_u.mutation.SetRequireConsentOnClaimChange(v)
+	return _u
+}
+
+// SetNillableRequireConsentOnClaimChange sets the "require_consent_on_claim_change" field if the given value is not nil.
+func (_u *OAuth2ClientUpdate) SetNillableRequireConsentOnClaimChange(v *bool) *OAuth2ClientUpdate {
+	if v != nil {
+		_u.SetRequireConsentOnClaimChange(*v)
+	}
+	return _u
+}
+
+// ClearRequireConsentOnClaimChange clears the value of the "require_consent_on_claim_change" field.
+func (_u *OAuth2ClientUpdate) ClearRequireConsentOnClaimChange() *OAuth2ClientUpdate {
+	_u.mutation.ClearRequireConsentOnClaimChange()
+	return _u
+}
+
+// SetClientCredentials sets the "client_credentials" field.
+func (_u *OAuth2ClientUpdate) SetClientCredentials(v *storage.ClientCredentialsConfig) *OAuth2ClientUpdate {
+	_u.mutation.SetClientCredentials(v)
+	return _u
+}
+
+// ClearClientCredentials clears the value of the "client_credentials" field.
+func (_u *OAuth2ClientUpdate) ClearClientCredentials() *OAuth2ClientUpdate {
+	_u.mutation.ClearClientCredentials()
+	return _u
+}
+
+// SetJWTBearerIssuers sets the "jwt_bearer_issuers" field.
+func (_u *OAuth2ClientUpdate) SetJWTBearerIssuers(v []storage.JWTBearerIssuer) *OAuth2ClientUpdate {
+	_u.mutation.SetJWTBearerIssuers(v)
+	return _u
+}
+
+// ClearJWTBearerIssuers clears the value of the "jwt_bearer_issuers" field.
+func (_u *OAuth2ClientUpdate) ClearJWTBearerIssuers() *OAuth2ClientUpdate {
+	_u.mutation.ClearJWTBearerIssuers()
+	return _u
+}
+
+// SetTokenExchange sets the "token_exchange" field.
+func (_u *OAuth2ClientUpdate) SetTokenExchange(v *storage.TokenExchangePolicy) *OAuth2ClientUpdate {
+	_u.mutation.SetTokenExchange(v)
+	return _u
+}
+
+// ClearTokenExchange clears the value of the "token_exchange" field.
+func (_u *OAuth2ClientUpdate) ClearTokenExchange() *OAuth2ClientUpdate {
+	_u.mutation.ClearTokenExchange()
+	return _u
+}
+
+// SetResources sets the "resources" field.
+func (_u *OAuth2ClientUpdate) SetResources(v []string) *OAuth2ClientUpdate {
+	_u.mutation.SetResources(v)
+	return _u
+}
+
+// ClearResources clears the value of the "resources" field.
+func (_u *OAuth2ClientUpdate) ClearResources() *OAuth2ClientUpdate {
+	_u.mutation.ClearResources()
+	return _u
+}
+
+// SetCustomClaims sets the "custom_claims" field.
+func (_u *OAuth2ClientUpdate) SetCustomClaims(v map[string]interface{}) *OAuth2ClientUpdate {
+	_u.mutation.SetCustomClaims(v)
+	return _u
+}
+
+// ClearCustomClaims clears the value of the "custom_claims" field.
+func (_u *OAuth2ClientUpdate) ClearCustomClaims() *OAuth2ClientUpdate {
+	_u.mutation.ClearCustomClaims()
+	return _u
+}
+
+// SetRefreshTokenReuse sets the "refresh_token_reuse" field.
+func (_u *OAuth2ClientUpdate) SetRefreshTokenReuse(v *storage.RefreshTokenReusePolicy) *OAuth2ClientUpdate {
+	_u.mutation.SetRefreshTokenReuse(v)
+	return _u
+}
+
+// ClearRefreshTokenReuse clears the value of the "refresh_token_reuse" field.
+func (_u *OAuth2ClientUpdate) ClearRefreshTokenReuse() *OAuth2ClientUpdate {
+	_u.mutation.ClearRefreshTokenReuse()
+	return _u
+}
+
+// SetDeviceFlow sets the "device_flow" field.
+func (_u *OAuth2ClientUpdate) SetDeviceFlow(v *storage.DeviceFlowConfig) *OAuth2ClientUpdate {
+	_u.mutation.SetDeviceFlow(v)
+	return _u
+}
+
+// ClearDeviceFlow clears the value of the "device_flow" field.
+func (_u *OAuth2ClientUpdate) ClearDeviceFlow() *OAuth2ClientUpdate {
+	_u.mutation.ClearDeviceFlow()
+	return _u
+}
+
+// SetAllowedOrigins sets the "allowed_origins" field.
+func (_u *OAuth2ClientUpdate) SetAllowedOrigins(v []string) *OAuth2ClientUpdate {
+	_u.mutation.SetAllowedOrigins(v)
+	return _u
+}
+
+// ClearAllowedOrigins clears the value of the "allowed_origins" field.
+func (_u *OAuth2ClientUpdate) ClearAllowedOrigins() *OAuth2ClientUpdate {
+	_u.mutation.ClearAllowedOrigins()
+	return _u
+}
+
+// SetReleasedConnectorClaims sets the "released_connector_claims" field.
+func (_u *OAuth2ClientUpdate) SetReleasedConnectorClaims(v []string) *OAuth2ClientUpdate {
+	_u.mutation.SetReleasedConnectorClaims(v)
+	return _u
+}
+
+// ClearReleasedConnectorClaims clears the value of the "released_connector_claims" field.
+func (_u *OAuth2ClientUpdate) ClearReleasedConnectorClaims() *OAuth2ClientUpdate {
+	_u.mutation.ClearReleasedConnectorClaims()
+	return _u
+}
+
+// SetGroupsClaim sets the "groups_claim" field.
+func (_u *OAuth2ClientUpdate) SetGroupsClaim(v *storage.GroupsClaim) *OAuth2ClientUpdate {
+	_u.mutation.SetGroupsClaim(v)
+	return _u
+}
+
+// ClearGroupsClaim clears the value of the "groups_claim" field.
+func (_u *OAuth2ClientUpdate) ClearGroupsClaim() *OAuth2ClientUpdate {
+	_u.mutation.ClearGroupsClaim()
+	return _u
+}
+
+// SetTerms sets the "terms" field.
+func (_u *OAuth2ClientUpdate) SetTerms(v *storage.ClientTerms) *OAuth2ClientUpdate {
+	_u.mutation.SetTerms(v)
+	return _u
+}
+
+// ClearTerms clears the value of the "terms" field.
+func (_u *OAuth2ClientUpdate) ClearTerms() *OAuth2ClientUpdate {
+	_u.mutation.ClearTerms()
+	return _u
+}
+
+// SetImpersonation sets the "impersonation" field.
+func (_u *OAuth2ClientUpdate) SetImpersonation(v *storage.ImpersonationPolicy) *OAuth2ClientUpdate {
+	_u.mutation.SetImpersonation(v)
+	return _u
+}
+
+// ClearImpersonation clears the value of the "impersonation" field.
+func (_u *OAuth2ClientUpdate) ClearImpersonation() *OAuth2ClientUpdate {
+	_u.mutation.ClearImpersonation()
+	return _u
+}
+
 // Mutation returns the OAuth2ClientMutation object of the builder.
 func (_u *OAuth2ClientUpdate) Mutation() *OAuth2ClientMutation {
 	return _u.mutation
@@ -334,6 +511,90 @@ func (_u *OAuth2ClientUpdate) sqlSave(ctx context.Context) (_node int, err error
 	if _u.mutation.SSOSharedWithCleared() {
 		_spec.ClearField(oauth2client.FieldSSOSharedWith, field.TypeJSON)
 	}
+	if value, ok := _u.mutation.Theme(); ok {
+		_spec.SetField(oauth2client.FieldTheme, field.TypeJSON, value)
+	}
+	if _u.mutation.ThemeCleared() {
+		_spec.ClearField(oauth2client.FieldTheme, field.TypeJSON)
+	}
+	if value, ok := _u.mutation.RequireConsentOnClaimChange(); ok {
+		_spec.SetField(oauth2client.FieldRequireConsentOnClaimChange, field.TypeBool, value)
+	}
+	if _u.mutation.RequireConsentOnClaimChangeCleared() {
+		_spec.ClearField(oauth2client.FieldRequireConsentOnClaimChange, field.TypeBool)
+	}
+	if value, ok := _u.mutation.ClientCredentials(); ok {
+		_spec.SetField(oauth2client.FieldClientCredentials, field.TypeJSON, value)
+	}
+	if _u.mutation.ClientCredentialsCleared() {
+		_spec.ClearField(oauth2client.FieldClientCredentials, field.TypeJSON)
+	}
+	if value, ok := _u.mutation.JWTBearerIssuers(); ok {
+		_spec.SetField(oauth2client.FieldJWTBearerIssuers, field.TypeJSON, value)
+	}
+	if _u.mutation.JWTBearerIssuersCleared() {
+		_spec.ClearField(oauth2client.FieldJWTBearerIssuers, field.TypeJSON)
+	}
+	if value, ok := _u.mutation.TokenExchange(); ok {
+		_spec.SetField(oauth2client.FieldTokenExchange, field.TypeJSON, value)
+	}
+	if _u.mutation.TokenExchangeCleared() {
+		_spec.ClearField(oauth2client.FieldTokenExchange, field.TypeJSON)
+	}
+	if value, ok := _u.mutation.Resources(); ok {
+		_spec.SetField(oauth2client.FieldResources, field.TypeJSON, value)
+	}
+	if _u.mutation.ResourcesCleared() {
+		_spec.ClearField(oauth2client.FieldResources, field.TypeJSON)
+	}
+	if value, ok := _u.mutation.CustomClaims(); ok {
+		_spec.SetField(oauth2client.FieldCustomClaims, field.TypeJSON, value)
+	}
+	if _u.mutation.CustomClaimsCleared() {
+		_spec.ClearField(oauth2client.FieldCustomClaims, field.TypeJSON)
+	}
+	if value, ok := _u.mutation.RefreshTokenReuse(); ok {
+		_spec.SetField(oauth2client.FieldRefreshTokenReuse, field.TypeJSON, value)
+	}
+	if _u.mutation.RefreshTokenReuseCleared() {
+		_spec.ClearField(oauth2client.FieldRefreshTokenReuse, field.TypeJSON)
+	}
+	if value, ok := _u.mutation.DeviceFlow(); ok {
+		_spec.SetField(oauth2client.FieldDeviceFlow, field.TypeJSON, value)
+	}
+	if _u.mutation.DeviceFlowCleared() {
+		_spec.ClearField(oauth2client.FieldDeviceFlow, field.TypeJSON)
+	}
+	if value, ok := _u.mutation.AllowedOrigins(); ok {
+		_spec.SetField(oauth2client.FieldAllowedOrigins, field.TypeJSON, value)
+	}
+	if _u.mutation.AllowedOriginsCleared() {
+		_spec.ClearField(oauth2client.FieldAllowedOrigins, field.TypeJSON)
+	}
+	if value, ok := _u.mutation.ReleasedConnectorClaims(); ok {
+		_spec.SetField(oauth2client.FieldReleasedConnectorClaims, field.TypeJSON, value)
+	}
+	if _u.mutation.ReleasedConnectorClaimsCleared() {
+		_spec.ClearField(oauth2client.FieldReleasedConnectorClaims, field.TypeJSON)
+	}
+	if value, ok := _u.mutation.GroupsClaim(); ok {
+		_spec.SetField(oauth2client.FieldGroupsClaim, field.TypeJSON, value)
+	}
+	if _u.mutation.GroupsClaimCleared() {
+		_spec.ClearField(oauth2client.FieldGroupsClaim, field.TypeJSON)
+	}
+	if value, ok := _u.mutation.Terms(); ok {
+		_spec.SetField(oauth2client.FieldTerms, field.TypeJSON, value)
+	}
+	if _u.mutation.TermsCleared() {
+		_spec.ClearField(oauth2client.FieldTerms, field.TypeJSON)
+	}
+	if value, ok := _u.mutation.Impersonation(); ok {
+		_spec.SetField(oauth2client.FieldImpersonation, field.TypeJSON, value)
+	}
+	if _u.mutation.ImpersonationCleared() {
+		_spec.ClearField(oauth2client.FieldImpersonation, field.TypeJSON)
+	}
 	if _node, err = sqlgraph.UpdateNodes(ctx, _u.driver, _spec); err != nil {
 		if _, ok := err.(*sqlgraph.NotFoundError); ok {
 			err = &NotFoundError{oauth2client.Label}
@@ -518,6 +779,182 @@ func (_u *OAuth2ClientUpdateOne) ClearSSOSharedWith() *OAuth2ClientUpdateOne {
 	return _u
 }
 
+// SetTheme sets the "theme" field.
+func (_u *OAuth2ClientUpdateOne) SetTheme(v *storage.ClientTheme) *OAuth2ClientUpdateOne {
+	_u.mutation.SetTheme(v)
+	return _u
+}
+
+// ClearTheme clears the value of the "theme" field.
+func (_u *OAuth2ClientUpdateOne) ClearTheme() *OAuth2ClientUpdateOne {
+	_u.mutation.ClearTheme()
+	return _u
+}
+
+// SetRequireConsentOnClaimChange sets the "require_consent_on_claim_change" field.
+func (_u *OAuth2ClientUpdateOne) SetRequireConsentOnClaimChange(v bool) *OAuth2ClientUpdateOne {
+	_u.mutation.SetRequireConsentOnClaimChange(v)
+	return _u
+}
+
+// SetNillableRequireConsentOnClaimChange sets the "require_consent_on_claim_change" field if the given value is not nil.
+func (_u *OAuth2ClientUpdateOne) SetNillableRequireConsentOnClaimChange(v *bool) *OAuth2ClientUpdateOne {
+	if v != nil {
+		_u.SetRequireConsentOnClaimChange(*v)
+	}
+	return _u
+}
+
+// ClearRequireConsentOnClaimChange clears the value of the "require_consent_on_claim_change" field.
+func (_u *OAuth2ClientUpdateOne) ClearRequireConsentOnClaimChange() *OAuth2ClientUpdateOne {
+	_u.mutation.ClearRequireConsentOnClaimChange()
+	return _u
+}
+
+// SetClientCredentials sets the "client_credentials" field.
+func (_u *OAuth2ClientUpdateOne) SetClientCredentials(v *storage.ClientCredentialsConfig) *OAuth2ClientUpdateOne {
+	_u.mutation.SetClientCredentials(v)
+	return _u
+}
+
+// ClearClientCredentials clears the value of the "client_credentials" field.
+func (_u *OAuth2ClientUpdateOne) ClearClientCredentials() *OAuth2ClientUpdateOne {
+	_u.mutation.ClearClientCredentials()
+	return _u
+}
+
+// SetJWTBearerIssuers sets the "jwt_bearer_issuers" field.
+func (_u *OAuth2ClientUpdateOne) SetJWTBearerIssuers(v []storage.JWTBearerIssuer) *OAuth2ClientUpdateOne {
+	_u.mutation.SetJWTBearerIssuers(v)
+	return _u
+}
+
+// ClearJWTBearerIssuers clears the value of the "jwt_bearer_issuers" field.
+func (_u *OAuth2ClientUpdateOne) ClearJWTBearerIssuers() *OAuth2ClientUpdateOne {
+	_u.mutation.ClearJWTBearerIssuers()
+	return _u
+}
+
+// SetTokenExchange sets the "token_exchange" field.
+func (_u *OAuth2ClientUpdateOne) SetTokenExchange(v *storage.TokenExchangePolicy) *OAuth2ClientUpdateOne {
+	_u.mutation.SetTokenExchange(v)
+	return _u
+}
+
+// ClearTokenExchange clears the value of the "token_exchange" field.
+func (_u *OAuth2ClientUpdateOne) ClearTokenExchange() *OAuth2ClientUpdateOne {
+	_u.mutation.ClearTokenExchange()
+	return _u
+}
+
+// SetResources sets the "resources" field.
+func (_u *OAuth2ClientUpdateOne) SetResources(v []string) *OAuth2ClientUpdateOne {
+	_u.mutation.SetResources(v)
+	return _u
+}
+
+// ClearResources clears the value of the "resources" field.
+func (_u *OAuth2ClientUpdateOne) ClearResources() *OAuth2ClientUpdateOne {
+	_u.mutation.ClearResources()
+	return _u
+}
+
+// SetCustomClaims sets the "custom_claims" field.
+func (_u *OAuth2ClientUpdateOne) SetCustomClaims(v map[string]interface{}) *OAuth2ClientUpdateOne {
+	_u.mutation.SetCustomClaims(v)
+	return _u
+}
+
+// ClearCustomClaims clears the value of the "custom_claims" field.
+func (_u *OAuth2ClientUpdateOne) ClearCustomClaims() *OAuth2ClientUpdateOne {
+	_u.mutation.ClearCustomClaims()
+	return _u
+}
+
+// SetRefreshTokenReuse sets the "refresh_token_reuse" field.
+func (_u *OAuth2ClientUpdateOne) SetRefreshTokenReuse(v *storage.RefreshTokenReusePolicy) *OAuth2ClientUpdateOne {
+	_u.mutation.SetRefreshTokenReuse(v)
+	return _u
+}
+
+// ClearRefreshTokenReuse clears the value of the "refresh_token_reuse" field.
+func (_u *OAuth2ClientUpdateOne) ClearRefreshTokenReuse() *OAuth2ClientUpdateOne {
+	_u.mutation.ClearRefreshTokenReuse()
+	return _u
+}
+
+// SetDeviceFlow sets the "device_flow" field.
+func (_u *OAuth2ClientUpdateOne) SetDeviceFlow(v *storage.DeviceFlowConfig) *OAuth2ClientUpdateOne {
+	_u.mutation.SetDeviceFlow(v)
+	return _u
+}
+
+// ClearDeviceFlow clears the value of the "device_flow" field.
+func (_u *OAuth2ClientUpdateOne) ClearDeviceFlow() *OAuth2ClientUpdateOne {
+	_u.mutation.ClearDeviceFlow()
+	return _u
+}
+
+// SetAllowedOrigins sets the "allowed_origins" field.
+func (_u *OAuth2ClientUpdateOne) SetAllowedOrigins(v []string) *OAuth2ClientUpdateOne {
+	_u.mutation.SetAllowedOrigins(v)
+	return _u
+}
+
+// ClearAllowedOrigins clears the value of the "allowed_origins" field.
+func (_u *OAuth2ClientUpdateOne) ClearAllowedOrigins() *OAuth2ClientUpdateOne {
+	_u.mutation.ClearAllowedOrigins()
+	return _u
+}
+
+// SetReleasedConnectorClaims sets the "released_connector_claims" field.
+func (_u *OAuth2ClientUpdateOne) SetReleasedConnectorClaims(v []string) *OAuth2ClientUpdateOne {
+	_u.mutation.SetReleasedConnectorClaims(v)
+	return _u
+}
+
+// ClearReleasedConnectorClaims clears the value of the "released_connector_claims" field.
+func (_u *OAuth2ClientUpdateOne) ClearReleasedConnectorClaims() *OAuth2ClientUpdateOne {
+	_u.mutation.ClearReleasedConnectorClaims()
+	return _u
+}
+
+// SetGroupsClaim sets the "groups_claim" field.
+func (_u *OAuth2ClientUpdateOne) SetGroupsClaim(v *storage.GroupsClaim) *OAuth2ClientUpdateOne {
+	_u.mutation.SetGroupsClaim(v)
+	return _u
+}
+
+// ClearGroupsClaim clears the value of the "groups_claim" field.
+func (_u *OAuth2ClientUpdateOne) ClearGroupsClaim() *OAuth2ClientUpdateOne {
+	_u.mutation.ClearGroupsClaim()
+	return _u
+}
+
+// SetTerms sets the "terms" field.
+func (_u *OAuth2ClientUpdateOne) SetTerms(v *storage.ClientTerms) *OAuth2ClientUpdateOne {
+	_u.mutation.SetTerms(v)
+	return _u
+}
+
+// ClearTerms clears the value of the "terms" field.
+func (_u *OAuth2ClientUpdateOne) ClearTerms() *OAuth2ClientUpdateOne {
+	_u.mutation.ClearTerms()
+	return _u
+}
+
+// SetImpersonation sets the "impersonation" field.
+func (_u *OAuth2ClientUpdateOne) SetImpersonation(v *storage.ImpersonationPolicy) *OAuth2ClientUpdateOne {
+	_u.mutation.SetImpersonation(v)
+	return _u
+}
+
+// ClearImpersonation clears the value of the "impersonation" field.
+func (_u *OAuth2ClientUpdateOne) ClearImpersonation() *OAuth2ClientUpdateOne {
+	_u.mutation.ClearImpersonation()
+	return _u
+}
+
 // Mutation returns the OAuth2ClientMutation object of the builder.
 func (_u *OAuth2ClientUpdateOne) Mutation() *OAuth2ClientMutation {
 	return _u.mutation
@@ -690,6 +1127,90 @@ func (_u *OAuth2ClientUpdateOne) sqlSave(ctx context.Context) (_node *OAuth2Clie
 	if _u.mutation.SSOSharedWithCleared() {
 		_spec.ClearField(oauth2client.FieldSSOSharedWith, field.TypeJSON)
 	}
+	if value, ok := _u.mutation.Theme(); ok {
+		_spec.SetField(oauth2client.FieldTheme, field.TypeJSON, value)
+	}
+	if _u.mutation.ThemeCleared() {
+		_spec.ClearField(oauth2client.FieldTheme, field.TypeJSON)
+	}
+	if value, ok := _u.mutation.RequireConsentOnClaimChange(); ok {
+		_spec.SetField(oauth2client.FieldRequireConsentOnClaimChange, field.TypeBool, value)
+	}
+	if _u.mutation.RequireConsentOnClaimChangeCleared() {
+		_spec.ClearField(oauth2client.FieldRequireConsentOnClaimChange, field.TypeBool)
+	}
+	if value, ok := _u.mutation.ClientCredentials(); ok {
+		_spec.SetField(oauth2client.FieldClientCredentials, field.TypeJSON, value)
+	}
+	if _u.mutation.ClientCredentialsCleared() {
+		_spec.ClearField(oauth2client.FieldClientCredentials, field.TypeJSON)
+	}
+	if value, ok := _u.mutation.JWTBearerIssuers(); ok {
+		_spec.SetField(oauth2client.FieldJWTBearerIssuers, field.TypeJSON, value)
+	}
+	if _u.mutation.JWTBearerIssuersCleared() {
+		_spec.ClearField(oauth2client.FieldJWTBearerIssuers, field.TypeJSON)
+	}
+	if value, ok := _u.mutation.TokenExchange(); ok {
+		_spec.SetField(oauth2client.FieldTokenExchange, field.TypeJSON, value)
+	}
+	if _u.mutation.TokenExchangeCleared() {
+		_spec.ClearField(oauth2client.FieldTokenExchange, field.TypeJSON)
+	}
+	if value, ok := _u.mutation.Resources(); ok {
+		_spec.SetField(oauth2client.FieldResources, field.TypeJSON, value)
+	}
+	if _u.mutation.ResourcesCleared() {
+		_spec.ClearField(oauth2client.FieldResources, field.TypeJSON)
+	}
+	if value, ok := _u.mutation.CustomClaims(); ok {
+		_spec.SetField(oauth2client.FieldCustomClaims, field.TypeJSON, value)
+	}
+	if _u.mutation.CustomClaimsCleared() {
+		_spec.ClearField(oauth2client.FieldCustomClaims, field.TypeJSON)
+	}
+	if value, ok := _u.mutation.RefreshTokenReuse(); ok {
+		_spec.SetField(oauth2client.FieldRefreshTokenReuse, field.TypeJSON, value)
+	}
+	if _u.mutation.RefreshTokenReuseCleared() {
+		_spec.ClearField(oauth2client.FieldRefreshTokenReuse, field.TypeJSON)
+	}
+	if value, ok := _u.mutation.DeviceFlow(); ok {
+		_spec.SetField(oauth2client.FieldDeviceFlow, field.TypeJSON, value)
+	}
+	if _u.mutation.DeviceFlowCleared() {
+		_spec.ClearField(oauth2client.FieldDeviceFlow, field.TypeJSON)
+	}
+	if value, ok := _u.mutation.AllowedOrigins(); ok {
+		_spec.SetField(oauth2client.FieldAllowedOrigins, field.TypeJSON, value)
+	}
+	if _u.mutation.AllowedOriginsCleared() {
+		_spec.ClearField(oauth2client.FieldAllowedOrigins, field.TypeJSON)
+	}
+	if value, ok := _u.mutation.ReleasedConnectorClaims(); ok {
+		_spec.SetField(oauth2client.FieldReleasedConnectorClaims, field.TypeJSON, value)
+	}
+	if _u.mutation.ReleasedConnectorClaimsCleared() {
+		_spec.ClearField(oauth2client.FieldReleasedConnectorClaims, field.TypeJSON)
+	}
+	if value, ok := _u.mutation.GroupsClaim(); ok {
+		_spec.SetField(oauth2client.FieldGroupsClaim, field.TypeJSON, value)
+	}
+	if _u.mutation.GroupsClaimCleared() {
+		_spec.ClearField(oauth2client.FieldGroupsClaim, field.TypeJSON)
+	}
+	if value, ok := _u.mutation.Terms(); ok {
+		_spec.SetField(oauth2client.FieldTerms, field.TypeJSON, value)
+	}
+	if _u.mutation.TermsCleared() {
+		_spec.ClearField(oauth2client.FieldTerms, field.TypeJSON)
+	}
+	if value, ok := _u.mutation.Impersonation(); ok {
+		_spec.SetField(oauth2client.FieldImpersonation, field.TypeJSON, value)
+	}
+	if _u.mutation.ImpersonationCleared() {
+		_spec.ClearField(oauth2client.FieldImpersonation, field.TypeJSON)
+	}
 	_node = &OAuth2Client{config: _u.config}
 	_spec.Assign = _node.assignValues
 	_spec.ScanValues = _node.scanValues
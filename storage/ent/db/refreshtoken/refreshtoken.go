@@ -43,6 +43,16 @@ const (
 	FieldCreatedAt = "created_at"
 	// FieldLastUsed holds the string denoting the last_used field in the database.
 	FieldLastUsed = "last_used"
+	// FieldResources holds the string denoting the resources field in the database.
+	FieldResources = "resources"
+	// FieldClaimsCustom holds the string denoting the claims_custom field in the database.
+	FieldClaimsCustom = "claims_custom"
+	// FieldParentID holds the string denoting the parent_id field in the database.
+	FieldParentID = "parent_id"
+	// FieldAudience holds the string denoting the audience field in the database.
+	FieldAudience = "audience"
+	// FieldRotationKey holds the string denoting the rotation_key field in the database.
+	FieldRotationKey = "rotation_key"
 	// Table holds the table name of the refreshtoken in the database.
 	Table = "refresh_tokens"
 )
@@ -65,6 +75,11 @@ var Columns = []string{
 	FieldObsoleteToken,
 	FieldCreatedAt,
 	FieldLastUsed,
+	FieldResources,
+	FieldClaimsCustom,
+	FieldParentID,
+	FieldAudience,
+	FieldRotationKey,
 }
 
 // ValidColumn reports if the column name is valid (part of the table columns).
@@ -171,3 +186,13 @@ func ByCreatedAt(opts ...sql.OrderTermOption) OrderOption {
 func ByLastUsed(opts ...sql.OrderTermOption) OrderOption {
 	return sql.OrderByField(FieldLastUsed, opts...).ToFunc()
 }
+
+// ByParentID orders the results by the parent_id field.
+func ByParentID(opts ...sql.OrderTermOption) OrderOption {
+	return sql.OrderByField(FieldParentID, opts...).ToFunc()
+}
+
+// ByRotationKey orders the results by the rotation_key field.
+func ByRotationKey(opts ...sql.OrderTermOption) OrderOption {
+	return sql.OrderByField(FieldRotationKey, opts...).ToFunc()
+}
@@ -150,6 +150,52 @@ func (_c *RefreshTokenCreate) SetNillableLastUsed(v *time.Time) *RefreshTokenCre
 	return _c
 }
 
+// SetResources sets the "resources" field.
+func (_c *RefreshTokenCreate) SetResources(v []string) *RefreshTokenCreate {
+	_c.mutation.SetResources(v)
+	return _c
+}
+
+// SetClaimsCustom sets the "claims_custom" field.
+func (_c *RefreshTokenCreate) SetClaimsCustom(v map[string]interface{}) *RefreshTokenCreate {
+	_c.mutation.SetClaimsCustom(v)
+	return _c
+}
+
+// SetParentID sets the "parent_id" field.
+func (_c *RefreshTokenCreate) SetParentID(v string) *RefreshTokenCreate {
+	_c.mutation.SetParentID(v)
+	return _c
+}
+
+// SetNillableParentID sets the "parent_id" field if the given value is not nil.
+func (_c *RefreshTokenCreate) SetNillableParentID(v *string) *RefreshTokenCreate {
+	if v != nil {
+		_c.SetParentID(*v)
+	}
+	return _c
+}
+
+// SetAudience sets the "audience" field.
+func (_c *RefreshTokenCreate) SetAudience(v []string) *RefreshTokenCreate {
+	_c.mutation.SetAudience(v)
+	return _c
+}
+
+// SetRotationKey sets the "rotation_key" field.
+func (_c *RefreshTokenCreate) SetRotationKey(v string) *RefreshTokenCreate {
+	_c.mutation.SetRotationKey(v)
+	return _c
+}
+
+// SetNillableRotationKey sets the "rotation_key" field if the given value is not nil.
+func (_c *RefreshTokenCreate) SetNillableRotationKey(v *string) *RefreshTokenCreate {
+	if v != nil {
+		_c.SetRotationKey(*v)
+	}
+	return _c
+}
+
 // SetID sets the "id" field.
 func (_c *RefreshTokenCreate) SetID(v string) *RefreshTokenCreate {
 	_c.mutation.SetID(v)
@@ -381,6 +427,26 @@ func (_c *RefreshTokenCreate) createSpec() (*RefreshToken, *sqlgraph.CreateSpec)
 		_spec.SetField(refreshtoken.FieldLastUsed, field.TypeTime, value)
 		_node.LastUsed = value
 	}
+	if value, ok := _c.mutation.Resources(); ok {
+		_spec.SetField(refreshtoken.FieldResources, field.TypeJSON, value)
+		_node.Resources = value
+	}
+	if value, ok := _c.mutation.ClaimsCustom(); ok {
+		_spec.SetField(refreshtoken.FieldClaimsCustom, field.TypeJSON, value)
+		_node.ClaimsCustom = value
+	}
+	if value, ok := _c.mutation.ParentID(); ok {
+		_spec.SetField(refreshtoken.FieldParentID, field.TypeString, value)
+		_node.ParentID = value
+	}
+	if value, ok := _c.mutation.Audience(); ok {
+		_spec.SetField(refreshtoken.FieldAudience, field.TypeJSON, value)
+		_node.Audience = value
+	}
+	if value, ok := _c.mutation.RotationKey(); ok {
+		_spec.SetField(refreshtoken.FieldRotationKey, field.TypeString, value)
+		_node.RotationKey = value
+	}
 	return _node, _spec
 }
 
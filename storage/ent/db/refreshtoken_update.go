@@ -245,6 +245,82 @@ func (_u *RefreshTokenUpdate) SetNillableLastUsed(v *time.Time) *RefreshTokenUpd
 	return _u
 }
 
+// SetResources sets the "resources" field.
+func (_u *RefreshTokenUpdate) SetResources(v []string) *RefreshTokenUpdate {
+	_u.mutation.SetResources(v)
+	return _u
+}
+
+// ClearResources clears the value of the "resources" field.
+func (_u *RefreshTokenUpdate) ClearResources() *RefreshTokenUpdate {
+	_u.mutation.ClearResources()
+	return _u
+}
+
+// SetClaimsCustom sets the "claims_custom" field.
+func (_u *RefreshTokenUpdate) SetClaimsCustom(v map[string]interface{}) *RefreshTokenUpdate {
+	_u.mutation.SetClaimsCustom(v)
+	return _u
+}
+
+// ClearClaimsCustom clears the value of the "claims_custom" field.
+func (_u *RefreshTokenUpdate) ClearClaimsCustom() *RefreshTokenUpdate {
+	_u.mutation.ClearClaimsCustom()
+	return _u
+}
+
+// SetParentID sets the "parent_id" field.
+func (_u *RefreshTokenUpdate) SetParentID(v string) *RefreshTokenUpdate {
+	_u.mutation.SetParentID(v)
+	return _u
+}
+
+// SetNillableParentID sets the "parent_id" field if the given value is not nil.
+func (_u *RefreshTokenUpdate) SetNillableParentID(v *string) *RefreshTokenUpdate {
+	if v != nil {
+		_u.SetParentID(*v)
+	}
+	return _u
+}
+
+// ClearParentID clears the value of the "parent_id" field.
+func (_u *RefreshTokenUpdate) ClearParentID() *RefreshTokenUpdate {
+	_u.mutation.ClearParentID()
+	return _u
+}
+
+// SetAudience sets the "audience" field.
+func (_u *RefreshTokenUpdate) SetAudience(v []string) *RefreshTokenUpdate {
+	_u.mutation.SetAudience(v)
+	return _u
+}
+
+// ClearAudience clears the value of the "audience" field.
+func (_u *RefreshTokenUpdate) ClearAudience() *RefreshTokenUpdate {
+	_u.mutation.ClearAudience()
+	return _u
+}
+
+// SetRotationKey sets the "rotation_key" field.
+func (_u *RefreshTokenUpdate) SetRotationKey(v string) *RefreshTokenUpdate {
+	_u.mutation.SetRotationKey(v)
+	return _u
+}
+
+// SetNillableRotationKey sets the "rotation_key" field if the given value is not nil.
+func (_u *RefreshTokenUpdate) SetNillableRotationKey(v *string) *RefreshTokenUpdate {
+	if v != nil {
+		_u.SetRotationKey(*v)
+	}
+	return _u
+}
+
+// ClearRotationKey clears the value of the "rotation_key" field.
+func (_u *RefreshTokenUpdate) ClearRotationKey() *RefreshTokenUpdate {
+	_u.mutation.ClearRotationKey()
+	return _u
+}
+
 // Mutation returns the RefreshTokenMutation object of the builder.
 func (_u *RefreshTokenUpdate) Mutation() *RefreshTokenMutation {
 	return _u.mutation
@@ -388,6 +464,36 @@ func (_u *RefreshTokenUpdate) sqlSave(ctx context.Context) (_node int, err error
 	if value, ok := _u.mutation.LastUsed(); ok {
 		_spec.SetField(refreshtoken.FieldLastUsed, field.TypeTime, value)
 	}
+	if value, ok := _u.mutation.Resources(); ok {
+		_spec.SetField(refreshtoken.FieldResources, field.TypeJSON, value)
+	}
+	if _u.mutation.ResourcesCleared() {
+		_spec.ClearField(refreshtoken.FieldResources, field.TypeJSON)
+	}
+	if value, ok := _u.mutation.ClaimsCustom(); ok {
+		_spec.SetField(refreshtoken.FieldClaimsCustom, field.TypeJSON, value)
+	}
+	if _u.mutation.ClaimsCustomCleared() {
+		_spec.ClearField(refreshtoken.FieldClaimsCustom, field.TypeJSON)
+	}
+	if value, ok := _u.mutation.ParentID(); ok {
+		_spec.SetField(refreshtoken.FieldParentID, field.TypeString, value)
+	}
+	if _u.mutation.ParentIDCleared() {
+		_spec.ClearField(refreshtoken.FieldParentID, field.TypeString)
+	}
+	if value, ok := _u.mutation.Audience(); ok {
+		_spec.SetField(refreshtoken.FieldAudience, field.TypeJSON, value)
+	}
+	if _u.mutation.AudienceCleared() {
+		_spec.ClearField(refreshtoken.FieldAudience, field.TypeJSON)
+	}
+	if value, ok := _u.mutation.RotationKey(); ok {
+		_spec.SetField(refreshtoken.FieldRotationKey, field.TypeString, value)
+	}
+	if _u.mutation.RotationKeyCleared() {
+		_spec.ClearField(refreshtoken.FieldRotationKey, field.TypeString)
+	}
 	if _node, err = sqlgraph.UpdateNodes(ctx, _u.driver, _spec); err != nil {
 		if _, ok := err.(*sqlgraph.NotFoundError); ok {
 			err = &NotFoundError{refreshtoken.Label}
@@ -624,6 +730,82 @@ func (_u *RefreshTokenUpdateOne) SetNillableLastUsed(v *time.Time) *RefreshToken
 	return _u
 }
 
+// SetResources sets the "resources" field.
+func (_u *RefreshTokenUpdateOne) SetResources(v []string) *RefreshTokenUpdateOne {
+	_u.mutation.SetResources(v)
+	return _u
+}
+
+// ClearResources clears the value of the "resources" field.
+func (_u *RefreshTokenUpdateOne) ClearResources() *RefreshTokenUpdateOne {
+	_u.mutation.ClearResources()
+	return _u
+}
+
+// SetClaimsCustom sets the "claims_custom" field.
+func (_u *RefreshTokenUpdateOne) SetClaimsCustom(v map[string]interface{}) *RefreshTokenUpdateOne {
+	_u.mutation.SetClaimsCustom(v)
+	return _u
+}
+
+// ClearClaimsCustom clears the value of the "claims_custom" field.
+func (_u *RefreshTokenUpdateOne) ClearClaimsCustom() *RefreshTokenUpdateOne {
+	_u.mutation.ClearClaimsCustom()
+	return _u
+}
+
+// SetParentID sets the "parent_id" field.
+func (_u *RefreshTokenUpdateOne) SetParentID(v string) *RefreshTokenUpdateOne {
+	_u.mutation.SetParentID(v)
+	return _u
+}
+
+// SetNillableParentID sets the "parent_id" field if the given value is not nil.
+func (_u *RefreshTokenUpdateOne) SetNillableParentID(v *string) *RefreshTokenUpdateOne {
+	if v != nil {
+		_u.SetParentID(*v)
+	}
+	return _u
+}
+
+// ClearParentID clears the value of the "parent_id" field.
+func (_u *RefreshTokenUpdateOne) ClearParentID() *RefreshTokenUpdateOne {
+	_u.mutation.ClearParentID()
+	return _u
+}
+
+// SetAudience sets the "audience" field.
+func (_u *RefreshTokenUpdateOne) SetAudience(v []string) *RefreshTokenUpdateOne {
+	_u.mutation.SetAudience(v)
+	return _u
+}
+
+// ClearAudience clears the value of the "audience" field.
+func (_u *RefreshTokenUpdateOne) ClearAudience() *RefreshTokenUpdateOne {
+	_u.mutation.ClearAudience()
+	return _u
+}
+
+// SetRotationKey sets the "rotation_key" field.
+func (_u *RefreshTokenUpdateOne) SetRotationKey(v string) *RefreshTokenUpdateOne {
+	_u.mutation.SetRotationKey(v)
+	return _u
+}
+
+// SetNillableRotationKey sets the "rotation_key" field if the given value is not nil.
+func (_u *RefreshTokenUpdateOne) SetNillableRotationKey(v *string) *RefreshTokenUpdateOne {
+	if v != nil {
+		_u.SetRotationKey(*v)
+	}
+	return _u
+}
+
+// ClearRotationKey clears the value of the "rotation_key" field.
+func (_u *RefreshTokenUpdateOne) ClearRotationKey() *RefreshTokenUpdateOne {
+	_u.mutation.ClearRotationKey()
+	return _u
+}
+
 // Mutation returns the RefreshTokenMutation object of the builder.
 func (_u *RefreshTokenUpdateOne) Mutation() *RefreshTokenMutation {
 	return _u.mutation
@@ -797,6 +979,36 @@ func (_u *RefreshTokenUpdateOne) sqlSave(ctx context.Context) (_node *RefreshTok
 	if value, ok := _u.mutation.LastUsed(); ok {
 		_spec.SetField(refreshtoken.FieldLastUsed, field.TypeTime, value)
 	}
+	if value, ok := _u.mutation.Resources(); ok {
+		_spec.SetField(refreshtoken.FieldResources, field.TypeJSON, value)
+	}
+	if _u.mutation.ResourcesCleared() {
+		_spec.ClearField(refreshtoken.FieldResources, field.TypeJSON)
+	}
+	if value, ok := _u.mutation.ClaimsCustom(); ok {
+		_spec.SetField(refreshtoken.FieldClaimsCustom, field.TypeJSON, value)
+	}
+	if _u.mutation.ClaimsCustomCleared() {
+		_spec.ClearField(refreshtoken.FieldClaimsCustom, field.TypeJSON)
+	}
+	if value, ok := _u.mutation.ParentID(); ok {
+		_spec.SetField(refreshtoken.FieldParentID, field.TypeString, value)
+	}
+	if _u.mutation.ParentIDCleared() {
+		_spec.ClearField(refreshtoken.FieldParentID, field.TypeString)
+	}
+	if value, ok := _u.mutation.Audience(); ok {
+		_spec.SetField(refreshtoken.FieldAudience, field.TypeJSON, value)
+	}
+	if _u.mutation.AudienceCleared() {
+		_spec.ClearField(refreshtoken.FieldAudience, field.TypeJSON)
+	}
+	if value, ok := _u.mutation.RotationKey(); ok {
+		_spec.SetField(refreshtoken.FieldRotationKey, field.TypeString, value)
+	}
+	if _u.mutation.RotationKeyCleared() {
+		_spec.ClearField(refreshtoken.FieldRotationKey, field.TypeString)
+	}
 	_node = &RefreshToken{config: _u.config}
 	_spec.Assign = _node.assignValues
 	_spec.ScanValues = _node.scanValues
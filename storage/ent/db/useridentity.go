@@ -10,6 +10,7 @@ import (
 
 	"entgo.io/ent"
 	"entgo.io/ent/dialect/sql"
+	"github.com/dexidp/dex/storage"
 	"github.com/dexidp/dex/storage/ent/db/useridentity"
 )
 
@@ -46,7 +47,13 @@ type UserIdentity struct {
 	LastLogin time.Time `json:"last_login,omitempty"`
 	// BlockedUntil holds the value of the "blocked_until" field.
 	BlockedUntil time.Time `json:"blocked_until,omitempty"`
-	selectValues sql.SelectValues
+	// ConsentedClaims holds the value of the "consented_claims" field.
+	ConsentedClaims map[string]string `json:"consented_claims,omitempty"`
+	// ClaimsCustom holds the value of the "claims_custom" field.
+	ClaimsCustom map[string]interface{} `json:"claims_custom,omitempty"`
+	// AcceptedTerms holds the value of the "accepted_terms" field.
+	AcceptedTerms map[string]storage.TermsAcceptance `json:"accepted_terms,omitempty"`
+	selectValues  sql.SelectValues
 }
 
 // scanValues returns the types for scanning values from sql.Rows.
@@ -54,7 +61,7 @@ func (*UserIdentity) scanValues(columns []string) ([]any, error) {
 	values := make([]any, len(columns))
 	for i := range columns {
 		switch columns[i] {
-		case useridentity.FieldClaimsGroups, useridentity.FieldConsents, useridentity.FieldMfaSecrets, useridentity.FieldWebauthnCredentials:
+		case useridentity.FieldClaimsGroups, useridentity.FieldConsents, useridentity.FieldMfaSecrets, useridentity.FieldWebauthnCredentials, useridentity.FieldConsentedClaims, useridentity.FieldClaimsCustom, useridentity.FieldAcceptedTerms:
 			values[i] = new([]byte)
 		case useridentity.FieldClaimsEmailVerified:
 			values[i] = new(sql.NullBool)
@@ -169,6 +176,30 @@ func (_m *UserIdentity) assignValues(columns []string, values []any) error {
 			} else if value.Valid {
 				_m.BlockedUntil = value.Time
 			}
+		case useridentity.FieldConsentedClaims:
+			if value, ok := values[i].(*[]byte); !ok {
+				return fmt.Errorf("unexpected type %T for field consented_claims", values[i])
+			} else if value != nil && len(*value) > 0 {
+				if err := json.Unmarshal(*value, &_m.ConsentedClaims); err != nil {
+					return fmt.Errorf("unmarshal field consented_claims: %w", err)
+				}
+			}
+		case useridentity.FieldClaimsCustom:
+			if value, ok := values[i].(*[]byte); !ok {
+				return fmt.Errorf("unexpected type %T for field claims_custom", values[i])
+			} else if value != nil && len(*value) > 0 {
+				if err := json.Unmarshal(*value, &_m.ClaimsCustom); err != nil {
+					return fmt.Errorf("unmarshal field claims_custom: %w", err)
+				}
+			}
+		case useridentity.FieldAcceptedTerms:
+			if value, ok := values[i].(*[]byte); !ok {
+				return fmt.Errorf("unexpected type %T for field accepted_terms", values[i])
+			} else if value != nil && len(*value) > 0 {
+				if err := json.Unmarshal(*value, &_m.AcceptedTerms); err != nil {
+					return fmt.Errorf("unmarshal field accepted_terms: %w", err)
+				}
+			}
 		default:
 			_m.selectValues.Set(columns[i], values[i])
 		}
@@ -250,6 +281,15 @@ func (_m *UserIdentity) String() string {
 	builder.WriteString(", ")
 	builder.WriteString("blocked_until=")
 	builder.WriteString(_m.BlockedUntil.Format(time.ANSIC))
+	builder.WriteString(", ")
+	builder.WriteString("consented_claims=")
+	builder.WriteString(fmt.Sprintf("%v", _m.ConsentedClaims))
+	builder.WriteString(", ")
+	builder.WriteString("claims_custom=")
+	builder.WriteString(fmt.Sprintf("%v", _m.ClaimsCustom))
+	builder.WriteString(", ")
+	builder.WriteString("accepted_terms=")
+	builder.WriteString(fmt.Sprintf("%v", _m.AcceptedTerms))
 	builder.WriteByte(')')
 	return builder.String()
 }
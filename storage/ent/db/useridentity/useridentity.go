@@ -39,6 +39,12 @@ const (
 	FieldLastLogin = "last_login"
 	// FieldBlockedUntil holds the string denoting the blocked_until field in the database.
 	FieldBlockedUntil = "blocked_until"
+	// FieldConsentedClaims holds the string denoting the consented_claims field in the database.
+	FieldConsentedClaims = "consented_claims"
+	// FieldClaimsCustom holds the string denoting the claims_custom field in the database.
+	FieldClaimsCustom = "claims_custom"
+	// FieldAcceptedTerms holds the string denoting the accepted_terms field in the database.
+	FieldAcceptedTerms = "accepted_terms"
 	// Table holds the table name of the useridentity in the database.
 	Table = "user_identities"
 )
@@ -60,6 +66,9 @@ var Columns = []string{
 	FieldCreatedAt,
 	FieldLastLogin,
 	FieldBlockedUntil,
+	FieldConsentedClaims,
+	FieldClaimsCustom,
+	FieldAcceptedTerms,
 }
 
 // ValidColumn reports if the column name is valid (part of the table columns).
@@ -799,6 +799,36 @@ func BlockedUntilLTE(v time.Time) predicate.UserIdentity {
 	return predicate.UserIdentity(sql.FieldLTE(FieldBlockedUntil, v))
 }
 
+// ConsentedClaimsIsNil applies the IsNil predicate on the "consented_claims" field.
+func ConsentedClaimsIsNil() predicate.UserIdentity {
+	return predicate.UserIdentity(sql.FieldIsNull(FieldConsentedClaims))
+}
+
+// ConsentedClaimsNotNil applies the NotNil predicate on the "consented_claims" field.
+func ConsentedClaimsNotNil() predicate.UserIdentity {
+	return predicate.UserIdentity(sql.FieldNotNull(FieldConsentedClaims))
+}
+
+// ClaimsCustomIsNil applies the IsNil predicate on the "claims_custom" field.
+func ClaimsCustomIsNil() predicate.UserIdentity {
+	return predicate.UserIdentity(sql.FieldIsNull(FieldClaimsCustom))
+}
+
+// ClaimsCustomNotNil applies the NotNil predicate on the "claims_custom" field.
+func ClaimsCustomNotNil() predicate.UserIdentity {
+	return predicate.UserIdentity(sql.FieldNotNull(FieldClaimsCustom))
+}
+
+// AcceptedTermsIsNil applies the IsNil predicate on the "accepted_terms" field.
+func AcceptedTermsIsNil() predicate.UserIdentity {
+	return predicate.UserIdentity(sql.FieldIsNull(FieldAcceptedTerms))
+}
+
+// AcceptedTermsNotNil applies the NotNil predicate on the "accepted_terms" field.
+func AcceptedTermsNotNil() predicate.UserIdentity {
+	return predicate.UserIdentity(sql.FieldNotNull(FieldAcceptedTerms))
+}
+
 // And groups predicates with the AND operator between them.
 func And(predicates ...predicate.UserIdentity) predicate.UserIdentity {
 	return predicate.UserIdentity(sql.AndPredicates(predicates...))
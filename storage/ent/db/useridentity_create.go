@@ -10,6 +10,7 @@ import (
 
 	"entgo.io/ent/dialect/sql/sqlgraph"
 	"entgo.io/ent/schema/field"
+	"github.com/dexidp/dex/storage"
 	"github.com/dexidp/dex/storage/ent/db/useridentity"
 )
 
@@ -144,6 +145,24 @@ func (_c *UserIdentityCreate) SetBlockedUntil(v time.Time) *UserIdentityCreate {
 	return _c
 }
 
+// SetConsentedClaims sets the "consented_claims" field.
+func (_c *UserIdentityCreate) SetConsentedClaims(v map[string]string) *UserIdentityCreate {
+	_c.mutation.SetConsentedClaims(v)
+	return _c
+}
+
+// SetClaimsCustom sets the "claims_custom" field.
+func (_c *UserIdentityCreate) SetClaimsCustom(v map[string]interface{}) *UserIdentityCreate {
+	_c.mutation.SetClaimsCustom(v)
+	return _c
+}
+
+// SetAcceptedTerms sets the "accepted_terms" field.
+func (_c *UserIdentityCreate) SetAcceptedTerms(v map[string]storage.TermsAcceptance) *UserIdentityCreate {
+	_c.mutation.SetAcceptedTerms(v)
+	return _c
+}
+
 // SetID sets the "id" field.
 func (_c *UserIdentityCreate) SetID(v string) *UserIdentityCreate {
 	_c.mutation.SetID(v)
@@ -348,6 +367,18 @@ func (_c *UserIdentityCreate) createSpec() (*UserIdentity, *sqlgraph.CreateSpec)
 		_spec.SetField(useridentity.FieldBlockedUntil, field.TypeTime, value)
 		_node.BlockedUntil = value
 	}
+	if value, ok := _c.mutation.ConsentedClaims(); ok {
+		_spec.SetField(useridentity.FieldConsentedClaims, field.TypeJSON, value)
+		_node.ConsentedClaims = value
+	}
+	if value, ok := _c.mutation.ClaimsCustom(); ok {
+		_spec.SetField(useridentity.FieldClaimsCustom, field.TypeJSON, value)
+		_node.ClaimsCustom = value
+	}
+	if value, ok := _c.mutation.AcceptedTerms(); ok {
+		_spec.SetField(useridentity.FieldAcceptedTerms, field.TypeJSON, value)
+		_node.AcceptedTerms = value
+	}
 	return _node, _spec
 }
 
@@ -12,6 +12,7 @@ import (
 	"entgo.io/ent/dialect/sql/sqlgraph"
 	"entgo.io/ent/dialect/sql/sqljson"
 	"entgo.io/ent/schema/field"
+	"github.com/dexidp/dex/storage"
 	"github.com/dexidp/dex/storage/ent/db/predicate"
 	"github.com/dexidp/dex/storage/ent/db/useridentity"
 )
@@ -217,6 +218,42 @@ func (_u *UserIdentityUpdate) SetNillableBlockedUntil(v *time.Time) *UserIdentit
 	return _u
 }
 
+// SetConsentedClaims sets the "consented_claims" field.
+func (_u *UserIdentityUpdate) SetConsentedClaims(v map[string]string) *UserIdentityUpdate {
+	_u.mutation.SetConsentedClaims(v)
+	return _u
+}
+
+// ClearConsentedClaims clears the value of the "consented_claims" field.
+func (_u *UserIdentityUpdate) ClearConsentedClaims() *UserIdentityUpdate {
+	_u.mutation.ClearConsentedClaims()
+	return _u
+}
+
+// SetClaimsCustom sets the "claims_custom" field.
+func (_u *UserIdentityUpdate) SetClaimsCustom(v map[string]interface{}) *UserIdentityUpdate {
+	_u.mutation.SetClaimsCustom(v)
+	return _u
+}
+
+// ClearClaimsCustom clears the value of the "claims_custom" field.
+func (_u *UserIdentityUpdate) ClearClaimsCustom() *UserIdentityUpdate {
+	_u.mutation.ClearClaimsCustom()
+	return _u
+}
+
+// SetAcceptedTerms sets the "accepted_terms" field.
+func (_u *UserIdentityUpdate) SetAcceptedTerms(v map[string]storage.TermsAcceptance) *UserIdentityUpdate {
+	_u.mutation.SetAcceptedTerms(v)
+	return _u
+}
+
+// ClearAcceptedTerms clears the value of the "accepted_terms" field.
+func (_u *UserIdentityUpdate) ClearAcceptedTerms() *UserIdentityUpdate {
+	_u.mutation.ClearAcceptedTerms()
+	return _u
+}
+
 // Mutation returns the UserIdentityMutation object of the builder.
 func (_u *UserIdentityUpdate) Mutation() *UserIdentityMutation {
 	return _u.mutation
@@ -332,6 +369,24 @@ func (_u *UserIdentityUpdate) sqlSave(ctx context.Context) (_node int, err error
 	if value, ok := _u.mutation.BlockedUntil(); ok {
 		_spec.SetField(useridentity.FieldBlockedUntil, field.TypeTime, value)
 	}
+	if value, ok := _u.mutation.ConsentedClaims(); ok {
+		_spec.SetField(useridentity.FieldConsentedClaims, field.TypeJSON, value)
+	}
+	if _u.mutation.ConsentedClaimsCleared() {
+		_spec.ClearField(useridentity.FieldConsentedClaims, field.TypeJSON)
+	}
+	if value, ok := _u.mutation.ClaimsCustom(); ok {
+		_spec.SetField(useridentity.FieldClaimsCustom, field.TypeJSON, value)
+	}
+	if _u.mutation.ClaimsCustomCleared() {
+		_spec.ClearField(useridentity.FieldClaimsCustom, field.TypeJSON)
+	}
+	if value, ok := _u.mutation.AcceptedTerms(); ok {
+		_spec.SetField(useridentity.FieldAcceptedTerms, field.TypeJSON, value)
+	}
+	if _u.mutation.AcceptedTermsCleared() {
+		_spec.ClearField(useridentity.FieldAcceptedTerms, field.TypeJSON)
+	}
 	if _node, err = sqlgraph.UpdateNodes(ctx, _u.driver, _spec); err != nil {
 		if _, ok := err.(*sqlgraph.NotFoundError); ok {
 			err = &NotFoundError{useridentity.Label}
@@ -540,6 +595,42 @@ func (_u *UserIdentityUpdateOne) SetNillableBlockedUntil(v *time.Time) *UserIden
 	return _u
 }
 
+// SetConsentedClaims sets the "consented_claims" field.
+func (_u *UserIdentityUpdateOne) SetConsentedClaims(v map[string]string) *UserIdentityUpdateOne {
+	_u.mutation.SetConsentedClaims(v)
+	return _u
+}
+
+// ClearConsentedClaims clears the value of the "consented_claims" field.
+func (_u *UserIdentityUpdateOne) ClearConsentedClaims() *UserIdentityUpdateOne {
+	_u.mutation.ClearConsentedClaims()
+	return _u
+}
+
+// SetClaimsCustom sets the "claims_custom" field.
+func (_u *UserIdentityUpdateOne) SetClaimsCustom(v map[string]interface{}) *UserIdentityUpdateOne {
+	_u.mutation.SetClaimsCustom(v)
+	return _u
+}
+
+// ClearClaimsCustom clears the value of the "claims_custom" field.
+func (_u *UserIdentityUpdateOne) ClearClaimsCustom() *UserIdentityUpdateOne {
+	_u.mutation.ClearClaimsCustom()
+	return _u
+}
+
+// SetAcceptedTerms sets the "accepted_terms" field.
+func (_u *UserIdentityUpdateOne) SetAcceptedTerms(v map[string]storage.TermsAcceptance) *UserIdentityUpdateOne {
+	_u.mutation.SetAcceptedTerms(v)
+	return _u
+}
+
+// ClearAcceptedTerms clears the value of the "accepted_terms" field.
+func (_u *UserIdentityUpdateOne) ClearAcceptedTerms() *UserIdentityUpdateOne {
+	_u.mutation.ClearAcceptedTerms()
+	return _u
+}
+
 // Mutation returns the UserIdentityMutation object of the builder.
 func (_u *UserIdentityUpdateOne) Mutation() *UserIdentityMutation {
 	return _u.mutation
@@ -685,6 +776,24 @@ func (_u *UserIdentityUpdateOne) sqlSave(ctx context.Context) (_node *UserIdenti
 	if value, ok := _u.mutation.BlockedUntil(); ok {
 		_spec.SetField(useridentity.FieldBlockedUntil, field.TypeTime, value)
 	}
+	if value, ok := _u.mutation.ConsentedClaims(); ok {
+		_spec.SetField(useridentity.FieldConsentedClaims, field.TypeJSON, value)
+	}
+	if _u.mutation.ConsentedClaimsCleared() {
+		_spec.ClearField(useridentity.FieldConsentedClaims, field.TypeJSON)
+	}
+	if value, ok := _u.mutation.ClaimsCustom(); ok {
+		_spec.SetField(useridentity.FieldClaimsCustom, field.TypeJSON, value)
+	}
+	if _u.mutation.ClaimsCustomCleared() {
+		_spec.ClearField(useridentity.FieldClaimsCustom, field.TypeJSON)
+	}
+	if value, ok := _u.mutation.AcceptedTerms(); ok {
+		_spec.SetField(useridentity.FieldAcceptedTerms, field.TypeJSON, value)
+	}
+	if _u.mutation.AcceptedTermsCleared() {
+		_spec.ClearField(useridentity.FieldAcceptedTerms, field.TypeJSON)
+	}
 	_node = &UserIdentity{config: _u.config}
 	_spec.Assign = _node.assignValues
 	_spec.ScanValues = _node.scanValues
@@ -60,6 +60,15 @@ func (m *MySQL) Open(logger *slog.Logger) (storage.Storage, error) {
 	return databaseClient, nil
 }
 
+// CheckConnection connects to the database without creating its schema.
+func (m *MySQL) CheckConnection(logger *slog.Logger) error {
+	drv, err := m.driver()
+	if err != nil {
+		return err
+	}
+	return checkConnection(drv)
+}
+
 func (m *MySQL) driver() (*entSQL.Driver, error) {
 	var tlsConfig string
 
@@ -60,6 +60,15 @@ func (p *Postgres) Open(logger *slog.Logger) (storage.Storage, error) {
 	return databaseClient, nil
 }
 
+// CheckConnection connects to the database without creating its schema.
+func (p *Postgres) CheckConnection(logger *slog.Logger) error {
+	drv, err := p.driver()
+	if err != nil {
+		return err
+	}
+	return checkConnection(drv)
+}
+
 func (p *Postgres) driver() (*entSQL.Driver, error) {
 	drv, err := entSQL.Open("postgres", p.dsn())
 	if err != nil {
@@ -84,6 +84,10 @@ func (AuthCode) Fields() []ent.Field {
 		field.Time("auth_time").
 			SchemaType(timeSchema).
 			Optional(),
+		field.JSON("resources", []string{}).
+			Optional(),
+		field.JSON("claims_custom", map[string]interface{}{}).
+			Optional(),
 	}
 }
 
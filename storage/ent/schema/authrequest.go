@@ -96,6 +96,13 @@ func (AuthRequest) Fields() []ent.Field {
 		field.Text("prompt").SchemaType(textSchema).Default(""),
 		field.Int("max_age").Default(-1),
 		field.Time("auth_time").SchemaType(timeSchema).Optional(),
+		field.JSON("resources", []string{}).
+			Optional(),
+		field.Text("login_hint").
+			SchemaType(textSchema).
+			Optional(),
+		field.JSON("claims_custom", map[string]interface{}{}).
+			Optional(),
 	}
 }
 
@@ -3,6 +3,8 @@ package schema
 import (
 	"entgo.io/ent"
 	"entgo.io/ent/schema/field"
+
+	"github.com/dexidp/dex/storage"
 )
 
 /* Original SQL table:
@@ -53,6 +55,34 @@ func (OAuth2Client) Fields() []ent.Field {
 			Optional(),
 		field.JSON("sso_shared_with", []string{}).
 			Optional(),
+		field.JSON("theme", &storage.ClientTheme{}).
+			Optional(),
+		field.Bool("require_consent_on_claim_change").
+			Optional(),
+		field.JSON("client_credentials", &storage.ClientCredentialsConfig{}).
+			Optional(),
+		field.JSON("jwt_bearer_issuers", []storage.JWTBearerIssuer{}).
+			Optional(),
+		field.JSON("token_exchange", &storage.TokenExchangePolicy{}).
+			Optional(),
+		field.JSON("resources", []string{}).
+			Optional(),
+		field.JSON("custom_claims", map[string]interface{}{}).
+			Optional(),
+		field.JSON("refresh_token_reuse", &storage.RefreshTokenReusePolicy{}).
+			Optional(),
+		field.JSON("device_flow", &storage.DeviceFlowConfig{}).
+			Optional(),
+		field.JSON("allowed_origins", []string{}).
+			Optional(),
+		field.JSON("released_connector_claims", []string{}).
+			Optional(),
+		field.JSON("groups_claim", &storage.GroupsClaim{}).
+			Optional(),
+		field.JSON("terms", &storage.ClientTerms{}).
+			Optional(),
+		field.JSON("impersonation", &storage.ImpersonationPolicy{}).
+			Optional(),
 	}
 }
 
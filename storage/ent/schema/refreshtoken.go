@@ -86,6 +86,18 @@ func (RefreshToken) Fields() []ent.Field {
 		field.Time("last_used").
 			SchemaType(timeSchema).
 			Default(time.Now),
+		field.JSON("resources", []string{}).
+			Optional(),
+		field.JSON("claims_custom", map[string]interface{}{}).
+			Optional(),
+		field.Text("parent_id").
+			SchemaType(textSchema).
+			Optional(),
+		field.JSON("audience", []string{}).
+			Optional(),
+		field.Text("rotation_key").
+			SchemaType(textSchema).
+			Optional(),
 	}
 }
 
@@ -3,6 +3,8 @@ package schema
 import (
 	"entgo.io/ent"
 	"entgo.io/ent/schema/field"
+
+	"github.com/dexidp/dex/storage"
 )
 
 // UserIdentity holds the schema definition for the UserIdentity entity.
@@ -53,6 +55,12 @@ func (UserIdentity) Fields() []ent.Field {
 			SchemaType(timeSchema),
 		field.Time("blocked_until").
 			SchemaType(timeSchema),
+		field.JSON("consented_claims", map[string]string{}).
+			Optional(),
+		field.JSON("claims_custom", map[string]interface{}{}).
+			Optional(),
+		field.JSON("accepted_terms", map[string]storage.TermsAcceptance{}).
+			Optional(),
 	}
 }
 
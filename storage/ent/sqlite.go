@@ -3,7 +3,10 @@ package ent
 import (
 	"context"
 	"crypto/sha256"
+	"errors"
+	"io/fs"
 	"log/slog"
+	"os"
 	"strings"
 
 	"entgo.io/ent/dialect/sql"
@@ -48,6 +51,25 @@ func (s *SQLite3) Open(logger *slog.Logger) (storage.Storage, error) {
 	return databaseClient, nil
 }
 
+// CheckConnection opens the database read-only, without creating it or its
+// schema, and reads from it.
+func (s *SQLite3) CheckConnection(logger *slog.Logger) error {
+	dsn := s.File
+	if !strings.HasPrefix(dsn, "file:") && !strings.Contains(dsn, "?") {
+		if _, err := os.Stat(dsn); dsn == ":memory:" || errors.Is(err, fs.ErrNotExist) {
+			// Open creates the database.
+			return nil
+		}
+		dsn = "file:" + dsn + "?mode=ro"
+	}
+
+	drv, err := sql.Open("sqlite3", dsn)
+	if err != nil {
+		return err
+	}
+	return checkConnection(drv)
+}
+
 func addFK(dsn string) string {
 	if strings.Contains(dsn, "_fk") {
 		return dsn
@@ -2,8 +2,12 @@ package ent
 
 import (
 	"log/slog"
+	"os"
+	"path/filepath"
 	"testing"
 
+	"github.com/stretchr/testify/require"
+
 	"github.com/dexidp/dex/storage"
 	"github.com/dexidp/dex/storage/conformance"
 )
@@ -23,3 +27,17 @@ func TestSQLite3(t *testing.T) {
 	conformance.RunTests(t, newSQLiteStorage)
 	conformance.RunConcurrencyTests(t, newSQLiteStorage)
 }
+
+func TestSQLite3CheckConnection(t *testing.T) {
+	logger := slog.New(slog.DiscardHandler)
+	file := filepath.Join(t.TempDir(), "dex.db")
+
+	require.NoError(t, (&SQLite3{File: file}).CheckConnection(logger))
+	_, err := os.Stat(file)
+	require.True(t, os.IsNotExist(err), "check created the database")
+
+	s, err := (&SQLite3{File: file}).Open(logger)
+	require.NoError(t, err)
+	require.NoError(t, s.Close())
+	require.NoError(t, (&SQLite3{File: file}).CheckConnection(logger))
+}
@@ -1,6 +1,12 @@
 package ent
 
-import "os"
+import (
+	"context"
+	"os"
+	"time"
+
+	entSQL "entgo.io/ent/dialect/sql"
+)
 
 func getenv(key, defaultVal string) string {
 	if val := os.Getenv(key); val != "" {
@@ -8,3 +14,14 @@ func getenv(key, defaultVal string) string {
 	}
 	return defaultVal
 }
+
+// checkConnection reads from the database of the driver and closes it.
+func checkConnection(drv *entSQL.Driver) error {
+	defer drv.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var n int
+	return drv.DB().QueryRowContext(ctx, "SELECT 1").Scan(&n)
+}
@@ -31,6 +31,11 @@ type Etcd struct {
 	Username  string   `json:"username"`
 	Password  string   `json:"password"`
 	SSL       SSL      `json:"ssl"`
+
+	// LeaseExpiry attaches expirable objects (auth requests, auth codes,
+	// device requests and device tokens) to etcd leases, so etcd deletes
+	// them on expiry without waiting for garbage collection.
+	LeaseExpiry bool `json:"leaseExpiry"`
 }
 
 // Open creates a new storage implementation backed by Etcd
@@ -84,8 +89,9 @@ func (p *Etcd) open(logger *slog.Logger) (*conn, error) {
 		db.KV = namespace.NewKV(db.KV, p.Namespace)
 	}
 	c := &conn{
-		db:     db,
-		logger: logger,
+		db:          db,
+		logger:      logger,
+		leaseExpiry: p.LeaseExpiry,
 	}
 	return c, nil
 }
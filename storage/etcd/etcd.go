@@ -29,13 +29,24 @@ const (
 
 	// defaultStorageTimeout will be applied to all storage's operations.
 	defaultStorageTimeout = 5 * time.Second
+
+	// leaseGracePeriod is added to the TTL of leases. Lease TTLs are counted
+	// by etcd while expiry times come from dex's clock, so without it clock
+	// skew could make etcd delete an object dex still considers valid.
+	// Garbage collection keeps deleting expired objects in the meantime.
+	leaseGracePeriod = time.Minute
 )
 
-var _ storage.Storage = (*conn)(nil)
+var (
+	_ storage.Storage                = (*conn)(nil)
+	_ storage.OfflineSessionsStorage = (*conn)(nil)
+)
 
 type conn struct {
 	db     *clientv3.Client
 	logger *slog.Logger
+
+	leaseExpiry bool
 }
 
 func (c *conn) Close() error {
@@ -129,7 +140,7 @@ func (c *conn) GarbageCollect(ctx context.Context, now time.Time) (result storag
 }
 
 func (c *conn) CreateAuthRequest(ctx context.Context, a storage.AuthRequest) error {
-	return c.txnCreate(ctx, keyID(authRequestPrefix, a.ID), fromStorageAuthRequest(a))
+	return c.txnCreate(ctx, keyID(authRequestPrefix, a.ID), fromStorageAuthRequest(a), a.Expiry)
 }
 
 func (c *conn) GetAuthRequest(ctx context.Context, id string) (a storage.AuthRequest, err error) {
@@ -158,7 +169,7 @@ func (c *conn) UpdateAuthRequest(ctx context.Context, id string, updater func(a
 			return nil, err
 		}
 		return json.Marshal(fromStorageAuthRequest(updated))
-	})
+	}, c.leaseUpdateOpts()...)
 }
 
 func (c *conn) DeleteAuthRequest(ctx context.Context, id string) error {
@@ -168,7 +179,7 @@ func (c *conn) DeleteAuthRequest(ctx context.Context, id string) error {
 }
 
 func (c *conn) CreateAuthCode(ctx context.Context, a storage.AuthCode) error {
-	return c.txnCreate(ctx, keyID(authCodePrefix, a.ID), fromStorageAuthCode(a))
+	return c.txnCreate(ctx, keyID(authCodePrefix, a.ID), fromStorageAuthCode(a), a.Expiry)
 }
 
 func (c *conn) GetAuthCode(ctx context.Context, id string) (a storage.AuthCode, err error) {
@@ -379,12 +390,60 @@ func (c *conn) GetOfflineSessions(ctx context.Context, userID string, connID str
 	return toStorageOfflineSessions(os), nil
 }
 
+func (c *conn) ListOfflineSessions(ctx context.Context) (sessions []storage.OfflineSessions, err error) {
+	ctx, cancel := context.WithTimeout(ctx, defaultStorageTimeout)
+	defer cancel()
+	res, err := c.db.Get(ctx, offlineSessionPrefix, clientv3.WithPrefix())
+	if err != nil {
+		return sessions, err
+	}
+	for _, v := range res.Kvs {
+		var os OfflineSessions
+		if err = json.Unmarshal(v.Value, &os); err != nil {
+			return sessions, err
+		}
+		sessions = append(sessions, toStorageOfflineSessions(os))
+	}
+	return sessions, nil
+}
+
 func (c *conn) DeleteOfflineSessions(ctx context.Context, userID string, connID string) error {
 	ctx, cancel := context.WithTimeout(ctx, defaultStorageTimeout)
 	defer cancel()
 	return c.deleteKey(ctx, keySession(userID, connID))
 }
 
+func (c *conn) DeleteOfflineSessionsIfEmpty(ctx context.Context, userID string, connID string) (bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, defaultStorageTimeout)
+	defer cancel()
+	key := keySession(userID, connID)
+	res, err := c.db.Get(ctx, key)
+	if err != nil {
+		return false, err
+	}
+	if res.Count == 0 {
+		return false, storage.ErrNotFound
+	}
+	var os OfflineSessions
+	if err := json.Unmarshal(res.Kvs[0].Value, &os); err != nil {
+		return false, err
+	}
+	if len(os.Refresh) > 0 {
+		return false, nil
+	}
+
+	// Only delete the session as it was read, a login may have added a
+	// refresh token to it since.
+	txnResp, err := c.db.Txn(ctx).
+		If(clientv3.Compare(clientv3.ModRevision(key), "=", res.Kvs[0].ModRevision)).
+		Then(clientv3.OpDelete(key)).
+		Commit()
+	if err != nil {
+		return false, err
+	}
+	return txnResp.Succeeded, nil
+}
+
 func (c *conn) CreateUserIdentity(ctx context.Context, u storage.UserIdentity) error {
 	return c.txnCreate(ctx, keyUserIdentity(u.UserID, u.ConnectorID), fromStorageUserIdentity(u))
 }
@@ -642,15 +701,26 @@ func (c *conn) listAuthSessionsInternal(ctx context.Context) (sessions []AuthSes
 	return sessions, nil
 }
 
-func (c *conn) txnCreate(ctx context.Context, key string, value interface{}) error {
+// txnCreate creates key if it does not exist. If lease expiry is enabled and
+// expiry is set, the key is attached to a lease which outlives it by
+// leaseGracePeriod.
+func (c *conn) txnCreate(ctx context.Context, key string, value interface{}, expiry ...time.Time) error {
 	b, err := json.Marshal(value)
 	if err != nil {
 		return err
 	}
+	var opts []clientv3.OpOption
+	if c.leaseExpiry && len(expiry) > 0 && !expiry[0].IsZero() {
+		lease, err := c.db.Grant(ctx, leaseTTL(time.Now(), expiry[0]))
+		if err != nil {
+			return fmt.Errorf("grant lease: %v", err)
+		}
+		opts = append(opts, clientv3.WithLease(lease.ID))
+	}
 	txn := c.db.Txn(ctx)
 	res, err := txn.
 		If(clientv3.Compare(clientv3.CreateRevision(key), "=", 0)).
-		Then(clientv3.OpPut(key, string(b))).
+		Then(clientv3.OpPut(key, string(b), opts...)).
 		Commit()
 	if err != nil {
 		return err
@@ -661,7 +731,7 @@ func (c *conn) txnCreate(ctx context.Context, key string, value interface{}) err
 	return nil
 }
 
-func (c *conn) txnUpdate(ctx context.Context, key string, update func(current []byte) ([]byte, error)) error {
+func (c *conn) txnUpdate(ctx context.Context, key string, update func(current []byte) ([]byte, error), opts ...clientv3.OpOption) error {
 	getResp, err := c.db.Get(ctx, key)
 	if err != nil {
 		return err
@@ -681,7 +751,7 @@ func (c *conn) txnUpdate(ctx context.Context, key string, update func(current []
 	txn := c.db.Txn(ctx)
 	updateResp, err := txn.
 		If(clientv3.Compare(clientv3.ModRevision(key), "=", modRev)).
-		Then(clientv3.OpPut(key, string(updatedValue))).
+		Then(clientv3.OpPut(key, string(updatedValue), opts...)).
 		Commit()
 	if err != nil {
 		return err
@@ -692,6 +762,22 @@ func (c *conn) txnUpdate(ctx context.Context, key string, update func(current []
 	return nil
 }
 
+// leaseUpdateOpts keeps the lease of keys created by txnCreate with an expiry
+// when they are updated.
+func (c *conn) leaseUpdateOpts() []clientv3.OpOption {
+	if !c.leaseExpiry {
+		return nil
+	}
+	return []clientv3.OpOption{clientv3.WithIgnoreLease()}
+}
+
+// leaseTTL returns the TTL in seconds of a lease for an object expiring at
+// expiry.
+func leaseTTL(now, expiry time.Time) int64 {
+	ttl := max(expiry.Sub(now), 0) + leaseGracePeriod
+	return int64((ttl + time.Second - 1) / time.Second)
+}
+
 func keyID(prefix, id string) string       { return prefix + id }
 func keyEmail(prefix, email string) string { return prefix + strings.ToLower(email) }
 func keySession(userID, connID string) string {
@@ -707,7 +793,7 @@ func keyAuthSession(userID, connectorID string) string {
 }
 
 func (c *conn) CreateDeviceRequest(ctx context.Context, d storage.DeviceRequest) error {
-	return c.txnCreate(ctx, keyID(deviceRequestPrefix, d.UserCode), fromStorageDeviceRequest(d))
+	return c.txnCreate(ctx, keyID(deviceRequestPrefix, d.UserCode), fromStorageDeviceRequest(d), d.Expiry)
 }
 
 func (c *conn) GetDeviceRequest(ctx context.Context, userCode string) (r storage.DeviceRequest, err error) {
@@ -736,7 +822,7 @@ func (c *conn) listDeviceRequests(ctx context.Context) (requests []DeviceRequest
 }
 
 func (c *conn) CreateDeviceToken(ctx context.Context, t storage.DeviceToken) error {
-	return c.txnCreate(ctx, keyID(deviceTokenPrefix, t.DeviceCode), fromStorageDeviceToken(t))
+	return c.txnCreate(ctx, keyID(deviceTokenPrefix, t.DeviceCode), fromStorageDeviceToken(t), t.Expiry)
 }
 
 func (c *conn) GetDeviceToken(ctx context.Context, deviceCode string) (t storage.DeviceToken, err error) {
@@ -779,5 +865,5 @@ func (c *conn) UpdateDeviceToken(ctx context.Context, deviceCode string, updater
 			return nil, err
 		}
 		return json.Marshal(fromStorageDeviceToken(updated))
-	})
+	}, c.leaseUpdateOpts()...)
 }
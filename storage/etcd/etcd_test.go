@@ -64,9 +64,18 @@ func TestEtcd(t *testing.T) {
 	}
 	endpoints := strings.Split(endpointsStr, ",")
 
+	for _, leaseExpiry := range []bool{false, true} {
+		t.Run(fmt.Sprintf("LeaseExpiry=%t", leaseExpiry), func(t *testing.T) {
+			testEtcd(t, endpoints, leaseExpiry)
+		})
+	}
+}
+
+func testEtcd(t *testing.T, endpoints []string, leaseExpiry bool) {
 	newStorage := func(t *testing.T) storage.Storage {
 		s := &Etcd{
-			Endpoints: endpoints,
+			Endpoints:   endpoints,
+			LeaseExpiry: leaseExpiry,
 		}
 		logger := slog.New(slog.NewTextHandler(t.Output(), &slog.HandlerOptions{Level: slog.LevelDebug}))
 		conn, err := s.open(logger)
@@ -97,3 +106,23 @@ func TestEtcd(t *testing.T) {
 	// 	  conformance.RunConcurrencyTests(t, newStorage)
 	// })
 }
+
+func TestLeaseTTL(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		name   string
+		expiry time.Time
+		want   int64
+	}{
+		{"Expires later", now.Add(10 * time.Minute), 660},
+		{"Rounds up", now.Add(10*time.Minute + time.Millisecond), 661},
+		{"Already expired", now.Add(-time.Hour), 60},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := leaseTTL(now, tc.expiry); got != tc.want {
+				t.Errorf("expected TTL %d, got %d", tc.want, got)
+			}
+		})
+	}
+}
@@ -26,6 +26,8 @@ type AuthCode struct {
 	CodeChallengeMethod string `json:"code_challenge_method,omitempty"`
 
 	AuthTime time.Time `json:"auth_time"`
+
+	Resources []string `json:"resources,omitempty"`
 }
 
 func toStorageAuthCode(a AuthCode) storage.AuthCode {
@@ -43,7 +45,8 @@ func toStorageAuthCode(a AuthCode) storage.AuthCode {
 			CodeChallenge:       a.CodeChallenge,
 			CodeChallengeMethod: a.CodeChallengeMethod,
 		},
-		AuthTime: a.AuthTime,
+		AuthTime:  a.AuthTime,
+		Resources: a.Resources,
 	}
 }
 
@@ -61,6 +64,7 @@ func fromStorageAuthCode(a storage.AuthCode) AuthCode {
 		CodeChallenge:       a.PKCE.CodeChallenge,
 		CodeChallengeMethod: a.PKCE.CodeChallengeMethod,
 		AuthTime:            a.AuthTime,
+		Resources:           a.Resources,
 	}
 }
 
@@ -95,9 +99,12 @@ type AuthRequest struct {
 
 	WebAuthnSessionData []byte `json:"webauthn_session_data,omitempty"`
 
-	Prompt   string    `json:"prompt,omitempty"`
-	MaxAge   int       `json:"max_age"`
-	AuthTime time.Time `json:"auth_time"`
+	Prompt    string    `json:"prompt,omitempty"`
+	LoginHint string    `json:"login_hint,omitempty"`
+	MaxAge    int       `json:"max_age"`
+	AuthTime  time.Time `json:"auth_time"`
+
+	Resources []string `json:"resources,omitempty"`
 }
 
 func fromStorageAuthRequest(a storage.AuthRequest) AuthRequest {
@@ -121,8 +128,10 @@ func fromStorageAuthRequest(a storage.AuthRequest) AuthRequest {
 		MFAValidated:        a.MFAValidated,
 		WebAuthnSessionData: a.WebAuthnSessionData,
 		Prompt:              a.Prompt,
+		LoginHint:           a.LoginHint,
 		MaxAge:              a.MaxAge,
 		AuthTime:            a.AuthTime,
+		Resources:           a.Resources,
 	}
 }
 
@@ -149,8 +158,10 @@ func toStorageAuthRequest(a AuthRequest) storage.AuthRequest {
 		MFAValidated:        a.MFAValidated,
 		WebAuthnSessionData: a.WebAuthnSessionData,
 		Prompt:              a.Prompt,
+		LoginHint:           a.LoginHint,
 		MaxAge:              a.MaxAge,
 		AuthTime:            a.AuthTime,
+		Resources:           a.Resources,
 	}
 }
 
@@ -173,6 +184,13 @@ type RefreshToken struct {
 	Scopes []string `json:"scopes"`
 
 	Nonce string `json:"nonce"`
+
+	Resources []string `json:"resources,omitempty"`
+
+	ParentID string   `json:"parent_id,omitempty"`
+	Audience []string `json:"audience,omitempty"`
+
+	RotationKey string `json:"rotation_key,omitempty"`
 }
 
 func toStorageRefreshToken(r RefreshToken) storage.RefreshToken {
@@ -188,6 +206,10 @@ func toStorageRefreshToken(r RefreshToken) storage.RefreshToken {
 		Scopes:        r.Scopes,
 		Nonce:         r.Nonce,
 		Claims:        toStorageClaims(r.Claims),
+		Resources:     r.Resources,
+		ParentID:      r.ParentID,
+		Audience:      r.Audience,
+		RotationKey:   r.RotationKey,
 	}
 }
 
@@ -204,6 +226,10 @@ func fromStorageRefreshToken(r storage.RefreshToken) RefreshToken {
 		Scopes:        r.Scopes,
 		Nonce:         r.Nonce,
 		Claims:        fromStorageClaims(r.Claims),
+		Resources:     r.Resources,
+		ParentID:      r.ParentID,
+		Audience:      r.Audience,
+		RotationKey:   r.RotationKey,
 	}
 }
 
@@ -215,6 +241,8 @@ type Claims struct {
 	Email             string   `json:"email"`
 	EmailVerified     bool     `json:"emailVerified"`
 	Groups            []string `json:"groups,omitempty"`
+
+	CustomClaims map[string]interface{} `json:"customClaims,omitempty"`
 }
 
 func fromStorageClaims(i storage.Claims) Claims {
@@ -225,6 +253,7 @@ func fromStorageClaims(i storage.Claims) Claims {
 		Email:             i.Email,
 		EmailVerified:     i.EmailVerified,
 		Groups:            i.Groups,
+		CustomClaims:      i.CustomClaims,
 	}
 }
 
@@ -236,6 +265,7 @@ func toStorageClaims(i Claims) storage.Claims {
 		Email:             i.Email,
 		EmailVerified:     i.EmailVerified,
 		Groups:            i.Groups,
+		CustomClaims:      i.CustomClaims,
 	}
 }
 
@@ -284,6 +314,8 @@ type UserIdentity struct {
 	ConnectorID         string                                  `json:"connector_id,omitempty"`
 	Claims              Claims                                  `json:"claims,omitempty"`
 	Consents            map[string][]string                     `json:"consents,omitempty"`
+	ConsentedClaims     map[string]string                       `json:"consented_claims,omitempty"`
+	AcceptedTerms       map[string]storage.TermsAcceptance      `json:"accepted_terms,omitempty"`
 	MFASecrets          map[string]*storage.MFASecret           `json:"mfa_secrets,omitempty"`
 	WebAuthnCredentials map[string][]storage.WebAuthnCredential `json:"webauthn_credentials,omitempty"`
 	CreatedAt           time.Time                               `json:"created_at"`
@@ -297,6 +329,8 @@ func fromStorageUserIdentity(u storage.UserIdentity) UserIdentity {
 		ConnectorID:         u.ConnectorID,
 		Claims:              fromStorageClaims(u.Claims),
 		Consents:            u.Consents,
+		ConsentedClaims:     u.ConsentedClaims,
+		AcceptedTerms:       u.AcceptedTerms,
 		MFASecrets:          u.MFASecrets,
 		WebAuthnCredentials: u.WebAuthnCredentials,
 		CreatedAt:           u.CreatedAt,
@@ -311,6 +345,8 @@ func toStorageUserIdentity(u UserIdentity) storage.UserIdentity {
 		ConnectorID:         u.ConnectorID,
 		Claims:              toStorageClaims(u.Claims),
 		Consents:            u.Consents,
+		ConsentedClaims:     u.ConsentedClaims,
+		AcceptedTerms:       u.AcceptedTerms,
 		MFASecrets:          u.MFASecrets,
 		WebAuthnCredentials: u.WebAuthnCredentials,
 		CreatedAt:           u.CreatedAt,
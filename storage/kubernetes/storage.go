@@ -71,12 +71,29 @@ func (c *Config) Open(logger *slog.Logger) (storage.Storage, error) {
 	return c.open(logger, false)
 }
 
-// open returns a kubernetes client, initializing the third party resources used
-// by dex.
-//
-// waitForResources controls if errors creating the resources cause this method to return
-// immediately (used during testing), or if the client will asynchronously retry.
-func (c *Config) open(logger *slog.Logger, waitForResources bool) (*client, error) {
+// CheckConnection connects to the API server and reads the custom resources
+// of dex without creating them. Missing resources are only an error if
+// crdHandling is "check", as Open creates them otherwise.
+func (c *Config) CheckConnection(logger *slog.Logger) error {
+	cli, err := c.newClient(logger)
+	if err != nil {
+		return err
+	}
+	for _, r := range customResourceDefinitions(cli.crdAPIVersion) {
+		var i interface{}
+		err := cli.listN(r.Spec.Names.Plural, &i, 1)
+		if errors.Is(err, storage.ErrNotFound) && c.CRDHandling != crdHandlingCheck {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("list %s: %v", r.ObjectMeta.Name, err)
+		}
+	}
+	return nil
+}
+
+// newClient returns a kubernetes client for the config.
+func (c *Config) newClient(logger *slog.Logger) (*client, error) {
 	if c.CRDHandling == "" {
 		c.CRDHandling = crdHandlingEnsure
 	}
@@ -110,6 +127,19 @@ func (c *Config) open(logger *slog.Logger, waitForResources bool) (*client, erro
 	if err = cli.detectKubernetesVersion(); err != nil {
 		return nil, fmt.Errorf("cannot get kubernetes version: %v", err)
 	}
+	return cli, nil
+}
+
+// open returns a kubernetes client, initializing the third party resources used
+// by dex.
+//
+// waitForResources controls if errors creating the resources cause this method to return
+// immediately (used during testing), or if the client will asynchronously retry.
+func (c *Config) open(logger *slog.Logger, waitForResources bool) (*client, error) {
+	cli, err := c.newClient(logger)
+	if err != nil {
+		return nil, err
+	}
 
 	ctx, cancel := context.WithCancel(context.Background())
 
@@ -399,6 +429,20 @@ func (cli *client) ListRefreshTokens(ctx context.Context) ([]storage.RefreshToke
 	return nil, errors.New("not implemented")
 }
 
+func (cli *client) ListOfflineSessions(ctx context.Context) ([]storage.OfflineSessions, error) {
+	var offlineSessionsList OfflineSessionsList
+	if err := cli.list(resourceOfflineSessions, &offlineSessionsList); err != nil {
+		return nil, fmt.Errorf("failed to list offline sessions: %v", err)
+	}
+
+	offlineSessions := make([]storage.OfflineSessions, len(offlineSessionsList.OfflineSessions))
+	for i, o := range offlineSessionsList.OfflineSessions {
+		offlineSessions[i] = toStorageOfflineSessions(o)
+	}
+
+	return offlineSessions, nil
+}
+
 func (cli *client) ListPasswords(ctx context.Context) (passwords []storage.Password, err error) {
 	var passwordList PasswordList
 	if err = cli.list(resourcePassword, &passwordList); err != nil {
@@ -293,6 +293,34 @@ type Client struct {
 	PostLogoutRedirectURIs []string `json:"postLogoutRedirectURIs,omitempty"`
 
 	SSOSharedWith []string `json:"ssoSharedWith"`
+
+	Theme *storage.ClientTheme `json:"theme,omitempty"`
+
+	RequireConsentOnClaimChange bool `json:"requireConsentOnClaimChange,omitempty"`
+
+	ClientCredentials *storage.ClientCredentialsConfig `json:"clientCredentials,omitempty"`
+
+	JWTBearerIssuers []storage.JWTBearerIssuer `json:"jwtBearerIssuers,omitempty"`
+
+	TokenExchange *storage.TokenExchangePolicy `json:"tokenExchange,omitempty"`
+
+	Resources []string `json:"resources,omitempty"`
+
+	CustomClaims map[string]interface{} `json:"customClaims,omitempty"`
+
+	RefreshTokenReuse *storage.RefreshTokenReusePolicy `json:"refreshTokenReuse,omitempty"`
+
+	DeviceFlow *storage.DeviceFlowConfig `json:"deviceFlow,omitempty"`
+
+	AllowedOrigins []string `json:"allowedOrigins,omitempty"`
+
+	ReleasedConnectorClaims []string `json:"releasedConnectorClaims,omitempty"`
+
+	GroupsClaim *storage.GroupsClaim `json:"groupsClaim,omitempty"`
+
+	Terms *storage.ClientTerms `json:"terms,omitempty"`
+
+	Impersonation *storage.ImpersonationPolicy `json:"impersonation,omitempty"`
 }
 
 // ClientList is a list of Clients.
@@ -323,6 +351,21 @@ func (cli *client) fromStorageClient(c storage.Client) Client {
 		MFAChain:               c.MFAChain,
 		PostLogoutRedirectURIs: c.PostLogoutRedirectURIs,
 		SSOSharedWith:          c.SSOSharedWith,
+		Theme:                  c.Theme,
+
+		RequireConsentOnClaimChange: c.RequireConsentOnClaimChange,
+		ClientCredentials:           c.ClientCredentials,
+		JWTBearerIssuers:            c.JWTBearerIssuers,
+		TokenExchange:               c.TokenExchange,
+		Resources:                   c.Resources,
+		CustomClaims:                c.CustomClaims,
+		RefreshTokenReuse:           c.RefreshTokenReuse,
+		DeviceFlow:                  c.DeviceFlow,
+		AllowedOrigins:              c.AllowedOrigins,
+		ReleasedConnectorClaims:     c.ReleasedConnectorClaims,
+		GroupsClaim:                 c.GroupsClaim,
+		Terms:                       c.Terms,
+		Impersonation:               c.Impersonation,
 	}
 }
 
@@ -339,6 +382,21 @@ func toStorageClient(c Client) storage.Client {
 		MFAChain:               c.MFAChain,
 		PostLogoutRedirectURIs: c.PostLogoutRedirectURIs,
 		SSOSharedWith:          c.SSOSharedWith,
+		Theme:                  c.Theme,
+
+		RequireConsentOnClaimChange: c.RequireConsentOnClaimChange,
+		ClientCredentials:           c.ClientCredentials,
+		JWTBearerIssuers:            c.JWTBearerIssuers,
+		TokenExchange:               c.TokenExchange,
+		Resources:                   c.Resources,
+		CustomClaims:                c.CustomClaims,
+		RefreshTokenReuse:           c.RefreshTokenReuse,
+		DeviceFlow:                  c.DeviceFlow,
+		AllowedOrigins:              c.AllowedOrigins,
+		ReleasedConnectorClaims:     c.ReleasedConnectorClaims,
+		GroupsClaim:                 c.GroupsClaim,
+		Terms:                       c.Terms,
+		Impersonation:               c.Impersonation,
 	}
 }
 
@@ -350,6 +408,8 @@ type Claims struct {
 	Email             string   `json:"email"`
 	EmailVerified     bool     `json:"emailVerified"`
 	Groups            []string `json:"groups,omitempty"`
+
+	CustomClaims map[string]interface{} `json:"customClaims,omitempty"`
 }
 
 func fromStorageClaims(i storage.Claims) Claims {
@@ -360,6 +420,7 @@ func fromStorageClaims(i storage.Claims) Claims {
 		Email:             i.Email,
 		EmailVerified:     i.EmailVerified,
 		Groups:            i.Groups,
+		CustomClaims:      i.CustomClaims,
 	}
 }
 
@@ -371,6 +432,7 @@ func toStorageClaims(i Claims) storage.Claims {
 		Email:             i.Email,
 		EmailVerified:     i.EmailVerified,
 		Groups:            i.Groups,
+		CustomClaims:      i.CustomClaims,
 	}
 }
 
@@ -413,9 +475,12 @@ type AuthRequest struct {
 
 	WebAuthnSessionData []byte `json:"webauthn_session_data,omitempty"`
 
-	Prompt   string    `json:"prompt,omitempty"`
-	MaxAge   int       `json:"maxAge"`
-	AuthTime time.Time `json:"authTime,omitempty"`
+	Prompt    string    `json:"prompt,omitempty"`
+	LoginHint string    `json:"loginHint,omitempty"`
+	MaxAge    int       `json:"maxAge"`
+	AuthTime  time.Time `json:"authTime,omitempty"`
+
+	Resources []string `json:"resources,omitempty"`
 }
 
 // AuthRequestList is a list of AuthRequests.
@@ -448,8 +513,10 @@ func toStorageAuthRequest(req AuthRequest) storage.AuthRequest {
 		MFAValidated:        req.MFAValidated,
 		WebAuthnSessionData: req.WebAuthnSessionData,
 		Prompt:              req.Prompt,
+		LoginHint:           req.LoginHint,
 		MaxAge:              req.MaxAge,
 		AuthTime:            req.AuthTime,
+		Resources:           req.Resources,
 	}
 	return a
 }
@@ -482,8 +549,10 @@ func (cli *client) fromStorageAuthRequest(a storage.AuthRequest) AuthRequest {
 		MFAValidated:        a.MFAValidated,
 		WebAuthnSessionData: a.WebAuthnSessionData,
 		Prompt:              a.Prompt,
+		LoginHint:           a.LoginHint,
 		MaxAge:              a.MaxAge,
 		AuthTime:            a.AuthTime,
+		Resources:           a.Resources,
 	}
 	return req
 }
@@ -574,6 +643,8 @@ type AuthCode struct {
 	CodeChallengeMethod string `json:"code_challenge_method,omitempty"`
 
 	AuthTime time.Time `json:"authTime,omitempty"`
+
+	Resources []string `json:"resources,omitempty"`
 }
 
 // AuthCodeList is a list of AuthCodes.
@@ -604,6 +675,7 @@ func (cli *client) fromStorageAuthCode(a storage.AuthCode) AuthCode {
 		CodeChallenge:       a.PKCE.CodeChallenge,
 		CodeChallengeMethod: a.PKCE.CodeChallengeMethod,
 		AuthTime:            a.AuthTime,
+		Resources:           a.Resources,
 	}
 }
 
@@ -622,7 +694,8 @@ func toStorageAuthCode(a AuthCode) storage.AuthCode {
 			CodeChallenge:       a.CodeChallenge,
 			CodeChallengeMethod: a.CodeChallengeMethod,
 		},
-		AuthTime: a.AuthTime,
+		AuthTime:  a.AuthTime,
+		Resources: a.Resources,
 	}
 }
 
@@ -646,6 +719,13 @@ type RefreshToken struct {
 	Claims        Claims `json:"claims,omitempty"`
 	ConnectorID   string `json:"connectorID,omitempty"`
 	ConnectorData []byte `json:"connectorData,omitempty"`
+
+	Resources []string `json:"resources,omitempty"`
+
+	ParentID string   `json:"parentID,omitempty"`
+	Audience []string `json:"audience,omitempty"`
+
+	RotationKey string `json:"rotationKey,omitempty"`
 }
 
 // RefreshList is a list of refresh tokens.
@@ -668,6 +748,10 @@ func toStorageRefreshToken(r RefreshToken) storage.RefreshToken {
 		Scopes:        r.Scopes,
 		Nonce:         r.Nonce,
 		Claims:        toStorageClaims(r.Claims),
+		Resources:     r.Resources,
+		ParentID:      r.ParentID,
+		Audience:      r.Audience,
+		RotationKey:   r.RotationKey,
 	}
 }
 
@@ -691,6 +775,10 @@ func (cli *client) fromStorageRefreshToken(r storage.RefreshToken) RefreshToken
 		Scopes:        r.Scopes,
 		Nonce:         r.Nonce,
 		Claims:        fromStorageClaims(r.Claims),
+		Resources:     r.Resources,
+		ParentID:      r.ParentID,
+		Audience:      r.Audience,
+		RotationKey:   r.RotationKey,
 	}
 }
 
@@ -751,6 +839,13 @@ type OfflineSessions struct {
 	ConnectorData []byte                              `json:"connectorData,omitempty"`
 }
 
+// OfflineSessionsList is a list of OfflineSessions.
+type OfflineSessionsList struct {
+	k8sapi.TypeMeta `json:",inline"`
+	k8sapi.ListMeta `json:"metadata,omitempty"`
+	OfflineSessions []OfflineSessions `json:"items"`
+}
+
 func (cli *client) fromStorageOfflineSessions(o storage.OfflineSessions) OfflineSessions {
 	return OfflineSessions{
 		TypeMeta: k8sapi.TypeMeta{
@@ -951,6 +1046,8 @@ type UserIdentity struct {
 	ConnectorID         string                                  `json:"connectorID,omitempty"`
 	Claims              Claims                                  `json:"claims,omitempty"`
 	Consents            map[string][]string                     `json:"consents,omitempty"`
+	ConsentedClaims     map[string]string                       `json:"consentedClaims,omitempty"`
+	AcceptedTerms       map[string]storage.TermsAcceptance      `json:"acceptedTerms,omitempty"`
 	MFASecrets          map[string]*storage.MFASecret           `json:"mfaSecrets,omitempty"`
 	WebAuthnCredentials map[string][]storage.WebAuthnCredential `json:"webauthnCredentials,omitempty"`
 	CreatedAt           time.Time                               `json:"createdAt,omitempty"`
@@ -979,6 +1076,8 @@ func (cli *client) fromStorageUserIdentity(u storage.UserIdentity) UserIdentity
 		ConnectorID:         u.ConnectorID,
 		Claims:              fromStorageClaims(u.Claims),
 		Consents:            u.Consents,
+		ConsentedClaims:     u.ConsentedClaims,
+		AcceptedTerms:       u.AcceptedTerms,
 		MFASecrets:          u.MFASecrets,
 		WebAuthnCredentials: u.WebAuthnCredentials,
 		CreatedAt:           u.CreatedAt,
@@ -993,6 +1092,8 @@ func toStorageUserIdentity(u UserIdentity) storage.UserIdentity {
 		ConnectorID:         u.ConnectorID,
 		Claims:              toStorageClaims(u.Claims),
 		Consents:            u.Consents,
+		ConsentedClaims:     u.ConsentedClaims,
+		AcceptedTerms:       u.AcceptedTerms,
 		MFASecrets:          u.MFASecrets,
 		WebAuthnCredentials: u.WebAuthnCredentials,
 		CreatedAt:           u.CreatedAt,
@@ -4,6 +4,8 @@ package memory
 import (
 	"context"
 	"log/slog"
+	"slices"
+	"sort"
 	"strings"
 	"sync"
 	"time"
@@ -11,7 +13,12 @@ import (
 	"github.com/dexidp/dex/storage"
 )
 
-var _ storage.Storage = (*memStorage)(nil)
+var (
+	_ storage.Storage                = (*memStorage)(nil)
+	_ storage.RateLimitStorage       = (*memStorage)(nil)
+	_ storage.AuditStorage           = (*memStorage)(nil)
+	_ storage.OfflineSessionsStorage = (*memStorage)(nil)
+)
 
 // New returns an in memory storage.
 func New(logger *slog.Logger) storage.Storage {
@@ -28,6 +35,8 @@ func New(logger *slog.Logger) storage.Storage {
 		deviceRequests:  make(map[string]storage.DeviceRequest),
 		deviceTokens:    make(map[string]storage.DeviceToken),
 		logger:          logger,
+
+		rateLimitBuckets: make(map[string]storage.RateLimitBucket),
 	}
 }
 
@@ -57,6 +66,9 @@ type memStorage struct {
 	deviceRequests  map[string]storage.DeviceRequest
 	deviceTokens    map[string]storage.DeviceToken
 
+	rateLimitBuckets map[string]storage.RateLimitBucket
+	auditEvents      []storage.AuditEvent // oldest first
+
 	keys storage.Keys
 
 	logger *slog.Logger
@@ -107,6 +119,21 @@ func (s *memStorage) GarbageCollect(ctx context.Context, now time.Time) (result
 				result.AuthSessions++
 			}
 		}
+		for key, b := range s.rateLimitBuckets {
+			if now.After(b.Expiry) {
+				delete(s.rateLimitBuckets, key)
+				result.RateLimitBuckets++
+			}
+		}
+		kept := s.auditEvents[:0]
+		for _, e := range s.auditEvents {
+			if now.After(e.Expiry) {
+				result.AuditEvents++
+				continue
+			}
+			kept = append(kept, e)
+		}
+		s.auditEvents = kept
 	})
 	return result, nil
 }
@@ -254,6 +281,15 @@ func (s *memStorage) ListUserIdentities(ctx context.Context) (identities []stora
 	return
 }
 
+func (s *memStorage) ListOfflineSessions(ctx context.Context) (sessions []storage.OfflineSessions, err error) {
+	s.tx(func() {
+		for _, o := range s.offlineSessions {
+			sessions = append(sessions, o)
+		}
+	})
+	return
+}
+
 func (s *memStorage) ListAuthSessions(ctx context.Context) (sessions []storage.AuthSession, err error) {
 	s.tx(func() {
 		for _, session := range s.authSessions {
@@ -515,6 +551,26 @@ func (s *memStorage) DeleteOfflineSessions(ctx context.Context, userID string, c
 	return
 }
 
+func (s *memStorage) DeleteOfflineSessionsIfEmpty(ctx context.Context, userID string, connID string) (deleted bool, err error) {
+	id := compositeKeyID{
+		userID: userID,
+		connID: connID,
+	}
+	s.tx(func() {
+		o, ok := s.offlineSessions[id]
+		if !ok {
+			err = storage.ErrNotFound
+			return
+		}
+		if len(o.Refresh) > 0 {
+			return
+		}
+		delete(s.offlineSessions, id)
+		deleted = true
+	})
+	return
+}
+
 func (s *memStorage) DeleteConnector(ctx context.Context, id string) (err error) {
 	s.tx(func() {
 		if _, ok := s.connectors[id]; !ok {
@@ -682,3 +738,44 @@ func (s *memStorage) UpdateDeviceToken(ctx context.Context, deviceCode string, u
 	})
 	return
 }
+
+func (s *memStorage) UpdateRateLimitBucket(ctx context.Context, key string, updater func(b storage.RateLimitBucket) (storage.RateLimitBucket, error)) (err error) {
+	s.tx(func() {
+		b, ok := s.rateLimitBuckets[key]
+		if !ok {
+			b = storage.RateLimitBucket{Key: key}
+		}
+		if b, err = updater(b); err == nil {
+			s.rateLimitBuckets[key] = b
+		}
+	})
+	return
+}
+
+func (s *memStorage) CreateAuditEvent(ctx context.Context, e storage.AuditEvent) (err error) {
+	s.tx(func() {
+		for _, existing := range s.auditEvents {
+			if existing.ID == e.ID {
+				err = storage.ErrAlreadyExists
+				return
+			}
+		}
+		i := sort.Search(len(s.auditEvents), func(i int) bool { return s.auditEvents[i].Time.After(e.Time) })
+		s.auditEvents = slices.Insert(s.auditEvents, i, e)
+	})
+	return
+}
+
+func (s *memStorage) ListAuditEvents(ctx context.Context, f storage.AuditEventFilter) (events []storage.AuditEvent, err error) {
+	s.tx(func() {
+		for i := len(s.auditEvents) - 1; i >= 0; i-- {
+			if f.Limit > 0 && len(events) == f.Limit {
+				break
+			}
+			if f.Match(s.auditEvents[i]) {
+				events = append(events, s.auditEvents[i])
+			}
+		}
+	})
+	return
+}
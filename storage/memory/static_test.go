@@ -290,3 +290,49 @@ func TestStaticConnectors(t *testing.T) {
 		}
 	}
 }
+
+func TestStaticObjectsReplace(t *testing.T) {
+	ctx := context.Background()
+	logger := slog.New(slog.DiscardHandler)
+	backing := New(logger)
+
+	backing.CreateClient(ctx, storage.Client{ID: "backing"})
+
+	s, static := storage.WithStaticObjects(backing,
+		[]storage.Client{{ID: "old"}},
+		[]storage.Password{{Email: "old@example.com"}},
+		[]storage.Connector{{ID: "old"}},
+		logger,
+	)
+
+	if _, err := s.GetClient(ctx, "old"); err != nil {
+		t.Fatalf("get static client: %v", err)
+	}
+
+	static.SetClients([]storage.Client{{ID: "new"}, {ID: "backing", Name: "static"}})
+	static.SetPasswords([]storage.Password{{Email: "New@example.com"}})
+	static.SetConnectors(nil)
+
+	if _, err := s.GetClient(ctx, "old"); err != storage.ErrNotFound {
+		t.Errorf("expected removed static client to be gone, got %v", err)
+	}
+	if _, err := s.GetClient(ctx, "new"); err != nil {
+		t.Errorf("get new static client: %v", err)
+	}
+	clients, err := s.ListClients(ctx)
+	if err != nil {
+		t.Fatalf("list clients: %v", err)
+	}
+	if len(clients) != 2 {
+		t.Errorf("expected static client to shadow backing client, got %d clients", len(clients))
+	}
+	if err := s.DeleteClient(ctx, "new"); err == nil {
+		t.Errorf("expected new static client to be read-only")
+	}
+	if _, err := s.GetPassword(ctx, "new@example.com"); err != nil {
+		t.Errorf("get new static password: %v", err)
+	}
+	if _, err := s.GetConnector(ctx, "old"); err != storage.ErrNotFound {
+		t.Errorf("expected removed static connector to be gone, got %v", err)
+	}
+}
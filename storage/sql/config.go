@@ -163,6 +163,28 @@ func (p *Postgres) createDataSourceName() string {
 }
 
 func (p *Postgres) open(logger *slog.Logger) (*conn, error) {
+	c, err := p.connect(logger)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := c.migrate(); err != nil {
+		return nil, fmt.Errorf("failed to perform migrations: %v", err)
+	}
+	return c, nil
+}
+
+// PendingMigrations returns the number of schema migrations Open would
+// apply, without applying them.
+func (p *Postgres) PendingMigrations(logger *slog.Logger) (int, error) {
+	c, err := p.connect(logger)
+	if err != nil {
+		return 0, err
+	}
+	defer c.Close()
+	return c.pendingMigrations()
+}
+
+func (p *Postgres) connect(logger *slog.Logger) (*conn, error) {
 	dataSourceName := p.createDataSourceName()
 
 	db, err := sql.Open("postgres", dataSourceName)
@@ -195,11 +217,7 @@ func (p *Postgres) open(logger *slog.Logger) (*conn, error) {
 		return sqlErr.Code == pgErrUniqueViolation
 	}
 
-	c := &conn{db, &flavorPostgres, logger, errCheck}
-	if _, err := c.migrate(); err != nil {
-		return nil, fmt.Errorf("failed to perform migrations: %v", err)
-	}
-	return c, nil
+	return &conn{db, &flavorPostgres, logger, errCheck}, nil
 }
 
 // MySQL options for creating a MySQL db.
@@ -223,6 +241,28 @@ func (s *MySQL) Open(logger *slog.Logger) (storage.Storage, error) {
 }
 
 func (s *MySQL) open(logger *slog.Logger) (*conn, error) {
+	c, err := s.connect(logger)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := c.migrate(); err != nil {
+		return nil, fmt.Errorf("failed to perform migrations: %v", err)
+	}
+	return c, nil
+}
+
+// PendingMigrations returns the number of schema migrations Open would
+// apply, without applying them.
+func (s *MySQL) PendingMigrations(logger *slog.Logger) (int, error) {
+	c, err := s.connect(logger)
+	if err != nil {
+		return 0, err
+	}
+	defer c.Close()
+	return c.pendingMigrations()
+}
+
+func (s *MySQL) connect(logger *slog.Logger) (*conn, error) {
 	cfg := mysql.Config{
 		User:                 s.User,
 		Passwd:               s.Password,
@@ -307,11 +347,7 @@ func (s *MySQL) open(logger *slog.Logger) (*conn, error) {
 			sqlErr.Number == mysqlErrDupEntryWithKeyName
 	}
 
-	c := &conn{db, &flavorMySQL, logger, errCheck}
-	if _, err := c.migrate(); err != nil {
-		return nil, fmt.Errorf("failed to perform migrations: %v", err)
-	}
-	return c, nil
+	return &conn{db, &flavorMySQL, logger, errCheck}, nil
 }
 
 func (s *MySQL) makeTLSConfig() error {
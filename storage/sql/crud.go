@@ -84,7 +84,12 @@ type scanner interface {
 	Scan(dest ...interface{}) error
 }
 
-var _ storage.Storage = (*conn)(nil)
+var (
+	_ storage.Storage                = (*conn)(nil)
+	_ storage.RateLimitStorage       = (*conn)(nil)
+	_ storage.AuditStorage           = (*conn)(nil)
+	_ storage.OfflineSessionsStorage = (*conn)(nil)
+)
 
 func (c *conn) GarbageCollect(ctc context.Context, now time.Time) (storage.GCResult, error) {
 	result := storage.GCResult{}
@@ -129,6 +134,22 @@ func (c *conn) GarbageCollect(ctc context.Context, now time.Time) (storage.GCRes
 		result.AuthSessions = n
 	}
 
+	r, err = c.Exec(`delete from rate_limit_bucket where expiry < $1`, now)
+	if err != nil {
+		return result, fmt.Errorf("gc rate_limit_bucket: %v", err)
+	}
+	if n, err := r.RowsAffected(); err == nil {
+		result.RateLimitBuckets = n
+	}
+
+	r, err = c.Exec(`delete from audit_event where expiry < $1`, now)
+	if err != nil {
+		return result, fmt.Errorf("gc audit_event: %v", err)
+	}
+	if n, err := r.RowsAffected(); err == nil {
+		result.AuditEvents = n
+	}
+
 	return result, nil
 }
 
@@ -145,10 +166,12 @@ func (c *conn) CreateAuthRequest(ctx context.Context, a storage.AuthRequest) err
 			hmac_key,
 			mfa_validated,
 			webauthn_session_data,
-			prompt, max_age, auth_time
+			prompt, max_age, auth_time,
+			resources, login_hint,
+			claims_custom
 		)
 		values (
-			$1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20, $21, $22, $23, $24, $25, $26
+			$1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20, $21, $22, $23, $24, $25, $26, $27, $28, $29
 		);
 	`,
 		a.ID, a.ClientID, encoder(a.ResponseTypes), encoder(a.Scopes), a.RedirectURI, a.Nonce, a.State,
@@ -162,6 +185,8 @@ func (c *conn) CreateAuthRequest(ctx context.Context, a storage.AuthRequest) err
 		a.MFAValidated,
 		a.WebAuthnSessionData,
 		a.Prompt, a.MaxAge, a.AuthTime,
+		encoder(a.Resources), a.LoginHint,
+		encoder(a.Claims.CustomClaims),
 	)
 	if err != nil {
 		if c.alreadyExistsCheck(err) {
@@ -197,8 +222,10 @@ func (c *conn) UpdateAuthRequest(ctx context.Context, id string, updater func(a
 				hmac_key = $20,
 				mfa_validated = $21,
 				webauthn_session_data = $22,
-				prompt = $23, max_age = $24, auth_time = $25
-			where id = $26;
+				prompt = $23, max_age = $24, auth_time = $25,
+				resources = $26, login_hint = $27,
+				claims_custom = $28
+			where id = $29;
 		`,
 			a.ClientID, encoder(a.ResponseTypes), encoder(a.Scopes), a.RedirectURI, a.Nonce, a.State,
 			a.ForceApprovalPrompt, a.LoggedIn,
@@ -211,6 +238,8 @@ func (c *conn) UpdateAuthRequest(ctx context.Context, id string, updater func(a
 			a.MFAValidated,
 			a.WebAuthnSessionData,
 			a.Prompt, a.MaxAge, a.AuthTime,
+			encoder(a.Resources), a.LoginHint,
+			encoder(a.Claims.CustomClaims),
 			r.ID,
 		)
 		if err != nil {
@@ -225,6 +254,7 @@ func (c *conn) GetAuthRequest(ctx context.Context, id string) (storage.AuthReque
 }
 
 func getAuthRequest(ctx context.Context, q querier, id string) (a storage.AuthRequest, err error) {
+	var resources, customClaims []byte
 	err = q.QueryRow(`
 		select
 			id, client_id, response_types, scopes, redirect_uri, nonce, state,
@@ -235,7 +265,9 @@ func getAuthRequest(ctx context.Context, q querier, id string) (a storage.AuthRe
 			code_challenge, code_challenge_method, hmac_key,
 			mfa_validated,
 			webauthn_session_data,
-			prompt, max_age, auth_time
+			prompt, max_age, auth_time,
+			resources, login_hint,
+			claims_custom
 		from auth_request where id = $1;
 	`, id).Scan(
 		&a.ID, &a.ClientID, decoder(&a.ResponseTypes), decoder(&a.Scopes), &a.RedirectURI, &a.Nonce, &a.State,
@@ -248,6 +280,8 @@ func getAuthRequest(ctx context.Context, q querier, id string) (a storage.AuthRe
 		&a.MFAValidated,
 		&a.WebAuthnSessionData,
 		&a.Prompt, &a.MaxAge, &a.AuthTime,
+		&resources, &a.LoginHint,
+		&customClaims,
 	)
 	if err != nil {
 		if err == sql.ErrNoRows {
@@ -255,6 +289,16 @@ func getAuthRequest(ctx context.Context, q querier, id string) (a storage.AuthRe
 		}
 		return a, fmt.Errorf("select auth request: %v", err)
 	}
+	if len(resources) > 0 {
+		if err := json.Unmarshal(resources, &a.Resources); err != nil {
+			return a, fmt.Errorf("unmarshal auth request resources: %v", err)
+		}
+	}
+	if len(customClaims) > 0 {
+		if err := json.Unmarshal(customClaims, &a.Claims.CustomClaims); err != nil {
+			return a, fmt.Errorf("unmarshal auth request custom claims: %v", err)
+		}
+	}
 	return a, nil
 }
 
@@ -267,15 +311,17 @@ func (c *conn) CreateAuthCode(ctx context.Context, a storage.AuthCode) error {
 			connector_id, connector_data,
 			expiry,
 			code_challenge, code_challenge_method,
-			auth_time
+			auth_time, resources,
+			claims_custom
 		)
-		values ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17);
+		values ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19);
 	`,
 		a.ID, a.ClientID, encoder(a.Scopes), a.Nonce, a.RedirectURI, a.Claims.UserID,
 		a.Claims.Username, a.Claims.PreferredUsername, a.Claims.Email, a.Claims.EmailVerified,
 		encoder(a.Claims.Groups), a.ConnectorID, a.ConnectorData, a.Expiry,
 		a.PKCE.CodeChallenge, a.PKCE.CodeChallengeMethod,
-		a.AuthTime,
+		a.AuthTime, encoder(a.Resources),
+		encoder(a.Claims.CustomClaims),
 	)
 	if err != nil {
 		if c.alreadyExistsCheck(err) {
@@ -287,6 +333,7 @@ func (c *conn) CreateAuthCode(ctx context.Context, a storage.AuthCode) error {
 }
 
 func (c *conn) GetAuthCode(ctx context.Context, id string) (a storage.AuthCode, err error) {
+	var resources, customClaims []byte
 	err = c.QueryRow(`
 		select
 			id, client_id, scopes, nonce, redirect_uri,
@@ -295,14 +342,16 @@ func (c *conn) GetAuthCode(ctx context.Context, id string) (a storage.AuthCode,
 			connector_id, connector_data,
 			expiry,
 			code_challenge, code_challenge_method,
-			auth_time
+			auth_time, resources,
+			claims_custom
 		from auth_code where id = $1;
 	`, id).Scan(
 		&a.ID, &a.ClientID, decoder(&a.Scopes), &a.Nonce, &a.RedirectURI, &a.Claims.UserID,
 		&a.Claims.Username, &a.Claims.PreferredUsername, &a.Claims.Email, &a.Claims.EmailVerified,
 		decoder(&a.Claims.Groups), &a.ConnectorID, &a.ConnectorData, &a.Expiry,
 		&a.PKCE.CodeChallenge, &a.PKCE.CodeChallengeMethod,
-		&a.AuthTime,
+		&a.AuthTime, &resources,
+		&customClaims,
 	)
 	if err != nil {
 		if err == sql.ErrNoRows {
@@ -310,6 +359,16 @@ func (c *conn) GetAuthCode(ctx context.Context, id string) (a storage.AuthCode,
 		}
 		return a, fmt.Errorf("select auth code: %v", err)
 	}
+	if len(resources) > 0 {
+		if err := json.Unmarshal(resources, &a.Resources); err != nil {
+			return a, fmt.Errorf("unmarshal auth code resources: %v", err)
+		}
+	}
+	if len(customClaims) > 0 {
+		if err := json.Unmarshal(customClaims, &a.Claims.CustomClaims); err != nil {
+			return a, fmt.Errorf("unmarshal auth code custom claims: %v", err)
+		}
+	}
 	return a, nil
 }
 
@@ -320,9 +379,11 @@ func (c *conn) CreateRefresh(ctx context.Context, r storage.RefreshToken) error
 			claims_user_id, claims_username, claims_preferred_username,
 			claims_email, claims_email_verified, claims_groups,
 			connector_id, connector_data,
-			token, obsolete_token, created_at, last_used
+			token, obsolete_token, created_at, last_used,
+			resources, claims_custom,
+			parent_id, audience, rotation_key
 		)
-		values ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16);
+		values ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20, $21);
 	`,
 		r.ID, r.ClientID, encoder(r.Scopes), r.Nonce,
 		r.Claims.UserID, r.Claims.Username, r.Claims.PreferredUsername,
@@ -330,6 +391,8 @@ func (c *conn) CreateRefresh(ctx context.Context, r storage.RefreshToken) error
 		encoder(r.Claims.Groups),
 		r.ConnectorID, r.ConnectorData,
 		r.Token, r.ObsoleteToken, r.CreatedAt, r.LastUsed,
+		encoder(r.Resources), encoder(r.Claims.CustomClaims),
+		r.ParentID, encoder(r.Audience), r.RotationKey,
 	)
 	if err != nil {
 		if c.alreadyExistsCheck(err) {
@@ -366,16 +429,23 @@ func (c *conn) UpdateRefreshToken(ctx context.Context, id string, updater func(o
 				token = $12,
                 obsolete_token = $13,
 				created_at = $14,
-				last_used = $15
+				last_used = $15,
+				resources = $16,
+				claims_custom = $17,
+				parent_id = $18,
+				audience = $19,
+				rotation_key = $20
 			where
-				id = $16
+				id = $21
 		`,
 			r.ClientID, encoder(r.Scopes), r.Nonce,
 			r.Claims.UserID, r.Claims.Username, r.Claims.PreferredUsername,
 			r.Claims.Email, r.Claims.EmailVerified,
 			encoder(r.Claims.Groups),
 			r.ConnectorID, r.ConnectorData,
-			r.Token, r.ObsoleteToken, r.CreatedAt, r.LastUsed, id,
+			r.Token, r.ObsoleteToken, r.CreatedAt, r.LastUsed,
+			encoder(r.Resources), encoder(r.Claims.CustomClaims),
+			r.ParentID, encoder(r.Audience), r.RotationKey, id,
 		)
 		if err != nil {
 			return fmt.Errorf("update refresh token: %v", err)
@@ -396,7 +466,9 @@ func getRefresh(ctx context.Context, q querier, id string) (storage.RefreshToken
 			claims_email, claims_email_verified,
 			claims_groups,
 			connector_id, connector_data,
-			token, obsolete_token, created_at, last_used
+			token, obsolete_token, created_at, last_used,
+			resources, claims_custom,
+			parent_id, audience, rotation_key
 		from refresh_token where id = $1;
 	`, id))
 }
@@ -408,7 +480,9 @@ func (c *conn) ListRefreshTokens(ctx context.Context) ([]storage.RefreshToken, e
 			claims_user_id, claims_username, claims_preferred_username,
 			claims_email, claims_email_verified, claims_groups,
 			connector_id, connector_data,
-			token, obsolete_token, created_at, last_used
+			token, obsolete_token, created_at, last_used,
+			resources, claims_custom,
+			parent_id, audience, rotation_key
 		from refresh_token;
 	`)
 	if err != nil {
@@ -431,6 +505,7 @@ func (c *conn) ListRefreshTokens(ctx context.Context) ([]storage.RefreshToken, e
 }
 
 func scanRefresh(s scanner) (r storage.RefreshToken, err error) {
+	var resources, customClaims, audience []byte
 	err = s.Scan(
 		&r.ID, &r.ClientID, decoder(&r.Scopes), &r.Nonce,
 		&r.Claims.UserID, &r.Claims.Username, &r.Claims.PreferredUsername,
@@ -438,6 +513,8 @@ func scanRefresh(s scanner) (r storage.RefreshToken, err error) {
 		decoder(&r.Claims.Groups),
 		&r.ConnectorID, &r.ConnectorData,
 		&r.Token, &r.ObsoleteToken, &r.CreatedAt, &r.LastUsed,
+		&resources, &customClaims,
+		&r.ParentID, &audience, &r.RotationKey,
 	)
 	if err != nil {
 		if err == sql.ErrNoRows {
@@ -445,6 +522,21 @@ func scanRefresh(s scanner) (r storage.RefreshToken, err error) {
 		}
 		return r, fmt.Errorf("scan refresh_token: %v", err)
 	}
+	if len(resources) > 0 {
+		if err := json.Unmarshal(resources, &r.Resources); err != nil {
+			return r, fmt.Errorf("unmarshal refresh token resources: %v", err)
+		}
+	}
+	if len(customClaims) > 0 {
+		if err := json.Unmarshal(customClaims, &r.Claims.CustomClaims); err != nil {
+			return r, fmt.Errorf("unmarshal refresh token custom claims: %v", err)
+		}
+	}
+	if len(audience) > 0 {
+		if err := json.Unmarshal(audience, &r.Audience); err != nil {
+			return r, fmt.Errorf("unmarshal refresh token audience: %v", err)
+		}
+	}
 	return r, nil
 }
 
@@ -547,9 +639,23 @@ func (c *conn) UpdateClient(ctx context.Context, id string, updater func(old sto
 				allowed_connectors = $7,
 				mfa_chain = $8,
 				post_logout_redirect_uris = $9,
-				sso_shared_with = $10
-			where id = $11;
-		`, nc.Secret, encoder(nc.RedirectURIs), encoder(nc.TrustedPeers), nc.Public, nc.Name, nc.LogoURL, encoder(nc.AllowedConnectors), encoder(nc.MFAChain), encoder(nc.PostLogoutRedirectURIs), encoder(nc.SSOSharedWith), id,
+				sso_shared_with = $10,
+				theme = $11,
+				require_consent_on_claim_change = $12,
+				client_credentials = $13,
+				jwt_bearer_issuers = $14,
+				token_exchange = $15,
+				resources = $16,
+				custom_claims = $17,
+				refresh_token_reuse = $18,
+				device_flow = $19,
+				allowed_origins = $20,
+				released_connector_claims = $21,
+				groups_claim = $22,
+				terms = $23,
+				impersonation = $24
+			where id = $25;
+		`, nc.Secret, encoder(nc.RedirectURIs), encoder(nc.TrustedPeers), nc.Public, nc.Name, nc.LogoURL, encoder(nc.AllowedConnectors), encoder(nc.MFAChain), encoder(nc.PostLogoutRedirectURIs), encoder(nc.SSOSharedWith), encoder(nc.Theme), nc.RequireConsentOnClaimChange, encoder(nc.ClientCredentials), encoder(nc.JWTBearerIssuers), encoder(nc.TokenExchange), encoder(nc.Resources), encoder(nc.CustomClaims), encoder(nc.RefreshTokenReuse), encoder(nc.DeviceFlow), encoder(nc.AllowedOrigins), encoder(nc.ReleasedConnectorClaims), encoder(nc.GroupsClaim), encoder(nc.Terms), encoder(nc.Impersonation), id,
 		)
 		if err != nil {
 			return fmt.Errorf("update client: %v", err)
@@ -561,12 +667,12 @@ func (c *conn) UpdateClient(ctx context.Context, id string, updater func(old sto
 func (c *conn) CreateClient(ctx context.Context, cli storage.Client) error {
 	_, err := c.Exec(`
 		insert into client (
-			id, secret, redirect_uris, trusted_peers, public, name, logo_url, allowed_connectors, mfa_chain, post_logout_redirect_uris, sso_shared_with
+			id, secret, redirect_uris, trusted_peers, public, name, logo_url, allowed_connectors, mfa_chain, post_logout_redirect_uris, sso_shared_with, theme, require_consent_on_claim_change, client_credentials, jwt_bearer_issuers, token_exchange, resources, custom_claims, refresh_token_reuse, device_flow, allowed_origins, released_connector_claims, groups_claim, terms, impersonation
 		)
-		values ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11);
+		values ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20, $21, $22, $23, $24, $25);
 	`,
 		cli.ID, cli.Secret, encoder(cli.RedirectURIs), encoder(cli.TrustedPeers),
-		cli.Public, cli.Name, cli.LogoURL, encoder(cli.AllowedConnectors), encoder(cli.MFAChain), encoder(cli.PostLogoutRedirectURIs), encoder(cli.SSOSharedWith),
+		cli.Public, cli.Name, cli.LogoURL, encoder(cli.AllowedConnectors), encoder(cli.MFAChain), encoder(cli.PostLogoutRedirectURIs), encoder(cli.SSOSharedWith), encoder(cli.Theme), cli.RequireConsentOnClaimChange, encoder(cli.ClientCredentials), encoder(cli.JWTBearerIssuers), encoder(cli.TokenExchange), encoder(cli.Resources), encoder(cli.CustomClaims), encoder(cli.RefreshTokenReuse), encoder(cli.DeviceFlow), encoder(cli.AllowedOrigins), encoder(cli.ReleasedConnectorClaims), encoder(cli.GroupsClaim), encoder(cli.Terms), encoder(cli.Impersonation),
 	)
 	if err != nil {
 		if c.alreadyExistsCheck(err) {
@@ -580,7 +686,7 @@ func (c *conn) CreateClient(ctx context.Context, cli storage.Client) error {
 func getClient(ctx context.Context, q querier, id string) (storage.Client, error) {
 	return scanClient(q.QueryRow(`
 		select
-			id, secret, redirect_uris, trusted_peers, public, name, logo_url, allowed_connectors, mfa_chain, post_logout_redirect_uris, sso_shared_with
+			id, secret, redirect_uris, trusted_peers, public, name, logo_url, allowed_connectors, mfa_chain, post_logout_redirect_uris, sso_shared_with, theme, require_consent_on_claim_change, client_credentials, jwt_bearer_issuers, token_exchange, resources, custom_claims, refresh_token_reuse, device_flow, allowed_origins, released_connector_claims, groups_claim, terms, impersonation
 	    from client where id = $1;
 	`, id))
 }
@@ -592,7 +698,7 @@ func (c *conn) GetClient(ctx context.Context, id string) (storage.Client, error)
 func (c *conn) ListClients(ctx context.Context) ([]storage.Client, error) {
 	rows, err := c.Query(`
 		select
-			id, secret, redirect_uris, trusted_peers, public, name, logo_url, allowed_connectors, mfa_chain, post_logout_redirect_uris, sso_shared_with
+			id, secret, redirect_uris, trusted_peers, public, name, logo_url, allowed_connectors, mfa_chain, post_logout_redirect_uris, sso_shared_with, theme, require_consent_on_claim_change, client_credentials, jwt_bearer_issuers, token_exchange, resources, custom_claims, refresh_token_reuse, device_flow, allowed_origins, released_connector_claims, groups_claim, terms, impersonation
 		from client;
 	`)
 	if err != nil {
@@ -619,9 +725,22 @@ func scanClient(s scanner) (cli storage.Client, err error) {
 	var mfaChain []byte
 	var postLogoutRedirectURIs []byte
 	var ssoSharedWith []byte
+	var theme []byte
+	var clientCredentials []byte
+	var jwtBearerIssuers []byte
+	var tokenExchange []byte
+	var resources []byte
+	var customClaims []byte
+	var refreshTokenReuse []byte
+	var deviceFlow []byte
+	var allowedOrigins []byte
+	var releasedConnectorClaims []byte
+	var groupsClaim []byte
+	var terms []byte
+	var impersonation []byte
 	err = s.Scan(
 		&cli.ID, &cli.Secret, decoder(&cli.RedirectURIs), decoder(&cli.TrustedPeers),
-		&cli.Public, &cli.Name, &cli.LogoURL, &allowedConnectors, &mfaChain, &postLogoutRedirectURIs, &ssoSharedWith,
+		&cli.Public, &cli.Name, &cli.LogoURL, &allowedConnectors, &mfaChain, &postLogoutRedirectURIs, &ssoSharedWith, &theme, &cli.RequireConsentOnClaimChange, &clientCredentials, &jwtBearerIssuers, &tokenExchange, &resources, &customClaims, &refreshTokenReuse, &deviceFlow, &allowedOrigins, &releasedConnectorClaims, &groupsClaim, &terms, &impersonation,
 	)
 	if err != nil {
 		if err == sql.ErrNoRows {
@@ -649,6 +768,71 @@ func scanClient(s scanner) (cli storage.Client, err error) {
 			return cli, fmt.Errorf("unmarshal client sso shared with: %v", err)
 		}
 	}
+	if len(theme) > 0 {
+		if err := json.Unmarshal(theme, &cli.Theme); err != nil {
+			return cli, fmt.Errorf("unmarshal client theme: %v", err)
+		}
+	}
+	if len(clientCredentials) > 0 {
+		if err := json.Unmarshal(clientCredentials, &cli.ClientCredentials); err != nil {
+			return cli, fmt.Errorf("unmarshal client credentials config: %v", err)
+		}
+	}
+	if len(jwtBearerIssuers) > 0 {
+		if err := json.Unmarshal(jwtBearerIssuers, &cli.JWTBearerIssuers); err != nil {
+			return cli, fmt.Errorf("unmarshal client jwt bearer issuers: %v", err)
+		}
+	}
+	if len(tokenExchange) > 0 {
+		if err := json.Unmarshal(tokenExchange, &cli.TokenExchange); err != nil {
+			return cli, fmt.Errorf("unmarshal client token exchange policy: %v", err)
+		}
+	}
+	if len(resources) > 0 {
+		if err := json.Unmarshal(resources, &cli.Resources); err != nil {
+			return cli, fmt.Errorf("unmarshal client resources: %v", err)
+		}
+	}
+	if len(customClaims) > 0 {
+		if err := json.Unmarshal(customClaims, &cli.CustomClaims); err != nil {
+			return cli, fmt.Errorf("unmarshal client custom claims: %v", err)
+		}
+	}
+	if len(refreshTokenReuse) > 0 {
+		if err := json.Unmarshal(refreshTokenReuse, &cli.RefreshTokenReuse); err != nil {
+			return cli, fmt.Errorf("unmarshal client refresh token reuse: %v", err)
+		}
+	}
+	if len(deviceFlow) > 0 {
+		if err := json.Unmarshal(deviceFlow, &cli.DeviceFlow); err != nil {
+			return cli, fmt.Errorf("unmarshal client device flow: %v", err)
+		}
+	}
+	if len(allowedOrigins) > 0 {
+		if err := json.Unmarshal(allowedOrigins, &cli.AllowedOrigins); err != nil {
+			return cli, fmt.Errorf("unmarshal client allowed origins: %v", err)
+		}
+	}
+	if len(releasedConnectorClaims) > 0 {
+		if err := json.Unmarshal(releasedConnectorClaims, &cli.ReleasedConnectorClaims); err != nil {
+			return cli, fmt.Errorf("unmarshal client released connector claims: %v", err)
+		}
+	}
+	if len(groupsClaim) > 0 {
+		if err := json.Unmarshal(groupsClaim, &cli.GroupsClaim); err != nil {
+			return cli, fmt.Errorf("unmarshal client groups claim: %v", err)
+		}
+	}
+	if len(terms) > 0 {
+		if err := json.Unmarshal(terms, &cli.Terms); err != nil {
+			return cli, fmt.Errorf("unmarshal client terms: %v", err)
+		}
+	}
+	if len(impersonation) > 0 {
+		if err := json.Unmarshal(impersonation, &cli.Impersonation); err != nil {
+			return cli, fmt.Errorf("unmarshal client impersonation: %v", err)
+		}
+	}
 	return cli, nil
 }
 
@@ -813,6 +997,31 @@ func getOfflineSessions(ctx context.Context, q querier, userID string, connID st
 		`, userID, connID))
 }
 
+func (c *conn) ListOfflineSessions(ctx context.Context) ([]storage.OfflineSessions, error) {
+	rows, err := c.Query(`
+		select
+			user_id, conn_id, refresh, connector_data
+		from offline_session;
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("query: %v", err)
+	}
+	defer rows.Close()
+
+	var sessions []storage.OfflineSessions
+	for rows.Next() {
+		o, err := scanOfflineSessions(rows)
+		if err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, o)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("scan: %v", err)
+	}
+	return sessions, nil
+}
+
 func scanOfflineSessions(s scanner) (o storage.OfflineSessions, err error) {
 	err = s.Scan(
 		&o.UserID, &o.ConnID, decoder(&o.Refresh), &o.ConnectorData,
@@ -832,18 +1041,20 @@ func (c *conn) CreateUserIdentity(ctx context.Context, u storage.UserIdentity) e
 			user_id, connector_id,
 			claims_user_id, claims_username, claims_preferred_username,
 			claims_email, claims_email_verified, claims_groups,
-			consents, mfa_secrets, webauthn_credentials,
-			created_at, last_login, blocked_until
+			consents, consented_claims, mfa_secrets, webauthn_credentials,
+			created_at, last_login, blocked_until,
+			claims_custom, accepted_terms
 		)
 		values (
-			$1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14
+			$1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17
 		);
 	`,
 		u.UserID, u.ConnectorID,
 		u.Claims.UserID, u.Claims.Username, u.Claims.PreferredUsername,
 		u.Claims.Email, u.Claims.EmailVerified, encoder(u.Claims.Groups),
-		encoder(u.Consents), encoder(u.MFASecrets), encoder(u.WebAuthnCredentials),
+		encoder(u.Consents), encoder(u.ConsentedClaims), encoder(u.MFASecrets), encoder(u.WebAuthnCredentials),
 		u.CreatedAt, u.LastLogin, u.BlockedUntil,
+		encoder(u.Claims.CustomClaims), encoder(u.AcceptedTerms),
 	)
 	if err != nil {
 		if c.alreadyExistsCheck(err) {
@@ -875,17 +1086,21 @@ func (c *conn) UpdateUserIdentity(ctx context.Context, userID, connectorID strin
 				claims_email_verified = $5,
 				claims_groups = $6,
 				consents = $7,
-				mfa_secrets = $8,
-				webauthn_credentials = $9,
-				created_at = $10,
-				last_login = $11,
-				blocked_until = $12
-			where user_id = $13 AND connector_id = $14;
+				consented_claims = $8,
+				mfa_secrets = $9,
+				webauthn_credentials = $10,
+				created_at = $11,
+				last_login = $12,
+				blocked_until = $13,
+				claims_custom = $14,
+				accepted_terms = $15
+			where user_id = $16 AND connector_id = $17;
 		`,
 			newIdentity.Claims.UserID, newIdentity.Claims.Username, newIdentity.Claims.PreferredUsername,
 			newIdentity.Claims.Email, newIdentity.Claims.EmailVerified, encoder(newIdentity.Claims.Groups),
-			encoder(newIdentity.Consents), encoder(newIdentity.MFASecrets), encoder(newIdentity.WebAuthnCredentials),
+			encoder(newIdentity.Consents), encoder(newIdentity.ConsentedClaims), encoder(newIdentity.MFASecrets), encoder(newIdentity.WebAuthnCredentials),
 			newIdentity.CreatedAt, newIdentity.LastLogin, newIdentity.BlockedUntil,
+			encoder(newIdentity.Claims.CustomClaims), encoder(newIdentity.AcceptedTerms),
 			u.UserID, u.ConnectorID,
 		)
 		if err != nil {
@@ -905,8 +1120,9 @@ func getUserIdentity(ctx context.Context, q querier, userID, connectorID string)
 			user_id, connector_id,
 			claims_user_id, claims_username, claims_preferred_username,
 			claims_email, claims_email_verified, claims_groups,
-			consents, mfa_secrets, webauthn_credentials,
-			created_at, last_login, blocked_until
+			consents, consented_claims, mfa_secrets, webauthn_credentials,
+			created_at, last_login, blocked_until,
+			claims_custom, accepted_terms
 		from user_identity
 		where user_id = $1 AND connector_id = $2;
 		`, userID, connectorID))
@@ -918,8 +1134,9 @@ func (c *conn) ListUserIdentities(ctx context.Context) ([]storage.UserIdentity,
 			user_id, connector_id,
 			claims_user_id, claims_username, claims_preferred_username,
 			claims_email, claims_email_verified, claims_groups,
-			consents, mfa_secrets, webauthn_credentials,
-			created_at, last_login, blocked_until
+			consents, consented_claims, mfa_secrets, webauthn_credentials,
+			created_at, last_login, blocked_until,
+			claims_custom, accepted_terms
 		from user_identity;
 	`)
 	if err != nil {
@@ -942,13 +1159,14 @@ func (c *conn) ListUserIdentities(ctx context.Context) ([]storage.UserIdentity,
 }
 
 func scanUserIdentity(s scanner) (u storage.UserIdentity, err error) {
-	var mfaSecrets, webauthnCreds []byte
+	var consentedClaims, mfaSecrets, webauthnCreds, customClaims, acceptedTerms []byte
 	err = s.Scan(
 		&u.UserID, &u.ConnectorID,
 		&u.Claims.UserID, &u.Claims.Username, &u.Claims.PreferredUsername,
 		&u.Claims.Email, &u.Claims.EmailVerified, decoder(&u.Claims.Groups),
-		decoder(&u.Consents), &mfaSecrets, &webauthnCreds,
+		decoder(&u.Consents), &consentedClaims, &mfaSecrets, &webauthnCreds,
 		&u.CreatedAt, &u.LastLogin, &u.BlockedUntil,
+		&customClaims, &acceptedTerms,
 	)
 	if err != nil {
 		if err == sql.ErrNoRows {
@@ -959,6 +1177,11 @@ func scanUserIdentity(s scanner) (u storage.UserIdentity, err error) {
 	if u.Consents == nil {
 		u.Consents = make(map[string][]string)
 	}
+	if len(consentedClaims) > 0 {
+		if err := json.Unmarshal(consentedClaims, &u.ConsentedClaims); err != nil {
+			return u, fmt.Errorf("unmarshal user identity consented claims: %v", err)
+		}
+	}
 	if len(mfaSecrets) > 0 {
 		if err := json.Unmarshal(mfaSecrets, &u.MFASecrets); err != nil {
 			return u, fmt.Errorf("unmarshal user identity mfa secrets: %v", err)
@@ -969,6 +1192,16 @@ func scanUserIdentity(s scanner) (u storage.UserIdentity, err error) {
 			return u, fmt.Errorf("unmarshal user identity webauthn credentials: %v", err)
 		}
 	}
+	if len(customClaims) > 0 {
+		if err := json.Unmarshal(customClaims, &u.Claims.CustomClaims); err != nil {
+			return u, fmt.Errorf("unmarshal user identity custom claims: %v", err)
+		}
+	}
+	if len(acceptedTerms) > 0 {
+		if err := json.Unmarshal(acceptedTerms, &u.AcceptedTerms); err != nil {
+			return u, fmt.Errorf("unmarshal user identity accepted terms: %v", err)
+		}
+	}
 	return u, nil
 }
 
@@ -1295,6 +1528,42 @@ func (c *conn) DeleteOfflineSessions(ctx context.Context, userID string, connID
 	return nil
 }
 
+func (c *conn) DeleteOfflineSessionsIfEmpty(ctx context.Context, userID string, connID string) (bool, error) {
+	var refresh []byte
+	err := c.QueryRow(`
+		select refresh from offline_session
+		where user_id = $1 AND conn_id = $2;
+	`, userID, connID).Scan(&refresh)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return false, storage.ErrNotFound
+		}
+		return false, fmt.Errorf("select offline session: %v", err)
+	}
+	var refs map[string]*storage.RefreshTokenRef
+	if err := json.Unmarshal(refresh, &refs); err != nil {
+		return false, fmt.Errorf("unmarshal refresh: %v", err)
+	}
+	if len(refs) > 0 {
+		return false, nil
+	}
+
+	// Only delete the session as it was read, a login may have added a
+	// refresh token to it since.
+	result, err := c.Exec(`
+		delete from offline_session
+		where user_id = $1 AND conn_id = $2 AND refresh = $3;
+	`, userID, connID, refresh)
+	if err != nil {
+		return false, fmt.Errorf("delete offline_session: %v", err)
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("rows affected: %v", err)
+	}
+	return n > 0, nil
+}
+
 // Do NOT call directly. Does not escape table.
 func (c *conn) delete(table, field, id string) error {
 	result, err := c.Exec(`delete from `+table+` where `+field+` = $1`, id)
@@ -1425,3 +1694,139 @@ func (c *conn) UpdateDeviceToken(ctx context.Context, deviceCode string, updater
 		return nil
 	})
 }
+
+func (c *conn) UpdateRateLimitBucket(ctx context.Context, key string, updater func(b storage.RateLimitBucket) (storage.RateLimitBucket, error)) error {
+	// Create missing buckets outside of the transaction, so concurrent
+	// requests for a new bucket don't fail on the insert.
+	empty := time.Unix(0, 0)
+	_, err := c.Exec(`
+		insert into rate_limit_bucket (bucket_key, tat, expiry) values ($1, $2, $3);
+	`, key, empty, empty)
+	if err != nil && !c.alreadyExistsCheck(err) {
+		return fmt.Errorf("insert rate limit bucket: %v", err)
+	}
+
+	// Update the bucket with a compare-and-swap on its previous timestamp
+	// instead of a read-modify-write transaction. A single update statement is
+	// atomic on every flavor, so concurrent requests can't overwrite each
+	// other's increments, and losing the race only means reading the bucket
+	// again.
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		b := storage.RateLimitBucket{Key: key}
+		err := c.QueryRow(`
+			select tat, failure_count, expiry from rate_limit_bucket where bucket_key = $1;
+		`, key).Scan(&b.TAT, &b.Count, &b.Expiry)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				return storage.ErrNotFound
+			}
+			return fmt.Errorf("select rate limit bucket: %v", err)
+		}
+		prev := b
+
+		if b, err = updater(b); err != nil {
+			return err
+		}
+		// A denied request leaves the bucket unchanged. Don't update it, MySQL
+		// reports changed rather than matched rows and the swap would never
+		// appear to succeed.
+		if b.TAT.Equal(prev.TAT) && b.Count == prev.Count && b.Expiry.Equal(prev.Expiry) {
+			return nil
+		}
+
+		result, err := c.Exec(`
+			update rate_limit_bucket set tat = $1, failure_count = $2, expiry = $3
+			where bucket_key = $4 and tat = $5 and failure_count = $6;
+		`, b.TAT, b.Count, b.Expiry, key, prev.TAT, prev.Count)
+		if err != nil {
+			return fmt.Errorf("update rate limit bucket: %v", err)
+		}
+		n, err := result.RowsAffected()
+		if err != nil {
+			return fmt.Errorf("update rate limit bucket: %v", err)
+		}
+		if n > 0 {
+			return nil
+		}
+	}
+}
+
+func (c *conn) CreateAuditEvent(ctx context.Context, e storage.AuditEvent) error {
+	_, err := c.Exec(`
+		insert into audit_event (
+			id, event_type, event_time, subject, client_id, connector_id, data, expiry
+		)
+		values ($1, $2, $3, $4, $5, $6, $7, $8);
+	`, e.ID, e.Type, e.Time, e.Subject, e.ClientID, e.ConnectorID, e.Data, e.Expiry)
+	if err != nil {
+		if c.alreadyExistsCheck(err) {
+			return storage.ErrAlreadyExists
+		}
+		return fmt.Errorf("insert audit event: %v", err)
+	}
+	return nil
+}
+
+func (c *conn) ListAuditEvents(ctx context.Context, f storage.AuditEventFilter) ([]storage.AuditEvent, error) {
+	var (
+		where []string
+		args  []interface{}
+	)
+	add := func(cond string, arg interface{}) {
+		args = append(args, arg)
+		where = append(where, fmt.Sprintf(cond, len(args)))
+	}
+	if f.Subject != "" {
+		add("subject = $%d", f.Subject)
+	}
+	if f.ClientID != "" {
+		add("client_id = $%d", f.ClientID)
+	}
+	if f.ConnectorID != "" {
+		add("connector_id = $%d", f.ConnectorID)
+	}
+	if f.Type != "" {
+		add("event_type = $%d", f.Type)
+	}
+	if !f.Since.IsZero() {
+		add("event_time >= $%d", f.Since)
+	}
+	if !f.Until.IsZero() {
+		add("event_time <= $%d", f.Until)
+	}
+
+	query := `
+		select id, event_type, event_time, subject, client_id, connector_id, data, expiry
+		from audit_event`
+	if len(where) > 0 {
+		query += " where " + strings.Join(where, " and ")
+	}
+	query += " order by event_time desc"
+	if f.Limit > 0 {
+		query += fmt.Sprintf(" limit %d", f.Limit)
+	}
+
+	rows, err := c.Query(query+";", args...)
+	if err != nil {
+		return nil, fmt.Errorf("query: %v", err)
+	}
+	defer rows.Close()
+
+	var events []storage.AuditEvent
+	for rows.Next() {
+		var e storage.AuditEvent
+		err := rows.Scan(&e.ID, &e.Type, &e.Time, &e.Subject, &e.ClientID, &e.ConnectorID, &e.Data, &e.Expiry)
+		if err != nil {
+			return nil, fmt.Errorf("scan audit event: %v", err)
+		}
+		events = append(events, e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("scan: %v", err)
+	}
+	return events, nil
+}
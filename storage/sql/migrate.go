@@ -19,12 +19,7 @@ func (c *conn) migrate() (int, error) {
 	i := 0
 	done := false
 
-	var flavorMigrations []migration
-	for _, m := range migrations {
-		if m.flavor == nil || m.flavor == c.flavor {
-			flavorMigrations = append(flavorMigrations, m)
-		}
-	}
+	flavorMigrations := flavorMigrations(c.flavor)
 
 	for {
 		err := c.ExecTx(func(tx *trans) error {
@@ -70,6 +65,37 @@ func (c *conn) migrate() (int, error) {
 	return i, nil
 }
 
+// pendingMigrations returns the number of migrations migrate would apply. It
+// doesn't write to the database.
+func (c *conn) pendingMigrations() (int, error) {
+	if err := c.db.Ping(); err != nil {
+		return 0, fmt.Errorf("connect: %v", err)
+	}
+	total := len(flavorMigrations(c.flavor))
+
+	var num sql.NullInt64
+	if err := c.QueryRow(`select max(num) from migrations;`).Scan(&num); err != nil {
+		// The database is reachable, so the migrations table doesn't exist
+		// yet: no migration has been applied.
+		return total, nil
+	}
+	if !num.Valid {
+		return total, nil
+	}
+	return max(total-int(num.Int64), 0), nil
+}
+
+// flavorMigrations returns the migrations applying to the flavor.
+func flavorMigrations(f *flavor) []migration {
+	var ms []migration
+	for _, m := range migrations {
+		if m.flavor == nil || m.flavor == f {
+			ms = append(ms, m)
+		}
+	}
+	return ms
+}
+
 type migration struct {
 	stmts []string
 
@@ -467,4 +493,130 @@ var migrations = []migration{
 				add column sso_shared_with bytea;`,
 		},
 	},
+	{
+		stmts: []string{
+			`alter table client add column theme bytea;`,
+		},
+	},
+	{
+		stmts: []string{
+			`alter table client add column require_consent_on_claim_change boolean not null default false;`,
+			`alter table user_identity add column consented_claims bytea;`,
+		},
+	},
+	{
+		stmts: []string{
+			`alter table client add column client_credentials bytea;`,
+		},
+	},
+	{
+		stmts: []string{
+			`alter table client add column jwt_bearer_issuers bytea;`,
+		},
+	},
+	{
+		stmts: []string{
+			`alter table client add column token_exchange bytea;`,
+		},
+	},
+	{
+		stmts: []string{
+			`alter table auth_request add column resources bytea;`,
+			`alter table auth_code add column resources bytea;`,
+			`alter table refresh_token add column resources bytea;`,
+			`alter table client add column resources bytea;`,
+		},
+	},
+	{
+		stmts: []string{
+			`alter table client add column custom_claims bytea;`,
+		},
+	},
+	{
+		stmts: []string{
+			`alter table client add column refresh_token_reuse bytea;`,
+		},
+	},
+	{
+		stmts: []string{
+			`alter table client add column device_flow bytea;`,
+		},
+	},
+	{
+		stmts: []string{
+			`alter table auth_request add column login_hint text not null default '';`,
+		},
+	},
+	{
+		stmts: []string{
+			`alter table client add column allowed_origins bytea;`,
+		},
+	},
+	{
+		stmts: []string{
+			`
+			create table rate_limit_bucket (
+				bucket_key text not null primary key,
+				tat timestamptz not null,
+				expiry timestamptz not null
+			);`,
+		},
+	},
+	{
+		stmts: []string{
+			`
+			create table audit_event (
+				id text not null primary key,
+				event_type text not null,
+				event_time timestamptz not null,
+				subject text not null,
+				client_id text not null,
+				connector_id text not null,
+				data bytea not null,
+				expiry timestamptz not null
+			);`,
+			`create index audit_event_time on audit_event (event_time);`,
+		},
+	},
+	{
+		stmts: []string{
+			`alter table client add column released_connector_claims bytea;`,
+			`alter table auth_request add column claims_custom bytea;`,
+			`alter table auth_code add column claims_custom bytea;`,
+			`alter table refresh_token add column claims_custom bytea;`,
+			`alter table user_identity add column claims_custom bytea;`,
+		},
+	},
+	{
+		stmts: []string{
+			`alter table client add column groups_claim bytea;`,
+		},
+	},
+	{
+		stmts: []string{
+			`alter table client add column terms bytea;`,
+			`alter table user_identity add column accepted_terms bytea;`,
+		},
+	},
+	{
+		stmts: []string{
+			`alter table refresh_token add column parent_id text not null default '';`,
+			`alter table refresh_token add column audience bytea;`,
+		},
+	},
+	{
+		stmts: []string{
+			`alter table client add column impersonation bytea;`,
+		},
+	},
+	{
+		stmts: []string{
+			`alter table refresh_token add column rotation_key text not null default '';`,
+		},
+	},
+	{
+		stmts: []string{
+			`alter table rate_limit_bucket add column failure_count integer not null default 0;`,
+		},
+	},
 }
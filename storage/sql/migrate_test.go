@@ -46,3 +46,33 @@ func TestMigrate(t *testing.T) {
 		}
 	}
 }
+
+func TestPendingMigrations(t *testing.T) {
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	logger := slog.New(slog.DiscardHandler)
+	c := &conn{db, &flavorSQLite3, logger, nil}
+
+	total := len(flavorMigrations(&flavorSQLite3))
+	got, err := c.pendingMigrations()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != total {
+		t.Errorf("expected %d pending migrations on an empty database, got %d", total, got)
+	}
+
+	if _, err := c.migrate(); err != nil {
+		t.Fatal(err)
+	}
+	if got, err = c.pendingMigrations(); err != nil {
+		t.Fatal(err)
+	}
+	if got != 0 {
+		t.Errorf("expected no pending migrations after migrating, got %d", got)
+	}
+}
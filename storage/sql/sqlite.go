@@ -5,8 +5,12 @@ package sql
 
 import (
 	"database/sql"
+	"errors"
 	"fmt"
+	"io/fs"
 	"log/slog"
+	"os"
+	"strings"
 
 	sqlite3 "github.com/mattn/go-sqlite3"
 
@@ -29,7 +33,37 @@ func (s *SQLite3) Open(logger *slog.Logger) (storage.Storage, error) {
 }
 
 func (s *SQLite3) open(logger *slog.Logger) (*conn, error) {
-	db, err := sql.Open("sqlite3", s.File)
+	c, err := s.connect(logger, s.File)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := c.migrate(); err != nil {
+		return nil, fmt.Errorf("failed to perform migrations: %v", err)
+	}
+	return c, nil
+}
+
+// PendingMigrations returns the number of schema migrations Open would
+// apply, without applying them. The database is opened read-only and not
+// created if it doesn't exist.
+func (s *SQLite3) PendingMigrations(logger *slog.Logger) (int, error) {
+	dsn := s.File
+	if !strings.HasPrefix(dsn, "file:") && !strings.Contains(dsn, "?") {
+		if _, err := os.Stat(dsn); dsn == ":memory:" || errors.Is(err, fs.ErrNotExist) {
+			return len(flavorMigrations(&flavorSQLite3)), nil
+		}
+		dsn = "file:" + dsn + "?mode=ro"
+	}
+	c, err := s.connect(logger, dsn)
+	if err != nil {
+		return 0, err
+	}
+	defer c.Close()
+	return c.pendingMigrations()
+}
+
+func (s *SQLite3) connect(logger *slog.Logger, dsn string) (*conn, error) {
+	db, err := sql.Open("sqlite3", dsn)
 	if err != nil {
 		return nil, err
 	}
@@ -45,9 +79,5 @@ func (s *SQLite3) open(logger *slog.Logger) (*conn, error) {
 		return sqlErr.ExtendedCode == sqlite3.ErrConstraintPrimaryKey
 	}
 
-	c := &conn{db, &flavorSQLite3, logger, errCheck}
-	if _, err := c.migrate(); err != nil {
-		return nil, fmt.Errorf("failed to perform migrations: %v", err)
-	}
-	return c, nil
+	return &conn{db, &flavorSQLite3, logger, errCheck}, nil
 }
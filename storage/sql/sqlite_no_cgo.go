@@ -17,3 +17,7 @@ type SQLite3 struct{}
 func (s *SQLite3) Open(logger *slog.Logger) (storage.Storage, error) {
 	return nil, fmt.Errorf("SQLite storage is not available: binary compiled without CGO support. Recompile with CGO_ENABLED=1 or use a different storage backend.")
 }
+
+func (s *SQLite3) PendingMigrations(logger *slog.Logger) (int, error) {
+	return 0, fmt.Errorf("SQLite storage is not available: binary compiled without CGO support. Recompile with CGO_ENABLED=1 or use a different storage backend.")
+}
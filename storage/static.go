@@ -5,11 +5,60 @@ import (
 	"errors"
 	"log/slog"
 	"strings"
+	"sync/atomic"
 )
 
 // Tests for this code are in the "memory" package, since this package doesn't
 // define a concrete storage implementation.
 
+// staticSet is a read-only list of objects indexed by their key. Static
+// storages hold it through a pointer so it can be swapped out as a whole.
+type staticSet[T any] struct {
+	list  []T
+	byKey map[string]T
+}
+
+// StaticObjects holds the static clients, passwords and connectors layered
+// over a storage by WithStaticObjects. They can be replaced while the storage
+// is in use, for example when the config file is reloaded.
+type StaticObjects struct {
+	clients    atomic.Pointer[staticSet[Client]]
+	passwords  atomic.Pointer[staticSet[Password]]
+	connectors atomic.Pointer[staticSet[Connector]]
+
+	logger *slog.Logger
+}
+
+// WithStaticObjects behaves like WithStaticClients, WithStaticPasswords and
+// WithStaticConnectors combined, but also returns a handle to replace the
+// static objects later.
+func WithStaticObjects(s Storage, clients []Client, passwords []Password, connectors []Connector, logger *slog.Logger) (Storage, *StaticObjects) {
+	o := &StaticObjects{logger: logger}
+	o.SetClients(clients)
+	o.SetPasswords(passwords)
+	o.SetConnectors(connectors)
+
+	s = staticClientsStorage{s, &o.clients}
+	s = staticPasswordsStorage{s, &o.passwords}
+	s = staticConnectorsStorage{s, &o.connectors}
+	return s, o
+}
+
+// SetClients replaces the static clients.
+func (o *StaticObjects) SetClients(clients []Client) {
+	o.clients.Store(newClientSet(clients))
+}
+
+// SetPasswords replaces the static passwords.
+func (o *StaticObjects) SetPasswords(passwords []Password) {
+	o.passwords.Store(newPasswordSet(passwords, o.logger))
+}
+
+// SetConnectors replaces the static connectors.
+func (o *StaticObjects) SetConnectors(connectors []Connector) {
+	o.connectors.Store(newConnectorSet(connectors))
+}
+
 // staticClientsStorage is a storage that only allow read-only actions on clients.
 // All read actions return from the list of clients stored in memory, not the
 // underlying
@@ -17,29 +66,33 @@ type staticClientsStorage struct {
 	Storage
 
 	// A read-only set of clients.
-	clients     []Client
-	clientsByID map[string]Client
+	clients *atomic.Pointer[staticSet[Client]]
 }
 
 // WithStaticClients adds a read-only set of clients to the underlying storages.
 func WithStaticClients(s Storage, staticClients []Client) Storage {
-	clientsByID := make(map[string]Client, len(staticClients))
-	for _, client := range staticClients {
-		clientsByID[client.ID] = client
-	}
+	clients := new(atomic.Pointer[staticSet[Client]])
+	clients.Store(newClientSet(staticClients))
+	return staticClientsStorage{s, clients}
+}
 
-	return staticClientsStorage{s, staticClients, clientsByID}
+func newClientSet(clients []Client) *staticSet[Client] {
+	set := &staticSet[Client]{list: clients, byKey: make(map[string]Client, len(clients))}
+	for _, client := range clients {
+		set.byKey[client.ID] = client
+	}
+	return set
 }
 
 func (s staticClientsStorage) GetClient(ctx context.Context, id string) (Client, error) {
-	if client, ok := s.clientsByID[id]; ok {
+	if client, ok := s.clients.Load().byKey[id]; ok {
 		return client, nil
 	}
 	return s.Storage.GetClient(ctx, id)
 }
 
 func (s staticClientsStorage) isStatic(id string) bool {
-	_, ok := s.clientsByID[id]
+	_, ok := s.clients.Load().byKey[id]
 	return ok
 }
 
@@ -48,16 +101,17 @@ func (s staticClientsStorage) ListClients(ctx context.Context) ([]Client, error)
 	if err != nil {
 		return nil, err
 	}
+	static := s.clients.Load()
 	n := 0
 	for _, client := range clients {
 		// If a client in the backing storage has the same ID as a static client
 		// prefer the static client.
-		if !s.isStatic(client.ID) {
+		if _, ok := static.byKey[client.ID]; !ok {
 			clients[n] = client
 			n++
 		}
 	}
-	return append(clients[:n], s.clients...), nil
+	return append(clients[:n], static.list...), nil
 }
 
 func (s staticClientsStorage) CreateClient(ctx context.Context, c Client) error {
@@ -84,31 +138,32 @@ func (s staticClientsStorage) UpdateClient(ctx context.Context, id string, updat
 type staticPasswordsStorage struct {
 	Storage
 
-	// A read-only set of passwords.
-	passwords []Password
-	// A map of passwords that is indexed by lower-case email ids
-	passwordsByEmail map[string]Password
-
-	logger *slog.Logger
+	// A read-only set of passwords, indexed by lower-case email ids.
+	passwords *atomic.Pointer[staticSet[Password]]
 }
 
 // WithStaticPasswords returns a storage with a read-only set of passwords.
 func WithStaticPasswords(s Storage, staticPasswords []Password, logger *slog.Logger) Storage {
-	passwordsByEmail := make(map[string]Password, len(staticPasswords))
-	for _, p := range staticPasswords {
+	passwords := new(atomic.Pointer[staticSet[Password]])
+	passwords.Store(newPasswordSet(staticPasswords, logger))
+	return staticPasswordsStorage{s, passwords}
+}
+
+func newPasswordSet(passwords []Password, logger *slog.Logger) *staticSet[Password] {
+	set := &staticSet[Password]{list: passwords, byKey: make(map[string]Password, len(passwords))}
+	for _, p := range passwords {
 		// Enable case insensitive email comparison.
 		lowerEmail := strings.ToLower(p.Email)
-		if _, ok := passwordsByEmail[lowerEmail]; ok {
+		if _, ok := set.byKey[lowerEmail]; ok {
 			logger.Error("attempting to create StaticPasswords with the same email id", "email", p.Email)
 		}
-		passwordsByEmail[lowerEmail] = p
+		set.byKey[lowerEmail] = p
 	}
-
-	return staticPasswordsStorage{s, staticPasswords, passwordsByEmail, logger}
+	return set
 }
 
 func (s staticPasswordsStorage) isStatic(email string) bool {
-	_, ok := s.passwordsByEmail[strings.ToLower(email)]
+	_, ok := s.passwords.Load().byKey[strings.ToLower(email)]
 	return ok
 }
 
@@ -116,7 +171,7 @@ func (s staticPasswordsStorage) GetPassword(ctx context.Context, email string) (
 	// TODO(ericchiang): BLAH. We really need to figure out how to handle
 	// lower cased emails better.
 	email = strings.ToLower(email)
-	if password, ok := s.passwordsByEmail[email]; ok {
+	if password, ok := s.passwords.Load().byKey[email]; ok {
 		return password, nil
 	}
 	return s.Storage.GetPassword(ctx, email)
@@ -128,16 +183,17 @@ func (s staticPasswordsStorage) ListPasswords(ctx context.Context) ([]Password,
 		return nil, err
 	}
 
+	static := s.passwords.Load()
 	n := 0
 	for _, password := range passwords {
 		// If an entry has the same email as those provided in the static
 		// values, prefer the static value.
-		if !s.isStatic(password.Email) {
+		if _, ok := static.byKey[strings.ToLower(password.Email)]; !ok {
 			passwords[n] = password
 			n++
 		}
 	}
-	return append(passwords[:n], s.passwords...), nil
+	return append(passwords[:n], static.list...), nil
 }
 
 func (s staticPasswordsStorage) CreatePassword(ctx context.Context, p Password) error {
@@ -166,27 +222,32 @@ type staticConnectorsStorage struct {
 	Storage
 
 	// A read-only set of connectors.
-	connectors     []Connector
-	connectorsByID map[string]Connector
+	connectors *atomic.Pointer[staticSet[Connector]]
 }
 
 // WithStaticConnectors returns a storage with a read-only set of Connectors. Write actions,
 // such as updating existing Connectors, will fail.
 func WithStaticConnectors(s Storage, staticConnectors []Connector) Storage {
-	connectorsByID := make(map[string]Connector, len(staticConnectors))
-	for _, c := range staticConnectors {
-		connectorsByID[c.ID] = c
+	connectors := new(atomic.Pointer[staticSet[Connector]])
+	connectors.Store(newConnectorSet(staticConnectors))
+	return staticConnectorsStorage{s, connectors}
+}
+
+func newConnectorSet(connectors []Connector) *staticSet[Connector] {
+	set := &staticSet[Connector]{list: connectors, byKey: make(map[string]Connector, len(connectors))}
+	for _, c := range connectors {
+		set.byKey[c.ID] = c
 	}
-	return staticConnectorsStorage{s, staticConnectors, connectorsByID}
+	return set
 }
 
 func (s staticConnectorsStorage) isStatic(id string) bool {
-	_, ok := s.connectorsByID[id]
+	_, ok := s.connectors.Load().byKey[id]
 	return ok
 }
 
 func (s staticConnectorsStorage) GetConnector(ctx context.Context, id string) (Connector, error) {
-	if connector, ok := s.connectorsByID[id]; ok {
+	if connector, ok := s.connectors.Load().byKey[id]; ok {
 		return connector, nil
 	}
 	return s.Storage.GetConnector(ctx, id)
@@ -198,16 +259,17 @@ func (s staticConnectorsStorage) ListConnectors(ctx context.Context) ([]Connecto
 		return nil, err
 	}
 
+	static := s.connectors.Load()
 	n := 0
 	for _, connector := range connectors {
 		// If an entry has the same id as those provided in the static
 		// values, prefer the static value.
-		if !s.isStatic(connector.ID) {
+		if _, ok := static.byKey[connector.ID]; !ok {
 			connectors[n] = connector
 			n++
 		}
 	}
-	return append(connectors[:n], s.connectors...), nil
+	return append(connectors[:n], static.list...), nil
 }
 
 func (s staticConnectorsStorage) CreateConnector(ctx context.Context, c Connector) error {
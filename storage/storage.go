@@ -56,11 +56,13 @@ func NewHMACKey(h crypto.Hash) []byte {
 
 // GCResult returns the number of objects deleted by garbage collection.
 type GCResult struct {
-	AuthRequests   int64
-	AuthCodes      int64
-	DeviceRequests int64
-	DeviceTokens   int64
-	AuthSessions   int64
+	AuthRequests     int64
+	AuthCodes        int64
+	DeviceRequests   int64
+	DeviceTokens     int64
+	AuthSessions     int64
+	RateLimitBuckets int64
+	AuditEvents      int64
 }
 
 // IsEmpty returns whether the garbage collection result is empty or not.
@@ -69,7 +71,9 @@ func (g *GCResult) IsEmpty() bool {
 		g.AuthCodes == 0 &&
 		g.DeviceRequests == 0 &&
 		g.DeviceTokens == 0 &&
-		g.AuthSessions == 0
+		g.AuthSessions == 0 &&
+		g.RateLimitBuckets == 0 &&
+		g.AuditEvents == 0
 }
 
 // Storage is the storage interface used by the server. Implementations are
@@ -108,6 +112,7 @@ type Storage interface {
 
 	ListClients(ctx context.Context) ([]Client, error)
 	ListRefreshTokens(ctx context.Context) ([]RefreshToken, error)
+	ListOfflineSessions(ctx context.Context) ([]OfflineSessions, error)
 	ListPasswords(ctx context.Context) ([]Password, error)
 	ListConnectors(ctx context.Context) ([]Connector, error)
 	ListUserIdentities(ctx context.Context) ([]UserIdentity, error)
@@ -154,6 +159,96 @@ type Storage interface {
 	GarbageCollect(ctx context.Context, now time.Time) (GCResult, error)
 }
 
+// OfflineSessionsStorage is implemented by storages which can prune offline
+// sessions. Kubernetes, which can't list refresh tokens, doesn't.
+type OfflineSessionsStorage interface {
+	// DeleteOfflineSessionsIfEmpty atomically deletes the offline sessions
+	// of the user with the connector if they have no refresh tokens, and
+	// reports whether they were deleted. Sessions a refresh token was added
+	// to concurrently are kept.
+	DeleteOfflineSessionsIfEmpty(ctx context.Context, userID string, connID string) (bool, error)
+}
+
+// RateLimitStorage is implemented by storages which can share rate limit
+// state between all dex instances using them. Their GarbageCollect also
+// deletes expired rate limit buckets.
+type RateLimitStorage interface {
+	// UpdateRateLimitBucket atomically updates the bucket identified by key.
+	// Missing buckets are passed to the updater with only Key set.
+	UpdateRateLimitBucket(ctx context.Context, key string, updater func(b RateLimitBucket) (RateLimitBucket, error)) error
+}
+
+// RateLimitBucket is the state of a rate limit bucket, following the generic
+// cell rate algorithm, or of a counter of failures, e.g. failed logins.
+type RateLimitBucket struct {
+	// Key identifies the bucket, e.g. "ip:192.0.2.1".
+	Key string
+
+	// TAT is the theoretical arrival time of the next request. The bucket
+	// is full once it has passed. Counters keep the time of the last failure.
+	TAT time.Time
+
+	// Count is the number of failures of a counter.
+	Count int
+
+	// Expiry is when the bucket is full again and can be deleted.
+	Expiry time.Time
+}
+
+// AuditStorage is implemented by storages which can keep a history of audit
+// events. Their GarbageCollect also deletes expired audit events.
+type AuditStorage interface {
+	CreateAuditEvent(ctx context.Context, e AuditEvent) error
+
+	// ListAuditEvents returns the events matching the filter, newest first.
+	ListAuditEvents(ctx context.Context, f AuditEventFilter) ([]AuditEvent, error)
+}
+
+// AuditEvent is an audit event kept in the storage.
+type AuditEvent struct {
+	ID string
+
+	// Type is the CloudEvents type of the event, e.g. "io.dexidp.login.v1".
+	Type string
+	Time time.Time
+
+	// Subject is the user the event is about, if any.
+	Subject     string
+	ClientID    string
+	ConnectorID string
+
+	// Data is the event data as JSON.
+	Data []byte
+
+	// Expiry is when the event is deleted by garbage collection.
+	Expiry time.Time
+}
+
+// AuditEventFilter selects audit events. Empty fields match all events.
+type AuditEventFilter struct {
+	Subject     string
+	ClientID    string
+	ConnectorID string
+	Type        string
+
+	// Since and Until bound the time of the events, both inclusive.
+	Since time.Time
+	Until time.Time
+
+	// Limit is the maximum number of events returned. Zero means no limit.
+	Limit int
+}
+
+// Match reports whether the event matches the filter, ignoring Limit.
+func (f AuditEventFilter) Match(e AuditEvent) bool {
+	return (f.Subject == "" || f.Subject == e.Subject) &&
+		(f.ClientID == "" || f.ClientID == e.ClientID) &&
+		(f.ConnectorID == "" || f.ConnectorID == e.ConnectorID) &&
+		(f.Type == "" || f.Type == e.Type) &&
+		(f.Since.IsZero() || !e.Time.Before(f.Since)) &&
+		(f.Until.IsZero() || !e.Time.After(f.Until))
+}
+
 // Client represents an OAuth2 client.
 //
 // For further reading see:
@@ -203,6 +298,232 @@ type Client struct {
 	// nil means use ssoSharedWithDefault from sessions config.
 	// Empty slice [] means explicitly share with no one.
 	SSOSharedWith []string `json:"ssoSharedWith" yaml:"ssoSharedWith"`
+
+	// Theme overrides the branding of the login and approval pages shown for
+	// this client. nil means the global theme is used.
+	Theme *ClientTheme `json:"theme,omitempty"`
+
+	// RequireConsentOnClaimChange asks users to approve this client again
+	// whenever the claims released to it differ from those shown when they
+	// last gave consent, e.g. after they joined a new group.
+	RequireConsentOnClaimChange bool `json:"requireConsentOnClaimChange,omitempty"`
+
+	// ClientCredentials configures the tokens this client obtains for itself
+	// with the client_credentials grant. nil means tokens are issued for the
+	// client itself, without additional claims.
+	ClientCredentials *ClientCredentialsConfig `json:"clientCredentials,omitempty"`
+
+	// JWTBearerIssuers are the issuers whose JWTs the client can trade for
+	// dex tokens with the JWT bearer grant (RFC 7523).
+	JWTBearerIssuers []JWTBearerIssuer `json:"jwtBearerIssuers,omitempty"`
+
+	// TokenExchange restricts the tokens the client can exchange and obtain
+	// with token exchange (RFC 8693). nil places no restrictions beyond
+	// AllowedConnectors.
+	TokenExchange *TokenExchangePolicy `json:"tokenExchange,omitempty"`
+
+	// Resources lists the resource indicators (RFC 8707) the client can
+	// request access tokens for. Tokens issued for resources are only valid
+	// for them.
+	Resources []string `json:"resources,omitempty"`
+
+	// CustomClaims are added to the ID tokens issued to the client. String
+	// values can be templates of the user's claims, e.g. "{{.Email}}". They
+	// never replace claims set by dex.
+	CustomClaims map[string]interface{} `json:"customClaims,omitempty"`
+
+	// ReleasedConnectorClaims are the custom claims of connector identities,
+	// e.g. tenant or roles, which are added to the tokens issued to the
+	// client. "*" releases all of them. None are released by default.
+	ReleasedConnectorClaims []string `json:"releasedConnectorClaims,omitempty"`
+
+	// RefreshTokenReuse configures what happens when a rotated refresh token
+	// of the client is presented again. nil only rejects the reused token.
+	RefreshTokenReuse *RefreshTokenReusePolicy `json:"refreshTokenReuse,omitempty"`
+
+	// DeviceFlow overrides the server's device flow settings for the client.
+	// nil uses the defaults.
+	DeviceFlow *DeviceFlowConfig `json:"deviceFlow,omitempty"`
+
+	// AllowedOrigins are the web origins, e.g. "https://app.example.com", the
+	// client's browser apps call dex from. They are allowed by the CORS
+	// policies of the endpoints which permit client origins.
+	AllowedOrigins []string `json:"allowedOrigins,omitempty"`
+
+	// GroupsClaim overrides the server's name and format of the groups claim
+	// in the tokens issued to the client. nil uses the server's.
+	GroupsClaim *GroupsClaim `json:"groupsClaim,omitempty"`
+
+	// Terms are the terms of service or consent users accept on the approval
+	// page. Users accept them again when their version changes. nil requires
+	// no acceptance.
+	Terms *ClientTerms `json:"terms,omitempty"`
+
+	// Impersonation lets the client request tokens for other users. nil
+	// forbids it.
+	Impersonation *ImpersonationPolicy `json:"impersonation,omitempty"`
+}
+
+// ClientTerms are the versioned terms of service or consent of a client.
+type ClientTerms struct {
+	// Version identifies the terms, e.g. "2024-05". Changing it asks every
+	// user to accept the terms again.
+	Version string `json:"version"`
+
+	// URL links the terms on the approval page.
+	URL string `json:"url,omitempty"`
+}
+
+// Formats of the groups claim.
+const (
+	// GroupsClaimArray emits groups as a JSON array, the default.
+	GroupsClaimArray = "array"
+	// GroupsClaimString emits groups as a space delimited string.
+	GroupsClaimString = "string"
+)
+
+// GroupsClaim is the name and format of the groups claim, for relying parties
+// which can't read the standard "groups" array.
+type GroupsClaim struct {
+	// Name of the claim, e.g. "roles" or "cognito:groups". Defaults to
+	// "groups".
+	Name string `json:"name,omitempty"`
+
+	// Format is GroupsClaimArray (default) or GroupsClaimString.
+	Format string `json:"format,omitempty"`
+}
+
+// DeviceFlowConfig holds the device flow (RFC 8628) settings of a client.
+// Zero values use the server's defaults.
+type DeviceFlowConfig struct {
+	// PollInterval is the minimum number of seconds the device waits between
+	// polls of the token endpoint.
+	PollInterval int `json:"pollInterval,omitempty"`
+
+	// ExpiresIn is the number of seconds the user code and device code are
+	// valid for.
+	ExpiresIn int `json:"expiresIn,omitempty"`
+
+	// UserCodeLength is the number of characters of user codes, without the
+	// dashes separating groups of four. Defaults to 8.
+	UserCodeLength int `json:"userCodeLength,omitempty"`
+
+	// UserCodeAlphabet are the characters user codes are made of, e.g. only
+	// digits for devices with a numeric keypad. It must only contain upper
+	// case letters and digits, as users can enter codes in any case.
+	UserCodeAlphabet string `json:"userCodeAlphabet,omitempty"`
+
+	// Only restricts the client to the device flow: it can't redirect users
+	// to its own redirect URIs or use other grants, apart from refreshing
+	// its tokens.
+	Only bool `json:"only,omitempty"`
+}
+
+// RefreshTokenReusePolicy controls the response to a refresh token that was
+// already rotated being presented again, which means it was stolen or the
+// client is broken.
+type RefreshTokenReusePolicy struct {
+	// RevokeSessions revokes the reused refresh token and the tokens exchanged
+	// for it, so neither the thief nor the client can keep using the chain.
+	// Refresh tokens of the user for other clients stay valid.
+	RevokeSessions bool `json:"revokeSessions,omitempty"`
+
+	// RevokeUpstream also revokes the user's tokens at the upstream identity
+	// provider, if the connector supports it.
+	RevokeUpstream bool `json:"revokeUpstream,omitempty"`
+}
+
+// TokenExchangePolicy restricts what a client can do with token exchange.
+// Empty lists place no restriction.
+type TokenExchangePolicy struct {
+	// SubjectTokenTypes the client can exchange, e.g.
+	// "urn:ietf:params:oauth:token-type:id_token".
+	SubjectTokenTypes []string `json:"subjectTokenTypes,omitempty"`
+
+	// Connectors whose tokens the client can exchange.
+	Connectors []string `json:"connectors,omitempty"`
+
+	// Audiences the client can request with the "audience" parameter. They
+	// are added to the audience of the issued token. Requesting an audience
+	// which isn't allowed is an error. Clients without a policy can't add
+	// audiences, the parameter is ignored for them.
+	Audiences []string `json:"audiences,omitempty"`
+
+	// Scopes the client can request.
+	Scopes []string `json:"scopes,omitempty"`
+}
+
+// ImpersonationPolicy lets a client request tokens for other users with token
+// exchange, e.g. for support engineers reproducing the issue of a user. The
+// tokens name the engineer in their "act" claim.
+type ImpersonationPolicy struct {
+	// Connectors whose users can be impersonated.
+	Connectors []string `json:"connectors"`
+
+	// ActorGroups are the groups allowed to impersonate users. The actor
+	// token of the engineer must carry one of them.
+	ActorGroups []string `json:"actorGroups"`
+
+	// ProtectedGroups are the groups whose members can't be impersonated.
+	ProtectedGroups []string `json:"protectedGroups,omitempty"`
+
+	// Scopes the client can request. Defaults to "openid".
+	Scopes []string `json:"scopes,omitempty"`
+
+	// ValidFor is the lifetime of the tokens, e.g. "10m". Defaults to 15
+	// minutes, and is capped by the lifetime of ID tokens.
+	ValidFor string `json:"validFor,omitempty"`
+}
+
+// JWTBearerIssuer is an issuer of JWTs a client can present as authorization
+// grants, like a SPIFFE trust domain or a cloud provider's workload identity.
+type JWTBearerIssuer struct {
+	// Issuer must match the "iss" claim of the JWTs.
+	Issuer string `json:"issuer"`
+
+	// JWKSURL is where the keys verifying the JWTs are published.
+	JWKSURL string `json:"jwksURL"`
+
+	// Audience the JWTs must be issued for. Defaults to dex's issuer URL or
+	// token endpoint.
+	Audience string `json:"audience,omitempty"`
+
+	// AllowedSubjects, if set, are the only subjects accepted from the issuer.
+	AllowedSubjects []string `json:"allowedSubjects,omitempty"`
+}
+
+// ClientCredentialsConfig holds the audiences and claims of the tokens a
+// client obtains with the client_credentials grant.
+type ClientCredentialsConfig struct {
+	// Audiences the client can obtain tokens for, typically the APIs it
+	// calls. The client picks some with the "audience" parameter of the
+	// token request, or gets a token for all of them if it doesn't.
+	Audiences []string `json:"audiences,omitempty"`
+
+	// Claims added to the tokens. Registered claims, like "sub" and "aud",
+	// can't be overridden.
+	Claims map[string]interface{} `json:"claims,omitempty"`
+}
+
+// ClientTheme holds the branding of a client's login and approval pages.
+type ClientTheme struct {
+	// LogoURL replaces the logo shown at the top of every page.
+	LogoURL string `json:"logoURL,omitempty"`
+
+	// PrimaryColor is used for buttons and BackgroundColor for the page
+	// background. Both take any CSS color, e.g. "#0b5ed7".
+	PrimaryColor    string `json:"primaryColor,omitempty"`
+	BackgroundColor string `json:"backgroundColor,omitempty"`
+
+	// FooterLinks are shown below the page content, e.g. links to the
+	// application's privacy policy or support page.
+	FooterLinks []ThemeLink `json:"footerLinks,omitempty"`
+}
+
+// ThemeLink is a link shown in the footer of a client's pages.
+type ThemeLink struct {
+	Text string `json:"text"`
+	URL  string `json:"url"`
 }
 
 // Claims represents the ID Token claims supported by the server.
@@ -214,6 +535,10 @@ type Claims struct {
 	EmailVerified     bool
 
 	Groups []string
+
+	// CustomClaims are claims of the connector identity beyond the above,
+	// released to clients according to their ReleasedConnectorClaims.
+	CustomClaims map[string]interface{}
 }
 
 // PKCE is a container for the data needed to perform Proof Key for Code Exchange (RFC 7636) auth flow
@@ -248,6 +573,10 @@ type AuthRequest struct {
 	// Values: "none", "login", "consent", "select_account".
 	Prompt string
 
+	// LoginHint is the OIDC login_hint parameter, a hint about the identifier
+	// the end user might use to log in.
+	LoginHint string
+
 	// MaxAge is the OIDC max_age parameter — maximum allowable elapsed time
 	// in seconds since the user last actively authenticated.
 	// -1 means not specified.
@@ -286,6 +615,10 @@ type AuthRequest struct {
 	// WebAuthnSessionData stores temporary WebAuthn ceremony data (challenge, etc.)
 	// between Begin and Finish calls. JSON-encoded webauthn.SessionData.
 	WebAuthnSessionData []byte
+
+	// Resources are the resource indicators (RFC 8707) the client requested
+	// tokens for.
+	Resources []string
 }
 
 // AuthCode represents a code which can be exchanged for an OAuth2 token response.
@@ -328,6 +661,9 @@ type AuthCode struct {
 
 	// PKCE CodeChallenge and CodeChallengeMethod
 	PKCE PKCE
+
+	// Resources the tokens can be issued for, carried over from AuthRequest.
+	Resources []string
 }
 
 // RefreshToken is an OAuth2 refresh token which allows a client to request new
@@ -341,6 +677,11 @@ type RefreshToken struct {
 	Token         string
 	ObsoleteToken string
 
+	// RotationKey derives the value of Token for every rotation, so values
+	// rotated out long ago are still recognized as this token's. Empty for
+	// tokens with random values.
+	RotationKey string
+
 	CreatedAt time.Time
 	LastUsed  time.Time
 
@@ -360,6 +701,17 @@ type RefreshToken struct {
 	// Nonce value supplied during the initial redirect. This is required to be part
 	// of the claims of any future id_token generated by the client.
 	Nonce string
+
+	// Resources the tokens can be issued for. Refresh requests may narrow them
+	// down like scopes.
+	Resources []string
+
+	// ParentID is the ID of the refresh token this one was exchanged for
+	// (RFC 8693). The token is only valid as long as its parent is.
+	ParentID string
+	// Audience is added to the audience of the tokens issued with this refresh
+	// token.
+	Audience []string
 }
 
 // RefreshTokenRef is a reference object that contains metadata about refresh tokens.
@@ -405,6 +757,8 @@ type UserIdentity struct {
 	ConnectorID         string
 	Claims              Claims
 	Consents            map[string][]string             // clientID -> approved scopes
+	ConsentedClaims     map[string]string               // clientID -> digest of the claims shown at consent
+	AcceptedTerms       map[string]TermsAcceptance      // clientID -> terms accepted at consent
 	MFASecrets          map[string]*MFASecret           // authenticatorID -> secret
 	WebAuthnCredentials map[string][]WebAuthnCredential // authenticatorID -> credentials
 	CreatedAt           time.Time
@@ -412,6 +766,13 @@ type UserIdentity struct {
 	BlockedUntil        time.Time
 }
 
+// TermsAcceptance records the version of the terms of a client a user
+// accepted, for compliance reporting.
+type TermsAcceptance struct {
+	Version    string    `json:"version"`
+	AcceptedAt time.Time `json:"acceptedAt"`
+}
+
 // ClientAuthState represents authentication state for a specific client within an auth session.
 type ClientAuthState struct {
 	Active            bool
@@ -561,16 +922,34 @@ type Keys struct {
 // NewUserCode returns a randomized 8 character user code for the device flow.
 // No vowels are included to prevent accidental generation of words
 func NewUserCode() string {
-	code := randomString(8)
-	return code[:4] + "-" + code[4:]
+	return NewUserCodeFrom("", 0)
+}
+
+// NewUserCodeFrom returns a randomized user code of length characters from
+// alphabet, split into groups of four by dashes. Empty values use the
+// defaults of NewUserCode.
+func NewUserCodeFrom(alphabet string, length int) string {
+	if alphabet == "" {
+		alphabet = validUserCharacters
+	}
+	if length <= 0 {
+		length = 8
+	}
+	code := randomString(alphabet, length)
+	groups := make([]string, 0, (length+3)/4)
+	for len(code) > 4 {
+		groups = append(groups, code[:4])
+		code = code[4:]
+	}
+	return strings.Join(append(groups, code), "-")
 }
 
-func randomString(n int) string {
-	v := big.NewInt(int64(len(validUserCharacters)))
+func randomString(alphabet string, n int) string {
+	v := big.NewInt(int64(len(alphabet)))
 	bytes := make([]byte, n)
 	for i := 0; i < n; i++ {
 		c, _ := rand.Int(rand.Reader, v)
-		bytes[i] = validUserCharacters[c.Int64()]
+		bytes[i] = alphabet[c.Int64()]
 	}
 	return string(bytes)
 }